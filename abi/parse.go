@@ -0,0 +1,107 @@
+package abi
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"gx/ipfs/QmY5Grm8pJdiSSVsYxx4uNRgweY72EmYwuSDbRnbFok3iY/go-libp2p-peer"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// ParseValue parses the string representation of a value of type t, e.g. a
+// command line argument. Amounts of AttoFIL are given in FIL, lists are comma
+// separated and bytes are taken as is.
+func ParseValue(t Type, s string) (*Value, error) {
+	var val interface{}
+	var ok bool
+	switch t {
+	case Address:
+		addr, err := address.NewFromString(s)
+		if err != nil {
+			return nil, err
+		}
+		val, ok = addr, true
+	case AttoFIL:
+		val, ok = types.NewAttoFILFromFILString(s)
+	case BytesAmount:
+		val, ok = types.NewBytesAmountFromString(s, 10)
+	case ChannelID:
+		val, ok = types.NewChannelIDFromString(s, 10)
+	case BlockHeight:
+		val, ok = types.NewBlockHeightFromString(s, 10)
+	case Integer:
+		val, ok = big.NewInt(0).SetString(s, 10)
+	case Bytes:
+		val, ok = []byte(s), true
+	case String:
+		val, ok = s, true
+	case UintArray:
+		var ints []uint64
+		for _, f := range splitList(s) {
+			i, err := strconv.ParseUint(f, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			ints = append(ints, i)
+		}
+		val, ok = ints, true
+	case PeerID:
+		id, err := peer.IDB58Decode(s)
+		if err != nil {
+			return nil, err
+		}
+		val, ok = id, true
+	case SectorID:
+		i, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		val, ok = i, true
+	case Addresses:
+		var addrs []address.Address
+		for _, f := range splitList(s) {
+			addr, err := address.NewFromString(f)
+			if err != nil {
+				return nil, err
+			}
+			addrs = append(addrs, addr)
+		}
+		val, ok = addrs, true
+	default:
+		return nil, fmt.Errorf("cannot parse values of type %s", t)
+	}
+	if !ok {
+		return nil, fmt.Errorf("invalid %s: %q", t, s)
+	}
+
+	return &Value{Type: t, Val: val}, nil
+}
+
+// ParseValues parses the string representations of values of the given
+// types, see ParseValue.
+func ParseValues(strs []string, types []Type) ([]*Value, error) {
+	if len(strs) != len(types) {
+		return nil, fmt.Errorf("expected %d values, got %d", len(types), len(strs))
+	}
+
+	vals := make([]*Value, len(strs))
+	for i, s := range strs {
+		v, err := ParseValue(types[i], s)
+		if err != nil {
+			return nil, err
+		}
+		vals[i] = v
+	}
+	return vals, nil
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
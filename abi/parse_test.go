@@ -0,0 +1,42 @@
+package abi
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func TestParseValues(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	addrGetter := address.NewForTestGetter()
+	a1, a2 := addrGetter(), addrGetter()
+
+	vals, err := ParseValues(
+		[]string{a1.String(), "2", "42", "17", "beep", "1,2,3", a1.String() + "," + a2.String()},
+		[]Type{Address, AttoFIL, BlockHeight, Integer, String, UintArray, Addresses},
+	)
+	require.NoError(err)
+	assert.Equal([]interface{}{
+		a1,
+		types.NewAttoFILFromFIL(2),
+		types.NewBlockHeight(42),
+		big.NewInt(17),
+		"beep",
+		[]uint64{1, 2, 3},
+		[]address.Address{a1, a2},
+	}, FromValues(vals))
+
+	_, err = ParseValues([]string{"x"}, []Type{Integer})
+	assert.EqualError(err, `invalid *big.Int: "x"`)
+	_, err = ParseValues([]string{"1"}, []Type{Integer, Integer})
+	assert.EqualError(err, "expected 2 values, got 1")
+	_, err = ParseValues([]string{"1"}, []Type{CommitmentsMap})
+	assert.Error(err)
+}
@@ -273,3 +273,46 @@ func TestMakeTypedExportFail(t *testing.T) {
 		})
 	}
 }
+
+func TestDispatch(t *testing.T) {
+	t.Run("uses the generated dispatch code of an actor", func(t *testing.T) {
+		assert := assert.New(t)
+
+		a := &FakeActor{}
+		var _ exec.Dispatcher = a
+
+		ret, exitCode, err := Dispatch(a, "nonZeroExitCode")(makeCtx("nonZeroExitCode"))
+		assert.NoError(err)
+		assert.Equal(uint8(42), exitCode)
+		assert.Nil(ret)
+
+		// Generated dispatch behaves like reflection based dispatch.
+		gRet, gExitCode, gErr := Dispatch(a, "hasReturnValue")(makeCtx("hasReturnValue"))
+		rRet, rExitCode, rErr := MakeTypedExport(a, "hasReturnValue")(makeCtx("hasReturnValue"))
+		assert.Equal(rRet, gRet)
+		assert.Equal(rExitCode, gExitCode)
+		assert.Equal(rErr.Error(), gErr.Error())
+	})
+
+	t.Run("falls back to reflection for actors without generated code", func(t *testing.T) {
+		assert := assert.New(t)
+
+		a := NewMockActor(map[string]*exec.FunctionSignature{
+			"two": {
+				Params: nil,
+				Return: nil,
+			},
+		})
+
+		ret, exitCode, err := Dispatch(a, "two")(makeCtx("two"))
+		assert.NoError(err)
+		assert.Equal(uint8(0), exitCode)
+		assert.Nil(ret)
+	})
+
+	t.Run("panics on a method missing from the generated code", func(t *testing.T) {
+		assert.Panics(t, func() {
+			Dispatch(&FakeActor{}, "doesNotExist")
+		})
+	})
+}
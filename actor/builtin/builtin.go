@@ -2,25 +2,59 @@
 package builtin
 
 import (
-	cid "gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
-
 	"github.com/filecoin-project/go-filecoin/actor/builtin/account"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/cron"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/miner"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/multisig"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/paymentbroker"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/reward"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/storagemarket"
-	"github.com/filecoin-project/go-filecoin/exec"
 	"github.com/filecoin-project/go-filecoin/types"
 )
 
-// Actors is list of all actors that ship with Filecoin.
-// They are indexed by their CID.
-var Actors = map[cid.Cid]exec.ExecutableActor{}
+// Default is the registry of the actors that ship with Filecoin. Test
+// networks may add experimental actors to it with Register before the node
+// starts.
+var Default = NewRegistry()
+
+// Actors is list of all actors that ship with Filecoin, and those registered
+// since. They are indexed by their CID.
+//
+// It is the map backing Default, so tests may add fake actors to it directly.
+var Actors = Default.actors
+
+// StateSchemas maps the code of the builtin actors whose state is a single
+// CBOR object to a constructor of the type that state decodes into. It is
+// used to present actor state to users; actors missing from it have no state
+// or keep it in lookups.
+var StateSchemas = Default.schemas
+
+// Register adds the actor in e to the default registry, naming its code after
+// e.Name for display.
+func Register(e Entry) error {
+	if err := Default.Register(e); err != nil {
+		return err
+	}
+	if _, ok := types.ActorCodeCidTypeNames[e.Code]; !ok && e.Name != "" {
+		types.ActorCodeCidTypeNames[e.Code] = e.Name
+	}
+	return nil
+}
 
 func init() {
 	// Instance Actors
-	Actors[types.AccountActorCodeCid] = &account.Actor{}
-	Actors[types.StorageMarketActorCodeCid] = &storagemarket.Actor{}
-	Actors[types.PaymentBrokerActorCodeCid] = &paymentbroker.Actor{}
-	Actors[types.MinerActorCodeCid] = &miner.Actor{}
-	Actors[types.BootstrapMinerActorCodeCid] = &miner.Actor{Bootstrap: true}
+	Default.MustRegister(Entry{Code: types.AccountActorCodeCid, Name: "AccountActor", Version: 1, Actor: &account.Actor{}})
+	Default.MustRegister(Entry{Code: types.StorageMarketActorCodeCid, Name: "StorageMarketActor", Version: 1, Actor: &storagemarket.Actor{},
+		StateSchema: func() interface{} { return &storagemarket.State{} }})
+	Default.MustRegister(Entry{Code: types.PaymentBrokerActorCodeCid, Name: "PaymentBrokerActor", Version: 1, Actor: &paymentbroker.Actor{}})
+	Default.MustRegister(Entry{Code: types.MinerActorCodeCid, Name: "MinerActor", Version: 1, Actor: &miner.Actor{},
+		StateSchema: func() interface{} { return &miner.State{} }})
+	Default.MustRegister(Entry{Code: types.BootstrapMinerActorCodeCid, Name: "BootstrapMinerActor", Version: 1, Actor: &miner.Actor{Bootstrap: true},
+		StateSchema: func() interface{} { return &miner.State{} }})
+	Default.MustRegister(Entry{Code: types.MultisigActorCodeCid, Name: "MultisigActor", Version: 1, Actor: &multisig.Actor{},
+		StateSchema: func() interface{} { return &multisig.State{} }})
+	Default.MustRegister(Entry{Code: types.RewardActorCodeCid, Name: "RewardActor", Version: 1, Actor: &reward.Actor{},
+		StateSchema: func() interface{} { return &reward.State{} }})
+	Default.MustRegister(Entry{Code: types.CronActorCodeCid, Name: "CronActor", Version: 1, Actor: &cron.Actor{},
+		StateSchema: func() interface{} { return &cron.State{} }})
 }
@@ -0,0 +1,197 @@
+package cron
+
+import (
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	cbor "gx/ipfs/QmRoARq3nkUb13HSKZGepCZSWe5GrVPwx7xURJGZ7KWv9V/go-ipld-cbor"
+	xerrors "gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+
+	"github.com/filecoin-project/go-filecoin/abi"
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/vm/errors"
+)
+
+func init() {
+	cbor.RegisterCborType(State{})
+	cbor.RegisterCborType(Callback{})
+}
+
+const (
+	// ErrCallerUnauthorized signals an unauthorized caller.
+	ErrCallerUnauthorized = 33
+	// ErrAccountCallback signals an account actor trying to register a callback.
+	ErrAccountCallback = 34
+)
+
+// Errors map error codes to revert errors this actor may return.
+var Errors = map[uint8]error{
+	ErrCallerUnauthorized: errors.NewCodedRevertErrorf(ErrCallerUnauthorized, "only the network may run the cron"),
+	ErrAccountCallback:    errors.NewCodedRevertErrorf(ErrAccountCallback, "account actors may not register callbacks"),
+}
+
+// Actor is the builtin actor calling back the actors registered with it at
+// the end of every epoch, so they can do periodic work (e.g. detecting
+// faults or expiring deals) without anyone sending them a message.
+type Actor struct{}
+
+// Callback is a method of an actor to call every epoch.
+type Callback struct {
+	Receiver address.Address
+	Method   string
+}
+
+// State is the cron actor's storage.
+type State struct {
+	// Callbacks are called in the order they were registered.
+	Callbacks []Callback
+}
+
+// NewActor returns a new cron actor.
+func NewActor() *actor.Actor {
+	return actor.NewActor(types.CronActorCodeCid, types.NewZeroAttoFIL())
+}
+
+// InitializeState stores the actor's initial data structure.
+func (ca *Actor) InitializeState(storage exec.Storage, _ interface{}) error {
+	stateBytes, err := cbor.DumpObject(&State{})
+	if err != nil {
+		return xerrors.Wrap(err, "failed to cbor marshal object")
+	}
+
+	id, err := storage.Put(stateBytes)
+	if err != nil {
+		return err
+	}
+
+	return storage.Commit(id, cid.Undef)
+}
+
+var _ exec.ExecutableActor = (*Actor)(nil)
+
+// Exports returns the actor's exports.
+func (ca *Actor) Exports() exec.Exports {
+	return cronExports
+}
+
+//go:generate go run ../../../tools/actorgen/main.go -type Actor -exports cronExports
+
+var cronExports = exec.Exports{
+	"registerCallback": &exec.FunctionSignature{
+		Params: []abi.Type{abi.String},
+		Return: nil,
+	},
+	"unregisterCallback": &exec.FunctionSignature{
+		Params: []abi.Type{abi.String},
+		Return: nil,
+	},
+	"getCallbacks": &exec.FunctionSignature{
+		Params: nil,
+		Return: []abi.Type{abi.Bytes},
+	},
+	"epochTick": &exec.FunctionSignature{
+		Params: nil,
+		Return: nil,
+	},
+}
+
+// RegisterCallback has the cron call method of the calling actor at the end
+// of every epoch. Registering the same method twice has no effect.
+func (ca *Actor) RegisterCallback(ctx exec.VMContext, method string) (uint8, error) {
+	if ctx.IsFromAccountActor() {
+		return ErrAccountCallback, Errors[ErrAccountCallback]
+	}
+
+	cb := Callback{Receiver: ctx.Message().From, Method: method}
+
+	var state State
+	_, err := actor.WithState(ctx, &state, func() (interface{}, error) {
+		for _, registered := range state.Callbacks {
+			if registered == cb {
+				return nil, nil
+			}
+		}
+		state.Callbacks = append(state.Callbacks, cb)
+		return nil, nil
+	})
+	if err != nil {
+		return errors.CodeError(err), err
+	}
+
+	return 0, nil
+}
+
+// UnregisterCallback stops the cron from calling method of the calling actor.
+func (ca *Actor) UnregisterCallback(ctx exec.VMContext, method string) (uint8, error) {
+	cb := Callback{Receiver: ctx.Message().From, Method: method}
+
+	var state State
+	_, err := actor.WithState(ctx, &state, func() (interface{}, error) {
+		for i, registered := range state.Callbacks {
+			if registered == cb {
+				state.Callbacks = append(state.Callbacks[:i], state.Callbacks[i+1:]...)
+				break
+			}
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return errors.CodeError(err), err
+	}
+
+	return 0, nil
+}
+
+// GetCallbacks returns the registered callbacks, in the order they are
+// called, serialized.
+func (ca *Actor) GetCallbacks(ctx exec.VMContext) ([]byte, uint8, error) {
+	var state State
+	out, err := actor.WithState(ctx, &state, func() (interface{}, error) {
+		return state.Callbacks, nil
+	})
+	if err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	callbacks, ok := out.([]Callback)
+	if !ok {
+		return nil, 1, errors.NewFaultErrorf("expected []Callback to be returned, but got %T instead", out)
+	}
+
+	callbacksBytes, err := actor.MarshalStorage(callbacks)
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal callbacks")
+	}
+
+	return callbacksBytes, 0, nil
+}
+
+// EpochTick calls every registered callback. It may only be called by the
+// network at the end of an epoch. A failing callback fails the whole tick.
+func (ca *Actor) EpochTick(ctx exec.VMContext) (uint8, error) {
+	if ctx.Message().From != address.NetworkAddress {
+		return ErrCallerUnauthorized, Errors[ErrCallerUnauthorized]
+	}
+
+	var state State
+	out, err := actor.WithState(ctx, &state, func() (interface{}, error) {
+		return state.Callbacks, nil
+	})
+	if err != nil {
+		return errors.CodeError(err), err
+	}
+
+	callbacks, ok := out.([]Callback)
+	if !ok {
+		return 1, errors.NewFaultErrorf("expected []Callback to be returned, but got %T instead", out)
+	}
+
+	for _, cb := range callbacks {
+		if _, code, err := ctx.Send(cb.Receiver, cb.Method, nil, nil); err != nil {
+			return code, err
+		}
+	}
+
+	return 0, nil
+}
@@ -0,0 +1,95 @@
+package cron_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/actor/builtin"
+	. "github.com/filecoin-project/go-filecoin/actor/builtin/cron"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/consensus"
+	"github.com/filecoin-project/go-filecoin/core"
+	"github.com/filecoin-project/go-filecoin/state"
+	th "github.com/filecoin-project/go-filecoin/testhelpers"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/vm"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCronCallbacks(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+
+	fakeActorCodeCid := types.NewCidForTestGetter()()
+	builtin.Actors[fakeActorCodeCid] = &actor.FakeActor{}
+	defer delete(builtin.Actors, fakeActorCodeCid)
+
+	st, vms := core.CreateStorages(ctx, t)
+	fakeAddr := address.NewForTestGetter()()
+	fakeAct := th.RequireNewFakeActor(require, vms, fakeAddr, fakeActorCodeCid)
+	require.NoError(st.SetActor(ctx, fakeAddr, fakeAct))
+
+	// registering twice has no effect
+	for nonce := uint64(0); nonce < 2; nonce++ {
+		msg := types.NewMessage(fakeAddr, address.CronAddress, nonce, nil, "registerCallback", actor.MustConvertParams("goodCall"))
+		result, err := th.ApplyTestMessage(st, vms, msg, types.NewBlockHeight(0))
+		require.NoError(err)
+		require.NoError(result.ExecutionError)
+	}
+	assert.Equal([]Callback{{Receiver: fakeAddr, Method: "goodCall"}}, requireGetCallbacks(t, st, vms))
+
+	head := state.MustGetActor(st, fakeAddr).Head
+	msg := types.NewMessage(address.NetworkAddress, address.CronAddress, 0, nil, "epochTick", nil)
+	result, err := th.ApplyTestMessage(st, vms, msg, types.NewBlockHeight(1))
+	require.NoError(err)
+	require.NoError(result.ExecutionError)
+	assert.False(head.Equals(state.MustGetActor(st, fakeAddr).Head))
+
+	msg = types.NewMessage(fakeAddr, address.CronAddress, 2, nil, "unregisterCallback", actor.MustConvertParams("goodCall"))
+	result, err = th.ApplyTestMessage(st, vms, msg, types.NewBlockHeight(1))
+	require.NoError(err)
+	require.NoError(result.ExecutionError)
+	assert.Empty(requireGetCallbacks(t, st, vms))
+}
+
+func TestCronAccountsMayNotRegister(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	st, vms := core.CreateStorages(context.Background(), t)
+
+	msg := types.NewMessage(address.TestAddress, address.CronAddress, 0, nil, "registerCallback", actor.MustConvertParams("goodCall"))
+	result, err := th.ApplyTestMessage(st, vms, msg, types.NewBlockHeight(0))
+	require.NoError(err)
+
+	assert.Error(result.ExecutionError)
+	assert.Equal(uint8(ErrAccountCallback), result.Receipt.ExitCode)
+}
+
+func TestCronEpochTickUnauthorized(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	st, vms := core.CreateStorages(context.Background(), t)
+
+	msg := types.NewMessage(address.TestAddress, address.CronAddress, 0, nil, "epochTick", nil)
+	result, err := th.ApplyTestMessage(st, vms, msg, types.NewBlockHeight(0))
+	require.NoError(err)
+
+	assert.Error(result.ExecutionError)
+	assert.Equal(uint8(ErrCallerUnauthorized), result.Receipt.ExitCode)
+}
+
+func requireGetCallbacks(t *testing.T, st state.Tree, vms vm.StorageMap) []Callback {
+	ret, code, err := consensus.CallQueryMethod(context.Background(), st, vms, address.CronAddress, "getCallbacks", nil, address.TestAddress, nil)
+	require.NoError(t, err)
+	require.Equal(t, uint8(0), code)
+
+	var callbacks []Callback
+	require.NoError(t, actor.UnmarshalStorage(ret[0], &callbacks))
+	return callbacks
+}
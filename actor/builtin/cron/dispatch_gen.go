@@ -0,0 +1,100 @@
+// Code generated by actorgen. DO NOT EDIT.
+
+package cron
+
+import (
+	"github.com/filecoin-project/go-filecoin/abi"
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/vm/errors"
+)
+
+var _ exec.Dispatcher = (*Actor)(nil)
+
+// Method returns the exported method with the given name, bound to a.
+func (a *Actor) Method(name string) (exec.ExportedFunc, bool) {
+	switch name {
+	case "epochTick":
+		return a.dispatchEpochTick, true
+	case "getCallbacks":
+		return a.dispatchGetCallbacks, true
+	case "registerCallback":
+		return a.dispatchRegisterCallback, true
+	case "unregisterCallback":
+		return a.dispatchUnregisterCallback, true
+	default:
+		return nil, false
+	}
+}
+
+func (a *Actor) dispatchEpochTick(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, cronExports["epochTick"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	code, err := a.EpochTick(ctx)
+	if err != nil {
+		return nil, code, actor.CheckExportError(a, "epochTick", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues()
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *Actor) dispatchGetCallbacks(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, cronExports["getCallbacks"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	r0, code, err := a.GetCallbacks(ctx)
+	if err != nil {
+		return nil, code, actor.CheckExportError(a, "getCallbacks", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues(r0)
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *Actor) dispatchRegisterCallback(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, cronExports["registerCallback"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	code, err := a.RegisterCallback(ctx, params[0].Val.(string))
+	if err != nil {
+		return nil, code, actor.CheckExportError(a, "registerCallback", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues()
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *Actor) dispatchUnregisterCallback(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, cronExports["unregisterCallback"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	code, err := a.UnregisterCallback(ctx, params[0].Val.(string))
+	if err != nil {
+		return nil, code, actor.CheckExportError(a, "unregisterCallback", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues()
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
@@ -0,0 +1,345 @@
+// Code generated by actorgen. DO NOT EDIT.
+
+package miner
+
+import (
+	"math/big"
+
+	"gx/ipfs/QmY5Grm8pJdiSSVsYxx4uNRgweY72EmYwuSDbRnbFok3iY/go-libp2p-peer"
+
+	"github.com/filecoin-project/go-filecoin/abi"
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/vm/errors"
+)
+
+var _ exec.Dispatcher = (*Actor)(nil)
+
+// Method returns the exported method with the given name, bound to a.
+func (a *Actor) Method(name string) (exec.ExportedFunc, bool) {
+	switch name {
+	case "addAsk":
+		return a.dispatchAddAsk, true
+	case "addAskWithPieceSizes":
+		return a.dispatchAddAskWithPieceSizes, true
+	case "commitSector":
+		return a.dispatchCommitSector, true
+	case "commitSectors":
+		return a.dispatchCommitSectors, true
+	case "getAsk":
+		return a.dispatchGetAsk, true
+	case "getAsks":
+		return a.dispatchGetAsks, true
+	case "getKey":
+		return a.dispatchGetKey, true
+	case "getLastUsedSectorID":
+		return a.dispatchGetLastUsedSectorID, true
+	case "getOwner":
+		return a.dispatchGetOwner, true
+	case "getPeerID":
+		return a.dispatchGetPeerID, true
+	case "getPledge":
+		return a.dispatchGetPledge, true
+	case "getPower":
+		return a.dispatchGetPower, true
+	case "getProvingPeriodStart":
+		return a.dispatchGetProvingPeriodStart, true
+	case "getSectorCommitments":
+		return a.dispatchGetSectorCommitments, true
+	case "submitPoSt":
+		return a.dispatchSubmitPoSt, true
+	case "updatePeerID":
+		return a.dispatchUpdatePeerID, true
+	default:
+		return nil, false
+	}
+}
+
+func (a *Actor) dispatchAddAsk(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, minerExports["addAsk"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	r0, code, err := a.AddAsk(ctx, params[0].Val.(*types.AttoFIL), params[1].Val.(*big.Int))
+	if err != nil {
+		return nil, code, actor.CheckExportError(a, "addAsk", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues(r0)
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *Actor) dispatchAddAskWithPieceSizes(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, minerExports["addAskWithPieceSizes"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	r0, code, err := a.AddAskWithPieceSizes(ctx, params[0].Val.(*types.AttoFIL), params[1].Val.(*big.Int), params[2].Val.(*types.BytesAmount), params[3].Val.(*types.BytesAmount))
+	if err != nil {
+		return nil, code, actor.CheckExportError(a, "addAskWithPieceSizes", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues(r0)
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *Actor) dispatchCommitSector(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, minerExports["commitSector"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	code, err := a.CommitSector(ctx, params[0].Val.(uint64), params[1].Val.([]byte), params[2].Val.([]byte), params[3].Val.([]byte), params[4].Val.([]byte))
+	if err != nil {
+		return nil, code, actor.CheckExportError(a, "commitSector", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues()
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *Actor) dispatchCommitSectors(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, minerExports["commitSectors"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	code, err := a.CommitSectors(ctx, params[0].Val.([]uint64), params[1].Val.([]byte))
+	if err != nil {
+		return nil, code, actor.CheckExportError(a, "commitSectors", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues()
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *Actor) dispatchGetAsk(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, minerExports["getAsk"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	r0, code, err := a.GetAsk(ctx, params[0].Val.(*big.Int))
+	if err != nil {
+		return nil, code, actor.CheckExportError(a, "getAsk", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues(r0)
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *Actor) dispatchGetAsks(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, minerExports["getAsks"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	r0, code, err := a.GetAsks(ctx)
+	if err != nil {
+		return nil, code, actor.CheckExportError(a, "getAsks", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues(r0)
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *Actor) dispatchGetKey(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, minerExports["getKey"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	r0, code, err := a.GetKey(ctx)
+	if err != nil {
+		return nil, code, actor.CheckExportError(a, "getKey", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues(r0)
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *Actor) dispatchGetLastUsedSectorID(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, minerExports["getLastUsedSectorID"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	r0, code, err := a.GetLastUsedSectorID(ctx)
+	if err != nil {
+		return nil, code, actor.CheckExportError(a, "getLastUsedSectorID", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues(r0)
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *Actor) dispatchGetOwner(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, minerExports["getOwner"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	r0, code, err := a.GetOwner(ctx)
+	if err != nil {
+		return nil, code, actor.CheckExportError(a, "getOwner", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues(r0)
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *Actor) dispatchGetPeerID(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, minerExports["getPeerID"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	r0, code, err := a.GetPeerID(ctx)
+	if err != nil {
+		return nil, code, actor.CheckExportError(a, "getPeerID", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues(r0)
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *Actor) dispatchGetPledge(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, minerExports["getPledge"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	r0, code, err := a.GetPledge(ctx)
+	if err != nil {
+		return nil, code, actor.CheckExportError(a, "getPledge", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues(r0)
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *Actor) dispatchGetPower(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, minerExports["getPower"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	r0, code, err := a.GetPower(ctx)
+	if err != nil {
+		return nil, code, actor.CheckExportError(a, "getPower", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues(r0)
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *Actor) dispatchGetProvingPeriodStart(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, minerExports["getProvingPeriodStart"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	r0, code, err := a.GetProvingPeriodStart(ctx)
+	if err != nil {
+		return nil, code, actor.CheckExportError(a, "getProvingPeriodStart", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues(r0)
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *Actor) dispatchGetSectorCommitments(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, minerExports["getSectorCommitments"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	r0, code, err := a.GetSectorCommitments(ctx)
+	if err != nil {
+		return nil, code, actor.CheckExportError(a, "getSectorCommitments", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues(r0)
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *Actor) dispatchSubmitPoSt(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, minerExports["submitPoSt"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	code, err := a.SubmitPoSt(ctx, params[0].Val.([]byte))
+	if err != nil {
+		return nil, code, actor.CheckExportError(a, "submitPoSt", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues()
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *Actor) dispatchUpdatePeerID(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, minerExports["updatePeerID"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	code, err := a.UpdatePeerID(ctx, params[0].Val.(peer.ID))
+	if err != nil {
+		return nil, code, actor.CheckExportError(a, "updatePeerID", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues()
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
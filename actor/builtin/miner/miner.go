@@ -1,11 +1,14 @@
 package miner
 
 import (
+	"context"
 	"math/big"
 	"os"
+	"sort"
 	"strconv"
 
 	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	"gx/ipfs/QmRXf2uUSdGSunRJsM9wXSUNVwLUGCY3So5fAs7h2CBJVf/go-hamt-ipld"
 	cbor "gx/ipfs/QmRoARq3nkUb13HSKZGepCZSWe5GrVPwx7xURJGZ7KWv9V/go-ipld-cbor"
 	xerrors "gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
 	"gx/ipfs/QmY5Grm8pJdiSSVsYxx4uNRgweY72EmYwuSDbRnbFok3iY/go-libp2p-peer"
@@ -63,6 +66,14 @@ const (
 	ErrInvalidSealProof = 41
 )
 
+// Types of the events this actor emits, and the values they carry.
+const (
+	// EventSectorCommitted carries the sector id and commR of a committed sector.
+	EventSectorCommitted = "sectorCommitted"
+	// EventPoStSubmitted carries the start of the proving period a PoSt moved the miner to.
+	EventPoStSubmitted = "postSubmitted"
+)
+
 // Errors map error codes to revert errors this actor may return.
 var Errors = map[uint8]error{
 	ErrPublicKeyTooBig:         errors.NewCodedRevertErrorf(ErrPublicKeyTooBig, "public key must be less than %d bytes", MaximumPublicKeySize),
@@ -95,6 +106,11 @@ type Ask struct {
 	Price  *types.AttoFIL
 	Expiry *types.BlockHeight
 	ID     *big.Int
+
+	// MinPieceSize and MaxPieceSize bound the size of the pieces the miner
+	// stores at the price of the ask. They are nil if the ask has no bound.
+	MinPieceSize *types.BytesAmount
+	MaxPieceSize *types.BytesAmount
 }
 
 // State is the miner actors storage.
@@ -114,16 +130,14 @@ type State struct {
 	// the miners pledge.
 	Collateral *types.AttoFIL
 
-	// Asks is the set of asks this miner has open
-	Asks      []*Ask
+	// Asks is the cid of the lookup of the asks this miner has open, keyed
+	// by ask id.
+	Asks      cid.Cid `refmt:",omitempty"`
 	NextAskID *big.Int
 
-	// SectorCommitments maps sector id to commitments, for all sectors this
-	// miner has committed. Due to a bug in refmt, the sector id-keys need to be
-	// stringified.
-	//
-	// See also: https://github.com/polydawn/refmt/issues/35
-	SectorCommitments map[string]types.Commitments
+	// SectorCommitments is the cid of the lookup of the commitments of all
+	// sectors this miner has committed, keyed by sector id.
+	SectorCommitments cid.Cid `refmt:",omitempty"`
 
 	LastUsedSectorID uint64
 
@@ -141,14 +155,13 @@ func NewActor() *actor.Actor {
 // NewState creates a miner state struct
 func NewState(owner address.Address, key []byte, pledge *big.Int, pid peer.ID, collateral *types.AttoFIL) *State {
 	return &State{
-		Owner:             owner,
-		PeerID:            pid,
-		PublicKey:         key,
-		PledgeSectors:     pledge,
-		Collateral:        collateral,
-		SectorCommitments: make(map[string]types.Commitments),
-		Power:             big.NewInt(0),
-		NextAskID:         big.NewInt(0),
+		Owner:         owner,
+		PeerID:        pid,
+		PublicKey:     key,
+		PledgeSectors: pledge,
+		Collateral:    collateral,
+		Power:         big.NewInt(0),
+		NextAskID:     big.NewInt(0),
 	}
 }
 
@@ -180,11 +193,17 @@ func (ma *Actor) InitializeState(storage exec.Storage, initializerData interface
 
 var _ exec.ExecutableActor = (*Actor)(nil)
 
+//go:generate go run ../../../tools/actorgen/main.go -type Actor -exports minerExports
+
 var minerExports = exec.Exports{
 	"addAsk": &exec.FunctionSignature{
 		Params: []abi.Type{abi.AttoFIL, abi.Integer},
 		Return: []abi.Type{abi.Integer},
 	},
+	"addAskWithPieceSizes": &exec.FunctionSignature{
+		Params: []abi.Type{abi.AttoFIL, abi.Integer, abi.BytesAmount, abi.BytesAmount},
+		Return: []abi.Type{abi.Integer},
+	},
 	"getAsks": &exec.FunctionSignature{
 		Params: nil,
 		Return: []abi.Type{abi.UintArray},
@@ -233,6 +252,10 @@ var minerExports = exec.Exports{
 		Params: []abi.Type{},
 		Return: []abi.Type{abi.BlockHeight},
 	},
+	"commitSectors": &exec.FunctionSignature{
+		Params: []abi.Type{abi.UintArray, abi.Bytes},
+		Return: []abi.Type{},
+	},
 	"getSectorCommitments": &exec.FunctionSignature{
 		Params: nil,
 		Return: []abi.Type{abi.CommitmentsMap},
@@ -247,10 +270,26 @@ func (ma *Actor) Exports() exec.Exports {
 // AddAsk adds an ask to this miners ask list
 func (ma *Actor) AddAsk(ctx exec.VMContext, price *types.AttoFIL, expiry *big.Int) (*big.Int, uint8,
 	error) {
-	if err := ctx.Charge(100); err != nil {
-		return nil, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	return ma.addAsk(ctx, price, expiry, nil, nil)
+}
+
+// AddAskWithPieceSizes adds an ask for pieces of at least minSize and at most
+// maxSize bytes to this miners ask list. A zero size leaves the ask unbounded
+// on that side.
+func (ma *Actor) AddAskWithPieceSizes(ctx exec.VMContext, price *types.AttoFIL, expiry *big.Int, minSize, maxSize *types.BytesAmount) (*big.Int, uint8, error) {
+	if minSize.IsZero() {
+		minSize = nil
 	}
+	if maxSize.IsZero() {
+		maxSize = nil
+	}
+	if minSize != nil && maxSize != nil && maxSize.LessThan(minSize) {
+		return nil, 1, errors.NewRevertError("max piece size is less than min piece size")
+	}
+	return ma.addAsk(ctx, price, expiry, minSize, maxSize)
+}
 
+func (ma *Actor) addAsk(ctx exec.VMContext, price *types.AttoFIL, expiry *big.Int, minSize, maxSize *types.BytesAmount) (*big.Int, uint8, error) {
 	var state State
 	out, err := actor.WithState(ctx, &state, func() (interface{}, error) {
 		if ctx.Message().From != state.Owner {
@@ -260,25 +299,41 @@ func (ma *Actor) AddAsk(ctx exec.VMContext, price *types.AttoFIL, expiry *big.In
 		id := big.NewInt(0).Set(state.NextAskID)
 		state.NextAskID = state.NextAskID.Add(state.NextAskID, big.NewInt(1))
 
-		// filter out expired asks
-		asks := state.Asks
-		state.Asks = state.Asks[:0]
-		for _, a := range asks {
-			if ctx.BlockHeight().LessThan(a.Expiry) {
-				state.Asks = append(state.Asks, a)
-			}
-		}
-
 		if !expiry.IsUint64() {
 			return nil, errors.NewRevertError("expiry was invalid")
 		}
 		expiryBH := types.NewBlockHeight(expiry.Uint64())
 
-		state.Asks = append(state.Asks, &Ask{
-			Price:  price,
-			Expiry: ctx.BlockHeight().Add(expiryBH),
-			ID:     id,
+		asksCid, err := actor.WithTypedLookup(context.Background(), ctx.Storage(), state.Asks, &Ask{}, func(asks exec.Lookup) error {
+			// filter out expired asks
+			kvs, err := asks.Values(context.Background())
+			if err != nil {
+				return err
+			}
+			for _, kv := range kvs {
+				a, err := askFromValue(kv.Value)
+				if err != nil {
+					return err
+				}
+				if !ctx.BlockHeight().LessThan(a.Expiry) {
+					if err := asks.Delete(context.Background(), kv.Key); err != nil {
+						return err
+					}
+				}
+			}
+
+			return asks.Set(context.Background(), id.String(), &Ask{
+				Price:        price,
+				Expiry:       ctx.BlockHeight().Add(expiryBH),
+				ID:           id,
+				MinPieceSize: minSize,
+				MaxPieceSize: maxSize,
+			})
 		})
+		if err != nil {
+			return nil, errors.FaultErrorWrap(err, "could not update asks")
+		}
+		state.Asks = asksCid
 
 		return id, nil
 	})
@@ -297,18 +352,30 @@ func (ma *Actor) AddAsk(ctx exec.VMContext, price *types.AttoFIL, expiry *big.In
 // GetAsks returns all the asks for this miner. (TODO: this isnt a great function signature, it returns the asks in a
 // serialized array. Consider doing this some other way)
 func (ma *Actor) GetAsks(ctx exec.VMContext) ([]uint64, uint8, error) {
-	if err := ctx.Charge(100); err != nil {
-		return nil, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
-	}
 	var state State
 	out, err := actor.WithState(ctx, &state, func() (interface{}, error) {
 		var askids []uint64
-		for _, ask := range state.Asks {
-			if !ask.ID.IsUint64() {
-				return nil, errors.NewFaultErrorf("miner ask has invalid ID (bad invariant)")
+		err := actor.WithTypedLookupForReading(context.Background(), ctx.Storage(), state.Asks, &Ask{}, func(asks exec.Lookup) error {
+			kvs, err := asks.Values(context.Background())
+			if err != nil {
+				return err
 			}
-			askids = append(askids, ask.ID.Uint64())
+			for _, kv := range kvs {
+				ask, err := askFromValue(kv.Value)
+				if err != nil {
+					return err
+				}
+				if !ask.ID.IsUint64() {
+					return errors.NewFaultErrorf("miner ask has invalid ID (bad invariant)")
+				}
+				askids = append(askids, ask.ID.Uint64())
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, errors.FaultErrorWrap(err, "could not read asks")
 		}
+		sort.Slice(askids, func(i, j int) bool { return askids[i] < askids[j] })
 
 		return askids, nil
 	})
@@ -326,22 +393,21 @@ func (ma *Actor) GetAsks(ctx exec.VMContext) ([]uint64, uint8, error) {
 
 // GetAsk returns an ask by ID
 func (ma *Actor) GetAsk(ctx exec.VMContext, askid *big.Int) ([]byte, uint8, error) {
-	if err := ctx.Charge(100); err != nil {
-		return nil, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
-	}
-
 	var state State
 	out, err := actor.WithState(ctx, &state, func() (interface{}, error) {
 		var ask *Ask
-		for _, a := range state.Asks {
-			if a.ID.Cmp(askid) == 0 {
-				ask = a
-				break
+		err := actor.WithTypedLookupForReading(context.Background(), ctx.Storage(), state.Asks, &Ask{}, func(asks exec.Lookup) error {
+			value, err := asks.Find(context.Background(), askid.String())
+			if err != nil {
+				return err
 			}
-		}
-
-		if ask == nil {
+			ask, err = askFromValue(value)
+			return err
+		})
+		if err == hamt.ErrNotFound {
 			return nil, Errors[ErrAskNotFound]
+		} else if err != nil {
+			return nil, errors.FaultErrorWrap(err, "could not read ask")
 		}
 
 		out, err := cbor.DumpObject(ask)
@@ -365,10 +431,6 @@ func (ma *Actor) GetAsk(ctx exec.VMContext, askid *big.Int) ([]byte, uint8, erro
 
 // GetOwner returns the miners owner.
 func (ma *Actor) GetOwner(ctx exec.VMContext) (address.Address, uint8, error) {
-	if err := ctx.Charge(100); err != nil {
-		return address.Address{}, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
-	}
-
 	var state State
 	out, err := actor.WithState(ctx, &state, func() (interface{}, error) {
 		return state.Owner, nil
@@ -387,9 +449,6 @@ func (ma *Actor) GetOwner(ctx exec.VMContext) (address.Address, uint8, error) {
 
 // GetLastUsedSectorID returns the last used sector id.
 func (ma *Actor) GetLastUsedSectorID(ctx exec.VMContext) (uint64, uint8, error) {
-	if err := ctx.Charge(100); err != nil {
-		return 0, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
-	}
 	var state State
 	out, err := actor.WithState(ctx, &state, func() (interface{}, error) {
 		return state.LastUsedSectorID, nil
@@ -408,13 +467,27 @@ func (ma *Actor) GetLastUsedSectorID(ctx exec.VMContext) (uint64, uint8, error)
 
 // GetSectorCommitments returns all sector commitments posted by this miner.
 func (ma *Actor) GetSectorCommitments(ctx exec.VMContext) (map[string]types.Commitments, uint8, error) {
-	if err := ctx.Charge(100); err != nil {
-		return nil, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
-	}
-
 	var state State
 	out, err := actor.WithState(ctx, &state, func() (interface{}, error) {
-		return state.SectorCommitments, nil
+		commitments := map[string]types.Commitments{}
+		err := actor.WithTypedLookupForReading(context.Background(), ctx.Storage(), state.SectorCommitments, &types.Commitments{}, func(sectors exec.Lookup) error {
+			kvs, err := sectors.Values(context.Background())
+			if err != nil {
+				return err
+			}
+			for _, kv := range kvs {
+				comms, err := commitmentsFromValue(kv.Value)
+				if err != nil {
+					return err
+				}
+				commitments[kv.Key] = *comms
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, errors.FaultErrorWrap(err, "could not read sector commitments")
+		}
+		return commitments, nil
 	})
 	if err != nil {
 		return map[string]types.Commitments{}, errors.CodeError(err), err
@@ -431,54 +504,49 @@ func (ma *Actor) GetSectorCommitments(ctx exec.VMContext) (map[string]types.Comm
 // CommitSector adds a commitment to the specified sector. The sector must not
 // already be committed.
 func (ma *Actor) CommitSector(ctx exec.VMContext, sectorID uint64, commD, commR, commRStar, proof []byte) (uint8, error) {
-	if err := ctx.Charge(100); err != nil {
-		return exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
-	}
-	if len(commD) != int(proofs.CommitmentBytesLen) {
-		return 1, errors.NewRevertError("invalid sized commD")
+	return ma.commitSectors(ctx, []sectorCommitment{{sectorID, commD, commR, commRStar, proof}})
+}
+
+// CommitSectors adds the commitments of several sectors at once, which costs
+// less than a commitSector message per sector. For each sector, commitments
+// holds its commD, commR, commRStar and seal proof, concatenated in the order
+// of sectorIDs. None of the sectors must already be committed.
+func (ma *Actor) CommitSectors(ctx exec.VMContext, sectorIDs []uint64, commitments []byte) (uint8, error) {
+	if len(sectorIDs) == 0 {
+		return 1, errors.NewRevertError("no sectors to commit")
 	}
-	if len(commR) != int(proofs.CommitmentBytesLen) {
-		return 1, errors.NewRevertError("invalid sized commR")
+	if len(commitments) != len(sectorIDs)*sectorCommitmentLen {
+		return 1, errors.NewRevertError("invalid sized commitments")
 	}
-	if len(commRStar) != int(proofs.CommitmentBytesLen) {
-		return 1, errors.NewRevertError("invalid sized commRStar")
+
+	cs := make([]sectorCommitment, len(sectorIDs))
+	for i, sectorID := range sectorIDs {
+		c := commitments[i*sectorCommitmentLen : (i+1)*sectorCommitmentLen]
+		n := int(proofs.CommitmentBytesLen)
+		cs[i] = sectorCommitment{sectorID, c[:n], c[n : 2*n], c[2*n : 3*n], c[3*n:]}
 	}
+	return ma.commitSectors(ctx, cs)
+}
 
-	if !ma.Bootstrap {
-		// This unfortunate environment variable-checking needs to happen because
-		// the PoRep verification operation needs to know some things (e.g. size)
-		// about the sector for which the proof was generated in order to verify.
-		//
-		// It is undefined behavior for a miner in "Live" mode to verify a proof
-		// created by a miner in "ProofsTest" mode (and vice-versa).
-		//
-		sectorStoreType := proofs.Live
-		if os.Getenv("FIL_USE_SMALL_SECTORS") == "true" {
-			sectorStoreType = proofs.Test
-		}
+// sectorCommitmentLen is the length of the commitments of a sector passed to
+// CommitSectors.
+const sectorCommitmentLen = 3*int(proofs.CommitmentBytesLen) + int(proofs.SealBytesLen)
 
-		req := proofs.VerifySealRequest{}
-		copy(req.CommD[:], commD)
-		copy(req.CommR[:], commR)
-		copy(req.CommRStar[:], commRStar)
-		copy(req.Proof[:], proof)
-		req.ProverID = sectorbuilder.AddressToProverID(ctx.Message().To)
-		req.SectorID = sectorbuilder.SectorIDToBytes(sectorID)
-		req.StoreType = sectorStoreType
+type sectorCommitment struct {
+	sectorID                       uint64
+	commD, commR, commRStar, proof []byte
+}
 
-		res, err := (&proofs.RustVerifier{}).VerifySeal(req)
-		if err != nil {
-			return 1, errors.RevertErrorWrap(err, "failed to verify seal proof")
-		}
-		if !res.IsValid {
-			return ErrInvalidSealProof, Errors[ErrInvalidSealProof]
+func (ma *Actor) commitSectors(ctx exec.VMContext, cs []sectorCommitment) (uint8, error) {
+	if err := ctx.ChargeSealVerification(len(cs)); err != nil {
+		return exec.ErrInsufficientGas, err
+	}
+	for _, c := range cs {
+		if code, err := ma.verifySectorCommitment(ctx, c); err != nil {
+			return code, err
 		}
 	}
 
-	// TODO: use uint64 instead of this abomination, once refmt is fixed
-	// https://github.com/polydawn/refmt/issues/35
-	sectorIDstr := strconv.FormatUint(sectorID, 10)
-
 	var state State
 	_, err := actor.WithState(ctx, &state, func() (interface{}, error) {
 		// verify that the caller is authorized to perform update
@@ -486,26 +554,43 @@ func (ma *Actor) CommitSector(ctx exec.VMContext, sectorID uint64, commD, commR,
 			return nil, Errors[ErrCallerUnauthorized]
 		}
 
-		_, ok := state.SectorCommitments[sectorIDstr]
-		if ok {
-			return nil, Errors[ErrSectorCommitted]
+		sectors, err := actor.LoadTypedLookup(context.Background(), ctx.Storage(), state.SectorCommitments, &types.Commitments{})
+		if err != nil {
+			return nil, errors.FaultErrorWrap(err, "could not load sector commitments")
 		}
 
 		if state.Power.Cmp(big.NewInt(0)) == 0 {
 			state.ProvingPeriodStart = ctx.BlockHeight()
 		}
-		inc := big.NewInt(1)
+		for _, c := range cs {
+			sectorIDstr := strconv.FormatUint(c.sectorID, 10)
+
+			_, err = sectors.Find(context.Background(), sectorIDstr)
+			if err == nil {
+				return nil, Errors[ErrSectorCommitted]
+			} else if err != hamt.ErrNotFound {
+				return nil, errors.FaultErrorWrap(err, "could not read sector commitments")
+			}
+
+			comms := types.Commitments{
+				CommD:     proofs.CommD{},
+				CommR:     proofs.CommR{},
+				CommRStar: proofs.CommRStar{},
+			}
+			copy(comms.CommD[:], c.commD)
+			copy(comms.CommR[:], c.commR)
+			copy(comms.CommRStar[:], c.commRStar)
+			state.LastUsedSectorID = c.sectorID
+			if err := sectors.Set(context.Background(), sectorIDstr, comms); err != nil {
+				return nil, errors.FaultErrorWrap(err, "could not add sector commitments")
+			}
+		}
+		inc := big.NewInt(int64(len(cs)))
 		state.Power = state.Power.Add(state.Power, inc)
-		comms := types.Commitments{
-			CommD:     proofs.CommD{},
-			CommR:     proofs.CommR{},
-			CommRStar: proofs.CommRStar{},
+		state.SectorCommitments, err = sectors.Commit(context.Background())
+		if err != nil {
+			return nil, errors.FaultErrorWrap(err, "could not commit sector commitments")
 		}
-		copy(comms.CommD[:], commD)
-		copy(comms.CommR[:], commR)
-		copy(comms.CommRStar[:], commRStar)
-		state.LastUsedSectorID = sectorID
-		state.SectorCommitments[sectorIDstr] = comms
 		_, ret, err := ctx.Send(address.StorageMarketAddress, "updatePower", nil, []interface{}{inc})
 		if err != nil {
 			return nil, err
@@ -519,15 +604,65 @@ func (ma *Actor) CommitSector(ctx exec.VMContext, sectorID uint64, commD, commR,
 		return errors.CodeError(err), err
 	}
 
+	for _, c := range cs {
+		if err := ctx.EmitEvent(EventSectorCommitted, c.sectorID, c.commR); err != nil {
+			return errors.CodeError(err), err
+		}
+	}
+
 	return 0, nil
 }
 
-// GetKey returns the public key for this miner.
-func (ma *Actor) GetKey(ctx exec.VMContext) ([]byte, uint8, error) {
-	if err := ctx.Charge(100); err != nil {
-		return nil, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+// verifySectorCommitment checks the sizes of the commitments of a sector and,
+// unless the miner is a bootstrap miner, its seal proof.
+func (ma *Actor) verifySectorCommitment(ctx exec.VMContext, c sectorCommitment) (uint8, error) {
+	if len(c.commD) != int(proofs.CommitmentBytesLen) {
+		return 1, errors.NewRevertError("invalid sized commD")
+	}
+	if len(c.commR) != int(proofs.CommitmentBytesLen) {
+		return 1, errors.NewRevertError("invalid sized commR")
+	}
+	if len(c.commRStar) != int(proofs.CommitmentBytesLen) {
+		return 1, errors.NewRevertError("invalid sized commRStar")
+	}
+
+	if ma.Bootstrap {
+		return 0, nil
+	}
+
+	// This unfortunate environment variable-checking needs to happen because
+	// the PoRep verification operation needs to know some things (e.g. size)
+	// about the sector for which the proof was generated in order to verify.
+	//
+	// It is undefined behavior for a miner in "Live" mode to verify a proof
+	// created by a miner in "ProofsTest" mode (and vice-versa).
+	//
+	sectorStoreType := proofs.Live
+	if os.Getenv("FIL_USE_SMALL_SECTORS") == "true" {
+		sectorStoreType = proofs.Test
 	}
 
+	req := proofs.VerifySealRequest{}
+	copy(req.CommD[:], c.commD)
+	copy(req.CommR[:], c.commR)
+	copy(req.CommRStar[:], c.commRStar)
+	copy(req.Proof[:], c.proof)
+	req.ProverID = sectorbuilder.AddressToProverID(ctx.Message().To)
+	req.SectorID = sectorbuilder.SectorIDToBytes(c.sectorID)
+	req.StoreType = sectorStoreType
+
+	res, err := proofs.NewVerifier().VerifySeal(req)
+	if err != nil {
+		return 1, errors.RevertErrorWrap(err, "failed to verify seal proof")
+	}
+	if !res.IsValid {
+		return ErrInvalidSealProof, Errors[ErrInvalidSealProof]
+	}
+	return 0, nil
+}
+
+// GetKey returns the public key for this miner.
+func (ma *Actor) GetKey(ctx exec.VMContext) ([]byte, uint8, error) {
 	var state State
 	out, err := actor.WithState(ctx, &state, func() (interface{}, error) {
 		return state.PublicKey, nil
@@ -546,10 +681,6 @@ func (ma *Actor) GetKey(ctx exec.VMContext) ([]byte, uint8, error) {
 
 // GetPeerID returns the libp2p peer ID that this miner can be reached at.
 func (ma *Actor) GetPeerID(ctx exec.VMContext) (peer.ID, uint8, error) {
-	if err := ctx.Charge(100); err != nil {
-		return peer.ID(""), exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
-	}
-
 	var state State
 
 	chunk, err := ctx.ReadStorage()
@@ -566,10 +697,6 @@ func (ma *Actor) GetPeerID(ctx exec.VMContext) (peer.ID, uint8, error) {
 
 // UpdatePeerID is used to update the peerID this miner is operating under.
 func (ma *Actor) UpdatePeerID(ctx exec.VMContext, pid peer.ID) (uint8, error) {
-	if err := ctx.Charge(100); err != nil {
-		return exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
-	}
-
 	var storage State
 	_, err := actor.WithState(ctx, &storage, func() (interface{}, error) {
 		// verify that the caller is authorized to perform update
@@ -590,10 +717,6 @@ func (ma *Actor) UpdatePeerID(ctx exec.VMContext, pid peer.ID) (uint8, error) {
 
 // GetPledge returns the number of pledged sectors
 func (ma *Actor) GetPledge(ctx exec.VMContext) (*big.Int, uint8, error) {
-	if err := ctx.Charge(100); err != nil {
-		return nil, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
-	}
-
 	var state State
 	ret, err := actor.WithState(ctx, &state, func() (interface{}, error) {
 		return state.PledgeSectors, nil
@@ -612,10 +735,6 @@ func (ma *Actor) GetPledge(ctx exec.VMContext) (*big.Int, uint8, error) {
 
 // GetPower returns the amount of proven sectors for this miner.
 func (ma *Actor) GetPower(ctx exec.VMContext) (*big.Int, uint8, error) {
-	if err := ctx.Charge(100); err != nil {
-		return nil, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
-	}
-
 	var state State
 	ret, err := actor.WithState(ctx, &state, func() (interface{}, error) {
 		return state.Power, nil
@@ -635,10 +754,6 @@ func (ma *Actor) GetPower(ctx exec.VMContext) (*big.Int, uint8, error) {
 // SubmitPoSt is used to submit a coalesced PoST to the chain to convince the chain
 // that you have been actually storing the files you claim to be.
 func (ma *Actor) SubmitPoSt(ctx exec.VMContext, proof []byte) (uint8, error) {
-	if err := ctx.Charge(100); err != nil {
-		return exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
-	}
-
 	if len(proof) != PoStProofLength {
 		return 0, errors.NewRevertError("invalid sized proof")
 	}
@@ -650,10 +765,11 @@ func (ma *Actor) SubmitPoSt(ctx exec.VMContext, proof []byte) (uint8, error) {
 			return nil, Errors[ErrCallerUnauthorized]
 		}
 
-		// reach in to actor storage to grab comm-r for each committed sector
-		var commRs []proofs.CommR
-		for _, v := range state.SectorCommitments {
-			commRs = append(commRs, v.CommR)
+		// reach in to actor storage to grab comm-r for each committed sector,
+		// in order of sector id
+		commRs, err := sectorCommRs(ctx.Storage(), state.SectorCommitments)
+		if err != nil {
+			return nil, errors.FaultErrorWrap(err, "could not read sector commitments")
 		}
 
 		// copy message-bytes into PoStProof slice
@@ -668,7 +784,7 @@ func (ma *Actor) SubmitPoSt(ctx exec.VMContext, proof []byte) (uint8, error) {
 			Proof:         postProof,
 		}
 
-		res, err := (&proofs.RustVerifier{}).VerifyPoST(req)
+		res, err := proofs.NewVerifier().VerifyPoST(req)
 		if err != nil {
 			return nil, errors.RevertErrorWrap(err, "failed to verify PoSt")
 		}
@@ -694,15 +810,15 @@ func (ma *Actor) SubmitPoSt(ctx exec.VMContext, proof []byte) (uint8, error) {
 		return errors.CodeError(err), err
 	}
 
+	if err := ctx.EmitEvent(EventPoStSubmitted, state.ProvingPeriodStart); err != nil {
+		return errors.CodeError(err), err
+	}
+
 	return 0, nil
 }
 
 // GetProvingPeriodStart returns the current ProvingPeriodStart value.
 func (ma *Actor) GetProvingPeriodStart(ctx exec.VMContext) (*types.BlockHeight, uint8, error) {
-	if err := ctx.Charge(100); err != nil {
-		return nil, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
-	}
-
 	chunk, err := ctx.ReadStorage()
 	if err != nil {
 		return nil, errors.CodeError(err), err
@@ -715,3 +831,61 @@ func (ma *Actor) GetProvingPeriodStart(ctx exec.VMContext) (*types.BlockHeight,
 
 	return state.ProvingPeriodStart, 0, nil
 }
+
+// askFromValue returns the ask stored as value in the asks lookup.
+func askFromValue(value interface{}) (*Ask, error) {
+	ask, ok := value.(*Ask)
+	if !ok {
+		return nil, errors.NewFaultErrorf("expected an ask in lookup, but got %T instead", value)
+	}
+	return ask, nil
+}
+
+// commitmentsFromValue returns the commitments stored as value in the sector
+// commitments lookup.
+func commitmentsFromValue(value interface{}) (*types.Commitments, error) {
+	comms, ok := value.(*types.Commitments)
+	if !ok {
+		return nil, errors.NewFaultErrorf("expected commitments in lookup, but got %T instead", value)
+	}
+	return comms, nil
+}
+
+// sectorCommRs returns the commRs of the sectors in the sector commitments
+// lookup at sectors, ordered by sector id.
+func sectorCommRs(storage exec.Storage, sectors cid.Cid) ([]proofs.CommR, error) {
+	type sector struct {
+		id    uint64
+		commR proofs.CommR
+	}
+	var committed []sector
+
+	err := actor.WithTypedLookupForReading(context.Background(), storage, sectors, &types.Commitments{}, func(lookup exec.Lookup) error {
+		kvs, err := lookup.Values(context.Background())
+		if err != nil {
+			return err
+		}
+		for _, kv := range kvs {
+			id, err := strconv.ParseUint(kv.Key, 10, 64)
+			if err != nil {
+				return err
+			}
+			comms, err := commitmentsFromValue(kv.Value)
+			if err != nil {
+				return err
+			}
+			committed = append(committed, sector{id: id, commR: comms.CommR})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(committed, func(i, j int) bool { return committed[i].id < committed[j].id })
+	commRs := make([]proofs.CommR, len(committed))
+	for i, s := range committed {
+		commRs[i] = s.commR
+	}
+	return commRs, nil
+}
@@ -7,6 +7,7 @@ import (
 
 	peer "gx/ipfs/QmY5Grm8pJdiSSVsYxx4uNRgweY72EmYwuSDbRnbFok3iY/go-libp2p-peer"
 
+	"github.com/filecoin-project/go-filecoin/abi"
 	"github.com/filecoin-project/go-filecoin/actor"
 	"github.com/filecoin-project/go-filecoin/actor/builtin"
 	. "github.com/filecoin-project/go-filecoin/actor/builtin/miner"
@@ -82,7 +83,7 @@ func TestAskFunctions(t *testing.T) {
 
 	var minerStorage State
 	builtin.RequireReadState(t, vms, minerAddr, miner, &minerStorage)
-	assert.Equal(1, len(minerStorage.Asks))
+	assert.True(minerStorage.Asks.Defined())
 	assert.Equal(uint64(1), minerStorage.NextAskID.Uint64())
 
 	// Look for an ask that doesn't exist
@@ -117,7 +118,55 @@ func TestAskFunctions(t *testing.T) {
 
 	var askids []uint64
 	require.NoError(actor.UnmarshalStorage(result.Receipt.Return[0], &askids))
-	assert.Len(askids, 2)
+	assert.Equal([]uint64{0, 1}, askids)
+
+	// adding an ask drops the expired ones
+	pdata = actor.MustConvertParams(types.NewAttoFILFromFIL(7), big.NewInt(100))
+	msg = types.NewMessage(address.TestAddress, minerAddr, 6, nil, "addAsk", pdata)
+	_, err = th.ApplyTestMessage(st, vms, msg, types.NewBlockHeight(1600))
+	require.NoError(err)
+
+	msg = types.NewMessage(address.TestAddress, minerAddr, 7, types.NewZeroAttoFIL(), "getAsks", nil)
+	result, err = th.ApplyTestMessage(st, vms, msg, types.NewBlockHeight(1600))
+	require.NoError(err)
+	require.NoError(actor.UnmarshalStorage(result.Receipt.Return[0], &askids))
+	assert.Equal([]uint64{2}, askids)
+}
+
+func TestAddAskWithPieceSizes(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	st, vms := core.CreateStorages(ctx, t)
+
+	minerAddr := createTestMiner(assert, st, vms, address.TestAddress, []byte{}, th.RequireRandomPeerID())
+
+	pdata := actor.MustConvertParams(types.NewAttoFILFromFIL(5), big.NewInt(1500), types.NewBytesAmount(1024), types.NewBytesAmount(0))
+	msg := types.NewMessage(address.TestAddress, minerAddr, 1, nil, "addAskWithPieceSizes", pdata)
+	result, err := th.ApplyTestMessage(st, vms, msg, types.NewBlockHeight(1))
+	require.NoError(err)
+	require.NoError(result.ExecutionError)
+
+	pdata = actor.MustConvertParams(big.NewInt(0))
+	msg = types.NewMessage(address.TestAddress, minerAddr, 2, types.NewZeroAttoFIL(), "getAsk", pdata)
+	result, err = th.ApplyTestMessage(st, vms, msg, types.NewBlockHeight(2))
+	require.NoError(err)
+
+	var ask Ask
+	require.NoError(actor.UnmarshalStorage(result.Receipt.Return[0], &ask))
+	assert.Equal(types.NewBytesAmount(1024), ask.MinPieceSize)
+	assert.Nil(ask.MaxPieceSize)
+
+	// the max piece size can't be less than the min piece size
+	pdata = actor.MustConvertParams(types.NewAttoFILFromFIL(5), big.NewInt(1500), types.NewBytesAmount(1024), types.NewBytesAmount(512))
+	msg = types.NewMessage(address.TestAddress, minerAddr, 3, nil, "addAskWithPieceSizes", pdata)
+	result, err = th.ApplyTestMessage(st, vms, msg, types.NewBlockHeight(3))
+	require.NoError(err)
+	assert.Error(result.ExecutionError)
 }
 
 func TestGetKey(t *testing.T) {
@@ -139,12 +188,6 @@ func TestCBOREncodeState(t *testing.T) {
 	assert := assert.New(t)
 	state := NewState(address.TestAddress, []byte{}, big.NewInt(1), th.RequireRandomPeerID(), types.NewZeroAttoFIL())
 
-	state.SectorCommitments["1"] = types.Commitments{
-		CommD:     proofs.CommD{},
-		CommR:     proofs.CommR{},
-		CommRStar: proofs.CommRStar{},
-	}
-
 	_, err := actor.MarshalStorage(state)
 	assert.NoError(err)
 
@@ -298,6 +341,18 @@ func TestMinerCommitSector(t *testing.T) {
 	// blockheight was 3
 	require.Equal(types.NewBlockHeight(3), types.NewBlockHeightFromBytes(res.Receipt.Return[0]))
 
+	// check that the commitments are stored under the sector id
+	res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 3, "getSectorCommitments")
+	require.NoError(err)
+	require.NoError(res.ExecutionError)
+	commitmentsVal, err := abi.Deserialize(res.Receipt.Return[0], abi.CommitmentsMap)
+	require.NoError(err)
+	commitments, ok := commitmentsVal.Val.(map[string]types.Commitments)
+	require.True(ok)
+	require.Len(commitments, 1)
+	stored := commitments["1"]
+	require.Equal(commR, stored.CommR[:])
+
 	// fail because commR already exists
 	res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 4, "commitSector", uint64(1), commD, commR, commRStar, th.MakeRandomBytes(int(proofs.SealBytesLen)))
 	require.NoError(err)
@@ -305,6 +360,56 @@ func TestMinerCommitSector(t *testing.T) {
 	require.Equal(uint8(0x23), res.Receipt.ExitCode)
 }
 
+func TestMinerCommitSectors(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	st, vms := core.CreateStorages(ctx, t)
+
+	minerAddr := createTestMiner(assert.New(t), st, vms, address.TestAddress, []byte("my public key"), th.RequireRandomPeerID())
+
+	commRs := [][]byte{th.MakeCommitment(), th.MakeCommitment()}
+	var commitments []byte
+	for _, commR := range commRs {
+		commitments = append(commitments, th.MakeCommitment()...)
+		commitments = append(commitments, commR...)
+		commitments = append(commitments, th.MakeCommitment()...)
+		commitments = append(commitments, th.MakeRandomBytes(int(proofs.SealBytesLen))...)
+	}
+
+	res, err := th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 3, "commitSectors", []uint64{1, 2}, commitments)
+	require.NoError(err)
+	require.NoError(res.ExecutionError)
+	require.Equal(uint8(0), res.Receipt.ExitCode)
+
+	// check that the commitments are stored under their sector ids
+	res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 3, "getSectorCommitments")
+	require.NoError(err)
+	require.NoError(res.ExecutionError)
+	commitmentsVal, err := abi.Deserialize(res.Receipt.Return[0], abi.CommitmentsMap)
+	require.NoError(err)
+	committed, ok := commitmentsVal.Val.(map[string]types.Commitments)
+	require.True(ok)
+	require.Len(committed, 2)
+	first, second := committed["1"], committed["2"]
+	require.Equal(commRs[0], first.CommR[:])
+	require.Equal(commRs[1], second.CommR[:])
+
+	// the power counts both sectors
+	result := callQueryMethodSuccess("getPower", ctx, t, st, vms, address.TestAddress, minerAddr)
+	require.Equal(big.NewInt(2), big.NewInt(0).SetBytes(result[0]))
+
+	// fail because the commitments do not match the sectors
+	res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 4, "commitSectors", []uint64{3, 4, 5}, commitments)
+	require.NoError(err)
+	require.EqualError(res.ExecutionError, "invalid sized commitments")
+
+	// fail because a sector is already committed
+	res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 4, "commitSectors", []uint64{2, 3}, commitments)
+	require.NoError(err)
+	require.EqualError(res.ExecutionError, "sector already committed")
+	require.Equal(uint8(0x23), res.Receipt.ExitCode)
+}
+
 func TestMinerSubmitPoSt(t *testing.T) {
 	require := require.New(t)
 	ctx := context.Background()
@@ -0,0 +1,124 @@
+// Code generated by actorgen. DO NOT EDIT.
+
+package multisig
+
+import (
+	"math/big"
+
+	"github.com/filecoin-project/go-filecoin/abi"
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/vm/errors"
+)
+
+var _ exec.Dispatcher = (*Actor)(nil)
+
+// Method returns the exported method with the given name, bound to a.
+func (a *Actor) Method(name string) (exec.ExportedFunc, bool) {
+	switch name {
+	case "approve":
+		return a.dispatchApprove, true
+	case "cancel":
+		return a.dispatchCancel, true
+	case "getPending":
+		return a.dispatchGetPending, true
+	case "getSigners":
+		return a.dispatchGetSigners, true
+	case "propose":
+		return a.dispatchPropose, true
+	default:
+		return nil, false
+	}
+}
+
+func (a *Actor) dispatchApprove(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, multisigExports["approve"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	code, err := a.Approve(ctx, params[0].Val.(*big.Int))
+	if err != nil {
+		return nil, code, actor.CheckExportError(a, "approve", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues()
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *Actor) dispatchCancel(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, multisigExports["cancel"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	code, err := a.Cancel(ctx, params[0].Val.(*big.Int))
+	if err != nil {
+		return nil, code, actor.CheckExportError(a, "cancel", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues()
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *Actor) dispatchGetPending(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, multisigExports["getPending"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	r0, code, err := a.GetPending(ctx)
+	if err != nil {
+		return nil, code, actor.CheckExportError(a, "getPending", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues(r0)
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *Actor) dispatchGetSigners(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, multisigExports["getSigners"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	r0, r1, code, err := a.GetSigners(ctx)
+	if err != nil {
+		return nil, code, actor.CheckExportError(a, "getSigners", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues(r0, r1)
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *Actor) dispatchPropose(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, multisigExports["propose"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	r0, code, err := a.Propose(ctx, params[0].Val.(address.Address), params[1].Val.(*types.AttoFIL))
+	if err != nil {
+		return nil, code, actor.CheckExportError(a, "propose", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues(r0)
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
@@ -0,0 +1,396 @@
+package multisig
+
+import (
+	"context"
+	"math/big"
+	"strconv"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	"gx/ipfs/QmRXf2uUSdGSunRJsM9wXSUNVwLUGCY3So5fAs7h2CBJVf/go-hamt-ipld"
+	cbor "gx/ipfs/QmRoARq3nkUb13HSKZGepCZSWe5GrVPwx7xURJGZ7KWv9V/go-ipld-cbor"
+	xerrors "gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+
+	"github.com/filecoin-project/go-filecoin/abi"
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/vm/errors"
+)
+
+func init() {
+	cbor.RegisterCborType(State{})
+	cbor.RegisterCborType(Transaction{})
+}
+
+const (
+	// ErrInvalidSigners indicates the signers or the number of required
+	// approvals of a new multisig wallet are invalid.
+	ErrInvalidSigners = 33
+	// ErrNotSigner indicates the caller is not one of the wallet's signers.
+	ErrNotSigner = 34
+	// ErrUnknownTransaction indicates an invalid transaction id.
+	ErrUnknownTransaction = 35
+	// ErrNotProposer indicates an attempt to cancel somebody else's transaction.
+	ErrNotProposer = 36
+)
+
+// Errors map error codes to revert errors this actor may return.
+var Errors = map[uint8]error{
+	ErrInvalidSigners:     errors.NewCodedRevertErrorf(ErrInvalidSigners, "required approvals must be between 1 and the number of distinct signers"),
+	ErrNotSigner:          errors.NewCodedRevertErrorf(ErrNotSigner, "caller is not a signer of this wallet"),
+	ErrUnknownTransaction: errors.NewCodedRevertErrorf(ErrUnknownTransaction, "unknown transaction"),
+	ErrNotProposer:        errors.NewCodedRevertErrorf(ErrNotProposer, "only the proposer may cancel a transaction"),
+}
+
+// Actor is a wallet whose funds can only be spent with the approval of M out
+// of its N signers. Any signer may propose sending funds, which counts as
+// their approval. Once enough signers have approved, and the wallet's
+// optional timelock has passed since the proposal, the funds are sent.
+type Actor struct{}
+
+// Transaction is a send proposed by one of the signers.
+type Transaction struct {
+	ID    uint64          `json:"id"`
+	To    address.Address `json:"to"`
+	Value *types.AttoFIL  `json:"value"`
+
+	Proposer   address.Address    `json:"proposer"`
+	ProposedAt *types.BlockHeight `json:"proposedAt"`
+	Approvals  []address.Address  `json:"approvals"`
+}
+
+// State is the multisig actor's storage.
+type State struct {
+	Signers  []address.Address
+	Required uint64
+
+	// UnlockDuration is the number of blocks that must pass after a
+	// transaction was proposed before it may be executed.
+	UnlockDuration *types.BlockHeight
+
+	NextTxID uint64
+
+	// Pending is the cid of the lookup of the transactions that have not
+	// executed yet, keyed by their stringified ids.
+	Pending cid.Cid `refmt:",omitempty"`
+}
+
+// NewState validates the given wallet parameters and returns the initial
+// state of a multisig actor.
+func NewState(signers []address.Address, required uint64, unlockDuration *types.BlockHeight) (*State, error) {
+	distinct := make(map[address.Address]struct{})
+	for _, s := range signers {
+		distinct[s] = struct{}{}
+	}
+	if required == 0 || required > uint64(len(distinct)) || len(distinct) != len(signers) {
+		return nil, Errors[ErrInvalidSigners]
+	}
+	if unlockDuration == nil {
+		unlockDuration = types.NewBlockHeight(0)
+	}
+
+	return &State{
+		Signers:        signers,
+		Required:       required,
+		UnlockDuration: unlockDuration,
+	}, nil
+}
+
+// NewActor returns a new multisig actor.
+func NewActor() *actor.Actor {
+	return actor.NewActor(types.MultisigActorCodeCid, types.NewZeroAttoFIL())
+}
+
+// InitializeState stores the actor's initial data structure.
+func (msa *Actor) InitializeState(storage exec.Storage, initializerData interface{}) error {
+	msState, ok := initializerData.(*State)
+	if !ok {
+		return errors.NewFaultError("Initial state to multisig actor is not a multisig.State struct")
+	}
+
+	stateBytes, err := cbor.DumpObject(msState)
+	if err != nil {
+		return xerrors.Wrap(err, "failed to cbor marshal object")
+	}
+
+	id, err := storage.Put(stateBytes)
+	if err != nil {
+		return err
+	}
+
+	return storage.Commit(id, cid.Undef)
+}
+
+var _ exec.ExecutableActor = (*Actor)(nil)
+
+// Exports returns the actor's exports.
+func (msa *Actor) Exports() exec.Exports {
+	return multisigExports
+}
+
+//go:generate go run ../../../tools/actorgen/main.go -type Actor -exports multisigExports
+
+var multisigExports = exec.Exports{
+	"propose": &exec.FunctionSignature{
+		Params: []abi.Type{abi.Address, abi.AttoFIL},
+		Return: []abi.Type{abi.Integer},
+	},
+	"approve": &exec.FunctionSignature{
+		Params: []abi.Type{abi.Integer},
+		Return: nil,
+	},
+	"cancel": &exec.FunctionSignature{
+		Params: []abi.Type{abi.Integer},
+		Return: nil,
+	},
+	"getSigners": &exec.FunctionSignature{
+		Params: []abi.Type{},
+		Return: []abi.Type{abi.Addresses, abi.Integer},
+	},
+	"getPending": &exec.FunctionSignature{
+		Params: []abi.Type{},
+		Return: []abi.Type{abi.Bytes},
+	},
+}
+
+// Propose proposes sending value to the given address. The caller must be a
+// signer and its proposal counts as its approval, so in a 1-of-N wallet
+// without a timelock the funds are sent right away. Returns the id of the
+// transaction.
+func (msa *Actor) Propose(vmctx exec.VMContext, to address.Address, value *types.AttoFIL) (*big.Int, uint8, error) {
+	var state State
+	ret, err := actor.WithState(vmctx, &state, func() (interface{}, error) {
+		caller := vmctx.Message().From
+		if !isSigner(&state, caller) {
+			return nil, Errors[ErrNotSigner]
+		}
+
+		tx := &Transaction{
+			ID:         state.NextTxID,
+			To:         to,
+			Value:      value,
+			Proposer:   caller,
+			ProposedAt: vmctx.BlockHeight(),
+			Approvals:  []address.Address{caller},
+		}
+		state.NextTxID++
+		if err := setPending(vmctx, &state, tx); err != nil {
+			return nil, err
+		}
+
+		if err := maybeExecute(vmctx, &state, tx); err != nil {
+			return nil, err
+		}
+		return big.NewInt(int64(tx.ID)), nil
+	})
+	if err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	return ret.(*big.Int), 0, nil
+}
+
+// Approve adds the caller's approval to a pending transaction and sends its
+// funds once it has enough approvals and its timelock has passed. Approving
+// a transaction twice does not count twice, but is how signers execute a
+// fully approved transaction after its timelock expires.
+func (msa *Actor) Approve(vmctx exec.VMContext, txID *big.Int) (uint8, error) {
+	var state State
+	_, err := actor.WithState(vmctx, &state, func() (interface{}, error) {
+		caller := vmctx.Message().From
+		if !isSigner(&state, caller) {
+			return nil, Errors[ErrNotSigner]
+		}
+
+		tx, err := getPending(vmctx, &state, txID.Uint64())
+		if err != nil {
+			return nil, err
+		}
+
+		if !hasApproved(tx, caller) {
+			tx.Approvals = append(tx.Approvals, caller)
+			if err := setPending(vmctx, &state, tx); err != nil {
+				return nil, err
+			}
+		}
+
+		return nil, maybeExecute(vmctx, &state, tx)
+	})
+	if err != nil {
+		return errors.CodeError(err), err
+	}
+
+	return 0, nil
+}
+
+// Cancel removes a pending transaction. Only its proposer may cancel it.
+func (msa *Actor) Cancel(vmctx exec.VMContext, txID *big.Int) (uint8, error) {
+	var state State
+	_, err := actor.WithState(vmctx, &state, func() (interface{}, error) {
+		tx, err := getPending(vmctx, &state, txID.Uint64())
+		if err != nil {
+			return nil, err
+		}
+		if tx.Proposer != vmctx.Message().From {
+			return nil, Errors[ErrNotProposer]
+		}
+
+		return nil, deletePending(vmctx, &state, tx.ID)
+	})
+	if err != nil {
+		return errors.CodeError(err), err
+	}
+
+	return 0, nil
+}
+
+// GetSigners returns the wallet's signers and the number of approvals
+// required to send funds.
+func (msa *Actor) GetSigners(vmctx exec.VMContext) ([]address.Address, *big.Int, uint8, error) {
+	chunk, err := vmctx.ReadStorage()
+	if err != nil {
+		return nil, nil, errors.CodeError(err), err
+	}
+
+	var state State
+	if err := actor.UnmarshalStorage(chunk, &state); err != nil {
+		return nil, nil, errors.CodeError(err), err
+	}
+
+	return state.Signers, big.NewInt(int64(state.Required)), 0, nil
+}
+
+// GetPending returns the cbor encoded map of pending transactions, keyed by
+// their stringified ids.
+func (msa *Actor) GetPending(vmctx exec.VMContext) ([]byte, uint8, error) {
+	chunk, err := vmctx.ReadStorage()
+	if err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	var state State
+	if err := actor.UnmarshalStorage(chunk, &state); err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	txs := make(map[string]*Transaction)
+	err = actor.WithTypedLookupForReading(context.Background(), vmctx.Storage(), state.Pending, &Transaction{}, func(pending exec.Lookup) error {
+		kvs, err := pending.Values(context.Background())
+		if err != nil {
+			return err
+		}
+		for _, kv := range kvs {
+			tx, err := txFromValue(kv.Value)
+			if err != nil {
+				return err
+			}
+			txs[kv.Key] = tx
+		}
+		return nil
+	})
+	if err != nil {
+		err = errors.FaultErrorWrap(err, "could not read pending transactions")
+		return nil, errors.CodeError(err), err
+	}
+
+	pending, err := cbor.DumpObject(txs)
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal pending transactions")
+	}
+
+	return pending, 0, nil
+}
+
+// maybeExecute sends the funds of tx and removes it from the pending
+// transactions if it has enough approvals and its timelock has passed.
+func maybeExecute(vmctx exec.VMContext, state *State, tx *Transaction) error {
+	if uint64(len(tx.Approvals)) < state.Required {
+		return nil
+	}
+	if vmctx.BlockHeight().LessThan(tx.ProposedAt.Add(state.UnlockDuration)) {
+		return nil
+	}
+
+	if _, _, err := vmctx.Send(tx.To, "", tx.Value, nil); err != nil {
+		return err
+	}
+
+	return deletePending(vmctx, state, tx.ID)
+}
+
+// getPending returns the pending transaction with the given id.
+func getPending(vmctx exec.VMContext, state *State, id uint64) (*Transaction, error) {
+	var tx *Transaction
+	err := actor.WithTypedLookupForReading(context.Background(), vmctx.Storage(), state.Pending, &Transaction{}, func(pending exec.Lookup) error {
+		value, err := pending.Find(context.Background(), txKey(id))
+		if err != nil {
+			return err
+		}
+		tx, err = txFromValue(value)
+		return err
+	})
+	if err == hamt.ErrNotFound {
+		return nil, Errors[ErrUnknownTransaction]
+	} else if err != nil {
+		return nil, errors.FaultErrorWrap(err, "could not read pending transaction")
+	}
+	return tx, nil
+}
+
+// setPending adds or updates tx in the pending transactions.
+func setPending(vmctx exec.VMContext, state *State, tx *Transaction) error {
+	pendingCid, err := actor.WithTypedLookup(context.Background(), vmctx.Storage(), state.Pending, &Transaction{}, func(pending exec.Lookup) error {
+		return pending.Set(context.Background(), txKey(tx.ID), tx)
+	})
+	if err != nil {
+		return errors.FaultErrorWrap(err, "could not update pending transactions")
+	}
+	state.Pending = pendingCid
+	return nil
+}
+
+// deletePending removes the transaction with the given id from the pending
+// transactions.
+func deletePending(vmctx exec.VMContext, state *State, id uint64) error {
+	pendingCid, err := actor.WithTypedLookup(context.Background(), vmctx.Storage(), state.Pending, &Transaction{}, func(pending exec.Lookup) error {
+		return pending.Delete(context.Background(), txKey(id))
+	})
+	if err != nil {
+		return errors.FaultErrorWrap(err, "could not update pending transactions")
+	}
+	state.Pending = pendingCid
+	return nil
+}
+
+// txFromValue returns the transaction stored as value in the pending
+// transactions lookup.
+func txFromValue(value interface{}) (*Transaction, error) {
+	tx, ok := value.(*Transaction)
+	if !ok {
+		return nil, errors.NewFaultErrorf("expected a transaction in lookup, but got %T instead", value)
+	}
+	return tx, nil
+}
+
+func isSigner(state *State, addr address.Address) bool {
+	for _, s := range state.Signers {
+		if s == addr {
+			return true
+		}
+	}
+	return false
+}
+
+func hasApproved(tx *Transaction, addr address.Address) bool {
+	for _, a := range tx.Approvals {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
+
+func txKey(id uint64) string {
+	return strconv.FormatUint(id, 10)
+}
@@ -0,0 +1,158 @@
+package multisig_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/actor"
+	. "github.com/filecoin-project/go-filecoin/actor/builtin/multisig"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/core"
+	"github.com/filecoin-project/go-filecoin/state"
+	th "github.com/filecoin-project/go-filecoin/testhelpers"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/vm"
+)
+
+var signers = []address.Address{address.TestAddress, address.TestAddress2}
+
+func createTestMultisig(require *require.Assertions, st state.Tree, vms vm.StorageMap, required int64, unlock uint64) address.Address {
+	pdata := actor.MustConvertParams(signers, big.NewInt(required), types.NewBlockHeight(unlock))
+	nonce := core.MustGetNonce(st, address.TestAddress)
+	msg := types.NewMessage(address.TestAddress, address.StorageMarketAddress, nonce, types.NewAttoFILFromFIL(100), "createMultisig", pdata)
+
+	result, err := th.ApplyTestMessage(st, vms, msg, types.NewBlockHeight(0))
+	require.NoError(err)
+	require.NoError(result.ExecutionError)
+
+	addr, err := address.NewFromBytes(result.Receipt.Return[0])
+	require.NoError(err)
+	return addr
+}
+
+func applyMultisigMessage(require *require.Assertions, st state.Tree, vms vm.StorageMap, from, wallet address.Address, height uint64, method string, params ...interface{}) *types.MessageReceipt {
+	nonce := core.MustGetNonce(st, from)
+	msg := types.NewMessage(from, wallet, nonce, types.NewZeroAttoFIL(), method, actor.MustConvertParams(params...))
+
+	result, err := th.ApplyTestMessage(st, vms, msg, types.NewBlockHeight(height))
+	require.NoError(err)
+	return result.Receipt
+}
+
+func requireBalance(ctx context.Context, require *require.Assertions, st state.Tree, addr address.Address) *types.AttoFIL {
+	act, err := st.GetActor(ctx, addr)
+	if state.IsActorNotFoundError(err) {
+		return types.NewZeroAttoFIL()
+	}
+	require.NoError(err)
+	return act.Balance
+}
+
+func TestMultisigApprovals(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ctx := context.Background()
+	st, vms := core.CreateStorages(ctx, t)
+
+	wallet := createTestMultisig(require, st, vms, 2, 0)
+	assert.Equal(types.NewAttoFILFromFIL(100), requireBalance(ctx, require, st, wallet))
+
+	recipient := address.NewForTestGetter()()
+
+	t.Log("a proposal alone does not send funds")
+	receipt := applyMultisigMessage(require, st, vms, address.TestAddress, wallet, 1, "propose", recipient, types.NewAttoFILFromFIL(10))
+	require.Equal(uint8(0), receipt.ExitCode)
+	txID := big.NewInt(0).SetBytes(receipt.Return[0])
+	assert.True(requireBalance(ctx, require, st, recipient).IsZero())
+
+	receipt = applyMultisigMessage(require, st, vms, address.TestAddress, wallet, 1, "getPending")
+	var pending map[string]*Transaction
+	require.NoError(actor.UnmarshalStorage(receipt.Return[0], &pending))
+	require.Len(pending, 1)
+	assert.Equal(recipient, pending["0"].To)
+	assert.Equal([]address.Address{address.TestAddress}, pending["0"].Approvals)
+
+	t.Log("non signers may not approve")
+	receipt = applyMultisigMessage(require, st, vms, address.NetworkAddress, wallet, 2, "approve", txID)
+	assert.Equal(uint8(ErrNotSigner), receipt.ExitCode)
+
+	t.Log("the second approval sends the funds")
+	receipt = applyMultisigMessage(require, st, vms, address.TestAddress2, wallet, 2, "approve", txID)
+	require.Equal(uint8(0), receipt.ExitCode)
+	assert.Equal(types.NewAttoFILFromFIL(10), requireBalance(ctx, require, st, recipient))
+	assert.Equal(types.NewAttoFILFromFIL(90), requireBalance(ctx, require, st, wallet))
+
+	receipt = applyMultisigMessage(require, st, vms, address.TestAddress, wallet, 2, "approve", txID)
+	assert.Equal(uint8(ErrUnknownTransaction), receipt.ExitCode)
+}
+
+func TestMultisigTimelock(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ctx := context.Background()
+	st, vms := core.CreateStorages(ctx, t)
+
+	wallet := createTestMultisig(require, st, vms, 1, 5)
+	recipient := address.NewForTestGetter()()
+
+	receipt := applyMultisigMessage(require, st, vms, address.TestAddress, wallet, 1, "propose", recipient, types.NewAttoFILFromFIL(10))
+	require.Equal(uint8(0), receipt.ExitCode)
+	txID := big.NewInt(0).SetBytes(receipt.Return[0])
+
+	receipt = applyMultisigMessage(require, st, vms, address.TestAddress2, wallet, 3, "approve", txID)
+	require.Equal(uint8(0), receipt.ExitCode)
+	assert.True(requireBalance(ctx, require, st, recipient).IsZero())
+
+	receipt = applyMultisigMessage(require, st, vms, address.TestAddress2, wallet, 6, "approve", txID)
+	require.Equal(uint8(0), receipt.ExitCode)
+	assert.Equal(types.NewAttoFILFromFIL(10), requireBalance(ctx, require, st, recipient))
+}
+
+func TestMultisigCancel(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ctx := context.Background()
+	st, vms := core.CreateStorages(ctx, t)
+
+	wallet := createTestMultisig(require, st, vms, 2, 0)
+	recipient := address.NewForTestGetter()()
+
+	receipt := applyMultisigMessage(require, st, vms, address.TestAddress, wallet, 1, "propose", recipient, types.NewAttoFILFromFIL(10))
+	require.Equal(uint8(0), receipt.ExitCode)
+	txID := big.NewInt(0).SetBytes(receipt.Return[0])
+
+	receipt = applyMultisigMessage(require, st, vms, address.TestAddress2, wallet, 2, "cancel", txID)
+	assert.Equal(uint8(ErrNotProposer), receipt.ExitCode)
+
+	receipt = applyMultisigMessage(require, st, vms, address.TestAddress, wallet, 2, "cancel", txID)
+	require.Equal(uint8(0), receipt.ExitCode)
+
+	receipt = applyMultisigMessage(require, st, vms, address.TestAddress2, wallet, 3, "approve", txID)
+	assert.Equal(uint8(ErrUnknownTransaction), receipt.ExitCode)
+	assert.True(requireBalance(ctx, require, st, recipient).IsZero())
+}
+
+func TestNewState(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := NewState(signers, 0, nil)
+	assert.Error(err)
+	_, err = NewState(signers, 3, nil)
+	assert.Error(err)
+	_, err = NewState([]address.Address{address.TestAddress, address.TestAddress}, 2, nil)
+	assert.Error(err)
+
+	st, err := NewState(signers, 2, nil)
+	assert.NoError(err)
+	assert.Equal(types.NewBlockHeight(0), st.UnlockDuration)
+}
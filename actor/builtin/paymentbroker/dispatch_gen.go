@@ -0,0 +1,162 @@
+// Code generated by actorgen. DO NOT EDIT.
+
+package paymentbroker
+
+import (
+	"github.com/filecoin-project/go-filecoin/abi"
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/vm/errors"
+)
+
+var _ exec.Dispatcher = (*Actor)(nil)
+
+// Method returns the exported method with the given name, bound to a.
+func (a *Actor) Method(name string) (exec.ExportedFunc, bool) {
+	switch name {
+	case "close":
+		return a.dispatchClose, true
+	case "createChannel":
+		return a.dispatchCreateChannel, true
+	case "extend":
+		return a.dispatchExtend, true
+	case "ls":
+		return a.dispatchLs, true
+	case "reclaim":
+		return a.dispatchReclaim, true
+	case "redeem":
+		return a.dispatchRedeem, true
+	case "voucher":
+		return a.dispatchVoucher, true
+	default:
+		return nil, false
+	}
+}
+
+func (a *Actor) dispatchClose(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, paymentBrokerExports["close"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	code, err := a.Close(ctx, params[0].Val.(address.Address), params[1].Val.(*types.ChannelID), params[2].Val.(*types.AttoFIL), params[3].Val.(*types.BlockHeight), params[4].Val.([]byte))
+	if err != nil {
+		return nil, code, actor.CheckExportError(a, "close", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues()
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *Actor) dispatchCreateChannel(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, paymentBrokerExports["createChannel"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	r0, code, err := a.CreateChannel(ctx, params[0].Val.(address.Address), params[1].Val.(*types.BlockHeight))
+	if err != nil {
+		return nil, code, actor.CheckExportError(a, "createChannel", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues(r0)
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *Actor) dispatchExtend(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, paymentBrokerExports["extend"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	code, err := a.Extend(ctx, params[0].Val.(*types.ChannelID), params[1].Val.(*types.BlockHeight))
+	if err != nil {
+		return nil, code, actor.CheckExportError(a, "extend", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues()
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *Actor) dispatchLs(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, paymentBrokerExports["ls"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	r0, code, err := a.Ls(ctx, params[0].Val.(address.Address))
+	if err != nil {
+		return nil, code, actor.CheckExportError(a, "ls", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues(r0)
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *Actor) dispatchReclaim(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, paymentBrokerExports["reclaim"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	code, err := a.Reclaim(ctx, params[0].Val.(*types.ChannelID))
+	if err != nil {
+		return nil, code, actor.CheckExportError(a, "reclaim", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues()
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *Actor) dispatchRedeem(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, paymentBrokerExports["redeem"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	code, err := a.Redeem(ctx, params[0].Val.(address.Address), params[1].Val.(*types.ChannelID), params[2].Val.(*types.AttoFIL), params[3].Val.(*types.BlockHeight), params[4].Val.([]byte))
+	if err != nil {
+		return nil, code, actor.CheckExportError(a, "redeem", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues()
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *Actor) dispatchVoucher(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, paymentBrokerExports["voucher"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	r0, code, err := a.Voucher(ctx, params[0].Val.(*types.ChannelID), params[1].Val.(*types.AttoFIL), params[2].Val.(*types.BlockHeight))
+	if err != nil {
+		return nil, code, actor.CheckExportError(a, "voucher", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues(r0)
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
@@ -40,6 +40,20 @@ const (
 	ErrTooEarly = 43
 )
 
+// Types of the events this actor emits, and the values they carry.
+const (
+	// EventChannelCreated carries the payer, target, channel id, amount and eol of a new channel.
+	EventChannelCreated = "channelCreated"
+	// EventChannelRedeemed carries the payer, channel id and total amount redeemed of a channel.
+	EventChannelRedeemed = "channelRedeemed"
+	// EventChannelClosed carries the payer, channel id and total amount redeemed of a closed channel.
+	EventChannelClosed = "channelClosed"
+	// EventChannelExtended carries the payer, channel id, new amount and new eol of a channel.
+	EventChannelExtended = "channelExtended"
+	// EventChannelReclaimed carries the payer and channel id of a reclaimed channel.
+	EventChannelReclaimed = "channelReclaimed"
+)
+
 // Errors map error codes to revert errors this actor may return.
 var Errors = map[uint8]error{
 	ErrTooEarly:                 errors.NewCodedRevertError(ErrTooEarly, "block height too low to redeem voucher"),
@@ -86,6 +100,8 @@ func (pb *Actor) Exports() exec.Exports {
 
 var _ exec.ExecutableActor = (*Actor)(nil)
 
+//go:generate go run ../../../tools/actorgen/main.go -type Actor -exports paymentBrokerExports
+
 var paymentBrokerExports = exec.Exports{
 	"close": &exec.FunctionSignature{
 		Params: []abi.Type{abi.Address, abi.ChannelID, abi.AttoFIL, abi.BlockHeight, abi.Bytes},
@@ -121,10 +137,6 @@ var paymentBrokerExports = exec.Exports{
 // The value attached to the invocation is used as the deposit, and the channel
 // will expire and return all of its money to the owner after the given block height.
 func (pb *Actor) CreateChannel(vmctx exec.VMContext, target address.Address, eol *types.BlockHeight) (*types.ChannelID, uint8, error) {
-	if err := vmctx.Charge(100); err != nil {
-		return nil, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
-	}
-
 	// require that from account be an account actor to ensure nonce is a valid id
 	if !vmctx.IsFromAccountActor() {
 		return nil, errors.CodeError(Errors[ErrNonAccountActor]), Errors[ErrNonAccountActor]
@@ -167,6 +179,11 @@ func (pb *Actor) CreateChannel(vmctx exec.VMContext, target address.Address, eol
 		return nil, errors.CodeError(err), err
 	}
 
+	err = vmctx.EmitEvent(EventChannelCreated, payerAddress, target, channelID, vmctx.Message().Value, eol)
+	if err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
 	return channelID, 0, nil
 }
 
@@ -183,10 +200,6 @@ func (pb *Actor) CreateChannel(vmctx exec.VMContext, target address.Address, eol
 // target Close(500)           -> Payer: 1500, Target: 500, Channel: 0
 //
 func (pb *Actor) Redeem(vmctx exec.VMContext, payer address.Address, chid *types.ChannelID, amt *types.AttoFIL, validAt *types.BlockHeight, sig []byte) (uint8, error) {
-	if err := vmctx.Charge(100); err != nil {
-		return exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
-	}
-
 	if !VerifyVoucherSignature(payer, chid, amt, validAt, sig) {
 		return errors.CodeError(Errors[ErrInvalidSignature]), Errors[ErrInvalidSignature]
 	}
@@ -227,16 +240,17 @@ func (pb *Actor) Redeem(vmctx exec.VMContext, payer address.Address, chid *types
 		return errors.CodeError(err), err
 	}
 
+	err = vmctx.EmitEvent(EventChannelRedeemed, payer, chid, amt)
+	if err != nil {
+		return errors.CodeError(err), err
+	}
+
 	return 0, nil
 }
 
 // Close first executes the logic performed in the the Update method, then returns all
 // funds remaining in the channel to the payer account and deletes the channel.
 func (pb *Actor) Close(vmctx exec.VMContext, payer address.Address, chid *types.ChannelID, amt *types.AttoFIL, validAt *types.BlockHeight, sig []byte) (uint8, error) {
-	if err := vmctx.Charge(100); err != nil {
-		return exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
-	}
-
 	if !VerifyVoucherSignature(payer, chid, amt, validAt, sig) {
 		return errors.CodeError(Errors[ErrInvalidSignature]), Errors[ErrInvalidSignature]
 	}
@@ -281,20 +295,22 @@ func (pb *Actor) Close(vmctx exec.VMContext, payer address.Address, chid *types.
 		return errors.CodeError(err), err
 	}
 
+	err = vmctx.EmitEvent(EventChannelClosed, payer, chid, amt)
+	if err != nil {
+		return errors.CodeError(err), err
+	}
+
 	return 0, nil
 }
 
 // Extend can be used by the owner of a channel to add more funds to it and
 // extend the Channel's lifespan.
 func (pb *Actor) Extend(vmctx exec.VMContext, chid *types.ChannelID, eol *types.BlockHeight) (uint8, error) {
-	if err := vmctx.Charge(100); err != nil {
-		return exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
-	}
-
 	ctx := context.Background()
 	storage := vmctx.Storage()
 	payerAddress := vmctx.Message().From
 
+	var channel *PaymentChannel
 	err := withPayerChannels(ctx, storage, payerAddress, func(byChannelID exec.Lookup) error {
 		chInt, err := byChannelID.Find(ctx, chid.KeyString())
 		if err != nil {
@@ -304,7 +320,8 @@ func (pb *Actor) Extend(vmctx exec.VMContext, chid *types.ChannelID, eol *types.
 			return errors.FaultErrorWrapf(err, "Could not retrieve payment channel with ID: %s", chid)
 		}
 
-		channel, ok := chInt.(*PaymentChannel)
+		var ok bool
+		channel, ok = chInt.(*PaymentChannel)
 		if !ok {
 			return errors.NewFaultError("Expected PaymentChannel from channels lookup")
 		}
@@ -331,16 +348,17 @@ func (pb *Actor) Extend(vmctx exec.VMContext, chid *types.ChannelID, eol *types.
 		return errors.CodeError(err), err
 	}
 
+	err = vmctx.EmitEvent(EventChannelExtended, payerAddress, chid, channel.Amount, channel.Eol)
+	if err != nil {
+		return errors.CodeError(err), err
+	}
+
 	return 0, nil
 }
 
 // Reclaim is used by the owner of a channel to reclaim unspent funds in timed
 // out payment Channels they own.
 func (pb *Actor) Reclaim(vmctx exec.VMContext, chid *types.ChannelID) (uint8, error) {
-	if err := vmctx.Charge(100); err != nil {
-		return exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
-	}
-
 	ctx := context.Background()
 	storage := vmctx.Storage()
 	payerAddress := vmctx.Message().From
@@ -376,6 +394,11 @@ func (pb *Actor) Reclaim(vmctx exec.VMContext, chid *types.ChannelID) (uint8, er
 		return errors.CodeError(err), err
 	}
 
+	err = vmctx.EmitEvent(EventChannelReclaimed, payerAddress, chid)
+	if err != nil {
+		return errors.CodeError(err), err
+	}
+
 	return 0, nil
 }
 
@@ -385,10 +408,6 @@ func (pb *Actor) Reclaim(vmctx exec.VMContext, chid *types.ChannelID) (uint8, er
 // Voucher errors if the channel doesn't exist or contains less than request
 // amount.
 func (pb *Actor) Voucher(vmctx exec.VMContext, chid *types.ChannelID, amount *types.AttoFIL, validAt *types.BlockHeight) ([]byte, uint8, error) {
-	if err := vmctx.Charge(100); err != nil {
-		return []byte{}, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
-	}
-
 	ctx := context.Background()
 	storage := vmctx.Storage()
 	payerAddress := vmctx.Message().From
@@ -446,10 +465,6 @@ func (pb *Actor) Voucher(vmctx exec.VMContext, chid *types.ChannelID, amount *ty
 // Ls returns all payment channels for a given payer address.
 // The slice of channels will be returned as cbor encoded map from string channelId to PaymentChannel.
 func (pb *Actor) Ls(vmctx exec.VMContext, payer address.Address) ([]byte, uint8, error) {
-	if err := vmctx.Charge(100); err != nil {
-		return []byte{}, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
-	}
-
 	ctx := context.Background()
 	storage := vmctx.Storage()
 	channels := map[string]*PaymentChannel{}
@@ -98,6 +98,16 @@ func TestPaymentBrokerUpdate(t *testing.T) {
 	assert.Equal(types.NewAttoFILFromFIL(1000), channel.Amount)
 	assert.Equal(types.NewAttoFILFromFIL(100), channel.AmountRedeemed)
 	assert.Equal(sys.target, channel.Target)
+
+	require.Len(result.Receipt.Events, 1)
+	event := result.Receipt.Events[0]
+	assert.Equal(address.PaymentBrokerAddress, event.Actor)
+	assert.Equal(EventChannelRedeemed, event.Type)
+	values, err := abi.DecodeValues(event.Data, []abi.Type{abi.Address, abi.ChannelID, abi.AttoFIL})
+	require.NoError(err)
+	assert.Equal(sys.payer, values[0].Val)
+	assert.Equal(sys.channelID, values[1].Val)
+	assert.Equal(types.NewAttoFILFromFIL(100), values[2].Val)
 }
 
 func TestPaymentBrokerUpdateErrorsWithIncorrectChannel(t *testing.T) {
@@ -0,0 +1,156 @@
+package builtin
+
+import (
+	"sort"
+	"sync"
+
+	cid "gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+
+	"github.com/filecoin-project/go-filecoin/exec"
+)
+
+// Entry is an actor implementation registered for a code cid.
+type Entry struct {
+	// Code is the cid actors executed by the implementation have as code.
+	Code cid.Cid
+	// Name identifies the actor across versions, e.g. "MinerActor".
+	Name string
+	// Version orders the implementations registered under the same name.
+	Version uint64
+	Actor   exec.ExecutableActor
+	// StateSchema, if set, constructs the type the actor's state decodes
+	// into (see StateSchemas).
+	StateSchema func() interface{}
+}
+
+// Registry maps actor code cids to the implementations executing them.
+//
+// Each version of an actor has its own code, so several versions may be
+// registered at once: actors keep executing the version their code names
+// until a migration at an upgrade height changes their code to the next one
+// (see migration.MigrateActors). Nodes thereby still validate the chain
+// before the upgrade.
+type Registry struct {
+	lk      sync.RWMutex
+	entries map[cid.Cid]Entry
+	actors  map[cid.Cid]exec.ExecutableActor
+	schemas map[cid.Cid]func() interface{}
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		entries: make(map[cid.Cid]Entry),
+		actors:  make(map[cid.Cid]exec.ExecutableActor),
+		schemas: make(map[cid.Cid]func() interface{}),
+	}
+}
+
+// Register adds e to the registry. It fails if e's code, or its name and
+// version, are registered already.
+func (r *Registry) Register(e Entry) error {
+	if !e.Code.Defined() {
+		return errors.New("actor code must be defined")
+	}
+	if e.Actor == nil {
+		return errors.Errorf("no implementation for actor code %s", e.Code)
+	}
+
+	r.lk.Lock()
+	defer r.lk.Unlock()
+
+	if _, ok := r.actors[e.Code]; ok {
+		return errors.Errorf("actor code %s already registered", e.Code)
+	}
+	for _, other := range r.entries {
+		if other.Name == e.Name && other.Version == e.Version {
+			return errors.Errorf("actor %s version %d already registered with code %s", e.Name, e.Version, other.Code)
+		}
+	}
+
+	r.entries[e.Code] = e
+	r.actors[e.Code] = e.Actor
+	if e.StateSchema != nil {
+		r.schemas[e.Code] = e.StateSchema
+	}
+	return nil
+}
+
+// MustRegister is Register, panicking on error. It is meant for registering
+// actors from init functions.
+func (r *Registry) MustRegister(e Entry) {
+	if err := r.Register(e); err != nil {
+		panic(err)
+	}
+}
+
+// Get returns the implementation of the actor code.
+func (r *Registry) Get(code cid.Cid) (exec.ExecutableActor, bool) {
+	r.lk.RLock()
+	defer r.lk.RUnlock()
+
+	a, ok := r.actors[code]
+	return a, ok
+}
+
+// Lookup returns the entry registered for the actor code.
+func (r *Registry) Lookup(code cid.Cid) (Entry, bool) {
+	r.lk.RLock()
+	defer r.lk.RUnlock()
+
+	e, ok := r.entries[code]
+	return e, ok
+}
+
+// Version returns the entry registered for the given version of the actor
+// called name.
+func (r *Registry) Version(name string, version uint64) (Entry, bool) {
+	for _, e := range r.Versions(name) {
+		if e.Version == version {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Latest returns the entry with the highest version registered for the actor
+// called name.
+func (r *Registry) Latest(name string) (Entry, bool) {
+	versions := r.Versions(name)
+	if len(versions) == 0 {
+		return Entry{}, false
+	}
+	return versions[len(versions)-1], true
+}
+
+// Versions returns the entries registered for the actor called name, ordered
+// by version.
+func (r *Registry) Versions(name string) []Entry {
+	r.lk.RLock()
+	defer r.lk.RUnlock()
+
+	var versions []Entry
+	for _, e := range r.entries {
+		if e.Name == name {
+			versions = append(versions, e)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Version < versions[j].Version
+	})
+	return versions
+}
+
+// Actors returns a copy of the map from code to implementation of the
+// registered actors, e.g. to load state trees with.
+func (r *Registry) Actors() map[cid.Cid]exec.ExecutableActor {
+	r.lk.RLock()
+	defer r.lk.RUnlock()
+
+	actors := make(map[cid.Cid]exec.ExecutableActor, len(r.actors))
+	for c, a := range r.actors {
+		actors[c] = a
+	}
+	return actors
+}
@@ -0,0 +1,90 @@
+package builtin_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/actor"
+	. "github.com/filecoin-project/go-filecoin/actor/builtin"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	cidGetter := types.NewCidForTestGetter()
+	v1, v2 := &actor.FakeActor{}, &actor.FakeActor{}
+	code1, code2 := cidGetter(), cidGetter()
+
+	r := NewRegistry()
+	require.NoError(r.Register(Entry{Code: code2, Name: "FakeActor", Version: 2, Actor: v2}))
+	require.NoError(r.Register(Entry{Code: code1, Name: "FakeActor", Version: 1, Actor: v1}))
+
+	t.Run("both versions execute their code", func(t *testing.T) {
+		_, ok := r.Get(code1)
+		assert.True(ok)
+		e, ok := r.Lookup(code2)
+		assert.True(ok)
+		assert.Equal(uint64(2), e.Version)
+
+		_, ok = r.Get(cidGetter())
+		assert.False(ok)
+
+		actors := r.Actors()
+		assert.Len(actors, 2)
+		assert.Contains(actors, code1)
+		assert.Contains(actors, code2)
+	})
+
+	t.Run("looks up versions by name", func(t *testing.T) {
+		e, ok := r.Version("FakeActor", 1)
+		assert.True(ok)
+		assert.Equal(code1, e.Code)
+
+		e, ok = r.Latest("FakeActor")
+		assert.True(ok)
+		assert.Equal(code2, e.Code)
+
+		versions := r.Versions("FakeActor")
+		require.Len(versions, 2)
+		assert.Equal(uint64(1), versions[0].Version)
+		assert.Equal(uint64(2), versions[1].Version)
+
+		_, ok = r.Latest("NoSuchActor")
+		assert.False(ok)
+	})
+
+	t.Run("rejects duplicates", func(t *testing.T) {
+		err := r.Register(Entry{Code: code1, Name: "OtherActor", Version: 1, Actor: v1})
+		assert.Contains(err.Error(), "already registered")
+
+		err = r.Register(Entry{Code: cidGetter(), Name: "FakeActor", Version: 2, Actor: v2})
+		assert.Contains(err.Error(), "FakeActor version 2 already registered")
+	})
+
+	t.Run("rejects entries without code or implementation", func(t *testing.T) {
+		assert.Error(r.Register(Entry{Name: "FakeActor", Version: 3, Actor: v1}))
+		assert.Error(r.Register(Entry{Code: cidGetter(), Name: "FakeActor", Version: 3}))
+	})
+}
+
+func TestDefaultRegistry(t *testing.T) {
+	assert := assert.New(t)
+
+	e, ok := Default.Latest("MinerActor")
+	assert.True(ok)
+	assert.Equal(types.MinerActorCodeCid, e.Code)
+	assert.True(Actors[types.MinerActorCodeCid] == e.Actor)
+	assert.NotNil(StateSchemas[types.MinerActorCodeCid])
+
+	// actors added to Actors directly, as tests do, are executed too
+	code := types.NewCidForTestGetter()()
+	Actors[code] = &actor.FakeActor{}
+	defer delete(Actors, code)
+	_, ok = Default.Get(code)
+	assert.True(ok)
+}
@@ -0,0 +1,61 @@
+// Code generated by actorgen. DO NOT EDIT.
+
+package reward
+
+import (
+	"github.com/filecoin-project/go-filecoin/abi"
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/vm/errors"
+)
+
+var _ exec.Dispatcher = (*Actor)(nil)
+
+// Method returns the exported method with the given name, bound to a.
+func (a *Actor) Method(name string) (exec.ExportedFunc, bool) {
+	switch name {
+	case "awardBlockReward":
+		return a.dispatchAwardBlockReward, true
+	case "getTotalMined":
+		return a.dispatchGetTotalMined, true
+	default:
+		return nil, false
+	}
+}
+
+func (a *Actor) dispatchAwardBlockReward(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, rewardExports["awardBlockReward"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	code, err := a.AwardBlockReward(ctx, params[0].Val.(address.Address))
+	if err != nil {
+		return nil, code, actor.CheckExportError(a, "awardBlockReward", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues()
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *Actor) dispatchGetTotalMined(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, rewardExports["getTotalMined"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	r0, code, err := a.GetTotalMined(ctx)
+	if err != nil {
+		return nil, code, actor.CheckExportError(a, "getTotalMined", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues(r0)
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
@@ -0,0 +1,190 @@
+package reward
+
+import (
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	cbor "gx/ipfs/QmRoARq3nkUb13HSKZGepCZSWe5GrVPwx7xURJGZ7KWv9V/go-ipld-cbor"
+	xerrors "gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+
+	"github.com/filecoin-project/go-filecoin/abi"
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/vm/errors"
+)
+
+func init() {
+	cbor.RegisterCborType(State{})
+}
+
+const (
+	// ErrCallerUnauthorized signals an unauthorized caller.
+	ErrCallerUnauthorized = 33
+)
+
+// Errors map error codes to revert errors this actor may return.
+var Errors = map[uint8]error{
+	ErrCallerUnauthorized: errors.NewCodedRevertErrorf(ErrCallerUnauthorized, "only the network may award block rewards"),
+}
+
+// MiningReserve is the amount of filecoin set aside at genesis to reward
+// miners with. The reward actor holds it and never pays out more.
+var MiningReserve = types.NewAttoFILFromFIL(1400000000)
+
+// EmissionSchedule lists the block rewards of the protocol ordered by the
+// block height from which they apply. The first entry must apply from
+// genesis.
+type EmissionSchedule []struct {
+	Height *types.BlockHeight
+	Reward *types.AttoFIL
+}
+
+// RewardAt returns the block reward that applies at block height bh. A nil
+// bh gets the genesis reward.
+func (es EmissionSchedule) RewardAt(bh *types.BlockHeight) *types.AttoFIL {
+	reward := es[0].Reward
+	if bh == nil {
+		return reward
+	}
+	for _, entry := range es[1:] {
+		if bh.LessThan(entry.Height) {
+			break
+		}
+		reward = entry.Reward
+	}
+	return reward
+}
+
+// HalvingInterval is the number of blocks after which the block reward
+// halves, about two years of 30 second blocks.
+const HalvingInterval = 2102400
+
+// Emissions is the emission schedule of the protocol. The block reward
+// halves every HalvingInterval blocks until it drops below one filecoin, and
+// stays there until the mining reserve is spent.
+var Emissions = EmissionSchedule{
+	{Height: types.NewBlockHeight(0), Reward: types.NewAttoFILFromFIL(1000)},
+	{Height: types.NewBlockHeight(1 * HalvingInterval), Reward: types.NewAttoFILFromFIL(500)},
+	{Height: types.NewBlockHeight(2 * HalvingInterval), Reward: types.NewAttoFILFromFIL(250)},
+	{Height: types.NewBlockHeight(3 * HalvingInterval), Reward: types.NewAttoFILFromFIL(125)},
+	{Height: types.NewBlockHeight(4 * HalvingInterval), Reward: mustParseFIL("62.5")},
+	{Height: types.NewBlockHeight(5 * HalvingInterval), Reward: mustParseFIL("31.25")},
+	{Height: types.NewBlockHeight(6 * HalvingInterval), Reward: mustParseFIL("15.625")},
+	{Height: types.NewBlockHeight(7 * HalvingInterval), Reward: mustParseFIL("7.8125")},
+	{Height: types.NewBlockHeight(8 * HalvingInterval), Reward: mustParseFIL("3.90625")},
+	{Height: types.NewBlockHeight(9 * HalvingInterval), Reward: mustParseFIL("1.953125")},
+	{Height: types.NewBlockHeight(10 * HalvingInterval), Reward: mustParseFIL("0.9765625")},
+}
+
+func mustParseFIL(s string) *types.AttoFIL {
+	v, ok := types.NewAttoFILFromFILString(s)
+	if !ok {
+		panic("invalid filecoin amount " + s)
+	}
+	return v
+}
+
+// Actor is the builtin actor paying the block reward to the miner of each
+// block out of the mining reserve.
+type Actor struct{}
+
+// State is the reward actor's storage.
+type State struct {
+	// TotalMined is the sum of all block rewards paid so far.
+	TotalMined *types.AttoFIL
+}
+
+// NewActor returns a new reward actor holding the mining reserve.
+func NewActor() *actor.Actor {
+	return actor.NewActor(types.RewardActorCodeCid, MiningReserve)
+}
+
+// InitializeState stores the actor's initial data structure.
+func (ra *Actor) InitializeState(storage exec.Storage, _ interface{}) error {
+	stateBytes, err := cbor.DumpObject(&State{TotalMined: types.NewZeroAttoFIL()})
+	if err != nil {
+		return xerrors.Wrap(err, "failed to cbor marshal object")
+	}
+
+	id, err := storage.Put(stateBytes)
+	if err != nil {
+		return err
+	}
+
+	return storage.Commit(id, cid.Undef)
+}
+
+var _ exec.ExecutableActor = (*Actor)(nil)
+
+// Exports returns the actor's exports.
+func (ra *Actor) Exports() exec.Exports {
+	return rewardExports
+}
+
+//go:generate go run ../../../tools/actorgen/main.go -type Actor -exports rewardExports
+
+var rewardExports = exec.Exports{
+	"awardBlockReward": &exec.FunctionSignature{
+		Params: []abi.Type{abi.Address},
+		Return: nil,
+	},
+	"getTotalMined": &exec.FunctionSignature{
+		Params: nil,
+		Return: []abi.Type{abi.AttoFIL},
+	},
+}
+
+// AwardBlockReward pays the block reward at the current block height to
+// miner, or what is left of the mining reserve if that is less. It may only
+// be called by the network while processing a block.
+func (ra *Actor) AwardBlockReward(ctx exec.VMContext, miner address.Address) (uint8, error) {
+	if ctx.Message().From != address.NetworkAddress {
+		return ErrCallerUnauthorized, Errors[ErrCallerUnauthorized]
+	}
+
+	var state State
+	out, err := actor.WithState(ctx, &state, func() (interface{}, error) {
+		reward := Emissions.RewardAt(ctx.BlockHeight())
+		if left := MiningReserve.Sub(state.TotalMined); left.LessThan(reward) {
+			reward = left
+		}
+		state.TotalMined = state.TotalMined.Add(reward)
+		return reward, nil
+	})
+	if err != nil {
+		return errors.CodeError(err), err
+	}
+
+	reward, ok := out.(*types.AttoFIL)
+	if !ok {
+		return 1, errors.NewFaultErrorf("expected *types.AttoFIL to be returned, but got %T instead", out)
+	}
+	if reward.IsZero() {
+		return 0, nil
+	}
+
+	_, code, err := ctx.Send(miner, "", reward, nil)
+	if err != nil {
+		return code, err
+	}
+
+	return 0, nil
+}
+
+// GetTotalMined returns the sum of all block rewards paid so far.
+func (ra *Actor) GetTotalMined(ctx exec.VMContext) (*types.AttoFIL, uint8, error) {
+	var state State
+	out, err := actor.WithState(ctx, &state, func() (interface{}, error) {
+		return state.TotalMined, nil
+	})
+	if err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	totalMined, ok := out.(*types.AttoFIL)
+	if !ok {
+		return nil, 1, errors.NewFaultErrorf("expected *types.AttoFIL to be returned, but got %T instead", out)
+	}
+
+	return totalMined, 0, nil
+}
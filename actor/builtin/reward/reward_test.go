@@ -0,0 +1,100 @@
+package reward_test
+
+import (
+	"context"
+	"testing"
+
+	"gx/ipfs/QmRXf2uUSdGSunRJsM9wXSUNVwLUGCY3So5fAs7h2CBJVf/go-hamt-ipld"
+
+	"github.com/filecoin-project/go-filecoin/actor"
+	. "github.com/filecoin-project/go-filecoin/actor/builtin/reward"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/core"
+	"github.com/filecoin-project/go-filecoin/state"
+	th "github.com/filecoin-project/go-filecoin/testhelpers"
+	"github.com/filecoin-project/go-filecoin/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmissionsRewardAt(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(types.NewAttoFILFromFIL(1000), Emissions.RewardAt(nil))
+	assert.Equal(types.NewAttoFILFromFIL(1000), Emissions.RewardAt(types.NewBlockHeight(0)))
+	assert.Equal(types.NewAttoFILFromFIL(1000), Emissions.RewardAt(types.NewBlockHeight(HalvingInterval-1)))
+	assert.Equal(types.NewAttoFILFromFIL(500), Emissions.RewardAt(types.NewBlockHeight(HalvingInterval)))
+	assert.Equal(types.NewAttoFILFromFIL(125), Emissions.RewardAt(types.NewBlockHeight(3*HalvingInterval+1)))
+
+	last := Emissions[len(Emissions)-1].Reward
+	assert.Equal(last, Emissions.RewardAt(types.NewBlockHeight(100*HalvingInterval)))
+	assert.True(last.LessThan(types.NewAttoFILFromFIL(1)))
+}
+
+func TestAwardBlockReward(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+
+	st, vms := core.CreateStorages(ctx, t)
+	minerAddr := address.NewForTestGetter()()
+
+	pdata := actor.MustConvertParams(minerAddr)
+	msg := types.NewMessage(address.NetworkAddress, address.RewardAddress, 0, nil, "awardBlockReward", pdata)
+	result, err := th.ApplyTestMessage(st, vms, msg, types.NewBlockHeight(HalvingInterval))
+	require.NoError(err)
+	require.NoError(result.ExecutionError)
+
+	reward := types.NewAttoFILFromFIL(500)
+	assert.Equal(reward, state.MustGetActor(st, minerAddr).Balance)
+	assert.Equal(MiningReserve.Sub(reward), state.MustGetActor(st, address.RewardAddress).Balance)
+
+	msg = types.NewMessage(address.NetworkAddress, address.RewardAddress, 1, nil, "getTotalMined", nil)
+	result, err = th.ApplyTestMessage(st, vms, msg, types.NewBlockHeight(HalvingInterval))
+	require.NoError(err)
+	require.NoError(result.ExecutionError)
+	assert.Equal(reward, types.NewAttoFILFromBytes(result.Receipt.Return[0]))
+}
+
+func TestAwardBlockRewardUnauthorized(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+
+	st, vms := core.CreateStorages(ctx, t)
+
+	pdata := actor.MustConvertParams(address.TestAddress)
+	msg := types.NewMessage(address.TestAddress, address.RewardAddress, 0, nil, "awardBlockReward", pdata)
+	result, err := th.ApplyTestMessage(st, vms, msg, types.NewBlockHeight(0))
+	require.NoError(err)
+
+	assert.Error(result.ExecutionError)
+	assert.Equal(uint8(ErrCallerUnauthorized), result.Receipt.ExitCode)
+	assert.Equal(MiningReserve, state.MustGetActor(st, address.RewardAddress).Balance)
+}
+
+func TestAwardBlockRewardCappedByReserve(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	vms := th.VMStorage()
+	left := types.NewAttoFILFromFIL(10)
+	minerAddr := address.NewForTestGetter()()
+	_, st := th.RequireMakeStateTree(require, hamt.NewCborStore(), map[address.Address]*actor.Actor{
+		address.NetworkAddress: th.RequireNewAccountActor(require, types.NewAttoFILFromFIL(100)),
+		address.RewardAddress:  th.RequireNewRewardActor(require, vms, left, MiningReserve.Sub(left)),
+	})
+
+	pdata := actor.MustConvertParams(minerAddr)
+	for nonce := uint64(0); nonce < 2; nonce++ {
+		msg := types.NewMessage(address.NetworkAddress, address.RewardAddress, nonce, nil, "awardBlockReward", pdata)
+		result, err := th.ApplyTestMessage(st, vms, msg, types.NewBlockHeight(0))
+		require.NoError(err)
+		require.NoError(result.ExecutionError)
+	}
+
+	// The first award drains what is left of the reserve, the second pays nothing.
+	assert.Equal(left, state.MustGetActor(st, minerAddr).Balance)
+	assert.True(state.MustGetActor(st, address.RewardAddress).Balance.IsZero())
+}
@@ -0,0 +1,65 @@
+package storagemarket
+
+import (
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	cbor "gx/ipfs/QmRoARq3nkUb13HSKZGepCZSWe5GrVPwx7xURJGZ7KWv9V/go-ipld-cbor"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func init() {
+	cbor.RegisterCborType(StorageDealProposal{})
+	cbor.RegisterCborType(StorageDeal{})
+}
+
+// StorageDealProposal holds the terms of a storage deal a client proposes to
+// a miner.
+type StorageDealProposal struct {
+	// PieceRef is the cid of the piece being stored.
+	PieceRef cid.Cid
+	// Size is the number of bytes of the piece.
+	Size *types.BytesAmount
+	// TotalPrice is the price the client pays for the entire deal.
+	TotalPrice *types.AttoFIL
+	// Duration is the number of blocks the piece is stored for.
+	Duration uint64
+	// Client is the address paying for the deal.
+	Client address.Address
+	// Miner is the address of the miner actor storing the piece.
+	Miner address.Address
+}
+
+// Marshal returns the bytes of the proposal both parties sign.
+func (p *StorageDealProposal) Marshal() ([]byte, error) {
+	return cbor.DumpObject(p)
+}
+
+// Sign returns the signature of the proposal by addr.
+func (p *StorageDealProposal) Sign(signer types.Signer, addr address.Address) (types.Signature, error) {
+	data, err := p.Marshal()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal proposal")
+	}
+	return signer.SignBytes(data, addr)
+}
+
+// VerifySignature returns true if sig is the signature of the proposal by
+// addr.
+func (p *StorageDealProposal) VerifySignature(addr address.Address, sig types.Signature) bool {
+	data, err := p.Marshal()
+	if err != nil {
+		return false
+	}
+	return types.IsValidSignature(data, addr, sig)
+}
+
+// StorageDeal is a storage deal proposal signed by the client and
+// counter-signed by the owner of the miner. Publishing it to the storage
+// market gives both parties an on-chain record of the deal.
+type StorageDeal struct {
+	Proposal        StorageDealProposal
+	ClientSignature types.Signature
+	MinerSignature  types.Signature
+}
@@ -0,0 +1,146 @@
+// Code generated by actorgen. DO NOT EDIT.
+
+package storagemarket
+
+import (
+	"math/big"
+
+	"gx/ipfs/QmY5Grm8pJdiSSVsYxx4uNRgweY72EmYwuSDbRnbFok3iY/go-libp2p-peer"
+
+	"github.com/filecoin-project/go-filecoin/abi"
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/vm/errors"
+)
+
+var _ exec.Dispatcher = (*Actor)(nil)
+
+// Method returns the exported method with the given name, bound to a.
+func (a *Actor) Method(name string) (exec.ExportedFunc, bool) {
+	switch name {
+	case "createMiner":
+		return a.dispatchCreateMiner, true
+	case "createMultisig":
+		return a.dispatchCreateMultisig, true
+	case "getStorageDeal":
+		return a.dispatchGetStorageDeal, true
+	case "getTotalStorage":
+		return a.dispatchGetTotalStorage, true
+	case "publishStorageDeals":
+		return a.dispatchPublishStorageDeals, true
+	case "updatePower":
+		return a.dispatchUpdatePower, true
+	default:
+		return nil, false
+	}
+}
+
+func (a *Actor) dispatchCreateMiner(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, storageMarketExports["createMiner"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	r0, code, err := a.CreateMiner(ctx, params[0].Val.(*big.Int), params[1].Val.([]byte), params[2].Val.(peer.ID))
+	if err != nil {
+		return nil, code, actor.CheckExportError(a, "createMiner", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues(r0)
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *Actor) dispatchCreateMultisig(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, storageMarketExports["createMultisig"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	r0, code, err := a.CreateMultisig(ctx, params[0].Val.([]address.Address), params[1].Val.(*big.Int), params[2].Val.(*types.BlockHeight))
+	if err != nil {
+		return nil, code, actor.CheckExportError(a, "createMultisig", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues(r0)
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *Actor) dispatchGetStorageDeal(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, storageMarketExports["getStorageDeal"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	r0, code, err := a.GetStorageDeal(ctx, params[0].Val.(*big.Int))
+	if err != nil {
+		return nil, code, actor.CheckExportError(a, "getStorageDeal", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues(r0)
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *Actor) dispatchGetTotalStorage(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, storageMarketExports["getTotalStorage"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	r0, code, err := a.GetTotalStorage(ctx)
+	if err != nil {
+		return nil, code, actor.CheckExportError(a, "getTotalStorage", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues(r0)
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *Actor) dispatchPublishStorageDeals(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, storageMarketExports["publishStorageDeals"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	r0, code, err := a.PublishStorageDeals(ctx, params[0].Val.([]byte))
+	if err != nil {
+		return nil, code, actor.CheckExportError(a, "publishStorageDeals", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues(r0)
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *Actor) dispatchUpdatePower(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, storageMarketExports["updatePower"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	code, err := a.UpdatePower(ctx, params[0].Val.(*big.Int))
+	if err != nil {
+		return nil, code, actor.CheckExportError(a, "updatePower", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues()
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"math/big"
+	"strconv"
 
 	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
 	"gx/ipfs/QmRXf2uUSdGSunRJsM9wXSUNVwLUGCY3So5fAs7h2CBJVf/go-hamt-ipld"
@@ -13,6 +14,7 @@ import (
 	"github.com/filecoin-project/go-filecoin/abi"
 	"github.com/filecoin-project/go-filecoin/actor"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/miner"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/multisig"
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/exec"
 	"github.com/filecoin-project/go-filecoin/types"
@@ -32,6 +34,23 @@ const (
 	ErrUnknownMiner = 34
 	// ErrInsufficientCollateral indicates the collateral is too low.
 	ErrInsufficientCollateral = 43
+	// ErrInvalidDeal indicates a published deal is malformed or not signed by
+	// both parties.
+	ErrInvalidDeal = 44
+	// ErrDealNotFound indicates no deal was published with the requested id.
+	ErrDealNotFound = 45
+)
+
+// Types of the events this actor emits, and the values they carry.
+const (
+	// EventMinerCreated carries the address and owner of a new miner.
+	EventMinerCreated = "minerCreated"
+	// EventPowerUpdated carries the address of a miner and the change in its
+	// number of sectors, which is negative when sectors are removed.
+	EventPowerUpdated = "powerUpdated"
+	// EventDealPublished carries the id of a published storage deal, and the
+	// addresses of its miner and client.
+	EventDealPublished = "dealPublished"
 )
 
 // Errors map error codes to revert errors this actor may return.
@@ -39,6 +58,8 @@ var Errors = map[uint8]error{
 	ErrPledgeTooLow:           errors.NewCodedRevertErrorf(ErrPledgeTooLow, "pledge must be at least %s sectors", MinimumPledge),
 	ErrUnknownMiner:           errors.NewCodedRevertErrorf(ErrUnknownMiner, "unknown miner"),
 	ErrInsufficientCollateral: errors.NewCodedRevertErrorf(ErrInsufficientCollateral, "collateral must be more than %s FIL per sector", MinimumCollateralPerSector),
+	ErrInvalidDeal:            errors.NewCodedRevertErrorf(ErrInvalidDeal, "invalid storage deal"),
+	ErrDealNotFound:           errors.NewCodedRevertErrorf(ErrDealNotFound, "storage deal not found"),
 }
 
 func init() {
@@ -57,6 +78,11 @@ type State struct {
 	// TotalCommitedStorage is the number of sectors that are currently committed
 	// in the whole network.
 	TotalCommittedStorage *big.Int
+
+	// Deals maps the ids of published storage deals to the deals.
+	Deals cid.Cid `refmt:",omitempty"`
+	// NextDealID is the id of the next deal published.
+	NextDealID uint64 `refmt:",omitempty"`
 }
 
 // NewActor returns a new storage market actor.
@@ -89,6 +115,8 @@ func (sma *Actor) Exports() exec.Exports {
 	return storageMarketExports
 }
 
+//go:generate go run ../../../tools/actorgen/main.go -type Actor -exports storageMarketExports
+
 var storageMarketExports = exec.Exports{
 	"createMiner": &exec.FunctionSignature{
 		Params: []abi.Type{abi.Integer, abi.Bytes, abi.PeerID},
@@ -102,15 +130,23 @@ var storageMarketExports = exec.Exports{
 		Params: []abi.Type{},
 		Return: []abi.Type{abi.Integer},
 	},
+	"createMultisig": &exec.FunctionSignature{
+		Params: []abi.Type{abi.Addresses, abi.Integer, abi.BlockHeight},
+		Return: []abi.Type{abi.Address},
+	},
+	"publishStorageDeals": &exec.FunctionSignature{
+		Params: []abi.Type{abi.Bytes},
+		Return: []abi.Type{abi.UintArray},
+	},
+	"getStorageDeal": &exec.FunctionSignature{
+		Params: []abi.Type{abi.Integer},
+		Return: []abi.Type{abi.Bytes},
+	},
 }
 
 // CreateMiner creates a new miner with the a pledge of the given amount of sectors. The
 // miners collateral is set by the value in the message.
 func (sma *Actor) CreateMiner(vmctx exec.VMContext, pledge *big.Int, publicKey []byte, pid peer.ID) (address.Address, uint8, error) {
-	if err := vmctx.Charge(100); err != nil {
-		return address.Address{}, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
-	}
-
 	var state State
 	ret, err := actor.WithState(vmctx, &state, func() (interface{}, error) {
 		if pledge.Cmp(MinimumPledge) < 0 {
@@ -156,17 +192,48 @@ func (sma *Actor) CreateMiner(vmctx exec.VMContext, pledge *big.Int, publicKey [
 		return address.Address{}, errors.CodeError(err), err
 	}
 
-	return ret.(address.Address), 0, nil
+	minerAddr := ret.(address.Address)
+	if err := vmctx.EmitEvent(EventMinerCreated, minerAddr, vmctx.Message().From); err != nil {
+		return address.Address{}, errors.CodeError(err), err
+	}
+
+	return minerAddr, 0, nil
+}
+
+// CreateMultisig creates a new multisig wallet actor owned by the given
+// signers, requiring the given number of approvals to send funds, which may
+// not be sent until unlockDuration blocks after they were proposed. The value
+// in the message is transferred to the new wallet.
+func (sma *Actor) CreateMultisig(vmctx exec.VMContext, signers []address.Address, required *big.Int, unlockDuration *types.BlockHeight) (address.Address, uint8, error) {
+	if !required.IsUint64() {
+		return address.Address{}, errors.CodeError(multisig.Errors[multisig.ErrInvalidSigners]), multisig.Errors[multisig.ErrInvalidSigners]
+	}
+	msState, err := multisig.NewState(signers, required.Uint64(), unlockDuration)
+	if err != nil {
+		return address.Address{}, errors.CodeError(err), err
+	}
+
+	addr, err := vmctx.AddressForNewActor()
+	if err != nil {
+		err = errors.FaultErrorWrap(err, "could not get address for new actor")
+		return address.Address{}, errors.CodeError(err), err
+	}
+
+	if err := vmctx.CreateNewActor(addr, types.MultisigActorCodeCid, msState); err != nil {
+		return address.Address{}, errors.CodeError(err), err
+	}
+
+	if _, _, err := vmctx.Send(addr, "", vmctx.Message().Value, nil); err != nil {
+		return address.Address{}, errors.CodeError(err), err
+	}
+
+	return addr, 0, nil
 }
 
 // UpdatePower is called to reflect a change in the overall power of the network.
 // This occurs either when a miner adds a new commitment, or when one is removed
 // (via slashing or willful removal). The delta is in number of sectors.
 func (sma *Actor) UpdatePower(vmctx exec.VMContext, delta *big.Int) (uint8, error) {
-	if err := vmctx.Charge(100); err != nil {
-		return exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
-	}
-
 	var state State
 	_, err := actor.WithState(vmctx, &state, func() (interface{}, error) {
 		miner := vmctx.Message().From
@@ -193,15 +260,15 @@ func (sma *Actor) UpdatePower(vmctx exec.VMContext, delta *big.Int) (uint8, erro
 		return errors.CodeError(err), err
 	}
 
+	if err := vmctx.EmitEvent(EventPowerUpdated, vmctx.Message().From, delta); err != nil {
+		return errors.CodeError(err), err
+	}
+
 	return 0, nil
 }
 
 // GetTotalStorage returns the total amount of proven storage in the system.
 func (sma *Actor) GetTotalStorage(vmctx exec.VMContext) (*big.Int, uint8, error) {
-	if err := vmctx.Charge(100); err != nil {
-		return nil, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
-	}
-
 	var state State
 	ret, err := actor.WithState(vmctx, &state, func() (interface{}, error) {
 		return state.TotalCommittedStorage, nil
@@ -218,6 +285,140 @@ func (sma *Actor) GetTotalStorage(vmctx exec.VMContext) (*big.Int, uint8, error)
 	return count, 0, nil
 }
 
+// PublishStorageDeals records the given storage deals, a CBOR encoded list of
+// StorageDeal, and returns their ids. The deals must be signed by their
+// clients and counter-signed by the owner of their miner, who must send the
+// message.
+func (sma *Actor) PublishStorageDeals(vmctx exec.VMContext, encoded []byte) ([]uint64, uint8, error) {
+	var deals []StorageDeal
+	if err := cbor.DecodeInto(encoded, &deals); err != nil {
+		err = errors.CodedRevertErrorWrap(ErrInvalidDeal, err, "could not decode storage deals")
+		return nil, errors.CodeError(err), err
+	}
+
+	// Checking the deals calls their miners, so it is done before the state
+	// is loaded for writing.
+	for _, deal := range deals {
+		if err := checkDeal(vmctx, deal); err != nil {
+			return nil, errors.CodeError(err), err
+		}
+	}
+
+	var state State
+	ret, err := actor.WithState(vmctx, &state, func() (interface{}, error) {
+		ctx := context.Background()
+
+		var ids []uint64
+		dealsCid, err := actor.WithTypedLookup(ctx, vmctx.Storage(), state.Deals, &StorageDeal{}, func(lookup exec.Lookup) error {
+			for i := range deals {
+				id := state.NextDealID
+				if err := lookup.Set(ctx, strconv.FormatUint(id, 10), &deals[i]); err != nil {
+					return errors.FaultErrorWrapf(err, "could not set deal %d", id)
+				}
+				ids = append(ids, id)
+				state.NextDealID++
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		state.Deals = dealsCid
+
+		return ids, nil
+	})
+	if err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	ids := ret.([]uint64)
+	for i, deal := range deals {
+		if err := vmctx.EmitEvent(EventDealPublished, ids[i], deal.Proposal.Miner, deal.Proposal.Client); err != nil {
+			return nil, errors.CodeError(err), err
+		}
+	}
+
+	return ids, 0, nil
+}
+
+// checkDeal checks deal is signed by both parties and published by the owner
+// of its miner.
+func checkDeal(vmctx exec.VMContext, deal StorageDeal) error {
+	var state State
+	_, err := actor.WithState(vmctx, &state, func() (interface{}, error) {
+		ctx := context.Background()
+		miners, err := actor.LoadLookup(ctx, vmctx.Storage(), state.Miners)
+		if err != nil {
+			return nil, errors.FaultErrorWrapf(err, "could not load lookup for miner with CID: %s", state.Miners)
+		}
+		if _, err := miners.Find(ctx, deal.Proposal.Miner.String()); err != nil {
+			if err == hamt.ErrNotFound {
+				return nil, Errors[ErrUnknownMiner]
+			}
+			return nil, errors.FaultErrorWrapf(err, "could not load lookup for miner with address: %s", deal.Proposal.Miner)
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	ret, _, err := vmctx.Send(deal.Proposal.Miner, "getOwner", types.ZeroAttoFIL, nil)
+	if err != nil {
+		return err
+	}
+	owner, err := address.NewFromBytes(ret[0])
+	if err != nil {
+		return errors.FaultErrorWrap(err, "could not decode miner owner")
+	}
+
+	if vmctx.Message().From != owner {
+		return errors.NewCodedRevertErrorf(ErrInvalidDeal, "deals of miner %s must be published by its owner", deal.Proposal.Miner)
+	}
+	if deal.Proposal.Size == nil || deal.Proposal.TotalPrice == nil {
+		return errors.NewCodedRevertError(ErrInvalidDeal, "deal proposal has no size or price")
+	}
+	if !deal.Proposal.VerifySignature(deal.Proposal.Client, deal.ClientSignature) {
+		return errors.NewCodedRevertError(ErrInvalidDeal, "invalid client signature")
+	}
+	if !deal.Proposal.VerifySignature(owner, deal.MinerSignature) {
+		return errors.NewCodedRevertError(ErrInvalidDeal, "invalid miner signature")
+	}
+	return nil
+}
+
+// GetStorageDeal returns the CBOR encoded storage deal published with the
+// given id.
+func (sma *Actor) GetStorageDeal(vmctx exec.VMContext, id *big.Int) ([]byte, uint8, error) {
+	var state State
+	ret, err := actor.WithState(vmctx, &state, func() (interface{}, error) {
+		if !id.IsUint64() || !state.Deals.Defined() {
+			return nil, Errors[ErrDealNotFound]
+		}
+
+		ctx := context.Background()
+		var deal interface{}
+		err := actor.WithTypedLookupForReading(ctx, vmctx.Storage(), state.Deals, &StorageDeal{}, func(lookup exec.Lookup) error {
+			var err error
+			deal, err = lookup.Find(ctx, id.String())
+			return err
+		})
+		if err != nil {
+			if err == hamt.ErrNotFound {
+				return nil, Errors[ErrDealNotFound]
+			}
+			return nil, errors.FaultErrorWrapf(err, "could not load deal %s", id)
+		}
+
+		return cbor.DumpObject(deal)
+	})
+	if err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	return ret.([]byte), 0, nil
+}
+
 // MinimumCollateral returns the minimum required amount of collateral for a given pledge
 func MinimumCollateral(sectors *big.Int) *types.AttoFIL {
 	return MinimumCollateralPerSector.MulBigInt(sectors)
@@ -7,6 +7,9 @@ import (
 	"math/big"
 	"testing"
 
+	cbor "gx/ipfs/QmRoARq3nkUb13HSKZGepCZSWe5GrVPwx7xURJGZ7KWv9V/go-ipld-cbor"
+
+	"github.com/filecoin-project/go-filecoin/abi"
 	"github.com/filecoin-project/go-filecoin/actor"
 	"github.com/filecoin-project/go-filecoin/actor/builtin"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/miner"
@@ -139,6 +142,91 @@ func TestStorageMarkeCreateMinerErrorsOnInvalidKey(t *testing.T) {
 	assert.Contains(result.ExecutionError.Error(), miner.Errors[miner.ErrPublicKeyTooBig].Error())
 }
 
+func TestStorageMarketPublishStorageDeals(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	st, vms := core.CreateStorages(ctx, t)
+
+	ki := types.MustGenerateKeyInfo(2, types.GenerateKeyInfoSeed())
+	signer := types.NewMockSigner(ki)
+	owner, client := signer.Addresses[0], signer.Addresses[1]
+
+	// fund the owner and have it create a miner
+	msg := types.NewMessage(address.TestAddress, owner, 0, types.NewAttoFILFromFIL(1000), "", nil)
+	result, err := th.ApplyTestMessage(st, vms, msg, types.NewBlockHeight(1))
+	require.NoError(err)
+	require.NoError(result.ExecutionError)
+
+	pdata := actor.MustConvertParams(big.NewInt(10), []byte{}, th.RequireRandomPeerID())
+	msg = types.NewMessage(owner, address.StorageMarketAddress, 0, types.NewAttoFILFromFIL(100), "createMiner", pdata)
+	result, err = th.ApplyTestMessage(st, vms, msg, types.NewBlockHeight(1))
+	require.NoError(err)
+	require.NoError(result.ExecutionError)
+	minerAddr, err := address.NewFromBytes(result.Receipt.Return[0])
+	require.NoError(err)
+
+	proposal := StorageDealProposal{
+		PieceRef:   types.NewCidForTestGetter()(),
+		Size:       types.NewBytesAmount(1000),
+		TotalPrice: types.NewAttoFILFromFIL(10),
+		Duration:   10000,
+		Client:     client,
+		Miner:      minerAddr,
+	}
+	clientSig, err := proposal.Sign(signer, client)
+	require.NoError(err)
+	minerSig, err := proposal.Sign(signer, owner)
+	require.NoError(err)
+
+	publish := func(from address.Address, deals ...StorageDeal) *types.MessageReceipt {
+		encoded, err := cbor.DumpObject(deals)
+		require.NoError(err)
+		msg := types.NewMessage(from, address.StorageMarketAddress, 0, types.ZeroAttoFIL, "publishStorageDeals", actor.MustConvertParams(encoded))
+		result, err := th.ApplyTestMessage(st, vms, msg, types.NewBlockHeight(1))
+		require.NoError(err)
+		return result.Receipt
+	}
+
+	t.Run("records deals signed by both parties", func(t *testing.T) {
+		receipt := publish(owner, StorageDeal{Proposal: proposal, ClientSignature: clientSig, MinerSignature: minerSig})
+		require.Equal(uint8(0), receipt.ExitCode)
+		ids, err := abi.Deserialize(receipt.Return[0], abi.UintArray)
+		require.NoError(err)
+		assert.Equal([]uint64{0}, ids.Val)
+
+		msg := types.NewMessage(address.TestAddress, address.StorageMarketAddress, 0, types.ZeroAttoFIL, "getStorageDeal", actor.MustConvertParams(big.NewInt(0)))
+		result, err := th.ApplyTestMessage(st, vms, msg, types.NewBlockHeight(1))
+		require.NoError(err)
+		require.NoError(result.ExecutionError)
+
+		var deal StorageDeal
+		require.NoError(cbor.DecodeInto(result.Receipt.Return[0], &deal))
+		assert.Equal(proposal.PieceRef, deal.Proposal.PieceRef)
+		assert.Equal(minerSig, deal.MinerSignature)
+	})
+
+	t.Run("rejects deals without the client's signature", func(t *testing.T) {
+		receipt := publish(owner, StorageDeal{Proposal: proposal, ClientSignature: minerSig, MinerSignature: minerSig})
+		assert.Equal(uint8(ErrInvalidDeal), receipt.ExitCode)
+		assert.Equal("invalid client signature", string(receipt.RevertReason))
+	})
+
+	t.Run("rejects deals not published by the owner of the miner", func(t *testing.T) {
+		receipt := publish(address.TestAddress, StorageDeal{Proposal: proposal, ClientSignature: clientSig, MinerSignature: minerSig})
+		assert.Equal(uint8(ErrInvalidDeal), receipt.ExitCode)
+	})
+
+	t.Run("fails to get unknown deals", func(t *testing.T) {
+		msg := types.NewMessage(address.TestAddress, address.StorageMarketAddress, 0, types.ZeroAttoFIL, "getStorageDeal", actor.MustConvertParams(big.NewInt(7)))
+		result, err := th.ApplyTestMessage(st, vms, msg, types.NewBlockHeight(1))
+		require.NoError(err)
+		assert.Equal(uint8(ErrDealNotFound), result.Receipt.ExitCode)
+	})
+}
+
 func TestMinimumCollateral(t *testing.T) {
 	assert := assert.New(t)
 	numSectors := big.NewInt(25000)
@@ -14,10 +14,38 @@ import (
 	"github.com/filecoin-project/go-filecoin/vm/errors"
 )
 
+// Dispatch returns the exported method of the given actor. Actors with
+// generated dispatch code (see tools/actorgen) are called directly, others
+// through MakeTypedExport.
+func Dispatch(actor exec.ExecutableActor, method string) exec.ExportedFunc {
+	d, ok := actor.(exec.Dispatcher)
+	if !ok {
+		return MakeTypedExport(actor, method)
+	}
+	f, ok := d.Method(method)
+	if !ok {
+		panic(fmt.Sprintf("Dispatch could not find passed in method in actor: %s", method))
+	}
+	return f
+}
+
+// CheckExportError returns err, the error an exported method of actor
+// returned. It panics if err is neither a revert error nor a fault.
+func CheckExportError(actor exec.ExecutableActor, method string, params []*abi.Value, err error) error {
+	if !(errors.ShouldRevert(err) || errors.IsFault(err)) {
+		var paramStr []string
+		for _, param := range params {
+			paramStr = append(paramStr, param.String())
+		}
+		msg := fmt.Sprintf("actor: %#+v, method: %s, args: %v, error: %s", actor, method, paramStr, err.Error())
+		panic(fmt.Sprintf("you are a bad person: error must be either a reverterror or a fault: %v", msg))
+	}
+	return err
+}
+
 // MakeTypedExport finds the correct method on the given actor and returns it.
 // The returned function is wrapped such that it takes care of serialization and type checks.
 //
-// TODO: the work of creating the wrapper should be ideally done at compile time, otherwise at least only once + cached
 // TODO: find a better name, naming is hard..
 // TODO: Ensure the method is not empty. We need to be paranoid we're not calling methods on transfer messages.
 func MakeTypedExport(actor exec.ExecutableActor, method string) exec.ExportedFunc {
@@ -79,7 +107,7 @@ func MakeTypedExport(actor exec.ExecutableActor, method string) exec.ExportedFun
 	return func(ctx exec.VMContext) ([]byte, uint8, error) {
 		params, err := abi.DecodeValues(ctx.Message().Params, signature.Params)
 		if err != nil {
-			return nil, 1, errors.RevertErrorWrap(err, "invalid params")
+			return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
 		}
 
 		args := []reflect.Value{
@@ -96,16 +124,7 @@ func MakeTypedExport(actor exec.ExecutableActor, method string) exec.ExportedFun
 
 		outErr, ok := out[len(out)-1].Interface().(error)
 		if ok {
-			if !(errors.ShouldRevert(outErr) || errors.IsFault(outErr)) {
-				var paramStr []string
-				for _, param := range params {
-					paramStr = append(paramStr, param.String())
-				}
-				msg := fmt.Sprintf("actor: %#+v, method: %s, args: %v, error: %s", actor, method, paramStr, outErr.Error())
-				panic(fmt.Sprintf("you are a bad person: error must be either a reverterror or a fault: %v", msg))
-			}
-
-			return nil, exitCode, outErr
+			return nil, exitCode, CheckExportError(actor, method, params, outErr)
 		}
 
 		vals := make([]interface{}, 0, len(out)-2)
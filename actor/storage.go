@@ -78,7 +78,12 @@ func SetKeyValue(ctx context.Context, storage exec.Storage, id cid.Cid, key stri
 // WithLookup allows one to read and write to a hamt-ipld node from storage via a callback function.
 // This function commits the lookup before returning.
 func WithLookup(ctx context.Context, storage exec.Storage, id cid.Cid, f func(exec.Lookup) error) (cid.Cid, error) {
-	lookup, err := LoadLookup(ctx, storage, id)
+	return WithTypedLookup(ctx, storage, id, nil, f)
+}
+
+// WithTypedLookup is WithLookup for a lookup whose values are of the provided type, see LoadTypedLookup.
+func WithTypedLookup(ctx context.Context, storage exec.Storage, id cid.Cid, valueType interface{}, f func(exec.Lookup) error) (cid.Cid, error) {
+	lookup, err := LoadTypedLookup(ctx, storage, id, valueType)
 	if err != nil {
 		return cid.Undef, err
 	}
@@ -93,7 +98,13 @@ func WithLookup(ctx context.Context, storage exec.Storage, id cid.Cid, f func(ex
 // WithLookupForReading allows one to read from a hamt-ipld node from storage via a callback function.
 // Unlike WithLookup, this function will not attempt to commit.
 func WithLookupForReading(ctx context.Context, storage exec.Storage, id cid.Cid, f func(exec.Lookup) error) error {
-	lookup, err := LoadLookup(ctx, storage, id)
+	return WithTypedLookupForReading(ctx, storage, id, nil, f)
+}
+
+// WithTypedLookupForReading is WithLookupForReading for a lookup whose values are of the provided type, see
+// LoadTypedLookup.
+func WithTypedLookupForReading(ctx context.Context, storage exec.Storage, id cid.Cid, valueType interface{}, f func(exec.Lookup) error) error {
+	lookup, err := LoadTypedLookup(ctx, storage, id, valueType)
 	if err != nil {
 		return err
 	}
@@ -12,6 +12,7 @@ import (
 
 	. "github.com/filecoin-project/go-filecoin/actor"
 	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/exec"
 	"github.com/filecoin-project/go-filecoin/types"
 	"github.com/filecoin-project/go-filecoin/vm"
 
@@ -165,3 +166,28 @@ func TestSetKeyValue(t *testing.T) {
 	require.NoError(err)
 	assert.Equal("bar", val)
 }
+
+func TestWithTypedLookup(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	ds := datastore.NewMapDatastore()
+	bs := blockstore.NewBlockstore(ds)
+	vms := vm.NewStorageMap(bs)
+	storage := vms.NewStorage(address.TestAddress, &Actor{})
+	ctx := context.TODO()
+
+	c, err := WithTypedLookup(ctx, storage, cid.Undef, &types.Commitments{}, func(lookup exec.Lookup) error {
+		return lookup.Set(ctx, "1", &types.Commitments{})
+	})
+	require.NoError(err)
+	assert.True(c.Defined())
+
+	err = WithTypedLookupForReading(ctx, storage, c, &types.Commitments{}, func(lookup exec.Lookup) error {
+		value, err := lookup.Find(ctx, "1")
+		require.NoError(err)
+		assert.Equal(&types.Commitments{}, value)
+		return nil
+	})
+	require.NoError(err)
+}
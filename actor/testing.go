@@ -24,6 +24,8 @@ type FakeActor struct{}
 
 var _ exec.ExecutableActor = (*FakeActor)(nil)
 
+//go:generate go run ../tools/actorgen/main.go -type FakeActor -exports FakeActorExports -out testing_gen.go
+
 // FakeActorExports are the exports of the fake actor.
 var FakeActorExports = exec.Exports{
 	"hasReturnValue": &exec.FunctionSignature{
@@ -74,6 +76,14 @@ var FakeActorExports = exec.Exports{
 		Params: nil,
 		Return: nil,
 	},
+	"abort": &exec.FunctionSignature{
+		Params: nil,
+		Return: nil,
+	},
+	"panics": &exec.FunctionSignature{
+		Params: nil,
+		Return: nil,
+	},
 }
 
 // InitializeState stores this actors
@@ -217,6 +227,27 @@ func (ma *FakeActor) BlockLimitTestMethod(ctx exec.VMContext) (uint8, error) {
 	return 0, nil
 }
 
+// Abort changes the actor's state, then aborts with exit code 43.
+func (ma *FakeActor) Abort(ctx exec.VMContext) (uint8, error) {
+	fastore := &FakeActorStorage{}
+	_, err := WithState(ctx, fastore, func() (interface{}, error) {
+		fastore.Changed = true
+		return nil, nil
+	})
+	if err != nil {
+		return 1, err
+	}
+	ctx.Abort(43, "aborted")
+	return 0, nil
+}
+
+// Panics panics as a buggy actor would.
+func (ma *FakeActor) Panics(ctx exec.VMContext) (uint8, error) {
+	var act *Actor
+	act.IncNonce()
+	return 0, nil
+}
+
 // MustConvertParams encodes the given params and panics if it fails to do so.
 func MustConvertParams(params ...interface{}) []byte {
 	vals, err := abi.ToValues(params)
@@ -0,0 +1,300 @@
+// Code generated by actorgen. DO NOT EDIT.
+
+package actor
+
+import (
+	"github.com/filecoin-project/go-filecoin/abi"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/vm/errors"
+)
+
+var _ exec.Dispatcher = (*FakeActor)(nil)
+
+// Method returns the exported method with the given name, bound to a.
+func (a *FakeActor) Method(name string) (exec.ExportedFunc, bool) {
+	switch name {
+	case "abort":
+		return a.dispatchAbort, true
+	case "attemptMultiSpend1":
+		return a.dispatchAttemptMultiSpend1, true
+	case "attemptMultiSpend2":
+		return a.dispatchAttemptMultiSpend2, true
+	case "blockLimitTestMethod":
+		return a.dispatchBlockLimitTestMethod, true
+	case "callSendTokens":
+		return a.dispatchCallSendTokens, true
+	case "chargeGasAndRevertError":
+		return a.dispatchChargeGasAndRevertError, true
+	case "goodCall":
+		return a.dispatchGoodCall, true
+	case "hasReturnValue":
+		return a.dispatchHasReturnValue, true
+	case "nestedBalance":
+		return a.dispatchNestedBalance, true
+	case "nonZeroExitCode":
+		return a.dispatchNonZeroExitCode, true
+	case "panics":
+		return a.dispatchPanics, true
+	case "returnRevertError":
+		return a.dispatchReturnRevertError, true
+	case "runsAnotherMessage":
+		return a.dispatchRunsAnotherMessage, true
+	case "sendTokens":
+		return a.dispatchSendTokens, true
+	default:
+		return nil, false
+	}
+}
+
+func (a *FakeActor) dispatchAbort(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, FakeActorExports["abort"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	code, err := a.Abort(ctx)
+	if err != nil {
+		return nil, code, CheckExportError(a, "abort", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues()
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *FakeActor) dispatchAttemptMultiSpend1(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, FakeActorExports["attemptMultiSpend1"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	code, err := a.AttemptMultiSpend1(ctx, params[0].Val.(address.Address), params[1].Val.(address.Address))
+	if err != nil {
+		return nil, code, CheckExportError(a, "attemptMultiSpend1", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues()
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *FakeActor) dispatchAttemptMultiSpend2(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, FakeActorExports["attemptMultiSpend2"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	code, err := a.AttemptMultiSpend2(ctx, params[0].Val.(address.Address), params[1].Val.(address.Address))
+	if err != nil {
+		return nil, code, CheckExportError(a, "attemptMultiSpend2", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues()
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *FakeActor) dispatchBlockLimitTestMethod(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, FakeActorExports["blockLimitTestMethod"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	code, err := a.BlockLimitTestMethod(ctx)
+	if err != nil {
+		return nil, code, CheckExportError(a, "blockLimitTestMethod", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues()
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *FakeActor) dispatchCallSendTokens(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, FakeActorExports["callSendTokens"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	code, err := a.CallSendTokens(ctx, params[0].Val.(address.Address), params[1].Val.(address.Address))
+	if err != nil {
+		return nil, code, CheckExportError(a, "callSendTokens", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues()
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *FakeActor) dispatchChargeGasAndRevertError(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, FakeActorExports["chargeGasAndRevertError"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	code, err := a.ChargeGasAndRevertError(ctx)
+	if err != nil {
+		return nil, code, CheckExportError(a, "chargeGasAndRevertError", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues()
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *FakeActor) dispatchGoodCall(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, FakeActorExports["goodCall"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	code, err := a.GoodCall(ctx)
+	if err != nil {
+		return nil, code, CheckExportError(a, "goodCall", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues()
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *FakeActor) dispatchHasReturnValue(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, FakeActorExports["hasReturnValue"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	r0, code, err := a.HasReturnValue(ctx)
+	if err != nil {
+		return nil, code, CheckExportError(a, "hasReturnValue", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues(r0)
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *FakeActor) dispatchNestedBalance(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, FakeActorExports["nestedBalance"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	code, err := a.NestedBalance(ctx, params[0].Val.(address.Address))
+	if err != nil {
+		return nil, code, CheckExportError(a, "nestedBalance", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues()
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *FakeActor) dispatchNonZeroExitCode(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, FakeActorExports["nonZeroExitCode"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	code, err := a.NonZeroExitCode(ctx)
+	if err != nil {
+		return nil, code, CheckExportError(a, "nonZeroExitCode", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues()
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *FakeActor) dispatchPanics(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, FakeActorExports["panics"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	code, err := a.Panics(ctx)
+	if err != nil {
+		return nil, code, CheckExportError(a, "panics", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues()
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *FakeActor) dispatchReturnRevertError(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, FakeActorExports["returnRevertError"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	code, err := a.ReturnRevertError(ctx)
+	if err != nil {
+		return nil, code, CheckExportError(a, "returnRevertError", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues()
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *FakeActor) dispatchRunsAnotherMessage(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, FakeActorExports["runsAnotherMessage"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	code, err := a.RunsAnotherMessage(ctx, params[0].Val.(address.Address))
+	if err != nil {
+		return nil, code, CheckExportError(a, "runsAnotherMessage", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues()
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+
+func (a *FakeActor) dispatchSendTokens(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, FakeActorExports["sendTokens"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	code, err := a.SendTokens(ctx, params[0].Val.(address.Address))
+	if err != nil {
+		return nil, code, CheckExportError(a, "sendTokens", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues()
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
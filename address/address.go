@@ -96,13 +96,25 @@ func NewTestnet(hash []byte) Address {
 
 // New constructs a new address for the given nework.
 func New(network Network, hash []byte) Address {
+	return NewWithProtocol(network, ProtocolHash, hash)
+}
+
+// NewWithProtocol constructs a new address of the given protocol for the
+// given network. It does not check the protocol is known.
+func NewWithProtocol(network Network, protocol Protocol, payload []byte) Address {
 	var addr [Length]byte
 	addr[0] = network
-	addr[1] = Version
-	copy(addr[2:], hash)
+	addr[1] = protocol
+	copy(addr[2:], payload)
 	return addr
 }
 
+// NewDelegated constructs a new address of the ProtocolDelegated protocol,
+// managed by the actor at namespace, for the given sub-address.
+func NewDelegated(network Network, namespace Address, subaddr []byte) Address {
+	return NewWithProtocol(network, ProtocolDelegated, Hash(append(namespace.Bytes(), subaddr...)))
+}
+
 // NewFromString tries to parse a given string into a filecoin address.
 func NewFromString(s string) (Address, error) {
 	networkString, version, hash, err := decode(s)
@@ -115,11 +127,11 @@ func NewFromString(s string) (Address, error) {
 		return Address{}, err
 	}
 
-	if version != Version {
+	if !KnownProtocol(version) {
 		return Address{}, ErrUnknownVersion
 	}
 
-	return New(network, hash), nil
+	return NewWithProtocol(network, version, hash), nil
 }
 
 // NewFromBytes tries to create an address from the given bytes.
@@ -134,11 +146,11 @@ func NewFromBytes(raw []byte) (Address, error) {
 	}
 
 	version := raw[1]
-	if version != Version {
+	if !KnownProtocol(version) {
 		return Address{}, ErrUnknownVersion
 	}
 
-	return New(network, raw[2:]), nil
+	return NewWithProtocol(network, version, raw[2:]), nil
 }
 
 // ParseError checks if the given address parses as a valid filecoin address.
@@ -152,7 +164,7 @@ func ParseError(addr string) error {
 		return errors.Wrap(err, "invalid network")
 	}
 
-	if version != Version {
+	if !KnownProtocol(version) {
 		return fmt.Errorf("invalid version: version=%d", version)
 	}
 
@@ -178,6 +190,12 @@ func (a Address) Version() byte {
 	return a[1]
 }
 
+// Protocol returns the protocol of the address, which is stored as its
+// version.
+func (a Address) Protocol() Protocol {
+	return a[1]
+}
+
 // Hash returns the hash part of the address.
 func (a Address) Hash() []byte {
 	return a[2:]
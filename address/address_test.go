@@ -113,7 +113,7 @@ func TestInvalidAddressCreation(t *testing.T) {
 		assert.Equal(ErrUnknownNetwork, err)
 	})
 
-	t.Run(fmt.Sprintf("NewFromBytes supports only AddressVersion %d", Version), func(t *testing.T) {
+	t.Run("NewFromBytes supports only known protocols", func(t *testing.T) {
 		assert := assert.New(t)
 
 		_, err := NewFromBytes([]byte{Testnet, Version + 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0})
@@ -122,6 +122,27 @@ func TestInvalidAddressCreation(t *testing.T) {
 	})
 }
 
+func TestDelegatedAddress(t *testing.T) {
+	assert := assert.New(t)
+
+	a := NewDelegated(Testnet, NewMainnet(hashes[0]), []byte("sub"))
+	assert.Equal(ProtocolDelegated, a.Protocol())
+	assert.Equal(Testnet, a.Network())
+	assert.NotEqual(NewDelegated(Testnet, NewMainnet(hashes[1]), []byte("sub")), a)
+
+	b, err := NewFromString(a.String())
+	assert.NoError(err)
+	assert.Equal(a, b)
+	assert.NoError(ParseError(a.String()))
+
+	b, err = NewFromBytes(a.Bytes())
+	assert.NoError(err)
+	assert.Equal(a, b)
+
+	_, err = NewFromString(NewWithProtocol(Testnet, 1, hashes[0]).String())
+	assert.Equal(ErrUnknownVersion, err)
+}
+
 func TestAddressFormat(t *testing.T) {
 	assert := assert.New(t)
 
@@ -13,6 +13,33 @@ const Length = 1 + 1 + HashLength
 // Version is the current version of the address format.
 const Version byte = 0
 
+// Protocol identifies how the payload of an address is derived. It is stored
+// in the version byte of the address, so addresses of the original format
+// are of ProtocolHash.
+type Protocol = byte
+
+const (
+	// ProtocolHash addresses carry the hash of a public key, or of the
+	// input an actor address was derived from.
+	ProtocolHash Protocol = Version
+	// ProtocolDelegated addresses are managed by the actor at another
+	// address, their namespace. They carry the hash of the namespace and a
+	// sub-address the namespace actor assigns.
+	ProtocolDelegated Protocol = 4
+)
+
+// protocols is the set of protocols addresses may be of. Protocols 1 to 3
+// are reserved for future public key and actor id addresses.
+var protocols = map[Protocol]bool{
+	ProtocolHash:      true,
+	ProtocolDelegated: true,
+}
+
+// KnownProtocol returns true if addresses may be of protocol p.
+func KnownProtocol(p Protocol) bool {
+	return protocols[p]
+}
+
 // Base32Charset is the character set used for base32 encoding in addresses.
 const Base32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
 
@@ -42,6 +69,10 @@ var (
 	StorageMarketAddress Address
 	// PaymentBrokerAddress is the hard-coded address of the filecoin storage market
 	PaymentBrokerAddress Address
+	// RewardAddress is the hard-coded address of the actor paying block rewards
+	RewardAddress Address
+	// CronAddress is the hard-coded address of the actor running scheduled callbacks
+	CronAddress Address
 )
 
 func init() {
@@ -59,4 +90,10 @@ func init() {
 
 	p := Hash([]byte("payments"))
 	PaymentBrokerAddress = NewMainnet(p)
+
+	r := Hash([]byte("reward"))
+	RewardAddress = NewMainnet(r)
+
+	c := Hash([]byte("cron"))
+	CronAddress = NewMainnet(c)
 }
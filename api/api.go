@@ -5,9 +5,11 @@ package api
 type API interface {
 	Actor() Actor
 	Address() Address
+	Auth() Auth
 	Client() Client
 	Daemon() Daemon
 	Dag() Dag
+	Deals() Deals
 	ID() ID
 	Log() Log
 	Miner() Miner
@@ -0,0 +1,17 @@
+package api
+
+import (
+	"context"
+
+	"github.com/filecoin-project/go-filecoin/auth"
+)
+
+// Auth is the interface that defines methods to manage the tokens granting
+// access to the api.
+type Auth interface {
+	// CreateToken returns a token granting perm, and the permissions
+	// before it.
+	CreateToken(ctx context.Context, perm auth.Permission) (string, error)
+	// Verify returns the permission a token grants.
+	Verify(ctx context.Context, token string) (auth.Permission, error)
+}
@@ -21,15 +21,31 @@ type Ask struct {
 	Expiry *types.BlockHeight
 	ID     uint64
 
+	// MinPieceSize and MaxPieceSize bound the size of the pieces the ask
+	// is for, if set.
+	MinPieceSize *types.BytesAmount `json:",omitempty"`
+	MaxPieceSize *types.BytesAmount `json:",omitempty"`
+
 	Error error
 }
 
 // Client is the interface that defines methods to manage client operations.
 type Client interface {
 	Cat(ctx context.Context, c cid.Cid) (uio.DagReader, error)
+	Ls(ctx context.Context, c cid.Cid) ([]*ipld.Link, error)
 	ImportData(ctx context.Context, data io.Reader) (ipld.Node, error)
 	ProposeStorageDeal(ctx context.Context, data cid.Cid, miner address.Address, ask uint64, duration uint64, allowDuplicates bool) (*storage.DealResponse, error)
+	ProposeOfflineStorageDeal(ctx context.Context, data cid.Cid, miner address.Address, ask uint64, duration uint64, allowDuplicates bool) (*storage.DealResponse, error)
+	ProposeReplicatedStorageDeal(ctx context.Context, data cid.Cid, miner address.Address, ask uint64, duration uint64, replicas int, allowDuplicates bool) ([]*storage.DealResponse, error)
+	StorageReplication(ctx context.Context, data cid.Cid) (*storage.ReplicationStatus, error)
+	ProposeStorageDealToBestMiner(ctx context.Context, data cid.Cid, duration uint64, allowDuplicates bool) (*storage.DealResponse, error)
+	MinerReputations(ctx context.Context) ([]storage.MinerReputation, error)
+	SelectMiners(ctx context.Context, data cid.Cid, maxPrice *types.AttoFIL) ([]storage.MinerChoice, error)
+	ExportCar(ctx context.Context, data cid.Cid, w io.Writer) error
 	QueryStorageDeal(ctx context.Context, prop cid.Cid) (*storage.DealResponse, error)
+	DealEvents(ctx context.Context) (<-chan storage.DealEvent, error)
 	ListAsks(ctx context.Context) (<-chan Ask, error)
+	ListPeerAsks(ctx context.Context) (<-chan Ask, error)
 	Payments(ctx context.Context, dealCid cid.Cid) ([]*paymentbroker.PaymentVoucher, error)
+	SetDealRenewal(ctx context.Context, prop cid.Cid, renew bool, maxPrice *types.AttoFIL) error
 }
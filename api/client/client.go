@@ -0,0 +1,600 @@
+// Package client is a Go client of the JSON-RPC api of the node, for tools
+// which integrate with a node without shelling out to its commands.
+//
+// The methods of Client are named and typed after the plumbing calls they
+// bind, e.g. Client.ChainHead calls plumbing.API.ChainHead on the node. Calls
+// are made over http, the methods returning a channel subscribe over a
+// websocket, which is closed once their context is done. Errors returned by
+// the node are *jsonrpc.Error, whose code tells e.g. an actor which doesn't
+// exist (jsonrpc.CodeNotFound) from an internal error.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	ma "gx/ipfs/QmNTCey11oxhb1AxDnQBRHtdhap6Ctud872NjAYPYYXPuc/go-multiaddr"
+	inet "gx/ipfs/QmNgLg1NTw37iWbYPKcyK85YJ9Whs1MkPtJwhfqbNYAyKg/go-libp2p-net"
+	cid "gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	peer "gx/ipfs/QmY5Grm8pJdiSSVsYxx4uNRgweY72EmYwuSDbRnbFok3iY/go-libp2p-peer"
+	manet "gx/ipfs/QmZcLBXKaFe8ND5YHPkJRAwmhJGrVsi1JqDZNyJ4nRK5Mj/go-multiaddr-net"
+	"gx/ipfs/QmdcULN1WCzgoQmcCaUAmEhwcxHYsDrbZ2LvRJKCL8dMrK/go-homedir"
+
+	"github.com/filecoin-project/go-filecoin/actor"
+	minerActor "github.com/filecoin-project/go-filecoin/actor/builtin/miner"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/api"
+	"github.com/filecoin-project/go-filecoin/core"
+	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/jsonrpc"
+	"github.com/filecoin-project/go-filecoin/logs"
+	"github.com/filecoin-project/go-filecoin/plumbing/msg"
+	"github.com/filecoin-project/go-filecoin/plumbing/sctr"
+	"github.com/filecoin-project/go-filecoin/porcelain"
+	"github.com/filecoin-project/go-filecoin/proofs"
+	"github.com/filecoin-project/go-filecoin/repo"
+	"github.com/filecoin-project/go-filecoin/state"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/wallet"
+)
+
+// Client calls the JSON-RPC api of a node.
+type Client struct {
+	rpc *jsonrpc.Client
+}
+
+// New returns a Client of the JSON-RPC api at url, e.g.
+// http://127.0.0.1:3453/rpc/v0, authenticating with token unless it is
+// empty. See 'go-filecoin auth' for the permissions tokens grant.
+func New(url, token string) *Client {
+	return &Client{rpc: jsonrpc.NewClient(url, token)}
+}
+
+// NewFromMultiaddr returns a Client of the node whose api listens on addr,
+// e.g. /ip4/127.0.0.1/tcp/3453.
+func NewFromMultiaddr(addr, token string) (*Client, error) {
+	maddr, err := ma.NewMultiaddr(addr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid api address %s", addr)
+	}
+	_, host, err := manet.DialArgs(maddr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to dial api address %s", addr)
+	}
+	return New(fmt.Sprintf("http://%s%s", host, jsonrpc.Path), token), nil
+}
+
+// NewFromRepo returns a Client of the daemon running on the repo at
+// repoDir, authenticating with the admin token the daemon writes to it.
+func NewFromRepo(repoDir string) (*Client, error) {
+	dir, err := homedir.Expand(filepath.Clean(repoDir))
+	if err != nil {
+		return nil, errors.Wrapf(err, "can't resolve repo path %s", repoDir)
+	}
+	addr, err := repo.APIAddrFromFile(filepath.Join(dir, repo.APIFile))
+	if err != nil {
+		return nil, errors.Wrap(err, "can't find the api address (is the daemon running?)")
+	}
+	token, err := repo.APITokenFromFile(filepath.Join(dir, repo.APITokenFile))
+	if err != nil {
+		return nil, err
+	}
+	return NewFromMultiaddr(addr, token)
+}
+
+// ActorGet returns the actor at addr.
+func (c *Client) ActorGet(ctx context.Context, addr address.Address) (*actor.Actor, error) {
+	var act actor.Actor
+	if err := c.rpc.Call(ctx, "state.getActor", &act, addr); err != nil {
+		return nil, err
+	}
+	return &act, nil
+}
+
+// ActorGetStateDecoded returns the decoded state of the actor at addr.
+func (c *Client) ActorGetStateDecoded(ctx context.Context, addr address.Address) (interface{}, error) {
+	return c.ActorReadState(ctx, addr, "")
+}
+
+// ActorReadState returns the state of the actor at addr as generic JSON,
+// or the value at path within it.
+func (c *Client) ActorReadState(ctx context.Context, addr address.Address, path string) (interface{}, error) {
+	var st interface{}
+	err := c.rpc.Call(ctx, "state.readState", &st, addr, path)
+	return st, err
+}
+
+// ActorGetSignature returns the signature of method of the actor at addr.
+func (c *Client) ActorGetSignature(ctx context.Context, addr address.Address, method string) (*exec.FunctionSignature, error) {
+	var sig exec.FunctionSignature
+	if err := c.rpc.Call(ctx, "state.getActorSignature", &sig, addr, method); err != nil {
+		return nil, err
+	}
+	return &sig, nil
+}
+
+// AddressBookLabel labels addr with name.
+func (c *Client) AddressBookLabel(ctx context.Context, addr address.Address, name string, force bool) error {
+	return c.rpc.Call(ctx, "addressBook.label", nil, addr, name, force)
+}
+
+// AddressBookUnlabel removes the label name.
+func (c *Client) AddressBookUnlabel(ctx context.Context, name string) error {
+	return c.rpc.Call(ctx, "addressBook.unlabel", nil, name)
+}
+
+// AddressBookResolve returns the address labeled s, or s parsed as an
+// address.
+func (c *Client) AddressBookResolve(ctx context.Context, s string) (address.Address, error) {
+	var addr address.Address
+	err := c.rpc.Call(ctx, "addressBook.resolve", &addr, s)
+	return addr, err
+}
+
+// AddressBookLabels lists the entries of the address book.
+func (c *Client) AddressBookLabels(ctx context.Context) ([]wallet.AddressLabel, error) {
+	var labels []wallet.AddressLabel
+	err := c.rpc.Call(ctx, "addressBook.labels", &labels)
+	return labels, err
+}
+
+// ConfigGet returns the config value at dottedPath as generic JSON.
+func (c *Client) ConfigGet(ctx context.Context, dottedPath string) (interface{}, error) {
+	var v interface{}
+	err := c.rpc.Call(ctx, "config.get", &v, dottedPath)
+	return v, err
+}
+
+// ConfigSet sets the config value at dottedPath to paramJSON.
+func (c *Client) ConfigSet(ctx context.Context, dottedPath string, paramJSON string) error {
+	if !json.Valid([]byte(paramJSON)) {
+		return errors.Errorf("invalid JSON value %s", paramJSON)
+	}
+	return c.rpc.Call(ctx, "config.set", nil, dottedPath, json.RawMessage(paramJSON))
+}
+
+// Version returns the version of the node.
+func (c *Client) Version(ctx context.Context) (*api.VersionInfo, error) {
+	var v api.VersionInfo
+	if err := c.rpc.Call(ctx, "version", &v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// CheckVersion returns an error unless the api version of the node is
+// compatible with the one of this package, api.APIVersion.
+func (c *Client) CheckVersion(ctx context.Context) error {
+	v, err := c.Version(ctx)
+	if err != nil {
+		return err
+	}
+	if !api.APIVersionCompatible(v.APIVersion) {
+		return fmt.Errorf("the node has api version %s, incompatible with the api version %s of this client", v.APIVersion, api.APIVersion)
+	}
+	return nil
+}
+
+// ChainHead returns the cids and height of the head of the chain.
+func (c *Client) ChainHead(ctx context.Context) (*jsonrpc.ChainHeadResult, error) {
+	var head jsonrpc.ChainHeadResult
+	if err := c.rpc.Call(ctx, "chain.head", &head); err != nil {
+		return nil, err
+	}
+	return &head, nil
+}
+
+// ChainLs returns a page of the tipsets of the chain, from the most recent.
+// Pass the next cursor of the result in params to get the following page.
+func (c *Client) ChainLs(ctx context.Context, params jsonrpc.ChainLsParams) (*jsonrpc.ChainLsResult, error) {
+	var res jsonrpc.ChainLsResult
+	if err := c.rpc.Call(ctx, "chain.ls", &res, params); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// ChainHeadEvents returns a channel receiving each new head of the chain
+// until ctx is done.
+func (c *Client) ChainHeadEvents(ctx context.Context) (<-chan jsonrpc.ChainHeadResult, error) {
+	events, err := c.rpc.Subscribe(ctx, "chain.subscribeHead")
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan jsonrpc.ChainHeadResult)
+	go func() {
+		defer close(out)
+		for raw := range events {
+			var head jsonrpc.ChainHeadResult
+			if err := json.Unmarshal(raw, &head); err != nil {
+				continue
+			}
+			select {
+			case out <- head:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// ChainStateDiff returns the actors that differ between the states of the
+// tipsets tsA and tsB.
+func (c *Client) ChainStateDiff(ctx context.Context, tsA, tsB types.SortedCidSet) ([]*state.ActorDiff, error) {
+	var diff []*state.ActorDiff
+	err := c.rpc.Call(ctx, "state.diff", &diff, tsA, tsB)
+	return diff, err
+}
+
+// StateMigrateDryRun returns the actors the upgrade would change, without
+// changing them.
+func (c *Client) StateMigrateDryRun(ctx context.Context, upgrade string) ([]*state.ActorDiff, error) {
+	var diff []*state.ActorDiff
+	err := c.rpc.Call(ctx, "state.migrateDryRun", &diff, upgrade)
+	return diff, err
+}
+
+// BlockGet returns the block with the given cid.
+func (c *Client) BlockGet(ctx context.Context, id cid.Cid) (*types.Block, error) {
+	var blk types.Block
+	if err := c.rpc.Call(ctx, "chain.getBlock", &blk, id); err != nil {
+		return nil, err
+	}
+	return &blk, nil
+}
+
+// MessagePoolPending returns the messages of the message pool.
+func (c *Client) MessagePoolPending(ctx context.Context) ([]*types.SignedMessage, error) {
+	var msgs []*types.SignedMessage
+	err := c.rpc.Call(ctx, "mpool.pending", &msgs)
+	return msgs, err
+}
+
+// MessagePoolRemove removes the message with the given cid from the message
+// pool.
+func (c *Client) MessagePoolRemove(ctx context.Context, id cid.Cid) error {
+	return c.rpc.Call(ctx, "mpool.remove", nil, id)
+}
+
+// MessagePoolStats returns the counters and gauges of the message pool.
+func (c *Client) MessagePoolStats(ctx context.Context) (core.MessagePoolStats, error) {
+	var stats core.MessagePoolStats
+	err := c.rpc.Call(ctx, "mpool.stats", &stats)
+	return stats, err
+}
+
+// MessagePoolSubscribe returns a channel receiving the messages added to or
+// dropped by the message pool until ctx is done.
+func (c *Client) MessagePoolSubscribe(ctx context.Context) (<-chan core.MessagePoolEvent, error) {
+	events, err := c.rpc.Subscribe(ctx, "mpool.subscribe")
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan core.MessagePoolEvent)
+	go func() {
+		defer close(out)
+		for raw := range events {
+			var e core.MessagePoolEvent
+			if err := json.Unmarshal(raw, &e); err != nil {
+				continue
+			}
+			select {
+			case out <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// MessagePreview returns the gas the message would use.
+func (c *Client) MessagePreview(ctx context.Context, m jsonrpc.MessageParams) (types.GasUnits, error) {
+	var gas types.GasUnits
+	err := c.rpc.Call(ctx, "message.preview", &gas, m)
+	return gas, err
+}
+
+// MessageCall calls the method of m on its actor, at the height of m or the
+// chain head, without sending a message, and returns the values returned
+// formatted as the message call command does.
+func (c *Client) MessageCall(ctx context.Context, m jsonrpc.MessageParams) ([]string, error) {
+	var vals []string
+	err := c.rpc.Call(ctx, "message.call", &vals, m)
+	return vals, err
+}
+
+// MessageQuery calls the method of m on its actor without sending a message
+// and returns the raw values returned, with the signature of the method.
+func (c *Client) MessageQuery(ctx context.Context, m jsonrpc.MessageParams) ([][]byte, *exec.FunctionSignature, error) {
+	var res jsonrpc.MessageQueryResult
+	if err := c.rpc.Call(ctx, "message.query", &res, m); err != nil {
+		return nil, nil, err
+	}
+	return res.Return, res.Signature, nil
+}
+
+// MessageReplay re-executes the message with the given cid over the state
+// it was applied to, tracing its execution.
+func (c *Client) MessageReplay(ctx context.Context, msgCid cid.Cid) (*msg.Replay, error) {
+	var replay msg.Replay
+	if err := c.rpc.Call(ctx, "message.replay", &replay, msgCid); err != nil {
+		return nil, err
+	}
+	return &replay, nil
+}
+
+// MessageSend signs m with the key of its from address and sends it.
+func (c *Client) MessageSend(ctx context.Context, m jsonrpc.MessageParams) (cid.Cid, error) {
+	var id cid.Cid
+	err := c.rpc.Call(ctx, "message.send", &id, m)
+	return id, err
+}
+
+// MessageSendBatch sends several messages from the same address, assigning
+// them consecutive nonces, all or none.
+func (c *Client) MessageSendBatch(ctx context.Context, from address.Address, batch []jsonrpc.MessageParams) ([]cid.Cid, error) {
+	var ids []cid.Cid
+	err := c.rpc.Call(ctx, "message.sendBatch", &ids, from, batch)
+	return ids, err
+}
+
+// MessageCompose builds the unsigned message m, e.g. to be signed offline
+// and sent with MessagePublish.
+func (c *Client) MessageCompose(ctx context.Context, m jsonrpc.MessageParams) (*types.MeteredMessage, error) {
+	var mm types.MeteredMessage
+	if err := c.rpc.Call(ctx, "message.compose", &mm, m); err != nil {
+		return nil, err
+	}
+	return &mm, nil
+}
+
+// MessagePublish sends a message signed elsewhere.
+func (c *Client) MessagePublish(ctx context.Context, smsg *types.SignedMessage) (cid.Cid, error) {
+	raw, err := smsg.Marshal()
+	if err != nil {
+		return cid.Cid{}, err
+	}
+	var id cid.Cid
+	err = c.rpc.Call(ctx, "mpool.publish", &id, raw)
+	return id, err
+}
+
+// MessageWait waits for the message with the given cid to be on chain and
+// returns the block including it and its receipt.
+func (c *Client) MessageWait(ctx context.Context, msgCid cid.Cid) (*jsonrpc.MessageWaitResult, error) {
+	var res jsonrpc.MessageWaitResult
+	if err := c.rpc.Call(ctx, "message.wait", &res, msgCid); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// NetworkGetPeerID returns the peer id of the node.
+func (c *Client) NetworkGetPeerID(ctx context.Context) (peer.ID, error) {
+	var s string
+	if err := c.rpc.Call(ctx, "network.peerID", &s); err != nil {
+		return "", err
+	}
+	return peer.IDB58Decode(s)
+}
+
+// NetworkConnectedness returns whether the node is connected to p.
+func (c *Client) NetworkConnectedness(ctx context.Context, p peer.ID) (inet.Connectedness, error) {
+	var conn inet.Connectedness
+	err := c.rpc.Call(ctx, "network.connectedness", &conn, p.Pretty())
+	return conn, err
+}
+
+// NetworkFindPeer looks up the addresses of p.
+func (c *Client) NetworkFindPeer(ctx context.Context, p peer.ID) (*jsonrpc.PeerInfoResult, error) {
+	var pi jsonrpc.PeerInfoResult
+	if err := c.rpc.Call(ctx, "network.findPeer", &pi, p.Pretty()); err != nil {
+		return nil, err
+	}
+	return &pi, nil
+}
+
+// NetworkLatencies returns the latencies of the peers the node is connected
+// to.
+func (c *Client) NetworkLatencies(ctx context.Context) ([]jsonrpc.PeerLatencyResult, error) {
+	var latencies []jsonrpc.PeerLatencyResult
+	err := c.rpc.Call(ctx, "network.latencies", &latencies)
+	return latencies, err
+}
+
+// NetworkPing pings p and returns the round trip time.
+func (c *Client) NetworkPing(ctx context.Context, p peer.ID) (time.Duration, error) {
+	var rtt time.Duration
+	err := c.rpc.Call(ctx, "network.ping", &rtt, p.Pretty())
+	return rtt, err
+}
+
+// NetworkFindMiner looks up the peer of the miner at minerAddr.
+func (c *Client) NetworkFindMiner(ctx context.Context, minerAddr address.Address) (*jsonrpc.PeerInfoResult, error) {
+	var pi jsonrpc.PeerInfoResult
+	if err := c.rpc.Call(ctx, "network.findMiner", &pi, minerAddr); err != nil {
+		return nil, err
+	}
+	return &pi, nil
+}
+
+// NetworkProvideMiner announces the node as the peer of the miner at
+// minerAddr.
+func (c *Client) NetworkProvideMiner(ctx context.Context, minerAddr address.Address) error {
+	return c.rpc.Call(ctx, "network.provideMiner", nil, minerAddr)
+}
+
+// LogStream returns a channel receiving the log records of the node and the
+// events of its subsystems which pass filter, until ctx is done. The events
+// of the entries are generic JSON.
+func (c *Client) LogStream(ctx context.Context, filter logs.Filter) (<-chan logs.Entry, error) {
+	entries, err := c.rpc.Subscribe(ctx, "log.subscribe", filter)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan logs.Entry)
+	go func() {
+		defer close(out)
+		for raw := range entries {
+			var e logs.Entry
+			if err := json.Unmarshal(raw, &e); err != nil {
+				continue
+			}
+			select {
+			case out <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// ProofsResources returns the resources of the machine the proofs use.
+func (c *Client) ProofsResources(ctx context.Context) (proofs.Resources, error) {
+	var res proofs.Resources
+	err := c.rpc.Call(ctx, "proofs.resources", &res)
+	return res, err
+}
+
+// SectorProgress returns the sealing progress of the sector with the given
+// id.
+func (c *Client) SectorProgress(ctx context.Context, sectorID uint64) (*sctr.Progress, error) {
+	var p sctr.Progress
+	if err := c.rpc.Call(ctx, "sector.progress", &p, sectorID); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// SectorProgressList returns the sealing progress of all the sectors the
+// node saw.
+func (c *Client) SectorProgressList(ctx context.Context) ([]*sctr.Progress, error) {
+	var ps []*sctr.Progress
+	err := c.rpc.Call(ctx, "sector.progressList", &ps)
+	return ps, err
+}
+
+// SectorEvents returns a channel receiving each change of stage of a sector
+// until ctx is done.
+func (c *Client) SectorEvents(ctx context.Context) (<-chan sctr.SectorEvent, error) {
+	events, err := c.rpc.Subscribe(ctx, "sector.subscribe")
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan sctr.SectorEvent)
+	go func() {
+		defer close(out)
+		for raw := range events {
+			var e sctr.SectorEvent
+			if err := json.Unmarshal(raw, &e); err != nil {
+				continue
+			}
+			select {
+			case out <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// SignBytes signs data with the key of addr.
+func (c *Client) SignBytes(ctx context.Context, data []byte, addr address.Address) (types.Signature, error) {
+	var sig types.Signature
+	err := c.rpc.Call(ctx, "wallet.sign", &sig, addr, data)
+	return sig, err
+}
+
+// WalletAddresses returns the addresses of the wallet.
+func (c *Client) WalletAddresses(ctx context.Context) ([]address.Address, error) {
+	var addrs []address.Address
+	err := c.rpc.Call(ctx, "wallet.addresses", &addrs)
+	return addrs, err
+}
+
+// WalletBalance returns the balance of addr.
+func (c *Client) WalletBalance(ctx context.Context, addr address.Address) (*types.AttoFIL, error) {
+	var balance types.AttoFIL
+	if err := c.rpc.Call(ctx, "wallet.balance", &balance, addr); err != nil {
+		return nil, err
+	}
+	return &balance, nil
+}
+
+// WalletHistory returns the messages sent from or to addr, most recent
+// first.
+func (c *Client) WalletHistory(ctx context.Context, addr address.Address, offset, limit uint) ([]*porcelain.WalletHistoryEntry, error) {
+	var entries []*porcelain.WalletHistoryEntry
+	err := c.rpc.Call(ctx, "wallet.history", &entries, addr, offset, limit)
+	return entries, err
+}
+
+// WalletNewAddress generates a new address of the wallet.
+func (c *Client) WalletNewAddress(ctx context.Context) (address.Address, error) {
+	return c.WalletNewAddressOfType(ctx, wallet.SECP256K1)
+}
+
+// WalletNewAddressOfType generates a new address of the wallet backed by a
+// key of the given type.
+func (c *Client) WalletNewAddressOfType(ctx context.Context, keyType string) (address.Address, error) {
+	var addr address.Address
+	err := c.rpc.Call(ctx, "wallet.newAddress", &addr, keyType)
+	return addr, err
+}
+
+// WalletNewHDAddress derives a new address from the HD seed of the wallet,
+// and returns the mnemonic of the seed if this call created it.
+func (c *Client) WalletNewHDAddress(ctx context.Context) (address.Address, string, error) {
+	var res jsonrpc.HDAddressResult
+	err := c.rpc.Call(ctx, "wallet.newHDAddress", &res)
+	return res.Address, res.Mnemonic, err
+}
+
+// WalletRecoverHD imports the HD seed of mnemonic and derives its first n
+// addresses.
+func (c *Client) WalletRecoverHD(ctx context.Context, mnemonic string, n uint32) ([]address.Address, error) {
+	var addrs []address.Address
+	err := c.rpc.Call(ctx, "wallet.recoverHD", &addrs, mnemonic, n)
+	return addrs, err
+}
+
+// MinerGetOwnerAddress returns the owner of the miner at minerAddr.
+func (c *Client) MinerGetOwnerAddress(ctx context.Context, minerAddr address.Address) (address.Address, error) {
+	var owner address.Address
+	err := c.rpc.Call(ctx, "miner.getOwner", &owner, minerAddr)
+	return owner, err
+}
+
+// MinerGetPeerID returns the peer id of the miner at minerAddr.
+func (c *Client) MinerGetPeerID(ctx context.Context, minerAddr address.Address) (peer.ID, error) {
+	var s string
+	if err := c.rpc.Call(ctx, "miner.getPeerID", &s, minerAddr); err != nil {
+		return "", err
+	}
+	return peer.IDB58Decode(s)
+}
+
+// MinerGetAsk returns the ask askID of the miner at minerAddr.
+func (c *Client) MinerGetAsk(ctx context.Context, minerAddr address.Address, askID uint64) (minerActor.Ask, error) {
+	var ask minerActor.Ask
+	err := c.rpc.Call(ctx, "miner.getAsk", &ask, minerAddr, askID)
+	return ask, err
+}
+
+// MinerGetPower returns the power of the miner at minerAddr and the total
+// power of the network, as decimal strings.
+func (c *Client) MinerGetPower(ctx context.Context, minerAddr address.Address) (*jsonrpc.MinerPowerResult, error) {
+	var power jsonrpc.MinerPowerResult
+	if err := c.rpc.Call(ctx, "miner.getPower", &power, minerAddr); err != nil {
+		return nil, err
+	}
+	return &power, nil
+}
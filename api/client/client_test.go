@@ -0,0 +1,90 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/core"
+	"github.com/filecoin-project/go-filecoin/fixtures"
+	"github.com/filecoin-project/go-filecoin/jsonrpc"
+	"github.com/filecoin-project/go-filecoin/logs"
+	th "github.com/filecoin-project/go-filecoin/testhelpers"
+	"github.com/filecoin-project/go-filecoin/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	d := th.NewDaemon(t, th.WithMiner(fixtures.TestMiners[0]), th.KeyFile(fixtures.KeyFilePaths()[0])).Start()
+	defer d.ShutdownSuccess()
+
+	c, err := NewFromRepo(d.RepoDir())
+	require.NoError(err)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	require.NoError(c.CheckVersion(ctx))
+
+	pid, err := c.NetworkGetPeerID(ctx)
+	require.NoError(err)
+	assert.Equal(d.GetID(), pid.Pretty())
+
+	head, err := c.ChainHead(ctx)
+	require.NoError(err)
+	assert.Equal(uint64(0), head.Height)
+
+	page, err := c.ChainLs(ctx, jsonrpc.ChainLsParams{Limit: 1})
+	require.NoError(err)
+	require.Len(page.TipSets, 1)
+	assert.True(page.TipSets[0][0].Parents.Empty())
+	assert.Equal("", page.Next)
+
+	_, err = c.ActorGet(ctx, address.NewForTestGetter()())
+	require.Error(err)
+	assert.Equal(jsonrpc.CodeNotFound, err.(*jsonrpc.Error).Code)
+
+	heads, err := c.ChainHeadEvents(ctx)
+	require.NoError(err)
+	pool, err := c.MessagePoolSubscribe(ctx)
+	require.NoError(err)
+	chainLogs, err := c.LogStream(ctx, logs.Filter{Subsystems: []string{"chain"}})
+	require.NoError(err)
+
+	from, err := address.NewFromString(fixtures.TestAddresses[0])
+	require.NoError(err)
+	to, err := c.WalletNewAddress(ctx)
+	require.NoError(err)
+	msgCid, err := c.MessageSend(ctx, jsonrpc.MessageParams{
+		From:     from,
+		To:       to,
+		Value:    types.NewAttoFILFromFIL(10),
+		GasPrice: *types.NewAttoFILFromFIL(1),
+		GasLimit: types.NewGasUnits(300),
+	})
+	require.NoError(err)
+
+	e := <-pool
+	assert.Equal(core.MessagePoolAdded, e.Type)
+	assert.True(msgCid.Equals(e.Cid))
+
+	d.RunSuccess("mining", "once")
+	assert.Equal(uint64(1), (<-heads).Height)
+	entry := <-chainLogs
+	assert.Equal("chain", entry.Subsystem)
+	assert.Contains(entry.Message, "at height 1")
+
+	res, err := c.MessageWait(ctx, msgCid)
+	require.NoError(err)
+	assert.Equal(uint8(0), res.Receipt.ExitCode)
+
+	balance, err := c.WalletBalance(ctx, to)
+	require.NoError(err)
+	assert.Equal(types.NewAttoFILFromFIL(10), balance)
+}
@@ -3,7 +3,10 @@ package api
 import (
 	"context"
 
+	cid "gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+
 	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/repo"
 )
 
 // Daemon is the interface that defines methods to change the state of the daemon.
@@ -14,6 +17,18 @@ type Daemon interface {
 	Stop(ctx context.Context) error
 	// Init, initializes everything needed to run a daemon, including the disk storage.
 	Init(ctx context.Context, opts ...DaemonInitOpt) error
+	// Backup writes a backup of the repo of the running daemon to the file
+	// at path, which is only replaced once the backup is complete.
+	Backup(ctx context.Context, path string) error
+	// CollectGarbage deletes the blocks no live root links to. If dryRun is
+	// set, they are only counted.
+	CollectGarbage(ctx context.Context, dryRun bool) (*repo.GCResult, error)
+	// Pin keeps the dag with the given root from being garbage collected.
+	Pin(ctx context.Context, c cid.Cid) error
+	// Unpin removes a pin added by Pin.
+	Unpin(ctx context.Context, c cid.Cid) error
+	// Pins lists the roots of the pinned dags.
+	Pins(ctx context.Context) ([]cid.Cid, error)
 }
 
 // DaemonInitConfig is a helper struct to configure the init process of a daemon.
@@ -24,17 +39,25 @@ type DaemonInitConfig struct {
 	RepoDir string
 	// PeerKeyFile is the path to a file containing a libp2p peer id key
 	PeerKeyFile string
+	// SwarmKeyFile is the path to a file containing the swarm key of a private network
+	SwarmKeyFile string
+	// GenSwarmKey, if set, generates the swarm key of a new private network.
+	GenSwarmKey bool
 	// WithMiner, if set, sets the config value for the local miner to this address.
 	WithMiner address.Address
-	// DevnetTest, if set, sets the config to enable bootstrapping to the test devnet.
-	DevnetTest bool
-	// DevnetNightly, if set, sets the config to enable bootstrapping to the nightly devnet.
-	DevnetNightly bool
-	// DevnetUser, if set, sets the config to enable bootstrapping to the user devnet.
-	DevnetUser bool
+	// Network is the name of the network the repo is initialized for, see
+	// package networks. Empty for the local network.
+	Network string
 	// AutoSealIntervalSeconds, when set, configures the daemon to check for and seal any staged sectors on an interval
 	AutoSealIntervalSeconds uint
 	DefaultAddress          address.Address
+	// DatastoreType, if set, is the type of the datastores of the repo.
+	DatastoreType string
+	// BlocksDatastoreType, if set, is the type of the datastore keeping the
+	// blocks of the repo.
+	BlocksDatastoreType string
+	// CompressDatastore, if set, compresses the values of the datastores.
+	CompressDatastore bool
 }
 
 // DaemonInitOpt is the signature a daemon init option has to fulfill.
@@ -61,31 +84,31 @@ func PeerKeyFile(p string) DaemonInitOpt {
 	}
 }
 
-// WithMiner sets the WithMiner option.
-func WithMiner(miner address.Address) DaemonInitOpt {
+// SwarmKeyFile defines the file to load the swarm key of a private network from
+func SwarmKeyFile(p string) DaemonInitOpt {
 	return func(dc *DaemonInitConfig) {
-		dc.WithMiner = miner
+		dc.SwarmKeyFile = p
 	}
 }
 
-// DevnetTest sets the DevnetTest option.
-func DevnetTest(doit bool) DaemonInitOpt {
+// GenSwarmKey sets the GenSwarmKey option.
+func GenSwarmKey(doit bool) DaemonInitOpt {
 	return func(dc *DaemonInitConfig) {
-		dc.DevnetTest = doit
+		dc.GenSwarmKey = doit
 	}
 }
 
-// DevnetNightly sets the DevnetNightly option.
-func DevnetNightly(doit bool) DaemonInitOpt {
+// WithMiner sets the WithMiner option.
+func WithMiner(miner address.Address) DaemonInitOpt {
 	return func(dc *DaemonInitConfig) {
-		dc.DevnetNightly = doit
+		dc.WithMiner = miner
 	}
 }
 
-// DevnetUser sets the DevnetUser option.
-func DevnetUser(doit bool) DaemonInitOpt {
+// Network sets the network the repo is initialized for.
+func Network(name string) DaemonInitOpt {
 	return func(dc *DaemonInitConfig) {
-		dc.DevnetUser = doit
+		dc.Network = name
 	}
 }
 
@@ -102,3 +125,25 @@ func DefaultAddress(address address.Address) DaemonInitOpt {
 		dc.DefaultAddress = address
 	}
 }
+
+// DatastoreType sets the type of the datastores of the repo.
+func DatastoreType(typ string) DaemonInitOpt {
+	return func(dc *DaemonInitConfig) {
+		dc.DatastoreType = typ
+	}
+}
+
+// BlocksDatastoreType sets the type of the datastore keeping the blocks of the
+// repo.
+func BlocksDatastoreType(typ string) DaemonInitOpt {
+	return func(dc *DaemonInitConfig) {
+		dc.BlocksDatastoreType = typ
+	}
+}
+
+// CompressDatastore sets the CompressDatastore option.
+func CompressDatastore(doit bool) DaemonInitOpt {
+	return func(dc *DaemonInitConfig) {
+		dc.CompressDatastore = doit
+	}
+}
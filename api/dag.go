@@ -1,9 +1,31 @@
 package api
 
-import "context"
+import (
+	"context"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+)
+
+// DagStat holds the statistics of a DAG object, and of the DAG it roots
+// when they are collected recursively.
+type DagStat struct {
+	Cid cid.Cid
+	// Codec is the codec of the object, e.g. dag-cbor.
+	Codec string
+	// Size is the size of the block of the object, in bytes.
+	Size     uint64
+	NumLinks int
+	// NumBlocks and TotalSize count the distinct blocks of the DAG, the
+	// object's included, when collected recursively.
+	NumBlocks int    `json:",omitempty"`
+	TotalSize uint64 `json:",omitempty"`
+}
 
 // Dag is the interface that defines methods to interact with IPLD DAG objects.
 type Dag interface {
 	// Get returns the associated DAG node for the passed in CID.
 	Get(ctx context.Context, ref string) (interface{}, error)
+	// Stat returns the statistics of the object of the passed in CID, and
+	// of the DAG it roots if recursive. Only the local blockstore is read.
+	Stat(ctx context.Context, ref string, recursive bool) (*DagStat, error)
 }
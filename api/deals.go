@@ -0,0 +1,17 @@
+package api
+
+import (
+	"context"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+
+	"github.com/filecoin-project/go-filecoin/protocol/storage"
+)
+
+// Deals is the interface that defines methods to inspect the storage deals a
+// node is party to, as client or miner.
+type Deals interface {
+	Show(ctx context.Context, proposalCid cid.Cid) (*storage.DealRecord, error)
+	PauseTransfer(ctx context.Context, proposalCid cid.Cid) error
+	ResumeTransfer(ctx context.Context, proposalCid cid.Cid) error
+}
@@ -7,8 +7,11 @@ import (
 
 	"github.com/filecoin-project/go-filecoin/actor"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/account"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/cron"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/miner"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/multisig"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/paymentbroker"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/reward"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/storagemarket"
 	"github.com/filecoin-project/go-filecoin/api"
 	"github.com/filecoin-project/go-filecoin/exec"
@@ -53,6 +56,12 @@ func ls(ctx context.Context, fcn *node.Node, actorGetter state.GetAllActorsFunc)
 			res[i] = makeActorView(a, addrs[i], &miner.Actor{})
 		case a.Code.Equals(types.BootstrapMinerActorCodeCid):
 			res[i] = makeActorView(a, addrs[i], &miner.Actor{})
+		case a.Code.Equals(types.MultisigActorCodeCid):
+			res[i] = makeActorView(a, addrs[i], &multisig.Actor{})
+		case a.Code.Equals(types.RewardActorCodeCid):
+			res[i] = makeActorView(a, addrs[i], &reward.Actor{})
+		case a.Code.Equals(types.CronActorCodeCid):
+			res[i] = makeActorView(a, addrs[i], &cron.Actor{})
 		default:
 			res[i] = makeActorView(a, addrs[i], nil)
 		}
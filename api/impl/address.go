@@ -2,7 +2,6 @@ package impl
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 
@@ -145,11 +144,11 @@ func parseKeyInfos(f files.File) ([]*types.KeyInfo, error) {
 			return nil, err
 		}
 
-		var ki types.KeyInfo
-		if err := json.NewDecoder(fi).Decode(&ki); err != nil {
+		kis, err := wallet.DecodeKeyFiles(fi)
+		if err != nil {
 			return nil, err
 		}
 
-		kinfos = append(kinfos, &ki)
+		kinfos = append(kinfos, kis...)
 	}
 }
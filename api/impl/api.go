@@ -14,9 +14,11 @@ type nodeAPI struct {
 
 	actor           *nodeActor
 	address         *nodeAddress
+	auth            *nodeAuth
 	client          *nodeClient
 	daemon          *nodeDaemon
 	dag             *nodeDag
+	deals           *nodeDeals
 	id              *nodeID
 	log             *nodeLog
 	miner           *nodeMiner
@@ -45,9 +47,11 @@ func New(node *node.Node) api.API {
 
 	api.actor = newNodeActor(api)
 	api.address = newNodeAddress(api)
+	api.auth = newNodeAuth(api)
 	api.client = newNodeClient(api)
 	api.daemon = newNodeDaemon(api)
 	api.dag = newNodeDag(api)
+	api.deals = newNodeDeals(api)
 	api.id = newNodeID(api)
 	api.log = newNodeLog(api)
 	api.miner = newNodeMiner(api, porcelainAPI)
@@ -70,6 +74,10 @@ func (api *nodeAPI) Address() api.Address {
 	return api.address
 }
 
+func (api *nodeAPI) Auth() api.Auth {
+	return api.auth
+}
+
 func (api *nodeAPI) Client() api.Client {
 	return api.client
 }
@@ -82,6 +90,10 @@ func (api *nodeAPI) Dag() api.Dag {
 	return api.dag
 }
 
+func (api *nodeAPI) Deals() api.Deals {
+	return api.deals
+}
+
 func (api *nodeAPI) ID() api.ID {
 	return api.id
 }
@@ -0,0 +1,35 @@
+package impl
+
+import (
+	"context"
+
+	"github.com/filecoin-project/go-filecoin/auth"
+)
+
+type nodeAuth struct {
+	api *nodeAPI
+}
+
+func newNodeAuth(api *nodeAPI) *nodeAuth {
+	return &nodeAuth{api: api}
+}
+
+// CreateToken returns a token granting perm, signed with the api secret of
+// the repo.
+func (na *nodeAuth) CreateToken(ctx context.Context, perm auth.Permission) (string, error) {
+	secret, err := na.api.node.Repo.APISecret()
+	if err != nil {
+		return "", err
+	}
+	return auth.CreateToken(secret, perm)
+}
+
+// Verify returns the permission a token grants, failing with
+// auth.ErrInvalidToken if it wasn't signed with the api secret of the repo.
+func (na *nodeAuth) Verify(ctx context.Context, token string) (auth.Permission, error) {
+	secret, err := na.api.node.Repo.APISecret()
+	if err != nil {
+		return "", err
+	}
+	return auth.VerifyToken(secret, token)
+}
@@ -2,8 +2,10 @@ package impl
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"math/big"
+	"time"
 
 	"github.com/filecoin-project/go-filecoin/api"
 	"github.com/filecoin-project/go-filecoin/plumbing/msg"
@@ -12,8 +14,10 @@ import (
 	uio "gx/ipfs/QmQXze9tG878pa4Euya4rrDpyTNX3kQe4dhCaBzBozGgpe/go-unixfs/io"
 	chunk "gx/ipfs/QmR4QQVkBZsZENRjYFVi8dEtPL3daZRNKk24m4r6WKJHNm/go-ipfs-chunker"
 	cid "gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	car "gx/ipfs/QmRa5sdhUGtLptMNYSHFWcU3axEJntpKht3LngrBpuurv1/go-car"
 	cbor "gx/ipfs/QmRoARq3nkUb13HSKZGepCZSWe5GrVPwx7xURJGZ7KWv9V/go-ipld-cbor"
 	dag "gx/ipfs/QmTQdH4848iTVCJmKXYyRiK72HufWTLYQQ8iN3JaQ8K1Hq/go-merkledag"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
 	ipld "gx/ipfs/QmcKKBwfz6FyQdHR2jsXrrF6XeSBXYL86anmWNewpFpoF5/go-ipld-format"
 
 	"github.com/filecoin-project/go-filecoin/actor"
@@ -35,36 +39,150 @@ func newNodeClient(api *nodeAPI) *nodeClient {
 
 var _ api.Client = &nodeClient{}
 
+// Cat reads the UnixFS file with the given cid. If the node does not hold the
+// file, it retrieves it from the miners storing it in a deal with the node.
 func (api *nodeClient) Cat(ctx context.Context, c cid.Cid) (uio.DagReader, error) {
-	// TODO: this goes back to 'how is data stored and referenced'
-	// For now, lets just do things the ipfs way.
+	ds, err := api.dagWithData(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ds.Get(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	return uio.NewDagReader(ctx, data, ds)
+}
+
+// Ls lists the links of the UnixFS node with the given cid, retrieving it
+// like Cat if the node does not hold it.
+func (api *nodeClient) Ls(ctx context.Context, c cid.Cid) ([]*ipld.Link, error) {
+	ds, err := api.dagWithData(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	nd, err := ds.Get(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	return nd.Links(), nil
+}
 
+// dagWithData returns the DAG service to read the data with the given cid
+// from. If the node does not hold the root of the data but has stored it in
+// deals, the data is retrieved from the miners and imported into the node.
+// Otherwise the blocks are fetched from the network, the ipfs way.
+func (api *nodeClient) dagWithData(ctx context.Context, c cid.Cid) (ipld.DAGService, error) {
 	nd := api.api.node
 	ds := dag.NewDAGService(nd.BlockService())
 
-	data, err := ds.Get(ctx, c)
+	has, err := nd.Blockstore.Has(c)
 	if err != nil {
 		return nil, err
 	}
+	if has || len(nd.StorageMinerClient.StoringMiners(c)) == 0 {
+		return ds, nil
+	}
 
-	return uio.NewDagReader(ctx, data, ds)
+	// the retrieved data is only linked once it is pinned
+	release := nd.HoldGC()
+	defer release()
+
+	r, err := api.api.RetrievalClient().RetrievePieceFromAny(ctx, c, nil, false)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to retrieve %s from the miners storing it", c)
+	}
+	defer r.Close() // nolint: errcheck
+
+	root, err := imp.BuildDagFromReader(ds, chunk.DefaultSplitter(r))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to import retrieved data of %s", c)
+	}
+	if !root.Cid().Equals(c) {
+		return nil, fmt.Errorf("retrieved data of %s imports as %s", c, root.Cid())
+	}
+	if err := nd.Pins.Add(c); err != nil {
+		return nil, err
+	}
+
+	return ds, nil
 }
 
+// ImportData imports data into the node and pins it, so garbage collection
+// keeps it.
 func (api *nodeClient) ImportData(ctx context.Context, data io.Reader) (ipld.Node, error) {
+	release := api.api.node.HoldGC()
+	defer release()
+
 	ds := dag.NewDAGService(api.api.node.BlockService())
 	spl := chunk.DefaultSplitter(data)
 
-	return imp.BuildDagFromReader(ds, spl)
+	root, err := imp.BuildDagFromReader(ds, spl)
+	if err != nil {
+		return nil, err
+	}
+	if err := api.api.node.Pins.Add(root.Cid()); err != nil {
+		return nil, err
+	}
+	return root, nil
 }
 
 func (api *nodeClient) ProposeStorageDeal(ctx context.Context, data cid.Cid, miner address.Address, askid uint64, duration uint64, allowDuplicates bool) (*storage.DealResponse, error) {
 	return api.api.node.StorageMinerClient.ProposeDeal(ctx, miner, data, askid, duration, allowDuplicates)
 }
 
+func (api *nodeClient) ProposeOfflineStorageDeal(ctx context.Context, data cid.Cid, miner address.Address, askid uint64, duration uint64, allowDuplicates bool) (*storage.DealResponse, error) {
+	return api.api.node.StorageMinerClient.ProposeOfflineDeal(ctx, miner, data, askid, duration, allowDuplicates)
+}
+
+// ProposeReplicatedStorageDeal stores data with several miners, the given one
+// and others selected by ask price and reputation.
+func (api *nodeClient) ProposeReplicatedStorageDeal(ctx context.Context, data cid.Cid, miner address.Address, askid uint64, duration uint64, replicas int, allowDuplicates bool) ([]*storage.DealResponse, error) {
+	return api.api.node.StorageMinerClient.ProposeReplicatedDeal(ctx, miner, data, askid, duration, replicas, allowDuplicates)
+}
+
+// StorageReplication returns the health of the replicas of data.
+func (api *nodeClient) StorageReplication(ctx context.Context, data cid.Cid) (*storage.ReplicationStatus, error) {
+	return api.api.node.StorageMinerClient.Replication(data)
+}
+
+// ProposeStorageDealToBestMiner proposes a deal storing data to the miners
+// ranked by SelectMiners, until one accepts it.
+func (api *nodeClient) ProposeStorageDealToBestMiner(ctx context.Context, data cid.Cid, duration uint64, allowDuplicates bool) (*storage.DealResponse, error) {
+	return api.api.node.StorageMinerClient.ProposeDealToBestMiner(ctx, data, duration, allowDuplicates)
+}
+
+// MinerReputations returns what the client observed of the miners it made
+// deals with.
+func (api *nodeClient) MinerReputations(ctx context.Context) ([]storage.MinerReputation, error) {
+	return api.api.node.StorageMinerClient.MinerReputations(), nil
+}
+
+// SelectMiners ranks the miners of connected peers with an ask for storing
+// data at a price of at most maxPrice, if not nil.
+func (api *nodeClient) SelectMiners(ctx context.Context, data cid.Cid, maxPrice *types.AttoFIL) ([]storage.MinerChoice, error) {
+	return api.api.node.StorageMinerClient.SelectMiners(ctx, data, maxPrice, nil)
+}
+
+// ExportCar writes the DAG rooted at data to w as a CAR file, e.g. to deliver
+// the data of offline deals to miners.
+func (api *nodeClient) ExportCar(ctx context.Context, data cid.Cid, w io.Writer) error {
+	dserv := dag.NewDAGService(api.api.node.BlockService())
+	return car.WriteCar(ctx, dserv, []cid.Cid{data}, w)
+}
+
 func (api *nodeClient) QueryStorageDeal(ctx context.Context, prop cid.Cid) (*storage.DealResponse, error) {
 	return api.api.node.StorageMinerClient.QueryDeal(ctx, prop)
 }
 
+// DealEvents streams the changes to the client's deals, until ctx is done.
+func (api *nodeClient) DealEvents(ctx context.Context) (<-chan storage.DealEvent, error) {
+	return api.api.node.StorageMinerClient.Subscribe(ctx), nil
+}
+
 func (api *nodeClient) ListAsks(ctx context.Context) (<-chan mapi.Ask, error) {
 	nd := api.api.node
 
@@ -106,12 +224,7 @@ func (api *nodeClient) ListAsks(ctx context.Context) (<-chan mapi.Ask, error) {
 					return err
 				}
 
-				out <- mapi.Ask{
-					Expiry: ask.Expiry,
-					ID:     ask.ID.Uint64(),
-					Price:  ask.Price,
-					Miner:  addr,
-				}
+				out <- askFromMinerAsk(addr, &ask)
 			}
 
 			return nil
@@ -126,6 +239,54 @@ func (api *nodeClient) ListAsks(ctx context.Context) (<-chan mapi.Ask, error) {
 	return out, nil
 }
 
+// askQueryTimeout bounds the time ListPeerAsks waits for each peer.
+const askQueryTimeout = 10 * time.Second
+
+// ListPeerAsks queries the connected peers for the asks of the miners they
+// operate. Peers that don't answer, e.g. because they don't mine, are skipped.
+func (api *nodeClient) ListPeerAsks(ctx context.Context) (<-chan mapi.Ask, error) {
+	nd := api.api.node
+	out := make(chan mapi.Ask)
+
+	go func() {
+		defer close(out)
+		for _, pid := range nd.Host().Network().Peers() {
+			qctx, cancel := context.WithTimeout(ctx, askQueryTimeout)
+			resp, err := nd.StorageMinerClient.QueryAsks(qctx, pid)
+			cancel()
+			if err != nil {
+				api.api.logger.Debugf("could not query asks of peer %s: %s", pid, err)
+				continue
+			}
+
+			for _, ask := range resp.Asks {
+				select {
+				case out <- askFromMinerAsk(resp.Miner, ask):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+func askFromMinerAsk(addr address.Address, ask *miner.Ask) mapi.Ask {
+	return mapi.Ask{
+		Expiry:       ask.Expiry,
+		ID:           ask.ID.Uint64(),
+		Price:        ask.Price,
+		Miner:        addr,
+		MinPieceSize: ask.MinPieceSize,
+		MaxPieceSize: ask.MaxPieceSize,
+	}
+}
+
+func (api *nodeClient) SetDealRenewal(ctx context.Context, prop cid.Cid, renew bool, maxPrice *types.AttoFIL) error {
+	return api.api.node.StorageMinerClient.SetDealRenewal(prop, renew, maxPrice)
+}
+
 func (api *nodeClient) Payments(ctx context.Context, dealCid cid.Cid) ([]*paymentbroker.PaymentVoucher, error) {
 	return api.api.node.StorageMinerClient.LoadVouchersForDeal(dealCid)
 }
@@ -20,7 +20,8 @@ import (
 	"github.com/filecoin-project/go-filecoin/api"
 	"github.com/filecoin-project/go-filecoin/config"
 	"github.com/filecoin-project/go-filecoin/consensus"
-	"github.com/filecoin-project/go-filecoin/fixtures"
+	"github.com/filecoin-project/go-filecoin/filnet"
+	"github.com/filecoin-project/go-filecoin/networks"
 	"github.com/filecoin-project/go-filecoin/node"
 	"github.com/filecoin-project/go-filecoin/repo"
 	"github.com/filecoin-project/go-filecoin/types"
@@ -51,6 +52,32 @@ func (nd *nodeDaemon) Stop(ctx context.Context) error {
 	return nil
 }
 
+// Backup writes a backup of the repo of the running daemon to the file at
+// path.
+func (nd *nodeDaemon) Backup(ctx context.Context, path string) error {
+	return repo.BackupToFile(nd.api.node.Repo, path)
+}
+
+// CollectGarbage deletes the blocks of the daemon no live root links to.
+func (nd *nodeDaemon) CollectGarbage(ctx context.Context, dryRun bool) (*repo.GCResult, error) {
+	return nd.api.node.CollectGarbage(ctx, dryRun)
+}
+
+// Pin keeps the dag with the given root from being garbage collected.
+func (nd *nodeDaemon) Pin(ctx context.Context, c cid.Cid) error {
+	return nd.api.node.Pins.Add(c)
+}
+
+// Unpin removes a pin added by Pin.
+func (nd *nodeDaemon) Unpin(ctx context.Context, c cid.Cid) error {
+	return nd.api.node.Pins.Remove(c)
+}
+
+// Pins lists the roots of the pinned dags.
+func (nd *nodeDaemon) Pins(ctx context.Context) ([]cid.Cid, error) {
+	return nd.api.node.Pins.List()
+}
+
 // Init, initializes everything needed to run a daemon, including the disk storage.
 func (nd *nodeDaemon) Init(ctx context.Context, opts ...api.DaemonInitOpt) error {
 	// load configuration options
@@ -59,7 +86,29 @@ func (nd *nodeDaemon) Init(ctx context.Context, opts ...api.DaemonInitOpt) error
 		o(cfg)
 	}
 
-	if err := repo.InitFSRepo(cfg.RepoDir, config.NewDefaultConfig()); err != nil {
+	networkName := cfg.Network
+	if networkName == "" {
+		networkName = networks.Local
+	}
+	network, err := networks.Get(networkName)
+	if err != nil {
+		return err
+	}
+
+	repoConfig := config.NewDefaultConfig()
+	network.Configure(repoConfig)
+	if cfg.DatastoreType != "" {
+		if err := repoConfig.Set("datastore.type", cfg.DatastoreType); err != nil {
+			return err
+		}
+	}
+	if cfg.BlocksDatastoreType != "" {
+		if err := repoConfig.Set("datastore.blocksType", cfg.BlocksDatastoreType); err != nil {
+			return err
+		}
+	}
+	repoConfig.Datastore.Compress = cfg.CompressDatastore
+	if err := repo.InitFSRepo(cfg.RepoDir, repoConfig); err != nil {
 		return err
 	}
 
@@ -89,65 +138,48 @@ func (nd *nodeDaemon) Init(ctx context.Context, opts ...api.DaemonInitOpt) error
 		initopts = append(initopts, node.PeerKeyOpt(peerKey))
 	}
 
-	initopts = append(initopts, node.AutoSealIntervalSecondsOpt(cfg.AutoSealIntervalSeconds))
-
-	if cfg.WithMiner != (address.Address{}) {
-		newConfig := rep.Config()
-		newConfig.Mining.MinerAddress = cfg.WithMiner
-		if err := rep.ReplaceConfig(newConfig); err != nil {
+	if cfg.SwarmKeyFile != "" && cfg.GenSwarmKey {
+		return fmt.Errorf(`cannot use both "--swarmkeyfile" and "--gen-swarm-key" options`)
+	}
+	if cfg.SwarmKeyFile != "" || cfg.GenSwarmKey {
+		swarmKey, err := loadSwarmKey(cfg)
+		if err != nil {
 			return err
 		}
-	}
-
-	if cfg.DefaultAddress != (address.Address{}) {
-		newConfig := rep.Config()
-		newConfig.Wallet.DefaultAddress = cfg.DefaultAddress
-		if err := rep.ReplaceConfig(newConfig); err != nil {
+		if err := rep.SetSwarmKey(swarmKey.Encode()); err != nil {
 			return err
 		}
 	}
 
-	if cfg.DevnetTest && cfg.DevnetNightly {
-		return fmt.Errorf(`cannot use both "--devnet-test" and "--devnet-nightly" options`)
-	}
+	initopts = append(initopts, node.AutoSealIntervalSecondsOpt(cfg.AutoSealIntervalSeconds))
 
-	// Setup devnet test specific config options.
-	if cfg.DevnetTest {
+	if cfg.WithMiner != (address.Address{}) {
 		newConfig := rep.Config()
-		newConfig.Bootstrap.Addresses = fixtures.DevnetTestBootstrapAddrs
-		newConfig.Bootstrap.MinPeerThreshold = 1
-		newConfig.Bootstrap.Period = "10s"
+		newConfig.Mining.MinerAddress = cfg.WithMiner
 		if err := rep.ReplaceConfig(newConfig); err != nil {
 			return err
 		}
 	}
 
-	// Setup devnet nightly specific config options.
-	if cfg.DevnetNightly {
+	if cfg.DefaultAddress != (address.Address{}) {
 		newConfig := rep.Config()
-		newConfig.Bootstrap.Addresses = fixtures.DevnetNightlyBootstrapAddrs
-		newConfig.Bootstrap.MinPeerThreshold = 1
-		newConfig.Bootstrap.Period = "10s"
+		newConfig.Wallet.DefaultAddress = cfg.DefaultAddress
 		if err := rep.ReplaceConfig(newConfig); err != nil {
 			return err
 		}
 	}
 
-	// Setup devnet user specific config options.
-	if cfg.DevnetUser {
-		newConfig := rep.Config()
-		newConfig.Bootstrap.Addresses = fixtures.DevnetUserBootstrapAddrs
-		newConfig.Bootstrap.MinPeerThreshold = 1
-		newConfig.Bootstrap.Period = "10s"
-		if err := rep.ReplaceConfig(newConfig); err != nil {
-			return err
-		}
+	// the genesis file option overrides the genesis block of the network,
+	// e.g. of a devnet redeployed since
+	genesisFile := cfg.GenesisFile
+	if genesisFile == "" {
+		genesisFile = network.GenesisFile
 	}
 
 	switch {
-	case cfg.GenesisFile != "":
+	case genesisFile != "":
 		// TODO: this feels a little wonky, I think the InitGenesis interface might need some tweaking
-		genCid, err := LoadGenesis(rep, cfg.GenesisFile)
+		genCid, err := LoadGenesis(rep, genesisFile)
 		if err != nil {
 			return err
 		}
@@ -176,6 +208,20 @@ func loadPeerKey(fname string) (crypto.PrivKey, error) {
 	return crypto.UnmarshalPrivateKey(data)
 }
 
+// loadSwarmKey loads the swarm key from the file of the config, or generates a
+// new one.
+func loadSwarmKey(cfg *api.DaemonInitConfig) (filnet.SwarmKey, error) {
+	if cfg.GenSwarmKey {
+		return filnet.NewSwarmKey()
+	}
+
+	data, err := ioutil.ReadFile(cfg.SwarmKeyFile)
+	if err != nil {
+		return filnet.SwarmKey{}, err
+	}
+	return filnet.DecodeSwarmKey(data)
+}
+
 // LoadGenesis gets the genesis block from either a local car file or an HTTP(S) URL.
 func LoadGenesis(rep repo.Repo, sourceName string) (cid.Cid, error) {
 	var source io.ReadCloser
@@ -5,7 +5,14 @@ import (
 
 	path "gx/ipfs/QmNYPETsdAu2uQ1k9q9S1jYEGURaLHV6cbYRSVFVRftpF8/go-path"
 	resolver "gx/ipfs/QmNYPETsdAu2uQ1k9q9S1jYEGURaLHV6cbYRSVFVRftpF8/go-path/resolver"
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	cbor "gx/ipfs/QmRoARq3nkUb13HSKZGepCZSWe5GrVPwx7xURJGZ7KWv9V/go-ipld-cbor"
 	dag "gx/ipfs/QmTQdH4848iTVCJmKXYyRiK72HufWTLYQQ8iN3JaQ8K1Hq/go-merkledag"
+	bserv "gx/ipfs/QmYPZzd9VqmJDwxUnThfeSbV1Y5o53aVPDijTB7j7rS9Ep/go-blockservice"
+	offline "gx/ipfs/QmYZwey1thDTynSrvd6qQkX24UpTka6TFhQ2v569UpoqxD/go-ipfs-exchange-offline"
+	ipld "gx/ipfs/QmcKKBwfz6FyQdHR2jsXrrF6XeSBXYL86anmWNewpFpoF5/go-ipld-format"
+
+	"github.com/filecoin-project/go-filecoin/api"
 )
 
 type nodeDag struct {
@@ -36,7 +43,7 @@ func (api *nodeDag) Get(ctx context.Context, ref string) (interface{}, error) {
 		return nil, err
 	}
 
-	var out interface{} = obj
+	var out interface{} = renderNode(obj)
 	if len(rem) > 0 {
 		final, _, err := obj.Resolve(rem)
 		if err != nil {
@@ -47,3 +54,74 @@ func (api *nodeDag) Get(ctx context.Context, ref string) (interface{}, error) {
 
 	return out, nil
 }
+
+// renderNode returns n as a value encoding to JSON. CBOR objects, e.g.
+// blocks, messages and the nodes of the state, encode to the JSON of their
+// content, the others to their raw data and links.
+func renderNode(n ipld.Node) interface{} {
+	if _, ok := n.(*cbor.Node); ok {
+		return n
+	}
+	return map[string]interface{}{
+		"Cid":   n.Cid(),
+		"Data":  n.RawData(),
+		"Links": n.Links(),
+	}
+}
+
+// Stat returns the statistics of the object of the passed in CID, and of
+// the DAG it roots if recursive. Only the local blockstore is read.
+func (nd *nodeDag) Stat(ctx context.Context, ref string, recursive bool) (*api.DagStat, error) {
+	parsedRef, err := path.ParsePath(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	bs := nd.api.node.Blockstore
+	dserv := dag.NewDAGService(bserv.New(bs, offline.Exchange(bs)))
+	objc, _, err := resolver.NewBasicResolver(dserv).ResolveToLastNode(ctx, parsedRef)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := dserv.Get(ctx, objc)
+	if err != nil {
+		return nil, err
+	}
+
+	stat := &api.DagStat{
+		Cid:      obj.Cid(),
+		Codec:    cid.CodecToStr[obj.Cid().Type()],
+		Size:     uint64(len(obj.RawData())),
+		NumLinks: len(obj.Links()),
+	}
+	if !recursive {
+		return stat, nil
+	}
+
+	seen := cid.NewSet()
+	var walk func(n ipld.Node) error
+	walk = func(n ipld.Node) error {
+		if !seen.Visit(n.Cid()) {
+			return nil
+		}
+		stat.NumBlocks++
+		stat.TotalSize += uint64(len(n.RawData()))
+		for _, l := range n.Links() {
+			if seen.Has(l.Cid) {
+				continue
+			}
+			child, err := dserv.Get(ctx, l.Cid)
+			if err != nil {
+				return err
+			}
+			if err := walk(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(obj); err != nil {
+		return nil, err
+	}
+	return stat, nil
+}
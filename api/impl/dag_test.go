@@ -6,9 +6,11 @@ import (
 	"testing"
 	"time"
 
+	cbor "gx/ipfs/QmRoARq3nkUb13HSKZGepCZSWe5GrVPwx7xURJGZ7KWv9V/go-ipld-cbor"
 	"gx/ipfs/QmcKKBwfz6FyQdHR2jsXrrF6XeSBXYL86anmWNewpFpoF5/go-ipld-format"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/filecoin-project/go-filecoin/node"
 	"github.com/filecoin-project/go-filecoin/types"
@@ -62,3 +64,48 @@ func TestDagGet(t *testing.T) {
 		assert.Equal(ipldnode.Cid().String(), nodeBack.Cid().String())
 	})
 }
+
+func TestDagStat(t *testing.T) {
+	t.Parallel()
+
+	t.Run("object and its DAG", func(t *testing.T) {
+		t.Parallel()
+		assert := assert.New(t)
+		require := require.New(t)
+		ctx := context.Background()
+		n := node.MakeOfflineNode(t)
+		api := New(n)
+
+		child, err := cbor.WrapObject(map[string]interface{}{"value": 1}, types.DefaultHashFunction, -1)
+		require.NoError(err)
+		root, err := cbor.WrapObject(map[string]interface{}{"a": child.Cid(), "b": child.Cid()}, types.DefaultHashFunction, -1)
+		require.NoError(err)
+		require.NoError(n.BlockService().AddBlock(child))
+		require.NoError(n.BlockService().AddBlock(root))
+
+		stat, err := api.Dag().Stat(ctx, root.Cid().String(), false)
+		require.NoError(err)
+		assert.Equal(root.Cid(), stat.Cid)
+		assert.Equal("cbor", stat.Codec)
+		assert.Equal(uint64(len(root.RawData())), stat.Size)
+		assert.Equal(2, stat.NumLinks)
+		assert.Equal(0, stat.NumBlocks)
+
+		// the child is linked twice but counted once
+		stat, err = api.Dag().Stat(ctx, root.Cid().String(), true)
+		require.NoError(err)
+		assert.Equal(2, stat.NumBlocks)
+		assert.Equal(uint64(len(root.RawData())+len(child.RawData())), stat.TotalSize)
+	})
+
+	t.Run("object missing from the blockstore", func(t *testing.T) {
+		t.Parallel()
+		assert := assert.New(t)
+		ctx := context.Background()
+		n := node.MakeOfflineNode(t)
+		api := New(n)
+
+		_, err := api.Dag().Stat(ctx, types.SomeCid().String(), false)
+		assert.Error(err)
+	})
+}
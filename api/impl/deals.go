@@ -0,0 +1,53 @@
+package impl
+
+import (
+	"context"
+	"fmt"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+
+	"github.com/filecoin-project/go-filecoin/protocol/storage"
+)
+
+type nodeDeals struct {
+	api *nodeAPI
+}
+
+func newNodeDeals(api *nodeAPI) *nodeDeals {
+	return &nodeDeals{api: api}
+}
+
+// Show returns the record of the deal with the given proposal cid, looking
+// at the deals the node made as client first.
+func (nd *nodeDeals) Show(ctx context.Context, proposalCid cid.Cid) (*storage.DealRecord, error) {
+	node := nd.api.node
+	if node.StorageMinerClient != nil {
+		if record, err := node.StorageMinerClient.Deal(proposalCid); err == nil {
+			return record, nil
+		}
+	}
+	if node.StorageMiner != nil {
+		if record, err := node.StorageMiner.Deal(proposalCid); err == nil {
+			return record, nil
+		}
+	}
+	return nil, fmt.Errorf("no such deal: %s", proposalCid)
+}
+
+// PauseTransfer pauses the transfer of the data of the deal with the given
+// proposal cid to the node's miner.
+func (nd *nodeDeals) PauseTransfer(ctx context.Context, proposalCid cid.Cid) error {
+	if nd.api.node.StorageMiner == nil {
+		return ErrNodeNotMiner
+	}
+	return nd.api.node.StorageMiner.Transfers().Pause(proposalCid)
+}
+
+// ResumeTransfer resumes the paused transfer of the data of the deal with the
+// given proposal cid to the node's miner.
+func (nd *nodeDeals) ResumeTransfer(ctx context.Context, proposalCid cid.Cid) error {
+	if nd.api.node.StorageMiner == nil {
+		return ErrNodeNotMiner
+	}
+	return nd.api.node.StorageMiner.Transfers().Resume(proposalCid)
+}
@@ -13,4 +13,7 @@ var (
 	ErrCannotPingSelf = errors.New("cannot ping self")
 	// ErrNodeOffline indicates that the node must not be offline for the operation performed.
 	ErrNodeOffline = errors.New("node must be online")
+	// ErrNodeNotMiner indicates that the node must run a storage miner for
+	// the operation performed.
+	ErrNodeNotMiner = errors.New("node is not a storage miner")
 )
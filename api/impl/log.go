@@ -2,9 +2,22 @@ package impl
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"sync"
+	"time"
 
 	writer "gx/ipfs/QmcuXC5cxs79ro2cUuHs4HQ2bkDLJUYokwL8aivcX6HW3C/go-log/writer"
+
+	"github.com/filecoin-project/go-filecoin/logs"
+	"github.com/filecoin-project/go-filecoin/protocol/storage"
+)
+
+// The subsystems of the events streamed with the log records.
+const (
+	chainSubsystem   = "chain"
+	dealsSubsystem   = "deals"
+	sectorsSubsystem = "sectors"
 )
 
 type nodeLog struct {
@@ -26,3 +39,98 @@ func (api *nodeLog) Tail(ctx context.Context) io.Reader {
 
 	return r
 }
+
+func (api *nodeLog) Stream(ctx context.Context, filter logs.Filter) (<-chan logs.Entry, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	nd := api.api.node
+	out := make(chan logs.Entry)
+	var wg sync.WaitGroup
+
+	send := func(e logs.Entry) {
+		if !filter.Match(e) {
+			return
+		}
+		select {
+		case out <- e:
+		case <-ctx.Done():
+		}
+	}
+	// wants returns whether the events of subsystem may pass the filter, so
+	// that the streams of the others aren't subscribed to.
+	wants := func(subsystem string) bool {
+		return filter.Match(logs.Entry{Subsystem: subsystem, Level: "critical"})
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for e := range logs.Subscribe(ctx, filter) {
+			send(e)
+		}
+	}()
+
+	if wants(chainSubsystem) {
+		heads := nd.PorcelainAPI.ChainHeadEvents(ctx)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ts := range heads {
+				h, err := ts.Height()
+				if err != nil {
+					continue
+				}
+				send(logs.Entry{
+					Time:      time.Now(),
+					Subsystem: chainSubsystem,
+					Level:     "info",
+					Message:   fmt.Sprintf("new head %s at height %d", ts.String(), h),
+					Event:     ts.ToSortedCidSet(),
+				})
+			}
+		}()
+	}
+
+	if wants(dealsSubsystem) && nd.StorageMinerClient != nil {
+		deals := nd.StorageMinerClient.Subscribe(ctx)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range deals {
+				level := "info"
+				if e.Type == storage.DealFailed || e.Type == storage.DealRejected {
+					level = "warning"
+				}
+				msg := fmt.Sprintf("deal %s with miner %s: %s", e.ProposalCid, e.Miner, e.Type)
+				if e.Message != "" {
+					msg += ": " + e.Message
+				}
+				send(logs.Entry{Time: time.Now(), Subsystem: dealsSubsystem, Level: level, Message: msg, Event: e})
+			}
+		}()
+	}
+
+	if wants(sectorsSubsystem) {
+		sectors := nd.PorcelainAPI.SectorEvents(ctx)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range sectors {
+				level := "info"
+				msg := fmt.Sprintf("sector %d: %s -> %s", e.SectorID, e.From, e.To)
+				if e.Error != "" {
+					level = "error"
+					msg += ": " + e.Error
+				}
+				send(logs.Entry{Time: e.Time, Subsystem: sectorsSubsystem, Level: level, Message: msg, Event: e})
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out, nil
+}
@@ -2,6 +2,7 @@ package impl
 
 import (
 	"context"
+	"io"
 	"math/big"
 
 	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/porcelain"
+	"github.com/filecoin-project/go-filecoin/protocol/storage"
 	"github.com/filecoin-project/go-filecoin/types"
 )
 
@@ -129,3 +131,89 @@ func (nm *nodeMiner) GetTotalPower(ctx context.Context) (*big.Int, error) {
 
 	return power, nil
 }
+
+// ImportDealData imports the data of an offline deal of the node's miner from
+// the CAR file read from r.
+func (nm *nodeMiner) ImportDealData(ctx context.Context, proposalCid cid.Cid, r io.Reader) error {
+	if nm.api.node.StorageMiner == nil {
+		return ErrNodeNotMiner
+	}
+	return nm.api.node.StorageMiner.ImportDealData(ctx, proposalCid, r)
+}
+
+// CheckSectors checks the sealed sectors of the node's miner against their
+// commitments on chain, flagging missing and corrupt ones.
+func (nm *nodeMiner) CheckSectors(ctx context.Context) ([]storage.SectorCheck, error) {
+	if nm.api.node.StorageMiner == nil {
+		return nil, ErrNodeNotMiner
+	}
+	return nm.api.node.StorageMiner.CheckSectors(ctx)
+}
+
+// FlaggedSectors returns the sectors of the node's miner flagged missing or
+// corrupt by CheckSectors.
+func (nm *nodeMiner) FlaggedSectors(ctx context.Context) ([]storage.SectorCheck, error) {
+	if nm.api.node.StorageMiner == nil {
+		return nil, ErrNodeNotMiner
+	}
+	return nm.api.node.StorageMiner.FlaggedSectors(), nil
+}
+
+// ScrubSectors scrubs the sealed sectors of the node's miner for corruption.
+func (nm *nodeMiner) ScrubSectors(ctx context.Context) (*storage.ScrubResult, error) {
+	if nm.api.node.StorageMiner == nil {
+		return nil, ErrNodeNotMiner
+	}
+	return nm.api.node.StorageMiner.Scrub(ctx)
+}
+
+// ScrubStatus returns the status of the scrubs of the sealed sectors of the
+// node's miner.
+func (nm *nodeMiner) ScrubStatus(ctx context.Context) (*storage.ScrubStatus, error) {
+	if nm.api.node.StorageMiner == nil {
+		return nil, ErrNodeNotMiner
+	}
+	status := nm.api.node.StorageMiner.ScrubStatus()
+	return &status, nil
+}
+
+// ExportSector writes a sealed sector of the node's miner to w as a CAR file.
+func (nm *nodeMiner) ExportSector(ctx context.Context, sectorID uint64, w io.Writer) error {
+	if nm.api.node.StorageMiner == nil {
+		return ErrNodeNotMiner
+	}
+	return nm.api.node.StorageMiner.ExportSector(ctx, sectorID, w)
+}
+
+// ImportSector imports a sector of the node's miner from the CAR file read
+// from r, returning the id of the sector.
+func (nm *nodeMiner) ImportSector(ctx context.Context, r io.Reader) (uint64, error) {
+	if nm.api.node.StorageMiner == nil {
+		return 0, ErrNodeNotMiner
+	}
+
+	// no live root links to the imported pieces, which are only needed
+	// until they are sealed again
+	release := nm.api.node.HoldGC()
+	defer release()
+	return nm.api.node.StorageMiner.ImportSector(ctx, r)
+}
+
+// PoStStatus returns the state of the PoSt of the current proving period of
+// the node's miner, and the alerts of recent proving periods.
+func (nm *nodeMiner) PoStStatus(ctx context.Context) (*storage.ProverStatus, error) {
+	if nm.api.node.StorageMiner == nil {
+		return nil, ErrNodeNotMiner
+	}
+	status := nm.api.node.StorageMiner.Prover().Status()
+	return &status, nil
+}
+
+// PledgeSectors fills count sectors of the node's miner with self-generated
+// data to be sealed and committed, returning the ids of the sectors.
+func (nm *nodeMiner) PledgeSectors(ctx context.Context, count uint64) ([]uint64, error) {
+	if nm.api.node.StorageMiner == nil {
+		return nil, ErrNodeNotMiner
+	}
+	return nm.api.node.StorageMiner.PledgeSectors(ctx, count)
+}
@@ -2,11 +2,16 @@ package impl
 
 import (
 	"context"
+
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+
 	"github.com/filecoin-project/go-filecoin/actor/builtin"
 	"github.com/filecoin-project/go-filecoin/chain"
 	"github.com/filecoin-project/go-filecoin/consensus"
 	"github.com/filecoin-project/go-filecoin/mining"
 	"github.com/filecoin-project/go-filecoin/node"
+	"github.com/filecoin-project/go-filecoin/proofs/sectorbuilder"
+	"github.com/filecoin-project/go-filecoin/protocol/sealing"
 	"github.com/filecoin-project/go-filecoin/state"
 	"github.com/filecoin-project/go-filecoin/types"
 )
@@ -82,3 +87,31 @@ func (api *nodeMining) Stop(ctx context.Context) error {
 	api.api.node.StopMining(ctx)
 	return nil
 }
+
+// SealingJobs returns the queued and running jobs filling, sealing and
+// committing sectors.
+func (api *nodeMining) SealingJobs(ctx context.Context) ([]sectorbuilder.Job, error) {
+	return api.api.node.SealingScheduler().Jobs(), nil
+}
+
+// SealingWorkers returns the remote workers registered to seal sectors for
+// the miner.
+func (api *nodeMining) SealingWorkers(ctx context.Context) ([]sealing.WorkerInfo, error) {
+	master := api.api.node.SealingMaster()
+	if master == nil {
+		return nil, errors.New("no sealing workers configured in mining.sealing.workers")
+	}
+	return master.Workers(), nil
+}
+
+// StartSealingWorker has the node seal sectors for the master at the given
+// multiaddr.
+func (api *nodeMining) StartSealingWorker(ctx context.Context, master string, capacity uint64) error {
+	return api.api.node.StartSealingWorker(ctx, master, capacity)
+}
+
+// StoragePaths reports the utilization of the storage paths of the sector
+// builder.
+func (api *nodeMining) StoragePaths(ctx context.Context) ([]sectorbuilder.PathUsage, error) {
+	return api.api.node.StoragePathUsage()
+}
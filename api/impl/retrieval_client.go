@@ -2,11 +2,14 @@ package impl
 
 import (
 	"context"
+	"fmt"
 	"io"
 
 	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	"gx/ipfs/QmY5Grm8pJdiSSVsYxx4uNRgweY72EmYwuSDbRnbFok3iY/go-libp2p-peer"
 
 	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/protocol/retrieval"
 )
 
 type nodeRetrievalClient struct {
@@ -17,6 +20,16 @@ func newNodeRetrievalClient(api *nodeAPI) *nodeRetrievalClient {
 	return &nodeRetrievalClient{api: api}
 }
 
+// QueryPiece asks a miner for the size and price of a piece it stores.
+func (nrc *nodeRetrievalClient) QueryPiece(ctx context.Context, pieceCID cid.Cid, minerAddr address.Address) (*retrieval.QueryResponse, error) {
+	minerPeerID, err := nrc.api.node.Lookup().GetPeerIDByMinerAddress(ctx, minerAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	return nrc.api.node.RetrievalClient.QueryPiece(ctx, minerPeerID, pieceCID)
+}
+
 func (nrc *nodeRetrievalClient) RetrievePiece(ctx context.Context, pieceCID cid.Cid, minerAddr address.Address) (io.ReadCloser, error) {
 	minerPeerID, err := nrc.api.node.Lookup().GetPeerIDByMinerAddress(ctx, minerAddr)
 	if err != nil {
@@ -25,3 +38,41 @@ func (nrc *nodeRetrievalClient) RetrievePiece(ctx context.Context, pieceCID cid.
 
 	return nrc.api.node.RetrievalClient.RetrievePiece(ctx, minerPeerID, pieceCID)
 }
+
+// RetrievePieceRange reads length bytes of a piece from a miner, starting at
+// offset, or the rest of the piece if length is zero.
+func (nrc *nodeRetrievalClient) RetrievePieceRange(ctx context.Context, pieceCID cid.Cid, minerAddr address.Address, offset, length uint64) (io.ReadCloser, error) {
+	minerPeerID, err := nrc.api.node.Lookup().GetPeerIDByMinerAddress(ctx, minerAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	return nrc.api.node.RetrievalClient.RetrievePieceRange(ctx, minerPeerID, pieceCID, offset, length)
+}
+
+// RetrievePieceFromAny reads a piece from the best of the given miners, or of
+// the miners storing the piece in a deal with the node if none are given,
+// falling back to the others on failure. With stripe, it reads ranges of the
+// piece from several miners in parallel.
+func (nrc *nodeRetrievalClient) RetrievePieceFromAny(ctx context.Context, pieceCID cid.Cid, minerAddrs []address.Address, stripe bool) (io.ReadCloser, error) {
+	if len(minerAddrs) == 0 {
+		minerAddrs = nrc.api.node.StorageMinerClient.StoringMiners(pieceCID)
+		if len(minerAddrs) == 0 {
+			return nil, fmt.Errorf("no miner stores piece %s in a deal with this node", pieceCID)
+		}
+	}
+
+	var providers []peer.ID
+	for _, minerAddr := range minerAddrs {
+		minerPeerID, err := nrc.api.node.Lookup().GetPeerIDByMinerAddress(ctx, minerAddr)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, minerPeerID)
+	}
+
+	if stripe {
+		return nrc.api.node.RetrievalClient.RetrievePieceStriped(ctx, providers, pieceCID)
+	}
+	return nrc.api.node.RetrievalClient.RetrievePieceFromProviders(ctx, providers, pieceCID)
+}
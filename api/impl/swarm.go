@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"time"
 
 	"gx/ipfs/QmPiemjiKBC9VA7vZF82m4x1oygtg2c2YVqag8PX7dN1BD/go-libp2p-peerstore"
 	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	autonat "gx/ipfs/QmXmZtMdQokSodDNvPdhDyaVRAjgybvR8dQtuMsNoWv4Lq/go-libp2p-autonat"
 	peer "gx/ipfs/QmY5Grm8pJdiSSVsYxx4uNRgweY72EmYwuSDbRnbFok3iY/go-libp2p-peer"
 	swarm "gx/ipfs/QmegQFxhr1J6yZ1vDQuDmJi5jntmj6BL96S11HVtXNCaHb/go-libp2p-swarm"
 
@@ -104,3 +106,70 @@ func (ns *nodeSwarm) Connect(ctx context.Context, addrs []string) ([]api.SwarmCo
 func (ns *nodeSwarm) FindPeer(ctx context.Context, peerID peer.ID) (peerstore.PeerInfo, error) {
 	return ns.api.node.Router.FindPeer(ctx, peerID)
 }
+
+// Ban bans the peer for d, or for good if d is 0, closing its connections.
+func (ns *nodeSwarm) Ban(ctx context.Context, peerID peer.ID, d time.Duration, reason string) error {
+	return ns.api.node.PeerScores.Ban(peerID, d, reason)
+}
+
+// Unban lifts the ban of the peer.
+func (ns *nodeSwarm) Unban(ctx context.Context, peerID peer.ID) error {
+	return ns.api.node.PeerScores.Unban(peerID)
+}
+
+// Bans lists the peers banned.
+func (ns *nodeSwarm) Bans(ctx context.Context) ([]filnet.Ban, error) {
+	return ns.api.node.PeerScores.Bans(), nil
+}
+
+// Scores lists the scores of the peers which misbehaved, worst first.
+func (ns *nodeSwarm) Scores(ctx context.Context) ([]filnet.PeerScore, error) {
+	return ns.api.node.PeerScores.Scores(), nil
+}
+
+// NAT returns whether the node is reachable from the internet, found out
+// by AutoNAT, and the addresses it advertises.
+func (ns *nodeSwarm) NAT(ctx context.Context) (*api.SwarmNATInfo, error) {
+	nd := ns.api.node
+	if nd.NAT == nil {
+		return nil, ErrNodeOffline
+	}
+
+	info := &api.SwarmNATInfo{Reachability: "unknown"}
+	switch nd.NAT.Status() {
+	case autonat.NATStatusPublic:
+		info.Reachability = "public"
+		if addr, err := nd.NAT.PublicAddr(); err == nil {
+			info.PublicAddr = addr.String()
+		}
+	case autonat.NATStatusPrivate:
+		info.Reachability = "private"
+	}
+	for _, a := range nd.Host().Addrs() {
+		info.Addrs = append(info.Addrs, a.String())
+	}
+	return info, nil
+}
+
+// Sessions returns the stats of the bitswap sessions of the node, the open
+// ones first.
+func (ns *nodeSwarm) Sessions(ctx context.Context) ([]filnet.SessionStats, error) {
+	return ns.api.node.FetchSessions().Stats(), nil
+}
+
+// Bandwidth returns the bandwidth of the node, by protocol and by peer.
+func (ns *nodeSwarm) Bandwidth(ctx context.Context) (*filnet.BandwidthReport, error) {
+	return ns.api.node.Bandwidth.Report(), nil
+}
+
+// Peering returns whether the node is connected to each of the peers it
+// peers with.
+func (ns *nodeSwarm) Peering(ctx context.Context) ([]filnet.PeeringStatus, error) {
+	return ns.api.node.Peering.Status(), nil
+}
+
+// Seen returns how many of the blocks and messages gossiped on each topic
+// were copies of ones seen recently.
+func (ns *nodeSwarm) Seen(ctx context.Context) ([]filnet.SeenCacheStats, error) {
+	return ns.api.node.SeenCache.Stats(), nil
+}
@@ -16,6 +16,7 @@ func newNodeVersion(api *nodeAPI) *nodeVersion {
 // Full, returns all version information that is available.
 func (a *nodeVersion) Full() (*api.VersionInfo, error) {
 	return &api.VersionInfo{
-		Commit: flags.Commit,
+		Commit:     flags.Commit,
+		APIVersion: api.APIVersion,
 	}, nil
 }
@@ -3,9 +3,16 @@ package api
 import (
 	"context"
 	"io"
+
+	"github.com/filecoin-project/go-filecoin/logs"
 )
 
 // Log is the interface that defines methods to interact with the event log output of the daemon.
 type Log interface {
 	Tail(ctx context.Context) io.Reader
+	// Stream streams the log records of the daemon and the events of its
+	// subsystems, the new heads of the chain, the changes to the deals of
+	// the storage client and the changes of stage of sectors, which pass
+	// filter, until ctx is done.
+	Stream(ctx context.Context, filter logs.Filter) (<-chan logs.Entry, error)
 }
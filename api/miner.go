@@ -2,12 +2,14 @@ package api
 
 import (
 	"context"
+	"io"
 	"math/big"
 
 	cid "gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
 	"gx/ipfs/QmY5Grm8pJdiSSVsYxx4uNRgweY72EmYwuSDbRnbFok3iY/go-libp2p-peer"
 
 	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/protocol/storage"
 	"github.com/filecoin-project/go-filecoin/types"
 )
 
@@ -20,4 +22,13 @@ type Miner interface {
 	GetPledge(ctx context.Context, minerAddr address.Address) (*big.Int, error)
 	GetPower(ctx context.Context, minerAddr address.Address) (*big.Int, error)
 	GetTotalPower(ctx context.Context) (*big.Int, error)
+	ImportDealData(ctx context.Context, proposalCid cid.Cid, r io.Reader) error
+	CheckSectors(ctx context.Context) ([]storage.SectorCheck, error)
+	FlaggedSectors(ctx context.Context) ([]storage.SectorCheck, error)
+	ScrubSectors(ctx context.Context) (*storage.ScrubResult, error)
+	ScrubStatus(ctx context.Context) (*storage.ScrubStatus, error)
+	ExportSector(ctx context.Context, sectorID uint64, w io.Writer) error
+	ImportSector(ctx context.Context, r io.Reader) (uint64, error)
+	PoStStatus(ctx context.Context) (*storage.ProverStatus, error)
+	PledgeSectors(ctx context.Context, count uint64) ([]uint64, error)
 }
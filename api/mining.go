@@ -3,6 +3,8 @@ package api
 import (
 	"context"
 
+	"github.com/filecoin-project/go-filecoin/proofs/sectorbuilder"
+	"github.com/filecoin-project/go-filecoin/protocol/sealing"
 	"github.com/filecoin-project/go-filecoin/types"
 )
 
@@ -11,4 +13,8 @@ type Mining interface {
 	Once(ctx context.Context) (*types.Block, error)
 	Start(ctx context.Context) error
 	Stop(ctx context.Context) error
+	SealingJobs(ctx context.Context) ([]sectorbuilder.Job, error)
+	SealingWorkers(ctx context.Context) ([]sealing.WorkerInfo, error)
+	StartSealingWorker(ctx context.Context, master string, capacity uint64) error
+	StoragePaths(ctx context.Context) ([]sectorbuilder.PathUsage, error)
 }
@@ -7,9 +7,13 @@ import (
 	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
 
 	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/protocol/retrieval"
 )
 
 // RetrievalClient is the interface that defines methods to manage retrieval client operations.
 type RetrievalClient interface {
+	QueryPiece(ctx context.Context, pieceCID cid.Cid, minerAddr address.Address) (*retrieval.QueryResponse, error)
 	RetrievePiece(ctx context.Context, pieceCID cid.Cid, minerAddr address.Address) (io.ReadCloser, error)
+	RetrievePieceRange(ctx context.Context, pieceCID cid.Cid, minerAddr address.Address, offset, length uint64) (io.ReadCloser, error)
+	RetrievePieceFromAny(ctx context.Context, pieceCID cid.Cid, minerAddrs []address.Address, stripe bool) (io.ReadCloser, error)
 }
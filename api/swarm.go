@@ -2,8 +2,12 @@ package api
 
 import (
 	"context"
+	"time"
+
 	peerstore "gx/ipfs/QmPiemjiKBC9VA7vZF82m4x1oygtg2c2YVqag8PX7dN1BD/go-libp2p-peerstore"
 	peer "gx/ipfs/QmY5Grm8pJdiSSVsYxx4uNRgweY72EmYwuSDbRnbFok3iY/go-libp2p-peer"
+
+	"github.com/filecoin-project/go-filecoin/filnet"
 )
 
 // Swarm is the interface that defines methods to interact with the p2p swarm of the node.
@@ -11,6 +15,27 @@ type Swarm interface {
 	Peers(ctx context.Context, verbose, latency, streams bool) (*SwarmConnInfos, error)
 	Connect(ctx context.Context, addrs []string) ([]SwarmConnectResult, error)
 	FindPeer(ctx context.Context, peerID peer.ID) (peerstore.PeerInfo, error)
+	Ban(ctx context.Context, peerID peer.ID, d time.Duration, reason string) error
+	Unban(ctx context.Context, peerID peer.ID) error
+	Bans(ctx context.Context) ([]filnet.Ban, error)
+	Scores(ctx context.Context) ([]filnet.PeerScore, error)
+	NAT(ctx context.Context) (*SwarmNATInfo, error)
+	Sessions(ctx context.Context) ([]filnet.SessionStats, error)
+	Bandwidth(ctx context.Context) (*filnet.BandwidthReport, error)
+	Peering(ctx context.Context) ([]filnet.PeeringStatus, error)
+	Seen(ctx context.Context) ([]filnet.SeenCacheStats, error)
+}
+
+// SwarmNATInfo is whether the node is reachable from the internet and the
+// addresses it advertises to its peers.
+type SwarmNATInfo struct {
+	// Reachability is public, private if the node is behind a NAT, or
+	// unknown until enough peers dialed it back.
+	Reachability string
+	// PublicAddr is the address peers reached the node on, if public.
+	PublicAddr string
+	// Addrs are the addresses advertised, relay addresses when private.
+	Addrs []string
 }
 
 // SwarmConnInfo represents details about a single swarm connection.
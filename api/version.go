@@ -1,9 +1,23 @@
 package api
 
+import "strings"
+
+// APIVersion is the version of the api of go-filecoin, major.minor. The
+// minor version is bumped by additions to the api, the major one by changes
+// breaking its clients, which are only compatible with nodes of the same
+// major version.
+const APIVersion = "1.0"
+
+// APIVersionHeader is the http header holding the api version of the client
+// in requests to the api, and of the node in its responses.
+const APIVersionHeader = "X-Filecoin-Api-Version"
+
 // VersionInfo holds details about a version of go-filecoin.
 type VersionInfo struct {
 	// Commit, is the git sha that was used to build this version of go-filecoin.
 	Commit string
+	// APIVersion, is the version of the api, see APIVersion.
+	APIVersion string
 }
 
 // Version is the interface that defines methods to view version information about this node.
@@ -11,3 +25,13 @@ type Version interface {
 	// Full, returns all version information that is available.
 	Full() (*VersionInfo, error)
 }
+
+// APIVersionCompatible returns whether the clients and nodes of api
+// version, e.g. 1.2, and of APIVersion are compatible.
+func APIVersionCompatible(version string) bool {
+	return version != "" && apiMajor(version) == apiMajor(APIVersion)
+}
+
+func apiMajor(version string) string {
+	return strings.SplitN(version, ".", 2)[0]
+}
@@ -0,0 +1,159 @@
+// Package auth issues and verifies the tokens which grant clients of the api
+// of the node permission to call it.
+//
+// Tokens are JSON Web Tokens signed with HMAC-SHA256 by a secret of the
+// repo, listing the permissions they grant. Permissions are ordered: each
+// one includes the ones before it, so that a token created for the sign
+// permission also grants read and write.
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+)
+
+// Permission is a scope of the api a token grants access to.
+type Permission string
+
+const (
+	// PermRead allows reading the chain, the state and the node's status.
+	PermRead = Permission("read")
+	// PermWrite allows changing the node's state, e.g. connecting to peers
+	// or starting to mine, without spending funds.
+	PermWrite = Permission("write")
+	// PermSign allows signing with the keys of the wallet, and so sending
+	// messages which spend funds.
+	PermSign = Permission("sign")
+	// PermAdmin allows everything, including exporting keys, changing the
+	// config and creating tokens.
+	PermAdmin = Permission("admin")
+)
+
+// Permissions are all the permissions, each one including the ones before
+// it.
+var Permissions = []Permission{PermRead, PermWrite, PermSign, PermAdmin}
+
+// SecretSize is the size of the secrets tokens are signed with.
+const SecretSize = 32
+
+// ErrInvalidToken is returned when verifying a token which is malformed or
+// wasn't signed with the secret.
+var ErrInvalidToken = errors.New("invalid api token")
+
+// jwtHeader is the header of all the tokens.
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+type claims struct {
+	Perms []Permission `json:"perms"`
+}
+
+// ParsePermission returns the permission named s.
+func ParsePermission(s string) (Permission, error) {
+	for _, p := range Permissions {
+		if string(p) == s {
+			return p, nil
+		}
+	}
+	return "", errors.Errorf("unknown permission %q, must be one of read, write, sign or admin", s)
+}
+
+// Includes returns whether the permission p includes required.
+func (p Permission) Includes(required Permission) bool {
+	return rank(p) >= rank(required) && rank(required) >= 0
+}
+
+func rank(p Permission) int {
+	for i, q := range Permissions {
+		if p == q {
+			return i
+		}
+	}
+	return -1
+}
+
+// NewSecret returns a random secret to sign tokens with.
+func NewSecret() ([]byte, error) {
+	secret := make([]byte, SecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, errors.Wrap(err, "failed to generate api secret")
+	}
+	return secret, nil
+}
+
+// CreateToken returns a token granting perm, signed with secret.
+func CreateToken(secret []byte, perm Permission) (string, error) {
+	if rank(perm) < 0 {
+		return "", errors.Errorf("unknown permission %q", perm)
+	}
+	payload, err := json.Marshal(claims{Perms: Permissions[:rank(perm)+1]})
+	if err != nil {
+		return "", err
+	}
+	signed := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + sign(secret, signed), nil
+}
+
+// VerifyToken checks token was signed with secret and returns the highest
+// permission it grants.
+func VerifyToken(secret []byte, token string) (Permission, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != jwtHeader {
+		return "", ErrInvalidToken
+	}
+	if !hmac.Equal([]byte(parts[2]), []byte(sign(secret, parts[0]+"."+parts[1]))) {
+		return "", ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+	var c claims
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return "", ErrInvalidToken
+	}
+
+	// The token grants the highest permission it lists.
+	granted := Permission("")
+	for _, p := range c.Perms {
+		if rank(p) > rank(granted) {
+			granted = p
+		}
+	}
+	if granted == "" {
+		return "", ErrInvalidToken
+	}
+	return granted, nil
+}
+
+func sign(secret []byte, signed string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signed)) // nolint: errcheck
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+type permissionKey struct{}
+
+// WithPermission returns a context carrying the permission of the client
+// making a request.
+func WithPermission(ctx context.Context, perm Permission) context.Context {
+	return context.WithValue(ctx, permissionKey{}, perm)
+}
+
+// HasPermission returns whether the client of the request ctx belongs to is
+// granted required. Contexts without a permission, e.g. of calls made by
+// the node itself, are granted everything.
+func HasPermission(ctx context.Context, required Permission) bool {
+	perm, ok := ctx.Value(permissionKey{}).(Permission)
+	if !ok {
+		return true
+	}
+	return perm.Includes(required)
+}
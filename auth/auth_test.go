@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokens(t *testing.T) {
+	t.Parallel()
+
+	secret, err := NewSecret()
+	require.NoError(t, err)
+
+	t.Run("grant the permission they were created for", func(t *testing.T) {
+		for _, perm := range Permissions {
+			token, err := CreateToken(secret, perm)
+			require.NoError(t, err)
+
+			granted, err := VerifyToken(secret, token)
+			require.NoError(t, err)
+			assert.Equal(t, perm, granted)
+		}
+	})
+
+	t.Run("are rejected if not signed with the secret", func(t *testing.T) {
+		other, err := NewSecret()
+		require.NoError(t, err)
+		token, err := CreateToken(other, PermAdmin)
+		require.NoError(t, err)
+
+		_, err = VerifyToken(secret, token)
+		assert.Equal(t, ErrInvalidToken, err)
+	})
+
+	t.Run("are rejected if tampered with", func(t *testing.T) {
+		read, err := CreateToken(secret, PermRead)
+		require.NoError(t, err)
+		admin, err := CreateToken(secret, PermAdmin)
+		require.NoError(t, err)
+
+		// the claims of the admin token with the signature of the read one
+		r, a := strings.Split(read, "."), strings.Split(admin, ".")
+		_, err = VerifyToken(secret, a[0]+"."+a[1]+"."+r[2])
+		assert.Equal(t, ErrInvalidToken, err)
+
+		_, err = VerifyToken(secret, "not a token")
+		assert.Equal(t, ErrInvalidToken, err)
+	})
+
+	t.Run("cannot be created for unknown permissions", func(t *testing.T) {
+		_, err := CreateToken(secret, Permission("root"))
+		assert.Error(t, err)
+	})
+}
+
+func TestPermissions(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	assert.True(PermSign.Includes(PermRead))
+	assert.True(PermSign.Includes(PermSign))
+	assert.False(PermSign.Includes(PermAdmin))
+	assert.False(PermAdmin.Includes(Permission("root")))
+
+	p, err := ParsePermission("write")
+	assert.NoError(err)
+	assert.Equal(PermWrite, p)
+	_, err = ParsePermission("root")
+	assert.Error(err)
+
+	ctx := context.Background()
+	assert.True(HasPermission(ctx, PermAdmin))
+	assert.True(HasPermission(WithPermission(ctx, PermWrite), PermRead))
+	assert.False(HasPermission(WithPermission(ctx, PermWrite), PermSign))
+}
@@ -309,6 +309,14 @@ func buildGenesisFileServer() {
 	runCmd(cmd([]string{"go", "build", "-o", "./tools/genesis-file-server/genesis-file-server", "./tools/genesis-file-server/"}...))
 }
 
+// generate regenerates the generated code, e.g. the dispatch code of the
+// actors.
+func generate() {
+	log.Println("Generating code...")
+
+	runCmd(cmd("go generate ./..."))
+}
+
 func install() {
 	log.Println("Installing...")
 
@@ -348,6 +356,8 @@ func main() {
 		buildGengen()
 	case "generate-genesis":
 		generateGenesis()
+	case "generate":
+		generate()
 	case "build":
 		build()
 	case "test":
@@ -386,6 +386,16 @@ func (store *DefaultStore) LatestState(ctx context.Context) (state.Tree, error)
 	return state.LoadStateTree(ctx, store.stateStore, tsas.TipSetStateRoot, builtin.Actors)
 }
 
+// GetTipSetState returns the state of the tipset with the given key, that is
+// the state after applying its messages.
+func (store *DefaultStore) GetTipSetState(ctx context.Context, tsKey string) (state.Tree, error) {
+	tsas, err := store.GetTipSetAndState(ctx, tsKey)
+	if err != nil {
+		return nil, err
+	}
+	return state.LoadStateTree(ctx, store.stateStore, tsas.TipSetStateRoot, builtin.Actors)
+}
+
 // BlockHistory returns a channel of block pointers (or errors), starting with the input tipset
 // followed by each subsequent parent and ending with the genesis block, after which the channel
 // is closed. If an error is encountered while fetching a block, the error is sent, and the channel is closed.
@@ -55,6 +55,9 @@ type DefaultSyncer struct {
 	mu sync.Mutex
 	// cstOnline is the online storage for fetching blocks.  It should be connected to the network with bitswap.
 	cstOnline *hamt.CborIpldStore
+	// openSession, if set, returns online storage fetching the blocks of a
+	// single sync in a session which ends with ctx, used instead of cstOnline.
+	openSession func(ctx context.Context) *hamt.CborIpldStore
 	// cstOffline is the node's shared offline storage.
 	cstOffline *hamt.CborIpldStore
 	// badTipSetCache is used to filter out collections of invalid blocks.
@@ -66,7 +69,7 @@ type DefaultSyncer struct {
 var _ Syncer = (*DefaultSyncer)(nil)
 
 // NewDefaultSyncer constructs a DefaultSyncer ready for use.
-func NewDefaultSyncer(online, offline *hamt.CborIpldStore, c consensus.Protocol, s Store) Syncer {
+func NewDefaultSyncer(online, offline *hamt.CborIpldStore, c consensus.Protocol, s Store) *DefaultSyncer {
 	return &DefaultSyncer{
 		cstOnline:  online,
 		cstOffline: offline,
@@ -78,6 +81,14 @@ func NewDefaultSyncer(online, offline *hamt.CborIpldStore, c consensus.Protocol,
 	}
 }
 
+// UseSessions makes the syncer fetch the blocks of each sync from the online
+// storage openSession returns, rather than from the shared online storage, so
+// that they are asked from the peers which had the previous blocks of the
+// chain.
+func (syncer *DefaultSyncer) UseSessions(openSession func(ctx context.Context) *hamt.CborIpldStore) {
+	syncer.openSession = openSession
+}
+
 // getBlksMaybeFromNet resolves cids of blocks.  It gets blocks from local
 // storage if they are available there, and otherwise resolves blocks over
 // the network.  This function will timeout if blocks are unavailable.
@@ -86,7 +97,7 @@ func NewDefaultSyncer(online, offline *hamt.CborIpldStore, c consensus.Protocol,
 // WARNING -- this will take one second to error out if blocks are not found.
 // TODO the timeout factor blkWaitTime and maybe the whole timeout mechanism
 // could use some actual thought, this was just a simple first pass.
-func (syncer *DefaultSyncer) getBlksMaybeFromNet(ctx context.Context, blkCids []cid.Cid) ([]*types.Block, error) {
+func (syncer *DefaultSyncer) getBlksMaybeFromNet(ctx context.Context, online *hamt.CborIpldStore, blkCids []cid.Cid) ([]*types.Block, error) {
 	var blks []*types.Block
 	ctx, cancel := context.WithTimeout(ctx, blkWaitTime)
 	defer cancel()
@@ -104,7 +115,7 @@ func (syncer *DefaultSyncer) getBlksMaybeFromNet(ctx context.Context, blkCids []
 			continue
 		}
 		// try the network
-		if err = syncer.cstOnline.Get(ctx, blkCid, &blk); err != nil {
+		if err = online.Get(ctx, blkCid, &blk); err != nil {
 			return nil, err
 		}
 		blks = append(blks, blk)
@@ -125,6 +136,14 @@ func (syncer *DefaultSyncer) getBlksMaybeFromNet(ctx context.Context, blkCids []
 func (syncer *DefaultSyncer) collectChain(ctx context.Context, blkCids []cid.Cid) ([]types.TipSet, types.TipSet, error) {
 	var chain []types.TipSet
 	defer logSyncer.Info("chain synced")
+
+	online := syncer.cstOnline
+	if syncer.openSession != nil {
+		sctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		online = syncer.openSession(sctx)
+	}
+
 	for {
 		var blks []*types.Block
 		// check the cache for bad tipsets before doing anything
@@ -136,7 +155,7 @@ func (syncer *DefaultSyncer) collectChain(ctx context.Context, blkCids []cid.Cid
 			return nil, nil, ErrChainHasBadTipSet
 		}
 
-		blks, err := syncer.getBlksMaybeFromNet(ctx, blkCids)
+		blks, err := syncer.getBlksMaybeFromNet(ctx, online, blkCids)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -39,6 +39,8 @@ type ReadStore interface {
 	Head() types.TipSet
 	// LatestState returns the latest state of the head
 	LatestState(ctx context.Context) (state.Tree, error)
+	// GetTipSetState returns the state of the tipset with the provided key.
+	GetTipSetState(ctx context.Context, tsKey string) (state.Tree, error)
 
 	BlockHistory(ctx context.Context, tips types.TipSet) <-chan interface{}
 	GenesisCid() cid.Cid
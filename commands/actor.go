@@ -15,7 +15,8 @@ var actorCmd = &cmds.Command{
 		Tagline: "Interact with actors. Actors are built-in smart contracts.",
 	},
 	Subcommands: map[string]*cmds.Command{
-		"ls": actorLsCmd,
+		"ls":   actorLsCmd,
+		"read": actorReadCmd,
 	},
 }
 
@@ -49,3 +50,49 @@ var actorLsCmd = &cmds.Command{
 		}),
 	},
 }
+
+var actorReadCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show the state of an actor",
+		ShortDescription: `
+Prints the state of an actor in the state of the chain head as JSON. The state
+of builtin actors is decoded into their state type, that of other actors is
+shown in its generic IPLD form. Pass a dot separated path of field names and
+array indices to only show part of the state, e.g.
+
+  go-filecoin actor read <miner addr> asks.0.price
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("address", true, false, "Address or address book label of the actor"),
+		cmdkit.StringArg("path", false, false, "Path of the field to show"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		addr, err := resolveAddr(env, req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		var path string
+		if len(req.Arguments) > 1 {
+			path = req.Arguments[1]
+		}
+
+		val, err := GetPorcelainAPI(env).ActorReadState(req.Context, addr, path)
+		if err != nil {
+			return err
+		}
+
+		raw, err := json.Marshal(val)
+		if err != nil {
+			return err
+		}
+		return re.Emit(json.RawMessage(raw))
+	},
+	Type: json.RawMessage{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, raw *json.RawMessage) error {
+			return printIndentedJSON(w, raw)
+		}),
+	},
+}
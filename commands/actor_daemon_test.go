@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/filecoin-project/go-filecoin/api"
+	"github.com/filecoin-project/go-filecoin/fixtures"
 	th "github.com/filecoin-project/go-filecoin/testhelpers"
 
 	"github.com/stretchr/testify/assert"
@@ -48,4 +49,22 @@ func TestActorDaemon(t *testing.T) {
 			}
 		}
 	})
+
+	t.Run("actor read decodes actor state", func(t *testing.T) {
+		require := require.New(t)
+		assert := assert.New(t)
+
+		d := th.NewDaemon(t).Start()
+		defer d.ShutdownSuccess()
+
+		var st map[string]interface{}
+		out := d.RunSuccess("actor", "read", fixtures.TestMiners[0]).ReadStdout()
+		require.NoError(json.Unmarshal([]byte(out), &st))
+		assert.Equal(fixtures.TestAddresses[0], st["Owner"])
+
+		owner := d.RunSuccess("actor", "read", fixtures.TestMiners[0], "owner").ReadStdoutTrimNewlines()
+		assert.Equal(`"`+fixtures.TestAddresses[0]+`"`, owner)
+
+		d.RunFail("no such field", "actor", "read", fixtures.TestMiners[0], "nope")
+	})
 }
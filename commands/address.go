@@ -1,14 +1,19 @@
 package commands
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
+	"strconv"
+	"text/tabwriter"
 
 	"gx/ipfs/Qma6uuSyjkecGhMFFLfzyJDPyoDtNJSHJNweDccZhaWkgU/go-ipfs-cmds"
 	"gx/ipfs/Qmde5VP1qUkyQXKCfmEUA7bP64V2HAptbJ7phuPp7jXWwg/go-ipfs-cmdkit"
 
 	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/porcelain"
 	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/wallet"
 )
 
 var walletCmd = &cmds.Command{
@@ -18,8 +23,16 @@ var walletCmd = &cmds.Command{
 	Subcommands: map[string]*cmds.Command{
 		"addrs":   addrsCmd,
 		"balance": balanceCmd,
+		"default": walletDefaultCmd,
 		"import":  walletImportCmd,
 		"export":  walletExportCmd,
+		"history": walletHistoryCmd,
+		"label":   walletLabelCmd,
+		"labels":  walletLabelsCmd,
+		"new":     walletNewCmd,
+		"recover": walletRecoverCmd,
+		"unlabel": walletUnlabelCmd,
+		"watch":   walletWatchCmd,
 	},
 }
 
@@ -60,6 +73,110 @@ var addrsNewCmd = &cmds.Command{
 	},
 }
 
+// WalletNewResult is the result of running the wallet new command.
+type WalletNewResult struct {
+	Address string
+	// Mnemonic is set when a new HD seed was generated.
+	Mnemonic string `json:",omitempty"`
+}
+
+var walletNewCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Create a new wallet address",
+		ShortDescription: `
+Creates a new address in the wallet. With --hd the address is derived from
+the wallet's HD seed along the path m/44'/461'/0'/0/i. If the wallet has no
+HD seed yet one is generated and its mnemonic is printed. The mnemonic is not
+stored by the node: write it down, it is the only way to recover the derived
+addresses with 'go-filecoin wallet recover'.
+
+By default addresses are backed by secp256k1 keys. Pass --type=bls to create
+an address backed by a BLS key, whose signatures block producers can
+aggregate. HD derivation only supports secp256k1 keys.
+`,
+	},
+	Options: []cmdkit.Option{
+		cmdkit.BoolOption("hd", "derive the address from the wallet's HD seed"),
+		cmdkit.StringOption("type", "type of key backing the address, secp256k1 or bls").WithDefault(wallet.SECP256K1),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		useHD, _ := req.Options["hd"].(bool)
+		keyType, _ := req.Options["type"].(string)
+		if !useHD {
+			addr, err := GetPorcelainAPI(env).WalletNewAddressOfType(keyType)
+			if err != nil {
+				return err
+			}
+			return re.Emit(&WalletNewResult{Address: addr.String()})
+		}
+
+		if keyType != wallet.SECP256K1 {
+			return fmt.Errorf("HD addresses can not be of type %s", keyType)
+		}
+
+		addr, mnemonic, err := GetPorcelainAPI(env).WalletNewHDAddress()
+		if err != nil {
+			return err
+		}
+		return re.Emit(&WalletNewResult{Address: addr.String(), Mnemonic: mnemonic})
+	},
+	Type: &WalletNewResult{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, res *WalletNewResult) error {
+			if res.Mnemonic != "" {
+				if _, err := fmt.Fprintf(w, "Generated a new HD seed. Write down its mnemonic:\n\n%s\n\n", res.Mnemonic); err != nil {
+					return err
+				}
+			}
+			_, err := fmt.Fprintln(w, res.Address)
+			return err
+		}),
+	},
+}
+
+var walletRecoverCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Recover HD wallet addresses from a mnemonic",
+		ShortDescription: `
+Restores the wallet's HD seed from a BIP39 mnemonic and derives its first
+--count addresses. Fails if the wallet already holds a different HD seed.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("mnemonic", true, false, "The mnemonic of the HD seed, quoted"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.UintOption("count", "number of addresses to derive").WithDefault(uint(1)),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		count, _ := req.Options["count"].(uint)
+
+		addrs, err := GetPorcelainAPI(env).WalletRecoverHD(req.Arguments[0], uint32(count))
+		if err != nil {
+			return err
+		}
+
+		var alr AddressLsResult
+		for _, addr := range addrs {
+			alr.Addresses = append(alr.Addresses, addr.String())
+		}
+
+		return re.Emit(&alr)
+	},
+	Type: &AddressLsResult{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, addrs *AddressLsResult) error {
+			for _, addr := range addrs.Addresses {
+				_, err := fmt.Fprintln(w, addr)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}),
+	},
+}
+
 var addrsLsCmd = &cmds.Command{
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
 		addrs, err := GetAPI(env).Address().Addrs().Ls(req.Context)
@@ -138,6 +255,14 @@ var balanceCmd = &cmds.Command{
 }
 
 var walletImportCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Import keys into the wallet",
+		ShortDescription: `
+Imports keys from files in the format written by 'go-filecoin wallet export'.
+Key files written by older versions, containing a bare key info, are accepted
+as well.
+`,
+	},
 	Arguments: []cmdkit.Argument{
 		cmdkit.FileArg("walletFile", true, false, "File containing wallet data to import").EnableStdin(),
 	},
@@ -170,10 +295,18 @@ var walletImportCmd = &cmds.Command{
 
 // WalletExportResult is the resut of running the wallet export command.
 type WalletExportResult struct {
-	KeyInfo []*types.KeyInfo
+	Keys []*wallet.KeyFile
 }
 
 var walletExportCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Export the private keys of wallet addresses",
+		ShortDescription: `
+Prints the keys of the given addresses in the versioned key file format, one
+JSON object per key. The output can be imported into any go-filecoin wallet
+with 'go-filecoin wallet import'.
+`,
+	},
 	Arguments: []cmdkit.Argument{
 		cmdkit.StringArg("addresses", true, true, "Addresses of keys to export").EnableStdin(),
 	},
@@ -193,20 +326,25 @@ var walletExportCmd = &cmds.Command{
 		}
 
 		var klr WalletExportResult
-		klr.KeyInfo = append(klr.KeyInfo, kis...)
+		for _, ki := range kis {
+			kf, err := wallet.NewKeyFile(ki)
+			if err != nil {
+				return err
+			}
+			klr.Keys = append(klr.Keys, kf)
+		}
 
 		return re.Emit(klr)
 	},
 	Type: &WalletExportResult{},
 	Encoders: cmds.EncoderMap{
 		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, klr *WalletExportResult) error {
-			for _, k := range klr.KeyInfo {
-				a, err := k.Address()
+			for _, kf := range klr.Keys {
+				b, err := json.MarshalIndent(kf, "", "  ")
 				if err != nil {
 					return err
 				}
-				_, err = fmt.Fprintf(w, "Address:\t%s\nPrivateKey:\t%x\nCurve:\t\t%s\n\n", a.String(), k.PrivateKey, k.Curve)
-				if err != nil {
+				if _, err := fmt.Fprintln(w, string(b)); err != nil {
 					return err
 				}
 			}
@@ -214,3 +352,232 @@ var walletExportCmd = &cmds.Command{
 		}),
 	},
 }
+
+var walletHistoryCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "List the messages sent from or to an address",
+		ShortDescription: `
+Lists the messages on chain sent from or to an address, newest first, with
+their block height, direction, counterparty, value, fee and exit code. Use
+--offset and --limit to page through long histories. The fee and exit code of
+a message without a receipt, e.g. one that conflicts with another message of
+its tipset, are printed as "-".
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("address", true, false, "Address to list the history of"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.UintOption("offset", "Number of messages to skip").WithDefault(uint(0)),
+		cmdkit.UintOption("limit", "Maximum number of messages to list, 0 for all").WithDefault(uint(20)),
+		cmdkit.BoolOption("json", "Print the history as JSON"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		addr, err := address.NewFromString(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		offset, _ := req.Options["offset"].(uint)
+		limit, _ := req.Options["limit"].(uint)
+
+		entries, err := GetPorcelainAPI(env).WalletHistory(req.Context, addr, offset, limit)
+		if err != nil {
+			return err
+		}
+		if entries == nil {
+			entries = []*porcelain.WalletHistoryEntry{}
+		}
+
+		return re.Emit(entries)
+	},
+	Type: []*porcelain.WalletHistoryEntry{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, entries *[]*porcelain.WalletHistoryEntry) error {
+			if asJSON, _ := req.Options["json"].(bool); asJSON {
+				b, err := json.MarshalIndent(entries, "", "  ")
+				if err != nil {
+					return err
+				}
+				_, err = fmt.Fprintln(w, string(b))
+				return err
+			}
+
+			tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "HEIGHT\tDIRECTION\tCOUNTERPARTY\tVALUE\tFEE\tEXIT\tCID") // nolint: errcheck
+			for _, e := range *entries {
+				counterparty := e.To
+				if e.Direction == porcelain.HistoryIncoming {
+					counterparty = e.From
+				}
+				fee, exitCode := "-", "-"
+				if e.Fee != nil {
+					fee = e.Fee.String()
+				}
+				if e.ExitCode != nil {
+					exitCode = strconv.Itoa(int(*e.ExitCode))
+				}
+				fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", e.BlockHeight, e.Direction, counterparty, e.Value, fee, exitCode, e.Cid) // nolint: errcheck
+			}
+			return tw.Flush()
+		}),
+	},
+}
+
+var walletLabelCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Give an address a name",
+		ShortDescription: `
+Adds an address to the node's address book under the given name. Commands
+that send messages or propose deals accept the name wherever they take an
+address. Labels can't contain slashes or whitespace. A name refers to a single
+address; pass --force to point an existing name at a different address.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("address", true, false, "Address to label"),
+		cmdkit.StringArg("name", true, false, "Name to give the address"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.BoolOption("force", "Replace the address an existing name refers to"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		addr, err := address.NewFromString(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		force, _ := req.Options["force"].(bool)
+		return GetPorcelainAPI(env).AddressBookLabel(addr, req.Arguments[1], force)
+	},
+}
+
+var walletUnlabelCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Remove a name from the address book",
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("name", true, false, "Name to remove"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		return GetPorcelainAPI(env).AddressBookUnlabel(req.Arguments[0])
+	},
+}
+
+var walletLabelsCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "List the names in the address book",
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		labels, err := GetPorcelainAPI(env).AddressBookLabels()
+		if err != nil {
+			return err
+		}
+		return re.Emit(labels)
+	},
+	Type: []wallet.AddressLabel{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, labels *[]wallet.AddressLabel) error {
+			tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+			for _, l := range *labels {
+				fmt.Fprintf(tw, "%s\t%s\n", l.Name, l.Address) // nolint: errcheck
+			}
+			return tw.Flush()
+		}),
+	},
+}
+
+var walletDefaultCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Manage the default wallet address",
+		ShortDescription: `
+Commands that send messages use the default address when no --from address is
+given. If none is set, the first address of the wallet becomes the default.
+`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"get": walletDefaultGetCmd,
+		"set": walletDefaultSetCmd,
+	},
+}
+
+var walletDefaultGetCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Print the default wallet address",
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		addr, err := GetPorcelainAPI(env).GetAndMaybeSetDefaultSenderAddress()
+		if err != nil {
+			return err
+		}
+		return re.Emit(&addressResult{addr.String()})
+	},
+	Type: &addressResult{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, a *addressResult) error {
+			_, err := fmt.Fprintln(w, a.Address)
+			return err
+		}),
+	},
+}
+
+var walletDefaultSetCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Set the default wallet address",
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("address", true, false, "Address or address book label of an address in the wallet"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		addr, err := resolveAddr(env, req.Arguments[0])
+		if err != nil {
+			return err
+		}
+		return GetPorcelainAPI(env).WalletSetDefaultAddress(addr)
+	},
+}
+
+var walletWatchCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Follow the balance and nonce of an address",
+		ShortDescription: `
+Prints the current balance and nonce of an address, then prints them again
+every time a new chain head changes them, until interrupted. Changes caused by
+a chain reorganization are marked as such.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("address", true, false, "Address or address book label to watch"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		addr, err := resolveAddr(env, req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		for raw := range GetPorcelainAPI(env).WatchBalance(req.Context, addr) {
+			switch v := raw.(type) {
+			case error:
+				return v
+			case *porcelain.BalanceEvent:
+				if err := re.Emit(v); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("unexpected type %T", raw)
+			}
+		}
+		return nil
+	},
+	Type: porcelain.BalanceEvent{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, evt *porcelain.BalanceEvent) error {
+			reorg := ""
+			if evt.Reorg {
+				reorg = " (reorg)"
+			}
+			_, err := fmt.Fprintf(w, "height %d: balance %s, nonce %d%s\n", evt.Height, evt.Balance, evt.Nonce, reorg)
+			return err
+		}),
+	},
+}
@@ -1,6 +1,9 @@
 package commands
 
 import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
 	"testing"
 	"time"
 
@@ -10,6 +13,7 @@ import (
 	th "github.com/filecoin-project/go-filecoin/testhelpers"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestAddrsNewAndList(t *testing.T) {
@@ -52,6 +56,32 @@ func TestWalletBalance(t *testing.T) {
 	assert.Equal("0", balance.ReadStdoutTrimNewlines())
 }
 
+func TestWalletNewHDAndRecover(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	d := th.NewDaemon(t).Start()
+	defer d.ShutdownSuccess()
+
+	var first WalletNewResult
+	out := d.RunSuccess("wallet", "new", "--hd", "--enc", "json").ReadStdoutTrimNewlines()
+	require.NoError(json.Unmarshal([]byte(out), &first))
+	assert.NotEmpty(first.Mnemonic)
+
+	var second WalletNewResult
+	out = d.RunSuccess("wallet", "new", "--hd", "--enc", "json").ReadStdoutTrimNewlines()
+	require.NoError(json.Unmarshal([]byte(out), &second))
+	assert.Empty(second.Mnemonic)
+
+	d2 := th.NewDaemon(t).Start()
+	defer d2.ShutdownSuccess()
+
+	recovered := d2.RunSuccess("wallet", "recover", "--count=2", first.Mnemonic).ReadStdout()
+	assert.Contains(recovered, first.Address)
+	assert.Contains(recovered, second.Address)
+}
+
 func TestAddrLookupAndUpdate(t *testing.T) {
 	assert := assert.New(t)
 	d1 := th.NewDaemon(t, th.WithMiner(fixtures.TestMiners[0]), th.KeyFile(fixtures.KeyFilePaths()[1])).Start()
@@ -115,3 +145,87 @@ func TestWalletLoadFromFile(t *testing.T) {
 	wb := d.RunSuccess("wallet", "balance", fixtures.TestAddresses[0]).ReadStdoutTrimNewlines()
 	assert.Contains(wb, "10000")
 }
+
+func TestWalletExportImport(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	d := th.NewDaemon(t).Start()
+	defer d.ShutdownSuccess()
+
+	addr := d.CreateWalletAddr()
+	exported := d.RunSuccess("wallet", "export", addr).ReadStdout()
+	assert.Contains(exported, `"version": 1`)
+
+	f, err := ioutil.TempFile("", "keyfile")
+	require.NoError(err)
+	defer os.Remove(f.Name()) // nolint: errcheck
+	_, err = f.WriteString(exported)
+	require.NoError(err)
+	require.NoError(f.Close())
+
+	d2 := th.NewDaemon(t).Start()
+	defer d2.ShutdownSuccess()
+
+	imported := d2.RunSuccess("wallet", "import", f.Name()).ReadStdoutTrimNewlines()
+	assert.Equal(addr, imported)
+}
+
+func TestWalletLabels(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	d := th.NewDaemon(
+		t,
+		th.WithMiner(fixtures.TestMiners[0]),
+		th.KeyFile(fixtures.KeyFilePaths()[0]),
+	).Start()
+	defer d.ShutdownSuccess()
+
+	d.RunSuccess("wallet", "label", fixtures.TestAddresses[0], "hot")
+	d.RunSuccess("wallet", "label", fixtures.TestAddresses[1], "friend")
+	d.RunFail("already refers to", "wallet", "label", fixtures.TestAddresses[0], "friend")
+
+	labels := d.RunSuccess("wallet", "labels").ReadStdout()
+	assert.Contains(labels, "friend")
+	assert.Contains(labels, fixtures.TestAddresses[1])
+
+	t.Log("labels are accepted in place of addresses when sending")
+	d.RunSuccess("message", "send",
+		"--from", "hot",
+		"--price", "0", "--limit", "300",
+		"--value=10", "friend",
+	)
+	d.RunFail("neither an address nor a known label", "message", "send",
+		"--from", "hot",
+		"--price", "0", "--limit", "300",
+		"--value=10", "stranger",
+	)
+
+	t.Log("labels survive restarts")
+	d.Restart()
+	d.RunSuccess("wallet", "unlabel", "friend")
+	assert.NotContains(d.RunSuccess("wallet", "labels").ReadStdout(), "friend")
+}
+
+func TestWalletDefault(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	d := th.NewDaemon(t, th.KeyFile(fixtures.KeyFilePaths()[0])).Start()
+	defer d.ShutdownSuccess()
+
+	d.RunSuccess("wallet", "default", "set", fixtures.TestAddresses[0])
+	assert.Equal(fixtures.TestAddresses[0], d.RunSuccess("wallet", "default", "get").ReadStdoutTrimNewlines())
+
+	d.RunFail("not in the wallet", "wallet", "default", "set", fixtures.TestAddresses[1])
+
+	newAddr := d.RunSuccess("wallet", "new").ReadStdoutTrimNewlines()
+	d.RunSuccess("wallet", "label", newAddr, "spare")
+	d.RunSuccess("wallet", "default", "set", "spare")
+	assert.Equal(newAddr, d.RunSuccess("wallet", "default", "get").ReadStdoutTrimNewlines())
+
+	t.Log("commands taking --from fall back to the default address")
+	composed := d.RunSuccess("message", "compose", fixtures.TestAddresses[0]).ReadStdout()
+	assert.Contains(composed, newAddr)
+}
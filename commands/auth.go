@@ -0,0 +1,207 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"gx/ipfs/Qma6uuSyjkecGhMFFLfzyJDPyoDtNJSHJNweDccZhaWkgU/go-ipfs-cmds"
+	"gx/ipfs/Qmde5VP1qUkyQXKCfmEUA7bP64V2HAptbJ7phuPp7jXWwg/go-ipfs-cmdkit"
+
+	"github.com/filecoin-project/go-filecoin/api"
+	"github.com/filecoin-project/go-filecoin/auth"
+)
+
+var authCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Manage the tokens granting access to the api",
+		ShortDescription: `
+Requests to the api must carry a token, as an "Authorization: Bearer <token>"
+header, granting the permission the command requires: read, write, sign or
+admin, each including the ones before it. Requests without a token are
+rejected, except for the version and health endpoints. The commands run
+locally use the admin token the daemon writes to the repo, or the token of
+the FIL_API_TOKEN environment variable.
+`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"create-token": authCreateTokenCmd,
+	},
+}
+
+var authCreateTokenCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Create a token granting a permission of the api",
+	},
+	Options: []cmdkit.Option{
+		cmdkit.StringOption("perm", "permission the token grants: read, write, sign or admin").WithDefault("read"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		perm, err := auth.ParsePermission(req.Options["perm"].(string))
+		if err != nil {
+			return err
+		}
+		token, err := GetAPI(env).Auth().CreateToken(req.Context, perm)
+		if err != nil {
+			return err
+		}
+		return re.Emit(token)
+	},
+	Type: "",
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, token string) error {
+			_, err := fmt.Fprintln(w, token)
+			return err
+		}),
+	},
+}
+
+// commandPermissions are the permissions the commands require, by path. A
+// command requires the permission of the longest path it starts with, admin
+// if there is none, so that new commands aren't exposed by accident.
+var commandPermissions = map[string]auth.Permission{
+	"actor":                           auth.PermRead,
+	"address addrs ls":                auth.PermRead,
+	"address addrs lookup":            auth.PermRead,
+	"address addrs new":               auth.PermWrite,
+	"address balance":                 auth.PermRead,
+	"address default get":             auth.PermRead,
+	"address default set":             auth.PermWrite,
+	"address history":                 auth.PermRead,
+	"address label":                   auth.PermWrite,
+	"address labels":                  auth.PermRead,
+	"address new":                     auth.PermWrite,
+	"address unlabel":                 auth.PermWrite,
+	"address watch":                   auth.PermRead,
+	"batch":                           auth.PermRead,
+	"bootstrap ls":                    auth.PermRead,
+	"chain":                           auth.PermRead,
+	"client":                          auth.PermRead,
+	"client cat":                      auth.PermSign,
+	"client export-car":               auth.PermWrite,
+	"client import":                   auth.PermWrite,
+	"client ls":                       auth.PermSign,
+	"client propose-storage-deal":     auth.PermSign,
+	"client renew-deal":               auth.PermSign,
+	"dag":                             auth.PermRead,
+	"deals show":                      auth.PermRead,
+	"deals pause-transfer":            auth.PermWrite,
+	"deals resume-transfer":           auth.PermWrite,
+	"event":                           auth.PermRead,
+	"id":                              auth.PermRead,
+	"message call":                    auth.PermRead,
+	"message compose":                 auth.PermRead,
+	"message estimate-gas-price":      auth.PermRead,
+	"message publish":                 auth.PermWrite,
+	"message replay":                  auth.PermRead,
+	"message send":                    auth.PermSign,
+	"message sign":                    auth.PermSign,
+	"message wait":                    auth.PermRead,
+	"miner":                           auth.PermSign,
+	"miner import-deal-data":          auth.PermWrite,
+	"miner owner":                     auth.PermRead,
+	"miner pledge":                    auth.PermRead,
+	"miner post-status":               auth.PermRead,
+	"miner power":                     auth.PermRead,
+	"miner sectors":                   auth.PermRead,
+	"mining":                          auth.PermWrite,
+	"mpool":                           auth.PermRead,
+	"mpool rm":                        auth.PermWrite,
+	"multisig":                        auth.PermSign,
+	"multisig info":                   auth.PermRead,
+	"paych":                           auth.PermSign,
+	"paych ls":                        auth.PermRead,
+	"ping":                            auth.PermRead,
+	"proofs resources":                auth.PermRead,
+	"repo pin ls":                     auth.PermRead,
+	"retrieval-client query-piece":    auth.PermRead,
+	"retrieval-client":                auth.PermWrite,
+	"retrieval-client retrieve-piece": auth.PermSign,
+	"sealing jobs":                    auth.PermRead,
+	"sealing paths":                   auth.PermRead,
+	"sealing workers":                 auth.PermRead,
+	"sectors check":                   auth.PermRead,
+	"sectors flagged":                 auth.PermRead,
+	"sectors scrub":                   auth.PermWrite,
+	"sectors scrub-status":            auth.PermRead,
+	"show":                            auth.PermRead,
+	"state diff":                      auth.PermRead,
+	"swarm":                           auth.PermRead,
+	"swarm ban":                       auth.PermAdmin,
+	"swarm connect":                   auth.PermWrite,
+	"swarm ping":                      auth.PermWrite,
+	"swarm unban":                     auth.PermAdmin,
+	"version":                         auth.PermRead,
+}
+
+// commandPermission returns the permission the command at path requires.
+func commandPermission(path []string) auth.Permission {
+	// requests for commands which don't exist fail anyway
+	if _, err := rootCmdDaemon.Get(path); err != nil {
+		return auth.PermRead
+	}
+	for n := len(path); n > 0; n-- {
+		if perm, ok := commandPermissions[strings.Join(path[:n], " ")]; ok {
+			return perm
+		}
+	}
+	return auth.PermAdmin
+}
+
+// requestPermission returns the permission a request to the api server
+// requires, or "" for the version and health endpoints anyone may call. The
+// methods of the JSON-RPC api check their own permission.
+func requestPermission(r *http.Request) auth.Permission {
+	switch {
+	case strings.HasPrefix(r.URL.Path, APIPrefix+"/"):
+		path := strings.Trim(strings.TrimPrefix(r.URL.Path, APIPrefix), "/")
+		return commandPermission(strings.Split(path, "/"))
+	case r.URL.Path == VersionPath, r.URL.Path == HealthPath, r.URL.Path == ReadyPath:
+		return ""
+	case strings.HasPrefix(r.URL.Path, JSONRPCPath):
+		return auth.PermRead
+	default:
+		return auth.PermAdmin
+	}
+}
+
+// authHandler authenticates the requests to the api server by their token,
+// rejecting those whose token doesn't grant the permission they require.
+func authHandler(a api.Auth, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get("Authorization")
+		if token != "" {
+			if !strings.HasPrefix(token, "Bearer ") {
+				http.Error(w, "the authorization header must hold a bearer token", http.StatusUnauthorized)
+				return
+			}
+			token = strings.TrimPrefix(token, "Bearer ")
+		} else if strings.HasPrefix(r.URL.Path, JSONRPCPath) {
+			// browsers can't set the headers of websockets
+			token = r.URL.Query().Get("token")
+		}
+
+		// requests without a token have no permission
+		var perm auth.Permission
+		if token != "" {
+			var err error
+			perm, err = a.Verify(r.Context(), token)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+		}
+
+		// CORS preflight requests never carry the token.
+		if required := requestPermission(r); required != "" && r.Method != http.MethodOptions && !perm.Includes(required) {
+			if perm == "" {
+				http.Error(w, "the api requires a token", http.StatusUnauthorized)
+				return
+			}
+			http.Error(w, fmt.Sprintf("api token doesn't grant the %s permission", required), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(auth.WithPermission(r.Context(), perm)))
+	})
+}
@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	ma "gx/ipfs/QmNTCey11oxhb1AxDnQBRHtdhap6Ctud872NjAYPYYXPuc/go-multiaddr"
+	manet "gx/ipfs/QmZcLBXKaFe8ND5YHPkJRAwmhJGrVsi1JqDZNyJ4nRK5Mj/go-multiaddr-net"
+
+	th "github.com/filecoin-project/go-filecoin/testhelpers"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuth(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	d := th.NewDaemon(t).Start()
+	defer d.ShutdownSuccess()
+
+	maddr, err := ma.NewMultiaddr(d.CmdAddr())
+	require.NoError(err)
+	_, host, err := manet.DialArgs(maddr)
+	require.NoError(err)
+
+	status := func(token, command string) int {
+		req, err := http.NewRequest("POST", fmt.Sprintf("http://%s/api/%s", host, command), nil)
+		require.NoError(err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(err)
+		res.Body.Close() // nolint: errcheck
+		return res.StatusCode
+	}
+
+	readToken := d.RunSuccess("auth", "create-token", "--perm", "read").ReadStdoutTrimNewlines()
+	writeToken := d.RunSuccess("auth", "create-token", "--perm", "write").ReadStdoutTrimNewlines()
+
+	// requests without a token are rejected
+	assert.Equal(http.StatusUnauthorized, status("", "id"))
+	assert.Equal(http.StatusUnauthorized, status("", "address/new"))
+
+	assert.Equal(http.StatusOK, status(readToken, "id"))
+	assert.Equal(http.StatusForbidden, status(readToken, "address/new"))
+	assert.Equal(http.StatusOK, status(writeToken, "address/new"))
+	assert.Equal(http.StatusForbidden, status(writeToken, "config/api"))
+	// retrievals pay the miner from the wallet
+	assert.Equal(http.StatusForbidden, status(writeToken, "client/cat"))
+	assert.Equal(http.StatusForbidden, status(writeToken, "client/ls"))
+	assert.Equal(http.StatusForbidden, status(writeToken, "retrieval-client/retrieve-piece"))
+	assert.Equal(http.StatusUnauthorized, status("not a token", "id"))
+
+	// the commands run locally use the admin token of the repo
+	d.RunSuccess("config", "api")
+	d.RunFail("unknown permission", "auth", "create-token", "--perm", "root")
+}
@@ -0,0 +1,308 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	"gx/ipfs/Qma6uuSyjkecGhMFFLfzyJDPyoDtNJSHJNweDccZhaWkgU/go-ipfs-cmds"
+	"gx/ipfs/Qmde5VP1qUkyQXKCfmEUA7bP64V2HAptbJ7phuPp7jXWwg/go-ipfs-cmdkit"
+
+	"github.com/filecoin-project/go-filecoin/auth"
+)
+
+// maxBatchJobs is the number of batch jobs kept, the oldest finished ones
+// are forgotten beyond it.
+const maxBatchJobs = 100
+
+var batchCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Run batches of commands on the daemon",
+		ShortDescription: `
+A batch is a list of commands, e.g. many message sends or deal proposals,
+the daemon runs in the background, one after the other, in a single request.
+The job of the batch is then polled for the results of its commands.
+`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"submit": batchSubmitCmd,
+		"status": batchStatusCmd,
+	},
+}
+
+var batchSubmitCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Submit a batch of commands and print the id of its job",
+		ShortDescription: `
+Reads the batch as a JSON list of commands, each given as in a request to the
+http api: its path, arguments and options, all strings. E.g.
+
+  [
+    {"path": ["message", "send"], "args": ["<to>"], "options": {"value": "10", "price": "0", "limit": "300"}},
+    {"path": ["wallet", "balance"], "args": ["<to>"]}
+  ]
+
+The commands are run in order, a failing command doesn't stop the next ones.
+The token of the request must grant the permissions of all of them.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.FileArg("batch", true, false, "File containing the batch").EnableStdin(),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		fi, err := req.Files.NextFile()
+		if err != nil {
+			return err
+		}
+		var batch []BatchCommand
+		if err := json.NewDecoder(fi).Decode(&batch); err != nil {
+			return errors.Wrap(err, "failed to decode batch")
+		}
+
+		job, err := getBatchJobs(env).Submit(req.Context, batch)
+		if err != nil {
+			return err
+		}
+		return re.Emit(job)
+	},
+	Type: BatchJob{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, job *BatchJob) error {
+			_, err := fmt.Fprintln(w, job.ID)
+			return err
+		}),
+	},
+}
+
+var batchStatusCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show the progress and results of a batch job",
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("id", true, false, "Id of the job"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		job, err := getBatchJobs(env).Status(req.Context, req.Arguments[0])
+		if err != nil {
+			return err
+		}
+		return re.Emit(job)
+	},
+	Type: BatchJob{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, job *BatchJob) error {
+			if _, err := fmt.Fprintf(w, "%s\t%d/%d done\n", job.ID, job.Done, len(job.Commands)); err != nil {
+				return err
+			}
+			for i, res := range job.Results {
+				status := "ok"
+				if res.Error != "" {
+					status = "error: " + res.Error
+				}
+				if _, err := fmt.Fprintf(w, "%d\t%s\t%s\n", i, strings.Join(job.Commands[i].Path, " "), status); err != nil {
+					return err
+				}
+			}
+			return nil
+		}),
+	},
+}
+
+// BatchCommand is a command of a batch, given as in a request to the http
+// api: its path, e.g. ["message", "send"], arguments and options.
+type BatchCommand struct {
+	Path    []string          `json:"path"`
+	Args    []string          `json:"args,omitempty"`
+	Options map[string]string `json:"options,omitempty"`
+}
+
+// BatchResult is the result of a command of a batch: the values it emitted,
+// as JSON, or its error.
+type BatchResult struct {
+	Values []json.RawMessage `json:"values"`
+	Error  string            `json:"error,omitempty"`
+}
+
+// BatchJob is the job running a batch. Results holds the results of the
+// commands done so far, in order.
+type BatchJob struct {
+	ID       string         `json:"id"`
+	Commands []BatchCommand `json:"commands"`
+	Done     int            `json:"done"`
+	Finished bool           `json:"finished"`
+	Results  []BatchResult  `json:"results"`
+}
+
+type batchJob struct {
+	BatchJob
+	// perm is the permission the commands require, and so the one
+	// required to see their results.
+	perm auth.Permission
+}
+
+// batchJobs runs the batches submitted to the daemon and keeps their jobs.
+type batchJobs struct {
+	root *cmds.Command
+	env  cmds.Environment
+
+	lk    sync.Mutex
+	next  uint64
+	jobs  map[string]*batchJob
+	order []string
+}
+
+func newBatchJobs(root *cmds.Command, env cmds.Environment) *batchJobs {
+	return &batchJobs{root: root, env: env, jobs: make(map[string]*batchJob)}
+}
+
+func getBatchJobs(env cmds.Environment) *batchJobs {
+	return env.(*Env).batchJobs
+}
+
+// Submit checks the commands of batch exist and are permitted to the client
+// of ctx, and starts running them.
+func (b *batchJobs) Submit(ctx context.Context, batch []BatchCommand) (*BatchJob, error) {
+	if len(batch) == 0 {
+		return nil, errors.New("empty batch")
+	}
+
+	perm := auth.PermRead
+	for i, c := range batch {
+		if len(c.Path) == 0 || c.Path[0] == "batch" {
+			return nil, fmt.Errorf("command %d: invalid path %q", i, strings.Join(c.Path, " "))
+		}
+		if _, err := b.root.Get(c.Path); err != nil {
+			return nil, fmt.Errorf("command %d: unknown command %q", i, strings.Join(c.Path, " "))
+		}
+		required := commandPermission(c.Path)
+		if !auth.HasPermission(ctx, required) {
+			return nil, fmt.Errorf("command %d: api token doesn't grant the %s permission", i, required)
+		}
+		if required.Includes(perm) {
+			perm = required
+		}
+	}
+
+	b.lk.Lock()
+	b.next++
+	job := &batchJob{
+		BatchJob: BatchJob{ID: strconv.FormatUint(b.next, 10), Commands: batch, Results: []BatchResult{}},
+		perm:     perm,
+	}
+	b.jobs[job.ID] = job
+	b.order = append(b.order, job.ID)
+	b.forgetLocked()
+	snapshot := job.BatchJob
+	b.lk.Unlock()
+
+	// the job outlives the request submitting it
+	go b.run(auth.WithPermission(context.Background(), perm), job)
+	return &snapshot, nil
+}
+
+// Status returns the job id, unless the client of ctx isn't permitted to
+// see its results.
+func (b *batchJobs) Status(ctx context.Context, id string) (*BatchJob, error) {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	job, ok := b.jobs[id]
+	if !ok || !auth.HasPermission(ctx, job.perm) {
+		return nil, fmt.Errorf("no batch job %s", id)
+	}
+	snapshot := job.BatchJob
+	snapshot.Results = append([]BatchResult{}, job.Results...)
+	return &snapshot, nil
+}
+
+// forgetLocked forgets the oldest finished jobs beyond maxBatchJobs.
+func (b *batchJobs) forgetLocked() {
+	for i := 0; len(b.order) > maxBatchJobs && i < len(b.order); {
+		id := b.order[i]
+		if !b.jobs[id].Finished {
+			i++
+			continue
+		}
+		delete(b.jobs, id)
+		b.order = append(b.order[:i], b.order[i+1:]...)
+	}
+}
+
+func (b *batchJobs) run(ctx context.Context, job *batchJob) {
+	for _, c := range job.Commands {
+		res := b.execute(ctx, c)
+
+		b.lk.Lock()
+		job.Results = append(job.Results, res)
+		job.Done++
+		b.lk.Unlock()
+	}
+
+	b.lk.Lock()
+	job.Finished = true
+	b.forgetLocked()
+	b.lk.Unlock()
+}
+
+// execute runs c and collects the values it emits.
+func (b *batchJobs) execute(ctx context.Context, c BatchCommand) BatchResult {
+	res := BatchResult{Values: []json.RawMessage{}}
+
+	opts := make(cmdkit.OptMap, len(c.Options))
+	for k, v := range c.Options {
+		opts[k] = v
+	}
+	req, err := cmds.NewRequest(ctx, c.Path, opts, c.Args, nil, b.root)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+
+	re, out := cmds.NewChanResponsePair(req)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- cmds.NewExecutor(b.root).Execute(req, re, b.env)
+	}()
+
+	for {
+		v, err := out.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// the error of the command ends its output
+			res.Error = err.Error()
+			break
+		}
+		raw, err := marshalBatchValue(v)
+		if err != nil {
+			// keep reading so that the command isn't blocked emitting
+			res.Error = err.Error()
+			continue
+		}
+		res.Values = append(res.Values, raw)
+	}
+	if err := <-errCh; err != nil && res.Error == "" {
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// marshalBatchValue returns the JSON of a value emitted by a command, the
+// content of readers being a string.
+func marshalBatchValue(v interface{}) (json.RawMessage, error) {
+	if r, ok := v.(io.Reader); ok {
+		content, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		v = string(content)
+	}
+	return json.Marshal(v)
+}
@@ -0,0 +1,49 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/fixtures"
+	th "github.com/filecoin-project/go-filecoin/testhelpers"
+)
+
+func TestBatchDaemon(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	d := th.NewDaemon(t, th.KeyFile(fixtures.KeyFilePaths()[0])).Start()
+	defer d.ShutdownSuccess()
+
+	batch := fmt.Sprintf(`[
+		{"path": ["id"]},
+		{"path": ["wallet", "balance"], "args": ["%s"]},
+		{"path": ["wallet", "balance"], "args": ["not an address"]}
+	]`, fixtures.TestAddresses[0])
+	id := d.RunWithStdin(strings.NewReader(batch), "batch", "submit").AssertSuccess().ReadStdoutTrimNewlines()
+
+	var job BatchJob
+	for i := 0; i < 50 && !job.Finished; i++ {
+		out := d.RunSuccess("batch", "status", id, "--enc=json").ReadStdout()
+		require.NoError(json.Unmarshal([]byte(out), &job))
+		time.Sleep(100 * time.Millisecond)
+	}
+	require.True(job.Finished)
+	require.Len(job.Results, 3)
+
+	assert.Empty(job.Results[0].Error)
+	assert.Contains(string(job.Results[0].Values[0]), d.GetID())
+	assert.Empty(job.Results[1].Error)
+	assert.Len(job.Results[1].Values, 1)
+	assert.NotEmpty(job.Results[2].Error)
+
+	assert.Contains(d.RunSuccess("batch", "status", id).ReadStdout(), "3/3 done")
+	d.RunFail("no batch job 42", "batch", "status", "42")
+}
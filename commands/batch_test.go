@@ -0,0 +1,111 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"gx/ipfs/Qma6uuSyjkecGhMFFLfzyJDPyoDtNJSHJNweDccZhaWkgU/go-ipfs-cmds"
+	"gx/ipfs/Qmde5VP1qUkyQXKCfmEUA7bP64V2HAptbJ7phuPp7jXWwg/go-ipfs-cmdkit"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/auth"
+)
+
+func TestBatchJobs(t *testing.T) {
+	t.Parallel()
+
+	root := &cmds.Command{
+		Subcommands: map[string]*cmds.Command{
+			"echo": {
+				Arguments: []cmdkit.Argument{cmdkit.StringArg("words", true, true, "")},
+				Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+					for _, w := range req.Arguments {
+						if err := re.Emit(w); err != nil {
+							return err
+						}
+					}
+					return nil
+				},
+			},
+			"fail": {
+				Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+					return errors.New("boom")
+				},
+			},
+			// requires write, as the command of the daemon
+			"mining": {
+				Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+					return nil
+				},
+			},
+		},
+	}
+	readCtx := auth.WithPermission(context.Background(), auth.PermRead)
+
+	waitFinished := func(require *require.Assertions, b *batchJobs, id string) *BatchJob {
+		for i := 0; i < 100; i++ {
+			job, err := b.Status(readCtx, id)
+			require.NoError(err)
+			if job.Finished {
+				return job
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		require.FailNow("batch job didn't finish")
+		return nil
+	}
+
+	t.Run("runs the commands in order and keeps their results", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		b := newBatchJobs(root, &Env{})
+		job, err := b.Submit(readCtx, []BatchCommand{
+			{Path: []string{"echo"}, Args: []string{"a", "b"}},
+			{Path: []string{"fail"}},
+			{Path: []string{"echo"}, Args: []string{"c"}},
+		})
+		require.NoError(err)
+
+		job = waitFinished(require, b, job.ID)
+		assert.Equal(3, job.Done)
+		require.Len(job.Results, 3)
+		assert.Equal([]json.RawMessage{json.RawMessage(`"a"`), json.RawMessage(`"b"`)}, job.Results[0].Values)
+		assert.Contains(job.Results[1].Error, "boom")
+		assert.Equal([]json.RawMessage{json.RawMessage(`"c"`)}, job.Results[2].Values)
+	})
+
+	t.Run("rejects invalid and unpermitted batches", func(t *testing.T) {
+		assert := assert.New(t)
+
+		b := newBatchJobs(root, &Env{})
+		_, err := b.Submit(readCtx, nil)
+		assert.Error(err)
+		_, err = b.Submit(readCtx, []BatchCommand{{Path: []string{"nope"}}})
+		assert.Contains(err.Error(), "unknown command")
+		_, err = b.Submit(readCtx, []BatchCommand{{Path: []string{"mining"}}})
+		assert.Contains(err.Error(), "doesn't grant the write permission")
+	})
+
+	t.Run("hides the jobs requiring more than the permission of the client", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		b := newBatchJobs(root, &Env{})
+		writeCtx := auth.WithPermission(context.Background(), auth.PermWrite)
+		job, err := b.Submit(writeCtx, []BatchCommand{{Path: []string{"mining"}}})
+		require.NoError(err)
+
+		_, err = b.Status(writeCtx, job.ID)
+		assert.NoError(err)
+		_, err = b.Status(readCtx, job.ID)
+		assert.Error(err)
+		_, err = b.Status(writeCtx, "42")
+		assert.Error(err)
+	})
+}
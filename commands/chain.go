@@ -8,9 +8,12 @@ import (
 	"strings"
 
 	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
 	"gx/ipfs/Qma6uuSyjkecGhMFFLfzyJDPyoDtNJSHJNweDccZhaWkgU/go-ipfs-cmds"
 	"gx/ipfs/Qmde5VP1qUkyQXKCfmEUA7bP64V2HAptbJ7phuPp7jXWwg/go-ipfs-cmdkit"
 
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/porcelain"
 	"github.com/filecoin-project/go-filecoin/types"
 )
 
@@ -47,24 +50,48 @@ var chainLsCmd = &cmds.Command{
 	Helptext: cmdkit.HelpText{
 		Tagline:          "List blocks in the blockchain",
 		ShortDescription: `Provides a list of blocks in order from head to genesis. By default, only CIDs are returned for each block.`,
+		LongDescription: `Provides a list of blocks in order from head to genesis. By default,
+only CIDs are returned for each block.
+
+The listing can be bounded by height and restricted to the blocks of a
+miner. With --limit, at most that many tipsets are listed: to list the
+following ones, pass the parents of the last tipset listed, the cids
+separated by commas, as --cursor.`,
 	},
 	Options: []cmdkit.Option{
 		cmdkit.BoolOption("long", "l", "List blocks in long format, including CID, Miner, StateRoot, block height and message count respectively"),
+		cmdkit.UintOption("from-height", "List the tipsets from this height up"),
+		cmdkit.UintOption("to-height", "List the tipsets up to this height"),
+		cmdkit.StringOption("miner", "Only list the blocks mined by this miner"),
+		cmdkit.BoolOption("messages", "List the CIDs of the messages of each block"),
+		cmdkit.UintOption("limit", "Maximum number of tipsets to list, 0 for all").WithDefault(uint(0)),
+		cmdkit.StringOption("cursor", "List from the tipset with these CIDs, comma separated, instead of the head"),
 	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
-		for raw := range GetPorcelainAPI(env).ChainLs(req.Context) {
-			switch v := raw.(type) {
-			case error:
-				return v
-			case types.TipSet:
-				if len(v) == 0 {
-					panic("tipsets from this channel should have at least one member")
-				}
-				if err := re.Emit(v.ToSlice()); err != nil {
-					return err
-				}
-			default:
-				return fmt.Errorf("unexpected type")
+		var filter porcelain.ChainLsFilter
+		if from, ok := req.Options["from-height"].(uint); ok {
+			filter.FromHeight = types.NewBlockHeight(uint64(from))
+		}
+		if to, ok := req.Options["to-height"].(uint); ok {
+			filter.ToHeight = types.NewBlockHeight(uint64(to))
+		}
+		if m, ok := req.Options["miner"].(string); ok && m != "" {
+			miner, err := address.NewFromString(m)
+			if err != nil {
+				return errors.Wrap(err, "invalid miner address")
+			}
+			filter.Miner = miner
+		}
+		cursor, _ := req.Options["cursor"].(string)
+		limit, _ := req.Options["limit"].(uint)
+
+		page, err := GetPorcelainAPI(env).ChainLsPage(req.Context, filter, cursor, int(limit))
+		if err != nil {
+			return err
+		}
+		for _, ts := range page.TipSets {
+			if err := re.Emit(ts.ToSlice()); err != nil {
+				return err
 			}
 		}
 		return nil
@@ -73,6 +100,7 @@ var chainLsCmd = &cmds.Command{
 	Encoders: cmds.EncoderMap{
 		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, res *[]types.Block) error {
 			showAll, _ := req.Options["long"].(bool)
+			showMessages, _ := req.Options["messages"].(bool)
 			blocks := *res
 
 			for _, block := range blocks {
@@ -96,6 +124,18 @@ var chainLsCmd = &cmds.Command{
 				if err != nil {
 					return err
 				}
+
+				if showMessages {
+					for _, msg := range block.Messages {
+						c, err := msg.Cid()
+						if err != nil {
+							return err
+						}
+						if _, err := fmt.Fprintf(w, "\t%s\n", c); err != nil {
+							return err
+						}
+					}
+				}
 			}
 
 			return nil
@@ -110,4 +110,30 @@ func TestChainDaemon(t *testing.T) {
 		assert.Contains(chainLsResult, "1")
 		assert.Contains(chainLsResult, "0")
 	})
+
+	t.Run("chain ls pages through the chain and filters it", func(t *testing.T) {
+		t.Parallel()
+		assert := assert.New(t)
+
+		daemon := th.NewDaemon(t, th.WithMiner(fixtures.TestMiners[0])).Start()
+		defer daemon.ShutdownSuccess()
+
+		genesisBlockCid := daemon.RunSuccess("chain", "ls").ReadStdoutTrimNewlines()
+		firstBlockCid := daemon.RunSuccess("mining", "once", "--enc", "text").ReadStdoutTrimNewlines()
+		secondBlockCid := daemon.RunSuccess("mining", "once", "--enc", "text").ReadStdoutTrimNewlines()
+
+		page1 := daemon.RunSuccess("chain", "ls", "--limit", "1").ReadStdoutTrimNewlines()
+		assert.Equal(secondBlockCid, page1)
+
+		page2 := daemon.RunSuccess("chain", "ls", "--limit", "1", "--cursor", firstBlockCid).ReadStdoutTrimNewlines()
+		assert.Equal(firstBlockCid, page2)
+
+		bounded := daemon.RunSuccess("chain", "ls", "--from-height", "0", "--to-height", "1").ReadStdoutTrimNewlines()
+		assert.Equal(fmt.Sprintf("%s\n%s", firstBlockCid, genesisBlockCid), bounded)
+
+		mined := daemon.RunSuccess("chain", "ls", "--miner", fixtures.TestMiners[0]).ReadStdoutTrimNewlines()
+		assert.Equal(fmt.Sprintf("%s\n%s", secondBlockCid, firstBlockCid), mined)
+
+		daemon.RunFail("invalid cursor", "chain", "ls", "--cursor", "nope")
+	})
 }
@@ -1,18 +1,22 @@
 package commands
 
 import (
+	"encoding/hex"
 	"fmt"
 	"io"
 	"strconv"
 
 	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
 	"gx/ipfs/Qma6uuSyjkecGhMFFLfzyJDPyoDtNJSHJNweDccZhaWkgU/go-ipfs-cmds"
+	ipld "gx/ipfs/QmcKKBwfz6FyQdHR2jsXrrF6XeSBXYL86anmWNewpFpoF5/go-ipld-format"
 	"gx/ipfs/Qmde5VP1qUkyQXKCfmEUA7bP64V2HAptbJ7phuPp7jXWwg/go-ipfs-cmdkit"
 
 	"github.com/filecoin-project/go-filecoin/actor/builtin/paymentbroker"
-	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/api"
+	"github.com/filecoin-project/go-filecoin/protocol/datatransfer"
 	"github.com/filecoin-project/go-filecoin/protocol/storage"
+	"github.com/filecoin-project/go-filecoin/types"
 )
 
 var clientCmd = &cmds.Command{
@@ -21,10 +25,19 @@ var clientCmd = &cmds.Command{
 	},
 	Subcommands: map[string]*cmds.Command{
 		"cat":                  clientCatCmd,
+		"commP":                clientCommPCmd,
+		"deal-events":          clientDealEventsCmd,
+		"export-car":           clientExportCarCmd,
 		"import":               clientImportDataCmd,
 		"propose-storage-deal": clientProposeStorageDealCmd,
 		"query-storage-deal":   clientQueryStorageDealCmd,
 		"list-asks":            clientListAsksCmd,
+		"ls":                   clientLsCmd,
+		"miner-reputation":     clientMinerReputationCmd,
+		"renew-deal":           clientRenewDealCmd,
+		"replication":          clientReplicationCmd,
+		"select-miners":        clientSelectMinersCmd,
+		"transfer":             clientTransferCmd,
 		"payments":             paymentsCmd,
 	},
 }
@@ -36,6 +49,9 @@ var clientCatCmd = &cmds.Command{
 Prints data from the storage market specified with a given CID to stdout. The
 only argument should be the CID to return. The data will be returned in whatever
 format was provided with the data initially.
+
+If the node does not hold the data but stored it in deals, the data is retrieved
+from the cheapest of the miners storing it and pinned in the node.
 `,
 	},
 	Arguments: []cmdkit.Argument{
@@ -56,6 +72,44 @@ format was provided with the data initially.
 	},
 }
 
+var clientLsCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "List the links of data stored on the network",
+		ShortDescription: `
+Prints the CID, size and name of each link of the UnixFS node with the given
+CID. Like client cat, the data is retrieved from the miners storing it in deals
+if the node does not hold it.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("cid", true, false, "CID of data to list"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		c, err := cid.Decode(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		links, err := GetAPI(env).Client().Ls(req.Context, c)
+		if err != nil {
+			return err
+		}
+		for _, l := range links {
+			if err := re.Emit(l); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Type: ipld.Link{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, l *ipld.Link) error {
+			_, err := fmt.Fprintf(w, "%s\t%d\t%s\n", l.Cid, l.Size, l.Name)
+			return err
+		}),
+	},
+}
+
 var clientImportDataCmd = &cmds.Command{
 	Helptext: cmdkit.HelpText{
 		Tagline: "Import data into the local node",
@@ -63,6 +117,8 @@ var clientImportDataCmd = &cmds.Command{
 Imports data previously exported with the client cat command into the storage
 market. This command takes only one argument, the path of the file to import.
 See the go-filecoin client cat command for more details.
+
+The data is pinned, so repo gc keeps it until it is unpinned with repo pin rm.
 `,
 	},
 	Arguments: []cmdkit.Argument{
@@ -89,6 +145,80 @@ See the go-filecoin client cat command for more details.
 	},
 }
 
+// PieceCommitmentResult is the piece commitment of a file and its size.
+type PieceCommitmentResult struct {
+	CommP      string
+	Size       uint64
+	PaddedSize uint64
+}
+
+var clientCommPCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Compute the piece commitment of a file",
+		ShortDescription: `
+Computes the piece commitment (CommP) of the file at the given path, and the
+size the file is padded to when a miner writes it to a sector. The computation
+runs locally without touching the network, so the result can be used to check
+what a miner commits to, or to prepare deals offline.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.FileArg("file", true, false, "Path to the file").EnableStdin(),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		fi, err := req.Files.NextFile()
+		if err != nil {
+			return err
+		}
+
+		commP, size, paddedSize, err := GetPorcelainAPI(env).PieceCommitment(req.Context, fi)
+		if err != nil {
+			return err
+		}
+
+		return re.Emit(&PieceCommitmentResult{
+			CommP:      hex.EncodeToString(commP[:]),
+			Size:       size,
+			PaddedSize: paddedSize,
+		})
+	},
+	Type: PieceCommitmentResult{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, res *PieceCommitmentResult) error {
+			fmt.Fprintf(w, "CommP:       %s\n", res.CommP)      // nolint: errcheck
+			fmt.Fprintf(w, "Size:        %d\n", res.Size)       // nolint: errcheck
+			fmt.Fprintf(w, "Padded size: %d\n", res.PaddedSize) // nolint: errcheck
+			return nil
+		}),
+	},
+}
+
+var clientExportCarCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Export data as a CAR file",
+		ShortDescription: `
+Writes the data with the given CID to stdout as a CAR file. Miners import the
+data of offline deals from such files with the miner import-deal-data command.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("cid", true, false, "CID of data to export"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		c, err := cid.Decode(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		r, w := io.Pipe()
+		go func() {
+			w.CloseWithError(GetAPI(env).Client().ExportCar(req.Context, c, w)) // nolint: errcheck
+		}()
+
+		return re.Emit(r)
+	},
+}
+
 var clientProposeStorageDealCmd = &cmds.Command{
 	Helptext: cmdkit.HelpText{
 		Tagline:          "Propose a storage deal with a storage miner",
@@ -105,21 +235,56 @@ Duration should be specified with the number of blocks for which to store the
 data. New blocks are generated about every 30 seconds, so the time given should
 be represented as a count of 30 second intervals. For example, 1 minute would
 be 2, 1 hour would be 120, and 1 day would be 2880.
+
+Without miner and ask, the deal is proposed to the miners of connected peers
+ranked by client select-miners, best first, until one accepts it.
+
+With --replicas N, the data is stored with N miners: the deal with the given
+miner and ask is the first replica, the others are proposed to the miners of
+connected peers asking at most the same price, ranked by client select-miners.
+Replicas whose deals fail are replaced until the deals end. Check their health
+with client replication.
 `,
 	},
 	Arguments: []cmdkit.Argument{
-		cmdkit.StringArg("miner", true, false, "Address of miner to send storage proposal"),
+		cmdkit.StringArg("miner", false, false, "Address or address book label of miner to send storage proposal"),
 		cmdkit.StringArg("data", true, false, "CID of the data to be stored"),
-		cmdkit.StringArg("ask", true, false, "ID of ask for which to propose a deal"),
+		cmdkit.StringArg("ask", false, false, "ID of ask for which to propose a deal"),
 		cmdkit.StringArg("duration", true, false, "Time in blocks (about 30 seconds per block) to store data"),
 	},
 	Options: []cmdkit.Option{
 		cmdkit.BoolOption("allow-duplicates", "Allows duplicate proposals to be created. Unless this flag is set, you will not be able to make more than one deal per piece per miner. This protection exists to prevent erroneous duplicate deals."),
+		cmdkit.BoolOption("offline", "Deliver the data out of band rather than over the network. Export it with client export-car for the miner to import."),
+		cmdkit.UintOption("replicas", "Number of miners to store the data with").WithDefault(uint(1)),
 	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
 		allowDuplicates, _ := req.Options["allow-duplicates"].(bool)
+		offline, _ := req.Options["offline"].(bool)
+		replicas, _ := req.Options["replicas"].(uint)
+
+		if len(req.Arguments) == 2 {
+			if offline || replicas > 1 {
+				return errors.New("a miner and ask are required for offline and replicated deals")
+			}
+			data, err := cid.Decode(req.Arguments[0])
+			if err != nil {
+				return err
+			}
+			duration, err := strconv.ParseUint(req.Arguments[1], 10, 64)
+			if err != nil {
+				return err
+			}
+			resp, err := GetAPI(env).Client().ProposeStorageDealToBestMiner(req.Context, data, duration, allowDuplicates)
+			if err != nil {
+				return err
+			}
+			return re.Emit(resp)
+		}
+		if len(req.Arguments) != 4 {
+			return errors.New("give both a miner and an ask, or neither")
+		}
 
-		miner, err := address.NewFromString(req.Arguments[0])
+		miner, err := resolveAddr(env, req.Arguments[0])
 		if err != nil {
 			return err
 		}
@@ -139,7 +304,24 @@ be 2, 1 hour would be 120, and 1 day would be 2880.
 			return err
 		}
 
-		resp, err := GetAPI(env).Client().ProposeStorageDeal(req.Context, data, miner, askid, duration, allowDuplicates)
+		if replicas > 1 {
+			if offline {
+				return errors.New("offline deals can't be replicated")
+			}
+			resps, err := GetAPI(env).Client().ProposeReplicatedStorageDeal(req.Context, data, miner, askid, duration, int(replicas), allowDuplicates)
+			for _, resp := range resps {
+				if err := re.Emit(resp); err != nil {
+					return err
+				}
+			}
+			return err
+		}
+
+		propose := GetAPI(env).Client().ProposeStorageDeal
+		if offline {
+			propose = GetAPI(env).Client().ProposeOfflineStorageDeal
+		}
+		resp, err := propose(req.Context, data, miner, askid, duration, allowDuplicates)
 		if err != nil {
 			return err
 		}
@@ -192,17 +374,226 @@ format is specified with the --enc flag.
 	},
 }
 
+var clientReplicationCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show the health of data replicated to several miners",
+		ShortDescription: `
+Lists the deals storing the replicas of data proposed with
+client propose-storage-deal --replicas, including the failed ones the client
+replaced.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("data", true, false, "CID of the replicated data"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		data, err := cid.Decode(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		status, err := GetAPI(env).Client().StorageReplication(req.Context, data)
+		if err != nil {
+			return err
+		}
+		return re.Emit(status)
+	},
+	Type: storage.ReplicationStatus{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, status *storage.ReplicationStatus) error {
+			fmt.Fprintf(w, "Healthy: %d of %d replicas\n", status.Healthy, status.Replicas) // nolint: errcheck
+			fmt.Fprintf(w, "Expiry:  %s\n", status.Expiry)                                  // nolint: errcheck
+			for _, d := range status.Deals {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", d.ProposalCid, d.Miner, d.State) // nolint: errcheck
+			}
+			return nil
+		}),
+	},
+}
+
+var clientDealEventsCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Stream the events of storage deals",
+		ShortDescription: `
+Prints an event every time a storage deal of this node is accepted, rejected,
+makes progress transferring its data, is sealed, has its storage proven by the
+miner, completes or fails, until interrupted. If an id is given, only the
+events of that deal are printed.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("id", false, false, "CID of the deal to follow"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		var propcid cid.Cid
+		if len(req.Arguments) > 0 {
+			c, err := cid.Decode(req.Arguments[0])
+			if err != nil {
+				return err
+			}
+			propcid = c
+		}
+
+		events, err := GetAPI(env).Client().DealEvents(req.Context)
+		if err != nil {
+			return err
+		}
+		for e := range events {
+			if propcid.Defined() && !propcid.Equals(e.ProposalCid) {
+				continue
+			}
+			e := e
+			if err := re.Emit(&e); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Type: storage.DealEvent{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, e *storage.DealEvent) error {
+			var err error
+			switch {
+			case e.Transfer != nil && e.Transfer.ExpectedBytes > 0:
+				_, err = fmt.Fprintf(w, "%s\t%s\t%d%%\n", e.ProposalCid, e.Type, e.Transfer.BytesReceived*100/e.Transfer.ExpectedBytes)
+			case e.Type == storage.DealPoSted:
+				_, err = fmt.Fprintf(w, "%s\t%s\tat height %d\n", e.ProposalCid, e.Type, e.BlockHeight)
+			case e.Message != "":
+				_, err = fmt.Fprintf(w, "%s\t%s\t%s\n", e.ProposalCid, e.Type, e.Message)
+			default:
+				_, err = fmt.Fprintf(w, "%s\t%s\n", e.ProposalCid, e.Type)
+			}
+			return err
+		}),
+	},
+}
+
+var clientTransferCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Inspect the transfer of the data of storage deals to miners",
+	},
+	Subcommands: map[string]*cmds.Command{
+		"status": clientTransferStatusCmd,
+	},
+}
+
+var clientTransferStatusCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show the progress of the transfer of a storage deal's data",
+		ShortDescription: `
+Queries the miner of the storage deal specified by the id for the progress of
+the transfer of the deal's data to it. The number of bytes and blocks received
+so far, and the number of times the transfer restarted, e.g. after the peers
+disconnected, are returned as a formatted string unless another format is
+specified with the --enc flag. The miner only reports progress while the data
+is being transferred.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("id", true, false, "CID of the deal proposal"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		propcid, err := cid.Decode(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		resp, err := GetAPI(env).Client().QueryStorageDeal(req.Context, propcid)
+		if err != nil {
+			return err
+		}
+
+		return re.Emit(resp)
+	},
+	Type: storage.DealResponse{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, resp *storage.DealResponse) error {
+			fmt.Fprintf(w, "Deal status: %s\n", resp.State.String()) // nolint: errcheck
+			if resp.Transfer == nil {
+				fmt.Fprintln(w, "Transfer: not in progress") // nolint: errcheck
+				return nil
+			}
+			return printTransferStatus(w, resp.Transfer)
+		}),
+	},
+}
+
+func printTransferStatus(w io.Writer, status *datatransfer.Status) error {
+	fmt.Fprintf(w, "Transfer: %s\n", status.State) // nolint: errcheck
+	if status.ExpectedBytes > 0 {
+		fmt.Fprintf(w, "Received: %d of %d bytes in %d blocks\n", status.BytesReceived, status.ExpectedBytes, status.BlocksReceived) // nolint: errcheck
+	} else {
+		fmt.Fprintf(w, "Received: %d bytes in %d blocks\n", status.BytesReceived, status.BlocksReceived) // nolint: errcheck
+	}
+	fmt.Fprintf(w, "Retries: %d\n", status.Retries) // nolint: errcheck
+	if status.Error != "" {
+		fmt.Fprintf(w, "Last error: %s\n", status.Error) // nolint: errcheck
+	}
+	return nil
+}
+
+var clientRenewDealCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Renew a storage deal automatically before it expires",
+		ShortDescription: `
+Opts the storage deal specified by the id in to automatic renewal. As the deal
+nears its expiry, within client.renewalWindow blocks, the client proposes a
+deal storing the same data with the same miner for the same duration, and
+renews that deal in turn. Renewals use the cheapest ask of the miner costing at
+most --max-price FIL per byte per block, client.maxRenewalPrice by default, or
+the price of the original deal if that is zero. Use --disable to opt out.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("id", true, false, "CID of the deal to renew"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.StringOption("max-price", "Highest price in FIL per byte per block to renew the deal at"),
+		cmdkit.BoolOption("disable", "Don't renew the deal"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		propcid, err := cid.Decode(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		var maxPrice *types.AttoFIL
+		if o, ok := req.Options["max-price"].(string); ok {
+			maxPrice, ok = types.NewAttoFILFromFILString(o)
+			if !ok {
+				return ErrInvalidPrice
+			}
+		}
+		disable, _ := req.Options["disable"].(bool)
+
+		return GetAPI(env).Client().SetDealRenewal(req.Context, propcid, !disable, maxPrice)
+	},
+}
+
 var clientListAsksCmd = &cmds.Command{
 	Helptext: cmdkit.HelpText{
 		Tagline: "List all asks in the storage market",
 		ShortDescription: `
 Lists all asks in the storage market. This command takes no arguments. Results
 will be returned as a space separated table with miner, id, price and expiration
-respectively.
+respectively, followed by the minimum and maximum piece size if the ask bounds
+them.
+
+By default the asks are read from the chain state. With --peers the connected
+peers are queried for the asks of the miners they operate instead, which only
+returns asks that have not expired.
 `,
 	},
+	Options: []cmdkit.Option{
+		cmdkit.BoolOption("peers", "Query connected peers for their asks instead of reading the chain state"),
+	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
-		asksCh, err := GetAPI(env).Client().ListAsks(req.Context)
+		listAsks := GetAPI(env).Client().ListAsks
+		if peers, _ := req.Options["peers"].(bool); peers {
+			listAsks = GetAPI(env).Client().ListPeerAsks
+		}
+
+		asksCh, err := listAsks(req.Context)
 		if err != nil {
 			return err
 		}
@@ -220,12 +611,24 @@ respectively.
 	Type: api.Ask{},
 	Encoders: cmds.EncoderMap{
 		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, ask *api.Ask) error {
-			fmt.Fprintf(w, "%s %.3d %s %s\n", ask.Miner, ask.ID, ask.Price, ask.Expiry) // nolint: errcheck
+			fmt.Fprintf(w, "%s %.3d %s %s", ask.Miner, ask.ID, ask.Price, ask.Expiry) // nolint: errcheck
+			if ask.MinPieceSize != nil || ask.MaxPieceSize != nil {
+				fmt.Fprintf(w, " %s %s", pieceSizeString(ask.MinPieceSize), pieceSizeString(ask.MaxPieceSize)) // nolint: errcheck
+			}
+			fmt.Fprintln(w) // nolint: errcheck
 			return nil
 		}),
 	},
 }
 
+// pieceSizeString returns the piece size bound of an ask, "-" if unbounded.
+func pieceSizeString(size *types.BytesAmount) string {
+	if size == nil {
+		return "-"
+	}
+	return size.String()
+}
+
 var paymentsCmd = &cmds.Command{
 	Helptext: cmdkit.HelpText{
 		Tagline:          "List payments for a given deal",
@@ -268,3 +671,84 @@ var paymentsCmd = &cmds.Command{
 		}),
 	},
 }
+
+var clientMinerReputationCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show what the client observed of the miners it made deals with",
+		ShortDescription: `
+Lists, for each miner the client proposed deals to, how many proposals the miner
+accepted, how many deals it proved or failed, the failed proofs it submitted on
+chain, and how fast data was transferred to it. The score ranks miners in
+client select-miners.
+`,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		reputations, err := GetAPI(env).Client().MinerReputations(req.Context)
+		if err != nil {
+			return err
+		}
+		for _, r := range reputations {
+			if err := re.Emit(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Type: storage.MinerReputation{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, r *storage.MinerReputation) error {
+			_, err := fmt.Fprintf(w, "%s\taccepted %d/%d\tproven %d\tfailed %d\tfaults %d\t%d B/s\tscore %.3f\n",
+				r.Miner, r.Accepted, r.Proposals, r.Proven, r.Failed, r.Faults, r.TransferSpeed(), r.Score())
+			return err
+		}),
+	},
+}
+
+var clientSelectMinersCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Rank the miners of connected peers for storing data",
+		ShortDescription: `
+Lists the miners of connected peers with an ask for the size of the data, best
+first. Miners are ranked by the price of their cheapest ask divided by their
+reputation score, see client miner-reputation.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("data", true, false, "CID of the data to be stored"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.StringOption("max-price", "Highest price in FIL per byte per block of the asks to consider"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		data, err := cid.Decode(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		var maxPrice *types.AttoFIL
+		if o, ok := req.Options["max-price"].(string); ok {
+			maxPrice, ok = types.NewAttoFILFromFILString(o)
+			if !ok {
+				return ErrInvalidPrice
+			}
+		}
+
+		choices, err := GetAPI(env).Client().SelectMiners(req.Context, data, maxPrice)
+		if err != nil {
+			return err
+		}
+		for _, c := range choices {
+			if err := re.Emit(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Type: storage.MinerChoice{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, c *storage.MinerChoice) error {
+			_, err := fmt.Fprintf(w, "%s\task %s\tprice %s\tscore %.3f\n", c.Miner, c.Ask.ID, c.Ask.Price, c.Score)
+			return err
+		}),
+	},
+}
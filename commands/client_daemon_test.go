@@ -187,3 +187,35 @@ func TestVoucherPersistenceAndPayments(t *testing.T) {
 	assert.Contains(result, "0\t480000\t20")
 	assert.Contains(result, "0\t720000\t30")
 }
+
+func TestClientCommP(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	d := th.NewDaemon(t).Start()
+	defer d.ShutdownSuccess()
+
+	out := d.RunWithStdin(strings.NewReader("HODLHODLHODL"), "client", "commP").ReadStdout()
+	assert.Contains(out, "Size:        12")
+	assert.Contains(out, "Padded size: 128")
+
+	other := d.RunWithStdin(strings.NewReader("HODLHODLHODL"), "client", "commP").ReadStdout()
+	assert.Equal(out, other)
+}
+
+func TestClientLs(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	d := th.NewDaemon(t).Start()
+	defer d.ShutdownSuccess()
+
+	// three chunks of the default chunker
+	data := strings.Repeat("HODL", 150000)
+	c := d.RunWithStdin(strings.NewReader(data), "client", "import").ReadStdoutTrimNewlines()
+
+	links := strings.Split(d.RunSuccess("client", "ls", c).ReadStdoutTrimNewlines(), "\n")
+	assert.Len(links, 3)
+
+	assert.Equal(data, d.RunSuccess("client", "cat", c).ReadStdout())
+}
@@ -2,11 +2,15 @@ package commands
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"strings"
 
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
 	"gx/ipfs/Qma6uuSyjkecGhMFFLfzyJDPyoDtNJSHJNweDccZhaWkgU/go-ipfs-cmds"
 	"gx/ipfs/Qmde5VP1qUkyQXKCfmEUA7bP64V2HAptbJ7phuPp7jXWwg/go-ipfs-cmdkit"
+
+	"github.com/filecoin-project/go-filecoin/config"
 )
 
 var configCmd = &cmds.Command{
@@ -71,6 +75,10 @@ $ go-filecoin config bootstrap
 }
 `,
 	},
+	Subcommands: map[string]*cmds.Command{
+		"doc":    configDocCmd,
+		"reload": configReloadCmd,
+	},
 	Arguments: []cmdkit.Argument{
 		cmdkit.StringArg("key", true, false, "The key of the config entry (e.g. \"api.address\")"),
 		cmdkit.StringArg("value", false, false, "Optionally, a value with which to set the config entry"),
@@ -109,3 +117,74 @@ $ go-filecoin config bootstrap
 		}),
 	},
 }
+
+var configDocCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Document a config setting",
+		ShortDescription: `
+Prints the type, the default value and the description of the config setting
+with the given key, and for a section of the config such as "api", the keys of
+the settings under it. The daemon need not be running.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("key", true, false, "The key of the config setting (e.g. \"api.address\")"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		doc, err := config.Doc(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+		return re.Emit(doc)
+	},
+	Type: &config.KeyDoc{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, doc *config.KeyDoc) error {
+			_, err := fmt.Fprint(w, doc.String())
+			return err
+		}),
+	},
+}
+
+var configReloadCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Apply the changes to the config file to the running daemon",
+		ShortDescription: `
+Re-reads the config file of the repo and applies the settings a running daemon
+can change without a restart: the levels of the logs (log.levels), the
+bootstrap peers (bootstrap), the mining delay (mining.mineDelay) and the CORS
+settings of the api (api.accessControlAllowOrigin,
+api.accessControlAllowMethods and api.accessControlAllowCredentials). Prints
+the settings changed.
+
+Nothing is applied if the config file changes any other setting, those require
+restarting the daemon. Sending SIGHUP to the daemon reloads its config too.
+`,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		reload := env.(*Env).reloadConfig
+		if reload == nil {
+			return errors.New("the config can only be reloaded by a running daemon")
+		}
+		changed, err := reload()
+		if err != nil {
+			return err
+		}
+		return re.Emit(changed)
+	},
+	Type: []string{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, changed []string) error {
+			if len(changed) == 0 {
+				_, err := fmt.Fprintln(w, "No settings changed")
+				return err
+			}
+			for _, key := range changed {
+				if _, err := fmt.Fprintf(w, "Reloaded %s\n", key); err != nil {
+					return err
+				}
+			}
+			return nil
+		}),
+	},
+}
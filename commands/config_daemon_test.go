@@ -1,8 +1,11 @@
 package commands
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"path/filepath"
 	"testing"
 
 	"github.com/filecoin-project/go-filecoin/config"
@@ -79,4 +82,60 @@ func TestConfigDaemon(t *testing.T) {
 		cfg := d.Config()
 		assert.Equal(cfg.Bootstrap, bootstrapConfig)
 	})
+
+	t.Run("config reload applies the changes to the config file", func(t *testing.T) {
+		t.Parallel()
+		assert := assert.New(t)
+		require := require.New(t)
+
+		d := th.NewDaemon(t).Start()
+		defer d.ShutdownSuccess()
+		configFile := filepath.Join(d.RepoDir(), "config.json")
+
+		cfg := d.Config()
+		cfg.Bootstrap.Period = "5m"
+		cfg.Log.Levels = map[string]string{"chain.syncer": "debug"}
+		require.NoError(cfg.WriteFile(configFile))
+
+		out := d.RunSuccess("config", "reload").ReadStdout()
+		assert.Equal("Reloaded bootstrap.period\nReloaded log.levels\n", out)
+		assert.Equal("\"5m\"\n", d.RunSuccess("config", "bootstrap.period").ReadStdout())
+
+		// settings requiring a restart are turned away, along with the
+		// other changes
+		cfg.Bootstrap.Period = "10m"
+		cfg.Datastore.Path = "elsewhere"
+		require.NoError(cfg.WriteFile(configFile))
+		d.RunFail("changing datastore.path requires restarting the daemon", "config", "reload")
+		assert.Equal("\"5m\"\n", d.RunSuccess("config", "bootstrap.period").ReadStdout())
+	})
+
+	t.Run("config doc <key> documents the setting", func(t *testing.T) {
+		t.Parallel()
+		assert := assert.New(t)
+
+		d := th.NewDaemon(t).Start()
+		defer d.ShutdownSuccess()
+
+		out := d.RunSuccess("config", "doc", "api.address").ReadStdout()
+		assert.Contains(out, "type:    a string")
+		assert.Contains(out, `default: "/ip4/127.0.0.1/tcp/3453"`)
+
+		d.RunFail(`did you mean "api.address"?`, "config", "doc", "api.adress")
+	})
+
+	t.Run("daemon refuses to start with unknown keys in the config file", func(t *testing.T) {
+		t.Parallel()
+		require := require.New(t)
+
+		d := th.NewDaemon(t)
+		configFile := filepath.Join(d.RepoDir(), "config.json")
+		raw, err := ioutil.ReadFile(configFile)
+		require.NoError(err)
+
+		raw = bytes.Replace(raw, []byte(`"minPeerThreshold"`), []byte(`"minPeerTreshold"`), 1)
+		require.NoError(ioutil.WriteFile(configFile, raw, 0644))
+
+		d.RunFail(`did you mean "bootstrap.minPeerThreshold"?`, "daemon")
+	})
 }
@@ -0,0 +1,360 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"gx/ipfs/Qma6uuSyjkecGhMFFLfzyJDPyoDtNJSHJNweDccZhaWkgU/go-ipfs-cmds"
+	"gx/ipfs/Qma6uuSyjkecGhMFFLfzyJDPyoDtNJSHJNweDccZhaWkgU/go-ipfs-cmds/cli"
+	"gx/ipfs/Qmde5VP1qUkyQXKCfmEUA7bP64V2HAptbJ7phuPp7jXWwg/go-ipfs-cmdkit"
+)
+
+const consoleHelp = `Enter commands without the go-filecoin prefix, e.g. "wallet addrs ls".
+
+  name = command   runs command and stores its output, trimmed, in name
+  $name, ${name}   are replaced by the value of name, except in single quotes
+  <partial>Tab     typing Tab then Enter lists the completions of the line
+  help             prints this help
+  exit, quit       leave the console
+`
+
+var consoleCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Run commands against the daemon interactively",
+		ShortDescription: `
+Connects to the running daemon and reads commands, one per line, from stdin.
+Outputs can be stored in variables and used by later commands, so flows such
+as sending a message then waiting for it can be run in a single session.
+`,
+		LongDescription: `
+Connects to the running daemon and reads commands, one per line, from stdin.
+Commands are entered without the go-filecoin prefix, and quoted as in a
+shell. Lines starting with # are comments.
+
+The output of a command is stored, trimmed, in a variable with
+'name = command', and $name or ${name} is replaced by it in later commands:
+
+  > msg = message send --from $from --value 10 --price 0 --limit 300 $to
+  > message wait $msg
+
+Typing Tab then Enter after a partial line lists its completions.
+
+When stdin isn't a terminal, e.g. a script is piped in, the console stops
+at the first command which fails and exits with an error.
+`,
+	},
+}
+
+func init() {
+	// set during init() as the console runs the other commands, see rootCmd
+	consoleCmd.Run = func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		c := newConsole(req, os.Stdout, os.Stderr)
+		if err := c.connect(req.Context); err != nil {
+			return err
+		}
+		return c.run(req.Context, os.Stdin, isTerminal(os.Stdin))
+	}
+}
+
+// console runs the commands entered in a session of the console command.
+type console struct {
+	// globalArgs are passed to every command, so that they all reach the
+	// daemon the console connected to.
+	globalArgs []string
+	vars       map[string]string
+	stdout     *os.File
+	stderr     *os.File
+}
+
+func newConsole(req *cmds.Request, stdout, stderr *os.File) *console {
+	var globalArgs []string
+	for _, name := range []string{OptionRepoDir, OptionAPI} {
+		if v, ok := req.Options[name].(string); ok && v != "" {
+			globalArgs = append(globalArgs, fmt.Sprintf("--%s=%s", name, v))
+		}
+	}
+	return &console{
+		globalArgs: globalArgs,
+		vars:       make(map[string]string),
+		stdout:     stdout,
+		stderr:     stderr,
+	}
+}
+
+// connect checks the daemon is running.
+func (c *console) connect(ctx context.Context) error {
+	if _, err := c.capture(ctx, []string{"id"}); err != nil {
+		return ErrMissingDaemon
+	}
+	return nil
+}
+
+// run runs the commands read from in until it ends or exit is entered. When
+// interactive, a prompt is printed and failing commands don't stop it.
+func (c *console) run(ctx context.Context, in io.Reader, interactive bool) error {
+	if interactive {
+		fmt.Fprint(c.stdout, "Connected to the daemon, enter help for help.\n> ") // nolint: errcheck
+	}
+
+	scanner := bufio.NewScanner(in)
+	for n := 1; scanner.Scan(); n++ {
+		stop, err := c.handle(ctx, scanner.Text())
+		if err != nil {
+			if !interactive {
+				return fmt.Errorf("line %d: %s", n, err)
+			}
+			fmt.Fprintln(c.stderr, err) // nolint: errcheck
+		}
+		if stop || ctx.Err() != nil {
+			return nil
+		}
+		if interactive {
+			fmt.Fprint(c.stdout, "> ") // nolint: errcheck
+		}
+	}
+	return scanner.Err()
+}
+
+// handle runs line and returns whether the console should stop.
+func (c *console) handle(ctx context.Context, line string) (bool, error) {
+	if strings.HasSuffix(line, "\t") {
+		fmt.Fprintln(c.stdout, strings.Join(complete(strings.TrimRight(line, "\t")), " ")) // nolint: errcheck
+		return false, nil
+	}
+
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return false, nil
+	}
+
+	args, err := splitLine(line, c.vars)
+	if err != nil {
+		return false, err
+	}
+
+	switch args[0] {
+	case "exit", "quit":
+		return true, nil
+	case "help":
+		fmt.Fprint(c.stdout, consoleHelp) // nolint: errcheck
+		return false, nil
+	}
+
+	if len(args) >= 3 && args[1] == "=" && isVarName(args[0]) {
+		out, err := c.capture(ctx, args[2:])
+		if err != nil {
+			return false, err
+		}
+		c.vars[args[0]] = strings.TrimSpace(out)
+		return false, nil
+	}
+
+	return false, c.exec(ctx, args, c.stdout)
+}
+
+// exec runs the command args, writing its output to stdout. The errors of
+// commands are written to stderr by the command itself.
+func (c *console) exec(ctx context.Context, args []string, stdout *os.File) error {
+	if _, ok := rootSubcmdsLocal[args[0]]; ok {
+		return fmt.Errorf("%s can't be run from the console", args[0])
+	}
+
+	// the commands mustn't read the lines meant for the console
+	stdin, err := os.Open(os.DevNull)
+	if err != nil {
+		return err
+	}
+	defer stdin.Close() // nolint: errcheck
+
+	cmdline := append(append([]string{"go-filecoin"}, args...), c.globalArgs...)
+	if err := cli.Run(ctx, rootCmd, cmdline, stdin, stdout, c.stderr, buildEnv, makeExecutor); err != nil {
+		return fmt.Errorf("%s failed", strings.Join(args, " "))
+	}
+	return nil
+}
+
+// capture runs the command args and returns its output.
+func (c *console) capture(ctx context.Context, args []string) (string, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return "", err
+	}
+	defer r.Close() // nolint: errcheck
+
+	var out bytes.Buffer
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		io.Copy(&out, r) // nolint: errcheck
+	}()
+
+	err = c.exec(ctx, args, w)
+	w.Close() // nolint: errcheck
+	<-done
+	return out.String(), err
+}
+
+// splitLine splits line into arguments as a shell would, replacing the
+// variables outside single quotes by their value in vars.
+func splitLine(line string, vars map[string]string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	inArg := false
+	var quote rune
+
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\\' && i+1 < len(runes) && (quote == 0 || runes[i+1] == '"' || runes[i+1] == '\\' || runes[i+1] == '$'):
+			i++
+			cur.WriteRune(runes[i])
+			inArg = true
+		case r == '$':
+			name, n := varName(runes[i+1:])
+			if name == "" {
+				cur.WriteRune(r)
+			} else {
+				v, ok := vars[name]
+				if !ok {
+					return nil, fmt.Errorf("undefined variable %s", name)
+				}
+				cur.WriteString(v)
+				i += n
+			}
+			inArg = true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
+
+// varName returns the name of the variable at the start of runes, which
+// follow a $, and the number of runes it spans, braces included.
+func varName(runes []rune) (string, int) {
+	if len(runes) > 0 && runes[0] == '{' {
+		for i := 1; i < len(runes); i++ {
+			if runes[i] == '}' {
+				return string(runes[1:i]), i + 1
+			}
+		}
+		return "", 0
+	}
+	n := 0
+	for n < len(runes) && isVarRune(runes[n], n) {
+		n++
+	}
+	return string(runes[:n]), n
+}
+
+func isVarName(s string) bool {
+	for i, r := range s {
+		if !isVarRune(r, i) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func isVarRune(r rune, i int) bool {
+	return r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (i > 0 && r >= '0' && r <= '9')
+}
+
+// complete returns the completions of the last word of line: the names of
+// the subcommands of the command it names so far, or of its options if the
+// word starts with a dash.
+func complete(line string) []string {
+	words := strings.Fields(line)
+	partial := ""
+	if len(words) > 0 && !strings.HasSuffix(line, " ") {
+		partial = words[len(words)-1]
+		words = words[:len(words)-1]
+	}
+
+	cmd := rootCmd
+	for _, w := range words {
+		if strings.HasPrefix(w, "-") {
+			continue
+		}
+		sub, ok := cmd.Subcommands[w]
+		if !ok {
+			break
+		}
+		cmd = sub
+	}
+
+	var candidates []string
+	if strings.HasPrefix(partial, "-") {
+		for _, opts := range [][]cmdkit.Option{cmd.Options, rootCmd.Options} {
+			for _, opt := range opts {
+				for _, name := range opt.Names() {
+					if len(name) > 1 {
+						candidates = append(candidates, "--"+name)
+					}
+				}
+			}
+		}
+	} else {
+		for name := range cmd.Subcommands {
+			if cmd == rootCmd {
+				if _, local := rootSubcmdsLocal[name]; local {
+					continue
+				}
+			}
+			candidates = append(candidates, name)
+		}
+		if cmd == rootCmd {
+			candidates = append(candidates, "exit", "help", "quit")
+		}
+	}
+
+	var matches []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, partial) {
+			matches = append(matches, c)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+// isTerminal returns whether f is a terminal.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
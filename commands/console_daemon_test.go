@@ -0,0 +1,42 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	th "github.com/filecoin-project/go-filecoin/testhelpers"
+)
+
+func TestConsoleDaemon(t *testing.T) {
+	t.Parallel()
+
+	t.Run("runs a script using the outputs of previous commands", func(t *testing.T) {
+		t.Parallel()
+		assert := assert.New(t)
+
+		d := th.NewDaemon(t).Start()
+		defer d.ShutdownSuccess()
+
+		script := strings.Join([]string{
+			"# create an address and look at its balance",
+			"addr = address new",
+			"wallet balance $addr",
+		}, "\n")
+		out := d.RunWithStdin(strings.NewReader(script), "console").AssertSuccess().ReadStdoutTrimNewlines()
+
+		// only the balance is printed, the address went to the variable
+		assert.Equal("0", out)
+	})
+
+	t.Run("stops a script at the first command failing", func(t *testing.T) {
+		t.Parallel()
+
+		d := th.NewDaemon(t).Start()
+		defer d.ShutdownSuccess()
+
+		script := "chain ls --cursor nope\naddress ls\n"
+		d.RunWithStdin(strings.NewReader(script), "console").AssertFail("line 1")
+	})
+}
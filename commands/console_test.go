@@ -0,0 +1,53 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitLine(t *testing.T) {
+	t.Parallel()
+
+	vars := map[string]string{"to": "t1abc", "amount": "10"}
+
+	t.Run("splits on spaces and expands variables", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		args, err := splitLine(`message send  --value $amount ${to}x`, vars)
+		require.NoError(err)
+		assert.Equal([]string{"message", "send", "--value", "10", "t1abcx"}, args)
+	})
+
+	t.Run("honours quotes and escapes", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		args, err := splitLine(`a "b $to c" '$to d' e\ f "g\"h" ''`, vars)
+		require.NoError(err)
+		assert.Equal([]string{"a", "b t1abc c", "$to d", "e f", `g"h`, ""}, args)
+	})
+
+	t.Run("rejects undefined variables and unterminated quotes", func(t *testing.T) {
+		assert := assert.New(t)
+
+		_, err := splitLine(`wallet balance $from`, vars)
+		assert.Error(err)
+		_, err = splitLine(`config "api`, vars)
+		assert.Error(err)
+	})
+}
+
+func TestConsoleComplete(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	assert.Equal([]string{"miner", "mining"}, complete("mi"))
+	assert.Equal([]string{"ls"}, complete("address l"))
+	assert.Contains(complete("chain ls --"), "--limit")
+	assert.Contains(complete("chain ls --"), "--repodir")
+	assert.NotContains(complete("d"), "daemon")
+	assert.Contains(complete(""), "exit")
+}
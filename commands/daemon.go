@@ -2,11 +2,14 @@ package commands
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
+	"net"
 	"net/http"
 	_ "net/http/pprof" // nolint: golint
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -19,8 +22,10 @@ import (
 	"gx/ipfs/Qmde5VP1qUkyQXKCfmEUA7bP64V2HAptbJ7phuPp7jXWwg/go-ipfs-cmdkit"
 
 	"github.com/filecoin-project/go-filecoin/api/impl"
+	"github.com/filecoin-project/go-filecoin/auth"
 	"github.com/filecoin-project/go-filecoin/config"
-	"github.com/filecoin-project/go-filecoin/mining"
+	"github.com/filecoin-project/go-filecoin/jsonrpc"
+	"github.com/filecoin-project/go-filecoin/logs"
 	"github.com/filecoin-project/go-filecoin/node"
 	"github.com/filecoin-project/go-filecoin/repo"
 )
@@ -28,6 +33,14 @@ import (
 // exposed here, to be available during testing
 var sigCh = make(chan os.Signal, 1)
 
+// apiShutdownTimeout bounds the time the api calls in flight have to finish
+// when the daemon shuts down, and shutdownTimeout the whole shutdown, after
+// which the daemon exits regardless.
+var (
+	apiShutdownTimeout = 5 * time.Second
+	shutdownTimeout    = time.Minute
+)
+
 var daemonCmd = &cmds.Command{
 	Helptext: cmdkit.HelpText{
 		Tagline: "Start a long-running daemon process",
@@ -38,7 +51,8 @@ var daemonCmd = &cmds.Command{
 		cmdkit.BoolOption(OfflineMode, "start the node without networking"),
 		cmdkit.BoolOption(ELStdout),
 		cmdkit.BoolOption(IsRelay, "advertise and allow filecoin network traffic to be relayed through this node"),
-		cmdkit.StringOption(BlockTime, "time a node waits before trying to mine the next block").WithDefault(mining.DefaultBlockTime.String()),
+		cmdkit.StringOption(BlockTime, "time a node waits before trying to mine the next block, overriding the block time of the network"),
+		cmdkit.StringOption(Network, "the network the repo must be of, to fail rather than join another network"),
 	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
 		return daemonRun(req, re, env)
@@ -50,27 +64,50 @@ var daemonCmd = &cmds.Command{
 
 func daemonRun(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
 	// third precedence is config file.
+	if err := repo.CheckConfig(getRepoDir(req)); err != nil {
+		return err
+	}
 	rep, err := getRepo(req)
 	if err != nil {
 		return err
 	}
 
-	// second highest precedence is env vars.
-	if envapi := os.Getenv("FIL_API"); envapi != "" {
-		rep.Config().API.Address = envapi
-	}
+	// the env vars and cmd line flags also override the config file when
+	// it is reloaded
+	overrideConfig := func(cfg *config.Config) {
+		// second highest precedence is env vars.
+		if envapi := os.Getenv("FIL_API"); envapi != "" {
+			cfg.API.Address = envapi
+		}
+
+		// highest precedence is cmd line flag.
+		if apiAddress, ok := req.Options[OptionAPI].(string); ok && apiAddress != "" {
+			cfg.API.Address = apiAddress
+		}
 
-	// highest precedence is cmd line flag.
-	if apiAddress, ok := req.Options[OptionAPI].(string); ok && apiAddress != "" {
-		rep.Config().API.Address = apiAddress
+		if swarmAddress, ok := req.Options[SwarmAddress].(string); ok && swarmAddress != "" {
+			cfg.Swarm.Address = swarmAddress
+		}
+
+		if publicRelayAddress, ok := req.Options[SwarmPublicRelayAddress].(string); ok && publicRelayAddress != "" {
+			cfg.Swarm.PublicRelayAddress = publicRelayAddress
+		}
+
+		if blockTime, ok := req.Options[BlockTime].(string); ok && blockTime != "" {
+			cfg.Network.BlockTime = blockTime
+		}
+	}
+	overrideConfig(rep.Config())
+	if err := rep.Config().Validate(); err != nil {
+		return errors.Wrap(err, "invalid config")
 	}
 
-	if swarmAddress, ok := req.Options[SwarmAddress].(string); ok && swarmAddress != "" {
-		rep.Config().Swarm.Address = swarmAddress
+	if err := checkNetwork(req, rep); err != nil {
+		return err
 	}
 
-	if publicRelayAddress, ok := req.Options[SwarmPublicRelayAddress].(string); ok && publicRelayAddress != "" {
-		rep.Config().Swarm.PublicRelayAddress = publicRelayAddress
+	if err := logs.SetLevels(rep.Config().Log.Levels); err != nil {
+		return err
 	}
 
 	opts, err := node.OptionsFromRepo(rep)
@@ -86,12 +123,7 @@ func daemonRun(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment)
 		opts = append(opts, node.IsRelay())
 	}
 
-	durStr, ok := req.Options[BlockTime].(string)
-	if !ok {
-		return errors.New("Bad block time passed")
-	}
-
-	blockTime, err := time.ParseDuration(durStr)
+	blockTime, err := time.ParseDuration(rep.Config().Network.BlockTime)
 	if err != nil {
 		return errors.Wrap(err, "Bad block time passed")
 	}
@@ -115,14 +147,27 @@ func daemonRun(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment)
 		writer.WriterGroup.AddWriter(os.Stdout)
 	}
 
-	return runAPIAndWait(req.Context, fcn, rep.Config(), req)
+	return runAPIAndWait(req.Context, fcn, rep.Config(), overrideConfig)
+}
+
+// checkNetwork fails if the repo is of another network than the --network
+// option or the network.name setting of its config name, rather than have
+// the node join another network with the chain and keys of the repo.
+func checkNetwork(req *cmds.Request, rep repo.Repo) error {
+	if network, ok := req.Options[Network].(string); ok && network != "" && network != rep.Network() {
+		return fmt.Errorf("the repo is of network %q, not %q", rep.Network(), network)
+	}
+	if name := rep.Config().Network.Name; name != rep.Network() {
+		return fmt.Errorf(`the repo is of network %q, but its config sets "network.name" to %q`, rep.Network(), name)
+	}
+	return nil
 }
 
 func getRepo(req *cmds.Request) (repo.Repo, error) {
 	return repo.OpenFSRepo(getRepoDir(req))
 }
 
-func runAPIAndWait(ctx context.Context, node *node.Node, config *config.Config, req *cmds.Request) error {
+func runAPIAndWait(ctx context.Context, node *node.Node, config *config.Config, overrideConfig func(*config.Config)) error {
 	api := impl.New(node)
 
 	if err := api.Daemon().Start(ctx); err != nil {
@@ -135,15 +180,19 @@ func runAPIAndWait(ctx context.Context, node *node.Node, config *config.Config,
 		api:          api,
 		porcelainAPI: node.PorcelainAPI,
 	}
+	servenv.batchJobs = newBatchJobs(rootCmdDaemon, servenv)
 
-	cfg := cmdhttp.NewServerConfig()
-	cfg.APIPath = APIPrefix
-	cfg.SetAllowedOrigins(config.API.AccessControlAllowOrigin...)
-	cfg.SetAllowedMethods(config.API.AccessControlAllowMethods...)
-	cfg.SetAllowCredentials(config.API.AccessControlAllowCredentials)
+	cmdCfg := cmdhttp.NewServerConfig()
+	cmdCfg.APIPath = APIPrefix
+	cmdCfg.SetAllowedOrigins(config.API.AccessControlAllowOrigin...)
+	cmdCfg.SetAllowedMethods(config.API.AccessControlAllowMethods...)
+	cmdCfg.SetAllowCredentials(config.API.AccessControlAllowCredentials)
 
 	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
 	defer signal.Stop(sigCh)
+	hupCh := make(chan os.Signal, 1)
+	signal.Notify(hupCh, syscall.SIGHUP)
+	defer signal.Stop(hupCh)
 
 	maddr, err := ma.NewMultiaddr(config.API.Address)
 	if err != nil {
@@ -157,22 +206,64 @@ func runAPIAndWait(ctx context.Context, node *node.Node, config *config.Config,
 	if err != nil {
 		return err
 	}
+	configuredAPIAddr := config.API.Address
 	config.API.Address = apiLis.Multiaddr().String()
 
+	extraLis, err := listenAPI(config.API)
+	if err != nil {
+		apiLis.Close() // nolint: errcheck
+		return err
+	}
+
 	handler := http.NewServeMux()
 	handler.Handle("/debug/pprof/", http.DefaultServeMux)
-	handler.Handle(APIPrefix+"/", cmdhttp.NewHandler(servenv, rootCmdDaemon, cfg))
+	handler.Handle(APIPrefix+"/", cmdhttp.NewHandler(servenv, rootCmdDaemon, cmdCfg))
+
+	// The JSON-RPC api is served alongside the api of the commands, for
+	// clients integrating with the node programmatically.
+	rpcServer := jsonrpc.NewServer()
+	rpcServer.AllowOrigins(config.API.AccessControlAllowOrigin...)
+	jsonrpc.RegisterFilecoin(rpcServer, api, node.PorcelainAPI)
+	handler.Handle(JSONRPCPath, rpcServer)
+	handler.Handle(VersionPath, versionHandler(api.Version()))
+
+	stopping := make(chan struct{})
+	handler.Handle(HealthPath, healthHandler(node.Liveness))
+	handler.Handle(ReadyPath, healthHandler(readiness(node, config.API, stopping)))
+
+	servenv.reloadConfig = func() ([]string, error) {
+		cfg, err := node.Repo.ReadConfig()
+		if err != nil {
+			return nil, err
+		}
+		overrideConfig(cfg)
+		if cfg.API.Address == configuredAPIAddr {
+			// the address is resolved when listening, e.g. of port 0
+			cfg.API.Address = config.API.Address
+		}
+		changed, err := node.ReloadConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		cmdCfg.SetAllowedOrigins(cfg.API.AccessControlAllowOrigin...)
+		cmdCfg.SetAllowedMethods(cfg.API.AccessControlAllowMethods...)
+		cmdCfg.SetAllowCredentials(cfg.API.AccessControlAllowCredentials)
+		rpcServer.AllowOrigins(cfg.API.AccessControlAllowOrigin...)
+		return changed, nil
+	}
 
 	apiserv := http.Server{
-		Handler: handler,
+		Handler: withBasePath(config.API.BasePath, withAPIVersion(authHandler(api.Auth(), handler))),
 	}
 
-	go func() {
-		err := apiserv.Serve(manet.NetListener(apiLis))
-		if err != nil && err != http.ErrServerClosed {
-			panic(err)
-		}
-	}()
+	for _, lis := range append([]net.Listener{manet.NetListener(apiLis)}, extraLis...) {
+		go func(lis net.Listener) {
+			err := apiserv.Serve(lis)
+			if err != nil && err != http.ErrServerClosed {
+				panic(err)
+			}
+		}(lis)
+	}
 
 	// write our api address to file
 	// TODO: use api.Repo() once implemented
@@ -180,16 +271,120 @@ func runAPIAndWait(ctx context.Context, node *node.Node, config *config.Config,
 		return errors.Wrap(err, "Could not save API address to repo")
 	}
 
-	signal := <-sigCh
-	fmt.Printf("Got %s, shutting down...\n", signal)
+	// write an admin token for the commands run locally to authenticate with
+	token, err := api.Auth().CreateToken(ctx, auth.PermAdmin)
+	if err != nil {
+		return errors.Wrap(err, "Could not create API token")
+	}
+	if err := node.Repo.SetAPIToken(token); err != nil {
+		return errors.Wrap(err, "Could not save API token to repo")
+	}
 
-	// allow 5 seconds for clean shutdown. Ideally it would never take this long.
-	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
-	defer cancel()
+	var sig os.Signal
+	for sig == nil {
+		select {
+		case <-hupCh:
+			changed, err := servenv.reloadConfig()
+			if err != nil {
+				fmt.Println("failed to reload config:", err)
+				continue
+			}
+			fmt.Printf("Reloaded config, changed: %s\n", strings.Join(changed, ", "))
+		case sig = <-sigCh:
+		}
+	}
+	fmt.Printf("Got %s, shutting down...\n", sig)
 
-	if err := apiserv.Shutdown(ctx); err != nil {
+	done := make(chan struct{})
+	defer close(done)
+	go forceExit(done, sigCh, shutdownTimeout)
+
+	// report the node unready while the api calls in flight finish
+	close(stopping)
+
+	// stop accepting api calls first, letting the ones in flight finish for
+	// a while, so that none reach the node while it stops
+	apiCtx, cancel := context.WithTimeout(ctx, apiShutdownTimeout)
+	defer cancel()
+	if err := apiserv.Shutdown(apiCtx); err != nil {
 		fmt.Println("failed to shut down api server:", err)
+		apiserv.Close() // nolint: errcheck
 	}
 
 	return api.Daemon().Stop(ctx)
 }
+
+// forceExit exits the process if the shutdown isn't done within timeout, or
+// when a second signal is received, e.g. a second Ctrl-C.
+func forceExit(done <-chan struct{}, sigCh <-chan os.Signal, timeout time.Duration) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		return
+	case signal := <-sigCh:
+		fmt.Printf("Got %s again, exiting without a clean shutdown\n", signal)
+	case <-timer.C:
+		fmt.Printf("Shutdown took longer than %s, exiting without a clean shutdown\n", timeout)
+	}
+	os.Exit(1)
+}
+
+// listenAPI listens on the addresses of the api besides its main address,
+// over TLS if a certificate is configured.
+func listenAPI(cfg *config.APIConfig) ([]net.Listener, error) {
+	var tlsConfig *tls.Config
+	switch {
+	case cfg.TLSCertFile != "" && cfg.TLSKeyFile != "":
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load the TLS certificate of the api")
+		}
+		tlsConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	case cfg.TLSCertFile != "" || cfg.TLSKeyFile != "":
+		return nil, errors.New("api.tlsCertFile and api.tlsKeyFile must be set together")
+	}
+
+	var listeners []net.Listener
+	closeAll := func() {
+		for _, l := range listeners {
+			l.Close() // nolint: errcheck
+		}
+	}
+	for _, a := range cfg.ListenAddresses {
+		maddr, err := ma.NewMultiaddr(a)
+		if err != nil {
+			closeAll()
+			return nil, errors.Wrapf(err, "invalid api address %s", a)
+		}
+		lis, err := manet.Listen(maddr)
+		if err != nil {
+			closeAll()
+			return nil, errors.Wrapf(err, "failed to listen on api address %s", a)
+		}
+		l := manet.NetListener(lis)
+		if tlsConfig != nil {
+			l = tls.NewListener(l, tlsConfig)
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
+
+// withBasePath serves the requests to paths under basePath as if they were
+// made to the root, so that reverse proxies needn't strip it. Requests to
+// the root are still served, for the local commands.
+func withBasePath(basePath string, next http.Handler) http.Handler {
+	if basePath == "" {
+		return next
+	}
+	stripped := http.StripPrefix(basePath, next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, basePath+"/") {
+			stripped.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -1,15 +1,20 @@
 package commands
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	ma "gx/ipfs/QmNTCey11oxhb1AxDnQBRHtdhap6Ctud872NjAYPYYXPuc/go-multiaddr"
 	manet "gx/ipfs/QmZcLBXKaFe8ND5YHPkJRAwmhJGrVsi1JqDZNyJ4nRK5Mj/go-multiaddr-net"
 
+	"github.com/filecoin-project/go-filecoin/jsonrpc"
 	th "github.com/filecoin-project/go-filecoin/testhelpers"
 
 	"github.com/stretchr/testify/assert"
@@ -124,3 +129,100 @@ func TestDaemonOverHttp(t *testing.T) {
 	require.NoError(err)
 	require.Equal(http.StatusNotFound, res.StatusCode)
 }
+
+func TestDaemonJSONRPC(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	td := th.NewDaemon(t).Start()
+	defer td.ShutdownSuccess()
+
+	maddr, err := ma.NewMultiaddr(td.CmdAddr())
+	require.NoError(err)
+	_, host, err := manet.DialArgs(maddr)
+	require.NoError(err)
+
+	token := td.RunSuccess("auth", "create-token", "--perm", "read").ReadStdoutTrimNewlines()
+	call := func(method string, params ...interface{}) map[string]interface{} {
+		body, err := json.Marshal(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      1,
+			"method":  method,
+			"params":  params,
+		})
+		require.NoError(err)
+		req, err := http.NewRequest("POST", fmt.Sprintf("http://%s%s", host, JSONRPCPath), bytes.NewReader(body))
+		require.NoError(err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(err)
+		defer res.Body.Close() // nolint: errcheck
+		require.Equal(http.StatusOK, res.StatusCode)
+
+		var out map[string]interface{}
+		require.NoError(json.NewDecoder(res.Body).Decode(&out))
+		return out
+	}
+
+	head := call("chain.head")
+	assert.Nil(head["error"])
+	assert.Contains(head["result"], "cids")
+
+	addrs := call("wallet.addresses")
+	assert.Contains(addrs["result"], td.GetDefaultAddress())
+
+	balance := call("wallet.balance", td.GetDefaultAddress())
+	assert.Nil(balance["error"])
+
+	missing := call("chain.nope")
+	assert.Equal(float64(jsonrpc.CodeMethodNotFound), missing["error"].(map[string]interface{})["code"])
+}
+
+func TestDaemonAPIListenAddressesAndBasePath(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// find a free port for the extra api address
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(err)
+	extra := l.Addr().String()
+	require.NoError(l.Close())
+	_, port, err := net.SplitHostPort(extra)
+	require.NoError(err)
+
+	td := th.NewDaemon(t).Start()
+	defer td.ShutdownSuccess()
+
+	td.RunSuccess("config", "api.basePath", `"/filecoin"`)
+	td.RunSuccess("config", "api.listenAddresses", fmt.Sprintf(`["/ip4/127.0.0.1/tcp/%s"]`, port))
+	td.RunFail("must start with a slash", "config", "api.basePath", `"filecoin"`)
+	td.Restart()
+
+	maddr, err := ma.NewMultiaddr(td.CmdAddr())
+	require.NoError(err)
+	_, host, err := manet.DialArgs(maddr)
+	require.NoError(err)
+
+	token := td.RunSuccess("auth", "create-token", "--perm", "read").ReadStdoutTrimNewlines()
+	head := func(url string) int {
+		body := `{"jsonrpc":"2.0","id":1,"method":"chain.head"}`
+		req, err := http.NewRequest("POST", url, strings.NewReader(body))
+		require.NoError(err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(err)
+		res.Body.Close() // nolint: errcheck
+		return res.StatusCode
+	}
+
+	assert.Equal(http.StatusOK, head(fmt.Sprintf("http://%s/filecoin%s", host, JSONRPCPath)))
+	assert.Equal(http.StatusOK, head(fmt.Sprintf("http://%s%s", host, JSONRPCPath)))
+	assert.Equal(http.StatusOK, head(fmt.Sprintf("http://%s/filecoin%s", extra, JSONRPCPath)))
+
+	// the commands keep using the main address, without the base path
+	td.RunSuccess("id")
+}
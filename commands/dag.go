@@ -2,8 +2,13 @@
 package commands
 
 import (
+	"fmt"
+	"io"
+
 	cmds "gx/ipfs/Qma6uuSyjkecGhMFFLfzyJDPyoDtNJSHJNweDccZhaWkgU/go-ipfs-cmds"
 	cmdkit "gx/ipfs/Qmde5VP1qUkyQXKCfmEUA7bP64V2HAptbJ7phuPp7jXWwg/go-ipfs-cmdkit"
+
+	"github.com/filecoin-project/go-filecoin/api"
 )
 
 var dagCmd = &cmds.Command{
@@ -11,13 +16,19 @@ var dagCmd = &cmds.Command{
 		Tagline: "Interact with IPLD DAG objects.",
 	},
 	Subcommands: map[string]*cmds.Command{
-		"get": dagGetCmd,
+		"get":  dagGetCmd,
+		"stat": dagStatCmd,
 	},
 }
 
 var dagGetCmd = &cmds.Command{
 	Helptext: cmdkit.HelpText{
 		Tagline: "Get a DAG node by its CID",
+		ShortDescription: `
+Prints the object of the CID as JSON, e.g. a block, a message or a node of the
+state of an actor. A path following the CID, e.g. <cid>/parents, selects a
+field of the object.
+`,
 	},
 	Arguments: []cmdkit.Argument{
 		cmdkit.StringArg("ref", true, false, "CID of object to get"),
@@ -30,4 +41,49 @@ var dagGetCmd = &cmds.Command{
 
 		return re.Emit(out)
 	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeEncoder(func(req *cmds.Request, w io.Writer, v interface{}) error {
+			return printIndentedJSON(w, v)
+		}),
+	},
+}
+
+var dagStatCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show the codec, size and links of a DAG node",
+		ShortDescription: `
+Only the blockstore of the node is read, the object isn't fetched from the
+network. With --recursive, the distinct blocks of the DAG the object roots are
+counted too, e.g. to size the state tree of a block.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("ref", true, false, "CID of object to stat"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.BoolOption("recursive", "r", "Count the blocks of the DAG the object roots"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		recursive, _ := req.Options["recursive"].(bool)
+		stat, err := GetAPI(env).Dag().Stat(req.Context, req.Arguments[0], recursive)
+		if err != nil {
+			return err
+		}
+
+		return re.Emit(stat)
+	},
+	Type: api.DagStat{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, stat *api.DagStat) error {
+			fmt.Fprintf(w, "cid:\t\t%s\n", stat.Cid)        // nolint: errcheck
+			fmt.Fprintf(w, "codec:\t\t%s\n", stat.Codec)    // nolint: errcheck
+			fmt.Fprintf(w, "size:\t\t%d\n", stat.Size)      // nolint: errcheck
+			fmt.Fprintf(w, "links:\t\t%d\n", stat.NumLinks) // nolint: errcheck
+			if stat.NumBlocks > 0 {
+				fmt.Fprintf(w, "blocks:\t\t%d\n", stat.NumBlocks)   // nolint: errcheck
+				fmt.Fprintf(w, "total size:\t%d\n", stat.TotalSize) // nolint: errcheck
+			}
+			return nil
+		}),
+	},
 }
@@ -9,6 +9,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/filecoin-project/go-filecoin/api"
 	th "github.com/filecoin-project/go-filecoin/testhelpers"
 	"github.com/filecoin-project/go-filecoin/types"
 	"github.com/stretchr/testify/require"
@@ -56,4 +57,30 @@ func TestDagDaemon(t *testing.T) {
 		// TODO: reenable once cbor versions are matching!
 		// types.AssertHaveSameCid(assert, &expected, &actual)
 	})
+	t.Run("dag stat <cid> of the genesis block", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		d := th.NewDaemon(t).Start()
+		defer d.ShutdownSuccess()
+
+		var blocks []types.Block
+		ls := d.RunSuccess("chain", "ls", "--enc", "json").ReadStdoutTrimNewlines()
+		require.NoError(json.Unmarshal([]byte(ls), &blocks))
+		require.Len(blocks, 1)
+		genesis := blocks[0].Cid().String()
+
+		var stat api.DagStat
+		out := d.RunSuccess("dag", "stat", genesis, "--enc", "json").ReadStdoutTrimNewlines()
+		require.NoError(json.Unmarshal([]byte(out), &stat))
+		assert.Equal(genesis, stat.Cid.String())
+		assert.Equal("cbor", stat.Codec)
+		assert.NotZero(stat.Size)
+
+		text := d.RunSuccess("dag", "stat", "--recursive", genesis).ReadStdoutTrimNewlines()
+		assert.Contains(text, "codec:\t\tcbor")
+		assert.Contains(text, "blocks:")
+
+		d.RunFail("failed to get block", "dag", "stat", types.SomeCid().String())
+	})
 }
@@ -0,0 +1,112 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	"gx/ipfs/Qma6uuSyjkecGhMFFLfzyJDPyoDtNJSHJNweDccZhaWkgU/go-ipfs-cmds"
+	"gx/ipfs/Qmde5VP1qUkyQXKCfmEUA7bP64V2HAptbJ7phuPp7jXWwg/go-ipfs-cmdkit"
+
+	"github.com/filecoin-project/go-filecoin/protocol/storage"
+)
+
+var dealsCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Inspect the storage deals of this node",
+	},
+	Subcommands: map[string]*cmds.Command{
+		"show":            dealsShowCmd,
+		"pause-transfer":  dealsPauseTransferCmd,
+		"resume-transfer": dealsResumeTransferCmd,
+	},
+}
+
+var dealsShowCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show the state and history of a storage deal",
+		ShortDescription: `
+Shows the storage deal whose proposal has the given CID, if this node is the
+client or the miner of the deal. The current state of the deal and the states
+it went through are returned as a formatted string unless another format is
+specified with the --enc flag. Deals survive restarts of the node, the states
+shown are the ones recorded so far.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("id", true, false, "CID of the deal proposal"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		propcid, err := cid.Decode(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		record, err := GetAPI(env).Deals().Show(req.Context, propcid)
+		if err != nil {
+			return err
+		}
+
+		return re.Emit(record)
+	},
+	Type: storage.DealRecord{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, record *storage.DealRecord) error {
+			fmt.Fprintf(w, "Deal: %s\n", record.ProposalCid)               // nolint: errcheck
+			fmt.Fprintf(w, "Role: %s\n", record.Role)                      // nolint: errcheck
+			fmt.Fprintf(w, "Status: %s\n", record.Response.State.String()) // nolint: errcheck
+			fmt.Fprintf(w, "Message: %s\n", record.Response.Message)       // nolint: errcheck
+			fmt.Fprintln(w, "History:")                                    // nolint: errcheck
+			for _, t := range record.History {
+				at := time.Unix(t.Time, 0).Format(time.RFC3339)
+				if t.Message != "" {
+					fmt.Fprintf(w, "  %s %s: %s\n", at, t.State, t.Message) // nolint: errcheck
+				} else {
+					fmt.Fprintf(w, "  %s %s\n", at, t.State) // nolint: errcheck
+				}
+			}
+			return nil
+		}),
+	},
+}
+
+var dealsPauseTransferCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Pause the transfer of a storage deal's data to this miner",
+		ShortDescription: `
+Pauses receiving the data of the storage deal whose proposal has the given CID.
+The data received so far is kept, the transfer continues from there once it is
+resumed with the deals resume-transfer command. Paused transfers stay paused
+across restarts of the node.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("id", true, false, "CID of the deal proposal"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		propcid, err := cid.Decode(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		return GetAPI(env).Deals().PauseTransfer(req.Context, propcid)
+	},
+}
+
+var dealsResumeTransferCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Resume the paused transfer of a storage deal's data to this miner",
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("id", true, false, "CID of the deal proposal"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		propcid, err := cid.Decode(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		return GetAPI(env).Deals().ResumeTransfer(req.Context, propcid)
+	},
+}
@@ -14,6 +14,11 @@ type Env struct {
 	ctx          context.Context
 	api          api.API
 	porcelainAPI *porcelain.API
+	// batchJobs is only set on the daemon.
+	batchJobs *batchJobs
+	// reloadConfig reloads the config file of the daemon, returning the
+	// settings changed. Only set on the daemon.
+	reloadConfig func() ([]string, error)
 }
 
 var _ cmds.Environment = (*Env)(nil)
@@ -0,0 +1,117 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	"gx/ipfs/Qma6uuSyjkecGhMFFLfzyJDPyoDtNJSHJNweDccZhaWkgU/go-ipfs-cmds"
+	"gx/ipfs/Qmde5VP1qUkyQXKCfmEUA7bP64V2HAptbJ7phuPp7jXWwg/go-ipfs-cmdkit"
+
+	"github.com/filecoin-project/go-filecoin/porcelain"
+)
+
+var eventCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Query the events emitted by actors",
+		ShortDescription: `
+Actors emit events recorded in the receipts of the messages they process, for
+example when a payment channel is created or redeemed, or a miner commits a
+sector. The values of an event are abi encoded and only shown with --enc=json.
+`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"ls":    eventLsCmd,
+		"watch": eventWatchCmd,
+	},
+}
+
+var eventFilterOptions = []cmdkit.Option{
+	cmdkit.StringOption("actor", "Only show events emitted by this address or address book label"),
+	cmdkit.StringOption("method", "Only show events emitted by this actor method"),
+	cmdkit.StringOption("type", "Only show events of this type"),
+}
+
+var eventLsCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "List the events on chain, newest first",
+	},
+	Options: append([]cmdkit.Option{
+		cmdkit.UintOption("limit", "Maximum number of events to list, 0 for all").WithDefault(uint(20)),
+	}, eventFilterOptions...),
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		filter, err := eventFilterFromOptions(req, env)
+		if err != nil {
+			return err
+		}
+		limit, _ := req.Options["limit"].(uint)
+
+		events, err := GetPorcelainAPI(env).Events(req.Context, filter, limit)
+		if err != nil {
+			return err
+		}
+		for _, e := range events {
+			if err := re.Emit(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Type: porcelain.ChainEvent{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(writeChainEvent),
+	},
+}
+
+var eventWatchCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Follow the events emitted by new chain heads",
+		ShortDescription: `
+Prints the events of each new chain head as it arrives, until interrupted.
+`,
+	},
+	Options: eventFilterOptions,
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		filter, err := eventFilterFromOptions(req, env)
+		if err != nil {
+			return err
+		}
+
+		for raw := range GetPorcelainAPI(env).WatchEvents(req.Context, filter) {
+			switch v := raw.(type) {
+			case error:
+				return v
+			case *porcelain.ChainEvent:
+				if err := re.Emit(v); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("unexpected type %T", raw)
+			}
+		}
+		return nil
+	},
+	Type: porcelain.ChainEvent{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(writeChainEvent),
+	},
+}
+
+func eventFilterFromOptions(req *cmds.Request, env cmds.Environment) (porcelain.EventFilter, error) {
+	var filter porcelain.EventFilter
+	if o, ok := req.Options["actor"].(string); ok {
+		addr, err := resolveAddr(env, o)
+		if err != nil {
+			return porcelain.EventFilter{}, errors.Wrap(err, "invalid actor address")
+		}
+		filter.Actor = addr
+	}
+	filter.Method, _ = req.Options["method"].(string)
+	filter.Type, _ = req.Options["type"].(string)
+	return filter, nil
+}
+
+func writeChainEvent(req *cmds.Request, w io.Writer, e *porcelain.ChainEvent) error {
+	_, err := fmt.Fprintf(w, "height %d: %s by %s.%s in message %s\n", e.BlockHeight, e.Type, e.Actor, e.Method, e.Message)
+	return err
+}
@@ -2,6 +2,7 @@ package commands
 
 import (
 	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	"gx/ipfs/Qma6uuSyjkecGhMFFLfzyJDPyoDtNJSHJNweDccZhaWkgU/go-ipfs-cmds"
 
 	"github.com/filecoin-project/go-filecoin/address"
 )
@@ -15,3 +16,38 @@ func optionalAddr(o interface{}) (ret address.Address, err error) {
 	}
 	return
 }
+
+// resolveAddr parses s as an address, or looks it up in the address book if
+// it is not one.
+func resolveAddr(env cmds.Environment, s string) (address.Address, error) {
+	return GetPorcelainAPI(env).AddressBookResolve(s)
+}
+
+// optionalResolvedAddr is like optionalAddr but also accepts address book
+// labels.
+func optionalResolvedAddr(env cmds.Environment, o interface{}) (address.Address, error) {
+	if o == nil {
+		return address.Address{}, nil
+	}
+	addr, err := resolveAddr(env, o.(string))
+	if err != nil {
+		return address.Address{}, errors.Wrap(err, "invalid from address")
+	}
+	return addr, nil
+}
+
+// fromAddrOrDefault returns the address or address book label given in o,
+// falling back to the wallet's default address when o is not set. Commands
+// taking a --from option should use it so that they all default the same way.
+func fromAddrOrDefault(env cmds.Environment, o interface{}) (address.Address, error) {
+	addr, err := optionalResolvedAddr(env, o)
+	if err != nil || !addr.Empty() {
+		return addr, err
+	}
+
+	addr, err = GetPorcelainAPI(env).GetAndMaybeSetDefaultSenderAddress()
+	if err != nil {
+		return address.Address{}, errors.Wrap(err, "no --from address given and no default address")
+	}
+	return addr, nil
+}
@@ -0,0 +1,58 @@
+package commands
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/filecoin-project/go-filecoin/config"
+	"github.com/filecoin-project/go-filecoin/node"
+)
+
+// HealthPath and ReadyPath are the paths of the api serving the liveness and
+// the readiness of the node as JSON, for the probes of orchestrators and
+// load balancers. They respond 503 Service Unavailable when a check fails.
+const (
+	HealthPath = "/healthz"
+	ReadyPath  = "/readyz"
+)
+
+// HealthReport is the response of HealthPath and ReadyPath.
+type HealthReport struct {
+	OK     bool               `json:"ok"`
+	Checks []node.HealthCheck `json:"checks"`
+}
+
+// healthHandler serves the outcome of checks.
+func healthHandler(checks func() []node.HealthCheck) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := HealthReport{OK: true, Checks: checks()}
+		for _, c := range report.Checks {
+			report.OK = report.OK && c.OK
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if !report.OK {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(report) // nolint: errcheck
+	})
+}
+
+// readiness returns the readiness checks of nd, and of the api server
+// until stopping is closed.
+func readiness(nd *node.Node, cfg *config.APIConfig, stopping <-chan struct{}) func() []node.HealthCheck {
+	return func() []node.HealthCheck {
+		return append(nd.Readiness(cfg.ReadyMinPeers, cfg.ReadyMaxSyncLag), apiCheck(stopping))
+	}
+}
+
+// apiCheck checks the api server isn't shutting down, i.e. stopping is
+// still open.
+func apiCheck(stopping <-chan struct{}) node.HealthCheck {
+	select {
+	case <-stopping:
+		return node.HealthCheck{Name: "api", Message: "shutting down"}
+	default:
+		return node.HealthCheck{Name: "api", OK: true}
+	}
+}
@@ -0,0 +1,53 @@
+package commands
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/node"
+)
+
+func TestHealthHandler(t *testing.T) {
+	t.Parallel()
+
+	serve := func(checks ...node.HealthCheck) (*httptest.ResponseRecorder, HealthReport) {
+		rec := httptest.NewRecorder()
+		healthHandler(func() []node.HealthCheck { return checks }).ServeHTTP(rec, httptest.NewRequest("GET", ReadyPath, nil))
+		var report HealthReport
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&report))
+		return rec, report
+	}
+
+	t.Run("reports ok when every check passes", func(t *testing.T) {
+		assert := assert.New(t)
+
+		rec, report := serve(node.HealthCheck{Name: "datastore", OK: true}, node.HealthCheck{Name: "peers", OK: true})
+		assert.Equal(http.StatusOK, rec.Code)
+		assert.True(report.OK)
+		assert.Len(report.Checks, 2)
+	})
+
+	t.Run("reports unavailable when a check fails", func(t *testing.T) {
+		assert := assert.New(t)
+
+		rec, report := serve(node.HealthCheck{Name: "datastore", OK: true}, node.HealthCheck{Name: "peers", Message: "0 peers, fewer than 1"})
+		assert.Equal(http.StatusServiceUnavailable, rec.Code)
+		assert.False(report.OK)
+		assert.Equal("0 peers, fewer than 1", report.Checks[1].Message)
+	})
+}
+
+func TestAPICheck(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	stopping := make(chan struct{})
+	assert.True(apiCheck(stopping).OK)
+	close(stopping)
+	assert.False(apiCheck(stopping).OK)
+}
@@ -1,7 +1,9 @@
 package commands
 
 import (
+	"encoding/json"
 	"io/ioutil"
+	"strings"
 	"testing"
 
 	th "github.com/filecoin-project/go-filecoin/testhelpers"
@@ -66,3 +68,25 @@ func TestPersistId(t *testing.T) {
 	t.Logf("d2: %s", d2.ReadStdout())
 	assert.Equal(id1, id2)
 }
+
+func TestIdOutput(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	d := th.NewDaemon(t).Start()
+	defer d.ShutdownSuccess()
+
+	var details map[string]interface{}
+	out := d.RunSuccess("id", "--output=json").ReadStdout()
+	require.NoError(json.Unmarshal([]byte(out), &details))
+	assert.Contains(details, "ID")
+	assert.Contains(details, "Addresses")
+
+	lines := strings.Split(d.RunSuccess("id", "--output=csv").ReadStdoutTrimNewlines(), "\n")
+	require.Len(lines, 2)
+	assert.Equal("Addresses,AgentVersion,ID,ProtocolVersion,PublicKey", lines[0])
+	assert.Contains(lines[1], d.SwarmAddr())
+
+	d.RunFail("unknown output format", "id", "--output=yaml")
+}
@@ -4,27 +4,42 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 
 	cmds "gx/ipfs/Qma6uuSyjkecGhMFFLfzyJDPyoDtNJSHJNweDccZhaWkgU/go-ipfs-cmds"
 	cmdkit "gx/ipfs/Qmde5VP1qUkyQXKCfmEUA7bP64V2HAptbJ7phuPp7jXWwg/go-ipfs-cmdkit"
 
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/api"
+	"github.com/filecoin-project/go-filecoin/networks"
 )
 
 var initCmd = &cmds.Command{
 	Helptext: cmdkit.HelpText{
 		Tagline: "Initialize a filecoin repo",
+		ShortDescription: `
+Initializes a filecoin repo for the network given by --network, which sets the
+genesis block, the bootstrap peers, the block time and the proofs mode of the
+network: one of devnet, staging, nightly or local, the default. The network is
+recorded in the repo, and the daemon refuses to start if its config or its
+--network option name another network.
+`,
 	},
 	Options: []cmdkit.Option{
 		cmdkit.StringOption(GenesisFile, "path of file or HTTP(S) URL containing archive of genesis block DAG data"),
 		cmdkit.StringOption(PeerKeyFile, "path of file containing key to use for new node's libp2p identity"),
+		cmdkit.StringOption(SwarmKeyFile, "path of file containing the swarm key of the private network the node joins"),
+		cmdkit.BoolOption(GenSwarmKey, "when set, generates the swarm key of a new private network, written to swarm.key in the repo"),
 		cmdkit.StringOption(WithMiner, "when set, creates a custom genesis block with a pre generated miner account, requires running the daemon using dev mode (--dev)"),
 		cmdkit.StringOption(DefaultAddress, "when set, sets the daemons's default address to the provided address"),
 		cmdkit.UintOption(AutoSealIntervalSeconds, "when set to a number > 0, configures the daemon to check for and seal any staged sectors on an interval.").WithDefault(uint(120)),
-		cmdkit.BoolOption(DevnetTest, "when set, populates config bootstrap addrs with the dns multiaddrs of the test devnet and other test devnet specific bootstrap parameters."),
-		cmdkit.BoolOption(DevnetNightly, "when set, populates config bootstrap addrs with the dns multiaddrs of the nightly devnet and other nightly devnet specific bootstrap parameters"),
-		cmdkit.BoolOption(DevnetUser, "when set, populates config bootstrap addrs with the dns multiaddrs of the user devnet and other user devnet specific bootstrap parameters"),
+		cmdkit.StringOption(Network, "the network the repo is initialized for: "+strings.Join(networks.Names(), ", ")).WithDefault(networks.Local),
+		cmdkit.StringOption(DatastoreType, "the type of the datastores of the repo: badgerds or leveldb").WithDefault("badgerds"),
+		cmdkit.StringOption(BlocksDatastoreType, "when set to flatfs, keeps the blocks of the repo in flatfs directories rather than in the datastores"),
+		cmdkit.BoolOption(CompressDatastore, "when set, compresses the values of the datastores of the repo"),
+		cmdkit.BoolOption(DevnetTest, "deprecated, same as --network=staging"),
+		cmdkit.BoolOption(DevnetNightly, "deprecated, same as --network=nightly"),
+		cmdkit.BoolOption(DevnetUser, "deprecated, same as --network=devnet"),
 	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
 		repoDir := getRepoDir(req)
@@ -34,10 +49,17 @@ var initCmd = &cmds.Command{
 
 		genesisFile, _ := req.Options[GenesisFile].(string)
 		peerKeyFile, _ := req.Options[PeerKeyFile].(string)
+		swarmKeyFile, _ := req.Options[SwarmKeyFile].(string)
+		genSwarmKey, _ := req.Options[GenSwarmKey].(bool)
 		autoSealIntervalSeconds, _ := req.Options[AutoSealIntervalSeconds].(uint)
-		devnetTest, _ := req.Options[DevnetTest].(bool)
-		devnetNightly, _ := req.Options[DevnetNightly].(bool)
-		devnetUser, _ := req.Options[DevnetUser].(bool)
+		datastoreType, _ := req.Options[DatastoreType].(string)
+		blocksDatastoreType, _ := req.Options[BlocksDatastoreType].(string)
+		compressDatastore, _ := req.Options[CompressDatastore].(bool)
+
+		network, err := initNetwork(req)
+		if err != nil {
+			return err
+		}
 
 		var withMiner address.Address
 		if m, ok := req.Options[WithMiner].(string); ok {
@@ -62,12 +84,15 @@ var initCmd = &cmds.Command{
 			api.RepoDir(repoDir),
 			api.GenesisFile(genesisFile),
 			api.PeerKeyFile(peerKeyFile),
+			api.SwarmKeyFile(swarmKeyFile),
+			api.GenSwarmKey(genSwarmKey),
 			api.WithMiner(withMiner),
-			api.DevnetTest(devnetTest),
-			api.DevnetNightly(devnetNightly),
-			api.DevnetUser(devnetUser),
+			api.Network(network),
 			api.AutoSealIntervalSeconds(autoSealIntervalSeconds),
 			api.DefaultAddress(defaultAddress),
+			api.DatastoreType(datastoreType),
+			api.BlocksDatastoreType(blocksDatastoreType),
+			api.CompressDatastore(compressDatastore),
 		)
 	},
 	Encoders: cmds.EncoderMap{
@@ -75,6 +100,36 @@ var initCmd = &cmds.Command{
 	},
 }
 
+// initNetwork returns the network of the --network option, or of one of the
+// deprecated devnet options.
+func initNetwork(req *cmds.Request) (string, error) {
+	network, _ := req.Options[Network].(string)
+
+	aliases := []struct {
+		option  string
+		network string
+	}{
+		{DevnetTest, "staging"},
+		{DevnetNightly, "nightly"},
+		{DevnetUser, "devnet"},
+	}
+	set := ""
+	for _, alias := range aliases {
+		if ok, _ := req.Options[alias.option].(bool); !ok {
+			continue
+		}
+		if set != "" {
+			return "", fmt.Errorf(`cannot use both "--%s" and "--%s" options`, set, alias.option)
+		}
+		if network != networks.Local {
+			return "", fmt.Errorf(`cannot use both "--%s" and "--%s" options`, Network, alias.option)
+		}
+		set = alias.option
+		network = alias.network
+	}
+	return network, nil
+}
+
 func initTextEncoder(req *cmds.Request, w io.Writer, val interface{}) error {
 	_, err := fmt.Fprintf(w, val.(string))
 	return err
@@ -3,6 +3,7 @@ package commands
 import (
 	"fmt"
 	"net/http"
+	"path/filepath"
 	"testing"
 
 	ma "gx/ipfs/QmNTCey11oxhb1AxDnQBRHtdhap6Ctud872NjAYPYYXPuc/go-multiaddr"
@@ -31,3 +32,28 @@ func TestInitOverHttp(t *testing.T) {
 	require.NoError(err)
 	require.Equal(http.StatusNotFound, res.StatusCode)
 }
+
+func TestInitNetwork(t *testing.T) {
+	t.Parallel()
+
+	t.Run("unknown networks are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		d := th.NewDaemon(t, th.ShouldInit(false))
+		d.RunFail(`unknown network "mainnet"`, "init", "--network=mainnet")
+		d.RunFail(`cannot use both "--network" and "--devnet-test" options`, "init", "--network=nightly", "--devnet-test")
+	})
+
+	t.Run("daemon refuses to start for another network", func(t *testing.T) {
+		t.Parallel()
+		require := require.New(t)
+
+		d := th.NewDaemon(t)
+		d.RunFail(`the repo is of network "local", not "nightly"`, "daemon", "--network=nightly")
+
+		cfg := d.Config()
+		cfg.Network.Name = "nightly"
+		require.NoError(cfg.WriteFile(filepath.Join(d.RepoDir(), "config.json")))
+		d.RunFail(`the repo is of network "local", but its config sets "network.name" to "nightly"`, "daemon")
+	})
+}
@@ -1,8 +1,15 @@
 package commands
 
 import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
 	"gx/ipfs/Qma6uuSyjkecGhMFFLfzyJDPyoDtNJSHJNweDccZhaWkgU/go-ipfs-cmds"
 	"gx/ipfs/Qmde5VP1qUkyQXKCfmEUA7bP64V2HAptbJ7phuPp7jXWwg/go-ipfs-cmdkit"
+
+	"github.com/filecoin-project/go-filecoin/logs"
 )
 
 var logCmd = &cmds.Command{
@@ -21,14 +28,50 @@ output of a running daemon.
 
 var logTailCmd = &cmds.Command{
 	Helptext: cmdkit.HelpText{
-		Tagline: "Read the event log.",
+		Tagline: "Follow the logs and the events of the daemon.",
 		ShortDescription: `
-Outputs event log messages (not other log messages) as they are generated.
+Outputs the log records of the daemon as they are generated, along with the
+events of its subsystems: the new heads of the chain (subsystem chain), the
+changes to the storage deals of the client (deals) and the changes of stage
+of sectors (sectors). Records are only logged at the levels the daemon was
+configured with. Use --enc=json to get the entries as structured JSON.
+
+With --eventlog, outputs the event log messages (not other log messages)
+instead.
 `,
 	},
-
+	Options: []cmdkit.Option{
+		cmdkit.StringOption("subsystem", "Comma separated subsystems, or names of loggers, to follow, all if unset"),
+		cmdkit.StringOption("level", "Least severe level to follow: critical, error, warning, notice, info or debug"),
+		cmdkit.BoolOption("eventlog", "Output the raw event log"),
+	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
-		r := GetAPI(env).Log().Tail(req.Context)
-		return re.Emit(r)
+		if eventlog, _ := req.Options["eventlog"].(bool); eventlog {
+			return re.Emit(GetAPI(env).Log().Tail(req.Context))
+		}
+
+		var filter logs.Filter
+		if s, ok := req.Options["subsystem"].(string); ok && s != "" {
+			filter.Subsystems = strings.Split(s, ",")
+		}
+		filter.Level, _ = req.Options["level"].(string)
+
+		entries, err := GetAPI(env).Log().Stream(req.Context, filter)
+		if err != nil {
+			return err
+		}
+		for e := range entries {
+			if err := re.Emit(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Type: logs.Entry{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, e *logs.Entry) error {
+			_, err := fmt.Fprintf(w, "%s %-8s %s: %s\n", e.Time.Format(time.RFC3339Nano), strings.ToUpper(e.Level), e.Subsystem, e.Message)
+			return err
+		}),
 	},
 }
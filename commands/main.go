@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -19,6 +20,8 @@ import (
 	"gx/ipfs/Qmde5VP1qUkyQXKCfmEUA7bP64V2HAptbJ7phuPp7jXWwg/go-ipfs-cmdkit"
 
 	"github.com/filecoin-project/go-filecoin/api/impl"
+	"github.com/filecoin-project/go-filecoin/jsonrpc"
+	"github.com/filecoin-project/go-filecoin/porcelain"
 	"github.com/filecoin-project/go-filecoin/repo"
 	"github.com/filecoin-project/go-filecoin/types"
 )
@@ -33,6 +36,9 @@ const (
 	// APIPrefix is the prefix for the http version of the api.
 	APIPrefix = "/api"
 
+	// JSONRPCPath is the path of the JSON-RPC api, over http and websockets.
+	JSONRPCPath = jsonrpc.Path
+
 	// OfflineMode tells us if we should try to connect this Filecoin node to the network
 	OfflineMode = "offline"
 
@@ -59,6 +65,12 @@ const (
 	// PeerKeyFile is the path of file containing key to use for new nodes libp2p identity
 	PeerKeyFile = "peerkeyfile"
 
+	// SwarmKeyFile is the path of file containing the swarm key of the private network new nodes join
+	SwarmKeyFile = "swarmkeyfile"
+
+	// GenSwarmKey when set, generates a swarm key for a new private network
+	GenSwarmKey = "gen-swarm-key"
+
 	// WithMiner when set, creates a custom genesis block with a pre generated miner account, requires to run the daemon using dev mode (--dev)
 	WithMiner = "with-miner"
 
@@ -68,13 +80,26 @@ const (
 	// GenesisFile is the path of file containing archive of genesis block DAG data
 	GenesisFile = "genesisfile"
 
-	// DevnetTest populates config bootstrap addrs with the dns multiaddrs of the test devnet and other test devnet specific bootstrap parameters
+	// Network is the name of the network a new repo is initialized for, and
+	// which the daemon checks its repo is of
+	Network = "network"
+
+	// DatastoreType is the type of the datastores of a new repo
+	DatastoreType = "datastore"
+
+	// BlocksDatastoreType is the type of the datastore keeping the blocks of a new repo
+	BlocksDatastoreType = "blocks-datastore"
+
+	// CompressDatastore when set, compresses the values of the datastores of a new repo
+	CompressDatastore = "compress-datastore"
+
+	// DevnetTest is the deprecated alias of --network=staging
 	DevnetTest = "devnet-test"
 
-	// DevnetNightly populates config bootstrap addrs with the dns multiaddrs of the nightly devnet and other nightly devnet specific bootstrap parameters
+	// DevnetNightly is the deprecated alias of --network=nightly
 	DevnetNightly = "devnet-nightly"
 
-	// DevnetUser populates config bootstrap addrs with the dns multiaddrs of the user devnet and other user devnet specific bootstrap parameters
+	// DevnetUser is the deprecated alias of --network=devnet
 	DevnetUser = "devnet-user"
 
 	// IsRelay when set causes the the daemon to provide libp2p relay
@@ -97,15 +122,21 @@ START RUNNING FILECOIN
 STORE AND RETRIEVE DATA
   go-filecoin client                 - Make deals, store data, retrieve data
   go-filecoin retrieval-client       - Manage retrieval client operations
+  go-filecoin deals                  - Inspect the storage deals of this node
 
 MINE
   go-filecoin miner                  - Manage a single miner actor
   go-filecoin mining                 - Manage all mining operations for a node
+  go-filecoin sealing                - Inspect the sealing of sectors
+  go-filecoin sectors                - Check, export and import sealed sectors
+  go-filecoin proofs                 - Manage the parameters and resources of the proofs backend
 
 VIEW DATA STRUCTURES
   go-filecoin chain                  - Inspect the filecoin blockchain
   go-filecoin dag                    - Interact with IPLD DAG objects
+  go-filecoin event                  - Query the events emitted by actors
   go-filecoin show                   - Get human-readable representations of filecoin objects
+  go-filecoin state                  - Inspect the state of the filecoin blockchain
 
 NETWORK COMMANDS
   go-filecoin bootstrap              - Interact with bootstrap addresses
@@ -115,6 +146,7 @@ NETWORK COMMANDS
 
 ACTOR COMMANDS
   go-filecoin actor                  - Interact with actors. Actors are built-in smart contracts.
+  go-filecoin multisig               - Manage multi-signature wallets
   go-filecoin paych                  - Payment channel operations
 
 MESSAGE COMMANDS
@@ -122,7 +154,11 @@ MESSAGE COMMANDS
   go-filecoin mpool                  - Manage the message pool
 
 TOOL COMMANDS
+  go-filecoin auth                   - Manage the tokens granting access to the api
+  go-filecoin batch                  - Run batches of commands on the daemon
+  go-filecoin console                - Run commands against the daemon interactively
   go-filecoin log                    - Interact with the daemon event log output.
+  go-filecoin repo                   - Migrate, back up and restore the repo
   go-filecoin version                - Show go-filecoin version information
 `,
 	},
@@ -130,6 +166,7 @@ TOOL COMMANDS
 		cmdkit.StringOption(OptionAPI, "set the api port to use"),
 		cmdkit.StringOption(OptionRepoDir, "set the directory of the repo, defaults to ~/.filecoin"),
 		cmds.OptionEncodingType,
+		outputOption,
 		cmdkit.BoolOption("help", "Show the full command help text."),
 		cmdkit.BoolOption("h", "Show a short version of the command help text."),
 	},
@@ -143,29 +180,40 @@ var rootCmdDaemon = &cmds.Command{
 
 // all top level commands, not available to daemon
 var rootSubcmdsLocal = map[string]*cmds.Command{
-	"daemon": daemonCmd,
-	"init":   initCmd,
+	"console": consoleCmd,
+	"daemon":  daemonCmd,
+	"init":    initCmd,
 }
 
 // all top level commands, available on daemon. set during init() to avoid configuration loops.
 var rootSubcmdsDaemon = map[string]*cmds.Command{
 	"actor":            actorCmd,
 	"address":          addrsCmd,
+	"auth":             authCmd,
+	"batch":            batchCmd,
 	"bootstrap":        bootstrapCmd,
 	"chain":            chainCmd,
 	"config":           configCmd,
 	"client":           clientCmd,
 	"dag":              dagCmd,
+	"deals":            dealsCmd,
+	"event":            eventCmd,
 	"id":               idCmd,
 	"log":              logCmd,
 	"message":          msgCmd,
 	"miner":            minerCmd,
 	"mining":           miningCmd,
 	"mpool":            mpoolCmd,
+	"multisig":         multisigCmd,
 	"paych":            paymentChannelCmd,
 	"ping":             pingCmd,
+	"proofs":           proofsCmd,
+	"repo":             repoCmd,
 	"retrieval-client": retrievalClientCmd,
+	"sealing":          sealingCmd,
+	"sectors":          sectorsCmd,
 	"show":             showCmd,
+	"state":            stateCmd,
 	"swarm":            swarmCmd,
 	"version":          versionCmd,
 	"wallet":           walletCmd,
@@ -195,12 +243,38 @@ func Run(args []string, stdin, stdout, stderr *os.File) (int, error) {
 }
 
 func buildEnv(ctx context.Context, req *cmds.Request) (cmds.Environment, error) {
+	// the cli picks the encoder of the response once the env is built
+	if err := applyOutputOption(req); err != nil {
+		return nil, err
+	}
 	return &Env{ctx: ctx, api: impl.New(nil)}, nil
 }
 
 type executor struct {
-	api  string
-	exec cmds.Executor
+	api   string
+	token string
+	exec  cmds.Executor
+}
+
+// tokenTransport authenticates the requests to the api with a token.
+type tokenTransport struct {
+	token string
+	next  http.RoundTripper
+}
+
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.WithContext(req.Context())
+	req.Header = cloneHeader(req.Header)
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return t.next.RoundTrip(req)
+}
+
+func cloneHeader(h http.Header) http.Header {
+	c := make(http.Header, len(h))
+	for k, v := range h {
+		c[k] = append([]string(nil), v...)
+	}
+	return c
 }
 
 func (e *executor) Execute(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
@@ -208,7 +282,19 @@ func (e *executor) Execute(req *cmds.Request, re cmds.ResponseEmitter, env cmds.
 		return e.exec.Execute(req, re, env)
 	}
 
-	client := cmdhttp.NewClient(e.api, cmdhttp.ClientWithAPIPrefix(APIPrefix))
+	var transport http.RoundTripper = &versionTransport{next: http.DefaultTransport}
+	if e.token != "" {
+		transport = &tokenTransport{token: e.token, next: transport}
+	}
+	client := cmdhttp.NewClient(e.api,
+		cmdhttp.ClientWithAPIPrefix(APIPrefix),
+		cmdhttp.ClientWithHTTPClient(&http.Client{Transport: transport}),
+	)
+
+	// the daemon responds in json, encoded by re as requested
+	if enc, _ := req.Options[cmds.EncLong].(string); enc == string(CSV) {
+		req.Options[cmds.EncLong] = string(cmds.JSON)
+	}
 
 	res, err := client.Send(req)
 	if err != nil {
@@ -228,13 +314,17 @@ func (e *executor) Execute(req *cmds.Request, re cmds.ResponseEmitter, env cmds.
 
 func makeExecutor(req *cmds.Request, env interface{}) (cmds.Executor, error) {
 	isDaemonRequired := requiresDaemon(req)
-	var api string
+	var api, token string
 	if isDaemonRequired {
 		var err error
 		api, err = getAPIAddress(req)
 		if err != nil {
 			return nil, err
 		}
+		token, err = getAPIToken(req)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	if api == "" && isDaemonRequired {
@@ -242,11 +332,27 @@ func makeExecutor(req *cmds.Request, env interface{}) (cmds.Executor, error) {
 	}
 
 	return &executor{
-		api:  api,
-		exec: cmds.NewExecutor(rootCmd),
+		api:   api,
+		token: token,
+		exec:  cmds.NewExecutor(rootCmd),
 	}, nil
 }
 
+// getAPIToken returns the token to authenticate to the api with, from the
+// environment or else the token file of the repo, if any.
+func getAPIToken(req *cmds.Request) (string, error) {
+	if token := os.Getenv("FIL_API_TOKEN"); token != "" {
+		return token, nil
+	}
+
+	rawPath := filepath.Join(filepath.Clean(getRepoDir(req)), repo.APITokenFile)
+	tokenFilePath, err := homedir.Expand(rawPath)
+	if err != nil {
+		return "", errors.Wrap(err, fmt.Sprintf("can't resolve local repo path %s", rawPath))
+	}
+	return repo.APITokenFromFile(tokenFilePath)
+}
+
 func getAPIAddress(req *cmds.Request) (string, error) {
 	var rawAddr string
 	// second highest precedence is env vars.
@@ -296,6 +402,23 @@ func requiresDaemon(req *cmds.Request) bool {
 		return false
 	}
 
+	if req.Command == consoleCmd {
+		return false
+	}
+
+	if req.Command == repoMigrateCmd || req.Command == repoRestoreCmd {
+		return false
+	}
+
+	if req.Command == configDocCmd {
+		return false
+	}
+
+	if req.Command == msgSignCmd {
+		offline, _ := req.Options["offline"].(bool)
+		return !offline
+	}
+
 	return true
 }
 
@@ -317,19 +440,26 @@ func isConnectionRefused(err error) bool {
 	return syscallErr.Err == syscall.ECONNREFUSED
 }
 
-var priceOption = cmdkit.StringOption("price", "Price (FIL e.g. 0.00013) to pay for each GasUnits consumed mining this message")
+var priceOption = cmdkit.StringOption("price", "Price (FIL e.g. 0.00013) to pay for each GasUnits consumed mining this message. Estimated from recent blocks if omitted")
 var limitOption = cmdkit.Uint64Option("limit", "Maximum number of GasUnits this message is allowed to consume")
 var previewOption = cmdkit.BoolOption("preview", "Preview the Gas cost of this command without actually executing it")
 
-func parseGasOptions(req *cmds.Request) (types.AttoFIL, types.GasUnits, bool, error) {
+func parseGasOptions(req *cmds.Request, env cmds.Environment) (types.AttoFIL, types.GasUnits, bool, error) {
+	var price types.AttoFIL
 	priceOption := req.Options["price"]
 	if priceOption == nil {
-		return types.AttoFIL{}, types.NewGasUnits(0), false, errors.New("price option is required")
-	}
-
-	price, ok := types.NewAttoFILFromFILString(priceOption.(string))
-	if !ok {
-		return types.AttoFIL{}, types.NewGasUnits(0), false, errors.New("invalid gas price (specify FIL as a decimal number)")
+		// No price given; use what recent blocks suggest.
+		estimate, err := GetPorcelainAPI(env).MessageEstimateGasPrice(req.Context, porcelain.DefaultGasEstimateBlocks, porcelain.DefaultGasEstimatePercentile)
+		if err != nil {
+			return types.AttoFIL{}, types.NewGasUnits(0), false, errors.Wrap(err, "failed to estimate gas price")
+		}
+		price = estimate
+	} else {
+		p, ok := types.NewAttoFILFromFILString(priceOption.(string))
+		if !ok {
+			return types.AttoFIL{}, types.NewGasUnits(0), false, errors.New("invalid gas price (specify FIL as a decimal number)")
+		}
+		price = *p
 	}
 
 	limitOption := req.Options["limit"]
@@ -345,5 +475,5 @@ func parseGasOptions(req *cmds.Request) (types.AttoFIL, types.GasUnits, bool, er
 
 	preview, _ := req.Options["preview"].(bool)
 
-	return *price, types.NewGasUnits(gasLimitInt), preview, nil
+	return price, types.NewGasUnits(gasLimitInt), preview, nil
 }
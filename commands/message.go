@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"strconv"
+	"strings"
 
 	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
 	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
@@ -12,10 +13,13 @@ import (
 	cmdkit "gx/ipfs/Qmde5VP1qUkyQXKCfmEUA7bP64V2HAptbJ7phuPp7jXWwg/go-ipfs-cmdkit"
 
 	"github.com/filecoin-project/go-filecoin/abi"
-	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/plumbing/msg"
 	"github.com/filecoin-project/go-filecoin/plumbing/mthdsig"
+	"github.com/filecoin-project/go-filecoin/porcelain"
 	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/vm"
+	"github.com/filecoin-project/go-filecoin/wallet"
 )
 
 var msgCmd = &cmds.Command{
@@ -24,8 +28,14 @@ var msgCmd = &cmds.Command{
 		Tagline: "Manage messages",
 	},
 	Subcommands: map[string]*cmds.Command{
-		"send": msgSendCmd,
-		"wait": msgWaitCmd,
+		"call":               msgCallCmd,
+		"compose":            msgComposeCmd,
+		"estimate-gas-price": msgEstimateGasPriceCmd,
+		"publish":            msgPublishCmd,
+		"replay":             msgReplayCmd,
+		"send":               msgSendCmd,
+		"sign":               msgSignCmd,
+		"wait":               msgWaitCmd,
 	},
 }
 
@@ -40,7 +50,7 @@ var msgSendCmd = &cmds.Command{
 		Tagline: "Send a message", // This feels too generic...
 	},
 	Arguments: []cmdkit.Argument{
-		cmdkit.StringArg("target", true, false, "Address of the actor to send the message to"),
+		cmdkit.StringArg("target", true, false, "Address or address book label of the actor to send the message to"),
 		cmdkit.StringArg("method", false, false, "The method to invoke on the target actor"),
 	},
 	Options: []cmdkit.Option{
@@ -52,7 +62,7 @@ var msgSendCmd = &cmds.Command{
 		// TODO: (per dignifiedquire) add an option to set the nonce and method explicitly
 	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
-		target, err := address.NewFromString(req.Arguments[0])
+		target, err := resolveAddr(env, req.Arguments[0])
 		if err != nil {
 			return err
 		}
@@ -62,17 +72,12 @@ var msgSendCmd = &cmds.Command{
 			val = 0
 		}
 
-		o := req.Options["from"]
-		var fromAddr address.Address
-		if o != nil {
-			var err error
-			fromAddr, err = address.NewFromString(o.(string))
-			if err != nil {
-				return errors.Wrap(err, "invalid from address")
-			}
+		fromAddr, err := fromAddrOrDefault(env, req.Options["from"])
+		if err != nil {
+			return err
 		}
 
-		gasPrice, gasLimit, preview, err := parseGasOptions(req)
+		gasPrice, gasLimit, preview, err := parseGasOptions(req, env)
 		if err != nil {
 			return err
 		}
@@ -131,6 +136,389 @@ var msgSendCmd = &cmds.Command{
 	},
 }
 
+var msgCallCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Call a method of an actor read-only",
+		ShortDescription: `
+Executes the method against the chain state and prints its decoded return
+values, one per line. Nothing is broadcast and no gas is paid, so the call
+cannot change any state. Parameters are given in the order of the method's
+signature, see 'go-filecoin actor ls'; amounts are in FIL and lists are comma
+separated.
+
+--height queries the state at that height instead of at the chain head.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("target", true, false, "Address or address book label of the actor to call"),
+		cmdkit.StringArg("method", true, false, "The method to call on the target actor"),
+		cmdkit.StringArg("params", false, true, "The parameters of the method"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.StringOption("from", "Address to call the method from"),
+		cmdkit.StringOption("height", "Height of the chain state to query, defaults to the chain head"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		target, err := resolveAddr(env, req.Arguments[0])
+		if err != nil {
+			return err
+		}
+		method := req.Arguments[1]
+
+		fromAddr, err := optionalResolvedAddr(env, req.Options["from"])
+		if err != nil {
+			return err
+		}
+
+		height, err := optionalBlockHeight(req.Options["height"])
+		if err != nil {
+			return err
+		}
+
+		sig, err := GetPorcelainAPI(env).ActorGetSignature(req.Context, target, method)
+		if err != nil {
+			return errors.Wrap(err, "couldn't get method signature")
+		}
+		params, err := abi.ParseValues(req.Arguments[2:], sig.Params)
+		if err != nil {
+			return errors.Wrap(err, "invalid method parameters")
+		}
+
+		vals, err := GetPorcelainAPI(env).MessageCall(req.Context, fromAddr, target, method, height, abi.FromValues(params)...)
+		if err != nil {
+			return err
+		}
+
+		out := make([]string, len(vals))
+		for i, v := range vals {
+			out[i] = v.String()
+		}
+		return re.Emit(out)
+	},
+	Type: []string{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, vals *[]string) error {
+			for _, v := range *vals {
+				if _, err := fmt.Fprintln(w, v); err != nil {
+					return err
+				}
+			}
+			return nil
+		}),
+	},
+}
+
+var msgComposeCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Build an unsigned message",
+		ShortDescription: `
+Prints an unsigned message as JSON, to be signed with 'go-filecoin message
+sign' and sent with 'go-filecoin message publish'. Together they let funds be
+managed from a cold wallet whose keys never touch a networked machine:
+
+  online$   go-filecoin message compose --from=<cold addr> <target> > msg.json
+  offline$  go-filecoin message sign --offline msg.json > signed.json
+  online$   go-filecoin message publish signed.json
+
+--from defaults to the wallet's default address, see 'go-filecoin wallet
+default'. The next nonce of the from address is used unless --nonce is given.
+It is not reserved, so pass explicit nonces when composing several messages
+before publishing them.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("target", true, false, "Address of the actor to send the message to"),
+		cmdkit.StringArg("method", false, false, "The method to invoke on the target actor"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.StringOption("from", "Address to send the message from"),
+		cmdkit.StringOption("value", "Value to send with the message, in FIL").WithDefault("0"),
+		cmdkit.Uint64Option("nonce", "Nonce of the message, defaults to the next nonce of the from address"),
+		priceOption,
+		limitOption,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		target, err := resolveAddr(env, req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		var method string
+		if len(req.Arguments) > 1 {
+			method = req.Arguments[1]
+		}
+
+		fromAddr, err := fromAddrOrDefault(env, req.Options["from"])
+		if err != nil {
+			return err
+		}
+
+		value, ok := types.NewAttoFILFromFILString(req.Options["value"].(string))
+		if !ok {
+			return ErrInvalidAmount
+		}
+
+		var nonce *uint64
+		if n, ok := req.Options["nonce"].(uint64); ok {
+			nonce = &n
+		}
+
+		gasPrice, gasLimit, _, err := parseGasOptions(req, env)
+		if err != nil {
+			return err
+		}
+
+		msg, err := GetPorcelainAPI(env).MessageCompose(req.Context, fromAddr, target, nonce, value, gasPrice, gasLimit, method)
+		if err != nil {
+			return err
+		}
+
+		return re.Emit(msg)
+	},
+	Type: types.MeteredMessage{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, msg *types.MeteredMessage) error {
+			return printIndentedJSON(w, msg)
+		}),
+	},
+}
+
+var msgSignCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Sign a message built with 'go-filecoin message compose'",
+		ShortDescription: `
+Signs the message with the key of its from address and prints the signed
+message as JSON. With --offline the key is read directly from the repo's
+keystore, so no daemon needs to run, e.g. on an air-gapped machine holding a
+cold wallet. The daemon must not be running on that repo at the same time.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.FileArg("message", true, false, "File containing the unsigned message").EnableStdin(),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.BoolOption("offline", "Sign with the repo's keystore instead of asking the daemon"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		fi, err := req.Files.NextFile()
+		if err != nil {
+			return err
+		}
+
+		var msg types.MeteredMessage
+		if err := json.NewDecoder(fi).Decode(&msg); err != nil {
+			return errors.Wrap(err, "failed to decode message")
+		}
+
+		var smsg *types.SignedMessage
+		if offline, _ := req.Options["offline"].(bool); offline {
+			smsg, err = signOffline(req, &msg)
+		} else {
+			smsg, err = types.NewSignedMessage(msg.Message, GetPorcelainAPI(env), msg.GasPrice, msg.GasLimit)
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to sign message")
+		}
+
+		return re.Emit(smsg)
+	},
+	Type: types.SignedMessage{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, smsg *types.SignedMessage) error {
+			return printIndentedJSON(w, smsg)
+		}),
+	},
+}
+
+// signOffline signs msg with a key from the keystore of the local repo,
+// without involving a daemon.
+func signOffline(req *cmds.Request, msg *types.MeteredMessage) (*types.SignedMessage, error) {
+	rep, err := getRepo(req)
+	if err != nil {
+		return nil, err
+	}
+	defer rep.Close() // nolint: errcheck
+
+	backend, err := wallet.NewDSBackend(rep.WalletDatastore())
+	if err != nil {
+		return nil, err
+	}
+
+	return types.NewSignedMessage(msg.Message, wallet.New(backend), msg.GasPrice, msg.GasLimit)
+}
+
+var msgPublishCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Send a message signed with 'go-filecoin message sign'",
+		ShortDescription: `
+Adds the signed message to the message pool and broadcasts it to the network.
+Prints the cid of the message, which can be passed to 'go-filecoin message wait'.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.FileArg("message", true, false, "File containing the signed message").EnableStdin(),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		fi, err := req.Files.NextFile()
+		if err != nil {
+			return err
+		}
+
+		var smsg types.SignedMessage
+		if err := json.NewDecoder(fi).Decode(&smsg); err != nil {
+			return errors.Wrap(err, "failed to decode signed message")
+		}
+
+		c, err := GetPorcelainAPI(env).MessagePublish(req.Context, &smsg)
+		if err != nil {
+			return err
+		}
+
+		return re.Emit(c)
+	},
+	Type: cid.Cid{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, c cid.Cid) error {
+			return PrintString(w, c)
+		}),
+	},
+}
+
+var msgReplayCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Re-execute a message that is on chain",
+		ShortDescription: `
+Re-executes the tipset including the message over the state it was applied to
+and prints the outcome of the message. Nothing is persisted. With --trace the
+execution of the message is printed too: the messages it sent to other
+actors, the gas charged by each call and the changes made to actors' balances
+and storage. This helps debugging messages that failed.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("cid", true, false, "CID of the message to replay"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.BoolOption("trace", "Print the execution trace of the message"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		msgCid, err := cid.Parse(req.Arguments[0])
+		if err != nil {
+			return errors.Wrap(err, "invalid message cid")
+		}
+
+		replay, err := GetPorcelainAPI(env).MessageReplay(req.Context, msgCid)
+		if err != nil {
+			return err
+		}
+
+		if trace, _ := req.Options["trace"].(bool); !trace {
+			replay.Trace = nil
+		}
+		return re.Emit(replay)
+	},
+	Type: msg.Replay{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, replay *msg.Replay) error {
+			fmt.Fprintf(w, "Block:     %s\n", replay.Block) // nolint: errcheck
+			if replay.Receipt != nil {
+				fmt.Fprintf(w, "Exit code: %d\n", replay.Receipt.ExitCode)   // nolint: errcheck
+				fmt.Fprintf(w, "Gas cost:  %s\n", replay.Receipt.GasAttoFIL) // nolint: errcheck
+			}
+			if replay.Error != "" {
+				fmt.Fprintf(w, "Error:     %s\n", replay.Error) // nolint: errcheck
+			}
+			if replay.Trace == nil {
+				return nil
+			}
+			fmt.Fprintln(w, "\nTrace:") // nolint: errcheck
+			return printTrace(w, replay.Trace, 1)
+		}),
+	},
+}
+
+// printTrace prints the execution trace of a call and its subcalls, indented
+// by depth.
+func printTrace(w io.Writer, trace *vm.Trace, depth int) error {
+	indent := strings.Repeat("  ", depth)
+
+	method := trace.Method
+	if method == "" {
+		method = "(transfer)"
+	}
+	value := types.ZeroAttoFIL
+	if trace.Value != nil {
+		value = trace.Value
+	}
+	if _, err := fmt.Fprintf(w, "%s%s -> %s %s value=%s gas=%d exit=%d\n", indent, trace.From, trace.To, method, value, trace.GasUsed, trace.ExitCode); err != nil {
+		return err
+	}
+	if trace.Error != "" {
+		fmt.Fprintf(w, "%s  error: %s\n", indent, trace.Error) // nolint: errcheck
+	}
+	if len(trace.GasCharges) > 0 {
+		charges := make([]string, len(trace.GasCharges))
+		for i, c := range trace.GasCharges {
+			charges[i] = strconv.FormatUint(uint64(c), 10)
+		}
+		fmt.Fprintf(w, "%s  gas charges: %s\n", indent, strings.Join(charges, ", ")) // nolint: errcheck
+	}
+	for _, c := range trace.StateChanges {
+		old := c.Old
+		if old == "" {
+			old = "-"
+		}
+		fmt.Fprintf(w, "%s  %s of %s: %s -> %s\n", indent, c.Kind, c.Actor, old, c.New) // nolint: errcheck
+	}
+	for _, sub := range trace.Subcalls {
+		if err := printTrace(w, sub, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func printIndentedJSON(w io.Writer, val interface{}) error {
+	b, err := json.MarshalIndent(val, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
+
+var msgEstimateGasPriceCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Suggest a gas price based on recently mined messages",
+		ShortDescription: `
+Looks at the gas prices paid by messages in the most recent blocks and
+suggests the given percentile of them. This is the price used by commands
+that send messages when --price is omitted.
+`,
+	},
+	Options: []cmdkit.Option{
+		cmdkit.UintOption("nblocks", "Number of recent blocks to consider").WithDefault(uint(porcelain.DefaultGasEstimateBlocks)),
+		cmdkit.UintOption("percentile", "Percentile (0-100) of recent gas prices to suggest").WithDefault(uint(porcelain.DefaultGasEstimatePercentile)),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		nBlocks, _ := req.Options["nblocks"].(uint)
+		percentile, _ := req.Options["percentile"].(uint)
+
+		price, err := GetPorcelainAPI(env).MessageEstimateGasPrice(req.Context, nBlocks, percentile)
+		if err != nil {
+			return err
+		}
+
+		return re.Emit(&price)
+	},
+	Type: types.AttoFIL{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, price *types.AttoFIL) error {
+			return PrintString(w, price)
+		}),
+	},
+}
+
 // WaitResult is the result of a message wait call.
 type WaitResult struct {
 	Message   *types.SignedMessage
@@ -10,6 +10,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/fixtures"
 	th "github.com/filecoin-project/go-filecoin/testhelpers"
 	"github.com/filecoin-project/go-filecoin/types"
@@ -142,3 +143,76 @@ func TestMessageSendBlockGasLimit(t *testing.T) {
 		assert.NotEmpty(t, result.Messages, "msg under the block gas limit passes validation and is run in the block")
 	})
 }
+
+func TestMessageComposeSignPublish(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	d := th.NewDaemon(
+		t,
+		th.WithMiner(fixtures.TestMiners[0]),
+		th.KeyFile(fixtures.KeyFilePaths()[0]),
+	).Start()
+	defer d.ShutdownSuccess()
+
+	unsigned := d.RunSuccess("message", "compose",
+		"--from", fixtures.TestAddresses[0],
+		"--price", "0", "--limit", "300",
+		"--value=10", fixtures.TestAddresses[1],
+	).ReadStdout()
+
+	var msg types.MeteredMessage
+	require.NoError(json.Unmarshal([]byte(unsigned), &msg))
+	assert.Equal(fixtures.TestAddresses[0], msg.From.String())
+	assert.Equal(types.NewAttoFILFromFIL(10), msg.Value)
+
+	t.Log("sign offline with the daemon stopped")
+	d.Stop()
+	signed := d.RunWithStdin(strings.NewReader(unsigned), "message", "sign", "--offline").ReadStdout()
+	d.Start()
+
+	var smsg types.SignedMessage
+	require.NoError(json.Unmarshal([]byte(signed), &smsg))
+	assert.True(smsg.VerifySignature())
+
+	msgCid, err := smsg.Cid()
+	require.NoError(err)
+	published := d.RunWithStdin(strings.NewReader(signed), "message", "publish").ReadStdoutTrimNewlines()
+	assert.Equal(msgCid.String(), published)
+
+	d.RunSuccess("mining", "once")
+	d.WaitForMessageRequireSuccess(msgCid)
+
+	t.Log("publishing a tampered message fails")
+	smsg.Nonce = smsg.Nonce + 1
+	tampered, err := json.Marshal(&smsg)
+	require.NoError(err)
+	out := d.RunWithStdin(strings.NewReader(string(tampered)), "message", "publish")
+	assert.Contains(out.ReadStderr(), "sig invalid")
+}
+
+func TestMessageCall(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	d := th.NewDaemon(
+		t,
+		th.WithMiner(fixtures.TestMiners[0]),
+		th.KeyFile(fixtures.KeyFilePaths()[0]),
+	).Start()
+	defer d.ShutdownSuccess()
+
+	d.RunSuccess("mining", "once")
+
+	reward := address.RewardAddress.String()
+	mined := d.RunSuccess("message", "call", reward, "getTotalMined").ReadStdoutTrimNewlines()
+	assert.NotEqual("0", mined)
+
+	t.Log("the genesis state has nothing mined")
+	genesis := d.RunSuccess("message", "call", "--height", "0", reward, "getTotalMined").ReadStdoutTrimNewlines()
+	assert.Equal("0", genesis)
+
+	d.RunFail("expected 0 values, got 1", "message", "call", reward, "getTotalMined", "1")
+	d.RunFail("missing export", "message", "call", reward, "nope")
+}
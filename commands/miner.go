@@ -5,6 +5,7 @@ import (
 	"io"
 	"math/big"
 	"strconv"
+	"time"
 
 	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
 	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
@@ -13,7 +14,9 @@ import (
 	"gx/ipfs/Qmde5VP1qUkyQXKCfmEUA7bP64V2HAptbJ7phuPp7jXWwg/go-ipfs-cmdkit"
 
 	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/plumbing/sctr"
 	"github.com/filecoin-project/go-filecoin/porcelain"
+	"github.com/filecoin-project/go-filecoin/protocol/storage"
 	"github.com/filecoin-project/go-filecoin/types"
 )
 
@@ -22,13 +25,17 @@ var minerCmd = &cmds.Command{
 		Tagline: "Manage a single miner actor",
 	},
 	Subcommands: map[string]*cmds.Command{
-		"create":        minerCreateCmd,
-		"add-ask":       minerAddAskCmd,
-		"owner":         minerOwnerCmd,
-		"pledge":        minerPledgeCmd,
-		"power":         minerPowerCmd,
-		"set-price":     minerSetPriceCmd,
-		"update-peerid": minerUpdatePeerIDCmd,
+		"create":           minerCreateCmd,
+		"add-ask":          minerAddAskCmd,
+		"import-deal-data": minerImportDealDataCmd,
+		"owner":            minerOwnerCmd,
+		"pledge":           minerPledgeCmd,
+		"pledge-sector":    minerPledgeSectorCmd,
+		"post-status":      minerPoStStatusCmd,
+		"power":            minerPowerCmd,
+		"sectors":          minerSectorsCmd,
+		"set-price":        minerSetPriceCmd,
+		"update-peerid":    minerUpdatePeerIDCmd,
 	},
 }
 
@@ -86,7 +93,7 @@ Collateral must be greater than 0.001 FIL per pledged sector.`,
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
 		var err error
 
-		fromAddr, err := optionalAddr(req.Options["from"])
+		fromAddr, err := fromAddrOrDefault(env, req.Options["from"])
 		if err != nil {
 			return err
 		}
@@ -110,7 +117,7 @@ Collateral must be greater than 0.001 FIL per pledged sector.`,
 			return ErrInvalidCollateral
 		}
 
-		gasPrice, gasLimit, preview, err := parseGasOptions(req)
+		gasPrice, gasLimit, preview, err := parseGasOptions(req, env)
 		if err != nil {
 			return err
 		}
@@ -167,6 +174,7 @@ var minerSetPriceCmd = &cmds.Command{
 	Helptext: cmdkit.HelpText{
 		Tagline: "Set the minimum price for storage",
 		ShortDescription: `Sets the mining.minimumPrice in config and creates a new ask for the given price.
+The ask is for pieces of any size, unless bounded by --min-piece-size or --max-piece-size.
 This command waits for the ask to be mined.`,
 	},
 	Arguments: []cmdkit.Argument{
@@ -176,6 +184,8 @@ This command waits for the ask to be mined.`,
 	Options: []cmdkit.Option{
 		cmdkit.StringOption("from", "Address to send from"),
 		cmdkit.StringOption("miner", "The address of the miner owning the ask"),
+		cmdkit.StringOption("min-piece-size", "The minimum size in bytes of the pieces the ask is for"),
+		cmdkit.StringOption("max-piece-size", "The maximum size in bytes of the pieces the ask is for"),
 		priceOption,
 		limitOption,
 		previewOption,
@@ -186,7 +196,16 @@ This command waits for the ask to be mined.`,
 			return ErrInvalidPrice
 		}
 
-		fromAddr, err := optionalAddr(req.Options["from"])
+		minSize, err := optionalBytesAmount(req.Options["min-piece-size"])
+		if err != nil {
+			return errors.Wrap(err, "invalid min-piece-size")
+		}
+		maxSize, err := optionalBytesAmount(req.Options["max-piece-size"])
+		if err != nil {
+			return errors.Wrap(err, "invalid max-piece-size")
+		}
+
+		fromAddr, err := fromAddrOrDefault(env, req.Options["from"])
 		if err != nil {
 			return err
 		}
@@ -204,7 +223,7 @@ This command waits for the ask to be mined.`,
 			return fmt.Errorf("expiry must be a valid integer")
 		}
 
-		gasPrice, gasLimit, preview, err := parseGasOptions(req)
+		gasPrice, gasLimit, preview, err := parseGasOptions(req, env)
 		if err != nil {
 			return err
 		}
@@ -215,7 +234,9 @@ This command waits for the ask to be mined.`,
 				fromAddr,
 				minerAddr,
 				price,
-				expiry)
+				expiry,
+				minSize,
+				maxSize)
 			if err != nil {
 				return err
 			}
@@ -233,7 +254,9 @@ This command waits for the ask to be mined.`,
 			gasPrice,
 			gasLimit,
 			price,
-			expiry)
+			expiry,
+			minSize,
+			maxSize)
 		if err != nil {
 			return err
 		}
@@ -272,6 +295,36 @@ type minerUpdatePeerIDResult struct {
 	Preview bool
 }
 
+var minerImportDealDataCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Import the data of an offline storage deal",
+		ShortDescription: `
+Imports the data of the offline storage deal whose proposal has the given CID
+from a CAR file, e.g. delivered on a hard drive. The client of an offline deal
+exports the data with the client export-car command instead of transferring it
+over the network. The data must match the piece commitment of the proposal,
+after which the deal proceeds to sealing.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("id", true, false, "CID of the deal proposal"),
+		cmdkit.FileArg("file", true, false, "Path to the CAR file").EnableStdin(),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		propcid, err := cid.Decode(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		fi, err := req.Files.NextFile()
+		if err != nil {
+			return err
+		}
+
+		return GetAPI(env).Miner().ImportDealData(req.Context, propcid, fi)
+	},
+}
+
 var minerUpdatePeerIDCmd = &cmds.Command{
 	Helptext: cmdkit.HelpText{
 		Tagline:          "Change the libp2p identity that a miner is operating",
@@ -293,7 +346,7 @@ var minerUpdatePeerIDCmd = &cmds.Command{
 			return err
 		}
 
-		fromAddr, err := optionalAddr(req.Options["from"])
+		fromAddr, err := fromAddrOrDefault(env, req.Options["from"])
 		if err != nil {
 			return err
 		}
@@ -303,7 +356,7 @@ var minerUpdatePeerIDCmd = &cmds.Command{
 			return err
 		}
 
-		gasPrice, gasLimit, preview, err := parseGasOptions(req)
+		gasPrice, gasLimit, preview, err := parseGasOptions(req, env)
 		if err != nil {
 			return err
 		}
@@ -351,6 +404,19 @@ var minerUpdatePeerIDCmd = &cmds.Command{
 	},
 }
 
+// optionalBytesAmount parses the byte amount given as option o, if any.
+func optionalBytesAmount(o interface{}) (*types.BytesAmount, error) {
+	str, ok := o.(string)
+	if !ok || str == "" {
+		return nil, nil
+	}
+	amount, ok := types.NewBytesAmountFromString(str, 10)
+	if !ok {
+		return nil, fmt.Errorf("%q is not a number of bytes", str)
+	}
+	return amount, nil
+}
+
 type minerAddAskResult struct {
 	Cid     cid.Cid
 	GasUsed types.GasUnits
@@ -373,7 +439,7 @@ var minerAddAskCmd = &cmds.Command{
 		previewOption,
 	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
-		fromAddr, err := optionalAddr(req.Options["from"])
+		fromAddr, err := fromAddrOrDefault(env, req.Options["from"])
 		if err != nil {
 			return err
 		}
@@ -393,7 +459,7 @@ var minerAddAskCmd = &cmds.Command{
 			return fmt.Errorf("expiry must be a valid integer")
 		}
 
-		gasPrice, gasLimit, preview, err := parseGasOptions(req)
+		gasPrice, gasLimit, preview, err := parseGasOptions(req, env)
 		if err != nil {
 			return err
 		}
@@ -501,3 +567,181 @@ Values will be output as a ratio where the first number is the miner power and s
 		}),
 	},
 }
+
+var minerSectorsCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Follow the sectors of the miner through sealing",
+	},
+	Subcommands: map[string]*cmds.Command{
+		"status": minerSectorsStatusCmd,
+		"events": minerSectorsEventsCmd,
+	},
+}
+
+var minerSectorsStatusCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show the sealing progress of a sector",
+		ShortDescription: `
+Shows the stage of the sector with the given id, or of every sector the node
+saw since it started if no id is given: staged, sealing, committing, committed,
+proving, faulted or failed. The percentage and ETA of a stage are estimated from the time the
+sectors before took for it, and are left out until one completed it. Sectors
+taking 3 times longer than usual are marked as stuck.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("id", false, false, "Id of the sector"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		if len(req.Arguments) == 0 {
+			for _, p := range GetPorcelainAPI(env).SectorProgressList() {
+				if err := re.Emit(p); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		sectorID, err := strconv.ParseUint(req.Arguments[0], 10, 64)
+		if err != nil {
+			return err
+		}
+		p, err := GetPorcelainAPI(env).SectorProgress(sectorID)
+		if err != nil {
+			return err
+		}
+		return re.Emit(p)
+	},
+	Type: sctr.Progress{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, p *sctr.Progress) error {
+			line := fmt.Sprintf("sector %d: %s for %s", p.SectorID, p.Stage, p.Elapsed.Round(time.Second))
+			if p.Percent > 0 && p.Percent < 100 {
+				line += fmt.Sprintf(", %d%% done, ETA %s", p.Percent, p.ETA.Round(time.Second))
+			}
+			if p.Stuck {
+				line += ", stuck"
+			}
+			if p.Error != "" {
+				line += ": " + p.Error
+			}
+			_, err := fmt.Fprintln(w, line)
+			return err
+		}),
+	},
+}
+
+var minerSectorsEventsCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Stream sector events",
+		ShortDescription: `
+Prints an event every time a sector of the miner moves to another stage, until
+interrupted. A sector is proving once a PoSt proved it, and faulted when a PoSt
+or a check found it missing or corrupt.
+`,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		for evt := range GetPorcelainAPI(env).SectorEvents(req.Context) {
+			evt := evt
+			if err := re.Emit(&evt); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Type: sctr.SectorEvent{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, evt *sctr.SectorEvent) error {
+			from := evt.From
+			if from == "" {
+				from = "-"
+			}
+			if evt.Error != "" {
+				_, err := fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n", evt.Time.Format(time.RFC3339), evt.SectorID, from, evt.To, evt.Error)
+				return err
+			}
+			_, err := fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", evt.Time.Format(time.RFC3339), evt.SectorID, from, evt.To)
+			return err
+		}),
+	},
+}
+
+var minerPoStStatusCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show the state of the PoSt of the current proving period",
+		ShortDescription: `
+The miner generates and submits a proof of spacetime once each proving period
+starts, retrying failed attempts with backoff until the period ends, with the
+gas and retries configured in mining.post. Shows the state of the PoSt of the
+current proving period, and the alerts raised for periods at risk of being
+missed, or missed, which leave the sectors of the miner faulty.
+`,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		status, err := GetAPI(env).Miner().PoStStatus(req.Context)
+		if err != nil {
+			return err
+		}
+		return re.Emit(status)
+	},
+	Type: storage.ProverStatus{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, s *storage.ProverStatus) error {
+			if s.ProvingPeriodStart == nil {
+				_, err := fmt.Fprintln(w, "no PoSt due, the miner has no committed sectors")
+				return err
+			}
+			fmt.Fprintf(w, "proving period: %s to %s\n", s.ProvingPeriodStart, s.ProvingPeriodEnd) // nolint: errcheck
+			fmt.Fprintf(w, "state:          %s\n", s.State)                                        // nolint: errcheck
+			if s.Attempts > 0 {
+				fmt.Fprintf(w, "failed:         %d attempts, last: %s\n", s.Attempts, s.LastError) // nolint: errcheck
+			}
+			if s.Message != nil {
+				fmt.Fprintf(w, "message:        %s\n", s.Message) // nolint: errcheck
+			}
+			if len(s.Faults) > 0 {
+				fmt.Fprintf(w, "faults:         %v\n", s.Faults) // nolint: errcheck
+			}
+			for _, a := range s.Alerts {
+				fmt.Fprintf(w, "alert at block %s: %s\n", a.Height, a.Message) // nolint: errcheck
+			}
+			return nil
+		}),
+	},
+}
+
+var minerPledgeSectorCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Seal sectors filled with self-generated data",
+		ShortDescription: `
+Fills sectors with self-generated data instead of the data of deals, which are
+sealed and committed like other sectors, adding to the power of the miner.
+Outputs the ids of the sectors. To keep pledging sectors until the miner has
+a given power, set mining.pledge.targetPower instead.
+`,
+	},
+	Options: []cmdkit.Option{
+		cmdkit.Uint64Option("count", "Number of sectors to pledge").WithDefault(uint64(1)),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		count, _ := req.Options["count"].(uint64)
+		if count == 0 {
+			return errors.New("count must be positive")
+		}
+
+		sectorIDs, err := GetAPI(env).Miner().PledgeSectors(req.Context, count)
+		for _, id := range sectorIDs {
+			if err := re.Emit(id); err != nil {
+				return err
+			}
+		}
+		return err
+	},
+	Type: uint64(0),
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, sectorID *uint64) error {
+			_, err := fmt.Fprintf(w, "pledged sector %d\n", *sectorID)
+			return err
+		}),
+	},
+}
@@ -358,7 +358,7 @@ func TestMinerCreateChargesGas(t *testing.T) {
 	d1.MineAndPropagate(time.Second, d)
 	wg.Wait()
 
-	expectedBlockReward := consensus.NewDefaultBlockRewarder().BlockRewardAmount()
+	expectedBlockReward := consensus.NewDefaultBlockRewarder().BlockRewardAmount(nil)
 	expectedPrice := types.NewAttoFILFromFIL(333)
 	expectedGasCost := big.NewInt(100)
 	expectedBalance := expectedBlockReward.Add(expectedPrice.MulBigInt(expectedGasCost))
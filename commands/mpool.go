@@ -9,6 +9,7 @@ import (
 	"gx/ipfs/Qma6uuSyjkecGhMFFLfzyJDPyoDtNJSHJNweDccZhaWkgU/go-ipfs-cmds"
 	"gx/ipfs/Qmde5VP1qUkyQXKCfmEUA7bP64V2HAptbJ7phuPp7jXWwg/go-ipfs-cmdkit"
 
+	"github.com/filecoin-project/go-filecoin/core"
 	"github.com/filecoin-project/go-filecoin/types"
 )
 
@@ -17,8 +18,10 @@ var mpoolCmd = &cmds.Command{
 		Tagline: "Manage the message pool",
 	},
 	Subcommands: map[string]*cmds.Command{
-		"ls": mpoolLsCmd,
-		"rm": mpoolRemoveCmd,
+		"events": mpoolEventsCmd,
+		"ls":     mpoolLsCmd,
+		"rm":     mpoolRemoveCmd,
+		"stats":  mpoolStatsCmd,
 	},
 }
 
@@ -72,3 +75,55 @@ var mpoolRemoveCmd = &cmds.Command{
 		return nil
 	},
 }
+
+var mpoolStatsCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show message pool counters and gauges",
+		ShortDescription: `
+Counters (adds, duplicates, drops, removes) are cumulative since the daemon
+started. Gauges (size, bytes) describe the pool as it is now.
+`,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		stats := GetPorcelainAPI(env).MessagePoolStats()
+		return re.Emit(&stats)
+	},
+	Type: core.MessagePoolStats{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, stats *core.MessagePoolStats) error {
+			_, err := fmt.Fprintf(w, "size:\t%d\nbytes:\t%d\nadds:\t%d\nduplicates:\t%d\ndrops:\t%d\nremoves:\t%d\n",
+				stats.Size, stats.Bytes, stats.Adds, stats.Duplicates, stats.Drops, stats.Removes)
+			return err
+		}),
+	},
+}
+
+var mpoolEventsCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Stream message pool events",
+		ShortDescription: `
+Prints an event every time a message is added to, dropped by or removed from
+the message pool, until interrupted.
+`,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		for evt := range GetPorcelainAPI(env).MessagePoolSubscribe(req.Context) {
+			evt := evt
+			if err := re.Emit(&evt); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Type: core.MessagePoolEvent{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, evt *core.MessagePoolEvent) error {
+			if evt.Reason != "" {
+				_, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", evt.Type, evt.Cid, evt.From, evt.Reason)
+				return err
+			}
+			_, err := fmt.Fprintf(w, "%s\t%s\t%s\n", evt.Type, evt.Cid, evt.From)
+			return err
+		}),
+	},
+}
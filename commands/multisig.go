@@ -0,0 +1,279 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"gx/ipfs/Qma6uuSyjkecGhMFFLfzyJDPyoDtNJSHJNweDccZhaWkgU/go-ipfs-cmds"
+	"gx/ipfs/Qmde5VP1qUkyQXKCfmEUA7bP64V2HAptbJ7phuPp7jXWwg/go-ipfs-cmdkit"
+
+	"github.com/filecoin-project/go-filecoin/actor/builtin/multisig"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/porcelain"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+var multisigCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Manage multi-signature wallets",
+		ShortDescription: `
+A multisig wallet holds funds that can only be sent once M of its N signers
+have approved. Any signer can propose a send, which counts as their approval;
+the other signers then approve it by its transaction id.
+`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"create":  multisigCreateCmd,
+		"propose": multisigProposeCmd,
+		"approve": multisigApproveCmd,
+		"cancel":  multisigCancelCmd,
+		"info":    multisigInfoCmd,
+	},
+}
+
+var multisigCreateCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Create a new multisig wallet",
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("required", true, false, "Number of approvals required to send funds"),
+		cmdkit.StringArg("signers", true, true, "Addresses of the wallet's signers"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.StringOption("from", "Address to send from"),
+		cmdkit.StringOption("value", "Amount in FIL to deposit in the wallet").WithDefault("0"),
+		cmdkit.StringOption("unlock", "Number of blocks that must pass after a send is proposed before it can be executed").WithDefault("0"),
+		priceOption,
+		limitOption,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		fromAddr, err := fromAddrOrDefault(env, req.Options["from"])
+		if err != nil {
+			return err
+		}
+
+		required, err := strconv.ParseUint(req.Arguments[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number of required approvals: %s", req.Arguments[0])
+		}
+
+		var signers []address.Address
+		for _, arg := range req.Arguments[1:] {
+			signer, err := address.NewFromString(arg)
+			if err != nil {
+				return err
+			}
+			signers = append(signers, signer)
+		}
+
+		value, ok := types.NewAttoFILFromFILString(req.Options["value"].(string))
+		if !ok {
+			return ErrInvalidAmount
+		}
+
+		unlock, ok := types.NewBlockHeightFromString(req.Options["unlock"].(string), 10)
+		if !ok {
+			return ErrInvalidBlockHeight
+		}
+
+		gasPrice, gasLimit, _, err := parseGasOptions(req, env)
+		if err != nil {
+			return err
+		}
+
+		walletAddr, err := GetPorcelainAPI(env).MultisigCreate(req.Context, fromAddr, gasPrice, gasLimit, signers, required, unlock, value)
+		if err != nil {
+			return err
+		}
+
+		return re.Emit(walletAddr)
+	},
+	Type: address.Address{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, a *address.Address) error {
+			return PrintString(w, a)
+		}),
+	},
+}
+
+var multisigProposeCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline:          "Propose sending funds from a multisig wallet",
+		ShortDescription: `Proposes a send and prints the id of the resulting transaction.`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("wallet", true, false, "Address of the multisig wallet"),
+		cmdkit.StringArg("to", true, false, "Address to send the funds to"),
+		cmdkit.StringArg("value", true, false, "Amount in FIL to send"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.StringOption("from", "Signer address to propose from"),
+		priceOption,
+		limitOption,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		fromAddr, err := fromAddrOrDefault(env, req.Options["from"])
+		if err != nil {
+			return err
+		}
+
+		wallet, err := address.NewFromString(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		to, err := address.NewFromString(req.Arguments[1])
+		if err != nil {
+			return err
+		}
+
+		value, ok := types.NewAttoFILFromFILString(req.Arguments[2])
+		if !ok {
+			return ErrInvalidAmount
+		}
+
+		gasPrice, gasLimit, _, err := parseGasOptions(req, env)
+		if err != nil {
+			return err
+		}
+
+		txID, err := GetPorcelainAPI(env).MultisigPropose(req.Context, fromAddr, gasPrice, gasLimit, wallet, to, value)
+		if err != nil {
+			return err
+		}
+
+		return re.Emit(txID)
+	},
+	Type: uint64(0),
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, txID *uint64) error {
+			_, err := fmt.Fprintln(w, *txID)
+			return err
+		}),
+	},
+}
+
+var multisigApproveCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Approve a pending multisig transaction",
+		ShortDescription: `
+Adds your approval to a pending transaction. The funds are sent once the
+transaction has enough approvals and the wallet's timelock has passed; if the
+timelock is still running, approve again after it has expired.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("wallet", true, false, "Address of the multisig wallet"),
+		cmdkit.StringArg("id", true, false, "Id of the transaction to approve"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.StringOption("from", "Signer address to approve from"),
+		priceOption,
+		limitOption,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		return runMultisigTxCmd(req, env, GetPorcelainAPI(env).MultisigApprove)
+	},
+}
+
+var multisigCancelCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Cancel a multisig transaction you proposed",
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("wallet", true, false, "Address of the multisig wallet"),
+		cmdkit.StringArg("id", true, false, "Id of the transaction to cancel"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.StringOption("from", "Address that proposed the transaction"),
+		priceOption,
+		limitOption,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		return runMultisigTxCmd(req, env, GetPorcelainAPI(env).MultisigCancel)
+	},
+}
+
+type multisigTxFunc func(ctx context.Context, from address.Address, gasPrice types.AttoFIL, gasLimit types.GasUnits, wallet address.Address, txID uint64) error
+
+// runMultisigTxCmd parses the arguments shared by the commands acting on a
+// pending multisig transaction and calls f with them.
+func runMultisigTxCmd(req *cmds.Request, env cmds.Environment, f multisigTxFunc) error {
+	fromAddr, err := fromAddrOrDefault(env, req.Options["from"])
+	if err != nil {
+		return err
+	}
+
+	wallet, err := address.NewFromString(req.Arguments[0])
+	if err != nil {
+		return err
+	}
+
+	txID, err := strconv.ParseUint(req.Arguments[1], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid transaction id: %s", req.Arguments[1])
+	}
+
+	gasPrice, gasLimit, _, err := parseGasOptions(req, env)
+	if err != nil {
+		return err
+	}
+
+	return f(req.Context, fromAddr, gasPrice, gasLimit, wallet, txID)
+}
+
+var multisigInfoCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show the signers and pending transactions of a multisig wallet",
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("wallet", true, false, "Address of the multisig wallet"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		wallet, err := address.NewFromString(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		info, err := GetPorcelainAPI(env).MultisigGetInfo(req.Context, wallet)
+		if err != nil {
+			return err
+		}
+
+		return re.Emit(info)
+	},
+	Type: &porcelain.MultisigInfo{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, info *porcelain.MultisigInfo) error {
+			fmt.Fprintf(w, "Required approvals: %d\n", info.Required) // nolint: errcheck
+			fmt.Fprintln(w, "Signers:")                               // nolint: errcheck
+			for _, s := range info.Signers {
+				fmt.Fprintf(w, "  %s\n", s) // nolint: errcheck
+			}
+
+			if len(info.Pending) == 0 {
+				fmt.Fprintln(w, "No pending transactions") // nolint: errcheck
+				return nil
+			}
+
+			var txs []*multisig.Transaction
+			for _, tx := range info.Pending {
+				txs = append(txs, tx)
+			}
+			sort.Slice(txs, func(i, j int) bool { return txs[i].ID < txs[j].ID })
+
+			fmt.Fprintln(w, "Pending transactions:") // nolint: errcheck
+			for _, tx := range txs {
+				_, err := fmt.Fprintf(w, "  %d: send %s to %s, proposed by %s at height %s, %d/%d approvals\n",
+					tx.ID, tx.Value, tx.To, tx.Proposer, tx.ProposedAt, len(tx.Approvals), info.Required)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}),
+	},
+}
@@ -0,0 +1,174 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"gx/ipfs/Qma6uuSyjkecGhMFFLfzyJDPyoDtNJSHJNweDccZhaWkgU/go-ipfs-cmds"
+	"gx/ipfs/Qmde5VP1qUkyQXKCfmEUA7bP64V2HAptbJ7phuPp7jXWwg/go-ipfs-cmdkit"
+)
+
+// OptionOutput is the name of the option selecting the format of the output
+// of commands.
+const OptionOutput = "output"
+
+// CSV is the encoding type writing the output of commands as comma
+// separated values.
+const CSV cmds.EncodingType = "csv"
+
+// outputFormats are the values of the output option. json and csv are
+// meant for scripts: they are derived from the JSON encoding of the results
+// of commands, so their field names are the JSON ones and stay stable.
+var outputFormats = map[string]cmds.EncodingType{
+	"text": cmds.Text,
+	"json": cmds.JSON,
+	"csv":  CSV,
+}
+
+var outputOption = cmdkit.StringOption(OptionOutput, "Output format: text, json or csv")
+
+func init() {
+	cmds.Encoders[CSV] = func(req *cmds.Request) func(io.Writer) cmds.Encoder {
+		return func(w io.Writer) cmds.Encoder { return newCSVEncoder(w) }
+	}
+}
+
+// applyOutputOption sets the encoding of req to the one selected by the
+// output option, if any. It has precedence over the encoding option.
+func applyOutputOption(req *cmds.Request) error {
+	output, ok := req.Options[OptionOutput].(string)
+	if !ok || output == "" {
+		return nil
+	}
+	enc, ok := outputFormats[output]
+	if !ok {
+		return fmt.Errorf("unknown output format %q, must be one of text, json or csv", output)
+	}
+	req.Options[cmds.EncLong] = string(enc)
+	return nil
+}
+
+// csvEncoder writes values as csv records. The columns are the fields of the
+// JSON encoding of the first value, in the order they are encoded, and are
+// written once as a header. A value encoding to an array is written as one
+// record per element, and scalars as a single "value" column. Fields which
+// are themselves objects or arrays are written as JSON.
+type csvEncoder struct {
+	out    io.Writer
+	w      *csv.Writer
+	header []string
+}
+
+func newCSVEncoder(w io.Writer) *csvEncoder {
+	return &csvEncoder{out: w, w: csv.NewWriter(w)}
+}
+
+// Encode writes the records of v.
+func (e *csvEncoder) Encode(v interface{}) error {
+	// raw output, e.g. the content of a file, is written as is
+	if r, ok := v.(io.Reader); ok {
+		_, err := io.Copy(e.out, r)
+		return err
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	raw = bytes.TrimSpace(raw)
+
+	if len(raw) > 0 && raw[0] == '[' {
+		var elems []json.RawMessage
+		if err := json.Unmarshal(raw, &elems); err != nil {
+			return err
+		}
+		for _, elem := range elems {
+			if err := e.writeRecord(elem); err != nil {
+				return err
+			}
+		}
+	} else if err := e.writeRecord(raw); err != nil {
+		return err
+	}
+
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvEncoder) writeRecord(raw json.RawMessage) error {
+	keys, fields, err := decodeObject(raw)
+	if err != nil {
+		return err
+	}
+	if keys == nil {
+		keys = []string{"value"}
+		fields = map[string]json.RawMessage{"value": raw}
+	}
+
+	if e.header == nil {
+		e.header = keys
+		if err := e.w.Write(e.header); err != nil {
+			return err
+		}
+	}
+
+	record := make([]string, len(e.header))
+	for i, key := range e.header {
+		record[i] = csvCell(fields[key])
+	}
+	return e.w.Write(record)
+}
+
+// decodeObject returns the keys, in order, and the fields of the JSON object
+// raw, or nil keys if raw isn't an object.
+func decodeObject(raw json.RawMessage) ([]string, map[string]json.RawMessage, error) {
+	if len(raw) == 0 || raw[0] != '{' {
+		return nil, nil, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if _, err := dec.Token(); err != nil {
+		return nil, nil, err
+	}
+	keys := []string{}
+	fields := make(map[string]json.RawMessage)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return nil, nil, fmt.Errorf("unexpected token %v in object", tok)
+		}
+		var field json.RawMessage
+		if err := dec.Decode(&field); err != nil {
+			return nil, nil, err
+		}
+		if _, dup := fields[key]; !dup {
+			keys = append(keys, key)
+		}
+		fields[key] = field
+	}
+	return keys, fields, nil
+}
+
+// csvCell returns the cell of the JSON value raw: strings unquoted, null
+// and missing fields empty, and anything else as its JSON.
+func csvCell(raw json.RawMessage) string {
+	s := strings.TrimSpace(string(raw))
+	switch {
+	case s == "" || s == "null":
+		return ""
+	case s[0] == '"':
+		var str string
+		if err := json.Unmarshal(raw, &str); err == nil {
+			return str
+		}
+	}
+	return s
+}
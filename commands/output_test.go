@@ -0,0 +1,65 @@
+package commands
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSVEncoder(t *testing.T) {
+	t.Parallel()
+
+	type row struct {
+		Name  string            `json:"name"`
+		Count int               `json:"count"`
+		Tags  []string          `json:"tags"`
+		Extra map[string]string `json:"extra,omitempty"`
+	}
+
+	t.Run("objects share the header of the first one", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		var buf bytes.Buffer
+		enc := newCSVEncoder(&buf)
+		require.NoError(enc.Encode(row{Name: "a", Count: 1, Tags: []string{"x", "y"}}))
+		require.NoError(enc.Encode(&row{Name: "b, c", Count: 2, Extra: map[string]string{"k": "v"}}))
+
+		assert.Equal("name,count,tags\na,1,\"[\"\"x\"\",\"\"y\"\"]\"\n\"b, c\",2,\n", buf.String())
+	})
+
+	t.Run("arrays are one record per element", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		var buf bytes.Buffer
+		require.NoError(newCSVEncoder(&buf).Encode([]row{{Name: "a"}, {Name: "b"}}))
+
+		assert.Equal("name,count,tags\na,0,\nb,0,\n", buf.String())
+	})
+
+	t.Run("scalars are a value column", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		var buf bytes.Buffer
+		enc := newCSVEncoder(&buf)
+		require.NoError(enc.Encode("hello"))
+		require.NoError(enc.Encode(42))
+
+		assert.Equal("value\nhello\n42\n", buf.String())
+	})
+
+	t.Run("readers are copied", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		var buf bytes.Buffer
+		require.NoError(newCSVEncoder(&buf).Encode(strings.NewReader("raw bytes")))
+
+		assert.Equal("raw bytes", buf.String())
+	})
+}
@@ -0,0 +1,111 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"gx/ipfs/Qma6uuSyjkecGhMFFLfzyJDPyoDtNJSHJNweDccZhaWkgU/go-ipfs-cmds"
+	"gx/ipfs/Qmde5VP1qUkyQXKCfmEUA7bP64V2HAptbJ7phuPp7jXWwg/go-ipfs-cmdkit"
+
+	"github.com/filecoin-project/go-filecoin/proofs"
+	"github.com/filecoin-project/go-filecoin/proofs/params"
+)
+
+var proofsCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Manage the parameters and resources of the proofs backend",
+	},
+	Subcommands: map[string]*cmds.Command{
+		"fetch-params": proofsFetchParamsCmd,
+		"resources":    proofsResourcesCmd,
+	},
+}
+
+var proofsFetchParamsCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Fetch the parameters needed to seal sectors and generate PoSts",
+		ShortDescription: `
+Downloads the Groth parameters and verifying keys for sectors of the given size
+listed in the manifest set in the config value proofs.parameterManifest from the
+gateway in proofs.parameterGateway, into the parameter cache in
+proofs.parameterCache, which the proofs library reads them from. Files already
+in the cache are verified and kept, interrupted downloads are resumed and every
+file downloaded is verified against the digest in the manifest.
+`,
+	},
+	Options: []cmdkit.Option{
+		cmdkit.Uint64Option("size", "Size of the sectors to fetch the parameters for, in bytes"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		size, ok := req.Options["size"].(uint64)
+		if !ok {
+			return fmt.Errorf("sector size is required")
+		}
+
+		var emitErr error
+		err := GetPorcelainAPI(env).ProofsFetchParams(req.Context, size, func(p params.Progress) {
+			if emitErr == nil {
+				emitErr = re.Emit(&p)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		return emitErr
+	},
+	Type: params.Progress{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, p *params.Progress) error {
+			if p.State == params.FetchDownloading && p.Total > 0 {
+				_, err := fmt.Fprintf(w, "%s: %s %d/%d bytes (%d%%)\n", p.Name, p.State, p.Bytes, p.Total, 100*p.Bytes/p.Total)
+				return err
+			}
+			if p.State == params.FetchDownloading {
+				_, err := fmt.Fprintf(w, "%s: %s %d bytes\n", p.Name, p.State, p.Bytes)
+				return err
+			}
+			_, err := fmt.Fprintf(w, "%s: %s\n", p.Name, p.State)
+			return err
+		}),
+	},
+}
+
+var proofsResourcesCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show the hardware the proofs backend detected and uses",
+		ShortDescription: `
+Shows the CPU cores, GPUs and memory of the machine, and how much of them the
+proofs backend uses as set in the config values proofs.maxCpus, proofs.useGpu
+and proofs.memoryPerProof. The number of seals running at once is bounded by
+the seals fitting into memory. Changes to the config take effect when the node
+restarts.
+`,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		return re.Emit(GetPorcelainAPI(env).ProofsResources())
+	},
+	Type: proofs.Resources{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, r *proofs.Resources) error {
+			fmt.Fprintf(w, "cpus:   %d of %d\n", r.CPULimit, r.CPUs) // nolint: errcheck
+			gpus := "none detected"
+			if len(r.GPUs) > 0 {
+				gpus = strings.Join(r.GPUs, ", ")
+				if !r.UseGPU {
+					gpus += " (disabled)"
+				}
+			}
+			fmt.Fprintf(w, "gpus:   %s\n", gpus) // nolint: errcheck
+			memory := "unknown"
+			if r.MemoryBytes > 0 {
+				memory = fmt.Sprintf("%d bytes", r.MemoryBytes)
+			}
+			if r.MaxParallelProofs > 0 {
+				memory += fmt.Sprintf(", %d proofs of %d bytes at once", r.MaxParallelProofs, r.MemoryPerProof)
+			}
+			_, err := fmt.Fprintf(w, "memory: %s\n", memory)
+			return err
+		}),
+	},
+}
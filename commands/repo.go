@@ -0,0 +1,243 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+
+	cid "gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	cmds "gx/ipfs/Qma6uuSyjkecGhMFFLfzyJDPyoDtNJSHJNweDccZhaWkgU/go-ipfs-cmds"
+	cmdkit "gx/ipfs/Qmde5VP1qUkyQXKCfmEUA7bP64V2HAptbJ7phuPp7jXWwg/go-ipfs-cmdkit"
+
+	"github.com/filecoin-project/go-filecoin/repo"
+)
+
+var repoCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Manage the filecoin repo",
+	},
+	Subcommands: map[string]*cmds.Command{
+		"backup":  repoBackupCmd,
+		"gc":      repoGCCmd,
+		"migrate": repoMigrateCmd,
+		"pin":     repoPinCmd,
+		"restore": repoRestoreCmd,
+	},
+}
+
+var repoMigrateCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Upgrade the repo to the version this binary requires",
+		ShortDescription: `
+Upgrades the datastores, config file and keystore of a repo created by an
+older release, which the daemon refuses to open until it is migrated. The
+daemon must not be running.
+
+The repo is backed up next to it before it is changed, and restored from the
+backup if a migration fails. Once the migrated repo works, the backup can be
+removed.
+`,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		res, err := repo.Migrate(getRepoDir(req))
+		if err != nil {
+			return err
+		}
+		return re.Emit(res)
+	},
+	Type: &repo.MigrateResult{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, res *repo.MigrateResult) error {
+			if res.From == res.To {
+				_, err := fmt.Fprintf(w, "Repo is up to date at version %d\n", res.To)
+				return err
+			}
+
+			if _, err := fmt.Fprintf(w, "Migrated the repo from version %d to %d:\n", res.From, res.To); err != nil {
+				return err
+			}
+			for _, applied := range res.Applied {
+				if _, err := fmt.Fprintf(w, "  %s\n", applied); err != nil {
+					return err
+				}
+			}
+			_, err := fmt.Fprintf(w, "The previous repo was backed up to %s\n", res.Backup)
+			return err
+		}),
+	},
+}
+
+var repoBackupCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Back the repo of the running daemon up",
+		ShortDescription: `
+Writes a backup of the repo of the running daemon to the given file, holding
+its config, keystore, wallet, deals, pieces and chain, blocks included. The
+daemon keeps running meanwhile, the backup is a snapshot of the repo at the
+time it started. The file is written by the daemon, so its path must be
+absolute, and it is only replaced once the backup is complete. Restore it with
+repo restore.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("file", true, false, "Absolute path of the backup"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		p := req.Arguments[0]
+		if !filepath.IsAbs(p) {
+			return fmt.Errorf("the backup path %s is not absolute", p)
+		}
+		if err := GetAPI(env).Daemon().Backup(req.Context, p); err != nil {
+			return err
+		}
+		return re.Emit(p)
+	},
+	Type: "",
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, p string) error {
+			_, err := fmt.Fprintf(w, "backed the repo up to %s\n", p)
+			return err
+		}),
+	},
+}
+
+var repoRestoreCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Create a repo from a backup written by repo backup",
+		ShortDescription: `
+Creates the repo at --repodir from a backup written by repo backup. The repo
+must not exist yet. Sealed sectors and staged pieces are not part of the
+backup, their storage paths have to be restored separately.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.FileArg("file", true, false, "Path to the backup").EnableStdin(),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		fi, err := req.Files.NextFile()
+		if err != nil {
+			return err
+		}
+
+		repoDir := getRepoDir(req)
+		if err := repo.Restore(fi, repoDir); err != nil {
+			return err
+		}
+		return re.Emit(repoDir)
+	},
+	Type: "",
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, repoDir string) error {
+			_, err := fmt.Fprintf(w, "restored the repo at %s\n", repoDir)
+			return err
+		}),
+	},
+}
+
+var repoGCCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Delete the blocks the running daemon no longer needs",
+		ShortDescription: `
+Deletes the blocks of the running daemon which no live root links to. The live
+roots are the headers of the chain, the state trees of its recent tipsets, the
+data of the storage deals which have not ended, or for a miner, which are not
+sealed yet, and the pins, see repo pin. The daemon keeps running meanwhile.
+
+With --dry-run, nothing is deleted, the space that would be reclaimed is
+reported instead.
+`,
+	},
+	Options: []cmdkit.Option{
+		cmdkit.BoolOption("dry-run", "Report the blocks that would be deleted without deleting them"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		dryRun, _ := req.Options["dry-run"].(bool)
+		res, err := GetAPI(env).Daemon().CollectGarbage(req.Context, dryRun)
+		if err != nil {
+			return err
+		}
+		return re.Emit(res)
+	},
+	Type: &repo.GCResult{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, res *repo.GCResult) error {
+			verb := "Deleted"
+			if res.DryRun {
+				verb = "Would delete"
+			}
+			_, err := fmt.Fprintf(w, "%s %d blocks, %d bytes, kept %d live blocks\n", verb, res.Blocks, res.Size, res.Live)
+			return err
+		}),
+	},
+}
+
+var repoPinCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Keep data from being garbage collected",
+		ShortDescription: `
+Pinned data is kept by repo gc with all the blocks it links to. Data imported
+with client import, or retrieved by client cat, is pinned.
+`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"add": repoPinAddCmd,
+		"ls":  repoPinLsCmd,
+		"rm":  repoPinRmCmd,
+	},
+}
+
+var repoPinAddCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Pin the data with the given cid",
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("cid", true, false, "CID of the data to pin"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		c, err := cid.Decode(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+		return GetAPI(env).Daemon().Pin(req.Context, c)
+	},
+}
+
+var repoPinRmCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Unpin the data with the given cid",
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("cid", true, false, "CID of the data to unpin"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		c, err := cid.Decode(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+		return GetAPI(env).Daemon().Unpin(req.Context, c)
+	},
+}
+
+var repoPinLsCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "List the cids of the pinned data",
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		pins, err := GetAPI(env).Daemon().Pins(req.Context)
+		if err != nil {
+			return err
+		}
+		return re.Emit(pins)
+	},
+	Type: []cid.Cid{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, pins *[]cid.Cid) error {
+			for _, c := range *pins {
+				if err := PrintString(w, c); err != nil {
+					return err
+				}
+			}
+			return nil
+		}),
+	},
+}
@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
+	"gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore/query"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/repo"
+	th "github.com/filecoin-project/go-filecoin/testhelpers"
+)
+
+func TestRepoBackup(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	d := th.NewDaemon(t).Start()
+	defer d.ShutdownSuccess()
+
+	d.CreateWalletAddr()
+
+	dir, err := ioutil.TempDir("", "restored")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+	backup := filepath.Join(dir, "backup")
+
+	d.RunFail("is not absolute", "repo", "backup", "backup")
+	out := d.RunSuccess("repo", "backup", backup).ReadStdout()
+	assert.Contains(out, backup)
+
+	f, err := os.Open(backup)
+	require.NoError(err)
+	defer f.Close() // nolint: errcheck
+	restored := filepath.Join(dir, "repo")
+	require.NoError(repo.Restore(f, restored))
+
+	r, err := repo.OpenFSRepo(restored)
+	require.NoError(err)
+	defer r.Close() // nolint: errcheck
+
+	assert.Equal(d.Config().Mining.MinerAddress, r.Config().Mining.MinerAddress)
+
+	names, err := r.Keystore().List()
+	require.NoError(err)
+	assert.Contains(names, "self")
+
+	head, err := r.ChainDatastore().Has(datastore.NewKey("/chain/heaviestTipSet"))
+	require.NoError(err)
+	assert.True(head)
+
+	wallet, err := r.WalletDatastore().Query(query.Query{KeysOnly: true})
+	require.NoError(err)
+	entries, err := wallet.Rest()
+	require.NoError(err)
+	assert.NotEmpty(entries)
+}
+
+func TestRepoGC(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	d := th.NewDaemon(t).Start()
+	defer d.ShutdownSuccess()
+
+	gc := func(args ...string) *repo.GCResult {
+		out := d.RunSuccess(append([]string{"repo", "gc", "--enc=json"}, args...)...).ReadStdout()
+		var res repo.GCResult
+		require.NoError(json.Unmarshal([]byte(out), &res))
+		return &res
+	}
+	gc()
+
+	data := strings.Repeat("HODL", 100000)
+	dataCid := d.RunWithStdin(strings.NewReader(data), "client", "import").ReadStdoutTrimNewlines()
+	assert.Contains(d.RunSuccess("repo", "pin", "ls").ReadStdout(), dataCid)
+
+	// the imported data is pinned
+	res := gc("--dry-run")
+	assert.True(res.DryRun)
+	assert.Equal(uint64(0), res.Blocks)
+
+	d.RunSuccess("repo", "pin", "rm", dataCid)
+	d.RunFail("is not pinned", "repo", "pin", "rm", dataCid)
+	assert.NotContains(d.RunSuccess("repo", "pin", "ls").ReadStdout(), dataCid)
+
+	res = gc("--dry-run")
+	assert.True(res.Blocks > 0)
+	assert.True(res.Size >= uint64(len(data)))
+
+	deleted := gc()
+	assert.False(deleted.DryRun)
+	assert.Equal(res.Blocks, deleted.Blocks)
+	assert.Equal(res.Size, deleted.Size)
+
+	assert.Equal(uint64(0), gc("--dry-run").Blocks)
+
+	// the chain is still there
+	d.RunSuccess("chain", "ls")
+}
@@ -1,11 +1,15 @@
 package commands
 
 import (
+	"fmt"
+	"io"
+
 	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
 	"gx/ipfs/Qma6uuSyjkecGhMFFLfzyJDPyoDtNJSHJNweDccZhaWkgU/go-ipfs-cmds"
 	"gx/ipfs/Qmde5VP1qUkyQXKCfmEUA7bP64V2HAptbJ7phuPp7jXWwg/go-ipfs-cmdkit"
 
 	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/protocol/retrieval"
 )
 
 var retrievalClientCmd = &cmds.Command{
@@ -13,18 +17,81 @@ var retrievalClientCmd = &cmds.Command{
 		Tagline: "Manage retrieval client operations",
 	},
 	Subcommands: map[string]*cmds.Command{
+		"query-piece":    clientQueryPieceCmd,
+		"retrieve":       clientRetrieveCmd,
 		"retrieve-piece": clientRetrievePieceCmd,
 	},
 }
 
+var clientQueryPieceCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show the size and retrieval price of a piece stored by a miner",
+		ShortDescription: `
+Asks the miner for the size of the piece, the sector it is sealed in, its price
+per byte, and the fee and expected time for unsealing it. Miners keep unsealed
+copies of recently retrieved pieces, which cost no fee and need no unsealing.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("miner", true, false, "Retrieval miner actor address"),
+		cmdkit.StringArg("cid", true, false, "Content identifier of piece to query"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		minerAddr, err := address.NewFromString(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		pieceCID, err := cid.Decode(req.Arguments[1])
+		if err != nil {
+			return err
+		}
+
+		resp, err := GetAPI(env).RetrievalClient().QueryPiece(req.Context, pieceCID, minerAddr)
+		if err != nil {
+			return err
+		}
+
+		return re.Emit(resp)
+	},
+	Type: retrieval.QueryResponse{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, resp *retrieval.QueryResponse) error {
+			fmt.Fprintf(w, "Size:         %d\n", resp.Size)         // nolint: errcheck
+			fmt.Fprintf(w, "Sector:       %d\n", resp.SectorID)     // nolint: errcheck
+			fmt.Fprintf(w, "PricePerByte: %s\n", resp.PricePerByte) // nolint: errcheck
+			fmt.Fprintf(w, "UnsealFee:    %s\n", resp.UnsealFee)    // nolint: errcheck
+			if resp.Unseal.Cached {
+				fmt.Fprintln(w, "Unseal:       cached") // nolint: errcheck
+			} else {
+				fmt.Fprintf(w, "Unseal:       ~%ds, %d ahead in queue\n", resp.Unseal.Seconds, resp.Unseal.QueuePosition) // nolint: errcheck
+			}
+			return nil
+		}),
+	},
+}
+
 var clientRetrievePieceCmd = &cmds.Command{
 	Helptext: cmdkit.HelpText{
 		Tagline: "Read out piece data stored by a miner on the network",
+		ShortDescription: `
+Unless the miner serves the piece for free, the client opens a payment channel
+to the miner covering the price of the whole piece, and pays for the data as it
+arrives. See retrieval-client query-piece for the price.
+
+If a retrieval is interrupted, retrieving the piece again, from the same or
+another miner, resumes it from the last bytes paid for. With --offset or
+--length, only that range of the piece is retrieved and paid for.
+`,
 	},
 	Arguments: []cmdkit.Argument{
 		cmdkit.StringArg("miner", true, false, "Retrieval miner actor address"),
 		cmdkit.StringArg("cid", true, false, "Content identifier of piece to read"),
 	},
+	Options: []cmdkit.Option{
+		cmdkit.Uint64Option("offset", "Offset in the piece of the first byte to read"),
+		cmdkit.Uint64Option("length", "Number of bytes to read, 0 for the rest of the piece"),
+	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
 		minerAddr, err := address.NewFromString(req.Arguments[0])
 		if err != nil {
@@ -36,7 +103,58 @@ var clientRetrievePieceCmd = &cmds.Command{
 			return err
 		}
 
-		readCloser, err := GetAPI(env).RetrievalClient().RetrievePiece(req.Context, pieceCID, minerAddr)
+		offset, hasOffset := req.Options["offset"].(uint64)
+		length, hasLength := req.Options["length"].(uint64)
+
+		var readCloser io.ReadCloser
+		if hasOffset || hasLength {
+			readCloser, err = GetAPI(env).RetrievalClient().RetrievePieceRange(req.Context, pieceCID, minerAddr, offset, length)
+		} else {
+			readCloser, err = GetAPI(env).RetrievalClient().RetrievePiece(req.Context, pieceCID, minerAddr)
+		}
+		if err != nil {
+			return err
+		}
+
+		return re.Emit(readCloser)
+	},
+}
+
+var clientRetrieveCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Read out piece data from the best of the miners storing it",
+		ShortDescription: `
+Queries the given miners, or if none are given the miners storing the piece in
+a deal with this node, and retrieves the piece from the cheapest of them. If a
+miner fails, the retrieval resumes with the next cheapest one. With --stripe,
+ranges of the piece are retrieved from several miners in parallel, each paid
+for separately.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("cid", true, false, "Content identifier of piece to read"),
+		cmdkit.StringArg("miners", false, true, "Retrieval miner actor addresses to choose from"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.BoolOption("stripe", "Retrieve ranges of the piece from several miners in parallel"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		pieceCID, err := cid.Decode(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		var minerAddrs []address.Address
+		for _, arg := range req.Arguments[1:] {
+			minerAddr, err := address.NewFromString(arg)
+			if err != nil {
+				return err
+			}
+			minerAddrs = append(minerAddrs, minerAddr)
+		}
+
+		stripe, _ := req.Options["stripe"].(bool)
+		readCloser, err := GetAPI(env).RetrievalClient().RetrievePieceFromAny(req.Context, pieceCID, minerAddrs, stripe)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,147 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"gx/ipfs/Qma6uuSyjkecGhMFFLfzyJDPyoDtNJSHJNweDccZhaWkgU/go-ipfs-cmds"
+	"gx/ipfs/Qmde5VP1qUkyQXKCfmEUA7bP64V2HAptbJ7phuPp7jXWwg/go-ipfs-cmdkit"
+
+	"github.com/filecoin-project/go-filecoin/proofs/sectorbuilder"
+	"github.com/filecoin-project/go-filecoin/protocol/sealing"
+)
+
+var sealingCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Inspect the sealing of sectors",
+		ShortDescription: `
+The miner writes the pieces of deals into staged sectors, seals the sectors and
+commits them to the chain in jobs of the addpiece, seal and commit stages. The
+number of jobs of each stage running at once is limited by the config values
+mining.sealing.addPieceConcurrency, sealConcurrency and commitConcurrency.
+
+A miner can also have remote workers seal pieces for it. The peer ids of the
+workers it accepts are set in the config value mining.sealing.workers. A worker
+is a node started with 'go-filecoin sealing work', which registers with the
+miner and seals the pieces the miner sends it into sectors of its own. Pieces
+are sealed locally while no worker has spare capacity.
+
+The sector builder keeps staged sectors, sealed sectors and its cache in the
+storage paths set in the config value mining.storagePaths, or in the repo if
+there are none. Each kind of data goes to the path keeping it with the most free
+space, scaled by the weight of the path, when mining starts.
+`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"jobs":    sealingJobsCmd,
+		"paths":   sealingPathsCmd,
+		"workers": sealingWorkersCmd,
+		"work":    sealingWorkCmd,
+	},
+}
+
+var sealingJobsCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "List the queued and running sealing jobs, oldest first",
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		jobs, err := GetAPI(env).Mining().SealingJobs(req.Context)
+		if err != nil {
+			return err
+		}
+		for _, j := range jobs {
+			if err := re.Emit(j); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Type: sectorbuilder.Job{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, j *sectorbuilder.Job) error {
+			since := j.QueuedAt
+			if j.State == sectorbuilder.JobRunning {
+				since = j.StartedAt
+			}
+			_, err := fmt.Fprintf(w, "%d\t%s\t%s for %s\t%s\n", j.ID, j.Stage, j.State, time.Since(since).Round(time.Second), j.Description)
+			return err
+		}),
+	},
+}
+
+var sealingWorkersCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "List the remote workers registered to seal sectors for the miner",
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		workers, err := GetAPI(env).Mining().SealingWorkers(req.Context)
+		if err != nil {
+			return err
+		}
+		for _, w := range workers {
+			if err := re.Emit(w); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Type: sealing.WorkerInfo{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, wi *sealing.WorkerInfo) error {
+			_, err := fmt.Fprintf(w, "%s\t%d/%d sealing\tseen %s ago\t%d failed health checks\n", wi.PeerID.Pretty(), wi.Active, wi.Capacity, time.Since(wi.LastSeen).Round(time.Second), wi.Failures)
+			return err
+		}),
+	},
+}
+
+var sealingWorkCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Seal sectors for a remote miner",
+		ShortDescription: `
+Registers the node as a sealing worker with the miner at the given multiaddr,
+whose config value mining.sealing.workers must hold the peer id of the node.
+The node then seals the pieces the miner sends it, at most --capacity at once,
+until it stops.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("master", true, false, "multiaddr of the miner, including its peer id"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.Uint64Option("capacity", "number of sectors to seal at once").WithDefault(uint64(1)),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		capacity, _ := req.Options["capacity"].(uint64)
+		return GetAPI(env).Mining().StartSealingWorker(req.Context, req.Arguments[0], capacity)
+	},
+}
+
+var sealingPathsCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show the utilization of the storage paths of the sector builder",
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		usages, err := GetAPI(env).Mining().StoragePaths(req.Context)
+		if err != nil {
+			return err
+		}
+		for _, u := range usages {
+			if err := re.Emit(u); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Type: sectorbuilder.PathUsage{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, u *sectorbuilder.PathUsage) error {
+			limit := "unlimited"
+			if u.MaxBytes > 0 {
+				limit = fmt.Sprintf("max %d", u.MaxBytes)
+			}
+			_, err := fmt.Fprintf(w, "%s\t%v\tused %d\tfree %d\t%s\tweight %d\tin use %v\n", u.Path, u.Kinds, u.UsedBytes, u.FreeBytes, limit, u.Weight, u.InUse)
+			return err
+		}),
+	},
+}
@@ -0,0 +1,216 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"gx/ipfs/Qma6uuSyjkecGhMFFLfzyJDPyoDtNJSHJNweDccZhaWkgU/go-ipfs-cmds"
+	"gx/ipfs/Qmde5VP1qUkyQXKCfmEUA7bP64V2HAptbJ7phuPp7jXWwg/go-ipfs-cmdkit"
+
+	"github.com/filecoin-project/go-filecoin/protocol/storage"
+)
+
+var sectorsCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Check, scrub, export and import the sealed sectors of the miner",
+		ShortDescription: `
+Checks the sealed sectors of the miner against their commitments on chain, and
+moves sectors between nodes of the miner, e.g. to recover a sector whose sealed
+file was lost or corrupted from a copy exported earlier.
+`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"check":        sectorsCheckCmd,
+		"flagged":      sectorsFlaggedCmd,
+		"scrub":        sectorsScrubCmd,
+		"scrub-status": sectorsScrubStatusCmd,
+		"export":       sectorsExportCmd,
+		"import":       sectorsImportCmd,
+	},
+}
+
+var sectorCheckEncoders = cmds.EncoderMap{
+	cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, c *storage.SectorCheck) error {
+		_, err := fmt.Fprintf(w, "%d\t%s\t%s\n", c.SectorID, c.Status, c.Message)
+		return err
+	}),
+}
+
+var sectorsCheckCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Verify the sealed sectors of the miner",
+		ShortDescription: `
+Checks every sector the miner committed on chain: the commitments the miner
+sealed the sector with must match the ones on chain, and the pieces of the
+miner's deals in the sector must unseal to their data. Sectors found missing or
+corrupt are flagged for recovery, see sectors flagged and sectors import.
+`,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		checks, err := GetAPI(env).Miner().CheckSectors(req.Context)
+		if err != nil {
+			return err
+		}
+		for _, c := range checks {
+			if err := re.Emit(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Type:     storage.SectorCheck{},
+	Encoders: sectorCheckEncoders,
+}
+
+var sectorsFlaggedCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "List the sectors found missing or corrupt, which await recovery",
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		checks, err := GetAPI(env).Miner().FlaggedSectors(req.Context)
+		if err != nil {
+			return err
+		}
+		for _, c := range checks {
+			if err := re.Emit(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Type:     storage.SectorCheck{},
+	Encoders: sectorCheckEncoders,
+}
+
+var sectorsScrubCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Scrub the sealed sectors of the miner for corruption",
+		ShortDescription: `
+Reads random ranges of the sealed sector files, checking them against the
+digests recorded when each range was first read, and verifies a random sector
+against its commitments on chain like sectors check. Corrupt ranges and sectors
+raise alerts, shown by sectors scrub-status, and corrupt sectors are flagged.
+The miner scrubs on its own every mining.scrub.interval.
+`,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		result, err := GetAPI(env).Miner().ScrubSectors(req.Context)
+		if err != nil {
+			return err
+		}
+		return re.Emit(result)
+	},
+	Type: storage.ScrubResult{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, r *storage.ScrubResult) error {
+			writeScrubResult(w, r)
+			return nil
+		}),
+	},
+}
+
+var sectorsScrubStatusCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show the outcome of the scrubs of the sealed sectors",
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		status, err := GetAPI(env).Miner().ScrubStatus(req.Context)
+		if err != nil {
+			return err
+		}
+		return re.Emit(status)
+	},
+	Type: storage.ScrubStatus{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, s *storage.ScrubStatus) error {
+			fmt.Fprintf(w, "scrubs:          %d\n", s.Runs)                                          // nolint: errcheck
+			fmt.Fprintf(w, "ranges checked:  %d, corrupt: %d\n", s.RangesChecked, s.CorruptRanges)   // nolint: errcheck
+			fmt.Fprintf(w, "sectors checked: %d, corrupt: %d\n", s.SectorsChecked, s.CorruptSectors) // nolint: errcheck
+			if s.Running {
+				fmt.Fprintln(w, "a scrub is running") // nolint: errcheck
+			}
+			if s.Last != nil {
+				fmt.Fprintln(w, "last scrub:") // nolint: errcheck
+				writeScrubResult(w, s.Last)
+			}
+			for _, a := range s.Alerts {
+				fmt.Fprintf(w, "alert at %s: %s\n", a.Time.Format(time.RFC3339), a.Message) // nolint: errcheck
+			}
+			return nil
+		}),
+	},
+}
+
+func writeScrubResult(w io.Writer, r *storage.ScrubResult) {
+	fmt.Fprintf(w, "started: %s\n", r.Started.Format(time.RFC3339))            // nolint: errcheck
+	fmt.Fprintf(w, "ranges:  %d read, %d read first\n", r.Ranges, r.Baselined) // nolint: errcheck
+	for _, c := range r.CorruptRanges {
+		fmt.Fprintf(w, "corrupt: %s at %d-%d: %s\n", c.File, c.Offset, c.Offset+c.Length, c.Message) // nolint: errcheck
+	}
+	if r.Sector != nil {
+		fmt.Fprintf(w, "sector:  %d\t%s\t%s\n", r.Sector.SectorID, r.Sector.Status, r.Sector.Message) // nolint: errcheck
+	}
+}
+
+var sectorsExportCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Export a sealed sector as a CAR file",
+		ShortDescription: `
+Writes the sector with the given id to stdout as a CAR file, holding its
+commitments and the unsealed data of the pieces of the miner's deals in it.
+Another node of the miner imports it with sectors import.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("id", true, false, "Id of the sector to export"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		sectorID, err := strconv.ParseUint(req.Arguments[0], 10, 64)
+		if err != nil {
+			return err
+		}
+
+		r, w := io.Pipe()
+		go func() {
+			w.CloseWithError(GetAPI(env).Miner().ExportSector(req.Context, sectorID, w)) // nolint: errcheck
+		}()
+
+		return re.Emit(r)
+	},
+}
+
+var sectorsImportCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Import a sector exported with sectors export",
+		ShortDescription: `
+Imports a sector of the miner from a CAR file written by sectors export. The
+sector must match its commitments on chain. Sealed replicas are bound to the
+node that sealed them, so the pieces of the sector are sealed again into a new
+sector, which is committed like any other. The imported sector loses its flag.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.FileArg("file", true, false, "Path to the CAR file").EnableStdin(),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		fi, err := req.Files.NextFile()
+		if err != nil {
+			return err
+		}
+
+		sectorID, err := GetAPI(env).Miner().ImportSector(req.Context, fi)
+		if err != nil {
+			return err
+		}
+		return re.Emit(sectorID)
+	},
+	Type: uint64(0),
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, sectorID *uint64) error {
+			_, err := fmt.Fprintf(w, "imported sector %d\n", *sectorID)
+			return err
+		}),
+	},
+}
@@ -0,0 +1,140 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	"gx/ipfs/Qma6uuSyjkecGhMFFLfzyJDPyoDtNJSHJNweDccZhaWkgU/go-ipfs-cmds"
+	"gx/ipfs/Qmde5VP1qUkyQXKCfmEUA7bP64V2HAptbJ7phuPp7jXWwg/go-ipfs-cmdkit"
+
+	"github.com/filecoin-project/go-filecoin/state"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+var stateCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Inspect the state of the filecoin blockchain",
+	},
+	Subcommands: map[string]*cmds.Command{
+		"diff":    stateDiffCmd,
+		"migrate": stateMigrateCmd,
+	},
+}
+
+var stateDiffCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show the actors that differ between the states of two tipsets",
+		ShortDescription: `Lists the actors created, deleted and modified between the states of
+two tipsets. A tipset is given as the comma separated CIDs of its blocks, as
+printed by 'go-filecoin chain head'.`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("ts1", true, false, "CIDs of the blocks of the first tipset"),
+		cmdkit.StringArg("ts2", true, false, "CIDs of the blocks of the second tipset"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		tsA, err := parseTipSetKey(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+		tsB, err := parseTipSetKey(req.Arguments[1])
+		if err != nil {
+			return err
+		}
+
+		diffs, err := GetPorcelainAPI(env).ChainStateDiff(req.Context, tsA, tsB)
+		if err != nil {
+			return err
+		}
+
+		return re.Emit(diffs)
+	},
+	Type: []*state.ActorDiff{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, diffs *[]*state.ActorDiff) error {
+			return writeActorDiffs(w, *diffs)
+		}),
+	},
+}
+
+var stateMigrateCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Test the state migration of a protocol upgrade",
+		ShortDescription: `Migrations of the state are part of the state transition: they run when
+the chain reaches the height of their upgrade. With --dry-run the migration of
+the given upgrade is applied to the state of the chain head without
+persisting it, and the actors it changes are listed as by 'state diff'.`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("upgrade", true, false, "Name of the upgrade to migrate the state for"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.BoolOption("dry-run", "Migrate the state of the chain head without persisting it"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		if dryRun, _ := req.Options["dry-run"].(bool); !dryRun {
+			return errors.New("state migrations only run as part of the state transition, use --dry-run to test one")
+		}
+
+		diffs, err := GetPorcelainAPI(env).StateMigrateDryRun(req.Context, req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		return re.Emit(diffs)
+	},
+	Type: []*state.ActorDiff{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, diffs *[]*state.ActorDiff) error {
+			return writeActorDiffs(w, *diffs)
+		}),
+	},
+}
+
+// writeActorDiffs writes diffs as text, showing the fields of modified actors
+// that changed.
+func writeActorDiffs(w io.Writer, diffs []*state.ActorDiff) error {
+	for _, d := range diffs {
+		if _, err := fmt.Fprintf(w, "%s %s\n", d.Kind, d.Address); err != nil {
+			return err
+		}
+		if d.Kind != state.ActorModified {
+			continue
+		}
+		changes := []struct {
+			name        string
+			before, now string
+		}{
+			{"code", d.Before.Code.String(), d.After.Code.String()},
+			{"balance", d.Before.Balance.String(), d.After.Balance.String()},
+			{"nonce", strconv.FormatUint(uint64(d.Before.Nonce), 10), strconv.FormatUint(uint64(d.After.Nonce), 10)},
+			{"head", d.Before.Head.String(), d.After.Head.String()},
+		}
+		for _, c := range changes {
+			if c.before == c.now {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "  %s: %s -> %s\n", c.name, c.before, c.now); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parseTipSetKey parses the comma separated CIDs of the blocks of a tipset.
+func parseTipSetKey(arg string) (types.SortedCidSet, error) {
+	var key types.SortedCidSet
+	for _, s := range strings.Split(arg, ",") {
+		c, err := cid.Decode(strings.TrimSpace(s))
+		if err != nil {
+			return types.SortedCidSet{}, errors.Wrapf(err, "invalid block CID %q", s)
+		}
+		key.Add(c)
+	}
+	return key, nil
+}
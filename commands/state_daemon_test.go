@@ -0,0 +1,44 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/filecoin-project/go-filecoin/fixtures"
+	th "github.com/filecoin-project/go-filecoin/testhelpers"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateDiffDaemon(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	d := th.NewDaemon(t, th.WithMiner(fixtures.TestMiners[0])).Start()
+	defer d.ShutdownSuccess()
+
+	genesis := d.RunSuccess("chain", "ls").ReadStdoutTrimNewlines()
+	head := d.RunSuccess("mining", "once", "--enc", "text").ReadStdoutTrimNewlines()
+
+	same := d.RunSuccess("state", "diff", genesis, genesis).ReadStdoutTrimNewlines()
+	assert.Equal("", same)
+
+	// Mining pays the block reward to the miner owner.
+	diff := d.RunSuccess("state", "diff", genesis, head).ReadStdoutTrimNewlines()
+	require.NotEqual("", diff)
+	assert.True(strings.Contains(diff, "balance: "))
+
+	d.RunFail("invalid block CID", "state", "diff", "notacid", head)
+}
+
+func TestStateMigrateDaemon(t *testing.T) {
+	t.Parallel()
+
+	d := th.NewDaemon(t).Start()
+	defer d.ShutdownSuccess()
+
+	d.RunFail("use --dry-run", "state", "migrate", "someupgrade")
+	d.RunFail(`unknown upgrade "someupgrade"`, "state", "migrate", "--dry-run", "someupgrade")
+}
@@ -3,14 +3,19 @@ package commands
 import (
 	"fmt"
 	"io"
+	"sort"
 	"strings"
+	"time"
 
 	ma "gx/ipfs/QmNTCey11oxhb1AxDnQBRHtdhap6Ctud872NjAYPYYXPuc/go-multiaddr"
+	inet "gx/ipfs/QmNgLg1NTw37iWbYPKcyK85YJ9Whs1MkPtJwhfqbNYAyKg/go-libp2p-net"
 	peer "gx/ipfs/QmY5Grm8pJdiSSVsYxx4uNRgweY72EmYwuSDbRnbFok3iY/go-libp2p-peer"
 	cmds "gx/ipfs/Qma6uuSyjkecGhMFFLfzyJDPyoDtNJSHJNweDccZhaWkgU/go-ipfs-cmds"
 	cmdkit "gx/ipfs/Qmde5VP1qUkyQXKCfmEUA7bP64V2HAptbJ7phuPp7jXWwg/go-ipfs-cmdkit"
 
 	"github.com/filecoin-project/go-filecoin/api"
+	"github.com/filecoin-project/go-filecoin/filnet"
+	"github.com/filecoin-project/go-filecoin/plumbing/ntwk"
 )
 
 // swarmCmd contains swarm commands.
@@ -24,9 +29,21 @@ libp2p peers on the internet.
 `,
 	},
 	Subcommands: map[string]*cmds.Command{
-		"connect":  swarmConnectCmd,
-		"peers":    swarmPeersCmd,
-		"findpeer": findPeerDhtCmd,
+		"connect":   swarmConnectCmd,
+		"peers":     swarmPeersCmd,
+		"findpeer":  findPeerDhtCmd,
+		"ban":       swarmBanCmd,
+		"unban":     swarmUnbanCmd,
+		"bans":      swarmBansCmd,
+		"scores":    swarmScoresCmd,
+		"nat":       swarmNATCmd,
+		"sessions":  swarmSessionsCmd,
+		"bandwidth": swarmBandwidthCmd,
+		"ping":      swarmPingCmd,
+		"latency":   swarmLatencyCmd,
+		"find":      swarmFindCmd,
+		"peering":   swarmPeeringCmd,
+		"seen":      swarmSeenCmd,
 	},
 }
 
@@ -150,3 +167,481 @@ var findPeerDhtCmd = &cmds.Command{
 		}),
 	},
 }
+
+var swarmBanCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Ban a peer",
+		ShortDescription: `
+Closes the connections of the peer and refuses its connections, blocks and
+messages until the ban ends. Bans without a duration are permanent. Bans are
+kept across restarts. Peers are also banned on their own for an hour when
+their score, lowered by invalid blocks, invalid messages, timeouts and spam,
+drops to -100.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("peerID", true, false, "The ID of the peer to ban."),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.StringOption("duration", "Length of the ban, e.g. 24h, permanent if not set"),
+		cmdkit.StringOption("reason", "Why the peer is banned").WithDefault("banned by hand"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		peerID, err := peer.IDB58Decode(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		var d time.Duration
+		if s, ok := req.Options["duration"].(string); ok && s != "" {
+			d, err = time.ParseDuration(s)
+			if err != nil {
+				return err
+			}
+			if d <= 0 {
+				return fmt.Errorf("duration must be positive")
+			}
+		}
+		reason, _ := req.Options["reason"].(string)
+
+		return GetAPI(env).Swarm().Ban(req.Context, peerID, d, reason)
+	},
+}
+
+var swarmUnbanCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Lift the ban of a peer and reset its score",
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("peerID", true, false, "The ID of the peer to unban."),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		peerID, err := peer.IDB58Decode(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+		return GetAPI(env).Swarm().Unban(req.Context, peerID)
+	},
+}
+
+var swarmBansCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "List the banned peers",
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		bans, err := GetAPI(env).Swarm().Bans(req.Context)
+		if err != nil {
+			return err
+		}
+		for _, b := range bans {
+			if err := re.Emit(b); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Type: filnet.Ban{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, b *filnet.Ban) error {
+			until := "permanent"
+			if b.Until != nil {
+				until = "until " + b.Until.Format(time.RFC3339)
+			}
+			_, err := fmt.Fprintf(w, "%s\t%s\t%s\n", b.Peer.Pretty(), until, b.Reason)
+			return err
+		}),
+	},
+}
+
+var swarmScoresCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "List the scores of the peers which misbehaved",
+		ShortDescription: `
+Peers start with a score of 0, which invalid blocks, invalid messages, timeouts
+and spam lower. Scores recover 50 points an hour, up to 0. Peers whose score
+drops to -100 are banned for an hour. Lists the worst peers first, with the
+number of their offenses of each kind.
+`,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		scores, err := GetAPI(env).Swarm().Scores(req.Context)
+		if err != nil {
+			return err
+		}
+		for _, s := range scores {
+			if err := re.Emit(s); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Type: filnet.PeerScore{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, s *filnet.PeerScore) error {
+			var offenses []string
+			for _, o := range []filnet.Offense{filnet.InvalidBlock, filnet.InvalidMessage, filnet.Timeout, filnet.Spam} {
+				if n := s.Offenses[o]; n > 0 {
+					offenses = append(offenses, fmt.Sprintf("%s: %d", o, n))
+				}
+			}
+			_, err := fmt.Fprintf(w, "%s\t%.0f\t%s\n", s.Peer.Pretty(), s.Score, strings.Join(offenses, ", "))
+			return err
+		}),
+	},
+}
+
+var swarmNATCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show whether the node is reachable from the internet",
+		ShortDescription: `
+Peers dial the node back to tell whether it is behind a NAT. Nodes behind a
+NAT advertise the addresses of relays they reach peers through, unless
+swarm.announceAddresses is set. Shows the reachability of the node and the
+addresses it advertises.
+`,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		info, err := GetAPI(env).Swarm().NAT(req.Context)
+		if err != nil {
+			return err
+		}
+		return re.Emit(info)
+	},
+	Type: api.SwarmNATInfo{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, info *api.SwarmNATInfo) error {
+			fmt.Fprintf(w, "Reachability: %s\n", info.Reachability) // nolint: errcheck
+			if info.PublicAddr != "" {
+				fmt.Fprintf(w, "Public address: %s\n", info.PublicAddr) // nolint: errcheck
+			}
+			fmt.Fprintln(w, "Advertised addresses:") // nolint: errcheck
+			for _, a := range info.Addrs {
+				fmt.Fprintf(w, "  %s\n", a) // nolint: errcheck
+			}
+			return nil
+		}),
+	},
+}
+
+var swarmSessionsCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "List the bitswap sessions of chain syncs and piece transfers",
+		ShortDescription: `
+Each chain sync and piece transfer fetches its blocks in a bitswap session,
+asking the peers which had the previous blocks of the session for the next.
+Lists the open sessions, then the last ones which ended, with the number of
+blocks and bytes fetched and of blocks which couldn't be.
+`,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		stats, err := GetAPI(env).Swarm().Sessions(req.Context)
+		if err != nil {
+			return err
+		}
+		for _, s := range stats {
+			if err := re.Emit(s); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Type: filnet.SessionStats{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, s *filnet.SessionStats) error {
+			state := "open"
+			if s.Ended != nil {
+				state = "ended after " + s.Ended.Sub(s.Started).Round(time.Millisecond).String()
+			}
+			_, err := fmt.Fprintf(w, "%d\t%s\t%s\t%d blocks\t%d bytes\t%d failures\n", s.ID, s.Kind, state, s.Blocks, s.Bytes, s.Failures)
+			return err
+		}),
+	},
+}
+
+var swarmBandwidthCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show the bandwidth of the node by protocol and by peer",
+		ShortDescription: `
+Shows the bytes the node sent and received, in total and per second, by
+protocol and, with --peers, by peer. The rates the protocols send at are
+capped by swarm.bandwidthLimits, e.g. to keep serving blocks from taking the
+I/O sealing needs.
+`,
+	},
+	Options: []cmdkit.Option{
+		cmdkit.BoolOption("peers", "Also show the bandwidth of each peer"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		report, err := GetAPI(env).Swarm().Bandwidth(req.Context)
+		if err != nil {
+			return err
+		}
+		if peers, _ := req.Options["peers"].(bool); !peers {
+			report.Peers = nil
+		}
+		return re.Emit(report)
+	},
+	Type: filnet.BandwidthReport{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, report *filnet.BandwidthReport) error {
+			writeBandwidth(w, "Total", report.Total)
+
+			var protocols []string
+			for proto := range report.Protocols {
+				protocols = append(protocols, proto)
+			}
+			sort.Strings(protocols)
+			for _, proto := range protocols {
+				writeBandwidth(w, proto, report.Protocols[proto])
+			}
+
+			var peers []string
+			for p := range report.Peers {
+				peers = append(peers, p)
+			}
+			sort.Strings(peers)
+			for _, p := range peers {
+				writeBandwidth(w, p, report.Peers[p])
+			}
+
+			var prefixes []string
+			for prefix := range report.Limits {
+				prefixes = append(prefixes, prefix)
+			}
+			sort.Strings(prefixes)
+			for _, prefix := range prefixes {
+				fmt.Fprintf(w, "Limit %s*\t%d B/s\n", prefix, report.Limits[prefix]) // nolint: errcheck
+			}
+			return nil
+		}),
+	},
+}
+
+func writeBandwidth(w io.Writer, name string, s filnet.BandwidthStats) {
+	fmt.Fprintf(w, "%s\tin %d B (%.0f B/s)\tout %d B (%.0f B/s)\n", name, s.TotalIn, s.RateIn, s.TotalOut, s.RateOut) // nolint: errcheck
+}
+
+// SwarmPingResult is the round trip time of a ping, or why it failed.
+type SwarmPingResult struct {
+	Seq   uint          `json:"seq"`
+	RTT   time.Duration `json:"rtt"`
+	Error string        `json:"error,omitempty"`
+}
+
+var swarmPingCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Measure the round trip time to a peer",
+		ShortDescription: `
+Sends pings to the peer, one a second, connecting to it first if needed, and
+shows the round trip time of each.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("peerID", true, false, "The ID of the peer to ping."),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.UintOption("count", "n", "Number of pings to send.").WithDefault(uint(5)),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		peerID, err := peer.IDB58Decode(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+		count, _ := req.Options["count"].(uint)
+
+		for seq := uint(1); seq <= count; seq++ {
+			if seq > 1 {
+				select {
+				case <-time.After(time.Second):
+				case <-req.Context.Done():
+					return req.Context.Err()
+				}
+			}
+
+			result := SwarmPingResult{Seq: seq}
+			rtt, err := GetPorcelainAPI(env).NetworkPing(req.Context, peerID)
+			if err == ntwk.ErrPingSelf {
+				return err
+			} else if err != nil {
+				result.Error = err.Error()
+			} else {
+				result.RTT = rtt
+			}
+			if err := re.Emit(result); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Type: SwarmPingResult{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, r *SwarmPingResult) error {
+			if r.Error != "" {
+				_, err := fmt.Fprintf(w, "seq=%d failed: %s\n", r.Seq, r.Error)
+				return err
+			}
+			_, err := fmt.Fprintf(w, "seq=%d time=%.2f ms\n", r.Seq, r.RTT.Seconds()*1000)
+			return err
+		}),
+	},
+}
+
+var swarmLatencyCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "List the latencies to the peers the node is connected to",
+		ShortDescription: `
+Lists the connected peers, the closest first, with the latency to each
+averaged over the round trips of the streams to it. Peers whose latency
+isn't known yet come last.
+`,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		latencies := GetPorcelainAPI(env).NetworkLatencies()
+		sort.Slice(latencies, func(i, j int) bool {
+			li, lj := latencies[i].Latency, latencies[j].Latency
+			if li == 0 || lj == 0 {
+				return lj == 0 && li != 0
+			}
+			return li < lj
+		})
+		for _, l := range latencies {
+			if err := re.Emit(l); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Type: ntwk.PeerLatency{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, l *ntwk.PeerLatency) error {
+			latency := "n/a"
+			if l.Latency != 0 {
+				latency = l.Latency.String()
+			}
+			_, err := fmt.Fprintf(w, "%s\t%s\n", l.Peer.Pretty(), latency)
+			return err
+		}),
+	},
+}
+
+// SwarmFindResult is the addresses of a peer and whether the node is
+// connected to it.
+type SwarmFindResult struct {
+	Peer      peer.ID  `json:"peer"`
+	Addrs     []string `json:"addrs"`
+	Connected bool     `json:"connected"`
+}
+
+var swarmFindCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Find the addresses of a peer",
+		ShortDescription: `
+Finds the addresses of the peer in the peerstore of the node, or else in the
+DHT, and shows whether the node is connected to it.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("peerID", true, false, "The ID of the peer to find."),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		peerID, err := peer.IDB58Decode(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		info, err := GetPorcelainAPI(env).NetworkFindPeer(req.Context, peerID)
+		if err != nil {
+			return err
+		}
+		result := SwarmFindResult{
+			Peer:      peerID,
+			Connected: GetPorcelainAPI(env).NetworkConnectedness(peerID) == inet.Connected,
+		}
+		for _, a := range info.Addrs {
+			result.Addrs = append(result.Addrs, a.String())
+		}
+		return re.Emit(result)
+	},
+	Type: SwarmFindResult{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, r *SwarmFindResult) error {
+			fmt.Fprintf(w, "Peer: %s\n", r.Peer.Pretty())  // nolint: errcheck
+			fmt.Fprintf(w, "Connected: %t\n", r.Connected) // nolint: errcheck
+			fmt.Fprintln(w, "Addresses:")                  // nolint: errcheck
+			for _, a := range r.Addrs {
+				fmt.Fprintf(w, "  %s\n", a) // nolint: errcheck
+			}
+			return nil
+		}),
+	},
+}
+
+var swarmPeeringCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "List the peers the node always stays connected to",
+		ShortDescription: `
+Lists the peers of peering.peers, which the node reconnects to whenever they
+disconnect, waiting longer after each failed attempt, with whether it is
+connected to each and when it next tries to connect if it isn't.
+`,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		statuses, err := GetAPI(env).Swarm().Peering(req.Context)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			if err := re.Emit(s); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Type: filnet.PeeringStatus{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, s *filnet.PeeringStatus) error {
+			state := "connected"
+			if !s.Connected {
+				state = "disconnected"
+				if s.NextAttempt != nil {
+					state += ", next attempt at " + s.NextAttempt.Format(time.RFC3339)
+				}
+				if s.LastError != "" {
+					state += ": " + s.LastError
+				}
+			}
+			_, err := fmt.Fprintf(w, "%s\t%s\n", s.Peer.Pretty(), state)
+			return err
+		}),
+	},
+}
+
+var swarmSeenCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show how many gossiped blocks and messages were duplicates",
+		ShortDescription: `
+Shows, for each pubsub topic, how many of the blocks and messages received
+were copies of ones seen within pubsub.seenMessagesTTL (hits), which are
+dropped, and how many were new (misses).
+`,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		stats, err := GetAPI(env).Swarm().Seen(req.Context)
+		if err != nil {
+			return err
+		}
+		for _, s := range stats {
+			if err := re.Emit(s); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Type: filnet.SeenCacheStats{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, s *filnet.SeenCacheStats) error {
+			_, err := fmt.Fprintf(w, "%s\thits: %d\tmisses: %d\thit rate: %.1f%%\n", s.Topic, s.Hits, s.Misses, s.HitRate*100)
+			return err
+		}),
+	},
+}
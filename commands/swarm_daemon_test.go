@@ -47,3 +47,29 @@ func TestSwarmFindPeer(t *testing.T) {
 
 	assert.Contains(d2Addr, findpeerOutput)
 }
+
+func TestSwarmDiagnostics(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	d1 := th.NewDaemon(t).Start()
+	defer d1.ShutdownSuccess()
+
+	d2 := th.NewDaemon(t).Start()
+	defer d2.ShutdownSuccess()
+
+	d1.ConnectSuccess(d2)
+	d2Id := d2.GetID()
+
+	pingOutput := d1.RunSuccess("swarm", "ping", "--count=2", d2Id).ReadStdout()
+	assert.Contains(pingOutput, "seq=2 time=")
+
+	d1.RunFail("cannot ping self", "swarm", "ping", d1.GetID())
+
+	latencyOutput := d1.RunSuccess("swarm", "latency").ReadStdout()
+	assert.Contains(latencyOutput, d2Id)
+
+	findOutput := d1.RunSuccess("swarm", "find", d2Id).ReadStdout()
+	assert.Contains(findOutput, "Peer: "+d2Id)
+	assert.Contains(findOutput, "Connected: true")
+}
@@ -1,8 +1,10 @@
 package commands
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
 
 	cmds "gx/ipfs/Qma6uuSyjkecGhMFFLfzyJDPyoDtNJSHJNweDccZhaWkgU/go-ipfs-cmds"
 	cmdkit "gx/ipfs/Qmde5VP1qUkyQXKCfmEUA7bP64V2HAptbJ7phuPp7jXWwg/go-ipfs-cmdkit"
@@ -24,8 +26,67 @@ var versionCmd = &cmds.Command{
 	Type: api.VersionInfo{},
 	Encoders: cmds.EncoderMap{
 		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, vo *api.VersionInfo) error {
-			_, err := fmt.Fprintf(w, "commit: %s\n", vo.Commit)
+			_, err := fmt.Fprintf(w, "commit: %s\napi version: %s\n", vo.Commit, vo.APIVersion)
 			return err
 		}),
 	},
 }
+
+// VersionPath is the path of the api serving the version of the node as
+// JSON, for clients to check they are compatible with it.
+const VersionPath = "/version"
+
+// versionHandler serves the version of the node.
+func versionHandler(v api.Version) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		version, err := v.Full()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(version) // nolint: errcheck
+	})
+}
+
+// withAPIVersion sets the api version header of the responses of next, and
+// rejects the requests of clients whose api version is incompatible, so
+// that they fail clearly rather than on a response they can't decode.
+// Requests without api version, e.g. from browsers, are served.
+func withAPIVersion(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(api.APIVersionHeader, api.APIVersion)
+		if v := r.Header.Get(api.APIVersionHeader); v != "" && !api.APIVersionCompatible(v) {
+			msg := fmt.Sprintf("the client has api version %s, incompatible with the api version %s of the daemon", v, api.APIVersion)
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// versionTransport sends the api version of the cli with the requests to the
+// daemon, and fails those to a daemon whose api version is incompatible.
+type versionTransport struct {
+	next http.RoundTripper
+}
+
+func (t *versionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.WithContext(req.Context())
+	req.Header = cloneHeader(req.Header)
+	req.Header.Set(api.APIVersionHeader, api.APIVersion)
+
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	v := res.Header.Get(api.APIVersionHeader)
+	if api.APIVersionCompatible(v) {
+		return res, nil
+	}
+	res.Body.Close() // nolint: errcheck
+	if v == "" {
+		return nil, fmt.Errorf("the daemon doesn't report its api version, it is older than this go-filecoin of api version %s: run go-filecoin commands of its version", api.APIVersion)
+	}
+	return nil, fmt.Errorf("the daemon has api version %s, incompatible with the api version %s of this go-filecoin: run go-filecoin commands of its version", v, api.APIVersion)
+}
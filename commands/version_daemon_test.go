@@ -1,12 +1,20 @@
 package commands
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"net/http"
 	"os/exec"
 	"testing"
 
+	ma "gx/ipfs/QmNTCey11oxhb1AxDnQBRHtdhap6Ctud872NjAYPYYXPuc/go-multiaddr"
+	manet "gx/ipfs/QmZcLBXKaFe8ND5YHPkJRAwmhJGrVsi1JqDZNyJ4nRK5Mj/go-multiaddr-net"
+
+	"github.com/filecoin-project/go-filecoin/api"
 	th "github.com/filecoin-project/go-filecoin/testhelpers"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestVersion(t *testing.T) {
@@ -26,5 +34,72 @@ func TestVersion(t *testing.T) {
 	defer d.ShutdownSuccess()
 
 	out := d.RunSuccess("version")
-	assert.Exactly(out.ReadStdout(), fmt.Sprintf("commit: %s", commit))
+	assert.Exactly(out.ReadStdout(), fmt.Sprintf("commit: %sapi version: %s\n", commit, api.APIVersion))
+}
+
+func TestAPIVersion(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	d := th.NewDaemon(t).Start()
+	defer d.ShutdownSuccess()
+
+	maddr, err := ma.NewMultiaddr(d.CmdAddr())
+	require.NoError(err)
+	_, host, err := manet.DialArgs(maddr)
+	require.NoError(err)
+
+	res, err := http.Get(fmt.Sprintf("http://%s%s", host, VersionPath))
+	require.NoError(err)
+	defer res.Body.Close() // nolint: errcheck
+	require.Equal(http.StatusOK, res.StatusCode)
+	assert.Equal(api.APIVersion, res.Header.Get(api.APIVersionHeader))
+	var v api.VersionInfo
+	require.NoError(json.NewDecoder(res.Body).Decode(&v))
+	assert.Equal(api.APIVersion, v.APIVersion)
+
+	// clients of another major version are turned away clearly
+	req, err := http.NewRequest("POST", fmt.Sprintf("http://%s/api/id", host), nil)
+	require.NoError(err)
+	req.Header.Set(api.APIVersionHeader, "0.1")
+	res, err = http.DefaultClient.Do(req)
+	require.NoError(err)
+	defer res.Body.Close() // nolint: errcheck
+	assert.Equal(http.StatusBadRequest, res.StatusCode)
+	body, err := ioutil.ReadAll(res.Body)
+	require.NoError(err)
+	assert.Contains(string(body), "incompatible with the api version")
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	d := th.NewDaemon(t).Start()
+	defer d.ShutdownSuccess()
+
+	maddr, err := ma.NewMultiaddr(d.CmdAddr())
+	require.NoError(err)
+	_, host, err := manet.DialArgs(maddr)
+	require.NoError(err)
+
+	res, err := http.Get(fmt.Sprintf("http://%s%s", host, HealthPath))
+	require.NoError(err)
+	defer res.Body.Close() // nolint: errcheck
+	assert.Equal(http.StatusOK, res.StatusCode)
+
+	// the daemon isn't connected to any peer, so it isn't ready yet
+	res, err = http.Get(fmt.Sprintf("http://%s%s", host, ReadyPath))
+	require.NoError(err)
+	defer res.Body.Close() // nolint: errcheck
+	assert.Equal(http.StatusServiceUnavailable, res.StatusCode)
+	var report HealthReport
+	require.NoError(json.NewDecoder(res.Body).Decode(&report))
+	require.Len(report.Checks, 4)
+	assert.Equal("peers", report.Checks[1].Name)
+	assert.False(report.Checks[1].OK)
+	assert.Equal("api", report.Checks[3].Name)
+	assert.True(report.Checks[3].OK)
 }
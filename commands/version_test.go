@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/api"
+)
+
+func TestVersionTransport(t *testing.T) {
+	t.Parallel()
+
+	serve := func(version string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if version != "" {
+				w.Header().Set(api.APIVersionHeader, version)
+			}
+			w.Write([]byte(r.Header.Get(api.APIVersionHeader))) // nolint: errcheck
+		}))
+	}
+	client := &http.Client{Transport: &versionTransport{next: http.DefaultTransport}}
+
+	t.Run("sends the api version and accepts compatible daemons", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		srv := serve(api.APIVersion)
+		defer srv.Close()
+
+		res, err := client.Get(srv.URL)
+		require.NoError(err)
+		defer res.Body.Close() // nolint: errcheck
+		assert.Equal(http.StatusOK, res.StatusCode)
+	})
+
+	t.Run("fails clearly on incompatible daemons", func(t *testing.T) {
+		assert := assert.New(t)
+
+		srv := serve("0.1")
+		defer srv.Close()
+		_, err := client.Get(srv.URL)
+		assert.Contains(err.Error(), "the daemon has api version 0.1, incompatible")
+
+		old := serve("")
+		defer old.Close()
+		_, err = client.Get(old.URL)
+		assert.Contains(err.Error(), "the daemon doesn't report its api version")
+	})
+}
+
+func TestWithAPIVersion(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	h := withAPIVersion(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for version, code := range map[string]int{"": http.StatusOK, api.APIVersion: http.StatusOK, "0.1": http.StatusBadRequest} {
+		req := httptest.NewRequest("GET", "/api/id", nil)
+		if version != "" {
+			req.Header.Set(api.APIVersionHeader, version)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		assert.Equal(code, rec.Code, version)
+		assert.Equal(api.APIVersion, rec.Header().Get(api.APIVersionHeader))
+	}
+}
@@ -8,30 +8,69 @@ import (
 	"reflect"
 	"regexp"
 	"strings"
+	"time"
 
+	ma "gx/ipfs/QmNTCey11oxhb1AxDnQBRHtdhap6Ctud872NjAYPYYXPuc/go-multiaddr"
 	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
 
 	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/logs"
 	"github.com/filecoin-project/go-filecoin/types"
 )
 
+// The roles of a node. The miner role runs all the subsystems of the node,
+// the client role doesn't mine nor seal, and the gateway role has neither a
+// wallet nor a miner but syncs and serves the chain and the api.
+const (
+	RoleMiner   = "miner"
+	RoleClient  = "client"
+	RoleGateway = "gateway"
+)
+
 // Config is an in memory representation of the filecoin configuration file
 type Config struct {
-	API       *APIConfig       `json:"api"`
-	Bootstrap *BootstrapConfig `json:"bootstrap"`
-	Datastore *DatastoreConfig `json:"datastore"`
-	Swarm     *SwarmConfig     `json:"swarm"`
-	Mining    *MiningConfig    `json:"mining"`
-	Wallet    *WalletConfig    `json:"wallet"`
-	Heartbeat *HeartbeatConfig `json:"heartbeat"`
+	// Role selects the subsystems the node runs, one of RoleMiner,
+	// RoleClient or RoleGateway.
+	Role         string              `json:"role" doc:"The subsystems the node runs: miner runs all of them, client doesn't mine nor seal, gateway has neither a wallet nor a miner but syncs and serves the chain and the api."`
+	API          *APIConfig          `json:"api" doc:"The http api of the daemon."`
+	Bootstrap    *BootstrapConfig    `json:"bootstrap" doc:"The peers the node connects to when it has too few peers."`
+	Peering      *PeeringConfig      `json:"peering" doc:"The peers the node always stays connected to."`
+	Datastore    *DatastoreConfig    `json:"datastore" doc:"The datastores of the repo."`
+	Swarm        *SwarmConfig        `json:"swarm" doc:"The libp2p host of the node."`
+	Mining       *MiningConfig       `json:"mining" doc:"Mining, sealing and the storage deals of a miner."`
+	Client       *ClientConfig       `json:"client" doc:"The storage deals of a client."`
+	DataTransfer *DataTransferConfig `json:"dataTransfer" doc:"Transferring the data of deals."`
+	Wallet       *WalletConfig       `json:"wallet" doc:"The wallet of the node."`
+	Heartbeat    *HeartbeatConfig    `json:"heartbeat" doc:"The heartbeats the node sends to a monitoring service."`
+	Proofs       *ProofsConfig       `json:"proofs" doc:"The proofs backend and its parameters."`
+	Network      *NetworkConfig      `json:"network" doc:"The network the node is part of."`
+	Pubsub       *PubsubConfig       `json:"pubsub" doc:"The gossip of blocks and messages."`
+	Log          *LogConfig          `json:"log" doc:"The logs of the daemon."`
 }
 
 // APIConfig holds all configuration options related to the api.
 type APIConfig struct {
-	Address                       string   `json:"address"`
-	AccessControlAllowOrigin      []string `json:"accessControlAllowOrigin"`
-	AccessControlAllowCredentials bool     `json:"accessControlAllowCredentials"`
-	AccessControlAllowMethods     []string `json:"accessControlAllowMethods"`
+	Address                       string   `json:"address" doc:"The multiaddr the api listens on, which the local commands use."`
+	AccessControlAllowOrigin      []string `json:"accessControlAllowOrigin" doc:"The origins of the browser clients allowed to call the api (CORS)."`
+	AccessControlAllowCredentials bool     `json:"accessControlAllowCredentials" doc:"Allows browser clients to send credentials with their requests (CORS)."`
+	AccessControlAllowMethods     []string `json:"accessControlAllowMethods" doc:"The http methods browser clients may use (CORS)."`
+	// ListenAddresses are the addresses the api listens on besides Address,
+	// e.g. of a public interface for browser clients.
+	ListenAddresses []string `json:"listenAddresses" doc:"Multiaddrs the api listens on besides address, e.g. of a public interface for browser clients."`
+	// TLSCertFile and TLSKeyFile, if set, are the PEM files of the
+	// certificate and key the api serves https with on ListenAddresses.
+	// Address, which the local commands use, always serves plain http.
+	TLSCertFile string `json:"tlsCertFile,omitempty" doc:"The PEM file of the certificate the api serves https with on listenAddresses."`
+	TLSKeyFile  string `json:"tlsKeyFile,omitempty" doc:"The PEM file of the key the api serves https with on listenAddresses."`
+	// BasePath, if set, is a path the api is also served under, e.g.
+	// /filecoin for a reverse proxy forwarding https://example.com/filecoin/
+	// to the api without stripping the path.
+	BasePath string `json:"basePath,omitempty" doc:"A path the api is also served under, e.g. /filecoin behind a reverse proxy."`
+	// ReadyMinPeers is the number of peers the node must be connected to,
+	// and ReadyMaxSyncLag the number of blocks its chain head may trail the
+	// heads announced by its peers, for /readyz to report it ready.
+	ReadyMinPeers   int    `json:"readyMinPeers" doc:"The number of peers the node must be connected to for /readyz to report it ready."`
+	ReadyMaxSyncLag uint64 `json:"readyMaxSyncLag" doc:"The number of blocks the chain head may trail the heads of the peers for /readyz to report the node ready."`
 }
 
 func newDefaultAPIConfig() *APIConfig {
@@ -44,14 +83,57 @@ func newDefaultAPIConfig() *APIConfig {
 			"https://127.0.0.1:8080",
 		},
 		AccessControlAllowMethods: []string{"GET", "POST", "PUT"},
+		ListenAddresses:           []string{},
+		ReadyMinPeers:             1,
+		ReadyMaxSyncLag:           5,
 	}
 }
 
 // DatastoreConfig holds all the configuration options for the datastore.
 // TODO: use the advanced datastore configuration from ipfs
 type DatastoreConfig struct {
-	Type string `json:"type"`
-	Path string `json:"path"`
+	// Type is the backend of the datastores, badgerds or leveldb. Like
+	// BlocksType and Compress, it is chosen at init.
+	Type string `json:"type" doc:"The type of the datastores, badgerds or leveldb. Chosen at init."`
+	Path string `json:"path" doc:"The directory of the main datastore, relative to the repo."`
+	// BlocksType, if set to flatfs, keeps the blocks of the repo in flatfs
+	// directories, a file per block, rather than in the datastores of Type.
+	BlocksType string `json:"blocksType" doc:"If flatfs, keeps the blocks in flatfs directories rather than in the datastores. Chosen at init."`
+	// Compress compresses the values of the datastores with deflate.
+	Compress bool `json:"compress" doc:"Compresses the values of the datastores. Chosen at init."`
+	// Badger tunes the badger datastores of the repo.
+	Badger *BadgerConfig `json:"badger" doc:"Tunes the badger datastores of the repo."`
+	// Pieces configures the datastore holding the data of the pieces of
+	// storage deals, kept apart from the chain and state blocks.
+	Pieces *PiecesDatastoreConfig `json:"pieces" doc:"The datastore of the data of the pieces of storage deals, kept apart from the chain."`
+}
+
+// PiecesDatastoreConfig holds the options of the datastore of the pieces of
+// storage deals, e.g. for miners to keep them on a larger, slower disk than
+// the chain.
+type PiecesDatastoreConfig struct {
+	// Path is the directory of the datastore, relative to the repo unless
+	// absolute.
+	Path string `json:"path" doc:"The directory of the pieces datastore, relative to the repo unless absolute."`
+	// GCInterval, if set, overrides datastore.badger.gcInterval for the
+	// pieces datastore.
+	GCInterval string `json:"gcInterval,omitempty" doc:"Overrides datastore.badger.gcInterval for the pieces datastore."`
+}
+
+// BadgerConfig holds the options of the badger datastores, whose defaults
+// suit small repos rather than ones holding a long chain.
+type BadgerConfig struct {
+	// ValueLogFileSize is the size in bytes of each file of the value log,
+	// between 1MiB and 2GiB. Larger files mean fewer of them to keep open.
+	ValueLogFileSize int64 `json:"valueLogFileSize" doc:"The size in bytes of each file of the value log, at least 1MiB and less than 2GiB."`
+	// SyncWrites makes each write wait for the value log to be synced to
+	// disk. Turning it off speeds writes up at the risk of losing the last
+	// ones in a crash.
+	SyncWrites bool `json:"syncWrites" doc:"Makes each write wait for the value log to be synced to disk."`
+	// GCInterval is how often the space of the deleted and overwritten
+	// values is reclaimed from the value logs, in Golang duration units.
+	// "0s" turns garbage collection off.
+	GCInterval string `json:"gcInterval" doc:"How often the space of deleted values is reclaimed from the value logs, a duration. 0s turns it off."`
 }
 
 // Validators hold the list of validation functions for each configuration
@@ -60,33 +142,116 @@ type DatastoreConfig struct {
 // the given key and value are valid. Validators will only be run if a property
 // being set matches the name given in this map.
 var Validators = map[string]func(string, string) error{
-	"heartbeat.nickname": validateLettersOnly,
+	"role":                              validateRole,
+	"api.listenAddresses":               validateMultiaddrs,
+	"api.basePath":                      validateBasePath,
+	"heartbeat.nickname":                validateLettersOnly,
+	"mining.storagePaths":               validateStoragePaths,
+	"mining.post.retryBackoff":          validateDuration,
+	"mining.sealing.commitBatchWait":    validateDuration,
+	"mining.scrub.interval":             validateDuration,
+	"mining.packing.maxWait":            validateDuration,
+	"swarm.connMgr.gracePeriod":         validateDuration,
+	"swarm.announceAddresses":           validateMultiaddrs,
+	"swarm.listenAddresses":             validateListenAddrs,
+	"peering.peers":                     validatePeerAddrs,
+	"pubsub.seenMessagesTTL":            validateDuration,
+	"log.levels":                        validateLogLevels,
+	"mining.mineDelay":                  validateDuration,
+	"datastore.badger.valueLogFileSize": validateValueLogFileSize,
+	"datastore.badger.gcInterval":       validateDuration,
+	"datastore.type":                    validateDatastoreType,
+	"datastore.blocksType":              validateBlocksDatastoreType,
+	"datastore.pieces.gcInterval":       validateDuration,
+	"network.blockTime":                 validateDuration,
 }
 
 func newDefaultDatastoreConfig() *DatastoreConfig {
 	return &DatastoreConfig{
-		Type: "badgerds",
-		Path: "badger",
+		Type:   "badgerds",
+		Path:   "badger",
+		Badger: newDefaultBadgerConfig(),
+		Pieces: &PiecesDatastoreConfig{
+			Path: "pieces",
+		},
+	}
+}
+
+func newDefaultBadgerConfig() *BadgerConfig {
+	return &BadgerConfig{
+		ValueLogFileSize: 1<<30 - 1,
+		SyncWrites:       true,
+		GCInterval:       "15m",
 	}
 }
 
 // SwarmConfig holds all configuration options related to the swarm.
 type SwarmConfig struct {
-	Address            string `json:"address"`
-	PublicRelayAddress string `json:"public_relay_address,omitempty"`
+	Address string `json:"address" doc:"The multiaddr the node listens on for peers."`
+	// ListenAddresses are the addresses the node listens on besides
+	// Address, e.g. a websocket address such as /ip4/0.0.0.0/tcp/6001/ws
+	// for browser clients, or a QUIC address such as
+	// /ip4/0.0.0.0/udp/6000/quic. The node only dials QUIC addresses if it
+	// listens on one.
+	ListenAddresses    []string `json:"listenAddresses" doc:"Multiaddrs the node listens on besides address, e.g. a websocket address for browser clients or a QUIC address."`
+	PublicRelayAddress string   `json:"public_relay_address,omitempty" doc:"The multiaddr of the relay the node is reachable through."`
+	// AnnounceAddresses, if not empty, are the addresses the node advertises
+	// to peers instead of the ones it listens on, e.g. the public address of
+	// a router forwarding a port to the node.
+	AnnounceAddresses []string `json:"announceAddresses" doc:"The multiaddrs the node advertises to peers instead of the ones it listens on."`
+	// NATPortMap makes the node ask the router of its network to forward a
+	// port to it, with UPnP or NAT-PMP. Without a mapping, a node behind a
+	// NAT is reached through a relay, until a hole is punched.
+	NATPortMap bool `json:"natPortMap" doc:"Asks the router of the network to forward a port to the node, with UPnP or NAT-PMP."`
+	// AutoNATService makes the node dial peers back so they can tell whether
+	// they are behind a NAT. Relays always do.
+	AutoNATService bool `json:"autoNATService" doc:"Dials peers back so they can tell whether they are behind a NAT."`
+	// HolePunching makes the node punch holes through the NATs of the peers
+	// it is connected to through a relay, so that it talks to them directly.
+	HolePunching bool           `json:"holePunching" doc:"Upgrades the connections relayed to peers behind NATs to direct ones by punching holes."`
+	ConnMgr      *ConnMgrConfig `json:"connMgr" doc:"The limits on the number of connections of the node."`
+	// BandwidthLimits caps, in bytes per second, the rate the protocols
+	// starting with each prefix send at, e.g. "/ipfs/bitswap" to keep
+	// serving blocks from taking the I/O sealing needs.
+	BandwidthLimits map[string]uint64 `json:"bandwidthLimits" doc:"The bytes per second the protocols starting with each prefix send at most, e.g. /ipfs/bitswap."`
 }
 
 func newDefaultSwarmConfig() *SwarmConfig {
 	return &SwarmConfig{
-		Address: "/ip4/0.0.0.0/tcp/6000",
+		Address:           "/ip4/0.0.0.0/tcp/6000",
+		ListenAddresses:   []string{},
+		AnnounceAddresses: []string{},
+		NATPortMap:        true,
+		HolePunching:      true,
+		ConnMgr:           newDefaultConnMgrConfig(),
+		BandwidthLimits:   map[string]uint64{},
+	}
+}
+
+// ConnMgrConfig holds the limits on the number of connections of the node.
+// When it has more than HighWater connections, the least valuable are closed
+// until LowWater are left, sparing protected peers and connections younger
+// than GracePeriod.
+type ConnMgrConfig struct {
+	LowWater  int `json:"lowWater" doc:"The number of connections left when connections are closed."`
+	HighWater int `json:"highWater" doc:"The number of connections above which the least valuable are closed."`
+	// GracePeriod is in Golang duration units.
+	GracePeriod string `json:"gracePeriod" doc:"How long new connections are spared, a duration."`
+}
+
+func newDefaultConnMgrConfig() *ConnMgrConfig {
+	return &ConnMgrConfig{
+		LowWater:    100,
+		HighWater:   300,
+		GracePeriod: "20s",
 	}
 }
 
 // BootstrapConfig holds all configuration options related to bootstrap nodes
 type BootstrapConfig struct {
-	Addresses        []string `json:"addresses"`
-	MinPeerThreshold int      `json:"minPeerThreshold"`
-	Period           string   `json:"period,omitempty"`
+	Addresses        []string `json:"addresses" doc:"The multiaddrs of the bootstrap peers, ending with their peer ids."`
+	MinPeerThreshold int      `json:"minPeerThreshold" doc:"The number of peers below which the node connects to bootstrap peers."`
+	Period           string   `json:"period,omitempty" doc:"How often the number of peers is checked, a duration."`
 }
 
 // TODO: provide bootstrap node addresses
@@ -98,11 +263,42 @@ func newDefaultBootstrapConfig() *BootstrapConfig {
 	}
 }
 
+// PeeringConfig holds the peers the node always stays connected to,
+// reconnecting when they disconnect, e.g. the other nodes of a miner cluster.
+type PeeringConfig struct {
+	// Peers are multiaddrs ending with the ids of the peers, as bootstrap
+	// addresses.
+	Peers []string `json:"peers" doc:"The multiaddrs of the peers, ending with their peer ids."`
+}
+
+func newDefaultPeeringConfig() *PeeringConfig {
+	return &PeeringConfig{
+		Peers: []string{},
+	}
+}
+
 // MiningConfig holds all configuration options related to mining.
 type MiningConfig struct {
-	MinerAddress            address.Address `json:"minerAddress"`
-	AutoSealIntervalSeconds uint            `json:"autoSealIntervalSeconds"`
-	StoragePrice            *types.AttoFIL  `json:"storagePrice"`
+	MinerAddress            address.Address   `json:"minerAddress" doc:"The address of the miner actor of the node."`
+	AutoSealIntervalSeconds uint              `json:"autoSealIntervalSeconds" doc:"How often staged sectors are sealed, in seconds."`
+	StoragePrice            *types.AttoFIL    `json:"storagePrice" doc:"The price per byte per block of the asks of the miner."`
+	RetrievalPrice          *types.AttoFIL    `json:"retrievalPrice" doc:"The price per byte of retrieving data from the miner."`
+	UnsealPrice             *types.AttoFIL    `json:"unsealPrice" doc:"The price of unsealing a sector to retrieve data from it."`
+	UnsealCacheSize         uint64            `json:"unsealCacheSize" doc:"The bytes of unsealed sectors kept for retrievals."`
+	DealPolicy              *DealPolicyConfig `json:"dealPolicy" doc:"The policy the miner accepts storage deal proposals by."`
+	Sealing                 *SealingConfig    `json:"sealing" doc:"The number of jobs of each stage of sealing run at once."`
+	// StoragePaths are the directories the sector builder keeps staged
+	// sectors, sealed sectors and its cache in. Without any, the sectors
+	// are kept in the repo.
+	StoragePaths []*StoragePathConfig `json:"storagePaths" doc:"The directories of the staged sectors, sealed sectors and cache. Without any, the sectors are kept in the repo."`
+	PoSt         *PoStConfig          `json:"post" doc:"How the miner submits its proofs of spacetime."`
+	Pledge       *PledgeConfig        `json:"pledge" doc:"How the miner pledges sectors filled with self-generated data."`
+	Scrub        *ScrubConfig         `json:"scrub" doc:"How the miner checks its sealed sectors for corruption."`
+	Packing      *PackingConfig       `json:"packing" doc:"How the miner packs the pieces of deals into sectors."`
+	// MineDelay, if set, is how long the miner waits for the blocks of a
+	// new height before mining on them, in Golang duration units. It
+	// defaults to a fraction of the block time.
+	MineDelay string `json:"mineDelay,omitempty" doc:"How long the miner waits for the blocks of a new height before mining on them, a duration. Defaults to a fraction of the block time."`
 }
 
 func newDefaultMiningConfig() *MiningConfig {
@@ -110,12 +306,233 @@ func newDefaultMiningConfig() *MiningConfig {
 		MinerAddress:            address.Address{},
 		AutoSealIntervalSeconds: 120,
 		StoragePrice:            types.NewZeroAttoFIL(),
+		RetrievalPrice:          types.NewZeroAttoFIL(),
+		UnsealPrice:             types.NewZeroAttoFIL(),
+		UnsealCacheSize:         1 << 30,
+		DealPolicy:              newDefaultDealPolicyConfig(),
+		Sealing:                 newDefaultSealingConfig(),
+		StoragePaths:            []*StoragePathConfig{},
+		PoSt:                    newDefaultPoStConfig(),
+		Pledge:                  newDefaultPledgeConfig(),
+		Scrub:                   newDefaultScrubConfig(),
+		Packing:                 newDefaultPackingConfig(),
+	}
+}
+
+// DealPolicyConfig holds the policy a miner accepts incoming storage deal
+// proposals by. Zero values of bounds mean unbounded.
+type DealPolicyConfig struct {
+	// MinPrice is the lowest price per byte per block the miner accepts.
+	MinPrice *types.AttoFIL `json:"minPrice" doc:"The lowest price per byte per block the miner accepts."`
+	// MinDuration and MaxDuration bound the duration of deals, in blocks.
+	MinDuration uint64 `json:"minDuration" doc:"The shortest duration of deals in blocks, 0 for no bound."`
+	MaxDuration uint64 `json:"maxDuration" doc:"The longest duration of deals in blocks, 0 for no bound."`
+	// MinPieceSize and MaxPieceSize bound the size of pieces, in bytes.
+	MinPieceSize uint64 `json:"minPieceSize" doc:"The smallest piece size in bytes, 0 for no bound."`
+	MaxPieceSize uint64 `json:"maxPieceSize" doc:"The largest piece size in bytes, 0 for no bound."`
+	// AllowedClients lists the only clients the miner makes deals with, if
+	// not empty.
+	AllowedClients []address.Address `json:"allowedClients" doc:"The only clients the miner makes deals with, if not empty."`
+	// BlockedClients lists clients the miner makes no deals with.
+	BlockedClients []address.Address `json:"blockedClients" doc:"The clients the miner makes no deals with."`
+}
+
+func newDefaultDealPolicyConfig() *DealPolicyConfig {
+	return &DealPolicyConfig{
+		MinPrice:       types.NewZeroAttoFIL(),
+		AllowedClients: []address.Address{},
+		BlockedClients: []address.Address{},
+	}
+}
+
+// SealingConfig limits how many jobs of each stage of getting piece data into
+// sealed sectors a miner runs at once.
+type SealingConfig struct {
+	// AddPieceConcurrency bounds the pieces written into staged sectors at
+	// once, each held in memory while it is written.
+	AddPieceConcurrency int `json:"addPieceConcurrency" doc:"The number of pieces written into staged sectors at once."`
+	// SealConcurrency bounds the sealings of staged sectors, which are CPU
+	// bound.
+	SealConcurrency int `json:"sealConcurrency" doc:"The number of sectors sealed at once."`
+	// CommitConcurrency bounds the commitments of sealed sectors sent to the
+	// chain at once.
+	CommitConcurrency int `json:"commitConcurrency" doc:"The number of commitments of sealed sectors sent to the chain at once."`
+	// CommitBatchSize is the number of sealed sectors committed together in
+	// a commitSectors message, and CommitBatchWait the longest a sealed
+	// sector waits for others to fill its batch. Batches of 1 are sent as
+	// commitSector messages.
+	// Golang duration units are accepted.
+	CommitBatchSize int    `json:"commitBatchSize" doc:"The number of sealed sectors committed together in a message."`
+	CommitBatchWait string `json:"commitBatchWait" doc:"The longest a sealed sector waits for others to fill its batch, a duration."`
+	// Workers holds the peer ids of the remote workers allowed to seal
+	// sectors for the miner.
+	Workers []string `json:"workers" doc:"The peer ids of the remote workers allowed to seal sectors for the miner."`
+}
+
+func newDefaultSealingConfig() *SealingConfig {
+	return &SealingConfig{
+		AddPieceConcurrency: 2,
+		SealConcurrency:     1,
+		CommitConcurrency:   1,
+		CommitBatchSize:     1,
+		CommitBatchWait:     "1m",
+		Workers:             []string{},
+	}
+}
+
+// StoragePathConfig configures a directory the sector builder keeps data in.
+type StoragePathConfig struct {
+	Path string `json:"path" doc:"The directory."`
+	// Kinds lists the data kept in the path: staged, sealed and cache.
+	Kinds []string `json:"kinds" doc:"The data kept in the directory: staged, sealed and cache."`
+	// MaxBytes bounds the bytes kept in the path, 0 for the free space of
+	// its file system.
+	MaxBytes uint64 `json:"maxBytes" doc:"The most bytes kept in the directory, 0 for the free space of its file system."`
+	// Weight scales the free space of the path when choosing between paths,
+	// 0 counting as 1.
+	Weight uint64 `json:"weight" doc:"Scales the free space of the directory when choosing between directories, 0 counting as 1."`
+}
+
+// PoStConfig holds how a miner submits its proofs of spacetime.
+type PoStConfig struct {
+	// GasPrice and GasLimit are the gas of submitPoSt messages.
+	GasPrice *types.AttoFIL `json:"gasPrice" doc:"The gas price of submitPoSt messages."`
+	GasLimit uint64         `json:"gasLimit" doc:"The gas limit of submitPoSt messages."`
+	// Retries is the number of times generating or submitting a PoSt is
+	// retried within a proving period, waiting RetryBackoff before the first
+	// retry and twice as long before each next one.
+	// Golang duration units are accepted.
+	Retries      int    `json:"retries" doc:"The number of times generating or submitting a PoSt is retried within a proving period."`
+	RetryBackoff string `json:"retryBackoff" doc:"How long to wait before the first retry, doubled before each next one, a duration."`
+	// AtRiskBlocks is the number of blocks before the end of a proving
+	// period from which an alert is raised while its PoSt is not on chain.
+	AtRiskBlocks uint64 `json:"atRiskBlocks" doc:"The number of blocks before the end of a proving period from which an alert is raised while its PoSt is not on chain."`
+}
+
+func newDefaultPoStConfig() *PoStConfig {
+	return &PoStConfig{
+		GasPrice:     types.NewZeroAttoFIL(),
+		GasLimit:     300,
+		Retries:      5,
+		RetryBackoff: "30s",
+		AtRiskBlocks: 2000,
+	}
+}
+
+// PledgeConfig holds how a miner pledges sectors, which are filled with
+// self-generated data instead of the data of deals.
+type PledgeConfig struct {
+	// TargetPower is the power, in sectors, the miner keeps pledging sectors
+	// until it reaches. Zero disables pledging on its own.
+	TargetPower uint64 `json:"targetPower" doc:"The power in sectors the miner pledges sectors until it reaches, 0 to not pledge on its own."`
+	// BatchSize is the number of sectors pledged at once to reach the
+	// target power.
+	BatchSize uint64 `json:"batchSize" doc:"The number of sectors pledged at once."`
+}
+
+func newDefaultPledgeConfig() *PledgeConfig {
+	return &PledgeConfig{
+		TargetPower: 0,
+		BatchSize:   1,
+	}
+}
+
+// ScrubConfig holds how a miner scrubs its sealed sectors, looking for
+// corruption before it makes the miner fail its PoSts.
+type ScrubConfig struct {
+	// Interval is the time between scrubs, in Golang duration units. Zero
+	// disables scrubbing on its own.
+	Interval string `json:"interval" doc:"The time between scrubs, a duration. 0s disables scrubbing on its own."`
+	// Samples is the number of ranges of sealed sector files a scrub reads,
+	// and RangeBytes the size of each.
+	Samples    int    `json:"samples" doc:"The number of ranges of sealed sector files a scrub reads."`
+	RangeBytes uint64 `json:"rangeBytes" doc:"The size of each range read."`
+}
+
+func newDefaultScrubConfig() *ScrubConfig {
+	return &ScrubConfig{
+		Interval:   "6h",
+		Samples:    16,
+		RangeBytes: 1 << 20,
+	}
+}
+
+// PackingConfig holds how a miner packs the pieces of deals into sectors.
+type PackingConfig struct {
+	// Enabled makes the miner pack the pieces of deals into the fullest
+	// sector they fit in, by their padded sizes, before staging them, rather
+	// than staging each piece as it arrives.
+	Enabled bool `json:"enabled" doc:"Packs the pieces of deals into the fullest sector they fit in before staging them."`
+	// MaxWait is the longest a partially full sector waits for more pieces
+	// before it is sealed, in Golang duration units.
+	MaxWait string `json:"maxWait" doc:"The longest a partially full sector waits for more pieces before it is sealed, a duration."`
+}
+
+func newDefaultPackingConfig() *PackingConfig {
+	return &PackingConfig{
+		Enabled: false,
+		MaxWait: "1h",
+	}
+}
+
+// ClientConfig holds all configuration options related to storage clients.
+type ClientConfig struct {
+	// RenewalWindow is the number of blocks before a deal expires at which the
+	// client proposes its renewal, if the deal is to be renewed.
+	RenewalWindow uint64 `json:"renewalWindow" doc:"The number of blocks before a deal expires at which the client proposes its renewal."`
+	// MaxRenewalPrice is the highest price per byte per block the client
+	// accepts when renewing deals that have no price ceiling of their own. If
+	// zero, renewals cost at most the price of the original deal.
+	MaxRenewalPrice *types.AttoFIL `json:"maxRenewalPrice" doc:"The highest price per byte per block of renewals of deals without a ceiling of their own, 0 for the price of the original deal."`
+}
+
+func newDefaultClientConfig() *ClientConfig {
+	return &ClientConfig{
+		RenewalWindow:   1000,
+		MaxRenewalPrice: types.NewZeroAttoFIL(),
+	}
+}
+
+// DataTransferConfig holds all configuration options related to transferring
+// the data of deals.
+type DataTransferConfig struct {
+	// MaxBandwidth is the number of bytes per second all transfers together
+	// use at most. Zero means unlimited.
+	MaxBandwidth uint64 `json:"maxBandwidth" doc:"The bytes per second all transfers together use at most, 0 for no limit."`
+}
+
+func newDefaultDataTransferConfig() *DataTransferConfig {
+	return &DataTransferConfig{
+		MaxBandwidth: 0,
 	}
 }
 
 // WalletConfig holds all configuration options related to the wallet.
 type WalletConfig struct {
-	DefaultAddress address.Address `json:"defaultAddress,omitempty"`
+	DefaultAddress address.Address `json:"defaultAddress,omitempty" doc:"The address messages are sent from by default."`
+	// RemoteSigner, if set, adds a wallet backend that forwards signing
+	// requests to a remote signing service.
+	RemoteSigner *RemoteSignerConfig `json:"remoteSigner,omitempty" doc:"A remote signing service the wallet forwards signing requests to."`
+}
+
+// RemoteSignerConfig holds the configuration options for connecting to a
+// remote signing service. The connection uses mutual TLS when ClientCert,
+// ClientKey and CACert are all set.
+type RemoteSignerConfig struct {
+	// URL is the base url of the signing service.
+	URL        string `json:"url" doc:"The base url of the signing service."`
+	ClientCert string `json:"clientCert,omitempty" doc:"The PEM file of the client certificate for mutual TLS."`
+	ClientKey  string `json:"clientKey,omitempty" doc:"The PEM file of the client key for mutual TLS."`
+	CACert     string `json:"caCert,omitempty" doc:"The PEM file of the CA certificate of the service for mutual TLS."`
+	// Timeout bounds each request to the signing service.
+	// Golang duration units are accepted.
+	Timeout string `json:"timeout,omitempty" doc:"Bounds each request to the signing service, a duration."`
+	// Retries is the number of times a failed request is retried.
+	Retries int `json:"retries,omitempty" doc:"The number of times a failed request is retried."`
+	// HealthCheckPeriod represents how often the signing service's health is
+	// checked and its list of addresses refreshed.
+	// Golang duration units are accepted.
+	HealthCheckPeriod string `json:"healthCheckPeriod,omitempty" doc:"How often the health of the service is checked and its addresses refreshed, a duration."`
 }
 
 func newDefaultWalletConfig() *WalletConfig {
@@ -127,15 +544,15 @@ func newDefaultWalletConfig() *WalletConfig {
 // HeartbeatConfig holds all configuration options related to node heartbeat.
 type HeartbeatConfig struct {
 	// BeatTarget represents the address the filecoin node will send heartbeats to.
-	BeatTarget string `json:"beatTarget"`
+	BeatTarget string `json:"beatTarget" doc:"The multiaddr the heartbeats are sent to, none if empty."`
 	// BeatPeriod represents how frequently heartbeats are sent.
 	// Golang duration units are accepted.
-	BeatPeriod string `json:"beatPeriod"`
+	BeatPeriod string `json:"beatPeriod" doc:"How often heartbeats are sent, a duration."`
 	// ReconnectPeriod represents how long the node waits before attempting to reconnect.
 	// Golang duration units are accepted.
-	ReconnectPeriod string `json:"reconnectPeriod"`
+	ReconnectPeriod string `json:"reconnectPeriod" doc:"How long to wait before reconnecting, a duration."`
 	// Nickname represents the nickname of the filecoin node,
-	Nickname string `json:"nickname"`
+	Nickname string `json:"nickname" doc:"The nickname of the node, letters only."`
 }
 
 func newDefaultHeartbeatConfig() *HeartbeatConfig {
@@ -147,17 +564,112 @@ func newDefaultHeartbeatConfig() *HeartbeatConfig {
 	}
 }
 
+// ProofsConfig holds all configuration options related to the parameters of
+// the proofs.
+type ProofsConfig struct {
+	// ParameterCache is the directory the Groth parameters and verifying keys
+	// are kept in, shared with the proofs library. Empty uses the directory
+	// the proofs library defaults to.
+	ParameterCache string `json:"parameterCache" doc:"The directory of the Groth parameters and verifying keys, empty for the default of the proofs library."`
+	// ParameterManifest is the URL or path of the manifest listing the
+	// parameter files with their cids and digests.
+	ParameterManifest string `json:"parameterManifest" doc:"The URL or path of the manifest of the parameter files."`
+	// ParameterGateway is the URL the cids of parameter files are appended to
+	// to download them.
+	ParameterGateway string `json:"parameterGateway" doc:"The URL the cids of parameter files are appended to to download them."`
+	// Insecure makes the node fake seal and PoSt proofs and accept fake
+	// proofs, which is only allowed on local networks.
+	Insecure bool `json:"insecure" doc:"Fakes seal and PoSt proofs and accepts fake proofs, only allowed on local networks."`
+	// UseGPU lets the proofs backend prove on the GPUs of the machine.
+	UseGPU bool `json:"useGpu" doc:"Lets the proofs backend prove on the GPUs of the machine."`
+	// MaxCPUs bounds the CPU cores the proofs backend proves on, 0 for all of
+	// them.
+	MaxCPUs int `json:"maxCpus" doc:"The most CPU cores the proofs backend proves on, 0 for all of them."`
+	// MemoryPerProof is the memory a seal takes, in bytes. No more seals run
+	// at once than fit into the memory of the machine, 0 for no bound.
+	MemoryPerProof uint64 `json:"memoryPerProof" doc:"The memory a seal takes in bytes, bounding the seals run at once, 0 for no bound."`
+}
+
+func newDefaultProofsConfig() *ProofsConfig {
+	return &ProofsConfig{
+		ParameterCache:    "",
+		ParameterManifest: "",
+		ParameterGateway:  "https://ipfs.io/ipfs/",
+		Insecure:          false,
+		UseGPU:            true,
+		MaxCPUs:           0,
+		MemoryPerProof:    0,
+	}
+}
+
+// NetworkConfig holds all configuration options related to the network the
+// node is part of.
+type NetworkConfig struct {
+	// Name is the name of the network, "local" for networks of nodes started
+	// in dev mode or in tests.
+	Name string `json:"name" doc:"The name of the network, local for nodes in dev mode or in tests."`
+	// BlockTime is the block time of the network, in Golang duration units,
+	// which the --block-time option of the daemon overrides.
+	BlockTime string `json:"blockTime" doc:"The block time of the network, a duration. The --block-time option of the daemon overrides it."`
+}
+
+func newDefaultNetworkConfig() *NetworkConfig {
+	return &NetworkConfig{
+		Name:      "local",
+		BlockTime: "30s",
+	}
+}
+
+// PubsubConfig holds the configuration of the gossip of blocks and messages.
+type PubsubConfig struct {
+	// SeenMessagesTTL is how long blocks and messages received are
+	// remembered, so that copies of them are dropped rather than processed
+	// again, in Golang duration units.
+	SeenMessagesTTL string `json:"seenMessagesTTL" doc:"How long blocks and messages received are remembered to drop copies of them, a duration."`
+	// SeenCacheSize is the most blocks and messages remembered.
+	SeenCacheSize int `json:"seenCacheSize" doc:"The most blocks and messages remembered."`
+}
+
+func newDefaultPubsubConfig() *PubsubConfig {
+	return &PubsubConfig{
+		SeenMessagesTTL: "2m",
+		SeenCacheSize:   100000,
+	}
+}
+
+// LogConfig holds the configuration of the logs of the daemon.
+type LogConfig struct {
+	// Levels are the least severe levels logged by subsystem, e.g.
+	// {"chain.syncer": "debug"}, overriding GO_FILECOIN_LOG_LEVEL. "*"
+	// sets the level of every subsystem.
+	Levels map[string]string `json:"levels" doc:"The least severe levels logged by subsystem, e.g. {\"chain.syncer\": \"debug\"}, overriding GO_FILECOIN_LOG_LEVEL. * sets the level of every subsystem."`
+}
+
+func newDefaultLogConfig() *LogConfig {
+	return &LogConfig{
+		Levels: map[string]string{},
+	}
+}
+
 // NewDefaultConfig returns a config object with all the fields filled out to
 // their default values
 func NewDefaultConfig() *Config {
 	return &Config{
-		API:       newDefaultAPIConfig(),
-		Bootstrap: newDefaultBootstrapConfig(),
-		Datastore: newDefaultDatastoreConfig(),
-		Swarm:     newDefaultSwarmConfig(),
-		Mining:    newDefaultMiningConfig(),
-		Wallet:    newDefaultWalletConfig(),
-		Heartbeat: newDefaultHeartbeatConfig(),
+		Role:         RoleMiner,
+		API:          newDefaultAPIConfig(),
+		Bootstrap:    newDefaultBootstrapConfig(),
+		Peering:      newDefaultPeeringConfig(),
+		Datastore:    newDefaultDatastoreConfig(),
+		Swarm:        newDefaultSwarmConfig(),
+		Mining:       newDefaultMiningConfig(),
+		Client:       newDefaultClientConfig(),
+		DataTransfer: newDefaultDataTransferConfig(),
+		Wallet:       newDefaultWalletConfig(),
+		Heartbeat:    newDefaultHeartbeatConfig(),
+		Proofs:       newDefaultProofsConfig(),
+		Network:      newDefaultNetworkConfig(),
+		Pubsub:       newDefaultPubsubConfig(),
+		Log:          newDefaultLogConfig(),
 	}
 }
 
@@ -257,6 +769,10 @@ OUTER:
 // validators map defined at the top of this file to determine which validations
 // to use for each key.
 func validate(dottedKey string, jsonString string) error {
+	if validationFunc, present := Validators[dottedKey]; present {
+		return validationFunc(dottedKey, jsonString)
+	}
+
 	var obj interface{}
 	if err := json.Unmarshal([]byte(jsonString), &obj); err != nil {
 		return err
@@ -275,17 +791,202 @@ func validate(dottedKey string, jsonString string) error {
 		return nil
 	}
 
-	if validationFunc, present := Validators[dottedKey]; present {
-		return validationFunc(dottedKey, jsonString)
+	return nil
+}
+
+// validateStoragePaths validates that each storage path has a path and keeps
+// known kinds of data.
+func validateStoragePaths(key string, value string) error {
+	var paths []*StoragePathConfig
+	if err := json.Unmarshal([]byte(value), &paths); err != nil {
+		return errors.Wrapf(err, `"%s" must be a list of storage paths`, key)
+	}
+	for _, p := range paths {
+		if p == nil || p.Path == "" {
+			return errors.Errorf(`"%s" must only contain storage paths with a path`, key)
+		}
+		if len(p.Kinds) == 0 {
+			return errors.Errorf(`storage path %s of "%s" keeps no kinds of data`, p.Path, key)
+		}
+		for _, k := range p.Kinds {
+			if k != "staged" && k != "sealed" && k != "cache" {
+				return errors.Errorf(`storage path %s of "%s" keeps unknown kind %q, not staged, sealed or cache`, p.Path, key, k)
+			}
+		}
+	}
+	return nil
+}
+
+// validateMultiaddr validates that a given value is a multiaddr.
+func validateMultiaddr(key string, value string) error {
+	var addr string
+	if err := json.Unmarshal([]byte(value), &addr); err != nil {
+		return errors.Wrapf(err, `"%s" must be a multiaddr`, key)
+	}
+	if _, err := ma.NewMultiaddr(addr); err != nil {
+		return errors.Wrapf(err, `"%s" must be a multiaddr, e.g. /ip4/127.0.0.1/tcp/3453`, key)
 	}
+	return nil
+}
 
+// validateMultiaddrs validates that a given value is a list of multiaddrs.
+func validateMultiaddrs(key string, value string) error {
+	var addrs []string
+	if err := json.Unmarshal([]byte(value), &addrs); err != nil {
+		return errors.Wrapf(err, `"%s" must be a list of multiaddrs`, key)
+	}
+	for _, a := range addrs {
+		if _, err := ma.NewMultiaddr(a); err != nil {
+			return errors.Wrapf(err, `"%s" must only contain multiaddrs`, key)
+		}
+	}
+	return nil
+}
+
+// validatePeerAddrs validates that a given value is a list of multiaddrs
+// ending with peer ids.
+func validatePeerAddrs(key string, value string) error {
+	var addrs []string
+	if err := json.Unmarshal([]byte(value), &addrs); err != nil {
+		return errors.Wrapf(err, `"%s" must be a list of multiaddrs`, key)
+	}
+	for _, a := range addrs {
+		maddr, err := ma.NewMultiaddr(a)
+		if err != nil {
+			return errors.Wrapf(err, `"%s" must only contain multiaddrs`, key)
+		}
+		if _, err := maddr.ValueForProtocol(ma.P_IPFS); err != nil {
+			return fmt.Errorf(`"%s" must only contain multiaddrs ending with /ipfs/<peer id>, not %s`, key, a)
+		}
+	}
+	return nil
+}
+
+// validateListenAddrs validates that a given value is a list of multiaddrs
+// of the transports of the node: tcp, websockets over tcp and QUIC over udp.
+func validateListenAddrs(key string, value string) error {
+	var addrs []string
+	if err := json.Unmarshal([]byte(value), &addrs); err != nil {
+		return errors.Wrapf(err, `"%s" must be a list of multiaddrs`, key)
+	}
+	for _, a := range addrs {
+		maddr, err := ma.NewMultiaddr(a)
+		if err != nil {
+			return errors.Wrapf(err, `"%s" must only contain multiaddrs`, key)
+		}
+		for _, p := range maddr.Protocols() {
+			if p.Code == ma.P_UDP && !IsQUICAddr(maddr) {
+				return fmt.Errorf(`"%s" must only contain tcp, websocket or QUIC addresses, not %s`, key, a)
+			}
+		}
+	}
+	return nil
+}
+
+// IsQUICAddr returns whether maddr is the address of a QUIC transport.
+func IsQUICAddr(maddr ma.Multiaddr) bool {
+	_, err := maddr.ValueForProtocol(ma.P_QUIC)
+	return err == nil
+}
+
+// validateBasePath validates that a given value is an absolute url path
+// without a trailing slash, or empty.
+func validateBasePath(key string, value string) error {
+	var s string
+	if err := json.Unmarshal([]byte(value), &s); err != nil {
+		return errors.Wrapf(err, `"%s" must be a path`, key)
+	}
+	if s != "" && (!strings.HasPrefix(s, "/") || strings.HasSuffix(s, "/")) {
+		return fmt.Errorf(`"%s" must start with a slash and not end with one, e.g. /filecoin`, key)
+	}
+	return nil
+}
+
+// validateDuration validates that a given value is a Golang duration.
+func validateDuration(key string, value string) error {
+	var s string
+	if err := json.Unmarshal([]byte(value), &s); err != nil {
+		return errors.Wrapf(err, `"%s" must be a duration`, key)
+	}
+	if _, err := time.ParseDuration(s); err != nil {
+		return errors.Wrapf(err, `"%s" must be a duration`, key)
+	}
+	return nil
+}
+
+// validateDatastoreType validates that the datastore type is supported.
+func validateDatastoreType(key string, value string) error {
+	var typ string
+	if err := json.Unmarshal([]byte(value), &typ); err != nil {
+		return errors.Wrapf(err, `"%s" must be a string`, key)
+	}
+	if typ != "badgerds" && typ != "leveldb" {
+		return fmt.Errorf(`"%s" must be badgerds or leveldb`, key)
+	}
+	return nil
+}
+
+// validateBlocksDatastoreType validates that the blocks datastore type is
+// supported, empty keeping the blocks in the datastores.
+func validateBlocksDatastoreType(key string, value string) error {
+	var typ string
+	if err := json.Unmarshal([]byte(value), &typ); err != nil {
+		return errors.Wrapf(err, `"%s" must be a string`, key)
+	}
+	if typ != "" && typ != "flatfs" {
+		return fmt.Errorf(`"%s" must be empty or flatfs`, key)
+	}
+	return nil
+}
+
+// validateValueLogFileSize validates that a given value is a size badger
+// accepts for the files of its value log.
+func validateValueLogFileSize(key string, value string) error {
+	var size int64
+	if err := json.Unmarshal([]byte(value), &size); err != nil {
+		return errors.Wrapf(err, `"%s" must be a number of bytes`, key)
+	}
+	if size < 1<<20 || size >= 2<<30 {
+		return fmt.Errorf(`"%s" must be at least 1MiB and less than 2GiB`, key)
+	}
+	return nil
+}
+
+// validateRole validates that a given value is a role of a node.
+func validateRole(key string, value string) error {
+	var role string
+	if err := json.Unmarshal([]byte(value), &role); err != nil {
+		return errors.Wrapf(err, `"%s" must be a string`, key)
+	}
+	if role != RoleMiner && role != RoleClient && role != RoleGateway {
+		return errors.Errorf(`"%s" must be one of %s, %s or %s`, key, RoleMiner, RoleClient, RoleGateway)
+	}
 	return nil
 }
 
 // validateLettersOnly validates that a given value contains only letters. If it
 // does not, an error is returned using the given key for the message.
+// validateLogLevels validates that a given value maps subsystems to levels
+// of the logs.
+func validateLogLevels(key string, value string) error {
+	var levels map[string]string
+	if err := json.Unmarshal([]byte(value), &levels); err != nil {
+		return errors.Wrapf(err, `"%s" must map subsystems to log levels`, key)
+	}
+	for subsystem, level := range levels {
+		found := false
+		for _, l := range logs.Levels {
+			found = found || l == level
+		}
+		if !found {
+			return errors.Errorf(`"%s" has invalid level %q for %s, must be one of %s`, key, level, subsystem, strings.Join(logs.Levels, ", "))
+		}
+	}
+	return nil
+}
+
 func validateLettersOnly(key string, value string) error {
-	if match, _ := regexp.MatchString("^\"[a-zA-Z]+\"$", value); !match {
+	if match, _ := regexp.MatchString("^\"[a-zA-Z]*\"$", value); !match {
 		return errors.Errorf(`"%s" must only contain letters`, key)
 	}
 	return nil
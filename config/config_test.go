@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestDefaults(t *testing.T) {
@@ -36,6 +37,7 @@ func TestWriteFile(t *testing.T) {
 
 	assert.Equal(
 		`{
+	"role": "miner",
 	"api": {
 		"address": "/ip4/127.0.0.1/tcp/3453",
 		"accessControlAllowOrigin": [
@@ -49,24 +51,97 @@ func TestWriteFile(t *testing.T) {
 			"GET",
 			"POST",
 			"PUT"
-		]
+		],
+		"listenAddresses": [],
+		"readyMinPeers": 1,
+		"readyMaxSyncLag": 5
 	},
 	"bootstrap": {
 		"addresses": [],
 		"minPeerThreshold": 0,
 		"period": "1m"
 	},
+	"peering": {
+		"peers": []
+	},
 	"datastore": {
 		"type": "badgerds",
-		"path": "badger"
+		"path": "badger",
+		"badger": {
+			"valueLogFileSize": 1073741823,
+			"syncWrites": true,
+			"gcInterval": "15m"
+		},
+		"pieces": {
+			"path": "pieces"
+		}
 	},
 	"swarm": {
-		"address": "/ip4/0.0.0.0/tcp/6000"
+		"address": "/ip4/0.0.0.0/tcp/6000",
+		"listenAddresses": [],
+		"announceAddresses": [],
+		"natPortMap": true,
+		"autoNATService": false,
+		"holePunching": true,
+		"connMgr": {
+			"lowWater": 100,
+			"highWater": 300,
+			"gracePeriod": "20s"
+		},
+		"bandwidthLimits": {}
 	},
 	"mining": {
 		"minerAddress": "",
 		"autoSealIntervalSeconds": 120,
-		"storagePrice": "0"
+		"storagePrice": "0",
+		"retrievalPrice": "0",
+		"unsealPrice": "0",
+		"unsealCacheSize": 1073741824,
+		"dealPolicy": {
+			"minPrice": "0",
+			"minDuration": 0,
+			"maxDuration": 0,
+			"minPieceSize": 0,
+			"maxPieceSize": 0,
+			"allowedClients": [],
+			"blockedClients": []
+		},
+		"sealing": {
+			"addPieceConcurrency": 2,
+			"sealConcurrency": 1,
+			"commitConcurrency": 1,
+			"commitBatchSize": 1,
+			"commitBatchWait": "1m",
+			"workers": []
+		},
+		"storagePaths": [],
+		"post": {
+			"gasPrice": "0",
+			"gasLimit": 300,
+			"retries": 5,
+			"retryBackoff": "30s",
+			"atRiskBlocks": 2000
+		},
+		"pledge": {
+			"targetPower": 0,
+			"batchSize": 1
+		},
+		"scrub": {
+			"interval": "6h",
+			"samples": 16,
+			"rangeBytes": 1048576
+		},
+		"packing": {
+			"enabled": false,
+			"maxWait": "1h"
+		}
+	},
+	"client": {
+		"renewalWindow": 1000,
+		"maxRenewalPrice": "0"
+	},
+	"dataTransfer": {
+		"maxBandwidth": 0
 	},
 	"wallet": {
 		"defaultAddress": ""
@@ -76,6 +151,26 @@ func TestWriteFile(t *testing.T) {
 		"beatPeriod": "3s",
 		"reconnectPeriod": "10s",
 		"nickname": ""
+	},
+	"proofs": {
+		"parameterCache": "",
+		"parameterManifest": "",
+		"parameterGateway": "https://ipfs.io/ipfs/",
+		"insecure": false,
+		"useGpu": true,
+		"maxCpus": 0,
+		"memoryPerProof": 0
+	},
+	"network": {
+		"name": "local",
+		"blockTime": "30s"
+	},
+	"pubsub": {
+		"seenMessagesTTL": "2m",
+		"seenCacheSize": 100000
+	},
+	"log": {
+		"levels": {}
 	}
 }`,
 		string(content),
@@ -91,12 +186,118 @@ func TestSetRejectsInvalidNicks(t *testing.T) {
 	// sic: json includes the quotes in the value
 	err := cfg.Set("heartbeat.nickname", "\"goodnick\"")
 	assert.NoError(err)
+	err = cfg.Set("heartbeat.nickname", `""`)
+	assert.NoError(err)
 	err = cfg.Set("heartbeat.nickname", "bad nick<p>")
 	assert.Error(err)
 	err = cfg.Set("heartbeat", `{"heartbeat": "bad nick"}`)
 	assert.Error(err)
 }
 
+func TestSetRejectsInvalidStoragePaths(t *testing.T) {
+	assert := assert.New(t)
+	cfg := NewDefaultConfig()
+
+	err := cfg.Set("mining.storagePaths", `[{"path": "/mnt/a", "kinds": ["staged", "sealed"], "maxBytes": 1024, "weight": 2}]`)
+	assert.NoError(err)
+	err = cfg.Set("mining.storagePaths", `[{"kinds": ["sealed"]}]`)
+	assert.Error(err)
+	err = cfg.Set("mining.storagePaths", `[{"path": "/mnt/a", "kinds": []}]`)
+	assert.Error(err)
+	err = cfg.Set("mining.storagePaths", `[{"path": "/mnt/a", "kinds": ["unsealed"]}]`)
+	assert.Error(err)
+}
+
+func TestSetRejectsInvalidAnnounceAddresses(t *testing.T) {
+	assert := assert.New(t)
+	cfg := NewDefaultConfig()
+
+	err := cfg.Set("swarm.announceAddresses", `["/ip4/1.2.3.4/tcp/6000"]`)
+	assert.NoError(err)
+	assert.Equal([]string{"/ip4/1.2.3.4/tcp/6000"}, cfg.Swarm.AnnounceAddresses)
+
+	err = cfg.Set("swarm.announceAddresses", `["1.2.3.4:6000"]`)
+	assert.Error(err)
+}
+
+func TestSetRejectsInvalidPeeringPeers(t *testing.T) {
+	assert := assert.New(t)
+	cfg := NewDefaultConfig()
+
+	peerAddr := "/ip4/10.0.0.2/tcp/6000/ipfs/QmaCpDMGvV2BGHeYERUEnRQAwe3N8SzbUtfsmvsqQLuvuJ"
+	err := cfg.Set("peering.peers", `["`+peerAddr+`"]`)
+	assert.NoError(err)
+	assert.Equal([]string{peerAddr}, cfg.Peering.Peers)
+
+	err = cfg.Set("peering.peers", `["/ip4/10.0.0.2/tcp/6000"]`)
+	assert.Error(err)
+}
+
+func TestSetRejectsInvalidListenAddresses(t *testing.T) {
+	assert := assert.New(t)
+	cfg := NewDefaultConfig()
+
+	err := cfg.Set("swarm.listenAddresses", `["/ip4/0.0.0.0/tcp/6001/ws", "/ip6/::/tcp/6000"]`)
+	assert.NoError(err)
+	assert.Equal([]string{"/ip4/0.0.0.0/tcp/6001/ws", "/ip6/::/tcp/6000"}, cfg.Swarm.ListenAddresses)
+
+	err = cfg.Set("swarm.listenAddresses", `["/ip4/0.0.0.0/udp/6000/quic"]`)
+	assert.NoError(err)
+	assert.Equal([]string{"/ip4/0.0.0.0/udp/6000/quic"}, cfg.Swarm.ListenAddresses)
+
+	err = cfg.Set("swarm.listenAddresses", `["/ip4/0.0.0.0/udp/6000"]`)
+	assert.Error(err)
+	err = cfg.Set("swarm.listenAddresses", `["0.0.0.0:6000"]`)
+	assert.Error(err)
+}
+
+func TestSetRejectsInvalidAPIBasePath(t *testing.T) {
+	assert := assert.New(t)
+	cfg := NewDefaultConfig()
+
+	assert.NoError(cfg.Set("api.basePath", `"/filecoin"`))
+	assert.Equal("/filecoin", cfg.API.BasePath)
+	assert.NoError(cfg.Set("api.basePath", `""`))
+
+	assert.Error(cfg.Set("api.basePath", `"filecoin"`))
+	assert.Error(cfg.Set("api.basePath", `"/filecoin/"`))
+	assert.Error(cfg.Set("api.listenAddresses", `["0.0.0.0:3453"]`))
+}
+
+func TestSetRejectsInvalidRoles(t *testing.T) {
+	assert := assert.New(t)
+	cfg := NewDefaultConfig()
+
+	assert.Equal(RoleMiner, cfg.Role)
+	assert.NoError(cfg.Set("role", `"gateway"`))
+	assert.Equal(RoleGateway, cfg.Role)
+
+	assert.Error(cfg.Set("role", `"relay"`))
+	assert.Error(cfg.Set("role", `3`))
+}
+
+func TestSetRejectsInvalidDurations(t *testing.T) {
+	assert := assert.New(t)
+	cfg := NewDefaultConfig()
+
+	err := cfg.Set("mining.post.retryBackoff", `"1m30s"`)
+	assert.NoError(err)
+	err = cfg.Set("mining.post.retryBackoff", `"soon"`)
+	assert.Error(err)
+	err = cfg.Set("mining.post", `{"retryBackoff": "soon"}`)
+	assert.Error(err)
+	err = cfg.Set("mining.sealing.commitBatchWait", `"soon"`)
+	assert.Error(err)
+	err = cfg.Set("mining.scrub.interval", `"soon"`)
+	assert.Error(err)
+	err = cfg.Set("mining.packing.maxWait", `"soon"`)
+	assert.Error(err)
+	err = cfg.Set("swarm.connMgr.gracePeriod", `"soon"`)
+	assert.Error(err)
+	err = cfg.Set("pubsub.seenMessagesTTL", `"soon"`)
+	assert.Error(err)
+}
+
 func TestConfigRoundtrip(t *testing.T) {
 	assert := assert.New(t)
 
@@ -321,6 +522,89 @@ path = "mushroom-mushroom"}`
 	})
 }
 
+func TestSetRejectsInvalidLogLevels(t *testing.T) {
+	assert := assert.New(t)
+	cfg := NewDefaultConfig()
+
+	assert.NoError(cfg.Set("log.levels", `{"chain": "debug", "*": "warning"}`))
+	assert.Equal(map[string]string{"chain": "debug", "*": "warning"}, cfg.Log.Levels)
+
+	err := cfg.Set("log.levels", `{"chain": "verbose"}`)
+	assert.Contains(err.Error(), `invalid level "verbose" for chain`)
+}
+
+func TestSetRejectsInvalidBadgerOptions(t *testing.T) {
+	assert := assert.New(t)
+	cfg := NewDefaultConfig()
+
+	assert.NoError(cfg.Set("datastore.badger.valueLogFileSize", "268435456"))
+	assert.Equal(int64(268435456), cfg.Datastore.Badger.ValueLogFileSize)
+	assert.NoError(cfg.Set("datastore.badger.gcInterval", "0s"))
+
+	err := cfg.Set("datastore.badger.valueLogFileSize", "1024")
+	assert.EqualError(err, `"datastore.badger.valueLogFileSize" must be at least 1MiB and less than 2GiB`)
+	err = cfg.Set("datastore.badger.gcInterval", "often")
+	assert.Contains(err.Error(), `"datastore.badger.gcInterval" must be a duration`)
+}
+
+func TestSetRejectsUnknownDatastoreTypes(t *testing.T) {
+	assert := assert.New(t)
+	cfg := NewDefaultConfig()
+
+	assert.NoError(cfg.Set("datastore.type", "leveldb"))
+	assert.Equal("leveldb", cfg.Datastore.Type)
+	assert.NoError(cfg.Set("datastore.blocksType", "flatfs"))
+	assert.Equal("flatfs", cfg.Datastore.BlocksType)
+
+	err := cfg.Set("datastore.type", "flatfs")
+	assert.EqualError(err, `"datastore.type" must be badgerds or leveldb`)
+	err = cfg.Set("datastore.blocksType", "badgerds")
+	assert.EqualError(err, `"datastore.blocksType" must be empty or flatfs`)
+}
+
+func TestReloadChanges(t *testing.T) {
+	t.Run("lists the reloadable settings changed", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		cfg := NewDefaultConfig()
+		next := NewDefaultConfig()
+		next.Bootstrap.Period = "5m"
+		next.API.AccessControlAllowOrigin = []string{"*"}
+		next.Log.Levels["chain"] = "debug"
+		next.Mining.MineDelay = "2s"
+
+		changed, err := cfg.ReloadChanges(next)
+		require.NoError(err)
+		assert.Equal([]string{"api.accessControlAllowOrigin", "bootstrap.period", "log.levels", "mining.mineDelay"}, changed)
+
+		changed, err = cfg.ReloadChanges(NewDefaultConfig())
+		require.NoError(err)
+		assert.Empty(changed)
+	})
+
+	t.Run("rejects settings that require a restart", func(t *testing.T) {
+		assert := assert.New(t)
+
+		next := NewDefaultConfig()
+		next.Bootstrap.Period = "5m"
+		next.API.Address = "/ip4/127.0.0.1/tcp/4000"
+		next.Swarm.Address = "/ip4/0.0.0.0/tcp/7000"
+
+		_, err := NewDefaultConfig().ReloadChanges(next)
+		assert.EqualError(err, "changing api.address, swarm.address requires restarting the daemon")
+	})
+
+	t.Run("rejects invalid settings", func(t *testing.T) {
+		assert := assert.New(t)
+
+		next := NewDefaultConfig()
+		next.Mining.MineDelay = "soon"
+		_, err := NewDefaultConfig().ReloadChanges(next)
+		assert.Contains(err.Error(), `"mining.mineDelay" must be a duration`)
+	})
+}
+
 func createConfigFile(content string) (string, func(), error) {
 	dir, err := ioutil.TempDir("", "config")
 	if err != nil {
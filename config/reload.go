@@ -0,0 +1,115 @@
+package config
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+)
+
+// Reloadable lists the settings a running daemon applies when its config is
+// reloaded, with all the settings under them. Changing any other setting
+// requires restarting the daemon.
+var Reloadable = []string{
+	"api.accessControlAllowOrigin",
+	"api.accessControlAllowCredentials",
+	"api.accessControlAllowMethods",
+	"bootstrap",
+	"log",
+	"mining.mineDelay",
+}
+
+// ReloadChanges returns the dotted keys of the settings next changes from
+// cfg. It returns an error naming the settings that can't be reloaded if
+// next changes any, or if a changed setting is invalid.
+func (cfg *Config) ReloadChanges(next *Config) ([]string, error) {
+	changed, err := cfg.diff(next)
+	if err != nil {
+		return nil, err
+	}
+
+	var restart []string
+	for key := range changed {
+		if !reloadable(key) {
+			restart = append(restart, key)
+		}
+	}
+	if len(restart) > 0 {
+		sort.Strings(restart)
+		return nil, errors.Errorf("changing %s requires restarting the daemon", strings.Join(restart, ", "))
+	}
+
+	keys := make([]string, 0, len(changed))
+	for key, value := range changed {
+		if err := validate(key, value); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func reloadable(key string) bool {
+	for _, r := range Reloadable {
+		if key == r || strings.HasPrefix(key, r+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// diff returns the settings whose values differ between cfg and next,
+// mapped to their JSON in next, "null" for the settings next removes.
+func (cfg *Config) diff(next *Config) (map[string]string, error) {
+	before, err := flatten(cfg)
+	if err != nil {
+		return nil, err
+	}
+	after, err := flatten(next)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := map[string]string{}
+	for key, value := range after {
+		if before[key] != value {
+			changed[key] = value
+		}
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			changed[key] = "null"
+		}
+	}
+	return changed, nil
+}
+
+// flatten maps the dotted keys of the settings of cfg to their JSON. Lists,
+// and settings with a validator, are kept whole.
+func flatten(cfg *Config) (map[string]string, error) {
+	raw, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	flat := map[string]string{}
+	flattenInto(flat, "", raw)
+	return flat, nil
+}
+
+func flattenInto(flat map[string]string, prefix string, raw json.RawMessage) {
+	var obj map[string]json.RawMessage
+	_, validated := Validators[prefix]
+	if validated || json.Unmarshal(raw, &obj) != nil {
+		flat[prefix] = string(raw)
+		return
+	}
+	for key, value := range obj {
+		dottedKey := key
+		if prefix != "" {
+			dottedKey = prefix + "." + key
+		}
+		flattenInto(flat, dottedKey, value)
+	}
+}
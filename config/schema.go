@@ -0,0 +1,362 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// CheckFile checks the config file strictly: every key must be a setting of
+// Config and every value of the type of its setting. ReadFile ignores unknown
+// keys, e.g. misspelled ones, which the daemon checks for with CheckFile
+// before it starts. A missing file passes.
+func CheckFile(file string) error {
+	raw, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return nil
+	}
+
+	if err := checkJSON("", raw, reflect.TypeOf(Config{})); err != nil {
+		return errors.Wrapf(err, "invalid config file %s", file)
+	}
+	return nil
+}
+
+// startValidators validate the settings the daemon needs to be valid to
+// start, besides the Validators, which config set accepts anything for.
+var startValidators = map[string]func(string, string) error{
+	"api.address":         validateMultiaddr,
+	"swarm.address":       validateMultiaddr,
+	"bootstrap.addresses": validatePeerAddrs,
+}
+
+// Validate runs the Validators of all the settings of cfg, and the
+// validations of the settings the daemon needs to start.
+func (cfg *Config) Validate() error {
+	raw, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	var settings map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &settings); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := validate(key, string(settings[key])); err != nil {
+			return err
+		}
+	}
+
+	keys = keys[:0]
+	for key := range startValidators {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value, err := cfg.Get(key)
+		if err != nil {
+			return err
+		}
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		if err := startValidators[key](key, string(raw)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkJSON checks that raw, the value of the setting with the given dotted
+// key, is of type t, and if t is a section of the config, that all its keys
+// are settings of the section.
+func checkJSON(key string, raw json.RawMessage, t reflect.Type) error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		return nil
+	}
+
+	if isSection(t) {
+		var settings map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &settings); err != nil {
+			return typeError(key, t)
+		}
+		for name, value := range settings {
+			f, ok := fieldByJSONName(t, name)
+			if !ok {
+				return unknownKeyError(key, name, t)
+			}
+			if err := checkJSON(joinKey(key, name), value, f.Type); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	elem := t
+	for elem.Kind() == reflect.Slice || elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	if t.Kind() == reflect.Slice && isSection(elem) {
+		var values []json.RawMessage
+		if err := json.Unmarshal(raw, &values); err != nil {
+			return typeError(key, t)
+		}
+		for _, value := range values {
+			if err := checkJSON(key, value, t.Elem()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := json.Unmarshal(raw, reflect.New(t).Interface()); err != nil {
+		return typeError(key, t)
+	}
+	return nil
+}
+
+// isSection returns true if values of t are json objects whose keys are the
+// settings under them.
+func isSection(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && !reflect.PtrTo(t).Implements(jsonUnmarshalerType)
+}
+
+func typeError(key string, t reflect.Type) error {
+	return errors.Errorf(`"%s" must be %s`, key, describeType(t))
+}
+
+func unknownKeyError(section, name string, t reflect.Type) error {
+	key := joinKey(section, name)
+	names := jsonNames(t)
+
+	best, bestDist := "", 3
+	for _, n := range names {
+		if d := editDistance(strings.ToLower(name), strings.ToLower(n)); d < bestDist {
+			best, bestDist = n, d
+		}
+	}
+	if best != "" {
+		return errors.Errorf(`unknown key "%s", did you mean "%s"?`, key, joinKey(section, best))
+	}
+	if section == "" {
+		return errors.Errorf(`unknown key "%s", the config has the keys %s`, key, strings.Join(names, ", "))
+	}
+	return errors.Errorf(`unknown key "%s", "%s" has the keys %s`, key, section, strings.Join(names, ", "))
+}
+
+func joinKey(section, name string) string {
+	if section == "" {
+		return name
+	}
+	return section + "." + name
+}
+
+func jsonName(f reflect.StructField) string {
+	return strings.Split(f.Tag.Get("json"), ",")[0]
+}
+
+func fieldByJSONName(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		if jsonName(t.Field(i)) == name {
+			return t.Field(i), true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// jsonNames returns the keys of the settings of the section t, sorted.
+func jsonNames(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		if name := jsonName(t.Field(i)); name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// describeType describes the json values of type t.
+func describeType(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t {
+	case reflect.TypeOf(address.Address{}):
+		return "an address"
+	case reflect.TypeOf(types.AttoFIL{}):
+		return "an amount of attoFIL"
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "an integer"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "a non-negative integer"
+	case reflect.Slice:
+		return "a list of " + plural(describeType(t.Elem()))
+	case reflect.Map:
+		return "a map of strings to " + plural(describeType(t.Elem()))
+	default:
+		return "an object"
+	}
+}
+
+// plural turns the description of a type, e.g. "an integer", into the
+// description of several values of it, "integers".
+func plural(desc string) string {
+	desc = strings.TrimPrefix(strings.TrimPrefix(desc, "a "), "an ")
+	if strings.HasPrefix(desc, "list of ") || strings.HasPrefix(desc, "map of ") {
+		return desc
+	}
+	if strings.HasPrefix(desc, "amount of ") {
+		return "amounts" + strings.TrimPrefix(desc, "amount")
+	}
+	if strings.HasSuffix(desc, "s") {
+		return desc + "es"
+	}
+	return desc + "s"
+}
+
+// editDistance returns the Levenshtein distance of a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// KeyDoc documents a setting of the config.
+type KeyDoc struct {
+	Key string `json:"key"`
+	// Type describes the json values of the setting.
+	Type string `json:"type"`
+	// Default is the json value of the setting in the default config.
+	Default json.RawMessage `json:"default"`
+	// Description is the doc tag of the setting.
+	Description string `json:"description"`
+	// Keys are the settings under a section.
+	Keys []string `json:"keys,omitempty"`
+}
+
+// Doc documents the setting with the given dotted key, from the doc tags of
+// the fields of Config.
+func Doc(key string) (*KeyDoc, error) {
+	t := reflect.TypeOf(Config{})
+	def := reflect.ValueOf(*NewDefaultConfig())
+	var field reflect.StructField
+	section := ""
+	for _, name := range strings.Split(key, ".") {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+			def = reflect.Indirect(def)
+		}
+		if !isSection(t) {
+			return nil, errors.Errorf(`unknown key "%s", "%s" has no keys under it`, key, section)
+		}
+		f, ok := fieldByJSONName(t, name)
+		if !ok {
+			return nil, unknownKeyError(section, name, t)
+		}
+		field = f
+		t = f.Type
+		// settings under a section missing from the default config, e.g.
+		// wallet.remoteSigner, default to null
+		if def.IsValid() {
+			def = def.FieldByIndex(f.Index)
+		}
+		section = joinKey(section, name)
+	}
+
+	rawDefault := json.RawMessage("null")
+	if def.IsValid() {
+		var err error
+		if rawDefault, err = json.Marshal(def.Interface()); err != nil {
+			return nil, err
+		}
+	}
+
+	doc := &KeyDoc{
+		Key:         key,
+		Type:        describeType(t),
+		Default:     rawDefault,
+		Description: field.Tag.Get("doc"),
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if isSection(t) {
+		for _, name := range jsonNames(t) {
+			doc.Keys = append(doc.Keys, joinKey(key, name))
+		}
+	}
+	return doc, nil
+}
+
+// String formats the documentation of the setting for the command line.
+func (d *KeyDoc) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\ntype:    %s\ndefault: %s\n", d.Key, d.Type, d.Default)
+	if d.Description != "" {
+		fmt.Fprintf(&b, "\n%s\n", d.Description)
+	}
+	if len(d.Keys) > 0 {
+		fmt.Fprintf(&b, "\nkeys:\n  %s\n", strings.Join(d.Keys, "\n  "))
+	}
+	return b.String()
+}
@@ -0,0 +1,184 @@
+package config
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfigFile(t *testing.T, content string) (string, func()) {
+	dir, err := ioutil.TempDir("", "config")
+	require.NoError(t, err)
+	file := filepath.Join(dir, "config.json")
+	require.NoError(t, ioutil.WriteFile(file, []byte(content), 0644))
+	return file, func() { os.RemoveAll(dir) } // nolint: errcheck
+}
+
+func TestCheckFile(t *testing.T) {
+	t.Run("default config passes", func(t *testing.T) {
+		assert := assert.New(t)
+
+		dir, err := ioutil.TempDir("", "config")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		file := filepath.Join(dir, "config.json")
+		require.NoError(t, NewDefaultConfig().WriteFile(file))
+		assert.NoError(CheckFile(file))
+	})
+
+	t.Run("missing and empty files pass", func(t *testing.T) {
+		assert := assert.New(t)
+
+		assert.NoError(CheckFile(filepath.Join(os.TempDir(), "no-such-config.json")))
+
+		file, cleanup := writeConfigFile(t, "")
+		defer cleanup()
+		assert.NoError(CheckFile(file))
+	})
+
+	t.Run("suggests the key a misspelled one is closest to", func(t *testing.T) {
+		file, cleanup := writeConfigFile(t, `{"api": {"adress": "/ip4/127.0.0.1/tcp/3453"}}`)
+		defer cleanup()
+
+		err := CheckFile(file)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `unknown key "api.adress", did you mean "api.address"?`)
+	})
+
+	t.Run("lists the keys of the section of an unknown key", func(t *testing.T) {
+		file, cleanup := writeConfigFile(t, `{"bootstrap": {"peers": []}}`)
+		defer cleanup()
+
+		err := CheckFile(file)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `unknown key "bootstrap.peers", "bootstrap" has the keys addresses, minPeerThreshold, period`)
+	})
+
+	t.Run("checks the keys of nested sections", func(t *testing.T) {
+		file, cleanup := writeConfigFile(t, `{"swarm": {"connMgr": {"lowWatr": 10}}}`)
+		defer cleanup()
+
+		err := CheckFile(file)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `did you mean "swarm.connMgr.lowWater"?`)
+	})
+
+	t.Run("checks the types of values", func(t *testing.T) {
+		assert := assert.New(t)
+
+		file, cleanup := writeConfigFile(t, `{"bootstrap": {"minPeerThreshold": "3"}}`)
+		defer cleanup()
+		err := CheckFile(file)
+		require.Error(t, err)
+		assert.Contains(err.Error(), `"bootstrap.minPeerThreshold" must be an integer`)
+
+		file, cleanup = writeConfigFile(t, `{"bootstrap": {"addresses": "/ip4/127.0.0.1/tcp/6000"}}`)
+		defer cleanup()
+		err = CheckFile(file)
+		require.Error(t, err)
+		assert.Contains(err.Error(), `"bootstrap.addresses" must be a list of strings`)
+
+		file, cleanup = writeConfigFile(t, `{"mining": {"minerAddress": 12}}`)
+		defer cleanup()
+		err = CheckFile(file)
+		require.Error(t, err)
+		assert.Contains(err.Error(), `"mining.minerAddress" must be an address`)
+	})
+
+	t.Run("null sections pass", func(t *testing.T) {
+		file, cleanup := writeConfigFile(t, `{"wallet": null}`)
+		defer cleanup()
+
+		assert.NoError(t, CheckFile(file))
+	})
+}
+
+func TestValidate(t *testing.T) {
+	t.Run("default config is valid", func(t *testing.T) {
+		assert.NoError(t, NewDefaultConfig().Validate())
+	})
+
+	t.Run("runs the validators of all the settings", func(t *testing.T) {
+		cfg := NewDefaultConfig()
+		cfg.Heartbeat.Nickname = "bad nick<p>"
+
+		err := cfg.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "heartbeat")
+	})
+
+	t.Run("rejects invalid api and swarm addresses", func(t *testing.T) {
+		cfg := NewDefaultConfig()
+		cfg.API.Address = ":1234"
+		assert.Error(t, cfg.Validate())
+
+		cfg = NewDefaultConfig()
+		cfg.Swarm.Address = "localhost:6000"
+		assert.Error(t, cfg.Validate())
+	})
+
+	t.Run("rejects bootstrap addresses without peer ids", func(t *testing.T) {
+		cfg := NewDefaultConfig()
+		cfg.Bootstrap.Addresses = []string{"/ip4/127.0.0.1/tcp/6000"}
+		assert.Error(t, cfg.Validate())
+	})
+}
+
+func TestDoc(t *testing.T) {
+	t.Run("documents a setting", func(t *testing.T) {
+		assert := assert.New(t)
+
+		doc, err := Doc("api.address")
+		require.NoError(t, err)
+		assert.Equal("api.address", doc.Key)
+		assert.Equal("a string", doc.Type)
+		assert.Equal(`"/ip4/127.0.0.1/tcp/3453"`, string(doc.Default))
+		assert.Contains(doc.Description, "multiaddr")
+		assert.Empty(doc.Keys)
+	})
+
+	t.Run("documents a section", func(t *testing.T) {
+		assert := assert.New(t)
+
+		doc, err := Doc("bootstrap")
+		require.NoError(t, err)
+		assert.Equal("an object", doc.Type)
+		assert.Equal([]string{"bootstrap.addresses", "bootstrap.minPeerThreshold", "bootstrap.period"}, doc.Keys)
+
+		var def BootstrapConfig
+		require.NoError(t, json.Unmarshal(doc.Default, &def))
+		assert.Equal(*newDefaultBootstrapConfig(), def)
+	})
+
+	t.Run("every setting has a description", func(t *testing.T) {
+		var check func(key string)
+		check = func(key string) {
+			doc, err := Doc(key)
+			require.NoError(t, err)
+			assert.NotEmpty(t, doc.Description, key)
+			for _, k := range doc.Keys {
+				check(k)
+			}
+		}
+		for _, key := range jsonNames(reflect.TypeOf(Config{})) {
+			check(key)
+		}
+	})
+
+	t.Run("unknown keys", func(t *testing.T) {
+		_, err := Doc("api.adress")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `did you mean "api.address"?`)
+
+		_, err = Doc("api.address.port")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `"api.address" has no keys under it`)
+	})
+}
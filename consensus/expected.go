@@ -133,6 +133,9 @@ func (c *Expected) validateBlockStructure(ctx context.Context, b *types.Block) e
 	if !b.StateRoot.Defined() {
 		return fmt.Errorf("block has nil StateRoot")
 	}
+	if err := b.VerifyBLSAggregate(); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -250,8 +253,21 @@ func (c *Expected) RunStateTransition(ctx context.Context, ts types.TipSet, ance
 		}
 	}
 
-	vms := vm.NewStorageMap(c.bstore)
-	st, err := c.runMessages(ctx, pSt, vms, ts, ancestors)
+	// The state objects written while running the messages are buffered and
+	// only reach the blockstore once the tipset turns out valid.
+	bs := vm.NewBufferedBlockstore(c.bstore)
+	cst, stateBlocks := state.NewBufferedCborStore(c.cstore)
+	pRoot, err := pSt.Flush(ctx)
+	if err != nil {
+		return nil, err
+	}
+	st, err := state.LoadStateTree(ctx, cst, pRoot, builtin.Actors)
+	if err != nil {
+		return nil, err
+	}
+
+	vms := vm.NewStorageMap(bs)
+	st, err = c.runMessages(ctx, cst, st, vms, ts, ancestors)
 	if err != nil {
 		return nil, err
 	}
@@ -259,7 +275,17 @@ func (c *Expected) RunStateTransition(ctx context.Context, ts types.TipSet, ance
 	if err != nil {
 		return nil, err
 	}
-	return st, nil
+	root, err := st.Flush(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := bs.Flush(); err != nil {
+		return nil, errors.Wrap(err, "failed to flush actor storage")
+	}
+	if err := stateBlocks.Flush(); err != nil {
+		return nil, errors.Wrap(err, "failed to flush state tree")
+	}
+	return state.LoadStateTree(ctx, c.cstore, root, builtin.Actors)
 }
 
 // validateMining checks validity of the block ticket, proof, and miner address.
@@ -381,7 +407,7 @@ func CreateTicket(proof proofs.PoStProof, minerAddr address.Address) []byte {
 // An error is returned if individual blocks contain messages that do not
 // lead to successful state transitions.  An error is also returned if the node
 // faults while running aggregate state computation.
-func (c *Expected) runMessages(ctx context.Context, st state.Tree, vms vm.StorageMap, ts types.TipSet, ancestors []types.TipSet) (state.Tree, error) {
+func (c *Expected) runMessages(ctx context.Context, cst *hamt.CborIpldStore, st state.Tree, vms vm.StorageMap, ts types.TipSet, ancestors []types.TipSet) (state.Tree, error) {
 	var cpySt state.Tree
 
 	// TODO: order blocks in the tipset by ticket
@@ -392,7 +418,7 @@ func (c *Expected) runMessages(ctx context.Context, st state.Tree, vms vm.Storag
 			return nil, errors.Wrap(err, "error validating block state")
 		}
 		// state copied so changes don't propagate between block validations
-		cpySt, err = state.LoadStateTree(ctx, c.cstore, cpyCid, builtin.Actors)
+		cpySt, err = state.LoadStateTree(ctx, cst, cpyCid, builtin.Actors)
 		if err != nil {
 			return nil, errors.Wrap(err, "error validating block state")
 		}
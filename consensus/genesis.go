@@ -9,7 +9,9 @@ import (
 	"github.com/filecoin-project/go-filecoin/actor"
 	"github.com/filecoin-project/go-filecoin/actor/builtin"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/account"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/cron"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/paymentbroker"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/reward"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/storagemarket"
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/state"
@@ -183,5 +185,25 @@ func SetupDefaultActors(ctx context.Context, st state.Tree, storageMap vm.Storag
 
 	pbAct.Balance = types.NewAttoFILFromFIL(0)
 
-	return st.SetActor(ctx, address.PaymentBrokerAddress, pbAct)
+	if err := st.SetActor(ctx, address.PaymentBrokerAddress, pbAct); err != nil {
+		return err
+	}
+
+	rewardAct := reward.NewActor()
+	err = (&reward.Actor{}).InitializeState(storageMap.NewStorage(address.RewardAddress, rewardAct), nil)
+	if err != nil {
+		return err
+	}
+
+	if err := st.SetActor(ctx, address.RewardAddress, rewardAct); err != nil {
+		return err
+	}
+
+	cronAct := cron.NewActor()
+	err = (&cron.Actor{}).InitializeState(storageMap.NewStorage(address.CronAddress, cronAct), nil)
+	if err != nil {
+		return err
+	}
+
+	return st.SetActor(ctx, address.CronAddress, cronAct)
 }
@@ -0,0 +1,182 @@
+package consensus
+
+import (
+	"context"
+	"sync"
+
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/state"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/vm"
+	"github.com/filecoin-project/go-filecoin/vm/errors"
+)
+
+// Messages of a block are executed in parallel optimistically: each message
+// is executed over the state the block starts from while recording the
+// actors it reads. The executions are then applied in block order. An
+// execution is only kept if none of the actors it read were touched by the
+// messages applied before it, otherwise the message is executed again over
+// the current state. Either way the resulting state is the one executing the
+// messages one after the other leads to.
+
+// recordingTree is a view of a state tree recording the addresses of the
+// actors read through it. Access to the underlying tree is serialized with
+// mu, so messages may execute concurrently over the same tree. Actors are
+// copied on read so that concurrent executions never share them.
+//
+// The VM only reads actors by address, walking the actors of the tree is not
+// recorded.
+type recordingTree struct {
+	state.Tree
+	mu    *sync.Mutex
+	reads map[address.Address]struct{}
+}
+
+func newRecordingTree(st state.Tree, mu *sync.Mutex) *recordingTree {
+	return &recordingTree{
+		Tree:  st,
+		mu:    mu,
+		reads: make(map[address.Address]struct{}),
+	}
+}
+
+// GetActor reads the actor at a from the underlying tree.
+func (t *recordingTree) GetActor(ctx context.Context, a address.Address) (*actor.Actor, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.reads[a] = struct{}{}
+	act, err := t.Tree.GetActor(ctx, a)
+	if err != nil {
+		return nil, err
+	}
+	cpy := *act
+	return &cpy, nil
+}
+
+// GetOrCreateActor reads the actor at a from the underlying tree, or creates
+// it.
+func (t *recordingTree) GetOrCreateActor(ctx context.Context, a address.Address, c func() (*actor.Actor, error)) (*actor.Actor, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.reads[a] = struct{}{}
+	act, err := t.Tree.GetOrCreateActor(ctx, a, c)
+	if err != nil {
+		return nil, err
+	}
+	cpy := *act
+	return &cpy, nil
+}
+
+// SetActor sets the actor at a in the underlying tree.
+func (t *recordingTree) SetActor(ctx context.Context, a address.Address, act *actor.Actor) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.reads[a] = struct{}{}
+	return t.Tree.SetActor(ctx, a, act)
+}
+
+// readAny returns true if any of the actors at addrs were read.
+func (t *recordingTree) readAny(addrs map[address.Address]struct{}) bool {
+	for a := range t.reads {
+		if _, ok := addrs[a]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// addReads adds the addresses of the actors read to addrs.
+func (t *recordingTree) addReads(addrs map[address.Address]struct{}) {
+	for a := range t.reads {
+		addrs[a] = struct{}{}
+	}
+}
+
+// speculation is the execution of a message over the state a block starts
+// from, before the message is applied.
+type speculation struct {
+	tree            *recordingTree
+	cachedStateTree *state.CachedTree
+	vms             vm.StorageMap
+	gasTracker      *vm.GasTracker
+	receipt         *types.MessageReceipt
+	err             error
+}
+
+// speculate executes messages concurrently over st, without changing it, and
+// returns their executions in order. Messages whose sender sent an earlier
+// message of the block are not executed, their execution would read the
+// sender after it was changed.
+func (p *DefaultProcessor) speculate(ctx context.Context, st state.Tree, vms vm.StorageMap, messages []*types.SignedMessage, bh *types.BlockHeight, ancestors []types.TipSet) ([]*speculation, error) {
+	// The executions see actor storage staged for the block through the
+	// blockstore.
+	if err := vms.Flush(); err != nil {
+		return nil, errors.FaultErrorWrap(err, "failed to flush actor storage")
+	}
+
+	specs := make([]*speculation, len(messages))
+	senders := make(map[address.Address]struct{})
+	work := make(chan int)
+	go func() {
+		defer close(work)
+		for i, msg := range messages {
+			if _, ok := senders[msg.From]; ok {
+				continue
+			}
+			senders[msg.From] = struct{}{}
+			work <- i
+		}
+	}()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for w := 0; w < p.parallelism; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				s := &speculation{
+					tree:       newRecordingTree(st, &mu),
+					vms:        vms.Fork(),
+					gasTracker: vm.NewGasTracker(),
+				}
+				s.cachedStateTree = state.NewCachedStateTree(s.tree)
+				s.receipt, s.err = p.attemptApplyMessage(ctx, s.cachedStateTree, s.vms, messages[i], bh, s.gasTracker, ancestors, nil)
+				specs[i] = s
+			}
+		}()
+	}
+	wg.Wait()
+
+	return specs, nil
+}
+
+// commitSpeculation applies msg to st, keeping its execution s unless s read
+// actors in committed, the actors touched by the messages applied before it.
+// It adds the actors msg touches to committed.
+func (p *DefaultProcessor) commitSpeculation(ctx context.Context, st state.Tree, vms vm.StorageMap, msg *types.SignedMessage, s *speculation, committed map[address.Address]struct{}, minerAddr address.Address, bh *types.BlockHeight, gasTracker *vm.GasTracker, ancestors []types.TipSet) (*ApplicationResult, error) {
+	// The execution did not account for the gas the block has used so far.
+	gasTracker.ResetForNewMessage(msg.MeteredMessage)
+	if s == nil || s.tree.readAny(committed) || errors.IsFault(s.err) || blockGasLimitError(gasTracker) != nil {
+		tree := newRecordingTree(st, &sync.Mutex{})
+		r, err := p.applyMessage(ctx, tree, vms, msg, minerAddr, bh, gasTracker, ancestors, nil)
+		tree.addReads(committed)
+		return r, err
+	}
+
+	gasTracker.Merge(s.gasTracker)
+	r, err := p.finishMessage(ctx, s.tree, s.cachedStateTree, msg, minerAddr, s.receipt, s.err)
+	s.tree.addReads(committed)
+	if err == nil && s.err == nil {
+		// The actor storage the message changed must be visible to the
+		// messages after it.
+		if err := s.vms.Flush(); err != nil {
+			return nil, errors.FaultErrorWrap(err, "failed to flush actor storage")
+		}
+	}
+	return r, err
+}
@@ -2,12 +2,19 @@ package consensus
 
 import (
 	"context"
+	"fmt"
 	"math/big"
+	"runtime"
 	"time"
 
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+
+	"github.com/filecoin-project/go-filecoin/abi"
 	"github.com/filecoin-project/go-filecoin/actor"
-	"github.com/filecoin-project/go-filecoin/actor/builtin/account"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/reward"
 	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/migration"
 	"github.com/filecoin-project/go-filecoin/state"
 	"github.com/filecoin-project/go-filecoin/types"
 	"github.com/filecoin-project/go-filecoin/vm"
@@ -23,8 +30,8 @@ type SignedMessageValidator interface {
 
 // BlockRewarder applies all rewards due to the miner for processing a block including block reward and gas
 type BlockRewarder interface {
-	// BlockReward pays out the mining reward
-	BlockReward(ctx context.Context, st state.Tree, minerAddr address.Address) error
+	// BlockReward pays out the mining reward for a block at height bh
+	BlockReward(ctx context.Context, st state.Tree, vms vm.StorageMap, minerAddr address.Address, bh *types.BlockHeight) error
 
 	// GasReward pays gas from the sender to the miner
 	GasReward(ctx context.Context, st state.Tree, minerAddr address.Address, msg *types.SignedMessage, cost *types.AttoFIL) error
@@ -52,6 +59,8 @@ type ProcessTipSetResponse struct {
 type DefaultProcessor struct {
 	signedMessageValidator SignedMessageValidator
 	blockRewarder          BlockRewarder
+	// parallelism is how many messages of a block are executed at once.
+	parallelism int
 }
 
 var _ Processor = (*DefaultProcessor)(nil)
@@ -61,6 +70,7 @@ func NewDefaultProcessor() *DefaultProcessor {
 	return &DefaultProcessor{
 		signedMessageValidator: NewDefaultMessageValidator(),
 		blockRewarder:          NewDefaultBlockRewarder(),
+		parallelism:            runtime.NumCPU(),
 	}
 }
 
@@ -69,9 +79,16 @@ func NewConfiguredProcessor(validator SignedMessageValidator, rewarder BlockRewa
 	return &DefaultProcessor{
 		signedMessageValidator: validator,
 		blockRewarder:          rewarder,
+		parallelism:            runtime.NumCPU(),
 	}
 }
 
+// SetParallelism sets how many messages of a block the processor executes at
+// once. With n = 1 messages are executed one after the other.
+func (p *DefaultProcessor) SetParallelism(n int) {
+	p.parallelism = n
+}
+
 // ProcessBlock is the entrypoint for validating the state transitions
 // of the messages in a block. When we receive a new block from the
 // network ProcessBlock applies the block's messages to the beginning
@@ -108,6 +125,9 @@ func (p *DefaultProcessor) ProcessBlock(ctx context.Context, st state.Tree, vms
 	}()
 
 	bh := types.NewBlockHeight(uint64(blk.Height))
+	if err := p.MigrateState(ctx, st, vms, bh, ancestors); err != nil {
+		return emptyResults, err
+	}
 	res, faultErr := p.ApplyMessagesAndPayRewards(ctx, st, vms, blk.Messages, blk.Miner, bh, ancestors)
 	if faultErr != nil {
 		return emptyResults, faultErr
@@ -118,6 +138,10 @@ func (p *DefaultProcessor) ProcessBlock(ctx context.Context, st state.Tree, vms
 	if len(res.TemporaryErrors) > 0 {
 		return emptyResults, res.TemporaryErrors[0]
 	}
+	// the block is processed as a tipset of its own, which ends its epoch.
+	if err := p.RunCron(ctx, st, vms, bh); err != nil {
+		return emptyResults, err
+	}
 	return res.Results, nil
 }
 
@@ -131,6 +155,61 @@ func (p *DefaultProcessor) ProcessBlock(ctx context.Context, st state.Tree, vms
 // TipSet containing conflicting messages and are ignored.  Blocks are applied
 // in the sorted order of their tickets.
 func (p *DefaultProcessor) ProcessTipSet(ctx context.Context, st state.Tree, vms vm.StorageMap, ts types.TipSet, ancestors []types.TipSet) (*ProcessTipSetResponse, error) {
+	return p.processTipSet(ctx, st, vms, ts, ancestors, nil)
+}
+
+// ReplayMessage processes the tipset ts over the state st it was mined on,
+// like ProcessTipSet, tracing the execution of the message with cid msgCid.
+// It returns the result of applying the message and its trace. If the message
+// could not be applied, e.g. because it conflicts with an earlier message of
+// the tipset, the application error is returned along with the trace.
+func (p *DefaultProcessor) ReplayMessage(ctx context.Context, st state.Tree, vms vm.StorageMap, ts types.TipSet, ancestors []types.TipSet, msgCid cid.Cid) (*ApplicationResult, *vm.Trace, error) {
+	tracer := &messageTracer{msgCid: msgCid}
+	if _, err := p.processTipSet(ctx, st, vms, ts, ancestors, tracer); err != nil {
+		return nil, nil, err
+	}
+	if tracer.trace == nil {
+		return nil, nil, fmt.Errorf("message %s not in tipset", msgCid)
+	}
+	return tracer.result, tracer.trace, tracer.err
+}
+
+// messageTracer selects the message whose execution is traced while
+// processing messages, and records the outcome of applying it.
+type messageTracer struct {
+	msgCid cid.Cid
+	trace  *vm.Trace
+	result *ApplicationResult
+	err    error
+}
+
+// traceFor returns the trace to record the execution of msg into, or nil if
+// msg is not traced.
+func (mt *messageTracer) traceFor(msgCid cid.Cid, msg *types.SignedMessage) *vm.Trace {
+	if mt == nil || !mt.msgCid.Equals(msgCid) {
+		return nil
+	}
+	mt.trace = &vm.Trace{
+		From:   msg.From,
+		To:     msg.To,
+		Method: msg.Method,
+		Value:  msg.Value,
+		Params: msg.Params,
+	}
+	return mt.trace
+}
+
+// record keeps the outcome of applying msg if it is the traced message.
+func (mt *messageTracer) record(msg *types.SignedMessage, r *ApplicationResult, err error) {
+	if mt == nil {
+		return
+	}
+	if c, cerr := msg.Cid(); cerr == nil && c.Equals(mt.msgCid) {
+		mt.result, mt.err = r, err
+	}
+}
+
+func (p *DefaultProcessor) processTipSet(ctx context.Context, st state.Tree, vms vm.StorageMap, ts types.TipSet, ancestors []types.TipSet, tracer *messageTracer) (*ProcessTipSetResponse, error) {
 	var res ProcessTipSetResponse
 	var emptyRes ProcessTipSetResponse
 	h, err := ts.Height()
@@ -140,6 +219,10 @@ func (p *DefaultProcessor) ProcessTipSet(ctx context.Context, st state.Tree, vms
 	bh := types.NewBlockHeight(h)
 	msgFilter := make(map[string]struct{})
 
+	if err := p.MigrateState(ctx, st, vms, bh, ancestors); err != nil {
+		return &emptyRes, err
+	}
+
 	tips := ts.ToSlice()
 	types.SortBlocks(tips)
 
@@ -162,7 +245,7 @@ func (p *DefaultProcessor) ProcessTipSet(ctx context.Context, st state.Tree, vms
 			// TODO is there ever a reason to try a duplicate failed message again within the same tipset?
 			msgFilter[mCid.String()] = struct{}{}
 		}
-		amRes, err := p.ApplyMessagesAndPayRewards(ctx, st, vms, msgs, blk.Miner, bh, ancestors)
+		amRes, err := p.applyMessagesAndPayRewards(ctx, st, vms, msgs, blk.Miner, bh, ancestors, tracer)
 		if err != nil {
 			return &emptyRes, err
 		}
@@ -190,6 +273,10 @@ func (p *DefaultProcessor) ProcessTipSet(ctx context.Context, st state.Tree, vms
 		}
 	}
 
+	if err := p.RunCron(ctx, st, vms, bh); err != nil {
+		return &emptyRes, err
+	}
+
 	return &res, nil
 }
 
@@ -249,12 +336,18 @@ func (p *DefaultProcessor) ProcessTipSet(ctx context.Context, st state.Tree, vms
 //   - send to self: permanently unapplyable (don't include in a block, revert changes,
 //       discard)
 //   - transfer negative value: permanently unapplyable (as above)
+//   - address of a protocol without account creation policy: permanently
+//       unapplyable (as above)
 //   - all other vmerrors: successfully applied! Include in the block and
 //       revert changes. Necessarily all vm errors that are not faults are
 //       revert errors.
 //   - everything else: successfully applied (include, keep changes)
 //
 func (p *DefaultProcessor) ApplyMessage(ctx context.Context, st state.Tree, vms vm.StorageMap, msg *types.SignedMessage, minerAddr address.Address, bh *types.BlockHeight, gasTracker *vm.GasTracker, ancestors []types.TipSet) (*ApplicationResult, error) {
+	return p.applyMessage(ctx, st, vms, msg, minerAddr, bh, gasTracker, ancestors, nil)
+}
+
+func (p *DefaultProcessor) applyMessage(ctx context.Context, st state.Tree, vms vm.StorageMap, msg *types.SignedMessage, minerAddr address.Address, bh *types.BlockHeight, gasTracker *vm.GasTracker, ancestors []types.TipSet, tracer *messageTracer) (*ApplicationResult, error) {
 
 	// used for log timer call below
 	msgCid, err := msg.Cid()
@@ -269,7 +362,14 @@ func (p *DefaultProcessor) ApplyMessage(ctx context.Context, st state.Tree, vms
 
 	cachedStateTree := state.NewCachedStateTree(st)
 
-	r, err := p.attemptApplyMessage(ctx, cachedStateTree, vms, msg, bh, gasTracker, ancestors)
+	r, err := p.attemptApplyMessage(ctx, cachedStateTree, vms, msg, bh, gasTracker, ancestors, tracer.traceFor(msgCid, msg))
+	return p.finishMessage(ctx, st, cachedStateTree, msg, minerAddr, r, err)
+}
+
+// finishMessage commits the state changes of a message attempted over
+// cachedStateTree to st unless err says to revert them, pays the gas to the
+// miner and increments the nonce of the sender.
+func (p *DefaultProcessor) finishMessage(ctx context.Context, st state.Tree, cachedStateTree *state.CachedTree, msg *types.SignedMessage, minerAddr address.Address, r *types.MessageReceipt, err error) (*ApplicationResult, error) {
 	if err == nil {
 		err = cachedStateTree.Commit(ctx)
 		if err != nil {
@@ -321,16 +421,17 @@ func (p *DefaultProcessor) ApplyMessage(ctx context.Context, st state.Tree, vms
 var (
 	// These errors are only to be used by ApplyMessage; they shouldn't be
 	// used in any other context as they are an implementation detail.
-	errFromAccountNotFound       = errors.NewRevertError("from (sender) account not found")
-	errGasAboveBlockLimit        = errors.NewRevertError("message gas limit above block gas limit")
-	errGasTooHighForCurrentBlock = errors.NewRevertError("message gas limit too high for current block")
-	errNonceTooHigh              = errors.NewRevertError("nonce too high")
-	errNonceTooLow               = errors.NewRevertError("nonce too low")
-	errNonAccountActor           = errors.NewRevertError("message from non-account actor")
-	errInsufficientGas           = errors.NewRevertError("balance insufficient to cover transfer+gas")
-	errInvalidSignature          = errors.NewRevertError("invalid signature by sender over message data")
+	errFromAccountNotFound       = errors.Errors[errors.ErrSenderNotFound]
+	errGasAboveBlockLimit        = errors.Errors[errors.ErrGasAboveBlockLimit]
+	errGasTooHighForCurrentBlock = errors.Errors[errors.ErrGasTooHighForCurrentBlock]
+	errNonceTooHigh              = errors.Errors[errors.ErrNonceTooHigh]
+	errNonceTooLow               = errors.Errors[errors.ErrNonceTooLow]
+	errNonAccountActor           = errors.Errors[errors.ErrNonAccountSender]
+	errInsufficientGas           = errors.Errors[errors.ErrInsufficientFundsForGas]
+	errInvalidSignature          = errors.Errors[errors.ErrInvalidSignature]
+	errUnknownAddressProtocol    = errors.Errors[errors.ErrUnknownAddressProtocol]
 	// TODO we'll eventually handle sending to self.
-	errSelfSend = errors.NewRevertError("cannot send to self")
+	errSelfSend = errors.Errors[errors.ErrSelfSend]
 )
 
 // CallQueryMethod calls a method on an actor in the given state tree. It does
@@ -415,7 +516,7 @@ func PreviewQueryMethod(ctx context.Context, st state.Tree, vms vm.StorageMap, t
 // should deal with trying to apply the message to the state tree whereas
 // ApplyMessage should deal with any side effects and how it should be presented
 // to the caller. attemptApplyMessage should only be called from ApplyMessage.
-func (p *DefaultProcessor) attemptApplyMessage(ctx context.Context, st *state.CachedTree, store vm.StorageMap, msg *types.SignedMessage, bh *types.BlockHeight, gasTracker *vm.GasTracker, ancestors []types.TipSet) (*types.MessageReceipt, error) {
+func (p *DefaultProcessor) attemptApplyMessage(ctx context.Context, st *state.CachedTree, store vm.StorageMap, msg *types.SignedMessage, bh *types.BlockHeight, gasTracker *vm.GasTracker, ancestors []types.TipSet, trace *vm.Trace) (*types.MessageReceipt, error) {
 	gasTracker.ResetForNewMessage(msg.MeteredMessage)
 	if err := blockGasLimitError(gasTracker); err != nil {
 		return &types.MessageReceipt{
@@ -427,17 +528,23 @@ func (p *DefaultProcessor) attemptApplyMessage(ctx context.Context, st *state.Ca
 	fromActor, err := st.GetActor(ctx, msg.From)
 	if state.IsActorNotFoundError(err) {
 		return &types.MessageReceipt{
-			ExitCode:   errors.CodeError(err),
+			ExitCode:   errors.CodeError(errFromAccountNotFound),
 			GasAttoFIL: types.ZeroAttoFIL,
 		}, errFromAccountNotFound
 	} else if err != nil {
 		return nil, errors.FaultErrorWrapf(err, "failed to get From actor %s", msg.From)
 	}
 
-	// processing an external message from an empty actor upgrades it to an account actor.
+	// processing an external message from an empty actor upgrades it as the
+	// account creation policy of its address protocol decides.
 	if !fromActor.Code.Defined() {
-		err := account.UpgradeActor(fromActor)
-		if err != nil {
+		err := vm.UpgradeEmptySender(msg.From, fromActor)
+		if errors.ShouldRevert(err) {
+			return &types.MessageReceipt{
+				ExitCode:   errors.CodeError(err),
+				GasAttoFIL: types.ZeroAttoFIL,
+			}, err
+		} else if err != nil {
 			return nil, errors.FaultErrorWrap(err, "failed to upgrade empty actor")
 		}
 	}
@@ -451,15 +558,18 @@ func (p *DefaultProcessor) attemptApplyMessage(ctx context.Context, st *state.Ca
 	}
 
 	toActor, err := st.GetOrCreateActor(ctx, msg.To, func() (*actor.Actor, error) {
-		// Addresses are deterministic so sending a message to a non-existent address must not install an actor,
-		// else actors could be installed ahead of address activation. So here we create the empty, upgradable
-		// actor to collect any balance that may be transferred.
-		return &actor.Actor{}, nil
+		return vm.NewActorForAddress(msg.To)
 	})
-	if err != nil {
+	if errors.ShouldRevert(err) {
+		return &types.MessageReceipt{
+			ExitCode:   errors.CodeError(err),
+			GasAttoFIL: types.ZeroAttoFIL,
+		}, err
+	} else if err != nil {
 		return nil, errors.FaultErrorWrap(err, "failed to get To actor")
 	}
 
+	events := &vm.EventLog{}
 	vmCtxParams := vm.NewContextParams{
 		From:        fromActor,
 		To:          toActor,
@@ -470,10 +580,19 @@ func (p *DefaultProcessor) attemptApplyMessage(ctx context.Context, st *state.Ca
 		BlockHeight: bh,
 		Ancestors:   ancestors,
 		LookBack:    LookBackParameter,
+		Trace:       trace,
+		Events:      events,
 	}
 	vmCtx := vm.NewVMContext(vmCtxParams)
 
-	ret, exitCode, vmErr := vm.Send(ctx, vmCtx)
+	var ret [][]byte
+	var exitCode uint8
+	vmErr := vmCtx.ChargeSignatureVerification()
+	if vmErr != nil {
+		exitCode = exec.ErrInsufficientGas
+	} else {
+		ret, exitCode, vmErr = vm.Send(ctx, vmCtx)
+	}
 	if errors.IsFault(vmErr) {
 		return nil, vmErr
 	}
@@ -481,9 +600,20 @@ func (p *DefaultProcessor) attemptApplyMessage(ctx context.Context, st *state.Ca
 	// compute gas charge
 	gasCharge := msg.GasPrice.MulBigInt(big.NewInt(int64(vmCtx.GasUnits())))
 
+	// An actor may fail without setting an exit code, the receipt must still
+	// tell the message failed.
+	if vmErr != nil && exitCode == 0 {
+		exitCode = errors.CodeError(vmErr)
+	}
+
 	receipt := &types.MessageReceipt{
-		ExitCode:   exitCode,
-		GasAttoFIL: gasCharge,
+		ExitCode:     exitCode,
+		RevertReason: errors.RevertReason(vmErr),
+		GasAttoFIL:   gasCharge,
+	}
+	// The state changes of a failed message are rolled back, so are its events.
+	if vmErr == nil {
+		receipt.Events = events.Events()
 	}
 
 	// :( - necessary because go slices aren't covariant and we need to convert
@@ -515,19 +645,43 @@ type ApplyMessagesResponse struct {
 // ApplyMessages will return an error iff a fault message occurs.
 // Precondition: signatures of messages are checked by the caller.
 func (p *DefaultProcessor) ApplyMessagesAndPayRewards(ctx context.Context, st state.Tree, vms vm.StorageMap, messages []*types.SignedMessage, minerAddr address.Address, bh *types.BlockHeight, ancestors []types.TipSet) (ApplyMessagesResponse, error) {
+	return p.applyMessagesAndPayRewards(ctx, st, vms, messages, minerAddr, bh, ancestors, nil)
+}
+
+func (p *DefaultProcessor) applyMessagesAndPayRewards(ctx context.Context, st state.Tree, vms vm.StorageMap, messages []*types.SignedMessage, minerAddr address.Address, bh *types.BlockHeight, ancestors []types.TipSet, tracer *messageTracer) (ApplyMessagesResponse, error) {
 	var emptyRet ApplyMessagesResponse
 	var ret ApplyMessagesResponse
 
-	// transfer block reward to miner from network address.
-	if err := p.blockRewarder.BlockReward(ctx, st, minerAddr); err != nil {
+	// pay the block reward to the miner.
+	if err := p.blockRewarder.BlockReward(ctx, st, vms, minerAddr, bh); err != nil {
 		return ApplyMessagesResponse{}, err
 	}
 
 	gasTracker := vm.NewGasTracker()
 
+	// Execute the messages in parallel ahead of applying them. Traced
+	// executions stay sequential so the traces are those of the messages
+	// applied.
+	var specs []*speculation
+	var committed map[address.Address]struct{}
+	if tracer == nil && p.parallelism > 1 && len(messages) > 1 {
+		var err error
+		if specs, err = p.speculate(ctx, st, vms, messages, bh, ancestors); err != nil {
+			return emptyRet, err
+		}
+		committed = make(map[address.Address]struct{})
+	}
+
 	// process all messages
-	for _, smsg := range messages {
-		r, err := p.ApplyMessage(ctx, st, vms, smsg, minerAddr, bh, gasTracker, ancestors)
+	for i, smsg := range messages {
+		var r *ApplicationResult
+		var err error
+		if specs != nil {
+			r, err = p.commitSpeculation(ctx, st, vms, smsg, specs[i], committed, minerAddr, bh, gasTracker, ancestors)
+		} else {
+			r, err = p.applyMessage(ctx, st, vms, smsg, minerAddr, bh, gasTracker, ancestors, tracer)
+		}
+		tracer.record(smsg, r, err)
 		// If the message should not have been in the block, bail somehow.
 		switch {
 		case errors.IsFault(err):
@@ -548,6 +702,65 @@ func (p *DefaultProcessor) ApplyMessagesAndPayRewards(ctx context.Context, st st
 	return ret, nil
 }
 
+// MigrateState migrates st for the protocol upgrades taking effect at the
+// epoch at height bh, before its messages are applied. The parent of the
+// epoch is the first of ancestors, or is taken to be at the previous height
+// when there are none.
+func (p *DefaultProcessor) MigrateState(ctx context.Context, st state.Tree, vms vm.StorageMap, bh *types.BlockHeight, ancestors []types.TipSet) error {
+	parent := bh.Sub(types.NewBlockHeight(1))
+	if len(ancestors) > 0 {
+		h, err := ancestors[0].Height()
+		if err != nil {
+			return errors.FaultErrorWrap(err, "failed to get parent height")
+		}
+		parent = types.NewBlockHeight(h)
+	}
+
+	upgrades := migration.Upgrades.After(parent, bh)
+	if len(upgrades) == 0 {
+		return nil
+	}
+	if err := migration.Apply(ctx, st, vms, upgrades); err != nil {
+		return errors.FaultErrorWrap(err, "failed to migrate state")
+	}
+	return nil
+}
+
+// RunCron has the cron actor call back the actors registered with it, at the
+// end of the state transition of the epoch at height bh. A tick that reverts
+// is logged and dropped so a broken callback can't halt the chain. States
+// without a cron actor are left unchanged.
+func (p *DefaultProcessor) RunCron(ctx context.Context, st state.Tree, vms vm.StorageMap, bh *types.BlockHeight) error {
+	cachedTree := state.NewCachedStateTree(st)
+
+	cronActor, err := cachedTree.GetActor(ctx, address.CronAddress)
+	if state.IsActorNotFoundError(err) {
+		return nil
+	} else if err != nil {
+		return errors.FaultErrorWrap(err, "could not retrieve cron actor")
+	}
+
+	gasTracker := vm.NewGasTracker()
+	gasTracker.MsgGasLimit = types.BlockGasLimit
+
+	vmCtx := vm.NewVMContext(vm.NewContextParams{
+		To:          cronActor,
+		Message:     types.NewMessage(address.NetworkAddress, address.CronAddress, 0, nil, "epochTick", nil),
+		State:       cachedTree,
+		StorageMap:  vms,
+		GasTracker:  gasTracker,
+		BlockHeight: bh,
+	})
+	if _, _, err := vm.Send(ctx, vmCtx); err != nil {
+		if errors.ShouldRevert(err) {
+			log.Warningf("cron tick at height %s reverted: %s", bh, err)
+			return nil
+		}
+		return errors.FaultErrorWrap(err, "Error attempting to run cron")
+	}
+	return cachedTree.Commit(ctx)
+}
+
 // DefaultMessageValidator validates that a message coming in from the network is valid.
 type DefaultMessageValidator struct{}
 
@@ -592,7 +805,7 @@ func (nmv *DefaultMessageValidator) Validate(ctx context.Context, msg *types.Sig
 	return nil
 }
 
-// DefaultBlockRewarder pays the block reward from the network actor to the miner.
+// DefaultBlockRewarder has the reward actor pay the block reward to the miner.
 type DefaultBlockRewarder struct{}
 
 // NewDefaultBlockRewarder creates a new rewarder that actually pays the appropriate rewards.
@@ -602,10 +815,34 @@ func NewDefaultBlockRewarder() *DefaultBlockRewarder {
 
 var _ BlockRewarder = (*DefaultBlockRewarder)(nil)
 
-// BlockReward transfers the block reward from the network actor to the miner.
-func (br *DefaultBlockRewarder) BlockReward(ctx context.Context, st state.Tree, minerAddr address.Address) error {
+// BlockReward calls the reward actor on behalf of the network to pay the
+// block reward at height bh to the miner. The call is not a message of the
+// block so it doesn't cost the miner gas.
+func (br *DefaultBlockRewarder) BlockReward(ctx context.Context, st state.Tree, vms vm.StorageMap, minerAddr address.Address, bh *types.BlockHeight) error {
 	cachedTree := state.NewCachedStateTree(st)
-	if err := rewardTransfer(ctx, address.NetworkAddress, minerAddr, br.BlockRewardAmount(), cachedTree); err != nil {
+
+	rewardActor, err := cachedTree.GetActor(ctx, address.RewardAddress)
+	if err != nil {
+		return errors.FaultErrorWrap(err, "could not retrieve reward actor")
+	}
+
+	params, err := abi.ToEncodedValues(minerAddr)
+	if err != nil {
+		return errors.FaultErrorWrap(err, "could not encode block reward params")
+	}
+
+	gasTracker := vm.NewGasTracker()
+	gasTracker.MsgGasLimit = types.BlockGasLimit
+
+	vmCtx := vm.NewVMContext(vm.NewContextParams{
+		To:          rewardActor,
+		Message:     types.NewMessage(address.NetworkAddress, address.RewardAddress, 0, nil, "awardBlockReward", params),
+		State:       cachedTree,
+		StorageMap:  vms,
+		GasTracker:  gasTracker,
+		BlockHeight: bh,
+	})
+	if _, _, err := vm.Send(ctx, vmCtx); err != nil {
 		return errors.FaultErrorWrap(err, "Error attempting to pay block reward")
 	}
 	return cachedTree.Commit(ctx)
@@ -620,11 +857,10 @@ func (br *DefaultBlockRewarder) GasReward(ctx context.Context, st state.Tree, mi
 	return cachedTree.Commit(ctx)
 }
 
-// BlockRewardAmount returns the max FIL value miners can claim as the block reward.
-// TODO this is one of the system parameters that should be configured as part of
-// https://github.com/filecoin-project/go-filecoin/issues/884.
-func (br *DefaultBlockRewarder) BlockRewardAmount() *types.AttoFIL {
-	return types.NewAttoFILFromFIL(1000)
+// BlockRewardAmount returns the FIL value miners are rewarded with for a
+// block at height bh.
+func (br *DefaultBlockRewarder) BlockRewardAmount(bh *types.BlockHeight) *types.AttoFIL {
+	return reward.Emissions.RewardAt(bh)
 }
 
 // rewardTransfer retrieves two actors from the given addresses and attempts to transfer the given value from the balance of the first's to the second.
@@ -672,5 +908,6 @@ func isPermanentError(err error) bool {
 		err == errNonceTooLow ||
 		err == errNonAccountActor ||
 		err == errors.Errors[errors.ErrCannotTransferNegativeValue] ||
-		err == errGasAboveBlockLimit
+		err == errGasAboveBlockLimit ||
+		err == errUnknownAddressProtocol
 }
@@ -2,6 +2,7 @@ package consensus_test
 
 import (
 	"context"
+	"math/big"
 	"testing"
 
 	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
@@ -12,8 +13,10 @@ import (
 	"github.com/filecoin-project/go-filecoin/abi"
 	"github.com/filecoin-project/go-filecoin/actor"
 	"github.com/filecoin-project/go-filecoin/actor/builtin"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/cron"
 	"github.com/filecoin-project/go-filecoin/address"
 	. "github.com/filecoin-project/go-filecoin/consensus"
+	"github.com/filecoin-project/go-filecoin/migration"
 	"github.com/filecoin-project/go-filecoin/state"
 	th "github.com/filecoin-project/go-filecoin/testhelpers"
 	"github.com/filecoin-project/go-filecoin/types"
@@ -48,19 +51,19 @@ func TestProcessBlockSuccess(t *testing.T) {
 	ki := types.MustGenerateKeyInfo(1, types.GenerateKeyInfoSeed())
 	mockSigner := types.NewMockSigner(ki)
 
-	startingNetworkBalance := uint64(10000000)
+	startingRewardBalance := types.NewAttoFILFromFIL(10000000)
 
 	toAddr := newAddress()
 	minerAddr := newAddress()
 	fromAddr := mockSigner.Addresses[0] // fromAddr needs to be known by signer
 	fromAct := th.RequireNewAccountActor(require, types.NewAttoFILFromFIL(10000))
+	vms := th.VMStorage()
 	stCid, st := th.RequireMakeStateTree(require, cst, map[address.Address]*actor.Actor{
-		address.NetworkAddress: th.RequireNewAccountActor(require, types.NewAttoFILFromFIL(startingNetworkBalance)),
-		minerAddr:              th.RequireNewAccountActor(require, types.ZeroAttoFIL),
-		fromAddr:               fromAct,
+		address.RewardAddress: th.RequireNewRewardActor(require, vms, startingRewardBalance, types.ZeroAttoFIL),
+		minerAddr:             th.RequireNewAccountActor(require, types.ZeroAttoFIL),
+		fromAddr:              fromAct,
 	})
 
-	vms := th.VMStorage()
 	msg := types.NewMessage(fromAddr, toAddr, 0, types.NewAttoFILFromFIL(550), "", nil)
 	smsg, err := types.NewSignedMessage(*msg, &mockSigner, types.NewGasPrice(0), types.NewGasUnits(0))
 	require.NoError(err)
@@ -79,13 +82,13 @@ func TestProcessBlockSuccess(t *testing.T) {
 	assert.NoError(err)
 	expAct1, expAct2 := th.RequireNewAccountActor(require, types.NewAttoFILFromFIL(10000-550)), th.RequireNewEmptyActor(require, types.NewAttoFILFromFIL(550))
 	expAct1.IncNonce()
-	blockRewardAmount := NewDefaultBlockRewarder().BlockRewardAmount()
-	expectedNetworkBalance := types.NewAttoFILFromFIL(startingNetworkBalance).Sub(blockRewardAmount)
+	blockRewardAmount := NewDefaultBlockRewarder().BlockRewardAmount(types.NewBlockHeight(20))
+	expectedRewardBalance := startingRewardBalance.Sub(blockRewardAmount)
 	expStCid, _ := th.RequireMakeStateTree(require, cst, map[address.Address]*actor.Actor{
-		address.NetworkAddress: th.RequireNewAccountActor(require, expectedNetworkBalance),
-		minerAddr:              th.RequireNewAccountActor(require, blockRewardAmount),
-		fromAddr:               expAct1,
-		toAddr:                 expAct2,
+		address.RewardAddress: th.RequireNewRewardActor(require, vms, expectedRewardBalance, blockRewardAmount),
+		minerAddr:             th.RequireNewAccountActor(require, blockRewardAmount),
+		fromAddr:              expAct1,
+		toAddr:                expAct2,
 	})
 	assert.True(expStCid.Equals(gotStCid))
 }
@@ -99,7 +102,7 @@ func TestProcessTipSetSuccess(t *testing.T) {
 	cst := hamt.NewCborStore()
 	vms := th.VMStorage()
 
-	startingNetworkBalance := types.NewAttoFILFromFIL(1000000)
+	startingRewardBalance := types.NewAttoFILFromFIL(1000000)
 	minerAddr := newAddress()
 
 	toAddr := newAddress()
@@ -111,9 +114,9 @@ func TestProcessTipSetSuccess(t *testing.T) {
 	fromAddr1Act := th.RequireNewAccountActor(require, types.NewAttoFILFromFIL(10000))
 	fromAddr2Act := th.RequireNewAccountActor(require, types.NewAttoFILFromFIL(10000))
 	stCid, st := th.RequireMakeStateTree(require, cst, map[address.Address]*actor.Actor{
-		address.NetworkAddress: th.RequireNewAccountActor(require, startingNetworkBalance),
-		fromAddr1:              fromAddr1Act,
-		fromAddr2:              fromAddr2Act,
+		address.RewardAddress: th.RequireNewRewardActor(require, vms, startingRewardBalance, types.ZeroAttoFIL),
+		fromAddr1:             fromAddr1Act,
+		fromAddr2:             fromAddr2Act,
 	})
 
 	msg1 := types.NewMessage(fromAddr1, toAddr, 0, types.NewAttoFILFromFIL(550), "", nil)
@@ -146,15 +149,15 @@ func TestProcessTipSetSuccess(t *testing.T) {
 	expAct1.IncNonce()
 	expAct2.IncNonce()
 
-	blockRewardAmount := NewDefaultBlockRewarder().BlockRewardAmount()
+	blockRewardAmount := NewDefaultBlockRewarder().BlockRewardAmount(types.NewBlockHeight(20))
 	twoBlockRewards := blockRewardAmount.Add(blockRewardAmount)
-	expectedNetworkBalance := startingNetworkBalance.Sub(twoBlockRewards)
+	expectedRewardBalance := startingRewardBalance.Sub(twoBlockRewards)
 	expStCid, _ := th.RequireMakeStateTree(require, cst, map[address.Address]*actor.Actor{
-		address.NetworkAddress: th.RequireNewAccountActor(require, expectedNetworkBalance),
-		minerAddr:              th.RequireNewEmptyActor(require, twoBlockRewards),
-		fromAddr1:              expAct1,
-		fromAddr2:              expAct2,
-		toAddr:                 expAct3,
+		address.RewardAddress: th.RequireNewRewardActor(require, vms, expectedRewardBalance, twoBlockRewards),
+		minerAddr:             th.RequireNewEmptyActor(require, twoBlockRewards),
+		fromAddr1:             expAct1,
+		fromAddr2:             expAct2,
+		toAddr:                expAct3,
 	})
 	assert.True(expStCid.Equals(gotStCid))
 }
@@ -164,7 +167,7 @@ func TestProcessTipsConflicts(t *testing.T) {
 	require := require.New(t)
 
 	newAddress := address.NewForTestGetter()
-	startingNetworkBalance := types.NewAttoFILFromFIL(1000000)
+	startingRewardBalance := types.NewAttoFILFromFIL(1000000)
 	minerAddr := newAddress()
 
 	ctx := context.Background()
@@ -176,8 +179,8 @@ func TestProcessTipsConflicts(t *testing.T) {
 	fromAddr, toAddr := mockSigner.Addresses[0], mockSigner.Addresses[1]
 	act1 := th.RequireNewAccountActor(require, types.NewAttoFILFromFIL(1000))
 	stCid, st := th.RequireMakeStateTree(require, cst, map[address.Address]*actor.Actor{
-		address.NetworkAddress: th.RequireNewAccountActor(require, startingNetworkBalance),
-		fromAddr:               act1,
+		address.RewardAddress: th.RequireNewRewardActor(require, vms, startingRewardBalance, types.ZeroAttoFIL),
+		fromAddr:              act1,
 	})
 
 	msg1 := types.NewMessage(fromAddr, toAddr, 0, types.NewAttoFILFromFIL(501), "", nil)
@@ -210,14 +213,14 @@ func TestProcessTipsConflicts(t *testing.T) {
 
 	expAct1, expAct2 := th.RequireNewAccountActor(require, types.NewAttoFILFromFIL(1000-501)), th.RequireNewEmptyActor(require, types.NewAttoFILFromFIL(501))
 	expAct1.IncNonce()
-	blockReward := NewDefaultBlockRewarder().BlockRewardAmount()
+	blockReward := NewDefaultBlockRewarder().BlockRewardAmount(types.NewBlockHeight(20))
 	twoBlockRewards := blockReward.Add(blockReward)
-	expectedNetworkBalance := startingNetworkBalance.Sub(twoBlockRewards)
+	expectedRewardBalance := startingRewardBalance.Sub(twoBlockRewards)
 	expStCid, _ := th.RequireMakeStateTree(require, cst, map[address.Address]*actor.Actor{
-		address.NetworkAddress: th.RequireNewAccountActor(require, expectedNetworkBalance),
-		minerAddr:              th.RequireNewEmptyActor(require, twoBlockRewards),
-		fromAddr:               expAct1,
-		toAddr:                 expAct2,
+		address.RewardAddress: th.RequireNewRewardActor(require, vms, expectedRewardBalance, twoBlockRewards),
+		minerAddr:             th.RequireNewEmptyActor(require, twoBlockRewards),
+		fromAddr:              expAct1,
+		toAddr:                expAct2,
 	})
 	assert.True(expStCid.Equals(gotStCid))
 }
@@ -235,12 +238,12 @@ func TestProcessBlockBadMsgSig(t *testing.T) {
 	toAddr := newAddress()
 	fromAddr := mockSigner.Addresses[0] // fromAddr needs to be known by signer
 	fromAct := th.RequireNewAccountActor(require, types.NewAttoFILFromFIL(10000))
+	vms := th.VMStorage()
 	stCid, st := th.RequireMakeStateTree(require, cst, map[address.Address]*actor.Actor{
-		address.NetworkAddress: th.RequireNewAccountActor(require, types.NewAttoFILFromFIL(100000)),
-		fromAddr:               fromAct,
+		address.RewardAddress: th.RequireNewRewardActor(require, vms, types.NewAttoFILFromFIL(100000), types.ZeroAttoFIL),
+		fromAddr:              fromAct,
 	})
 
-	vms := th.VMStorage()
 	msg := types.NewMessage(fromAddr, toAddr, 0, types.NewAttoFILFromFIL(550), "", nil)
 	smsg, err := types.NewSignedMessage(*msg, &mockSigner, types.NewGasPrice(0), types.NewGasUnits(0))
 	require.NoError(err)
@@ -269,16 +272,14 @@ func TestProcessBlockReward(t *testing.T) {
 	minerOwnerAddr := newAddress()
 	minerBalance := types.NewAttoFILFromFIL(10000)
 	ownerAct := th.RequireNewAccountActor(require, minerBalance)
-	networkAct := th.RequireNewAccountActor(require, types.NewAttoFILFromFIL(100000000000))
+	vms := th.VMStorage()
+	rewardBalance := types.NewAttoFILFromFIL(100000000000)
+	rewardAct := th.RequireNewRewardActor(require, vms, rewardBalance, types.ZeroAttoFIL)
 	stCid, st := th.RequireMakeStateTree(require, cst, map[address.Address]*actor.Actor{
-		minerOwnerAddr: ownerAct,
-		// TODO: get rid of this ugly hack as soon once we have
-		// sustainable reward support (i.e. anything but setting
-		// up network address in genesis with a bunch of FIL).
-		address.NetworkAddress: networkAct,
+		minerOwnerAddr:        ownerAct,
+		address.RewardAddress: rewardAct,
 	})
 
-	vms := th.VMStorage()
 	blk := &types.Block{
 		Miner:     minerOwnerAddr,
 		Height:    20,
@@ -292,8 +293,12 @@ func TestProcessBlockReward(t *testing.T) {
 	minerOwnerActor, err := st.GetActor(ctx, minerOwnerAddr)
 	require.NoError(err)
 
-	blockRewardAmount := NewDefaultBlockRewarder().BlockRewardAmount()
+	blockRewardAmount := NewDefaultBlockRewarder().BlockRewardAmount(types.NewBlockHeight(20))
 	assert.Equal(minerBalance.Add(blockRewardAmount), minerOwnerActor.Balance)
+
+	rewardActor, err := st.GetActor(ctx, address.RewardAddress)
+	require.NoError(err)
+	assert.Equal(rewardBalance.Sub(blockRewardAmount), rewardActor.Balance)
 }
 
 func TestProcessBlockVMErrors(t *testing.T) {
@@ -305,7 +310,7 @@ func TestProcessBlockVMErrors(t *testing.T) {
 	vms := th.VMStorage()
 
 	newAddress := address.NewForTestGetter()
-	startingNetworkBalance := types.NewAttoFILFromFIL(1000000)
+	startingRewardBalance := types.NewAttoFILFromFIL(1000000)
 	minerAddr := newAddress()
 
 	// Install the fake actor so we can execute it.
@@ -322,9 +327,9 @@ func TestProcessBlockVMErrors(t *testing.T) {
 
 	act1, act2 := th.RequireNewEmptyActor(require, types.NewAttoFILFromFIL(0)), th.RequireNewFakeActor(require, vms, toAddr, fakeActorCodeCid)
 	stCid, st := th.RequireMakeStateTree(require, cst, map[address.Address]*actor.Actor{
-		address.NetworkAddress: th.RequireNewAccountActor(require, startingNetworkBalance),
-		fromAddr:               act1,
-		toAddr:                 act2,
+		address.RewardAddress: th.RequireNewRewardActor(require, vms, startingRewardBalance, types.ZeroAttoFIL),
+		fromAddr:              act1,
+		toAddr:                act2,
 	})
 	msg := types.NewMessage(fromAddr, toAddr, 0, nil, "returnRevertError", nil)
 	smsg, err := types.NewSignedMessage(*msg, &mockSigner, types.NewGasPrice(0), types.NewGasUnits(0))
@@ -351,12 +356,12 @@ func TestProcessBlockVMErrors(t *testing.T) {
 	// 3 & 4. That on VM error the state is rolled back and nonce is inc'd.
 	expectedAct1, expectedAct2 := th.RequireNewEmptyActor(require, types.NewAttoFILFromFIL(0)), th.RequireNewFakeActor(require, vms, toAddr, fakeActorCodeCid)
 	expectedAct1.IncNonce()
-	blockRewardAmount := NewDefaultBlockRewarder().BlockRewardAmount()
+	blockRewardAmount := NewDefaultBlockRewarder().BlockRewardAmount(types.NewBlockHeight(20))
 	expectedStCid, _ := th.RequireMakeStateTree(require, cst, map[address.Address]*actor.Actor{
-		address.NetworkAddress: th.RequireNewAccountActor(require, startingNetworkBalance.Sub(blockRewardAmount)),
-		minerAddr:              th.RequireNewEmptyActor(require, blockRewardAmount),
-		fromAddr:               expectedAct1,
-		toAddr:                 expectedAct2,
+		address.RewardAddress: th.RequireNewRewardActor(require, vms, startingRewardBalance.Sub(blockRewardAmount), blockRewardAmount),
+		minerAddr:             th.RequireNewEmptyActor(require, blockRewardAmount),
+		fromAddr:              expectedAct1,
+		toAddr:                expectedAct2,
 	})
 	gotStCid, err := st.Flush(ctx)
 	assert.NoError(err)
@@ -364,6 +369,58 @@ func TestProcessBlockVMErrors(t *testing.T) {
 	assert.True(expectedStCid.Equals(gotStCid))
 }
 
+func TestProcessBlockAbortsAndPanics(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ctx := context.Background()
+	cst := hamt.NewCborStore()
+	vms := th.VMStorage()
+
+	fakeActorCodeCid := types.NewCidForTestGetter()()
+	builtin.Actors[fakeActorCodeCid] = &actor.FakeActor{}
+	defer func() {
+		delete(builtin.Actors, fakeActorCodeCid)
+	}()
+	ki := types.MustGenerateKeyInfo(2, types.GenerateKeyInfoSeed())
+	mockSigner := types.NewMockSigner(ki)
+
+	fromAddr, toAddr := mockSigner.Addresses[0], mockSigner.Addresses[1]
+	fakeAct := th.RequireNewFakeActor(require, vms, toAddr, fakeActorCodeCid)
+	_, st := th.RequireMakeStateTree(require, cst, map[address.Address]*actor.Actor{
+		address.RewardAddress: th.RequireNewRewardActor(require, vms, types.NewAttoFILFromFIL(1000000), types.ZeroAttoFIL),
+		fromAddr:              th.RequireNewEmptyActor(require, types.NewAttoFILFromFIL(0)),
+		toAddr:                fakeAct,
+	})
+
+	var msgs []*types.SignedMessage
+	for i, method := range []string{"abort", "panics"} {
+		msg := types.NewMessage(fromAddr, toAddr, uint64(i), nil, method, nil)
+		smsg, err := types.NewSignedMessage(*msg, &mockSigner, types.NewGasPrice(0), types.NewGasUnits(0))
+		require.NoError(err)
+		msgs = append(msgs, smsg)
+	}
+	blk := &types.Block{
+		Height:   20,
+		Messages: msgs,
+		Miner:    address.NewForTestGetter()(),
+	}
+
+	// Neither aborting nor panicking fails the block.
+	results, err := NewDefaultProcessor().ProcessBlock(ctx, st, vms, blk, nil)
+	require.NoError(err)
+	require.Len(results, 2)
+
+	assert.Equal(uint8(43), results[0].Receipt.ExitCode)
+	assert.Equal("aborted", string(results[0].Receipt.RevertReason))
+	assert.Equal(uint8(errors.ErrActorPanic), results[1].Receipt.ExitCode)
+
+	// The state the aborted message changed is rolled back, the nonce of the
+	// sender is incremented for both messages.
+	assert.True(fakeAct.Head.Equals(state.MustGetActor(st, toAddr).Head))
+	assert.Equal(types.Uint64(2), state.MustGetActor(st, fromAddr).Nonce)
+}
+
 func TestProcessBlockParamsLengthError(t *testing.T) {
 	assert := assert.New(t)
 	require := require.New(t)
@@ -413,6 +470,8 @@ func TestProcessBlockParamsError(t *testing.T) {
 
 	assert.Empty(rct.Receipt.Return)
 	assert.Contains(rct.ExecutionError.Error(), "invalid params: malformed stream")
+	assert.Equal(uint8(errors.ErrInvalidParams), rct.Receipt.ExitCode)
+	assert.Equal(types.Bytes("invalid params"), rct.Receipt.RevertReason)
 }
 
 func TestApplyMessagesValidation(t *testing.T) {
@@ -944,7 +1003,8 @@ func TestBlockGasLimitBehavior(t *testing.T) {
 	builtin.Actors[fakeActorCodeCid] = &actor.FakeActor{}
 	defer delete(builtin.Actors, fakeActorCodeCid)
 
-	actors, stateTree, signer := setupActorsForGasTest(t, th.VMStorage(), fakeActorCodeCid, 0)
+	vms := th.VMStorage()
+	actors, stateTree, signer := setupActorsForGasTest(t, vms, fakeActorCodeCid, 0)
 	sender := actors[1]
 	receiver := actors[2]
 	processor := NewTestProcessor()
@@ -955,7 +1015,7 @@ func TestBlockGasLimitBehavior(t *testing.T) {
 		sgnedMsg, err := types.NewSignedMessage(*msg, signer, *types.NewZeroAttoFIL(), types.BlockGasLimit*2)
 		require.NoError(t, err)
 
-		result, err := processor.ApplyMessagesAndPayRewards(ctx, stateTree, th.VMStorage(), []*types.SignedMessage{sgnedMsg}, sender, types.NewBlockHeight(0), nil)
+		result, err := processor.ApplyMessagesAndPayRewards(ctx, stateTree, vms, []*types.SignedMessage{sgnedMsg}, sender, types.NewBlockHeight(0), nil)
 		require.NoError(t, err)
 
 		assert.Contains(t, result.PermanentFailures, sgnedMsg)
@@ -970,7 +1030,7 @@ func TestBlockGasLimitBehavior(t *testing.T) {
 		sgnedMsg2, err := types.NewSignedMessage(*msg2, signer, *types.NewZeroAttoFIL(), types.BlockGasLimit*5/8)
 		require.NoError(t, err)
 
-		result, err := processor.ApplyMessagesAndPayRewards(ctx, stateTree, th.VMStorage(), []*types.SignedMessage{sgnedMsg1, sgnedMsg2}, sender, types.NewBlockHeight(0), nil)
+		result, err := processor.ApplyMessagesAndPayRewards(ctx, stateTree, vms, []*types.SignedMessage{sgnedMsg1, sgnedMsg2}, sender, types.NewBlockHeight(0), nil)
 		require.NoError(t, err)
 
 		assert.Contains(t, result.SuccessfulMessages, sgnedMsg1)
@@ -986,7 +1046,7 @@ func TestBlockGasLimitBehavior(t *testing.T) {
 		sgnedMsg2, err := types.NewSignedMessage(*msg2, signer, *types.NewZeroAttoFIL(), types.BlockGasLimit*7/8)
 		require.NoError(t, err)
 
-		result, err := processor.ApplyMessagesAndPayRewards(ctx, stateTree, th.VMStorage(), []*types.SignedMessage{sgnedMsg1, sgnedMsg2}, sender, types.NewBlockHeight(0), nil)
+		result, err := processor.ApplyMessagesAndPayRewards(ctx, stateTree, vms, []*types.SignedMessage{sgnedMsg1, sgnedMsg2}, sender, types.NewBlockHeight(0), nil)
 		require.NoError(t, err)
 
 		assert.Contains(t, result.SuccessfulMessages, sgnedMsg1)
@@ -1006,7 +1066,7 @@ func TestBlockGasLimitBehavior(t *testing.T) {
 		sgnedMsg3, err := types.NewSignedMessage(*msg3, signer, *types.NewZeroAttoFIL(), types.BlockGasLimit*3/8)
 		require.NoError(t, err)
 
-		result, err := processor.ApplyMessagesAndPayRewards(ctx, stateTree, th.VMStorage(), []*types.SignedMessage{sgnedMsg1, sgnedMsg2, sgnedMsg3}, sender, types.NewBlockHeight(0), nil)
+		result, err := processor.ApplyMessagesAndPayRewards(ctx, stateTree, vms, []*types.SignedMessage{sgnedMsg1, sgnedMsg2, sgnedMsg3}, sender, types.NewBlockHeight(0), nil)
 		require.NoError(t, err)
 
 		assert.Contains(t, result.SuccessfulMessages, sgnedMsg1, sgnedMsg3)
@@ -1040,16 +1100,16 @@ func setupActorsForGasTest(t *testing.T, vms vm.StorageMap, fakeActorCodeCid cid
 	// minerActor
 	actors = append(actors, th.RequireNewAccountActor(require, types.NewAttoFILFromFIL(0)))
 
-	// networkActor
-	actors = append(actors, th.RequireNewAccountActor(require, types.NewAttoFILFromFIL(10000000)))
+	// rewardActor
+	actors = append(actors, th.RequireNewRewardActor(require, vms, types.NewAttoFILFromFIL(10000000), types.ZeroAttoFIL))
 
 	cst := hamt.NewCborStore()
 	cid, st := th.RequireMakeStateTree(require, cst, map[address.Address]*actor.Actor{
-		addresses[0]:           actors[0],
-		addresses[1]:           actors[1],
-		addresses[2]:           actors[2],
-		addresses[3]:           actors[3],
-		address.NetworkAddress: actors[4],
+		addresses[0]:          actors[0],
+		addresses[1]:          actors[1],
+		addresses[2]:          actors[2],
+		addresses[3]:          actors[3],
+		address.RewardAddress: actors[4],
 	})
 	require.NotNil(cid)
 
@@ -1074,3 +1134,278 @@ func mustSetup2Actors(t *testing.T, balance1 *types.AttoFIL, balance2 *types.Att
 	})
 	return addr1, act1, addr2, act2, st, mockSigner
 }
+
+func TestRunCron(t *testing.T) {
+	newAddress := address.NewForTestGetter()
+	ctx := context.Background()
+
+	// Install the fake actor so the cron can call it back.
+	fakeActorCodeCid := types.NewCidForTestGetter()()
+	builtin.Actors[fakeActorCodeCid] = &actor.FakeActor{}
+	defer func() {
+		delete(builtin.Actors, fakeActorCodeCid)
+	}()
+
+	t.Run("calls back registered actors", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		vms := th.VMStorage()
+		addr := newAddress()
+		fakeAct := th.RequireNewFakeActor(require, vms, addr, fakeActorCodeCid)
+		head := fakeAct.Head
+		_, st := th.RequireMakeStateTree(require, hamt.NewCborStore(), map[address.Address]*actor.Actor{
+			address.CronAddress: th.RequireNewCronActor(require, vms, cron.Callback{Receiver: addr, Method: "goodCall"}),
+			addr:                fakeAct,
+		})
+
+		require.NoError(NewDefaultProcessor().RunCron(ctx, st, vms, types.NewBlockHeight(1)))
+
+		// goodCall writes the fake actor's storage.
+		assert.False(head.Equals(state.MustGetActor(st, addr).Head))
+	})
+
+	t.Run("drops a reverted tick", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		vms := th.VMStorage()
+		addr1, addr2 := newAddress(), newAddress()
+		stCid, st := th.RequireMakeStateTree(require, hamt.NewCborStore(), map[address.Address]*actor.Actor{
+			address.CronAddress: th.RequireNewCronActor(require, vms,
+				cron.Callback{Receiver: addr1, Method: "goodCall"},
+				cron.Callback{Receiver: addr2, Method: "returnRevertError"},
+			),
+			addr1: th.RequireNewFakeActor(require, vms, addr1, fakeActorCodeCid),
+			addr2: th.RequireNewFakeActor(require, vms, addr2, fakeActorCodeCid),
+		})
+
+		require.NoError(NewDefaultProcessor().RunCron(ctx, st, vms, types.NewBlockHeight(1)))
+
+		gotStCid, err := st.Flush(ctx)
+		require.NoError(err)
+		assert.Equal(stCid, gotStCid)
+	})
+
+	t.Run("ignores states without a cron actor", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		stCid, st := th.RequireMakeStateTree(require, hamt.NewCborStore(), map[address.Address]*actor.Actor{})
+
+		require.NoError(NewDefaultProcessor().RunCron(ctx, st, th.VMStorage(), types.NewBlockHeight(1)))
+
+		gotStCid, err := st.Flush(ctx)
+		require.NoError(err)
+		assert.Equal(stCid, gotStCid)
+	})
+}
+
+func TestMigrateState(t *testing.T) {
+	ctx := context.Background()
+	addr := address.NewForTestGetter()()
+
+	defer func(upgrades migration.Schedule) {
+		migration.Upgrades = upgrades
+	}(migration.Upgrades)
+	migration.Upgrades = migration.Schedule{{
+		Name:   "bump",
+		Height: types.NewBlockHeight(5),
+		Migration: func(ctx context.Context, st state.Tree, vms vm.StorageMap) error {
+			act, err := st.GetActor(ctx, addr)
+			if err != nil {
+				return err
+			}
+			act.Nonce++
+			return st.SetActor(ctx, addr, act)
+		},
+	}}
+
+	migrated := func(t *testing.T, bh uint64, ancestors []types.TipSet) bool {
+		require := require.New(t)
+		_, st := th.RequireMakeStateTree(require, hamt.NewCborStore(), map[address.Address]*actor.Actor{
+			addr: th.RequireNewAccountActor(require, types.NewAttoFILFromFIL(1)),
+		})
+		require.NoError(NewDefaultProcessor().MigrateState(ctx, st, th.VMStorage(), types.NewBlockHeight(bh), ancestors))
+		return state.MustGetActor(st, addr).Nonce == 1
+	}
+
+	t.Run("at the upgrade height", func(t *testing.T) {
+		assert.True(t, migrated(t, 5, nil))
+		assert.False(t, migrated(t, 4, nil))
+		assert.False(t, migrated(t, 6, nil))
+	})
+
+	t.Run("after null blocks at the upgrade height", func(t *testing.T) {
+		parent := th.RequireNewTipSet(require.New(t), &types.Block{Height: 3})
+		assert.True(t, migrated(t, 7, []types.TipSet{parent}))
+
+		parent = th.RequireNewTipSet(require.New(t), &types.Block{Height: 5})
+		assert.False(t, migrated(t, 7, []types.TipSet{parent}))
+	})
+}
+
+func TestReplayMessage(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	newAddress := address.NewForTestGetter()
+	ctx := context.Background()
+	cst := hamt.NewCborStore()
+	vms := th.VMStorage()
+
+	// Install the fake actor so we can execute it.
+	fakeActorCodeCid := types.NewCidForTestGetter()()
+	builtin.Actors[fakeActorCodeCid] = &actor.FakeActor{}
+	defer func() {
+		delete(builtin.Actors, fakeActorCodeCid)
+	}()
+
+	ki := types.MustGenerateKeyInfo(1, types.GenerateKeyInfoSeed())
+	mockSigner := types.NewMockSigner(ki)
+	fromAddr := mockSigner.Addresses[0]
+	addr1, addr2 := newAddress(), newAddress()
+
+	stCid, st := th.RequireMakeStateTree(require, cst, map[address.Address]*actor.Actor{
+		address.RewardAddress: th.RequireNewRewardActor(require, vms, types.NewAttoFILFromFIL(1000000), types.ZeroAttoFIL),
+		fromAddr:              th.RequireNewAccountActor(require, types.NewAttoFILFromFIL(100)),
+		addr1:                 th.RequireNewFakeActorWithTokens(require, vms, addr1, fakeActorCodeCid, types.NewAttoFILFromFIL(102)),
+		addr2:                 th.RequireNewFakeActorWithTokens(require, vms, addr2, fakeActorCodeCid, types.NewAttoFILFromFIL(0)),
+	})
+
+	// fromAddr asks addr1 to send 100 to addr2.
+	params, err := abi.ToEncodedValues(addr2)
+	require.NoError(err)
+	msg := types.NewMessage(fromAddr, addr1, 0, types.NewAttoFILFromFIL(1), "nestedBalance", params)
+	smsg, err := types.NewSignedMessage(*msg, &mockSigner, types.NewGasPrice(0), types.NewGasUnits(300))
+	require.NoError(err)
+	msgCid, err := smsg.Cid()
+	require.NoError(err)
+
+	blk := &types.Block{
+		Height:    20,
+		StateRoot: stCid,
+		Messages:  []*types.SignedMessage{smsg},
+		Miner:     newAddress(),
+	}
+	ts := th.RequireNewTipSet(require, blk)
+
+	res, trace, err := NewDefaultProcessor().ReplayMessage(ctx, st, vms, ts, nil, msgCid)
+	require.NoError(err)
+	require.NotNil(res)
+	assert.Equal(uint8(0), res.Receipt.ExitCode)
+
+	assert.Equal(fromAddr, trace.From)
+	assert.Equal(addr1, trace.To)
+	assert.Equal("nestedBalance", trace.Method)
+	assert.Contains(trace.StateChanges, &vm.StateChange{
+		Actor: fromAddr,
+		Kind:  vm.StateChangeBalance,
+		Old:   types.NewAttoFILFromFIL(100).String(),
+		New:   types.NewAttoFILFromFIL(99).String(),
+	})
+
+	require.Len(trace.Subcalls, 1)
+	sub := trace.Subcalls[0]
+	assert.Equal(addr1, sub.From)
+	assert.Equal(addr2, sub.To)
+	assert.Equal(types.NewAttoFILFromFIL(100), sub.Value)
+	assert.Contains(sub.StateChanges, &vm.StateChange{
+		Actor: addr2,
+		Kind:  vm.StateChangeBalance,
+		Old:   types.NewAttoFILFromFIL(0).String(),
+		New:   types.NewAttoFILFromFIL(100).String(),
+	})
+
+	_, _, err = NewDefaultProcessor().ReplayMessage(ctx, st, vms, ts, nil, types.SomeCid())
+	assert.Error(err)
+}
+
+func TestParallelMessageExecution(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+
+	fakeActorCodeCid := types.NewCidForTestGetter()()
+	builtin.Actors[fakeActorCodeCid] = &actor.FakeActor{}
+	defer delete(builtin.Actors, fakeActorCodeCid)
+
+	ki := types.MustGenerateKeyInfo(5, types.GenerateKeyInfoSeed())
+	signer := types.NewMockSigner(ki)
+	senders := signer.Addresses
+	addrGetter := address.NewForTestGetter()
+	fake1, fake2, minerAddr := addrGetter(), addrGetter(), addrGetter()
+
+	setup := func() (state.Tree, vm.StorageMap) {
+		vms := th.VMStorage()
+		acts := map[address.Address]*actor.Actor{
+			fake1:                 th.RequireNewFakeActor(require, vms, fake1, fakeActorCodeCid),
+			fake2:                 th.RequireNewFakeActor(require, vms, fake2, fakeActorCodeCid),
+			minerAddr:             th.RequireNewAccountActor(require, types.ZeroAttoFIL),
+			address.RewardAddress: th.RequireNewRewardActor(require, vms, types.NewAttoFILFromFIL(10000000), types.ZeroAttoFIL),
+		}
+		for _, sender := range senders {
+			acts[sender] = th.RequireNewAccountActor(require, types.NewAttoFILFromFIL(100))
+		}
+		_, st := th.RequireMakeStateTree(require, hamt.NewCborStore(), acts)
+		return st, vms
+	}
+
+	msg := func(from, to address.Address, nonce uint64, value uint64, method string) *types.SignedMessage {
+		m := types.NewMessage(from, to, nonce, types.NewAttoFILFromFIL(value), method, nil)
+		smsg, err := types.NewSignedMessage(*m, &signer, *types.NewAttoFIL(big.NewInt(1)), types.NewGasUnits(300))
+		require.NoError(err)
+		return smsg
+	}
+	messages := []*types.SignedMessage{
+		msg(senders[0], senders[1], 0, 10, ""),
+		// reads the receiver of the first message
+		msg(senders[1], senders[2], 0, 105, ""),
+		// independent
+		msg(senders[3], fake1, 0, 0, "goodCall"),
+		// a second message of the same sender
+		msg(senders[0], senders[4], 1, 5, ""),
+		// calls the actor another message changed
+		msg(senders[4], fake1, 0, 0, "goodCall"),
+		// nonce too high
+		msg(senders[2], fake2, 5, 0, "goodCall"),
+		// reverted
+		msg(senders[2], fake2, 0, 0, "returnRevertError"),
+	}
+
+	apply := func(parallelism int) (cid.Cid, ApplyMessagesResponse, state.Tree, vm.StorageMap) {
+		st, vms := setup()
+		processor := NewDefaultProcessor()
+		processor.SetParallelism(parallelism)
+		res, err := processor.ApplyMessagesAndPayRewards(ctx, st, vms, messages, minerAddr, types.NewBlockHeight(1), nil)
+		require.NoError(err)
+		require.NoError(vms.Flush())
+		root, err := st.Flush(ctx)
+		require.NoError(err)
+		return root, res, st, vms
+	}
+
+	sequentialRoot, sequential, _, _ := apply(1)
+	parallelRoot, parallel, st, vms := apply(4)
+
+	assert.Equal(sequentialRoot, parallelRoot)
+	assert.Equal(sequential.SuccessfulMessages, parallel.SuccessfulMessages)
+	assert.Equal(sequential.TemporaryFailures, parallel.TemporaryFailures)
+	assert.Equal(sequential.PermanentFailures, parallel.PermanentFailures)
+	require.Equal(len(sequential.Results), len(parallel.Results))
+	for i := range sequential.Results {
+		assert.Equal(sequential.Results[i].Receipt, parallel.Results[i].Receipt)
+		assert.Equal(sequential.Results[i].ExecutionError, parallel.Results[i].ExecutionError)
+	}
+	assert.Len(parallel.SuccessfulMessages, 6)
+	assert.Equal([]*types.SignedMessage{messages[5]}, parallel.TemporaryFailures)
+	assert.Error(parallel.Results[5].ExecutionError)
+
+	// the actor storage changed by the messages was written to the blockstore
+	fakeAct := state.MustGetActor(st, fake1)
+	chunk, err := vms.Fork().NewStorage(fake1, fakeAct).Get(fakeAct.Head)
+	require.NoError(err)
+	var fakeState actor.FakeActorStorage
+	require.NoError(actor.UnmarshalStorage(chunk, &fakeState))
+	assert.True(fakeState.Changed)
+}
@@ -2,10 +2,13 @@ package consensus
 
 import (
 	"context"
+	"runtime"
+
 	"github.com/filecoin-project/go-filecoin/actor"
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/state"
 	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/vm"
 	"gx/ipfs/QmS2aqUZLJp8kF1ihE5rvDGE5LvmKDPnx32w9Z1BW9xLV5/go-ipfs-blockstore"
 
 	"github.com/stretchr/testify/require"
@@ -88,7 +91,7 @@ type TestBlockRewarder struct{}
 var _ BlockRewarder = (*TestBlockRewarder)(nil)
 
 // BlockReward is a noop
-func (tbr *TestBlockRewarder) BlockReward(ctx context.Context, st state.Tree, minerAddr address.Address) error {
+func (tbr *TestBlockRewarder) BlockReward(ctx context.Context, st state.Tree, vms vm.StorageMap, minerAddr address.Address, bh *types.BlockHeight) error {
 	// do nothing to keep state root the same
 	return nil
 }
@@ -104,5 +107,6 @@ func NewTestProcessor() *DefaultProcessor {
 	return &DefaultProcessor{
 		signedMessageValidator: &TestSignedMessageValidator{},
 		blockRewarder:          &TestBlockRewarder{},
+		parallelism:            runtime.NumCPU(),
 	}
 }
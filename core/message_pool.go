@@ -18,30 +18,157 @@ import (
 // via network or directly created via user command that have yet to be included
 // in a block. Messages are removed as they are processed.
 //
+// Everything that happens to the pool is published as a MessagePoolEvent to
+// subscribers, and summarized by Stats(). Subscribers which fall behind miss
+// events rather than hold up the pool.
+//
 // MessagePool is safe for concurrent access.
 type MessagePool struct {
 	lk sync.RWMutex
 
 	pending map[cid.Cid]*types.SignedMessage // all pending messages
+
+	filters []AdmissionFilter // consulted in order before a message is added
+
+	stats MessagePoolStats
+
+	subsLk sync.Mutex
+	subs   map[chan MessagePoolEvent]struct{}
+}
+
+// subscriberBuffer is the number of events a subscriber can fall behind
+// before it misses some.
+const subscriberBuffer = 128
+
+// AddAdmissionFilter registers a filter that every message must pass before
+// it is added to the pool. Filters are consulted in the order they were
+// registered and the first rejection wins.
+func (pool *MessagePool) AddAdmissionFilter(f AdmissionFilter) {
+	pool.lk.Lock()
+	defer pool.lk.Unlock()
+
+	pool.filters = append(pool.filters, f)
 }
 
 // Add adds a message to the pool.
 func (pool *MessagePool) Add(msg *types.SignedMessage) (cid.Cid, error) {
 	pool.lk.Lock()
-	defer pool.lk.Unlock()
+	c, evt, err := pool.admit(msg)
+	if err == nil {
+		pool.insert(c, msg, &evt)
+	}
+	pool.lk.Unlock()
+
+	pool.publish(evt)
+	if err != nil {
+		return cid.Undef, err
+	}
+	return c, nil
+}
+
+// AddBatch adds all of the given messages to the pool, or none of them if
+// any message is rejected. It returns the CIDs of the messages in the order
+// they were given.
+func (pool *MessagePool) AddBatch(msgs []*types.SignedMessage) ([]cid.Cid, error) {
+	pool.lk.Lock()
+
+	cids := make([]cid.Cid, len(msgs))
+	evts := make([]MessagePoolEvent, len(msgs))
+	for i, msg := range msgs {
+		c, evt, err := pool.admit(msg)
+		if err != nil {
+			pool.lk.Unlock()
+			pool.publish(evt)
+			return nil, errors.Wrapf(err, "message %d of batch", i)
+		}
+		cids[i] = c
+		evts[i] = evt
+	}
+
+	for i, msg := range msgs {
+		pool.insert(cids[i], msg, &evts[i])
+	}
+	pool.lk.Unlock()
+
+	pool.publish(evts...)
+	return cids, nil
+}
+
+// admit checks whether msg may be added to the pool and returns its CID if
+// so, along with the event describing the outcome. The caller must hold the
+// pool lock.
+func (pool *MessagePool) admit(msg *types.SignedMessage) (cid.Cid, MessagePoolEvent, error) {
+	evt := MessagePoolEvent{From: msg.From}
 
 	c, err := msg.Cid()
 	if err != nil {
-		return cid.Undef, errors.Wrap(err, "failed to create CID")
+		return pool.drop(evt, errors.Wrap(err, "failed to create CID"))
 	}
+	evt.Cid = c
 
 	// Reject messages with invalid signatires
 	if !msg.VerifySignature() {
-		return cid.Undef, errors.Errorf("failed to add message %s to pool: sig invalid", c.String())
+		return pool.drop(evt, errors.Errorf("failed to add message %s to pool: sig invalid", c.String()))
+	}
+
+	// Messages already in the pool were admitted once; don't make them
+	// run the gauntlet again (e.g. when a rate limiter sees a duplicate).
+	if _, ok := pool.pending[c]; ok {
+		evt.Type = MessagePoolDuplicate
+		return c, evt, nil
+	}
+
+	for _, f := range pool.filters {
+		if err := f.Admit(msg); err != nil {
+			return pool.drop(evt, errors.Wrapf(&admissionRejectedError{reason: err}, "failed to add message %s to pool", c.String()))
+		}
+	}
+
+	evt.Type = MessagePoolAdded
+	return c, evt, nil
+}
+
+// drop records that a message was refused. The caller must hold the pool
+// lock.
+func (pool *MessagePool) drop(evt MessagePoolEvent, err error) (cid.Cid, MessagePoolEvent, error) {
+	pool.stats.Drops++
+	evt.Type = MessagePoolDropped
+	evt.Reason = err.Error()
+	return cid.Undef, evt, err
+}
+
+// insert puts an admitted message into the pool and updates the stats. The
+// caller must hold the pool lock.
+func (pool *MessagePool) insert(c cid.Cid, msg *types.SignedMessage, evt *MessagePoolEvent) {
+	// A batch may contain the same message twice.
+	if _, ok := pool.pending[c]; ok {
+		evt.Type = MessagePoolDuplicate
+	}
+
+	if evt.Type == MessagePoolDuplicate {
+		pool.stats.Duplicates++
+		return
 	}
 
 	pool.pending[c] = msg
-	return c, nil
+	pool.stats.Adds++
+	pool.stats.Bytes += messageSize(msg)
+}
+
+// publish sends events to subscribers, skipping the ones whose buffer is
+// full so that a slow subscriber never blocks the pool.
+func (pool *MessagePool) publish(evts ...MessagePoolEvent) {
+	pool.subsLk.Lock()
+	defer pool.subsLk.Unlock()
+
+	for sub := range pool.subs {
+		for _, evt := range evts {
+			select {
+			case sub <- evt:
+			default:
+			}
+		}
+	}
 }
 
 // Pending returns all pending messages.
@@ -59,16 +186,65 @@ func (pool *MessagePool) Pending() []*types.SignedMessage {
 // Remove removes the message by CID from the pending pool.
 func (pool *MessagePool) Remove(c cid.Cid) {
 	pool.lk.Lock()
-	defer pool.lk.Unlock()
+	msg, ok := pool.pending[c]
+	if ok {
+		delete(pool.pending, c)
+		pool.stats.Removes++
+		pool.stats.Bytes -= messageSize(msg)
+	}
+	pool.lk.Unlock()
+
+	if ok {
+		pool.publish(MessagePoolEvent{Type: MessagePoolRemoved, Cid: c, From: msg.From})
+	}
+}
+
+// Stats returns a snapshot of the pool's counters and gauges.
+func (pool *MessagePool) Stats() MessagePoolStats {
+	pool.lk.RLock()
+	defer pool.lk.RUnlock()
+
+	stats := pool.stats
+	stats.Size = len(pool.pending)
+	return stats
+}
 
-	delete(pool.pending, c)
+// Subscribe returns a channel on which every subsequent MessagePoolEvent is
+// delivered. Events are dropped while the channel's buffer is full. The
+// channel is closed once ctx is done.
+func (pool *MessagePool) Subscribe(ctx context.Context) <-chan MessagePoolEvent {
+	sub := make(chan MessagePoolEvent, subscriberBuffer)
+
+	pool.subsLk.Lock()
+	pool.subs[sub] = struct{}{}
+	pool.subsLk.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		pool.subsLk.Lock()
+		defer pool.subsLk.Unlock()
+		delete(pool.subs, sub)
+		close(sub)
+	}()
+
+	return sub
 }
 
 // NewMessagePool constructs a new MessagePool.
 func NewMessagePool() *MessagePool {
 	return &MessagePool{
 		pending: make(map[cid.Cid]*types.SignedMessage),
+		subs:    make(map[chan MessagePoolEvent]struct{}),
+	}
+}
+
+// messageSize is the number of bytes msg takes up on the wire.
+func messageSize(msg *types.SignedMessage) int {
+	data, err := msg.Marshal()
+	if err != nil {
+		return 0
 	}
+	return len(data)
 }
 
 // getParentTips returns the parent tipset of the provided tipset
@@ -202,6 +378,11 @@ func UpdateMessagePool(ctx context.Context, pool *MessagePool, store *hamt.CborI
 	// Now actually update the pool.
 	for _, m := range addToPool {
 		_, err := pool.Add(m)
+		if IsAdmissionRejectedError(err) {
+			// Local policy may have changed since the message was first
+			// admitted; it's fine to leave it out of the pool.
+			continue
+		}
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,119 @@
+package core
+
+import (
+	"sync"
+	"time"
+
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// AdmissionFilter decides whether a message may enter the MessagePool.
+// Filters let node operators enforce local policy (blocklists, gas price
+// caps, rate limits, ...) without modifying the pool itself. A filter rejects
+// a message by returning a non-nil error describing why.
+//
+// Filters are called with the pool lock held, so they must not call back
+// into the pool.
+type AdmissionFilter interface {
+	Admit(msg *types.SignedMessage) error
+}
+
+// AdmissionFilterFunc adapts an ordinary function to an AdmissionFilter.
+type AdmissionFilterFunc func(msg *types.SignedMessage) error
+
+// Admit calls f(msg).
+func (f AdmissionFilterFunc) Admit(msg *types.SignedMessage) error {
+	return f(msg)
+}
+
+// IsAdmissionRejectedError is true of the error returned by MessagePool.Add
+// when an admission filter refused the message.
+func IsAdmissionRejectedError(err error) bool {
+	_, ok := errors.Cause(err).(*admissionRejectedError)
+	return ok
+}
+
+type admissionRejectedError struct {
+	reason error
+}
+
+func (e *admissionRejectedError) Error() string {
+	return e.reason.Error()
+}
+
+// NewSenderBlocklistFilter returns a filter that rejects all messages sent
+// from any of the given addresses.
+func NewSenderBlocklistFilter(blocked ...address.Address) AdmissionFilter {
+	set := make(address.Set)
+	for _, a := range blocked {
+		set[a] = struct{}{}
+	}
+	return AdmissionFilterFunc(func(msg *types.SignedMessage) error {
+		if _, ok := set[msg.From]; ok {
+			return errors.Errorf("sender %s is blocked", msg.From)
+		}
+		return nil
+	})
+}
+
+// NewMaxGasPriceFilter returns a filter that rejects messages offering a gas
+// price above max.
+func NewMaxGasPriceFilter(max types.AttoFIL) AdmissionFilter {
+	return AdmissionFilterFunc(func(msg *types.SignedMessage) error {
+		if msg.GasPrice.GreaterThan(&max) {
+			return errors.Errorf("gas price %s exceeds maximum %s", msg.GasPrice.String(), max.String())
+		}
+		return nil
+	})
+}
+
+// SenderRateLimitFilter rejects messages from a sender who has already had
+// Limit messages admitted within the trailing Window.
+type SenderRateLimitFilter struct {
+	lk     sync.Mutex
+	limit  int
+	window time.Duration
+	now    func() time.Time
+	seen   map[address.Address][]time.Time
+}
+
+var _ AdmissionFilter = (*SenderRateLimitFilter)(nil)
+
+// NewSenderRateLimitFilter returns a filter admitting at most limit messages
+// per sender in any window-long period.
+func NewSenderRateLimitFilter(limit int, window time.Duration) *SenderRateLimitFilter {
+	return &SenderRateLimitFilter{
+		limit:  limit,
+		window: window,
+		now:    time.Now,
+		seen:   make(map[address.Address][]time.Time),
+	}
+}
+
+// Admit implements AdmissionFilter.
+func (f *SenderRateLimitFilter) Admit(msg *types.SignedMessage) error {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+
+	now := f.now()
+	cutoff := now.Add(-f.window)
+
+	// Drop timestamps that have aged out of the window.
+	recent := f.seen[msg.From][:0]
+	for _, t := range f.seen[msg.From] {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+
+	if len(recent) >= f.limit {
+		f.seen[msg.From] = recent
+		return errors.Errorf("sender %s exceeded %d messages per %s", msg.From, f.limit, f.window)
+	}
+
+	f.seen[msg.From] = append(recent, now)
+	return nil
+}
@@ -0,0 +1,102 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func TestMessagePoolAdmissionFilters(t *testing.T) {
+	t.Run("rejecting filter keeps message out of the pool", func(t *testing.T) {
+		assert := assert.New(t)
+
+		pool := NewMessagePool()
+		pool.AddAdmissionFilter(AdmissionFilterFunc(func(msg *types.SignedMessage) error {
+			return errors.New("nope")
+		}))
+
+		c, err := pool.Add(newSignedMessage())
+		assert.Error(err)
+		assert.True(IsAdmissionRejectedError(err))
+		assert.Contains(err.Error(), "nope")
+		assert.False(c.Defined())
+		assert.Len(pool.Pending(), 0)
+	})
+
+	t.Run("filters run in registration order", func(t *testing.T) {
+		assert := assert.New(t)
+
+		var calls []string
+		pool := NewMessagePool()
+		pool.AddAdmissionFilter(AdmissionFilterFunc(func(msg *types.SignedMessage) error {
+			calls = append(calls, "first")
+			return nil
+		}))
+		pool.AddAdmissionFilter(AdmissionFilterFunc(func(msg *types.SignedMessage) error {
+			calls = append(calls, "second")
+			return nil
+		}))
+
+		_, err := pool.Add(newSignedMessage())
+		assert.NoError(err)
+		assert.Equal([]string{"first", "second"}, calls)
+		assert.Len(pool.Pending(), 1)
+	})
+
+	t.Run("duplicates are not filtered again", func(t *testing.T) {
+		assert := assert.New(t)
+
+		pool := NewMessagePool()
+		pool.AddAdmissionFilter(NewSenderRateLimitFilter(1, time.Hour))
+
+		msg := newSignedMessage()
+		_, err := pool.Add(msg)
+		assert.NoError(err)
+		_, err = pool.Add(msg)
+		assert.NoError(err)
+		assert.Len(pool.Pending(), 1)
+	})
+}
+
+func TestSenderBlocklistFilter(t *testing.T) {
+	assert := assert.New(t)
+
+	msg := newSignedMessage()
+	other := address.NewForTestGetter()()
+
+	assert.Error(NewSenderBlocklistFilter(msg.From).Admit(msg))
+	assert.NoError(NewSenderBlocklistFilter(other).Admit(msg))
+}
+
+func TestMaxGasPriceFilter(t *testing.T) {
+	assert := assert.New(t)
+
+	msg := newSignedMessage()
+	msg.GasPrice = types.NewGasPrice(10)
+
+	assert.NoError(NewMaxGasPriceFilter(types.NewGasPrice(10)).Admit(msg))
+	assert.Error(NewMaxGasPriceFilter(types.NewGasPrice(9)).Admit(msg))
+}
+
+func TestSenderRateLimitFilter(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Unix(1000, 0)
+	f := NewSenderRateLimitFilter(2, time.Minute)
+	f.now = func() time.Time { return now }
+
+	msg := newSignedMessage()
+	assert.NoError(f.Admit(msg))
+	assert.NoError(f.Admit(msg))
+	assert.Error(f.Admit(msg))
+
+	// Once the window slides past the first admissions the sender may send again.
+	now = now.Add(time.Minute + time.Second)
+	assert.NoError(f.Admit(msg))
+}
@@ -0,0 +1,46 @@
+package core
+
+import (
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+
+	"github.com/filecoin-project/go-filecoin/address"
+)
+
+// MessagePoolEventType identifies what happened in a MessagePoolEvent.
+type MessagePoolEventType string
+
+const (
+	// MessagePoolAdded is published when a new message enters the pool.
+	MessagePoolAdded = MessagePoolEventType("added")
+	// MessagePoolDuplicate is published when a message already in the pool is
+	// added again.
+	MessagePoolDuplicate = MessagePoolEventType("duplicate")
+	// MessagePoolDropped is published when a message is refused, either because
+	// it is invalid or because an admission filter rejected it.
+	MessagePoolDropped = MessagePoolEventType("dropped")
+	// MessagePoolRemoved is published when a message leaves the pool.
+	MessagePoolRemoved = MessagePoolEventType("removed")
+)
+
+// MessagePoolEvent describes a change to the message pool.
+type MessagePoolEvent struct {
+	Type MessagePoolEventType `json:"type"`
+	Cid  cid.Cid              `json:"cid"`
+	From address.Address      `json:"from"`
+	// Reason is set for dropped messages.
+	Reason string `json:"reason,omitempty"`
+}
+
+// MessagePoolStats is a snapshot of the message pool's counters and gauges.
+// Counters are cumulative over the lifetime of the pool.
+type MessagePoolStats struct {
+	// Counters.
+	Adds       uint64 `json:"adds"`
+	Duplicates uint64 `json:"duplicates"`
+	Drops      uint64 `json:"drops"`
+	Removes    uint64 `json:"removes"`
+
+	// Gauges.
+	Size  int `json:"size"`
+	Bytes int `json:"bytes"`
+}
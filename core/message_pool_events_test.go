@@ -0,0 +1,102 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func TestMessagePoolStats(t *testing.T) {
+	assert := assert.New(t)
+
+	pool := NewMessagePool()
+	msg1 := newSignedMessage()
+	msg2 := newSignedMessage()
+	bad := newSignedMessage()
+	bad.Message.Nonce = types.Uint64(uint64(bad.Message.Nonce) + uint64(1)) // invalidate message
+
+	MustAdd(pool, msg1, msg2, msg1)
+	_, err := pool.Add(bad)
+	assert.Error(err)
+
+	c1, err := msg1.Cid()
+	assert.NoError(err)
+	pool.Remove(c1)
+
+	stats := pool.Stats()
+	assert.Equal(uint64(2), stats.Adds)
+	assert.Equal(uint64(1), stats.Duplicates)
+	assert.Equal(uint64(1), stats.Drops)
+	assert.Equal(uint64(1), stats.Removes)
+	assert.Equal(1, stats.Size)
+	assert.Equal(messageSize(msg2), stats.Bytes)
+}
+
+func TestMessagePoolSubscribe(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pool := NewMessagePool()
+	events := pool.Subscribe(ctx)
+
+	msg := newSignedMessage()
+	c, err := pool.Add(msg)
+	require.NoError(err)
+	pool.Remove(c)
+
+	next := func() MessagePoolEvent {
+		select {
+		case evt := <-events:
+			return evt
+		case <-time.After(5 * time.Second):
+			require.FailNow("timed out waiting for message pool event")
+		}
+		return MessagePoolEvent{}
+	}
+
+	added := next()
+	assert.Equal(MessagePoolAdded, added.Type)
+	assert.Equal(c, added.Cid)
+	assert.Equal(msg.From, added.From)
+
+	removed := next()
+	assert.Equal(MessagePoolRemoved, removed.Type)
+	assert.Equal(c, removed.Cid)
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestMessagePoolSlowSubscriber(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pool := NewMessagePool()
+	events := pool.Subscribe(ctx)
+
+	// Nobody reads events, the pool carries on once the buffer is full.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 2*subscriberBuffer; i++ {
+			MustAdd(pool, newSignedMessage())
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		require.FailNow("a slow subscriber blocked the message pool")
+	}
+
+	assert.Len(events, subscriberBuffer)
+	assert.Equal(2*subscriberBuffer, pool.Stats().Size)
+}
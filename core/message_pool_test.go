@@ -6,6 +6,7 @@ import (
 	"sync"
 	"testing"
 
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
 	hamt "gx/ipfs/QmRXf2uUSdGSunRJsM9wXSUNVwLUGCY3So5fAs7h2CBJVf/go-hamt-ipld"
 
 	"github.com/stretchr/testify/assert"
@@ -74,6 +75,38 @@ func TestMessagePoolDedup(t *testing.T) {
 	assert.Len(pool.Pending(), 1)
 }
 
+func TestMessagePoolAddBatch(t *testing.T) {
+	t.Run("adds all messages", func(t *testing.T) {
+		assert := assert.New(t)
+
+		pool := NewMessagePool()
+		msg1 := newSignedMessage()
+		msg2 := newSignedMessage()
+
+		cids, err := pool.AddBatch([]*types.SignedMessage{msg1, msg2})
+		assert.NoError(err)
+		assert.Len(pool.Pending(), 2)
+
+		c1, err := msg1.Cid()
+		assert.NoError(err)
+		c2, err := msg2.Cid()
+		assert.NoError(err)
+		assert.Equal([]cid.Cid{c1, c2}, cids)
+	})
+
+	t.Run("adds nothing if one message is bad", func(t *testing.T) {
+		assert := assert.New(t)
+
+		pool := NewMessagePool()
+		bad := newSignedMessage()
+		bad.Message.Nonce = types.Uint64(uint64(bad.Message.Nonce) + uint64(1)) // invalidate message
+
+		_, err := pool.AddBatch([]*types.SignedMessage{newSignedMessage(), bad})
+		assert.Error(err)
+		assert.Len(pool.Pending(), 0)
+	})
+}
+
 func TestMessagePoolAsync(t *testing.T) {
 	assert := assert.New(t)
 
@@ -17,22 +17,24 @@ type Error string
 
 func (e Error) Error() string { return string(e) }
 
+// The exit codes of storage and gas failures. They are VM exit codes, see
+// package vm/errors.
 const (
 	// ErrDecode indicates that a chunk an actor tried to write could not be decoded
-	ErrDecode = 33
+	ErrDecode = errors.ErrDecode
 	// ErrDanglingPointer indicates that an actor attempted to commit a pointer to a non-existent chunk
-	ErrDanglingPointer = 34
+	ErrDanglingPointer = errors.ErrDanglingPointer
 	// ErrStaleHead indicates that an actor attempted to commit over a stale chunk
-	ErrStaleHead = 35
+	ErrStaleHead = errors.ErrStaleHead
 	// ErrInsufficientGas indicates that an actor did not have sufficient gas to run a message
-	ErrInsufficientGas = 36
+	ErrInsufficientGas = errors.ErrInsufficientGas
 )
 
 // Errors map error codes to revert errors this actor may return
 var Errors = map[uint8]error{
-	ErrDecode:          errors.NewCodedRevertError(ErrDecode, "State could not be decoded"),
-	ErrDanglingPointer: errors.NewCodedRevertError(ErrDanglingPointer, "State contains pointer to non-existent chunk"),
-	ErrStaleHead:       errors.NewCodedRevertError(ErrStaleHead, "Expected head is stale"),
+	ErrDecode:          errors.Errors[ErrDecode],
+	ErrDanglingPointer: errors.Errors[ErrDanglingPointer],
+	ErrStaleHead:       errors.Errors[ErrStaleHead],
 }
 
 // Exports describe the public methods of an actor.
@@ -56,6 +58,12 @@ type ExecutableActor interface {
 // ExportedFunc is the signature an exported method of an actor is expected to have.
 type ExportedFunc func(ctx VMContext) ([]byte, uint8, error)
 
+// Dispatcher is implemented by actors with generated dispatch code. Method
+// returns the exported method with the given name, bound to the actor.
+type Dispatcher interface {
+	Method(name string) (ExportedFunc, bool)
+}
+
 // FunctionSignature describes the signature of a single function.
 // TODO: convert signatures into non go types, but rather low level agreed up types
 type FunctionSignature struct {
@@ -74,6 +82,14 @@ type VMContext interface {
 	BlockHeight() *types.BlockHeight
 	IsFromAccountActor() bool
 	Charge(cost types.GasUnits) error
+	// ChargeSealVerification charges for verifying the seal proofs of the
+	// given number of sectors, at the prices of the current block height.
+	ChargeSealVerification(sectors int) error
+	EmitEvent(eventType string, values ...interface{}) error
+	// Abort stops the execution of the actor method and reverts the message
+	// with the given exit code and message. It does not return. Codes
+	// reserved for the VM are replaced by ErrGeneric.
+	Abort(code uint8, msg string)
 
 	CreateNewActor(addr address.Address, code cid.Cid, initalizationParams interface{}) error
 
@@ -0,0 +1,313 @@
+package filnet
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	inet "gx/ipfs/QmNgLg1NTw37iWbYPKcyK85YJ9Whs1MkPtJwhfqbNYAyKg/go-libp2p-net"
+	peer "gx/ipfs/QmY5Grm8pJdiSSVsYxx4uNRgweY72EmYwuSDbRnbFok3iY/go-libp2p-peer"
+	protocol "gx/ipfs/QmZNkThpqfVXs9GNbexPrfBbXSLNYeKrE7jwFM2oqHbyqN/go-libp2p-protocol"
+	host "gx/ipfs/QmaoXrM4Z41PD48JY36YqQGKQpLGjyLA2cKcLsES7YddAq/go-libp2p-host"
+)
+
+// bandwidthPeerTTL is how long the bandwidth of peers which stopped sending
+// and receiving is kept.
+const bandwidthPeerTTL = time.Hour
+
+// maxLimitedWrite is the most bytes written at once to streams, so that the
+// writes of rate limited protocols are spread over time.
+const maxLimitedWrite = 32 << 10
+
+// BandwidthStats are the bytes sent and received, in total and per second.
+type BandwidthStats struct {
+	TotalIn  uint64  `json:"totalIn"`
+	TotalOut uint64  `json:"totalOut"`
+	RateIn   float64 `json:"rateIn"`
+	RateOut  float64 `json:"rateOut"`
+}
+
+// BandwidthReport is the bandwidth of the node, in total, by protocol and by
+// peer, with the limits on the rates protocols send at. Peers are keyed by
+// their base58 ids.
+type BandwidthReport struct {
+	Total     BandwidthStats            `json:"total"`
+	Protocols map[string]BandwidthStats `json:"protocols"`
+	Peers     map[string]BandwidthStats `json:"peers"`
+	Limits    map[string]uint64         `json:"limits"`
+}
+
+// BandwidthMeter meters the bytes the streams of a host send and receive,
+// by protocol and by peer, and limits the rate protocols send at, e.g. so
+// that serving blocks doesn't take the I/O a miner needs to seal.
+type BandwidthMeter struct {
+	now func() time.Time
+
+	lk        sync.Mutex
+	total     *bandwidthCounter
+	protocols map[protocol.ID]*bandwidthCounter
+	peers     map[peer.ID]*bandwidthCounter
+	// limits holds the rate limits by protocol prefix.
+	limits map[string]*tokenBucket
+}
+
+// NewBandwidthMeter returns a BandwidthMeter without rate limits.
+func NewBandwidthMeter() *BandwidthMeter {
+	now := time.Now
+	return &BandwidthMeter{
+		now:       now,
+		total:     newBandwidthCounter(now()),
+		protocols: make(map[protocol.ID]*bandwidthCounter),
+		peers:     make(map[peer.ID]*bandwidthCounter),
+		limits:    make(map[string]*tokenBucket),
+	}
+}
+
+// SetLimit limits the rate the protocols starting with prefix send at, all
+// together, to bytesPerSecond. A limit of 0 removes the limit. Protocols
+// matching several prefixes are limited by the longest.
+func (bm *BandwidthMeter) SetLimit(prefix string, bytesPerSecond uint64) {
+	bm.lk.Lock()
+	defer bm.lk.Unlock()
+
+	if bytesPerSecond == 0 {
+		delete(bm.limits, prefix)
+		return
+	}
+	bm.limits[prefix] = newTokenBucket(float64(bytesPerSecond), bm.now())
+}
+
+// WrapHost returns the host with the streams of its protocols metered and
+// rate limited.
+func (bm *BandwidthMeter) WrapHost(h host.Host) host.Host {
+	return &meteredHost{Host: h, meter: bm}
+}
+
+// Report returns the bandwidth of the node as of the last sample.
+func (bm *BandwidthMeter) Report() *BandwidthReport {
+	bm.lk.Lock()
+	defer bm.lk.Unlock()
+
+	report := &BandwidthReport{
+		Total:     bm.total.stats(),
+		Protocols: make(map[string]BandwidthStats),
+		Peers:     make(map[string]BandwidthStats),
+		Limits:    make(map[string]uint64),
+	}
+	for proto, c := range bm.protocols {
+		report.Protocols[string(proto)] = c.stats()
+	}
+	for p, c := range bm.peers {
+		report.Peers[p.Pretty()] = c.stats()
+	}
+	for prefix, b := range bm.limits {
+		report.Limits[prefix] = uint64(b.rate)
+	}
+	return report
+}
+
+// Run samples the rates every interval, and forgets the peers idle for long.
+func (bm *BandwidthMeter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			bm.sample()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (bm *BandwidthMeter) sample() {
+	bm.lk.Lock()
+	defer bm.lk.Unlock()
+
+	now := bm.now()
+	bm.total.sample(now)
+	for _, c := range bm.protocols {
+		c.sample(now)
+	}
+	for p, c := range bm.peers {
+		c.sample(now)
+		if now.Sub(c.lastActive) > bandwidthPeerTTL {
+			delete(bm.peers, p)
+		}
+	}
+}
+
+func (bm *BandwidthMeter) record(s inet.Stream, in, out int) {
+	if in == 0 && out == 0 {
+		return
+	}
+
+	bm.lk.Lock()
+	defer bm.lk.Unlock()
+
+	now := bm.now()
+	bm.total.add(now, in, out)
+
+	proto := s.Protocol()
+	pc, ok := bm.protocols[proto]
+	if !ok {
+		pc = newBandwidthCounter(now)
+		bm.protocols[proto] = pc
+	}
+	pc.add(now, in, out)
+
+	p := s.Conn().RemotePeer()
+	peerc, ok := bm.peers[p]
+	if !ok {
+		peerc = newBandwidthCounter(now)
+		bm.peers[p] = peerc
+	}
+	peerc.add(now, in, out)
+}
+
+// reserve takes n bytes from the limit of the protocol, returning how long
+// to wait before sending them.
+func (bm *BandwidthMeter) reserve(proto protocol.ID, n int) time.Duration {
+	bm.lk.Lock()
+	defer bm.lk.Unlock()
+
+	var prefixes []string
+	for prefix := range bm.limits {
+		if strings.HasPrefix(string(proto), prefix) {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	if len(prefixes) == 0 {
+		return 0
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+	return bm.limits[prefixes[0]].reserve(bm.now(), n)
+}
+
+type bandwidthCounter struct {
+	in, out    uint64
+	rateIn     float64
+	rateOut    float64
+	sampledIn  uint64
+	sampledOut uint64
+	sampled    time.Time
+	lastActive time.Time
+}
+
+func newBandwidthCounter(now time.Time) *bandwidthCounter {
+	return &bandwidthCounter{sampled: now, lastActive: now}
+}
+
+func (c *bandwidthCounter) add(now time.Time, in, out int) {
+	c.in += uint64(in)
+	c.out += uint64(out)
+	c.lastActive = now
+}
+
+func (c *bandwidthCounter) sample(now time.Time) {
+	elapsed := now.Sub(c.sampled).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	c.rateIn = float64(c.in-c.sampledIn) / elapsed
+	c.rateOut = float64(c.out-c.sampledOut) / elapsed
+	c.sampledIn, c.sampledOut, c.sampled = c.in, c.out, now
+}
+
+func (c *bandwidthCounter) stats() BandwidthStats {
+	return BandwidthStats{TotalIn: c.in, TotalOut: c.out, RateIn: c.rateIn, RateOut: c.rateOut}
+}
+
+// tokenBucket allows sending rate bytes per second, in bursts of up to a
+// second's worth.
+type tokenBucket struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, now time.Time) *tokenBucket {
+	return &tokenBucket{rate: rate, tokens: rate, last: now}
+}
+
+// reserve takes n tokens, returning how long to wait until the bucket is no
+// longer in debt.
+func (b *tokenBucket) reserve(now time.Time, n int) time.Duration {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.rate {
+		b.tokens = b.rate
+	}
+	b.last = now
+
+	b.tokens -= float64(n)
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// meteredHost wraps the streams of the protocols of a host in
+// meteredStreams.
+type meteredHost struct {
+	host.Host
+	meter *BandwidthMeter
+}
+
+func (h *meteredHost) SetStreamHandler(pid protocol.ID, handler inet.StreamHandler) {
+	h.Host.SetStreamHandler(pid, h.wrapHandler(handler))
+}
+
+func (h *meteredHost) SetStreamHandlerMatch(pid protocol.ID, match func(string) bool, handler inet.StreamHandler) {
+	h.Host.SetStreamHandlerMatch(pid, match, h.wrapHandler(handler))
+}
+
+func (h *meteredHost) NewStream(ctx context.Context, p peer.ID, pids ...protocol.ID) (inet.Stream, error) {
+	s, err := h.Host.NewStream(ctx, p, pids...)
+	if err != nil {
+		return nil, err
+	}
+	return &meteredStream{Stream: s, meter: h.meter}, nil
+}
+
+func (h *meteredHost) wrapHandler(handler inet.StreamHandler) inet.StreamHandler {
+	return func(s inet.Stream) {
+		handler(&meteredStream{Stream: s, meter: h.meter})
+	}
+}
+
+// meteredStream records the bytes read from and written to a stream, and
+// waits before writing while its protocol is over its rate limit.
+type meteredStream struct {
+	inet.Stream
+	meter *BandwidthMeter
+}
+
+func (s *meteredStream) Read(b []byte) (int, error) {
+	n, err := s.Stream.Read(b)
+	s.meter.record(s.Stream, n, 0)
+	return n, err
+}
+
+func (s *meteredStream) Write(b []byte) (int, error) {
+	var written int
+	for len(b) > 0 {
+		chunk := b
+		if len(chunk) > maxLimitedWrite {
+			chunk = chunk[:maxLimitedWrite]
+		}
+		if wait := s.meter.reserve(s.Stream.Protocol(), len(chunk)); wait > 0 {
+			time.Sleep(wait)
+		}
+
+		n, err := s.Stream.Write(chunk)
+		s.meter.record(s.Stream, 0, n)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		b = b[n:]
+	}
+	return written, nil
+}
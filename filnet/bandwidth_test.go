@@ -0,0 +1,95 @@
+package filnet
+
+import (
+	"testing"
+	"time"
+
+	inet "gx/ipfs/QmNgLg1NTw37iWbYPKcyK85YJ9Whs1MkPtJwhfqbNYAyKg/go-libp2p-net"
+	peer "gx/ipfs/QmY5Grm8pJdiSSVsYxx4uNRgweY72EmYwuSDbRnbFok3iY/go-libp2p-peer"
+	protocol "gx/ipfs/QmZNkThpqfVXs9GNbexPrfBbXSLNYeKrE7jwFM2oqHbyqN/go-libp2p-protocol"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type bandwidthTestConn struct {
+	inet.Conn
+	remote peer.ID
+}
+
+func (c *bandwidthTestConn) RemotePeer() peer.ID {
+	return c.remote
+}
+
+type bandwidthTestStream struct {
+	inet.Stream
+	proto protocol.ID
+	conn  *bandwidthTestConn
+}
+
+func (s *bandwidthTestStream) Protocol() protocol.ID {
+	return s.proto
+}
+
+func (s *bandwidthTestStream) Conn() inet.Conn {
+	return s.conn
+}
+
+func TestBandwidthMeter(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1000, 0)
+	newMeter := func() *BandwidthMeter {
+		bm := NewBandwidthMeter()
+		bm.now = func() time.Time { return now }
+		bm.total = newBandwidthCounter(now)
+		return bm
+	}
+	stream := func(proto protocol.ID, p peer.ID) inet.Stream {
+		return &bandwidthTestStream{proto: proto, conn: &bandwidthTestConn{remote: p}}
+	}
+
+	t.Run("meters by protocol and by peer", func(t *testing.T) {
+		bm := newMeter()
+		bm.record(stream("/ipfs/bitswap/1.1.0", "a"), 100, 0)
+		bm.record(stream("/ipfs/bitswap/1.1.0", "b"), 0, 300)
+		bm.record(stream("/fil/hello/2.0.0", "a"), 10, 20)
+
+		now = now.Add(2 * time.Second)
+		bm.sample()
+
+		report := bm.Report()
+		assert.Equal(t, BandwidthStats{TotalIn: 110, TotalOut: 320, RateIn: 55, RateOut: 160}, report.Total)
+		assert.Equal(t, BandwidthStats{TotalIn: 100, TotalOut: 300, RateIn: 50, RateOut: 150}, report.Protocols["/ipfs/bitswap/1.1.0"])
+		assert.Equal(t, BandwidthStats{TotalIn: 110, TotalOut: 20, RateIn: 55, RateOut: 10}, report.Peers[peer.ID("a").Pretty()])
+	})
+
+	t.Run("forgets idle peers", func(t *testing.T) {
+		bm := newMeter()
+		bm.record(stream("/ipfs/bitswap/1.1.0", "a"), 100, 0)
+
+		now = now.Add(bandwidthPeerTTL + time.Second)
+		bm.sample()
+
+		report := bm.Report()
+		assert.Empty(t, report.Peers)
+		assert.Equal(t, uint64(100), report.Protocols["/ipfs/bitswap/1.1.0"].TotalIn)
+	})
+
+	t.Run("limits protocols by their longest prefix", func(t *testing.T) {
+		bm := newMeter()
+		bm.SetLimit("/ipfs/", 1000)
+		bm.SetLimit("/ipfs/bitswap", 100)
+
+		assert.Equal(t, time.Duration(0), bm.reserve("/ipfs/bitswap/1.1.0", 100))
+		assert.Equal(t, 500*time.Millisecond, bm.reserve("/ipfs/bitswap/1.1.0", 50))
+		assert.Equal(t, time.Duration(0), bm.reserve("/ipfs/kad/1.0.0", 1000))
+		assert.Equal(t, time.Duration(0), bm.reserve("/fil/hello/2.0.0", 1<<20))
+
+		// the debt is paid back over time
+		now = now.Add(time.Second)
+		assert.Equal(t, time.Duration(0), bm.reserve("/ipfs/bitswap/1.1.0", 50))
+
+		bm.SetLimit("/ipfs/bitswap", 0)
+		assert.Equal(t, map[string]uint64{"/ipfs/": 1000}, bm.Report().Limits)
+	})
+}
@@ -0,0 +1,305 @@
+package filnet
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	ma "gx/ipfs/QmNTCey11oxhb1AxDnQBRHtdhap6Ctud872NjAYPYYXPuc/go-multiaddr"
+	inet "gx/ipfs/QmNgLg1NTw37iWbYPKcyK85YJ9Whs1MkPtJwhfqbNYAyKg/go-libp2p-net"
+	peer "gx/ipfs/QmY5Grm8pJdiSSVsYxx4uNRgweY72EmYwuSDbRnbFok3iY/go-libp2p-peer"
+)
+
+// ConnManager keeps the number of peers the node is connected to between a
+// low and a high watermark. When more than highWater peers are connected, it
+// disconnects the peers which were idle the longest until lowWater are left.
+// Protected peers, and peers connected for less than the grace period, are
+// never disconnected.
+type ConnManager struct {
+	network     inet.Network
+	lowWater    int
+	highWater   int
+	gracePeriod time.Duration
+	now         func() time.Time
+
+	lk    sync.Mutex
+	peers map[peer.ID]*connPeer
+	// protected holds the protections of peers by tag, with the time each
+	// ends, zero if it doesn't.
+	protected map[peer.ID]map[string]time.Time
+	// protocols holds the protections given to peers whose streams of
+	// protocols with a prefix close.
+	protocols []protocolProtection
+	trimming  bool
+}
+
+type connPeer struct {
+	conns      int
+	connected  time.Time
+	lastActive time.Time
+}
+
+type protocolProtection struct {
+	prefix string
+	tag    string
+	d      time.Duration
+}
+
+// ProtectedPeer is a peer the ConnManager doesn't disconnect, with the tags
+// it is protected by.
+type ProtectedPeer struct {
+	Peer peer.ID  `json:"peer"`
+	Tags []string `json:"tags"`
+}
+
+// NewConnManager returns a ConnManager keeping the peers connected to network
+// between lowWater and highWater.
+func NewConnManager(network inet.Network, lowWater, highWater int, gracePeriod time.Duration) *ConnManager {
+	cm := &ConnManager{
+		network:     network,
+		lowWater:    lowWater,
+		highWater:   highWater,
+		gracePeriod: gracePeriod,
+		now:         time.Now,
+		peers:       make(map[peer.ID]*connPeer),
+		protected:   make(map[peer.ID]map[string]time.Time),
+	}
+	if network != nil {
+		network.Notify((*connNotify)(cm))
+	}
+	return cm
+}
+
+// Protect protects the peer from being disconnected until Unprotect is
+// called with the same tag.
+func (cm *ConnManager) Protect(p peer.ID, tag string) {
+	cm.protect(p, tag, time.Time{})
+}
+
+// ProtectFor protects the peer from being disconnected for d, or until
+// Unprotect is called with the same tag.
+func (cm *ConnManager) ProtectFor(p peer.ID, tag string, d time.Duration) {
+	cm.protect(p, tag, cm.now().Add(d))
+}
+
+// ProtectProtocols protects, for d, the peers whose streams of protocols
+// starting with one of the prefixes close.
+func (cm *ConnManager) ProtectProtocols(tag string, d time.Duration, prefixes ...string) {
+	cm.lk.Lock()
+	defer cm.lk.Unlock()
+
+	for _, prefix := range prefixes {
+		cm.protocols = append(cm.protocols, protocolProtection{prefix: prefix, tag: tag, d: d})
+	}
+}
+
+// Unprotect removes the protection of the peer by the tag, returning whether
+// the peer is still protected by other tags.
+func (cm *ConnManager) Unprotect(p peer.ID, tag string) bool {
+	cm.lk.Lock()
+	defer cm.lk.Unlock()
+
+	delete(cm.protected[p], tag)
+	return cm.isProtected(p)
+}
+
+// Protected lists the protected peers.
+func (cm *ConnManager) Protected() []ProtectedPeer {
+	cm.lk.Lock()
+	defer cm.lk.Unlock()
+
+	var protected []ProtectedPeer
+	for p := range cm.protected {
+		if !cm.isProtected(p) {
+			continue
+		}
+		pp := ProtectedPeer{Peer: p}
+		for tag := range cm.protected[p] {
+			pp.Tags = append(pp.Tags, tag)
+		}
+		sort.Strings(pp.Tags)
+		protected = append(protected, pp)
+	}
+	sort.Slice(protected, func(i, j int) bool { return protected[i].Peer < protected[j].Peer })
+	return protected
+}
+
+// TrimOpenConns disconnects peers if more than highWater are connected.
+func (cm *ConnManager) TrimOpenConns(ctx context.Context) {
+	cm.lk.Lock()
+	if cm.trimming {
+		cm.lk.Unlock()
+		return
+	}
+	cm.trimming = true
+	toClose := cm.selectTrim()
+	cm.lk.Unlock()
+
+	defer func() {
+		cm.lk.Lock()
+		cm.trimming = false
+		cm.lk.Unlock()
+	}()
+
+	if len(toClose) > 0 {
+		log.Infof("connection manager disconnecting %d peers", len(toClose))
+	}
+	for _, p := range toClose {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := cm.network.ClosePeer(p); err != nil {
+			log.Warningf("failed to disconnect peer %s: %s", p, err)
+		}
+	}
+}
+
+// Run trims the connections of the node every interval, so that peers out
+// of their grace period are disconnected without waiting for new peers to
+// connect.
+func (cm *ConnManager) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			cm.TrimOpenConns(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// selectTrim returns the peers to disconnect to get back to lowWater, the
+// ones idle the longest first. The caller holds lk.
+func (cm *ConnManager) selectTrim() []peer.ID {
+	if len(cm.peers) <= cm.highWater {
+		return nil
+	}
+
+	now := cm.now()
+	var candidates []peer.ID
+	for p, cp := range cm.peers {
+		if cm.isProtected(p) || now.Sub(cp.connected) < cm.gracePeriod {
+			continue
+		}
+		candidates = append(candidates, p)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return cm.peers[candidates[i]].lastActive.Before(cm.peers[candidates[j]].lastActive)
+	})
+
+	n := len(cm.peers) - cm.lowWater
+	if n > len(candidates) {
+		n = len(candidates)
+	}
+	return candidates[:n]
+}
+
+func (cm *ConnManager) protect(p peer.ID, tag string, until time.Time) {
+	cm.lk.Lock()
+	defer cm.lk.Unlock()
+
+	tags, ok := cm.protected[p]
+	if !ok {
+		tags = make(map[string]time.Time)
+		cm.protected[p] = tags
+	}
+	// a protection for good isn't shortened by a temporary one
+	if end, ok := tags[tag]; ok && (end.IsZero() || (!until.IsZero() && end.After(until))) {
+		return
+	}
+	tags[tag] = until
+}
+
+// isProtected returns whether the peer is protected, dropping the
+// protections which ended. The caller holds lk.
+func (cm *ConnManager) isProtected(p peer.ID) bool {
+	now := cm.now()
+	for tag, until := range cm.protected[p] {
+		if !until.IsZero() && !now.Before(until) {
+			delete(cm.protected[p], tag)
+		}
+	}
+	if len(cm.protected[p]) == 0 {
+		delete(cm.protected, p)
+		return false
+	}
+	return true
+}
+
+func (cm *ConnManager) active(p peer.ID) {
+	cm.lk.Lock()
+	defer cm.lk.Unlock()
+
+	if cp, ok := cm.peers[p]; ok {
+		cp.lastActive = cm.now()
+	}
+}
+
+// connNotify keeps track of the peers of the ConnManager and of their
+// activity.
+type connNotify ConnManager
+
+func (cn *connNotify) Connected(n inet.Network, c inet.Conn) {
+	cm := (*ConnManager)(cn)
+	cm.lk.Lock()
+	p := c.RemotePeer()
+	cp, ok := cm.peers[p]
+	if !ok {
+		now := cm.now()
+		cp = &connPeer{connected: now, lastActive: now}
+		cm.peers[p] = cp
+	}
+	cp.conns++
+	trim := len(cm.peers) > cm.highWater && !cm.trimming
+	cm.lk.Unlock()
+
+	if trim {
+		go cm.TrimOpenConns(context.Background())
+	}
+}
+
+func (cn *connNotify) Disconnected(n inet.Network, c inet.Conn) {
+	cm := (*ConnManager)(cn)
+	cm.lk.Lock()
+	defer cm.lk.Unlock()
+
+	p := c.RemotePeer()
+	cp, ok := cm.peers[p]
+	if !ok {
+		return
+	}
+	cp.conns--
+	if cp.conns <= 0 {
+		delete(cm.peers, p)
+	}
+}
+
+func (cn *connNotify) OpenedStream(n inet.Network, s inet.Stream) {
+	(*ConnManager)(cn).active(s.Conn().RemotePeer())
+}
+
+func (cn *connNotify) ClosedStream(n inet.Network, s inet.Stream) {
+	cm := (*ConnManager)(cn)
+	p := s.Conn().RemotePeer()
+	cm.active(p)
+
+	cm.lk.Lock()
+	var protections []protocolProtection
+	for _, pp := range cm.protocols {
+		if strings.HasPrefix(string(s.Protocol()), pp.prefix) {
+			protections = append(protections, pp)
+		}
+	}
+	cm.lk.Unlock()
+
+	for _, pp := range protections {
+		cm.ProtectFor(p, pp.tag, pp.d)
+	}
+}
+
+func (cn *connNotify) Listen(n inet.Network, a ma.Multiaddr)      {}
+func (cn *connNotify) ListenClose(n inet.Network, a ma.Multiaddr) {}
@@ -0,0 +1,79 @@
+package filnet
+
+import (
+	"testing"
+	"time"
+
+	peer "gx/ipfs/QmY5Grm8pJdiSSVsYxx4uNRgweY72EmYwuSDbRnbFok3iY/go-libp2p-peer"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnManager(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1000, 0)
+	newConnManager := func() *ConnManager {
+		cm := NewConnManager(nil, 2, 3, time.Minute)
+		cm.now = func() time.Time { return now }
+		return cm
+	}
+	// connect adds a peer connected at start, last active at start plus
+	// idle seconds
+	connect := func(cm *ConnManager, p peer.ID, start time.Time, active int) {
+		cm.peers[p] = &connPeer{conns: 1, connected: start, lastActive: start.Add(time.Duration(active) * time.Second)}
+	}
+	old := now.Add(-time.Hour)
+
+	t.Run("trims the idlest peers down to the low watermark", func(t *testing.T) {
+		cm := newConnManager()
+		connect(cm, "a", old, 4)
+		connect(cm, "b", old, 1)
+		connect(cm, "c", old, 3)
+		assert.Empty(t, cm.selectTrim())
+
+		connect(cm, "d", old, 2)
+		assert.Equal(t, []peer.ID{"b", "d"}, cm.selectTrim())
+	})
+
+	t.Run("spares protected peers and peers in their grace period", func(t *testing.T) {
+		cm := newConnManager()
+		connect(cm, "a", old, 1)
+		connect(cm, "b", old, 2)
+		connect(cm, "c", now, 0)
+		connect(cm, "d", old, 3)
+		cm.Protect("a", "bootstrap")
+
+		assert.Equal(t, []peer.ID{"b", "d"}, cm.selectTrim())
+
+		cm.Protect("b", "sync")
+		cm.Protect("d", "sync")
+		assert.Empty(t, cm.selectTrim())
+	})
+
+	t.Run("protections end", func(t *testing.T) {
+		cm := newConnManager()
+		cm.ProtectFor("a", "deal", time.Minute)
+		cm.Protect("a", "bootstrap")
+		cm.ProtectFor("b", "deal", time.Minute)
+		require.Len(t, cm.Protected(), 2)
+		assert.Equal(t, []string{"bootstrap", "deal"}, cm.Protected()[0].Tags)
+
+		now = now.Add(time.Minute)
+		assert.Equal(t, []ProtectedPeer{{Peer: "a", Tags: []string{"bootstrap"}}}, cm.Protected())
+		assert.False(t, cm.Unprotect("a", "bootstrap"))
+		assert.Empty(t, cm.Protected())
+	})
+
+	t.Run("temporary protections don't shorten others", func(t *testing.T) {
+		cm := newConnManager()
+		cm.Protect("a", "deal")
+		cm.ProtectFor("a", "deal", time.Minute)
+		cm.ProtectFor("b", "deal", time.Hour)
+		cm.ProtectFor("b", "deal", time.Minute)
+
+		now = now.Add(time.Minute)
+		assert.Len(t, cm.Protected(), 2)
+	})
+}
@@ -0,0 +1,465 @@
+package filnet
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	ma "gx/ipfs/QmNTCey11oxhb1AxDnQBRHtdhap6Ctud872NjAYPYYXPuc/go-multiaddr"
+	inet "gx/ipfs/QmNgLg1NTw37iWbYPKcyK85YJ9Whs1MkPtJwhfqbNYAyKg/go-libp2p-net"
+	pstore "gx/ipfs/QmPiemjiKBC9VA7vZF82m4x1oygtg2c2YVqag8PX7dN1BD/go-libp2p-peerstore"
+	cbor "gx/ipfs/QmRoARq3nkUb13HSKZGepCZSWe5GrVPwx7xURJGZ7KWv9V/go-ipld-cbor"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	circuit "gx/ipfs/QmWuMW6UKZMJo9bFFDwnjg8tW3AtKisMHHrXEutQdmJ19N/go-libp2p-circuit"
+	peer "gx/ipfs/QmY5Grm8pJdiSSVsYxx4uNRgweY72EmYwuSDbRnbFok3iY/go-libp2p-peer"
+	protocol "gx/ipfs/QmZNkThpqfVXs9GNbexPrfBbXSLNYeKrE7jwFM2oqHbyqN/go-libp2p-protocol"
+	host "gx/ipfs/QmaoXrM4Z41PD48JY36YqQGKQpLGjyLA2cKcLsES7YddAq/go-libp2p-host"
+	swarm "gx/ipfs/QmegQFxhr1J6yZ1vDQuDmJi5jntmj6BL96S11HVtXNCaHb/go-libp2p-swarm"
+
+	cbu "github.com/filecoin-project/go-filecoin/cborutil"
+)
+
+func init() {
+	cbor.RegisterCborType(holePunchMsg{})
+}
+
+// HolePunchProtocol is the protocol over which two peers connected through a
+// relay agree on when to dial each other directly.
+const HolePunchProtocol = protocol.ID("/fil/holepunch/1.0.0")
+
+// ObservedAddrProtocol is the protocol over which a node tells a peer the
+// address it sees the peer at, the address of the NAT of the peer if it is
+// behind one.
+const ObservedAddrProtocol = protocol.ID("/fil/holepunch/observed/1.0.0")
+
+const (
+	// holePunchTimeout is how long the direct connection to a peer takes to
+	// be established before the peers fall back to the relay.
+	holePunchTimeout = 10 * time.Second
+	// holePunchBackoff is how long after failing to punch a hole to a peer
+	// the node waits before trying again.
+	holePunchBackoff = 10 * time.Minute
+	// minPunchDuration bounds how short the punch of the responder is when
+	// the peers are very close.
+	minPunchDuration = 10 * time.Millisecond
+	// maxObservers is how many directly connected peers the node asks for
+	// the address they see it at.
+	maxObservers = 3
+	// observeTimeout is how long a peer takes to tell the address it sees
+	// the node at.
+	observeTimeout = 5 * time.Second
+)
+
+// holePunchMsg is a message of the hole punching protocol, the direct
+// addresses of its sender, or empty to sync the peers.
+type holePunchMsg struct {
+	Addrs [][]byte
+}
+
+// HolePuncher upgrades the connections to peers relayed by a third node to
+// direct ones, so that two nodes behind NATs talk directly once a relay
+// introduced them.
+//
+// The old libp2p the node uses has no simultaneous open, so the peers take
+// turns rather than dialing each other at once. Over the relayed connection,
+// the peer with the lowest ID, the initiator, sends its direct addresses and
+// the other peer, the responder, answers with its own. A ping then gives the
+// initiator the round trip time to the responder, and the initiator sends a
+// sync message. When the responder receives it, it closes the relayed
+// connection and dials the initiator for half a round trip, which opens its
+// NAT to the initiator whether or not the dial gets through the NAT of the
+// initiator. Once the dial of the responder gave up, a round trip after
+// sending the sync message, the initiator dials the responder through the
+// hole the responder punched. The peers fall back to the relay if the direct
+// connection fails.
+//
+// The direct addresses of a node behind a NAT are the ones the peers it is
+// directly connected to, e.g. its relay, see it at. Both dials must leave
+// from the port the peers listen on, which the TCP transport does as it
+// reuses the listening ports, so that the NAT maps them to that address.
+type HolePuncher struct {
+	h host.Host
+
+	lk       sync.Mutex
+	ctx      context.Context
+	cancel   context.CancelFunc
+	started  bool
+	punching map[peer.ID]struct{}
+	failed   map[peer.ID]time.Time
+}
+
+// NewHolePuncher returns a HolePuncher upgrading the relayed connections of
+// the host.
+func NewHolePuncher(h host.Host) *HolePuncher {
+	return &HolePuncher{
+		h:        h,
+		punching: make(map[peer.ID]struct{}),
+		failed:   make(map[peer.ID]time.Time),
+	}
+}
+
+// Start punches holes to the peers the host gets connected to through a
+// relay until ctx is done or Stop is called.
+func (hp *HolePuncher) Start(ctx context.Context) {
+	hp.lk.Lock()
+	defer hp.lk.Unlock()
+
+	if hp.started {
+		return
+	}
+	hp.started = true
+	hp.ctx, hp.cancel = context.WithCancel(ctx)
+	hp.h.SetStreamHandler(HolePunchProtocol, hp.handleStream)
+	hp.h.SetStreamHandler(ObservedAddrProtocol, hp.handleObservedAddr)
+	hp.h.Network().Notify((*holePunchNotify)(hp))
+}
+
+// Stop stops punching holes.
+func (hp *HolePuncher) Stop() {
+	hp.lk.Lock()
+	defer hp.lk.Unlock()
+
+	if !hp.started {
+		return
+	}
+	hp.started = false
+	hp.cancel()
+	hp.h.RemoveStreamHandler(HolePunchProtocol)
+	hp.h.RemoveStreamHandler(ObservedAddrProtocol)
+	hp.h.Network().StopNotify((*holePunchNotify)(hp))
+}
+
+// connected punches a hole to the peer of c if c is relayed, the node is the
+// initiator and it isn't directly connected to the peer already.
+func (hp *HolePuncher) connected(c inet.Conn) {
+	p := c.RemotePeer()
+	if !isRelayed(c.RemoteMultiaddr()) || hp.h.ID() > p || hp.directlyConnected(p) {
+		return
+	}
+
+	hp.lk.Lock()
+	defer hp.lk.Unlock()
+
+	if !hp.started {
+		return
+	}
+	if _, ok := hp.punching[p]; ok {
+		return
+	}
+	if t, ok := hp.failed[p]; ok && time.Since(t) < holePunchBackoff {
+		return
+	}
+	hp.punching[p] = struct{}{}
+
+	ctx := hp.ctx
+	go func() {
+		err := hp.punch(ctx, p)
+
+		hp.lk.Lock()
+		defer hp.lk.Unlock()
+
+		delete(hp.punching, p)
+		if err != nil {
+			log.Infof("failed to punch a hole to peer %s: %s", p, err)
+			hp.failed[p] = time.Now()
+			return
+		}
+		delete(hp.failed, p)
+	}()
+}
+
+// punch upgrades the relayed connection to p to a direct one, as the
+// initiator.
+func (hp *HolePuncher) punch(ctx context.Context, p peer.ID) error {
+	s, err := hp.h.NewStream(ctx, p, HolePunchProtocol)
+	if err != nil {
+		return err
+	}
+	stopReset := ResetOnDone(ctx, s)
+	defer stopReset()
+	defer s.Close() // nolint: errcheck
+
+	reader := cbu.NewMsgReader(s)
+	writer := cbu.NewMsgWriter(s)
+
+	if err := writer.WriteMsg(&holePunchMsg{Addrs: addrsToBytes(hp.directAddrs(ctx))}); err != nil {
+		return err
+	}
+	var resp holePunchMsg
+	if err := reader.ReadMsg(&resp); err != nil {
+		return err
+	}
+	addrs := addrsFromBytes(resp.Addrs)
+	if len(addrs) == 0 {
+		return errors.New("peer has no direct address")
+	}
+
+	// a ping measures the round trip time, the time the responder took to
+	// find its addresses left out
+	if err := writer.WriteMsg(&holePunchMsg{}); err != nil {
+		return err
+	}
+	sent := time.Now()
+	var pong holePunchMsg
+	if err := reader.ReadMsg(&pong); err != nil {
+		return err
+	}
+	rtt := time.Since(sent)
+	if err := writer.WriteMsg(&holePunchMsg{}); err != nil {
+		return err
+	}
+
+	// the responder punches once the sync message reached it, after half a
+	// round trip
+	select {
+	case <-time.After(rtt/2 + punchDuration(rtt)):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	restore := hp.dropRelay(p, addrs)
+	defer restore()
+
+	dctx, cancel := context.WithTimeout(ctx, holePunchTimeout)
+	defer cancel()
+	if err := hp.h.Connect(dctx, pstore.PeerInfo{ID: p, Addrs: addrs}); err != nil {
+		// fall back to the relay, the relay addresses are restored
+		restore()
+		if rerr := hp.h.Connect(ctx, pstore.PeerInfo{ID: p}); rerr != nil {
+			log.Warningf("failed to reconnect to peer %s through a relay: %s", p, rerr)
+		}
+		return err
+	}
+	return nil
+}
+
+// handleStream punches a hole to the peer of s, as the responder.
+func (hp *HolePuncher) handleStream(s inet.Stream) {
+	defer s.Close() // nolint: errcheck
+
+	hp.lk.Lock()
+	ctx := hp.ctx
+	hp.lk.Unlock()
+	if ctx == nil {
+		return
+	}
+	stopReset := ResetOnDone(ctx, s)
+	defer stopReset()
+
+	p := s.Conn().RemotePeer()
+	reader := cbu.NewMsgReader(s)
+	writer := cbu.NewMsgWriter(s)
+
+	var req holePunchMsg
+	if err := reader.ReadMsg(&req); err != nil {
+		log.Infof("failed to read hole punching request of peer %s: %s", p, err)
+		return
+	}
+	if err := writer.WriteMsg(&holePunchMsg{Addrs: addrsToBytes(hp.directAddrs(ctx))}); err != nil {
+		log.Infof("failed to answer hole punching request of peer %s: %s", p, err)
+		return
+	}
+	var ping holePunchMsg
+	if err := reader.ReadMsg(&ping); err != nil {
+		// the initiator gave up, e.g. as the node has no direct address
+		return
+	}
+	if err := writer.WriteMsg(&holePunchMsg{}); err != nil {
+		log.Infof("failed to answer hole punching ping of peer %s: %s", p, err)
+		return
+	}
+	sent := time.Now()
+	var syncMsg holePunchMsg
+	if err := reader.ReadMsg(&syncMsg); err != nil {
+		log.Infof("failed to read hole punching sync of peer %s: %s", p, err)
+		return
+	}
+	rtt := time.Since(sent)
+
+	addrs := addrsFromBytes(req.Addrs)
+	if len(addrs) == 0 {
+		return
+	}
+	restore := hp.dropRelay(p, addrs)
+	defer restore()
+
+	// The punch is cut short before the dial of the initiator reaches the
+	// node, which accepts it on the port it listens on. It gets through if
+	// the initiator isn't behind a NAT.
+	pctx, cancel := context.WithTimeout(ctx, punchDuration(rtt))
+	hp.h.Connect(pctx, pstore.PeerInfo{ID: p, Addrs: addrs}) // nolint: errcheck
+	cancel()
+	hp.clearBackoff(p)
+
+	deadline := time.After(holePunchTimeout)
+	for !hp.directlyConnected(p) {
+		select {
+		case <-time.After(10 * time.Millisecond):
+		case <-deadline:
+			// the initiator falls back to the relay
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// dropRelay closes the connections to p and makes the node only know of its
+// direct addresses, so that dialing p doesn't go through a relay. The
+// returned function restores the addresses the node knew.
+func (hp *HolePuncher) dropRelay(p peer.ID, direct []ma.Multiaddr) (restore func()) {
+	ps := hp.h.Peerstore()
+	known := ps.Addrs(p)
+
+	if err := hp.h.Network().ClosePeer(p); err != nil {
+		log.Infof("failed to close relayed connection to peer %s: %s", p, err)
+	}
+	ps.ClearAddrs(p)
+	ps.AddAddrs(p, direct, pstore.TempAddrTTL)
+	hp.clearBackoff(p)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			ps.AddAddrs(p, known, pstore.RecentlyConnectedAddrTTL)
+			hp.clearBackoff(p)
+		})
+	}
+}
+
+// clearBackoff lets the node dial p again right away after a failed dial.
+func (hp *HolePuncher) clearBackoff(p peer.ID) {
+	if swrm, ok := hp.h.Network().(*swarm.Swarm); ok {
+		swrm.Backoff().Clear(p)
+	}
+}
+
+// directlyConnected returns whether the node has a connection to p that
+// isn't relayed.
+func (hp *HolePuncher) directlyConnected(p peer.ID) bool {
+	for _, c := range hp.h.Network().ConnsToPeer(p) {
+		if !isRelayed(c.RemoteMultiaddr()) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleObservedAddr tells the peer of s the address the node sees it at,
+// unless s is relayed.
+func (hp *HolePuncher) handleObservedAddr(s inet.Stream) {
+	defer s.Close() // nolint: errcheck
+
+	var addrs []ma.Multiaddr
+	if a := s.Conn().RemoteMultiaddr(); !isRelayed(a) {
+		addrs = append(addrs, a)
+	}
+	if err := cbu.NewMsgWriter(s).WriteMsg(&holePunchMsg{Addrs: addrsToBytes(addrs)}); err != nil {
+		log.Infof("failed to send observed address to peer %s: %s", s.Conn().RemotePeer(), err)
+	}
+}
+
+// observedAddr asks p for the address it sees the node at.
+func (hp *HolePuncher) observedAddr(ctx context.Context, p peer.ID) ([]ma.Multiaddr, error) {
+	ctx, cancel := context.WithTimeout(ctx, observeTimeout)
+	defer cancel()
+
+	s, err := hp.h.NewStream(ctx, p, ObservedAddrProtocol)
+	if err != nil {
+		return nil, err
+	}
+	stopReset := ResetOnDone(ctx, s)
+	defer stopReset()
+	defer s.Close() // nolint: errcheck
+
+	var resp holePunchMsg
+	if err := cbu.NewMsgReader(s).ReadMsg(&resp); err != nil {
+		return nil, err
+	}
+	return addrsFromBytes(resp.Addrs), nil
+}
+
+// directAddrs returns the direct addresses of the node, the ones it
+// advertises and the ones a few of its directly connected peers see it at.
+func (hp *HolePuncher) directAddrs(ctx context.Context) []ma.Multiaddr {
+	all := hp.h.Addrs()
+	asked := make(map[peer.ID]struct{})
+	for _, c := range hp.h.Network().Conns() {
+		p := c.RemotePeer()
+		if len(asked) == maxObservers {
+			break
+		}
+		if _, ok := asked[p]; ok || isRelayed(c.RemoteMultiaddr()) {
+			continue
+		}
+		asked[p] = struct{}{}
+		observed, err := hp.observedAddr(ctx, p)
+		if err != nil {
+			log.Debugf("failed to get observed address from peer %s: %s", p, err)
+			continue
+		}
+		all = append(all, observed...)
+	}
+
+	seen := make(map[string]struct{})
+	var addrs []ma.Multiaddr
+	for _, a := range all {
+		if _, ok := seen[a.String()]; ok || isRelayed(a) {
+			continue
+		}
+		seen[a.String()] = struct{}{}
+		addrs = append(addrs, a)
+	}
+	return addrs
+}
+
+// punchDuration returns how long the responder dials the initiator given the
+// round trip time between them, so that the dial is cut short before the one
+// of the initiator reaches the responder.
+func punchDuration(rtt time.Duration) time.Duration {
+	if rtt/2 < minPunchDuration {
+		return minPunchDuration
+	}
+	return rtt / 2
+}
+
+func isRelayed(a ma.Multiaddr) bool {
+	_, err := a.ValueForProtocol(circuit.P_CIRCUIT)
+	return err == nil
+}
+
+func addrsToBytes(addrs []ma.Multiaddr) [][]byte {
+	out := make([][]byte, len(addrs))
+	for i, a := range addrs {
+		out[i] = a.Bytes()
+	}
+	return out
+}
+
+// addrsFromBytes decodes the direct addresses of a message, skipping the
+// invalid and relayed ones.
+func addrsFromBytes(raw [][]byte) []ma.Multiaddr {
+	var addrs []ma.Multiaddr
+	for _, b := range raw {
+		a, err := ma.NewMultiaddrBytes(b)
+		if err != nil || isRelayed(a) {
+			continue
+		}
+		addrs = append(addrs, a)
+	}
+	return addrs
+}
+
+// holePunchNotify punches holes to the peers a HolePuncher gets connected to
+// through a relay.
+type holePunchNotify HolePuncher
+
+func (hn *holePunchNotify) Connected(n inet.Network, c inet.Conn) {
+	(*HolePuncher)(hn).connected(c)
+}
+
+func (hn *holePunchNotify) Disconnected(n inet.Network, c inet.Conn)   {}
+func (hn *holePunchNotify) OpenedStream(n inet.Network, s inet.Stream) {}
+func (hn *holePunchNotify) ClosedStream(n inet.Network, s inet.Stream) {}
+func (hn *holePunchNotify) Listen(n inet.Network, a ma.Multiaddr)      {}
+func (hn *holePunchNotify) ListenClose(n inet.Network, a ma.Multiaddr) {}
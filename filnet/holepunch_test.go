@@ -0,0 +1,102 @@
+package filnet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ma "gx/ipfs/QmNTCey11oxhb1AxDnQBRHtdhap6Ctud872NjAYPYYXPuc/go-multiaddr"
+	inet "gx/ipfs/QmNgLg1NTw37iWbYPKcyK85YJ9Whs1MkPtJwhfqbNYAyKg/go-libp2p-net"
+	"gx/ipfs/QmYxivS34F2M2n44WQQnRHGAKS8aoRUxwGpi9wk4Cdn4Jf/go-libp2p/p2p/net/mock"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHolePuncher(t *testing.T) {
+	t.Parallel()
+
+	t.Run("reconnects directly to the responder", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mn, err := mocknet.WithNPeers(ctx, 2)
+		require.NoError(err)
+		require.NoError(mn.LinkAll())
+		require.NoError(mn.ConnectAllButSelf())
+		a, b := mn.Hosts()[0], mn.Hosts()[1]
+
+		relayed, err := ma.NewMultiaddr("/ip4/1.2.3.4/tcp/4001/ipfs/QmNnooDu7bfjPFoTZYxMNLWUQJyrVwtbZg5gBMjTezGAJN/p2p-circuit")
+		require.NoError(err)
+		a.Peerstore().AddAddr(b.ID(), relayed, time.Hour)
+		b.Peerstore().AddAddr(a.ID(), relayed, time.Hour)
+
+		hpa := NewHolePuncher(a)
+		hpb := NewHolePuncher(b)
+		hpa.Start(ctx)
+		hpb.Start(ctx)
+		defer hpa.Stop()
+		defer hpb.Stop()
+
+		initiator, responder := hpa, hpb
+		if b.ID() < a.ID() {
+			initiator, responder = hpb, hpa
+		}
+		connected := func() bool {
+			return a.Network().Connectedness(b.ID()) == inet.Connected
+		}
+
+		// mocknet connections aren't relayed, punching closes the connection
+		// and opens a new one
+		require.NoError(initiator.punch(ctx, responder.h.ID()))
+		assert.True(waitFor(connected))
+
+		// the relay addresses are known again afterwards
+		assert.Contains(initiator.h.Peerstore().Addrs(responder.h.ID()), relayed)
+		assert.True(waitFor(func() bool {
+			for _, addr := range responder.h.Peerstore().Addrs(initiator.h.ID()) {
+				if addr.Equal(relayed) {
+					return true
+				}
+			}
+			return false
+		}))
+	})
+
+	t.Run("keeps the relayed connection if the peer doesn't punch holes", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mn, err := mocknet.WithNPeers(ctx, 2)
+		require.NoError(err)
+		require.NoError(mn.LinkAll())
+		require.NoError(mn.ConnectAllButSelf())
+		a, b := mn.Hosts()[0], mn.Hosts()[1]
+
+		hp := NewHolePuncher(a)
+		hp.Start(ctx)
+		defer hp.Stop()
+
+		assert.Error(hp.punch(ctx, b.ID()))
+		assert.Equal(inet.Connected, a.Network().Connectedness(b.ID()))
+	})
+
+	t.Run("relayed addresses aren't direct addresses", func(t *testing.T) {
+		assert := assert.New(t)
+
+		direct, err := ma.NewMultiaddr("/ip4/1.2.3.4/tcp/4001")
+		require.NoError(t, err)
+		relayed, err := ma.NewMultiaddr("/ip4/1.2.3.4/tcp/4001/ipfs/QmNnooDu7bfjPFoTZYxMNLWUQJyrVwtbZg5gBMjTezGAJN/p2p-circuit")
+		require.NoError(t, err)
+
+		assert.False(isRelayed(direct))
+		assert.True(isRelayed(relayed))
+		assert.Equal([]ma.Multiaddr{direct}, addrsFromBytes(addrsToBytes([]ma.Multiaddr{direct, relayed})))
+	})
+}
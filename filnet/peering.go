@@ -0,0 +1,230 @@
+package filnet
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	ma "gx/ipfs/QmNTCey11oxhb1AxDnQBRHtdhap6Ctud872NjAYPYYXPuc/go-multiaddr"
+	inet "gx/ipfs/QmNgLg1NTw37iWbYPKcyK85YJ9Whs1MkPtJwhfqbNYAyKg/go-libp2p-net"
+	pstore "gx/ipfs/QmPiemjiKBC9VA7vZF82m4x1oygtg2c2YVqag8PX7dN1BD/go-libp2p-peerstore"
+	peer "gx/ipfs/QmY5Grm8pJdiSSVsYxx4uNRgweY72EmYwuSDbRnbFok3iY/go-libp2p-peer"
+	host "gx/ipfs/QmaoXrM4Z41PD48JY36YqQGKQpLGjyLA2cKcLsES7YddAq/go-libp2p-host"
+)
+
+const (
+	// peeringMinBackoff is how long Peering waits before reconnecting to a
+	// peer which just disconnected.
+	peeringMinBackoff = 5 * time.Second
+	// peeringMaxBackoff is the longest Peering waits between attempts to
+	// reconnect to a peer.
+	peeringMaxBackoff = 10 * time.Minute
+	// peeringConnectTimeout is how long an attempt to connect to a peer
+	// lasts.
+	peeringConnectTimeout = 30 * time.Second
+)
+
+// PeeringStatus is whether the node is connected to a peer it peers with,
+// and when it next tries to connect to it if it isn't.
+type PeeringStatus struct {
+	Peer        peer.ID    `json:"peer"`
+	Connected   bool       `json:"connected"`
+	NextAttempt *time.Time `json:"nextAttempt,omitempty"`
+	LastError   string     `json:"lastError,omitempty"`
+}
+
+// Peering keeps the node connected to a set of peers, e.g. the other nodes of
+// a miner cluster. Unlike bootstrap peers, which are only connected to when
+// the node has too few peers, the node always reconnects to the peers it
+// peers with when they disconnect, waiting longer after each failed attempt.
+type Peering struct {
+	h          host.Host
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	lk      sync.Mutex
+	ctx     context.Context
+	cancel  context.CancelFunc
+	peers   map[peer.ID]*peeringPeer
+	started bool
+}
+
+type peeringPeer struct {
+	info pstore.PeerInfo
+	// failures is the number of attempts to connect which failed in a row.
+	failures  uint
+	timer     *time.Timer
+	next      time.Time
+	lastError string
+}
+
+// NewPeering returns a Peering keeping the host connected to peers.
+func NewPeering(h host.Host, peers []pstore.PeerInfo) *Peering {
+	p := &Peering{
+		h:          h,
+		minBackoff: peeringMinBackoff,
+		maxBackoff: peeringMaxBackoff,
+		peers:      make(map[peer.ID]*peeringPeer),
+	}
+	for _, pi := range peers {
+		if pp, ok := p.peers[pi.ID]; ok {
+			pp.info.Addrs = append(pp.info.Addrs, pi.Addrs...)
+			continue
+		}
+		p.peers[pi.ID] = &peeringPeer{info: pi}
+	}
+	return p
+}
+
+// Start connects to the peers, and reconnects to them when they disconnect
+// until ctx is done or Stop is called.
+func (p *Peering) Start(ctx context.Context) {
+	p.lk.Lock()
+	defer p.lk.Unlock()
+
+	if p.started {
+		return
+	}
+	p.started = true
+	p.ctx, p.cancel = context.WithCancel(ctx)
+	p.h.Network().Notify((*peeringNotify)(p))
+
+	for _, pp := range p.peers {
+		p.schedule(pp, 0)
+	}
+}
+
+// Stop stops reconnecting to the peers.
+func (p *Peering) Stop() {
+	p.lk.Lock()
+	defer p.lk.Unlock()
+
+	if !p.started {
+		return
+	}
+	p.started = false
+	p.cancel()
+	p.h.Network().StopNotify((*peeringNotify)(p))
+	for _, pp := range p.peers {
+		if pp.timer != nil {
+			pp.timer.Stop()
+			pp.timer = nil
+		}
+	}
+}
+
+// Status returns whether the node is connected to each of the peers.
+func (p *Peering) Status() []PeeringStatus {
+	p.lk.Lock()
+	defer p.lk.Unlock()
+
+	var statuses []PeeringStatus
+	for id, pp := range p.peers {
+		s := PeeringStatus{
+			Peer:      id,
+			Connected: p.h.Network().Connectedness(id) == inet.Connected,
+			LastError: pp.lastError,
+		}
+		if !s.Connected && pp.timer != nil {
+			next := pp.next
+			s.NextAttempt = &next
+		}
+		statuses = append(statuses, s)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Peer < statuses[j].Peer })
+	return statuses
+}
+
+// schedule attempts to connect to the peer after d, unless an attempt is
+// already scheduled. The caller holds lk.
+func (p *Peering) schedule(pp *peeringPeer, d time.Duration) {
+	if !p.started || pp.timer != nil {
+		return
+	}
+	pp.next = time.Now().Add(d)
+	pp.timer = time.AfterFunc(d, func() { p.connect(pp) })
+}
+
+func (p *Peering) connect(pp *peeringPeer) {
+	p.lk.Lock()
+	ctx := p.ctx
+	pp.timer = nil
+	p.lk.Unlock()
+
+	if p.h.Network().Connectedness(pp.info.ID) == inet.Connected {
+		return
+	}
+
+	cctx, cancel := context.WithTimeout(ctx, peeringConnectTimeout)
+	err := p.h.Connect(cctx, pp.info)
+	cancel()
+
+	p.lk.Lock()
+	defer p.lk.Unlock()
+
+	if err == nil {
+		pp.failures = 0
+		pp.lastError = ""
+		return
+	}
+	if ctx.Err() != nil {
+		return
+	}
+	log.Warningf("failed to connect to peer %s: %s", pp.info.ID, err)
+	pp.lastError = err.Error()
+	pp.failures++
+	p.schedule(pp, p.backoff(pp))
+}
+
+// backoff returns how long to wait before the next attempt to connect to the
+// peer, doubling with each failed attempt, give or take a tenth so that the
+// peers of a cluster restarting together don't all reconnect at once.
+func (p *Peering) backoff(pp *peeringPeer) time.Duration {
+	d := p.minBackoff
+	for i := uint(0); i < pp.failures && d < p.maxBackoff; i++ {
+		d *= 2
+	}
+	if d > p.maxBackoff {
+		d = p.maxBackoff
+	}
+	return d - d/10 + time.Duration(rand.Int63n(int64(d/5)+1))
+}
+
+func (p *Peering) connected(id peer.ID) {
+	p.lk.Lock()
+	defer p.lk.Unlock()
+
+	if pp, ok := p.peers[id]; ok {
+		pp.failures = 0
+		pp.lastError = ""
+	}
+}
+
+func (p *Peering) disconnected(id peer.ID) {
+	p.lk.Lock()
+	defer p.lk.Unlock()
+
+	pp, ok := p.peers[id]
+	if !ok || p.h.Network().Connectedness(id) == inet.Connected {
+		return
+	}
+	p.schedule(pp, p.backoff(pp))
+}
+
+// peeringNotify reconnects to the peers of a Peering when they disconnect.
+type peeringNotify Peering
+
+func (pn *peeringNotify) Connected(n inet.Network, c inet.Conn) {
+	(*Peering)(pn).connected(c.RemotePeer())
+}
+
+func (pn *peeringNotify) Disconnected(n inet.Network, c inet.Conn) {
+	(*Peering)(pn).disconnected(c.RemotePeer())
+}
+
+func (pn *peeringNotify) OpenedStream(n inet.Network, s inet.Stream) {}
+func (pn *peeringNotify) ClosedStream(n inet.Network, s inet.Stream) {}
+func (pn *peeringNotify) Listen(n inet.Network, a ma.Multiaddr)      {}
+func (pn *peeringNotify) ListenClose(n inet.Network, a ma.Multiaddr) {}
@@ -0,0 +1,73 @@
+package filnet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	inet "gx/ipfs/QmNgLg1NTw37iWbYPKcyK85YJ9Whs1MkPtJwhfqbNYAyKg/go-libp2p-net"
+	pstore "gx/ipfs/QmPiemjiKBC9VA7vZF82m4x1oygtg2c2YVqag8PX7dN1BD/go-libp2p-peerstore"
+	"gx/ipfs/QmYxivS34F2M2n44WQQnRHGAKS8aoRUxwGpi9wk4Cdn4Jf/go-libp2p/p2p/net/mock"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitFor polls cond until it holds or a second passes.
+func waitFor(cond func() bool) bool {
+	for i := 0; i < 100; i++ {
+		if cond() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func TestPeering(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mn, err := mocknet.WithNPeers(ctx, 2)
+	require.NoError(err)
+	require.NoError(mn.LinkAll())
+	a, b := mn.Hosts()[0], mn.Hosts()[1]
+	connected := func() bool { return a.Network().Connectedness(b.ID()) == inet.Connected }
+
+	p := NewPeering(a, []pstore.PeerInfo{{ID: b.ID(), Addrs: b.Addrs()}})
+	p.minBackoff = 10 * time.Millisecond
+	p.maxBackoff = 40 * time.Millisecond
+	p.Start(ctx)
+
+	// connects on start
+	require.True(waitFor(connected))
+	assert.Equal([]PeeringStatus{{Peer: b.ID(), Connected: true}}, p.Status())
+
+	// reconnects when disconnected
+	require.NoError(mn.DisconnectPeers(a.ID(), b.ID()))
+	require.True(waitFor(connected))
+
+	// backs off while the peer is unreachable
+	require.NoError(mn.UnlinkPeers(a.ID(), b.ID()))
+	require.NoError(mn.DisconnectPeers(a.ID(), b.ID()))
+	require.True(waitFor(func() bool {
+		s := p.Status()[0]
+		return s.LastError != "" && s.NextAttempt != nil
+	}))
+	assert.False(p.Status()[0].Connected)
+
+	_, err = mn.LinkPeers(a.ID(), b.ID())
+	require.NoError(err)
+	require.True(waitFor(connected))
+	assert.True(waitFor(func() bool { return p.Status()[0].LastError == "" }))
+
+	// stops reconnecting once stopped
+	p.Stop()
+	require.NoError(mn.DisconnectPeers(a.ID(), b.ID()))
+	time.Sleep(100 * time.Millisecond)
+	assert.False(connected())
+}
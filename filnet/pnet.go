@@ -0,0 +1,54 @@
+package filnet
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+)
+
+// swarmKeyHeader is the first line of encoded swarm keys, naming their
+// format. Swarm keys use the format of the libp2p private network protector,
+// go-libp2p-pnet, which encrypts the connections of the node with the key.
+const swarmKeyHeader = "/key/swarm/psk/1.0.0/"
+
+// SwarmKey is the pre-shared key of a private network. Nodes only connect to
+// the nodes with the same swarm key.
+type SwarmKey [32]byte
+
+// NewSwarmKey returns a random swarm key, for a new private network.
+func NewSwarmKey() (SwarmKey, error) {
+	var k SwarmKey
+	if _, err := io.ReadFull(rand.Reader, k[:]); err != nil {
+		return k, errors.Wrap(err, "failed to generate swarm key")
+	}
+	return k, nil
+}
+
+// DecodeSwarmKey decodes a swarm key encoded by SwarmKey.Encode.
+func DecodeSwarmKey(data []byte) (SwarmKey, error) {
+	var k SwarmKey
+	lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+	if len(lines) != 3 || string(bytes.TrimSpace(lines[0])) != swarmKeyHeader {
+		return k, errors.Errorf("swarm key must start with %s", swarmKeyHeader)
+	}
+	if enc := string(bytes.TrimSpace(lines[1])); enc != "/base16/" {
+		return k, errors.Errorf("unsupported swarm key encoding %s", enc)
+	}
+	decoded, err := hex.DecodeString(string(bytes.TrimSpace(lines[2])))
+	if err != nil {
+		return k, errors.Wrap(err, "invalid swarm key")
+	}
+	if len(decoded) != len(k) {
+		return k, errors.Errorf("swarm key must be %d bytes, not %d", len(k), len(decoded))
+	}
+	copy(k[:], decoded)
+	return k, nil
+}
+
+// Encode encodes the swarm key for the swarm.key file of repos.
+func (k SwarmKey) Encode() []byte {
+	return []byte(swarmKeyHeader + "\n/base16/\n" + hex.EncodeToString(k[:]) + "\n")
+}
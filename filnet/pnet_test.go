@@ -0,0 +1,45 @@
+package filnet
+
+import (
+	"bytes"
+	"testing"
+
+	pnet "gx/ipfs/QmY4Q5JC4vxLEi8EpVxJM4rcRryEVtH1zRKVTAm6BKV1pg/go-libp2p-pnet"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSwarmKey(t *testing.T) {
+	t.Parallel()
+
+	t.Run("round trips", func(t *testing.T) {
+		k, err := NewSwarmKey()
+		require.NoError(t, err)
+		decoded, err := DecodeSwarmKey(k.Encode())
+		require.NoError(t, err)
+		assert.Equal(t, k, decoded)
+	})
+
+	t.Run("is read by the libp2p protector", func(t *testing.T) {
+		k1, err := NewSwarmKey()
+		require.NoError(t, err)
+		k2, err := NewSwarmKey()
+		require.NoError(t, err)
+
+		p1, err := pnet.NewProtector(bytes.NewReader(k1.Encode()))
+		require.NoError(t, err)
+		p2, err := pnet.NewProtector(bytes.NewReader(k2.Encode()))
+		require.NoError(t, err)
+		assert.NotEqual(t, p1.Fingerprint(), p2.Fingerprint())
+	})
+
+	t.Run("rejects invalid keys", func(t *testing.T) {
+		_, err := DecodeSwarmKey([]byte("/fil/swarm/psk/1.0.0/\n/base16/\n00"))
+		assert.Error(t, err)
+		_, err = DecodeSwarmKey([]byte(swarmKeyHeader + "\n/base64/\nAA=="))
+		assert.Error(t, err)
+		_, err = DecodeSwarmKey([]byte(swarmKeyHeader + "\n/base16/\n00ff"))
+		assert.Error(t, err)
+	})
+}
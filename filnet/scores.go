@@ -0,0 +1,300 @@
+package filnet
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	ma "gx/ipfs/QmNTCey11oxhb1AxDnQBRHtdhap6Ctud872NjAYPYYXPuc/go-multiaddr"
+	inet "gx/ipfs/QmNgLg1NTw37iWbYPKcyK85YJ9Whs1MkPtJwhfqbNYAyKg/go-libp2p-net"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	peer "gx/ipfs/QmY5Grm8pJdiSSVsYxx4uNRgweY72EmYwuSDbRnbFok3iY/go-libp2p-peer"
+	ds "gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
+
+	"github.com/filecoin-project/go-filecoin/repo"
+)
+
+// banlistKey is the datastore key the banlist is persisted under.
+var banlistKey = ds.NewKey("/banlist")
+
+// Offense is a misbehavior of a peer, which lowers its score.
+type Offense string
+
+const (
+	// InvalidBlock is sending a block, or a chain, which fails validation.
+	InvalidBlock = Offense("invalid-block")
+	// InvalidMessage is sending a message which can't be decoded or whose
+	// signature is invalid.
+	InvalidMessage = Offense("invalid-message")
+	// Timeout is failing to answer a protocol request in time.
+	Timeout = Offense("timeout")
+	// Spam is sending messages the node's admission filters reject, e.g.
+	// for exceeding a rate limit.
+	Spam = Offense("spam")
+)
+
+// penalties are the points each offense takes from the score of a peer.
+var penalties = map[Offense]float64{
+	InvalidBlock:   50,
+	InvalidMessage: 10,
+	Timeout:        5,
+	Spam:           10,
+}
+
+const (
+	// BanScore is the score at or below which a peer is banned for
+	// TempBanDuration.
+	BanScore = -100
+	// TempBanDuration is the length of the bans of peers whose score
+	// dropped to BanScore.
+	TempBanDuration = time.Hour
+	// RecoveryPerHour is the points the score of a peer recovers each hour,
+	// up to 0, the score of peers in good standing.
+	RecoveryPerHour = 50
+)
+
+// PeerScore is the standing of a peer with the node.
+type PeerScore struct {
+	Peer  peer.ID `json:"peer"`
+	Score float64 `json:"score"`
+	// Offenses counts the offenses of the peer by kind.
+	Offenses map[Offense]uint64 `json:"offenses"`
+	// Updated is the time Score was last recomputed.
+	Updated time.Time `json:"updated"`
+}
+
+// Ban is a ban of a peer, which the node refuses connections and gossip from.
+type Ban struct {
+	Peer   peer.ID `json:"peer"`
+	Reason string  `json:"reason"`
+	// Until is the time the ban ends, nil if it is permanent.
+	Until *time.Time `json:"until,omitempty"`
+}
+
+// Scorekeeper scores peers by their offenses, banning peers whose score drops
+// to BanScore for TempBanDuration. Peers can also be banned by hand, for a
+// time or for good. Bans are persisted, scores are not.
+type Scorekeeper struct {
+	ds      repo.Datastore
+	network inet.Network
+	now     func() time.Time
+
+	lk     sync.Mutex
+	scores map[peer.ID]*PeerScore
+	bans   map[peer.ID]Ban
+}
+
+// NewScorekeeper returns a Scorekeeper persisting its banlist in dstore. If
+// network isn't nil, the connections of banned peers to it are closed.
+func NewScorekeeper(dstore repo.Datastore, network inet.Network) (*Scorekeeper, error) {
+	sk := &Scorekeeper{
+		ds:      dstore,
+		network: network,
+		now:     time.Now,
+		scores:  make(map[peer.ID]*PeerScore),
+		bans:    make(map[peer.ID]Ban),
+	}
+
+	data, err := dstore.Get(banlistKey)
+	switch err {
+	case nil:
+		var bans []Ban
+		if err := json.Unmarshal(data, &bans); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal banlist")
+		}
+		for _, b := range bans {
+			sk.bans[b.Peer] = b
+		}
+	case ds.ErrNotFound:
+	default:
+		return nil, errors.Wrap(err, "failed to read banlist")
+	}
+
+	if network != nil {
+		network.Notify((*scoreNotify)(sk))
+	}
+	return sk, nil
+}
+
+// Penalize lowers the score of the peer for the offense, banning the peer
+// if its score drops to BanScore.
+func (sk *Scorekeeper) Penalize(p peer.ID, o Offense) {
+	sk.lk.Lock()
+	defer sk.lk.Unlock()
+
+	s := sk.score(p)
+	s.Score -= penalties[o]
+	s.Offenses[o]++
+	log.Debugf("peer %s penalized for %s, score %.0f", p, o, s.Score)
+
+	if s.Score > BanScore {
+		return
+	}
+	if _, banned := sk.banned(p); banned {
+		return
+	}
+	until := sk.now().Add(TempBanDuration)
+	reason := fmt.Sprintf("score dropped to %.0f, last offense %s", s.Score, o)
+	if err := sk.ban(Ban{Peer: p, Reason: reason, Until: &until}); err != nil {
+		log.Errorf("failed to ban peer %s: %s", p, err)
+	}
+}
+
+// Ban bans the peer for d, or for good if d is 0, and closes its
+// connections.
+func (sk *Scorekeeper) Ban(p peer.ID, d time.Duration, reason string) error {
+	b := Ban{Peer: p, Reason: reason}
+	if d > 0 {
+		until := sk.now().Add(d)
+		b.Until = &until
+	}
+
+	sk.lk.Lock()
+	defer sk.lk.Unlock()
+	return sk.ban(b)
+}
+
+// Unban lifts the ban of the peer and resets its score.
+func (sk *Scorekeeper) Unban(p peer.ID) error {
+	sk.lk.Lock()
+	defer sk.lk.Unlock()
+
+	if _, banned := sk.banned(p); !banned {
+		return errors.Errorf("peer %s is not banned", p)
+	}
+	delete(sk.bans, p)
+	delete(sk.scores, p)
+	log.Infof("unbanned peer %s", p)
+	return sk.saveBans()
+}
+
+// Banned returns whether the peer is banned.
+func (sk *Scorekeeper) Banned(p peer.ID) bool {
+	sk.lk.Lock()
+	defer sk.lk.Unlock()
+
+	_, banned := sk.banned(p)
+	return banned
+}
+
+// Bans lists the bans in force, by peer.
+func (sk *Scorekeeper) Bans() []Ban {
+	sk.lk.Lock()
+	defer sk.lk.Unlock()
+
+	var bans []Ban
+	for p := range sk.bans {
+		if b, banned := sk.banned(p); banned {
+			bans = append(bans, b)
+		}
+	}
+	sort.Slice(bans, func(i, j int) bool { return bans[i].Peer < bans[j].Peer })
+	return bans
+}
+
+// Scores lists the scores of the peers which offended, worst first.
+func (sk *Scorekeeper) Scores() []PeerScore {
+	sk.lk.Lock()
+	defer sk.lk.Unlock()
+
+	var scores []PeerScore
+	for p := range sk.scores {
+		s := *sk.score(p)
+		s.Offenses = make(map[Offense]uint64)
+		for o, n := range sk.scores[p].Offenses {
+			s.Offenses[o] = n
+		}
+		scores = append(scores, s)
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Score != scores[j].Score {
+			return scores[i].Score < scores[j].Score
+		}
+		return scores[i].Peer < scores[j].Peer
+	})
+	return scores
+}
+
+// score returns the score of the peer, recovered for the time since it was
+// last updated. The caller holds lk.
+func (sk *Scorekeeper) score(p peer.ID) *PeerScore {
+	now := sk.now()
+	s, ok := sk.scores[p]
+	if !ok {
+		s = &PeerScore{Peer: p, Offenses: make(map[Offense]uint64), Updated: now}
+		sk.scores[p] = s
+	}
+	s.Score += now.Sub(s.Updated).Hours() * RecoveryPerHour
+	if s.Score > 0 {
+		s.Score = 0
+	}
+	s.Updated = now
+	return s
+}
+
+// banned returns the ban of the peer, if it is in force, dropping it if it
+// ended. The caller holds lk.
+func (sk *Scorekeeper) banned(p peer.ID) (Ban, bool) {
+	b, ok := sk.bans[p]
+	if !ok {
+		return Ban{}, false
+	}
+	if b.Until != nil && !sk.now().Before(*b.Until) {
+		delete(sk.bans, p)
+		if err := sk.saveBans(); err != nil {
+			log.Errorf("failed to drop ended ban of peer %s: %s", p, err)
+		}
+		return Ban{}, false
+	}
+	return b, true
+}
+
+// ban records the ban and closes the connections of the peer. The caller
+// holds lk.
+func (sk *Scorekeeper) ban(b Ban) error {
+	sk.bans[b.Peer] = b
+	if err := sk.saveBans(); err != nil {
+		return err
+	}
+	log.Warningf("banned peer %s: %s", b.Peer, b.Reason)
+
+	if sk.network != nil {
+		go func() {
+			if err := sk.network.ClosePeer(b.Peer); err != nil {
+				log.Warningf("failed to disconnect banned peer %s: %s", b.Peer, err)
+			}
+		}()
+	}
+	return nil
+}
+
+// saveBans persists the banlist. The caller holds lk.
+func (sk *Scorekeeper) saveBans() error {
+	bans := make([]Ban, 0, len(sk.bans))
+	for _, b := range sk.bans {
+		bans = append(bans, b)
+	}
+	data, err := json.Marshal(bans)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal banlist")
+	}
+	return errors.Wrap(sk.ds.Put(banlistKey, data), "failed to save banlist")
+}
+
+// scoreNotify closes the connections banned peers open.
+type scoreNotify Scorekeeper
+
+func (sn *scoreNotify) Connected(n inet.Network, c inet.Conn) {
+	if (*Scorekeeper)(sn).Banned(c.RemotePeer()) {
+		log.Debugf("closing connection of banned peer %s", c.RemotePeer())
+		go c.Close() // nolint: errcheck
+	}
+}
+
+func (sn *scoreNotify) Listen(n inet.Network, a ma.Multiaddr)      {}
+func (sn *scoreNotify) ListenClose(n inet.Network, a ma.Multiaddr) {}
+func (sn *scoreNotify) Disconnected(n inet.Network, c inet.Conn)   {}
+func (sn *scoreNotify) OpenedStream(n inet.Network, s inet.Stream) {}
+func (sn *scoreNotify) ClosedStream(n inet.Network, s inet.Stream) {}
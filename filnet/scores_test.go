@@ -0,0 +1,82 @@
+package filnet
+
+import (
+	"testing"
+	"time"
+
+	peer "gx/ipfs/QmY5Grm8pJdiSSVsYxx4uNRgweY72EmYwuSDbRnbFok3iY/go-libp2p-peer"
+
+	"github.com/filecoin-project/go-filecoin/repo"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScorekeeper(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1000, 0)
+	newScorekeeper := func(t *testing.T, r repo.Repo) *Scorekeeper {
+		sk, err := NewScorekeeper(r.Datastore(), nil)
+		require.NoError(t, err)
+		sk.now = func() time.Time { return now }
+		return sk
+	}
+	p := peer.ID("offender")
+
+	t.Run("bans peers whose score drops to the ban score", func(t *testing.T) {
+		sk := newScorekeeper(t, repo.NewInMemoryRepo())
+
+		sk.Penalize(p, InvalidBlock)
+		assert.False(t, sk.Banned(p))
+		sk.Penalize(p, InvalidBlock)
+		assert.True(t, sk.Banned(p))
+
+		scores := sk.Scores()
+		require.Len(t, scores, 1)
+		assert.Equal(t, float64(-100), scores[0].Score)
+		assert.Equal(t, uint64(2), scores[0].Offenses[InvalidBlock])
+
+		bans := sk.Bans()
+		require.Len(t, bans, 1)
+		assert.Equal(t, now.Add(TempBanDuration), *bans[0].Until)
+	})
+
+	t.Run("scores recover over time", func(t *testing.T) {
+		sk := newScorekeeper(t, repo.NewInMemoryRepo())
+
+		sk.Penalize(p, InvalidBlock)
+		sk.now = func() time.Time { return now.Add(30 * time.Minute) }
+		assert.Equal(t, float64(-25), sk.Scores()[0].Score)
+		sk.Penalize(p, InvalidBlock)
+		sk.Penalize(p, Spam)
+		assert.False(t, sk.Banned(p))
+
+		sk.now = func() time.Time { return now.Add(10 * time.Hour) }
+		assert.Equal(t, float64(0), sk.Scores()[0].Score)
+	})
+
+	t.Run("temporary bans end", func(t *testing.T) {
+		sk := newScorekeeper(t, repo.NewInMemoryRepo())
+
+		require.NoError(t, sk.Ban(p, time.Minute, "testing"))
+		assert.True(t, sk.Banned(p))
+		sk.now = func() time.Time { return now.Add(time.Minute) }
+		assert.False(t, sk.Banned(p))
+		assert.Empty(t, sk.Bans())
+	})
+
+	t.Run("persists bans", func(t *testing.T) {
+		r := repo.NewInMemoryRepo()
+		sk := newScorekeeper(t, r)
+		require.NoError(t, sk.Ban(p, 0, "testing"))
+
+		sk = newScorekeeper(t, r)
+		assert.True(t, sk.Banned(p))
+		assert.Equal(t, []Ban{{Peer: p, Reason: "testing"}}, sk.Bans())
+
+		require.NoError(t, sk.Unban(p))
+		assert.False(t, newScorekeeper(t, r).Banned(p))
+		assert.Error(t, sk.Unban(p))
+	})
+}
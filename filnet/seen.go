@@ -0,0 +1,116 @@
+package filnet
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// SeenCacheStats are the number of gossip messages of a topic found in the
+// seen cache, and not.
+type SeenCacheStats struct {
+	Topic   string  `json:"topic"`
+	Hits    uint64  `json:"hits"`
+	Misses  uint64  `json:"misses"`
+	HitRate float64 `json:"hitRate"`
+}
+
+// SeenCache remembers the contents of the gossip messages seen recently, by
+// cid, so that a block or message published several times, e.g. by several
+// peers or again by the same one under churn, is only processed and
+// forwarded once. Pubsub only drops the copies of a message with the same
+// sender and sequence number.
+type SeenCache struct {
+	ttl     time.Duration
+	maxSize int
+	now     func() time.Time
+
+	lk   sync.Mutex
+	seen map[seenKey]time.Time
+	// queue holds the keys in the order they were seen, so the oldest are
+	// evicted first.
+	queue []seenKey
+	stats map[string]*SeenCacheStats
+}
+
+type seenKey struct {
+	topic string
+	key   string
+}
+
+// NewSeenCache returns a SeenCache remembering up to maxSize contents for
+// ttl.
+func NewSeenCache(ttl time.Duration, maxSize int) *SeenCache {
+	return &SeenCache{
+		ttl:     ttl,
+		maxSize: maxSize,
+		now:     time.Now,
+		seen:    make(map[seenKey]time.Time),
+		stats:   make(map[string]*SeenCacheStats),
+	}
+}
+
+// Seen returns whether the content with key was seen on the topic within
+// the ttl, remembering it if it wasn't.
+func (sc *SeenCache) Seen(topic, key string) bool {
+	sc.lk.Lock()
+	defer sc.lk.Unlock()
+
+	now := sc.now()
+	sc.evict(now)
+
+	stats, ok := sc.stats[topic]
+	if !ok {
+		stats = &SeenCacheStats{Topic: topic}
+		sc.stats[topic] = stats
+	}
+
+	k := seenKey{topic: topic, key: key}
+	if _, ok := sc.seen[k]; ok {
+		stats.Hits++
+		return true
+	}
+	stats.Misses++
+	sc.seen[k] = now.Add(sc.ttl)
+	sc.queue = append(sc.queue, k)
+	return false
+}
+
+// Len returns the number of contents remembered.
+func (sc *SeenCache) Len() int {
+	sc.lk.Lock()
+	defer sc.lk.Unlock()
+
+	return len(sc.seen)
+}
+
+// Stats returns the hits and misses of the cache by topic.
+func (sc *SeenCache) Stats() []SeenCacheStats {
+	sc.lk.Lock()
+	defer sc.lk.Unlock()
+
+	var stats []SeenCacheStats
+	for _, s := range sc.stats {
+		st := *s
+		if total := st.Hits + st.Misses; total > 0 {
+			st.HitRate = float64(st.Hits) / float64(total)
+		}
+		stats = append(stats, st)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Topic < stats[j].Topic })
+	return stats
+}
+
+// evict forgets the contents seen longer than the ttl ago, and the oldest
+// while more than maxSize are remembered. The caller holds lk.
+func (sc *SeenCache) evict(now time.Time) {
+	n := 0
+	for ; n < len(sc.queue); n++ {
+		k := sc.queue[n]
+		if now.Before(sc.seen[k]) && len(sc.seen) < sc.maxSize {
+			break
+		}
+		delete(sc.seen, k)
+	}
+	sc.queue = sc.queue[n:]
+}
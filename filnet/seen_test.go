@@ -0,0 +1,62 @@
+package filnet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeenCache(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1000, 0)
+	newSeenCache := func(maxSize int) *SeenCache {
+		sc := NewSeenCache(time.Minute, maxSize)
+		sc.now = func() time.Time { return now }
+		return sc
+	}
+
+	t.Run("suppresses duplicates by topic", func(t *testing.T) {
+		assert := assert.New(t)
+		sc := newSeenCache(10)
+
+		assert.False(sc.Seen("blocks", "a"))
+		assert.True(sc.Seen("blocks", "a"))
+		assert.True(sc.Seen("blocks", "a"))
+		assert.False(sc.Seen("msgs", "a"))
+		assert.False(sc.Seen("blocks", "b"))
+
+		assert.Equal([]SeenCacheStats{
+			{Topic: "blocks", Hits: 2, Misses: 2, HitRate: 0.5},
+			{Topic: "msgs", Hits: 0, Misses: 1, HitRate: 0},
+		}, sc.Stats())
+	})
+
+	t.Run("forgets contents after the ttl", func(t *testing.T) {
+		assert := assert.New(t)
+		sc := newSeenCache(10)
+
+		assert.False(sc.Seen("blocks", "a"))
+		now = now.Add(30 * time.Second)
+		assert.False(sc.Seen("blocks", "b"))
+		now = now.Add(30 * time.Second)
+
+		assert.False(sc.Seen("blocks", "a"))
+		assert.True(sc.Seen("blocks", "b"))
+		assert.Equal(2, sc.Len())
+	})
+
+	t.Run("evicts the oldest contents when full", func(t *testing.T) {
+		assert := assert.New(t)
+		sc := newSeenCache(2)
+
+		assert.False(sc.Seen("blocks", "a"))
+		assert.False(sc.Seen("blocks", "b"))
+		assert.False(sc.Seen("blocks", "c"))
+		assert.Equal(2, sc.Len())
+
+		assert.True(sc.Seen("blocks", "c"))
+		assert.False(sc.Seen("blocks", "a"))
+	})
+}
@@ -0,0 +1,200 @@
+package filnet
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	"gx/ipfs/QmRXf2uUSdGSunRJsM9wXSUNVwLUGCY3So5fAs7h2CBJVf/go-hamt-ipld"
+	dag "gx/ipfs/QmTQdH4848iTVCJmKXYyRiK72HufWTLYQQ8iN3JaQ8K1Hq/go-merkledag"
+	blocks "gx/ipfs/QmWoXtvgC8inqFkAATB7cp2Dax7XBi9VDvSg9RCCZufmRk/go-block-format"
+	bserv "gx/ipfs/QmYPZzd9VqmJDwxUnThfeSbV1Y5o53aVPDijTB7j7rS9Ep/go-blockservice"
+	ipld "gx/ipfs/QmcKKBwfz6FyQdHR2jsXrrF6XeSBXYL86anmWNewpFpoF5/go-ipld-format"
+)
+
+// maxRecentSessions is the number of ended sessions whose stats are kept.
+const maxRecentSessions = 50
+
+// Kinds of the operations sessions are scoped to.
+const (
+	ChainSyncSession     = "chain-sync"
+	PieceTransferSession = "piece-transfer"
+)
+
+// SessionStats are the stats of a fetch session.
+type SessionStats struct {
+	ID      uint64     `json:"id"`
+	Kind    string     `json:"kind"`
+	Started time.Time  `json:"started"`
+	Ended   *time.Time `json:"ended,omitempty"`
+	// Blocks and Bytes count the blocks fetched in the session, locally or
+	// from the network, and Failures the blocks which couldn't be.
+	Blocks   uint64 `json:"blocks"`
+	Bytes    uint64 `json:"bytes"`
+	Failures uint64 `json:"failures"`
+}
+
+// FetchSessions opens bitswap sessions scoped to single operations of the
+// node, e.g. syncing a chain or transferring a piece. Blocks fetched in a
+// session are asked from the peers which had the previous blocks of the
+// session, rather than from every peer of the node. FetchSessions keeps the
+// stats of the sessions open and of the last ones which ended.
+type FetchSessions struct {
+	blockService bserv.BlockService
+	now          func() time.Time
+
+	lk     sync.Mutex
+	nextID uint64
+	active map[uint64]*FetchSession
+	recent []SessionStats
+}
+
+// NewFetchSessions returns FetchSessions opening sessions on blockService.
+func NewFetchSessions(blockService bserv.BlockService) *FetchSessions {
+	return &FetchSessions{
+		blockService: blockService,
+		now:          time.Now,
+		active:       make(map[uint64]*FetchSession),
+	}
+}
+
+// Open opens a session of the kind, which ends when ctx is done.
+func (fs *FetchSessions) Open(ctx context.Context, kind string) *FetchSession {
+	s := &FetchSession{
+		sessions: fs,
+		fetcher:  bserv.NewSession(ctx, fs.blockService),
+	}
+
+	fs.lk.Lock()
+	fs.nextID++
+	s.stats = SessionStats{ID: fs.nextID, Kind: kind, Started: fs.now()}
+	fs.active[s.stats.ID] = s
+	fs.lk.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		fs.end(s)
+	}()
+	return s
+}
+
+// NodeGetter returns a getter of the nodes of DAGs, which opens a session
+// of the kind for each call to merkledag.NewSession on it.
+func (fs *FetchSessions) NodeGetter(kind string) ipld.NodeGetter {
+	return &sessionMaker{
+		NodeGetter: dag.NewDAGService(fs.blockService),
+		sessions:   fs,
+		kind:       kind,
+	}
+}
+
+// Stats returns the stats of the open sessions, then of the last ones which
+// ended, the latest first.
+func (fs *FetchSessions) Stats() []SessionStats {
+	fs.lk.Lock()
+	defer fs.lk.Unlock()
+
+	var stats []SessionStats
+	for _, s := range fs.active {
+		stats = append(stats, s.stats)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].ID > stats[j].ID })
+	for i := len(fs.recent) - 1; i >= 0; i-- {
+		stats = append(stats, fs.recent[i])
+	}
+	return stats
+}
+
+func (fs *FetchSessions) end(s *FetchSession) {
+	fs.lk.Lock()
+	defer fs.lk.Unlock()
+
+	ended := fs.now()
+	s.stats.Ended = &ended
+	delete(fs.active, s.stats.ID)
+	fs.recent = append(fs.recent, s.stats)
+	if len(fs.recent) > maxRecentSessions {
+		fs.recent = fs.recent[len(fs.recent)-maxRecentSessions:]
+	}
+}
+
+func (fs *FetchSessions) fetched(s *FetchSession, b blocks.Block, err error) {
+	fs.lk.Lock()
+	defer fs.lk.Unlock()
+
+	if err != nil {
+		s.stats.Failures++
+		return
+	}
+	s.stats.Blocks++
+	s.stats.Bytes += uint64(len(b.RawData()))
+}
+
+// FetchSession fetches blocks in a bitswap session. It is both a getter of
+// blocks, e.g. for a hamt.CborIpldStore, and of the nodes of DAGs.
+type FetchSession struct {
+	sessions *FetchSessions
+	fetcher  *bserv.Session
+	// stats is guarded by the lock of sessions.
+	stats SessionStats
+}
+
+var _ ipld.NodeGetter = (*FetchSession)(nil)
+
+// GetBlock gets the block from the local blockstore, or from the network.
+func (s *FetchSession) GetBlock(ctx context.Context, c cid.Cid) (blocks.Block, error) {
+	b, err := s.fetcher.GetBlock(ctx, c)
+	s.sessions.fetched(s, b, err)
+	return b, err
+}
+
+// AddBlock adds the block to the blockservice of the session.
+func (s *FetchSession) AddBlock(b blocks.Block) error {
+	return s.sessions.blockService.AddBlock(b)
+}
+
+// Get gets the node from the local blockstore, or from the network.
+func (s *FetchSession) Get(ctx context.Context, c cid.Cid) (ipld.Node, error) {
+	b, err := s.GetBlock(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	return ipld.Decode(b)
+}
+
+// GetMany gets the nodes one after the other.
+func (s *FetchSession) GetMany(ctx context.Context, cids []cid.Cid) <-chan *ipld.NodeOption {
+	out := make(chan *ipld.NodeOption, len(cids))
+	go func() {
+		defer close(out)
+		for _, c := range cids {
+			nd, err := s.Get(ctx, c)
+			select {
+			case out <- &ipld.NodeOption{Node: nd, Err: err}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// CborStore returns a store whose blocks are fetched in the session.
+func (s *FetchSession) CborStore() *hamt.CborIpldStore {
+	return &hamt.CborIpldStore{Blocks: s}
+}
+
+// sessionMaker is a node getter opening sessions for merkledag.NewSession.
+type sessionMaker struct {
+	ipld.NodeGetter
+	sessions *FetchSessions
+	kind     string
+}
+
+var _ dag.SessionMaker = (*sessionMaker)(nil)
+
+func (sm *sessionMaker) Session(ctx context.Context) ipld.NodeGetter {
+	return sm.sessions.Open(ctx, sm.kind)
+}
@@ -0,0 +1,49 @@
+package filnet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gx/ipfs/QmS2aqUZLJp8kF1ihE5rvDGE5LvmKDPnx32w9Z1BW9xLV5/go-ipfs-blockstore"
+	blocks "gx/ipfs/QmWoXtvgC8inqFkAATB7cp2Dax7XBi9VDvSg9RCCZufmRk/go-block-format"
+	"gx/ipfs/QmYPZzd9VqmJDwxUnThfeSbV1Y5o53aVPDijTB7j7rS9Ep/go-blockservice"
+	"gx/ipfs/QmYZwey1thDTynSrvd6qQkX24UpTka6TFhQ2v569UpoqxD/go-ipfs-exchange-offline"
+	"gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchSessions(t *testing.T) {
+	t.Parallel()
+
+	bs := blockstore.NewBlockstore(datastore.NewMapDatastore())
+	fs := NewFetchSessions(blockservice.New(bs, offline.Exchange(bs)))
+
+	present := blocks.NewBlock([]byte("present"))
+	require.NoError(t, bs.Put(present))
+	missing := blocks.NewBlock([]byte("missing"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := fs.Open(ctx, ChainSyncSession)
+
+	_, err := s.GetBlock(ctx, present.Cid())
+	require.NoError(t, err)
+	_, err = s.GetBlock(ctx, missing.Cid())
+	require.Error(t, err)
+
+	stats := fs.Stats()
+	require.Len(t, stats, 1)
+	assert.Equal(t, ChainSyncSession, stats[0].Kind)
+	assert.Equal(t, uint64(1), stats[0].Blocks)
+	assert.Equal(t, uint64(len("present")), stats[0].Bytes)
+	assert.Equal(t, uint64(1), stats[0].Failures)
+	assert.Nil(t, stats[0].Ended)
+
+	cancel()
+	for i := 0; i < 10 && fs.Stats()[0].Ended == nil; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.NotNil(t, fs.Stats()[0].Ended)
+}
@@ -0,0 +1,29 @@
+package filnet
+
+import (
+	"context"
+
+	inet "gx/ipfs/QmNgLg1NTw37iWbYPKcyK85YJ9Whs1MkPtJwhfqbNYAyKg/go-libp2p-net"
+)
+
+// ResetOnDone resets s once ctx is done, unblocking any read or write in
+// progress on it. Reads and writes on a stream otherwise ignore the context
+// it was opened with. The returned function stops watching ctx, and must be
+// called once the caller is done with the stream; s is never reset after it
+// returns.
+func ResetOnDone(ctx context.Context, s inet.Stream) (stop func()) {
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		select {
+		case <-ctx.Done():
+			s.Reset() // nolint: errcheck
+		case <-done:
+		}
+	}()
+	return func() {
+		close(done)
+		<-exited
+	}
+}
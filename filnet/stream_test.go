@@ -0,0 +1,49 @@
+package filnet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	inet "gx/ipfs/QmNgLg1NTw37iWbYPKcyK85YJ9Whs1MkPtJwhfqbNYAyKg/go-libp2p-net"
+)
+
+type resetStream struct {
+	inet.Stream
+	reset chan struct{}
+}
+
+func (s *resetStream) Reset() error {
+	close(s.reset)
+	return nil
+}
+
+func TestResetOnDone(t *testing.T) {
+	t.Run("resets the stream once ctx is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		s := &resetStream{reset: make(chan struct{})}
+		stop := ResetOnDone(ctx, s)
+		defer stop()
+
+		cancel()
+		select {
+		case <-s.reset:
+		case <-time.After(time.Second):
+			t.Fatal("stream was not reset")
+		}
+	})
+
+	t.Run("leaves the stream alone once stopped", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		s := &resetStream{reset: make(chan struct{})}
+		stop := ResetOnDone(ctx, s)
+
+		stop()
+		cancel()
+		select {
+		case <-s.reset:
+			t.Fatal("stream was reset")
+		default:
+		}
+	})
+}
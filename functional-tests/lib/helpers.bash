@@ -59,15 +59,13 @@ function init_devnet_daemon {
             --auto-seal-interval-seconds="${AUTO_SEAL_INTERVAL_SECONDS}" \
             --repodir="$1" \
             --cmdapiaddr=/ip4/127.0.0.1/tcp/"$2" \
-            --devnet-test \
-            --genesisfile="http://test.kittyhawk.wtf:8020/genesis.car"
+            --network=staging
    else
         ./go-filecoin init \
             --auto-seal-interval-seconds="${AUTO_SEAL_INTERVAL_SECONDS}" \
             --repodir="$1" \
             --cmdapiaddr=/ip4/127.0.0.1/tcp/"$2" \
-            --devnet-nightly \
-            --genesisfile="http://nightly.kittyhawk.wtf:8020/genesis.car"
+            --network=nightly
     fi
 }
 
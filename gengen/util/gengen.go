@@ -363,7 +363,7 @@ type blockRewarder struct{}
 var _ consensus.BlockRewarder = (*blockRewarder)(nil)
 
 // BlockReward is a noop
-func (gbr *blockRewarder) BlockReward(ctx context.Context, st state.Tree, minerAddr address.Address) error {
+func (gbr *blockRewarder) BlockReward(ctx context.Context, st state.Tree, vms vm.StorageMap, minerAddr address.Address, bh *types.BlockHeight) error {
 	return nil
 }
 
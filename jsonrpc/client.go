@@ -0,0 +1,180 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+)
+
+// Client calls the methods of a JSON-RPC server. Calls are POSTed, each
+// subscription opens its own websocket.
+type Client struct {
+	url    string
+	header http.Header
+	http   *http.Client
+	nextID uint64
+}
+
+// NewClient returns a Client of the server at url, e.g.
+// http://127.0.0.1:3453/rpc/v0, authenticating with token unless it is
+// empty.
+func NewClient(url, token string) *Client {
+	header := make(http.Header)
+	if token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+	return &Client{url: url, header: header, http: http.DefaultClient}
+}
+
+func (c *Client) newRequest(method string, params []interface{}) ([]byte, json.RawMessage, error) {
+	id := json.RawMessage(strconv.FormatUint(atomic.AddUint64(&c.nextID, 1), 10))
+	if params == nil {
+		params = []interface{}{}
+	}
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := json.Marshal(request{JSONRPC: Version, ID: id, Method: method, Params: raw})
+	return req, id, err
+}
+
+// Call calls method with params and decodes its result into result, unless
+// result is nil. Errors returned by the server are *Error.
+func (c *Client) Call(ctx context.Context, method string, result interface{}, params ...interface{}) error {
+	body, _, err := c.newRequest(method, params)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for name, values := range c.header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := c.http.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close() // nolint: errcheck
+	out, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", res.Status, bytes.TrimSpace(out))
+	}
+
+	var resp response
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return err
+	}
+	return decodeResult(&resp, result)
+}
+
+func decodeResult(resp *response, result interface{}) error {
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(resp.Result, result)
+}
+
+// Subscribe calls the subscription method with params and returns its
+// events. The channel is closed once ctx is done or the websocket closes.
+func (c *Client) Subscribe(ctx context.Context, method string, params ...interface{}) (<-chan json.RawMessage, error) {
+	ws, err := dialWebsocket(ctx, c.url, c.header)
+	if err != nil {
+		return nil, err
+	}
+
+	req, id, err := c.newRequest(method, params)
+	if err == nil {
+		err = ws.writeFrame(opText, req)
+	}
+	if err != nil {
+		ws.conn.Close() // nolint: errcheck
+		return nil, err
+	}
+
+	// The server may send events before the response carrying the id of
+	// the subscription, which is the only one on this websocket anyway.
+	var early []json.RawMessage
+	for {
+		msg, err := ws.readMessage()
+		if err != nil {
+			ws.conn.Close() // nolint: errcheck
+			return nil, err
+		}
+		var n notification
+		if err := json.Unmarshal(msg, &n); err == nil && n.Method == NotificationMethod {
+			early = append(early, n.Params.Result)
+			continue
+		}
+		var resp response
+		if err := json.Unmarshal(msg, &resp); err != nil {
+			ws.conn.Close() // nolint: errcheck
+			return nil, err
+		}
+		if !bytes.Equal(resp.ID, id) {
+			continue
+		}
+		if err := decodeResult(&resp, nil); err != nil {
+			ws.conn.Close() // nolint: errcheck
+			return nil, err
+		}
+		break
+	}
+
+	out := make(chan json.RawMessage)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			ws.writeFrame(opClose, closePayload(1000)) // nolint: errcheck
+		case <-done:
+		}
+		ws.conn.Close() // nolint: errcheck
+	}()
+	go func() {
+		defer close(out)
+		defer close(done)
+		send := func(e json.RawMessage) bool {
+			select {
+			case out <- e:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		for _, e := range early {
+			if !send(e) {
+				return
+			}
+		}
+		for {
+			msg, err := ws.readMessage()
+			if err != nil {
+				return
+			}
+			var n notification
+			if err := json.Unmarshal(msg, &n); err != nil || n.Method != NotificationMethod {
+				continue
+			}
+			if !send(n.Params.Result) {
+				return
+			}
+		}
+	}()
+	return out, nil
+}
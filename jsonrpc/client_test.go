@@ -0,0 +1,88 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/go-filecoin/auth"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+
+	s := newTestServer()
+	// test.count sends the numbers from 0 until the subscription ends
+	s.RegisterSubscription("test.count", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (<-chan interface{}, error) {
+		out := make(chan interface{})
+		go func() {
+			defer close(out)
+			for i := 0; ; i++ {
+				select {
+				case out <- i:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+		return out, nil
+	})
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "")
+	ctx := context.Background()
+
+	t.Run("calls methods", func(t *testing.T) {
+		var sum int
+		require.NoError(t, c.Call(ctx, "test.add", &sum, 2, 3))
+		assert.Equal(t, 5, sum)
+
+		err := c.Call(ctx, "test.missing", nil)
+		require.Error(t, err)
+		rpcErr, ok := err.(*Error)
+		require.True(t, ok)
+		assert.Equal(t, CodeNotFound, rpcErr.Code)
+	})
+
+	t.Run("subscribes", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		events, err := c.Subscribe(ctx, "test.count")
+		require.NoError(t, err)
+		for i := 0; i < 3; i++ {
+			var n int
+			require.NoError(t, json.Unmarshal(<-events, &n))
+			assert.Equal(t, i, n)
+		}
+
+		cancel()
+		timeout := time.After(5 * time.Second)
+		for {
+			select {
+			case _, ok := <-events:
+				if !ok {
+					return
+				}
+			case <-timeout:
+				t.Fatal("subscription not closed")
+			}
+		}
+	})
+
+	t.Run("doesn't subscribe over http", func(t *testing.T) {
+		err := c.Call(ctx, "test.count", nil)
+		require.Error(t, err)
+		assert.Equal(t, CodeInvalidRequest, err.(*Error).Code)
+	})
+
+	t.Run("fails to subscribe to methods which don't exist", func(t *testing.T) {
+		_, err := c.Subscribe(ctx, "test.nope")
+		require.Error(t, err)
+		assert.Equal(t, CodeMethodNotFound, err.(*Error).Code)
+	})
+}
@@ -0,0 +1,866 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"time"
+
+	inet "gx/ipfs/QmNgLg1NTw37iWbYPKcyK85YJ9Whs1MkPtJwhfqbNYAyKg/go-libp2p-net"
+	pstore "gx/ipfs/QmPiemjiKBC9VA7vZF82m4x1oygtg2c2YVqag8PX7dN1BD/go-libp2p-peerstore"
+	cid "gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	peer "gx/ipfs/QmY5Grm8pJdiSSVsYxx4uNRgweY72EmYwuSDbRnbFok3iY/go-libp2p-peer"
+
+	"github.com/filecoin-project/go-filecoin/abi"
+	"github.com/filecoin-project/go-filecoin/actor"
+	minerActor "github.com/filecoin-project/go-filecoin/actor/builtin/miner"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/api"
+	"github.com/filecoin-project/go-filecoin/auth"
+	"github.com/filecoin-project/go-filecoin/core"
+	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/logs"
+	"github.com/filecoin-project/go-filecoin/plumbing/msg"
+	"github.com/filecoin-project/go-filecoin/plumbing/ntwk"
+	"github.com/filecoin-project/go-filecoin/plumbing/sctr"
+	"github.com/filecoin-project/go-filecoin/porcelain"
+	"github.com/filecoin-project/go-filecoin/proofs"
+	"github.com/filecoin-project/go-filecoin/state"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/wallet"
+)
+
+// porcelainAPI is the subset of the porcelain api the filecoin namespaces
+// call.
+type porcelainAPI interface {
+	ChainHead(ctx context.Context) types.TipSet
+	ChainHeadEvents(ctx context.Context) <-chan types.TipSet
+	ChainLsPage(ctx context.Context, filter porcelain.ChainLsFilter, cursor string, limit int) (*porcelain.ChainPage, error)
+	ChainStateDiff(ctx context.Context, tsA, tsB types.SortedCidSet) ([]*state.ActorDiff, error)
+	StateMigrateDryRun(ctx context.Context, upgrade string) ([]*state.ActorDiff, error)
+	BlockGet(ctx context.Context, id cid.Cid) (*types.Block, error)
+	ActorGet(ctx context.Context, addr address.Address) (*actor.Actor, error)
+	ActorGetSignature(ctx context.Context, actorAddr address.Address, method string) (*exec.FunctionSignature, error)
+	ActorReadState(ctx context.Context, addr address.Address, path string) (interface{}, error)
+	AddressBookLabel(addr address.Address, name string, force bool) error
+	AddressBookUnlabel(name string) error
+	AddressBookResolve(s string) (address.Address, error)
+	AddressBookLabels() ([]wallet.AddressLabel, error)
+	ConfigGet(dottedPath string) (interface{}, error)
+	ConfigSet(dottedPath string, paramJSON string) error
+	MessagePoolRemove(cid cid.Cid)
+	MessagePoolStats() core.MessagePoolStats
+	MessagePoolSubscribe(ctx context.Context) <-chan core.MessagePoolEvent
+	MessagePreview(ctx context.Context, from, to address.Address, method string, params ...interface{}) (types.GasUnits, error)
+	MessageCall(ctx context.Context, optFrom, to address.Address, method string, optHeight *types.BlockHeight, params ...interface{}) ([]*abi.Value, error)
+	MessageQuery(ctx context.Context, optFrom, to address.Address, method string, params ...interface{}) ([][]byte, *exec.FunctionSignature, error)
+	MessageReplay(ctx context.Context, msgCid cid.Cid) (*msg.Replay, error)
+	MessageCompose(ctx context.Context, from, to address.Address, nonce *uint64, value *types.AttoFIL, gasPrice types.AttoFIL, gasLimit types.GasUnits, method string, params ...interface{}) (*types.MeteredMessage, error)
+	MessagePublish(ctx context.Context, smsg *types.SignedMessage) (cid.Cid, error)
+	MessageSend(ctx context.Context, from, to address.Address, value *types.AttoFIL, gasPrice types.AttoFIL, gasLimit types.GasUnits, method string, params ...interface{}) (cid.Cid, error)
+	MessageSendBatch(ctx context.Context, from address.Address, batch []msg.BatchMessage) ([]cid.Cid, error)
+	MessageWait(ctx context.Context, msgCid cid.Cid, cb func(*types.Block, *types.SignedMessage, *types.MessageReceipt) error) error
+	NetworkGetPeerID() peer.ID
+	NetworkConnectedness(p peer.ID) inet.Connectedness
+	NetworkFindPeer(ctx context.Context, p peer.ID) (pstore.PeerInfo, error)
+	NetworkLatencies() []ntwk.PeerLatency
+	NetworkPing(ctx context.Context, p peer.ID) (time.Duration, error)
+	NetworkFindMiner(ctx context.Context, minerAddr address.Address) (pstore.PeerInfo, error)
+	NetworkProvideMiner(ctx context.Context, minerAddr address.Address) error
+	ProofsResources() proofs.Resources
+	SectorProgress(sectorID uint64) (*sctr.Progress, error)
+	SectorProgressList() []*sctr.Progress
+	SectorEvents(ctx context.Context) <-chan sctr.SectorEvent
+	SignBytes(data []byte, addr address.Address) (types.Signature, error)
+	WalletAddresses() []address.Address
+	WalletNewAddressOfType(keyType string) (address.Address, error)
+	WalletNewHDAddress() (address.Address, string, error)
+	WalletRecoverHD(mnemonic string, n uint32) ([]address.Address, error)
+	WalletHistory(ctx context.Context, addr address.Address, offset, limit uint) ([]*porcelain.WalletHistoryEntry, error)
+	MinerGetOwnerAddress(ctx context.Context, minerAddr address.Address) (address.Address, error)
+	MinerGetPeerID(ctx context.Context, minerAddr address.Address) (peer.ID, error)
+	MinerGetAsk(ctx context.Context, minerAddr address.Address, askID uint64) (minerActor.Ask, error)
+}
+
+// ChainHeadResult is the result of chain.head.
+type ChainHeadResult struct {
+	Cids   types.SortedCidSet `json:"cids"`
+	Height uint64             `json:"height"`
+}
+
+// MinerPowerResult is the result of miner.getPower. Powers are decimal
+// strings as they may not fit in the numbers of JSON parsers.
+type MinerPowerResult struct {
+	Power string `json:"power"`
+	Total string `json:"total"`
+}
+
+// SendParams are the params of mpool.send, a transfer of value.
+type SendParams struct {
+	From     address.Address `json:"from"`
+	To       address.Address `json:"to"`
+	Value    *types.AttoFIL  `json:"value"`
+	GasPrice types.AttoFIL   `json:"gasPrice"`
+	GasLimit types.GasUnits  `json:"gasLimit"`
+}
+
+// ChainLsParams are the params of chain.ls. Their fields are those of
+// porcelain.ChainLsFilter, and the cursor and limit of the page.
+type ChainLsParams struct {
+	FromHeight *uint64         `json:"fromHeight,omitempty"`
+	ToHeight   *uint64         `json:"toHeight,omitempty"`
+	Miner      address.Address `json:"miner,omitempty"`
+	Cursor     string          `json:"cursor,omitempty"`
+	Limit      int             `json:"limit,omitempty"`
+}
+
+// ChainLsResult is the result of chain.ls: the blocks of each tipset, from
+// the most recent, and the cursor of the next page, empty on the last one.
+type ChainLsResult struct {
+	TipSets [][]*types.Block `json:"tipSets"`
+	Next    string           `json:"next"`
+}
+
+// MessageParams are the params of the methods of the message namespace. The
+// params of the actor method are strings, parsed according to the signature
+// of the method as the arguments of the message commands are.
+type MessageParams struct {
+	From     address.Address `json:"from"`
+	To       address.Address `json:"to"`
+	Value    *types.AttoFIL  `json:"value,omitempty"`
+	GasPrice types.AttoFIL   `json:"gasPrice"`
+	GasLimit types.GasUnits  `json:"gasLimit"`
+	Method   string          `json:"method,omitempty"`
+	Params   []string        `json:"params,omitempty"`
+	// Nonce is only used by message.compose, Height by message.call.
+	Nonce  *uint64 `json:"nonce,omitempty"`
+	Height *uint64 `json:"height,omitempty"`
+}
+
+// MessageQueryResult is the result of message.query.
+type MessageQueryResult struct {
+	Return    [][]byte                `json:"return"`
+	Signature *exec.FunctionSignature `json:"signature"`
+}
+
+// MessageWaitResult is the result of message.wait.
+type MessageWaitResult struct {
+	Block   *types.Block          `json:"block"`
+	Message *types.SignedMessage  `json:"message"`
+	Receipt *types.MessageReceipt `json:"receipt"`
+}
+
+// PeerInfoResult is the result of network.findPeer and network.findMiner.
+type PeerInfoResult struct {
+	ID    string   `json:"id"`
+	Addrs []string `json:"addrs"`
+}
+
+// PeerLatencyResult is an element of the result of network.latencies.
+type PeerLatencyResult struct {
+	Peer    string        `json:"peer"`
+	Latency time.Duration `json:"latency"`
+}
+
+// HDAddressResult is the result of wallet.newHDAddress.
+type HDAddressResult struct {
+	Address  address.Address `json:"address"`
+	Mnemonic string          `json:"mnemonic"`
+}
+
+// RegisterFilecoin registers the version method, and the chain, state, addressBook, config, mpool,
+// message, network, log, proofs, sector, wallet and miner namespaces on s. Their method names and results are part of the api of
+// the node: add new methods rather than changing existing ones.
+func RegisterFilecoin(s *Server, nodeAPI api.API, plumbing porcelainAPI) {
+	s.Register("version", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		return nodeAPI.Version().Full()
+	})
+
+	// chain
+	s.Register("chain.head", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		ts := plumbing.ChainHead(ctx)
+		h, err := ts.Height()
+		if err != nil {
+			return nil, err
+		}
+		return ChainHeadResult{Cids: ts.ToSortedCidSet(), Height: h}, nil
+	})
+	s.Register("chain.ls", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		var p ChainLsParams
+		if err := DecodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		var filter porcelain.ChainLsFilter
+		if p.FromHeight != nil {
+			filter.FromHeight = types.NewBlockHeight(*p.FromHeight)
+		}
+		if p.ToHeight != nil {
+			filter.ToHeight = types.NewBlockHeight(*p.ToHeight)
+		}
+		filter.Miner = p.Miner
+		page, err := plumbing.ChainLsPage(ctx, filter, p.Cursor, p.Limit)
+		if err != nil {
+			return nil, err
+		}
+		res := ChainLsResult{TipSets: [][]*types.Block{}, Next: page.Next}
+		for _, ts := range page.TipSets {
+			res.TipSets = append(res.TipSets, ts.ToSlice())
+		}
+		return res, nil
+	})
+	s.Register("chain.getBlock", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		var c cidParam
+		if err := DecodeParams(params, &c); err != nil {
+			return nil, err
+		}
+		if !c.Defined() {
+			return nil, Errorf(CodeInvalidParams, "missing block cid")
+		}
+		return plumbing.BlockGet(ctx, c.Cid)
+	})
+
+	// state
+	s.Register("state.getActor", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		addr, err := decodeAddress(params)
+		if err != nil {
+			return nil, err
+		}
+		act, err := plumbing.ActorGet(ctx, addr)
+		if state.IsActorNotFoundError(err) {
+			return nil, Errorf(CodeNotFound, "no actor at %s", addr)
+		}
+		return act, err
+	})
+	s.Register("state.readState", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		var addr address.Address
+		var path string
+		if err := DecodeParams(params, &addr, &path); err != nil {
+			return nil, err
+		}
+		if addr.Empty() {
+			return nil, Errorf(CodeInvalidParams, "missing address")
+		}
+		st, err := plumbing.ActorReadState(ctx, addr, path)
+		if state.IsActorNotFoundError(err) {
+			return nil, Errorf(CodeNotFound, "no actor at %s", addr)
+		}
+		return st, err
+	})
+
+	// mpool
+	s.Register("mpool.pending", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		return nodeAPI.Mpool().View(ctx, 0)
+	})
+	s.Register("mpool.stats", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		return plumbing.MessagePoolStats(), nil
+	})
+	s.Register("mpool.publish", auth.PermWrite, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		// the signed message is cbor, in base64 as any JSON bytes
+		var raw []byte
+		if err := DecodeParams(params, &raw); err != nil {
+			return nil, err
+		}
+		smsg := &types.SignedMessage{}
+		if err := smsg.Unmarshal(raw); err != nil {
+			return nil, Errorf(CodeInvalidParams, "invalid signed message: %s", err)
+		}
+		return plumbing.MessagePublish(ctx, smsg)
+	})
+	s.Register("mpool.send", auth.PermSign, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		var p SendParams
+		if err := DecodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		if p.From.Empty() || p.To.Empty() || p.Value == nil {
+			return nil, Errorf(CodeInvalidParams, "from, to and value are required")
+		}
+		return plumbing.MessageSend(ctx, p.From, p.To, p.Value, p.GasPrice, p.GasLimit, "")
+	})
+
+	// wallet
+	s.Register("wallet.addresses", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		return plumbing.WalletAddresses(), nil
+	})
+	s.Register("wallet.balance", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		addr, err := decodeAddress(params)
+		if err != nil {
+			return nil, err
+		}
+		return nodeAPI.Address().Balance(ctx, addr)
+	})
+	s.Register("wallet.history", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		var addr address.Address
+		var offset uint
+		limit := uint(100)
+		if err := DecodeParams(params, &addr, &offset, &limit); err != nil {
+			return nil, err
+		}
+		if addr.Empty() {
+			return nil, Errorf(CodeInvalidParams, "missing address")
+		}
+		return plumbing.WalletHistory(ctx, addr, offset, limit)
+	})
+
+	// miner
+	s.Register("miner.getOwner", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		addr, err := decodeAddress(params)
+		if err != nil {
+			return nil, err
+		}
+		owner, err := plumbing.MinerGetOwnerAddress(ctx, addr)
+		return owner, minerError(addr, err)
+	})
+	s.Register("miner.getPeerID", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		addr, err := decodeAddress(params)
+		if err != nil {
+			return nil, err
+		}
+		pid, err := plumbing.MinerGetPeerID(ctx, addr)
+		if err != nil {
+			return nil, minerError(addr, err)
+		}
+		return pid.Pretty(), nil
+	})
+	s.Register("miner.getAsk", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		var addr address.Address
+		var askID uint64
+		if err := DecodeParams(params, &addr, &askID); err != nil {
+			return nil, err
+		}
+		if addr.Empty() {
+			return nil, Errorf(CodeInvalidParams, "missing address")
+		}
+		ask, err := plumbing.MinerGetAsk(ctx, addr, askID)
+		return ask, minerError(addr, err)
+	})
+	s.Register("miner.getPower", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		addr, err := decodeAddress(params)
+		if err != nil {
+			return nil, err
+		}
+		power, err := nodeAPI.Miner().GetPower(ctx, addr)
+		if err != nil {
+			return nil, minerError(addr, err)
+		}
+		total, err := nodeAPI.Miner().GetTotalPower(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return MinerPowerResult{Power: bigString(power), Total: bigString(total)}, nil
+	})
+
+	// chain, continued
+	s.RegisterSubscription("chain.subscribeHead", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (<-chan interface{}, error) {
+		heads := plumbing.ChainHeadEvents(ctx)
+		out := make(chan interface{})
+		go func() {
+			defer close(out)
+			for ts := range heads {
+				h, err := ts.Height()
+				if err != nil {
+					continue
+				}
+				select {
+				case out <- ChainHeadResult{Cids: ts.ToSortedCidSet(), Height: h}:
+				case <-ctx.Done():
+				}
+			}
+		}()
+		return out, nil
+	})
+
+	// state, continued
+	s.Register("state.getActorSignature", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		var addr address.Address
+		var method string
+		if err := DecodeParams(params, &addr, &method); err != nil {
+			return nil, err
+		}
+		if addr.Empty() || method == "" {
+			return nil, Errorf(CodeInvalidParams, "address and method are required")
+		}
+		sig, err := plumbing.ActorGetSignature(ctx, addr, method)
+		if state.IsActorNotFoundError(err) {
+			return nil, Errorf(CodeNotFound, "no actor at %s", addr)
+		}
+		return sig, err
+	})
+	s.Register("state.diff", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		var tsA, tsB types.SortedCidSet
+		if err := DecodeParams(params, &tsA, &tsB); err != nil {
+			return nil, err
+		}
+		if tsA.Len() == 0 || tsB.Len() == 0 {
+			return nil, Errorf(CodeInvalidParams, "two tipsets are required")
+		}
+		return plumbing.ChainStateDiff(ctx, tsA, tsB)
+	})
+	s.Register("state.migrateDryRun", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		var upgrade string
+		if err := DecodeParams(params, &upgrade); err != nil {
+			return nil, err
+		}
+		return plumbing.StateMigrateDryRun(ctx, upgrade)
+	})
+
+	// addressBook
+	s.Register("addressBook.labels", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		return plumbing.AddressBookLabels()
+	})
+	s.Register("addressBook.resolve", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		var name string
+		if err := DecodeParams(params, &name); err != nil {
+			return nil, err
+		}
+		addr, err := plumbing.AddressBookResolve(name)
+		if err != nil {
+			return nil, Errorf(CodeNotFound, "%s", err)
+		}
+		return addr, nil
+	})
+	s.Register("addressBook.label", auth.PermWrite, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		var addr address.Address
+		var name string
+		var force bool
+		if err := DecodeParams(params, &addr, &name, &force); err != nil {
+			return nil, err
+		}
+		return nil, plumbing.AddressBookLabel(addr, name, force)
+	})
+	s.Register("addressBook.unlabel", auth.PermWrite, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		var name string
+		if err := DecodeParams(params, &name); err != nil {
+			return nil, err
+		}
+		return nil, plumbing.AddressBookUnlabel(name)
+	})
+
+	// config
+	s.Register("config.get", auth.PermAdmin, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		var path string
+		if err := DecodeParams(params, &path); err != nil {
+			return nil, err
+		}
+		return plumbing.ConfigGet(path)
+	})
+	s.Register("config.set", auth.PermAdmin, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		// the value is any JSON, set as is
+		var path string
+		var value json.RawMessage
+		if err := DecodeParams(params, &path, &value); err != nil {
+			return nil, err
+		}
+		if path == "" || value == nil {
+			return nil, Errorf(CodeInvalidParams, "path and value are required")
+		}
+		return nil, plumbing.ConfigSet(path, string(value))
+	})
+
+	// mpool, continued
+	s.Register("mpool.remove", auth.PermWrite, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		var c cidParam
+		if err := DecodeParams(params, &c); err != nil {
+			return nil, err
+		}
+		if !c.Defined() {
+			return nil, Errorf(CodeInvalidParams, "missing message cid")
+		}
+		plumbing.MessagePoolRemove(c.Cid)
+		return nil, nil
+	})
+	s.RegisterSubscription("mpool.subscribe", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (<-chan interface{}, error) {
+		events := plumbing.MessagePoolSubscribe(ctx)
+		out := make(chan interface{})
+		go func() {
+			defer close(out)
+			for e := range events {
+				select {
+				case out <- e:
+				case <-ctx.Done():
+				}
+			}
+		}()
+		return out, nil
+	})
+
+	// message
+	s.Register("message.call", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		p, args, err := decodeMessage(ctx, plumbing, params)
+		if err != nil {
+			return nil, err
+		}
+		var height *types.BlockHeight
+		if p.Height != nil {
+			height = types.NewBlockHeight(*p.Height)
+		}
+		vals, err := plumbing.MessageCall(ctx, p.From, p.To, p.Method, height, args...)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]string, len(vals))
+		for i, v := range vals {
+			out[i] = v.String()
+		}
+		return out, nil
+	})
+	s.Register("message.query", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		p, args, err := decodeMessage(ctx, plumbing, params)
+		if err != nil {
+			return nil, err
+		}
+		ret, sig, err := plumbing.MessageQuery(ctx, p.From, p.To, p.Method, args...)
+		if err != nil {
+			return nil, err
+		}
+		return MessageQueryResult{Return: ret, Signature: sig}, nil
+	})
+	s.Register("message.preview", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		p, args, err := decodeMessage(ctx, plumbing, params)
+		if err != nil {
+			return nil, err
+		}
+		return plumbing.MessagePreview(ctx, p.From, p.To, p.Method, args...)
+	})
+	s.Register("message.compose", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		p, args, err := decodeMessage(ctx, plumbing, params)
+		if err != nil {
+			return nil, err
+		}
+		return plumbing.MessageCompose(ctx, p.From, p.To, p.Nonce, messageValue(p), p.GasPrice, p.GasLimit, p.Method, args...)
+	})
+	s.Register("message.send", auth.PermSign, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		p, args, err := decodeMessage(ctx, plumbing, params)
+		if err != nil {
+			return nil, err
+		}
+		if p.From.Empty() {
+			return nil, Errorf(CodeInvalidParams, "missing from address")
+		}
+		return plumbing.MessageSend(ctx, p.From, p.To, messageValue(p), p.GasPrice, p.GasLimit, p.Method, args...)
+	})
+	s.Register("message.sendBatch", auth.PermSign, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		var from address.Address
+		var ps []MessageParams
+		if err := DecodeParams(params, &from, &ps); err != nil {
+			return nil, err
+		}
+		if from.Empty() || len(ps) == 0 {
+			return nil, Errorf(CodeInvalidParams, "from address and messages are required")
+		}
+		batch := make([]msg.BatchMessage, len(ps))
+		for i, p := range ps {
+			args, err := parseMethodParams(ctx, plumbing, p)
+			if err != nil {
+				return nil, err
+			}
+			batch[i] = msg.BatchMessage{
+				To:       p.To,
+				Value:    messageValue(p),
+				GasPrice: p.GasPrice,
+				GasLimit: p.GasLimit,
+				Method:   p.Method,
+				Params:   args,
+			}
+		}
+		return plumbing.MessageSendBatch(ctx, from, batch)
+	})
+	s.Register("message.replay", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		var c cidParam
+		if err := DecodeParams(params, &c); err != nil {
+			return nil, err
+		}
+		if !c.Defined() {
+			return nil, Errorf(CodeInvalidParams, "missing message cid")
+		}
+		return plumbing.MessageReplay(ctx, c.Cid)
+	})
+	s.Register("message.wait", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		// waits until the message is on chain or the client goes away
+		var c cidParam
+		if err := DecodeParams(params, &c); err != nil {
+			return nil, err
+		}
+		if !c.Defined() {
+			return nil, Errorf(CodeInvalidParams, "missing message cid")
+		}
+		var res MessageWaitResult
+		err := plumbing.MessageWait(ctx, c.Cid, func(blk *types.Block, smsg *types.SignedMessage, receipt *types.MessageReceipt) error {
+			res = MessageWaitResult{Block: blk, Message: smsg, Receipt: receipt}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return res, nil
+	})
+
+	// network
+	s.Register("network.peerID", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		return plumbing.NetworkGetPeerID().Pretty(), nil
+	})
+	s.Register("network.connectedness", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		pid, err := decodePeer(params)
+		if err != nil {
+			return nil, err
+		}
+		return plumbing.NetworkConnectedness(pid), nil
+	})
+	s.Register("network.findPeer", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		pid, err := decodePeer(params)
+		if err != nil {
+			return nil, err
+		}
+		pi, err := plumbing.NetworkFindPeer(ctx, pid)
+		if err != nil {
+			return nil, err
+		}
+		return peerInfoResult(pi), nil
+	})
+	s.Register("network.latencies", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		var out []PeerLatencyResult
+		for _, l := range plumbing.NetworkLatencies() {
+			out = append(out, PeerLatencyResult{Peer: l.Peer.Pretty(), Latency: l.Latency})
+		}
+		return out, nil
+	})
+	s.Register("network.ping", auth.PermWrite, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		pid, err := decodePeer(params)
+		if err != nil {
+			return nil, err
+		}
+		return plumbing.NetworkPing(ctx, pid)
+	})
+	s.Register("network.findMiner", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		addr, err := decodeAddress(params)
+		if err != nil {
+			return nil, err
+		}
+		pi, err := plumbing.NetworkFindMiner(ctx, addr)
+		if err != nil {
+			return nil, minerError(addr, err)
+		}
+		return peerInfoResult(pi), nil
+	})
+	s.Register("network.provideMiner", auth.PermWrite, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		addr, err := decodeAddress(params)
+		if err != nil {
+			return nil, err
+		}
+		return nil, plumbing.NetworkProvideMiner(ctx, addr)
+	})
+
+	// log
+	s.RegisterSubscription("log.subscribe", auth.PermAdmin, func(ctx context.Context, params []json.RawMessage) (<-chan interface{}, error) {
+		var filter logs.Filter
+		if err := DecodeParams(params, &filter); err != nil {
+			return nil, err
+		}
+		if err := filter.Validate(); err != nil {
+			return nil, Errorf(CodeInvalidParams, "%s", err)
+		}
+		entries, err := nodeAPI.Log().Stream(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		out := make(chan interface{})
+		go func() {
+			defer close(out)
+			for e := range entries {
+				select {
+				case out <- e:
+				case <-ctx.Done():
+				}
+			}
+		}()
+		return out, nil
+	})
+
+	// proofs
+	s.Register("proofs.resources", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		return plumbing.ProofsResources(), nil
+	})
+
+	// sector
+	s.Register("sector.progress", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		var id uint64
+		if err := DecodeParams(params, &id); err != nil {
+			return nil, err
+		}
+		p, err := plumbing.SectorProgress(id)
+		if err != nil {
+			return nil, Errorf(CodeNotFound, "%s", err)
+		}
+		return p, nil
+	})
+	s.Register("sector.progressList", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		return plumbing.SectorProgressList(), nil
+	})
+	s.RegisterSubscription("sector.subscribe", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (<-chan interface{}, error) {
+		events := plumbing.SectorEvents(ctx)
+		out := make(chan interface{})
+		go func() {
+			defer close(out)
+			for e := range events {
+				select {
+				case out <- e:
+				case <-ctx.Done():
+				}
+			}
+		}()
+		return out, nil
+	})
+
+	// wallet, continued
+	s.Register("wallet.sign", auth.PermSign, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		var addr address.Address
+		var data []byte
+		if err := DecodeParams(params, &addr, &data); err != nil {
+			return nil, err
+		}
+		if addr.Empty() {
+			return nil, Errorf(CodeInvalidParams, "missing address")
+		}
+		return plumbing.SignBytes(data, addr)
+	})
+	s.Register("wallet.newAddress", auth.PermWrite, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		keyType := wallet.SECP256K1
+		if err := DecodeParams(params, &keyType); err != nil {
+			return nil, err
+		}
+		return plumbing.WalletNewAddressOfType(keyType)
+	})
+	s.Register("wallet.newHDAddress", auth.PermAdmin, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		// the mnemonic recovers the keys, hence admin
+		addr, mnemonic, err := plumbing.WalletNewHDAddress()
+		if err != nil {
+			return nil, err
+		}
+		return HDAddressResult{Address: addr, Mnemonic: mnemonic}, nil
+	})
+	s.Register("wallet.recoverHD", auth.PermAdmin, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		// restores the HD seed of the wallet, admin as address recover is
+		var mnemonic string
+		n := uint32(1)
+		if err := DecodeParams(params, &mnemonic, &n); err != nil {
+			return nil, err
+		}
+		if mnemonic == "" {
+			return nil, Errorf(CodeInvalidParams, "missing mnemonic")
+		}
+		return plumbing.WalletRecoverHD(mnemonic, n)
+	})
+}
+
+// cidParam decodes a cid given either as a string or as the {"/": "..."}
+// object the node encodes cids as.
+type cidParam struct {
+	cid.Cid
+}
+
+func (c *cidParam) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return c.Cid.UnmarshalJSON(b)
+	}
+	decoded, err := cid.Decode(s)
+	if err != nil {
+		return err
+	}
+	c.Cid = decoded
+	return nil
+}
+
+// decodeAddress decodes the params of methods taking a single, required
+// address.
+func decodeAddress(params []json.RawMessage) (address.Address, error) {
+	var addr address.Address
+	if err := DecodeParams(params, &addr); err != nil {
+		return address.Address{}, err
+	}
+	if addr.Empty() {
+		return address.Address{}, Errorf(CodeInvalidParams, "missing address")
+	}
+	return addr, nil
+}
+
+// minerError reports the methods of the miner namespace called with the
+// address of an actor which doesn't exist as not found.
+func minerError(addr address.Address, err error) error {
+	if state.IsActorNotFoundError(err) {
+		return Errorf(CodeNotFound, "no miner at %s", addr)
+	}
+	return err
+}
+
+func bigString(i *big.Int) string {
+	if i == nil {
+		return "0"
+	}
+	return i.String()
+}
+
+// decodeMessage decodes the MessageParams of the methods of the message
+// namespace and parses the params of their actor method.
+func decodeMessage(ctx context.Context, plumbing porcelainAPI, params []json.RawMessage) (MessageParams, []interface{}, error) {
+	var p MessageParams
+	if err := DecodeParams(params, &p); err != nil {
+		return p, nil, err
+	}
+	if p.To.Empty() {
+		return p, nil, Errorf(CodeInvalidParams, "missing to address")
+	}
+	args, err := parseMethodParams(ctx, plumbing, p)
+	return p, args, err
+}
+
+// parseMethodParams parses the params of the actor method of p according to
+// its signature.
+func parseMethodParams(ctx context.Context, plumbing porcelainAPI, p MessageParams) ([]interface{}, error) {
+	if p.Method == "" {
+		if len(p.Params) > 0 {
+			return nil, Errorf(CodeInvalidParams, "params given without a method")
+		}
+		return nil, nil
+	}
+	sig, err := plumbing.ActorGetSignature(ctx, p.To, p.Method)
+	if err != nil {
+		if state.IsActorNotFoundError(err) {
+			return nil, Errorf(CodeNotFound, "no actor at %s", p.To)
+		}
+		return nil, Errorf(CodeInvalidParams, "couldn't get the signature of %s: %s", p.Method, err)
+	}
+	vals, err := abi.ParseValues(p.Params, sig.Params)
+	if err != nil {
+		return nil, Errorf(CodeInvalidParams, "invalid method params: %s", err)
+	}
+	return abi.FromValues(vals), nil
+}
+
+func messageValue(p MessageParams) *types.AttoFIL {
+	if p.Value == nil {
+		return types.ZeroAttoFIL
+	}
+	return p.Value
+}
+
+// decodePeer decodes the params of methods taking a single, required peer
+// id.
+func decodePeer(params []json.RawMessage) (peer.ID, error) {
+	var s string
+	if err := DecodeParams(params, &s); err != nil {
+		return "", err
+	}
+	pid, err := peer.IDB58Decode(s)
+	if err != nil {
+		return "", Errorf(CodeInvalidParams, "invalid peer id: %s", err)
+	}
+	return pid, nil
+}
+
+func peerInfoResult(pi pstore.PeerInfo) PeerInfoResult {
+	res := PeerInfoResult{ID: pi.ID.Pretty(), Addrs: []string{}}
+	for _, a := range pi.Addrs {
+		res.Addrs = append(res.Addrs, a.String())
+	}
+	return res
+}
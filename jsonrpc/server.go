@@ -0,0 +1,327 @@
+// Package jsonrpc serves the node's api over JSON-RPC 2.0, on http and
+// websockets, for clients such as exchanges and block explorers which
+// integrate with the node programmatically rather than through the output of
+// the commands.
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	logging "gx/ipfs/QmcuXC5cxs79ro2cUuHs4HQ2bkDLJUYokwL8aivcX6HW3C/go-log"
+
+	"github.com/filecoin-project/go-filecoin/auth"
+)
+
+var log = logging.Logger("jsonrpc")
+
+// Version is the version of the JSON-RPC protocol served.
+const Version = "2.0"
+
+// Path is the path the daemon serves the JSON-RPC api on.
+const Path = "/rpc/v0"
+
+// maxRequestSize is the largest request, or websocket message, read.
+const maxRequestSize = 10 << 20
+
+// Error codes. The codes from -32768 to -32000 are reserved by the JSON-RPC
+// specification, the codes from -32099 to -32000 for errors of the server.
+const (
+	// CodeParseError is returned when the request isn't valid JSON.
+	CodeParseError = -32700
+	// CodeInvalidRequest is returned when the request isn't a valid request
+	// object.
+	CodeInvalidRequest = -32600
+	// CodeMethodNotFound is returned when the method doesn't exist.
+	CodeMethodNotFound = -32601
+	// CodeInvalidParams is returned when the params of the method are
+	// invalid.
+	CodeInvalidParams = -32602
+	// CodeInternalError is returned when the method failed.
+	CodeInternalError = -32603
+	// CodeNotFound is returned when the block, actor or miner requested
+	// doesn't exist.
+	CodeNotFound = -32001
+	// CodeUnauthorized is returned when the token of the client doesn't
+	// grant the permission the method requires.
+	CodeUnauthorized = -32002
+)
+
+// Error is the error of a JSON-RPC response. Methods return an *Error to
+// choose the code of the error, any other error is an internal error.
+type Error struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("jsonrpc error %d: %s", e.Code, e.Message)
+}
+
+// Errorf returns an *Error with code and a formatted message.
+func Errorf(code int, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Method is a JSON-RPC method, called with the positional params of the
+// request.
+type Method func(ctx context.Context, params []json.RawMessage) (interface{}, error)
+
+// DecodeParams decodes the params of a request into args, in order. Params
+// missing at the end leave their args untouched so that they can be
+// optional.
+func DecodeParams(params []json.RawMessage, args ...interface{}) error {
+	if len(params) > len(args) {
+		return Errorf(CodeInvalidParams, "expected at most %d params, got %d", len(args), len(params))
+	}
+	for i, p := range params {
+		if err := json.Unmarshal(p, args[i]); err != nil {
+			return Errorf(CodeInvalidParams, "invalid param %d: %s", i, err)
+		}
+	}
+	return nil
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Server serves JSON-RPC requests over http and websockets.
+type Server struct {
+	lk             sync.RWMutex
+	methods        map[string]method
+	allowedOrigins []string
+}
+
+// method is a registered method, either a call or a subscription.
+type method struct {
+	perm auth.Permission
+	call Method
+	sub  Subscription
+}
+
+// NewServer returns a Server without methods but unsubscribe, accepting
+// requests from any origin.
+func NewServer() *Server {
+	return &Server{
+		methods: map[string]method{
+			UnsubscribeMethod: {perm: auth.PermRead, call: unsubscribe},
+		},
+	}
+}
+
+// Register registers m as the method name, callable by the clients granted
+// perm. Names are of the form namespace.method, e.g. chain.head, and must
+// not change once released.
+func (s *Server) Register(name string, perm auth.Permission, m Method) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	s.methods[name] = method{perm: perm, call: m}
+}
+
+// Methods returns the names of the methods registered.
+func (s *Server) Methods() []string {
+	s.lk.RLock()
+	defer s.lk.RUnlock()
+
+	var names []string
+	for name := range s.methods {
+		names = append(names, name)
+	}
+	return names
+}
+
+// AllowOrigins restricts the requests made by browsers, which send an Origin
+// header, to the origins given. "*" allows any origin.
+func (s *Server) AllowOrigins(origins ...string) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	s.allowedOrigins = origins
+}
+
+func (s *Server) originAllowed(origin string) bool {
+	s.lk.RLock()
+	defer s.lk.RUnlock()
+
+	if origin == "" || s.allowedOrigins == nil {
+		return true
+	}
+	for _, o := range s.allowedOrigins {
+		if o == "*" || o == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// ServeHTTP serves JSON-RPC requests POSTed, and websocket connections on
+// which each message is a request.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if !s.originAllowed(origin) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+	if origin != "" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+	}
+
+	switch {
+	case r.Method == http.MethodGet && strings.EqualFold(r.Header.Get("Upgrade"), "websocket"):
+		s.serveWebsocket(w, r)
+	case r.Method == http.MethodOptions:
+		w.Header().Set("Access-Control-Allow-Methods", "POST")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost:
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestSize))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		out := s.Handle(r.Context(), body)
+		if out == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(out) // nolint: errcheck
+	default:
+		http.Error(w, "JSON-RPC requests must be POSTed", http.StatusMethodNotAllowed)
+	}
+}
+
+// Handle handles a request, or a batch of requests, and returns the
+// response, or nil if all the requests were notifications.
+func (s *Server) Handle(ctx context.Context, data []byte) []byte {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || data[0] != '[' {
+		resp := s.handleOne(ctx, data)
+		if resp == nil {
+			return nil
+		}
+		return marshalResponse(resp)
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(data, &batch); err != nil {
+		return marshalResponse(errorResponse(nil, Errorf(CodeParseError, "%s", err)))
+	}
+	if len(batch) == 0 {
+		return marshalResponse(errorResponse(nil, Errorf(CodeInvalidRequest, "empty batch")))
+	}
+
+	resps := make([]*response, len(batch))
+	var wg sync.WaitGroup
+	for i, req := range batch {
+		wg.Add(1)
+		go func(i int, req json.RawMessage) {
+			defer wg.Done()
+			resps[i] = s.handleOne(ctx, req)
+		}(i, req)
+	}
+	wg.Wait()
+
+	var out []*response
+	for _, resp := range resps {
+		if resp != nil {
+			out = append(out, resp)
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return marshalResponse(out)
+}
+
+// handleOne handles a single request and returns its response, or nil if it
+// is a notification.
+func (s *Server) handleOne(ctx context.Context, data json.RawMessage) *response {
+	var req request
+	if err := json.Unmarshal(data, &req); err != nil {
+		if _, ok := err.(*json.SyntaxError); ok {
+			return errorResponse(nil, Errorf(CodeParseError, "%s", err))
+		}
+		return errorResponse(nil, Errorf(CodeInvalidRequest, "%s", err))
+	}
+	if req.JSONRPC != Version || req.Method == "" {
+		return errorResponse(req.ID, Errorf(CodeInvalidRequest, "not a JSON-RPC %s request", Version))
+	}
+
+	var params []json.RawMessage
+	if p := bytes.TrimSpace(req.Params); len(p) > 0 && !bytes.Equal(p, []byte("null")) {
+		if err := json.Unmarshal(p, &params); err != nil {
+			return errorResponse(req.ID, Errorf(CodeInvalidParams, "params must be an array"))
+		}
+	}
+
+	s.lk.RLock()
+	m, ok := s.methods[req.Method]
+	s.lk.RUnlock()
+
+	var result interface{}
+	var err error
+	switch {
+	case !ok:
+		err = Errorf(CodeMethodNotFound, "method %s not found", req.Method)
+	case !auth.HasPermission(ctx, m.perm):
+		err = Errorf(CodeUnauthorized, "method %s requires the %s permission", req.Method, m.perm)
+	case m.sub != nil:
+		result, err = subscribe(ctx, m.sub, params)
+	default:
+		result, err = m.call(ctx, params)
+	}
+
+	// A request without an id is a notification, which isn't answered.
+	if req.ID == nil {
+		return nil
+	}
+	if err != nil {
+		rpcErr, ok := err.(*Error)
+		if !ok {
+			rpcErr = &Error{Code: CodeInternalError, Message: err.Error()}
+		}
+		return errorResponse(req.ID, rpcErr)
+	}
+
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return errorResponse(req.ID, Errorf(CodeInternalError, "failed to marshal result: %s", err))
+	}
+	return &response{JSONRPC: Version, ID: req.ID, Result: raw}
+}
+
+func errorResponse(id json.RawMessage, err *Error) *response {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return &response{JSONRPC: Version, ID: id, Error: err}
+}
+
+func marshalResponse(v interface{}) []byte {
+	out, err := json.Marshal(v)
+	if err != nil {
+		// responses only hold raw json and errors, which always marshal
+		panic(err)
+	}
+	return out
+}
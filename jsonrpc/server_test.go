@@ -0,0 +1,292 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/go-filecoin/auth"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer() *Server {
+	s := NewServer()
+	s.Register("test.add", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		var a, b int
+		if err := DecodeParams(params, &a, &b); err != nil {
+			return nil, err
+		}
+		return a + b, nil
+	})
+	s.Register("test.missing", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		return nil, Errorf(CodeNotFound, "nothing here")
+	})
+	s.Register("test.fail", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		return nil, errors.New("boom")
+	})
+	s.Register("test.admin", auth.PermAdmin, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		return "ok", nil
+	})
+	return s
+}
+
+func TestServerHandle(t *testing.T) {
+	t.Parallel()
+
+	s := newTestServer()
+	call := func(req string) string {
+		return string(s.Handle(context.Background(), []byte(req)))
+	}
+
+	t.Run("calls methods", func(t *testing.T) {
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"result":3}`, call(`{"jsonrpc":"2.0","id":1,"method":"test.add","params":[1,2]}`))
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":"a","result":0}`, call(`{"jsonrpc":"2.0","id":"a","method":"test.add"}`))
+	})
+
+	t.Run("does not answer notifications", func(t *testing.T) {
+		assert.Equal(t, "", call(`{"jsonrpc":"2.0","method":"test.add","params":[1,2]}`))
+	})
+
+	t.Run("returns typed errors", func(t *testing.T) {
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"error":{"code":-32001,"message":"nothing here"}}`, call(`{"jsonrpc":"2.0","id":1,"method":"test.missing"}`))
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"error":{"code":-32603,"message":"boom"}}`, call(`{"jsonrpc":"2.0","id":1,"method":"test.fail"}`))
+		assert.Contains(t, call(`{"jsonrpc":"2.0","id":1,"method":"test.nope"}`), `"code":-32601`)
+		assert.Contains(t, call(`{"jsonrpc":"2.0","id":1,"method":"test.add","params":[1,2,3]}`), `"code":-32602`)
+		assert.Contains(t, call(`{"jsonrpc":"2.0","id":1,"method":"test.add","params":{"a":1}}`), `"code":-32602`)
+		assert.Contains(t, call(`{"jsonrpc":"1.0","id":1,"method":"test.add"}`), `"code":-32600`)
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"unexpected end of JSON input"}}`, call(`{"jsonrpc":`))
+	})
+
+	t.Run("checks permissions", func(t *testing.T) {
+		req := []byte(`{"jsonrpc":"2.0","id":1,"method":"test.admin"}`)
+		out := s.Handle(auth.WithPermission(context.Background(), auth.PermSign), req)
+		assert.Contains(t, string(out), `"code":-32002`)
+
+		out = s.Handle(auth.WithPermission(context.Background(), auth.PermAdmin), req)
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"result":"ok"}`, string(out))
+	})
+
+	t.Run("handles batches", func(t *testing.T) {
+		assert.JSONEq(t, `[
+			{"jsonrpc":"2.0","id":1,"result":3},
+			{"jsonrpc":"2.0","id":2,"error":{"code":-32001,"message":"nothing here"}}
+		]`, call(`[
+			{"jsonrpc":"2.0","id":1,"method":"test.add","params":[1,2]},
+			{"jsonrpc":"2.0","method":"test.add","params":[1,2]},
+			{"jsonrpc":"2.0","id":2,"method":"test.missing"}
+		]`))
+		assert.Equal(t, "", call(`[{"jsonrpc":"2.0","method":"test.add"}]`))
+		assert.Contains(t, call(`[]`), `"code":-32600`)
+	})
+}
+
+func TestServerHTTP(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s := newTestServer()
+	s.AllowOrigins("http://localhost:8080")
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	post := func(origin, body string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader(body))
+		require.NoError(err)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(err)
+		return resp
+	}
+
+	resp := post("", `{"jsonrpc":"2.0","id":1,"method":"test.add","params":[1,2]}`)
+	defer resp.Body.Close() // nolint: errcheck
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.Equal("application/json", resp.Header.Get("Content-Type"))
+	var out response
+	require.NoError(json.NewDecoder(resp.Body).Decode(&out))
+	assert.Equal("3", string(out.Result))
+
+	resp = post("http://localhost:8080", `{"jsonrpc":"2.0","method":"test.add"}`)
+	defer resp.Body.Close() // nolint: errcheck
+	assert.Equal(http.StatusNoContent, resp.StatusCode)
+	assert.Equal("http://localhost:8080", resp.Header.Get("Access-Control-Allow-Origin"))
+
+	resp = post("http://evil.example", `{"jsonrpc":"2.0","id":1,"method":"test.add"}`)
+	defer resp.Body.Close() // nolint: errcheck
+	assert.Equal(http.StatusForbidden, resp.StatusCode)
+
+	resp, err := http.Get(srv.URL)
+	require.NoError(err)
+	defer resp.Body.Close() // nolint: errcheck
+	assert.Equal(http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestServerWebsocket(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	srv := httptest.NewServer(newTestServer())
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	require.NoError(err)
+	defer conn.Close() // nolint: errcheck
+
+	_, err = io.WriteString(conn, "GET / HTTP/1.1\r\n"+
+		"Host: localhost\r\n"+
+		"Upgrade: websocket\r\n"+
+		"Connection: keep-alive, Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n"+
+		"Sec-WebSocket-Version: 13\r\n\r\n")
+	require.NoError(err)
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	require.NoError(err)
+	assert.Equal(http.StatusSwitchingProtocols, resp.StatusCode)
+	assert.Equal("s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", resp.Header.Get("Sec-WebSocket-Accept"))
+
+	// a request split in two fragments, with a ping in between
+	writeClientFrame(t, conn, false, opText, []byte(`{"jsonrpc":"2.0","id":1,`))
+	writeClientFrame(t, conn, true, opPing, []byte("hi"))
+	writeClientFrame(t, conn, true, opContinuation, []byte(`"method":"test.add","params":[2,3]}`))
+
+	op, payload := readServerFrame(t, br)
+	assert.Equal(byte(opPong), op)
+	assert.Equal("hi", string(payload))
+
+	op, payload = readServerFrame(t, br)
+	assert.Equal(byte(opText), op)
+	assert.JSONEq(`{"jsonrpc":"2.0","id":1,"result":5}`, string(payload))
+
+	// a large request, whose length takes two more bytes
+	req := `{"jsonrpc":"2.0","id":2,"method":"test.add","params":[4,5]}`
+	writeClientFrame(t, conn, true, opText, []byte(req+strings.Repeat(" ", 200)))
+	op, payload = readServerFrame(t, br)
+	assert.Equal(byte(opText), op)
+	assert.JSONEq(`{"jsonrpc":"2.0","id":2,"result":9}`, string(payload))
+
+	writeClientFrame(t, conn, true, opClose, closePayload(1000))
+	op, _ = readServerFrame(t, br)
+	assert.Equal(byte(opClose), op)
+}
+
+func TestServerWebsocketBoundsConcurrentRequests(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s := newTestServer()
+	release := make(chan struct{})
+	s.Register("test.block", auth.PermRead, func(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+		<-release
+		return "released", nil
+	})
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	require.NoError(err)
+	defer conn.Close() // nolint: errcheck
+
+	_, err = io.WriteString(conn, "GET / HTTP/1.1\r\n"+
+		"Host: localhost\r\n"+
+		"Upgrade: websocket\r\n"+
+		"Connection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n"+
+		"Sec-WebSocket-Version: 13\r\n\r\n")
+	require.NoError(err)
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	require.NoError(err)
+	require.Equal(http.StatusSwitchingProtocols, resp.StatusCode)
+
+	for i := 0; i < maxWebsocketRequests; i++ {
+		writeClientFrame(t, conn, true, opText, []byte(`{"jsonrpc":"2.0","id":1,"method":"test.block"}`))
+	}
+	writeClientFrame(t, conn, true, opText, []byte(`{"jsonrpc":"2.0","id":2,"method":"test.add","params":[2,3]}`))
+
+	// the request after the blocked ones isn't read until one of them is
+	// answered
+	answered := make(chan []byte)
+	go func() {
+		_, payload := readServerFrame(t, br)
+		answered <- payload
+	}()
+	select {
+	case payload := <-answered:
+		t.Fatalf("answered %s while all the requests were blocked", payload)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	results := map[string]int{}
+	payload := <-answered
+	for i := 0; ; i++ {
+		var out struct {
+			Result json.RawMessage
+		}
+		require.NoError(json.Unmarshal(payload, &out))
+		results[string(out.Result)]++
+		if i == maxWebsocketRequests {
+			break
+		}
+		_, payload = readServerFrame(t, br)
+	}
+	assert.Equal(map[string]int{`"released"`: maxWebsocketRequests, "5": 1}, results)
+}
+
+func writeClientFrame(t *testing.T, w io.Writer, fin bool, op byte, payload []byte) {
+	var buf bytes.Buffer
+	b0 := op
+	if fin {
+		b0 |= 0x80
+	}
+	buf.WriteByte(b0)
+	if len(payload) < 126 {
+		buf.WriteByte(0x80 | byte(len(payload)))
+	} else {
+		buf.WriteByte(0x80 | 126)
+		binary.Write(&buf, binary.BigEndian, uint16(len(payload))) // nolint: errcheck
+	}
+	mask := []byte{1, 2, 3, 4}
+	buf.Write(mask)
+	for i, b := range payload {
+		buf.WriteByte(b ^ mask[i%4])
+	}
+	_, err := w.Write(buf.Bytes())
+	require.NoError(t, err)
+}
+
+func readServerFrame(t *testing.T, r io.Reader) (byte, []byte) {
+	var hdr [2]byte
+	_, err := io.ReadFull(r, hdr[:])
+	require.NoError(t, err)
+	require.Zero(t, hdr[1]&0x80, "server frames are not masked")
+	n := int(hdr[1] & 0x7f)
+	if n == 126 {
+		var ext [2]byte
+		_, err := io.ReadFull(r, ext[:])
+		require.NoError(t, err)
+		n = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload := make([]byte, n)
+	_, err = io.ReadFull(r, payload)
+	require.NoError(t, err)
+	return hdr[0] & 0x0f, payload
+}
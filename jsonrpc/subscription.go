@@ -0,0 +1,139 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+
+	"github.com/filecoin-project/go-filecoin/auth"
+)
+
+// NotificationMethod is the method of the notifications carrying the events
+// of subscriptions. Their params are a SubscriptionEvent.
+const NotificationMethod = "subscription"
+
+// UnsubscribeMethod is the method which cancels a subscription, given its
+// id. Subscriptions are also canceled when their websocket closes.
+const UnsubscribeMethod = "unsubscribe"
+
+// Subscription is a JSON-RPC method returning a stream of events, called
+// with the positional params of the request. The channel must be closed
+// once ctx is done.
+type Subscription func(ctx context.Context, params []json.RawMessage) (<-chan interface{}, error)
+
+// SubscriptionEvent is the params of the notifications of subscriptions.
+type SubscriptionEvent struct {
+	Subscription string          `json:"subscription"`
+	Result       json.RawMessage `json:"result"`
+}
+
+type notification struct {
+	JSONRPC string            `json:"jsonrpc"`
+	Method  string            `json:"method"`
+	Params  SubscriptionEvent `json:"params"`
+}
+
+// RegisterSubscription registers sub as the method name, callable by the
+// clients granted perm. Subscriptions are only served over websockets: the
+// method returns the id of the subscription, and each event is then sent
+// as a notification holding this id.
+func (s *Server) RegisterSubscription(name string, perm auth.Permission, sub Subscription) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	s.methods[name] = method{perm: perm, sub: sub}
+}
+
+// subscriptions are the subscriptions of a websocket.
+type subscriptions struct {
+	// ctx is done when the websocket closes.
+	ctx    context.Context
+	notify func([]byte) error
+
+	lk      sync.Mutex
+	next    uint64
+	cancels map[string]context.CancelFunc
+}
+
+type subscriptionsKey struct{}
+
+func withSubscriptions(ctx context.Context, notify func([]byte) error) context.Context {
+	subs := &subscriptions{
+		ctx:     ctx,
+		notify:  notify,
+		cancels: make(map[string]context.CancelFunc),
+	}
+	return context.WithValue(ctx, subscriptionsKey{}, subs)
+}
+
+// subscribe starts sub and forwards its events to the websocket of ctx.
+func subscribe(ctx context.Context, sub Subscription, params []json.RawMessage) (interface{}, error) {
+	subs, ok := ctx.Value(subscriptionsKey{}).(*subscriptions)
+	if !ok {
+		return nil, Errorf(CodeInvalidRequest, "subscriptions are only served over websockets")
+	}
+
+	subCtx, cancel := context.WithCancel(subs.ctx)
+	events, err := sub(subCtx, params)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	subs.lk.Lock()
+	subs.next++
+	id := strconv.FormatUint(subs.next, 10)
+	subs.cancels[id] = cancel
+	subs.lk.Unlock()
+
+	go func() {
+		defer subs.cancel(id)
+		for e := range events {
+			raw, err := json.Marshal(e)
+			if err != nil {
+				log.Warningf("failed to marshal event of subscription %s: %s", id, err)
+				continue
+			}
+			n := notification{
+				JSONRPC: Version,
+				Method:  NotificationMethod,
+				Params:  SubscriptionEvent{Subscription: id, Result: raw},
+			}
+			if err := subs.notify(marshalResponse(n)); err != nil {
+				// the websocket is closed: drain the events until the
+				// subscription notices
+				subs.cancel(id)
+				for range events {
+				}
+				return
+			}
+		}
+	}()
+	return id, nil
+}
+
+// cancel cancels the subscription id and returns whether it existed.
+func (subs *subscriptions) cancel(id string) bool {
+	subs.lk.Lock()
+	defer subs.lk.Unlock()
+
+	cancel, ok := subs.cancels[id]
+	if ok {
+		cancel()
+		delete(subs.cancels, id)
+	}
+	return ok
+}
+
+func unsubscribe(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+	var id string
+	if err := DecodeParams(params, &id); err != nil {
+		return nil, err
+	}
+	subs, ok := ctx.Value(subscriptionsKey{}).(*subscriptions)
+	if !ok {
+		return false, nil
+	}
+	return subs.cancel(id), nil
+}
@@ -0,0 +1,324 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1" // nolint: gosec
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// websocketGUID is appended to the key of the client to accept a websocket
+// handshake, as per RFC 6455.
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Websocket opcodes.
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+// maxWebsocketRequests is the number of requests of a websocket handled at
+// once.
+const maxWebsocketRequests = 16
+
+var errMessageTooLarge = errors.New("websocket message too large")
+
+// serveWebsocket upgrades the connection to a websocket and handles each
+// message received as a request, answering on the same websocket. Requests
+// are handled concurrently so a slow method doesn't hold up the others, up
+// to maxWebsocketRequests at once: the next message isn't read until one of
+// them is answered, which slows down a client sending requests faster than
+// they are handled.
+func (s *Server) serveWebsocket(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" || r.Header.Get("Sec-WebSocket-Version") != "13" || !headerContains(r.Header, "Connection", "upgrade") {
+		http.Error(w, "invalid websocket handshake", http.StatusBadRequest)
+		return
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websockets not supported", http.StatusInternalServerError)
+		return
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close() // nolint: errcheck
+
+	accept := sha1.Sum([]byte(key + websocketGUID)) // nolint: gosec
+
+	handshake := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(accept[:]) + "\r\n\r\n"
+	if _, err := rw.WriteString(handshake); err != nil {
+		return
+	}
+	if err := rw.Flush(); err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	ws := &wsConn{conn: conn, r: rw.Reader}
+	ctx = withSubscriptions(ctx, func(msg []byte) error {
+		return ws.writeFrame(opText, msg)
+	})
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	handling := make(chan struct{}, maxWebsocketRequests)
+	for {
+		select {
+		case handling <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		msg, err := ws.readMessage()
+		if err != nil {
+			if err == errMessageTooLarge {
+				ws.writeFrame(opClose, closePayload(1009)) // nolint: errcheck
+			}
+			return
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-handling }()
+			if out := s.Handle(ctx, msg); out != nil {
+				ws.writeFrame(opText, out) // nolint: errcheck
+			}
+		}()
+	}
+}
+
+// dialWebsocket opens a websocket to the JSON-RPC server at url, an http
+// or https url, sending header with the handshake.
+func dialWebsocket(ctx context.Context, url string, header http.Header) (*wsConn, error) {
+	var key [16]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", base64.StdEncoding.EncodeToString(key[:]))
+	req.Header.Set("Sec-WebSocket-Version", "13")
+
+	var conn net.Conn
+	var d net.Dialer
+	switch req.URL.Scheme {
+	case "http":
+		conn, err = d.DialContext(ctx, "tcp", hostPort(req.URL.Host, "80"))
+	case "https":
+		var raw net.Conn
+		raw, err = d.DialContext(ctx, "tcp", hostPort(req.URL.Host, "443"))
+		if err == nil {
+			conn = tls.Client(raw, &tls.Config{ServerName: req.URL.Hostname()})
+		}
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q", req.URL.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := req.Write(conn); err != nil {
+		conn.Close() // nolint: errcheck
+		return nil, err
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close() // nolint: errcheck
+		return nil, err
+	}
+	resp.Body.Close() // nolint: errcheck
+
+	accept := sha1.Sum([]byte(base64.StdEncoding.EncodeToString(key[:]) + websocketGUID)) // nolint: gosec
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		conn.Close() // nolint: errcheck
+		return nil, fmt.Errorf("websocket handshake failed: %s", resp.Status)
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != base64.StdEncoding.EncodeToString(accept[:]) {
+		conn.Close() // nolint: errcheck
+		return nil, errors.New("websocket handshake failed: invalid accept key")
+	}
+	return &wsConn{conn: conn, r: br, client: true}, nil
+}
+
+func hostPort(host, defaultPort string) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	return net.JoinHostPort(host, defaultPort)
+}
+
+func headerContains(h http.Header, name, token string) bool {
+	for _, v := range h[http.CanonicalHeaderKey(name)] {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func closePayload(code uint16) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, code)
+	return b
+}
+
+// wsConn is an end of a websocket. Frames sent by the client are masked,
+// frames sent by the server aren't.
+type wsConn struct {
+	conn   net.Conn
+	r      *bufio.Reader
+	client bool
+
+	wlk sync.Mutex
+}
+
+// readMessage returns the next text or binary message, reassembling
+// fragmented messages and answering control frames. It returns io.EOF once
+// the client closes the websocket.
+func (ws *wsConn) readMessage() ([]byte, error) {
+	var msg []byte
+	for {
+		fin, op, payload, err := ws.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case opClose:
+			ws.writeFrame(opClose, payload) // nolint: errcheck
+			return nil, io.EOF
+		case opPing:
+			if err := ws.writeFrame(opPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opText, opBinary, opContinuation:
+		default:
+			return nil, errors.New("unknown websocket opcode")
+		}
+		if len(msg)+len(payload) > maxRequestSize {
+			return nil, errMessageTooLarge
+		}
+		msg = append(msg, payload...)
+		if fin {
+			return msg, nil
+		}
+	}
+}
+
+func (ws *wsConn) readFrame() (fin bool, op byte, payload []byte, err error) {
+	var hdr [2]byte
+	if _, err = io.ReadFull(ws.r, hdr[:]); err != nil {
+		return
+	}
+	fin = hdr[0]&0x80 != 0
+	op = hdr[0] & 0x0f
+	masked := hdr[1]&0x80 != 0
+	if masked == ws.client {
+		err = errors.New("websocket frame masked by the wrong end")
+		return
+	}
+
+	n := uint64(hdr[1] & 0x7f)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(ws.r, ext[:]); err != nil {
+			return
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(ws.r, ext[:]); err != nil {
+			return
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if n > maxRequestSize {
+		err = errMessageTooLarge
+		return
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err = io.ReadFull(ws.r, mask[:]); err != nil {
+			return
+		}
+	}
+	payload = make([]byte, n)
+	if _, err = io.ReadFull(ws.r, payload); err != nil {
+		return
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return
+}
+
+func (ws *wsConn) writeFrame(op byte, payload []byte) error {
+	ws.wlk.Lock()
+	defer ws.wlk.Unlock()
+
+	hdr := []byte{0x80 | op}
+	switch n := len(payload); {
+	case n < 126:
+		hdr = append(hdr, byte(n))
+	case n <= 0xffff:
+		hdr = append(hdr, 126, 0, 0)
+		binary.BigEndian.PutUint16(hdr[2:], uint16(n))
+	default:
+		hdr = append(hdr, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(hdr[2:], uint64(n))
+	}
+	if ws.client {
+		var mask [4]byte
+		if _, err := rand.Read(mask[:]); err != nil {
+			return err
+		}
+		hdr[1] |= 0x80
+		hdr = append(hdr, mask[:]...)
+		masked := make([]byte, len(payload))
+		for i, b := range payload {
+			masked[i] = b ^ mask[i%4]
+		}
+		payload = masked
+	}
+	if _, err := ws.conn.Write(hdr); err != nil {
+		return err
+	}
+	_, err := ws.conn.Write(payload)
+	return err
+}
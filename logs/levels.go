@@ -0,0 +1,50 @@
+package logs
+
+import (
+	"sort"
+
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	oldlogging "gx/ipfs/QmcaSwFc5RBg8yCq54QURwEU4nwjfCpjbpmaAm4VbdGLKv/go-logging"
+	logging "gx/ipfs/QmcuXC5cxs79ro2cUuHs4HQ2bkDLJUYokwL8aivcX6HW3C/go-log"
+)
+
+// SetLevels sets the levels of the loggers of the subsystems of levels. The
+// level of "*" is set first, as it sets the level of every logger, so that
+// the other subsystems of levels override it.
+func SetLevels(levels map[string]string) error {
+	subsystems := make([]string, 0, len(levels))
+	for s := range levels {
+		if s != "*" {
+			subsystems = append(subsystems, s)
+		}
+	}
+	sort.Strings(subsystems)
+	if _, ok := levels["*"]; ok {
+		subsystems = append([]string{"*"}, subsystems...)
+	}
+
+	for _, s := range subsystems {
+		if err := logging.SetLogLevel(s, levels[s]); err != nil {
+			return errors.Wrapf(err, "failed to set the log level of %s", s)
+		}
+	}
+	return nil
+}
+
+// ValidateLevels returns the error SetLevels would fail with, without setting
+// any level: a level that doesn't exist or a subsystem without a logger.
+func ValidateLevels(levels map[string]string) error {
+	subsystems := make(map[string]bool)
+	for _, s := range logging.GetSubsystems() {
+		subsystems[s] = true
+	}
+	for s, level := range levels {
+		if _, err := oldlogging.LogLevel(level); err != nil {
+			return errors.Wrapf(err, "failed to set the log level of %s", s)
+		}
+		if s != "*" && !subsystems[s] {
+			return errors.Wrapf(logging.ErrNoSuchLogger, "failed to set the log level of %s", s)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+// Package logs streams the log records of the node, and the events of its
+// subsystems, as structured entries to the clients of the api.
+//
+// The records of the loggers reach the stream through Backend, which main
+// installs as a go-logging backend next to the one writing to stderr, so
+// the stream holds the records the node logs at the levels configured.
+package logs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	oldlogging "gx/ipfs/QmcaSwFc5RBg8yCq54QURwEU4nwjfCpjbpmaAm4VbdGLKv/go-logging"
+)
+
+// Levels are the levels of entries, from the most to the least severe.
+var Levels = []string{"critical", "error", "warning", "notice", "info", "debug"}
+
+// subscriberBuffer is the number of entries buffered for a subscriber. The
+// entries published while it is full are dropped rather than holding up
+// the logger.
+const subscriberBuffer = 256
+
+// Entry is a log record, or an event of a subsystem.
+type Entry struct {
+	Time      time.Time `json:"time"`
+	Subsystem string    `json:"subsystem"`
+	Level     string    `json:"level"`
+	Message   string    `json:"message"`
+	// Event is set for the events of subsystems, e.g. the new head of the
+	// chain or the change of stage of a sector.
+	Event interface{} `json:"event,omitempty"`
+}
+
+// Filter selects the entries streamed to a subscriber.
+type Filter struct {
+	// Subsystems are the subsystems, or the names of the loggers, whose
+	// entries are streamed, all if empty.
+	Subsystems []string `json:"subsystems,omitempty"`
+	// Level is the least severe level streamed, e.g. warning streams the
+	// critical, error and warning entries. All levels if empty.
+	Level string `json:"level,omitempty"`
+}
+
+// Validate checks the level of f exists.
+func (f Filter) Validate() error {
+	if f.Level != "" && levelRank(f.Level) < 0 {
+		return fmt.Errorf("unknown level %q, must be one of %s", f.Level, strings.Join(Levels, ", "))
+	}
+	return nil
+}
+
+// Match returns whether e passes f.
+func (f Filter) Match(e Entry) bool {
+	if f.Level != "" && levelRank(e.Level) > levelRank(f.Level) {
+		return false
+	}
+	if len(f.Subsystems) == 0 {
+		return true
+	}
+	for _, s := range f.Subsystems {
+		if s == e.Subsystem {
+			return true
+		}
+	}
+	return false
+}
+
+func levelRank(level string) int {
+	for i, l := range Levels {
+		if l == level {
+			return i
+		}
+	}
+	return -1
+}
+
+type subscriber struct {
+	filter Filter
+	ch     chan Entry
+}
+
+var (
+	lk          sync.Mutex
+	subscribers = map[*subscriber]struct{}{}
+	// count is the number of subscribers, read without the lock so that
+	// logging costs nothing while nobody streams.
+	count   int32
+	dropped uint64
+)
+
+// Subscribe returns a channel on which the entries passing filter are
+// delivered until ctx is done, when it is closed.
+func Subscribe(ctx context.Context, filter Filter) <-chan Entry {
+	sub := &subscriber{filter: filter, ch: make(chan Entry, subscriberBuffer)}
+
+	lk.Lock()
+	subscribers[sub] = struct{}{}
+	atomic.AddInt32(&count, 1)
+	lk.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		lk.Lock()
+		defer lk.Unlock()
+		delete(subscribers, sub)
+		atomic.AddInt32(&count, -1)
+		close(sub.ch)
+	}()
+	return sub.ch
+}
+
+// Publish delivers e to the subscribers whose filter it passes.
+func Publish(e Entry) {
+	if atomic.LoadInt32(&count) == 0 {
+		return
+	}
+	lk.Lock()
+	defer lk.Unlock()
+	for sub := range subscribers {
+		if !sub.filter.Match(e) {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+			atomic.AddUint64(&dropped, 1)
+		}
+	}
+}
+
+// Dropped returns the number of entries dropped because a subscriber
+// didn't keep up.
+func Dropped() uint64 {
+	return atomic.LoadUint64(&dropped)
+}
+
+type backend struct{}
+
+// Backend returns the go-logging backend publishing the log records.
+func Backend() oldlogging.Backend {
+	return backend{}
+}
+
+func (backend) Log(level oldlogging.Level, calldepth int, rec *oldlogging.Record) error {
+	// formatting the message is the costly part
+	if atomic.LoadInt32(&count) == 0 {
+		return nil
+	}
+	Publish(Entry{
+		Time:      rec.Time,
+		Subsystem: rec.Module,
+		Level:     strings.ToLower(level.String()),
+		Message:   rec.Message(),
+	})
+	return nil
+}
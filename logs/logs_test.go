@@ -0,0 +1,60 @@
+package logs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilter(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	e := Entry{Subsystem: "chain", Level: "warning"}
+	assert.True(Filter{}.Match(e))
+	assert.True(Filter{Subsystems: []string{"deals", "chain"}}.Match(e))
+	assert.False(Filter{Subsystems: []string{"deals"}}.Match(e))
+	assert.True(Filter{Level: "warning"}.Match(e))
+	assert.True(Filter{Level: "debug"}.Match(e))
+	assert.False(Filter{Level: "error"}.Match(e))
+
+	assert.NoError(Filter{Level: "notice"}.Validate())
+	assert.Error(Filter{Level: "loud"}.Validate())
+}
+
+func TestSubscribe(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errors := Subscribe(ctx, Filter{Level: "error"})
+	all := Subscribe(ctx, Filter{})
+
+	Publish(Entry{Subsystem: "chain", Level: "info", Message: "new head"})
+	Publish(Entry{Subsystem: "deals", Level: "error", Message: "deal failed"})
+
+	assert.Equal("new head", (<-all).Message)
+	assert.Equal("deal failed", (<-all).Message)
+	assert.Equal("deal failed", (<-errors).Message)
+
+	t.Run("drops entries of subscribers which don't keep up", func(t *testing.T) {
+		before := Dropped()
+		for i := 0; i < subscriberBuffer+1; i++ {
+			Publish(Entry{Subsystem: "chain", Level: "info"})
+		}
+		assert.Equal(before+1, Dropped())
+	})
+
+	cancel()
+	timeout := time.After(5 * time.Second)
+	for range all {
+		select {
+		case <-timeout:
+			t.Fatal("subscription not closed")
+		default:
+		}
+	}
+	_, ok := <-errors
+	assert.False(ok)
+}
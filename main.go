@@ -8,6 +8,7 @@ import (
 	logging "gx/ipfs/QmcuXC5cxs79ro2cUuHs4HQ2bkDLJUYokwL8aivcX6HW3C/go-log"
 
 	"github.com/filecoin-project/go-filecoin/commands"
+	"github.com/filecoin-project/go-filecoin/logs"
 	"github.com/filecoin-project/go-filecoin/metrics"
 )
 
@@ -22,6 +23,10 @@ func main() {
 		n = 4
 	}
 
+	// Log to stderr and to the clients streaming the logs. This resets the
+	// levels, so it must come before they are set.
+	oldlogging.SetBackend(oldlogging.NewLogBackend(os.Stderr, "", 0), logs.Backend())
+
 	if os.Getenv("GO_FILECOIN_LOG_JSON") == "1" {
 		oldlogging.SetFormatter(&metrics.JSONFormatter{})
 	}
@@ -0,0 +1,117 @@
+// Package migration transforms the state of the chain at protocol upgrade
+// heights, e.g. to reshape the storage of an actor whose code changes.
+package migration
+
+import (
+	"context"
+	"sort"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/state"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/vm"
+)
+
+// Migration transforms the state tree st, and the actor storage in vms, into
+// the state the protocol expects from an upgrade on.
+type Migration func(ctx context.Context, st state.Tree, vms vm.StorageMap) error
+
+// Upgrade is a protocol upgrade migrating the state when the chain reaches
+// its height.
+type Upgrade struct {
+	// Name identifies the upgrade, e.g. for dry runs.
+	Name string
+	// Height is the height of the first epoch processed by the new protocol.
+	// The state is migrated before that epoch's messages are applied.
+	Height    *types.BlockHeight
+	Migration Migration
+}
+
+// Schedule lists protocol upgrades ordered by height.
+type Schedule []Upgrade
+
+// Upgrades is the upgrade schedule of the protocol. Adding an upgrade here is
+// a consensus change: every node must run it at the same height.
+var Upgrades = Schedule{}
+
+// After returns, in order, the upgrades with a height greater than parent and
+// no greater than bh. Migrating the state for all of them when processing an
+// epoch at bh whose parent is at parent makes sure upgrades at the height of
+// null blocks aren't skipped.
+func (s Schedule) After(parent, bh *types.BlockHeight) []Upgrade {
+	var upgrades []Upgrade
+	for _, u := range s {
+		if parent.LessThan(u.Height) && u.Height.LessEqual(bh) {
+			upgrades = append(upgrades, u)
+		}
+	}
+	return upgrades
+}
+
+// Find returns the upgrade named name.
+func (s Schedule) Find(name string) (Upgrade, bool) {
+	for _, u := range s {
+		if u.Name == name {
+			return u, true
+		}
+	}
+	return Upgrade{}, false
+}
+
+// Apply migrates st and vms for each of upgrades in order, flushing st in
+// between so each migration sees the state left by the previous one.
+func Apply(ctx context.Context, st state.Tree, vms vm.StorageMap, upgrades []Upgrade) error {
+	for _, u := range upgrades {
+		if err := u.Migration(ctx, st, vms); err != nil {
+			return errors.Wrapf(err, "failed to migrate state for upgrade %s", u.Name)
+		}
+		if _, err := st.Flush(ctx); err != nil {
+			return errors.Wrapf(err, "failed to flush state migrated for upgrade %s", u.Name)
+		}
+	}
+	return nil
+}
+
+// ActorMigration transforms the actor act at addr. It may change the actor,
+// e.g. its code or balance, and reshape its storage through storage, which
+// updates the actor's head on commit.
+type ActorMigration func(ctx context.Context, addr address.Address, act *actor.Actor, storage exec.Storage) error
+
+// MigrateActors returns a migration running f for every actor with the given
+// code, in address order.
+func MigrateActors(code cid.Cid, f ActorMigration) Migration {
+	return func(ctx context.Context, st state.Tree, vms vm.StorageMap) error {
+		var addrs []address.Address
+		err := st.ForEachActor(ctx, func(addr address.Address, act *actor.Actor) error {
+			if act.Code.Equals(code) {
+				addrs = append(addrs, addr)
+			}
+			return nil
+		})
+		if err != nil {
+			return errors.Wrap(err, "failed to walk state tree")
+		}
+		sort.Slice(addrs, func(i, j int) bool {
+			return addrs[i].String() < addrs[j].String()
+		})
+
+		for _, addr := range addrs {
+			act, err := st.GetActor(ctx, addr)
+			if err != nil {
+				return errors.Wrapf(err, "failed to get actor %s", addr)
+			}
+			if err := f(ctx, addr, act, vms.NewStorage(addr, act)); err != nil {
+				return errors.Wrapf(err, "failed to migrate actor %s", addr)
+			}
+			if err := st.SetActor(ctx, addr, act); err != nil {
+				return errors.Wrapf(err, "failed to set actor %s", addr)
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,112 @@
+package migration_test
+
+import (
+	"context"
+	"testing"
+
+	"gx/ipfs/QmRXf2uUSdGSunRJsM9wXSUNVwLUGCY3So5fAs7h2CBJVf/go-hamt-ipld"
+
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/exec"
+	. "github.com/filecoin-project/go-filecoin/migration"
+	"github.com/filecoin-project/go-filecoin/state"
+	th "github.com/filecoin-project/go-filecoin/testhelpers"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/vm"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduleAfter(t *testing.T) {
+	assert := assert.New(t)
+
+	schedule := Schedule{
+		{Name: "first", Height: types.NewBlockHeight(10)},
+		{Name: "second", Height: types.NewBlockHeight(20)},
+	}
+	names := func(upgrades []Upgrade) []string {
+		var names []string
+		for _, u := range upgrades {
+			names = append(names, u.Name)
+		}
+		return names
+	}
+
+	assert.Empty(schedule.After(types.NewBlockHeight(8), types.NewBlockHeight(9)))
+	assert.Equal([]string{"first"}, names(schedule.After(types.NewBlockHeight(9), types.NewBlockHeight(10))))
+	assert.Empty(schedule.After(types.NewBlockHeight(10), types.NewBlockHeight(11)))
+
+	// upgrades at the height of null blocks run with the next epoch
+	assert.Equal([]string{"first", "second"}, names(schedule.After(types.NewBlockHeight(5), types.NewBlockHeight(25))))
+
+	u, ok := schedule.Find("second")
+	assert.True(ok)
+	assert.Equal(types.NewBlockHeight(20), u.Height)
+	_, ok = schedule.Find("third")
+	assert.False(ok)
+}
+
+func TestMigrateActors(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+
+	vms := th.VMStorage()
+	fakeCode := types.NewCidForTestGetter()()
+	addrGetter := address.NewForTestGetter()
+	fake1, fake2, acct := addrGetter(), addrGetter(), addrGetter()
+	_, st := th.RequireMakeStateTree(require, hamt.NewCborStore(), map[address.Address]*actor.Actor{
+		fake1: th.RequireNewFakeActorWithTokens(require, vms, fake1, fakeCode, types.NewAttoFILFromFIL(1)),
+		fake2: th.RequireNewFakeActorWithTokens(require, vms, fake2, fakeCode, types.NewAttoFILFromFIL(2)),
+		acct:  th.RequireNewAccountActor(require, types.NewAttoFILFromFIL(3)),
+	})
+
+	// reshape the state of fake actors and double their balance
+	var migrated []address.Address
+	upgrade := Upgrade{
+		Name:   "reshape",
+		Height: types.NewBlockHeight(1),
+		Migration: MigrateActors(fakeCode, func(ctx context.Context, addr address.Address, act *actor.Actor, storage exec.Storage) error {
+			migrated = append(migrated, addr)
+			act.Balance = act.Balance.Add(act.Balance)
+			id, err := storage.Put(&actor.FakeActorStorage{Changed: true})
+			if err != nil {
+				return err
+			}
+			return storage.Commit(id, act.Head)
+		}),
+	}
+	require.NoError(Apply(ctx, st, vms, []Upgrade{upgrade}))
+
+	assert.ElementsMatch([]address.Address{fake1, fake2}, migrated)
+	assert.Equal(types.NewAttoFILFromFIL(2), state.MustGetActor(st, fake1).Balance)
+	assert.Equal(types.NewAttoFILFromFIL(4), state.MustGetActor(st, fake2).Balance)
+	assert.Equal(types.NewAttoFILFromFIL(3), state.MustGetActor(st, acct).Balance)
+
+	fakeAct := state.MustGetActor(st, fake1)
+	chunk, err := vms.NewStorage(fake1, fakeAct).Get(fakeAct.Head)
+	require.NoError(err)
+	var fakeState actor.FakeActorStorage
+	require.NoError(actor.UnmarshalStorage(chunk, &fakeState))
+	assert.True(fakeState.Changed)
+}
+
+func TestApplyFailure(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	_, st := th.RequireMakeStateTree(require, hamt.NewCborStore(), map[address.Address]*actor.Actor{})
+	broken := Upgrade{
+		Name:   "broken",
+		Height: types.NewBlockHeight(1),
+		Migration: func(context.Context, state.Tree, vm.StorageMap) error {
+			return exec.Errors[exec.ErrStaleHead]
+		},
+	}
+
+	err := Apply(ctx, st, th.VMStorage(), []Upgrade{broken})
+	require.Error(err)
+	require.Contains(err.Error(), "upgrade broken")
+}
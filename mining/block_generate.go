@@ -60,11 +60,19 @@ func (w *DefaultWorker) Generate(ctx context.Context,
 	copy(messages, core.OrderMessagesByNonce(pending))
 
 	vms := vm.NewStorageMap(w.blockstore)
+	if err := w.processor.MigrateState(ctx, stateTree, vms, types.NewBlockHeight(blockHeight), ancestors); err != nil {
+		return nil, errors.Wrap(err, "generate migrate state")
+	}
+
 	res, err := w.processor.ApplyMessagesAndPayRewards(ctx, stateTree, vms, messages, w.minerAddr, types.NewBlockHeight(blockHeight), ancestors)
 	if err != nil {
 		return nil, errors.Wrap(err, "generate apply messages")
 	}
 
+	if err := w.processor.RunCron(ctx, stateTree, vms, types.NewBlockHeight(blockHeight)); err != nil {
+		return nil, errors.Wrap(err, "generate run cron")
+	}
+
 	newStateTreeCid, err := stateTree.Flush(ctx)
 	if err != nil {
 		return nil, errors.Wrap(err, "generate flush state tree")
@@ -90,6 +98,9 @@ func (w *DefaultWorker) Generate(ctx context.Context,
 		StateRoot:       newStateTreeCid,
 		Ticket:          ticket,
 	}
+	if err := next.AggregateBLSSignatures(); err != nil {
+		return nil, errors.Wrap(err, "generate aggregate BLS signatures")
+	}
 
 	// TODO: Should we really be pruning the message pool here at all? Maybe this should happen elsewhere.
 	for i, msg := range res.PermanentFailures {
@@ -56,13 +56,16 @@ import (
 type Scheduler interface {
 	Start(miningCtx context.Context) (<-chan Output, *sync.WaitGroup)
 	IsStarted() bool
+	// SetMineDelay changes the mining delay from the next round on.
+	SetMineDelay(md time.Duration)
 }
 
 type timingScheduler struct {
 	// worker contains the actual mining logic.
 	worker Worker
 	// mineDelay is the time the scheduler blocks for collection.
-	mineDelay time.Duration
+	mineDelayLk sync.Mutex
+	mineDelay   time.Duration
 	// pollHeadFunc is the function the scheduler uses to poll for the
 	// current heaviest tipset
 	pollHeadFunc func() types.TipSet
@@ -107,7 +110,7 @@ func (s *timingScheduler) Start(miningCtx context.Context) (<-chan Output, *sync
 			default:
 			}
 			// This is the sleep during which we collect. TODO: maybe this should vary?
-			time.Sleep(s.mineDelay)
+			time.Sleep(s.getMineDelay())
 			// Ask for the heaviest tipset.
 			base := s.pollHeadFunc()
 			if base == nil { // Don't try to mine on an unset head.
@@ -147,6 +150,20 @@ func (s *timingScheduler) IsStarted() bool {
 	return s.isStarted
 }
 
+// SetMineDelay changes the time the scheduler blocks for collection, from
+// the next round on.
+func (s *timingScheduler) SetMineDelay(md time.Duration) {
+	s.mineDelayLk.Lock()
+	defer s.mineDelayLk.Unlock()
+	s.mineDelay = md
+}
+
+func (s *timingScheduler) getMineDelay() time.Duration {
+	s.mineDelayLk.Lock()
+	defer s.mineDelayLk.Unlock()
+	return s.mineDelay
+}
+
 // nextNullBlkCount determines how many null blocks should be mined on top of
 // the current base tipset, currBase, given the previous base, prevBase and the
 // previous number of null blocks mined on the previous base, prevNullBlkCount.
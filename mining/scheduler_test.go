@@ -180,6 +180,29 @@ func TestSchedulerCollect(t *testing.T) {
 	cancel()
 }
 
+func TestSchedulerSetMineDelay(t *testing.T) {
+	assert, _, ts := newTestUtils(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mine := func(c context.Context, inTS types.TipSet, nBC int, outCh chan<- Output) bool {
+		outCh <- Output{}
+		return false
+	}
+	headFunc := func() types.TipSet {
+		return ts
+	}
+	scheduler := NewScheduler(NewTestWorkerWithDeps(mine), time.Hour, headFunc)
+	scheduler.SetMineDelay(MineDelayTest)
+	outCh, _ := scheduler.Start(ctx)
+
+	select {
+	case <-outCh:
+	case <-time.After(10 * MineDelayTest):
+		assert.Fail("the scheduler didn't mine after the new mining delay")
+	}
+}
+
 // This test is no longer meaningful without mocking ticket generation winning.
 // We need some way to make sure that the block being mined is still the block
 // received during collect.  TODO: isWinningTicket faking and reimplementing
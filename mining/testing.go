@@ -38,6 +38,11 @@ func (s *MockScheduler) IsStarted() bool {
 	return s.isStarted
 }
 
+// SetMineDelay is the MockScheduler's SetMineDelay function.
+func (s *MockScheduler) SetMineDelay(md time.Duration) {
+	s.Called(md)
+}
+
 // TestWorker is a worker with a customizable work function to facilitate
 // easy testing.
 type TestWorker struct {
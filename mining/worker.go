@@ -63,6 +63,10 @@ type GetAncestors func(context.Context, types.TipSet, *types.BlockHeight) ([]typ
 type MessageApplier interface {
 	// ApplyMessagesAndPayRewards applies all state transitions related to a set of messages.
 	ApplyMessagesAndPayRewards(ctx context.Context, st state.Tree, vms vm.StorageMap, messages []*types.SignedMessage, minerAddr address.Address, bh *types.BlockHeight, ancestors []types.TipSet) (consensus.ApplyMessagesResponse, error)
+	// MigrateState applies the protocol upgrades taking effect at an epoch.
+	MigrateState(ctx context.Context, st state.Tree, vms vm.StorageMap, bh *types.BlockHeight, ancestors []types.TipSet) error
+	// RunCron calls back the actors scheduled to run at the end of an epoch.
+	RunCron(ctx context.Context, st state.Tree, vms vm.StorageMap, bh *types.BlockHeight) error
 }
 
 // DefaultWorker runs a mining job.
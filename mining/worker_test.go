@@ -13,6 +13,7 @@ import (
 	"github.com/filecoin-project/go-filecoin/state"
 	th "github.com/filecoin-project/go-filecoin/testhelpers"
 	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/vm"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -98,22 +99,22 @@ func sharedSetupInitial() (*hamt.CborIpldStore, *core.MessagePool, cid.Cid) {
 func sharedSetup(t *testing.T) (state.Tree, *core.MessagePool, []address.Address, *hamt.CborIpldStore, blockstore.Blockstore) {
 	require := require.New(t)
 	cst, pool, fakeActorCodeCid := sharedSetupInitial()
-	vms := th.VMStorage()
 	d := datastore.NewMapDatastore()
 	bs := blockstore.NewBlockstore(d)
+	// The worker loads actor storage from bs when generating blocks.
+	vms := vm.NewStorageMap(bs)
 
 	// TODO: We don't need fake actors here, so these could be made real.
-	//       And the NetworkAddress actor can/should be the real one.
 	// Stick two fake actors in the state tree so they can talk.
 	addr1, addr2, addr3, addr4, addr5 := mockSigner.Addresses[0], mockSigner.Addresses[1], mockSigner.Addresses[2], mockSigner.Addresses[3], mockSigner.Addresses[4]
 	act1 := th.RequireNewFakeActor(require, vms, addr1, fakeActorCodeCid)
 	act2 := th.RequireNewFakeActor(require, vms, addr2, fakeActorCodeCid)
-	fakeNetAct := th.RequireNewFakeActorWithTokens(require, vms, addr3, fakeActorCodeCid, types.NewAttoFILFromFIL(1000000))
+	rewardAct := th.RequireNewRewardActor(require, vms, types.NewAttoFILFromFIL(1000000), types.ZeroAttoFIL)
 	minerAct := th.RequireNewMinerActor(require, vms, addr4, addr5, []byte{}, 10, th.RequireRandomPeerID(), types.NewAttoFILFromFIL(10000))
 	minerOwner := th.RequireNewFakeActor(require, vms, addr5, fakeActorCodeCid)
 	_, st := th.RequireMakeStateTree(require, cst, map[address.Address]*actor.Actor{
-		// Ensure core.NetworkAddress exists to prevent mining reward failures.
-		address.NetworkAddress: fakeNetAct,
+		// Ensure the reward actor exists to prevent mining reward failures.
+		address.RewardAddress: rewardAct,
 
 		addr1: act1,
 		addr2: act2,
@@ -135,8 +136,8 @@ func TestApplyMessagesForSuccessTempAndPermFailures(t *testing.T) {
 	addr1, addr2 := mockSigner.Addresses[0], mockSigner.Addresses[1]
 	act1 := th.RequireNewFakeActor(require, vms, addr1, fakeActorCodeCid)
 	_, st := th.RequireMakeStateTree(require, cst, map[address.Address]*actor.Actor{
-		address.NetworkAddress: th.RequireNewAccountActor(require, types.NewAttoFILFromFIL(1000000)),
-		addr1:                  act1,
+		address.RewardAddress: th.RequireNewRewardActor(require, vms, types.NewAttoFILFromFIL(1000000), types.ZeroAttoFIL),
+		addr1:                 act1,
 	})
 
 	ctx := context.Background()
@@ -0,0 +1,112 @@
+// Package networks defines the filecoin networks a node can be initialized
+// for with `go-filecoin init --network <name>`.
+package networks
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/filecoin-project/go-filecoin/config"
+	"github.com/filecoin-project/go-filecoin/mining"
+)
+
+// Local is the name of the network of nodes started in dev mode or in tests,
+// which repos are initialized for by default.
+const Local = "local"
+
+// Network defines the genesis block, the bootstrap peers, the block time and
+// the proofs mode of a network.
+type Network struct {
+	// Name is the name of the network, which the nodes of a network
+	// exchange in the hello protocol.
+	Name string
+	// GenesisFile is the path or HTTP(S) URL of the car file of the genesis
+	// block of the network, empty for the genesis block of consensus.InitGenesis.
+	GenesisFile string
+	// BootstrapAddrs are the dns multiaddrs of the bootstrap peers.
+	BootstrapAddrs []string
+	BlockTime      time.Duration
+	// InsecureProofs makes the nodes of the network fake seal and PoSt
+	// proofs, see proofs.UseInsecureProofs.
+	InsecureProofs bool
+}
+
+var networks = map[string]*Network{
+	Local: {
+		Name:      Local,
+		BlockTime: mining.DefaultBlockTime,
+	},
+	// the devnet users join
+	"devnet": {
+		Name:        "devnet",
+		GenesisFile: "http://user.kittyhawk.wtf:8020/genesis.car",
+		BootstrapAddrs: []string{
+			"/dns4/user.kittyhawk.wtf/tcp/9000/ipfs/Qmd6xrWYHsxivfakYRy6MszTpuAiEoFbgE1LWw4EvwBpp4",
+			"/dns4/user.kittyhawk.wtf/tcp/9001/ipfs/QmXq6XEYeEmUzBFuuKbVEGgxEpVD4xbSkG2Rhek6zkFMp4",
+			"/dns4/user.kittyhawk.wtf/tcp/9002/ipfs/QmXhxqTKzBKHA5FcMuiKZv8YaMPwpbKGXHRVZcFB2DX9XY",
+			"/dns4/user.kittyhawk.wtf/tcp/9003/ipfs/QmZGDLdQLUTi7uYTNavKwCd7SBc5KMfxzWxAyvqRQvwuiV",
+			"/dns4/user.kittyhawk.wtf/tcp/9004/ipfs/QmZRnwmCjyNHgeNDiyT8mXRtGhP6uSzgHtrozc42crmVbg",
+		},
+		BlockTime: mining.DefaultBlockTime,
+	},
+	// the devnet releases are tested on before the devnet is upgraded
+	"staging": {
+		Name:        "staging",
+		GenesisFile: "http://test.kittyhawk.wtf:8020/genesis.car",
+		BootstrapAddrs: []string{
+			"/dns4/test.kittyhawk.wtf/tcp/9000/ipfs/Qmd6xrWYHsxivfakYRy6MszTpuAiEoFbgE1LWw4EvwBpp4",
+			"/dns4/test.kittyhawk.wtf/tcp/9001/ipfs/QmXq6XEYeEmUzBFuuKbVEGgxEpVD4xbSkG2Rhek6zkFMp4",
+			"/dns4/test.kittyhawk.wtf/tcp/9002/ipfs/QmXhxqTKzBKHA5FcMuiKZv8YaMPwpbKGXHRVZcFB2DX9XY",
+			"/dns4/test.kittyhawk.wtf/tcp/9003/ipfs/QmZGDLdQLUTi7uYTNavKwCd7SBc5KMfxzWxAyvqRQvwuiV",
+			"/dns4/test.kittyhawk.wtf/tcp/9004/ipfs/QmZRnwmCjyNHgeNDiyT8mXRtGhP6uSzgHtrozc42crmVbg",
+		},
+		BlockTime: mining.DefaultBlockTime,
+	},
+	// the devnet redeployed from master every night
+	"nightly": {
+		Name:        "nightly",
+		GenesisFile: "http://nightly.kittyhawk.wtf:8020/genesis.car",
+		BootstrapAddrs: []string{
+			"/dns4/nightly.kittyhawk.wtf/tcp/9000/ipfs/Qmd6xrWYHsxivfakYRy6MszTpuAiEoFbgE1LWw4EvwBpp4",
+			"/dns4/nightly.kittyhawk.wtf/tcp/9001/ipfs/QmXq6XEYeEmUzBFuuKbVEGgxEpVD4xbSkG2Rhek6zkFMp4",
+			"/dns4/nightly.kittyhawk.wtf/tcp/9002/ipfs/QmXhxqTKzBKHA5FcMuiKZv8YaMPwpbKGXHRVZcFB2DX9XY",
+			"/dns4/nightly.kittyhawk.wtf/tcp/9003/ipfs/QmZGDLdQLUTi7uYTNavKwCd7SBc5KMfxzWxAyvqRQvwuiV",
+			"/dns4/nightly.kittyhawk.wtf/tcp/9004/ipfs/QmZRnwmCjyNHgeNDiyT8mXRtGhP6uSzgHtrozc42crmVbg",
+		},
+		BlockTime: mining.DefaultBlockTime,
+	},
+}
+
+// Get returns the network with the given name.
+func Get(name string) (*Network, error) {
+	n, ok := networks[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown network %q, the networks are %s", name, strings.Join(Names(), ", "))
+	}
+	return n, nil
+}
+
+// Names returns the names of the networks, sorted.
+func Names() []string {
+	var names []string
+	for name := range networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Configure sets the settings of cfg the network defines.
+func (n *Network) Configure(cfg *config.Config) {
+	cfg.Network.Name = n.Name
+	cfg.Network.BlockTime = n.BlockTime.String()
+	cfg.Proofs.Insecure = n.InsecureProofs
+
+	if len(n.BootstrapAddrs) > 0 {
+		cfg.Bootstrap.Addresses = append([]string{}, n.BootstrapAddrs...)
+		cfg.Bootstrap.MinPeerThreshold = 1
+		cfg.Bootstrap.Period = "10s"
+	}
+}
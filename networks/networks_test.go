@@ -0,0 +1,62 @@
+package networks
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-filecoin/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGet(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range []string{"devnet", "staging", "nightly", "local"} {
+		n, err := Get(name)
+		require.NoError(t, err)
+		assert.Equal(t, name, n.Name)
+	}
+
+	_, err := Get("mainnet")
+	assert.EqualError(t, err, `unknown network "mainnet", the networks are devnet, local, nightly, staging`)
+}
+
+func TestConfigure(t *testing.T) {
+	t.Parallel()
+
+	t.Run("the local network keeps the default config", func(t *testing.T) {
+		n, err := Get(Local)
+		require.NoError(t, err)
+
+		cfg := config.NewDefaultConfig()
+		n.Configure(cfg)
+		assert.Equal(t, config.NewDefaultConfig(), cfg)
+	})
+
+	t.Run("devnets bootstrap to their peers", func(t *testing.T) {
+		assert := assert.New(t)
+
+		n, err := Get("nightly")
+		require.NoError(t, err)
+
+		cfg := config.NewDefaultConfig()
+		n.Configure(cfg)
+		assert.Equal("nightly", cfg.Network.Name)
+		assert.Equal("30s", cfg.Network.BlockTime)
+		assert.Equal(n.BootstrapAddrs, cfg.Bootstrap.Addresses)
+		assert.Equal(1, cfg.Bootstrap.MinPeerThreshold)
+		assert.False(cfg.Proofs.Insecure)
+	})
+
+	t.Run("the configs of all networks are valid", func(t *testing.T) {
+		for _, name := range Names() {
+			n, err := Get(name)
+			require.NoError(t, err)
+
+			cfg := config.NewDefaultConfig()
+			n.Configure(cfg)
+			assert.NoError(t, cfg.Validate(), name)
+		}
+	})
+}
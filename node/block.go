@@ -7,6 +7,9 @@ import (
 	"gx/ipfs/QmVRxA4J3UPQpw74dLrQ6NJkfysCA1H4GU28gVpXQt9zMU/go-libp2p-pubsub"
 	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
 
+	"github.com/filecoin-project/go-filecoin/chain"
+	"github.com/filecoin-project/go-filecoin/consensus"
+	"github.com/filecoin-project/go-filecoin/filnet"
 	"github.com/filecoin-project/go-filecoin/types"
 )
 
@@ -15,6 +18,9 @@ const BlockTopic = "/fil/blocks"
 
 // AddNewBlock receives a newly mined block and stores, validates and propagates it to the network.
 func (node *Node) AddNewBlock(ctx context.Context, b *types.Block) (err error) {
+	release := node.HoldGC()
+	defer release()
+
 	// Put block in storage wired to an exchange so this node and other
 	// nodes can fetch it.
 	log.Debugf("putting block in bitswap exchange: %s", b.Cid().String())
@@ -33,24 +39,88 @@ func (node *Node) AddNewBlock(ctx context.Context, b *types.Block) (err error) {
 	return node.PubSub.Publish(BlockTopic, b.ToNode().RawData())
 }
 
+// validateBlockTopic is the pubsub validator of BlockTopic. It runs before
+// blocks are forwarded, so that blocks which can't be decoded, are malformed
+// or come from banned peers aren't propagated, and drops the copies of
+// blocks seen recently. Blocks are scored against the peer that forwarded
+// them, not their unsigned and so spoofable author.
+func (node *Node) validateBlockTopic(ctx context.Context, pubSubMsg *pubsub.Message) bool {
+	from := pubSubMsg.ReceivedFrom
+	if from == node.Host().ID() {
+		return true
+	}
+	if node.PeerScores.Banned(from) {
+		return false
+	}
+
+	blk, err := types.DecodeBlock(pubSubMsg.GetData())
+	if err == nil && node.SeenCache.Seen(BlockTopic, blk.Cid().String()) {
+		return false
+	}
+	if err == nil {
+		err = validateBlockSyntax(blk)
+	}
+	if err != nil {
+		log.Debugf("rejecting block from %s: %s", from, err)
+		node.PeerScores.Penalize(from, filnet.InvalidBlock)
+		return false
+	}
+	return true
+}
+
+// validateBlockSyntax checks the fields of a block received from the
+// network are filled out, without looking at the chain it extends.
+func validateBlockSyntax(blk *types.Block) error {
+	switch {
+	case blk.Miner.Empty():
+		return errors.New("block has no miner")
+	case len(blk.Ticket) == 0:
+		return errors.New("block has no ticket")
+	case blk.Parents.Empty():
+		return errors.New("block has no parents")
+	case blk.Height == 0:
+		return errors.New("block has height 0")
+	case !blk.StateRoot.Defined():
+		return errors.New("block has nil StateRoot")
+	}
+	return nil
+}
+
 func (node *Node) processBlock(ctx context.Context, pubSubMsg *pubsub.Message) (err error) {
-	// ignore messages from ourself
-	if pubSubMsg.GetFrom() == node.Host().ID() {
+	// ignore blocks we published, and blocks forwarded by banned peers
+	from := pubSubMsg.ReceivedFrom
+	if from == node.Host().ID() || node.PeerScores.Banned(from) {
 		return nil
 	}
 
 	blk, err := types.DecodeBlock(pubSubMsg.GetData())
 	if err != nil {
+		node.PeerScores.Penalize(from, filnet.InvalidBlock)
 		return errors.Wrap(err, "got bad block data")
 	}
 
 	log.Infof("Received new block from network cid: %s", blk.Cid().String())
 	log.Debugf("Received new block from network: %s", blk)
+	node.noteAnnouncedHeight(uint64(blk.Height))
 
 	err = node.Syncer.HandleNewBlocks(ctx, []cid.Cid{blk.Cid()})
 	if err != nil {
+		if isInvalidChainErr(err) {
+			node.PeerScores.Penalize(from, filnet.InvalidBlock)
+		}
 		return errors.Wrap(err, "processing block from network")
 	}
 
 	return nil
 }
+
+// isInvalidChainErr returns whether err, returned by the syncer, means the
+// blocks synced are invalid, rather than e.g. that fetching them failed.
+func isInvalidChainErr(err error) bool {
+	switch errors.Cause(err) {
+	case chain.ErrChainHasBadTipSet, chain.ErrNewChainTooLong, consensus.ErrStateRootMismatch, consensus.ErrInvalidBase:
+		return true
+	default:
+		return false
+	}
+}
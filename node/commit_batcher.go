@@ -0,0 +1,78 @@
+package node
+
+import (
+	"sync"
+	"time"
+
+	"github.com/filecoin-project/go-filecoin/proofs/sectorbuilder"
+)
+
+// commitBatcher collects sealed sectors to commit them in batches, cutting
+// the messages a miner sends when it seals many sectors. A batch is committed
+// once it holds size sectors, or wait after its first sector was added.
+type commitBatcher struct {
+	size   int
+	wait   time.Duration
+	commit func([]*sectorbuilder.SealedSectorMetadata)
+
+	lk      sync.Mutex
+	pending []*sectorbuilder.SealedSectorMetadata
+	timer   *time.Timer
+}
+
+func newCommitBatcher(size int, wait time.Duration, commit func([]*sectorbuilder.SealedSectorMetadata)) *commitBatcher {
+	if size < 1 {
+		size = 1
+	}
+	return &commitBatcher{
+		size:   size,
+		wait:   wait,
+		commit: commit,
+	}
+}
+
+// add adds a sealed sector to the current batch.
+func (b *commitBatcher) add(sector *sectorbuilder.SealedSectorMetadata) {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	b.pending = append(b.pending, sector)
+	if len(b.pending) >= b.size {
+		b.flushLocked()
+		return
+	}
+	if b.timer == nil {
+		b.timer = time.AfterFunc(b.wait, b.flush)
+	}
+}
+
+// flush commits the current batch, if any.
+func (b *commitBatcher) flush() {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+	b.flushLocked()
+}
+
+func (b *commitBatcher) flushLocked() {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	if len(b.pending) == 0 {
+		return
+	}
+	batch := b.pending
+	b.pending = nil
+	go b.commit(batch)
+}
+
+// stop drops the current batch without committing it.
+func (b *commitBatcher) stop() {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	b.pending = nil
+}
@@ -0,0 +1,70 @@
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/go-filecoin/proofs/sectorbuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommitBatcher(t *testing.T) {
+	t.Parallel()
+
+	sector := func(id uint64) *sectorbuilder.SealedSectorMetadata {
+		return &sectorbuilder.SealedSectorMetadata{SectorID: id}
+	}
+	ids := func(sectors []*sectorbuilder.SealedSectorMetadata) []uint64 {
+		var ids []uint64
+		for _, s := range sectors {
+			ids = append(ids, s.SectorID)
+		}
+		return ids
+	}
+
+	t.Run("commits full batches", func(t *testing.T) {
+		batches := make(chan []*sectorbuilder.SealedSectorMetadata, 2)
+		b := newCommitBatcher(2, time.Hour, func(sectors []*sectorbuilder.SealedSectorMetadata) {
+			batches <- sectors
+		})
+
+		b.add(sector(1))
+		b.add(sector(2))
+		b.add(sector(3))
+		b.add(sector(4))
+		// batches are committed concurrently
+		assert.ElementsMatch(t, [][]uint64{{1, 2}, {3, 4}}, [][]uint64{ids(<-batches), ids(<-batches)})
+	})
+
+	t.Run("commits partial batches after the wait", func(t *testing.T) {
+		batches := make(chan []*sectorbuilder.SealedSectorMetadata, 1)
+		b := newCommitBatcher(10, 10*time.Millisecond, func(sectors []*sectorbuilder.SealedSectorMetadata) {
+			batches <- sectors
+		})
+
+		b.add(sector(1))
+		b.add(sector(2))
+		select {
+		case batch := <-batches:
+			assert.Equal(t, []uint64{1, 2}, ids(batch))
+		case <-time.After(time.Second):
+			require.Fail(t, "partial batch not committed")
+		}
+	})
+
+	t.Run("drops the batch when stopped", func(t *testing.T) {
+		batches := make(chan []*sectorbuilder.SealedSectorMetadata, 1)
+		b := newCommitBatcher(10, 10*time.Millisecond, func(sectors []*sectorbuilder.SealedSectorMetadata) {
+			batches <- sectors
+		})
+
+		b.add(sector(1))
+		b.stop()
+		select {
+		case <-batches:
+			require.Fail(t, "stopped batcher committed a batch")
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+}
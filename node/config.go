@@ -1,10 +1,16 @@
 package node
 
 import (
+	"bytes"
+
+	ma "gx/ipfs/QmNTCey11oxhb1AxDnQBRHtdhap6Ctud872NjAYPYYXPuc/go-multiaddr"
+	libp2pquic "gx/ipfs/QmNfTyj6KEpmpX2s9zcbb7xQpUbTNv6yJE2h4sDNuHR2vV/go-libp2p-quic-transport"
 	ci "gx/ipfs/QmNiJiXwWE3kRhZrC5ej3kSjWHm337pYfhjLGSCDNKJP2s/go-libp2p-crypto"
 	errors "gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	pnet "gx/ipfs/QmY4Q5JC4vxLEi8EpVxJM4rcRryEVtH1zRKVTAm6BKV1pg/go-libp2p-pnet"
 	libp2p "gx/ipfs/QmYxivS34F2M2n44WQQnRHGAKS8aoRUxwGpi9wk4Cdn4Jf/go-libp2p"
 
+	"github.com/filecoin-project/go-filecoin/config"
 	"github.com/filecoin-project/go-filecoin/repo"
 )
 
@@ -17,12 +23,43 @@ func OptionsFromRepo(r repo.Repo) ([]ConfigOpt, error) {
 	}
 
 	cfg := r.Config()
+	listenAddrs := append([]string{cfg.Swarm.Address}, cfg.Swarm.ListenAddresses...)
+	libp2pOpts := []libp2p.Option{
+		// the default transports of libp2p are tcp and websockets
+		libp2p.ListenAddrStrings(listenAddrs...),
+		libp2p.Identity(sk),
+	}
+
+	quic, err := listensOnQUIC(listenAddrs)
+	if err != nil {
+		return nil, err
+	}
+	if quic {
+		// libp2p only uses its default transports if none are given
+		libp2pOpts = append(libp2pOpts, libp2p.DefaultTransports, libp2p.Transport(libp2pquic.NewTransport))
+	}
+
+	swarmKey, err := r.SwarmKey()
+	if err != nil {
+		return nil, err
+	}
+	if swarmKey != nil {
+		// QUIC connections are encrypted by the transport, they can't be
+		// protected with the swarm key
+		if quic {
+			return nil, errors.New("private networks can't listen on QUIC addresses")
+		}
+		protector, err := pnet.NewProtector(bytes.NewReader(swarmKey))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to decode swarm key")
+		}
+		log.Infof("joining private network with swarm key fingerprint %x", protector.Fingerprint())
+		libp2pOpts = append(libp2pOpts, libp2p.PrivateNetwork(protector))
+	}
+
 	cfgopts := []ConfigOpt{
 		// Libp2pOptions can only be called once, so add all options here.
-		Libp2pOptions(
-			libp2p.ListenAddrStrings(cfg.Swarm.Address),
-			libp2p.Identity(sk),
-		),
+		Libp2pOptions(libp2pOpts...),
 	}
 
 	dsopt := func(c *Config) error {
@@ -33,6 +70,21 @@ func OptionsFromRepo(r repo.Repo) ([]ConfigOpt, error) {
 	return append(cfgopts, dsopt), nil
 }
 
+// listensOnQUIC returns whether any of the listen addresses is a QUIC
+// address.
+func listensOnQUIC(listenAddrs []string) (bool, error) {
+	for _, a := range listenAddrs {
+		maddr, err := ma.NewMultiaddr(a)
+		if err != nil {
+			return false, errors.Wrapf(err, "invalid listen address %s", a)
+		}
+		if config.IsQUICAddr(maddr) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 func privKeyFromKeystore(r repo.Repo) (ci.PrivKey, error) {
 	sk, err := r.Keystore().Get("self")
 	if err != nil {
@@ -0,0 +1,193 @@
+package node
+
+import (
+	"context"
+
+	cid "gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	cbor "gx/ipfs/QmRoARq3nkUb13HSKZGepCZSWe5GrVPwx7xURJGZ7KWv9V/go-ipld-cbor"
+	bstore "gx/ipfs/QmS2aqUZLJp8kF1ihE5rvDGE5LvmKDPnx32w9Z1BW9xLV5/go-ipfs-blockstore"
+	dag "gx/ipfs/QmTQdH4848iTVCJmKXYyRiK72HufWTLYQQ8iN3JaQ8K1Hq/go-merkledag"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	ipld "gx/ipfs/QmcKKBwfz6FyQdHR2jsXrrF6XeSBXYL86anmWNewpFpoF5/go-ipld-format"
+
+	"github.com/filecoin-project/go-filecoin/chain"
+	"github.com/filecoin-project/go-filecoin/consensus"
+	"github.com/filecoin-project/go-filecoin/repo"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// CollectGarbage deletes the blocks of the blockstore which no live root
+// links to. The live roots are the headers of the canonical chain, the state
+// trees of its tipsets of the last consensus.AncestorRoundsNeeded rounds, the
+// data of the storage deals which have not ended, or for a miner, which are
+// not sealed yet, and the pins. If dryRun is set, the unreachable blocks are
+// only counted.
+//
+// The collection waits for the syncs, mined blocks and imports in progress to
+// link the blocks they put, see HoldGC, and holds new ones off until it is
+// done. Other blocks put meanwhile are kept. Only the state trees of the
+// canonical chain are kept: messages of older tipsets, or of forks, can't be
+// replayed afterwards.
+func (node *Node) CollectGarbage(ctx context.Context, dryRun bool) (*repo.GCResult, error) {
+	bs, ok := node.Blockstore.(*repo.SplitBlockstore)
+	if !ok {
+		return nil, errors.New("the blockstore does not support garbage collection")
+	}
+
+	if err := bs.BeginGC(); err != nil {
+		return nil, err
+	}
+	defer bs.EndGC()
+
+	live, err := node.markLive(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to mark the live blocks")
+	}
+
+	blocks, size, err := bs.Sweep(ctx, live, dryRun)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to sweep the blockstore")
+	}
+	return &repo.GCResult{DryRun: dryRun, Live: live.Len(), Blocks: blocks, Size: size}, nil
+}
+
+// HoldGC keeps garbage from being collected until release is called, so that
+// the blocks put meanwhile aren't deleted before a live root links to them.
+func (node *Node) HoldGC() (release func()) {
+	bs, ok := node.Blockstore.(*repo.SplitBlockstore)
+	if !ok {
+		return func() {}
+	}
+	return bs.HoldGC()
+}
+
+// gcHoldingSyncer holds the garbage collection off while syncing, from
+// fetching the blocks until the chain head is set to them.
+type gcHoldingSyncer struct {
+	chain.Syncer
+	bs *repo.SplitBlockstore
+}
+
+func (s *gcHoldingSyncer) HandleNewBlocks(ctx context.Context, blkCids []cid.Cid) error {
+	release := s.bs.HoldGC()
+	defer release()
+	return s.Syncer.HandleNewBlocks(ctx, blkCids)
+}
+
+// markLive returns the set of the live blocks, see CollectGarbage.
+func (node *Node) markLive(ctx context.Context) (*cid.Set, error) {
+	live := cid.NewSet()
+	if err := node.markChain(ctx, live); err != nil {
+		return nil, err
+	}
+
+	var roots []cid.Cid
+	if node.StorageMinerClient != nil {
+		roots = append(roots, node.StorageMinerClient.LivePieces()...)
+	}
+	if node.StorageMiner != nil {
+		roots = append(roots, node.StorageMiner.LivePieces()...)
+	}
+	pins, err := node.Pins.List()
+	if err != nil {
+		return nil, err
+	}
+	roots = append(roots, pins...)
+
+	for _, root := range roots {
+		if err := markDag(ctx, node.Blockstore, root, live); err != nil {
+			return nil, err
+		}
+	}
+	return live, nil
+}
+
+// markChain marks the headers of the canonical chain, which loading the chain
+// requires, and the state trees of its recent tipsets.
+func (node *Node) markChain(ctx context.Context, live *cid.Set) error {
+	head := node.ChainReader.Head()
+	headHeight, err := head.Height()
+	if err != nil {
+		return err
+	}
+	keepStates := consensus.AncestorRoundsNeeded.AsBigInt().Uint64()
+
+	for raw := range node.ChainReader.BlockHistory(ctx, head) {
+		var ts types.TipSet
+		switch v := raw.(type) {
+		case error:
+			return v
+		case types.TipSet:
+			ts = v
+		}
+
+		for _, blk := range ts.ToSlice() {
+			live.Add(blk.Cid())
+		}
+
+		height, err := ts.Height()
+		if err != nil {
+			return err
+		}
+		if height+keepStates < headHeight {
+			continue
+		}
+
+		tsas, err := node.ChainReader.GetTipSetAndState(ctx, ts.String())
+		if err != nil {
+			return err
+		}
+		if err := markDag(ctx, node.Blockstore, tsas.TipSetStateRoot, live); err != nil {
+			return err
+		}
+		for _, blk := range ts.ToSlice() {
+			if err := markDag(ctx, node.Blockstore, blk.StateRoot, live); err != nil {
+				return err
+			}
+		}
+	}
+	return ctx.Err()
+}
+
+// markDag marks the blocks of the dag with the given root. Blocks missing
+// from bs, e.g. of data still being transferred, are skipped.
+func markDag(ctx context.Context, bs bstore.Blockstore, root cid.Cid, live *cid.Set) error {
+	todo := []cid.Cid{root}
+	for len(todo) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		c := todo[len(todo)-1]
+		todo = todo[:len(todo)-1]
+		if !c.Defined() || !live.Visit(c) {
+			continue
+		}
+
+		blk, err := bs.Get(c)
+		if err == bstore.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		var nd ipld.Node
+		switch c.Type() {
+		case cid.DagCBOR:
+			nd, err = cbor.DecodeBlock(blk)
+		case cid.DagProtobuf:
+			nd, err = dag.DecodeProtobufBlock(blk)
+		default:
+			// raw leaves, and codecs the node doesn't store, have no links
+			continue
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to decode block %s", c)
+		}
+		for _, l := range nd.Links() {
+			todo = append(todo, l.Cid)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	blocks "gx/ipfs/QmWoXtvgC8inqFkAATB7cp2Dax7XBi9VDvSg9RCCZufmRk/go-block-format"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectGarbageKeepsBlocksPutConcurrently(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+
+	nd := MakeOfflineNode(t)
+	require.NoError(nd.Start(ctx))
+	defer nd.Stop(ctx)
+
+	// each block is unreachable from when it is put until it is pinned
+	const count = 50
+	var put []blocks.Block
+	done := make(chan error)
+	go func() {
+		for i := 0; i < count; i++ {
+			blk := blocks.NewBlock([]byte(fmt.Sprintf("block %d", i)))
+			release := nd.HoldGC()
+			err := nd.Blockstore.Put(blk)
+			if err == nil {
+				err = nd.Pins.Add(blk.Cid())
+			}
+			release()
+			if err != nil {
+				done <- err
+				return
+			}
+			put = append(put, blk)
+		}
+		done <- nil
+	}()
+
+collect:
+	for {
+		select {
+		case err := <-done:
+			require.NoError(err)
+			break collect
+		default:
+		}
+		_, err := nd.CollectGarbage(ctx, false)
+		require.NoError(err)
+	}
+
+	_, err := nd.CollectGarbage(ctx, false)
+	require.NoError(err)
+	require.Len(put, count)
+	for _, blk := range put {
+		has, err := nd.Blockstore.Has(blk.Cid())
+		require.NoError(err)
+		assert.True(has, "block %s was deleted", blk.Cid())
+	}
+
+	// blocks nothing links to are still collected
+	unlinked := blocks.NewBlock([]byte("unlinked"))
+	require.NoError(nd.Blockstore.Put(unlinked))
+	_, err = nd.CollectGarbage(ctx, false)
+	require.NoError(err)
+	has, err := nd.Blockstore.Has(unlinked.Cid())
+	require.NoError(err)
+	assert.False(has)
+}
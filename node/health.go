@@ -0,0 +1,92 @@
+package node
+
+import (
+	"fmt"
+
+	"gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
+)
+
+// healthKey is the key read to check the datastore of the node is open.
+var healthKey = datastore.NewKey("/health")
+
+// HealthCheck is the status of one subsystem of the node.
+type HealthCheck struct {
+	Name    string `json:"name"`
+	OK      bool   `json:"ok"`
+	Message string `json:"message,omitempty"`
+}
+
+// Liveness checks the subsystems the node can't recover without a restart.
+func (node *Node) Liveness() []HealthCheck {
+	return []HealthCheck{node.checkDatastore()}
+}
+
+// Readiness checks the node can serve requests: its datastore is open, it
+// is connected to at least minPeers peers, and its chain head trails the
+// highest head its peers announced by at most maxSyncLag blocks.
+func (node *Node) Readiness(minPeers int, maxSyncLag uint64) []HealthCheck {
+	return []HealthCheck{
+		node.checkDatastore(),
+		node.checkPeers(minPeers),
+		node.checkSync(maxSyncLag),
+	}
+}
+
+func (node *Node) checkDatastore() HealthCheck {
+	check := HealthCheck{Name: "datastore", OK: true}
+	if _, err := node.Repo.Datastore().Has(healthKey); err != nil {
+		check.OK = false
+		check.Message = err.Error()
+	}
+	return check
+}
+
+func (node *Node) checkPeers(minPeers int) HealthCheck {
+	check := HealthCheck{Name: "peers", OK: true}
+	if node.OfflineMode {
+		check.Message = "offline"
+		return check
+	}
+	n := len(node.Host().Network().Peers())
+	check.Message = fmt.Sprintf("%d peers", n)
+	if n < minPeers {
+		check.OK = false
+		check.Message = fmt.Sprintf("%d peers, fewer than %d", n, minPeers)
+	}
+	return check
+}
+
+func (node *Node) checkSync(maxSyncLag uint64) HealthCheck {
+	check := HealthCheck{Name: "sync", OK: true}
+	height, err := node.ChainReader.Head().Height()
+	if err != nil {
+		check.OK = false
+		check.Message = err.Error()
+		return check
+	}
+	peerHeight := node.announcedHeight()
+	check.Message = fmt.Sprintf("head at %d, peers at %d", height, peerHeight)
+	if height+maxSyncLag < peerHeight {
+		check.OK = false
+		check.Message = fmt.Sprintf("head at %d trails peers at %d by more than %d", height, peerHeight, maxSyncLag)
+	}
+	return check
+}
+
+// noteAnnouncedHeight records the height of a head announced by a peer.
+// The announcements aren't validated, the height is only used to tell how
+// far behind the network the node is.
+func (node *Node) noteAnnouncedHeight(height uint64) {
+	node.peerHeight.Lock()
+	defer node.peerHeight.Unlock()
+	if height > node.peerHeight.max {
+		node.peerHeight.max = height
+	}
+}
+
+// announcedHeight returns the highest height of a head announced by a peer.
+func (node *Node) announcedHeight() uint64 {
+	node.peerHeight.Lock()
+	defer node.peerHeight.Unlock()
+	return node.peerHeight.max
+}
@@ -2,19 +2,67 @@ package node
 
 import (
 	"context"
+
+	cbor "gx/ipfs/QmRoARq3nkUb13HSKZGepCZSWe5GrVPwx7xURJGZ7KWv9V/go-ipld-cbor"
 	"gx/ipfs/QmVRxA4J3UPQpw74dLrQ6NJkfysCA1H4GU28gVpXQt9zMU/go-libp2p-pubsub"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	"gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
 
+	"github.com/filecoin-project/go-filecoin/core"
+	"github.com/filecoin-project/go-filecoin/filnet"
+	"github.com/filecoin-project/go-filecoin/plumbing/msg"
 	"github.com/filecoin-project/go-filecoin/types"
 )
 
+// validateMessageTopic is the pubsub validator of msg.Topic. It runs before
+// messages are forwarded, so that messages which can't be decoded, whose
+// signature is invalid or which are forwarded by banned peers aren't
+// propagated, and drops the copies of messages seen recently.
+func (node *Node) validateMessageTopic(ctx context.Context, pubSubMsg *pubsub.Message) bool {
+	from := pubSubMsg.ReceivedFrom
+	if from == node.Host().ID() {
+		return true
+	}
+	if node.PeerScores.Banned(from) {
+		return false
+	}
+
+	smsg := &types.SignedMessage{}
+	if err := smsg.Unmarshal(pubSubMsg.GetData()); err != nil {
+		log.Debugf("rejecting message from %s: %s", from, err)
+		node.PeerScores.Penalize(from, filnet.InvalidMessage)
+		return false
+	}
+	c, err := smsg.Cid()
+	if err != nil {
+		log.Debugf("rejecting message from %s: %s", from, err)
+		return false
+	}
+	if node.SeenCache.Seen(msg.Topic, c.String()) {
+		return false
+	}
+	if !smsg.VerifySignature() {
+		log.Debugf("rejecting message from %s: invalid signature", from)
+		node.PeerScores.Penalize(from, filnet.InvalidMessage)
+		return false
+	}
+	return true
+}
+
 func (node *Node) processMessage(ctx context.Context, pubSubMsg *pubsub.Message) (err error) {
 	ctx = log.Start(ctx, "Node.processMessage")
 	defer func() {
 		log.FinishWithErr(ctx, err)
 	}()
 
+	from := pubSubMsg.ReceivedFrom
+	if node.PeerScores.Banned(from) {
+		return nil
+	}
+
 	unmarshaled := &types.SignedMessage{}
 	if err := unmarshaled.Unmarshal(pubSubMsg.GetData()); err != nil {
+		node.PeerScores.Penalize(from, filnet.InvalidMessage)
 		return err
 	}
 	log.SetTag(ctx, "message", unmarshaled)
@@ -22,5 +70,61 @@ func (node *Node) processMessage(ctx context.Context, pubSubMsg *pubsub.Message)
 	log.Debugf("Received new message from network: %s", unmarshaled)
 
 	_, err = node.MsgPool.Add(unmarshaled)
+	if err != nil && from != node.Host().ID() {
+		if core.IsAdmissionRejectedError(err) {
+			node.PeerScores.Penalize(from, filnet.Spam)
+		} else {
+			node.PeerScores.Penalize(from, filnet.InvalidMessage)
+		}
+	}
 	return err
 }
+
+// pendingMessagesKey is the key of the messages the pool held when the node
+// last stopped.
+var pendingMessagesKey = datastore.NewKey("/mpool/pending")
+
+// saveMessagePool writes the messages of the pool to the datastore, so that
+// the node keeps sending them after it restarts.
+func (node *Node) saveMessagePool() error {
+	pending := node.MsgPool.Pending()
+	encoded := make([][]byte, len(pending))
+	for i, smsg := range pending {
+		b, err := smsg.Marshal()
+		if err != nil {
+			return err
+		}
+		encoded[i] = b
+	}
+	data, err := cbor.DumpObject(encoded)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal pending messages")
+	}
+	return errors.Wrap(node.Repo.Datastore().Put(pendingMessagesKey, data), "failed to save pending messages")
+}
+
+// loadMessagePool adds the messages saved by saveMessagePool back to the
+// pool, dropping the ones it no longer admits.
+func (node *Node) loadMessagePool() error {
+	data, err := node.Repo.Datastore().Get(pendingMessagesKey)
+	if err == datastore.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to load pending messages")
+	}
+	var encoded [][]byte
+	if err := cbor.DecodeInto(data, &encoded); err != nil {
+		return errors.Wrap(err, "failed to unmarshal pending messages")
+	}
+	for _, b := range encoded {
+		smsg := &types.SignedMessage{}
+		if err := smsg.Unmarshal(b); err != nil {
+			return errors.Wrap(err, "failed to unmarshal pending message")
+		}
+		if _, err := node.MsgPool.Add(smsg); err != nil {
+			log.Infof("dropping saved message: %s", err)
+		}
+	}
+	return nil
+}
@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/big"
+	"net/http"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,6 +15,7 @@ import (
 	dht "gx/ipfs/QmNoNExMdWrYSPZDiJJTVmxSh6uKLN26xYVzbLzBLedRcv/go-libp2p-kad-dht"
 	dhtopts "gx/ipfs/QmNoNExMdWrYSPZDiJJTVmxSh6uKLN26xYVzbLzBLedRcv/go-libp2p-kad-dht/opts"
 	"gx/ipfs/QmP2g3VxmC7g7fyRJDj1VJ72KHZbJ9UW24YjSWEj1XTb4H/go-ipfs-exchange-interface"
+	pstore "gx/ipfs/QmPiemjiKBC9VA7vZF82m4x1oygtg2c2YVqag8PX7dN1BD/go-libp2p-peerstore"
 	cid "gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
 	"gx/ipfs/QmRXf2uUSdGSunRJsM9wXSUNVwLUGCY3So5fAs7h2CBJVf/go-hamt-ipld"
 	autonatsvc "gx/ipfs/QmRmMbeY5QC5iMsuW16wchtFt8wmYTv2suWb8t9MV8dsxm/go-libp2p-autonat-svc"
@@ -23,6 +26,7 @@ import (
 	offroute "gx/ipfs/QmVZ6cQXHoTQja4oo9GhhHZi7dThi4x98mRKgGtKnTy37u/go-ipfs-routing/offline"
 	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
 	circuit "gx/ipfs/QmWuMW6UKZMJo9bFFDwnjg8tW3AtKisMHHrXEutQdmJ19N/go-libp2p-circuit"
+	autonat "gx/ipfs/QmXmZtMdQokSodDNvPdhDyaVRAjgybvR8dQtuMsNoWv4Lq/go-libp2p-autonat"
 	libp2ppeer "gx/ipfs/QmY5Grm8pJdiSSVsYxx4uNRgweY72EmYwuSDbRnbFok3iY/go-libp2p-peer"
 	bserv "gx/ipfs/QmYPZzd9VqmJDwxUnThfeSbV1Y5o53aVPDijTB7j7rS9Ep/go-blockservice"
 	"gx/ipfs/QmYZwey1thDTynSrvd6qQkX24UpTka6TFhQ2v569UpoqxD/go-ipfs-exchange-offline"
@@ -47,18 +51,24 @@ import (
 	"github.com/filecoin-project/go-filecoin/filnet"
 	"github.com/filecoin-project/go-filecoin/lookup"
 	"github.com/filecoin-project/go-filecoin/metrics"
+	"github.com/filecoin-project/go-filecoin/migration"
 	"github.com/filecoin-project/go-filecoin/mining"
 	"github.com/filecoin-project/go-filecoin/plumbing"
+	"github.com/filecoin-project/go-filecoin/plumbing/actr"
 	"github.com/filecoin-project/go-filecoin/plumbing/cfg"
 	"github.com/filecoin-project/go-filecoin/plumbing/chn"
+	"github.com/filecoin-project/go-filecoin/plumbing/mgrt"
 	"github.com/filecoin-project/go-filecoin/plumbing/msg"
 	"github.com/filecoin-project/go-filecoin/plumbing/mthdsig"
 	"github.com/filecoin-project/go-filecoin/plumbing/ntwk"
+	"github.com/filecoin-project/go-filecoin/plumbing/sctr"
 	"github.com/filecoin-project/go-filecoin/porcelain"
 	"github.com/filecoin-project/go-filecoin/proofs"
+	"github.com/filecoin-project/go-filecoin/proofs/params"
 	"github.com/filecoin-project/go-filecoin/proofs/sectorbuilder"
 	"github.com/filecoin-project/go-filecoin/protocol/hello"
 	"github.com/filecoin-project/go-filecoin/protocol/retrieval"
+	"github.com/filecoin-project/go-filecoin/protocol/sealing"
 	"github.com/filecoin-project/go-filecoin/protocol/storage"
 	"github.com/filecoin-project/go-filecoin/repo"
 	"github.com/filecoin-project/go-filecoin/state"
@@ -71,6 +81,27 @@ var filecoinDHTProtocol dhtprotocol.ID = "/fil/kad/1.0.0"
 
 var log = logging.Logger("node") // nolint: deadcode
 
+const (
+	// syncPeerProtection is how long the connection manager keeps the peers
+	// the node synced a chain from.
+	syncPeerProtection = time.Hour
+	// dealPeerProtection is how long the connection manager keeps the peers
+	// the node exchanged storage, retrieval or sealing requests with.
+	dealPeerProtection = 24 * time.Hour
+	// connMgrTrimInterval is how often the connection manager checks the
+	// number of connections of the node.
+	connMgrTrimInterval = time.Minute
+	// minerProvideCheckInterval is how often the node checks whether its
+	// miner has to be announced in the DHT.
+	minerProvideCheckInterval = time.Minute
+	// minerReprovideInterval is how often the node announces its miner in the
+	// DHT again, before the provider records expire.
+	minerReprovideInterval = 12 * time.Hour
+	// bandwidthSampleInterval is how often the rates of the bandwidth meter
+	// are sampled.
+	bandwidthSampleInterval = time.Second
+)
+
 var (
 	// ErrNoRepo is returned when the configs repo is nil
 	ErrNoRepo = errors.New("must pass a repo option to the node build process")
@@ -128,8 +159,35 @@ type Node struct {
 	Ping         *ping.PingService
 	HelloSvc     *hello.Handler
 	Bootstrapper *filnet.Bootstrapper
+	Peering      *filnet.Peering
+	HolePuncher  *filnet.HolePuncher
+	SeenCache    *filnet.SeenCache
+	PeerScores   *filnet.Scorekeeper
+	ConnMgr      *filnet.ConnManager
+	NAT          autonat.AutoNAT
+	Bandwidth    *filnet.BandwidthMeter
 	OnlineStore  *hamt.CborIpldStore
 
+	// reloadLk serializes the reloads of the config, see ReloadConfig, and
+	// stopping the node, which reloads replace the Bootstrapper of.
+	reloadLk sync.Mutex
+	// stopped is set once the node is stopped. It is guarded by reloadLk.
+	stopped bool
+	// bootstrapPeers are the bootstrap peers ConnMgr protects.
+	bootstrapPeers []pstore.PeerInfo
+
+	// peerHeight is the highest height of the heads announced by peers,
+	// see Readiness.
+	peerHeight struct {
+		sync.Mutex
+		max uint64
+	}
+
+	// Role is the role of the node, and Subsystems the subsystems it
+	// enables, see config.Config.Role.
+	Role       string
+	Subsystems Subsystems
+
 	// Data Storage Fields
 
 	// Repo is the repo this node was created with
@@ -139,12 +197,40 @@ type Node struct {
 	// SectorBuilder is used by the miner to fill and seal sectors.
 	sectorBuilder sectorbuilder.SectorBuilder
 
+	// sectorDirs holds the directories the sector builder keeps each kind
+	// of data in, chosen among the storage paths when it is created.
+	sectorDirs map[sectorbuilder.PathKind]string
+
+	// sealingScheduler bounds the jobs filling, sealing and committing
+	// sectors the miner runs at once.
+	sealingScheduler *sectorbuilder.Scheduler
+
+	// sectorProgress tracks the sectors of the miner through sealing.
+	sectorProgress *sctr.Tracker
+
+	// sealingMaster hands the sealing of pieces to remote workers, nil if
+	// no workers are configured.
+	sealingMaster *sealing.Master
+
+	// fetchSessions opens the bitswap sessions of chain syncs and piece
+	// transfers.
+	fetchSessions *filnet.FetchSessions
+
+	// sealingWorker seals sectors for the miner of a remote master, nil
+	// unless the node was started as a sealing worker.
+	sealingWorker       *sealing.Worker
+	cancelSealingWorker context.CancelFunc
+
 	// Exchange is the interface for fetching data from other nodes.
 	Exchange exchange.Interface
 
 	// Blockstore is the un-networked blocks interface
 	Blockstore bstore.Blockstore
 
+	// Pins holds the roots of the dags garbage collection keeps, see
+	// CollectGarbage.
+	Pins *repo.Pins
+
 	// Blockservice is a higher level interface for fetching data
 	blockservice bserv.BlockService
 
@@ -162,6 +248,12 @@ type Node struct {
 
 	// Router is a router from IPFS
 	Router routing.IpfsRouting
+
+	// remoteSigner is the wallet backend forwarding to a remote signing
+	// service, if one is configured. Its health is checked every
+	// remoteSignerPeriod while the node runs.
+	remoteSigner       *wallet.RemoteBackend
+	remoteSignerPeriod time.Duration
 }
 
 // Config is a helper to aid in the construction of a filecoin node.
@@ -173,6 +265,8 @@ type Config struct {
 	Rewarder    consensus.BlockRewarder
 	Repo        repo.Repo
 	IsRelay     bool
+
+	MessagePoolFilters []core.AdmissionFilter
 }
 
 // ConfigOpt is a configuration option for a filecoin node.
@@ -230,6 +324,15 @@ func RewarderConfigOption(rewarder consensus.BlockRewarder) ConfigOpt {
 	}
 }
 
+// MessagePoolAdmissionFilters returns a node config option that registers
+// the given admission filters on the node's message pool.
+func MessagePoolAdmissionFilters(filters ...core.AdmissionFilter) ConfigOpt {
+	return func(c *Config) error {
+		c.MessagePoolFilters = append(c.MessagePoolFilters, filters...)
+		return nil
+	}
+}
+
 // New creates a new node.
 func New(ctx context.Context, opts ...ConfigOpt) (*Node, error) {
 	n := &Config{}
@@ -265,6 +368,10 @@ func readGenesisCid(ds datastore.Datastore) (cid.Cid, error) {
 
 // buildHost determines if we are publically dialable.  If so use public
 // address, if not configure node to announce relay address.
+//
+// Nodes behind a NAT are reached through a port mapping if the router grants
+// one, or else through a relay, until the HolePuncher of the node upgrades
+// the relayed connections to direct ones.
 func (nc *Config) buildHost(ctx context.Context, makeDHT func(host host.Host) (routing.IpfsRouting, error)) (host.Host, error) {
 	// Node must build a host acting as a libp2p relay.  Additionally it
 	// runs the autoNAT service which allows other nodes to check for their
@@ -273,45 +380,73 @@ func (nc *Config) buildHost(ctx context.Context, makeDHT func(host host.Host) (r
 		return makeDHT(h)
 	}
 
+	cfg := nc.Repo.Config()
+	addrsFactory, err := makeAddrsFactory(cfg.Swarm, nc.IsRelay)
+	if err != nil {
+		return nil, err
+	}
+	opts := []libp2p.Option{
+		libp2p.EnableAutoRelay(),
+		libp2p.Routing(makeDHTRightType),
+		libp2p.AddrsFactory(addrsFactory),
+	}
+	if cfg.Swarm.NATPortMap {
+		opts = append(opts, libp2p.NATPortMap())
+	}
 	if nc.IsRelay {
-		cfg := nc.Repo.Config()
-		publicAddr, err := ma.NewMultiaddr(cfg.Swarm.PublicRelayAddress)
+		opts = append(opts, libp2p.EnableRelay(circuit.OptHop))
+	}
+
+	h, err := libp2p.New(
+		ctx,
+		libp2p.ChainOptions(opts...),
+		libp2p.ChainOptions(nc.Libp2pOpts...),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if nc.IsRelay || cfg.Swarm.AutoNATService {
+		// Set up autoNATService as a streamhandler on the host.
+		_, err = autonatsvc.NewAutoNATService(ctx, h)
 		if err != nil {
 			return nil, err
 		}
-		publicAddrFactory := func(lc *libp2p.Config) error {
-			lc.AddrsFactory = func(addrs []ma.Multiaddr) []ma.Multiaddr {
-				if cfg.Swarm.PublicRelayAddress == "" {
-					return addrs
-				}
-				return append(addrs, publicAddr)
-			}
-			return nil
-		}
-		relayHost, err := libp2p.New(
-			ctx,
-			libp2p.EnableRelay(circuit.OptHop),
-			libp2p.EnableAutoRelay(),
-			libp2p.Routing(makeDHTRightType),
-			publicAddrFactory,
-			libp2p.ChainOptions(nc.Libp2pOpts...),
-		)
+	}
+	return h, nil
+}
+
+// makeAddrsFactory returns the addresses factory of the host, advertising
+// the announce addresses of the config instead of the listen addresses if
+// there are any, and the public relay address of relays.
+func makeAddrsFactory(cfg *config.SwarmConfig, isRelay bool) (func([]ma.Multiaddr) []ma.Multiaddr, error) {
+	var announce []ma.Multiaddr
+	for _, a := range cfg.AnnounceAddresses {
+		maddr, err := ma.NewMultiaddr(a)
 		if err != nil {
-			return nil, err
+			return nil, errors.Wrapf(err, "invalid announce address %s", a)
 		}
-		// Set up autoNATService as a streamhandler on the host.
-		_, err = autonatsvc.NewAutoNATService(ctx, relayHost)
+		announce = append(announce, maddr)
+	}
+
+	var publicRelayAddr ma.Multiaddr
+	if isRelay && cfg.PublicRelayAddress != "" {
+		var err error
+		publicRelayAddr, err = ma.NewMultiaddr(cfg.PublicRelayAddress)
 		if err != nil {
-			return nil, err
+			return nil, errors.Wrapf(err, "invalid public relay address %s", cfg.PublicRelayAddress)
 		}
-		return relayHost, nil
 	}
-	return libp2p.New(
-		ctx,
-		libp2p.EnableAutoRelay(),
-		libp2p.Routing(makeDHTRightType),
-		libp2p.ChainOptions(nc.Libp2pOpts...),
-	)
+
+	return func(addrs []ma.Multiaddr) []ma.Multiaddr {
+		if len(announce) > 0 {
+			addrs = append([]ma.Multiaddr{}, announce...)
+		}
+		if publicRelayAddr != nil {
+			addrs = append(addrs, publicRelayAddr)
+		}
+		return addrs
+	}, nil
 }
 
 // Build instantiates a filecoin Node from the settings specified in the config.
@@ -320,12 +455,24 @@ func (nc *Config) Build(ctx context.Context) (*Node, error) {
 		nc.Repo = repo.NewInMemoryRepo()
 	}
 
-	bs := bstore.NewBlockstore(nc.Repo.Datastore())
+	role := nc.Repo.Config().Role
+	subsystems, err := SubsystemsOf(role)
+	if err != nil {
+		return nil, err
+	}
+
+	bs := repo.NewBlockstore(nc.Repo)
 
 	validator := blankValidator{}
 
 	var peerHost host.Host
 	var router routing.IpfsRouting
+	var nat autonat.AutoNAT
+
+	bwMeter := filnet.NewBandwidthMeter()
+	for prefix, limit := range nc.Repo.Config().Swarm.BandwidthLimits {
+		bwMeter.SetLimit(prefix, limit)
+	}
 
 	if !nc.OfflineMode {
 		makeDHT := func(h host.Host) (routing.IpfsRouting, error) {
@@ -347,6 +494,8 @@ func (nc *Config) Build(ctx context.Context) (*Node, error) {
 		if err != nil {
 			return nil, err
 		}
+		peerHost = bwMeter.WrapHost(peerHost)
+		nat = autonat.NewAutoNAT(ctx, peerHost, nil)
 	} else {
 		router = offroute.NewOfflineRouter(nc.Repo.Datastore(), validator)
 		peerHost = rhost.Wrap(noopLibP2PHost{}, router)
@@ -378,41 +527,91 @@ func (nc *Config) Build(ctx context.Context) (*Node, error) {
 		processor = consensus.NewConfiguredProcessor(consensus.NewDefaultMessageValidator(), nc.Rewarder)
 	}
 
+	if nc.Repo.Config().Proofs.Insecure {
+		if err := proofs.UseInsecureProofs(nc.Repo.Config().Network.Name); err != nil {
+			return nil, err
+		}
+		log.Warning("using insecure proofs, seal and PoSt proofs are fake")
+	}
+
 	var nodeConsensus consensus.Protocol
 	if nc.Verifier == nil {
-		nodeConsensus = consensus.NewExpected(&cstOffline, bs, processor, powerTable, genCid, &proofs.RustVerifier{})
+		nodeConsensus = consensus.NewExpected(&cstOffline, bs, processor, powerTable, genCid, proofs.NewVerifier())
 	} else {
 		nodeConsensus = consensus.NewExpected(&cstOffline, bs, processor, powerTable, genCid, nc.Verifier)
 	}
 
 	// only the syncer gets the storage which is online connected
+	fetchSessions := filnet.NewFetchSessions(bservice)
 	chainSyncer := chain.NewDefaultSyncer(&cstOnline, &cstOffline, nodeConsensus, chainStore)
+	chainSyncer.UseSessions(func(ctx context.Context) *hamt.CborIpldStore {
+		return fetchSessions.Open(ctx, filnet.ChainSyncSession).CborStore()
+	})
 	chainReader, ok := chainStore.(chain.ReadStore)
 	if !ok {
 		return nil, errors.New("failed to cast chain.Store to chain.ReadStore")
 	}
 	msgPool := core.NewMessagePool()
+	for _, f := range nc.MessagePoolFilters {
+		msgPool.AddAdmissionFilter(f)
+	}
 
-	// Set up libp2p pubsub
-	fsub, err := pubsub.NewFloodSub(ctx, peerHost)
+	// Copies of blocks and messages are dropped for as long as pubsub
+	// remembers the ids of messages.
+	pubsubCfg := nc.Repo.Config().Pubsub
+	seenTTL, err := time.ParseDuration(pubsubCfg.SeenMessagesTTL)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to set up pubsub")
+		return nil, errors.Wrapf(err, "invalid pubsub seen messages ttl %s", pubsubCfg.SeenMessagesTTL)
 	}
-	backend, err := wallet.NewDSBackend(nc.Repo.WalletDatastore())
+	pubsub.TimeCacheDuration = seenTTL
+
+	// Set up libp2p pubsub. Gossipsub only forwards messages to a subset of
+	// peers, and still speaks floodsub to peers which don't support it.
+	fsub, err := pubsub.NewGossipSub(ctx, peerHost)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to set up wallet backend")
+		return nil, errors.Wrap(err, "failed to set up pubsub")
 	}
-	fcWallet := wallet.New(backend)
+
+	// without a wallet the node holds no keys, so it signs nothing
+	var backends []wallet.Backend
+	var remoteSigner *wallet.RemoteBackend
+	var remoteSignerPeriod time.Duration
+	if subsystems.Wallet {
+		backend, err := wallet.NewDSBackend(nc.Repo.WalletDatastore())
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to set up wallet backend")
+		}
+		backends = append(backends, backend)
+
+		if rsCfg := nc.Repo.Config().Wallet.RemoteSigner; rsCfg != nil && rsCfg.URL != "" {
+			remoteSigner, remoteSignerPeriod, err = newRemoteSigner(rsCfg)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to set up remote signer")
+			}
+			backends = append(backends, remoteSigner)
+		}
+	}
+	fcWallet := wallet.New(backends...)
+	sectorProgress := sctr.NewTracker()
+	proofsCfg := nc.Repo.Config().Proofs
+	proverResources := proofs.DetectResources(proofsCfg.UseGPU, proofsCfg.MaxCPUs, proofsCfg.MemoryPerProof)
 
 	PorcelainAPI := porcelain.New(plumbing.New(&plumbing.APIDeps{
+		ActorState:   actr.NewStateDecoder(chainReader, bs, builtin.StateSchemas),
+		AddressBook:  wallet.NewAddressBook(nc.Repo.Datastore()),
 		Chain:        chn.New(chainReader),
 		Config:       cfg.NewConfig(nc.Repo),
 		MessagePool:  msgPool,
+		Migrator:     mgrt.NewMigrator(chainReader, bs, migration.Upgrades),
+		MsgIndex:     msg.NewIndex(chainReader, nc.Repo.ChainDatastore()),
 		MsgPreviewer: msg.NewPreviewer(fcWallet, chainReader, &cstOffline, bs),
 		MsgQueryer:   msg.NewQueryer(nc.Repo, fcWallet, chainReader, &cstOffline, bs),
-		MsgSender:    msg.NewSender(nc.Repo, fcWallet, chainReader, msgPool, fsub.Publish),
+		MsgReplayer:  msg.NewReplayer(chainReader, bs, &cstOffline),
+		MsgSender:    msg.NewSender(nc.Repo, fcWallet, msg.NewNonceTracker(chainReader, msgPool), msgPool, fsub.Publish),
 		MsgWaiter:    msg.NewWaiter(chainReader, bs, &cstOffline),
-		Network:      ntwk.NewNetwork(peerHost),
+		Network:      ntwk.NewNetwork(peerHost, router, pinger),
+		Resources:    proverResources,
+		Sectors:      sectorProgress,
 		SigGetter:    mthdsig.NewGetter(chainReader),
 		Wallet:       fcWallet,
 	}))
@@ -420,11 +619,12 @@ func (nc *Config) Build(ctx context.Context) (*Node, error) {
 	nd := &Node{
 		blockservice: bservice,
 		Blockstore:   bs,
+		Pins:         repo.NewPins(nc.Repo),
 		cborStore:    &cstOffline,
 		OnlineStore:  &cstOnline,
 		Consensus:    nodeConsensus,
 		ChainReader:  chainReader,
-		Syncer:       chainSyncer,
+		Syncer:       &gcHoldingSyncer{Syncer: chainSyncer, bs: bs},
 		PowerTable:   powerTable,
 		PorcelainAPI: PorcelainAPI,
 		Exchange:     bswap,
@@ -434,27 +634,90 @@ func (nc *Config) Build(ctx context.Context) (*Node, error) {
 		PeerHost:     peerHost,
 		Ping:         pinger,
 		PubSub:       fsub,
+		NAT:          nat,
+		Bandwidth:    bwMeter,
+		SeenCache:    filnet.NewSeenCache(seenTTL, pubsubCfg.SeenCacheSize),
 		Repo:         nc.Repo,
 		Wallet:       fcWallet,
 		blockTime:    nc.BlockTime,
 		Router:       router,
+		Role:         role,
+		Subsystems:   subsystems,
+
+		remoteSigner:       remoteSigner,
+		remoteSignerPeriod: remoteSignerPeriod,
+		sectorProgress:     sectorProgress,
+		fetchSessions:      fetchSessions,
 	}
 
-	// Bootstrapping network peers.
-	periodStr := nd.Repo.Config().Bootstrap.Period
-	period, err := time.ParseDuration(periodStr)
-	if err != nil {
-		return nil, errors.Wrapf(err, "couldn't parse bootstrap period %s", periodStr)
+	// the proofs backend reads its resources from the environment when it
+	// starts proving
+	if err := proverResources.Apply(); err != nil {
+		return nil, errors.Wrap(err, "failed to configure proofs backend")
+	}
+
+	sealingCfg := nd.Repo.Config().Mining.Sealing
+	nd.sealingScheduler = sectorbuilder.NewScheduler(map[sectorbuilder.Stage]int{
+		sectorbuilder.AddPieceStage: sealingCfg.AddPieceConcurrency,
+		sectorbuilder.SealStage:     proverResources.BoundParallelProofs(sealingCfg.SealConcurrency),
+		sectorbuilder.CommitStage:   sealingCfg.CommitConcurrency,
+	})
+	if len(sealingCfg.Workers) > 0 && subsystems.Mining {
+		var workers []libp2ppeer.ID
+		for _, w := range sealingCfg.Workers {
+			pid, err := libp2ppeer.IDB58Decode(w)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid sealing worker %s", w)
+			}
+			workers = append(workers, pid)
+		}
+		nd.sealingMaster = sealing.NewMaster(nd.Host(), nd.MiningAddress, workers)
 	}
 
 	// Bootstrapper maintains connections to some subset of addresses
-	ba := nd.Repo.Config().Bootstrap.Addresses
-	bpi, err := filnet.PeerAddrsToPeerInfos(ba)
+	var bpi []pstore.PeerInfo
+	nd.Bootstrapper, bpi, err = nd.newBootstrapper(nd.Repo.Config().Bootstrap)
 	if err != nil {
-		return nil, errors.Wrapf(err, "couldn't parse bootstrap addresses [%s]", ba)
+		return nil, err
+	}
+
+	// Peering keeps the node connected to the peers of its cluster
+	pa := nd.Repo.Config().Peering.Peers
+	ppi, err := filnet.PeerAddrsToPeerInfos(pa)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't parse peering addresses [%s]", pa)
+	}
+	nd.Peering = filnet.NewPeering(nd.Host(), ppi)
+
+	// HolePuncher upgrades the connections relayed to peers to direct ones
+	if nd.Repo.Config().Swarm.HolePunching {
+		nd.HolePuncher = filnet.NewHolePuncher(nd.Host())
+	}
+
+	connMgrCfg := nd.Repo.Config().Swarm.ConnMgr
+	gracePeriod, err := time.ParseDuration(connMgrCfg.GracePeriod)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid connection manager grace period %s", connMgrCfg.GracePeriod)
+	}
+	nd.ConnMgr = filnet.NewConnManager(nd.Host().Network(), connMgrCfg.LowWater, connMgrCfg.HighWater, gracePeriod)
+	nd.protectBootstrapPeers(bpi)
+	for _, pi := range ppi {
+		nd.ConnMgr.Protect(pi.ID, "peering")
+	}
+	nd.ConnMgr.ProtectProtocols("deal", dealPeerProtection, "/fil/storage/", "/fil/retrieval/", "/fil/sealing/")
+
+	nd.PeerScores, err = filnet.NewScorekeeper(nd.Repo.Datastore(), nd.Host().Network())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to set up peer scores")
+	}
+
+	// Validate blocks and messages before forwarding them
+	if err := fsub.RegisterTopicValidator(BlockTopic, nd.validateBlockTopic); err != nil {
+		return nil, errors.Wrap(err, "failed to register block topic validator")
+	}
+	if err := fsub.RegisterTopicValidator(msg.Topic, nd.validateMessageTopic); err != nil {
+		return nil, errors.Wrap(err, "failed to register message topic validator")
 	}
-	minPeerThreshold := nd.Repo.Config().Bootstrap.MinPeerThreshold
-	nd.Bootstrapper = filnet.NewBootstrapper(bpi, nd.Host(), nd.Host().Network(), nd.Router, minPeerThreshold, period)
 
 	// On-chain lookup service
 	defaultAddressGetter := func() (address.Address, error) {
@@ -465,14 +728,61 @@ func (nc *Config) Build(ctx context.Context) (*Node, error) {
 	return nd, nil
 }
 
+// newRemoteSigner creates the remote signer wallet backend described by
+// cfg, returning it along with how often its health should be checked.
+func newRemoteSigner(cfg *config.RemoteSignerConfig) (*wallet.RemoteBackend, time.Duration, error) {
+	timeout := wallet.DefaultRemoteSignerTimeout
+	if cfg.Timeout != "" {
+		var err error
+		timeout, err = time.ParseDuration(cfg.Timeout)
+		if err != nil {
+			return nil, 0, errors.Wrapf(err, "couldn't parse remote signer timeout %s", cfg.Timeout)
+		}
+	}
+
+	period := 30 * time.Second
+	if cfg.HealthCheckPeriod != "" {
+		var err error
+		period, err = time.ParseDuration(cfg.HealthCheckPeriod)
+		if err != nil {
+			return nil, 0, errors.Wrapf(err, "couldn't parse remote signer health check period %s", cfg.HealthCheckPeriod)
+		}
+	}
+
+	client := &http.Client{Timeout: timeout}
+	if cfg.ClientCert != "" || cfg.ClientKey != "" || cfg.CACert != "" {
+		var err error
+		client, err = wallet.NewMutualTLSClient(cfg.ClientCert, cfg.ClientKey, cfg.CACert, timeout)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	opts := []wallet.RemoteBackendOption{wallet.WithHTTPClient(client)}
+	if cfg.Retries > 0 {
+		opts = append(opts, wallet.WithRetries(cfg.Retries, wallet.DefaultRemoteSignerBackoff))
+	}
+	return wallet.NewRemoteBackend(cfg.URL, opts...), period, nil
+}
+
 // Start boots up the node.
 func (node *Node) Start(ctx context.Context) error {
 	if err := node.ChainReader.Load(ctx); err != nil {
 		return err
 	}
 
+	if err := node.loadMessagePool(); err != nil {
+		log.Warningf("failed to restore the message pool: %s", err)
+	}
+
+	if err := node.useParameterCache(); err != nil {
+		return errors.Wrap(err, "failed to set parameter cache")
+	}
+
 	// Only set these up, if there is a miner configured.
-	if _, err := node.MiningAddress(); err == nil {
+	if _, err := node.MiningAddress(); err == nil && !node.Subsystems.Mining {
+		log.Warningf("not setting up the configured miner, mining is disabled for nodes in the %s role", node.Role)
+	} else if err == nil {
 		if err := node.setupMining(ctx); err != nil {
 			log.Errorf("setup mining failed: %v", err)
 			return err
@@ -483,13 +793,22 @@ func (node *Node) Start(ctx context.Context) error {
 	syncCallBack := func(pid libp2ppeer.ID, cids []cid.Cid, height uint64) {
 		// TODO it is possible the syncer interface should be modified to
 		// make use of the additional context not used here (from addr + height).
-		// To keep things simple for now this info is not used.
+		// To keep things simple for now this info is only used for readiness.
+		node.noteAnnouncedHeight(height)
 		err := node.Syncer.HandleNewBlocks(context.Background(), cids)
 		if err != nil {
 			log.Infof("error handling blocks: %s", types.NewSortedCidSet(cids...).String())
+			if isInvalidChainErr(err) {
+				node.PeerScores.Penalize(pid, filnet.InvalidBlock)
+			}
+			return
 		}
+		node.ConnMgr.ProtectFor(pid, "sync", syncPeerProtection)
 	}
-	node.HelloSvc = hello.New(node.Host(), node.ChainReader.GenesisCid(), syncCallBack, node.ChainReader.Head)
+	timeoutCallBack := func(pid libp2ppeer.ID) {
+		node.PeerScores.Penalize(pid, filnet.Timeout)
+	}
+	node.HelloSvc = hello.New(node.Host(), node.ChainReader.GenesisCid(), node.Repo.Config().Network.Name, syncCallBack, node.ChainReader.Head, timeoutCallBack)
 
 	cni := storage.NewClientNodeImpl(dag.NewDAGService(node.BlockService()), node.Host(), node.GetBlockTime())
 	var err error
@@ -498,8 +817,13 @@ func (node *Node) Start(ctx context.Context) error {
 		return errors.Wrap(err, "Could not make new storage client")
 	}
 
-	node.RetrievalClient = retrieval.NewClient(node)
-	node.RetrievalMiner = retrieval.NewMiner(node)
+	node.RetrievalClient = retrieval.NewClient(node, node.PorcelainAPI)
+	if node.Subsystems.Mining {
+		node.RetrievalMiner, err = retrieval.NewMiner(node, node.PorcelainAPI, node.Repo.DealsDatastore())
+		if err != nil {
+			return errors.Wrap(err, "Could not make new retrieval miner")
+		}
+	}
 
 	// subscribe to block notifications
 	blkSub, err := node.PubSub.Subscribe(BlockTopic)
@@ -518,6 +842,12 @@ func (node *Node) Start(ctx context.Context) error {
 	cctx, cancel := context.WithCancel(context.Background())
 	node.cancelSubscriptionsCtx = cancel
 
+	go node.ConnMgr.Run(cctx, connMgrTrimInterval)
+	go node.Bandwidth.Run(cctx, bandwidthSampleInterval)
+	if !node.OfflineMode && node.Subsystems.Mining {
+		go node.provideMiner(cctx)
+	}
+
 	go node.handleSubscription(cctx, node.processBlock, "processBlock", node.BlockSub, "BlockSub")
 	go node.handleSubscription(cctx, node.processMessage, "processMessage", node.MessageSub, "MessageSub")
 
@@ -525,8 +855,16 @@ func (node *Node) Start(ctx context.Context) error {
 	node.HeaviestTipSetCh = node.ChainReader.HeadEvents().Sub(chain.NewHeadTopic)
 	go node.handleNewHeaviestTipSet(cctx, node.ChainReader.Head())
 
+	if node.remoteSigner != nil {
+		go node.remoteSigner.RunHealthChecks(cctx, node.remoteSignerPeriod)
+	}
+
 	if !node.OfflineMode {
 		node.Bootstrapper.Start(context.Background())
+		node.Peering.Start(context.Background())
+		if node.HolePuncher != nil {
+			node.HolePuncher.Start(context.Background())
+		}
 	}
 
 	mag := func() address.Address {
@@ -558,6 +896,71 @@ func (node *Node) Start(ctx context.Context) error {
 	return nil
 }
 
+// useParameterCache points the proofs library to the parameter cache
+// configured in proofs.parameterCache, if any.
+func (node *Node) useParameterCache() error {
+	dir := node.Repo.Config().Proofs.ParameterCache
+	if dir == "" {
+		return nil
+	}
+	return os.Setenv(params.CacheDirEnv, dir)
+}
+
+// checkProofParams fails if a parameter manifest is configured and the
+// parameter cache lacks some parameters for the sectors of the node, rather
+// than failing at the first seal.
+func (node *Node) checkProofParams(ctx context.Context) error {
+	cfg := node.Repo.Config().Proofs
+	if cfg.ParameterManifest == "" {
+		return nil
+	}
+	manifest, err := params.LoadManifest(ctx, cfg.ParameterManifest)
+	if err != nil {
+		return err
+	}
+	maxUserBytes, err := node.SectorBuilder().GetMaxUserBytesPerStagedSector()
+	if err != nil {
+		return errors.Wrap(err, "failed to get sector size")
+	}
+
+	size := params.SectorSize(maxUserBytes)
+	dir := params.CacheDir(cfg.ParameterCache)
+	missing, err := params.Missing(manifest, dir, size)
+	if err != nil {
+		return errors.Wrapf(err, "failed to check parameter cache %s", dir)
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("parameter cache %s lacks %s, fetch them with 'go-filecoin proofs fetch-params --size %d'", dir, strings.Join(missing, ", "), size)
+	}
+	return nil
+}
+
+// provideMiner announces the miner of the node in the DHT, so clients find
+// its peer, once it is configured and then again before the records expire.
+func (node *Node) provideMiner(ctx context.Context) {
+	var provided address.Address
+	var last time.Time
+
+	ticker := time.NewTicker(minerProvideCheckInterval)
+	defer ticker.Stop()
+	for {
+		addr, err := node.MiningAddress()
+		if err == nil && (addr != provided || time.Since(last) > minerReprovideInterval) {
+			if err := node.PorcelainAPI.NetworkProvideMiner(ctx, addr); err != nil {
+				log.Warningf("failed to announce miner %s in the DHT: %s", addr, err)
+			} else {
+				provided, last = addr, time.Now()
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
 func (node *Node) setupMining(ctx context.Context) error {
 	// configure the underlying sector store, defaulting to the non-test version
 	sectorStoreType := proofs.Live
@@ -575,6 +978,68 @@ func (node *Node) setupMining(ctx context.Context) error {
 	return nil
 }
 
+// StartSealingWorker registers the node as a sealing worker with the master
+// at the given multiaddr and seals at most capacity sectors at once for it,
+// until the node stops.
+func (node *Node) StartSealingWorker(ctx context.Context, masterAddr string, capacity uint64) error {
+	if !node.Subsystems.Mining {
+		return node.errDisabled("sealing")
+	}
+	if node.sealingWorker != nil {
+		return errors.New("node is already a sealing worker")
+	}
+	if node.isMining() {
+		return errors.New("a mining node cannot be a sealing worker")
+	}
+	if capacity == 0 {
+		return errors.New("capacity must be positive")
+	}
+
+	pis, err := filnet.PeerAddrsToPeerInfos([]string{masterAddr})
+	if err != nil {
+		return errors.Wrapf(err, "invalid master address %s", masterAddr)
+	}
+	master := pis[0]
+	if err := node.Host().Connect(ctx, master); err != nil {
+		return errors.Wrapf(err, "failed to connect to master %s", master.ID.Pretty())
+	}
+
+	minerAddr, err := sealing.Register(ctx, node.Host(), master.ID, capacity)
+	if err != nil {
+		return errors.Wrapf(err, "failed to register with master %s", master.ID.Pretty())
+	}
+
+	sectorStoreType := proofs.Live
+	if os.Getenv("FIL_USE_SMALL_SECTORS") == "true" {
+		sectorStoreType = proofs.Test
+	}
+	dirs, err := node.selectSectorDirs()
+	if err != nil {
+		return err
+	}
+	sb, err := newSectorBuilder(sectorbuilder.RustSectorBuilderConfig{
+		BlockService:     node.blockservice,
+		LastUsedSectorID: sealing.SectorIDBase(node.Host().ID()),
+		MetadataDir:      dirs[sectorbuilder.CachePath],
+		MinerAddr:        minerAddr,
+		SealedSectorDir:  dirs[sectorbuilder.SealedPath],
+		SectorStoreType:  sectorStoreType,
+		StagedSectorDir:  dirs[sectorbuilder.StagedPath],
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to initialize sector builder for miner %s", minerAddr)
+	}
+	node.sectorBuilder = sb
+
+	var workerCtx context.Context
+	workerCtx, node.cancelSealingWorker = context.WithCancel(context.Background())
+	node.sealingWorker = sealing.NewWorker(node.Host(), master.ID, sb, capacity)
+	go node.sealingWorker.Run(workerCtx)
+
+	log.Infof("sealing sectors of miner %s for master %s", minerAddr, master.ID.Pretty())
+	return nil
+}
+
 func (node *Node) setIsMining(isMining bool) {
 	node.mining.Lock()
 	defer node.mining.Unlock()
@@ -643,6 +1108,9 @@ func (node *Node) handleNewHeaviestTipSet(ctx context.Context, head types.TipSet
 			if node.StorageMiner != nil {
 				node.StorageMiner.OnNewHeaviestTipSet(newHead)
 			}
+			if node.StorageMinerClient != nil {
+				node.StorageMinerClient.OnNewHeaviestTipSet(newHead)
+			}
 			node.HeaviestTipSetHandled()
 		case <-ctx.Done():
 			return
@@ -666,13 +1134,23 @@ func (node *Node) cancelSubscriptions() {
 	}
 }
 
-// Stop initiates the shutdown of the node.
+// Stop shuts the node down one subsystem after the other: it stops taking
+// blocks and messages from the network, then mining and sealing, saves the
+// messages of the pool, closes the sector builder and the network, saves the
+// deals, and closes the repo last, once the writes in flight to it are done.
 func (node *Node) Stop(ctx context.Context) {
 	node.ChainReader.HeadEvents().Unsub(node.HeaviestTipSetCh)
+	node.cancelSubscriptions()
+
 	node.StopMining(ctx)
+	if node.cancelSealingWorker != nil {
+		node.cancelSealingWorker()
+	}
 
-	node.cancelSubscriptions()
-	node.ChainReader.Stop()
+	// the messages not mined yet are sent again once the node restarts
+	if err := node.saveMessagePool(); err != nil {
+		fmt.Printf("error saving message pool: %s\n", err)
+	}
 
 	if node.SectorBuilder() != nil {
 		if err := node.SectorBuilder().Close(); err != nil {
@@ -681,16 +1159,36 @@ func (node *Node) Stop(ctx context.Context) {
 		node.sectorBuilder = nil
 	}
 
+	node.reloadLk.Lock()
+	node.stopped = true
+	node.Bootstrapper.Stop()
+	node.reloadLk.Unlock()
+	node.Peering.Stop()
+	if node.HolePuncher != nil {
+		node.HolePuncher.Stop()
+	}
 	if err := node.Host().Close(); err != nil {
 		fmt.Printf("error closing host: %s\n", err)
 	}
 
+	node.ChainReader.Stop()
+
+	// deals resume from the state they are in once the node restarts
+	if node.StorageMiner != nil {
+		if err := node.StorageMiner.SaveDeals(); err != nil {
+			fmt.Printf("error saving miner deals: %s\n", err)
+		}
+	}
+	if node.StorageMinerClient != nil {
+		if err := node.StorageMinerClient.SaveDeals(); err != nil {
+			fmt.Printf("error saving client deals: %s\n", err)
+		}
+	}
+
 	if err := node.Repo.Close(); err != nil {
 		fmt.Printf("error closing repo: %s\n", err)
 	}
 
-	node.Bootstrapper.Stop()
-
 	fmt.Println("stopping filecoin :(")
 }
 
@@ -715,11 +1213,15 @@ func (node *Node) MiningAddress() (address.Address, error) {
 }
 
 // MiningTimes returns the configured time it takes to mine a block, and also
-// the mining delay duration, which is currently a fixed fraction of block time.
+// the mining delay duration, which is mining.mineDelay if configured and
+// otherwise a fixed fraction of block time.
 // Note this is mocked behavior, in production this time is determined by how
 // long it takes to generate PoSTs.
 func (node *Node) MiningTimes() (time.Duration, time.Duration) {
 	mineDelay := node.GetBlockTime() / mining.MineDelayConversionFactor
+	if md, err := time.ParseDuration(node.Repo.Config().Mining.MineDelay); err == nil {
+		mineDelay = md
+	}
 	return node.GetBlockTime(), mineDelay
 }
 
@@ -743,6 +1245,9 @@ func StartMining(ctx context.Context, node *Node) error {
 // StartMining causes the node to start feeding blocks to the mining worker and initializes
 // the SectorBuilder for the mining address.
 func (node *Node) StartMining(ctx context.Context) error {
+	if !node.Subsystems.Mining {
+		return node.errDisabled("mining")
+	}
 	if node.isMining() {
 		return errors.New("Node is already mining")
 	}
@@ -758,6 +1263,10 @@ func (node *Node) StartMining(ctx context.Context) error {
 		}
 	}
 
+	if err := node.checkProofParams(ctx); err != nil {
+		return err
+	}
+
 	minerOwnerAddr, err := node.MiningOwnerAddress(ctx, minerAddr)
 	if err != nil {
 		return errors.Wrapf(err, "failed to get mining owner address for miner %s", minerAddr)
@@ -817,47 +1326,43 @@ func (node *Node) StartMining(ctx context.Context) error {
 	}
 	node.StorageMiner = storageMiner
 
+	// sectors sealed by remote workers are committed like the ones sealed
+	// locally; without a sealing master the channel stays nil
+	var remoteSealResults <-chan sectorbuilder.SectorSealResult
+	if node.sealingMaster != nil {
+		remoteSealResults = node.sealingMaster.SectorSealResults()
+		go node.sealingMaster.Run(node.miningCtx)
+	}
+
+	sealingCfg := node.Repo.Config().Mining.Sealing
+	batchWait, err := time.ParseDuration(sealingCfg.CommitBatchWait)
+	if err != nil {
+		return errors.Wrapf(err, "invalid commit batch wait %s", sealingCfg.CommitBatchWait)
+	}
+	commits := newCommitBatcher(sealingCfg.CommitBatchSize, batchWait, func(sectors []*sectorbuilder.SealedSectorMetadata) {
+		node.commitSectors(minerOwnerAddr, minerAddr, sectors)
+	})
+
 	// loop, turning sealing-results into commitSector messages to be included
 	// in the chain
 	go func() {
 		for {
+			var result sectorbuilder.SectorSealResult
 			select {
-			case result := <-node.SectorBuilder().SectorSealResults():
-				if result.SealingErr != nil {
-					log.Errorf("failed to seal sector with id %d: %s", result.SectorID, result.SealingErr.Error())
-				} else if result.SealingResult != nil {
-
-					// TODO: determine these algorithmically by simulating call and querying historical prices
-					gasPrice := types.NewGasPrice(0)
-					gasUnits := types.NewGasUnits(300)
-
-					val := result.SealingResult
-					// This call can fail due to, e.g. nonce collisions. Our miners existence depends on this.
-					// We should deal with this, but MessageSendWithRetry is problematic.
-					_, err := node.PorcelainAPI.MessageSend(
-						node.miningCtx,
-						minerOwnerAddr,
-						minerAddr,
-						nil,
-						gasPrice,
-						gasUnits,
-						"commitSector",
-						val.SectorID,
-						val.CommD[:],
-						val.CommR[:],
-						val.CommRStar[:],
-						val.Proof[:],
-					)
-					if err != nil {
-						log.Errorf("failed to send commitSector message from %s to %s for sector with id %d: %s", minerOwnerAddr, minerAddr, val.SectorID, err)
-						continue
-					}
-
-					node.StorageMiner.OnCommitmentAddedToChain(val, nil)
-				}
+			case result = <-node.SectorBuilder().SectorSealResults():
+			case result = <-remoteSealResults:
 			case <-node.miningCtx.Done():
+				commits.stop()
 				return
 			}
+
+			if result.SealingErr != nil {
+				log.Errorf("failed to seal sector with id %d: %s", result.SectorID, result.SealingErr.Error())
+				node.sectorProgress.Fail(result.SectorID, result.SealingErr)
+			} else if result.SealingResult != nil {
+				node.sectorProgress.Enter(result.SectorID, sctr.Committing)
+				commits.add(result.SealingResult)
+			}
 		}
 	}()
 
@@ -870,7 +1375,11 @@ func (node *Node) StartMining(ctx context.Context) error {
 					return
 				case <-time.After(time.Duration(node.Repo.Config().Mining.AutoSealIntervalSeconds) * time.Second):
 					log.Info("auto-seal has been triggered")
-					if err := node.SectorBuilder().SealAllStagedSectors(node.miningCtx); err != nil {
+					err := node.sealingScheduler.Do(node.miningCtx, sectorbuilder.SealStage, "seal staged sectors", func() error {
+						node.sectorProgress.EnterAll(sctr.Staged, sctr.Sealing)
+						return node.SectorBuilder().SealAllStagedSectors(node.miningCtx)
+					})
+					if err != nil {
 						log.Errorf("scheduler received error from node.SectorBuilder.SealAllStagedSectors (%s) - exiting", err.Error())
 						return
 					}
@@ -885,6 +1394,94 @@ func (node *Node) StartMining(ctx context.Context) error {
 	return nil
 }
 
+// commitSectors commits a batch of sealed sectors, in a commitSector message
+// for a single sector and a commitSectors message otherwise.
+func (node *Node) commitSectors(minerOwnerAddr, minerAddr address.Address, sectors []*sectorbuilder.SealedSectorMetadata) {
+	if len(sectors) == 1 {
+		node.commitSector(minerOwnerAddr, minerAddr, sectors[0])
+		return
+	}
+
+	sectorIDs := make([]uint64, len(sectors))
+	var commitments []byte
+	for i, val := range sectors {
+		sectorIDs[i] = val.SectorID
+		commitments = append(commitments, val.CommD[:]...)
+		commitments = append(commitments, val.CommR[:]...)
+		commitments = append(commitments, val.CommRStar[:]...)
+		commitments = append(commitments, val.Proof[:]...)
+	}
+
+	desc := fmt.Sprintf("commit sectors %v", sectorIDs)
+	err := node.sealingScheduler.Do(node.miningCtx, sectorbuilder.CommitStage, desc, func() error {
+		// TODO: determine these algorithmically by simulating call and querying historical prices
+		gasPrice := types.NewGasPrice(0)
+		gasUnits := types.NewGasUnits(300 * uint64(len(sectors)))
+
+		_, err := node.PorcelainAPI.MessageSend(
+			node.miningCtx,
+			minerOwnerAddr,
+			minerAddr,
+			nil,
+			gasPrice,
+			gasUnits,
+			"commitSectors",
+			sectorIDs,
+			commitments,
+		)
+		return err
+	})
+	if err != nil {
+		log.Errorf("failed to send commitSectors message from %s to %s for sectors with ids %v: %s", minerOwnerAddr, minerAddr, sectorIDs, err)
+		for _, val := range sectors {
+			node.sectorProgress.Fail(val.SectorID, err)
+		}
+		return
+	}
+
+	for _, val := range sectors {
+		node.sectorProgress.Enter(val.SectorID, sctr.Committed)
+		node.StorageMiner.OnCommitmentAddedToChain(val, nil)
+	}
+}
+
+// commitSector sends the commitSector message of a sealed sector, as a job of
+// the sealing scheduler, and tells the storage miner about the commitment.
+func (node *Node) commitSector(minerOwnerAddr, minerAddr address.Address, val *sectorbuilder.SealedSectorMetadata) {
+	desc := fmt.Sprintf("commit sector %d", val.SectorID)
+	err := node.sealingScheduler.Do(node.miningCtx, sectorbuilder.CommitStage, desc, func() error {
+		// TODO: determine these algorithmically by simulating call and querying historical prices
+		gasPrice := types.NewGasPrice(0)
+		gasUnits := types.NewGasUnits(300)
+
+		// This call can fail due to, e.g. nonce collisions. Our miners existence depends on this.
+		// We should deal with this, but MessageSendWithRetry is problematic.
+		_, err := node.PorcelainAPI.MessageSend(
+			node.miningCtx,
+			minerOwnerAddr,
+			minerAddr,
+			nil,
+			gasPrice,
+			gasUnits,
+			"commitSector",
+			val.SectorID,
+			val.CommD[:],
+			val.CommR[:],
+			val.CommRStar[:],
+			val.Proof[:],
+		)
+		return err
+	})
+	if err != nil {
+		log.Errorf("failed to send commitSector message from %s to %s for sector with id %d: %s", minerOwnerAddr, minerAddr, val.SectorID, err)
+		node.sectorProgress.Fail(val.SectorID, err)
+		return
+	}
+	node.sectorProgress.Enter(val.SectorID, sctr.Committed)
+
+	node.StorageMiner.OnCommitmentAddedToChain(val, nil)
+}
+
 func (node *Node) getLastUsedSectorID(ctx context.Context, minerAddr address.Address) (uint64, error) {
 	rets, methodSignature, err := node.PorcelainAPI.MessageQuery(
 		ctx,
@@ -919,21 +1516,22 @@ func initSectorBuilderForNode(ctx context.Context, node *Node, sectorStoreType p
 		return nil, errors.Wrapf(err, "failed to get last used sector id for miner w/address %s", minerAddr.String())
 	}
 
-	// TODO: Where should we store the RustSectorBuilder metadata? Currently, we
-	// configure the RustSectorBuilder to store its metadata in the staging
-	// directory.
+	dirs, err := node.selectSectorDirs()
+	if err != nil {
+		return nil, err
+	}
 
 	cfg := sectorbuilder.RustSectorBuilderConfig{
 		BlockService:     node.blockservice,
 		LastUsedSectorID: lastUsedSectorID,
-		MetadataDir:      node.Repo.StagingDir(),
+		MetadataDir:      dirs[sectorbuilder.CachePath],
 		MinerAddr:        minerAddr,
-		SealedSectorDir:  node.Repo.SealedDir(),
+		SealedSectorDir:  dirs[sectorbuilder.SealedPath],
 		SectorStoreType:  sectorStoreType,
-		StagedSectorDir:  node.Repo.StagingDir(),
+		StagedSectorDir:  dirs[sectorbuilder.StagedPath],
 	}
 
-	sb, err := sectorbuilder.NewRustSectorBuilder(cfg)
+	sb, err := newSectorBuilder(cfg)
 	if err != nil {
 		return nil, errors.Wrap(err, fmt.Sprintf("failed to initialize sector builder for miner %s", minerAddr.String()))
 	}
@@ -941,6 +1539,86 @@ func initSectorBuilderForNode(ctx context.Context, node *Node, sectorStoreType p
 	return sb, nil
 }
 
+// newSectorBuilder returns the sector builder of the proofs the node uses.
+func newSectorBuilder(cfg sectorbuilder.RustSectorBuilderConfig) (sectorbuilder.SectorBuilder, error) {
+	if proofs.InsecureProofs() {
+		return sectorbuilder.NewInsecureSectorBuilder(cfg)
+	}
+	return sectorbuilder.NewRustSectorBuilder(cfg)
+}
+
+// storagePaths returns the storage paths of the sector builder configured in
+// mining.storagePaths, or the sector directories of the repo if there are none.
+func (node *Node) storagePaths() ([]sectorbuilder.StoragePath, bool) {
+	configured := node.Repo.Config().Mining.StoragePaths
+	if len(configured) == 0 {
+		return []sectorbuilder.StoragePath{
+			{Path: node.Repo.StagingDir(), Kinds: []sectorbuilder.PathKind{sectorbuilder.StagedPath, sectorbuilder.CachePath}},
+			{Path: node.Repo.SealedDir(), Kinds: []sectorbuilder.PathKind{sectorbuilder.SealedPath}},
+		}, false
+	}
+
+	var paths []sectorbuilder.StoragePath
+	for _, c := range configured {
+		p := sectorbuilder.StoragePath{Path: c.Path, MaxBytes: c.MaxBytes, Weight: c.Weight}
+		for _, k := range c.Kinds {
+			p.Kinds = append(p.Kinds, sectorbuilder.PathKind(k))
+		}
+		paths = append(paths, p)
+	}
+	return paths, true
+}
+
+// selectSectorDirs chooses the directories the sector builder keeps each kind
+// of data in, by the free space of the storage paths, and creates them.
+func (node *Node) selectSectorDirs() (map[sectorbuilder.PathKind]string, error) {
+	paths, configured := node.storagePaths()
+	if !configured {
+		// the sector builder keeps its metadata with the staged sectors
+		node.sectorDirs = map[sectorbuilder.PathKind]string{
+			sectorbuilder.StagedPath: node.Repo.StagingDir(),
+			sectorbuilder.SealedPath: node.Repo.SealedDir(),
+			sectorbuilder.CachePath:  node.Repo.StagingDir(),
+		}
+		return node.sectorDirs, nil
+	}
+
+	dirs, err := sectorbuilder.SelectPaths(paths)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to choose sector storage paths")
+	}
+	for kind, dir := range dirs {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, errors.Wrapf(err, "failed to create %s directory %s", kind, dir)
+		}
+		log.Infof("keeping %s sector data in %s", kind, dir)
+	}
+	node.sectorDirs = dirs
+	return dirs, nil
+}
+
+// StoragePathUsage reports the utilization of the storage paths of the sector
+// builder, and which kinds of data it writes to each.
+func (node *Node) StoragePathUsage() ([]sectorbuilder.PathUsage, error) {
+	paths, _ := node.storagePaths()
+
+	var usages []sectorbuilder.PathUsage
+	for _, p := range paths {
+		u, err := p.Usage()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to measure storage path %s", p.Path)
+		}
+		for _, kind := range p.Kinds {
+			dir := node.sectorDirs[kind]
+			if dir != "" && (dir == p.Path || dir == p.Dir(kind)) {
+				u.InUse = append(u.InUse, kind)
+			}
+		}
+		usages = append(usages, u)
+	}
+	return usages, nil
+}
+
 func initStorageMinerForNode(ctx context.Context, node *Node) (*storage.Miner, error) {
 	minerAddr, err := node.MiningAddress()
 	if err != nil {
@@ -984,6 +1662,10 @@ func (node *Node) NewAddress() (address.Address, error) {
 // It will wait for the the actor to appear on-chain and add set the address to mining.minerAddress in the config.
 // TODO: This should live in a MinerAPI or some such. It's here until we have a proper API layer.
 func (node *Node) CreateMiner(ctx context.Context, accountAddr address.Address, gasPrice types.AttoFIL, gasLimit types.GasUnits, pledge uint64, pid libp2ppeer.ID, collateral *types.AttoFIL) (_ *address.Address, err error) {
+	if !node.Subsystems.Mining {
+		return nil, node.errDisabled("mining")
+	}
+
 	// Only create a miner if we don't already have one.
 	if _, err := node.MiningAddress(); err != ErrNoMinerAddress {
 		return nil, fmt.Errorf("can only have one miner per node")
@@ -1110,6 +1792,45 @@ func (node *Node) SectorBuilder() sectorbuilder.SectorBuilder {
 	return node.sectorBuilder
 }
 
+// SealingScheduler returns the scheduler of the jobs filling, sealing and
+// committing sectors.
+func (node *Node) SealingScheduler() *sectorbuilder.Scheduler {
+	return node.sealingScheduler
+}
+
+// SectorProgress returns the tracker of the sectors of the miner through
+// sealing.
+func (node *Node) SectorProgress() *sctr.Tracker {
+	return node.sectorProgress
+}
+
+// SectorDir returns the directory the sector builder keeps the given kind of
+// data in, empty if the node isn't mining.
+func (node *Node) SectorDir(kind sectorbuilder.PathKind) string {
+	return node.sectorDirs[kind]
+}
+
+// FetchSessions returns the bitswap sessions of the chain syncs and piece
+// transfers of the node.
+func (node *Node) FetchSessions() *filnet.FetchSessions {
+	return node.fetchSessions
+}
+
+// SealingMaster returns the master handing the sealing of pieces to remote
+// workers, nil if no workers are configured.
+func (node *Node) SealingMaster() *sealing.Master {
+	return node.sealingMaster
+}
+
+// SealedPiece returns the sector the storage miner sealed a piece in, and the
+// size of the piece.
+func (node *Node) SealedPiece(pieceRef cid.Cid) (uint64, uint64, error) {
+	if node.StorageMiner == nil {
+		return 0, 0, errors.New("mining disabled, no sealed pieces")
+	}
+	return node.StorageMiner.SealedPiece(pieceRef)
+}
+
 // BlockService returns the nodes blockservice.
 func (node *Node) BlockService() bserv.BlockService {
 	return node.blockservice
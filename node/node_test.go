@@ -133,6 +133,49 @@ func TestConnectsToBootstrapNodes(t *testing.T) {
 	})
 }
 
+func TestNodeListensOnQUIC(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	newRepo := func(t *testing.T) *repo.MemRepo {
+		r := repo.NewInMemoryRepo()
+		r.Config().Swarm.Address = "/ip4/127.0.0.1/tcp/0"
+		r.Config().Swarm.ListenAddresses = []string{"/ip4/127.0.0.1/udp/0/quic"}
+		require.NoError(t, Init(ctx, r, consensus.InitGenesis))
+		return r
+	}
+
+	t.Run("listens on QUIC and tcp addresses", func(t *testing.T) {
+		require := require.New(t)
+
+		opts, err := OptionsFromRepo(newRepo(t))
+		require.NoError(err)
+		nd, err := New(ctx, opts...)
+		require.NoError(err)
+		require.NoError(nd.Start(ctx))
+		defer nd.Stop(ctx)
+
+		var quic, tcp bool
+		for _, a := range nd.Host().Addrs() {
+			if config.IsQUICAddr(a) {
+				quic = true
+			} else {
+				tcp = true
+			}
+		}
+		assert.True(t, quic)
+		assert.True(t, tcp)
+	})
+
+	t.Run("private networks can't listen on QUIC", func(t *testing.T) {
+		r := newRepo(t)
+		r.SK = []byte("swarm key")
+
+		_, err := OptionsFromRepo(r)
+		assert.Error(t, err)
+	})
+}
+
 func TestNodeInit(t *testing.T) {
 	t.Parallel()
 	assert := assert.New(t)
@@ -163,11 +206,11 @@ func TestNodeStartMining(t *testing.T) {
 		SigGetter:    mthdsig.NewGetter(minerNode.ChainReader),
 		MsgPreviewer: msg.NewPreviewer(minerNode.Wallet, minerNode.ChainReader, minerNode.CborStore(), minerNode.Blockstore),
 		MsgQueryer:   msg.NewQueryer(minerNode.Repo, minerNode.Wallet, minerNode.ChainReader, minerNode.CborStore(), minerNode.Blockstore),
-		MsgSender:    msg.NewSender(minerNode.Repo, minerNode.Wallet, minerNode.ChainReader, minerNode.MsgPool, minerNode.PubSub.Publish),
+		MsgSender:    msg.NewSender(minerNode.Repo, minerNode.Wallet, msg.NewNonceTracker(minerNode.ChainReader, minerNode.MsgPool), minerNode.MsgPool, minerNode.PubSub.Publish),
 		MsgWaiter:    msg.NewWaiter(minerNode.ChainReader, minerNode.Blockstore, minerNode.CborStore()),
 		Config:       pbConfig.NewConfig(minerNode.Repo),
 		Chain:        chn.New(minerNode.ChainReader),
-		Network:      ntwk.NewNetwork(minerNode.Host()),
+		Network:      ntwk.NewNetwork(minerNode.Host(), minerNode.Router, minerNode.Ping),
 		Wallet:       wallet.New(walletBackend),
 	})
 	porcelainAPI := porcelain.New(plumbingAPI)
@@ -335,6 +378,150 @@ func TestUpdateMessagePool(t *testing.T) {
 	node.Stop(ctx)
 }
 
+func TestMessagePoolSurvivesRestart(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	node := MakeNodesUnstarted(t, 1, true, false)[0]
+	m := types.NewSignedMsgs(2, mockSigner)
+	core.MustAdd(node.MsgPool, m[0], m[1])
+	require.NoError(node.saveMessagePool())
+
+	// a restarted node starts with an empty pool
+	node.MsgPool = core.NewMessagePool()
+	require.NoError(node.loadMessagePool())
+
+	pending := node.MsgPool.Pending()
+	require.Len(pending, 2)
+	assert.True(types.SmsgCidsEqual(m[0], pending[0]) || types.SmsgCidsEqual(m[0], pending[1]))
+	assert.True(types.SmsgCidsEqual(m[1], pending[0]) || types.SmsgCidsEqual(m[1], pending[1]))
+}
+
+func TestNodeReadiness(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+
+	node := MakeOfflineNode(t)
+	require.NoError(node.Start(ctx))
+	defer node.Stop(ctx)
+
+	ready := func(checks []HealthCheck) bool {
+		for _, c := range checks {
+			if !c.OK {
+				return false
+			}
+		}
+		return true
+	}
+	assert.True(ready(node.Liveness()))
+	assert.True(ready(node.Readiness(1, 5)), "offline nodes need no peers")
+
+	// a peer announcing a head far ahead leaves the node unready
+	node.noteAnnouncedHeight(10)
+	checks := node.Readiness(1, 5)
+	assert.False(ready(checks))
+	assert.Equal("sync", checks[2].Name)
+	assert.Contains(checks[2].Message, "trails peers at 10")
+	assert.True(ready(node.Readiness(1, 10)))
+}
+
+func TestReloadConfig(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+
+	node := MakeOfflineNode(t)
+	require.NoError(node.Start(ctx))
+	defer node.Stop(ctx)
+
+	cfg, err := node.Repo.ReadConfig()
+	require.NoError(err)
+	cfg.Bootstrap.MinPeerThreshold = 3
+	cfg.Mining.MineDelay = "2s"
+	changed, err := node.ReloadConfig(cfg)
+	require.NoError(err)
+	assert.Equal([]string{"bootstrap.minPeerThreshold", "mining.mineDelay"}, changed)
+	assert.Equal(3, node.Bootstrapper.MinPeerThreshold)
+	_, mineDelay := node.MiningTimes()
+	assert.Equal(2*time.Second, mineDelay)
+
+	// nothing is applied along with a setting requiring a restart
+	cfg, err = node.Repo.ReadConfig()
+	require.NoError(err)
+	cfg.Bootstrap.MinPeerThreshold = 5
+	cfg.Datastore.Path = "elsewhere"
+	_, err = node.ReloadConfig(cfg)
+	assert.EqualError(err, "changing datastore.path requires restarting the daemon")
+	assert.Equal(3, node.Bootstrapper.MinPeerThreshold)
+	assert.Equal(3, node.Repo.Config().Bootstrap.MinPeerThreshold)
+
+	// nor is a config with levels that can't be set saved
+	cfg, err = node.Repo.ReadConfig()
+	require.NoError(err)
+	cfg.Log.Levels["nosuchsubsystem"] = "debug"
+	_, err = node.ReloadConfig(cfg)
+	assert.Error(err)
+	assert.NotContains(node.Repo.Config().Log.Levels, "nosuchsubsystem")
+}
+
+func TestReloadConfigOfStoppedNode(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+	ctx := context.Background()
+
+	node := MakeOfflineNode(t)
+	require.NoError(node.Start(ctx))
+	cfg, err := node.Repo.ReadConfig()
+	require.NoError(err)
+	node.Stop(ctx)
+
+	cfg.Bootstrap.MinPeerThreshold = 4
+	_, err = node.ReloadConfig(cfg)
+	assert.EqualError(t, err, "node is stopped")
+}
+
+func TestReloadConfigProtectsBootstrapPeers(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+
+	node := MakeOfflineNode(t)
+	require.NoError(node.Start(ctx))
+	defer node.Stop(ctx)
+
+	protected := func() map[string][]string {
+		tags := make(map[string][]string)
+		for _, pp := range node.ConnMgr.Protected() {
+			tags[pp.Peer.Pretty()] = pp.Tags
+		}
+		return tags
+	}
+
+	peerA := "Qmd6xrWYHsxivfakYRy6MszTpuAiEoFbgE1LWw4EvwBpp4"
+	peerB := "QmXq6XEYeEmUzBFuuKbVEGgxEpVD4xbSkG2Rhek6zkFMp4"
+
+	cfg, err := node.Repo.ReadConfig()
+	require.NoError(err)
+	cfg.Bootstrap.Addresses = []string{"/ip4/127.0.0.1/tcp/6000/ipfs/" + peerA}
+	_, err = node.ReloadConfig(cfg)
+	require.NoError(err)
+	assert.Equal([]string{"bootstrap"}, protected()[peerA])
+
+	// the peers no longer bootstrapped to lose their protection
+	cfg, err = node.Repo.ReadConfig()
+	require.NoError(err)
+	cfg.Bootstrap.Addresses = []string{"/ip4/127.0.0.1/tcp/6001/ipfs/" + peerB}
+	_, err = node.ReloadConfig(cfg)
+	require.NoError(err)
+	assert.NotContains(protected(), peerA)
+	assert.Equal([]string{"bootstrap"}, protected()[peerB])
+}
+
 func TestOptionWithError(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
@@ -425,3 +612,68 @@ func TestNodeConfig(t *testing.T) {
 		Address: "/ip4/0.0.0.0/tcp/0",
 	}, cfg.Swarm)
 }
+
+func TestNodeRoles(t *testing.T) {
+	t.Parallel()
+
+	withRole := func(role string) ConfigOpt {
+		return func(c *Config) error {
+			c.Repo.Config().Role = role
+			return nil
+		}
+	}
+
+	makeNode := func(t *testing.T, role string) *Node {
+		return GenNode(t, &TestNodeOptions{
+			ConfigOpts:  []ConfigOpt{withRole(role)},
+			OfflineMode: true,
+			GenesisFunc: consensus.InitGenesis,
+		})
+	}
+
+	t.Run("miner nodes run all the subsystems", func(t *testing.T) {
+		assert := assert.New(t)
+
+		n := makeNode(t, config.RoleMiner)
+		assert.Equal(Subsystems{Wallet: true, Mining: true}, n.Subsystems)
+		assert.Len(n.Wallet.Backends(wallet.DSBackendType), 1)
+	})
+
+	t.Run("client nodes neither mine nor seal", func(t *testing.T) {
+		assert := assert.New(t)
+		ctx := context.Background()
+
+		n := makeNode(t, config.RoleClient)
+		assert.Equal(Subsystems{Wallet: true}, n.Subsystems)
+		assert.EqualError(n.StartMining(ctx), "mining is disabled for nodes in the client role")
+		assert.EqualError(n.StartSealingWorker(ctx, "/ip4/127.0.0.1/tcp/6000", 1), "sealing is disabled for nodes in the client role")
+	})
+
+	t.Run("gateway nodes have no wallet", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+		ctx := context.Background()
+
+		n := makeNode(t, config.RoleGateway)
+		assert.Equal(Subsystems{}, n.Subsystems)
+		assert.Empty(n.Wallet.Backends(wallet.DSBackendType))
+		assert.Error(n.StartMining(ctx))
+
+		require.NoError(n.Start(ctx))
+		defer n.Stop(ctx)
+		assert.NotNil(n.ChainReader.Head())
+		assert.Nil(n.RetrievalMiner)
+	})
+
+	t.Run("unknown roles fail the build", func(t *testing.T) {
+		assert := assert.New(t)
+
+		r := repo.NewInMemoryRepo()
+		r.Config().Role = "relay"
+		_, err := New(context.Background(), func(c *Config) error {
+			c.Repo = r
+			return nil
+		})
+		assert.EqualError(err, `unknown node role "relay"`)
+	})
+}
@@ -0,0 +1,111 @@
+package node
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	pstore "gx/ipfs/QmPiemjiKBC9VA7vZF82m4x1oygtg2c2YVqag8PX7dN1BD/go-libp2p-peerstore"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+
+	"github.com/filecoin-project/go-filecoin/config"
+	"github.com/filecoin-project/go-filecoin/filnet"
+	"github.com/filecoin-project/go-filecoin/logs"
+)
+
+// ReloadConfig applies the settings cfg changes, which must all be listed
+// in config.Reloadable, to the running node, and makes cfg the config of
+// its repo. It returns the dotted keys of the settings changed. Nothing is
+// changed if cfg changes a setting that requires a restart.
+//
+// The settings of the api server are left to the caller, which owns it. The
+// config of a stopped node can't be reloaded.
+func (node *Node) ReloadConfig(cfg *config.Config) ([]string, error) {
+	node.reloadLk.Lock()
+	defer node.reloadLk.Unlock()
+
+	if node.stopped {
+		return nil, errors.New("node is stopped")
+	}
+
+	changed, err := node.Repo.Config().ReloadChanges(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// build what can fail before changing anything
+	var bootstrapper *filnet.Bootstrapper
+	var bpi []pstore.PeerInfo
+	if changedUnder(changed, "bootstrap") {
+		if bootstrapper, bpi, err = node.newBootstrapper(cfg.Bootstrap); err != nil {
+			return nil, err
+		}
+	}
+	if changedUnder(changed, "log") {
+		if err := logs.ValidateLevels(cfg.Log.Levels); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := node.Repo.ReplaceConfig(cfg); err != nil {
+		return nil, errors.Wrap(err, "failed to save the config")
+	}
+
+	if changedUnder(changed, "log") {
+		if err := logs.SetLevels(cfg.Log.Levels); err != nil {
+			return nil, err
+		}
+	}
+
+	if bootstrapper != nil {
+		node.Bootstrapper.Stop()
+		node.Bootstrapper = bootstrapper
+		node.protectBootstrapPeers(bpi)
+		if !node.OfflineMode {
+			node.Bootstrapper.Start(context.Background())
+		}
+	}
+	if changedUnder(changed, "mining.mineDelay") && node.MiningScheduler != nil {
+		_, mineDelay := node.MiningTimes()
+		node.MiningScheduler.SetMineDelay(mineDelay)
+	}
+
+	return changed, nil
+}
+
+// newBootstrapper returns a bootstrapper keeping the node connected to the
+// bootstrap peers of cfg, and the peers.
+func (node *Node) newBootstrapper(cfg *config.BootstrapConfig) (*filnet.Bootstrapper, []pstore.PeerInfo, error) {
+	period, err := time.ParseDuration(cfg.Period)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "couldn't parse bootstrap period %s", cfg.Period)
+	}
+	bpi, err := filnet.PeerAddrsToPeerInfos(cfg.Addresses)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "couldn't parse bootstrap addresses [%s]", cfg.Addresses)
+	}
+	return filnet.NewBootstrapper(bpi, node.Host(), node.Host().Network(), node.Router, cfg.MinPeerThreshold, period), bpi, nil
+}
+
+// protectBootstrapPeers makes bpi the bootstrap peers the connection manager
+// protects from being trimmed, in place of the previous ones.
+func (node *Node) protectBootstrapPeers(bpi []pstore.PeerInfo) {
+	for _, pi := range node.bootstrapPeers {
+		node.ConnMgr.Unprotect(pi.ID, "bootstrap")
+	}
+	for _, pi := range bpi {
+		node.ConnMgr.Protect(pi.ID, "bootstrap")
+	}
+	node.bootstrapPeers = bpi
+}
+
+// changedUnder returns whether one of the dotted keys changed is key or a
+// key under it.
+func changedUnder(changed []string, key string) bool {
+	for _, c := range changed {
+		if c == key || strings.HasPrefix(c, key+".") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package node
+
+import (
+	"fmt"
+
+	"github.com/filecoin-project/go-filecoin/config"
+)
+
+// Subsystems are the optional subsystems of a node, which its role enables.
+// Every role syncs, validates and serves the chain, and serves the api.
+type Subsystems struct {
+	// Wallet holds the keys of the node and signs its messages, deals and
+	// payments.
+	Wallet bool
+	// Mining is mining blocks, sealing sectors and the storage and
+	// retrieval miners serving deals to clients.
+	Mining bool
+}
+
+// roleSubsystems maps the roles of config.Config.Role to their subsystems.
+var roleSubsystems = map[string]Subsystems{
+	config.RoleMiner:   {Wallet: true, Mining: true},
+	config.RoleClient:  {Wallet: true},
+	config.RoleGateway: {},
+}
+
+// SubsystemsOf returns the subsystems a node in the given role runs. Configs
+// written before roles existed have no role and run all of them.
+func SubsystemsOf(role string) (Subsystems, error) {
+	if role == "" {
+		role = config.RoleMiner
+	}
+	subsystems, ok := roleSubsystems[role]
+	if !ok {
+		return Subsystems{}, fmt.Errorf("unknown node role %q", role)
+	}
+	return subsystems, nil
+}
+
+// errDisabled returns the error of using a subsystem the role of the node
+// doesn't run.
+func (node *Node) errDisabled(subsystem string) error {
+	return fmt.Errorf("%s is disabled for nodes in the %s role", subsystem, node.Role)
+}
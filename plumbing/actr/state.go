@@ -0,0 +1,79 @@
+package actr
+
+import (
+	"context"
+	"encoding/json"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	cbor "gx/ipfs/QmRoARq3nkUb13HSKZGepCZSWe5GrVPwx7xURJGZ7KWv9V/go-ipld-cbor"
+	"gx/ipfs/QmS2aqUZLJp8kF1ihE5rvDGE5LvmKDPnx32w9Z1BW9xLV5/go-ipfs-blockstore"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/state"
+)
+
+// ChainReadStore is the subset of chain.ReadStore that StateDecoder needs.
+type ChainReadStore interface {
+	LatestState(ctx context.Context) (state.Tree, error)
+}
+
+// StateDecoder decodes the state of actors into values that can be
+// presented to users as JSON.
+type StateDecoder struct {
+	chainReader ChainReadStore
+	blockstore  blockstore.Blockstore
+	schemas     map[cid.Cid]func() interface{}
+}
+
+// NewStateDecoder returns a new StateDecoder. schemas maps actor code to a
+// constructor of the type the state of actors with that code decodes into.
+func NewStateDecoder(chainReader ChainReadStore, bs blockstore.Blockstore, schemas map[cid.Cid]func() interface{}) *StateDecoder {
+	return &StateDecoder{
+		chainReader: chainReader,
+		blockstore:  bs,
+		schemas:     schemas,
+	}
+}
+
+// Decode returns the state of the actor at addr in the state of the chain
+// head. The state of actors with a schema is decoded into their state type,
+// other state is returned in its generic IPLD JSON representation. Actors
+// without state, e.g. accounts, have a nil state.
+func (sd *StateDecoder) Decode(ctx context.Context, addr address.Address) (interface{}, error) {
+	st, err := sd.chainReader.LatestState(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldnt get current state tree")
+	}
+
+	act, err := st.GetActor(ctx, addr)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get actor")
+	}
+	if !act.Head.Defined() {
+		return nil, nil
+	}
+
+	blk, err := sd.blockstore.Get(act.Head)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read actor state %s", act.Head)
+	}
+
+	if newState, ok := sd.schemas[act.Code]; ok {
+		out := newState()
+		if err := cbor.DecodeInto(blk.RawData(), out); err != nil {
+			return nil, errors.Wrap(err, "failed to decode actor state")
+		}
+		return out, nil
+	}
+
+	nd, err := cbor.DecodeBlock(blk)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode actor state")
+	}
+	raw, err := nd.MarshalJSON()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode actor state")
+	}
+	return json.RawMessage(raw), nil
+}
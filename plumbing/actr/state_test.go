@@ -0,0 +1,84 @@
+package actr_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	hamt "gx/ipfs/QmRXf2uUSdGSunRJsM9wXSUNVwLUGCY3So5fAs7h2CBJVf/go-hamt-ipld"
+	blockstore "gx/ipfs/QmS2aqUZLJp8kF1ihE5rvDGE5LvmKDPnx32w9Z1BW9xLV5/go-ipfs-blockstore"
+	datastore "gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/actor/builtin"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/miner"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/plumbing/actr"
+	"github.com/filecoin-project/go-filecoin/state"
+	th "github.com/filecoin-project/go-filecoin/testhelpers"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/vm"
+)
+
+type fakeChainReadStore struct {
+	st state.Tree
+}
+
+func (f *fakeChainReadStore) LatestState(ctx context.Context) (state.Tree, error) {
+	return f.st, nil
+}
+
+func TestStateDecoder(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ctx := context.Background()
+	cst := hamt.NewCborStore()
+	bs := blockstore.NewBlockstore(datastore.NewMapDatastore())
+	vms := vm.NewStorageMap(bs)
+
+	addrGetter := address.NewForTestGetter()
+	minerAddr, owner, fakeAddr, acctAddr := addrGetter(), addrGetter(), addrGetter(), addrGetter()
+	fakeCode := types.NewCidForTestGetter()()
+
+	_, st := th.RequireMakeStateTree(require, cst, map[address.Address]*actor.Actor{
+		minerAddr: th.RequireNewMinerActor(require, vms, minerAddr, owner, []byte("key"), 10, th.RequireRandomPeerID(), types.NewAttoFILFromFIL(1)),
+		fakeAddr:  th.RequireNewFakeActor(require, vms, fakeAddr, fakeCode),
+		acctAddr:  th.RequireNewAccountActor(require, types.NewAttoFILFromFIL(1)),
+	})
+	decoder := actr.NewStateDecoder(&fakeChainReadStore{st}, bs, builtin.StateSchemas)
+
+	t.Run("decodes state with a schema into its type", func(t *testing.T) {
+		decoded, err := decoder.Decode(ctx, minerAddr)
+		require.NoError(err)
+		minerState, ok := decoded.(*miner.State)
+		require.True(ok)
+		assert.Equal(owner, minerState.Owner)
+	})
+
+	t.Run("decodes state without a schema generically", func(t *testing.T) {
+		decoded, err := decoder.Decode(ctx, fakeAddr)
+		require.NoError(err)
+		raw, ok := decoded.(json.RawMessage)
+		require.True(ok)
+
+		var generic map[string]interface{}
+		require.NoError(json.Unmarshal(raw, &generic))
+		assert.Contains(generic, "Changed")
+	})
+
+	t.Run("actors without state have nil state", func(t *testing.T) {
+		decoded, err := decoder.Decode(ctx, acctAddr)
+		require.NoError(err)
+		assert.Nil(decoded)
+	})
+
+	t.Run("fails for unknown actors", func(t *testing.T) {
+		_, err := decoder.Decode(ctx, addrGetter())
+		assert.Error(err)
+	})
+}
@@ -2,19 +2,30 @@ package plumbing
 
 import (
 	"context"
+	"io"
+	"time"
 
+	inet "gx/ipfs/QmNgLg1NTw37iWbYPKcyK85YJ9Whs1MkPtJwhfqbNYAyKg/go-libp2p-net"
+	pstore "gx/ipfs/QmPiemjiKBC9VA7vZF82m4x1oygtg2c2YVqag8PX7dN1BD/go-libp2p-peerstore"
 	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
 	"gx/ipfs/QmY5Grm8pJdiSSVsYxx4uNRgweY72EmYwuSDbRnbFok3iY/go-libp2p-peer"
 	logging "gx/ipfs/QmcuXC5cxs79ro2cUuHs4HQ2bkDLJUYokwL8aivcX6HW3C/go-log"
 
+	"github.com/filecoin-project/go-filecoin/abi"
+	"github.com/filecoin-project/go-filecoin/actor"
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/core"
 	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/plumbing/actr"
 	"github.com/filecoin-project/go-filecoin/plumbing/cfg"
 	"github.com/filecoin-project/go-filecoin/plumbing/chn"
+	"github.com/filecoin-project/go-filecoin/plumbing/mgrt"
 	"github.com/filecoin-project/go-filecoin/plumbing/msg"
 	"github.com/filecoin-project/go-filecoin/plumbing/mthdsig"
 	"github.com/filecoin-project/go-filecoin/plumbing/ntwk"
+	"github.com/filecoin-project/go-filecoin/plumbing/sctr"
+	"github.com/filecoin-project/go-filecoin/proofs"
+	"github.com/filecoin-project/go-filecoin/state"
 	"github.com/filecoin-project/go-filecoin/types"
 	"github.com/filecoin-project/go-filecoin/wallet"
 )
@@ -24,31 +35,52 @@ import (
 // depend on the higher level porcelain.API instead of this api, as it includes
 // these calls in addition to higher level convenience calls to make them more
 // ergonomic.
+//
+// The calls which wait on the chain, the network or the proofs take a
+// context, and return once it is done. The ones answering from the state the
+// node holds in memory, e.g. the address book, the config or the progress of
+// the sectors, return without waiting and take none. So do the calls of the
+// wallet, although signing with a remote signer waits on it: those requests
+// are bounded by the timeout and retries of the remote signer config instead.
 type API struct {
 	logger logging.EventLogger
 
+	actorState   *actr.StateDecoder
+	addressBook  *wallet.AddressBook
 	chain        *chn.Reader
 	config       *cfg.Config
 	messagePool  *core.MessagePool
+	migrator     *mgrt.Migrator
+	msgIndex     *msg.Index
 	msgPreviewer *msg.Previewer
 	msgQueryer   *msg.Queryer
+	msgReplayer  *msg.Replayer
 	msgSender    *msg.Sender
 	msgWaiter    *msg.Waiter
 	network      *ntwk.Network
+	resources    proofs.Resources
+	sectors      *sctr.Tracker
 	sigGetter    *mthdsig.Getter
 	wallet       *wallet.Wallet
 }
 
 // APIDeps contains all the API's dependencies
 type APIDeps struct {
+	ActorState   *actr.StateDecoder
+	AddressBook  *wallet.AddressBook
 	Chain        *chn.Reader
 	Config       *cfg.Config
 	MessagePool  *core.MessagePool
+	Migrator     *mgrt.Migrator
+	MsgIndex     *msg.Index
 	MsgPreviewer *msg.Previewer
 	MsgQueryer   *msg.Queryer
+	MsgReplayer  *msg.Replayer
 	MsgSender    *msg.Sender
 	MsgWaiter    *msg.Waiter
 	Network      *ntwk.Network
+	Resources    proofs.Resources
+	Sectors      *sctr.Tracker
 	SigGetter    *mthdsig.Getter
 	Wallet       *wallet.Wallet
 }
@@ -58,19 +90,37 @@ func New(deps *APIDeps) *API {
 	return &API{
 		logger: logging.Logger("porcelain"),
 
+		actorState:   deps.ActorState,
+		addressBook:  deps.AddressBook,
 		chain:        deps.Chain,
 		config:       deps.Config,
 		messagePool:  deps.MessagePool,
+		migrator:     deps.Migrator,
+		msgIndex:     deps.MsgIndex,
 		msgPreviewer: deps.MsgPreviewer,
 		msgQueryer:   deps.MsgQueryer,
+		msgReplayer:  deps.MsgReplayer,
 		msgSender:    deps.MsgSender,
 		msgWaiter:    deps.MsgWaiter,
 		network:      deps.Network,
+		resources:    deps.Resources,
+		sectors:      deps.Sectors,
 		sigGetter:    deps.SigGetter,
 		wallet:       deps.Wallet,
 	}
 }
 
+// ActorGet returns an actor in the state of the chain head.
+func (api *API) ActorGet(ctx context.Context, addr address.Address) (*actor.Actor, error) {
+	return api.chain.ActorGet(ctx, addr)
+}
+
+// ActorGetStateDecoded returns the state of an actor in the state of the chain
+// head, decoded into a value that can be marshalled to JSON.
+func (api *API) ActorGetStateDecoded(ctx context.Context, addr address.Address) (interface{}, error) {
+	return api.actorState.Decode(ctx, addr)
+}
+
 // ActorGetSignature returns the signature of the given actor's given method.
 // The function signature is typically used to enable a caller to decode the
 // output of an actor method call (message).
@@ -78,6 +128,28 @@ func (api *API) ActorGetSignature(ctx context.Context, actorAddr address.Address
 	return api.sigGetter.Get(ctx, actorAddr, method)
 }
 
+// AddressBookLabel gives an address a human readable name. Relabelling a
+// name to a different address fails unless force is set.
+func (api *API) AddressBookLabel(addr address.Address, name string, force bool) error {
+	return api.addressBook.Label(addr, name, force)
+}
+
+// AddressBookUnlabel removes a name from the address book.
+func (api *API) AddressBookUnlabel(name string) error {
+	return api.addressBook.Unlabel(name)
+}
+
+// AddressBookResolve returns the address s refers to, either because it is
+// an address or because it is the label of one.
+func (api *API) AddressBookResolve(s string) (address.Address, error) {
+	return api.addressBook.Resolve(s)
+}
+
+// AddressBookLabels lists the entries of the address book.
+func (api *API) AddressBookLabels() ([]wallet.AddressLabel, error) {
+	return api.addressBook.Labels()
+}
+
 // ConfigSet sets the given parameters at the given path in the local config.
 // The given path may be either a single field name, or a dotted path to a field.
 // The JSON value may be either a single value or a whole data structure to be replace.
@@ -99,27 +171,83 @@ func (api *API) ChainHead(ctx context.Context) types.TipSet {
 	return api.chain.Head(ctx)
 }
 
+// ChainHeadEvents returns a channel receiving each new head of the chain
+// until ctx is done.
+func (api *API) ChainHeadEvents(ctx context.Context) <-chan types.TipSet {
+	return api.chain.HeadEvents(ctx)
+}
+
 // ChainLs returns a channel of tipsets from head to genesis
 func (api *API) ChainLs(ctx context.Context) <-chan interface{} {
 	return api.chain.Ls(ctx)
 }
 
+// ChainLsFrom returns a channel of tipsets from the tipset with the cids of
+// start to genesis
+func (api *API) ChainLsFrom(ctx context.Context, start types.SortedCidSet) <-chan interface{} {
+	return api.chain.LsFrom(ctx, start)
+}
+
+// ChainStateDiff returns the actors that differ between the states of two
+// tipsets.
+func (api *API) ChainStateDiff(ctx context.Context, tsA, tsB types.SortedCidSet) ([]*state.ActorDiff, error) {
+	return api.chain.StateDiff(ctx, tsA, tsB)
+}
+
+// StateMigrateDryRun migrates the state of the chain head for the named
+// protocol upgrade without persisting it, and returns the actors the
+// migration changes.
+func (api *API) StateMigrateDryRun(ctx context.Context, upgrade string) ([]*state.ActorDiff, error) {
+	return api.migrator.DryRun(ctx, upgrade)
+}
+
 // BlockGet gets a block by CID
 func (api *API) BlockGet(ctx context.Context, id cid.Cid) (*types.Block, error) {
 	return api.chain.BlockGet(ctx, id)
 }
 
+// MessageHistory returns the messages on chain sent from or to addr, newest
+// first.
+func (api *API) MessageHistory(ctx context.Context, addr address.Address) ([]*msg.IndexEntry, error) {
+	return api.msgIndex.Messages(ctx, addr)
+}
+
+// MessageReceipts returns the receipts of the messages of a tipset by message
+// cid. Messages that failed because they conflict with another message of the
+// tipset have no receipt.
+func (api *API) MessageReceipts(ctx context.Context, ts types.TipSet) (map[cid.Cid]*types.MessageReceipt, error) {
+	return api.msgWaiter.Receipts(ctx, ts)
+}
+
 // MessagePoolRemove removes a message from the message pool
 func (api *API) MessagePoolRemove(cid cid.Cid) {
 	api.messagePool.Remove(cid)
 }
 
+// MessagePoolStats returns the message pool's counters and gauges.
+func (api *API) MessagePoolStats() core.MessagePoolStats {
+	return api.messagePool.Stats()
+}
+
+// MessagePoolSubscribe returns a channel of events describing every change
+// to the message pool. The channel is closed when the context is done.
+func (api *API) MessagePoolSubscribe(ctx context.Context) <-chan core.MessagePoolEvent {
+	return api.messagePool.Subscribe(ctx)
+}
+
 // MessagePreview previews the Gas cost of a message by running it locally on the client and
 // recording the amount of Gas used.
 func (api *API) MessagePreview(ctx context.Context, from, to address.Address, method string, params ...interface{}) (types.GasUnits, error) {
 	return api.msgPreviewer.Preview(ctx, from, to, method, params...)
 }
 
+// MessageCall calls an actor's method read-only using the chain state at the
+// given height, or the most recent chain state if optHeight is nil, and returns
+// the decoded return values. The from address is optional, see MessageQuery.
+func (api *API) MessageCall(ctx context.Context, optFrom, to address.Address, method string, optHeight *types.BlockHeight, params ...interface{}) ([]*abi.Value, error) {
+	return api.msgQueryer.Call(ctx, optFrom, to, method, optHeight, params...)
+}
+
 // MessageQuery calls an actor's method using the most recent chain state. It is read-only,
 // it does not change any state. It is use to interrogate actor state. The from address
 // is optional; if not provided, an address will be chosen from the node's wallet.
@@ -127,6 +255,12 @@ func (api *API) MessageQuery(ctx context.Context, optFrom, to address.Address, m
 	return api.msgQueryer.Query(ctx, optFrom, to, method, params...)
 }
 
+// MessageReplay re-executes a message that is on chain over the state it was
+// applied to, tracing its execution. Nothing is persisted.
+func (api *API) MessageReplay(ctx context.Context, msgCid cid.Cid) (*msg.Replay, error) {
+	return api.msgReplayer.Replay(ctx, msgCid)
+}
+
 // MessageSend sends a message. It uses the default from address if none is given and signs the
 // message using the wallet. This call "sends" in the sense that it enqueues the
 // message in the msg pool and broadcasts it to the network; it does not wait for the
@@ -136,6 +270,26 @@ func (api *API) MessageSend(ctx context.Context, from, to address.Address, value
 	return api.msgSender.Send(ctx, from, to, value, gasPrice, gasLimit, method, params...)
 }
 
+// MessageSendBatch sends several messages from the same address, assigning
+// them consecutive nonces. The messages are enqueued in the msg pool
+// atomically, either all or none, and then broadcast to the network.
+func (api *API) MessageSendBatch(ctx context.Context, from address.Address, batch []msg.BatchMessage) ([]cid.Cid, error) {
+	return api.msgSender.SendBatch(ctx, from, batch)
+}
+
+// MessageCompose builds an unsigned message, e.g. to be signed offline and
+// later sent with MessagePublish. The next nonce of the from address is used
+// unless one is given; it is not reserved.
+func (api *API) MessageCompose(ctx context.Context, from, to address.Address, nonce *uint64, value *types.AttoFIL, gasPrice types.AttoFIL, gasLimit types.GasUnits, method string, params ...interface{}) (*types.MeteredMessage, error) {
+	return api.msgSender.Compose(ctx, from, to, nonce, value, gasPrice, gasLimit, method, params...)
+}
+
+// MessagePublish enqueues a message signed elsewhere in the msg pool and
+// broadcasts it to the network.
+func (api *API) MessagePublish(ctx context.Context, smsg *types.SignedMessage) (cid.Cid, error) {
+	return api.msgSender.Publish(ctx, smsg)
+}
+
 // MessageWait invokes the callback when a message with the given cid appears on chain.
 // It will find the message in both the case that it is already on chain and
 // the case that it appears in a newly mined block. An error is returned if one is
@@ -150,6 +304,82 @@ func (api *API) NetworkGetPeerID() peer.ID {
 	return api.network.GetPeerID()
 }
 
+// NetworkConnectedness returns whether the node is connected to the peer.
+func (api *API) NetworkConnectedness(p peer.ID) inet.Connectedness {
+	return api.network.Connectedness(p)
+}
+
+// NetworkFindPeer finds the addresses of the peer.
+func (api *API) NetworkFindPeer(ctx context.Context, p peer.ID) (pstore.PeerInfo, error) {
+	return api.network.FindPeer(ctx, p)
+}
+
+// NetworkLatencies returns the latencies to the peers the node is connected
+// to.
+func (api *API) NetworkLatencies() []ntwk.PeerLatency {
+	return api.network.Latencies()
+}
+
+// NetworkPing sends a ping to the peer, returning the round trip time.
+func (api *API) NetworkPing(ctx context.Context, p peer.ID) (time.Duration, error) {
+	return api.network.Ping(ctx, p)
+}
+
+// NetworkFindMiner finds the peer of the miner in the DHT, for miners whose
+// peer isn't known from the chain.
+func (api *API) NetworkFindMiner(ctx context.Context, minerAddr address.Address) (pstore.PeerInfo, error) {
+	return api.network.FindMiner(ctx, minerAddr)
+}
+
+// NetworkProvideMiner announces in the DHT that this node is the peer of the
+// miner.
+func (api *API) NetworkProvideMiner(ctx context.Context, minerAddr address.Address) error {
+	return api.network.ProvideMiner(ctx, minerAddr)
+}
+
+// PieceCommitment computes the piece commitment of the data read from r
+// locally, along with the size of the data and the size it is padded to in a
+// sector. Reading r stops once ctx is done.
+func (api *API) PieceCommitment(ctx context.Context, r io.Reader) (proofs.CommP, uint64, uint64, error) {
+	return proofs.GeneratePieceCommitment(&ctxReader{ctx: ctx, r: r})
+}
+
+// ctxReader is a reader failing with the error of ctx once it is done.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr *ctxReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
+
+// ProofsResources returns the hardware the proofs backend detected and how
+// much of it it uses.
+func (api *API) ProofsResources() proofs.Resources {
+	return api.resources
+}
+
+// SectorProgress returns the sealing progress of the sector with the given id.
+func (api *API) SectorProgress(sectorID uint64) (*sctr.Progress, error) {
+	return api.sectors.Progress(sectorID)
+}
+
+// SectorProgressList returns the sealing progress of all sectors this node
+// saw, by sector id.
+func (api *API) SectorProgressList() []*sctr.Progress {
+	return api.sectors.List()
+}
+
+// SectorEvents returns a channel of events describing every change of stage
+// of a sector. The channel is closed when the context is done.
+func (api *API) SectorEvents(ctx context.Context) <-chan sctr.SectorEvent {
+	return api.sectors.Subscribe(ctx)
+}
+
 // SignBytes uses private key information associated with the given address to sign the given bytes.
 func (api *API) SignBytes(data []byte, addr address.Address) (types.Signature, error) {
 	return api.wallet.SignBytes(data, addr)
@@ -169,3 +399,22 @@ func (api *API) WalletFind(address address.Address) (wallet.Backend, error) {
 func (api *API) WalletNewAddress() (address.Address, error) {
 	return wallet.NewAddress(api.wallet)
 }
+
+// WalletNewAddressOfType generates a new wallet address backed by a key of
+// the given type
+func (api *API) WalletNewAddressOfType(keyType string) (address.Address, error) {
+	return wallet.NewAddressOfType(api.wallet, keyType)
+}
+
+// WalletNewHDAddress derives a new wallet address from the wallet's HD seed,
+// creating the seed if needed. The seed's mnemonic is returned only when it
+// was created by this call.
+func (api *API) WalletNewHDAddress() (address.Address, string, error) {
+	return wallet.NewHDAddress(api.wallet)
+}
+
+// WalletRecoverHD restores the wallet's HD seed from a mnemonic and derives
+// its first n addresses.
+func (api *API) WalletRecoverHD(mnemonic string, n uint32) ([]address.Address, error) {
+	return wallet.RecoverHD(api.wallet, mnemonic, n)
+}
@@ -4,7 +4,13 @@ import (
 	"context"
 
 	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	"gx/ipfs/QmdbxjQWogRCHRaxhhGnYdT1oQJzL9GdqSKzCdqWr85AP2/pubsub"
 
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/chain"
+	"github.com/filecoin-project/go-filecoin/state"
 	"github.com/filecoin-project/go-filecoin/types"
 )
 
@@ -13,6 +19,9 @@ type ChainReader interface {
 	BlockHistory(ctx context.Context, ts types.TipSet) <-chan interface{}
 	GetBlock(ctx context.Context, id cid.Cid) (*types.Block, error)
 	Head() types.TipSet
+	HeadEvents() *pubsub.PubSub
+	LatestState(ctx context.Context) (state.Tree, error)
+	GetTipSetState(ctx context.Context, tsKey string) (state.Tree, error)
 }
 
 // Reader is plumbing implementation for inspecting the blockchain
@@ -37,7 +46,98 @@ func (c *Reader) Ls(ctx context.Context) <-chan interface{} {
 	return c.chainReader.BlockHistory(ctx, c.chainReader.Head())
 }
 
+// LsFrom returns a channel of historical tip sets from the tipset with the
+// cids of start to genesis. An error is sent if it can't be read.
+func (c *Reader) LsFrom(ctx context.Context, start types.SortedCidSet) <-chan interface{} {
+	ts, err := c.TipSetGet(ctx, start)
+	if err != nil {
+		out := make(chan interface{}, 1)
+		out <- err
+		close(out)
+		return out
+	}
+	return c.chainReader.BlockHistory(ctx, ts)
+}
+
+// TipSetGet returns the tipset made of the blocks with the cids of key.
+func (c *Reader) TipSetGet(ctx context.Context, key types.SortedCidSet) (types.TipSet, error) {
+	var blocks []*types.Block
+	for it := key.Iter(); !it.Complete(); it.Next() {
+		blk, err := c.chainReader.GetBlock(ctx, it.Value())
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get block %s", it.Value())
+		}
+		blocks = append(blocks, blk)
+	}
+	return types.NewTipSet(blocks...)
+}
+
 // BlockGet returns a block by its CID
 func (c *Reader) BlockGet(ctx context.Context, id cid.Cid) (*types.Block, error) {
 	return c.chainReader.GetBlock(ctx, id)
 }
+
+// HeadEvents returns a channel receiving each new head of the chain until
+// ctx is done.
+func (c *Reader) HeadEvents(ctx context.Context) <-chan types.TipSet {
+	sub := c.chainReader.HeadEvents().Sub(chain.NewHeadTopic)
+	out := make(chan types.TipSet)
+
+	go func() {
+		defer close(out)
+		defer func() {
+			// Keep draining so the pubsub never blocks on us while
+			// processing the unsubscription.
+			go func() {
+				for range sub {
+				}
+			}()
+			c.chainReader.HeadEvents().Unsub(sub, chain.NewHeadTopic)
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case raw, more := <-sub:
+				if !more {
+					return
+				}
+				ts, ok := raw.(types.TipSet)
+				if !ok {
+					continue
+				}
+				select {
+				case out <- ts:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out
+}
+
+// ActorGet returns the actor at addr in the state of the chain head.
+func (c *Reader) ActorGet(ctx context.Context, addr address.Address) (*actor.Actor, error) {
+	st, err := c.chainReader.LatestState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return st.GetActor(ctx, addr)
+}
+
+// StateDiff returns the actors that differ between the states of the tipsets
+// with keys tsA and tsB.
+func (c *Reader) StateDiff(ctx context.Context, tsA, tsB types.SortedCidSet) ([]*state.ActorDiff, error) {
+	a, err := c.chainReader.GetTipSetState(ctx, tsA.String())
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get state of tipset %s", tsA)
+	}
+	b, err := c.chainReader.GetTipSetState(ctx, tsB.String())
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get state of tipset %s", tsB)
+	}
+	return state.Diff(ctx, a, b)
+}
@@ -5,7 +5,9 @@ import (
 	"testing"
 
 	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	"gx/ipfs/QmdbxjQWogRCHRaxhhGnYdT1oQJzL9GdqSKzCdqWr85AP2/pubsub"
 
+	"github.com/filecoin-project/go-filecoin/state"
 	"github.com/filecoin-project/go-filecoin/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -46,6 +48,18 @@ func (mcr *FakeChainer) Head() types.TipSet {
 	return mcr.head
 }
 
+func (mcr *FakeChainer) HeadEvents() *pubsub.PubSub {
+	return nil
+}
+
+func (mcr *FakeChainer) LatestState(ctx context.Context) (state.Tree, error) {
+	return nil, errors.New("no state")
+}
+
+func (mcr *FakeChainer) GetTipSetState(ctx context.Context, tsKey string) (state.Tree, error) {
+	return nil, errors.New("no state")
+}
+
 func TestChainLs(t *testing.T) {
 	t.Parallel()
 	t.Run("Head returns chain head", func(t *testing.T) {
@@ -102,4 +116,21 @@ func TestChainLs(t *testing.T) {
 		assert.NoError(err)
 		assert.True(found.Equals(blk))
 	})
+	t.Run("TipSetGet returns the tipset of a key", func(t *testing.T) {
+		t.Parallel()
+		assert := assert.New(t)
+		require := require.New(t)
+
+		blk := types.NewBlockForTest(nil, 1)
+		chainAPI := New(&FakeChainer{
+			blocks: map[cid.Cid]*types.Block{blk.Cid(): blk},
+		})
+
+		_, err := chainAPI.TipSetGet(context.Background(), types.NewSortedCidSet(types.SomeCid()))
+		assert.Error(err)
+
+		ts, err := chainAPI.TipSetGet(context.Background(), types.NewSortedCidSet(blk.Cid()))
+		require.NoError(err)
+		assert.True(ts.ToSortedCidSet().Equals(types.NewSortedCidSet(blk.Cid())))
+	})
 }
@@ -0,0 +1,68 @@
+package mgrt
+
+import (
+	"context"
+	"fmt"
+
+	"gx/ipfs/QmS2aqUZLJp8kF1ihE5rvDGE5LvmKDPnx32w9Z1BW9xLV5/go-ipfs-blockstore"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+
+	"github.com/filecoin-project/go-filecoin/migration"
+	"github.com/filecoin-project/go-filecoin/state"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/vm"
+)
+
+// ChainReadStore is the subset of chain.ReadStore that Migrator needs.
+type ChainReadStore interface {
+	Head() types.TipSet
+	GetTipSetState(ctx context.Context, tsKey string) (state.Tree, error)
+}
+
+// Migrator dry runs the state migrations of protocol upgrades against the
+// state of the chain, so they can be validated on real data before the
+// chain reaches their height.
+type Migrator struct {
+	chainReader ChainReadStore
+	blockstore  blockstore.Blockstore
+	upgrades    migration.Schedule
+}
+
+// NewMigrator returns a new Migrator dry running the upgrades of the given
+// schedule.
+func NewMigrator(chainReader ChainReadStore, bs blockstore.Blockstore, upgrades migration.Schedule) *Migrator {
+	return &Migrator{
+		chainReader: chainReader,
+		blockstore:  bs,
+		upgrades:    upgrades,
+	}
+}
+
+// DryRun migrates the state of the chain head for the upgrade with the given
+// name and returns the actors the migration changes. The chain never sees
+// the migrated state: the actor storage the migration writes is discarded,
+// and the migrated state tree is only flushed to compute the difference.
+func (m *Migrator) DryRun(ctx context.Context, name string) ([]*state.ActorDiff, error) {
+	upgrade, ok := m.upgrades.Find(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown upgrade %q", name)
+	}
+
+	key := m.chainReader.Head().ToSortedCidSet().String()
+	before, err := m.chainReader.GetTipSetState(ctx, key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get head state")
+	}
+	after, err := m.chainReader.GetTipSetState(ctx, key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get head state")
+	}
+
+	// The storage map is never flushed so the actor storage the migration
+	// writes leaves no trace in the blockstore.
+	if err := migration.Apply(ctx, after, vm.NewStorageMap(m.blockstore), []migration.Upgrade{upgrade}); err != nil {
+		return nil, err
+	}
+
+	return state.Diff(ctx, before, after)
+}
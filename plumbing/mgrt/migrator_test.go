@@ -0,0 +1,93 @@
+package mgrt_test
+
+import (
+	"context"
+	"testing"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	hamt "gx/ipfs/QmRXf2uUSdGSunRJsM9wXSUNVwLUGCY3So5fAs7h2CBJVf/go-hamt-ipld"
+	blockstore "gx/ipfs/QmS2aqUZLJp8kF1ihE5rvDGE5LvmKDPnx32w9Z1BW9xLV5/go-ipfs-blockstore"
+	datastore "gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/migration"
+	"github.com/filecoin-project/go-filecoin/plumbing/mgrt"
+	"github.com/filecoin-project/go-filecoin/state"
+	th "github.com/filecoin-project/go-filecoin/testhelpers"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/vm"
+)
+
+type fakeChainReadStore struct {
+	head types.TipSet
+	cst  *hamt.CborIpldStore
+	root cid.Cid
+}
+
+func (f *fakeChainReadStore) Head() types.TipSet {
+	return f.head
+}
+
+func (f *fakeChainReadStore) GetTipSetState(ctx context.Context, tsKey string) (state.Tree, error) {
+	return state.LoadStateTree(ctx, f.cst, f.root, nil)
+}
+
+func TestMigratorDryRun(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ctx := context.Background()
+	cst := hamt.NewCborStore()
+	bs := blockstore.NewBlockstore(datastore.NewMapDatastore())
+
+	addrGetter := address.NewForTestGetter()
+	rich, poor := addrGetter(), addrGetter()
+	root, _ := th.RequireMakeStateTree(require, cst, map[address.Address]*actor.Actor{
+		rich: th.RequireNewAccountActor(require, types.NewAttoFILFromFIL(100)),
+		poor: th.RequireNewAccountActor(require, types.NewAttoFILFromFIL(1)),
+	})
+	chainReader := &fakeChainReadStore{
+		head: th.RequireNewTipSet(require, &types.Block{StateRoot: root}),
+		cst:  cst,
+		root: root,
+	}
+
+	upgrades := migration.Schedule{{
+		Name:   "redistribute",
+		Height: types.NewBlockHeight(10),
+		Migration: func(ctx context.Context, st state.Tree, vms vm.StorageMap) error {
+			act, err := st.GetActor(ctx, rich)
+			if err != nil {
+				return err
+			}
+			act.Balance = types.NewAttoFILFromFIL(50)
+			return st.SetActor(ctx, rich, act)
+		},
+	}}
+	migrator := mgrt.NewMigrator(chainReader, bs, upgrades)
+
+	t.Run("lists the actors the migration changes", func(t *testing.T) {
+		diffs, err := migrator.DryRun(ctx, "redistribute")
+		require.NoError(err)
+		require.Len(diffs, 1)
+		assert.Equal(rich, diffs[0].Address)
+		assert.Equal(state.ActorModified, diffs[0].Kind)
+		assert.Equal(types.NewAttoFILFromFIL(100), diffs[0].Before.Balance)
+		assert.Equal(types.NewAttoFILFromFIL(50), diffs[0].After.Balance)
+
+		// the chain state is left as it was
+		st, err := chainReader.GetTipSetState(ctx, "")
+		require.NoError(err)
+		assert.Equal(types.NewAttoFILFromFIL(100), state.MustGetActor(st, rich).Balance)
+	})
+
+	t.Run("fails for unknown upgrades", func(t *testing.T) {
+		_, err := migrator.DryRun(ctx, "nope")
+		assert.EqualError(err, `unknown upgrade "nope"`)
+	})
+}
@@ -0,0 +1,304 @@
+package msg
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	cbor "gx/ipfs/QmRoARq3nkUb13HSKZGepCZSWe5GrVPwx7xURJGZ7KWv9V/go-ipld-cbor"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	"gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
+	"gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore/query"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/chain"
+	"github.com/filecoin-project/go-filecoin/repo"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func init() {
+	cbor.RegisterCborType(indexRecord{})
+}
+
+const indexDatastorePrefix = "msgindex"
+
+var indexHeadKey = datastore.KeyWithNamespaces([]string{indexDatastorePrefix, "head"})
+
+// IndexEntry is a message on chain sent from or to an address.
+type IndexEntry struct {
+	Message *types.SignedMessage
+	Cid     cid.Cid
+	// TipSet is the tipset that includes the message.
+	TipSet types.TipSet
+	Height uint64
+}
+
+// indexRecord is an IndexEntry as it is stored.
+type indexRecord struct {
+	Message *types.SignedMessage
+	TipSet  string
+	Height  uint64
+	// Position is the position of the message in the canonical message order
+	// of the tipset.
+	Position int
+}
+
+// Index indexes the messages on chain by the addresses that sent or received
+// them, in a datastore so that it survives restarts and does not grow the
+// memory of the node with the chain. It catches up with the chain head when
+// it is queried, walking back only to the first tipset it already indexed on
+// the current chain. Entries of tipsets a reorg took off the chain are kept,
+// in case the chain switches back, but are not returned.
+//
+// The datastore holds, under the index prefix:
+//   - head: the key of the chain head the index caught up with
+//   - chain/<height>: the key of the tipset at each height of the indexed
+//     chain, empty for null rounds
+//   - tipset/<key>: a marker for each tipset whose messages are indexed
+//   - addr/<address>/<key>/<position>: the messages sent from or to address
+type Index struct {
+	chainReader chain.ReadStore
+	ds          repo.Datastore
+
+	mu sync.Mutex
+}
+
+// NewIndex returns a new Index storing its entries in ds.
+func NewIndex(chainReader chain.ReadStore, ds repo.Datastore) *Index {
+	return &Index{
+		chainReader: chainReader,
+		ds:          ds,
+	}
+}
+
+// Messages returns the messages on chain sent from or to addr, newest first
+// and in the canonical message order within a tipset.
+func (idx *Index) Messages(ctx context.Context, addr address.Address) ([]*IndexEntry, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if err := idx.catchUp(ctx); err != nil {
+		return nil, err
+	}
+
+	res, err := idx.ds.Query(query.Query{Prefix: datastore.KeyWithNamespaces([]string{indexDatastorePrefix, "addr", addr.String()}).String()})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query message index")
+	}
+	defer res.Close() // nolint: errcheck
+
+	var records []*indexRecord
+	for entry := range res.Next() {
+		if entry.Error != nil {
+			return nil, errors.Wrap(entry.Error, "failed to read message index")
+		}
+		var r indexRecord
+		if err := cbor.DecodeInto(entry.Value, &r); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal message index entry")
+		}
+		onChain, err := idx.onChain(r.Height)
+		if err != nil {
+			return nil, err
+		}
+		if onChain == r.TipSet {
+			records = append(records, &r)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Height != records[j].Height {
+			return records[i].Height > records[j].Height
+		}
+		return records[i].Position < records[j].Position
+	})
+
+	tipSets := make(map[string]types.TipSet)
+	entries := make([]*IndexEntry, len(records))
+	for i, r := range records {
+		ts, ok := tipSets[r.TipSet]
+		if !ok {
+			tsas, err := idx.chainReader.GetTipSetAndState(ctx, r.TipSet)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to load tipset %s", r.TipSet)
+			}
+			ts = tsas.TipSet
+			tipSets[r.TipSet] = ts
+		}
+		c, err := r.Message.Cid()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to compute message cid")
+		}
+		entries[i] = &IndexEntry{
+			Message: r.Message,
+			Cid:     c,
+			TipSet:  ts,
+			Height:  r.Height,
+		}
+	}
+	return entries, nil
+}
+
+// catchUp indexes the tipsets from the chain head back to the first one that
+// is already on the indexed chain. Everything the walk finds is written in a
+// single batch once it succeeded, so that a failed walk is retried from
+// scratch.
+func (idx *Index) catchUp(ctx context.Context) error {
+	head := idx.chainReader.Head()
+	indexedHead, err := idx.get(indexHeadKey)
+	if err != nil {
+		return err
+	}
+	if head.String() == indexedHead {
+		return nil
+	}
+	headHeight, err := head.Height()
+	if err != nil {
+		return err
+	}
+
+	batch, err := idx.ds.Batch()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	prevHeight := headHeight + 1
+walk:
+	for raw := range idx.chainReader.BlockHistory(ctx, head) {
+		switch v := raw.(type) {
+		case error:
+			return errors.Wrap(v, "failed to walk chain")
+		case types.TipSet:
+			h, err := v.Height()
+			if err != nil {
+				return err
+			}
+			// Heights skipped by null rounds have no tipset on this chain.
+			for skipped := h + 1; skipped < prevHeight; skipped++ {
+				if err := batch.Put(indexChainKey(skipped), []byte{}); err != nil {
+					return err
+				}
+			}
+			prevHeight = h
+
+			key := v.String()
+			onChain, err := idx.onChain(h)
+			if err != nil {
+				return err
+			}
+			if onChain == key {
+				break walk
+			}
+			if err := batch.Put(indexChainKey(h), []byte(key)); err != nil {
+				return err
+			}
+
+			indexed, err := idx.ds.Has(indexTipSetKey(key))
+			if err != nil {
+				return err
+			}
+			if !indexed {
+				if err := indexTipSet(batch, v, h); err != nil {
+					return err
+				}
+			}
+		default:
+			return fmt.Errorf("unexpected type in channel: %T", raw)
+		}
+	}
+
+	// The indexed chain may have been longer than the new one.
+	for h := headHeight + 1; ; h++ {
+		ok, err := idx.ds.Has(indexChainKey(h))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		if err := batch.Delete(indexChainKey(h)); err != nil {
+			return err
+		}
+	}
+
+	if err := batch.Put(indexHeadKey, []byte(head.String())); err != nil {
+		return err
+	}
+	return errors.Wrap(batch.Commit(), "failed to write message index")
+}
+
+// indexTipSet adds the messages of ts to batch. Messages included in several
+// blocks of the tipset are only indexed once.
+func indexTipSet(batch datastore.Batch, ts types.TipSet, height uint64) error {
+	key := ts.String()
+	seen := make(map[cid.Cid]struct{})
+	var position int
+
+	blks := ts.ToSlice()
+	types.SortBlocks(blks)
+	for _, blk := range blks {
+		for _, msg := range blk.Messages {
+			c, err := msg.Cid()
+			if err != nil {
+				return errors.Wrap(err, "failed to compute message cid")
+			}
+			if _, ok := seen[c]; ok {
+				continue
+			}
+			seen[c] = struct{}{}
+
+			data, err := cbor.DumpObject(&indexRecord{
+				Message:  msg,
+				TipSet:   key,
+				Height:   height,
+				Position: position,
+			})
+			if err != nil {
+				return errors.Wrap(err, "failed to marshal message index entry")
+			}
+			if err := batch.Put(indexAddrKey(msg.From, key, position), data); err != nil {
+				return err
+			}
+			if msg.To != msg.From {
+				if err := batch.Put(indexAddrKey(msg.To, key, position), data); err != nil {
+					return err
+				}
+			}
+			position++
+		}
+	}
+	return batch.Put(indexTipSetKey(key), []byte{})
+}
+
+// onChain returns the key of the tipset at height h of the indexed chain,
+// empty if there is none.
+func (idx *Index) onChain(h uint64) (string, error) {
+	return idx.get(indexChainKey(h))
+}
+
+func (idx *Index) get(key datastore.Key) (string, error) {
+	val, err := idx.ds.Get(key)
+	if err == datastore.ErrNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read message index")
+	}
+	return string(val), nil
+}
+
+func indexChainKey(h uint64) datastore.Key {
+	return datastore.KeyWithNamespaces([]string{indexDatastorePrefix, "chain", strconv.FormatUint(h, 10)})
+}
+
+func indexTipSetKey(key string) datastore.Key {
+	return datastore.KeyWithNamespaces([]string{indexDatastorePrefix, "tipset", key})
+}
+
+func indexAddrKey(addr address.Address, key string, position int) datastore.Key {
+	return datastore.KeyWithNamespaces([]string{indexDatastorePrefix, "addr", addr.String(), key, strconv.Itoa(position)})
+}
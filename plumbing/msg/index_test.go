@@ -0,0 +1,85 @@
+package msg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/chain"
+	"github.com/filecoin-project/go-filecoin/core"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func TestIndexMessages(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+
+	d := requireCommonDeps(require)
+	idx := NewIndex(d.chainStore, d.repo.ChainDatastore())
+	genesis := d.chainStore.Head()
+
+	putHead := func(tipSets []types.TipSet) {
+		for _, ts := range tipSets[1:] {
+			chain.RequirePutTsas(ctx, require, d.chainStore, &chain.TipSetAndState{
+				TipSet:          ts,
+				TipSetStateRoot: genesis.ToSlice()[0].StateRoot,
+			})
+		}
+		require.NoError(d.chainStore.SetHead(ctx, tipSets[len(tipSets)-1]))
+	}
+	requireCids := func(entries []*IndexEntry, msgs ...*types.SignedMessage) {
+		require.Len(entries, len(msgs))
+		for i, msg := range msgs {
+			c, err := msg.Cid()
+			require.NoError(err)
+			assert.Equal(c, entries[i].Cid)
+		}
+	}
+
+	m1, m2, m3 := newSignedMessage(), newSignedMessage(), newSignedMessage()
+	from := m1.From
+
+	mainChain := core.NewChainWithMessages(d.cst, genesis, smsgsSet{smsgs{m1}}, smsgsSet{smsgs{m2}})
+	putHead(mainChain)
+
+	entries, err := idx.Messages(ctx, from)
+	require.NoError(err)
+	requireCids(entries, m2, m1)
+	assert.Equal(uint64(2), entries[0].Height)
+
+	entries, err = idx.Messages(ctx, m1.To)
+	require.NoError(err)
+	requireCids(entries, m1)
+
+	// The index catches up with new heads.
+	extended := core.NewChainWithMessages(d.cst, mainChain[len(mainChain)-1], smsgsSet{smsgs{m3}})
+	putHead(extended)
+
+	entries, err = idx.Messages(ctx, from)
+	require.NoError(err)
+	requireCids(entries, m3, m2, m1)
+
+	// Reorg onto a longer fork of genesis that only includes m2.
+	m4 := newSignedMessage()
+	fork := core.NewChainWithMessages(d.cst, genesis, smsgsSet{smsgs{m2}}, smsgsSet{}, smsgsSet{}, smsgsSet{smsgs{m4}})
+	putHead(fork)
+
+	entries, err = idx.Messages(ctx, from)
+	require.NoError(err)
+	requireCids(entries, m4, m2)
+	assert.Equal(uint64(1), entries[1].Height)
+
+	entries, err = idx.Messages(ctx, m1.To)
+	require.NoError(err)
+	assert.Empty(entries)
+
+	// The index is kept in the datastore across restarts.
+	reopened := NewIndex(d.chainStore, d.repo.ChainDatastore())
+	entries, err = reopened.Messages(ctx, from)
+	require.NoError(err)
+	requireCids(entries, m4, m2)
+}
@@ -0,0 +1,125 @@
+package msg
+
+import (
+	"context"
+	"sync"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/chain"
+	"github.com/filecoin-project/go-filecoin/core"
+)
+
+// NonceTracker hands out nonces to local senders. It consults both the
+// on-chain actor state and the message pool, and additionally remembers
+// nonces it has handed out that have not made it into the pool yet, so that
+// concurrent sends from the same address never pick the same nonce.
+//
+// There should be exactly one NonceTracker per node.
+type NonceTracker struct {
+	chainReader chain.ReadStore
+	msgPool     *core.MessagePool
+
+	lk       sync.Mutex
+	reserved map[address.Address]*reservation
+}
+
+// reservation tracks the nonces handed out for an address that are still
+// in flight, i.e. reserved but not yet released.
+type reservation struct {
+	next     uint64
+	inFlight int
+}
+
+// NewNonceTracker returns a new NonceTracker.
+func NewNonceTracker(chainReader chain.ReadStore, msgPool *core.MessagePool) *NonceTracker {
+	return &NonceTracker{
+		chainReader: chainReader,
+		msgPool:     msgPool,
+		reserved:    make(map[address.Address]*reservation),
+	}
+}
+
+// Reserve returns the next nonce for addr and marks it as taken. Callers
+// must call Release once the message carrying the nonce has been added to
+// the message pool, or once they've given up on sending it.
+func (nt *NonceTracker) Reserve(ctx context.Context, addr address.Address) (uint64, error) {
+	return nt.ReserveN(ctx, addr, 1)
+}
+
+// Next returns the nonce the next reservation for addr would get, without
+// reserving it.
+func (nt *NonceTracker) Next(ctx context.Context, addr address.Address) (uint64, error) {
+	nt.lk.Lock()
+	defer nt.lk.Unlock()
+
+	nonce, err := nextNonce(ctx, nt.chainReader, nt.msgPool, addr)
+	if err != nil {
+		return 0, err
+	}
+
+	if r, ok := nt.reserved[addr]; ok && r.next > nonce {
+		nonce = r.next
+	}
+	return nonce, nil
+}
+
+// ReserveN is like Reserve but reserves n consecutive nonces for addr,
+// returning the first of them. The whole range is released by a single
+// call to Release.
+func (nt *NonceTracker) ReserveN(ctx context.Context, addr address.Address, n uint64) (uint64, error) {
+	nt.lk.Lock()
+	defer nt.lk.Unlock()
+
+	nonce, err := nextNonce(ctx, nt.chainReader, nt.msgPool, addr)
+	if err != nil {
+		return 0, err
+	}
+
+	r, ok := nt.reserved[addr]
+	if !ok {
+		r = &reservation{}
+		nt.reserved[addr] = r
+	}
+	if r.next > nonce {
+		nonce = r.next
+	}
+	r.next = nonce + n
+	r.inFlight++
+
+	return nonce, nil
+}
+
+// Release ends a reservation made by Reserve. Once no reservations for addr
+// are in flight the tracker forgets about it and subsequent reservations
+// are derived from chain state and the message pool alone. This means that
+// a nonce reserved for a message that never reached the pool is reused.
+func (nt *NonceTracker) Release(addr address.Address) {
+	nt.lk.Lock()
+	defer nt.lk.Unlock()
+
+	r, ok := nt.reserved[addr]
+	if !ok {
+		return
+	}
+	r.inFlight--
+	if r.inFlight <= 0 {
+		delete(nt.reserved, addr)
+	}
+}
+
+// nextNonce returns the next nonce for the given address. It checks
+// the actor's memory and also scans the message pool for any pending
+// messages.
+func nextNonce(ctx context.Context, chainReader chain.ReadStore, msgPool *core.MessagePool, address address.Address) (nonce uint64, err error) {
+	st, err := chainReader.LatestState(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	nonce, err = core.NextNonce(ctx, st, msgPool, address)
+	if err != nil {
+		return 0, err
+	}
+
+	return nonce, nil
+}
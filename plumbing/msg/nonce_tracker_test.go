@@ -0,0 +1,106 @@
+package msg
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/core"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/wallet"
+)
+
+func TestNonceTracker(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("outstanding reservations are not handed out twice", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		_, _, chainStore, msgPool := setupSendTest(require)
+		nt := NewNonceTracker(chainStore, msgPool)
+		addr := address.NewForTestGetter()()
+
+		n0, err := nt.Reserve(ctx, addr)
+		require.NoError(err)
+		n1, err := nt.Reserve(ctx, addr)
+		require.NoError(err)
+
+		assert.Equal(uint64(0), n0)
+		assert.Equal(uint64(1), n1)
+	})
+
+	t.Run("reservations build on pending pool messages", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		_, w, chainStore, msgPool := setupSendTest(require)
+		addr, err := wallet.NewAddress(w)
+		require.NoError(err)
+
+		msg := types.NewMessage(addr, addr, 7, nil, "foo", []byte{})
+		smsg, err := types.NewSignedMessage(*msg, w, types.NewGasPrice(0), types.NewGasUnits(0))
+		require.NoError(err)
+		core.MustAdd(msgPool, smsg)
+
+		nt := NewNonceTracker(chainStore, msgPool)
+		n, err := nt.Reserve(ctx, addr)
+		require.NoError(err)
+		assert.Equal(uint64(8), n)
+	})
+
+	t.Run("releasing all reservations forgets unused nonces", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		_, _, chainStore, msgPool := setupSendTest(require)
+		nt := NewNonceTracker(chainStore, msgPool)
+		addr := address.NewForTestGetter()()
+
+		_, err := nt.Reserve(ctx, addr)
+		require.NoError(err)
+		_, err = nt.Reserve(ctx, addr)
+		require.NoError(err)
+		nt.Release(addr)
+		nt.Release(addr)
+
+		// Neither message reached the pool so nonce 0 is still next.
+		n, err := nt.Reserve(ctx, addr)
+		require.NoError(err)
+		assert.Equal(uint64(0), n)
+	})
+
+	t.Run("concurrent reservations are unique", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		_, _, chainStore, msgPool := setupSendTest(require)
+		nt := NewNonceTracker(chainStore, msgPool)
+		addr := address.NewForTestGetter()()
+
+		var lk sync.Mutex
+		var wg sync.WaitGroup
+		seen := map[uint64]bool{}
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				n, err := nt.Reserve(ctx, addr)
+				require.NoError(err)
+
+				lk.Lock()
+				defer lk.Unlock()
+				seen[n] = true
+			}()
+		}
+		wg.Wait()
+
+		assert.Len(seen, 10)
+	})
+}
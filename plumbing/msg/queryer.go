@@ -2,6 +2,7 @@ package msg
 
 import (
 	"context"
+	"fmt"
 
 	hamt "gx/ipfs/QmRXf2uUSdGSunRJsM9wXSUNVwLUGCY3So5fAs7h2CBJVf/go-hamt-ipld"
 	bstore "gx/ipfs/QmS2aqUZLJp8kF1ihE5rvDGE5LvmKDPnx32w9Z1BW9xLV5/go-ipfs-blockstore"
@@ -78,3 +79,109 @@ func (q *Queryer) Query(ctx context.Context, optFrom, to address.Address, method
 	}
 	return r, sig, nil
 }
+
+// Call sends a read-only message to an actor over the state of the chain at
+// optHeight, or at the head if optHeight is nil, and returns the decoded return
+// values. The state at a height is the state after the tipset at that height,
+// or at the greatest height below it for null blocks. Nothing is broadcast and
+// no gas is paid.
+func (q *Queryer) Call(ctx context.Context, optFrom, to address.Address, method string, optHeight *types.BlockHeight, params ...interface{}) ([]*abi.Value, error) {
+	encodedParams, err := abi.ToEncodedValues(params...)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldnt encode message params")
+	}
+
+	ts, err := q.tipSetAt(ctx, optHeight)
+	if err != nil {
+		return nil, err
+	}
+	tsas, err := q.chainReader.GetTipSetAndState(ctx, ts.String())
+	if err != nil {
+		return nil, errors.Wrap(err, "couldnt get tipset state root")
+	}
+	st, err := state.LoadStateTree(ctx, q.cst, tsas.TipSetStateRoot, builtin.Actors)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldnt load tree for tipset state root")
+	}
+	h, err := ts.Height()
+	if err != nil {
+		return nil, errors.Wrap(err, "couldnt get tipset height")
+	}
+
+	// The signature is looked up in the queried state as the actor's code may
+	// have changed since.
+	sig, err := signature(ctx, st, to, method)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to determine return type")
+	}
+
+	r, ec, err := consensus.CallQueryMethod(ctx, st, vm.NewStorageMap(q.bs), to, method, encodedParams, optFrom, types.NewBlockHeight(h))
+	if err != nil {
+		return nil, errors.Wrap(err, "querymethod returned an error")
+	} else if ec != 0 {
+		return nil, errors.Errorf("querymethod returned a non-zero error code %d", ec)
+	}
+
+	if len(r) != len(sig.Return) {
+		return nil, fmt.Errorf("expected %d return values, got %d", len(sig.Return), len(r))
+	}
+	vals := make([]*abi.Value, len(r))
+	for i, ret := range r {
+		vals[i], err = abi.Deserialize(ret, sig.Return[i])
+		if err != nil {
+			return nil, errors.Wrap(err, "couldnt decode return value")
+		}
+	}
+	return vals, nil
+}
+
+// tipSetAt returns the tipset at the greatest height no greater than optHeight,
+// or the head if optHeight is nil.
+func (q *Queryer) tipSetAt(ctx context.Context, optHeight *types.BlockHeight) (types.TipSet, error) {
+	head := q.chainReader.Head()
+	if optHeight == nil {
+		return head, nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for raw := range q.chainReader.BlockHistory(ctx, head) {
+		switch v := raw.(type) {
+		case error:
+			return nil, errors.Wrap(v, "failed to walk chain")
+		case types.TipSet:
+			h, err := v.Height()
+			if err != nil {
+				return nil, err
+			}
+			if types.NewBlockHeight(h).LessEqual(optHeight) {
+				return v, nil
+			}
+		default:
+			return nil, fmt.Errorf("unexpected type in channel: %T", raw)
+		}
+	}
+
+	return nil, fmt.Errorf("no tipset at or below height %s", optHeight)
+}
+
+func signature(ctx context.Context, st state.Tree, to address.Address, method string) (*exec.FunctionSignature, error) {
+	act, err := st.GetActor(ctx, to)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get actor")
+	} else if !act.Code.Defined() {
+		return nil, mthdsig.ErrNoActorImpl
+	}
+
+	executable, err := st.GetBuiltinActorCode(act.Code)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load actor code")
+	}
+
+	export, ok := executable.Exports()[method]
+	if !ok {
+		return nil, fmt.Errorf("missing export: %s", method)
+	}
+	return export, nil
+}
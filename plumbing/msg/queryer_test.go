@@ -10,6 +10,7 @@ import (
 	"github.com/filecoin-project/go-filecoin/actor"
 	"github.com/filecoin-project/go-filecoin/actor/builtin"
 	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/chain"
 	"github.com/filecoin-project/go-filecoin/consensus"
 	"github.com/filecoin-project/go-filecoin/repo"
 	th "github.com/filecoin-project/go-filecoin/testhelpers"
@@ -95,3 +96,71 @@ func TestQuery(t *testing.T) {
 		assert.Contains(err.Error(), "42")
 	})
 }
+
+func TestCall(t *testing.T) {
+	// Don't add t.Parallel here; these tests muck with globals.
+
+	require := require.New(t)
+	assert := assert.New(t)
+	newAddr := address.NewForTestGetter()
+	ctx := context.Background()
+	r := repo.NewInMemoryRepo()
+	bs := bstore.NewBlockstore(r.Datastore())
+
+	fakeActorCodeCid := types.NewCidForTestGetter()()
+	fakeActorAddr := newAddr()
+	fromAddr := newAddr()
+	builtin.Actors[fakeActorCodeCid] = &actor.FakeActor{}
+	defer func() {
+		delete(builtin.Actors, fakeActorCodeCid)
+	}()
+	testGen := consensus.MakeGenesisFunc(
+		consensus.ActorAccount(fromAddr, types.NewAttoFILFromFIL(0)),
+	)
+	deps := requireCommonDepsWithGifAndBlockstore(require, testGen, r, bs)
+
+	// The fake actor only exists from height 2 on, after a null block at
+	// height 1.
+	genesis := deps.chainStore.Head()
+	st, err := deps.chainStore.LatestState(ctx)
+	require.NoError(err)
+	require.NoError(st.SetActor(ctx, fakeActorAddr, th.RequireNewFakeActor(require, vm.NewStorageMap(bs), fakeActorAddr, fakeActorCodeCid)))
+	root, err := st.Flush(ctx)
+	require.NoError(err)
+	child := chain.RequireMkFakeChild(require, chain.FakeChildParams{
+		Parent:         genesis,
+		GenesisCid:     deps.chainStore.GenesisCid(),
+		StateRoot:      root,
+		NullBlockCount: 1,
+	})
+	head := chain.MustNewTipSet(child)
+	chain.RequirePutTsas(ctx, require, deps.chainStore, &chain.TipSetAndState{TipSet: head, TipSetStateRoot: root})
+	require.NoError(deps.chainStore.SetHead(ctx, head))
+
+	queryer := NewQueryer(deps.repo, deps.wallet, deps.chainStore, deps.cst, deps.blockstore)
+
+	t.Run("decodes return values at the head", func(t *testing.T) {
+		vals, err := queryer.Call(ctx, fromAddr, fakeActorAddr, "hasReturnValue", nil)
+		require.NoError(err)
+		require.Len(vals, 1)
+		assert.Equal(abi.Address, vals[0].Type)
+	})
+
+	t.Run("queries historical state", func(t *testing.T) {
+		_, err := queryer.Call(ctx, fromAddr, fakeActorAddr, "hasReturnValue", types.NewBlockHeight(2))
+		assert.NoError(err)
+
+		// height 1 is a null block, the state is the genesis state
+		for _, h := range []uint64{0, 1} {
+			_, err := queryer.Call(ctx, fromAddr, fakeActorAddr, "hasReturnValue", types.NewBlockHeight(h))
+			require.Error(err)
+			assert.Contains(err.Error(), "failed to get actor")
+		}
+	})
+
+	t.Run("non-zero exit code is an error", func(t *testing.T) {
+		_, err := queryer.Call(ctx, fromAddr, fakeActorAddr, "nonZeroExitCode", nil)
+		require.Error(err)
+		assert.Contains(err.Error(), "42")
+	})
+}
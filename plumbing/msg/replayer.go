@@ -0,0 +1,139 @@
+package msg
+
+import (
+	"context"
+	"fmt"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	"gx/ipfs/QmRXf2uUSdGSunRJsM9wXSUNVwLUGCY3So5fAs7h2CBJVf/go-hamt-ipld"
+	bstore "gx/ipfs/QmS2aqUZLJp8kF1ihE5rvDGE5LvmKDPnx32w9Z1BW9xLV5/go-ipfs-blockstore"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+
+	"github.com/filecoin-project/go-filecoin/actor/builtin"
+	"github.com/filecoin-project/go-filecoin/chain"
+	"github.com/filecoin-project/go-filecoin/consensus"
+	"github.com/filecoin-project/go-filecoin/state"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/vm"
+)
+
+// Replay is the outcome of re-executing a message that is on chain.
+type Replay struct {
+	// Block is the block the message was included in.
+	Block   cid.Cid               `json:"block"`
+	Receipt *types.MessageReceipt `json:"receipt,omitempty"`
+	// Error is the error the execution failed with, or the reason the
+	// message could not be applied at all, e.g. a conflicting message.
+	Error string    `json:"error,omitempty"`
+	Trace *vm.Trace `json:"trace"`
+}
+
+// Replayer re-executes messages that are on chain.
+type Replayer struct {
+	chainReader chain.ReadStore
+	cst         *hamt.CborIpldStore
+	bs          bstore.Blockstore
+}
+
+// NewReplayer returns a new Replayer.
+func NewReplayer(chainStore chain.ReadStore, bs bstore.Blockstore, cst *hamt.CborIpldStore) *Replayer {
+	return &Replayer{
+		chainReader: chainStore,
+		cst:         cst,
+		bs:          bs,
+	}
+}
+
+// Replay finds the message with the given cid on chain and re-executes the
+// tipset that includes it over that tipset's parent state, tracing the
+// execution of the message. Nothing is persisted: the state changes are
+// discarded.
+//
+// TODO: like Waiter.Wait this traverses the chain to find the message, it
+// should use an index instead.
+func (r *Replayer) Replay(ctx context.Context, msgCid cid.Cid) (*Replay, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for raw := range r.chainReader.BlockHistory(ctx, r.chainReader.Head()) {
+		switch v := raw.(type) {
+		case error:
+			return nil, errors.Wrap(v, "failed to walk chain")
+		case types.TipSet:
+			blk, err := blockWithMessage(v, msgCid)
+			if err != nil {
+				return nil, err
+			}
+			if blk != nil {
+				return r.replayInTipSet(ctx, msgCid, v, blk)
+			}
+		default:
+			return nil, fmt.Errorf("unexpected type in channel: %T", raw)
+		}
+	}
+
+	return nil, fmt.Errorf("message %s not found on chain", msgCid)
+}
+
+func (r *Replayer) replayInTipSet(ctx context.Context, msgCid cid.Cid, ts types.TipSet, blk *types.Block) (*Replay, error) {
+	ids, err := ts.Parents()
+	if err != nil {
+		return nil, err
+	}
+	tsas, err := r.chainReader.GetTipSetAndState(ctx, ids.String())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get parent state")
+	}
+	st, err := state.LoadStateTree(ctx, r.cst, tsas.TipSetStateRoot, builtin.Actors)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load parent state")
+	}
+
+	tsHeight, err := ts.Height()
+	if err != nil {
+		return nil, err
+	}
+	ancestors, err := chain.GetRecentAncestors(ctx, tsas.TipSet, r.chainReader, types.NewBlockHeight(tsHeight), consensus.AncestorRoundsNeeded, consensus.LookBackParameter)
+	if err != nil {
+		return nil, err
+	}
+
+	// The storage map is never flushed so the replay leaves no trace in the
+	// blockstore.
+	res, trace, err := consensus.NewDefaultProcessor().ReplayMessage(ctx, st, vm.NewStorageMap(r.bs), ts, ancestors, msgCid)
+	if trace == nil {
+		return nil, errors.Wrap(err, "failed to replay message")
+	}
+
+	replay := &Replay{
+		Block: blk.Cid(),
+		Trace: trace,
+	}
+	switch {
+	case err != nil:
+		replay.Error = err.Error()
+	case res != nil:
+		replay.Receipt = res.Receipt
+		if res.ExecutionError != nil {
+			replay.Error = res.ExecutionError.Error()
+		}
+	}
+	return replay, nil
+}
+
+// blockWithMessage returns the block of ts including the message with the
+// given cid, or nil if there is none.
+func blockWithMessage(ts types.TipSet, msgCid cid.Cid) (*types.Block, error) {
+	for _, blk := range ts.ToSlice() {
+		for _, msg := range blk.Messages {
+			c, err := msg.Cid()
+			if err != nil {
+				return nil, err
+			}
+			if c.Equals(msgCid) {
+				return blk, nil
+			}
+		}
+	}
+	return nil, nil
+}
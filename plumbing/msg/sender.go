@@ -2,14 +2,12 @@ package msg
 
 import (
 	"context"
-	"sync"
 
 	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
 	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
 
 	"github.com/filecoin-project/go-filecoin/abi"
 	"github.com/filecoin-project/go-filecoin/address"
-	"github.com/filecoin-project/go-filecoin/chain"
 	"github.com/filecoin-project/go-filecoin/core"
 	"github.com/filecoin-project/go-filecoin/repo"
 	"github.com/filecoin-project/go-filecoin/types"
@@ -28,21 +26,18 @@ type Sender struct {
 	repo   repo.Repo
 	wallet *wallet.Wallet
 
-	// For getting the latest nonce and enqueuing messages.
-	chainReader chain.ReadStore
-	msgPool     *core.MessagePool
+	// For reserving nonces and enqueuing messages.
+	nonces  *NonceTracker
+	msgPool *core.MessagePool
 
 	// To publish the new message to the network.
 	publish PublishFunc
-
-	// Locking in send reduces the chance of nonce collision.
-	l sync.Mutex
 }
 
-// NewSender returns a new Sender. There should be exactly one of these per node because
-// sending locks to reduce nonce collisions.
-func NewSender(repo repo.Repo, wallet *wallet.Wallet, chainReader chain.ReadStore, msgPool *core.MessagePool, publish PublishFunc) *Sender {
-	return &Sender{repo: repo, wallet: wallet, chainReader: chainReader, msgPool: msgPool, publish: publish}
+// NewSender returns a new Sender. The nonce tracker should be shared by
+// everything on the node that sends messages.
+func NewSender(repo repo.Repo, wallet *wallet.Wallet, nonces *NonceTracker, msgPool *core.MessagePool, publish PublishFunc) *Sender {
+	return &Sender{repo: repo, wallet: wallet, nonces: nonces, msgPool: msgPool, publish: publish}
 }
 
 // Send sends a message. See api description.
@@ -52,14 +47,13 @@ func (s *Sender) Send(ctx context.Context, from, to address.Address, value *type
 		return cid.Undef, errors.Wrap(err, "invalid params")
 	}
 
-	// Lock to avoid race for message nonce.
-	s.l.Lock()
-	defer s.l.Unlock()
-
-	nonce, err := nextNonce(ctx, s.chainReader, s.msgPool, from)
+	// The reservation is held until the message is in the pool, after
+	// which the pool itself accounts for the nonce.
+	nonce, err := s.nonces.Reserve(ctx, from)
 	if err != nil {
 		return cid.Undef, errors.Wrap(err, "couldn't get next nonce")
 	}
+	defer s.nonces.Release(from)
 
 	msg := types.NewMessage(from, to, nonce, value, method, encodedParams)
 	smsg, err := types.NewSignedMessage(*msg, s.wallet, gasPrice, gasLimit)
@@ -67,6 +61,36 @@ func (s *Sender) Send(ctx context.Context, from, to address.Address, value *type
 		return cid.Undef, errors.Wrap(err, "failed to sign message")
 	}
 
+	return s.Publish(ctx, smsg)
+}
+
+// Compose builds an unsigned message from the given address, using the next
+// nonce of that address unless nonce is given. The nonce is not reserved:
+// composing several messages before publishing them requires passing
+// explicit nonces.
+func (s *Sender) Compose(ctx context.Context, from, to address.Address, nonce *uint64, value *types.AttoFIL, gasPrice types.AttoFIL, gasLimit types.GasUnits, method string, params ...interface{}) (*types.MeteredMessage, error) {
+	encodedParams, err := abi.ToEncodedValues(params...)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid params")
+	}
+
+	var n uint64
+	if nonce != nil {
+		n = *nonce
+	} else {
+		n, err = s.nonces.Next(ctx, from)
+		if err != nil {
+			return nil, errors.Wrap(err, "couldn't get next nonce")
+		}
+	}
+
+	msg := types.NewMessage(from, to, n, value, method, encodedParams)
+	return types.NewMeteredMessage(*msg, gasPrice, gasLimit), nil
+}
+
+// Publish adds a signed message to the message pool and publishes it to the
+// network. The message pool rejects messages with invalid signatures.
+func (s *Sender) Publish(ctx context.Context, smsg *types.SignedMessage) (cid.Cid, error) {
 	smsgdata, err := smsg.Marshal()
 	if err != nil {
 		return cid.Undef, errors.Wrap(err, "failed to marshal message")
@@ -85,19 +109,65 @@ func (s *Sender) Send(ctx context.Context, from, to address.Address, value *type
 	return smsg.Cid()
 }
 
-// nextNonce returns the next nonce for the given address. It checks
-// the actor's memory and also scans the message pool for any pending
-// messages.
-func nextNonce(ctx context.Context, chainReader chain.ReadStore, msgPool *core.MessagePool, address address.Address) (nonce uint64, err error) {
-	st, err := chainReader.LatestState(ctx)
+// BatchMessage describes one of the messages sent by SendBatch.
+type BatchMessage struct {
+	To       address.Address
+	Value    *types.AttoFIL
+	GasPrice types.AttoFIL
+	GasLimit types.GasUnits
+	Method   string
+	Params   []interface{}
+}
+
+// SendBatch sends several messages from the same address. The messages are
+// given consecutive nonces in the order provided and are added to the
+// message pool atomically: either all of them are enqueued or none are.
+func (s *Sender) SendBatch(ctx context.Context, from address.Address, batch []BatchMessage) ([]cid.Cid, error) {
+	if len(batch) == 0 {
+		return nil, errors.New("empty batch")
+	}
+
+	encoded := make([][]byte, len(batch))
+	for i, m := range batch {
+		encodedParams, err := abi.ToEncodedValues(m.Params...)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid params for message %d", i)
+		}
+		encoded[i] = encodedParams
+	}
+
+	firstNonce, err := s.nonces.ReserveN(ctx, from, uint64(len(batch)))
 	if err != nil {
-		return 0, err
+		return nil, errors.Wrap(err, "couldn't get next nonce")
+	}
+	defer s.nonces.Release(from)
+
+	smsgs := make([]*types.SignedMessage, len(batch))
+	for i, m := range batch {
+		msg := types.NewMessage(from, m.To, firstNonce+uint64(i), m.Value, m.Method, encoded[i])
+		smsg, err := types.NewSignedMessage(*msg, s.wallet, m.GasPrice, m.GasLimit)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to sign message %d", i)
+		}
+		smsgs[i] = smsg
 	}
 
-	nonce, err = core.NextNonce(ctx, st, msgPool, address)
+	cids, err := s.msgPool.AddBatch(smsgs)
 	if err != nil {
-		return 0, err
+		return nil, errors.Wrap(err, "failed to add batch to the message pool")
 	}
 
-	return nonce, nil
+	for i, smsg := range smsgs {
+		smsgdata, err := smsg.Marshal()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to marshal message %d", i)
+		}
+		if err = s.publish(Topic, smsgdata); err != nil {
+			return nil, errors.Wrapf(err, "couldnt publish message %d to network", i)
+		}
+	}
+
+	log.Debugf("MessageSendBatch with %d messages from %s", len(smsgs), from)
+
+	return cids, nil
 }
@@ -35,7 +35,7 @@ func TestSend(t *testing.T) {
 			return nil
 		}
 
-		s := NewSender(repo, w, chainStore, msgPool, publish)
+		s := NewSender(repo, w, NewNonceTracker(chainStore, msgPool), msgPool, publish)
 		require.Equal(0, len(msgPool.Pending()))
 		_, err = s.Send(context.Background(), addr, addr, types.NewAttoFILFromFIL(uint64(2)), types.NewGasPrice(0), types.NewGasUnits(0), "")
 		require.NoError(err)
@@ -52,7 +52,7 @@ func TestSend(t *testing.T) {
 		addr, err := wallet.NewAddress(w)
 		require.NoError(err)
 		nopPublish := func(string, []byte) error { return nil }
-		s := NewSender(repo, w, chainStore, msgPool, nopPublish)
+		s := NewSender(repo, w, NewNonceTracker(chainStore, msgPool), msgPool, nopPublish)
 
 		var wg sync.WaitGroup
 		addTwentyMessages := func(batch int) {
@@ -84,6 +84,54 @@ func TestSend(t *testing.T) {
 
 }
 
+func TestSendBatch(t *testing.T) {
+	t.Parallel()
+
+	t.Run("batch gets consecutive nonces in order", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		repo, w, chainStore, msgPool := setupSendTest(require)
+		addr, err := wallet.NewAddress(w)
+		require.NoError(err)
+
+		published := 0
+		publish := func(string, []byte) error {
+			published++
+			return nil
+		}
+		s := NewSender(repo, w, NewNonceTracker(chainStore, msgPool), msgPool, publish)
+
+		batch := []BatchMessage{
+			{To: addr, Value: types.NewZeroAttoFIL(), GasPrice: types.NewGasPrice(0), GasLimit: types.NewGasUnits(0), Method: "a"},
+			{To: addr, Value: types.NewZeroAttoFIL(), GasPrice: types.NewGasPrice(0), GasLimit: types.NewGasUnits(0), Method: "b"},
+			{To: addr, Value: types.NewZeroAttoFIL(), GasPrice: types.NewGasPrice(0), GasLimit: types.NewGasUnits(0), Method: "c"},
+		}
+		cids, err := s.SendBatch(context.Background(), addr, batch)
+		require.NoError(err)
+		require.Len(cids, 3)
+		assert.Equal(3, published)
+
+		byMethod := map[string]uint64{}
+		for _, m := range msgPool.Pending() {
+			byMethod[m.Method] = uint64(m.Nonce)
+		}
+		assert.Equal(map[string]uint64{"a": 0, "b": 1, "c": 2}, byMethod)
+	})
+
+	t.Run("empty batch is an error", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		repo, w, chainStore, msgPool := setupSendTest(require)
+		nopPublish := func(string, []byte) error { return nil }
+		s := NewSender(repo, w, NewNonceTracker(chainStore, msgPool), msgPool, nopPublish)
+
+		_, err := s.SendBatch(context.Background(), address.NewForTestGetter()(), nil)
+		assert.Error(err)
+	})
+}
+
 func TestNextNonce(t *testing.T) {
 	t.Parallel()
 
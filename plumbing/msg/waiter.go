@@ -66,17 +66,19 @@ func (w *Waiter) Wait(ctx context.Context, msgCid cid.Cid, cb func(*types.Block,
 	// Historical blocks
 	historyCh := w.chainReader.BlockHistory(ctx, w.chainReader.Head())
 
-	// Merge historical and new block Channels.
-	go func() {
-		for raw := range newHeadCh {
-			ch <- raw
-		}
-	}()
-	go func() {
-		for raw := range historyCh {
-			ch <- raw
+	// Merge historical and new block Channels. Both forwarders keep draining
+	// their input once ctx is done so that neither the pubsub nor the
+	// history walk is left blocked on a send after Wait returns.
+	forward := func(in <-chan interface{}) {
+		for raw := range in {
+			select {
+			case ch <- raw:
+			case <-ctx.Done():
+			}
 		}
-	}()
+	}
+	go forward(newHeadCh)
+	go forward(historyCh)
 
 	for {
 		select {
@@ -121,25 +123,68 @@ func (w *Waiter) Wait(ctx context.Context, msgCid cid.Cid, cb func(*types.Block,
 // parent block in the case that the message is in conflict with another
 // message of the tipset.
 func (w *Waiter) receiptFromTipSet(ctx context.Context, msgCid cid.Cid, ts types.TipSet) (*types.MessageReceipt, error) {
-	// Receipts always match block if tipset has only 1 member.
-	var rcpt *types.MessageReceipt
+	receipts, err := w.Receipts(ctx, ts)
+	if err != nil {
+		return nil, err
+	}
+	return receipts[msgCid], nil
+}
+
+// Receipts returns the receipts of the messages of ts by message cid. A
+// block's receipts only account for the messages of that block, so they are
+// recomputed by applying all of the tipset's messages when it has several
+// blocks. A message that failed because it conflicts with another message of
+// the tipset has no receipt.
+func (w *Waiter) Receipts(ctx context.Context, ts types.TipSet) (map[cid.Cid]*types.MessageReceipt, error) {
 	blks := ts.ToSlice()
-	if len(ts) == 1 {
-		b := blks[0]
-		// TODO: this should return an error if a receipt doesn't exist.
-		// Right now doing so breaks tests because our test helpers
-		// don't correctly apply messages when making test chains.
-		j, err := msgIndexOfTipSet(msgCid, ts, types.SortedCidSet{})
+	types.SortBlocks(blks)
+
+	// Receipts always match block if tipset has only 1 member.
+	// TODO: a missing receipt should be an error. Right now it breaks tests
+	// because our test helpers don't correctly apply messages when making
+	// test chains.
+	var receipts []*types.MessageReceipt
+	var fails types.SortedCidSet
+	if len(blks) == 1 {
+		receipts = blks[0].MessageReceipts
+	} else {
+		res, err := w.processTipSet(ctx, ts)
 		if err != nil {
 			return nil, err
 		}
-		if j < len(b.MessageReceipts) {
-			rcpt = b.MessageReceipts[j]
+		for _, r := range res.Results {
+			receipts = append(receipts, r.Receipt)
+		}
+		fails = res.Failures
+	}
+
+	// Receipts are in the canonical message order of the tipset, which
+	// skips failing conflict messages and duplicates.
+	byCid := make(map[cid.Cid]*types.MessageReceipt)
+	var duplicates types.SortedCidSet
+	var msgCnt int
+	for _, b := range blks {
+		for _, msg := range b.Messages {
+			c, err := msg.Cid()
+			if err != nil {
+				return nil, err
+			}
+			if fails.Has(c) || duplicates.Has(c) {
+				continue
+			}
+			(&duplicates).Add(c)
+			if msgCnt < len(receipts) {
+				byCid[c] = receipts[msgCnt]
+			}
+			msgCnt++
 		}
-		return rcpt, nil
 	}
+	return byCid, nil
+}
 
-	// Apply all the tipset's messages to determine the correct receipts.
+// processTipSet applies the messages of ts over its parent state. Nothing is
+// persisted.
+func (w *Waiter) processTipSet(ctx context.Context, ts types.TipSet) (*consensus.ProcessTipSetResponse, error) {
 	ids, err := ts.Parents()
 	if err != nil {
 		return nil, err
@@ -163,55 +208,5 @@ func (w *Waiter) receiptFromTipSet(ctx context.Context, msgCid cid.Cid, ts types
 		return nil, err
 	}
 
-	res, err := consensus.NewDefaultProcessor().ProcessTipSet(ctx, st, vm.NewStorageMap(w.bs), ts, ancestors)
-	if err != nil {
-		return nil, err
-	}
-
-	// If this is a failing conflict message there is no application receipt.
-	if res.Failures.Has(msgCid) {
-		return nil, nil
-	}
-
-	j, err := msgIndexOfTipSet(msgCid, ts, res.Failures)
-	if err != nil {
-		return nil, err
-	}
-	// TODO: out of bounds receipt index should return an error.
-	if j < len(res.Results) {
-		rcpt = res.Results[j].Receipt
-	}
-	return rcpt, nil
-}
-
-// msgIndexOfTipSet returns the order in which msgCid appears in the canonical
-// message ordering of the given tipset, or an error if it is not in the
-// tipset.
-// TODO: find a better home for this method
-func msgIndexOfTipSet(msgCid cid.Cid, ts types.TipSet, fails types.SortedCidSet) (int, error) {
-	blks := ts.ToSlice()
-	types.SortBlocks(blks)
-	var duplicates types.SortedCidSet
-	var msgCnt int
-	for _, b := range blks {
-		for _, msg := range b.Messages {
-			c, err := msg.Cid()
-			if err != nil {
-				return -1, err
-			}
-			if fails.Has(c) {
-				continue
-			}
-			if duplicates.Has(c) {
-				continue
-			}
-			(&duplicates).Add(c)
-			if c.Equals(msgCid) {
-				return msgCnt, nil
-			}
-			msgCnt++
-		}
-	}
-
-	return -1, fmt.Errorf("message cid %s not in tipset", msgCid.String())
+	return consensus.NewDefaultProcessor().ProcessTipSet(ctx, st, vm.NewStorageMap(w.bs), ts, ancestors)
 }
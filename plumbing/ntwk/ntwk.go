@@ -1,21 +1,166 @@
 package ntwk
 
 import (
+	"context"
+	"sync"
+	"time"
+
+	inet "gx/ipfs/QmNgLg1NTw37iWbYPKcyK85YJ9Whs1MkPtJwhfqbNYAyKg/go-libp2p-net"
+	pstore "gx/ipfs/QmPiemjiKBC9VA7vZF82m4x1oygtg2c2YVqag8PX7dN1BD/go-libp2p-peerstore"
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	routing "gx/ipfs/QmTiRqrF5zkdZyrdsL5qndG1UbeWi8k8N2pYxCtXWrahR2/go-libp2p-routing"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
 	"gx/ipfs/QmY5Grm8pJdiSSVsYxx4uNRgweY72EmYwuSDbRnbFok3iY/go-libp2p-peer"
+	"gx/ipfs/QmYxivS34F2M2n44WQQnRHGAKS8aoRUxwGpi9wk4Cdn4Jf/go-libp2p/p2p/protocol/ping"
 	"gx/ipfs/QmaoXrM4Z41PD48JY36YqQGKQpLGjyLA2cKcLsES7YddAq/go-libp2p-host"
+	mh "gx/ipfs/QmerPMzPk1mJVowm8KgmoknWa4yCYvvugMPsgWmDNUvDLW/go-multihash"
+
+	"github.com/filecoin-project/go-filecoin/address"
 )
 
+// minerCacheTTL is how long the peers of miners found in the DHT are cached
+// before being looked up again.
+const minerCacheTTL = 10 * time.Minute
+
+// ErrPingSelf is returned when the node is asked to ping itself.
+var ErrPingSelf = errors.New("cannot ping self")
+
+// ErrMinerNotFound is returned when no peer provides a miner in the DHT.
+var ErrMinerNotFound = errors.New("no peer found providing the miner")
+
+// PeerLatency is the latency to a peer, measured by the round trips of the
+// streams to it. It is 0 if unknown.
+type PeerLatency struct {
+	Peer    peer.ID       `json:"peer"`
+	Latency time.Duration `json:"latency"`
+}
+
 // Network is a unified interface for dealing with libp2p
 type Network struct {
-	host host.Host
+	host   host.Host
+	router routing.IpfsRouting
+	pinger *ping.PingService
+	now    func() time.Time
+
+	lk     sync.Mutex
+	miners map[address.Address]cachedMiner
+}
+
+type cachedMiner struct {
+	info    pstore.PeerInfo
+	expires time.Time
 }
 
 // NewNetwork returns a new Network
-func NewNetwork(host host.Host) *Network {
-	return &Network{host: host}
+func NewNetwork(host host.Host, router routing.IpfsRouting, pinger *ping.PingService) *Network {
+	return &Network{
+		host:   host,
+		router: router,
+		pinger: pinger,
+		now:    time.Now,
+		miners: make(map[address.Address]cachedMiner),
+	}
 }
 
 // GetPeerID gets the current peer id from libp2p-host
 func (network *Network) GetPeerID() peer.ID {
 	return network.host.ID()
 }
+
+// Ping sends a ping to the peer, returning the round trip time.
+func (network *Network) Ping(ctx context.Context, p peer.ID) (time.Duration, error) {
+	if p == network.host.ID() {
+		return 0, ErrPingSelf
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	times, err := network.pinger.Ping(ctx, p)
+	if err != nil {
+		return 0, err
+	}
+	select {
+	case rtt, ok := <-times:
+		if !ok {
+			return 0, errors.New("ping failed")
+		}
+		return rtt, nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+// FindPeer finds the addresses of the peer, in the peerstore or else in the
+// DHT.
+func (network *Network) FindPeer(ctx context.Context, p peer.ID) (pstore.PeerInfo, error) {
+	if addrs := network.host.Peerstore().Addrs(p); len(addrs) > 0 {
+		return pstore.PeerInfo{ID: p, Addrs: addrs}, nil
+	}
+	return network.router.FindPeer(ctx, p)
+}
+
+// Latencies returns the latencies to the peers the node is connected to.
+func (network *Network) Latencies() []PeerLatency {
+	var latencies []PeerLatency
+	for _, p := range network.host.Network().Peers() {
+		latencies = append(latencies, PeerLatency{Peer: p, Latency: network.host.Peerstore().LatencyEWMA(p)})
+	}
+	return latencies
+}
+
+// Connectedness returns whether the node is connected to the peer.
+func (network *Network) Connectedness(p peer.ID) inet.Connectedness {
+	return network.host.Network().Connectedness(p)
+}
+
+// ProvideMiner announces in the DHT that this node is the peer of the miner.
+// Provider records expire, so miners announce themselves again periodically.
+func (network *Network) ProvideMiner(ctx context.Context, minerAddr address.Address) error {
+	key, err := MinerKey(minerAddr)
+	if err != nil {
+		return err
+	}
+	return network.router.Provide(ctx, key, true)
+}
+
+// FindMiner finds the peer providing the miner in the DHT, and adds its
+// addresses to the peerstore so it can be dialed. Found peers are cached for
+// a while.
+func (network *Network) FindMiner(ctx context.Context, minerAddr address.Address) (pstore.PeerInfo, error) {
+	network.lk.Lock()
+	cached, ok := network.miners[minerAddr]
+	network.lk.Unlock()
+	if ok && network.now().Before(cached.expires) {
+		return cached.info, nil
+	}
+
+	key, err := MinerKey(minerAddr)
+	if err != nil {
+		return pstore.PeerInfo{}, err
+	}
+	for info := range network.router.FindProvidersAsync(ctx, key, 1) {
+		if info.ID == "" {
+			continue
+		}
+		network.host.Peerstore().AddAddrs(info.ID, info.Addrs, pstore.ProviderAddrTTL)
+
+		network.lk.Lock()
+		network.miners[minerAddr] = cachedMiner{info: info, expires: network.now().Add(minerCacheTTL)}
+		network.lk.Unlock()
+		return info, nil
+	}
+	if ctx.Err() != nil {
+		return pstore.PeerInfo{}, ctx.Err()
+	}
+	return pstore.PeerInfo{}, errors.Wrapf(ErrMinerNotFound, "miner %s", minerAddr)
+}
+
+// MinerKey returns the key of the provider records of the miner in the DHT.
+func MinerKey(minerAddr address.Address) (cid.Cid, error) {
+	h, err := mh.Sum([]byte("/fil/miner/"+minerAddr.String()), mh.SHA2_256, -1)
+	if err != nil {
+		return cid.Undef, errors.Wrap(err, "failed to hash miner address")
+	}
+	return cid.NewCidV1(cid.Raw, h), nil
+}
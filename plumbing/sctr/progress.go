@@ -0,0 +1,278 @@
+package sctr
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"gx/ipfs/QmdbxjQWogRCHRaxhhGnYdT1oQJzL9GdqSKzCdqWr85AP2/pubsub"
+)
+
+// Stage is a step a sector goes through from receiving pieces to being
+// committed on chain.
+type Stage string
+
+const (
+	// Staged means pieces are being written into the sector.
+	Staged = Stage("staged")
+	// Sealing means the sector is being sealed.
+	Sealing = Stage("sealing")
+	// Committing means the commitment of the sealed sector is being sent to
+	// the chain.
+	Committing = Stage("committing")
+	// Committed means the sector is committed on chain.
+	Committed = Stage("committed")
+	// Proving means the sector is proven by the PoSts of the miner.
+	Proving = Stage("proving")
+	// Faulted means a PoSt or a check of the miner found the sector missing
+	// or corrupt.
+	Faulted = Stage("faulted")
+	// Failed means sealing or committing the sector failed.
+	Failed = Stage("failed")
+)
+
+// sectorEventsTopic is the topic sector events are published on.
+const sectorEventsTopic = "sectors"
+
+// StuckFactor is how many times longer than usual a sector stays in a stage
+// before it counts as stuck.
+const StuckFactor = 3
+
+// averageWeight is the weight of the latest duration of a stage in the moving
+// average of its durations.
+const averageWeight = 0.2
+
+// Progress reports how far a sector got.
+type Progress struct {
+	SectorID uint64 `json:"sectorId"`
+	Stage    Stage  `json:"stage"`
+	// Started is the time the sector was first seen, StageStarted the time it
+	// entered its stage.
+	Started      time.Time `json:"started"`
+	StageStarted time.Time `json:"stageStarted"`
+	// Elapsed is the time spent in the stage.
+	Elapsed time.Duration `json:"elapsed"`
+	// Percent estimates how much of the stage is done and ETA the time left
+	// to complete it, from the time sectors usually take for the stage. Both
+	// are zero until a sector completed the stage.
+	Percent int           `json:"percent"`
+	ETA     time.Duration `json:"eta"`
+	// Stuck is set when the sector spent StuckFactor times longer than usual
+	// in the stage.
+	Stuck bool   `json:"stuck"`
+	Error string `json:"error,omitempty"`
+}
+
+// SectorEvent reports a sector moving from one stage to another. From is
+// empty for sectors seen for the first time.
+type SectorEvent struct {
+	SectorID uint64    `json:"sectorId"`
+	From     Stage     `json:"from"`
+	To       Stage     `json:"to"`
+	Time     time.Time `json:"time"`
+	Error    string    `json:"error,omitempty"`
+}
+
+type sector struct {
+	id           uint64
+	stage        Stage
+	started      time.Time
+	stageStarted time.Time
+	err          string
+}
+
+// Tracker tracks the progress of the sectors of a miner through the stages
+// of sealing, estimating the time left from the time earlier sectors took.
+// Every change of stage is published as a SectorEvent to subscribers.
+type Tracker struct {
+	lk      sync.Mutex
+	sectors map[uint64]*sector
+	// usual holds the moving average of the durations of each stage.
+	usual map[Stage]time.Duration
+	now   func() time.Time
+
+	events *pubsub.PubSub
+}
+
+// NewTracker returns a Tracker without sectors.
+func NewTracker() *Tracker {
+	return &Tracker{
+		sectors: make(map[uint64]*sector),
+		usual:   make(map[Stage]time.Duration),
+		now:     time.Now,
+		events:  pubsub.New(128),
+	}
+}
+
+// Enter records that the sector entered the given stage, completing its
+// previous stage. Entering the stage a sector is in does nothing.
+func (t *Tracker) Enter(sectorID uint64, stage Stage) {
+	t.lk.Lock()
+	evt, ok := t.enter(sectorID, stage, "")
+	t.lk.Unlock()
+
+	if ok {
+		t.publish(evt)
+	}
+}
+
+// EnterAll moves all sectors in stage from to stage to, e.g. all staged
+// sectors to sealing once the sector builder seals them.
+func (t *Tracker) EnterAll(from, to Stage) {
+	var evts []SectorEvent
+	t.lk.Lock()
+	for id, s := range t.sectors {
+		if s.stage == from {
+			if evt, ok := t.enter(id, to, ""); ok {
+				evts = append(evts, evt)
+			}
+		}
+	}
+	t.lk.Unlock()
+
+	sort.Slice(evts, func(i, j int) bool { return evts[i].SectorID < evts[j].SectorID })
+	t.publish(evts...)
+}
+
+// Fail records that sealing or committing the sector failed.
+func (t *Tracker) Fail(sectorID uint64, err error) {
+	t.lk.Lock()
+	evt, ok := t.enter(sectorID, Failed, err.Error())
+	t.lk.Unlock()
+
+	if ok {
+		t.publish(evt)
+	}
+}
+
+// enter moves the sector to the stage, returning the event to publish if
+// the stage changed. The caller holds lk.
+func (t *Tracker) enter(sectorID uint64, stage Stage, errMsg string) (SectorEvent, bool) {
+	now := t.now()
+	evt := SectorEvent{SectorID: sectorID, To: stage, Time: now, Error: errMsg}
+
+	s, ok := t.sectors[sectorID]
+	if !ok {
+		t.sectors[sectorID] = &sector{id: sectorID, stage: stage, started: now, stageStarted: now, err: errMsg}
+		return evt, true
+	}
+	if s.stage == stage {
+		return SectorEvent{}, false
+	}
+	evt.From = s.stage
+
+	if stage != Failed && stage != Faulted {
+		d := now.Sub(s.stageStarted)
+		if usual, ok := t.usual[s.stage]; ok {
+			d = time.Duration(averageWeight*float64(d) + (1-averageWeight)*float64(usual))
+		}
+		t.usual[s.stage] = d
+	}
+	s.stage = stage
+	s.stageStarted = now
+	s.err = errMsg
+	return evt, true
+}
+
+// publish sends events to subscribers. It must not be called with lk held as
+// a slow subscriber can block it.
+func (t *Tracker) publish(evts ...SectorEvent) {
+	for _, evt := range evts {
+		t.events.Pub(evt, sectorEventsTopic)
+	}
+}
+
+// Subscribe returns a channel on which every subsequent SectorEvent is
+// delivered. The channel is closed once ctx is done.
+func (t *Tracker) Subscribe(ctx context.Context) <-chan SectorEvent {
+	sub := t.events.Sub(sectorEventsTopic)
+	out := make(chan SectorEvent)
+
+	go func() {
+		defer close(out)
+		defer func() {
+			// Keep draining so that a publish in flight can't block the
+			// unsubscription.
+			go func() {
+				for range sub {
+				}
+			}()
+			t.events.Unsub(sub)
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e, ok := <-sub:
+				if !ok {
+					return
+				}
+				select {
+				case out <- e.(SectorEvent):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out
+}
+
+// Progress returns the progress of the sector with the given id.
+func (t *Tracker) Progress(sectorID uint64) (*Progress, error) {
+	t.lk.Lock()
+	defer t.lk.Unlock()
+	s, ok := t.sectors[sectorID]
+	if !ok {
+		return nil, fmt.Errorf("no progress of sector %d", sectorID)
+	}
+	return t.progress(s), nil
+}
+
+// List returns the progress of all sectors, by sector id.
+func (t *Tracker) List() []*Progress {
+	t.lk.Lock()
+	defer t.lk.Unlock()
+
+	var all []*Progress
+	for _, s := range t.sectors {
+		all = append(all, t.progress(s))
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].SectorID < all[j].SectorID })
+	return all
+}
+
+func (t *Tracker) progress(s *sector) *Progress {
+	p := &Progress{
+		SectorID:     s.id,
+		Stage:        s.stage,
+		Started:      s.started,
+		StageStarted: s.stageStarted,
+		Elapsed:      t.now().Sub(s.stageStarted),
+		Error:        s.err,
+	}
+
+	switch s.stage {
+	case Committed, Proving:
+		p.Percent = 100
+	case Failed, Faulted:
+	default:
+		usual, ok := t.usual[s.stage]
+		if !ok || usual <= 0 {
+			break
+		}
+		p.Percent = int(100 * p.Elapsed / usual)
+		if p.Percent > 99 {
+			p.Percent = 99
+		}
+		if p.Elapsed < usual {
+			p.ETA = usual - p.Elapsed
+		}
+		p.Stuck = p.Elapsed > StuckFactor*usual
+	}
+	return p
+}
@@ -0,0 +1,136 @@
+package sctr
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestTracker() (*Tracker, func(time.Duration)) {
+	now := time.Unix(1000, 0)
+	t := NewTracker()
+	t.now = func() time.Time { return now }
+	return t, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestTrackerProgress(t *testing.T) {
+	t.Parallel()
+
+	t.Run("no estimate before a sector completed the stage", func(t *testing.T) {
+		tracker, advance := newTestTracker()
+		tracker.Enter(1, Staged)
+		advance(time.Minute)
+
+		p, err := tracker.Progress(1)
+		require.NoError(t, err)
+		assert.Equal(t, Staged, p.Stage)
+		assert.Equal(t, time.Minute, p.Elapsed)
+		assert.Equal(t, 0, p.Percent)
+		assert.Equal(t, time.Duration(0), p.ETA)
+	})
+
+	t.Run("estimates from earlier sectors", func(t *testing.T) {
+		tracker, advance := newTestTracker()
+		tracker.Enter(1, Sealing)
+		advance(10 * time.Minute)
+		tracker.Enter(1, Committing)
+
+		tracker.Enter(2, Sealing)
+		advance(4 * time.Minute)
+
+		p, err := tracker.Progress(2)
+		require.NoError(t, err)
+		assert.Equal(t, 40, p.Percent)
+		assert.Equal(t, 6*time.Minute, p.ETA)
+		assert.False(t, p.Stuck)
+
+		advance(30 * time.Minute)
+		p, err = tracker.Progress(2)
+		require.NoError(t, err)
+		assert.Equal(t, 99, p.Percent)
+		assert.Equal(t, time.Duration(0), p.ETA)
+		assert.True(t, p.Stuck)
+	})
+
+	t.Run("moves all sectors of a stage", func(t *testing.T) {
+		tracker, _ := newTestTracker()
+		tracker.Enter(1, Staged)
+		tracker.Enter(2, Staged)
+		tracker.Enter(3, Committed)
+		tracker.EnterAll(Staged, Sealing)
+
+		var stages []Stage
+		for _, p := range tracker.List() {
+			stages = append(stages, p.Stage)
+		}
+		assert.Equal(t, []Stage{Sealing, Sealing, Committed}, stages)
+	})
+
+	t.Run("reports failures", func(t *testing.T) {
+		tracker, _ := newTestTracker()
+		tracker.Enter(1, Sealing)
+		tracker.Fail(1, errors.New("out of disk"))
+
+		p, err := tracker.Progress(1)
+		require.NoError(t, err)
+		assert.Equal(t, Failed, p.Stage)
+		assert.Equal(t, "out of disk", p.Error)
+	})
+
+	t.Run("unknown sector", func(t *testing.T) {
+		tracker, _ := newTestTracker()
+		_, err := tracker.Progress(1)
+		assert.EqualError(t, err, "no progress of sector 1")
+	})
+}
+
+func TestTrackerSubscribe(t *testing.T) {
+	t.Parallel()
+
+	next := func(t *testing.T, events <-chan SectorEvent) SectorEvent {
+		select {
+		case evt := <-events:
+			return evt
+		case <-time.After(time.Second):
+			require.Fail(t, "no sector event")
+			return SectorEvent{}
+		}
+	}
+
+	t.Run("publishes every change of stage", func(t *testing.T) {
+		tracker, _ := newTestTracker()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		events := tracker.Subscribe(ctx)
+
+		tracker.Enter(1, Staged)
+		tracker.Enter(2, Staged)
+		tracker.Enter(1, Staged)
+		tracker.EnterAll(Staged, Sealing)
+		tracker.Fail(2, errors.New("out of disk space"))
+		tracker.Enter(1, Proving)
+
+		assert.Equal(t, SectorEvent{SectorID: 1, To: Staged, Time: time.Unix(1000, 0)}, next(t, events))
+		assert.Equal(t, SectorEvent{SectorID: 2, To: Staged, Time: time.Unix(1000, 0)}, next(t, events))
+		assert.Equal(t, SectorEvent{SectorID: 1, From: Staged, To: Sealing, Time: time.Unix(1000, 0)}, next(t, events))
+		assert.Equal(t, SectorEvent{SectorID: 2, From: Staged, To: Sealing, Time: time.Unix(1000, 0)}, next(t, events))
+		assert.Equal(t, SectorEvent{SectorID: 2, From: Sealing, To: Failed, Time: time.Unix(1000, 0), Error: "out of disk space"}, next(t, events))
+		assert.Equal(t, SectorEvent{SectorID: 1, From: Sealing, To: Proving, Time: time.Unix(1000, 0)}, next(t, events))
+	})
+
+	t.Run("closes the channel when the context is done", func(t *testing.T) {
+		tracker, _ := newTestTracker()
+		ctx, cancel := context.WithCancel(context.Background())
+		events := tracker.Subscribe(ctx)
+		cancel()
+
+		for range events {
+		}
+		// publishing without subscribers doesn't block
+		tracker.Enter(1, Staged)
+	})
+}
@@ -0,0 +1,77 @@
+package porcelain
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+
+	"github.com/filecoin-project/go-filecoin/address"
+)
+
+// arsAPI is the subset of the plumbing.API that ActorReadState uses.
+type arsAPI interface {
+	ActorGetStateDecoded(ctx context.Context, addr address.Address) (interface{}, error)
+}
+
+// ActorReadState returns the state of the actor at addr as generic JSON
+// values (maps, slices, strings, numbers, bools and nil). If path is not
+// empty only the field it points to is returned. A path is a dot separated
+// list of object field names, matched case insensitively, and array indices,
+// e.g. "asks.0.price".
+func ActorReadState(ctx context.Context, plumbing arsAPI, addr address.Address, path string) (interface{}, error) {
+	decoded, err := plumbing.ActorGetStateDecoded(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	// Round trip through JSON so that state of any type can be walked.
+	raw, err := json.Marshal(decoded)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode actor state")
+	}
+	var val interface{}
+	if err := json.Unmarshal(raw, &val); err != nil {
+		return nil, errors.Wrap(err, "failed to encode actor state")
+	}
+
+	if path == "" {
+		return val, nil
+	}
+
+	fields := strings.Split(path, ".")
+	for i, field := range fields {
+		val, err = selectField(val, field)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid path %s at %s", path, strings.Join(fields[:i+1], "."))
+		}
+	}
+	return val, nil
+}
+
+// selectField returns the field of val named field, or its element at index
+// field if val is an array.
+func selectField(val interface{}, field string) (interface{}, error) {
+	switch v := val.(type) {
+	case map[string]interface{}:
+		if fv, ok := v[field]; ok {
+			return fv, nil
+		}
+		for k, fv := range v {
+			if strings.EqualFold(k, field) {
+				return fv, nil
+			}
+		}
+		return nil, errors.New("no such field")
+	case []interface{}:
+		idx, err := strconv.Atoi(field)
+		if err != nil || idx < 0 || idx >= len(v) {
+			return nil, errors.Errorf("index out of range [0, %d)", len(v))
+		}
+		return v[idx], nil
+	default:
+		return nil, errors.New("not an object or an array")
+	}
+}
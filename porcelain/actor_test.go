@@ -0,0 +1,75 @@
+package porcelain_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/porcelain"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+type fakeActorReadStatePlumbing struct {
+	state interface{}
+}
+
+func (fp *fakeActorReadStatePlumbing) ActorGetStateDecoded(ctx context.Context, addr address.Address) (interface{}, error) {
+	return fp.state, nil
+}
+
+type fakeActorState struct {
+	Owner   address.Address
+	Balance *types.AttoFIL
+	Asks    []struct{ ID int }
+}
+
+func TestActorReadState(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	owner := address.NewForTestGetter()()
+	fp := &fakeActorReadStatePlumbing{state: &fakeActorState{
+		Owner:   owner,
+		Balance: types.NewAttoFILFromFIL(3),
+		Asks:    []struct{ ID int }{{ID: 7}, {ID: 8}},
+	}}
+
+	t.Run("returns the whole state without a path", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		val, err := porcelain.ActorReadState(ctx, fp, owner, "")
+		require.NoError(err)
+		st, ok := val.(map[string]interface{})
+		require.True(ok)
+		assert.Equal(owner.String(), st["Owner"])
+		assert.Len(st["Asks"], 2)
+	})
+
+	t.Run("selects fields and array elements", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		val, err := porcelain.ActorReadState(ctx, fp, owner, "owner")
+		require.NoError(err)
+		assert.Equal(owner.String(), val)
+
+		val, err = porcelain.ActorReadState(ctx, fp, owner, "asks.1.id")
+		require.NoError(err)
+		assert.Equal(float64(8), val)
+	})
+
+	t.Run("rejects invalid paths", func(t *testing.T) {
+		assert := assert.New(t)
+
+		_, err := porcelain.ActorReadState(ctx, fp, owner, "nope")
+		assert.Error(err)
+		_, err = porcelain.ActorReadState(ctx, fp, owner, "asks.2")
+		assert.Error(err)
+		_, err = porcelain.ActorReadState(ctx, fp, owner, "owner.foo")
+		assert.Error(err)
+	})
+}
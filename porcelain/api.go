@@ -10,6 +10,8 @@ import (
 	minerActor "github.com/filecoin-project/go-filecoin/actor/builtin/miner"
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/plumbing"
+	"github.com/filecoin-project/go-filecoin/plumbing/msg"
+	"github.com/filecoin-project/go-filecoin/proofs/params"
 	"github.com/filecoin-project/go-filecoin/types"
 )
 
@@ -41,11 +43,23 @@ func New(plumbing *plumbing.API) *API {
 	return &API{plumbing}
 }
 
+// ActorReadState returns the state of an actor, or the field of it path points
+// to, as generic JSON values.
+func (a *API) ActorReadState(ctx context.Context, addr address.Address, path string) (interface{}, error) {
+	return ActorReadState(ctx, a, addr, path)
+}
+
 // ChainBlockHeight determines the current block height
 func (a *API) ChainBlockHeight(ctx context.Context) (*types.BlockHeight, error) {
 	return ChainBlockHeight(ctx, a)
 }
 
+// ChainLsPage lists a page of the tipsets of the chain passing filter, from
+// the tipset of cursor, or the head if it is empty, towards genesis
+func (a *API) ChainLsPage(ctx context.Context, filter ChainLsFilter, cursor string, limit int) (*ChainPage, error) {
+	return ChainLsPage(ctx, a, filter, cursor, limit)
+}
+
 // CreatePayments establishes a payment channel and create multiple payments against it
 func (a *API) CreatePayments(ctx context.Context, config CreatePaymentsParams) (*CreatePaymentsReturn, error) {
 	return CreatePayments(ctx, a, config)
@@ -76,6 +90,75 @@ func (a *API) MessageSendWithDefaultAddress(
 	)
 }
 
+// MessageEstimateGasPrice suggests a gas price based on the prices paid by
+// messages in the most recent nBlocks blocks.
+func (a *API) MessageEstimateGasPrice(ctx context.Context, nBlocks uint, percentile uint) (types.AttoFIL, error) {
+	return MessageEstimateGasPrice(ctx, a, nBlocks, percentile)
+}
+
+// SendBatch sends several messages from one address with consecutive nonces,
+// using the default from address if none is provided.
+func (a *API) SendBatch(ctx context.Context, from address.Address, batch []msg.BatchMessage) ([]cid.Cid, error) {
+	return SendBatch(ctx, a, from, batch)
+}
+
+// MultisigCreate creates a multisig wallet and returns its address once the
+// creation is mined.
+func (a *API) MultisigCreate(ctx context.Context, from address.Address, gasPrice types.AttoFIL, gasLimit types.GasUnits, signers []address.Address, required uint64, unlockDuration *types.BlockHeight, value *types.AttoFIL) (address.Address, error) {
+	return MultisigCreate(ctx, a, from, gasPrice, gasLimit, signers, required, unlockDuration, value)
+}
+
+// MultisigPropose proposes a send from a multisig wallet and returns the
+// transaction id once the proposal is mined.
+func (a *API) MultisigPropose(ctx context.Context, from address.Address, gasPrice types.AttoFIL, gasLimit types.GasUnits, wallet, to address.Address, value *types.AttoFIL) (uint64, error) {
+	return MultisigPropose(ctx, a, from, gasPrice, gasLimit, wallet, to, value)
+}
+
+// Events lists the events on chain matching filter, newest first.
+func (a *API) Events(ctx context.Context, filter EventFilter, limit uint) ([]*ChainEvent, error) {
+	return Events(ctx, a, filter, limit)
+}
+
+// MultisigApprove approves a pending multisig transaction.
+func (a *API) MultisigApprove(ctx context.Context, from address.Address, gasPrice types.AttoFIL, gasLimit types.GasUnits, wallet address.Address, txID uint64) error {
+	return MultisigApprove(ctx, a, from, gasPrice, gasLimit, wallet, txID)
+}
+
+// MultisigCancel cancels a pending multisig transaction.
+func (a *API) MultisigCancel(ctx context.Context, from address.Address, gasPrice types.AttoFIL, gasLimit types.GasUnits, wallet address.Address, txID uint64) error {
+	return MultisigCancel(ctx, a, from, gasPrice, gasLimit, wallet, txID)
+}
+
+// MultisigGetInfo queries the signers and pending transactions of a
+// multisig wallet.
+func (a *API) MultisigGetInfo(ctx context.Context, wallet address.Address) (*MultisigInfo, error) {
+	return MultisigGetInfo(ctx, a, wallet)
+}
+
+// WalletHistory lists the messages on chain sent from or to an address,
+// newest first.
+func (a *API) WalletHistory(ctx context.Context, addr address.Address, offset, limit uint) ([]*WalletHistoryEntry, error) {
+	return WalletHistory(ctx, a, addr, offset, limit)
+}
+
+// WalletSetDefaultAddress makes an address of the wallet the one messages
+// are sent from when no from address is given.
+func (a *API) WalletSetDefaultAddress(addr address.Address) error {
+	return WalletSetDefaultAddress(a, addr)
+}
+
+// WatchBalance streams the balance and nonce of an address, sending an event
+// whenever a new chain head changes them.
+func (a *API) WatchBalance(ctx context.Context, addr address.Address) <-chan interface{} {
+	return WatchBalance(ctx, a, addr)
+}
+
+// WatchEvents streams the events matching filter emitted by each new chain
+// head.
+func (a *API) WatchEvents(ctx context.Context, filter EventFilter) <-chan interface{} {
+	return WatchEvents(ctx, a, filter)
+}
+
 // MinerPreviewCreate previews the Gas cost of creating a miner
 func (a *API) MinerPreviewCreate(
 	ctx context.Context,
@@ -103,8 +186,8 @@ func (a *API) MinerGetPeerID(ctx context.Context, minerAddr address.Address) (pe
 }
 
 // MinerSetPrice configures the price of storage. See implementation for details.
-func (a *API) MinerSetPrice(ctx context.Context, from address.Address, miner address.Address, gasPrice types.AttoFIL, gasLimit types.GasUnits, price *types.AttoFIL, expiry *big.Int) (MinerSetPriceResponse, error) {
-	return MinerSetPrice(ctx, a, from, miner, gasPrice, gasLimit, price, expiry)
+func (a *API) MinerSetPrice(ctx context.Context, from address.Address, miner address.Address, gasPrice types.AttoFIL, gasLimit types.GasUnits, price *types.AttoFIL, expiry *big.Int, minSize, maxSize *types.BytesAmount) (MinerSetPriceResponse, error) {
+	return MinerSetPrice(ctx, a, from, miner, gasPrice, gasLimit, price, expiry, minSize, maxSize)
 }
 
 // MinerPreviewSetPrice calculates the amount of Gas needed for a call to MinerSetPrice.
@@ -115,8 +198,15 @@ func (a *API) MinerPreviewSetPrice(
 	miner address.Address,
 	price *types.AttoFIL,
 	expiry *big.Int,
+	minSize, maxSize *types.BytesAmount,
 ) (types.GasUnits, error) {
-	return MinerPreviewSetPrice(ctx, a, from, miner, price, expiry)
+	return MinerPreviewSetPrice(ctx, a, from, miner, price, expiry, minSize, maxSize)
+}
+
+// ProofsFetchParams fetches the parameters of the proofs for sectors of the
+// given size into the parameter cache
+func (a *API) ProofsFetchParams(ctx context.Context, size uint64, progress func(params.Progress)) error {
+	return ProofsFetchParams(ctx, a, size, progress)
 }
 
 // GetAndMaybeSetDefaultSenderAddress returns a default address from which to
@@ -3,6 +3,12 @@ package porcelain
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+
+	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/types"
 )
 
@@ -10,6 +16,11 @@ type chPlumbing interface {
 	ChainLs(ctx context.Context) <-chan interface{}
 }
 
+type chLsPlumbing interface {
+	ChainLs(ctx context.Context) <-chan interface{}
+	ChainLsFrom(ctx context.Context, start types.SortedCidSet) <-chan interface{}
+}
+
 // ChainBlockHeight determines the current block height
 func ChainBlockHeight(ctx context.Context, plumbing chPlumbing) (*types.BlockHeight, error) {
 	lsCtx, cancelLs := context.WithCancel(ctx)
@@ -18,12 +29,129 @@ func ChainBlockHeight(ctx context.Context, plumbing chPlumbing) (*types.BlockHei
 	cancelLs()
 
 	if head == nil {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		return nil, errors.New("could not retrieve block height")
 	}
+	ts, ok := head.(types.TipSet)
+	if !ok {
+		return nil, fmt.Errorf("could not retrieve block height: %v", head)
+	}
 
-	currentHeight, err := head.(types.TipSet).Height()
+	currentHeight, err := ts.Height()
 	if err != nil {
 		return nil, err
 	}
 	return types.NewBlockHeight(currentHeight), nil
 }
+
+// ChainLsFilter selects the tipsets, and the blocks of them, listed by
+// ChainLsPage.
+type ChainLsFilter struct {
+	// FromHeight and ToHeight bound the heights of the tipsets listed,
+	// inclusively. Nil bounds are open.
+	FromHeight *types.BlockHeight
+	ToHeight   *types.BlockHeight
+	// Miner, unless empty, only lists the blocks mined by it, and the
+	// tipsets holding any.
+	Miner address.Address
+}
+
+// ChainPage is a page of the tipsets of the chain, from the most recent.
+type ChainPage struct {
+	TipSets []types.TipSet
+	// Next is the cursor of the next page, empty on the last one.
+	Next string
+}
+
+// ChainLsPage lists the tipsets passing filter from the tipset of cursor,
+// or the head if it is empty, towards genesis. At most limit tipsets are
+// listed if it is positive, and the page returned holds the cursor of the
+// following ones.
+func ChainLsPage(ctx context.Context, plumbing chLsPlumbing, filter ChainLsFilter, cursor string, limit int) (*ChainPage, error) {
+	lsCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var history <-chan interface{}
+	if cursor == "" {
+		history = plumbing.ChainLs(lsCtx)
+	} else {
+		start, err := ParseChainCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		history = plumbing.ChainLsFrom(lsCtx, start)
+	}
+
+	page := &ChainPage{}
+	for raw := range history {
+		var ts types.TipSet
+		switch v := raw.(type) {
+		case error:
+			return nil, v
+		case types.TipSet:
+			ts = v
+		default:
+			return nil, fmt.Errorf("unexpected type %T in chain history", raw)
+		}
+
+		h, err := ts.Height()
+		if err != nil {
+			return nil, err
+		}
+		height := types.NewBlockHeight(h)
+		if filter.FromHeight != nil && height.LessThan(filter.FromHeight) {
+			break
+		}
+		if filter.ToHeight != nil && height.GreaterThan(filter.ToHeight) {
+			continue
+		}
+
+		if limit > 0 && len(page.TipSets) == limit {
+			page.Next = ChainCursor(ts.ToSortedCidSet())
+			break
+		}
+		if ts = filterTipSet(ts, filter); len(ts) > 0 {
+			page.TipSets = append(page.TipSets, ts)
+		}
+	}
+	return page, nil
+}
+
+// filterTipSet returns the blocks of ts passing filter.
+func filterTipSet(ts types.TipSet, filter ChainLsFilter) types.TipSet {
+	if filter.Miner.Empty() {
+		return ts
+	}
+	filtered := types.TipSet{}
+	for key, blk := range ts {
+		if blk.Miner == filter.Miner {
+			filtered[key] = blk
+		}
+	}
+	return filtered
+}
+
+// ChainCursor returns the cursor of the tipset with the cids of key, the
+// cids separated by commas.
+func ChainCursor(key types.SortedCidSet) string {
+	ids := make([]string, 0, key.Len())
+	for it := key.Iter(); !it.Complete(); it.Next() {
+		ids = append(ids, it.Value().String())
+	}
+	return strings.Join(ids, ",")
+}
+
+// ParseChainCursor returns the cids of the tipset of cursor.
+func ParseChainCursor(cursor string) (types.SortedCidSet, error) {
+	var key types.SortedCidSet
+	for _, s := range strings.Split(cursor, ",") {
+		id, err := cid.Decode(strings.TrimSpace(s))
+		if err != nil {
+			return types.SortedCidSet{}, fmt.Errorf("invalid cursor %q: %s", cursor, err)
+		}
+		key.Add(id)
+	}
+	return key, nil
+}
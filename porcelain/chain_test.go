@@ -0,0 +1,170 @@
+package porcelain_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/porcelain"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+type fakeChainLsPlumbing struct {
+	// tipSets are the tipsets of the chain, from the head.
+	tipSets []types.TipSet
+}
+
+func (fp *fakeChainLsPlumbing) ChainLs(ctx context.Context) <-chan interface{} {
+	return fp.history(fp.tipSets)
+}
+
+func (fp *fakeChainLsPlumbing) ChainLsFrom(ctx context.Context, start types.SortedCidSet) <-chan interface{} {
+	for i, ts := range fp.tipSets {
+		if ts.ToSortedCidSet().Equals(start) {
+			return fp.history(fp.tipSets[i:])
+		}
+	}
+	return fp.history(nil)
+}
+
+func (fp *fakeChainLsPlumbing) history(tipSets []types.TipSet) <-chan interface{} {
+	out := make(chan interface{}, len(tipSets))
+	for _, ts := range tipSets {
+		out <- ts
+	}
+	close(out)
+	return out
+}
+
+func requireTipSetMinedBy(require *require.Assertions, height uint64, miners ...address.Address) types.TipSet {
+	var blocks []*types.Block
+	for i, m := range miners {
+		blocks = append(blocks, &types.Block{Height: types.Uint64(height), Miner: m, Nonce: types.Uint64(i)})
+	}
+	ts, err := types.NewTipSet(blocks...)
+	require.NoError(err)
+	return ts
+}
+
+func TestChainLsPage(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	addrs := address.NewForTestGetter()
+	minerA, minerB := addrs(), addrs()
+
+	newPlumbing := func(require *require.Assertions) *fakeChainLsPlumbing {
+		return &fakeChainLsPlumbing{tipSets: []types.TipSet{
+			requireTipSetMinedBy(require, 4, minerA),
+			requireTipSetMinedBy(require, 3, minerA, minerB),
+			requireTipSetMinedBy(require, 2, minerB),
+			requireTipSetMinedBy(require, 1, minerA),
+			requireTipSetMinedBy(require, 0, minerB),
+		}}
+	}
+
+	heights := func(require *require.Assertions, page *porcelain.ChainPage) []uint64 {
+		var hs []uint64
+		for _, ts := range page.TipSets {
+			h, err := ts.Height()
+			require.NoError(err)
+			hs = append(hs, h)
+		}
+		return hs
+	}
+
+	t.Run("lists the whole chain without filter nor limit", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		page, err := porcelain.ChainLsPage(ctx, newPlumbing(require), porcelain.ChainLsFilter{}, "", 0)
+		require.NoError(err)
+		assert.Equal([]uint64{4, 3, 2, 1, 0}, heights(require, page))
+		assert.Equal("", page.Next)
+	})
+
+	t.Run("bounds the heights", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		filter := porcelain.ChainLsFilter{FromHeight: types.NewBlockHeight(1), ToHeight: types.NewBlockHeight(3)}
+		page, err := porcelain.ChainLsPage(ctx, newPlumbing(require), filter, "", 0)
+		require.NoError(err)
+		assert.Equal([]uint64{3, 2, 1}, heights(require, page))
+	})
+
+	t.Run("keeps the blocks of the miner", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		page, err := porcelain.ChainLsPage(ctx, newPlumbing(require), porcelain.ChainLsFilter{Miner: minerB}, "", 0)
+		require.NoError(err)
+		assert.Equal([]uint64{3, 2, 0}, heights(require, page))
+		for _, ts := range page.TipSets {
+			require.Len(ts, 1)
+			assert.Equal(minerB, ts.ToSlice()[0].Miner)
+		}
+	})
+
+	t.Run("walks the chain page by page", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		fp := newPlumbing(require)
+		var all []uint64
+		cursor := ""
+		for i := 0; i < 3; i++ {
+			page, err := porcelain.ChainLsPage(ctx, fp, porcelain.ChainLsFilter{}, cursor, 2)
+			require.NoError(err)
+			all = append(all, heights(require, page)...)
+			cursor = page.Next
+			if cursor == "" {
+				break
+			}
+		}
+		assert.Equal("", cursor)
+		assert.Equal([]uint64{4, 3, 2, 1, 0}, all)
+	})
+
+	t.Run("rejects an invalid cursor", func(t *testing.T) {
+		require := require.New(t)
+
+		_, err := porcelain.ChainLsPage(ctx, newPlumbing(require), porcelain.ChainLsFilter{}, "nope", 0)
+		require.Error(err)
+	})
+}
+
+func TestChainCursor(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	newCid := types.NewCidForTestGetter()
+	key := types.NewSortedCidSet(newCid(), newCid())
+	parsed, err := porcelain.ParseChainCursor(porcelain.ChainCursor(key))
+	require.NoError(err)
+	assert.True(key.Equals(parsed))
+}
+
+func TestChainBlockHeight(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	plumbing := &fakeChainLsPlumbing{tipSets: []types.TipSet{
+		requireTipSetMinedBy(require, 2, address.TestAddress),
+		requireTipSetMinedBy(require, 1, address.TestAddress),
+	}}
+	height, err := porcelain.ChainBlockHeight(context.Background(), plumbing)
+	require.NoError(err)
+	assert.Equal(types.NewBlockHeight(2), height)
+
+	// a walk ended by ctx fails with its error
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = porcelain.ChainBlockHeight(ctx, &fakeChainLsPlumbing{})
+	assert.Equal(context.Canceled, err)
+}
@@ -0,0 +1,166 @@
+package porcelain
+
+import (
+	"context"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// EventFilter selects events. Its empty fields match any event.
+type EventFilter struct {
+	Actor  address.Address
+	Method string
+	Type   string
+}
+
+func (f EventFilter) matches(e *types.Event) bool {
+	return (f.Actor.Empty() || f.Actor == e.Actor) &&
+		(f.Method == "" || f.Method == e.Method) &&
+		(f.Type == "" || f.Type == e.Type)
+}
+
+// ChainEvent is an event emitted by a message on chain.
+type ChainEvent struct {
+	*types.Event
+	BlockHeight uint64  `json:"blockHeight"`
+	Message     cid.Cid `json:"message"`
+}
+
+// mrAPI is the subset of the plumbing.API that eventsOfTipSet uses.
+type mrAPI interface {
+	MessageReceipts(ctx context.Context, ts types.TipSet) (map[cid.Cid]*types.MessageReceipt, error)
+}
+
+// evAPI is the subset of the plumbing.API that Events uses.
+type evAPI interface {
+	mrAPI
+	ChainLs(ctx context.Context) <-chan interface{}
+}
+
+// Events lists the events on chain matching filter, newest tipset first and
+// in the order they were emitted within a tipset. It returns at most limit
+// events, or all of them if limit is 0. The chain is walked from the head
+// until limit events are found, so a small limit keeps the query cheap.
+func Events(ctx context.Context, plumbing evAPI, filter EventFilter, limit uint) ([]*ChainEvent, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var events []*ChainEvent
+	for raw := range plumbing.ChainLs(ctx) {
+		switch v := raw.(type) {
+		case error:
+			return nil, errors.Wrap(v, "failed to walk chain")
+		case types.TipSet:
+			tsEvents, err := eventsOfTipSet(ctx, plumbing, v, filter)
+			if err != nil {
+				return nil, err
+			}
+			for _, e := range tsEvents {
+				events = append(events, e)
+				if limit > 0 && uint(len(events)) >= limit {
+					return events, nil
+				}
+			}
+		}
+	}
+
+	return events, nil
+}
+
+// weAPI is the subset of the plumbing.API that WatchEvents uses.
+type weAPI interface {
+	mrAPI
+	ChainHeadEvents(ctx context.Context) <-chan types.TipSet
+}
+
+// WatchEvents streams the events matching filter emitted by each new chain
+// head. The channel receives *ChainEvent values; if an error is encountered
+// it is sent and the channel is closed. The channel is also closed once ctx
+// is done. Tipsets the head skips over when it advances by several at once
+// are not looked at.
+func WatchEvents(ctx context.Context, plumbing weAPI, filter EventFilter) <-chan interface{} {
+	out := make(chan interface{})
+	heads := plumbing.ChainHeadEvents(ctx)
+
+	go func() {
+		defer close(out)
+
+		send := func(v interface{}) bool {
+			select {
+			case out <- v:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for ts := range heads {
+			events, err := eventsOfTipSet(ctx, plumbing, ts, filter)
+			if err != nil {
+				send(err)
+				return
+			}
+			for _, e := range events {
+				if !send(e) {
+					return
+				}
+			}
+		}
+	}()
+
+	return out
+}
+
+// eventsOfTipSet returns the events of ts matching filter. The receipts of
+// tipsets with several blocks are recomputed by the plumbing, since a block's
+// receipts only account for its own messages.
+func eventsOfTipSet(ctx context.Context, plumbing mrAPI, ts types.TipSet, filter EventFilter) ([]*ChainEvent, error) {
+	receipts, err := plumbing.MessageReceipts(ctx, ts)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get receipts")
+	}
+	height, err := ts.Height()
+	if err != nil {
+		return nil, err
+	}
+
+	// Walk the messages in their canonical order, the order they were
+	// applied in, once each.
+	seen := make(map[cid.Cid]struct{})
+	blks := ts.ToSlice()
+	types.SortBlocks(blks)
+
+	var events []*ChainEvent
+	for _, blk := range blks {
+		for _, msg := range blk.Messages {
+			c, err := msg.Cid()
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to compute message cid")
+			}
+			if _, ok := seen[c]; ok {
+				continue
+			}
+			seen[c] = struct{}{}
+
+			rcpt := receipts[c]
+			if rcpt == nil {
+				continue
+			}
+			for _, e := range rcpt.Events {
+				if !filter.matches(e) {
+					continue
+				}
+				events = append(events, &ChainEvent{
+					Event:       e,
+					BlockHeight: height,
+					Message:     c,
+				})
+			}
+		}
+	}
+	return events, nil
+}
@@ -0,0 +1,162 @@
+package porcelain_test
+
+import (
+	"context"
+	"testing"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/porcelain"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+type fakeEventsPlumbing struct {
+	tipSets []types.TipSet
+}
+
+func (fp *fakeEventsPlumbing) ChainLs(ctx context.Context) <-chan interface{} {
+	out := make(chan interface{}, len(fp.tipSets))
+	for _, ts := range fp.tipSets {
+		out <- ts
+	}
+	close(out)
+	return out
+}
+
+func (fp *fakeEventsPlumbing) ChainHeadEvents(ctx context.Context) <-chan types.TipSet {
+	out := make(chan types.TipSet, len(fp.tipSets))
+	for _, ts := range fp.tipSets {
+		out <- ts
+	}
+	close(out)
+	return out
+}
+
+func (fp *fakeEventsPlumbing) MessageReceipts(ctx context.Context, ts types.TipSet) (map[cid.Cid]*types.MessageReceipt, error) {
+	receipts := make(map[cid.Cid]*types.MessageReceipt)
+	for _, blk := range ts {
+		for i, rcpt := range blk.MessageReceipts {
+			c, err := blk.Messages[i].Cid()
+			if err != nil {
+				return nil, err
+			}
+			receipts[c] = rcpt
+		}
+	}
+	return receipts, nil
+}
+
+func blockWithEvents(height, firstNonce uint64, events ...[]*types.Event) *types.Block {
+	blk := &types.Block{Height: types.Uint64(height), Nonce: types.Uint64(firstNonce)}
+	for i, evts := range events {
+		smsg := &types.SignedMessage{}
+		smsg.Message = *types.NewMessage(address.TestAddress, address.TestAddress2, firstNonce+uint64(i), nil, "", nil)
+		blk.Messages = append(blk.Messages, smsg)
+		blk.MessageReceipts = append(blk.MessageReceipts, &types.MessageReceipt{Events: evts})
+	}
+	return blk
+}
+
+func requireTipSetWithEvents(require *require.Assertions, height uint64, events ...[]*types.Event) types.TipSet {
+	ts, err := types.NewTipSet(blockWithEvents(height, 0, events...))
+	require.NoError(err)
+	return ts
+}
+
+func TestEvents(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	addrGetter := address.NewForTestGetter()
+	market, broker := addrGetter(), addrGetter()
+
+	created := &types.Event{Actor: market, Method: "createMiner", Type: "minerCreated"}
+	power := &types.Event{Actor: market, Method: "updatePower", Type: "powerUpdated"}
+	redeemed := &types.Event{Actor: broker, Method: "redeem", Type: "channelRedeemed"}
+
+	newPlumbing := func(require *require.Assertions) *fakeEventsPlumbing {
+		return &fakeEventsPlumbing{tipSets: []types.TipSet{
+			requireTipSetWithEvents(require, 2, []*types.Event{redeemed}, nil, []*types.Event{power}),
+			requireTipSetWithEvents(require, 1, []*types.Event{created, power}),
+		}}
+	}
+
+	t.Run("lists events newest first", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		events, err := porcelain.Events(ctx, newPlumbing(require), porcelain.EventFilter{}, 0)
+		require.NoError(err)
+		require.Len(events, 4)
+
+		assert.Equal(redeemed, events[0].Event)
+		assert.Equal(uint64(2), events[0].BlockHeight)
+		assert.Equal(power, events[1].Event)
+		assert.Equal(created, events[2].Event)
+		assert.Equal(uint64(1), events[2].BlockHeight)
+		assert.Equal(events[2].Message, events[3].Message)
+		assert.NotEqual(events[0].Message, events[1].Message)
+	})
+
+	t.Run("filters by actor, method and type", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		events, err := porcelain.Events(ctx, newPlumbing(require), porcelain.EventFilter{Actor: market}, 0)
+		require.NoError(err)
+		assert.Len(events, 3)
+
+		events, err = porcelain.Events(ctx, newPlumbing(require), porcelain.EventFilter{Actor: market, Method: "updatePower"}, 0)
+		require.NoError(err)
+		assert.Len(events, 2)
+
+		events, err = porcelain.Events(ctx, newPlumbing(require), porcelain.EventFilter{Type: "channelRedeemed"}, 0)
+		require.NoError(err)
+		require.Len(events, 1)
+		assert.Equal(redeemed, events[0].Event)
+	})
+
+	t.Run("limits the number of events", func(t *testing.T) {
+		require := require.New(t)
+
+		events, err := porcelain.Events(ctx, newPlumbing(require), porcelain.EventFilter{}, 2)
+		require.NoError(err)
+		require.Len(events, 2)
+	})
+
+	t.Run("lists the events of tipsets with several blocks", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		ts, err := types.NewTipSet(
+			blockWithEvents(1, 0, []*types.Event{created}),
+			blockWithEvents(1, 1, []*types.Event{redeemed}),
+		)
+		require.NoError(err)
+
+		events, err := porcelain.Events(ctx, &fakeEventsPlumbing{tipSets: []types.TipSet{ts}}, porcelain.EventFilter{}, 0)
+		require.NoError(err)
+		require.Len(events, 2)
+		assert.NotEqual(events[0].Message, events[1].Message)
+	})
+
+	t.Run("watches the events of new heads", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		var events []*porcelain.ChainEvent
+		for raw := range porcelain.WatchEvents(ctx, newPlumbing(require), porcelain.EventFilter{Type: "powerUpdated"}) {
+			evt, ok := raw.(*porcelain.ChainEvent)
+			require.True(ok, "unexpected %v", raw)
+			events = append(events, evt)
+		}
+
+		require.Len(events, 2)
+		assert.Equal(uint64(2), events[0].BlockHeight)
+		assert.Equal(uint64(1), events[1].BlockHeight)
+	})
+}
@@ -0,0 +1,74 @@
+package porcelain
+
+import (
+	"context"
+	"sort"
+
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// DefaultGasEstimateBlocks is the number of recent blocks the gas estimator
+// looks at when no explicit number is given.
+const DefaultGasEstimateBlocks = 10
+
+// DefaultGasEstimatePercentile is the percentile of recently paid gas prices
+// suggested by the gas estimator when no explicit percentile is given.
+const DefaultGasEstimatePercentile = 50
+
+// DefaultGasPrice is suggested when there are no recent messages on chain
+// from which to derive an estimate.
+var DefaultGasPrice = types.NewGasPrice(0)
+
+// gepAPI is the subset of the plumbing.API that MessageEstimateGasPrice uses.
+type gepAPI interface {
+	ChainLs(ctx context.Context) <-chan interface{}
+}
+
+// MessageEstimateGasPrice suggests a gas price by looking at the gas prices
+// of the messages included in the most recent nBlocks blocks and picking the
+// given percentile (0-100) of them. If no messages were included in those
+// blocks DefaultGasPrice is returned.
+func MessageEstimateGasPrice(ctx context.Context, plumbing gepAPI, nBlocks uint, percentile uint) (types.AttoFIL, error) {
+	if nBlocks == 0 {
+		return types.AttoFIL{}, errors.New("must look at one or more blocks to estimate gas price")
+	}
+	if percentile > 100 {
+		return types.AttoFIL{}, errors.Errorf("invalid percentile %d", percentile)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var prices []types.AttoFIL
+	seen := uint(0)
+	for raw := range plumbing.ChainLs(ctx) {
+		switch v := raw.(type) {
+		case error:
+			return types.AttoFIL{}, errors.Wrap(v, "failed to walk chain")
+		case types.TipSet:
+			for _, blk := range v.ToSlice() {
+				for _, msg := range blk.Messages {
+					prices = append(prices, msg.GasPrice)
+				}
+				seen++
+				if seen >= nBlocks {
+					break
+				}
+			}
+		}
+		if seen >= nBlocks {
+			break
+		}
+	}
+
+	if len(prices) == 0 {
+		return DefaultGasPrice, nil
+	}
+
+	sort.Slice(prices, func(i, j int) bool { return prices[i].LessThan(&prices[j]) })
+
+	idx := (len(prices) - 1) * int(percentile) / 100
+	return prices[idx], nil
+}
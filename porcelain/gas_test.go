@@ -0,0 +1,99 @@
+package porcelain_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/porcelain"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+type fakeGasEstimatePlumbing struct {
+	tipSets []types.TipSet
+}
+
+func (fp *fakeGasEstimatePlumbing) ChainLs(ctx context.Context) <-chan interface{} {
+	out := make(chan interface{}, len(fp.tipSets))
+	for _, ts := range fp.tipSets {
+		out <- ts
+	}
+	close(out)
+	return out
+}
+
+func requireTipSetWithPrices(require *require.Assertions, height uint64, prices ...int64) types.TipSet {
+	blk := &types.Block{Height: types.Uint64(height)}
+	for _, p := range prices {
+		msg := &types.SignedMessage{}
+		msg.GasPrice = types.NewGasPrice(p)
+		blk.Messages = append(blk.Messages, msg)
+	}
+	ts, err := types.NewTipSet(blk)
+	require.NoError(err)
+	return ts
+}
+
+func TestMessageEstimateGasPrice(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("picks the requested percentile of recent prices", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		fp := &fakeGasEstimatePlumbing{tipSets: []types.TipSet{
+			requireTipSetWithPrices(require, 3, 5, 1),
+			requireTipSetWithPrices(require, 2, 3, 4, 2),
+		}}
+
+		median, err := porcelain.MessageEstimateGasPrice(ctx, fp, 2, 50)
+		require.NoError(err)
+		assert.Equal(types.NewGasPrice(3), median)
+
+		max, err := porcelain.MessageEstimateGasPrice(ctx, fp, 2, 100)
+		require.NoError(err)
+		assert.Equal(types.NewGasPrice(5), max)
+	})
+
+	t.Run("only looks at the most recent blocks", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		fp := &fakeGasEstimatePlumbing{tipSets: []types.TipSet{
+			requireTipSetWithPrices(require, 3, 7),
+			requireTipSetWithPrices(require, 2, 100),
+		}}
+
+		price, err := porcelain.MessageEstimateGasPrice(ctx, fp, 1, 100)
+		require.NoError(err)
+		assert.Equal(types.NewGasPrice(7), price)
+	})
+
+	t.Run("falls back to the default price on an empty chain", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		fp := &fakeGasEstimatePlumbing{tipSets: []types.TipSet{
+			requireTipSetWithPrices(require, 1),
+		}}
+
+		price, err := porcelain.MessageEstimateGasPrice(ctx, fp, 10, 50)
+		require.NoError(err)
+		assert.Equal(porcelain.DefaultGasPrice, price)
+	})
+
+	t.Run("rejects bad arguments", func(t *testing.T) {
+		assert := assert.New(t)
+
+		fp := &fakeGasEstimatePlumbing{}
+
+		_, err := porcelain.MessageEstimateGasPrice(ctx, fp, 0, 50)
+		assert.Error(err)
+		_, err = porcelain.MessageEstimateGasPrice(ctx, fp, 1, 101)
+		assert.Error(err)
+	})
+}
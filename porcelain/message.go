@@ -7,6 +7,7 @@ import (
 	logging "gx/ipfs/QmcuXC5cxs79ro2cUuHs4HQ2bkDLJUYokwL8aivcX6HW3C/go-log"
 
 	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/plumbing/msg"
 	"github.com/filecoin-project/go-filecoin/types"
 )
 
@@ -47,6 +48,28 @@ func MessageSendWithDefaultAddress(
 	return plumbing.MessageSend(ctx, from, to, value, gasPrice, gasLimit, method, params...)
 }
 
+// sbAPI is the subset of the plumbing.API that SendBatch uses.
+type sbAPI interface {
+	GetAndMaybeSetDefaultSenderAddress() (address.Address, error)
+	MessageSendBatch(ctx context.Context, from address.Address, batch []msg.BatchMessage) ([]cid.Cid, error)
+}
+
+// SendBatch sends all messages in batch from the same address, using the
+// default from address if none is provided. The messages get consecutive
+// nonces in the order given and are enqueued atomically. The returned CIDs
+// are in the same order as the batch.
+func SendBatch(ctx context.Context, plumbing sbAPI, from address.Address, batch []msg.BatchMessage) ([]cid.Cid, error) {
+	if from == (address.Address{}) {
+		ret, err := plumbing.GetAndMaybeSetDefaultSenderAddress()
+		if (err != nil && err == ErrNoDefaultFromAddress) || ret == (address.Address{}) {
+			return nil, ErrNoDefaultFromAddress
+		}
+		from = ret
+	}
+
+	return plumbing.MessageSendBatch(ctx, from, batch)
+}
+
 // gamsdsaAPI is the subset of the plumbing.API that GetAndMaybeSetDefaultSenderAddress uses.
 type gamsdsaAPI interface {
 	ConfigGet(dottedPath string) (interface{}, error)
@@ -60,9 +83,11 @@ type gamsdsaAPI interface {
 // sets it as the default in the config.
 func GetAndMaybeSetDefaultSenderAddress(plumbing gamsdsaAPI) (address.Address, error) {
 	ret, err := plumbing.ConfigGet("wallet.defaultAddress")
-	addr := ret.(address.Address)
-	if err != nil || addr != (address.Address{}) {
-		return addr, err
+	if err != nil {
+		return address.Address{}, err
+	}
+	if addr := ret.(address.Address); addr != (address.Address{}) {
+		return addr, nil
 	}
 
 	// No default is set; pick the 0th and make it the default.
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"math/big"
 
+	pstore "gx/ipfs/QmPiemjiKBC9VA7vZF82m4x1oygtg2c2YVqag8PX7dN1BD/go-libp2p-peerstore"
 	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
 	cbor "gx/ipfs/QmRoARq3nkUb13HSKZGepCZSWe5GrVPwx7xURJGZ7KWv9V/go-ipld-cbor"
 	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
@@ -103,9 +104,10 @@ type MinerSetPriceResponse struct {
 }
 
 // MinerSetPrice configures the price of storage, then sends an ask advertising that price and waits for it to be mined.
-// If minerAddr is empty, the default miner will be used.
+// If minerAddr is empty, the default miner will be used. The ask is for pieces of any size unless minSize or maxSize
+// bound it.
 // This method is non-transactional in the sense that it will set the price whether or not it creates the ask successfully.
-func MinerSetPrice(ctx context.Context, plumbing mspAPI, from address.Address, miner address.Address, gasPrice types.AttoFIL, gasLimit types.GasUnits, price *types.AttoFIL, expiry *big.Int) (MinerSetPriceResponse, error) {
+func MinerSetPrice(ctx context.Context, plumbing mspAPI, from address.Address, miner address.Address, gasPrice types.AttoFIL, gasLimit types.GasUnits, price *types.AttoFIL, expiry *big.Int, minSize, maxSize *types.BytesAmount) (MinerSetPriceResponse, error) {
 	res := MinerSetPriceResponse{
 		Price: price,
 	}
@@ -134,7 +136,8 @@ func MinerSetPrice(ctx context.Context, plumbing mspAPI, from address.Address, m
 	}
 
 	// create ask
-	res.AddAskCid, err = plumbing.MessageSendWithDefaultAddress(ctx, from, res.MinerAddr, types.NewZeroAttoFIL(), gasPrice, gasLimit, "addAsk", price, expiry)
+	method, params := addAskMessage(price, expiry, minSize, maxSize)
+	res.AddAskCid, err = plumbing.MessageSendWithDefaultAddress(ctx, from, res.MinerAddr, types.NewZeroAttoFIL(), gasPrice, gasLimit, method, params...)
 	if err != nil {
 		return res, errors.Wrap(err, "couldn't send message")
 	}
@@ -160,7 +163,7 @@ type mpspAPI interface {
 
 // MinerPreviewSetPrice calculates the amount of Gas needed for a call to MinerSetPrice.
 // This method accepts all the same arguments as MinerSetPrice.
-func MinerPreviewSetPrice(ctx context.Context, plumbing mpspAPI, from address.Address, miner address.Address, price *types.AttoFIL, expiry *big.Int) (types.GasUnits, error) {
+func MinerPreviewSetPrice(ctx context.Context, plumbing mpspAPI, from address.Address, miner address.Address, price *types.AttoFIL, expiry *big.Int, minSize, maxSize *types.BytesAmount) (types.GasUnits, error) {
 	// get miner address if not provided
 	if miner.Empty() {
 		minerValue, err := plumbing.ConfigGet("mining.minerAddress")
@@ -184,13 +187,13 @@ func MinerPreviewSetPrice(ctx context.Context, plumbing mpspAPI, from address.Ad
 	}
 
 	// create ask
+	method, params := addAskMessage(price, expiry, minSize, maxSize)
 	usedGas, err := plumbing.MessagePreview(
 		ctx,
 		from,
 		miner,
-		"addAsk",
-		price,
-		expiry,
+		method,
+		params...,
 	)
 	if err != nil {
 		return types.NewGasUnits(0), errors.Wrap(err, "couldn't preview message")
@@ -199,6 +202,21 @@ func MinerPreviewSetPrice(ctx context.Context, plumbing mpspAPI, from address.Ad
 	return usedGas, nil
 }
 
+// addAskMessage returns the method and params of the message adding an ask
+// for the given price and expiry, bounded by minSize and maxSize if set.
+func addAskMessage(price *types.AttoFIL, expiry *big.Int, minSize, maxSize *types.BytesAmount) (string, []interface{}) {
+	if minSize == nil && maxSize == nil {
+		return "addAsk", []interface{}{price, expiry}
+	}
+	if minSize == nil {
+		minSize = types.ZeroBytes
+	}
+	if maxSize == nil {
+		maxSize = types.ZeroBytes
+	}
+	return "addAskWithPieceSizes", []interface{}{price, expiry, minSize, maxSize}
+}
+
 // mgoaAPI is the subset of the plumbing.API that MinerGetOwnerAddress uses.
 type mgoaAPI interface {
 	MessageQuery(ctx context.Context, optFrom, to address.Address, method string, params ...interface{}) ([][]byte, *exec.FunctionSignature, error)
@@ -237,10 +255,26 @@ func MinerGetAsk(ctx context.Context, plumbing mgaAPI, minerAddr address.Address
 // mgpidAPI is the subset of the plumbing.API that MinerGetPeerID uses.
 type mgpidAPI interface {
 	MessageQuery(ctx context.Context, optFrom, to address.Address, method string, params ...interface{}) ([][]byte, *exec.FunctionSignature, error)
+	NetworkFindMiner(ctx context.Context, minerAddr address.Address) (pstore.PeerInfo, error)
 }
 
-// MinerGetPeerID queries for the peer id of the given miner
+// MinerGetPeerID queries for the peer id of the given miner. If the peer id
+// isn't on chain, it is looked up in the DHT, where miners announce their
+// peers.
 func MinerGetPeerID(ctx context.Context, plumbing mgpidAPI, minerAddr address.Address) (peer.ID, error) {
+	pid, chainErr := minerGetPeerIDFromChain(ctx, plumbing, minerAddr)
+	if chainErr == nil {
+		return pid, nil
+	}
+
+	info, err := plumbing.NetworkFindMiner(ctx, minerAddr)
+	if err != nil {
+		return peer.ID(""), errors.Wrapf(err, "peer id not on chain (%s) nor in the DHT", chainErr)
+	}
+	return info.ID, nil
+}
+
+func minerGetPeerIDFromChain(ctx context.Context, plumbing mgpidAPI, minerAddr address.Address) (peer.ID, error) {
 	res, _, err := plumbing.MessageQuery(ctx, address.Address{}, minerAddr, "getPeerID")
 	if err != nil {
 		return "", err
@@ -6,6 +6,7 @@ import (
 	"math/big"
 	"testing"
 
+	pstore "gx/ipfs/QmPiemjiKBC9VA7vZF82m4x1oygtg2c2YVqag8PX7dN1BD/go-libp2p-peerstore"
 	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
 	cbor "gx/ipfs/QmRoARq3nkUb13HSKZGepCZSWe5GrVPwx7xURJGZ7KWv9V/go-ipld-cbor"
 	"gx/ipfs/QmY5Grm8pJdiSSVsYxx4uNRgweY72EmYwuSDbRnbFok3iY/go-libp2p-peer"
@@ -155,7 +156,7 @@ func TestMinerSetPrice(t *testing.T) {
 
 		ctx := context.Background()
 		price := types.NewAttoFILFromFIL(50)
-		_, err := MinerSetPrice(ctx, plumbing, address.Address{}, address.Address{}, types.NewGasPrice(0), types.NewGasUnits(0), price, big.NewInt(0))
+		_, err := MinerSetPrice(ctx, plumbing, address.Address{}, address.Address{}, types.NewGasPrice(0), types.NewGasUnits(0), price, big.NewInt(0), nil, nil)
 		require.Error(err)
 		assert.Contains(err.Error(), "Test error in ConfigGet")
 	})
@@ -169,7 +170,7 @@ func TestMinerSetPrice(t *testing.T) {
 
 		ctx := context.Background()
 		price := types.NewAttoFILFromFIL(50)
-		_, err := MinerSetPrice(ctx, plumbing, address.Address{}, address.Address{}, types.NewGasPrice(0), types.NewGasUnits(0), price, big.NewInt(0))
+		_, err := MinerSetPrice(ctx, plumbing, address.Address{}, address.Address{}, types.NewGasPrice(0), types.NewGasUnits(0), price, big.NewInt(0), nil, nil)
 		require.Error(err)
 		assert.Contains(err.Error(), "Test error in ConfigSet")
 	})
@@ -182,7 +183,7 @@ func TestMinerSetPrice(t *testing.T) {
 
 		ctx := context.Background()
 		price := types.NewAttoFILFromFIL(50)
-		_, err := MinerSetPrice(ctx, plumbing, address.Address{}, address.Address{}, types.NewGasPrice(0), types.NewGasUnits(0), price, big.NewInt(0))
+		_, err := MinerSetPrice(ctx, plumbing, address.Address{}, address.Address{}, types.NewGasPrice(0), types.NewGasUnits(0), price, big.NewInt(0), nil, nil)
 		require.NoError(err)
 
 		configPrice, err := plumbing.config.Get("mining.storagePrice")
@@ -200,7 +201,7 @@ func TestMinerSetPrice(t *testing.T) {
 
 		ctx := context.Background()
 		price := types.NewAttoFILFromFIL(50)
-		_, err := MinerSetPrice(ctx, plumbing, address.Address{}, address.Address{}, types.NewGasPrice(0), types.NewGasUnits(0), price, big.NewInt(0))
+		_, err := MinerSetPrice(ctx, plumbing, address.Address{}, address.Address{}, types.NewGasPrice(0), types.NewGasUnits(0), price, big.NewInt(0), nil, nil)
 		require.Error(err)
 		assert.Contains(err.Error(), "Test error in MessageSend")
 
@@ -225,7 +226,7 @@ func TestMinerSetPrice(t *testing.T) {
 			return types.NewCidForTestGetter()(), nil
 		}
 
-		_, err := MinerSetPrice(ctx, plumbing, address.Address{}, minerAddr, types.NewGasPrice(0), types.NewGasUnits(0), price, big.NewInt(0))
+		_, err := MinerSetPrice(ctx, plumbing, address.Address{}, minerAddr, types.NewGasPrice(0), types.NewGasUnits(0), price, big.NewInt(0), nil, nil)
 		require.NoError(err)
 	})
 
@@ -246,7 +247,7 @@ func TestMinerSetPrice(t *testing.T) {
 			return types.NewCidForTestGetter()(), nil
 		}
 
-		_, err := MinerSetPrice(ctx, plumbing, address.Address{}, address.Address{}, types.NewGasPrice(0), types.NewGasUnits(0), price, big.NewInt(0))
+		_, err := MinerSetPrice(ctx, plumbing, address.Address{}, address.Address{}, types.NewGasPrice(0), types.NewGasUnits(0), price, big.NewInt(0), nil, nil)
 		require.NoError(err)
 	})
 
@@ -267,7 +268,30 @@ func TestMinerSetPrice(t *testing.T) {
 			return types.NewCidForTestGetter()(), nil
 		}
 
-		_, err := MinerSetPrice(ctx, plumbing, address.Address{}, address.Address{}, types.NewGasPrice(0), types.NewGasUnits(0), price, expiry)
+		_, err := MinerSetPrice(ctx, plumbing, address.Address{}, address.Address{}, types.NewGasPrice(0), types.NewGasUnits(0), price, expiry, nil, nil)
+		require.NoError(err)
+	})
+
+	t.Run("sends ask bounded by piece sizes", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		plumbing := newMinerSetPricePlumbing(assert, require)
+
+		ctx := context.Background()
+		price := types.NewAttoFILFromFIL(50)
+		expiry := big.NewInt(24)
+		maxSize := types.NewBytesAmount(1 << 20)
+
+		plumbing.messageSend = func(ctx context.Context, from, to address.Address, value *types.AttoFIL, gasPrice types.AttoFIL, gasLimit types.GasUnits, method string, params ...interface{}) (cid.Cid, error) {
+			assert.Equal("addAskWithPieceSizes", method)
+			require.Len(params, 4)
+			assert.Equal(types.ZeroBytes, params[2])
+			assert.Equal(maxSize, params[3])
+			return types.NewCidForTestGetter()(), nil
+		}
+
+		_, err := MinerSetPrice(ctx, plumbing, address.Address{}, address.Address{}, types.NewGasPrice(0), types.NewGasUnits(0), price, expiry, nil, maxSize)
 		require.NoError(err)
 	})
 
@@ -281,7 +305,7 @@ func TestMinerSetPrice(t *testing.T) {
 		ctx := context.Background()
 		price := types.NewAttoFILFromFIL(50)
 
-		_, err := MinerSetPrice(ctx, plumbing, address.Address{}, address.Address{}, types.NewGasPrice(0), types.NewGasUnits(0), price, big.NewInt(0))
+		_, err := MinerSetPrice(ctx, plumbing, address.Address{}, address.Address{}, types.NewGasPrice(0), types.NewGasUnits(0), price, big.NewInt(0), nil, nil)
 		require.Error(err)
 		assert.Contains(err.Error(), "Test error in MessageWait")
 	})
@@ -303,7 +327,7 @@ func TestMinerSetPrice(t *testing.T) {
 			return messageCid, nil
 		}
 
-		res, err := MinerSetPrice(ctx, plumbing, address.Address{}, minerAddr, types.NewGasPrice(0), types.NewGasUnits(0), price, expiry)
+		res, err := MinerSetPrice(ctx, plumbing, address.Address{}, minerAddr, types.NewGasPrice(0), types.NewGasUnits(0), price, expiry, nil, nil)
 		require.NoError(err)
 
 		assert.Equal(price, res.Price)
@@ -344,7 +368,7 @@ func TestMinerPreviewSetPrice(t *testing.T) {
 		ctx := context.Background()
 		price := types.NewAttoFILFromFIL(0)
 
-		usedGas, err := MinerPreviewSetPrice(ctx, plumbing, address.Address{}, address.Address{}, price, big.NewInt(0))
+		usedGas, err := MinerPreviewSetPrice(ctx, plumbing, address.Address{}, address.Address{}, price, big.NewInt(0), nil, nil)
 
 		require.NoError(err)
 		assert.Equal(types.NewGasUnits(7), usedGas)
@@ -365,14 +389,27 @@ func TestMinerGetOwnerAddress(t *testing.T) {
 	assert.Equal(address.TestAddress, addr)
 }
 
-type minerGetPeerIDPlumbing struct{}
+type minerGetPeerIDPlumbing struct {
+	notOnChain bool
+	inDHT      bool
+}
 
 func (mgop *minerGetPeerIDPlumbing) MessageQuery(ctx context.Context, optFrom, to address.Address, method string, params ...interface{}) ([][]byte, *exec.FunctionSignature, error) {
+	if mgop.notOnChain {
+		return nil, nil, errors.New("no peer id on chain")
+	}
 
 	peerID := requirePeerID()
 	return [][]byte{[]byte(peerID)}, nil, nil
 }
 
+func (mgop *minerGetPeerIDPlumbing) NetworkFindMiner(ctx context.Context, minerAddr address.Address) (pstore.PeerInfo, error) {
+	if !mgop.inDHT {
+		return pstore.PeerInfo{}, errors.New("no peer providing the miner")
+	}
+	return pstore.PeerInfo{ID: requirePeerID()}, nil
+}
+
 func TestMinerGetPeerID(t *testing.T) {
 	assert := assert.New(t)
 	require := require.New(t)
@@ -383,6 +420,20 @@ func TestMinerGetPeerID(t *testing.T) {
 	expected := requirePeerID()
 	require.NoError(err)
 	assert.Equal(expected, id)
+
+	t.Run("falls back to the DHT", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		id, err := MinerGetPeerID(context.Background(), &minerGetPeerIDPlumbing{notOnChain: true, inDHT: true}, address.TestAddress2)
+		require.NoError(err)
+		assert.Equal(requirePeerID(), id)
+	})
+
+	t.Run("fails if neither on chain nor in the DHT", func(t *testing.T) {
+		_, err := MinerGetPeerID(context.Background(), &minerGetPeerIDPlumbing{notOnChain: true}, address.TestAddress2)
+		assert.Error(err)
+	})
 }
 
 type minerGetAskPlumbing struct{}
@@ -0,0 +1,131 @@
+package porcelain
+
+import (
+	"context"
+	"math/big"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	cbor "gx/ipfs/QmRoARq3nkUb13HSKZGepCZSWe5GrVPwx7xURJGZ7KWv9V/go-ipld-cbor"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+
+	"github.com/filecoin-project/go-filecoin/abi"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/multisig"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/storagemarket"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/types"
+	vmErrors "github.com/filecoin-project/go-filecoin/vm/errors"
+)
+
+// msigSendAPI is the subset of the plumbing.API that the multisig calls
+// which send messages use.
+type msigSendAPI interface {
+	MessageSendWithDefaultAddress(ctx context.Context, from, to address.Address, value *types.AttoFIL, gasPrice types.AttoFIL, gasLimit types.GasUnits, method string, params ...interface{}) (cid.Cid, error)
+	MessageWait(ctx context.Context, msgCid cid.Cid, cb func(*types.Block, *types.SignedMessage, *types.MessageReceipt) error) error
+}
+
+// MultisigCreate creates a multisig wallet owned by signers that requires
+// `required` approvals to send funds, no earlier than unlockDuration blocks
+// after they were proposed. value is deposited into the new wallet. It waits
+// for the creation to be mined and returns the wallet's address.
+func MultisigCreate(ctx context.Context, plumbing msigSendAPI, from address.Address, gasPrice types.AttoFIL, gasLimit types.GasUnits, signers []address.Address, required uint64, unlockDuration *types.BlockHeight, value *types.AttoFIL) (address.Address, error) {
+	var walletAddr address.Address
+	err := sendAndWait(ctx, plumbing, from, address.StorageMarketAddress, value, gasPrice, gasLimit, storagemarket.Errors, func(receipt *types.MessageReceipt) error {
+		var err error
+		walletAddr, err = address.NewFromBytes(receipt.Return[0])
+		return err
+	}, "createMultisig", signers, big.NewInt(int64(required)), unlockDuration)
+	if err != nil {
+		return address.Address{}, errors.Wrap(err, "failed to create multisig wallet")
+	}
+	return walletAddr, nil
+}
+
+// MultisigPropose proposes sending value from a multisig wallet to the given
+// address, waits for the proposal to be mined and returns its transaction id.
+func MultisigPropose(ctx context.Context, plumbing msigSendAPI, from address.Address, gasPrice types.AttoFIL, gasLimit types.GasUnits, wallet, to address.Address, value *types.AttoFIL) (uint64, error) {
+	var txID uint64
+	err := sendAndWait(ctx, plumbing, from, wallet, types.NewZeroAttoFIL(), gasPrice, gasLimit, multisig.Errors, func(receipt *types.MessageReceipt) error {
+		txID = big.NewInt(0).SetBytes(receipt.Return[0]).Uint64()
+		return nil
+	}, "propose", to, value)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to propose multisig transaction")
+	}
+	return txID, nil
+}
+
+// MultisigApprove approves a pending transaction of a multisig wallet and
+// waits for the approval to be mined.
+func MultisigApprove(ctx context.Context, plumbing msigSendAPI, from address.Address, gasPrice types.AttoFIL, gasLimit types.GasUnits, wallet address.Address, txID uint64) error {
+	err := sendAndWait(ctx, plumbing, from, wallet, types.NewZeroAttoFIL(), gasPrice, gasLimit, multisig.Errors, nil, "approve", big.NewInt(int64(txID)))
+	return errors.Wrap(err, "failed to approve multisig transaction")
+}
+
+// MultisigCancel cancels a pending transaction of a multisig wallet and
+// waits for the cancellation to be mined.
+func MultisigCancel(ctx context.Context, plumbing msigSendAPI, from address.Address, gasPrice types.AttoFIL, gasLimit types.GasUnits, wallet address.Address, txID uint64) error {
+	err := sendAndWait(ctx, plumbing, from, wallet, types.NewZeroAttoFIL(), gasPrice, gasLimit, multisig.Errors, nil, "cancel", big.NewInt(int64(txID)))
+	return errors.Wrap(err, "failed to cancel multisig transaction")
+}
+
+// sendAndWait sends a message, waits for it to be mined, translates a non
+// zero exit code using actorErrors and otherwise hands the receipt to onSuccess.
+func sendAndWait(ctx context.Context, plumbing msigSendAPI, from, to address.Address, value *types.AttoFIL, gasPrice types.AttoFIL, gasLimit types.GasUnits, actorErrors map[uint8]error, onSuccess func(*types.MessageReceipt) error, method string, params ...interface{}) error {
+	msgCid, err := plumbing.MessageSendWithDefaultAddress(ctx, from, to, value, gasPrice, gasLimit, method, params...)
+	if err != nil {
+		return err
+	}
+
+	return plumbing.MessageWait(ctx, msgCid, func(blk *types.Block, smsg *types.SignedMessage, receipt *types.MessageReceipt) error {
+		if receipt.ExitCode != uint8(0) {
+			return vmErrors.VMExitCodeToError(receipt.ExitCode, actorErrors)
+		}
+		if onSuccess == nil {
+			return nil
+		}
+		return onSuccess(receipt)
+	})
+}
+
+// msigQueryAPI is the subset of the plumbing.API that MultisigInfo uses.
+type msigQueryAPI interface {
+	MessageQuery(ctx context.Context, optFrom, to address.Address, method string, params ...interface{}) ([][]byte, *exec.FunctionSignature, error)
+}
+
+// MultisigInfo describes a multisig wallet and its pending transactions.
+type MultisigInfo struct {
+	Signers  []address.Address
+	Required uint64
+	// Pending maps the ids of pending transactions to the transactions.
+	Pending map[string]*multisig.Transaction
+}
+
+// MultisigGetInfo queries the signers and pending transactions of a
+// multisig wallet.
+func MultisigGetInfo(ctx context.Context, plumbing msigQueryAPI, wallet address.Address) (*MultisigInfo, error) {
+	ret, _, err := plumbing.MessageQuery(ctx, address.Address{}, wallet, "getSigners")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query multisig signers")
+	}
+
+	signersVal, err := abi.Deserialize(ret[0], abi.Addresses)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode multisig signers")
+	}
+
+	info := &MultisigInfo{
+		Signers:  signersVal.Val.([]address.Address),
+		Required: big.NewInt(0).SetBytes(ret[1]).Uint64(),
+	}
+
+	ret, _, err = plumbing.MessageQuery(ctx, address.Address{}, wallet, "getPending")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query pending multisig transactions")
+	}
+	if err := cbor.DecodeInto(ret[0], &info.Pending); err != nil {
+		return nil, errors.Wrap(err, "failed to decode pending multisig transactions")
+	}
+
+	return info, nil
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/exec"
 	"github.com/filecoin-project/go-filecoin/types"
+	vmErrors "github.com/filecoin-project/go-filecoin/vm/errors"
 )
 
 // cpPlumbing is the subset of the plumbing.API that CreatePayments uses.
@@ -119,7 +120,7 @@ func CreatePayments(ctx context.Context, plumbing cpPlumbing, config CreatePayme
 	// wait for response
 	err = plumbing.MessageWait(ctx, response.ChannelMsgCid, func(block *types.Block, message *types.SignedMessage, receipt *types.MessageReceipt) error {
 		if receipt.ExitCode != 0 {
-			return fmt.Errorf("createChannel failed %d", receipt.ExitCode)
+			return errors.Wrap(vmErrors.VMExitCodeToError(receipt.ExitCode, paymentbroker.Errors), "createChannel failed")
 		}
 
 		response.Channel = types.NewChannelIDFromBytes(receipt.Return[0])
@@ -0,0 +1,39 @@
+package porcelain
+
+import (
+	"context"
+
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+
+	"github.com/filecoin-project/go-filecoin/config"
+	"github.com/filecoin-project/go-filecoin/proofs/params"
+)
+
+// pfpAPI is the subset of the plumbing.API that ProofsFetchParams uses.
+type pfpAPI interface {
+	ConfigGet(dottedPath string) (interface{}, error)
+}
+
+// ProofsFetchParams fetches the parameters of the proofs for sectors of the
+// given size into the parameter cache, from the manifest and gateway in the
+// config, reporting the progress of each file to progress.
+func ProofsFetchParams(ctx context.Context, plumbing pfpAPI, size uint64, progress func(params.Progress)) error {
+	val, err := plumbing.ConfigGet("proofs")
+	if err != nil {
+		return errors.Wrap(err, "failed to get proofs config")
+	}
+	cfg, ok := val.(*config.ProofsConfig)
+	if !ok {
+		return errors.New("could not retrieve proofs from config")
+	}
+	if cfg.ParameterManifest == "" {
+		return errors.New("no parameter manifest configured, set proofs.parameterManifest")
+	}
+
+	manifest, err := params.LoadManifest(ctx, cfg.ParameterManifest)
+	if err != nil {
+		return err
+	}
+	fetcher := params.NewFetcher(params.CacheDir(cfg.ParameterCache), cfg.ParameterGateway)
+	return fetcher.Fetch(ctx, manifest, size, progress)
+}
@@ -0,0 +1,241 @@
+package porcelain
+
+import (
+	"context"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/plumbing/msg"
+	"github.com/filecoin-project/go-filecoin/state"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+const (
+	// HistoryIncoming marks a message sent to the address.
+	HistoryIncoming = "in"
+	// HistoryOutgoing marks a message sent from the address.
+	HistoryOutgoing = "out"
+	// HistorySelf marks a message the address sent to itself.
+	HistorySelf = "self"
+)
+
+// WalletHistoryEntry is a message sent from or to an address.
+type WalletHistoryEntry struct {
+	Cid         cid.Cid         `json:"cid"`
+	BlockHeight uint64          `json:"blockHeight"`
+	Direction   string          `json:"direction"`
+	From        address.Address `json:"from"`
+	To          address.Address `json:"to"`
+	Value       *types.AttoFIL  `json:"value"`
+	Method      string          `json:"method"`
+	// Fee and ExitCode are taken from the message's receipt. They are nil
+	// if the message has none, e.g. because it conflicts with another
+	// message of its tipset.
+	Fee      *types.AttoFIL `json:"fee,omitempty"`
+	ExitCode *uint8         `json:"exitCode,omitempty"`
+}
+
+// whAPI is the subset of the plumbing.API that WalletHistory uses.
+type whAPI interface {
+	MessageHistory(ctx context.Context, addr address.Address) ([]*msg.IndexEntry, error)
+	MessageReceipts(ctx context.Context, ts types.TipSet) (map[cid.Cid]*types.MessageReceipt, error)
+}
+
+// WalletHistory lists the messages on chain sent from or to addr, newest
+// first. It skips the first offset messages and returns at most limit
+// messages, or all of them if limit is 0.
+func WalletHistory(ctx context.Context, plumbing whAPI, addr address.Address, offset, limit uint) ([]*WalletHistoryEntry, error) {
+	indexed, err := plumbing.MessageHistory(ctx, addr)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to look up messages")
+	}
+	if offset >= uint(len(indexed)) {
+		return nil, nil
+	}
+	indexed = indexed[offset:]
+	if limit > 0 && uint(len(indexed)) > limit {
+		indexed = indexed[:limit]
+	}
+
+	// Receipts are computed per tipset, only for the tipsets of the page.
+	receipts := make(map[string]map[cid.Cid]*types.MessageReceipt)
+	var entries []*WalletHistoryEntry
+	for _, ie := range indexed {
+		key := ie.TipSet.String()
+		tsReceipts, ok := receipts[key]
+		if !ok {
+			tsReceipts, err = plumbing.MessageReceipts(ctx, ie.TipSet)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to get receipts of message %s", ie.Cid)
+			}
+			receipts[key] = tsReceipts
+		}
+		entries = append(entries, historyEntry(ie, addr, tsReceipts[ie.Cid]))
+	}
+
+	return entries, nil
+}
+
+func historyEntry(ie *msg.IndexEntry, addr address.Address, rcpt *types.MessageReceipt) *WalletHistoryEntry {
+	value := ie.Message.Value
+	if value == nil {
+		value = types.NewZeroAttoFIL()
+	}
+
+	e := &WalletHistoryEntry{
+		Cid:         ie.Cid,
+		BlockHeight: ie.Height,
+		Direction:   historyDirection(ie.Message, addr),
+		From:        ie.Message.From,
+		To:          ie.Message.To,
+		Value:       value,
+		Method:      ie.Message.Method,
+	}
+	if rcpt != nil {
+		exitCode := rcpt.ExitCode
+		e.Fee = rcpt.GasAttoFIL
+		e.ExitCode = &exitCode
+	}
+	return e
+}
+
+func historyDirection(msg *types.SignedMessage, addr address.Address) string {
+	switch {
+	case msg.From == addr && msg.To == addr:
+		return HistorySelf
+	case msg.From == addr:
+		return HistoryOutgoing
+	default:
+		return HistoryIncoming
+	}
+}
+
+// BalanceEvent describes the balance and nonce of an address after the chain
+// head changed.
+type BalanceEvent struct {
+	Address address.Address `json:"address"`
+	Height  uint64          `json:"height"`
+	Balance *types.AttoFIL  `json:"balance"`
+	Nonce   uint64          `json:"nonce"`
+	// Reorg is set when the new head is not higher than the previous one,
+	// meaning tipsets the previous state depended on were reverted.
+	Reorg bool `json:"reorg"`
+}
+
+// wbAPI is the subset of the plumbing.API that WatchBalance uses.
+type wbAPI interface {
+	ActorGet(ctx context.Context, addr address.Address) (*actor.Actor, error)
+	ChainHead(ctx context.Context) types.TipSet
+	ChainHeadEvents(ctx context.Context) <-chan types.TipSet
+}
+
+// WatchBalance streams the balance and nonce of addr. The current balance
+// and nonce are sent first, followed by an event every time a new chain head
+// changes either of them. The channel receives *BalanceEvent values; if an
+// error is encountered it is sent and the channel is closed. The channel is
+// also closed once ctx is done.
+func WatchBalance(ctx context.Context, plumbing wbAPI, addr address.Address) <-chan interface{} {
+	out := make(chan interface{})
+
+	// Subscribe before reading the current state so no head is missed.
+	heads := plumbing.ChainHeadEvents(ctx)
+
+	go func() {
+		defer close(out)
+
+		send := func(v interface{}) bool {
+			select {
+			case out <- v:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		head := plumbing.ChainHead(ctx)
+		height, err := head.Height()
+		if err != nil {
+			send(err)
+			return
+		}
+		last, err := balanceEvent(ctx, plumbing, addr, height)
+		if err != nil {
+			send(err)
+			return
+		}
+		if !send(last) {
+			return
+		}
+
+		for ts := range heads {
+			height, err := ts.Height()
+			if err != nil {
+				send(err)
+				return
+			}
+
+			evt, err := balanceEvent(ctx, plumbing, addr, height)
+			if err != nil {
+				send(err)
+				return
+			}
+			evt.Reorg = height <= last.Height
+
+			changed := !evt.Balance.Equal(last.Balance) || evt.Nonce != last.Nonce
+			last = evt
+			if changed && !send(evt) {
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+func balanceEvent(ctx context.Context, plumbing wbAPI, addr address.Address, height uint64) (*BalanceEvent, error) {
+	evt := &BalanceEvent{
+		Address: addr,
+		Height:  height,
+		Balance: types.NewZeroAttoFIL(),
+	}
+
+	act, err := plumbing.ActorGet(ctx, addr)
+	if state.IsActorNotFoundError(err) {
+		return evt, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get actor %s", addr)
+	}
+
+	if act.Balance != nil {
+		evt.Balance = act.Balance
+	}
+	evt.Nonce = uint64(act.Nonce)
+	return evt, nil
+}
+
+// wsdaAPI is the subset of the plumbing.API that WalletSetDefaultAddress uses.
+type wsdaAPI interface {
+	ConfigSet(dottedPath string, paramJSON string) error
+	WalletAddresses() []address.Address
+}
+
+// WalletSetDefaultAddress makes addr the address messages are sent from when
+// no from address is given. addr must be in the wallet.
+func WalletSetDefaultAddress(plumbing wsdaAPI, addr address.Address) error {
+	found := false
+	for _, a := range plumbing.WalletAddresses() {
+		if a == addr {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return errors.Errorf("address %s is not in the wallet", addr)
+	}
+
+	return plumbing.ConfigSet("wallet.defaultAddress", addr.String())
+}
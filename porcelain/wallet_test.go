@@ -0,0 +1,235 @@
+package porcelain_test
+
+import (
+	"context"
+	"testing"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/plumbing/msg"
+	"github.com/filecoin-project/go-filecoin/porcelain"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+type fakeWalletHistoryPlumbing struct {
+	tipSets []types.TipSet
+}
+
+func (fp *fakeWalletHistoryPlumbing) MessageHistory(ctx context.Context, addr address.Address) ([]*msg.IndexEntry, error) {
+	var entries []*msg.IndexEntry
+	for _, ts := range fp.tipSets {
+		for _, blk := range ts {
+			for _, smsg := range blk.Messages {
+				if smsg.From != addr && smsg.To != addr {
+					continue
+				}
+				c, err := smsg.Cid()
+				if err != nil {
+					return nil, err
+				}
+				entries = append(entries, &msg.IndexEntry{Message: smsg, Cid: c, TipSet: ts, Height: uint64(blk.Height)})
+			}
+		}
+	}
+	return entries, nil
+}
+
+func (fp *fakeWalletHistoryPlumbing) MessageReceipts(ctx context.Context, ts types.TipSet) (map[cid.Cid]*types.MessageReceipt, error) {
+	receipts := make(map[cid.Cid]*types.MessageReceipt)
+	for _, blk := range ts {
+		for i, rcpt := range blk.MessageReceipts {
+			c, err := blk.Messages[i].Cid()
+			if err != nil {
+				return nil, err
+			}
+			receipts[c] = rcpt
+		}
+	}
+	return receipts, nil
+}
+
+func requireTipSetWithMessages(require *require.Assertions, height uint64, msgs ...*types.Message) types.TipSet {
+	blk := &types.Block{Height: types.Uint64(height)}
+	for _, msg := range msgs {
+		smsg := &types.SignedMessage{}
+		smsg.Message = *msg
+		blk.Messages = append(blk.Messages, smsg)
+		blk.MessageReceipts = append(blk.MessageReceipts, &types.MessageReceipt{GasAttoFIL: types.NewAttoFILFromFIL(1), ExitCode: 2})
+	}
+	ts, err := types.NewTipSet(blk)
+	require.NoError(err)
+	return ts
+}
+
+func TestWalletHistory(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	addrGetter := address.NewForTestGetter()
+	me, other := addrGetter(), addrGetter()
+
+	newPlumbing := func(require *require.Assertions) *fakeWalletHistoryPlumbing {
+		noReceipt := requireTipSetWithMessages(require, 1,
+			types.NewMessage(me, me, 1, nil, "", nil),
+		)
+		noReceipt.ToSlice()[0].MessageReceipts = nil
+
+		return &fakeWalletHistoryPlumbing{tipSets: []types.TipSet{
+			requireTipSetWithMessages(require, 3,
+				types.NewMessage(me, other, 2, types.NewAttoFILFromFIL(5), "", nil),
+				types.NewMessage(other, addrGetter(), 0, types.NewAttoFILFromFIL(7), "", nil),
+			),
+			requireTipSetWithMessages(require, 2,
+				types.NewMessage(other, me, 1, types.NewAttoFILFromFIL(3), "", nil),
+			),
+			noReceipt,
+		}}
+	}
+
+	t.Run("lists incoming and outgoing messages newest first", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		entries, err := porcelain.WalletHistory(ctx, newPlumbing(require), me, 0, 0)
+		require.NoError(err)
+		require.Len(entries, 3)
+
+		assert.Equal(uint64(3), entries[0].BlockHeight)
+		assert.Equal(porcelain.HistoryOutgoing, entries[0].Direction)
+		assert.Equal(types.NewAttoFILFromFIL(5), entries[0].Value)
+		assert.Equal(types.NewAttoFILFromFIL(1), entries[0].Fee)
+		require.NotNil(entries[0].ExitCode)
+		assert.Equal(uint8(2), *entries[0].ExitCode)
+
+		assert.Equal(porcelain.HistoryIncoming, entries[1].Direction)
+		assert.Equal(other, entries[1].From)
+
+		assert.Equal(porcelain.HistorySelf, entries[2].Direction)
+		assert.True(entries[2].Value.IsZero())
+	})
+
+	t.Run("leaves the fee and exit code unset without a receipt", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		entries, err := porcelain.WalletHistory(ctx, newPlumbing(require), me, 2, 0)
+		require.NoError(err)
+		require.Len(entries, 1)
+		assert.Nil(entries[0].Fee)
+		assert.Nil(entries[0].ExitCode)
+	})
+
+	t.Run("pages through the history", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		entries, err := porcelain.WalletHistory(ctx, newPlumbing(require), me, 1, 1)
+		require.NoError(err)
+		require.Len(entries, 1)
+		assert.Equal(uint64(2), entries[0].BlockHeight)
+
+		entries, err = porcelain.WalletHistory(ctx, newPlumbing(require), me, 3, 10)
+		require.NoError(err)
+		assert.Empty(entries)
+	})
+}
+
+type fakeWatchBalancePlumbing struct {
+	head   types.TipSet
+	heads  chan types.TipSet
+	actors chan *actor.Actor
+}
+
+func (fp *fakeWatchBalancePlumbing) ActorGet(ctx context.Context, addr address.Address) (*actor.Actor, error) {
+	return <-fp.actors, nil
+}
+
+func (fp *fakeWatchBalancePlumbing) ChainHead(ctx context.Context) types.TipSet {
+	return fp.head
+}
+
+func (fp *fakeWatchBalancePlumbing) ChainHeadEvents(ctx context.Context) <-chan types.TipSet {
+	return fp.heads
+}
+
+func requireTipSetAtHeight(require *require.Assertions, height uint64) types.TipSet {
+	ts, err := types.NewTipSet(&types.Block{Height: types.Uint64(height)})
+	require.NoError(err)
+	return ts
+}
+
+func TestWatchBalance(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	addr := address.NewForTestGetter()()
+	fp := &fakeWatchBalancePlumbing{
+		head:   requireTipSetAtHeight(require, 1),
+		heads:  make(chan types.TipSet, 3),
+		actors: make(chan *actor.Actor, 4),
+	}
+
+	fp.actors <- &actor.Actor{Balance: types.NewAttoFILFromFIL(10), Nonce: 1}
+	// height 2 changes nothing, height 3 receives funds and height 2 reverts them.
+	fp.heads <- requireTipSetAtHeight(require, 2)
+	fp.actors <- &actor.Actor{Balance: types.NewAttoFILFromFIL(10), Nonce: 1}
+	fp.heads <- requireTipSetAtHeight(require, 3)
+	fp.actors <- &actor.Actor{Balance: types.NewAttoFILFromFIL(15), Nonce: 1}
+	fp.heads <- requireTipSetAtHeight(require, 2)
+	fp.actors <- &actor.Actor{Balance: types.NewAttoFILFromFIL(10), Nonce: 1}
+	close(fp.heads)
+
+	var events []*porcelain.BalanceEvent
+	for raw := range porcelain.WatchBalance(ctx, fp, addr) {
+		evt, ok := raw.(*porcelain.BalanceEvent)
+		require.True(ok, "unexpected %v", raw)
+		events = append(events, evt)
+	}
+
+	require.Len(events, 3)
+
+	assert.Equal(uint64(1), events[0].Height)
+	assert.Equal(types.NewAttoFILFromFIL(10), events[0].Balance)
+	assert.Equal(uint64(1), events[0].Nonce)
+
+	assert.Equal(uint64(3), events[1].Height)
+	assert.Equal(types.NewAttoFILFromFIL(15), events[1].Balance)
+	assert.False(events[1].Reorg)
+
+	assert.Equal(uint64(2), events[2].Height)
+	assert.Equal(types.NewAttoFILFromFIL(10), events[2].Balance)
+	assert.True(events[2].Reorg)
+}
+
+func TestWalletSetDefaultAddress(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	fp := newFakeGetAndMaybeSetDefaultSenderAddressPlumbing(require)
+	addrA, err := fp.WalletNewAddress()
+	require.NoError(err)
+	addrB, err := fp.WalletNewAddress()
+	require.NoError(err)
+
+	require.NoError(porcelain.WalletSetDefaultAddress(fp, addrB))
+	got, err := porcelain.GetAndMaybeSetDefaultSenderAddress(fp)
+	require.NoError(err)
+	assert.Equal(addrB, got)
+
+	require.NoError(porcelain.WalletSetDefaultAddress(fp, addrA))
+	got, err = porcelain.GetAndMaybeSetDefaultSenderAddress(fp)
+	require.NoError(err)
+	assert.Equal(addrA, got)
+
+	assert.Error(porcelain.WalletSetDefaultAddress(fp, address.NewForTestGetter()()))
+}
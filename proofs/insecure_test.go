@@ -0,0 +1,47 @@
+package proofs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUseInsecureProofs(t *testing.T) {
+	assert.Error(t, UseInsecureProofs("devnet"))
+	assert.False(t, InsecureProofs())
+}
+
+func TestInsecureVerifier(t *testing.T) {
+	t.Parallel()
+
+	verifier := &InsecureVerifier{}
+
+	t.Run("accepts fake seal proofs of the sector", func(t *testing.T) {
+		req := VerifySealRequest{CommD: CommD{1}, CommR: CommR{2}, CommRStar: CommRStar{3}, ProverID: [31]byte{4}, SectorID: [31]byte{5}}
+		req.Proof = InsecureSealProof(req.CommD, req.CommR, req.CommRStar, req.ProverID, req.SectorID)
+
+		res, err := verifier.VerifySeal(req)
+		require.NoError(t, err)
+		assert.True(t, res.IsValid)
+
+		req.SectorID = [31]byte{6}
+		res, err = verifier.VerifySeal(req)
+		require.NoError(t, err)
+		assert.False(t, res.IsValid)
+	})
+
+	t.Run("accepts fake PoSt proofs of the sectors", func(t *testing.T) {
+		commRs := []CommR{{1}, {2}}
+		req := VerifyPoSTRequest{CommRs: commRs, Proof: InsecurePoStProof(commRs)}
+
+		res, err := verifier.VerifyPoST(req)
+		require.NoError(t, err)
+		assert.True(t, res.IsValid)
+
+		req.CommRs = commRs[:1]
+		res, err = verifier.VerifyPoST(req)
+		require.NoError(t, err)
+		assert.False(t, res.IsValid)
+	})
+}
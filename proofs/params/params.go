@@ -0,0 +1,306 @@
+// Package params fetches the Groth parameters and verifying keys the proofs
+// need to seal sectors and generate PoSts, and keeps them in a cache shared
+// with the proofs library.
+package params
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+)
+
+// CacheDirEnv is the environment variable the proofs library reads the
+// location of the parameter cache from.
+const CacheDirEnv = "FILECOIN_PARAMETER_CACHE"
+
+// DefaultCacheDir is the parameter cache the proofs library uses when
+// CacheDirEnv is not set.
+const DefaultCacheDir = "/tmp/filecoin-proof-parameters"
+
+// progressInterval is how often the progress of a download is reported.
+const progressInterval = time.Second
+
+// ParameterInfo describes a parameter file.
+type ParameterInfo struct {
+	// Cid is the content id of the file, which is downloaded from a gateway
+	// by it.
+	Cid string `json:"cid"`
+	// Digest is the hex encoded sha256 digest of the file.
+	Digest string `json:"digest"`
+	// SectorSize is the size of the sectors the parameters are for, in bytes.
+	SectorSize uint64 `json:"sector_size"`
+}
+
+// Manifest lists the parameter files, by file name.
+type Manifest map[string]ParameterInfo
+
+// ForSize returns the names of the files of the parameters for sectors of the
+// given size, sorted.
+func (m Manifest) ForSize(size uint64) []string {
+	var names []string
+	for name, info := range m {
+		if info.SectorSize == size {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadManifest reads a manifest from location, a http(s) URL or a file path.
+func LoadManifest(ctx context.Context, location string) (Manifest, error) {
+	var data []byte
+	var err error
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		data, err = httpGet(ctx, location)
+	} else {
+		data, err = ioutil.ReadFile(location)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read parameter manifest %s", location)
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse parameter manifest %s", location)
+	}
+	return m, nil
+}
+
+func httpGet(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// CacheDir returns the configured parameter cache, falling back to the one
+// of the proofs library.
+func CacheDir(configured string) string {
+	if configured != "" {
+		return configured
+	}
+	if dir := os.Getenv(CacheDirEnv); dir != "" {
+		return dir
+	}
+	return DefaultCacheDir
+}
+
+// SectorSize returns the size of the sectors holding maxUserBytes bytes of
+// pieces. Padding expands each 127 bytes of pieces to 128 bytes of sector.
+func SectorSize(maxUserBytes uint64) uint64 {
+	return maxUserBytes / 127 * 128
+}
+
+// Missing returns the names of the files of the parameters for sectors of
+// the given size which are not in dir. It doesn't verify the files present.
+func Missing(m Manifest, dir string, size uint64) ([]string, error) {
+	var missing []string
+	for _, name := range m.ForSize(size) {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			missing = append(missing, name)
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	return missing, nil
+}
+
+// FetchState is the state of a parameter file being fetched.
+type FetchState string
+
+const (
+	// FetchVerified means the file was in the cache already.
+	FetchVerified = FetchState("verified")
+	// FetchDownloading means the file is being downloaded.
+	FetchDownloading = FetchState("downloading")
+	// FetchDone means the file was downloaded and verified.
+	FetchDone = FetchState("done")
+)
+
+// Progress reports the progress of fetching a parameter file.
+type Progress struct {
+	Name  string     `json:"name"`
+	State FetchState `json:"state"`
+	// Bytes is the number of bytes of the file downloaded, including the
+	// ones of an earlier download which was resumed.
+	Bytes uint64 `json:"bytes"`
+	// Total is the size of the file, 0 if the gateway didn't tell.
+	Total uint64 `json:"total"`
+}
+
+// Fetcher downloads parameter files from a gateway into a cache directory,
+// verifying their digests. Interrupted downloads are resumed.
+type Fetcher struct {
+	// Dir is the cache directory.
+	Dir string
+	// Gateway is the URL the cids of the files are appended to to download
+	// them.
+	Gateway string
+	Client  *http.Client
+}
+
+// NewFetcher returns a Fetcher downloading into dir from gateway.
+func NewFetcher(dir, gateway string) *Fetcher {
+	return &Fetcher{Dir: dir, Gateway: gateway, Client: http.DefaultClient}
+}
+
+// Fetch makes sure the cache holds verified copies of the files of the
+// parameters for sectors of the given size, reporting progress to progress.
+func (f *Fetcher) Fetch(ctx context.Context, m Manifest, size uint64, progress func(Progress)) error {
+	names := m.ForSize(size)
+	if len(names) == 0 {
+		return fmt.Errorf("no parameters for sectors of %d bytes", size)
+	}
+	if err := os.MkdirAll(f.Dir, 0755); err != nil {
+		return errors.Wrapf(err, "failed to create parameter cache %s", f.Dir)
+	}
+
+	for _, name := range names {
+		if err := f.fetch(ctx, name, m[name], progress); err != nil {
+			return errors.Wrapf(err, "failed to fetch %s", name)
+		}
+	}
+	return nil
+}
+
+func (f *Fetcher) fetch(ctx context.Context, name string, info ParameterInfo, progress func(Progress)) error {
+	path := filepath.Join(f.Dir, name)
+	if _, err := os.Stat(path); err == nil {
+		if err := verify(path, info.Digest); err == nil {
+			progress(Progress{Name: name, State: FetchVerified})
+			return nil
+		}
+		// a corrupt file is downloaded anew
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+
+	partial := path + ".part"
+	if err := f.download(ctx, name, info, partial, progress); err != nil {
+		return err
+	}
+	if err := verify(partial, info.Digest); err != nil {
+		os.Remove(partial) // nolint: errcheck
+		return err
+	}
+	if err := os.Rename(partial, path); err != nil {
+		return err
+	}
+	progress(Progress{Name: name, State: FetchDone})
+	return nil
+}
+
+// download downloads the file into partial, resuming from the bytes already
+// in it.
+func (f *Fetcher) download(ctx context.Context, name string, info ParameterInfo, partial string, progress func(Progress)) error {
+	var offset int64
+	if fi, err := os.Stat(partial); err == nil {
+		offset = fi.Size()
+	}
+
+	req, err := http.NewRequest("GET", strings.TrimSuffix(f.Gateway, "/")+"/"+info.Cid, nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := f.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		flags |= os.O_APPEND
+	case http.StatusOK:
+		// the gateway ignored the range, start over
+		offset = 0
+		flags |= os.O_TRUNC
+	case http.StatusRequestedRangeNotSatisfiable:
+		// the partial file is complete already
+		return nil
+	default:
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	out, err := os.OpenFile(partial, flags, 0644)
+	if err != nil {
+		return err
+	}
+	defer out.Close() // nolint: errcheck
+
+	p := Progress{Name: name, State: FetchDownloading, Bytes: uint64(offset)}
+	if resp.ContentLength > 0 {
+		p.Total = uint64(offset + resp.ContentLength)
+	}
+	progress(p)
+
+	buf := make([]byte, 1<<20)
+	last := time.Now()
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			if _, err := out.Write(buf[:n]); err != nil {
+				return err
+			}
+			p.Bytes += uint64(n)
+			if time.Since(last) >= progressInterval {
+				progress(p)
+				last = time.Now()
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	progress(p)
+	return out.Close()
+}
+
+// verify checks the sha256 digest of the file at path.
+func verify(path, digest string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close() // nolint: errcheck
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != strings.ToLower(digest) {
+		return fmt.Errorf("digest %s does not match the expected %s", got, digest)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package params
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func digest(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+// newTestGateway serves files by cid, with support for ranges, and counts
+// the requests.
+func newTestGateway(files map[string]string) (*httptest.Server, *int) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		data, ok := files[strings.TrimPrefix(r.URL.Path, "/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(data))
+	}))
+	return srv, &requests
+}
+
+func TestFetch(t *testing.T) {
+	t.Parallel()
+
+	const params, vk = "groth parameters", "verifying key"
+	manifest := Manifest{
+		"v1-1024.params": {Cid: "Qmparams", Digest: digest(params), SectorSize: 1024},
+		"v1-1024.vk":     {Cid: "Qmvk", Digest: digest(vk), SectorSize: 1024},
+		"v1-2048.params": {Cid: "Qmother", Digest: digest("other"), SectorSize: 2048},
+	}
+	files := map[string]string{"Qmparams": params, "Qmvk": vk, "Qmother": "other"}
+
+	newFetcher := func(t *testing.T) (*Fetcher, *int, func()) {
+		dir, err := ioutil.TempDir("", "params")
+		require.NoError(t, err)
+		srv, requests := newTestGateway(files)
+		return NewFetcher(dir, srv.URL), requests, func() {
+			srv.Close()
+			os.RemoveAll(dir) // nolint: errcheck
+		}
+	}
+	ignore := func(Progress) {}
+
+	t.Run("downloads the parameters of the sector size", func(t *testing.T) {
+		f, _, done := newFetcher(t)
+		defer done()
+
+		missing, err := Missing(manifest, f.Dir, 1024)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"v1-1024.params", "v1-1024.vk"}, missing)
+
+		var states []FetchState
+		require.NoError(t, f.Fetch(context.Background(), manifest, 1024, func(p Progress) {
+			if p.Name == "v1-1024.params" {
+				states = append(states, p.State)
+			}
+		}))
+		assert.Equal(t, []FetchState{FetchDownloading, FetchDownloading, FetchDone}, states)
+
+		data, err := ioutil.ReadFile(filepath.Join(f.Dir, "v1-1024.params"))
+		require.NoError(t, err)
+		assert.Equal(t, params, string(data))
+		missing, err = Missing(manifest, f.Dir, 1024)
+		require.NoError(t, err)
+		assert.Empty(t, missing)
+		missing, err = Missing(manifest, f.Dir, 2048)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"v1-2048.params"}, missing)
+	})
+
+	t.Run("keeps verified files", func(t *testing.T) {
+		f, requests, done := newFetcher(t)
+		defer done()
+		require.NoError(t, f.Fetch(context.Background(), manifest, 1024, ignore))
+		*requests = 0
+
+		require.NoError(t, f.Fetch(context.Background(), manifest, 1024, ignore))
+		assert.Equal(t, 0, *requests)
+	})
+
+	t.Run("downloads corrupt files anew", func(t *testing.T) {
+		f, _, done := newFetcher(t)
+		defer done()
+		require.NoError(t, ioutil.WriteFile(filepath.Join(f.Dir, "v1-1024.vk"), []byte("garbage"), 0644))
+
+		require.NoError(t, f.Fetch(context.Background(), manifest, 1024, ignore))
+		data, err := ioutil.ReadFile(filepath.Join(f.Dir, "v1-1024.vk"))
+		require.NoError(t, err)
+		assert.Equal(t, vk, string(data))
+	})
+
+	t.Run("resumes interrupted downloads", func(t *testing.T) {
+		f, _, done := newFetcher(t)
+		defer done()
+		require.NoError(t, ioutil.WriteFile(filepath.Join(f.Dir, "v1-1024.params.part"), []byte(params[:5]), 0644))
+
+		var first Progress
+		require.NoError(t, f.Fetch(context.Background(), manifest, 1024, func(p Progress) {
+			if p.Name == "v1-1024.params" && first.Name == "" {
+				first = p
+			}
+		}))
+		assert.Equal(t, uint64(5), first.Bytes)
+		assert.Equal(t, uint64(len(params)), first.Total)
+
+		data, err := ioutil.ReadFile(filepath.Join(f.Dir, "v1-1024.params"))
+		require.NoError(t, err)
+		assert.Equal(t, params, string(data))
+	})
+
+	t.Run("rejects files not matching their digest", func(t *testing.T) {
+		f, _, done := newFetcher(t)
+		defer done()
+		corrupt := Manifest{"v1-1024.params": {Cid: "Qmvk", Digest: digest(params), SectorSize: 1024}}
+
+		err := f.Fetch(context.Background(), corrupt, 1024, ignore)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "does not match")
+		_, err = os.Stat(filepath.Join(f.Dir, "v1-1024.params"))
+		assert.True(t, os.IsNotExist(err))
+		_, err = os.Stat(filepath.Join(f.Dir, "v1-1024.params.part"))
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("fails without parameters of the sector size", func(t *testing.T) {
+		f, _, done := newFetcher(t)
+		defer done()
+		assert.Error(t, f.Fetch(context.Background(), manifest, 4096, ignore))
+	})
+}
+
+func TestSectorSize(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, uint64(1024), SectorSize(1016))
+	assert.Equal(t, uint64(256<<20), SectorSize(266338304))
+}
@@ -0,0 +1,157 @@
+package proofs
+
+import (
+	"crypto/sha256"
+	"io"
+
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+)
+
+// Pieces are padded before they are written to sectors such that every 32 byte
+// node of the merkle tree over them holds 254 bits of the piece, which fits
+// into a field element. Every 127 bytes of a piece take up 128 bytes padded.
+const (
+	unpaddedChunkLen = 127
+	paddedChunkLen   = 128
+	nodeLen          = 32
+	bitsPerNode      = 254
+)
+
+// PaddedPieceSize returns the number of bytes a piece of the given size takes
+// up in a sector: the size padded to whole nodes and rounded up to a power of
+// two.
+func PaddedPieceSize(size uint64) uint64 {
+	chunks := (size + unpaddedChunkLen - 1) / unpaddedChunkLen
+	if chunks == 0 {
+		chunks = 1
+	}
+	padded := uint64(paddedChunkLen)
+	for padded < chunks*paddedChunkLen {
+		padded *= 2
+	}
+	return padded
+}
+
+// GeneratePieceCommitment computes the piece commitment of the data read from
+// r, the root of a binary merkle tree over the padded data. It returns the
+// commitment, the number of bytes read and the padded size of the piece.
+//
+// Nodes are hashed with SHA-256, truncated to 254 bits.
+func GeneratePieceCommitment(r io.Reader) (commP CommP, size uint64, paddedSize uint64, err error) {
+	var tree pieceTree
+	chunk := make([]byte, unpaddedChunkLen)
+	padded := make([]byte, paddedChunkLen)
+
+	for {
+		n, readErr := io.ReadFull(r, chunk)
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil && readErr != io.ErrUnexpectedEOF {
+			return CommP{}, 0, 0, errors.Wrap(readErr, "failed to read piece")
+		}
+		// the last chunk is padded with zeros
+		for i := n; i < unpaddedChunkLen; i++ {
+			chunk[i] = 0
+		}
+		size += uint64(n)
+
+		fr32Pad(chunk, padded)
+		for i := 0; i < paddedChunkLen; i += nodeLen {
+			var leaf [nodeLen]byte
+			copy(leaf[:], padded[i:i+nodeLen])
+			tree.add(leaf, 0)
+		}
+
+		if readErr == io.ErrUnexpectedEOF {
+			break
+		}
+	}
+
+	if size == 0 {
+		return CommP{}, 0, 0, errors.New("piece is empty")
+	}
+
+	paddedSize = PaddedPieceSize(size)
+	levels := 0
+	for leaves := paddedSize / nodeLen; leaves > 1; leaves /= 2 {
+		levels++
+	}
+	return CommP(tree.root(levels)), size, paddedSize, nil
+}
+
+// fr32Pad spreads the 127 bytes of in over the 128 bytes of out, 254 bits to
+// every 32 bytes, leaving the two most significant bits of every 32 bytes
+// zero.
+func fr32Pad(in, out []byte) {
+	for node := 0; node < paddedChunkLen/nodeLen; node++ {
+		offset := node * bitsPerNode
+		start, shift := offset/8, uint(offset%8)
+		for j := 0; j < nodeLen; j++ {
+			var b byte
+			if start+j < len(in) {
+				b = in[start+j] >> shift
+			}
+			if shift > 0 && start+j+1 < len(in) {
+				b |= in[start+j+1] << (8 - shift)
+			}
+			out[node*nodeLen+j] = b
+		}
+		out[node*nodeLen+nodeLen-1] &= 0x3f
+	}
+}
+
+// pieceTree computes the root of a merkle tree from its leaves, left to right,
+// keeping the roots of the complete subtrees so far.
+type pieceTree struct {
+	// stack holds the roots of complete subtrees, of decreasing height.
+	stack []subtree
+	// zeros holds the roots of subtrees of zero leaves, by height.
+	zeros [][nodeLen]byte
+}
+
+type subtree struct {
+	height int
+	root   [nodeLen]byte
+}
+
+func (t *pieceTree) add(node [nodeLen]byte, height int) {
+	for len(t.stack) > 0 && t.stack[len(t.stack)-1].height == height {
+		left := t.stack[len(t.stack)-1]
+		t.stack = t.stack[:len(t.stack)-1]
+		node = hashNodes(left.root, node)
+		height++
+	}
+	t.stack = append(t.stack, subtree{height: height, root: node})
+}
+
+// root completes the tree to the given height with zero leaves and returns
+// its root.
+func (t *pieceTree) root(height int) [nodeLen]byte {
+	for len(t.stack) > 1 || t.stack[0].height < height {
+		top := t.stack[len(t.stack)-1].height
+		t.add(t.zero(top), top)
+	}
+	return t.stack[0].root
+}
+
+func (t *pieceTree) zero(height int) [nodeLen]byte {
+	if len(t.zeros) == 0 {
+		t.zeros = append(t.zeros, [nodeLen]byte{})
+	}
+	for len(t.zeros) <= height {
+		below := t.zeros[len(t.zeros)-1]
+		t.zeros = append(t.zeros, hashNodes(below, below))
+	}
+	return t.zeros[height]
+}
+
+func hashNodes(left, right [nodeLen]byte) [nodeLen]byte {
+	h := sha256.New()
+	h.Write(left[:])  // nolint: errcheck
+	h.Write(right[:]) // nolint: errcheck
+	var out [nodeLen]byte
+	copy(out[:], h.Sum(nil))
+	out[nodeLen-1] &= 0x3f
+	return out
+}
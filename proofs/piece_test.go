@@ -0,0 +1,95 @@
+package proofs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaddedPieceSize(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(uint64(128), PaddedPieceSize(1))
+	assert.Equal(uint64(128), PaddedPieceSize(127))
+	assert.Equal(uint64(256), PaddedPieceSize(128))
+	assert.Equal(uint64(512), PaddedPieceSize(127*3))
+	assert.Equal(uint64(1024), PaddedPieceSize(127*8))
+}
+
+func TestFr32Pad(t *testing.T) {
+	assert := assert.New(t)
+
+	in := bytes.Repeat([]byte{0xff}, unpaddedChunkLen)
+	out := make([]byte, paddedChunkLen)
+	fr32Pad(in, out)
+
+	for node := 0; node < 4; node++ {
+		expected := append(bytes.Repeat([]byte{0xff}, nodeLen-1), 0x3f)
+		assert.Equal(expected, out[node*nodeLen:(node+1)*nodeLen])
+	}
+
+	// the 255th bit of the piece is the first bit of the second node
+	in = make([]byte, unpaddedChunkLen)
+	in[31] = 0x40
+	fr32Pad(in, out)
+	assert.Equal(byte(0), out[31])
+	assert.Equal(byte(1), out[32])
+}
+
+func TestGeneratePieceCommitment(t *testing.T) {
+	t.Run("commits to the padded data", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		data := []byte("a piece of data")
+		commP, size, paddedSize, err := GeneratePieceCommitment(bytes.NewReader(data))
+		require.NoError(err)
+		assert.Equal(uint64(len(data)), size)
+		assert.Equal(uint64(128), paddedSize)
+
+		chunk := make([]byte, unpaddedChunkLen)
+		copy(chunk, data)
+		padded := make([]byte, paddedChunkLen)
+		fr32Pad(chunk, padded)
+
+		var leaves [4][nodeLen]byte
+		for i := range leaves {
+			copy(leaves[i][:], padded[i*nodeLen:])
+		}
+		expected := testHash(testHash(leaves[0], leaves[1]), testHash(leaves[2], leaves[3]))
+		assert.Equal(CommP(expected), commP)
+	})
+
+	t.Run("pads the tree with zeros", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		data := bytes.Repeat([]byte{7}, unpaddedChunkLen*3)
+		commP, _, paddedSize, err := GeneratePieceCommitment(bytes.NewReader(data))
+		require.NoError(err)
+		assert.Equal(uint64(512), paddedSize)
+
+		withZeros := append(data, make([]byte, unpaddedChunkLen)...)
+		expected, _, _, err := GeneratePieceCommitment(bytes.NewReader(withZeros))
+		require.NoError(err)
+		assert.Equal(expected, commP)
+
+		other, _, _, err := GeneratePieceCommitment(bytes.NewReader(data[1:]))
+		require.NoError(err)
+		assert.NotEqual(other, commP)
+	})
+
+	t.Run("fails on empty pieces", func(t *testing.T) {
+		_, _, _, err := GeneratePieceCommitment(bytes.NewReader(nil))
+		assert.Error(t, err)
+	})
+}
+
+func testHash(left, right [nodeLen]byte) [nodeLen]byte {
+	sum := sha256.Sum256(append(left[:], right[:]...))
+	sum[nodeLen-1] &= 0x3f
+	return sum
+}
@@ -0,0 +1,123 @@
+package proofs
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+const (
+	// NoGPUEnv is the environment variable which, set to 1, keeps the proofs
+	// backend from proving on GPUs.
+	NoGPUEnv = "BELLMAN_NO_GPU"
+	// ThreadsEnv is the environment variable bounding the threads the proofs
+	// backend proves on.
+	ThreadsEnv = "RAYON_NUM_THREADS"
+)
+
+// gpuDevices matches the device files of the GPUs the proofs backend can
+// prove on.
+const gpuDevices = "/dev/nvidia[0-9]*"
+
+// Resources reports the hardware the proofs backend detected and how much of
+// it the node lets it use.
+type Resources struct {
+	// CPUs is the number of CPU cores of the machine, and CPULimit the number
+	// of them the proofs backend uses.
+	CPUs     int `json:"cpus"`
+	CPULimit int `json:"cpuLimit"`
+	// GPUs lists the device files of the GPUs of the machine. UseGPU is set
+	// if the proofs backend may prove on them.
+	GPUs   []string `json:"gpus"`
+	UseGPU bool     `json:"useGpu"`
+	// MemoryBytes is the memory of the machine, 0 if unknown.
+	MemoryBytes uint64 `json:"memoryBytes"`
+	// MemoryPerProof is the memory a proof takes, and MaxParallelProofs the
+	// number of proofs fitting into the memory of the machine at once, 0 if
+	// not bounded.
+	MemoryPerProof    uint64 `json:"memoryPerProof"`
+	MaxParallelProofs int    `json:"maxParallelProofs"`
+}
+
+// DetectResources detects the hardware of the machine and bounds the use the
+// proofs backend makes of it: at most maxCPUs CPU cores, 0 for all of them,
+// GPUs only if useGPU is set, and as many proofs at once as memoryPerProof
+// bytes fit into the memory of the machine, without bound if 0.
+func DetectResources(useGPU bool, maxCPUs int, memoryPerProof uint64) Resources {
+	gpus, _ := filepath.Glob(gpuDevices)
+	var memory uint64
+	if f, err := os.Open("/proc/meminfo"); err == nil {
+		memory, _ = parseMemTotal(f)
+		f.Close() // nolint: errcheck
+	}
+	return newResources(runtime.NumCPU(), gpus, memory, useGPU, maxCPUs, memoryPerProof)
+}
+
+func newResources(cpus int, gpus []string, memory uint64, useGPU bool, maxCPUs int, memoryPerProof uint64) Resources {
+	r := Resources{
+		CPUs:           cpus,
+		CPULimit:       cpus,
+		GPUs:           gpus,
+		UseGPU:         useGPU && len(gpus) > 0,
+		MemoryBytes:    memory,
+		MemoryPerProof: memoryPerProof,
+	}
+	if maxCPUs > 0 && maxCPUs < cpus {
+		r.CPULimit = maxCPUs
+	}
+	if memoryPerProof > 0 && memory > 0 {
+		r.MaxParallelProofs = int(memory / memoryPerProof)
+		if r.MaxParallelProofs == 0 {
+			// a proof which doesn't fit still runs, if slowly
+			r.MaxParallelProofs = 1
+		}
+	}
+	return r
+}
+
+// Apply makes the proofs backend use the resources, through the environment
+// variables it reads. It must be called before the backend starts proving.
+func (r Resources) Apply() error {
+	if r.UseGPU {
+		if err := os.Unsetenv(NoGPUEnv); err != nil {
+			return err
+		}
+	} else if err := os.Setenv(NoGPUEnv, "1"); err != nil {
+		return err
+	}
+	return os.Setenv(ThreadsEnv, strconv.Itoa(r.CPULimit))
+}
+
+// BoundParallelProofs returns n, bounded by the proofs fitting into memory.
+func (r Resources) BoundParallelProofs(n int) int {
+	if r.MaxParallelProofs > 0 && r.MaxParallelProofs < n {
+		return r.MaxParallelProofs
+	}
+	return n
+}
+
+// parseMemTotal returns the total memory in bytes from the contents of
+// /proc/meminfo.
+func parseMemTotal(r io.Reader) (uint64, error) {
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) < 2 || fields[0] != "MemTotal:" {
+			continue
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return kb * 1024, nil
+	}
+	if err := s.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("no MemTotal in meminfo")
+}
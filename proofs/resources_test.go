@@ -0,0 +1,52 @@
+package proofs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResources(t *testing.T) {
+	t.Parallel()
+
+	t.Run("uses all CPUs and GPUs by default", func(t *testing.T) {
+		r := newResources(8, []string{"/dev/nvidia0"}, 64<<30, true, 0, 0)
+		assert.Equal(t, 8, r.CPULimit)
+		assert.True(t, r.UseGPU)
+		assert.Equal(t, 0, r.MaxParallelProofs)
+		assert.Equal(t, 3, r.BoundParallelProofs(3))
+	})
+
+	t.Run("bounds CPUs", func(t *testing.T) {
+		assert.Equal(t, 2, newResources(8, nil, 0, true, 2, 0).CPULimit)
+		assert.Equal(t, 8, newResources(8, nil, 0, true, 16, 0).CPULimit)
+	})
+
+	t.Run("doesn't use missing GPUs", func(t *testing.T) {
+		assert.False(t, newResources(8, nil, 0, true, 0, 0).UseGPU)
+		assert.False(t, newResources(8, []string{"/dev/nvidia0"}, 0, false, 0, 0).UseGPU)
+	})
+
+	t.Run("bounds parallel proofs by memory", func(t *testing.T) {
+		r := newResources(8, nil, 64<<30, true, 0, 24<<30)
+		assert.Equal(t, 2, r.MaxParallelProofs)
+		assert.Equal(t, 2, r.BoundParallelProofs(3))
+		assert.Equal(t, 1, r.BoundParallelProofs(1))
+
+		assert.Equal(t, 1, newResources(8, nil, 16<<30, true, 0, 24<<30).MaxParallelProofs)
+		assert.Equal(t, 0, newResources(8, nil, 0, true, 0, 24<<30).MaxParallelProofs, "memory unknown")
+	})
+}
+
+func TestParseMemTotal(t *testing.T) {
+	t.Parallel()
+
+	memory, err := parseMemTotal(strings.NewReader("MemTotal:       16318480 kB\nMemFree:         1234567 kB\n"))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(16318480*1024), memory)
+
+	_, err = parseMemTotal(strings.NewReader("MemFree: 1 kB\n"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,178 @@
+package sectorbuilder
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"sync"
+
+	uio "gx/ipfs/QmQXze9tG878pa4Euya4rrDpyTNX3kQe4dhCaBzBozGgpe/go-unixfs/io"
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	dag "gx/ipfs/QmTQdH4848iTVCJmKXYyRiK72HufWTLYQQ8iN3JaQ8K1Hq/go-merkledag"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	bserv "gx/ipfs/QmYPZzd9VqmJDwxUnThfeSbV1Y5o53aVPDijTB7j7rS9Ep/go-blockservice"
+
+	"github.com/filecoin-project/go-filecoin/proofs"
+)
+
+// insecureMaxUserBytes is the number of piece bytes fitting into the sectors
+// of an InsecureSectorBuilder, the same as for the sectors of the proofs
+// library.
+var insecureMaxUserBytes = map[proofs.SectorStoreType]uint64{
+	proofs.Live: 266338304,
+	proofs.Test: 127,
+}
+
+// InsecureSectorBuilder is a SectorBuilder for insecure proofs mode. It seals
+// sectors instantly, with commitments derived from their pieces and the fake
+// proofs proofs.InsecureVerifier accepts. Pieces are read back from the
+// block service rather than unsealed. Sectors are kept in memory, so staged
+// sectors are lost when the node stops.
+type InsecureSectorBuilder struct {
+	blockService bserv.BlockService
+	proverID     [31]byte
+	maxUserBytes uint64
+	results      chan SectorSealResult
+
+	lk           sync.Mutex
+	lastSectorID uint64
+	staged       *insecureSector
+	// sealed maps the pieces of sealed sectors to their sectors.
+	sealed map[string]uint64
+}
+
+var _ SectorBuilder = &InsecureSectorBuilder{}
+
+type insecureSector struct {
+	id     uint64
+	pieces []*PieceInfo
+	bytes  uint64
+}
+
+// NewInsecureSectorBuilder returns an InsecureSectorBuilder. Only the block
+// service, last used sector id, miner address and sector store type of cfg
+// are used.
+func NewInsecureSectorBuilder(cfg RustSectorBuilderConfig) (*InsecureSectorBuilder, error) {
+	maxUserBytes, ok := insecureMaxUserBytes[cfg.SectorStoreType]
+	if !ok {
+		return nil, errors.Errorf("unknown sector store type: %v", cfg.SectorStoreType)
+	}
+	return &InsecureSectorBuilder{
+		blockService: cfg.BlockService,
+		proverID:     AddressToProverID(cfg.MinerAddr),
+		maxUserBytes: maxUserBytes,
+		results:      make(chan SectorSealResult),
+		lastSectorID: cfg.LastUsedSectorID,
+		sealed:       make(map[string]uint64),
+	}, nil
+}
+
+// AddPiece adds the piece to the staged sector, sealing the sector once
+// full.
+func (sb *InsecureSectorBuilder) AddPiece(ctx context.Context, pi *PieceInfo) (uint64, error) {
+	if pi.Size > sb.maxUserBytes {
+		return 0, fmt.Errorf("piece of %d bytes does not fit into a sector of %d bytes", pi.Size, sb.maxUserBytes)
+	}
+	// the piece is read back from the block service, it must be there
+	if _, err := dag.NewDAGService(sb.blockService).Get(ctx, pi.Ref); err != nil {
+		return 0, err
+	}
+
+	sb.lk.Lock()
+	defer sb.lk.Unlock()
+
+	if sb.staged != nil && sb.staged.bytes+pi.Size > sb.maxUserBytes {
+		sb.sealStaged()
+	}
+	if sb.staged == nil {
+		sb.lastSectorID++
+		sb.staged = &insecureSector{id: sb.lastSectorID}
+	}
+	s := sb.staged
+	s.pieces = append(s.pieces, pi)
+	s.bytes += pi.Size
+	if s.bytes == sb.maxUserBytes {
+		sb.sealStaged()
+	}
+	return s.id, nil
+}
+
+// ReadPieceFromSealedSector reads the piece from the block service.
+func (sb *InsecureSectorBuilder) ReadPieceFromSealedSector(pieceCid cid.Cid) (io.Reader, error) {
+	sb.lk.Lock()
+	_, ok := sb.sealed[pieceCid.String()]
+	sb.lk.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("piece %s is not in a sealed sector", pieceCid)
+	}
+
+	ctx := context.Background()
+	dserv := dag.NewDAGService(sb.blockService)
+	root, err := dserv.Get(ctx, pieceCid)
+	if err != nil {
+		return nil, err
+	}
+	return uio.NewDagReader(ctx, root, dserv)
+}
+
+// SealAllStagedSectors seals the staged sector, if any.
+func (sb *InsecureSectorBuilder) SealAllStagedSectors(ctx context.Context) error {
+	sb.lk.Lock()
+	defer sb.lk.Unlock()
+	if sb.staged != nil {
+		sb.sealStaged()
+	}
+	return nil
+}
+
+// sealStaged seals the staged sector. The caller holds lk.
+func (sb *InsecureSectorBuilder) sealStaged() {
+	s := sb.staged
+	sb.staged = nil
+
+	h := sha256.New()
+	for _, p := range s.pieces {
+		h.Write(p.Ref.Bytes()) // nolint: errcheck
+		sb.sealed[p.Ref.String()] = s.id
+	}
+	var commD proofs.CommD
+	copy(commD[:], h.Sum(nil))
+
+	sectorID := SectorIDToBytes(s.id)
+	commR := proofs.CommR(sha256.Sum256(append(append(commD[:], sb.proverID[:]...), sectorID[:]...)))
+	commRStar := proofs.CommRStar(sha256.Sum256(commR[:]))
+
+	meta := &SealedSectorMetadata{
+		CommD:     commD,
+		CommR:     commR,
+		CommRStar: commRStar,
+		Pieces:    s.pieces,
+		Proof:     proofs.InsecureSealProof(commD, commR, commRStar, sb.proverID, sectorID),
+		SectorID:  s.id,
+	}
+	go func() {
+		sb.results <- SectorSealResult{SectorID: s.id, SealingResult: meta}
+	}()
+}
+
+// SectorSealResults returns the channel sealed sectors are sent on.
+func (sb *InsecureSectorBuilder) SectorSealResults() <-chan SectorSealResult {
+	return sb.results
+}
+
+// GetMaxUserBytesPerStagedSector returns the number of piece bytes fitting
+// into a sector.
+func (sb *InsecureSectorBuilder) GetMaxUserBytesPerStagedSector() (uint64, error) {
+	return sb.maxUserBytes, nil
+}
+
+// GeneratePoST returns the fake PoSt of the sectors, without faults.
+func (sb *InsecureSectorBuilder) GeneratePoST(req GeneratePoSTRequest) (GeneratePoSTResponse, error) {
+	return GeneratePoSTResponse{Proof: proofs.InsecurePoStProof(req.CommRs)}, nil
+}
+
+// Close does nothing.
+func (sb *InsecureSectorBuilder) Close() error {
+	return nil
+}
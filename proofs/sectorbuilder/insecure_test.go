@@ -0,0 +1,88 @@
+package sectorbuilder
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	imp "gx/ipfs/QmQXze9tG878pa4Euya4rrDpyTNX3kQe4dhCaBzBozGgpe/go-unixfs/importer"
+	chunk "gx/ipfs/QmR4QQVkBZsZENRjYFVi8dEtPL3daZRNKk24m4r6WKJHNm/go-ipfs-chunker"
+	"gx/ipfs/QmS2aqUZLJp8kF1ihE5rvDGE5LvmKDPnx32w9Z1BW9xLV5/go-ipfs-blockstore"
+	dag "gx/ipfs/QmTQdH4848iTVCJmKXYyRiK72HufWTLYQQ8iN3JaQ8K1Hq/go-merkledag"
+	bserv "gx/ipfs/QmYPZzd9VqmJDwxUnThfeSbV1Y5o53aVPDijTB7j7rS9Ep/go-blockservice"
+	"gx/ipfs/QmYZwey1thDTynSrvd6qQkX24UpTka6TFhQ2v569UpoqxD/go-ipfs-exchange-offline"
+	ds "gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/proofs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInsecureSectorBuilder(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	bs := blockstore.NewBlockstore(ds.NewMapDatastore())
+	blockService := bserv.New(bs, offline.Exchange(bs))
+	minerAddr := address.MakeTestAddress("wombat")
+	sb, err := NewInsecureSectorBuilder(RustSectorBuilderConfig{
+		BlockService:     blockService,
+		LastUsedSectorID: 41,
+		MinerAddr:        minerAddr,
+		SectorStoreType:  proofs.Test,
+	})
+	require.NoError(err)
+
+	addPiece := func(data []byte) *PieceInfo {
+		nd, err := imp.BuildDagFromReader(dag.NewDAGService(blockService), chunk.DefaultSplitter(bytes.NewReader(data)))
+		require.NoError(err)
+		pi := &PieceInfo{Ref: nd.Cid(), Size: uint64(len(data))}
+		sectorID, err := sb.AddPiece(ctx, pi)
+		require.NoError(err)
+		assert.Equal(uint64(42), sectorID)
+		return pi
+	}
+	first := addPiece([]byte("hello"))
+	addPiece([]byte("world"))
+
+	_, err = sb.ReadPieceFromSealedSector(first.Ref)
+	assert.Error(err, "the sector is not sealed yet")
+
+	require.NoError(sb.SealAllStagedSectors(ctx))
+	var res SectorSealResult
+	select {
+	case res = <-sb.SectorSealResults():
+	case <-time.After(time.Second):
+		require.Fail("sector not sealed")
+	}
+	require.NoError(res.SealingErr)
+	meta := res.SealingResult
+	assert.Equal(uint64(42), meta.SectorID)
+	assert.Len(meta.Pieces, 2)
+
+	valid, err := (&proofs.InsecureVerifier{}).VerifySeal(proofs.VerifySealRequest{
+		CommD:     meta.CommD,
+		CommR:     meta.CommR,
+		CommRStar: meta.CommRStar,
+		Proof:     meta.Proof,
+		ProverID:  AddressToProverID(minerAddr),
+		SectorID:  SectorIDToBytes(meta.SectorID),
+	})
+	require.NoError(err)
+	assert.True(valid.IsValid)
+
+	r, err := sb.ReadPieceFromSealedSector(first.Ref)
+	require.NoError(err)
+	data, err := ioutil.ReadAll(r)
+	require.NoError(err)
+	assert.Equal("hello", string(data))
+
+	post, err := sb.GeneratePoST(GeneratePoSTRequest{CommRs: []proofs.CommR{meta.CommR}})
+	require.NoError(err)
+	assert.Equal(proofs.InsecurePoStProof([]proofs.CommR{meta.CommR}), post.Proof)
+}
@@ -0,0 +1,108 @@
+package sectorbuilder
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/filecoin-project/go-filecoin/proofs"
+)
+
+// Bin is a sector's worth of pieces packed by a Packer.
+type Bin struct {
+	Pieces []*PieceInfo
+	// Used is the sum of the padded sizes of the pieces, out of the
+	// Capacity of the sector.
+	Used     uint64
+	Capacity uint64
+	// Opened is the time the first piece was packed into the bin.
+	Opened time.Time
+}
+
+// Free returns the padded bytes left in the bin.
+func (b *Bin) Free() uint64 {
+	return b.Capacity - b.Used
+}
+
+// Full returns whether the bin has no room left.
+func (b *Bin) Full() bool {
+	return b.Used == b.Capacity
+}
+
+// Packer packs pieces into sectors before they are staged, putting each
+// piece into the fullest sector it fits in, by the padded sizes of the
+// pieces, to waste as little of the sectors as possible on padding. Pieces
+// wait in their bin until it is full, or until it waited long enough to be
+// sealed partially full.
+type Packer struct {
+	capacity uint64
+
+	lk   sync.Mutex
+	bins []*Bin
+}
+
+// NewPacker returns a Packer packing pieces into sectors holding maxUserBytes
+// bytes of pieces.
+func NewPacker(maxUserBytes uint64) *Packer {
+	return &Packer{capacity: maxUserBytes / 127 * 128}
+}
+
+// Add packs the piece into the fullest bin it fits in, opening a new bin at
+// now if it fits in none.
+func (p *Packer) Add(pi *PieceInfo, now time.Time) error {
+	size := proofs.PaddedPieceSize(pi.Size)
+	if size > p.capacity {
+		return fmt.Errorf("piece of %d bytes does not fit into a sector of %d bytes", pi.Size, p.capacity)
+	}
+
+	p.lk.Lock()
+	defer p.lk.Unlock()
+
+	var best *Bin
+	for _, b := range p.bins {
+		if b.Free() >= size && (best == nil || b.Free() < best.Free()) {
+			best = b
+		}
+	}
+	if best == nil {
+		best = &Bin{Capacity: p.capacity, Opened: now}
+		p.bins = append(p.bins, best)
+	}
+	best.Pieces = append(best.Pieces, pi)
+	best.Used += size
+	return nil
+}
+
+// Ready removes and returns the bins to seal: the full ones, and the ones
+// opened maxWait or longer before now. The pieces of each bin are sorted by
+// decreasing size, the order to stage them in.
+func (p *Packer) Ready(now time.Time, maxWait time.Duration) []*Bin {
+	p.lk.Lock()
+	defer p.lk.Unlock()
+
+	var ready, open []*Bin
+	for _, b := range p.bins {
+		if b.Full() || now.Sub(b.Opened) >= maxWait {
+			sort.SliceStable(b.Pieces, func(i, j int) bool { return b.Pieces[i].Size > b.Pieces[j].Size })
+			ready = append(ready, b)
+		} else {
+			open = append(open, b)
+		}
+	}
+	p.bins = open
+	return ready
+}
+
+// Bins returns copies of the bins waiting for more pieces.
+func (p *Packer) Bins() []Bin {
+	p.lk.Lock()
+	defer p.lk.Unlock()
+
+	bins := make([]Bin, len(p.bins))
+	for i, b := range p.bins {
+		bins[i] = *b
+		bins[i].Pieces = append([]*PieceInfo{}, b.Pieces...)
+	}
+	return bins
+}
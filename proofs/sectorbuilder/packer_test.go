@@ -0,0 +1,75 @@
+package sectorbuilder
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func TestPacker(t *testing.T) {
+	t.Parallel()
+
+	// sectors of 1024 padded bytes, pieces of 127 bytes take up 128
+	const maxUserBytes = 127 * 8
+	start := time.Unix(1000, 0)
+
+	piece := func(size uint64) *PieceInfo {
+		return &PieceInfo{Ref: types.SomeCid(), Size: size}
+	}
+
+	t.Run("packs pieces into the fullest bin they fit in", func(t *testing.T) {
+		p := NewPacker(maxUserBytes)
+		require.NoError(t, p.Add(piece(500), start))  // 512 padded
+		require.NoError(t, p.Add(piece(1000), start)) // fills a sector
+		require.NoError(t, p.Add(piece(200), start))  // 256 padded
+		require.NoError(t, p.Add(piece(100), start))  // 128 padded
+
+		bins := p.Bins()
+		require.Len(t, bins, 2)
+		assert.Equal(t, uint64(896), bins[0].Used)
+		assert.Len(t, bins[0].Pieces, 3)
+		assert.True(t, bins[1].Full())
+	})
+
+	t.Run("seals full bins and bins which waited long enough", func(t *testing.T) {
+		p := NewPacker(maxUserBytes)
+		require.NoError(t, p.Add(piece(100), start))
+		require.NoError(t, p.Add(piece(1000), start.Add(time.Minute)))
+		require.NoError(t, p.Add(piece(500), start.Add(time.Minute)))
+
+		ready := p.Ready(start.Add(time.Minute), time.Hour)
+		require.Len(t, ready, 1)
+		assert.Equal(t, uint64(1000), ready[0].Pieces[0].Size)
+		require.Len(t, p.Bins(), 1)
+
+		assert.Empty(t, p.Ready(start.Add(59*time.Minute), time.Hour))
+		ready = p.Ready(start.Add(time.Hour), time.Hour)
+		require.Len(t, ready, 1)
+		assert.Equal(t, uint64(640), ready[0].Used)
+		assert.Empty(t, p.Bins())
+	})
+
+	t.Run("sorts the pieces of ready bins by decreasing size", func(t *testing.T) {
+		p := NewPacker(maxUserBytes)
+		require.NoError(t, p.Add(piece(100), start))
+		require.NoError(t, p.Add(piece(500), start))
+		require.NoError(t, p.Add(piece(200), start))
+
+		ready := p.Ready(start, 0)
+		require.Len(t, ready, 1)
+		var sizes []uint64
+		for _, pi := range ready[0].Pieces {
+			sizes = append(sizes, pi.Size)
+		}
+		assert.Equal(t, []uint64{500, 200, 100}, sizes)
+	})
+
+	t.Run("rejects pieces larger than a sector", func(t *testing.T) {
+		p := NewPacker(maxUserBytes)
+		assert.Error(t, p.Add(piece(maxUserBytes+1), start))
+	})
+}
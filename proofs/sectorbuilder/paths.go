@@ -0,0 +1,182 @@
+package sectorbuilder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// PathKind is a kind of data the sector builder keeps on disk.
+type PathKind string
+
+const (
+	// StagedPath holds the staged sectors pieces are written into.
+	StagedPath = PathKind("staged")
+	// SealedPath holds the sealed sectors.
+	SealedPath = PathKind("sealed")
+	// CachePath holds the metadata of the sector builder.
+	CachePath = PathKind("cache")
+)
+
+// StoragePath is a directory the sector builder keeps data in.
+type StoragePath struct {
+	Path string
+	// Kinds lists the data kept in the path, each in a directory of its
+	// own, see Dir.
+	Kinds []PathKind
+	// MaxBytes bounds the bytes kept in the path, 0 for the free space of
+	// its file system.
+	MaxBytes uint64
+	// Weight scales the free space of the path when choosing between paths,
+	// 0 counting as 1.
+	Weight uint64
+}
+
+// PathUsage reports the utilization of a storage path.
+type PathUsage struct {
+	Path      string     `json:"path"`
+	Kinds     []PathKind `json:"kinds"`
+	UsedBytes uint64     `json:"usedBytes"`
+	FreeBytes uint64     `json:"freeBytes"`
+	MaxBytes  uint64     `json:"maxBytes"`
+	Weight    uint64     `json:"weight"`
+	// InUse lists the kinds of data the sector builder writes to the path.
+	InUse []PathKind `json:"inUse"`
+}
+
+// Dir returns the directory of the path holding the given kind of data.
+func (p StoragePath) Dir(kind PathKind) string {
+	return filepath.Join(p.Path, string(kind))
+}
+
+// Keeps returns whether the path keeps the given kind of data.
+func (p StoragePath) Keeps(kind PathKind) bool {
+	for _, k := range p.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Usage measures the bytes kept in the path and the bytes free for more.
+func (p StoragePath) Usage() (PathUsage, error) {
+	used, err := dirSize(p.Path)
+	if err != nil {
+		return PathUsage{}, err
+	}
+	free, err := diskFree(p.Path)
+	if err != nil {
+		return PathUsage{}, err
+	}
+	if p.MaxBytes > 0 {
+		left := uint64(0)
+		if used < p.MaxBytes {
+			left = p.MaxBytes - used
+		}
+		if left < free {
+			free = left
+		}
+	}
+
+	return PathUsage{
+		Path:      p.Path,
+		Kinds:     p.Kinds,
+		UsedBytes: used,
+		FreeBytes: free,
+		MaxBytes:  p.MaxBytes,
+		Weight:    p.Weight,
+	}, nil
+}
+
+// SelectPaths chooses the directory of each kind of data among the paths
+// keeping it: the one with the most free space, scaled by the weight of the
+// path. The cache holds the metadata of the sector builder, so it stays in
+// the first path already holding a cache.
+func SelectPaths(paths []StoragePath) (map[PathKind]string, error) {
+	return selectPaths(paths, StoragePath.Usage, func(dir string) bool {
+		_, err := os.Stat(dir)
+		return err == nil
+	})
+}
+
+func selectPaths(paths []StoragePath, usage func(StoragePath) (PathUsage, error), exists func(string) bool) (map[PathKind]string, error) {
+	dirs := make(map[PathKind]string)
+
+	for _, p := range paths {
+		if p.Keeps(CachePath) && exists(p.Dir(CachePath)) {
+			dirs[CachePath] = p.Dir(CachePath)
+			break
+		}
+	}
+
+	for _, kind := range []PathKind{StagedPath, SealedPath, CachePath} {
+		if _, ok := dirs[kind]; ok {
+			continue
+		}
+
+		var best *StoragePath
+		var bestScore uint64
+		for i, p := range paths {
+			if !p.Keeps(kind) {
+				continue
+			}
+			u, err := usage(p)
+			if err != nil {
+				return nil, fmt.Errorf("failed to measure storage path %s: %s", p.Path, err)
+			}
+			weight := p.Weight
+			if weight == 0 {
+				weight = 1
+			}
+			if score := u.FreeBytes * weight; u.FreeBytes > 0 && (best == nil || score > bestScore) {
+				best, bestScore = &paths[i], score
+			}
+		}
+		if best == nil {
+			return nil, fmt.Errorf("no storage path for %s data has free space", kind)
+		}
+		dirs[kind] = best.Dir(kind)
+	}
+	return dirs, nil
+}
+
+// dirSize returns the bytes of the files under dir, 0 if it does not exist.
+func dirSize(dir string) (uint64, error) {
+	var size uint64
+	err := filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !info.IsDir() {
+			size += uint64(info.Size())
+		}
+		return nil
+	})
+	return size, err
+}
+
+// diskFree returns the bytes available on the file system of dir, or of its
+// closest existing parent.
+func diskFree(dir string) (uint64, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return 0, err
+	}
+	for {
+		var st syscall.Statfs_t
+		err := syscall.Statfs(dir, &st)
+		if err == nil {
+			return uint64(st.Bavail) * uint64(st.Bsize), nil
+		}
+		parent := filepath.Dir(dir)
+		if !os.IsNotExist(err) || parent == dir {
+			return 0, err
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,89 @@
+package sectorbuilder
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectPaths(t *testing.T) {
+	t.Parallel()
+
+	free := map[string]uint64{"/a": 100, "/b": 300, "/c": 0}
+	usage := func(p StoragePath) (PathUsage, error) {
+		return PathUsage{Path: p.Path, FreeBytes: free[p.Path]}, nil
+	}
+	none := func(string) bool { return false }
+
+	t.Run("chooses the path with the most free space", func(t *testing.T) {
+		paths := []StoragePath{
+			{Path: "/a", Kinds: []PathKind{StagedPath, SealedPath, CachePath}},
+			{Path: "/b", Kinds: []PathKind{SealedPath}},
+		}
+		dirs, err := selectPaths(paths, usage, none)
+		require.NoError(t, err)
+		assert.Equal(t, map[PathKind]string{
+			StagedPath: "/a/staged",
+			SealedPath: "/b/sealed",
+			CachePath:  "/a/cache",
+		}, dirs)
+	})
+
+	t.Run("scales free space by weight", func(t *testing.T) {
+		paths := []StoragePath{
+			{Path: "/a", Kinds: []PathKind{StagedPath, SealedPath, CachePath}, Weight: 4},
+			{Path: "/b", Kinds: []PathKind{SealedPath}},
+		}
+		dirs, err := selectPaths(paths, usage, none)
+		require.NoError(t, err)
+		assert.Equal(t, "/a/sealed", dirs[SealedPath])
+	})
+
+	t.Run("keeps the cache where it is", func(t *testing.T) {
+		paths := []StoragePath{
+			{Path: "/b", Kinds: []PathKind{StagedPath, SealedPath, CachePath}},
+			{Path: "/a", Kinds: []PathKind{CachePath}},
+		}
+		dirs, err := selectPaths(paths, usage, func(dir string) bool { return dir == "/a/cache" })
+		require.NoError(t, err)
+		assert.Equal(t, "/a/cache", dirs[CachePath])
+	})
+
+	t.Run("fails without a path with free space", func(t *testing.T) {
+		paths := []StoragePath{
+			{Path: "/b", Kinds: []PathKind{StagedPath, CachePath}},
+			{Path: "/c", Kinds: []PathKind{SealedPath}},
+		}
+		_, err := selectPaths(paths, usage, none)
+		assert.EqualError(t, err, "no storage path for sealed data has free space")
+	})
+}
+
+func TestStoragePathUsage(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "storagepath")
+	require.NoError(err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	p := StoragePath{Path: dir, Kinds: []PathKind{SealedPath}, MaxBytes: 1000}
+	require.NoError(os.MkdirAll(p.Dir(SealedPath), 0755))
+	require.NoError(ioutil.WriteFile(filepath.Join(p.Dir(SealedPath), "sector"), make([]byte, 600), 0644))
+
+	u, err := p.Usage()
+	require.NoError(err)
+	assert.Equal(uint64(600), u.UsedBytes)
+	assert.Equal(uint64(400), u.FreeBytes)
+
+	missing := StoragePath{Path: filepath.Join(dir, "missing"), Kinds: []PathKind{SealedPath}}
+	u, err = missing.Usage()
+	require.NoError(err)
+	assert.Equal(uint64(0), u.UsedBytes)
+	assert.True(u.FreeBytes > 0)
+}
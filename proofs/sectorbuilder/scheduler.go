@@ -0,0 +1,163 @@
+package sectorbuilder
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Stage is a step of getting piece data into a sealed sector.
+type Stage string
+
+const (
+	// AddPieceStage writes the bytes of a piece into a staged sector.
+	AddPieceStage = Stage("addpiece")
+	// SealStage seals staged sectors.
+	SealStage = Stage("seal")
+	// CommitStage commits a sealed sector to the chain.
+	CommitStage = Stage("commit")
+)
+
+// JobState is the state of a job of the Scheduler.
+type JobState string
+
+const (
+	// JobQueued means the job waits for a slot of its stage.
+	JobQueued = JobState("queued")
+	// JobRunning means the job is running.
+	JobRunning = JobState("running")
+)
+
+// Job describes a job of the Scheduler.
+type Job struct {
+	ID          uint64    `json:"id"`
+	Stage       Stage     `json:"stage"`
+	Description string    `json:"description"`
+	State       JobState  `json:"state"`
+	QueuedAt    time.Time `json:"queuedAt"`
+	// StartedAt is the time the job started running, zero while it is
+	// queued.
+	StartedAt time.Time `json:"startedAt"`
+}
+
+type schedulerJob struct {
+	Job
+	run  func() error
+	done chan error
+}
+
+// Scheduler runs the jobs of each stage in the order they are queued in,
+// running at most as many jobs of a stage at once as the limit of the stage
+// allows.
+type Scheduler struct {
+	lk sync.Mutex
+	// limits holds the number of jobs of each stage run at once.
+	limits  map[Stage]int
+	running map[Stage]int
+	// jobs holds the queued and running jobs, in the order they were queued.
+	jobs   []*schedulerJob
+	nextID uint64
+}
+
+// NewScheduler returns a Scheduler running at most limits[stage] jobs of each
+// stage at once. Stages without a positive limit run one job at a time.
+func NewScheduler(limits map[Stage]int) *Scheduler {
+	return &Scheduler{
+		limits:  limits,
+		running: make(map[Stage]int),
+	}
+}
+
+// Do queues fn as a job of the given stage and waits for it to complete,
+// returning its error. If ctx is done before the job starts, the job is
+// dropped from the queue and Do returns the error of ctx. Once started, the job
+// runs to completion.
+func (s *Scheduler) Do(ctx context.Context, stage Stage, description string, fn func() error) error {
+	s.lk.Lock()
+	s.nextID++
+	j := &schedulerJob{
+		Job: Job{
+			ID:          s.nextID,
+			Stage:       stage,
+			Description: description,
+			State:       JobQueued,
+			QueuedAt:    time.Now(),
+		},
+		run:  fn,
+		done: make(chan error, 1),
+	}
+	s.jobs = append(s.jobs, j)
+	s.dispatch()
+	s.lk.Unlock()
+
+	select {
+	case err := <-j.done:
+		return err
+	case <-ctx.Done():
+	}
+
+	s.lk.Lock()
+	if j.State == JobQueued {
+		s.remove(j)
+		s.lk.Unlock()
+		return ctx.Err()
+	}
+	s.lk.Unlock()
+	return <-j.done
+}
+
+// Jobs returns the queued and running jobs, in the order they were queued in.
+func (s *Scheduler) Jobs() []Job {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	jobs := make([]Job, len(s.jobs))
+	for i, j := range s.jobs {
+		jobs[i] = j.Job
+	}
+	return jobs
+}
+
+// dispatch starts the queued jobs whose stage has a free slot. The caller
+// must hold the lock.
+func (s *Scheduler) dispatch() {
+	for _, j := range s.jobs {
+		if j.State != JobQueued || s.running[j.Stage] >= s.limit(j.Stage) {
+			continue
+		}
+
+		j.State = JobRunning
+		j.StartedAt = time.Now()
+		s.running[j.Stage]++
+		go s.runJob(j)
+	}
+}
+
+func (s *Scheduler) runJob(j *schedulerJob) {
+	err := j.run()
+
+	s.lk.Lock()
+	s.running[j.Stage]--
+	s.remove(j)
+	s.dispatch()
+	s.lk.Unlock()
+
+	j.done <- err
+}
+
+func (s *Scheduler) limit(stage Stage) int {
+	if l := s.limits[stage]; l > 0 {
+		return l
+	}
+	return 1
+}
+
+// remove drops j from the jobs. The caller must hold the lock.
+func (s *Scheduler) remove(j *schedulerJob) {
+	for i, other := range s.jobs {
+		if other == j {
+			s.jobs = append(s.jobs[:i], s.jobs[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package sectorbuilder
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduler(t *testing.T) {
+	t.Parallel()
+
+	// blockingJob queues a job that runs until release is closed.
+	blockingJob := func(s *Scheduler, stage Stage, desc string, release chan struct{}) <-chan error {
+		done := make(chan error, 1)
+		go func() {
+			done <- s.Do(context.Background(), stage, desc, func() error {
+				<-release
+				return nil
+			})
+		}()
+		return done
+	}
+
+	jobStates := func(s *Scheduler) map[string]JobState {
+		states := make(map[string]JobState)
+		for _, j := range s.Jobs() {
+			states[j.Description] = j.State
+		}
+		return states
+	}
+
+	waitForJobs := func(s *Scheduler, n int) bool {
+		for i := 0; i < 100; i++ {
+			if len(s.Jobs()) == n {
+				return true
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return false
+	}
+
+	t.Run("limits the jobs of each stage running at once", func(t *testing.T) {
+		require := require.New(t)
+		assert := assert.New(t)
+
+		s := NewScheduler(map[Stage]int{AddPieceStage: 2})
+		release := make(chan struct{})
+		var done []<-chan error
+		for _, desc := range []string{"a", "b", "c"} {
+			done = append(done, blockingJob(s, AddPieceStage, desc, release))
+			require.True(waitForJobs(s, len(done)))
+		}
+		done = append(done, blockingJob(s, SealStage, "seal", release))
+		require.True(waitForJobs(s, 4))
+
+		assert.Equal(map[string]JobState{
+			"a":    JobRunning,
+			"b":    JobRunning,
+			"c":    JobQueued,
+			"seal": JobRunning,
+		}, jobStates(s))
+
+		close(release)
+		for _, d := range done {
+			assert.NoError(<-d)
+		}
+		assert.Empty(s.Jobs())
+	})
+
+	t.Run("starts queued jobs in order as others complete", func(t *testing.T) {
+		require := require.New(t)
+		assert := assert.New(t)
+
+		s := NewScheduler(nil)
+		first := make(chan struct{})
+		rest := make(chan struct{})
+		defer close(rest)
+		firstDone := blockingJob(s, CommitStage, "first", first)
+		require.True(waitForJobs(s, 1))
+		secondDone := blockingJob(s, CommitStage, "second", rest)
+		require.True(waitForJobs(s, 2))
+		thirdDone := blockingJob(s, CommitStage, "third", rest)
+		require.True(waitForJobs(s, 3))
+
+		jobs := s.Jobs()
+		assert.Equal("first", jobs[0].Description)
+		assert.Equal("second", jobs[1].Description)
+		assert.Equal("third", jobs[2].Description)
+
+		close(first)
+		assert.NoError(<-firstDone)
+		require.True(waitForJobs(s, 2))
+		assert.Equal(map[string]JobState{
+			"second": JobRunning,
+			"third":  JobQueued,
+		}, jobStates(s))
+
+		assert.Empty(secondDone)
+		assert.Empty(thirdDone)
+	})
+
+	t.Run("returns the error of the job", func(t *testing.T) {
+		s := NewScheduler(nil)
+		err := s.Do(context.Background(), SealStage, "fail", func() error {
+			return errors.New("boom")
+		})
+		assert.EqualError(t, err, "boom")
+	})
+
+	t.Run("drops queued jobs whose context is done", func(t *testing.T) {
+		require := require.New(t)
+		assert := assert.New(t)
+
+		s := NewScheduler(nil)
+		release := make(chan struct{})
+		defer close(release)
+		blockingJob(s, SealStage, "running", release)
+		require.True(waitForJobs(s, 1))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			done <- s.Do(ctx, SealStage, "queued", func() error {
+				t.Error("dropped job ran")
+				return nil
+			})
+		}()
+		require.True(waitForJobs(s, 2))
+
+		cancel()
+		assert.Equal(context.Canceled, <-done)
+		assert.Equal(map[string]JobState{"running": JobRunning}, jobStates(s))
+	})
+}
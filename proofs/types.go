@@ -32,3 +32,7 @@ type CommD [CommitmentBytesLen]byte
 // CommRStar is a hash of intermediate layers. It is an output of the sector
 // sealing (PoRep) process.
 type CommRStar [CommitmentBytesLen]byte
+
+// CommP is the merkle root of the data of a piece, padded as it is when
+// written to a sector. It commits to the piece independent of the sector.
+type CommP [CommitmentBytesLen]byte
@@ -0,0 +1,431 @@
+// Package datatransfer moves the DAGs of pieces between nodes. It tracks the
+// progress of each transfer, persisting it so transfers can be paused, resumed
+// after restarts, and reported on.
+package datatransfer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	cbor "gx/ipfs/QmRoARq3nkUb13HSKZGepCZSWe5GrVPwx7xURJGZ7KWv9V/go-ipld-cbor"
+	dag "gx/ipfs/QmTQdH4848iTVCJmKXYyRiK72HufWTLYQQ8iN3JaQ8K1Hq/go-merkledag"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	ipld "gx/ipfs/QmcKKBwfz6FyQdHR2jsXrrF6XeSBXYL86anmWNewpFpoF5/go-ipld-format"
+	logging "gx/ipfs/QmcuXC5cxs79ro2cUuHs4HQ2bkDLJUYokwL8aivcX6HW3C/go-log"
+	"gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
+	"gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore/query"
+
+	"github.com/filecoin-project/go-filecoin/repo"
+)
+
+var log = logging.Logger("/fil/datatransfer")
+
+const datastorePrefix = "transfers"
+
+// maxRetries is the number of times a transfer restarts after failing, e.g.
+// because the peers holding the data disconnected, before giving up.
+const maxRetries = 10
+
+// The delay before restarting a failed transfer doubles with every retry,
+// from minRetryDelay up to maxRetryDelay.
+var (
+	minRetryDelay = time.Second
+	maxRetryDelay = time.Minute
+)
+
+// saveInterval is the number of blocks received between persisting the
+// progress of a transfer.
+const saveInterval = 64
+
+func init() {
+	cbor.RegisterCborType(Status{})
+}
+
+// State is the state of a transfer.
+type State int
+
+const (
+	// Ongoing means the data is being transferred.
+	Ongoing = State(iota)
+	// Paused means the transfer was paused and waits to be resumed.
+	Paused
+	// Complete means all of the data was transferred.
+	Complete
+	// Failed means the transfer failed, even after restarting it.
+	Failed
+)
+
+func (s State) String() string {
+	switch s {
+	case Ongoing:
+		return "ongoing"
+	case Paused:
+		return "paused"
+	case Complete:
+		return "complete"
+	case Failed:
+		return "failed"
+	default:
+		return fmt.Sprintf("<unrecognized %d>", s)
+	}
+}
+
+// Status is the progress of a transfer.
+type Status struct {
+	// ID identifies the transfer, e.g. by the cid of the deal proposal it
+	// transfers the data of.
+	ID cid.Cid
+	// Root is the cid of the root of the DAG being transferred.
+	Root  cid.Cid
+	State State
+
+	BytesReceived  uint64
+	BlocksReceived uint64
+	// ExpectedBytes is the size of the data, or 0 if unknown.
+	ExpectedBytes uint64
+
+	// Retries is the number of times the transfer restarted after failing.
+	Retries uint64
+	// Error is the last error the transfer failed with.
+	Error string
+}
+
+type transfer struct {
+	status Status
+	// cancel stops the running attempt at fetching the data, if any.
+	cancel context.CancelFunc
+	// resumed is closed when the transfer is resumed after being paused.
+	resumed chan struct{}
+}
+
+// Manager runs the transfers of a node.
+type Manager struct {
+	nodes    ipld.NodeGetter
+	ds       repo.Datastore
+	throttle *throttle
+
+	lk        sync.Mutex
+	transfers map[cid.Cid]*transfer
+}
+
+// NewManager returns a manager fetching the nodes of DAGs from nodes, and
+// persisting the progress of transfers in ds.
+func NewManager(nodes ipld.NodeGetter, ds repo.Datastore) (*Manager, error) {
+	m := &Manager{
+		nodes:     nodes,
+		ds:        ds,
+		throttle:  &throttle{},
+		transfers: make(map[cid.Cid]*transfer),
+	}
+	if err := m.load(); err != nil {
+		return nil, errors.Wrap(err, "failed to load transfers")
+	}
+	return m, nil
+}
+
+// SetBandwidth limits the bandwidth all transfers together use to the given
+// number of bytes per second. Zero lifts the limit.
+func (m *Manager) SetBandwidth(bytesPerSecond uint64) {
+	m.throttle.setRate(bytesPerSecond)
+}
+
+// Pull transfers the DAG rooted at root from the network to the local store,
+// as the transfer with the given id. Transfers with the id that were
+// interrupted, e.g. by a restart, continue where they stopped.
+//
+// Pull blocks until the transfer completes, fails or ctx is done. Transfers
+// that fail are restarted, up to maxRetries times. While the transfer is
+// paused, Pull waits for it to be resumed.
+func (m *Manager) Pull(ctx context.Context, id, root cid.Cid, expectedBytes uint64) error {
+	t := m.register(id, root, expectedBytes)
+
+	retryDelay := minRetryDelay
+	for {
+		if err := m.waitUntilResumed(ctx, t); err != nil {
+			return err
+		}
+
+		attemptCtx, cancel := context.WithCancel(ctx)
+		if !m.startAttempt(t, cancel) {
+			cancel()
+			continue
+		}
+		err := m.fetch(attemptCtx, t)
+		cancel()
+
+		if err == nil {
+			m.finish(t, Complete, "")
+			return nil
+		}
+		if ctx.Err() != nil {
+			m.save(t)
+			return ctx.Err()
+		}
+		if m.State(id) == Paused {
+			continue
+		}
+
+		if !m.retry(t, err) {
+			m.finish(t, Failed, err.Error())
+			return errors.Wrap(err, "transfer failed")
+		}
+		log.Warningf("transfer %s failed, restarting in %s: %s", id, retryDelay, err)
+
+		select {
+		case <-time.After(retryDelay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		retryDelay *= 2
+		if retryDelay > maxRetryDelay {
+			retryDelay = maxRetryDelay
+		}
+	}
+}
+
+// Pause pauses the ongoing transfer with the given id.
+func (m *Manager) Pause(id cid.Cid) error {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	t, ok := m.transfers[id]
+	if !ok {
+		return fmt.Errorf("no such transfer: %s", id)
+	}
+	if t.status.State != Ongoing {
+		return fmt.Errorf("transfer is %s", t.status.State)
+	}
+
+	t.status.State = Paused
+	t.resumed = make(chan struct{})
+	if t.cancel != nil {
+		t.cancel()
+	}
+	return m.saveLocked(t)
+}
+
+// Resume resumes the paused transfer with the given id.
+func (m *Manager) Resume(id cid.Cid) error {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	t, ok := m.transfers[id]
+	if !ok {
+		return fmt.Errorf("no such transfer: %s", id)
+	}
+	if t.status.State != Paused {
+		return fmt.Errorf("transfer is %s", t.status.State)
+	}
+
+	t.status.State = Ongoing
+	close(t.resumed)
+	return m.saveLocked(t)
+}
+
+// Status returns the status of the transfer with the given id.
+func (m *Manager) Status(id cid.Cid) (Status, bool) {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	t, ok := m.transfers[id]
+	if !ok {
+		return Status{}, false
+	}
+	return t.status, true
+}
+
+// State returns the state of the transfer with the given id.
+func (m *Manager) State(id cid.Cid) State {
+	status, _ := m.Status(id)
+	return status.State
+}
+
+// List returns the status of all transfers.
+func (m *Manager) List() []Status {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	var statuses []Status
+	for _, t := range m.transfers {
+		statuses = append(statuses, t.status)
+	}
+	return statuses
+}
+
+func (m *Manager) register(id, root cid.Cid, expectedBytes uint64) *transfer {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	t, ok := m.transfers[id]
+	if !ok || !t.status.Root.Equals(root) {
+		t = &transfer{status: Status{
+			ID:            id,
+			Root:          root,
+			State:         Ongoing,
+			ExpectedBytes: expectedBytes,
+		}}
+		m.transfers[id] = t
+	} else if t.status.State != Paused {
+		// a transfer that completed or failed before is repeated
+		t.status.State = Ongoing
+	}
+	return t
+}
+
+func (m *Manager) waitUntilResumed(ctx context.Context, t *transfer) error {
+	m.lk.Lock()
+	if t.status.State != Paused {
+		m.lk.Unlock()
+		return nil
+	}
+	resumed := t.resumed
+	m.lk.Unlock()
+
+	select {
+	case <-resumed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// startAttempt records the start of an attempt at fetching the data, which
+// cancel stops. It returns false if the transfer was paused meanwhile.
+func (m *Manager) startAttempt(t *transfer, cancel context.CancelFunc) bool {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	if t.status.State == Paused {
+		return false
+	}
+	t.cancel = cancel
+	// blocks received before are counted again as they are read from the
+	// local store
+	t.status.BytesReceived = 0
+	t.status.BlocksReceived = 0
+	return true
+}
+
+// retry records that the transfer failed with err, and returns false if it is
+// not to be restarted.
+func (m *Manager) retry(t *transfer, err error) bool {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	t.cancel = nil
+	t.status.Error = err.Error()
+	if t.status.Retries >= maxRetries {
+		return false
+	}
+	t.status.Retries++
+	if err := m.saveLocked(t); err != nil {
+		log.Errorf("failed to save transfer %s: %s", t.status.ID, err)
+	}
+	return true
+}
+
+func (m *Manager) finish(t *transfer, state State, message string) {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	t.cancel = nil
+	t.status.State = state
+	t.status.Error = message
+	if err := m.saveLocked(t); err != nil {
+		log.Errorf("failed to save transfer %s: %s", t.status.ID, err)
+	}
+}
+
+// fetch walks the DAG of the transfer, fetching the nodes missing locally from
+// the network.
+func (m *Manager) fetch(ctx context.Context, t *transfer) error {
+	nodes := dag.NewSession(ctx, m.nodes)
+
+	visited := make(map[cid.Cid]bool)
+	todo := []cid.Cid{t.status.Root}
+	for len(todo) > 0 {
+		c := todo[len(todo)-1]
+		todo = todo[:len(todo)-1]
+		if visited[c] {
+			continue
+		}
+		visited[c] = true
+
+		nd, err := nodes.Get(ctx, c)
+		if err != nil {
+			return errors.Wrapf(err, "failed to fetch %s", c)
+		}
+
+		size := len(nd.RawData())
+		if err := m.throttle.wait(ctx, size); err != nil {
+			return err
+		}
+		m.progress(t, uint64(size))
+
+		for _, l := range nd.Links() {
+			todo = append(todo, l.Cid)
+		}
+	}
+	return nil
+}
+
+func (m *Manager) progress(t *transfer, size uint64) {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	t.status.BytesReceived += size
+	t.status.BlocksReceived++
+	if t.status.BlocksReceived%saveInterval == 0 {
+		if err := m.saveLocked(t); err != nil {
+			log.Errorf("failed to save transfer %s: %s", t.status.ID, err)
+		}
+	}
+}
+
+func (m *Manager) save(t *transfer) {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	if err := m.saveLocked(t); err != nil {
+		log.Errorf("failed to save transfer %s: %s", t.status.ID, err)
+	}
+}
+
+func (m *Manager) saveLocked(t *transfer) error {
+	datum, err := cbor.DumpObject(t.status)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal transfer")
+	}
+
+	key := datastore.KeyWithNamespaces([]string{datastorePrefix, t.status.ID.String()})
+	if err := m.ds.Put(key, datum); err != nil {
+		return errors.Wrap(err, "could not save transfer")
+	}
+	return nil
+}
+
+func (m *Manager) load() error {
+	res, err := m.ds.Query(query.Query{
+		Prefix: "/" + datastorePrefix,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to query transfers from datastore")
+	}
+	defer res.Close() // nolint: errcheck
+
+	for entry := range res.Next() {
+		var status Status
+		if err := cbor.DecodeInto(entry.Value, &status); err != nil {
+			return errors.Wrap(err, "failed to unmarshal transfer from datastore")
+		}
+		t := &transfer{status: status}
+		if status.State == Paused {
+			t.resumed = make(chan struct{})
+		}
+		m.transfers[status.ID] = t
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package datatransfer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	dag "gx/ipfs/QmTQdH4848iTVCJmKXYyRiK72HufWTLYQQ8iN3JaQ8K1Hq/go-merkledag"
+	ipld "gx/ipfs/QmcKKBwfz6FyQdHR2jsXrrF6XeSBXYL86anmWNewpFpoF5/go-ipld-format"
+
+	"github.com/filecoin-project/go-filecoin/repo"
+	"github.com/filecoin-project/go-filecoin/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testNodeGetter serves the nodes of a DAG, failing the first failures
+// requests as if the peers holding the data disconnected, and blocking
+// requests while blocked is set.
+type testNodeGetter struct {
+	nodes map[cid.Cid]ipld.Node
+
+	lk       sync.Mutex
+	failures int
+	blocked  chan struct{}
+}
+
+func (g *testNodeGetter) Get(ctx context.Context, c cid.Cid) (ipld.Node, error) {
+	g.lk.Lock()
+	blocked := g.blocked
+	if g.failures > 0 {
+		g.failures--
+		g.lk.Unlock()
+		return nil, fmt.Errorf("peers disconnected")
+	}
+	g.lk.Unlock()
+
+	if blocked != nil {
+		select {
+		case <-blocked:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	nd, ok := g.nodes[c]
+	if !ok {
+		return nil, ipld.ErrNotFound
+	}
+	return nd, nil
+}
+
+func (g *testNodeGetter) GetMany(ctx context.Context, cids []cid.Cid) <-chan *ipld.NodeOption {
+	out := make(chan *ipld.NodeOption, len(cids))
+	for _, c := range cids {
+		nd, err := g.Get(ctx, c)
+		out <- &ipld.NodeOption{Node: nd, Err: err}
+	}
+	close(out)
+	return out
+}
+
+// newTestDAG returns a getter for a DAG of a root with the given number of raw
+// leaves of 100 bytes each, and the cid of the root.
+func newTestDAG(t *testing.T, leaves int) (*testNodeGetter, cid.Cid) {
+	g := &testNodeGetter{nodes: make(map[cid.Cid]ipld.Node)}
+
+	root := &dag.ProtoNode{}
+	for i := 0; i < leaves; i++ {
+		data := make([]byte, 100)
+		data[0] = byte(i)
+		leaf := dag.NewRawNode(data)
+		g.nodes[leaf.Cid()] = leaf
+		require.NoError(t, root.AddNodeLink(fmt.Sprintf("%d", i), leaf))
+	}
+	g.nodes[root.Cid()] = root
+	return g, root.Cid()
+}
+
+func TestPullReportsProgress(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	nodes, root := newTestDAG(t, 5)
+	m, err := NewManager(nodes, repo.NewInMemoryRepo().DealsDatastore())
+	require.NoError(err)
+
+	id := types.SomeCid()
+	require.NoError(m.Pull(context.Background(), id, root, 500))
+
+	status, ok := m.Status(id)
+	require.True(ok)
+	assert.Equal(Complete, status.State)
+	assert.Equal(root, status.Root)
+	assert.Equal(uint64(6), status.BlocksReceived)
+	assert.True(status.BytesReceived > 500)
+	assert.Equal(uint64(500), status.ExpectedBytes)
+	assert.Len(m.List(), 1)
+}
+
+func TestPullRestartsAfterFailures(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	defer func(d time.Duration) { minRetryDelay = d }(minRetryDelay)
+	minRetryDelay = time.Millisecond
+
+	nodes, root := newTestDAG(t, 3)
+	nodes.failures = 2
+	m, err := NewManager(nodes, repo.NewInMemoryRepo().DealsDatastore())
+	require.NoError(err)
+
+	id := types.SomeCid()
+	require.NoError(m.Pull(context.Background(), id, root, 0))
+
+	status, _ := m.Status(id)
+	assert.Equal(Complete, status.State)
+	assert.Equal(uint64(2), status.Retries)
+	assert.Equal(uint64(4), status.BlocksReceived)
+
+	nodes.failures = maxRetries + 1
+	err = m.Pull(context.Background(), types.NewCidForTestGetter()(), root, 0)
+	assert.Error(err)
+}
+
+func TestPauseAndResume(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	nodes, root := newTestDAG(t, 3)
+	nodes.blocked = make(chan struct{})
+	ds := repo.NewInMemoryRepo().DealsDatastore()
+	m, err := NewManager(nodes, ds)
+	require.NoError(err)
+
+	id := types.SomeCid()
+	done := make(chan error)
+	go func() {
+		done <- m.Pull(context.Background(), id, root, 0)
+	}()
+
+	require.NoError(waitForState(m, id, Ongoing))
+	require.NoError(m.Pause(id))
+	assert.Equal(Paused, m.State(id))
+	assert.Error(m.Pause(id))
+
+	// the pause survives a restart
+	restarted, err := NewManager(nodes, ds)
+	require.NoError(err)
+	assert.Equal(Paused, restarted.State(id))
+
+	close(nodes.blocked)
+	select {
+	case <-done:
+		t.Fatal("paused transfer completed")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	require.NoError(m.Resume(id))
+	require.NoError(<-done)
+	assert.Equal(Complete, m.State(id))
+	assert.Error(m.Resume(id))
+}
+
+func TestThrottleLimitsBandwidth(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+
+	nodes, root := newTestDAG(t, 4)
+	m, err := NewManager(nodes, repo.NewInMemoryRepo().DealsDatastore())
+	require.NoError(err)
+	m.SetBandwidth(2000)
+
+	start := time.Now()
+	require.NoError(m.Pull(context.Background(), types.SomeCid(), root, 0))
+
+	// at least 400 bytes of leaves at 2000 bytes per second
+	require.True(time.Since(start) >= 200*time.Millisecond)
+}
+
+func waitForState(m *Manager, id cid.Cid, state State) error {
+	for i := 0; i < 100; i++ {
+		if status, ok := m.Status(id); ok && status.State == state {
+			return nil
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return fmt.Errorf("transfer never reached state %s", state)
+}
@@ -0,0 +1,49 @@
+package datatransfer
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// throttle limits the rate at which bytes are transferred. Transfers wait on
+// the throttle for the time it takes to transfer their bytes at the rate, in
+// turn.
+type throttle struct {
+	lk             sync.Mutex
+	bytesPerSecond uint64
+	// next is the time the bytes transferred so far take until at the rate.
+	next time.Time
+}
+
+func (th *throttle) setRate(bytesPerSecond uint64) {
+	th.lk.Lock()
+	defer th.lk.Unlock()
+	th.bytesPerSecond = bytesPerSecond
+}
+
+// wait blocks until n more bytes may be transferred, or ctx is done.
+func (th *throttle) wait(ctx context.Context, n int) error {
+	th.lk.Lock()
+	if th.bytesPerSecond == 0 {
+		th.lk.Unlock()
+		return nil
+	}
+	now := time.Now()
+	if th.next.Before(now) {
+		th.next = now
+	}
+	th.next = th.next.Add(time.Duration(uint64(n) * uint64(time.Second) / th.bytesPerSecond))
+	delay := th.next.Sub(now)
+	th.lk.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	select {
+	case <-time.After(delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -3,17 +3,22 @@ package hello
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	ma "gx/ipfs/QmNTCey11oxhb1AxDnQBRHtdhap6Ctud872NjAYPYYXPuc/go-multiaddr"
 	net "gx/ipfs/QmNgLg1NTw37iWbYPKcyK85YJ9Whs1MkPtJwhfqbNYAyKg/go-libp2p-net"
 	cid "gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
 	cbor "gx/ipfs/QmRoARq3nkUb13HSKZGepCZSWe5GrVPwx7xURJGZ7KWv9V/go-ipld-cbor"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
 	peer "gx/ipfs/QmY5Grm8pJdiSSVsYxx4uNRgweY72EmYwuSDbRnbFok3iY/go-libp2p-peer"
+	"gx/ipfs/QmabLh8TrJ3emfAoQk5AbqbLTbMyj7XqumMFmAFxa9epo8/go-multistream"
 	host "gx/ipfs/QmaoXrM4Z41PD48JY36YqQGKQpLGjyLA2cKcLsES7YddAq/go-libp2p-host"
 	logging "gx/ipfs/QmcuXC5cxs79ro2cUuHs4HQ2bkDLJUYokwL8aivcX6HW3C/go-log"
 
 	cbu "github.com/filecoin-project/go-filecoin/cborutil"
+	"github.com/filecoin-project/go-filecoin/flags"
 	"github.com/filecoin-project/go-filecoin/types"
 )
 
@@ -22,7 +27,7 @@ func init() {
 }
 
 // Protocol is the libp2p protocol identifier for the hello protocol.
-const protocol = "/fil/hello/1.0.0"
+const protocol = "/fil/hello/2.0.0"
 
 var log = logging.Logger("/fil/hello")
 
@@ -31,12 +36,21 @@ type Message struct {
 	HeaviestTipSetCids   []cid.Cid
 	HeaviestTipSetHeight uint64
 	GenesisHash          cid.Cid
+	// NetworkName is the name of the network the node is part of.
+	NetworkName string
+	// Protocols lists the filecoin protocols the node speaks, with their
+	// versions.
+	Protocols []string
+	// Commit is the git commit the node was built from.
+	Commit string
 }
 
 type syncCallback func(from peer.ID, cids []cid.Cid, height uint64)
 
 type getTipSetFunc func() types.TipSet
 
+type timeoutCallback func(p peer.ID)
+
 // Handler implements the 'Hello' protocol handler. Upon connecting to a new
 // node, we send them a message containing some information about the state of
 // our chain, and receive the same information from them. This is used to
@@ -46,22 +60,30 @@ type Handler struct {
 
 	genesis cid.Cid
 
+	networkName string
+
 	// chainSyncCB is called when new peers tell us about their chain
 	chainSyncCB syncCallback
 
 	// getHeaviestTipSet is used to retrieve the current heaviest tipset
 	// for filling out our hello messages.
 	getHeaviestTipSet getTipSetFunc
+
+	// timeoutCB, if set, is called when a peer doesn't take our hello
+	// message in time
+	timeoutCB timeoutCallback
 }
 
 // New creates a new instance of the hello protocol and registers it to
-// the given host, with the provided callbacks.
-func New(h host.Host, gen cid.Cid, syncCallback syncCallback, getHeaviestTipSet getTipSetFunc) *Handler {
+// the given host, with the provided callbacks. timeoutCallback may be nil.
+func New(h host.Host, gen cid.Cid, networkName string, syncCallback syncCallback, getHeaviestTipSet getTipSetFunc, timeoutCallback timeoutCallback) *Handler {
 	hello := &Handler{
 		host:              h,
 		genesis:           gen,
+		networkName:       networkName,
 		chainSyncCB:       syncCallback,
 		getHeaviestTipSet: getHeaviestTipSet,
+		timeoutCB:         timeoutCallback,
 	}
 	h.SetStreamHandler(protocol, hello.handleNewStream)
 
@@ -82,29 +104,91 @@ func (h *Handler) handleNewStream(s net.Stream) {
 		return
 	}
 
-	switch err := h.processHelloMessage(from, &hello); err {
-	case ErrBadGenesis:
-		log.Warningf("genesis cid: %s does not match: %s, disconnecting from peer: %s", &hello.GenesisHash, h.genesis, from)
-		s.Conn().Close() // nolint: errcheck
-		return
-	case nil: // ok, noop
-	default:
-		log.Error(err)
+	if err := h.processHelloMessage(from, &hello); err != nil {
+		log.Warningf("disconnecting from peer %s: %s", from, err)
+		h.host.Network().ClosePeer(from) // nolint: errcheck
 	}
 }
 
 // ErrBadGenesis is the error returned when a missmatch in genesis blocks happens.
 var ErrBadGenesis = fmt.Errorf("bad genesis block")
 
+// ErrWrongNetwork is the error returned when a peer is part of another
+// network.
+var ErrWrongNetwork = fmt.Errorf("wrong network")
+
+// ErrIncompatibleProtocols is the error returned when a peer speaks other
+// versions of the protocols of the node.
+var ErrIncompatibleProtocols = fmt.Errorf("incompatible protocols")
+
 func (h *Handler) processHelloMessage(from peer.ID, msg *Message) error {
+	if msg.NetworkName != h.networkName {
+		return errors.Wrapf(ErrWrongNetwork, "peer is part of network %q, not %q", msg.NetworkName, h.networkName)
+	}
 	if !msg.GenesisHash.Equals(h.genesis) {
-		return ErrBadGenesis
+		return errors.Wrapf(ErrBadGenesis, "genesis cid: %s does not match: %s", msg.GenesisHash, h.genesis)
+	}
+	if incompatible := incompatibleProtocols(h.protocols(), msg.Protocols); len(incompatible) > 0 {
+		return errors.Wrapf(ErrIncompatibleProtocols, "peer built from commit %q speaks %s", msg.Commit, strings.Join(incompatible, ", "))
 	}
 
 	h.chainSyncCB(from, msg.HeaviestTipSetCids, msg.HeaviestTipSetHeight)
 	return nil
 }
 
+// protocols lists the filecoin protocols the host handles.
+func (h *Handler) protocols() []string {
+	var protocols []string
+	for _, p := range h.host.Mux().Protocols() {
+		if strings.HasPrefix(p, "/fil/") {
+			protocols = append(protocols, p)
+		}
+	}
+	sort.Strings(protocols)
+	return protocols
+}
+
+// incompatibleProtocols returns the protocols of theirs which are other
+// versions of protocols of ours, e.g. /fil/hello/1.0.0 when we speak
+// /fil/hello/2.0.0. A protocol is only incompatible if they offer none of
+// the versions of it we speak.
+func incompatibleProtocols(ours, theirs []string) []string {
+	versions := make(map[string]map[string]bool)
+	for _, p := range ours {
+		name, version := splitProtocol(p)
+		if versions[name] == nil {
+			versions[name] = make(map[string]bool)
+		}
+		versions[name][version] = true
+	}
+
+	shared := make(map[string]bool)
+	for _, p := range theirs {
+		name, version := splitProtocol(p)
+		if versions[name][version] {
+			shared[name] = true
+		}
+	}
+
+	var incompatible []string
+	for _, p := range theirs {
+		name, _ := splitProtocol(p)
+		if _, ok := versions[name]; ok && !shared[name] {
+			incompatible = append(incompatible, p)
+		}
+	}
+	return incompatible
+}
+
+// splitProtocol splits a protocol identifier into its name and version.
+func splitProtocol(p string) (string, string) {
+	i := strings.LastIndex(p, "/")
+	if i < 0 {
+		return p, ""
+	}
+	return p[:i], p[i+1:]
+}
+
 func (h *Handler) getOurHelloMessage() *Message {
 	heaviest := h.getHeaviestTipSet()
 	height, err := heaviest.Height()
@@ -116,6 +200,9 @@ func (h *Handler) getOurHelloMessage() *Message {
 		GenesisHash:          h.genesis,
 		HeaviestTipSetCids:   heaviest.ToSortedCidSet().ToSlice(),
 		HeaviestTipSetHeight: height,
+		NetworkName:          h.networkName,
+		Protocols:            h.protocols(),
+		Commit:               flags.Commit,
 	}
 }
 
@@ -147,7 +234,14 @@ func (hn *helloNotify) Connected(n net.Network, c net.Conn) {
 		defer cancel()
 		p := c.RemotePeer()
 		if err := hn.hello().sayHello(ctx, p); err != nil {
+			if err == multistream.ErrNotSupported {
+				log.Warningf("peer %s doesn't speak %s, it is probably running an older version", p, protocol)
+				return
+			}
 			log.Warningf("failed to send hello handshake to peer %s: %s", p, err)
+			if ctx.Err() == context.DeadlineExceeded && hn.timeoutCB != nil {
+				hn.timeoutCB(p)
+			}
 		}
 	}()
 }
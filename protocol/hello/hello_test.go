@@ -54,8 +54,8 @@ func TestHelloHandshake(t *testing.T) {
 	msc1, msc2 := new(mockSyncCallback), new(mockSyncCallback)
 	hg1, hg2 := &mockHeaviestGetter{heavy1}, &mockHeaviestGetter{heavy2}
 
-	New(a, genesisA.Cid(), msc1.SyncCallback, hg1.getHeaviestTipSet)
-	New(b, genesisA.Cid(), msc2.SyncCallback, hg2.getHeaviestTipSet)
+	New(a, genesisA.Cid(), "local", msc1.SyncCallback, hg1.getHeaviestTipSet, nil)
+	New(b, genesisA.Cid(), "local", msc2.SyncCallback, hg2.getHeaviestTipSet, nil)
 
 	msc1.On("SyncCallback", b.ID(), heavy2.ToSortedCidSet().ToSlice(), uint64(3)).Return()
 	msc2.On("SyncCallback", a.ID(), heavy1.ToSortedCidSet().ToSlice(), uint64(2)).Return()
@@ -109,8 +109,8 @@ func TestHelloBadGenesis(t *testing.T) {
 	msc1, msc2 := new(mockSyncCallback), new(mockSyncCallback)
 	hg1, hg2 := &mockHeaviestGetter{heavy1}, &mockHeaviestGetter{heavy2}
 
-	New(a, genesisA.Cid(), msc1.SyncCallback, hg1.getHeaviestTipSet)
-	New(b, genesisB.Cid(), msc2.SyncCallback, hg2.getHeaviestTipSet)
+	New(a, genesisA.Cid(), "local", msc1.SyncCallback, hg1.getHeaviestTipSet, nil)
+	New(b, genesisB.Cid(), "local", msc2.SyncCallback, hg2.getHeaviestTipSet, nil)
 
 	msc1.On("SyncCallback", mock.Anything, mock.Anything, mock.Anything).Return()
 	msc2.On("SyncCallback", mock.Anything, mock.Anything, mock.Anything).Return()
@@ -124,6 +124,57 @@ func TestHelloBadGenesis(t *testing.T) {
 	msc2.AssertNumberOfCalls(t, "SyncCallback", 0)
 }
 
+func TestHelloWrongNetwork(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require := require.New(t)
+
+	mn, err := mocknet.WithNPeers(ctx, 2)
+	assert.NoError(t, err)
+
+	a := mn.Hosts()[0]
+	b := mn.Hosts()[1]
+
+	genesisA := &types.Block{Nonce: 451}
+
+	heavy1 := th.RequireNewTipSet(require, &types.Block{Nonce: 1000, Height: 2})
+	heavy2 := th.RequireNewTipSet(require, &types.Block{Nonce: 1001, Height: 3})
+
+	msc1, msc2 := new(mockSyncCallback), new(mockSyncCallback)
+	hg1, hg2 := &mockHeaviestGetter{heavy1}, &mockHeaviestGetter{heavy2}
+
+	New(a, genesisA.Cid(), "local", msc1.SyncCallback, hg1.getHeaviestTipSet, nil)
+	New(b, genesisA.Cid(), "devnet-user", msc2.SyncCallback, hg2.getHeaviestTipSet, nil)
+
+	msc1.On("SyncCallback", mock.Anything, mock.Anything, mock.Anything).Return()
+	msc2.On("SyncCallback", mock.Anything, mock.Anything, mock.Anything).Return()
+
+	require.NoError(mn.LinkAll())
+	require.NoError(mn.ConnectAllButSelf())
+
+	require.NoError(th.WaitForIt(10, 50*time.Millisecond, func() (bool, error) {
+		return len(a.Network().ConnsToPeer(b.ID())) == 0, nil
+	}))
+
+	msc1.AssertNumberOfCalls(t, "SyncCallback", 0)
+	msc2.AssertNumberOfCalls(t, "SyncCallback", 0)
+}
+
+func TestIncompatibleProtocols(t *testing.T) {
+	t.Parallel()
+
+	ours := []string{"/fil/hello/2.0.0", "/fil/storage/mk/1.0.0"}
+	assert.Empty(t, incompatibleProtocols(ours, []string{"/fil/hello/2.0.0", "/fil/retrieval/1.0.0"}))
+	assert.Equal(t, []string{"/fil/storage/mk/2.0.0"}, incompatibleProtocols(ours, []string{"/fil/hello/2.0.0", "/fil/storage/mk/2.0.0"}))
+
+	// peers speaking several versions of a protocol are compatible if one
+	// of them is ours
+	assert.Empty(t, incompatibleProtocols(ours, []string{"/fil/storage/mk/1.0.0", "/fil/storage/mk/2.0.0"}))
+	assert.Empty(t, incompatibleProtocols(append(ours, "/fil/hello/1.0.0"), []string{"/fil/hello/1.0.0"}))
+}
+
 func TestHelloMultiBlock(t *testing.T) {
 	t.Parallel()
 
@@ -153,8 +204,8 @@ func TestHelloMultiBlock(t *testing.T) {
 	msc1, msc2 := new(mockSyncCallback), new(mockSyncCallback)
 	hg1, hg2 := &mockHeaviestGetter{heavy1}, &mockHeaviestGetter{heavy2}
 
-	New(a, genesisA.Cid(), msc1.SyncCallback, hg1.getHeaviestTipSet)
-	New(b, genesisA.Cid(), msc2.SyncCallback, hg2.getHeaviestTipSet)
+	New(a, genesisA.Cid(), "local", msc1.SyncCallback, hg1.getHeaviestTipSet, nil)
+	New(b, genesisA.Cid(), "local", msc2.SyncCallback, hg2.getHeaviestTipSet, nil)
 
 	msc1.On("SyncCallback", b.ID(), heavy2.ToSortedCidSet().ToSlice(), uint64(3)).Return()
 	msc2.On("SyncCallback", a.ID(), heavy1.ToSortedCidSet().ToSlice(), uint64(2)).Return()
@@ -3,15 +3,32 @@ package retrieval
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"math/big"
+	"sync"
 
+	imp "gx/ipfs/QmQXze9tG878pa4Euya4rrDpyTNX3kQe4dhCaBzBozGgpe/go-unixfs/importer"
+	chunk "gx/ipfs/QmR4QQVkBZsZENRjYFVi8dEtPL3daZRNKk24m4r6WKJHNm/go-ipfs-chunker"
 	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	cbor "gx/ipfs/QmRoARq3nkUb13HSKZGepCZSWe5GrVPwx7xURJGZ7KWv9V/go-ipld-cbor"
+	"gx/ipfs/QmS2aqUZLJp8kF1ihE5rvDGE5LvmKDPnx32w9Z1BW9xLV5/go-ipfs-blockstore"
+	dag "gx/ipfs/QmTQdH4848iTVCJmKXYyRiK72HufWTLYQQ8iN3JaQ8K1Hq/go-merkledag"
 	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
 	"gx/ipfs/QmY5Grm8pJdiSSVsYxx4uNRgweY72EmYwuSDbRnbFok3iY/go-libp2p-peer"
+	bserv "gx/ipfs/QmYPZzd9VqmJDwxUnThfeSbV1Y5o53aVPDijTB7j7rS9Ep/go-blockservice"
+	"gx/ipfs/QmYZwey1thDTynSrvd6qQkX24UpTka6TFhQ2v569UpoqxD/go-ipfs-exchange-offline"
 	host "gx/ipfs/QmaoXrM4Z41PD48JY36YqQGKQpLGjyLA2cKcLsES7YddAq/go-libp2p-host"
+	ds "gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
 
+	"github.com/filecoin-project/go-filecoin/actor/builtin/paymentbroker"
+	"github.com/filecoin-project/go-filecoin/address"
 	cbu "github.com/filecoin-project/go-filecoin/cborutil"
+	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/filnet"
+	"github.com/filecoin-project/go-filecoin/types"
+	vmErrors "github.com/filecoin-project/go-filecoin/vm/errors"
 )
 
 // RetrievePieceChunkSize defines the size of piece-chunks to be sent from miner to client. The maximum size of readable
@@ -19,39 +36,175 @@ import (
 // succeed.
 const RetrievePieceChunkSize = 256 << 8
 
+const (
+	// ChannelExpiryInterval is the number of blocks the payment channel paying
+	// for a retrieval stays open, for the miner to redeem the vouchers.
+	ChannelExpiryInterval = 1000
+
+	// CreateChannelGasPrice is the gas price of the message used to create the payment channel
+	CreateChannelGasPrice = 0
+
+	// CreateChannelGasLimit is the gas limit of the message used to create the payment channel
+	CreateChannelGasLimit = 300
+)
+
 // TODO: better name
 type clientNode interface {
 	Host() host.Host
 }
 
+// clientPorcelain is the subset of the porcelain API that Client needs.
+type clientPorcelain interface {
+	ChainBlockHeight(ctx context.Context) (*types.BlockHeight, error)
+	GetAndMaybeSetDefaultSenderAddress() (address.Address, error)
+
+	MessageSend(ctx context.Context, from, to address.Address, value *types.AttoFIL, gasPrice types.AttoFIL, gasLimit types.GasUnits, method string, params ...interface{}) (cid.Cid, error)
+	MessageQuery(ctx context.Context, optFrom, to address.Address, method string, params ...interface{}) ([][]byte, *exec.FunctionSignature, error)
+	MessageWait(ctx context.Context, msgCid cid.Cid, cb func(*types.Block, *types.SignedMessage, *types.MessageReceipt) error) error
+
+	SignBytes(data []byte, addr address.Address) (types.Signature, error)
+}
+
 // Client is a client interface to the retrieval market protocols.
 type Client struct {
-	node clientNode
+	node         clientNode
+	porcelainAPI clientPorcelain
+
+	// partial holds the bytes received by interrupted retrievals of whole
+	// pieces, keyed by piece cid.
+	partialLk sync.Mutex
+	partial   map[cid.Cid][]byte
 }
 
 // NewClient produces a new Client.
-func NewClient(nd clientNode) *Client {
+func NewClient(nd clientNode, porcelainAPI clientPorcelain) *Client {
 	return &Client{
-		node: nd,
+		node:         nd,
+		porcelainAPI: porcelainAPI,
+		partial:      make(map[cid.Cid][]byte),
 	}
 }
 
-// RetrievePiece connects to a miner and transfers a piece of content.
-func (sc *Client) RetrievePiece(ctx context.Context, minerPeerID peer.ID, pieceCID cid.Cid) (io.ReadCloser, error) {
-	s, err := sc.node.Host().NewStream(ctx, minerPeerID, retrievalFreeProtocol)
+// QueryPiece asks a miner for the terms it serves a piece of content on.
+func (sc *Client) QueryPiece(ctx context.Context, minerPeerID peer.ID, pieceCID cid.Cid) (*QueryResponse, error) {
+	s, err := sc.node.Host().NewStream(ctx, minerPeerID, retrievalQueryProtocol)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create stream to retrieval miner")
 	}
 
 	defer s.Close() // nolint: errcheck
+	defer filnet.ResetOnDone(ctx, s)()
 
-	streamReader := cbu.NewMsgReader(s)
+	if err := cbu.NewMsgWriter(s).WriteMsg(&QueryRequest{PieceRef: pieceCID}); err != nil {
+		return nil, errors.Wrap(err, "failed to write query message to stream")
+	}
+
+	var res QueryResponse
+	if err := cbu.NewMsgReader(s).ReadMsg(&res); err != nil {
+		return nil, errors.Wrap(err, "failed to read query response from stream")
+	}
+
+	if res.Status != Success {
+		return nil, errors.Errorf("could not query piece - error from miner: %s", res.ErrorMessage)
+	}
+
+	return &res, nil
+}
+
+// RetrievePiece connects to a miner and transfers a piece of content. Unless
+// the miner serves the piece for free, the client opens a payment channel to
+// the miner and pays for each PaymentInterval bytes it receives. If the
+// transfer is interrupted, the client keeps the bytes it received, and the
+// next retrieval of the piece, from any miner, resumes from there.
+func (sc *Client) RetrievePiece(ctx context.Context, minerPeerID peer.ID, pieceCID cid.Cid) (io.ReadCloser, error) {
+	sc.partialLk.Lock()
+	buf := sc.partial[pieceCID]
+	delete(sc.partial, pieceCID)
+	sc.partialLk.Unlock()
+
+	data, err := sc.retrieve(ctx, minerPeerID, pieceCID, uint64(len(buf)), 0)
+	buf = append(buf, data...)
+	if err != nil {
+		if len(buf) > 0 {
+			sc.partialLk.Lock()
+			sc.partial[pieceCID] = buf
+			sc.partialLk.Unlock()
+		}
+		return nil, err
+	}
+
+	if err := verifyPiece(pieceCID, buf); err != nil {
+		return nil, err
+	}
+
+	// TODO: Figure out how to stream piece-bytes w/out having to buffer.
+	buffered := ioutil.NopCloser(bytes.NewReader(buf))
+
+	return buffered, nil
+}
+
+// RetrievePieceRange connects to a miner and transfers length bytes of a piece
+// of content starting at offset, or the rest of the piece if length is zero.
+// The client pays for the bytes of the range only. Unlike a whole piece, the
+// data of a range cannot be checked against the piece cid.
+func (sc *Client) RetrievePieceRange(ctx context.Context, minerPeerID peer.ID, pieceCID cid.Cid, offset, length uint64) (io.ReadCloser, error) {
+	buf, err := sc.retrieve(ctx, minerPeerID, pieceCID, offset, length)
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.NopCloser(bytes.NewReader(buf)), nil
+}
+
+// retrieve transfers the given range of a piece from a miner, paying for it
+// as it arrives. On error, it returns the bytes paid for before the error.
+func (sc *Client) retrieve(ctx context.Context, minerPeerID peer.ID, pieceCID cid.Cid, offset, length uint64) ([]byte, error) {
+	terms, err := sc.QueryPiece(ctx, minerPeerID, pieceCID)
+	if err != nil {
+		return nil, err
+	}
+
+	length, err = pieceRange(terms.Size, offset, length)
+	if err != nil {
+		return nil, err
+	}
 
 	req := RetrievePieceRequest{
-		PieceRef: pieceCID,
+		PieceRef:  pieceCID,
+		UnsealFee: terms.UnsealFee,
+		Offset:    offset,
+		Length:    length,
+	}
+
+	// the client pays the unseal fee with the first bytes
+	cost := func(n uint64) *types.AttoFIL {
+		return terms.UnsealFee.Add(terms.PricePerByte.MulBigInt(big.NewInt(0).SetUint64(n)))
+	}
+
+	var pay *retrievalPayer
+	total := cost(length)
+	if total.GreaterThan(types.ZeroAttoFIL) {
+		pay, err = sc.openPaymentChannel(ctx, terms.Payee, total)
+		if err != nil {
+			return nil, err
+		}
+		req.Payer = pay.payer
+		req.Channel = pay.channel
+		req.ChannelMsgCid = &pay.channelMsgCid
+	}
+
+	s, err := sc.node.Host().NewStream(ctx, minerPeerID, retrievalProtocol)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create stream to retrieval miner")
 	}
 
-	if err := cbu.NewMsgWriter(s).WriteMsg(&req); err != nil {
+	defer s.Close() // nolint: errcheck
+	defer filnet.ResetOnDone(ctx, s)()
+
+	streamReader := cbu.NewMsgReader(s)
+	streamWriter := cbu.NewMsgWriter(s)
+
+	if err := streamWriter.WriteMsg(&req); err != nil {
 		return nil, errors.Wrap(err, "failed to write request message to stream")
 	}
 
@@ -64,22 +217,140 @@ func (sc *Client) RetrievePiece(ctx context.Context, minerPeerID peer.ID, pieceC
 		return nil, errors.Errorf("could not retrieve piece - error from miner: %s", res.ErrorMessage)
 	}
 
+	// only bytes paid for are kept, so that resuming never skips payment
 	var buf []byte
-	for {
+	var paidFor uint64
+	for uint64(len(buf)) < length {
 		var chunk RetrievePieceChunk
 		if err := streamReader.ReadMsg(&chunk); err != nil {
 			if err == io.EOF {
-				break
+				return buf[:paidFor], fmt.Errorf("miner stopped sending after %d of %d bytes", len(buf), length)
 			}
 
-			return nil, errors.Errorf("could not read chunk from stream: %s", err.Error())
+			return buf[:paidFor], errors.Errorf("could not read chunk from stream: %s", err.Error())
 		}
 
 		buf = append(buf, chunk.Data...)
+		received := uint64(len(buf))
+		if received > length {
+			return nil, fmt.Errorf("miner sent more than the %d bytes requested", length)
+		}
+
+		if pay == nil {
+			paidFor = received
+			continue
+		}
+		if received-paidFor < terms.PaymentInterval && received != length {
+			continue
+		}
+
+		voucher, err := sc.createVoucher(ctx, pay, cost(received))
+		if err != nil {
+			return buf[:paidFor], errors.Wrap(err, "failed to create voucher")
+		}
+		if err := streamWriter.WriteMsg(&RetrievePiecePayment{Voucher: voucher}); err != nil {
+			return buf[:paidFor], errors.Wrap(err, "failed to write payment to stream")
+		}
+		paidFor = received
 	}
 
-	// TODO: Figure out how to stream piece-bytes w/out having to buffer.
-	buffered := ioutil.NopCloser(bytes.NewReader(buf))
+	return buf, nil
+}
 
-	return buffered, nil
+// retrievalPayer is a payment channel paying for a retrieval.
+type retrievalPayer struct {
+	payer         address.Address
+	channel       *types.ChannelID
+	channelMsgCid cid.Cid
+	validAt       *types.BlockHeight
+}
+
+// openPaymentChannel creates a payment channel holding amount for target,
+// and waits for it to be created.
+func (sc *Client) openPaymentChannel(ctx context.Context, target address.Address, amount *types.AttoFIL) (*retrievalPayer, error) {
+	payer, err := sc.porcelainAPI.GetAndMaybeSetDefaultSenderAddress()
+	if err != nil {
+		return nil, err
+	}
+
+	height, err := sc.porcelainAPI.ChainBlockHeight(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pay := &retrievalPayer{
+		payer:   payer,
+		validAt: height,
+	}
+
+	expiry := height.Add(types.NewBlockHeight(ChannelExpiryInterval))
+	pay.channelMsgCid, err = sc.porcelainAPI.MessageSend(ctx,
+		payer,
+		address.PaymentBrokerAddress,
+		amount,
+		*types.NewAttoFIL(big.NewInt(CreateChannelGasPrice)),
+		types.NewGasUnits(CreateChannelGasLimit),
+		"createChannel",
+		target,
+		expiry)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating payment channel")
+	}
+
+	err = sc.porcelainAPI.MessageWait(ctx, pay.channelMsgCid, func(block *types.Block, message *types.SignedMessage, receipt *types.MessageReceipt) error {
+		if receipt.ExitCode != 0 {
+			return errors.Wrap(vmErrors.VMExitCodeToError(receipt.ExitCode, paymentbroker.Errors), "createChannel failed")
+		}
+
+		pay.channel = types.NewChannelIDFromBytes(receipt.Return[0])
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pay, nil
+}
+
+// createVoucher creates a voucher paying amount from the payment channel.
+func (sc *Client) createVoucher(ctx context.Context, pay *retrievalPayer, amount *types.AttoFIL) (*paymentbroker.PaymentVoucher, error) {
+	ret, _, err := sc.porcelainAPI.MessageQuery(ctx,
+		pay.payer,
+		address.PaymentBrokerAddress,
+		"voucher",
+		pay.channel,
+		amount,
+		pay.validAt)
+	if err != nil {
+		return nil, err
+	}
+
+	var voucher paymentbroker.PaymentVoucher
+	if err := cbor.DecodeInto(ret[0], &voucher); err != nil {
+		return nil, err
+	}
+
+	sig, err := paymentbroker.SignVoucher(pay.channel, amount, pay.validAt, pay.payer, sc.porcelainAPI)
+	if err != nil {
+		return nil, err
+	}
+	voucher.Signature = sig
+
+	return &voucher, nil
+}
+
+// verifyPiece returns an error if data is not the content of the piece with
+// the given cid, as imported with the default chunker.
+func verifyPiece(pieceCID cid.Cid, data []byte) error {
+	bs := blockstore.NewBlockstore(ds.NewMapDatastore())
+	dserv := dag.NewDAGService(bserv.New(bs, offline.Exchange(bs)))
+
+	nd, err := imp.BuildDagFromReader(dserv, chunk.DefaultSplitter(bytes.NewReader(data)))
+	if err != nil {
+		return errors.Wrap(err, "failed to rebuild piece")
+	}
+	if !nd.Cid().Equals(pieceCID) {
+		return fmt.Errorf("miner sent data of %s, not of piece %s", nd.Cid(), pieceCID)
+	}
+	return nil
 }
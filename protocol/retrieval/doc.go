@@ -1,8 +1,11 @@
-// Package retrieval implements a very simple retrieval protocol that works on high level like this:
+// Package retrieval implements a paid retrieval protocol that works on high level like this:
 //
-// 1. CLIENT opens /fil/retrieval/free/0.0.0 stream to MINER
-// 2. CLIENT sends MINER a RetrievePieceRequest
-// 3. MINER sends CLIENT a RetrievePieceResponse with Status set to Success if it has PieceRef in a sealed sector
-// 4. MINER sends CLIENT RetrievePieceChunks until all data associated with PieceRef has been sent
-// 5. CLIENT reads RetrievePieceChunk from stream until EOF and then closes stream
+// 1. CLIENT opens /fil/retrieval/query/1.0.0 stream to MINER and sends a QueryRequest
+// 2. MINER sends CLIENT a QueryResponse with the size of the piece, its price per byte, and the fee and time to unseal it
+// 3. CLIENT creates a payment channel to the miner owner covering the fee and the bytes it wants, unless both are free
+// 4. CLIENT opens /fil/retrieval/1.0.0 stream to MINER and sends a RetrievePieceRequest naming the channel and the range of the piece to send
+// 5. MINER unseals PieceRef, retrievals paying more first, and sends CLIENT a RetrievePieceResponse with Status Success
+// 6. MINER sends CLIENT PaymentInterval bytes of RetrievePieceChunks, then waits for a RetrievePiecePayment
+// 7. CLIENT sends a voucher paying the fee and all bytes received so far, and so on until all data has been sent
+// 8. CLIENT checks the data it read matches PieceRef if it read the whole piece, MINER redeems the last voucher it received
 package retrieval
@@ -1,82 +1,221 @@
 package retrieval
 
 import (
-	"io/ioutil"
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
 
 	inet "gx/ipfs/QmNgLg1NTw37iWbYPKcyK85YJ9Whs1MkPtJwhfqbNYAyKg/go-libp2p-net"
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	cbor "gx/ipfs/QmRoARq3nkUb13HSKZGepCZSWe5GrVPwx7xURJGZ7KWv9V/go-ipld-cbor"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
 	"gx/ipfs/QmZNkThpqfVXs9GNbexPrfBbXSLNYeKrE7jwFM2oqHbyqN/go-libp2p-protocol"
 	host "gx/ipfs/QmaoXrM4Z41PD48JY36YqQGKQpLGjyLA2cKcLsES7YddAq/go-libp2p-host"
 	logging "gx/ipfs/QmcuXC5cxs79ro2cUuHs4HQ2bkDLJUYokwL8aivcX6HW3C/go-log"
+	"gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
 
+	"github.com/filecoin-project/go-filecoin/actor/builtin/paymentbroker"
+	"github.com/filecoin-project/go-filecoin/address"
 	cbu "github.com/filecoin-project/go-filecoin/cborutil"
+	"github.com/filecoin-project/go-filecoin/exec"
 	"github.com/filecoin-project/go-filecoin/proofs/sectorbuilder"
+	"github.com/filecoin-project/go-filecoin/repo"
+	"github.com/filecoin-project/go-filecoin/types"
 )
 
 var log = logging.Logger("/fil/retrieval")
 
-const retrievalFreeProtocol = protocol.ID("/fil/retrieval/free/0.0.0")
+const (
+	retrievalQueryProtocol = protocol.ID("/fil/retrieval/query/1.0.0")
+	retrievalProtocol      = protocol.ID("/fil/retrieval/1.0.0")
+)
+
+// PaymentInterval is the number of bytes a miner sends before it waits for
+// the client to pay for them.
+const PaymentInterval = 16 * RetrievePieceChunkSize
+
+// voucherHeightTolerance is how many blocks ahead of the miner's chain head
+// the vouchers of a client may be valid at, for clients with a fresher head.
+const voucherHeightTolerance = 1
+
+// waitForPaymentChannelDuration is how long the miner waits for the message
+// creating the payment channel of a retrieval to be mined.
+const waitForPaymentChannelDuration = 2 * time.Minute
+
+// TODO: replace this with a queries to pick reasonable gas price and limits.
+const redeemGasPrice = 0
+const redeemGasLimit = 300
 
 // TODO: better name
 type minerNode interface {
 	Host() host.Host
 	SectorBuilder() sectorbuilder.SectorBuilder
+	// SealedPiece returns the sector a piece was sealed in and the size of
+	// the piece.
+	SealedPiece(pieceRef cid.Cid) (sectorID uint64, size uint64, err error)
+}
+
+// minerPorcelain is the subset of the porcelain API that Miner needs.
+type minerPorcelain interface {
+	ChainBlockHeight(ctx context.Context) (*types.BlockHeight, error)
+	ConfigGet(dottedPath string) (interface{}, error)
+	MinerGetOwnerAddress(ctx context.Context, minerAddr address.Address) (address.Address, error)
+
+	MessageSend(ctx context.Context, from, to address.Address, value *types.AttoFIL, gasPrice types.AttoFIL, gasLimit types.GasUnits, method string, params ...interface{}) (cid.Cid, error)
+	MessageQuery(ctx context.Context, optFrom, to address.Address, method string, params ...interface{}) ([][]byte, *exec.FunctionSignature, error)
+	MessageWait(ctx context.Context, msgCid cid.Cid, cb func(*types.Block, *types.SignedMessage, *types.MessageReceipt) error) error
 }
 
 // Miner serves requests for pieces from RetrievalClients.
 type Miner struct {
-	node minerNode
+	node         minerNode
+	porcelainAPI minerPorcelain
+	unsealer     *unsealer
+	payments     *channelPayments
 }
 
-// NewMiner is used to create a Miner and bind a handling function to the piece retrieval protocol.
-func NewMiner(nd minerNode) *Miner {
+// NewMiner is used to create a Miner and bind handling functions to the piece retrieval protocols.
+// The payments accepted for retrievals are recorded in ds.
+func NewMiner(nd minerNode, porcelainAPI minerPorcelain, ds repo.Datastore) (*Miner, error) {
 	rm := &Miner{
-		node: nd,
+		node:         nd,
+		porcelainAPI: porcelainAPI,
+		payments:     newChannelPayments(ds),
+	}
+
+	val, err := porcelainAPI.ConfigGet("mining.unsealCacheSize")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get unseal cache size")
 	}
+	cacheSize, ok := val.(uint64)
+	if !ok {
+		return nil, errors.New("could not retrieve unsealCacheSize from config")
+	}
+	rm.unsealer = newUnsealer(nd.SectorBuilder, cacheSize)
+	go rm.unsealer.run(context.Background())
 
-	nd.Host().SetStreamHandler(retrievalFreeProtocol, rm.handleRetrievePieceForFree)
+	nd.Host().SetStreamHandler(retrievalQueryProtocol, rm.handleQuery)
+	nd.Host().SetStreamHandler(retrievalProtocol, rm.handleRetrievePiece)
 
-	return rm
+	return rm, nil
 }
 
-func (rm *Miner) handleRetrievePieceForFree(s inet.Stream) {
+func (rm *Miner) handleQuery(s inet.Stream) {
 	defer s.Close() // nolint: errcheck
 
-	var req RetrievePieceRequest
+	var req QueryRequest
 	if err := cbu.NewMsgReader(s).ReadMsg(&req); err != nil {
+		log.Errorf("failed to read retrieval query: %s", err)
+		return
+	}
+
+	resp, err := rm.query(context.Background(), req.PieceRef)
+	if err != nil {
+		resp = &QueryResponse{Status: Failure, ErrorMessage: err.Error()}
+	}
+
+	if err := cbu.NewMsgWriter(s).WriteMsg(resp); err != nil {
+		log.Warningf("failed to write query response for piece with CID %s: %s", req.PieceRef.String(), err)
+	}
+}
+
+// query returns the terms the miner serves the piece with the given cid on.
+func (rm *Miner) query(ctx context.Context, pieceRef cid.Cid) (*QueryResponse, error) {
+	sectorID, size, err := rm.node.SealedPiece(pieceRef)
+	if err != nil {
+		return nil, err
+	}
+
+	price, err := rm.getRetrievalPrice()
+	if err != nil {
+		return nil, err
+	}
+
+	fee, err := rm.unsealFee(pieceRef)
+	if err != nil {
+		return nil, err
+	}
+
+	terms := &retrievalTerms{pricePerByte: price, unsealFee: fee, length: size}
+	total := terms.cost(size)
+	resp := &QueryResponse{
+		Status:          Success,
+		Size:            size,
+		SectorID:        sectorID,
+		PricePerByte:    price,
+		UnsealFee:       fee,
+		Unseal:          rm.unsealer.estimate(pieceRef, total),
+		PaymentInterval: PaymentInterval,
+	}
+	if total.GreaterThan(types.ZeroAttoFIL) {
+		resp.Payee, err = rm.getOwnerAddress(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+func (rm *Miner) handleRetrievePiece(s inet.Stream) {
+	defer s.Close() // nolint: errcheck
+
+	ctx := context.Background()
+	reader := cbu.NewMsgReader(s)
+	writer := cbu.NewMsgWriter(s)
+
+	var req RetrievePieceRequest
+	if err := reader.ReadMsg(&req); err != nil {
 		log.Errorf("failed to read piece retrieval request: %s", err)
 		return
 	}
+	if req.Channel != nil {
+		defer rm.payments.lock(req.Payer, req.Channel)()
+	}
 
-	reader, err := rm.node.SectorBuilder().ReadPieceFromSealedSector(req.PieceRef)
+	var bs []byte
+	terms, err := rm.acceptRetrieval(ctx, &req)
+	if err == nil {
+		// retrievals paying more are unsealed first
+		bs, err = rm.unsealer.unseal(ctx, req.PieceRef, terms.cost(terms.length))
+	}
+	if err == nil && uint64(len(bs)) < req.Offset+terms.length {
+		err = fmt.Errorf("unsealed piece has %d bytes, not %d", len(bs), req.Offset+terms.length)
+	}
 	if err != nil {
-		log.Warningf("failed to obtain a reader for piece with CID %s: %s", req.PieceRef.String(), err)
+		log.Warningf("refusing retrieval of piece with CID %s: %s", req.PieceRef.String(), err)
 
 		resp := RetrievePieceResponse{
 			Status:       Failure,
 			ErrorMessage: err.Error(),
 		}
 
-		if err := cbu.NewMsgWriter(s).WriteMsg(&resp); err != nil {
+		if err := writer.WriteMsg(&resp); err != nil {
 			log.Warningf("failed to write response for piece with CID %s: %s", req.PieceRef.String(), err)
 		}
 
 		return
 	}
 
-	bs, err := ioutil.ReadAll(reader)
-	if err != nil {
-		log.Errorf("failed to read all bytes: %s", err)
-	}
-
 	resp := RetrievePieceResponse{
 		Status: Success,
 	}
 
-	if err := cbu.NewMsgWriter(s).WriteMsg(&resp); err != nil {
+	if err := writer.WriteMsg(&resp); err != nil {
 		log.Warningf("failed to write response for piece with CID %s: %s", req.PieceRef.String(), err)
 		return
 	}
 
+	bs = bs[req.Offset : req.Offset+terms.length]
+	free := terms.cost(terms.length).Equal(types.ZeroAttoFIL)
+	var last *paymentbroker.PaymentVoucher
+	defer func() {
+		if last != nil {
+			go rm.redeemVoucher(last)
+		}
+	}()
+
 	for i := 0; i < len(bs); i += RetrievePieceChunkSize {
 		end := i + RetrievePieceChunkSize
 
@@ -88,9 +227,325 @@ func (rm *Miner) handleRetrievePieceForFree(s inet.Stream) {
 			Data: bs[i:end],
 		}
 
-		if err := cbu.NewMsgWriter(s).WriteMsg(&chunk); err != nil {
+		if err := writer.WriteMsg(&chunk); err != nil {
 			log.Warningf("failed to write chunk for CID %s: %s", req.PieceRef.String(), err)
 			return
 		}
+
+		if free || (end%PaymentInterval != 0 && end != len(bs)) {
+			continue
+		}
+
+		var payment RetrievePiecePayment
+		if err := reader.ReadMsg(&payment); err != nil {
+			log.Warningf("failed to read payment for CID %s: %s", req.PieceRef.String(), err)
+			return
+		}
+		owed := terms.paid.Add(terms.cost(uint64(end)))
+		if err := rm.checkPayment(ctx, &req, payment.Voucher, owed); err != nil {
+			log.Warningf("invalid payment for CID %s: %s", req.PieceRef.String(), err)
+			return
+		}
+		if err := rm.payments.accept(req.Payer, req.Channel, &payment.Voucher.Amount); err != nil {
+			log.Errorf("failed to record payment for CID %s: %s", req.PieceRef.String(), err)
+			return
+		}
+		last = payment.Voucher
+	}
+}
+
+// retrievalTerms are the terms a piece is retrieved on.
+type retrievalTerms struct {
+	pricePerByte *types.AttoFIL
+	unsealFee    *types.AttoFIL
+	// length is the number of bytes of the piece retrieved.
+	length uint64
+	// paid is the amount the payment channel paid for earlier retrievals,
+	// the vouchers of this one pay on top of it.
+	paid *types.AttoFIL
+}
+
+// cost returns the amount owed for the first n bytes retrieved.
+func (t *retrievalTerms) cost(n uint64) *types.AttoFIL {
+	return t.unsealFee.Add(t.pricePerByte.MulBigInt(big.NewInt(0).SetUint64(n)))
+}
+
+// acceptRetrieval returns the terms the requested range of a piece is
+// retrieved on, if the miner has the piece and the payment channel of the
+// request pays for all of the range, on top of what it paid for earlier
+// retrievals. The payment channel must be locked.
+func (rm *Miner) acceptRetrieval(ctx context.Context, req *RetrievePieceRequest) (*retrievalTerms, error) {
+	_, size, err := rm.node.SealedPiece(req.PieceRef)
+	if err != nil {
+		return nil, err
+	}
+
+	length, err := pieceRange(size, req.Offset, req.Length)
+	if err != nil {
+		return nil, err
+	}
+
+	price, err := rm.getRetrievalPrice()
+	if err != nil {
+		return nil, err
+	}
+
+	fee, err := rm.unsealFee(req.PieceRef)
+	if err != nil {
+		return nil, err
+	}
+	if fee.GreaterThan(types.ZeroAttoFIL) {
+		// the fee the client agreed to in its query holds, even if the
+		// miner has unsealed the piece since
+		if req.UnsealFee == nil || req.UnsealFee.LessThan(fee) {
+			return nil, fmt.Errorf("retrieval requires an unseal fee of %s", fee)
+		}
+	}
+	if req.UnsealFee != nil {
+		fee = req.UnsealFee
+	}
+
+	terms := &retrievalTerms{pricePerByte: price, unsealFee: fee, length: length, paid: types.ZeroAttoFIL}
+	total := terms.cost(length)
+	if total.Equal(types.ZeroAttoFIL) {
+		return terms, nil
+	}
+
+	if req.Channel == nil || req.ChannelMsgCid == nil {
+		return nil, errors.New("retrieval requires a payment channel")
+	}
+
+	channel, err := rm.getPaymentChannel(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	owner, err := rm.getOwnerAddress(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if channel.Target != owner {
+		return nil, fmt.Errorf("miner account (%s) is not target of payment channel (%s)", owner.String(), channel.Target.String())
+	}
+
+	terms.paid, err = rm.payments.paid(req.Payer, req.Channel)
+	if err != nil {
+		return nil, err
+	}
+	if channel.AmountRedeemed.GreaterThan(terms.paid) {
+		terms.paid = channel.AmountRedeemed
+	}
+	if channel.Amount.Sub(terms.paid).LessThan(total) {
+		return nil, fmt.Errorf("payment channel does not contain enough funds (%s < %s)", channel.Amount.Sub(terms.paid).String(), total.String())
+	}
+
+	height, err := rm.porcelainAPI.ChainBlockHeight(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !channel.Eol.GreaterThan(height) {
+		return nil, errors.New("payment channel has expired")
+	}
+
+	return terms, nil
+}
+
+// checkPayment returns an error if v is not a valid voucher of the payment
+// channel of the request paying at least owed.
+func (rm *Miner) checkPayment(ctx context.Context, req *RetrievePieceRequest, v *paymentbroker.PaymentVoucher, owed *types.AttoFIL) error {
+	height, err := rm.porcelainAPI.ChainBlockHeight(ctx)
+	if err != nil {
+		return err
+	}
+	return checkVoucher(req, v, owed, height)
+}
+
+// checkVoucher returns an error if v is not a valid voucher of the payment
+// channel of the request paying at least owed, redeemable at height.
+func checkVoucher(req *RetrievePieceRequest, v *paymentbroker.PaymentVoucher, owed *types.AttoFIL, height *types.BlockHeight) error {
+	if v == nil {
+		return errors.New("payment has no voucher")
+	}
+	if !v.Channel.Equal(req.Channel) {
+		return fmt.Errorf("voucher is for channel %s, not %s", &v.Channel, req.Channel)
+	}
+	if !paymentbroker.VerifyVoucherSignature(req.Payer, req.Channel, &v.Amount, &v.ValidAt, v.Signature) {
+		return errors.New("invalid signature in voucher")
+	}
+	if v.Amount.LessThan(owed) {
+		return fmt.Errorf("voucher amount (%s) less than amount owed (%s)", &v.Amount, owed)
+	}
+	if v.ValidAt.GreaterThan(height.Add(types.NewBlockHeight(voucherHeightTolerance))) {
+		return fmt.Errorf("voucher valid at %s, after current block height %s", &v.ValidAt, height)
+	}
+	return nil
+}
+
+// redeemVoucher redeems the last voucher paying for a retrieval.
+func (rm *Miner) redeemVoucher(v *paymentbroker.PaymentVoucher) {
+	ctx := context.Background()
+
+	owner, err := rm.getOwnerAddress(ctx)
+	if err != nil {
+		log.Errorf("failed to redeem retrieval voucher: %s", err)
+		return
+	}
+
+	// TODO: algorithmically determine appropriate values for these
+	gasPrice := types.NewGasPrice(redeemGasPrice)
+	gasLimit := types.NewGasUnits(redeemGasLimit)
+
+	msgCid, err := rm.porcelainAPI.MessageSend(ctx, owner, address.PaymentBrokerAddress, types.ZeroAttoFIL, gasPrice, gasLimit, "redeem", v.Payer, &v.Channel, &v.Amount, &v.ValidAt, []byte(v.Signature))
+	if err != nil {
+		log.Errorf("failed to redeem retrieval voucher: %s", err)
+		return
+	}
+
+	err = rm.porcelainAPI.MessageWait(ctx, msgCid, func(blk *types.Block, smsg *types.SignedMessage, receipt *types.MessageReceipt) error {
+		if receipt.ExitCode != uint8(0) {
+			return fmt.Errorf("redeem failed with exit code %d", receipt.ExitCode)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Errorf("failed to redeem retrieval voucher: %s", err)
+		return
+	}
+	log.Infof("redeemed %s for retrieval from channel %s", &v.Amount, &v.Channel)
+}
+
+// unsealFee returns the fee the miner charges for unsealing the piece, zero
+// if it holds an unsealed copy.
+func (rm *Miner) unsealFee(pieceRef cid.Cid) (*types.AttoFIL, error) {
+	if rm.unsealer.estimate(pieceRef, types.ZeroAttoFIL).Cached {
+		return types.ZeroAttoFIL, nil
+	}
+	val, err := rm.porcelainAPI.ConfigGet("mining.unsealPrice")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get unseal price")
+	}
+	fee, ok := val.(*types.AttoFIL)
+	if !ok {
+		return nil, errors.New("could not retrieve unsealPrice from config")
+	}
+	return fee, nil
+}
+
+func (rm *Miner) getRetrievalPrice() (*types.AttoFIL, error) {
+	val, err := rm.porcelainAPI.ConfigGet("mining.retrievalPrice")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get retrieval price")
+	}
+	price, ok := val.(*types.AttoFIL)
+	if !ok {
+		return nil, errors.New("could not retrieve retrievalPrice from config")
+	}
+	return price, nil
+}
+
+func (rm *Miner) getOwnerAddress(ctx context.Context) (address.Address, error) {
+	val, err := rm.porcelainAPI.ConfigGet("mining.minerAddress")
+	if err != nil {
+		return address.Address{}, errors.Wrap(err, "failed to get miner address")
+	}
+	minerAddr, ok := val.(address.Address)
+	if !ok || minerAddr.Empty() {
+		return address.Address{}, errors.New("no miner address configured")
+	}
+	return rm.porcelainAPI.MinerGetOwnerAddress(ctx, minerAddr)
+}
+
+func (rm *Miner) getPaymentChannel(ctx context.Context, req *RetrievePieceRequest) (*paymentbroker.PaymentChannel, error) {
+	waitCtx, waitCancel := context.WithTimeout(ctx, waitForPaymentChannelDuration)
+	err := rm.porcelainAPI.MessageWait(waitCtx, *req.ChannelMsgCid, func(blk *types.Block, smsg *types.SignedMessage, receipt *types.MessageReceipt) error {
+		return nil
+	})
+	waitCancel()
+	if err != nil {
+		if err == context.DeadlineExceeded {
+			return nil, errors.Wrap(err, "Timeout waiting for payment channel")
+		}
+		return nil, err
+	}
+
+	ret, _, err := rm.porcelainAPI.MessageQuery(ctx, address.Address{}, address.PaymentBrokerAddress, "ls", req.Payer)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error getting payment channel for payer")
+	}
+
+	var channels map[string]*paymentbroker.PaymentChannel
+	if err := cbor.DecodeInto(ret[0], &channels); err != nil {
+		return nil, errors.Wrap(err, "Could not decode payment channels for payer")
+	}
+	channel, ok := channels[req.Channel.KeyString()]
+	if !ok {
+		return nil, fmt.Errorf("could not find payment channel for payer %s and id %s", req.Payer.String(), req.Channel.KeyString())
+	}
+	return channel, nil
+}
+
+const retrievalPaymentsDatastorePrefix = "retrieval-payments"
+
+// channelPayments records the highest voucher amount the miner accepted from
+// each payment channel, whether or not it was redeemed. Vouchers are for the
+// total a channel paid, so a voucher of an earlier retrieval must not pay for
+// a later one on the same channel. Retrievals paid by the same channel are
+// served one at a time.
+type channelPayments struct {
+	ds repo.Datastore
+
+	lk       sync.Mutex
+	channels map[datastore.Key]*sync.Mutex
+}
+
+func newChannelPayments(ds repo.Datastore) *channelPayments {
+	return &channelPayments{
+		ds:       ds,
+		channels: make(map[datastore.Key]*sync.Mutex),
+	}
+}
+
+// lock locks the payment channel and returns the function unlocking it.
+func (p *channelPayments) lock(payer address.Address, channel *types.ChannelID) func() {
+	key := channelPaymentsKey(payer, channel)
+
+	p.lk.Lock()
+	lk, ok := p.channels[key]
+	if !ok {
+		lk = &sync.Mutex{}
+		p.channels[key] = lk
+	}
+	p.lk.Unlock()
+
+	lk.Lock()
+	return lk.Unlock
+}
+
+// paid returns the highest voucher amount accepted from the payment channel.
+func (p *channelPayments) paid(payer address.Address, channel *types.ChannelID) (*types.AttoFIL, error) {
+	val, err := p.ds.Get(channelPaymentsKey(payer, channel))
+	if err == datastore.ErrNotFound {
+		return types.ZeroAttoFIL, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read payments of channel")
 	}
+	return types.NewAttoFILFromBytes(val), nil
+}
+
+// accept records that a voucher of amount was accepted from the payment
+// channel.
+func (p *channelPayments) accept(payer address.Address, channel *types.ChannelID, amount *types.AttoFIL) error {
+	paid, err := p.paid(payer, channel)
+	if err != nil {
+		return err
+	}
+	if !amount.GreaterThan(paid) {
+		return nil
+	}
+	return errors.Wrap(p.ds.Put(channelPaymentsKey(payer, channel), amount.Bytes()), "failed to record payment of channel")
+}
+
+func channelPaymentsKey(payer address.Address, channel *types.ChannelID) datastore.Key {
+	return datastore.KeyWithNamespaces([]string{retrievalPaymentsDatastorePrefix, payer.String(), channel.KeyString()})
 }
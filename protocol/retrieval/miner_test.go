@@ -0,0 +1,194 @@
+package retrieval
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/big"
+	"testing"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	cbor "gx/ipfs/QmRoARq3nkUb13HSKZGepCZSWe5GrVPwx7xURJGZ7KWv9V/go-ipld-cbor"
+	"gx/ipfs/QmYxivS34F2M2n44WQQnRHGAKS8aoRUxwGpi9wk4Cdn4Jf/go-libp2p/p2p/net/mock"
+	host "gx/ipfs/QmaoXrM4Z41PD48JY36YqQGKQpLGjyLA2cKcLsES7YddAq/go-libp2p-host"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/actor/builtin/paymentbroker"
+	"github.com/filecoin-project/go-filecoin/address"
+	cbu "github.com/filecoin-project/go-filecoin/cborutil"
+	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/proofs/sectorbuilder"
+	"github.com/filecoin-project/go-filecoin/repo"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// pieceSize is the size of the piece served by testMinerNode, two payment
+// intervals.
+const pieceSize = 2 * PaymentInterval
+
+type testMinerNode struct {
+	host host.Host
+}
+
+func (n *testMinerNode) Host() host.Host {
+	return n.host
+}
+
+func (n *testMinerNode) SectorBuilder() sectorbuilder.SectorBuilder {
+	return &pieceSectorBuilder{}
+}
+
+func (n *testMinerNode) SealedPiece(pieceRef cid.Cid) (uint64, uint64, error) {
+	return 1, pieceSize, nil
+}
+
+type pieceSectorBuilder struct {
+	sectorbuilder.SectorBuilder
+}
+
+func (sb *pieceSectorBuilder) ReadPieceFromSealedSector(pieceCid cid.Cid) (io.Reader, error) {
+	return bytes.NewReader(make([]byte, pieceSize)), nil
+}
+
+// testMinerPorcelain charges an attoFIL per byte retrieved, and knows of a
+// single payment channel.
+type testMinerPorcelain struct {
+	minerAddr address.Address
+	owner     address.Address
+	channel   *types.ChannelID
+	funds     *types.AttoFIL
+}
+
+func (api *testMinerPorcelain) ChainBlockHeight(ctx context.Context) (*types.BlockHeight, error) {
+	return types.NewBlockHeight(100), nil
+}
+
+func (api *testMinerPorcelain) ConfigGet(dottedPath string) (interface{}, error) {
+	switch dottedPath {
+	case "mining.unsealCacheSize":
+		return uint64(2 * pieceSize), nil
+	case "mining.retrievalPrice":
+		return types.NewAttoFIL(big.NewInt(1)), nil
+	case "mining.unsealPrice":
+		return types.ZeroAttoFIL, nil
+	case "mining.minerAddress":
+		return api.minerAddr, nil
+	}
+	return nil, nil
+}
+
+func (api *testMinerPorcelain) MinerGetOwnerAddress(ctx context.Context, minerAddr address.Address) (address.Address, error) {
+	return api.owner, nil
+}
+
+func (api *testMinerPorcelain) MessageSend(ctx context.Context, from, to address.Address, value *types.AttoFIL, gasPrice types.AttoFIL, gasLimit types.GasUnits, method string, params ...interface{}) (cid.Cid, error) {
+	return types.SomeCid(), nil
+}
+
+func (api *testMinerPorcelain) MessageQuery(ctx context.Context, optFrom, to address.Address, method string, params ...interface{}) ([][]byte, *exec.FunctionSignature, error) {
+	channels := map[string]*paymentbroker.PaymentChannel{
+		api.channel.KeyString(): {
+			Target:         api.owner,
+			Amount:         api.funds,
+			AmountRedeemed: types.ZeroAttoFIL,
+			Eol:            types.NewBlockHeight(1000),
+		},
+	}
+	ret, err := cbor.DumpObject(channels)
+	if err != nil {
+		return nil, nil, err
+	}
+	return [][]byte{ret}, nil, nil
+}
+
+func (api *testMinerPorcelain) MessageWait(ctx context.Context, msgCid cid.Cid, cb func(*types.Block, *types.SignedMessage, *types.MessageReceipt) error) error {
+	return nil
+}
+
+func TestMinerRejectsReplayedVouchers(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	mn, err := mocknet.WithNPeers(ctx, 2)
+	require.NoError(err)
+	require.NoError(mn.LinkAll())
+	require.NoError(mn.ConnectAllButSelf())
+	minerHost, clientHost := mn.Hosts()[0], mn.Hosts()[1]
+
+	ki := types.MustGenerateKeyInfo(1, types.GenerateKeyInfoSeed())
+	signer := types.NewMockSigner(ki)
+	payer, err := ki[0].Address()
+	require.NoError(err)
+
+	addrs := address.NewForTestGetter()
+	api := &testMinerPorcelain{
+		minerAddr: addrs(),
+		owner:     addrs(),
+		channel:   types.NewChannelID(7),
+		// enough for three retrievals of the piece
+		funds: types.NewAttoFIL(big.NewInt(3 * pieceSize)),
+	}
+	_, err = NewMiner(&testMinerNode{host: minerHost}, api, repo.NewInMemoryRepo().DealsDatastore())
+	require.NoError(err)
+
+	voucher := func(amount uint64) *paymentbroker.PaymentVoucher {
+		v := &paymentbroker.PaymentVoucher{
+			Channel: *api.channel,
+			Payer:   payer,
+			Amount:  *types.NewAttoFIL(big.NewInt(0).SetUint64(amount)),
+			ValidAt: *types.NewBlockHeight(100),
+		}
+		v.Signature, err = paymentbroker.SignVoucher(api.channel, &v.Amount, &v.ValidAt, payer, signer)
+		require.NoError(err)
+		return v
+	}
+
+	// retrieve retrieves the piece, paying with a voucher for each payment
+	// interval, and returns the number of bytes received.
+	retrieve := func(vouchers ...*paymentbroker.PaymentVoucher) uint64 {
+		s, err := clientHost.NewStream(ctx, minerHost.ID(), retrievalProtocol)
+		require.NoError(err)
+		defer s.Close() // nolint: errcheck
+
+		reader := cbu.NewMsgReader(s)
+		writer := cbu.NewMsgWriter(s)
+
+		channelMsgCid := types.SomeCid()
+		require.NoError(writer.WriteMsg(&RetrievePieceRequest{
+			PieceRef:      types.SomeCid(),
+			Payer:         payer,
+			Channel:       api.channel,
+			ChannelMsgCid: &channelMsgCid,
+		}))
+
+		var resp RetrievePieceResponse
+		require.NoError(reader.ReadMsg(&resp))
+		require.Equal(Success, resp.Status, resp.ErrorMessage)
+
+		var received uint64
+		for received < pieceSize {
+			var chunk RetrievePieceChunk
+			if err := reader.ReadMsg(&chunk); err != nil {
+				return received
+			}
+			received += uint64(len(chunk.Data))
+			if received%PaymentInterval == 0 {
+				require.NoError(writer.WriteMsg(&RetrievePiecePayment{Voucher: vouchers[0]}))
+				vouchers = vouchers[1:]
+			}
+		}
+		return received
+	}
+
+	assert.Equal(uint64(pieceSize), retrieve(voucher(PaymentInterval), voucher(2*PaymentInterval)))
+
+	// the vouchers of the first retrieval don't pay for another one
+	assert.Equal(uint64(PaymentInterval), retrieve(voucher(PaymentInterval), voucher(2*PaymentInterval)))
+
+	// vouchers paying on top of the earlier retrievals do
+	assert.Equal(uint64(pieceSize), retrieve(voucher(3*PaymentInterval), voucher(4*PaymentInterval)))
+}
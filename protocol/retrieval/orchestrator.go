@@ -0,0 +1,186 @@
+package retrieval
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	"gx/ipfs/QmY5Grm8pJdiSSVsYxx4uNRgweY72EmYwuSDbRnbFok3iY/go-libp2p-peer"
+
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// MaxStripes is the largest number of providers a striped retrieval reads
+// from in parallel.
+const MaxStripes = 4
+
+// Provider is a miner a piece can be retrieved from, with the terms it
+// serves the piece on.
+type Provider struct {
+	PeerID peer.ID
+	Terms  *QueryResponse
+}
+
+// cost returns the price of retrieving the whole piece from the provider.
+func (p *Provider) cost() *types.AttoFIL {
+	return p.Terms.UnsealFee.Add(p.Terms.PricePerByte.MulBigInt(big.NewInt(0).SetUint64(p.Terms.Size)))
+}
+
+// RankProviders queries the providers for the terms they serve a piece on,
+// and returns the providers that answered, cheapest first. Of equally priced
+// providers, the ones holding an unsealed copy of the piece, then the ones
+// expecting to unseal it sooner, come first.
+func (sc *Client) RankProviders(ctx context.Context, providers []peer.ID, pieceCID cid.Cid) ([]Provider, error) {
+	var lk sync.Mutex
+	var ranked []Provider
+	var errs []string
+
+	var wg sync.WaitGroup
+	for _, p := range providers {
+		wg.Add(1)
+		go func(p peer.ID) {
+			defer wg.Done()
+			terms, err := sc.QueryPiece(ctx, p, pieceCID)
+
+			lk.Lock()
+			defer lk.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %s", p.Pretty(), err))
+				return
+			}
+			ranked = append(ranked, Provider{PeerID: p, Terms: terms})
+		}(p)
+	}
+	wg.Wait()
+
+	if len(ranked) == 0 {
+		return nil, fmt.Errorf("no provider serves piece %s: %s", pieceCID, strings.Join(errs, "; "))
+	}
+
+	rankProviders(ranked)
+	return ranked, nil
+}
+
+// rankProviders sorts providers cheapest first, then by unseal time.
+func rankProviders(providers []Provider) {
+	sort.SliceStable(providers, func(i, j int) bool {
+		ci, cj := providers[i].cost(), providers[j].cost()
+		if !ci.Equal(cj) {
+			return ci.LessThan(cj)
+		}
+		ui, uj := providers[i].Terms.Unseal, providers[j].Terms.Unseal
+		if ui.Cached != uj.Cached {
+			return ui.Cached
+		}
+		return ui.Seconds < uj.Seconds
+	})
+}
+
+// RetrievePieceFromProviders retrieves a piece from the best ranked of the
+// providers. If a provider fails, the client falls back to the next one,
+// resuming the retrieval from the bytes received so far.
+func (sc *Client) RetrievePieceFromProviders(ctx context.Context, providers []peer.ID, pieceCID cid.Cid) (io.ReadCloser, error) {
+	ranked, err := sc.RankProviders(ctx, providers, pieceCID)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []string
+	for _, p := range ranked {
+		r, err := sc.RetrievePiece(ctx, p.PeerID, pieceCID)
+		if err == nil {
+			return r, nil
+		}
+		log.Warningf("failed to retrieve piece %s from %s, falling back: %s", pieceCID, p.PeerID.Pretty(), err)
+		errs = append(errs, fmt.Sprintf("%s: %s", p.PeerID.Pretty(), err))
+	}
+
+	return nil, fmt.Errorf("failed to retrieve piece %s from any provider: %s", pieceCID, strings.Join(errs, "; "))
+}
+
+// RetrievePieceStriped splits a piece into ranges, one for each of the best
+// ranked providers up to MaxStripes, and retrieves the ranges in parallel. A
+// range a provider fails to serve in full is resumed with the next provider.
+// Each range is paid for separately, so every provider that has to unseal the
+// piece charges its unseal fee.
+func (sc *Client) RetrievePieceStriped(ctx context.Context, providers []peer.ID, pieceCID cid.Cid) (io.ReadCloser, error) {
+	ranked, err := sc.RankProviders(ctx, providers, pieceCID)
+	if err != nil {
+		return nil, err
+	}
+
+	size := ranked[0].Terms.Size
+	if size == 0 {
+		return nil, fmt.Errorf("piece %s is empty", pieceCID)
+	}
+	stripes := uint64(len(ranked))
+	if stripes > MaxStripes {
+		stripes = MaxStripes
+	}
+	if stripes > size {
+		stripes = size
+	}
+	stripeSize := (size + stripes - 1) / stripes
+
+	bufs := make([][]byte, stripes)
+	errs := make([]error, stripes)
+	var wg sync.WaitGroup
+	for i := uint64(0); i < stripes; i++ {
+		offset := i * stripeSize
+		length := stripeSize
+		if offset+length > size {
+			length = size - offset
+		}
+
+		wg.Add(1)
+		go func(i int, offset, length uint64) {
+			defer wg.Done()
+			bufs[i], errs[i] = sc.retrieveStripe(ctx, ranked, i, pieceCID, offset, length)
+		}(int(i), offset, length)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	buf := bytes.Join(bufs, nil)
+	if err := verifyPiece(pieceCID, buf); err != nil {
+		return nil, err
+	}
+
+	return ioutil.NopCloser(bytes.NewReader(buf)), nil
+}
+
+// retrieveStripe retrieves a range of a piece from the provider at index
+// first of ranked, falling back to the providers after it, and finally to the
+// ones before it, until the whole range is received.
+func (sc *Client) retrieveStripe(ctx context.Context, ranked []Provider, first int, pieceCID cid.Cid, offset, length uint64) ([]byte, error) {
+	var buf []byte
+	var err error
+	for i := 0; i < len(ranked) && uint64(len(buf)) < length; i++ {
+		p := ranked[(first+i)%len(ranked)]
+
+		var data []byte
+		received := uint64(len(buf))
+		data, err = sc.retrieve(ctx, p.PeerID, pieceCID, offset+received, length-received)
+		buf = append(buf, data...)
+		if err != nil {
+			log.Warningf("failed to retrieve bytes %d to %d of piece %s from %s, falling back: %s", offset, offset+length, pieceCID, p.PeerID.Pretty(), err)
+		}
+	}
+	if uint64(len(buf)) < length {
+		return nil, errors.Wrapf(err, "failed to retrieve bytes %d to %d of piece %s", offset, offset+length, pieceCID)
+	}
+	return buf, nil
+}
@@ -0,0 +1,42 @@
+package retrieval
+
+import (
+	"testing"
+
+	"gx/ipfs/QmY5Grm8pJdiSSVsYxx4uNRgweY72EmYwuSDbRnbFok3iY/go-libp2p-peer"
+
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRankProviders(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	provider := func(id string, price, fee uint64, unseal UnsealEstimate) Provider {
+		return Provider{
+			PeerID: peer.ID(id),
+			Terms: &QueryResponse{
+				Size:         100,
+				PricePerByte: types.NewAttoFILFromFIL(price),
+				UnsealFee:    types.NewAttoFILFromFIL(fee),
+				Unseal:       unseal,
+			},
+		}
+	}
+
+	providers := []Provider{
+		provider("expensive", 2, 0, UnsealEstimate{Cached: true}),
+		provider("slow", 1, 0, UnsealEstimate{Seconds: 120}),
+		provider("fast", 1, 0, UnsealEstimate{Seconds: 60}),
+		provider("cached", 1, 0, UnsealEstimate{Cached: true}),
+		provider("fee", 1, 1, UnsealEstimate{Seconds: 1}),
+	}
+	rankProviders(providers)
+
+	var order []string
+	for _, p := range providers {
+		order = append(order, string(p.PeerID))
+	}
+	assert.Equal([]string{"cached", "fast", "slow", "fee", "expensive"}, order)
+}
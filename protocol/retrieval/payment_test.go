@@ -0,0 +1,109 @@
+package retrieval
+
+import (
+	"bytes"
+	"testing"
+
+	imp "gx/ipfs/QmQXze9tG878pa4Euya4rrDpyTNX3kQe4dhCaBzBozGgpe/go-unixfs/importer"
+	chunk "gx/ipfs/QmR4QQVkBZsZENRjYFVi8dEtPL3daZRNKk24m4r6WKJHNm/go-ipfs-chunker"
+	"gx/ipfs/QmS2aqUZLJp8kF1ihE5rvDGE5LvmKDPnx32w9Z1BW9xLV5/go-ipfs-blockstore"
+	dag "gx/ipfs/QmTQdH4848iTVCJmKXYyRiK72HufWTLYQQ8iN3JaQ8K1Hq/go-merkledag"
+	bserv "gx/ipfs/QmYPZzd9VqmJDwxUnThfeSbV1Y5o53aVPDijTB7j7rS9Ep/go-blockservice"
+	"gx/ipfs/QmYZwey1thDTynSrvd6qQkX24UpTka6TFhQ2v569UpoqxD/go-ipfs-exchange-offline"
+	ds "gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
+
+	"github.com/filecoin-project/go-filecoin/actor/builtin/paymentbroker"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckVoucher(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+	assert := assert.New(t)
+
+	ki := types.MustGenerateKeyInfo(1, types.GenerateKeyInfoSeed())
+	signer := types.NewMockSigner(ki)
+	payer, err := ki[0].Address()
+	require.NoError(err)
+
+	req := &RetrievePieceRequest{
+		PieceRef: types.SomeCid(),
+		Payer:    payer,
+		Channel:  types.NewChannelID(7),
+	}
+	height := types.NewBlockHeight(100)
+
+	voucher := func(channel *types.ChannelID, amount uint64, validAt uint64) *paymentbroker.PaymentVoucher {
+		v := &paymentbroker.PaymentVoucher{
+			Channel: *channel,
+			Payer:   payer,
+			Amount:  *types.NewAttoFILFromFIL(amount),
+			ValidAt: *types.NewBlockHeight(validAt),
+		}
+		v.Signature, err = paymentbroker.SignVoucher(channel, &v.Amount, &v.ValidAt, payer, signer)
+		require.NoError(err)
+		return v
+	}
+	owed := types.NewAttoFILFromFIL(10)
+
+	assert.NoError(checkVoucher(req, voucher(req.Channel, 10, 100), owed, height))
+	assert.NoError(checkVoucher(req, voucher(req.Channel, 12, 101), owed, height))
+
+	err = checkVoucher(req, voucher(req.Channel, 9, 100), owed, height)
+	assert.Contains(err.Error(), "less than amount owed")
+
+	err = checkVoucher(req, voucher(types.NewChannelID(8), 10, 100), owed, height)
+	assert.Contains(err.Error(), "voucher is for channel")
+
+	err = checkVoucher(req, voucher(req.Channel, 10, 102), owed, height)
+	assert.Contains(err.Error(), "after current block height")
+
+	forged := voucher(req.Channel, 10, 100)
+	forged.Amount = *types.NewAttoFILFromFIL(20)
+	err = checkVoucher(req, forged, owed, height)
+	assert.Contains(err.Error(), "invalid signature")
+
+	err = checkVoucher(req, nil, owed, height)
+	assert.Contains(err.Error(), "no voucher")
+}
+
+func TestVerifyPiece(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+	assert := assert.New(t)
+
+	data := bytes.Repeat([]byte("filecoin"), 100000)
+	bs := blockstore.NewBlockstore(ds.NewMapDatastore())
+	nd, err := imp.BuildDagFromReader(dag.NewDAGService(bserv.New(bs, offline.Exchange(bs))), chunk.DefaultSplitter(bytes.NewReader(data)))
+	require.NoError(err)
+
+	assert.NoError(verifyPiece(nd.Cid(), data))
+
+	err = verifyPiece(nd.Cid(), data[1:])
+	assert.Contains(err.Error(), "not of piece")
+}
+
+func TestPieceRange(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	length, err := pieceRange(100, 0, 0)
+	assert.NoError(err)
+	assert.Equal(uint64(100), length)
+
+	length, err = pieceRange(100, 40, 0)
+	assert.NoError(err)
+	assert.Equal(uint64(60), length)
+
+	length, err = pieceRange(100, 40, 60)
+	assert.NoError(err)
+	assert.Equal(uint64(60), length)
+
+	_, err = pieceRange(100, 40, 61)
+	assert.Error(err)
+
+	_, err = pieceRange(100, 100, 0)
+	assert.Error(err)
+}
@@ -1,14 +1,24 @@
 package retrieval
 
 import (
+	"fmt"
+
 	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
 	cbor "gx/ipfs/QmRoARq3nkUb13HSKZGepCZSWe5GrVPwx7xURJGZ7KWv9V/go-ipld-cbor"
+
+	"github.com/filecoin-project/go-filecoin/actor/builtin/paymentbroker"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/types"
 )
 
 func init() {
+	cbor.RegisterCborType(QueryRequest{})
+	cbor.RegisterCborType(QueryResponse{})
 	cbor.RegisterCborType(RetrievePieceRequest{})
 	cbor.RegisterCborType(RetrievePieceResponse{})
 	cbor.RegisterCborType(RetrievePieceChunk{})
+	cbor.RegisterCborType(RetrievePiecePayment{})
+	cbor.RegisterCborType(UnsealEstimate{})
 }
 
 // RetrievePieceStatus communicates a successful (or failed) piece retrieval
@@ -25,9 +35,54 @@ const (
 	Success
 )
 
+// QueryRequest asks a retrieval miner for the terms it serves a piece on.
+type QueryRequest struct {
+	PieceRef cid.Cid
+}
+
+// QueryResponse holds the terms a retrieval miner serves a piece on.
+type QueryResponse struct {
+	Status       RetrievePieceStatus `json:"status"`
+	ErrorMessage string              `json:"errorMessage"`
+
+	// Size is the number of bytes of the piece.
+	Size uint64 `json:"size"`
+	// SectorID is the sector the piece is sealed in.
+	SectorID uint64 `json:"sectorId"`
+	// PricePerByte is the price of each byte retrieved, zero if the miner
+	// serves the piece for free.
+	PricePerByte *types.AttoFIL `json:"pricePerByte"`
+	// UnsealFee is the fee the miner charges on top of the bytes for
+	// unsealing the piece, zero if it holds an unsealed copy.
+	UnsealFee *types.AttoFIL `json:"unsealFee"`
+	// Unseal is how long the miner expects unsealing the piece takes.
+	Unseal UnsealEstimate `json:"unseal"`
+	// PaymentInterval is the number of bytes the miner sends before it waits
+	// for the client to pay for them.
+	PaymentInterval uint64 `json:"paymentInterval"`
+	// Payee is the target of the payment channel paying for the retrieval,
+	// the owner of the miner.
+	Payee address.Address `json:"payee"`
+}
+
 // RetrievePieceRequest represents a retrieval miner's request for content.
 type RetrievePieceRequest struct {
 	PieceRef cid.Cid
+
+	// Payer, Channel and ChannelMsgCid identify the payment channel paying
+	// for the retrieval, unless the piece is free.
+	Payer         address.Address
+	Channel       *types.ChannelID
+	ChannelMsgCid *cid.Cid
+
+	// UnsealFee is the unseal fee the client agreed to pay, as quoted in the
+	// QueryResponse.
+	UnsealFee *types.AttoFIL
+
+	// Offset and Length select the bytes of the piece to retrieve. A Length
+	// of zero selects the rest of the piece.
+	Offset uint64
+	Length uint64
 }
 
 // RetrievePieceResponse contains the requested content.
@@ -40,3 +95,24 @@ type RetrievePieceResponse struct {
 type RetrievePieceChunk struct {
 	Data []byte
 }
+
+// RetrievePiecePayment pays for the bytes of a piece received so far. The
+// voucher is for the total amount owed, not for the last interval alone.
+type RetrievePiecePayment struct {
+	Voucher *paymentbroker.PaymentVoucher
+}
+
+// pieceRange returns the number of bytes selected by offset and length from a
+// piece of the given size, or an error if they select bytes past its end.
+func pieceRange(size, offset, length uint64) (uint64, error) {
+	if offset >= size {
+		return 0, fmt.Errorf("offset %d is past the end of the piece of %d bytes", offset, size)
+	}
+	if length == 0 {
+		return size - offset, nil
+	}
+	if length > size-offset {
+		return 0, fmt.Errorf("range of %d bytes at offset %d is past the end of the piece of %d bytes", length, offset, size)
+	}
+	return length, nil
+}
@@ -0,0 +1,260 @@
+package retrieval
+
+import (
+	"context"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+
+	"github.com/filecoin-project/go-filecoin/proofs/sectorbuilder"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// defaultUnsealTime is the time an unseal is expected to take until the
+// miner has unsealed a piece.
+const defaultUnsealTime = time.Minute
+
+// UnsealEstimate is what it would take the miner to unseal a piece for a
+// retrieval.
+type UnsealEstimate struct {
+	// Cached is true if the miner holds an unsealed copy of the piece.
+	Cached bool `json:"cached"`
+	// QueuePosition is the number of unseals the miner would run before
+	// this one.
+	QueuePosition int `json:"queuePosition"`
+	// Seconds is the expected time until the piece is unsealed.
+	Seconds uint64 `json:"seconds"`
+}
+
+// unsealJob is a piece queued for unsealing, and the retrievals waiting for
+// it.
+type unsealJob struct {
+	pieceRef cid.Cid
+	// priority is the highest amount paid by the retrievals waiting for the
+	// piece. Jobs are unsealed highest priority first, then in the order
+	// they were queued.
+	priority *types.AttoFIL
+	seq      uint64
+
+	done chan struct{}
+	data []byte
+	err  error
+}
+
+// unsealedPiece is a cached unsealed copy of a piece.
+type unsealedPiece struct {
+	data     []byte
+	lastUsed uint64
+}
+
+// unsealer unseals pieces one at a time, keeping the unsealed copies of the
+// recently retrieved pieces up to a number of bytes.
+type unsealer struct {
+	sectorBuilder func() sectorbuilder.SectorBuilder
+
+	lk       sync.Mutex
+	queue    []*unsealJob
+	jobs     map[cid.Cid]*unsealJob
+	active   *unsealJob
+	seq      uint64
+	wake     chan struct{}
+	avgTime  time.Duration
+	cache    map[cid.Cid]*unsealedPiece
+	cached   uint64
+	maxCache uint64
+}
+
+func newUnsealer(sectorBuilder func() sectorbuilder.SectorBuilder, maxCache uint64) *unsealer {
+	return &unsealer{
+		sectorBuilder: sectorBuilder,
+		jobs:          make(map[cid.Cid]*unsealJob),
+		wake:          make(chan struct{}, 1),
+		avgTime:       defaultUnsealTime,
+		cache:         make(map[cid.Cid]*unsealedPiece),
+		maxCache:      maxCache,
+	}
+}
+
+// unseal returns the bytes of the piece, from the cache or once the piece is
+// unsealed. If the piece is queued already, its priority is raised to
+// priority if that is higher.
+func (u *unsealer) unseal(ctx context.Context, pieceRef cid.Cid, priority *types.AttoFIL) ([]byte, error) {
+	u.lk.Lock()
+	if data, ok := u.getCached(pieceRef); ok {
+		u.lk.Unlock()
+		return data, nil
+	}
+
+	job, ok := u.jobs[pieceRef]
+	if !ok {
+		u.seq++
+		job = &unsealJob{
+			pieceRef: pieceRef,
+			priority: priority,
+			seq:      u.seq,
+			done:     make(chan struct{}),
+		}
+		u.jobs[pieceRef] = job
+		u.queue = append(u.queue, job)
+	} else if priority.GreaterThan(job.priority) {
+		job.priority = priority
+	}
+	u.lk.Unlock()
+
+	select {
+	case u.wake <- struct{}{}:
+	default:
+	}
+
+	select {
+	case <-job.done:
+		return job.data, job.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// estimate returns what it would take to unseal the piece for a retrieval
+// paying priority.
+func (u *unsealer) estimate(pieceRef cid.Cid, priority *types.AttoFIL) UnsealEstimate {
+	u.lk.Lock()
+	defer u.lk.Unlock()
+
+	if _, ok := u.cache[pieceRef]; ok {
+		return UnsealEstimate{Cached: true}
+	}
+
+	ahead := 0
+	if u.active != nil {
+		ahead++
+	}
+	job := u.jobs[pieceRef]
+	for _, j := range u.queue {
+		if j == job {
+			continue
+		}
+		if job != nil && j.before(job) || job == nil && !priority.GreaterThan(j.priority) {
+			ahead++
+		}
+	}
+
+	return UnsealEstimate{
+		QueuePosition: ahead,
+		Seconds:       uint64((time.Duration(ahead+1) * u.avgTime).Seconds()),
+	}
+}
+
+// before returns true if j is to be unsealed before other.
+func (j *unsealJob) before(other *unsealJob) bool {
+	if !j.priority.Equal(other.priority) {
+		return j.priority.GreaterThan(other.priority)
+	}
+	return j.seq < other.seq
+}
+
+// run unseals the queued pieces until ctx is done.
+func (u *unsealer) run(ctx context.Context) {
+	for {
+		job := u.next()
+		if job == nil {
+			select {
+			case <-u.wake:
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		start := time.Now()
+		job.data, job.err = u.readPiece(job.pieceRef)
+		elapsed := time.Since(start)
+
+		u.lk.Lock()
+		if job.err == nil {
+			// the estimate follows recent unseals, an eighth at a time
+			u.avgTime += (elapsed - u.avgTime) / 8
+			u.putCached(job.pieceRef, job.data)
+		}
+		delete(u.jobs, job.pieceRef)
+		u.active = nil
+		u.lk.Unlock()
+		close(job.done)
+	}
+}
+
+// next removes the job to run next from the queue, and marks it active.
+func (u *unsealer) next() *unsealJob {
+	u.lk.Lock()
+	defer u.lk.Unlock()
+
+	if len(u.queue) == 0 {
+		return nil
+	}
+	best := 0
+	for i, j := range u.queue {
+		if j.before(u.queue[best]) {
+			best = i
+		}
+	}
+	job := u.queue[best]
+	u.queue = append(u.queue[:best], u.queue[best+1:]...)
+	u.active = job
+	return job
+}
+
+func (u *unsealer) readPiece(pieceRef cid.Cid) ([]byte, error) {
+	sb := u.sectorBuilder()
+	if sb == nil {
+		return nil, errors.New("mining disabled, can not unseal pieces")
+	}
+
+	reader, err := sb.ReadPieceFromSealedSector(pieceRef)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to obtain a reader for piece with CID %s", pieceRef.String())
+	}
+
+	bs, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read piece with CID %s", pieceRef.String())
+	}
+	return bs, nil
+}
+
+// getCached returns the cached copy of the piece, if any. u.lk must be held.
+func (u *unsealer) getCached(pieceRef cid.Cid) ([]byte, bool) {
+	p, ok := u.cache[pieceRef]
+	if !ok {
+		return nil, false
+	}
+	u.seq++
+	p.lastUsed = u.seq
+	return p.data, true
+}
+
+// putCached caches the unsealed copy of a piece, evicting the least recently
+// used copies to stay within maxCache bytes. u.lk must be held.
+func (u *unsealer) putCached(pieceRef cid.Cid, data []byte) {
+	size := uint64(len(data))
+	if size > u.maxCache {
+		return
+	}
+
+	for u.cached+size > u.maxCache {
+		var oldest cid.Cid
+		var oldestUse uint64
+		for c, p := range u.cache {
+			if !oldest.Defined() || p.lastUsed < oldestUse {
+				oldest, oldestUse = c, p.lastUsed
+			}
+		}
+		u.cached -= uint64(len(u.cache[oldest].data))
+		delete(u.cache, oldest)
+	}
+
+	u.seq++
+	u.cache[pieceRef] = &unsealedPiece{data: data, lastUsed: u.seq}
+	u.cached += size
+}
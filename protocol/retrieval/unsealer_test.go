@@ -0,0 +1,149 @@
+package retrieval
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+
+	"github.com/filecoin-project/go-filecoin/proofs/sectorbuilder"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSectorBuilder serves pieces of 10 bytes, once released to.
+type testSectorBuilder struct {
+	sectorbuilder.SectorBuilder
+
+	lk    sync.Mutex
+	reads []cid.Cid
+	gate  chan struct{}
+}
+
+func (sb *testSectorBuilder) ReadPieceFromSealedSector(pieceCid cid.Cid) (io.Reader, error) {
+	<-sb.gate
+	sb.lk.Lock()
+	defer sb.lk.Unlock()
+	sb.reads = append(sb.reads, pieceCid)
+	return bytes.NewReader(bytes.Repeat([]byte{1}, 10)), nil
+}
+
+func (sb *testSectorBuilder) readOrder() []cid.Cid {
+	sb.lk.Lock()
+	defer sb.lk.Unlock()
+	return append([]cid.Cid{}, sb.reads...)
+}
+
+func TestUnsealer(t *testing.T) {
+	t.Parallel()
+
+	newTestUnsealer := func(maxCache uint64) (*unsealer, *testSectorBuilder, context.CancelFunc) {
+		sb := &testSectorBuilder{gate: make(chan struct{})}
+		u := newUnsealer(func() sectorbuilder.SectorBuilder { return sb }, maxCache)
+		ctx, cancel := context.WithCancel(context.Background())
+		go u.run(ctx)
+		return u, sb, cancel
+	}
+
+	t.Run("caches unsealed pieces", func(t *testing.T) {
+		require := require.New(t)
+		assert := assert.New(t)
+
+		u, sb, cancel := newTestUnsealer(100)
+		defer cancel()
+		close(sb.gate)
+
+		piece := types.NewCidForTestGetter()()
+		assert.False(u.estimate(piece, types.ZeroAttoFIL).Cached)
+
+		data, err := u.unseal(context.Background(), piece, types.ZeroAttoFIL)
+		require.NoError(err)
+		assert.Equal(10, len(data))
+
+		_, err = u.unseal(context.Background(), piece, types.ZeroAttoFIL)
+		require.NoError(err)
+		assert.Equal(1, len(sb.readOrder()))
+		assert.True(u.estimate(piece, types.ZeroAttoFIL).Cached)
+	})
+
+	t.Run("evicts least recently used pieces", func(t *testing.T) {
+		require := require.New(t)
+		assert := assert.New(t)
+
+		u, sb, cancel := newTestUnsealer(20)
+		defer cancel()
+		close(sb.gate)
+
+		ctx := context.Background()
+		cidGetter := types.NewCidForTestGetter()
+		a, b, c := cidGetter(), cidGetter(), cidGetter()
+		for _, piece := range []cid.Cid{a, b, a, c} {
+			_, err := u.unseal(ctx, piece, types.ZeroAttoFIL)
+			require.NoError(err)
+		}
+
+		assert.True(u.estimate(a, types.ZeroAttoFIL).Cached)
+		assert.False(u.estimate(b, types.ZeroAttoFIL).Cached)
+		assert.True(u.estimate(c, types.ZeroAttoFIL).Cached)
+	})
+
+	t.Run("unseals retrievals paying more first", func(t *testing.T) {
+		require := require.New(t)
+		assert := assert.New(t)
+
+		u, sb, cancel := newTestUnsealer(100)
+		defer cancel()
+
+		ctx := context.Background()
+		cidGetter := types.NewCidForTestGetter()
+		first, low, high := cidGetter(), cidGetter(), cidGetter()
+		var wg sync.WaitGroup
+		unseal := func(piece cid.Cid, priority uint64) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := u.unseal(ctx, piece, types.NewAttoFILFromFIL(priority))
+				assert.NoError(err)
+			}()
+		}
+		queued := func(n int) {
+			require.True(waitFor(func() bool {
+				u.lk.Lock()
+				defer u.lk.Unlock()
+				return len(u.jobs) == n
+			}))
+		}
+
+		unseal(first, 1)
+		queued(1)
+		require.True(waitFor(func() bool { return u.estimate(low, types.ZeroAttoFIL).QueuePosition == 1 }))
+		unseal(low, 1)
+		queued(2)
+		unseal(high, 5)
+		queued(3)
+
+		// the high priority retrieval skips the queue, not the unseal in progress
+		assert.Equal(1, u.estimate(high, types.NewAttoFILFromFIL(5)).QueuePosition)
+		assert.Equal(2, u.estimate(low, types.NewAttoFILFromFIL(1)).QueuePosition)
+		assert.Equal(3, u.estimate(cidGetter(), types.NewAttoFILFromFIL(1)).QueuePosition)
+
+		close(sb.gate)
+		wg.Wait()
+		assert.Equal([]cid.Cid{first, high, low}, sb.readOrder())
+	})
+}
+
+func waitFor(cond func() bool) bool {
+	for i := 0; i < 100; i++ {
+		if cond() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
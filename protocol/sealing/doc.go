@@ -0,0 +1,12 @@
+// Package sealing delegates the sealing of sectors to remote workers:
+//
+// 1. WORKER opens /fil/sealing/register/1.0.0 stream to MASTER and sends a RegisterRequest with its capacity
+// 2. MASTER accepts workers it is configured to trust and answers with a RegisterResponse
+// 3. MASTER periodically opens /fil/sealing/health/1.0.0 streams to WORKER, dropping it after repeated failures
+// 4. MASTER opens /fil/sealing/seal/1.0.0 stream to the WORKER with the most spare capacity and sends a SealRequest
+// 5. WORKER fetches the piece from the network, seals it into a sector of its own and sends a SealResponse
+// 6. MASTER commits the sealed sector to the chain
+//
+// Workers seal for the miner of the master, with sector ids of a range of their
+// own, see SectorIDBase. The sealed sectors stay with the worker.
+package sealing
@@ -0,0 +1,301 @@
+package sealing
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	inet "gx/ipfs/QmNgLg1NTw37iWbYPKcyK85YJ9Whs1MkPtJwhfqbNYAyKg/go-libp2p-net"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	"gx/ipfs/QmY5Grm8pJdiSSVsYxx4uNRgweY72EmYwuSDbRnbFok3iY/go-libp2p-peer"
+	host "gx/ipfs/QmaoXrM4Z41PD48JY36YqQGKQpLGjyLA2cKcLsES7YddAq/go-libp2p-host"
+	logging "gx/ipfs/QmcuXC5cxs79ro2cUuHs4HQ2bkDLJUYokwL8aivcX6HW3C/go-log"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	cbu "github.com/filecoin-project/go-filecoin/cborutil"
+	"github.com/filecoin-project/go-filecoin/filnet"
+	"github.com/filecoin-project/go-filecoin/proofs/sectorbuilder"
+)
+
+var log = logging.Logger("/fil/sealing")
+
+const (
+	// HealthCheckInterval is how often a master checks that its workers are
+	// alive.
+	HealthCheckInterval = 30 * time.Second
+
+	// MaxHealthCheckFailures is the number of health checks in a row a worker
+	// fails before its master drops it.
+	MaxHealthCheckFailures = 3
+
+	healthCheckTimeout = 10 * time.Second
+)
+
+// ErrNoWorker is returned by Master.Seal if no worker has spare capacity.
+var ErrNoWorker = errors.New("no sealing worker with spare capacity")
+
+// WorkerInfo describes a worker registered with a master.
+type WorkerInfo struct {
+	PeerID peer.ID `json:"peerId"`
+	// Capacity is the number of sectors the worker seals at once, Active the
+	// number of sectors the master has it sealing.
+	Capacity uint64 `json:"capacity"`
+	Active   uint64 `json:"active"`
+	// LastSeen is the time the worker last registered or passed a health
+	// check, Failures the number of health checks it failed since.
+	LastSeen time.Time `json:"lastSeen"`
+	Failures int       `json:"failures"`
+}
+
+// Master hands the sealing of pieces to the remote workers registered with
+// it.
+type Master struct {
+	host  host.Host
+	miner func() (address.Address, error)
+	// allowed holds the workers the master accepts.
+	allowed map[peer.ID]bool
+
+	lk      sync.Mutex
+	workers map[peer.ID]*WorkerInfo
+
+	sectorSealResults chan sectorbuilder.SectorSealResult
+}
+
+// NewMaster returns a Master accepting the given workers, which seal sectors
+// for the miner returned by miner.
+func NewMaster(h host.Host, miner func() (address.Address, error), allowed []peer.ID) *Master {
+	m := &Master{
+		host:              h,
+		miner:             miner,
+		allowed:           make(map[peer.ID]bool),
+		workers:           make(map[peer.ID]*WorkerInfo),
+		sectorSealResults: make(chan sectorbuilder.SectorSealResult),
+	}
+	for _, p := range allowed {
+		m.allowed[p] = true
+	}
+
+	h.SetStreamHandler(registerProtocol, m.handleRegister)
+	return m
+}
+
+func (m *Master) handleRegister(s inet.Stream) {
+	defer s.Close() // nolint: errcheck
+
+	var req RegisterRequest
+	if err := cbu.NewMsgReader(s).ReadMsg(&req); err != nil {
+		log.Errorf("failed to read worker registration: %s", err)
+		return
+	}
+
+	worker := s.Conn().RemotePeer()
+	resp, err := m.register(worker, req.Capacity)
+	if err != nil {
+		log.Warningf("refused sealing worker %s: %s", worker.Pretty(), err)
+		resp = &RegisterResponse{ErrorMessage: err.Error()}
+	}
+
+	if err := cbu.NewMsgWriter(s).WriteMsg(resp); err != nil {
+		log.Warningf("failed to write registration response to worker %s: %s", worker.Pretty(), err)
+	}
+}
+
+func (m *Master) register(worker peer.ID, capacity uint64) (*RegisterResponse, error) {
+	if !m.allowed[worker] {
+		return nil, errors.New("worker is not configured in mining.sealing.workers")
+	}
+	if capacity == 0 {
+		return nil, errors.New("worker has no capacity")
+	}
+
+	miner, err := m.miner()
+	if err != nil {
+		return nil, err
+	}
+
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	w, ok := m.workers[worker]
+	if !ok {
+		w = &WorkerInfo{PeerID: worker}
+		m.workers[worker] = w
+		log.Infof("sealing worker %s registered with capacity %d", worker.Pretty(), capacity)
+	}
+	w.Capacity = capacity
+	w.LastSeen = time.Now()
+	w.Failures = 0
+
+	return &RegisterResponse{Accepted: true, Miner: miner}, nil
+}
+
+// Workers returns the registered workers.
+func (m *Master) Workers() []WorkerInfo {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	var workers []WorkerInfo
+	for _, w := range m.workers {
+		workers = append(workers, *w)
+	}
+	sort.Slice(workers, func(i, j int) bool {
+		return workers[i].PeerID < workers[j].PeerID
+	})
+	return workers
+}
+
+// SectorSealResults returns the channel the sectors sealed by workers are
+// sent to, like SectorBuilder.SectorSealResults.
+func (m *Master) SectorSealResults() <-chan sectorbuilder.SectorSealResult {
+	return m.sectorSealResults
+}
+
+// Seal has the worker with the most spare capacity seal the piece into a
+// sector, and waits for it. The sealed sector is also sent to
+// SectorSealResults. If no worker has spare capacity, Seal returns
+// ErrNoWorker.
+func (m *Master) Seal(ctx context.Context, piece *sectorbuilder.PieceInfo) (*sectorbuilder.SealedSectorMetadata, error) {
+	worker, ok := m.reserveWorker()
+	if !ok {
+		return nil, ErrNoWorker
+	}
+	defer m.releaseWorker(worker)
+
+	sector, err := m.seal(ctx, worker, piece)
+	if err != nil {
+		return nil, errors.Wrapf(err, "worker %s failed to seal piece %s", worker.Pretty(), piece.Ref)
+	}
+
+	go func() {
+		m.sectorSealResults <- sectorbuilder.SectorSealResult{
+			SectorID:      sector.SectorID,
+			SealingResult: sector,
+		}
+	}()
+	return sector, nil
+}
+
+func (m *Master) seal(ctx context.Context, worker peer.ID, piece *sectorbuilder.PieceInfo) (*sectorbuilder.SealedSectorMetadata, error) {
+	s, err := m.host.NewStream(ctx, worker, sealProtocol)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create stream to worker")
+	}
+	defer s.Close() // nolint: errcheck
+	defer filnet.ResetOnDone(ctx, s)()
+
+	if err := cbu.NewMsgWriter(s).WriteMsg(&SealRequest{Piece: piece}); err != nil {
+		return nil, errors.Wrap(err, "failed to write seal request")
+	}
+
+	var resp SealResponse
+	if err := cbu.NewMsgReader(s).ReadMsg(&resp); err != nil {
+		return nil, errors.Wrap(err, "failed to read seal response")
+	}
+	if resp.ErrorMessage != "" {
+		return nil, errors.New(resp.ErrorMessage)
+	}
+	return resp.sector()
+}
+
+// reserveWorker picks the worker with the most spare capacity and counts a
+// seal against it.
+func (m *Master) reserveWorker() (peer.ID, bool) {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	var best *WorkerInfo
+	for _, w := range m.workers {
+		if w.Active >= w.Capacity {
+			continue
+		}
+		if best == nil || w.Capacity-w.Active > best.Capacity-best.Active ||
+			(w.Capacity-w.Active == best.Capacity-best.Active && w.PeerID < best.PeerID) {
+			best = w
+		}
+	}
+	if best == nil {
+		return "", false
+	}
+	best.Active++
+	return best.PeerID, true
+}
+
+func (m *Master) releaseWorker(worker peer.ID) {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	if w, ok := m.workers[worker]; ok && w.Active > 0 {
+		w.Active--
+	}
+}
+
+// Run checks the health of the workers every HealthCheckInterval until ctx
+// is done.
+func (m *Master) Run(ctx context.Context) {
+	ticker := time.NewTicker(HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.checkWorkers(ctx)
+		}
+	}
+}
+
+// checkWorkers checks the health of all workers, dropping the ones that
+// failed MaxHealthCheckFailures checks in a row.
+func (m *Master) checkWorkers(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, w := range m.Workers() {
+		wg.Add(1)
+		go func(worker peer.ID) {
+			defer wg.Done()
+			err := m.checkHealth(ctx, worker)
+			m.recordHealth(worker, err)
+		}(w.PeerID)
+	}
+	wg.Wait()
+}
+
+func (m *Master) checkHealth(ctx context.Context, worker peer.ID) error {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	s, err := m.host.NewStream(ctx, worker, healthProtocol)
+	if err != nil {
+		return err
+	}
+	defer s.Close() // nolint: errcheck
+	defer filnet.ResetOnDone(ctx, s)()
+
+	if err := cbu.NewMsgWriter(s).WriteMsg(&HealthRequest{}); err != nil {
+		return err
+	}
+	var resp HealthResponse
+	return cbu.NewMsgReader(s).ReadMsg(&resp)
+}
+
+// recordHealth records the outcome of a health check of the worker.
+func (m *Master) recordHealth(worker peer.ID, err error) {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	w, ok := m.workers[worker]
+	if !ok {
+		return
+	}
+	if err == nil {
+		w.LastSeen = time.Now()
+		w.Failures = 0
+		return
+	}
+
+	w.Failures++
+	log.Warningf("sealing worker %s failed health check %d of %d: %s", worker.Pretty(), w.Failures, MaxHealthCheckFailures, err)
+	if w.Failures >= MaxHealthCheckFailures {
+		log.Warningf("dropping sealing worker %s", worker.Pretty())
+		delete(m.workers, worker)
+	}
+}
@@ -0,0 +1,117 @@
+package sealing
+
+import (
+	"encoding/binary"
+
+	cbor "gx/ipfs/QmRoARq3nkUb13HSKZGepCZSWe5GrVPwx7xURJGZ7KWv9V/go-ipld-cbor"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	"gx/ipfs/QmY5Grm8pJdiSSVsYxx4uNRgweY72EmYwuSDbRnbFok3iY/go-libp2p-peer"
+	"gx/ipfs/QmZNkThpqfVXs9GNbexPrfBbXSLNYeKrE7jwFM2oqHbyqN/go-libp2p-protocol"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/proofs/sectorbuilder"
+)
+
+const (
+	registerProtocol = protocol.ID("/fil/sealing/register/1.0.0")
+	healthProtocol   = protocol.ID("/fil/sealing/health/1.0.0")
+	sealProtocol     = protocol.ID("/fil/sealing/seal/1.0.0")
+)
+
+func init() {
+	cbor.RegisterCborType(RegisterRequest{})
+	cbor.RegisterCborType(RegisterResponse{})
+	cbor.RegisterCborType(HealthRequest{})
+	cbor.RegisterCborType(HealthResponse{})
+	cbor.RegisterCborType(SealRequest{})
+	cbor.RegisterCborType(SealResponse{})
+}
+
+// RegisterRequest offers the sealing capacity of a worker to a master.
+type RegisterRequest struct {
+	// Capacity is the number of sectors the worker seals at once.
+	Capacity uint64
+}
+
+// RegisterResponse tells a worker whether a master accepted it.
+type RegisterResponse struct {
+	Accepted     bool
+	ErrorMessage string
+	// Miner is the miner the worker seals sectors for.
+	Miner address.Address
+}
+
+// HealthRequest checks that a worker is alive.
+type HealthRequest struct{}
+
+// HealthResponse reports the load of a worker.
+type HealthResponse struct {
+	Capacity uint64
+	Active   uint64
+}
+
+// SealRequest asks a worker to seal a piece into a sector.
+type SealRequest struct {
+	Piece *sectorbuilder.PieceInfo
+}
+
+// SealResponse holds the sector a worker sealed a piece into, unless
+// ErrorMessage is set.
+type SealResponse struct {
+	ErrorMessage string
+
+	SectorID  uint64
+	CommD     []byte
+	CommR     []byte
+	CommRStar []byte
+	Proof     []byte
+	Pieces    []*sectorbuilder.PieceInfo
+}
+
+func newSealResponse(sector *sectorbuilder.SealedSectorMetadata) *SealResponse {
+	return &SealResponse{
+		SectorID:  sector.SectorID,
+		CommD:     sector.CommD[:],
+		CommR:     sector.CommR[:],
+		CommRStar: sector.CommRStar[:],
+		Proof:     sector.Proof[:],
+		Pieces:    sector.Pieces,
+	}
+}
+
+// sector returns the sealed sector of the response.
+func (r *SealResponse) sector() (*sectorbuilder.SealedSectorMetadata, error) {
+	sector := &sectorbuilder.SealedSectorMetadata{
+		SectorID: r.SectorID,
+		Pieces:   r.Pieces,
+	}
+	if len(r.CommD) != len(sector.CommD) || len(r.CommR) != len(sector.CommR) ||
+		len(r.CommRStar) != len(sector.CommRStar) || len(r.Proof) != len(sector.Proof) {
+		return nil, errors.New("malformed sealed sector")
+	}
+	copy(sector.CommD[:], r.CommD)
+	copy(sector.CommR[:], r.CommR)
+	copy(sector.CommRStar[:], r.CommRStar)
+	copy(sector.Proof[:], r.Proof)
+	return sector, nil
+}
+
+// SectorIDBase returns the sector id after which a worker numbers the sectors
+// it seals. Worker ranges start at 2^56, far above the sector ids of the
+// master, and are derived from the worker's peer id so that they stay the same
+// across restarts.
+func SectorIDBase(worker peer.ID) uint64 {
+	h := []byte(worker)
+	if len(h) > 3 {
+		h = h[len(h)-3:]
+	}
+	var b [8]byte
+	copy(b[8-len(h):], h)
+	return 1<<56 | binary.BigEndian.Uint64(b[:])<<32
+}
+
+// IsWorkerSector returns whether the sector with the given id was sealed by a
+// worker, in the range of a SectorIDBase.
+func IsWorkerSector(sectorID uint64) bool {
+	return sectorID >= 1<<56
+}
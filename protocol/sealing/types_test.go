@@ -0,0 +1,61 @@
+package sealing
+
+import (
+	"testing"
+
+	cbor "gx/ipfs/QmRoARq3nkUb13HSKZGepCZSWe5GrVPwx7xURJGZ7KWv9V/go-ipld-cbor"
+
+	"github.com/filecoin-project/go-filecoin/filnet"
+	"github.com/filecoin-project/go-filecoin/proofs/sectorbuilder"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSectorIDBase(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+	assert := assert.New(t)
+
+	a, err := filnet.RandPeerID()
+	require.NoError(err)
+	b, err := filnet.RandPeerID()
+	require.NoError(err)
+
+	assert.Equal(SectorIDBase(a), SectorIDBase(a))
+	assert.NotEqual(SectorIDBase(a), SectorIDBase(b))
+
+	// each worker numbers 2^32 sectors within its range, above 2^56
+	assert.True(SectorIDBase(a) >= 1<<56)
+	assert.Equal(uint64(0), SectorIDBase(a)&(1<<32-1))
+}
+
+func TestSealResponseRoundTrip(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+	assert := assert.New(t)
+
+	sector := &sectorbuilder.SealedSectorMetadata{
+		SectorID: SectorIDBase("worker") + 1,
+		Pieces: []*sectorbuilder.PieceInfo{
+			{Ref: types.SomeCid(), Size: 100},
+		},
+	}
+	sector.CommD[0] = 1
+	sector.CommR[0] = 2
+	sector.CommRStar[0] = 3
+	sector.Proof[0] = 4
+
+	raw, err := cbor.DumpObject(newSealResponse(sector))
+	require.NoError(err)
+	var resp SealResponse
+	require.NoError(cbor.DecodeInto(raw, &resp))
+
+	decoded, err := resp.sector()
+	require.NoError(err)
+	assert.Equal(sector, decoded)
+
+	resp.Proof = resp.Proof[1:]
+	_, err = resp.sector()
+	assert.EqualError(err, "malformed sealed sector")
+}
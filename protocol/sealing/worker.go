@@ -0,0 +1,207 @@
+package sealing
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	inet "gx/ipfs/QmNgLg1NTw37iWbYPKcyK85YJ9Whs1MkPtJwhfqbNYAyKg/go-libp2p-net"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	"gx/ipfs/QmY5Grm8pJdiSSVsYxx4uNRgweY72EmYwuSDbRnbFok3iY/go-libp2p-peer"
+	host "gx/ipfs/QmaoXrM4Z41PD48JY36YqQGKQpLGjyLA2cKcLsES7YddAq/go-libp2p-host"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	cbu "github.com/filecoin-project/go-filecoin/cborutil"
+	"github.com/filecoin-project/go-filecoin/proofs/sectorbuilder"
+)
+
+// RegisterInterval is how often a worker registers with its master again, so
+// that a master that restarted or dropped the worker learns of it.
+const RegisterInterval = time.Minute
+
+// Register offers the sealing capacity of the host to the master, returning
+// the miner the master has the host seal sectors for.
+func Register(ctx context.Context, h host.Host, master peer.ID, capacity uint64) (address.Address, error) {
+	s, err := h.NewStream(ctx, master, registerProtocol)
+	if err != nil {
+		return address.Address{}, errors.Wrap(err, "failed to create stream to master")
+	}
+	defer s.Close() // nolint: errcheck
+
+	if err := cbu.NewMsgWriter(s).WriteMsg(&RegisterRequest{Capacity: capacity}); err != nil {
+		return address.Address{}, errors.Wrap(err, "failed to write registration")
+	}
+
+	var resp RegisterResponse
+	if err := cbu.NewMsgReader(s).ReadMsg(&resp); err != nil {
+		return address.Address{}, errors.Wrap(err, "failed to read registration response")
+	}
+	if !resp.Accepted {
+		return address.Address{}, errors.Errorf("master refused worker: %s", resp.ErrorMessage)
+	}
+	return resp.Miner, nil
+}
+
+// Worker seals the pieces a master sends it into sectors of its own sector
+// builder.
+type Worker struct {
+	host     host.Host
+	master   peer.ID
+	sb       sectorbuilder.SectorBuilder
+	capacity uint64
+
+	lk     sync.Mutex
+	active uint64
+	// sealing holds the seal requests waiting for each sector to be sealed.
+	sealing map[uint64][]chan sectorbuilder.SectorSealResult
+
+	// addLk makes sure each piece is sealed into a sector of its own.
+	addLk sync.Mutex
+}
+
+// NewWorker returns a Worker sealing at most capacity sectors at once with sb
+// for the master.
+func NewWorker(h host.Host, master peer.ID, sb sectorbuilder.SectorBuilder, capacity uint64) *Worker {
+	w := &Worker{
+		host:     h,
+		master:   master,
+		sb:       sb,
+		capacity: capacity,
+		sealing:  make(map[uint64][]chan sectorbuilder.SectorSealResult),
+	}
+
+	h.SetStreamHandler(healthProtocol, w.handleHealth)
+	h.SetStreamHandler(sealProtocol, w.handleSeal)
+	return w
+}
+
+// Run hands sealed sectors to the requests waiting for them and registers
+// with the master every RegisterInterval until ctx is done.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(RegisterInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case result, ok := <-w.sb.SectorSealResults():
+			if !ok {
+				return
+			}
+			w.lk.Lock()
+			waiters := w.sealing[result.SectorID]
+			delete(w.sealing, result.SectorID)
+			w.lk.Unlock()
+			for _, waiter := range waiters {
+				waiter <- result
+			}
+		case <-ticker.C:
+			if _, err := Register(ctx, w.host, w.master, w.capacity); err != nil {
+				log.Warningf("failed to register with sealing master %s: %s", w.master.Pretty(), err)
+			}
+		}
+	}
+}
+
+func (w *Worker) handleHealth(s inet.Stream) {
+	defer s.Close() // nolint: errcheck
+
+	var req HealthRequest
+	if err := cbu.NewMsgReader(s).ReadMsg(&req); err != nil {
+		log.Errorf("failed to read health check: %s", err)
+		return
+	}
+
+	w.lk.Lock()
+	resp := &HealthResponse{Capacity: w.capacity, Active: w.active}
+	w.lk.Unlock()
+
+	if err := cbu.NewMsgWriter(s).WriteMsg(resp); err != nil {
+		log.Warningf("failed to write health response: %s", err)
+	}
+}
+
+func (w *Worker) handleSeal(s inet.Stream) {
+	defer s.Close() // nolint: errcheck
+
+	if s.Conn().RemotePeer() != w.master {
+		log.Warningf("refused seal request from %s, which is not the master", s.Conn().RemotePeer().Pretty())
+		return
+	}
+
+	var req SealRequest
+	if err := cbu.NewMsgReader(s).ReadMsg(&req); err != nil {
+		log.Errorf("failed to read seal request: %s", err)
+		return
+	}
+
+	resp := &SealResponse{}
+	sector, err := w.seal(context.Background(), req.Piece)
+	if err != nil {
+		log.Errorf("failed to seal piece: %s", err)
+		resp.ErrorMessage = err.Error()
+	} else {
+		resp = newSealResponse(sector)
+	}
+
+	if err := cbu.NewMsgWriter(s).WriteMsg(resp); err != nil {
+		log.Warningf("failed to write seal response: %s", err)
+	}
+}
+
+// seal writes the piece into a sector of its own, seals the sector and waits
+// for it to be sealed.
+func (w *Worker) seal(ctx context.Context, piece *sectorbuilder.PieceInfo) (*sectorbuilder.SealedSectorMetadata, error) {
+	if piece == nil {
+		return nil, errors.New("seal request without piece")
+	}
+
+	w.lk.Lock()
+	if w.active >= w.capacity {
+		w.lk.Unlock()
+		return nil, errors.New("worker is at capacity")
+	}
+	w.active++
+	w.lk.Unlock()
+	defer func() {
+		w.lk.Lock()
+		w.active--
+		w.lk.Unlock()
+	}()
+
+	waiter := make(chan sectorbuilder.SectorSealResult, 1)
+	if err := w.addPiece(ctx, piece, waiter); err != nil {
+		return nil, err
+	}
+
+	result := <-waiter
+	if result.SealingErr != nil {
+		return nil, errors.Wrapf(result.SealingErr, "failed to seal sector %d", result.SectorID)
+	}
+	return result.SealingResult, nil
+}
+
+// addPiece writes the piece into a staged sector and starts sealing it,
+// registering waiter for the sealed sector.
+func (w *Worker) addPiece(ctx context.Context, piece *sectorbuilder.PieceInfo, waiter chan sectorbuilder.SectorSealResult) error {
+	w.addLk.Lock()
+	defer w.addLk.Unlock()
+
+	sectorID, err := w.sb.AddPiece(ctx, piece)
+	if err != nil {
+		return errors.Wrap(err, "failed to add piece")
+	}
+
+	w.lk.Lock()
+	w.sealing[sectorID] = append(w.sealing[sectorID], waiter)
+	w.lk.Unlock()
+
+	if err := w.sb.SealAllStagedSectors(ctx); err != nil {
+		w.lk.Lock()
+		delete(w.sealing, sectorID)
+		w.lk.Unlock()
+		return errors.Wrap(err, "failed to seal staged sectors")
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	inet "gx/ipfs/QmNgLg1NTw37iWbYPKcyK85YJ9Whs1MkPtJwhfqbNYAyKg/go-libp2p-net"
+	cbor "gx/ipfs/QmRoARq3nkUb13HSKZGepCZSWe5GrVPwx7xURJGZ7KWv9V/go-ipld-cbor"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	"gx/ipfs/QmY5Grm8pJdiSSVsYxx4uNRgweY72EmYwuSDbRnbFok3iY/go-libp2p-peer"
+	"gx/ipfs/QmZNkThpqfVXs9GNbexPrfBbXSLNYeKrE7jwFM2oqHbyqN/go-libp2p-protocol"
+
+	"github.com/filecoin-project/go-filecoin/actor/builtin/miner"
+	"github.com/filecoin-project/go-filecoin/address"
+	cbu "github.com/filecoin-project/go-filecoin/cborutil"
+)
+
+const queryAsksProtocol = protocol.ID("/fil/storage/asks/1.0.0")
+
+func init() {
+	cbor.RegisterCborType(askRequest{})
+	cbor.RegisterCborType(AskResponse{})
+}
+
+// askRequest is sent by clients to query the asks of a miner.
+type askRequest struct{}
+
+// AskResponse is the information sent over the wire, when a miner responds to
+// a query for its asks.
+type AskResponse struct {
+	// Miner is the address of the miner actor the asks are for.
+	Miner address.Address
+
+	// Asks are the asks of the miner that have not expired.
+	Asks []*miner.Ask
+
+	// Error tells why the miner could not list its asks.
+	Error string
+}
+
+// checkPieceSize returns an error if pieces of size bytes are not covered by
+// the ask.
+func checkPieceSize(ask miner.Ask, size uint64) error {
+	if ask.MinPieceSize != nil && size < ask.MinPieceSize.Uint64() {
+		return fmt.Errorf("piece size %d is less than the minimum piece size %s of the ask", size, ask.MinPieceSize)
+	}
+	if ask.MaxPieceSize != nil && size > ask.MaxPieceSize.Uint64() {
+		return fmt.Errorf("piece size %d is greater than the maximum piece size %s of the ask", size, ask.MaxPieceSize)
+	}
+	return nil
+}
+
+// QueryAsks queries the miner the peer pid operates for its asks.
+func (smc *Client) QueryAsks(ctx context.Context, pid peer.ID) (*AskResponse, error) {
+	var resp AskResponse
+	if err := smc.node.MakeProtocolRequest(ctx, queryAsksProtocol, pid, askRequest{}, &resp); err != nil {
+		return nil, errors.Wrap(err, "error querying asks")
+	}
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+	return &resp, nil
+}
+
+// QueryAsks responds to a query for the asks of the miner.
+func (sm *Miner) QueryAsks(ctx context.Context) *AskResponse {
+	resp := &AskResponse{Miner: sm.minerAddr}
+
+	asks, err := sm.liveAsks(ctx)
+	if err != nil {
+		log.Errorf("failed to read asks: %s", err)
+		resp.Error = "could not read asks"
+		return resp
+	}
+	resp.Asks = asks
+
+	return resp
+}
+
+// liveAsks returns the asks of the miner that have not expired at the current
+// block height.
+func (sm *Miner) liveAsks(ctx context.Context) ([]*miner.Ask, error) {
+	height, err := sm.porcelainAPI.ChainBlockHeight(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get block height")
+	}
+
+	rets, _, err := sm.porcelainAPI.MessageQuery(ctx, address.Address{}, sm.minerAddr, "getAsks")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query ask ids")
+	}
+	var ids []uint64
+	if err := cbor.DecodeInto(rets[0], &ids); err != nil {
+		return nil, errors.Wrap(err, "failed to decode ask ids")
+	}
+
+	var asks []*miner.Ask
+	for _, id := range ids {
+		rets, _, err := sm.porcelainAPI.MessageQuery(ctx, address.Address{}, sm.minerAddr, "getAsk", big.NewInt(int64(id)))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to query ask %d", id)
+		}
+		var ask miner.Ask
+		if err := cbor.DecodeInto(rets[0], &ask); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode ask %d", id)
+		}
+		if height.LessThan(ask.Expiry) {
+			asks = append(asks, &ask)
+		}
+	}
+	return asks, nil
+}
+
+func (sm *Miner) handleQueryAsks(s inet.Stream) {
+	defer s.Close() // nolint: errcheck
+
+	var req askRequest
+	if err := cbu.NewMsgReader(s).ReadMsg(&req); err != nil {
+		log.Errorf("received invalid ask query: %s", err)
+		return
+	}
+
+	resp := sm.QueryAsks(context.Background())
+
+	if err := cbu.NewMsgWriter(s).WriteMsg(resp); err != nil {
+		log.Errorf("failed to write ask query response: %s", err)
+	}
+}
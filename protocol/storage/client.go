@@ -15,6 +15,7 @@ import (
 	"gx/ipfs/QmabLh8TrJ3emfAoQk5AbqbLTbMyj7XqumMFmAFxa9epo8/go-multistream"
 	"gx/ipfs/QmaoXrM4Z41PD48JY36YqQGKQpLGjyLA2cKcLsES7YddAq/go-libp2p-host"
 	ipld "gx/ipfs/QmcKKBwfz6FyQdHR2jsXrrF6XeSBXYL86anmWNewpFpoF5/go-ipld-format"
+	"gx/ipfs/QmdbxjQWogRCHRaxhhGnYdT1oQJzL9GdqSKzCdqWr85AP2/pubsub"
 	"gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
 	"gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore/query"
 
@@ -22,7 +23,9 @@ import (
 	"github.com/filecoin-project/go-filecoin/actor/builtin/paymentbroker"
 	"github.com/filecoin-project/go-filecoin/address"
 	cbu "github.com/filecoin-project/go-filecoin/cborutil"
+	"github.com/filecoin-project/go-filecoin/filnet"
 	"github.com/filecoin-project/go-filecoin/porcelain"
+	"github.com/filecoin-project/go-filecoin/proofs"
 	"github.com/filecoin-project/go-filecoin/repo"
 	"github.com/filecoin-project/go-filecoin/types"
 	"github.com/filecoin-project/go-filecoin/util/convert"
@@ -57,23 +60,51 @@ const (
 
 type clientNode interface {
 	GetFileSize(context.Context, cid.Cid) (uint64, error)
+	GetPieceCommitment(context.Context, cid.Cid) (proofs.CommP, error)
 	MakeProtocolRequest(ctx context.Context, protocol protocol.ID, peer peer.ID, request interface{}, response interface{}) error
 	GetBlockTime() time.Duration
+	Peers() []peer.ID
 }
 
 type clientPorcelainAPI interface {
 	ChainBlockHeight(ctx context.Context) (*types.BlockHeight, error)
 	CreatePayments(ctx context.Context, config porcelain.CreatePaymentsParams) (*porcelain.CreatePaymentsReturn, error)
 	GetAndMaybeSetDefaultSenderAddress() (address.Address, error)
+	MessageReceipts(ctx context.Context, ts types.TipSet) (map[cid.Cid]*types.MessageReceipt, error)
 	MinerGetAsk(ctx context.Context, minerAddr address.Address, askID uint64) (miner.Ask, error)
 	MinerGetOwnerAddress(ctx context.Context, minerAddr address.Address) (address.Address, error)
 	MinerGetPeerID(ctx context.Context, minerAddr address.Address) (peer.ID, error)
+	SignBytes(data []byte, addr address.Address) (types.Signature, error)
+	ConfigGet(dottedPath string) (interface{}, error)
 }
 
 type clientDeal struct {
 	Miner    address.Address
 	Proposal *DealProposal
 	Response *DealResponse
+	// History lists the states the deal went through, as far as the client
+	// observed them.
+	History []DealTransition
+
+	// AskPrice is the price per byte per block of the ask the deal was
+	// proposed for.
+	AskPrice *types.AttoFIL
+	// Expiry is the block height the deal ends at, at the latest.
+	Expiry *types.BlockHeight
+
+	// Renew is set if the client proposes a deal renewing this one as it
+	// nears its expiry, at a price of at most MaxRenewalPrice per byte per
+	// block. If MaxRenewalPrice is nil, the configured ceiling applies.
+	Renew           bool
+	MaxRenewalPrice *types.AttoFIL
+	// RenewedBy is the proposal cid of the deal renewing this one.
+	RenewedBy *cid.Cid
+
+	// Vouchers are the payments for the whole deal. The client sends them to
+	// the miner one proving period ahead, as long as the miner proves the
+	// storage of the data. VouchersSent is the number of vouchers sent.
+	Vouchers     []*paymentbroker.PaymentVoucher
+	VouchersSent int
 }
 
 // Client is used to make deals directly with storage miners.
@@ -82,6 +113,28 @@ type Client struct {
 	dealsDs repo.Datastore
 	dealsLk sync.Mutex
 
+	// renewing holds the deals whose renewal is being proposed.
+	renewing map[cid.Cid]bool
+	// paying holds the deals whose vouchers are being sent.
+	paying map[cid.Cid]bool
+	// querying holds the deals whose state is being queried by watchDeals.
+	querying map[cid.Cid]bool
+
+	// replications holds the data replicated to several miners, indexed by
+	// the cid of the data.
+	replications map[cid.Cid]*replicationSet
+	// repairing holds the data whose missing replicas are being proposed.
+	repairing map[cid.Cid]bool
+
+	// reputation records the outcomes of the deals with each miner.
+	reputation *reputationStore
+
+	// events publishes a DealEvent for every change observed in the deals.
+	events *pubsub.PubSub
+	// transferred holds the bytes of the deals' data the miners last reported
+	// to have received.
+	transferred map[cid.Cid]uint64
+
 	node clientNode
 	api  clientPorcelainAPI
 }
@@ -93,19 +146,46 @@ func init() {
 // NewClient creates a new storage client.
 func NewClient(nd clientNode, api clientPorcelainAPI, dealsDs repo.Datastore) (*Client, error) {
 	smc := &Client{
-		deals:   make(map[cid.Cid]*clientDeal),
-		node:    nd,
-		api:     api,
-		dealsDs: dealsDs,
+		deals:        make(map[cid.Cid]*clientDeal),
+		renewing:     make(map[cid.Cid]bool),
+		paying:       make(map[cid.Cid]bool),
+		querying:     make(map[cid.Cid]bool),
+		replications: make(map[cid.Cid]*replicationSet),
+		repairing:    make(map[cid.Cid]bool),
+		events:       pubsub.New(128),
+		transferred:  make(map[cid.Cid]uint64),
+		node:         nd,
+		api:          api,
+		dealsDs:      dealsDs,
 	}
 	if err := smc.loadDeals(); err != nil {
 		return nil, errors.Wrap(err, "failed to load client deals")
 	}
+	if err := smc.loadReplications(); err != nil {
+		return nil, errors.Wrap(err, "failed to load client replications")
+	}
+	reputation, err := newReputationStore(dealsDs)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load miner reputations")
+	}
+	smc.reputation = reputation
 	return smc, nil
 }
 
 // ProposeDeal is
 func (smc *Client) ProposeDeal(ctx context.Context, miner address.Address, data cid.Cid, askID uint64, duration uint64, allowDuplicates bool) (*DealResponse, error) {
+	return smc.proposeDeal(ctx, miner, data, askID, duration, allowDuplicates, false)
+}
+
+// ProposeOfflineDeal proposes a deal whose data the client delivers to the
+// miner out of band, e.g. on a hard drive, rather than over the network. The
+// proposal carries the piece commitment of the data, which the miner checks
+// the data against when importing it.
+func (smc *Client) ProposeOfflineDeal(ctx context.Context, miner address.Address, data cid.Cid, askID uint64, duration uint64, allowDuplicates bool) (*DealResponse, error) {
+	return smc.proposeDeal(ctx, miner, data, askID, duration, allowDuplicates, true)
+}
+
+func (smc *Client) proposeDeal(ctx context.Context, miner address.Address, data cid.Cid, askID uint64, duration uint64, allowDuplicates bool, offline bool) (*DealResponse, error) {
 	ctx, cancel := context.WithTimeout(ctx, 4*smc.node.GetBlockTime())
 	defer cancel()
 	size, err := smc.node.GetFileSize(ctx, data)
@@ -119,6 +199,10 @@ func (smc *Client) ProposeDeal(ctx context.Context, miner address.Address, data
 	}
 	price := ask.Price
 
+	if err := checkPieceSize(ask, size); err != nil {
+		return nil, err
+	}
+
 	chainHeight, err := smc.api.ChainBlockHeight(ctx)
 	if err != nil {
 		return nil, err
@@ -142,7 +226,14 @@ func (smc *Client) ProposeDeal(ctx context.Context, miner address.Address, data
 		TotalPrice:   totalPrice,
 		Duration:     duration,
 		MinerAddress: miner,
-		// TODO: Sign this proposal
+	}
+
+	if offline {
+		commP, err := smc.node.GetPieceCommitment(ctx, data)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to compute the piece commitment of the data")
+		}
+		proposal.CommP = commP[:]
 	}
 
 	if smc.isMaybeDupDeal(proposal) && !allowDuplicates {
@@ -167,16 +258,33 @@ func (smc *Client) ProposeDeal(ctx context.Context, miner address.Address, data
 	proposal.Payment.PayChActor = address.PaymentBrokerAddress
 	proposal.Payment.Payer = fromAddress
 	proposal.Payment.ChannelMsgCid = &cpResp.ChannelMsgCid
-	proposal.Payment.Vouchers = cpResp.Vouchers
+	// only the first payment is made up front, see sendDueVouchers
+	proposal.Payment.Vouchers = cpResp.Vouchers[:1]
+
+	proposal.Signature, err = proposal.Terms().Sign(smc.api, fromAddress)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to sign proposal")
+	}
 
 	// send proposal
 	pid, err := smc.api.MinerGetPeerID(ctx, miner)
 	if err != nil {
 		return nil, err
 	}
+	proposedAt := time.Now().Unix()
 
 	var response DealResponse
 	err = smc.node.MakeProtocolRequest(ctx, makeDealProtocol, pid, proposal, &response)
+	smc.reputation.update(miner, func(r *MinerReputation) {
+		r.Proposals++
+		switch {
+		case err != nil:
+		case response.State == Rejected:
+			r.Rejected++
+		default:
+			r.Accepted++
+		}
+	})
 	if err != nil {
 		return nil, errors.Wrap(err, "error sending proposal")
 	}
@@ -185,17 +293,32 @@ func (smc *Client) ProposeDeal(ctx context.Context, miner address.Address, data
 		return nil, errors.Wrap(err, "response check failed")
 	}
 
-	// Note: currently the miner requests the data out of band
+	// The miner's counter-signature is what makes the deal binding, it is
+	// published to the storage market along with ours.
+	if !proposal.Terms().VerifySignature(minerOwner, response.Signature) {
+		return nil, errors.New("response check failed: invalid miner signature")
+	}
+
+	// Note: currently the miner requests the data out of band, unless the
+	// deal is offline
 
-	if err := smc.recordResponse(&response, miner, proposal); err != nil {
+	deal := &clientDeal{
+		Miner:        miner,
+		Proposal:     proposal,
+		AskPrice:     price,
+		Expiry:       chainHeight.Add(types.NewBlockHeight(duration)),
+		Vouchers:     cpResp.Vouchers,
+		VouchersSent: len(proposal.Payment.Vouchers),
+	}
+	if err := smc.recordResponse(&response, deal, proposedAt); err != nil {
 		return nil, errors.Wrap(err, "failed to track response")
 	}
 
 	return &response, nil
 }
 
-func (smc *Client) recordResponse(resp *DealResponse, miner address.Address, p *DealProposal) error {
-	proposalCid, err := convert.ToCid(p)
+func (smc *Client) recordResponse(resp *DealResponse, deal *clientDeal, proposedAt int64) error {
+	proposalCid, err := convert.ToCid(deal.Proposal)
 	if err != nil {
 		return errors.New("failed to get cid of proposal")
 	}
@@ -209,12 +332,20 @@ func (smc *Client) recordResponse(resp *DealResponse, miner address.Address, p *
 		return fmt.Errorf("deal [%s] is already in progress", proposalCid.String())
 	}
 
-	smc.deals[proposalCid] = &clientDeal{
-		Miner:    miner,
-		Proposal: p,
-		Response: resp,
+	history := []DealTransition{{State: Proposed, Time: proposedAt}}
+	history, err = moveDeal(Proposed, resp, history)
+	if err != nil {
+		return err
+	}
+
+	deal.Response = resp
+	deal.History = history
+	smc.deals[proposalCid] = deal
+	if err := smc.saveDeal(proposalCid); err != nil {
+		return err
 	}
-	return smc.saveDeal(proposalCid)
+	smc.publishResponse(deal, nil, resp)
+	return nil
 }
 
 func (smc *Client) checkDealResponse(ctx context.Context, resp *DealResponse) error {
@@ -260,9 +391,115 @@ func (smc *Client) QueryDeal(ctx context.Context, proposalCid cid.Cid) (*DealRes
 		return nil, errors.Wrap(err, "error querying deal")
 	}
 
+	if err := smc.recordQueryResponse(proposalCid, resp); err != nil {
+		return nil, errors.Wrap(err, "failed to track response")
+	}
+
 	return &resp, nil
 }
 
+// OnNewHeaviestTipSet is a callback called by node, everytime the the latest
+// head is updated. It follows the progress of the deals, pays for the deals
+// being proven, replaces failed replicas, and renews the deals nearing their
+// expiry.
+func (smc *Client) OnNewHeaviestTipSet(ts types.TipSet) {
+	height, err := ts.Height()
+	if err != nil {
+		log.Errorf("failed to get block height: %s", err)
+		return
+	}
+
+	smc.watchDeals(ts)
+	smc.recordFaults(ts)
+	smc.sendDueVouchers(types.NewBlockHeight(height))
+	smc.repairReplications(types.NewBlockHeight(height))
+	smc.renewExpiringDeals(height)
+}
+
+// recordQueryResponse records the state the miner reported for a deal in
+// response to a query. Responses moving the deal to a state it can't move to,
+// e.g. because the miner lost track of it, are not recorded.
+func (smc *Client) recordQueryResponse(proposalCid cid.Cid, resp DealResponse) error {
+	smc.dealsLk.Lock()
+	defer smc.dealsLk.Unlock()
+	deal, ok := smc.deals[proposalCid]
+	if !ok {
+		return fmt.Errorf("no such proposal by cid: %s", proposalCid)
+	}
+	if resp.State == deal.Response.State {
+		smc.publishResponse(deal, deal.Response, &resp)
+		return nil
+	}
+
+	history, err := moveDeal(deal.Response.State, &resp, deal.History)
+	if err != nil {
+		log.Warningf("ignoring response for deal %s: %s", proposalCid, err)
+		return nil
+	}
+	prev := deal.Response
+	deal.Response = &resp
+	deal.History = history
+	if err := smc.saveDeal(proposalCid); err != nil {
+		return err
+	}
+	smc.recordTransition(deal, prev.State)
+	smc.publishResponse(deal, prev, &resp)
+	return nil
+}
+
+// Deal returns the record of the deal with the given proposal cid.
+func (smc *Client) Deal(proposalCid cid.Cid) (*DealRecord, error) {
+	smc.dealsLk.Lock()
+	defer smc.dealsLk.Unlock()
+	deal, ok := smc.deals[proposalCid]
+	if !ok {
+		return nil, fmt.Errorf("no such proposal by cid: %s", proposalCid)
+	}
+
+	return &DealRecord{
+		ProposalCid: proposalCid,
+		Role:        ClientRole,
+		Proposal:    deal.Proposal,
+		Response:    deal.Response,
+		History:     append([]DealTransition{}, deal.History...),
+	}, nil
+}
+
+// StoringMiners returns the miners proving the storage of data in a deal with
+// the client, the miners data can be retrieved from.
+func (smc *Client) StoringMiners(data cid.Cid) []address.Address {
+	smc.dealsLk.Lock()
+	defer smc.dealsLk.Unlock()
+
+	var miners []address.Address
+	seen := make(map[address.Address]bool)
+	for _, deal := range smc.deals {
+		if deal.Proposal.PieceRef.Equals(data) && deal.Response.State == Proving && !seen[deal.Miner] {
+			seen[deal.Miner] = true
+			miners = append(miners, deal.Miner)
+		}
+	}
+	return miners
+}
+
+// LivePieces returns the data of the client's deals which have not ended yet,
+// which the client keeps until the deals end, e.g. to renew them.
+func (smc *Client) LivePieces() []cid.Cid {
+	smc.dealsLk.Lock()
+	defer smc.dealsLk.Unlock()
+
+	var pieces []cid.Cid
+	seen := make(map[cid.Cid]bool)
+	for _, deal := range smc.deals {
+		if deal.Response.State.IsFinal() || seen[deal.Proposal.PieceRef] {
+			continue
+		}
+		seen[deal.Proposal.PieceRef] = true
+		pieces = append(pieces, deal.Proposal.PieceRef)
+	}
+	return pieces
+}
+
 func (smc *Client) loadDeals() error {
 	res, err := smc.dealsDs.Query(query.Query{
 		Prefix: "/" + clientDatastorePrefix,
@@ -270,6 +507,7 @@ func (smc *Client) loadDeals() error {
 	if err != nil {
 		return errors.Wrap(err, "failed to query deals from datastore")
 	}
+	defer res.Close() // nolint: errcheck
 
 	smc.deals = make(map[cid.Cid]*clientDeal)
 
@@ -302,6 +540,20 @@ func (smc *Client) saveDeal(cid cid.Cid) error {
 	return nil
 }
 
+// SaveDeals writes the current state of every deal of the client to its
+// datastore.
+func (smc *Client) SaveDeals() error {
+	smc.dealsLk.Lock()
+	defer smc.dealsLk.Unlock()
+
+	for c := range smc.deals {
+		if err := smc.saveDeal(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (smc *Client) isMaybeDupDeal(p *DealProposal) bool {
 	smc.dealsLk.Lock()
 	defer smc.dealsLk.Unlock()
@@ -319,6 +571,7 @@ func (smc *Client) LoadVouchersForDeal(dealCid cid.Cid) ([]*paymentbroker.Paymen
 	if err != nil {
 		return []*paymentbroker.PaymentVoucher{}, errors.Wrap(err, "failed to query vouchers from datastore")
 	}
+	defer queryResults.Close() // nolint: errcheck
 
 	var results []*paymentbroker.PaymentVoucher
 
@@ -327,7 +580,12 @@ func (smc *Client) LoadVouchersForDeal(dealCid cid.Cid) ([]*paymentbroker.Paymen
 		if err := cbor.DecodeInto(entry.Value, &deal); err != nil {
 			return results, errors.Wrap(err, "failed to unmarshal deals from datastore")
 		}
-		if deal.Response.ProposalCid == dealCid {
+		if deal.Response.ProposalCid != dealCid {
+			continue
+		}
+		if deal.Vouchers != nil {
+			results = append(results, deal.Vouchers...)
+		} else {
 			results = append(results, deal.Proposal.Payment.Vouchers...)
 		}
 	}
@@ -356,11 +614,21 @@ func (cni *ClientNodeImpl) GetBlockTime() time.Duration {
 	return cni.blockTime
 }
 
+// Peers returns the peers the node is connected to.
+func (cni *ClientNodeImpl) Peers() []peer.ID {
+	return cni.host.Network().Peers()
+}
+
 // GetFileSize returns the size of the file referenced by 'c'
 func (cni *ClientNodeImpl) GetFileSize(ctx context.Context, c cid.Cid) (uint64, error) {
 	return getFileSize(ctx, c, cni.dserv)
 }
 
+// GetPieceCommitment returns the piece commitment of the file referenced by 'c'
+func (cni *ClientNodeImpl) GetPieceCommitment(ctx context.Context, c cid.Cid) (proofs.CommP, error) {
+	return getPieceCommitment(ctx, c, cni.dserv)
+}
+
 // MakeProtocolRequest makes a request and expects a response from the host using the given protocol.
 func (cni *ClientNodeImpl) MakeProtocolRequest(ctx context.Context, protocol protocol.ID, peer peer.ID, request interface{}, response interface{}) error {
 	s, err := cni.host.NewStream(ctx, peer, protocol)
@@ -371,6 +639,8 @@ func (cni *ClientNodeImpl) MakeProtocolRequest(ctx context.Context, protocol pro
 
 		return errors.Wrap(err, "failed to establish connection with the peer")
 	}
+	defer s.Close() // nolint: errcheck
+	defer filnet.ResetOnDone(ctx, s)()
 
 	if err := cbu.NewMsgWriter(s).WriteMsg(request); err != nil {
 		return errors.Wrap(err, "failed to write request")
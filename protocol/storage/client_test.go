@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"fmt"
 	"math/big"
 	"testing"
 	"time"
@@ -15,7 +16,9 @@ import (
 	"github.com/filecoin-project/go-filecoin/actor/builtin/miner"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/paymentbroker"
 	"github.com/filecoin-project/go-filecoin/address"
+	cfg "github.com/filecoin-project/go-filecoin/config"
 	"github.com/filecoin-project/go-filecoin/porcelain"
+	"github.com/filecoin-project/go-filecoin/proofs"
 	"github.com/filecoin-project/go-filecoin/repo"
 	"github.com/filecoin-project/go-filecoin/types"
 	"github.com/filecoin-project/go-filecoin/util/convert"
@@ -32,6 +35,7 @@ func TestProposeDeal(t *testing.T) {
 
 	var proposal *DealProposal
 
+	testAPI := newTestClientAPI()
 	testNode := newTestClientNode(func(request interface{}) (interface{}, error) {
 		p, ok := request.(*DealProposal)
 		require.True(ok)
@@ -39,14 +43,16 @@ func TestProposeDeal(t *testing.T) {
 
 		pcid, err := convert.ToCid(p)
 		require.NoError(err)
+		sig, err := p.Terms().Sign(testAPI.signer, testAPI.target)
+		require.NoError(err)
 		return &DealResponse{
 			State:       Accepted,
 			Message:     "OK",
 			ProposalCid: pcid,
+			Signature:   sig,
 		}, nil
 	})
 
-	testAPI := newTestClientAPI()
 	testRepo := repo.NewInMemoryRepo()
 
 	client, err := NewClient(testNode, testAPI, testRepo.DealsDs)
@@ -66,6 +72,10 @@ func TestProposeDeal(t *testing.T) {
 		assert.Equal(minerAddr, proposal.MinerAddress)
 	})
 
+	t.Run("and delivers the data over the network", func(t *testing.T) {
+		assert.False(proposal.IsOffline())
+	})
+
 	t.Run("and creates proposal with file size", func(t *testing.T) {
 		expectedFileSize, err := testNode.GetFileSize(ctx, dataCid)
 		require.NoError(err)
@@ -81,6 +91,11 @@ func TestProposeDeal(t *testing.T) {
 		assert.Equal(expectedTotalPrice, proposal.TotalPrice)
 	})
 
+	t.Run("and signs the proposal as the payer", func(t *testing.T) {
+		assert.Equal(testAPI.payer, proposal.Payment.Payer)
+		assert.True(proposal.Terms().VerifySignature(testAPI.payer, proposal.Signature))
+	})
+
 	t.Run("and creates a new payment channel", func(t *testing.T) {
 		// correct payment id and message cid in proposal implies a call to createChannel
 		assert.Equal(testAPI.channelID, proposal.Payment.Channel)
@@ -88,10 +103,12 @@ func TestProposeDeal(t *testing.T) {
 	})
 
 	t.Run("and creates payment info", func(t *testing.T) {
-		assert.Equal(int(duration/VoucherInterval), len(proposal.Payment.Vouchers))
+		vouchers, err := client.LoadVouchersForDeal(dealResponse.ProposalCid)
+		require.NoError(err)
+		assert.Equal(int(duration/VoucherInterval), len(vouchers))
 
 		lastValidAt := types.NewBlockHeight(0)
-		for i, voucher := range proposal.Payment.Vouchers {
+		for i, voucher := range vouchers {
 			assert.Equal(testAPI.channelID, &voucher.Channel)
 			assert.True(voucher.ValidAt.GreaterThan(lastValidAt))
 			assert.Equal(testAPI.target, voucher.Target)
@@ -101,6 +118,11 @@ func TestProposeDeal(t *testing.T) {
 		}
 	})
 
+	t.Run("and only pays the first interval up front", func(t *testing.T) {
+		require.Equal(1, len(proposal.Payment.Vouchers))
+		assert.Equal(testAPI.perPayment, &proposal.Payment.Vouchers[0].Amount)
+	})
+
 	t.Run("and sends proposal and stores response", func(t *testing.T) {
 		assert.NotNil(dealResponse)
 
@@ -125,6 +147,198 @@ func TestProposeDeal(t *testing.T) {
 	})
 }
 
+func TestQueryDealRecordsHistory(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	queryState := Proving
+	testAPI := newTestClientAPI()
+	testNode := newTestClientNode(func(request interface{}) (interface{}, error) {
+		switch r := request.(type) {
+		case *DealProposal:
+			pcid, err := convert.ToCid(r)
+			require.NoError(err)
+			sig, err := r.Terms().Sign(testAPI.signer, testAPI.target)
+			require.NoError(err)
+			return &DealResponse{State: Accepted, ProposalCid: pcid, Signature: sig}, nil
+		case queryRequest:
+			return &DealResponse{State: queryState, ProposalCid: r.Cid}, nil
+		}
+		return nil, fmt.Errorf("unexpected request %T", request)
+	})
+
+	testRepo := repo.NewInMemoryRepo()
+	client, err := NewClient(testNode, testAPI, testRepo.DealsDs)
+	require.NoError(err)
+
+	ctx := context.Background()
+	dealResponse, err := client.ProposeDeal(ctx, address.NewForTestGetter()(), types.SomeCid(), 0, 10000, false)
+	require.NoError(err)
+
+	_, err = client.QueryDeal(ctx, dealResponse.ProposalCid)
+	require.NoError(err)
+
+	// a deal can't move back, so this response isn't recorded
+	queryState = Accepted
+	_, err = client.QueryDeal(ctx, dealResponse.ProposalCid)
+	require.NoError(err)
+
+	// the history survives a restart
+	client, err = NewClient(testNode, testAPI, testRepo.DealsDs)
+	require.NoError(err)
+	record, err := client.Deal(dealResponse.ProposalCid)
+	require.NoError(err)
+
+	assert.Equal(ClientRole, record.Role)
+	assert.Equal(Proving, record.Response.State)
+	var states []DealState
+	for _, transition := range record.History {
+		states = append(states, transition.State)
+	}
+	assert.Equal([]DealState{Proposed, Accepted, Proving}, states)
+}
+
+func TestClientQueryAsks(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	minerAddr := address.NewForTestGetter()()
+	asks := []*miner.Ask{{
+		Price:        types.NewAttoFILFromFIL(2),
+		Expiry:       types.NewBlockHeight(100),
+		ID:           big.NewInt(3),
+		MaxPieceSize: types.NewBytesAmount(1024),
+	}}
+	minerError := ""
+
+	testNode := newTestClientNode(func(request interface{}) (interface{}, error) {
+		_, ok := request.(askRequest)
+		require.True(ok)
+		return &AskResponse{Miner: minerAddr, Asks: asks, Error: minerError}, nil
+	})
+	client, err := NewClient(testNode, newTestClientAPI(), repo.NewInMemoryRepo().DealsDs)
+	require.NoError(err)
+
+	pid, err := newTestClientAPI().MinerGetPeerID(context.Background(), minerAddr)
+	require.NoError(err)
+
+	resp, err := client.QueryAsks(context.Background(), pid)
+	require.NoError(err)
+	assert.Equal(minerAddr, resp.Miner)
+	assert.Equal(asks, resp.Asks)
+
+	minerError = "could not read asks"
+	_, err = client.QueryAsks(context.Background(), pid)
+	assert.EqualError(err, minerError)
+}
+
+func TestCheckPieceSize(t *testing.T) {
+	assert := assert.New(t)
+
+	ask := miner.Ask{MinPieceSize: types.NewBytesAmount(10), MaxPieceSize: types.NewBytesAmount(20)}
+	assert.NoError(checkPieceSize(ask, 10))
+	assert.NoError(checkPieceSize(ask, 20))
+	assert.Error(checkPieceSize(ask, 9))
+	assert.Error(checkPieceSize(ask, 21))
+	assert.NoError(checkPieceSize(miner.Ask{}, 1<<40))
+}
+
+func TestClientRenewsDeals(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	proposals := 0
+	testAPI := newTestClientAPI()
+	testNode := newTestClientNode(func(request interface{}) (interface{}, error) {
+		switch r := request.(type) {
+		case *DealProposal:
+			proposals++
+			pcid, err := convert.ToCid(r)
+			require.NoError(err)
+			sig, err := r.Terms().Sign(testAPI.signer, testAPI.target)
+			require.NoError(err)
+			return &DealResponse{State: Accepted, ProposalCid: pcid, Signature: sig}, nil
+		case askRequest:
+			return &AskResponse{Asks: []*miner.Ask{
+				{ID: big.NewInt(4), Price: types.NewAttoFILFromFIL(40), Expiry: types.NewBlockHeight(20000)},
+				{ID: big.NewInt(5), Price: types.NewAttoFILFromFIL(30), Expiry: types.NewBlockHeight(20000)},
+			}}, nil
+		}
+		return nil, fmt.Errorf("unexpected request %T", request)
+	})
+
+	client, err := NewClient(testNode, testAPI, repo.NewInMemoryRepo().DealsDs)
+	require.NoError(err)
+
+	ctx := context.Background()
+	dealResponse, err := client.ProposeDeal(ctx, address.NewForTestGetter()(), types.SomeCid(), 0, 10000, false)
+	require.NoError(err)
+	c := dealResponse.ProposalCid
+
+	// the deal expires at 773 + 10000, the default window is 1000 blocks
+	assert.Empty(client.dealsToRenew(types.NewBlockHeight(11773)))
+	require.NoError(client.SetDealRenewal(c, true, nil))
+	assert.Empty(client.dealsToRenew(types.NewBlockHeight(10773)))
+	assert.Equal([]cid.Cid{c}, client.dealsToRenew(types.NewBlockHeight(10774)))
+	// the deal is being renewed
+	assert.Empty(client.dealsToRenew(types.NewBlockHeight(10774)))
+
+	t.Run("renewals cost at most the price of the original deal", func(t *testing.T) {
+		deal := *client.deals[c]
+		maxPrice, err := client.renewalPriceCeiling(&deal)
+		require.NoError(err)
+		assert.Equal(types.NewAttoFILFromFIL(32), maxPrice)
+
+		ask, err := client.renewalAsk(ctx, &deal, maxPrice)
+		require.NoError(err)
+		assert.Equal(uint64(5), ask.ID.Uint64())
+
+		_, err = client.renewalAsk(ctx, &deal, types.NewAttoFILFromFIL(20))
+		assert.Error(err)
+	})
+
+	testAPI.blockHeight = types.NewBlockHeight(10000)
+	client.renewDeal(c)
+	assert.Equal(2, proposals)
+
+	renewedBy := client.deals[c].RenewedBy
+	require.NotNil(renewedBy)
+	renewal := client.deals[*renewedBy]
+	assert.True(renewal.Renew)
+	assert.Equal(types.NewBlockHeight(20000), renewal.Expiry)
+
+	// renewed deals aren't renewed again
+	assert.Empty(client.dealsToRenew(types.NewBlockHeight(20000)))
+	assert.Error(client.SetDealRenewal(c, false, nil))
+}
+
+func TestProposeDealRejectsInvalidMinerSignature(t *testing.T) {
+	require := require.New(t)
+
+	testAPI := newTestClientAPI()
+	testNode := newTestClientNode(func(request interface{}) (interface{}, error) {
+		p := request.(*DealProposal)
+		pcid, err := convert.ToCid(p)
+		require.NoError(err)
+
+		// signed by the client rather than the miner's owner
+		sig, err := p.Terms().Sign(testAPI.signer, testAPI.payer)
+		require.NoError(err)
+		return &DealResponse{
+			State:       Accepted,
+			ProposalCid: pcid,
+			Signature:   sig,
+		}, nil
+	})
+
+	client, err := NewClient(testNode, testAPI, repo.NewInMemoryRepo().DealsDs)
+	require.NoError(err)
+
+	_, err = client.ProposeDeal(context.Background(), address.NewForTestGetter()(), types.SomeCid(), 0, 10000, false)
+	require.Error(err)
+	require.Contains(err.Error(), "invalid miner signature")
+}
+
 type clientTestAPI struct {
 	blockHeight *types.BlockHeight
 	channelID   *types.ChannelID
@@ -132,22 +346,31 @@ type clientTestAPI struct {
 	payer       address.Address
 	target      address.Address
 	perPayment  *types.AttoFIL
+	signer      types.MockSigner
+	config      *cfg.Config
 }
 
 func newTestClientAPI() *clientTestAPI {
 	cidGetter := types.NewCidForTestGetter()
-	addressGetter := address.NewForTestGetter()
+	ki := types.MustGenerateKeyInfo(2, types.GenerateKeyInfoSeed())
+	signer := types.NewMockSigner(ki)
 
 	return &clientTestAPI{
 		blockHeight: types.NewBlockHeight(773),
 		msgCid:      cidGetter(),
 		channelID:   types.NewChannelID(23),
-		payer:       addressGetter(),
-		target:      addressGetter(),
+		payer:       signer.Addresses[0],
+		target:      signer.Addresses[1],
 		perPayment:  types.NewAttoFILFromFIL(10),
+		signer:      signer,
+		config:      cfg.NewDefaultConfig(),
 	}
 }
 
+func (ctp *clientTestAPI) ConfigGet(dottedPath string) (interface{}, error) {
+	return ctp.config.Get(dottedPath)
+}
+
 func (ctp *clientTestAPI) ChainBlockHeight(ctx context.Context) (*types.BlockHeight, error) {
 	return ctp.blockHeight, nil
 }
@@ -173,6 +396,20 @@ func (ctp *clientTestAPI) CreatePayments(ctx context.Context, config porcelain.C
 	return resp, nil
 }
 
+func (ctp *clientTestAPI) MessageReceipts(ctx context.Context, ts types.TipSet) (map[cid.Cid]*types.MessageReceipt, error) {
+	receipts := make(map[cid.Cid]*types.MessageReceipt)
+	for _, blk := range ts {
+		for i, rcpt := range blk.MessageReceipts {
+			c, err := blk.Messages[i].Cid()
+			if err != nil {
+				return nil, err
+			}
+			receipts[c] = rcpt
+		}
+	}
+	return receipts, nil
+}
+
 func (ctp *clientTestAPI) MinerGetAsk(ctx context.Context, minerAddr address.Address, askID uint64) (miner.Ask, error) {
 	return miner.Ask{
 		Price:  types.NewAttoFILFromFIL(32),
@@ -182,7 +419,7 @@ func (ctp *clientTestAPI) MinerGetAsk(ctx context.Context, minerAddr address.Add
 }
 
 func (ctp *clientTestAPI) MinerGetOwnerAddress(ctx context.Context, minerAddr address.Address) (address.Address, error) {
-	return address.TestAddress, nil
+	return ctp.target, nil
 }
 
 func (ctp *clientTestAPI) MinerGetPeerID(ctx context.Context, minerAddr address.Address) (peer.ID, error) {
@@ -193,6 +430,10 @@ func (ctp *clientTestAPI) MinerGetPeerID(ctx context.Context, minerAddr address.
 	return id, nil
 }
 
+func (ctp *clientTestAPI) SignBytes(data []byte, addr address.Address) (types.Signature, error) {
+	return ctp.signer.SignBytes(data, addr)
+}
+
 func (ctp *clientTestAPI) GetAndMaybeSetDefaultSenderAddress() (address.Address, error) {
 	// always just default address
 	return ctp.payer, nil
@@ -200,6 +441,11 @@ func (ctp *clientTestAPI) GetAndMaybeSetDefaultSenderAddress() (address.Address,
 
 type testClientNode struct {
 	responder func(request interface{}) (interface{}, error)
+
+	// peers are the connected peers, peerResponder answers their asks
+	// queries if set.
+	peers         []peer.ID
+	peerResponder func(pid peer.ID, request interface{}) (interface{}, error)
 }
 
 func newTestClientNode(responder func(request interface{}) (interface{}, error)) *testClientNode {
@@ -216,12 +462,61 @@ func (tcn *testClientNode) GetFileSize(context.Context, cid.Cid) (uint64, error)
 	return 1000000000, nil
 }
 
+func (tcn *testClientNode) GetPieceCommitment(context.Context, cid.Cid) (proofs.CommP, error) {
+	return proofs.CommP{1, 2, 3}, nil
+}
+
+func (tcn *testClientNode) Peers() []peer.ID {
+	return tcn.peers
+}
+
 func (tcn *testClientNode) MakeProtocolRequest(ctx context.Context, protocol protocol.ID, peer peer.ID, request interface{}, response interface{}) error {
-	dealResponse := response.(*DealResponse)
-	res, err := tcn.responder(request)
+	respond := tcn.responder
+	if _, ok := request.(askRequest); ok && tcn.peerResponder != nil {
+		respond = func(request interface{}) (interface{}, error) {
+			return tcn.peerResponder(peer, request)
+		}
+	}
+	res, err := respond(request)
 	if err != nil {
 		return err
 	}
-	*dealResponse = *res.(*DealResponse)
+	switch r := response.(type) {
+	case *DealResponse:
+		*r = *res.(*DealResponse)
+	case *AskResponse:
+		*r = *res.(*AskResponse)
+	case *voucherDeliveryResponse:
+		*r = *res.(*voucherDeliveryResponse)
+	default:
+		return fmt.Errorf("unexpected response %T", response)
+	}
 	return nil
 }
+
+func TestProposeOfflineDeal(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	var proposal *DealProposal
+
+	testAPI := newTestClientAPI()
+	testNode := newTestClientNode(func(request interface{}) (interface{}, error) {
+		proposal = request.(*DealProposal)
+		pcid, err := convert.ToCid(proposal)
+		require.NoError(err)
+		sig, err := proposal.Terms().Sign(testAPI.signer, testAPI.target)
+		require.NoError(err)
+		return &DealResponse{State: Accepted, ProposalCid: pcid, Signature: sig}, nil
+	})
+
+	client, err := NewClient(testNode, testAPI, repo.NewInMemoryRepo().DealsDs)
+	require.NoError(err)
+
+	_, err = client.ProposeOfflineDeal(context.Background(), address.NewForTestGetter()(), types.SomeCid(), 67, 10000, false)
+	require.NoError(err)
+
+	assert.True(proposal.IsOffline())
+	commP := proofs.CommP{1, 2, 3}
+	assert.Equal(commP[:], proposal.CommP)
+}
@@ -0,0 +1,214 @@
+package storage
+
+import (
+	"context"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+
+	"github.com/filecoin-project/go-filecoin/actor/builtin/miner"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/protocol/datatransfer"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// DealEventsTopic is the topic on which the storage client publishes a
+// DealEvent for every change it observes in its deals.
+const DealEventsTopic = "deal-events"
+
+// DealEventType identifies what happened in a DealEvent.
+type DealEventType string
+
+const (
+	// DealAccepted is published when the miner accepts a proposal.
+	DealAccepted = DealEventType("accepted")
+	// DealRejected is published when the miner rejects a proposal.
+	DealRejected = DealEventType("rejected")
+	// DealTransfer is published when the transfer of the data to the miner
+	// starts, and every time the miner reports progress.
+	DealTransfer = DealEventType("transfer")
+	// DealSealed is published when the data was sealed into a sector and the
+	// miner starts proving it.
+	DealSealed = DealEventType("sealed")
+	// DealPoSted is published every time the miner of a deal being proven
+	// submits a proof of spacetime.
+	DealPoSted = DealEventType("posted")
+	// DealCompleted is published when the deal ends.
+	DealCompleted = DealEventType("completed")
+	// DealFailed is published when the deal fails.
+	DealFailed = DealEventType("failed")
+	// DealSlashed is published when the miner is slashed for failing to prove
+	// the storage of a deal's data.
+	// TODO: nothing publishes it until the storage market slashes miners.
+	DealSlashed = DealEventType("slashed")
+	// DealStateChanged is published when the deal moves to a state without a
+	// more specific event, e.g. when it starts sealing.
+	DealStateChanged = DealEventType("state")
+)
+
+// DealEvent describes a change to a deal of the storage client.
+type DealEvent struct {
+	Type        DealEventType   `json:"type"`
+	ProposalCid cid.Cid         `json:"proposalCid"`
+	Miner       address.Address `json:"miner"`
+	State       DealState       `json:"state"`
+	Message     string          `json:"message,omitempty"`
+	// Transfer is set for transfer events, if the miner reported progress.
+	Transfer *datatransfer.Status `json:"transfer,omitempty"`
+	// BlockHeight is set for posted events, it is the height of the block
+	// including the proof.
+	BlockHeight uint64 `json:"blockHeight,omitempty"`
+}
+
+// dealEventForState returns the type of the event published when a deal
+// moves to state s.
+func dealEventForState(s DealState) DealEventType {
+	switch s {
+	case Accepted:
+		return DealAccepted
+	case Rejected:
+		return DealRejected
+	case Transferring:
+		return DealTransfer
+	case Proving:
+		return DealSealed
+	case Complete:
+		return DealCompleted
+	case Failed:
+		return DealFailed
+	default:
+		return DealStateChanged
+	}
+}
+
+// Subscribe returns a channel on which every subsequent DealEvent is
+// delivered. The channel is closed once ctx is done.
+func (smc *Client) Subscribe(ctx context.Context) <-chan DealEvent {
+	sub := smc.events.Sub(DealEventsTopic)
+	out := make(chan DealEvent)
+
+	go func() {
+		defer close(out)
+		defer func() {
+			// Keep draining so that a publish in flight can't block the
+			// unsubscription.
+			go func() {
+				for range sub {
+				}
+			}()
+			smc.events.Unsub(sub)
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e, ok := <-sub:
+				if !ok {
+					return
+				}
+				select {
+				case out <- e.(DealEvent):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out
+}
+
+// publishResponse publishes the event for the response the miner sent for
+// the deal, if it changes the deal's state or reports transfer progress.
+// prev is the previous response, nil for new deals. smc.dealsLk must be held.
+func (smc *Client) publishResponse(deal *clientDeal, prev, resp *DealResponse) {
+	if prev != nil && prev.State == resp.State {
+		if resp.State != Transferring || resp.Transfer == nil {
+			return
+		}
+		if last, ok := smc.transferred[resp.ProposalCid]; ok && last == resp.Transfer.BytesReceived {
+			return
+		}
+	}
+	if resp.Transfer != nil {
+		smc.transferred[resp.ProposalCid] = resp.Transfer.BytesReceived
+	}
+	if resp.State.IsFinal() || resp.State == Proving {
+		delete(smc.transferred, resp.ProposalCid)
+	}
+
+	smc.events.Pub(DealEvent{
+		Type:        dealEventForState(resp.State),
+		ProposalCid: resp.ProposalCid,
+		Miner:       deal.Miner,
+		State:       resp.State,
+		Message:     resp.Message,
+		Transfer:    resp.Transfer,
+	}, DealEventsTopic)
+}
+
+// watchDeals queries the miners of the deals in progress for their state,
+// and publishes a posted event for the deals being proven by the miners that
+// submitted a proof of spacetime in ts.
+func (smc *Client) watchDeals(ts types.TipSet) {
+	height, err := ts.Height()
+	if err != nil {
+		log.Errorf("failed to get block height: %s", err)
+		return
+	}
+
+	posted := map[address.Address]bool{}
+	for _, blk := range ts.ToSlice() {
+		for _, receipt := range blk.MessageReceipts {
+			for _, e := range receipt.Events {
+				if e.Type == miner.EventPoStSubmitted {
+					posted[e.Actor] = true
+				}
+			}
+		}
+	}
+
+	smc.dealsLk.Lock()
+	var toQuery []cid.Cid
+	for c, deal := range smc.deals {
+		switch deal.Response.State {
+		case Accepted, Transferring, WaitingForData, Packing, Sealing:
+			if !smc.querying[c] {
+				smc.querying[c] = true
+				toQuery = append(toQuery, c)
+			}
+		case Proving:
+			if posted[deal.Miner] {
+				smc.events.Pub(DealEvent{
+					Type:        DealPoSted,
+					ProposalCid: c,
+					Miner:       deal.Miner,
+					State:       Proving,
+					BlockHeight: height,
+				}, DealEventsTopic)
+			}
+		}
+	}
+	smc.dealsLk.Unlock()
+
+	for _, c := range toQuery {
+		go smc.refreshDeal(c)
+	}
+}
+
+// refreshDeal queries the state of the deal with the given proposal cid, so
+// its changes are recorded and published.
+func (smc *Client) refreshDeal(c cid.Cid) {
+	defer func() {
+		smc.dealsLk.Lock()
+		delete(smc.querying, c)
+		smc.dealsLk.Unlock()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 4*smc.node.GetBlockTime())
+	defer cancel()
+
+	if _, err := smc.QueryDeal(ctx, c); err != nil {
+		log.Warningf("could not query deal %s: %s", c, err)
+	}
+}
@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/go-filecoin/actor/builtin/miner"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/protocol/datatransfer"
+	"github.com/filecoin-project/go-filecoin/repo"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/util/convert"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientPublishesDealEvents(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	queryResp := DealResponse{State: Transferring, Transfer: &datatransfer.Status{BytesReceived: 100, ExpectedBytes: 1000}}
+	testAPI := newTestClientAPI()
+	testNode := newTestClientNode(func(request interface{}) (interface{}, error) {
+		switch r := request.(type) {
+		case *DealProposal:
+			pcid, err := convert.ToCid(r)
+			require.NoError(err)
+			sig, err := r.Terms().Sign(testAPI.signer, testAPI.target)
+			require.NoError(err)
+			return &DealResponse{State: Accepted, ProposalCid: pcid, Signature: sig}, nil
+		case queryRequest:
+			resp := queryResp
+			resp.ProposalCid = r.Cid
+			return &resp, nil
+		}
+		return nil, fmt.Errorf("unexpected request %T", request)
+	})
+
+	client, err := NewClient(testNode, testAPI, repo.NewInMemoryRepo().DealsDs)
+	require.NoError(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := client.Subscribe(ctx)
+	next := func() DealEvent {
+		select {
+		case e := <-events:
+			return e
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for deal event")
+		}
+		return DealEvent{}
+	}
+
+	minerAddr := address.NewForTestGetter()()
+	dealResponse, err := client.ProposeDeal(ctx, minerAddr, types.SomeCid(), 0, 10000, false)
+	require.NoError(err)
+	c := dealResponse.ProposalCid
+
+	e := next()
+	assert.Equal(DealAccepted, e.Type)
+	assert.Equal(c, e.ProposalCid)
+	assert.Equal(minerAddr, e.Miner)
+
+	_, err = client.QueryDeal(ctx, c)
+	require.NoError(err)
+	e = next()
+	assert.Equal(DealTransfer, e.Type)
+	assert.Equal(uint64(100), e.Transfer.BytesReceived)
+
+	// no progress, no event
+	_, err = client.QueryDeal(ctx, c)
+	require.NoError(err)
+	queryResp.Transfer = &datatransfer.Status{BytesReceived: 1000, ExpectedBytes: 1000}
+	_, err = client.QueryDeal(ctx, c)
+	require.NoError(err)
+	e = next()
+	assert.Equal(DealTransfer, e.Type)
+	assert.Equal(uint64(1000), e.Transfer.BytesReceived)
+
+	queryResp = DealResponse{State: Proving}
+	_, err = client.QueryDeal(ctx, c)
+	require.NoError(err)
+	e = next()
+	assert.Equal(DealSealed, e.Type)
+	assert.Equal(Proving, e.State)
+
+	blk := &types.Block{
+		Height: 42,
+		MessageReceipts: []*types.MessageReceipt{{
+			Events: []*types.Event{{Actor: minerAddr, Method: "submitPoSt", Type: miner.EventPoStSubmitted}},
+		}},
+	}
+	client.watchDeals(types.RequireNewTipSet(require, blk))
+	e = next()
+	assert.Equal(DealPoSted, e.Type)
+	assert.Equal(c, e.ProposalCid)
+	assert.Equal(uint64(42), e.BlockHeight)
+}
+
+func TestDealEventForState(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	assert.Equal(DealAccepted, dealEventForState(Accepted))
+	assert.Equal(DealSealed, dealEventForState(Proving))
+	assert.Equal(DealFailed, dealEventForState(Failed))
+	assert.Equal(DealStateChanged, dealEventForState(Sealing))
+}
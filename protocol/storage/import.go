@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	uio "gx/ipfs/QmQXze9tG878pa4Euya4rrDpyTNX3kQe4dhCaBzBozGgpe/go-unixfs/io"
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	car "gx/ipfs/QmRa5sdhUGtLptMNYSHFWcU3axEJntpKht3LngrBpuurv1/go-car"
+	dag "gx/ipfs/QmTQdH4848iTVCJmKXYyRiK72HufWTLYQQ8iN3JaQ8K1Hq/go-merkledag"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	ipld "gx/ipfs/QmcKKBwfz6FyQdHR2jsXrrF6XeSBXYL86anmWNewpFpoF5/go-ipld-format"
+
+	"github.com/filecoin-project/go-filecoin/proofs"
+)
+
+// ImportDealData imports the data of the offline deal with the given proposal
+// cid from the CAR file read from r, e.g. shipped to the miner on a hard drive.
+// The data must match the piece commitment the client proposed. Once it is
+// imported, the deal proceeds to sealing without transferring data over the
+// network.
+func (sm *Miner) ImportDealData(ctx context.Context, proposalCid cid.Cid, r io.Reader) error {
+	d := sm.getStorageDeal(proposalCid)
+	if d == nil {
+		return fmt.Errorf("no such deal: %s", proposalCid)
+	}
+	if !d.Proposal.IsOffline() {
+		return errors.New("deal is not an offline deal, its data is transferred over the network")
+	}
+	if d.Response.State != WaitingForData {
+		return fmt.Errorf("deal is %s, not waiting for data", d.Response.State)
+	}
+
+	sm.dealsLk.Lock()
+	if sm.imported[proposalCid] {
+		sm.dealsLk.Unlock()
+		return errors.New("deal data was imported already")
+	}
+	sm.imported[proposalCid] = true
+	sm.dealsLk.Unlock()
+
+	if err := sm.importCar(ctx, d.Proposal, r); err != nil {
+		sm.dealsLk.Lock()
+		delete(sm.imported, proposalCid)
+		sm.dealsLk.Unlock()
+		return err
+	}
+
+	go sm.processStorageDeal(proposalCid)
+	return nil
+}
+
+// importCar loads the CAR file read from r into the miner's blockstore, and
+// checks it holds the data of the proposal.
+func (sm *Miner) importCar(ctx context.Context, p *DealProposal, r io.Reader) error {
+	header, err := car.LoadCar(sm.node.BlockService().Blockstore(), r)
+	if err != nil {
+		return errors.Wrap(err, "failed to load car file")
+	}
+
+	found := false
+	for _, root := range header.Roots {
+		if root.Equals(p.PieceRef) {
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("car file does not contain the deal's piece %s", p.PieceRef)
+	}
+
+	dserv := dag.NewDAGService(sm.node.BlockService())
+	size, err := getFileSize(ctx, p.PieceRef, dserv)
+	if err != nil {
+		return errors.Wrap(err, "failed to determine the size of the imported data")
+	}
+	if size != p.Size.Uint64() {
+		return fmt.Errorf("imported data has %d bytes, the deal is for %s", size, p.Size)
+	}
+
+	commP, err := getPieceCommitment(ctx, p.PieceRef, dserv)
+	if err != nil {
+		return errors.Wrap(err, "failed to compute the piece commitment of the imported data")
+	}
+	if !bytes.Equal(commP[:], p.CommP) {
+		return errors.New("imported data does not match the deal's piece commitment")
+	}
+	return nil
+}
+
+// getPieceCommitment computes the piece commitment of the file referenced by
+// c, as it is written to sectors.
+func getPieceCommitment(ctx context.Context, c cid.Cid, dserv ipld.DAGService) (proofs.CommP, error) {
+	root, err := dserv.Get(ctx, c)
+	if err != nil {
+		return proofs.CommP{}, err
+	}
+
+	r, err := uio.NewDagReader(ctx, root, dserv)
+	if err != nil {
+		return proofs.CommP{}, err
+	}
+
+	commP, _, _, err := proofs.GeneratePieceCommitment(r)
+	return commP, err
+}
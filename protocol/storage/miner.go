@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/big"
-	"math/rand"
 	"strconv"
 	"sync"
 	"time"
@@ -25,13 +24,16 @@ import (
 	"gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore/query"
 
 	"github.com/filecoin-project/go-filecoin/abi"
-	"github.com/filecoin-project/go-filecoin/actor/builtin/miner"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/paymentbroker"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/storagemarket"
 	"github.com/filecoin-project/go-filecoin/address"
 	cbu "github.com/filecoin-project/go-filecoin/cborutil"
 	"github.com/filecoin-project/go-filecoin/exec"
-	"github.com/filecoin-project/go-filecoin/proofs"
+	"github.com/filecoin-project/go-filecoin/filnet"
+	"github.com/filecoin-project/go-filecoin/plumbing/sctr"
 	"github.com/filecoin-project/go-filecoin/proofs/sectorbuilder"
+	"github.com/filecoin-project/go-filecoin/protocol/datatransfer"
+	"github.com/filecoin-project/go-filecoin/protocol/sealing"
 	"github.com/filecoin-project/go-filecoin/repo"
 	"github.com/filecoin-project/go-filecoin/types"
 	"github.com/filecoin-project/go-filecoin/util/convert"
@@ -43,10 +45,11 @@ const makeDealProtocol = protocol.ID("/fil/storage/mk/1.0.0")
 const queryDealProtocol = protocol.ID("/fil/storage/qry/1.0.0")
 
 // TODO: replace this with a queries to pick reasonable gas price and limits.
-const submitPostGasPrice = 0
-const submitPostGasLimit = 300
+const publishDealsGasPrice = 0
+const publishDealsGasLimit = 300
 
 const waitForPaymentChannelDuration = 2 * time.Minute
+const waitForDealPublicationDuration = 10 * time.Minute
 
 const minerDatastorePrefix = "miner"
 const dealsAwatingSealDatastorePrefix = "dealsAwaitingSeal"
@@ -61,11 +64,47 @@ type Miner struct {
 	dealsDs repo.Datastore
 	dealsLk sync.Mutex
 
-	postInProcessLk sync.Mutex
-	postInProcess   *types.BlockHeight
+	// prover generates and submits the PoSts of the miner.
+	prover *Prover
 
 	dealsAwaitingSeal *dealsAwaitingSealStruct
 
+	// flaggedSectors holds the sectors found missing or corrupt, see
+	// CheckSectors.
+	flaggedSectors map[uint64]SectorCheck
+	flaggedLk      sync.Mutex
+
+	// pledgedSectors holds the pledged sectors which are not committed yet,
+	// see PledgeSectors. pledging is set while sectors are pledged to reach
+	// the target power.
+	pledgedSectors map[uint64]bool
+	pledging       bool
+	pledgedLk      sync.Mutex
+
+	// scrub holds the state of scrubbing the sealed sectors, see Scrub.
+	// scrubbing is set while a scrub runs.
+	scrub     scrubState
+	scrubbing bool
+	scrubLk   sync.Mutex
+
+	// packer packs the pieces of deals into sectors if mining.packing is
+	// enabled, see packPiece. packedDeals maps the pieces in the packer to
+	// their deals. stagingLk keeps the pieces of different bins of the
+	// packer from being staged at once.
+	packer      *sectorbuilder.Packer
+	packedDeals map[*sectorbuilder.PieceInfo]cid.Cid
+	packedLk    sync.Mutex
+	stagingLk   sync.Mutex
+
+	// transfers receives the data of deals from clients.
+	transfers *datatransfer.Manager
+
+	// imported holds the offline deals whose data was imported.
+	imported map[cid.Cid]bool
+
+	// redeeming holds the deals with a voucher redemption in flight.
+	redeeming map[cid.Cid]bool
+
 	porcelainAPI minerPorcelain
 	node         node
 
@@ -76,6 +115,15 @@ type Miner struct {
 type storageDeal struct {
 	Proposal *DealProposal
 	Response *DealResponse
+	// History lists the states the deal went through.
+	History []DealTransition
+	// Expiry is the block height the deal ends at, set once the deal is
+	// published.
+	Expiry *types.BlockHeight
+	// Vouchers are the vouchers the client sent after the proposal.
+	Vouchers []*paymentbroker.PaymentVoucher
+	// Redeemed is the amount the miner redeemed from the deal's vouchers.
+	Redeemed *types.AttoFIL
 }
 
 // minerPorcelain is the subset of the porcelain API that storage.Miner needs.
@@ -86,6 +134,8 @@ type minerPorcelain interface {
 	MessageSend(ctx context.Context, from, to address.Address, value *types.AttoFIL, gasPrice types.AttoFIL, gasLimit types.GasUnits, method string, params ...interface{}) (cid.Cid, error)
 	MessageQuery(ctx context.Context, optFrom, to address.Address, method string, params ...interface{}) ([][]byte, *exec.FunctionSignature, error)
 	MessageWait(ctx context.Context, msgCid cid.Cid, cb func(*types.Block, *types.SignedMessage, *types.MessageReceipt) error) error
+
+	SignBytes(data []byte, addr address.Address) (types.Signature, error)
 }
 
 // node is subset of node on which this protocol depends. These deps
@@ -97,15 +147,11 @@ type node interface {
 	BlockService() bserv.BlockService
 	Host() host.Host
 	SectorBuilder() sectorbuilder.SectorBuilder
-}
-
-// generatePostInput is a struct containing sector id and related commitments
-// used to generate a proof-of-spacetime
-type generatePostInput struct {
-	commD     proofs.CommD
-	commR     proofs.CommR
-	commRStar proofs.CommRStar
-	sectorID  uint64
+	SealingScheduler() *sectorbuilder.Scheduler
+	SealingMaster() *sealing.Master
+	SectorProgress() *sctr.Tracker
+	SectorDir(kind sectorbuilder.PathKind) string
+	FetchSessions() *filnet.FetchSessions
 }
 
 func init() {
@@ -119,6 +165,9 @@ func NewMiner(ctx context.Context, minerAddr, minerOwnerAddr address.Address, nd
 		minerAddr:        minerAddr,
 		minerOwnerAddr:   minerOwnerAddr,
 		deals:            make(map[cid.Cid]*storageDeal),
+		imported:         make(map[cid.Cid]bool),
+		redeeming:        make(map[cid.Cid]bool),
+		packedDeals:      make(map[*sectorbuilder.PieceInfo]cid.Cid),
 		porcelainAPI:     porcelainAPI,
 		dealsDs:          dealsDs,
 		node:             nd,
@@ -126,18 +175,50 @@ func NewMiner(ctx context.Context, minerAddr, minerOwnerAddr address.Address, nd
 		proposalRejector: rejectProposal,
 	}
 
+	transfers, err := datatransfer.NewManager(nd.FetchSessions().NodeGetter(filnet.PieceTransferSession), dealsDs)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create data transfer manager when creating miner")
+	}
+	sm.transfers = transfers
+	sm.prover = NewProver(minerAddr, minerOwnerAddr, porcelainAPI, nd, sm.sectorCommitments)
+
+	maxBandwidth, err := porcelainAPI.ConfigGet("dataTransfer.maxBandwidth")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get max transfer bandwidth")
+	}
+	bytesPerSecond, ok := maxBandwidth.(uint64)
+	if !ok {
+		return nil, errors.New("could not retrieve maxBandwidth from config")
+	}
+	sm.transfers.SetBandwidth(bytesPerSecond)
+
 	if err := sm.loadDealsAwaitingSeal(); err != nil {
 		return nil, errors.Wrap(err, "failed to load dealAwaitingSeal when creating miner")
 	}
 	sm.dealsAwaitingSeal.onSuccess = sm.onCommitSuccess
 	sm.dealsAwaitingSeal.onFail = sm.onCommitFail
 
+	if err := sm.loadFlaggedSectors(); err != nil {
+		return nil, errors.Wrap(err, "failed to load flagged sectors when creating miner")
+	}
+
+	if err := sm.loadPledgedSectors(); err != nil {
+		return nil, errors.Wrap(err, "failed to load pledged sectors when creating miner")
+	}
+
+	if err := sm.loadScrubState(); err != nil {
+		return nil, errors.Wrap(err, "failed to load scrub state when creating miner")
+	}
+
 	if err := sm.loadDeals(); err != nil {
 		return nil, errors.Wrap(err, "failed to load miner deals when creating miner")
 	}
+	sm.resumeDeals()
 
 	nd.Host().SetStreamHandler(makeDealProtocol, sm.handleMakeDeal)
 	nd.Host().SetStreamHandler(queryDealProtocol, sm.handleQueryDeal)
+	nd.Host().SetStreamHandler(queryAsksProtocol, sm.handleQueryAsks)
+	nd.Host().SetStreamHandler(deliverVouchersProtocol, sm.handleDeliverVouchers)
 
 	return sm, nil
 }
@@ -165,7 +246,17 @@ func (sm *Miner) handleMakeDeal(s inet.Stream) {
 
 // receiveStorageProposal is the entry point for the miner storage protocol
 func (sm *Miner) receiveStorageProposal(ctx context.Context, p *DealProposal) (*DealResponse, error) {
-	// TODO: Check signature
+	if p.MinerAddress != sm.minerAddr {
+		return sm.proposalRejector(ctx, sm, p, fmt.Sprintf("proposal is for miner %s", p.MinerAddress))
+	}
+
+	if !p.Terms().VerifySignature(p.Payment.Payer, p.Signature) {
+		return sm.proposalRejector(ctx, sm, p, "invalid client signature")
+	}
+
+	if err := sm.checkDealPolicy(p); err != nil {
+		return sm.proposalRejector(ctx, sm, p, err.Error())
+	}
 
 	if err := sm.validateDealPayment(ctx, p); err != nil {
 		return sm.proposalRejector(ctx, sm, p, err.Error())
@@ -252,14 +343,10 @@ func (sm *Miner) validateDealPayment(ctx context.Context, p *DealProposal) error
 		lastValidAt = &v.ValidAt
 	}
 
-	// confirm last voucher value is for full amount
-	lastVoucher := p.Payment.Vouchers[len(p.Payment.Vouchers)-1]
-	if lastVoucher.Amount.LessThan(p.TotalPrice) {
-		return fmt.Errorf("last payment (%s) does not cover total price (%s)", lastVoucher.Amount.String(), p.TotalPrice.String())
-	}
-
-	// require channel expires at or after last voucher + ChannelExpiryInterval
-	expectedEol := lastVoucher.ValidAt.Add(types.NewBlockHeight(ChannelExpiryInterval))
+	// The proposal only carries the first payments, the client sends the
+	// following vouchers as the deal progresses (see receiveVouchers). Require
+	// the channel to stay open until the last of them can be redeemed.
+	expectedEol := blockHeight.Add(types.NewBlockHeight(p.Duration + ChannelExpiryInterval))
 	if channel.Eol.LessThan(expectedEol) {
 		return fmt.Errorf("payment channel eol (%s) less than required eol (%s)", channel.Eol, expectedEol)
 	}
@@ -324,10 +411,16 @@ func acceptProposal(ctx context.Context, sm *Miner, p *DealProposal) (*DealRespo
 		return nil, errors.Wrap(err, "failed to get cid of proposal")
 	}
 
+	sig, publishCid, err := sm.publishDeal(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
 	resp := &DealResponse{
-		State:       Accepted,
-		ProposalCid: proposalCid,
-		Signature:   types.Signature("signaturrreee"),
+		State:          Accepted,
+		ProposalCid:    proposalCid,
+		Signature:      sig,
+		PublishMessage: &publishCid,
 	}
 
 	sm.dealsLk.Lock()
@@ -336,6 +429,7 @@ func acceptProposal(ctx context.Context, sm *Miner, p *DealProposal) (*DealRespo
 	sm.deals[proposalCid] = &storageDeal{
 		Proposal: p,
 		Response: resp,
+		History:  []DealTransition{{State: Accepted, Time: time.Now().Unix()}},
 	}
 	if err := sm.saveDeal(proposalCid); err != nil {
 		sm.deals[proposalCid].Response.State = Failed
@@ -359,7 +453,6 @@ func rejectProposal(ctx context.Context, sm *Miner, p *DealProposal, reason stri
 		State:       Rejected,
 		ProposalCid: proposalCid,
 		Message:     reason,
-		Signature:   types.Signature("signaturrreee"),
 	}
 
 	sm.dealsLk.Lock()
@@ -368,6 +461,7 @@ func rejectProposal(ctx context.Context, sm *Miner, p *DealProposal, reason stri
 	sm.deals[proposalCid] = &storageDeal{
 		Proposal: p,
 		Response: resp,
+		History:  []DealTransition{{State: Rejected, Message: reason, Time: time.Now().Unix()}},
 	}
 	if err := sm.saveDeal(proposalCid); err != nil {
 		return nil, errors.Wrap(err, "failed to save miner deal")
@@ -376,51 +470,195 @@ func rejectProposal(ctx context.Context, sm *Miner, p *DealProposal, reason stri
 	return resp, nil
 }
 
+// publishDeal counter-signs the terms of p and sends the message publishing
+// the deal to the storage market. It returns the counter-signature and the cid
+// of the message.
+func (sm *Miner) publishDeal(ctx context.Context, p *DealProposal) (types.Signature, cid.Cid, error) {
+	terms := p.Terms()
+	sig, err := terms.Sign(sm.porcelainAPI, sm.minerOwnerAddr)
+	if err != nil {
+		return nil, cid.Undef, errors.Wrap(err, "failed to counter-sign proposal")
+	}
+
+	deals, err := cbor.DumpObject([]storagemarket.StorageDeal{{
+		Proposal:        *terms,
+		ClientSignature: p.Signature,
+		MinerSignature:  sig,
+	}})
+	if err != nil {
+		return nil, cid.Undef, errors.Wrap(err, "failed to encode deal")
+	}
+
+	// TODO: algorithmically determine appropriate values for these
+	gasPrice := types.NewGasPrice(publishDealsGasPrice)
+	gasLimit := types.NewGasUnits(publishDealsGasLimit)
+
+	msgCid, err := sm.porcelainAPI.MessageSend(ctx, sm.minerOwnerAddr, address.StorageMarketAddress, types.ZeroAttoFIL, gasPrice, gasLimit, "publishStorageDeals", deals)
+	if err != nil {
+		return nil, cid.Undef, errors.Wrap(err, "failed to publish deal")
+	}
+	return sig, msgCid, nil
+}
+
+// waitForDealPublication waits for the message publishing a deal to be mined
+// and returns the id of the deal.
+func (sm *Miner) waitForDealPublication(ctx context.Context, msgCid cid.Cid) (uint64, error) {
+	ctx, cancel := context.WithTimeout(ctx, waitForDealPublicationDuration)
+	defer cancel()
+
+	var dealID uint64
+	err := sm.porcelainAPI.MessageWait(ctx, msgCid, func(blk *types.Block, smsg *types.SignedMessage, receipt *types.MessageReceipt) error {
+		if receipt.ExitCode != uint8(0) {
+			return fmt.Errorf("publishStorageDeals failed with exit code %d: %s", receipt.ExitCode, receipt.RevertReason)
+		}
+		ids, err := abi.Deserialize(receipt.Return[0], abi.UintArray)
+		if err != nil {
+			return errors.Wrap(err, "could not decode deal ids")
+		}
+		dealID = ids.Val.([]uint64)[0]
+		return nil
+	})
+	return dealID, err
+}
+
 func (sm *Miner) getStorageDeal(c cid.Cid) *storageDeal {
 	sm.dealsLk.Lock()
 	defer sm.dealsLk.Unlock()
 	return sm.deals[c]
 }
 
-func (sm *Miner) updateDealResponse(proposalCid cid.Cid, f func(*DealResponse)) error {
+// Deal returns the record of the deal with the given proposal cid.
+func (sm *Miner) Deal(proposalCid cid.Cid) (*DealRecord, error) {
+	sm.dealsLk.Lock()
+	defer sm.dealsLk.Unlock()
+	d, ok := sm.deals[proposalCid]
+	if !ok {
+		return nil, fmt.Errorf("no such deal: %s", proposalCid)
+	}
+
+	return &DealRecord{
+		ProposalCid: proposalCid,
+		Role:        MinerRole,
+		Proposal:    d.Proposal,
+		Response:    d.Response,
+		History:     append([]DealTransition{}, d.History...),
+	}, nil
+}
+
+// SealedPiece returns the id of the sector the piece with the given cid was
+// sealed in, and the size of the piece, if a deal of the miner stores it.
+func (sm *Miner) SealedPiece(pieceRef cid.Cid) (uint64, uint64, error) {
 	sm.dealsLk.Lock()
 	defer sm.dealsLk.Unlock()
-	f(sm.deals[proposalCid].Response)
-	err := sm.saveDeal(proposalCid)
+	for _, d := range sm.deals {
+		if !d.Proposal.PieceRef.Equals(pieceRef) || d.Response.ProofInfo == nil {
+			continue
+		}
+		return d.Response.ProofInfo.SectorID, d.Proposal.Size.Uint64(), nil
+	}
+	return 0, 0, fmt.Errorf("no sealed sector stores piece %s", pieceRef)
+}
+
+// LivePieces returns the pieces of the miner's deals which are not sealed
+// yet, whose data the miner needs until they are.
+func (sm *Miner) LivePieces() []cid.Cid {
+	sm.dealsLk.Lock()
+	defer sm.dealsLk.Unlock()
+
+	var pieces []cid.Cid
+	seen := make(map[cid.Cid]bool)
+	for _, d := range sm.deals {
+		state := d.Response.State
+		if state.IsFinal() || state == Proving || seen[d.Proposal.PieceRef] {
+			continue
+		}
+		seen[d.Proposal.PieceRef] = true
+		pieces = append(pieces, d.Proposal.PieceRef)
+	}
+	return pieces
+}
+
+// updateDeal applies f to the deal and persists it.
+func (sm *Miner) updateDeal(proposalCid cid.Cid, f func(*storageDeal) error) error {
+	sm.dealsLk.Lock()
+	defer sm.dealsLk.Unlock()
+	if err := f(sm.deals[proposalCid]); err != nil {
+		return err
+	}
+	if err := sm.saveDeal(proposalCid); err != nil {
+		return errors.Wrap(err, "failed to store updated deal in datastore")
+	}
+	return nil
+}
+
+// updateDealResponse applies f to the response of the deal, recording the
+// transition if f changes the state of the deal. It fails if deals can't move
+// to the new state.
+func (sm *Miner) updateDealResponse(proposalCid cid.Cid, f func(*DealResponse)) error {
+	err := sm.updateDeal(proposalCid, func(d *storageDeal) error {
+		prev := d.Response.State
+		f(d.Response)
+		history, err := moveDeal(prev, d.Response, d.History)
+		if err != nil {
+			return err
+		}
+		d.History = history
+		return nil
+	})
 	if err != nil {
-		return errors.Wrap(err, "failed to store updated deal response in datastore")
+		return err
 	}
 
-	log.Debugf("Miner.updateDealResponse(%s) - %d", proposalCid.String(), sm.deals[proposalCid].Response)
+	log.Debugf("Miner.updateDealResponse(%s) - %s", proposalCid.String(), sm.getStorageDeal(proposalCid).Response.State)
 	return nil
 }
 
-func (sm *Miner) processStorageDeal(c cid.Cid) {
-	log.Debugf("Miner.processStorageDeal(%s)", c.String())
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+// resumeDeals continues processing the deals whose processing a restart of
+// the miner interrupted. Deals being sealed resume when the sector is
+// committed, see dealsAwaitingSeal.
+func (sm *Miner) resumeDeals() {
+	sm.dealsLk.Lock()
+	defer sm.dealsLk.Unlock()
+	for c, d := range sm.deals {
+		switch d.Response.State {
+		case Accepted, Transferring, Packing:
+			log.Infof("resuming %s deal %s", d.Response.State, c)
+			go sm.processStorageDeal(c)
+		}
+	}
+}
 
-	d := sm.getStorageDeal(c)
-	if d.Response.State != Accepted {
-		// TODO: handle resumption of deal processing across miner restarts
-		log.Error("attempted to process an already started deal")
-		return
+// completeDeals moves the deals being proven whose term ended at or before
+// height h to the Complete state.
+func (sm *Miner) completeDeals(h *types.BlockHeight) {
+	sm.dealsLk.Lock()
+	var ended []cid.Cid
+	for c, d := range sm.deals {
+		if d.Response.State == Proving && d.Expiry != nil && d.Expiry.LessEqual(h) {
+			ended = append(ended, c)
+		}
 	}
+	sm.dealsLk.Unlock()
 
-	// 'Receive' the data, this could also be a truck full of hard drives. (TODO: proper abstraction)
-	// TODO: this is not a great way to do this. At least use a session
-	// Also, this needs to be fetched into a staging area for miners to prepare and seal in data
-	log.Debug("Miner.processStorageDeal - FetchGraph")
-	if err := dag.FetchGraph(ctx, d.Proposal.PieceRef, dag.NewDAGService(sm.node.BlockService())); err != nil {
-		log.Errorf("failed to fetch data: %s", err)
+	for _, c := range ended {
 		err := sm.updateDealResponse(c, func(resp *DealResponse) {
-			resp.Message = "Transfer failed"
-			resp.State = Failed
-			// TODO: signature?
+			resp.State = Complete
 		})
 		if err != nil {
-			log.Errorf("could not update to deal to 'Failed' state: %s", err)
+			log.Errorf("could not update deal %s to 'Complete' state: %s", c, err)
 		}
+	}
+}
+
+func (sm *Miner) processStorageDeal(c cid.Cid) {
+	log.Debugf("Miner.processStorageDeal(%s)", c.String())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	d := sm.getStorageDeal(c)
+	state := d.Response.State
+	if state != Accepted && state != Transferring && state != WaitingForData && state != Packing {
+		log.Errorf("attempted to process deal in state %s", state)
 		return
 	}
 
@@ -435,11 +673,96 @@ func (sm *Miner) processStorageDeal(c cid.Cid) {
 		}
 	}
 
+	// Only transfer the data once both parties have a record of the deal on
+	// chain. Deals resumed in the Transferring state are published already.
+	if state == Accepted {
+		if d.Response.PublishMessage == nil {
+			fail("deal was never published", "accepted deal has no publish message")
+			return
+		}
+
+		log.Debug("Miner.processStorageDeal - waitForDealPublication")
+		dealID, err := sm.waitForDealPublication(ctx, *d.Response.PublishMessage)
+		if err != nil {
+			fail("failed to publish deal", fmt.Sprintf("failed to publish deal: %s", err))
+			return
+		}
+
+		height, err := sm.porcelainAPI.ChainBlockHeight(ctx)
+		if err != nil {
+			fail("internal error", fmt.Sprintf("failed to get block height: %s", err))
+			return
+		}
+
+		err = sm.updateDeal(c, func(d *storageDeal) error {
+			d.Expiry = height.Add(types.NewBlockHeight(d.Proposal.Duration))
+			return nil
+		})
+		if err != nil {
+			log.Errorf("could not record deal expiry: %s", err)
+		}
+		next := Transferring
+		if d.Proposal.IsOffline() {
+			next = WaitingForData
+		}
+		err = sm.updateDealResponse(c, func(resp *DealResponse) {
+			resp.DealID = dealID
+			resp.State = next
+		})
+		if err != nil {
+			log.Errorf("could not update to '%s': %s", next, err)
+		}
+
+		// the data of offline deals is processed once it is imported, see
+		// ImportDealData
+		if next == WaitingForData {
+			log.Infof("deal %s is waiting for its data to be imported", c)
+			return
+		}
+	}
+
+	// 'Receive' the data, this could also be a truck full of hard drives. (TODO: proper abstraction)
+	// TODO: this needs to be fetched into a staging area for miners to prepare and seal in data
+	if state == Accepted || state == Transferring {
+		log.Debug("Miner.processStorageDeal - Pull")
+		if err := sm.transfers.Pull(ctx, c, d.Proposal.PieceRef, d.Proposal.Size.Uint64()); err != nil {
+			fail("Transfer failed", fmt.Sprintf("failed to fetch data: %s", err))
+			return
+		}
+	}
+
 	pi := &sectorbuilder.PieceInfo{
 		Ref:  d.Proposal.PieceRef,
 		Size: d.Proposal.Size.Uint64(),
 	}
 
+	// Hand the piece to a remote sealing worker if there is one with spare
+	// capacity, sealing it locally otherwise.
+	if master := sm.node.SealingMaster(); master != nil {
+		sealed, err := sm.sealRemotely(ctx, master, c, pi)
+		if err != nil {
+			fail("failed to submit seal proof", fmt.Sprintf("failed to seal piece remotely: %s", err))
+			return
+		}
+		if sealed {
+			return
+		}
+	}
+
+	// Pack the piece with the pieces of other deals into a sector if packing
+	// is enabled, staging it as it arrives otherwise.
+	packing, err := sm.packingConfig()
+	if err != nil {
+		fail("internal error", err.Error())
+		return
+	}
+	if packing.Enabled {
+		if err := sm.packPiece(c, pi); err != nil {
+			fail("failed to submit seal proof", fmt.Sprintf("failed to pack piece: %s", err))
+		}
+		return
+	}
+
 	// There is a race here that requires us to use dealsAwaitingSeal below. If the
 	// sector gets sealed and OnCommitmentAddedToChain is called right after
 	// AddPiece returns but before we record the sector/deal mapping we might
@@ -449,27 +772,60 @@ func (sm *Miner) processStorageDeal(c cid.Cid) {
 	//
 	// Also, this pattern of not being able to set up book-keeping ahead of
 	// the call is inelegant.
-	sectorID, err := sm.node.SectorBuilder().AddPiece(ctx, pi)
+	var sectorID uint64
+	err = sm.node.SealingScheduler().Do(ctx, sectorbuilder.AddPieceStage, fmt.Sprintf("add piece %s of deal %s", pi.Ref, c), func() error {
+		var err error
+		sectorID, err = sm.node.SectorBuilder().AddPiece(ctx, pi)
+		return err
+	})
 	if err != nil {
 		fail("failed to submit seal proof", fmt.Sprintf("failed to add piece: %s", err))
 		return
 	}
+	sm.node.SectorProgress().Enter(sectorID, sctr.Staged)
 
 	err = sm.updateDealResponse(c, func(resp *DealResponse) {
-		resp.State = Staged
+		resp.State = Sealing
 	})
 	if err != nil {
-		log.Errorf("could update to 'Staged': %s", err)
+		log.Errorf("could not update to 'Sealing': %s", err)
 	}
 
 	// Careful: this might update state to success or failure so it should go after
-	// updating state to Staged.
+	// updating state to Sealing.
 	sm.dealsAwaitingSeal.add(sectorID, c)
 	if err := sm.saveDealsAwaitingSeal(); err != nil {
 		log.Errorf("could not save deal awaiting seal: %s", err)
 	}
 }
 
+// sealRemotely has a worker of the master seal the piece of the deal into a
+// sector, returning false if no worker has spare capacity.
+func (sm *Miner) sealRemotely(ctx context.Context, master *sealing.Master, c cid.Cid, pi *sectorbuilder.PieceInfo) (bool, error) {
+	err := sm.updateDealResponse(c, func(resp *DealResponse) {
+		resp.State = Sealing
+	})
+	if err != nil {
+		log.Errorf("could not update to 'Sealing': %s", err)
+	}
+
+	sector, err := master.Seal(ctx, pi)
+	if err == sealing.ErrNoWorker {
+		log.Infof("no sealing worker available for deal %s, sealing locally", c)
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	sm.node.SectorProgress().Enter(sector.SectorID, sctr.Sealing)
+	sm.dealsAwaitingSeal.add(sector.SectorID, c)
+	if err := sm.saveDealsAwaitingSeal(); err != nil {
+		log.Errorf("could not save deal awaiting seal: %s", err)
+	}
+	return true, nil
+}
+
 // dealsAwaitingSealStruct is a container for keeping track of which sectors have
 // pieces from which deals. We need it to accommodate a race condition where
 // a sector commit message is added to chain before we can add the sector/deal
@@ -584,6 +940,7 @@ func (sm *Miner) OnCommitmentAddedToChain(sector *sectorbuilder.SealedSectorMeta
 	} else {
 		sm.dealsAwaitingSeal.success(sector)
 	}
+	sm.onPledgedSectorDone(sectorID)
 	if err := sm.saveDealsAwaitingSeal(); err != nil {
 		errMsg := fmt.Sprintf("failed persisting deals awaiting seal: %s", err)
 		log.Error(errMsg)
@@ -593,7 +950,7 @@ func (sm *Miner) OnCommitmentAddedToChain(sector *sectorbuilder.SealedSectorMeta
 
 func (sm *Miner) onCommitSuccess(dealCid cid.Cid, sector *sectorbuilder.SealedSectorMetadata) {
 	err := sm.updateDealResponse(dealCid, func(resp *DealResponse) {
-		resp.State = Posted
+		resp.State = Proving
 		resp.ProofInfo = &ProofInfo{
 			SectorID: sector.SectorID,
 			CommR:    sector.CommR[:],
@@ -601,7 +958,7 @@ func (sm *Miner) onCommitSuccess(dealCid cid.Cid, sector *sectorbuilder.SealedSe
 		}
 	})
 	if err != nil {
-		log.Errorf("commit succeeded but could not update to deal 'Posted' state: %s", err)
+		log.Errorf("commit succeeded but could not update to deal 'Proving' state: %s", err)
 	}
 }
 
@@ -618,6 +975,24 @@ func (sm *Miner) onCommitFail(dealCid cid.Cid, message string) {
 func (sm *Miner) OnNewHeaviestTipSet(ts types.TipSet) {
 	ctx := context.Background()
 
+	height, err := ts.Height()
+	if err != nil {
+		log.Errorf("failed to get block height: %s", err)
+		return
+	}
+	h := types.NewBlockHeight(height)
+
+	sm.completeDeals(h)
+	sm.redeemVouchers(h)
+	sm.prover.OnNewHead(ctx, h)
+	sm.pledgeToTarget(ctx)
+	sm.scrubIfDue(ctx)
+	sm.sealPacked()
+}
+
+// sectorCommitments returns the commitments of the sectors the miner committed
+// on chain, keyed by sector id.
+func (sm *Miner) sectorCommitments(ctx context.Context) (map[uint64]types.Commitments, error) {
 	rets, sig, err := sm.porcelainAPI.MessageQuery(
 		ctx,
 		address.Address{},
@@ -625,164 +1000,33 @@ func (sm *Miner) OnNewHeaviestTipSet(ts types.TipSet) {
 		"getSectorCommitments",
 	)
 	if err != nil {
-		log.Errorf("failed to call query method getSectorCommitments: %s", err)
-		return
+		return nil, errors.Wrap(err, "failed to call query method getSectorCommitments")
 	}
 
 	commitmentsVal, err := abi.Deserialize(rets[0], sig.Return[0])
 	if err != nil {
-		log.Errorf("failed to convert returned ABI value: %s", err)
-		return
+		return nil, errors.Wrap(err, "failed to convert returned ABI value")
 	}
 
 	commitments, ok := commitmentsVal.Val.(map[string]types.Commitments)
 	if !ok {
-		log.Errorf("failed to convert returned ABI value to miner.Commitments")
-		return
+		return nil, errors.New("failed to convert returned ABI value to miner.Commitments")
 	}
 
-	var inputs []generatePostInput
+	sectors := make(map[uint64]types.Commitments)
 	for k, v := range commitments {
 		n, err := strconv.ParseUint(k, 10, 64)
 		if err != nil {
-			log.Errorf("failed to parse commitment sector id to uint64: %s", err)
-			return
+			return nil, errors.Wrap(err, "failed to parse commitment sector id to uint64")
 		}
-
-		inputs = append(inputs, generatePostInput{
-			commD:     v.CommD,
-			commR:     v.CommR,
-			commRStar: v.CommRStar,
-			sectorID:  n,
-		})
-	}
-
-	if len(inputs) == 0 {
-		// no sector sealed, nothing to do
-		return
-	}
-
-	provingPeriodStart, err := sm.getProvingPeriodStart()
-	if err != nil {
-		log.Errorf("failed to get provingPeriodStart: %s", err)
-		return
-	}
-
-	sm.postInProcessLk.Lock()
-	defer sm.postInProcessLk.Unlock()
-
-	if sm.postInProcess != nil && sm.postInProcess.Equal(provingPeriodStart) {
-		// post is already being generated for this period, nothing to do
-		return
+		sectors[n] = v
 	}
-
-	height, err := ts.Height()
-	if err != nil {
-		log.Errorf("failed to get block height: %s", err)
-		return
-	}
-
-	h := types.NewBlockHeight(height)
-	provingPeriodEnd := provingPeriodStart.Add(miner.ProvingPeriodBlocks)
-
-	if h.GreaterEqual(provingPeriodStart) {
-		if h.LessThan(provingPeriodEnd) {
-			// we are in a new proving period, lets get this post going
-			sm.postInProcess = provingPeriodStart
-			go sm.submitPoSt(provingPeriodStart, provingPeriodEnd, inputs)
-		} else {
-			// we are too late
-			// TODO: figure out faults and payments here
-			log.Errorf("too late start=%s  end=%s current=%s", provingPeriodStart, provingPeriodEnd, h)
-		}
-	}
-}
-
-func (sm *Miner) getProvingPeriodStart() (*types.BlockHeight, error) {
-	res, _, err := sm.porcelainAPI.MessageQuery(
-		context.Background(),
-		address.Address{},
-		sm.minerAddr,
-		"getProvingPeriodStart",
-	)
-	if err != nil {
-		return nil, err
-	}
-
-	return types.NewBlockHeightFromBytes(res[0]), nil
-}
-
-// generatePoSt creates the required PoSt, given a list of sector ids and
-// matching seeds. It returns the Snark Proof for the PoSt, and a list of
-// sectors that faulted, if there were any faults.
-func (sm *Miner) generatePoSt(commRs []proofs.CommR, challenge proofs.PoStChallengeSeed) (proofs.PoStProof, []uint64, error) {
-	req := sectorbuilder.GeneratePoSTRequest{
-		CommRs:        commRs,
-		ChallengeSeed: challenge,
-	}
-	res, err := sm.node.SectorBuilder().GeneratePoST(req)
-	if err != nil {
-		return proofs.PoStProof{}, nil, errors.Wrap(err, "failed to generate PoSt")
-	}
-
-	return res.Proof, res.Faults, nil
+	return sectors, nil
 }
 
-func (sm *Miner) submitPoSt(start, end *types.BlockHeight, inputs []generatePostInput) {
-	// TODO: real seed generation
-	seed := proofs.PoStChallengeSeed{}
-	if _, err := rand.Read(seed[:]); err != nil {
-		panic(err)
-	}
-
-	commRs := make([]proofs.CommR, len(inputs))
-	for i, input := range inputs {
-		commRs[i] = input.commR
-	}
-
-	proof, faults, err := sm.generatePoSt(commRs, seed)
-	if err != nil {
-		log.Errorf("failed to generate PoSts: %s", err)
-		return
-	}
-	if len(faults) != 0 {
-		log.Warningf("some faults when generating PoSt: %v", faults)
-		// TODO: proper fault handling
-	}
-
-	height, err := sm.node.BlockHeight()
-	if err != nil {
-		log.Errorf("failed to submit PoSt, as the current block height can not be determined: %s", err)
-		// TODO: what should happen in this case?
-		return
-	}
-	if height.LessThan(start) {
-		// TODO: what to do here? not sure this can happen, maybe through reordering?
-		log.Errorf("PoSt generation time took negative block time: %s < %s", height, start)
-		return
-	}
-
-	if height.GreaterEqual(end) {
-		// TODO: we are too late, figure out faults and decide if we want to still submit
-		log.Errorf("PoSt generation was too slow height=%s end=%s", height, end)
-		return
-	}
-
-	// TODO: figure out a more sensible timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
-	defer cancel()
-
-	// TODO: algorithmically determine appropriate values for these
-	gasPrice := types.NewGasPrice(submitPostGasPrice)
-	gasLimit := types.NewGasUnits(submitPostGasLimit)
-
-	_, err = sm.porcelainAPI.MessageSend(ctx, sm.minerOwnerAddr, sm.minerAddr, types.ZeroAttoFIL, gasPrice, gasLimit, "submitPoSt", proof[:])
-	if err != nil {
-		log.Errorf("failed to submit PoSt: %s", err)
-		return
-	}
-
-	log.Debug("submitted PoSt")
+// Prover returns the prover generating and submitting the PoSts of the miner.
+func (sm *Miner) Prover() *Prover {
+	return sm.prover
 }
 
 // Query responds to a query for the proposal referenced by the given cid
@@ -797,7 +1041,20 @@ func (sm *Miner) Query(ctx context.Context, c cid.Cid) *DealResponse {
 		}
 	}
 
-	return d.Response
+	if d.Response.State != Transferring {
+		return d.Response
+	}
+	resp := *d.Response
+	if status, ok := sm.transfers.Status(c); ok {
+		resp.Transfer = &status
+	}
+	return &resp
+}
+
+// Transfers returns the data transfer manager receiving the data of the
+// miner's deals.
+func (sm *Miner) Transfers() *datatransfer.Manager {
+	return sm.transfers
 }
 
 func (sm *Miner) handleQueryDeal(s inet.Stream) {
@@ -839,6 +1096,7 @@ func (sm *Miner) loadDeals() error {
 	if err != nil {
 		return errors.Wrap(err, "failed to query deals from datastore")
 	}
+	defer res.Close() // nolint: errcheck
 
 	sm.deals = make(map[cid.Cid]*storageDeal)
 
@@ -865,3 +1123,17 @@ func (sm *Miner) saveDeal(proposalCid cid.Cid) error {
 	}
 	return nil
 }
+
+// SaveDeals writes the current state of every deal of the miner, and of the
+// deals awaiting seal, to its datastore.
+func (sm *Miner) SaveDeals() error {
+	sm.dealsLk.Lock()
+	defer sm.dealsLk.Unlock()
+
+	for c := range sm.deals {
+		if err := sm.saveDeal(c); err != nil {
+			return err
+		}
+	}
+	return sm.saveDealsAwaitingSeal()
+}
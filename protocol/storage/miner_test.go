@@ -2,16 +2,24 @@ package storage
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	bserv "gx/ipfs/QmYPZzd9VqmJDwxUnThfeSbV1Y5o53aVPDijTB7j7rS9Ep/go-blockservice"
+	"gx/ipfs/QmaoXrM4Z41PD48JY36YqQGKQpLGjyLA2cKcLsES7YddAq/go-libp2p-host"
 
 	"github.com/filecoin-project/go-filecoin/actor"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/paymentbroker"
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/filnet"
 	"github.com/filecoin-project/go-filecoin/plumbing/cfg"
+	"github.com/filecoin-project/go-filecoin/plumbing/sctr"
 	"github.com/filecoin-project/go-filecoin/proofs/sectorbuilder"
+	"github.com/filecoin-project/go-filecoin/protocol/sealing"
 	"github.com/filecoin-project/go-filecoin/repo"
 	"github.com/filecoin-project/go-filecoin/types"
 	"github.com/stretchr/testify/assert"
@@ -31,6 +39,7 @@ func TestReceiveStorageProposal(t *testing.T) {
 		miner := Miner{
 			porcelainAPI:   porcelainAPI,
 			minerOwnerAddr: porcelainAPI.targetAddress,
+			node:           &minerTestNode{},
 			proposalAcceptor: func(ctx context.Context, m *Miner, p *DealProposal) (*DealResponse, error) {
 				accepted = true
 				return &DealResponse{State: Accepted}, nil
@@ -68,6 +77,34 @@ func TestReceiveStorageProposal(t *testing.T) {
 		assert.Equal("proposed price (2500) is less than expected (5000) given asking price of 0.0005", res.Message)
 	})
 
+	t.Run("Rejects proposals not signed by the payer", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		_, miner, proposal := newMinerTestSetup()
+		proposal.TotalPrice = types.NewAttoFILFromFIL(5000)
+
+		res, err := miner.receiveStorageProposal(context.Background(), proposal)
+		require.NoError(err)
+
+		assert.Equal(Rejected, res.State)
+		assert.Equal("invalid client signature", res.Message)
+	})
+
+	t.Run("Rejects proposals for other miners", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		_, miner, proposal := newMinerTestSetup()
+		miner.minerAddr = address.TestAddress
+
+		res, err := miner.receiveStorageProposal(context.Background(), proposal)
+		require.NoError(err)
+
+		assert.Equal(Rejected, res.State)
+		assert.Contains(res.Message, "proposal is for miner")
+	})
+
 	t.Run("Rejects proposals with invalid payment channel", func(t *testing.T) {
 		assert := assert.New(t)
 		require := require.New(t)
@@ -96,6 +133,21 @@ func TestReceiveStorageProposal(t *testing.T) {
 		assert.Contains(res.Message, "not target of payment channel")
 	})
 
+	t.Run("Rejects proposals the deal policy does not accept", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		porcelainAPI, miner, proposal := newMinerTestSetup()
+		blocked := fmt.Sprintf(`["%s"]`, porcelainAPI.payerAddress)
+		require.NoError(porcelainAPI.config.Set("mining.dealPolicy.blockedClients", blocked))
+
+		res, err := miner.receiveStorageProposal(context.Background(), proposal)
+		require.NoError(err)
+
+		assert.Equal(Rejected, res.State)
+		assert.Contains(res.Message, "does not make deals with client")
+	})
+
 	t.Run("Rejects proposals with too short channel eol", func(t *testing.T) {
 		assert := assert.New(t)
 		require := require.New(t)
@@ -311,6 +363,58 @@ func TestDealsAwaitingSeal(t *testing.T) {
 	})
 }
 
+func TestMinerDealStateMachine(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	porcelainAPI, _, proposal := newMinerTestSetup()
+	dealsDs := repo.NewInMemoryRepo().DealsDatastore()
+	miner := &Miner{
+		porcelainAPI: porcelainAPI,
+		deals:        make(map[cid.Cid]*storageDeal),
+		dealsDs:      dealsDs,
+	}
+
+	proposalCid := types.SomeCid()
+	miner.deals[proposalCid] = &storageDeal{
+		Proposal: proposal,
+		Response: &DealResponse{State: Accepted, ProposalCid: proposalCid},
+		History:  []DealTransition{{State: Accepted}},
+		Expiry:   types.NewBlockHeight(100),
+	}
+
+	for _, state := range []DealState{Transferring, Sealing, Proving} {
+		require.NoError(miner.updateDealResponse(proposalCid, func(resp *DealResponse) {
+			resp.State = state
+		}))
+	}
+
+	err := miner.updateDealResponse(proposalCid, func(resp *DealResponse) {
+		resp.State = Accepted
+	})
+	assert.Error(err)
+
+	miner.completeDeals(types.NewBlockHeight(99))
+	assert.Equal(Proving, miner.Query(context.Background(), proposalCid).State)
+	miner.completeDeals(types.NewBlockHeight(100))
+	assert.Equal(Complete, miner.Query(context.Background(), proposalCid).State)
+
+	// the deal and its history survive a restart
+	restarted := &Miner{dealsDs: dealsDs}
+	require.NoError(restarted.loadDeals())
+	record, err := restarted.Deal(proposalCid)
+	require.NoError(err)
+
+	assert.Equal(MinerRole, record.Role)
+	assert.Equal(Complete, record.Response.State)
+	var states []DealState
+	for _, transition := range record.History {
+		states = append(states, transition.State)
+	}
+	assert.Equal([]DealState{Accepted, Transferring, Sealing, Proving, Complete}, states)
+}
+
 type minerTestPorcelain struct {
 	config        *cfg.Config
 	payerAddress  address.Address
@@ -387,13 +491,61 @@ func (mtp *minerTestPorcelain) ChainBlockHeight(ctx context.Context) (*types.Blo
 	return mtp.blockHeight, nil
 }
 
+func (mtp *minerTestPorcelain) SignBytes(data []byte, addr address.Address) (types.Signature, error) {
+	return mtp.signer.SignBytes(data, addr)
+}
+
 func (mtp *minerTestPorcelain) MessageWait(ctx context.Context, msgCid cid.Cid, cb func(*types.Block, *types.SignedMessage, *types.MessageReceipt) error) error {
 	return nil
 }
 
+// minerTestNode is a node without a sector builder.
+type minerTestNode struct{}
+
+func (mtn *minerTestNode) BlockHeight() (*types.BlockHeight, error) {
+	return types.NewBlockHeight(0), nil
+}
+
+func (mtn *minerTestNode) GetBlockTime() time.Duration {
+	return time.Second
+}
+
+func (mtn *minerTestNode) BlockService() bserv.BlockService {
+	return nil
+}
+
+func (mtn *minerTestNode) Host() host.Host {
+	return nil
+}
+
+func (mtn *minerTestNode) SectorBuilder() sectorbuilder.SectorBuilder {
+	return nil
+}
+
+func (mtn *minerTestNode) SealingScheduler() *sectorbuilder.Scheduler {
+	return sectorbuilder.NewScheduler(nil)
+}
+
+func (mtn *minerTestNode) SealingMaster() *sealing.Master {
+	return nil
+}
+
+func (mtn *minerTestNode) SectorProgress() *sctr.Tracker {
+	return sctr.NewTracker()
+}
+
+func (mtn *minerTestNode) SectorDir(kind sectorbuilder.PathKind) string {
+	return ""
+}
+
+func (mtn *minerTestNode) FetchSessions() *filnet.FetchSessions {
+	return filnet.NewFetchSessions(nil)
+}
+
 func newTestMiner(api *minerTestPorcelain) *Miner {
 	return &Miner{
 		porcelainAPI:   api,
+		node:           &minerTestNode{},
 		minerOwnerAddr: api.targetAddress,
 		proposalAcceptor: func(ctx context.Context, m *Miner, p *DealProposal) (*DealResponse, error) {
 			return &DealResponse{State: Accepted}, nil
@@ -429,7 +581,8 @@ func testDealProposal(porcelainAPI *minerTestPorcelain, voucherInterval int, amo
 		}
 	}
 
-	return &DealProposal{
+	proposal := &DealProposal{
+		PieceRef:   types.SomeCid(),
 		TotalPrice: types.NewAttoFILFromFIL(2500),
 		Size:       types.NewBytesAmount(1000),
 		Duration:   10000,
@@ -441,4 +594,81 @@ func testDealProposal(porcelainAPI *minerTestPorcelain, voucherInterval int, amo
 			Vouchers:      vouchers,
 		},
 	}
+
+	signature, err := proposal.Terms().Sign(porcelainAPI.signer, porcelainAPI.payerAddress)
+	if err != nil {
+		panic("Could not sign proposal")
+	}
+	proposal.Signature = signature
+	return proposal
+}
+
+func TestImportDealDataRejectsInvalidDeals(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	porcelainAPI, _, proposal := newMinerTestSetup()
+	miner := &Miner{
+		porcelainAPI: porcelainAPI,
+		deals:        make(map[cid.Cid]*storageDeal),
+		imported:     make(map[cid.Cid]bool),
+		dealsDs:      repo.NewInMemoryRepo().DealsDatastore(),
+	}
+	ctx := context.Background()
+	cidGetter := types.NewCidForTestGetter()
+
+	online := cidGetter()
+	miner.deals[online] = &storageDeal{
+		Proposal: proposal,
+		Response: &DealResponse{State: Transferring, ProposalCid: online},
+	}
+
+	offlineProposal := *proposal
+	offlineProposal.CommP = []byte{1, 2, 3}
+	published := cidGetter()
+	miner.deals[published] = &storageDeal{
+		Proposal: &offlineProposal,
+		Response: &DealResponse{State: Accepted, ProposalCid: published},
+	}
+
+	err := miner.ImportDealData(ctx, cidGetter(), strings.NewReader(""))
+	assert.Contains(err.Error(), "no such deal")
+
+	err = miner.ImportDealData(ctx, online, strings.NewReader(""))
+	assert.Contains(err.Error(), "not an offline deal")
+
+	err = miner.ImportDealData(ctx, published, strings.NewReader(""))
+	assert.Contains(err.Error(), "not waiting for data")
+}
+
+func TestMinerLivePieces(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	cidGetter := types.NewCidForTestGetter()
+	miner := &Miner{deals: make(map[cid.Cid]*storageDeal)}
+
+	var live []cid.Cid
+	for state, isLive := range map[DealState]bool{
+		Accepted:       true,
+		Transferring:   true,
+		WaitingForData: true,
+		Packing:        true,
+		Sealing:        true,
+		Proving:        false,
+		Complete:       false,
+		Rejected:       false,
+		Failed:         false,
+	} {
+		piece := cidGetter()
+		miner.deals[cidGetter()] = &storageDeal{
+			Proposal: &DealProposal{PieceRef: piece},
+			Response: &DealResponse{State: state},
+		}
+		if isLive {
+			live = append(live, piece)
+		}
+	}
+
+	assert.ElementsMatch(live, miner.LivePieces())
 }
@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+
+	"github.com/filecoin-project/go-filecoin/config"
+	"github.com/filecoin-project/go-filecoin/plumbing/sctr"
+	"github.com/filecoin-project/go-filecoin/proofs/sectorbuilder"
+)
+
+// packPiece packs the piece of the deal into a sector with the other pieces
+// waiting to be staged, see sectorbuilder.Packer. The deal waits in the
+// Packing state until the sector is full or waited mining.packing.maxWait,
+// when the pieces of the sector are staged and it is sealed.
+func (sm *Miner) packPiece(c cid.Cid, pi *sectorbuilder.PieceInfo) error {
+	sm.packedLk.Lock()
+	if sm.packer == nil {
+		maxUserBytes, err := sm.node.SectorBuilder().GetMaxUserBytesPerStagedSector()
+		if err != nil {
+			sm.packedLk.Unlock()
+			return errors.Wrap(err, "failed to get the size of sectors")
+		}
+		sm.packer = sectorbuilder.NewPacker(maxUserBytes)
+	}
+	if err := sm.packer.Add(pi, time.Now()); err != nil {
+		sm.packedLk.Unlock()
+		return err
+	}
+	sm.packedDeals[pi] = c
+	sm.packedLk.Unlock()
+
+	err := sm.updateDealResponse(c, func(resp *DealResponse) {
+		resp.State = Packing
+	})
+	if err != nil {
+		log.Errorf("could not update to 'Packing': %s", err)
+	}
+
+	sm.sealPacked()
+	return nil
+}
+
+// sealPacked stages and seals, in the background, the sectors of the packer
+// which are full or waited mining.packing.maxWait for more pieces.
+func (sm *Miner) sealPacked() {
+	cfg, err := sm.packingConfig()
+	if err != nil {
+		log.Errorf("failed to get packing config: %s", err)
+		return
+	}
+	maxWait, err := time.ParseDuration(cfg.MaxWait)
+	if err != nil {
+		log.Errorf("invalid packing max wait %s, sealing partially full sectors: %s", cfg.MaxWait, err)
+		maxWait = 0
+	}
+
+	sm.packedLk.Lock()
+	if sm.packer == nil {
+		sm.packedLk.Unlock()
+		return
+	}
+	bins := sm.packer.Ready(time.Now(), maxWait)
+	sm.packedLk.Unlock()
+
+	if len(bins) == 0 {
+		return
+	}
+	go func() {
+		for _, b := range bins {
+			sm.sealBin(context.Background(), b)
+		}
+	}()
+}
+
+// sealBin stages the pieces of the bin and seals them into a sector, moving
+// their deals to the Sealing state.
+func (sm *Miner) sealBin(ctx context.Context, b *sectorbuilder.Bin) {
+	// bins are staged one at a time, so that the pieces of a bin are staged
+	// into the same sector
+	sm.stagingLk.Lock()
+	defer sm.stagingLk.Unlock()
+
+	sb := sm.node.SectorBuilder()
+	for _, pi := range b.Pieces {
+		sm.packedLk.Lock()
+		c := sm.packedDeals[pi]
+		delete(sm.packedDeals, pi)
+		sm.packedLk.Unlock()
+
+		var sectorID uint64
+		err := sm.node.SealingScheduler().Do(ctx, sectorbuilder.AddPieceStage, fmt.Sprintf("add piece %s of deal %s", pi.Ref, c), func() error {
+			var err error
+			sectorID, err = sb.AddPiece(ctx, pi)
+			return err
+		})
+		if err != nil {
+			log.Errorf("failed to add packed piece %s: %s", pi.Ref, err)
+			err := sm.updateDealResponse(c, func(resp *DealResponse) {
+				resp.Message = "failed to submit seal proof"
+				resp.State = Failed
+			})
+			if err != nil {
+				log.Errorf("could not update to deal to 'Failed' state: %s", err)
+			}
+			continue
+		}
+		sm.node.SectorProgress().Enter(sectorID, sctr.Staged)
+
+		err = sm.updateDealResponse(c, func(resp *DealResponse) {
+			resp.State = Sealing
+		})
+		if err != nil {
+			log.Errorf("could not update to 'Sealing': %s", err)
+		}
+		sm.dealsAwaitingSeal.add(sectorID, c)
+		if err := sm.saveDealsAwaitingSeal(); err != nil {
+			log.Errorf("could not save deal awaiting seal: %s", err)
+		}
+	}
+
+	desc := fmt.Sprintf("seal packed sector of %d pieces, %d of %d bytes used", len(b.Pieces), b.Used, b.Capacity)
+	err := sm.node.SealingScheduler().Do(ctx, sectorbuilder.SealStage, desc, func() error {
+		sm.node.SectorProgress().EnterAll(sctr.Staged, sctr.Sealing)
+		return sb.SealAllStagedSectors(ctx)
+	})
+	if err != nil {
+		log.Errorf("failed to seal packed sector: %s", err)
+	}
+}
+
+func (sm *Miner) packingConfig() (*config.PackingConfig, error) {
+	val, err := sm.porcelainAPI.ConfigGet("mining.packing")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get packing config")
+	}
+	cfg, ok := val.(*config.PackingConfig)
+	if !ok {
+		return nil, errors.New("could not retrieve packing from config")
+	}
+	return cfg, nil
+}
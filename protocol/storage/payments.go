@@ -0,0 +1,298 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	inet "gx/ipfs/QmNgLg1NTw37iWbYPKcyK85YJ9Whs1MkPtJwhfqbNYAyKg/go-libp2p-net"
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	cbor "gx/ipfs/QmRoARq3nkUb13HSKZGepCZSWe5GrVPwx7xURJGZ7KWv9V/go-ipld-cbor"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	"gx/ipfs/QmZNkThpqfVXs9GNbexPrfBbXSLNYeKrE7jwFM2oqHbyqN/go-libp2p-protocol"
+
+	"github.com/filecoin-project/go-filecoin/actor/builtin/paymentbroker"
+	"github.com/filecoin-project/go-filecoin/address"
+	cbu "github.com/filecoin-project/go-filecoin/cborutil"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// Clients pay for deals incrementally. The proposal of a deal carries the
+// voucher paying for the first VoucherInterval blocks, the client sends the
+// miner the following vouchers one interval ahead, as long as the miner proves
+// the storage of the data. The miner redeems the vouchers as they become
+// valid.
+
+const deliverVouchersProtocol = protocol.ID("/fil/storage/vouchers/1.0.0")
+
+// TODO: replace this with a queries to pick reasonable gas price and limits.
+const redeemGasPrice = 0
+const redeemGasLimit = 300
+
+func init() {
+	cbor.RegisterCborType(voucherDelivery{})
+	cbor.RegisterCborType(voucherDeliveryResponse{})
+}
+
+// voucherDelivery carries vouchers paying for a deal from the client to the
+// miner.
+type voucherDelivery struct {
+	ProposalCid cid.Cid
+	Vouchers    []*paymentbroker.PaymentVoucher
+}
+
+type voucherDeliveryResponse struct {
+	// Error tells why the miner refused the vouchers, if it did.
+	Error string
+}
+
+// sendDueVouchers sends the miners of the deals being proven the vouchers
+// that become valid within VoucherInterval blocks of height h.
+func (smc *Client) sendDueVouchers(h *types.BlockHeight) {
+	due := h.Add(types.NewBlockHeight(VoucherInterval))
+	for _, c := range smc.dealsToPay(due) {
+		go smc.payDeal(c, due)
+	}
+}
+
+// dealsToPay returns the deals with vouchers to send that become valid at or
+// before height due, and marks them as being paid.
+func (smc *Client) dealsToPay(due *types.BlockHeight) []cid.Cid {
+	smc.dealsLk.Lock()
+	defer smc.dealsLk.Unlock()
+
+	var toPay []cid.Cid
+	for c, deal := range smc.deals {
+		if smc.paying[c] || deal.VouchersSent >= len(deal.Vouchers) {
+			continue
+		}
+		switch deal.Response.State {
+		case Rejected, Failed, Unknown:
+			continue
+		}
+		if deal.Vouchers[deal.VouchersSent].ValidAt.GreaterThan(due) {
+			continue
+		}
+		smc.paying[c] = true
+		toPay = append(toPay, c)
+	}
+	return toPay
+}
+
+// payDeal sends the miner of the deal with the given proposal cid the vouchers
+// that become valid at or before height due, if the miner still proves the
+// storage of the deal's data.
+func (smc *Client) payDeal(c cid.Cid, due *types.BlockHeight) {
+	defer func() {
+		smc.dealsLk.Lock()
+		delete(smc.paying, c)
+		smc.dealsLk.Unlock()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 4*smc.node.GetBlockTime())
+	defer cancel()
+
+	resp, err := smc.QueryDeal(ctx, c)
+	if err != nil {
+		log.Errorf("could not query deal %s for payment: %s", c, err)
+		return
+	}
+	if resp.State != Proving && resp.State != Complete {
+		log.Debugf("not paying for deal %s in state %s", c, resp.State)
+		return
+	}
+
+	smc.dealsLk.Lock()
+	deal := smc.deals[c]
+	miner := deal.Miner
+	var vouchers []*paymentbroker.PaymentVoucher
+	for _, v := range deal.Vouchers[deal.VouchersSent:] {
+		if v.ValidAt.GreaterThan(due) {
+			break
+		}
+		vouchers = append(vouchers, v)
+	}
+	smc.dealsLk.Unlock()
+
+	pid, err := smc.api.MinerGetPeerID(ctx, miner)
+	if err != nil {
+		log.Errorf("could not pay for deal %s: %s", c, err)
+		return
+	}
+
+	var dresp voucherDeliveryResponse
+	err = smc.node.MakeProtocolRequest(ctx, deliverVouchersProtocol, pid, &voucherDelivery{ProposalCid: c, Vouchers: vouchers}, &dresp)
+	if err != nil {
+		log.Errorf("could not send vouchers for deal %s: %s", c, err)
+		return
+	}
+	if dresp.Error != "" {
+		log.Errorf("miner refused vouchers for deal %s: %s", c, dresp.Error)
+		return
+	}
+
+	smc.dealsLk.Lock()
+	defer smc.dealsLk.Unlock()
+	deal.VouchersSent += len(vouchers)
+	if err := smc.saveDeal(c); err != nil {
+		log.Errorf("could not save deal %s: %s", c, err)
+	}
+}
+
+func (sm *Miner) handleDeliverVouchers(s inet.Stream) {
+	defer s.Close() // nolint: errcheck
+
+	var delivery voucherDelivery
+	if err := cbu.NewMsgReader(s).ReadMsg(&delivery); err != nil {
+		log.Errorf("received invalid voucher delivery: %s", err)
+		return
+	}
+
+	var resp voucherDeliveryResponse
+	if err := sm.receiveVouchers(delivery.ProposalCid, delivery.Vouchers); err != nil {
+		resp.Error = err.Error()
+	}
+
+	if err := cbu.NewMsgWriter(s).WriteMsg(&resp); err != nil {
+		log.Errorf("failed to write voucher delivery response: %s", err)
+	}
+}
+
+// receiveVouchers adds vouchers the client sent after proposing the deal with
+// the given proposal cid to the vouchers paying for it.
+func (sm *Miner) receiveVouchers(proposalCid cid.Cid, vouchers []*paymentbroker.PaymentVoucher) error {
+	if sm.getStorageDeal(proposalCid) == nil {
+		return fmt.Errorf("no such deal: %s", proposalCid)
+	}
+
+	return sm.updateDeal(proposalCid, func(d *storageDeal) error {
+		all := d.vouchers()
+		last := all[len(all)-1]
+		for _, v := range vouchers {
+			if err := checkNextVoucher(d.Proposal, last, v); err != nil {
+				return err
+			}
+			last = v
+		}
+		d.Vouchers = append(d.Vouchers, vouchers...)
+		return nil
+	})
+}
+
+// checkNextVoucher returns an error if v is not a valid voucher paying for the
+// deal of proposal p following voucher last.
+func checkNextVoucher(p *DealProposal, last, v *paymentbroker.PaymentVoucher) error {
+	if !v.Channel.Equal(p.Payment.Channel) {
+		return fmt.Errorf("voucher is for channel %s, not %s", &v.Channel, p.Payment.Channel)
+	}
+	if !paymentbroker.VerifyVoucherSignature(p.Payment.Payer, p.Payment.Channel, &v.Amount, &v.ValidAt, v.Signature) {
+		return errors.New("invalid signature in voucher")
+	}
+	if !v.ValidAt.GreaterThan(&last.ValidAt) {
+		return fmt.Errorf("voucher valid at %s does not follow voucher valid at %s", &v.ValidAt, &last.ValidAt)
+	}
+	if v.ValidAt.GreaterThan(last.ValidAt.Add(types.NewBlockHeight(VoucherInterval))) {
+		return fmt.Errorf("interval between vouchers too high (%s - %s > %d)", &v.ValidAt, &last.ValidAt, VoucherInterval)
+	}
+	if v.Amount.LessThan(&last.Amount) {
+		return fmt.Errorf("voucher amount (%s) less than previous amount (%s)", &v.Amount, &last.Amount)
+	}
+	if v.Amount.GreaterThan(p.TotalPrice) {
+		return fmt.Errorf("voucher amount (%s) more than total price (%s)", &v.Amount, p.TotalPrice)
+	}
+	return nil
+}
+
+// vouchers returns all vouchers paying for the deal, in order.
+func (d *storageDeal) vouchers() []*paymentbroker.PaymentVoucher {
+	all := append([]*paymentbroker.PaymentVoucher{}, d.Proposal.Payment.Vouchers...)
+	return append(all, d.Vouchers...)
+}
+
+// redeemableVoucher returns the voucher valid at height h paying the most for
+// the deal, if it pays more than the miner redeemed so far.
+func (d *storageDeal) redeemableVoucher(h *types.BlockHeight) *paymentbroker.PaymentVoucher {
+	var best *paymentbroker.PaymentVoucher
+	for _, v := range d.vouchers() {
+		if v.ValidAt.GreaterThan(h) {
+			continue
+		}
+		if best == nil || v.Amount.GreaterThan(&best.Amount) {
+			best = v
+		}
+	}
+	if best == nil || (d.Redeemed != nil && !best.Amount.GreaterThan(d.Redeemed)) {
+		return nil
+	}
+	return best
+}
+
+// redeemVouchers redeems the vouchers valid at height h of the deals whose
+// data the miner proves, or proved until the deal completed.
+func (sm *Miner) redeemVouchers(h *types.BlockHeight) {
+	type redemption struct {
+		proposalCid cid.Cid
+		voucher     *paymentbroker.PaymentVoucher
+	}
+
+	sm.dealsLk.Lock()
+	var redemptions []redemption
+	for c, d := range sm.deals {
+		if d.Response.State != Proving && d.Response.State != Complete {
+			continue
+		}
+		if sm.redeeming[c] {
+			continue
+		}
+		if v := d.redeemableVoucher(h); v != nil {
+			sm.redeeming[c] = true
+			redemptions = append(redemptions, redemption{c, v})
+		}
+	}
+	sm.dealsLk.Unlock()
+
+	for _, r := range redemptions {
+		go sm.redeemVoucher(r.proposalCid, r.voucher)
+	}
+}
+
+// redeemVoucher redeems the voucher paying for the deal with the given
+// proposal cid, and records the amount redeemed.
+func (sm *Miner) redeemVoucher(proposalCid cid.Cid, v *paymentbroker.PaymentVoucher) {
+	defer func() {
+		sm.dealsLk.Lock()
+		delete(sm.redeeming, proposalCid)
+		sm.dealsLk.Unlock()
+	}()
+
+	ctx := context.Background()
+
+	// TODO: algorithmically determine appropriate values for these
+	gasPrice := types.NewGasPrice(redeemGasPrice)
+	gasLimit := types.NewGasUnits(redeemGasLimit)
+
+	msgCid, err := sm.porcelainAPI.MessageSend(ctx, sm.minerOwnerAddr, address.PaymentBrokerAddress, types.ZeroAttoFIL, gasPrice, gasLimit, "redeem", v.Payer, &v.Channel, &v.Amount, &v.ValidAt, []byte(v.Signature))
+	if err != nil {
+		log.Errorf("failed to redeem voucher for deal %s: %s", proposalCid, err)
+		return
+	}
+
+	err = sm.porcelainAPI.MessageWait(ctx, msgCid, func(blk *types.Block, smsg *types.SignedMessage, receipt *types.MessageReceipt) error {
+		if receipt.ExitCode != uint8(0) {
+			return fmt.Errorf("redeem failed with exit code %d", receipt.ExitCode)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Errorf("failed to redeem voucher for deal %s: %s", proposalCid, err)
+		return
+	}
+
+	err = sm.updateDeal(proposalCid, func(d *storageDeal) error {
+		d.Redeemed = &v.Amount
+		return nil
+	})
+	if err != nil {
+		log.Errorf("could not record redemption for deal %s: %s", proposalCid, err)
+	}
+	log.Infof("redeemed %s for deal %s", &v.Amount, proposalCid)
+}
@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+
+	"github.com/filecoin-project/go-filecoin/actor/builtin/paymentbroker"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/repo"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/util/convert"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientPaysDealsIncrementally(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	queryState := Accepted
+	var delivered []*paymentbroker.PaymentVoucher
+	testAPI := newTestClientAPI()
+	testNode := newTestClientNode(func(request interface{}) (interface{}, error) {
+		switch r := request.(type) {
+		case *DealProposal:
+			pcid, err := convert.ToCid(r)
+			require.NoError(err)
+			sig, err := r.Terms().Sign(testAPI.signer, testAPI.target)
+			require.NoError(err)
+			return &DealResponse{State: Accepted, ProposalCid: pcid, Signature: sig}, nil
+		case queryRequest:
+			return &DealResponse{State: queryState, ProposalCid: r.Cid}, nil
+		case *voucherDelivery:
+			delivered = append(delivered, r.Vouchers...)
+			return &voucherDeliveryResponse{}, nil
+		}
+		return nil, fmt.Errorf("unexpected request %T", request)
+	})
+
+	client, err := NewClient(testNode, testAPI, repo.NewInMemoryRepo().DealsDs)
+	require.NoError(err)
+
+	ctx := context.Background()
+	dealResponse, err := client.ProposeDeal(ctx, address.NewForTestGetter()(), types.SomeCid(), 0, 10000, false)
+	require.NoError(err)
+	c := dealResponse.ProposalCid
+
+	// the proposal paid for the first voucher, the second is valid at 773 + 2000
+	assert.Empty(client.dealsToPay(types.NewBlockHeight(2772)))
+	assert.Equal([]cid.Cid{c}, client.dealsToPay(types.NewBlockHeight(2773)))
+	// the deal is being paid
+	assert.Empty(client.dealsToPay(types.NewBlockHeight(2773)))
+
+	// nothing is paid until the miner proves the data
+	client.payDeal(c, types.NewBlockHeight(3773))
+	assert.Empty(delivered)
+	assert.Equal(1, client.deals[c].VouchersSent)
+
+	queryState = Proving
+	client.payDeal(c, types.NewBlockHeight(3773))
+	require.Equal(2, len(delivered))
+	assert.Equal(types.NewBlockHeight(2773), &delivered[0].ValidAt)
+	assert.Equal(types.NewBlockHeight(3773), &delivered[1].ValidAt)
+	assert.Equal(3, client.deals[c].VouchersSent)
+
+	// the next voucher is valid at 4773
+	assert.Empty(client.dealsToPay(types.NewBlockHeight(4772)))
+	assert.Equal([]cid.Cid{c}, client.dealsToPay(types.NewBlockHeight(4773)))
+}
+
+func TestMinerReceivesVouchers(t *testing.T) {
+	t.Parallel()
+
+	setup := func() (*Miner, cid.Cid, []*paymentbroker.PaymentVoucher) {
+		_, miner, proposal := newMinerTestSetup()
+		miner.dealsDs = repo.NewInMemoryRepo().DealsDatastore()
+		miner.deals = make(map[cid.Cid]*storageDeal)
+
+		// the client only pays the first voucher with the proposal
+		vouchers := proposal.Payment.Vouchers
+		proposal.Payment.Vouchers = vouchers[:1]
+
+		c := types.SomeCid()
+		miner.deals[c] = &storageDeal{
+			Proposal: proposal,
+			Response: &DealResponse{State: Proving, ProposalCid: c},
+		}
+		return miner, c, vouchers[1:]
+	}
+
+	t.Run("accepts the following vouchers", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+		miner, c, vouchers := setup()
+
+		require.NoError(miner.receiveVouchers(c, vouchers[:2]))
+		require.NoError(miner.receiveVouchers(c, vouchers[2:]))
+		assert.Equal(vouchers, miner.deals[c].Vouchers)
+		assert.Equal(10, len(miner.deals[c].vouchers()))
+	})
+
+	t.Run("rejects vouchers for unknown deals", func(t *testing.T) {
+		miner, _, vouchers := setup()
+		err := miner.receiveVouchers(types.NewCidForTestGetter()(), vouchers)
+		assert.Contains(t, err.Error(), "no such deal")
+	})
+
+	t.Run("rejects vouchers skipping an interval", func(t *testing.T) {
+		miner, c, vouchers := setup()
+		err := miner.receiveVouchers(c, vouchers[1:])
+		assert.Contains(t, err.Error(), "interval between vouchers too high")
+		assert.Empty(t, miner.deals[c].Vouchers)
+	})
+
+	t.Run("rejects vouchers out of order", func(t *testing.T) {
+		miner, c, vouchers := setup()
+		err := miner.receiveVouchers(c, []*paymentbroker.PaymentVoucher{vouchers[0], vouchers[0]})
+		assert.Contains(t, err.Error(), "does not follow")
+	})
+
+	t.Run("rejects vouchers with invalid signatures", func(t *testing.T) {
+		miner, c, vouchers := setup()
+		forged := *vouchers[0]
+		forged.Amount = *types.NewAttoFILFromFIL(2500)
+		err := miner.receiveVouchers(c, []*paymentbroker.PaymentVoucher{&forged})
+		assert.Contains(t, err.Error(), "invalid signature")
+	})
+}
+
+func TestRedeemableVoucher(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	porcelainAPI, _, proposal := newMinerTestSetup()
+	vouchers := proposal.Payment.Vouchers
+	proposal.Payment.Vouchers = vouchers[:1]
+	deal := &storageDeal{Proposal: proposal, Vouchers: vouchers[1:3]}
+
+	// vouchers are valid at paymentStart + 1000, + 2000, + 3000
+	start := porcelainAPI.paymentStart
+	assert.Nil(deal.redeemableVoucher(start))
+	assert.Equal(vouchers[0], deal.redeemableVoucher(start.Add(types.NewBlockHeight(1000))))
+	assert.Equal(vouchers[1], deal.redeemableVoucher(start.Add(types.NewBlockHeight(2500))))
+	assert.Equal(vouchers[2], deal.redeemableVoucher(start.Add(types.NewBlockHeight(5000))))
+
+	// nothing left to redeem once the last voucher was redeemed
+	deal.Redeemed = &vouchers[2].Amount
+	assert.Nil(deal.redeemableVoucher(start.Add(types.NewBlockHeight(5000))))
+}
@@ -0,0 +1,239 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"math/rand"
+	"sort"
+	"time"
+
+	imp "gx/ipfs/QmQXze9tG878pa4Euya4rrDpyTNX3kQe4dhCaBzBozGgpe/go-unixfs/importer"
+	chunk "gx/ipfs/QmR4QQVkBZsZENRjYFVi8dEtPL3daZRNKk24m4r6WKJHNm/go-ipfs-chunker"
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	dag "gx/ipfs/QmTQdH4848iTVCJmKXYyRiK72HufWTLYQQ8iN3JaQ8K1Hq/go-merkledag"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	ipld "gx/ipfs/QmcKKBwfz6FyQdHR2jsXrrF6XeSBXYL86anmWNewpFpoF5/go-ipld-format"
+	ds "gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/config"
+	"github.com/filecoin-project/go-filecoin/plumbing/sctr"
+	"github.com/filecoin-project/go-filecoin/proofs/sectorbuilder"
+)
+
+const pledgedSectorsDatastorePrefix = "pledgedSectors"
+
+// PledgeSectors fills count sectors with self-generated data, which are
+// sealed and committed like the sectors of deals, adding to the power of the
+// miner without storing data of clients. It returns the ids of the sectors.
+func (sm *Miner) PledgeSectors(ctx context.Context, count uint64) ([]uint64, error) {
+	sb := sm.node.SectorBuilder()
+	if sb == nil {
+		return nil, errors.New("miner has no sector builder")
+	}
+	size, err := sb.GetMaxUserBytesPerStagedSector()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get sector size")
+	}
+
+	var sectorIDs []uint64
+	for i := uint64(0); i < count; i++ {
+		sectorID, err := sm.pledgeSector(ctx, sb, size)
+		if err != nil {
+			return sectorIDs, errors.Wrapf(err, "failed to pledge sector %d of %d", i+1, count)
+		}
+		sectorIDs = append(sectorIDs, sectorID)
+	}
+	return sectorIDs, nil
+}
+
+// pledgeSector adds a piece of random data filling a whole sector to the
+// sector builder, which seals the sector once full.
+func (sm *Miner) pledgeSector(ctx context.Context, sb sectorbuilder.SectorBuilder, size uint64) (uint64, error) {
+	dserv := dag.NewDAGService(sm.node.BlockService())
+	data := io.LimitReader(rand.New(rand.NewSource(time.Now().UnixNano())), int64(size))
+	root, err := imp.BuildDagFromReader(dserv, chunk.DefaultSplitter(data))
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to generate pledge data")
+	}
+
+	pi := &sectorbuilder.PieceInfo{Ref: root.Cid(), Size: size}
+	var sectorID uint64
+	err = sm.node.SealingScheduler().Do(ctx, sectorbuilder.AddPieceStage, fmt.Sprintf("add pledge piece %s", pi.Ref), func() error {
+		var err error
+		sectorID, err = sb.AddPiece(ctx, pi)
+		return err
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to add pledge piece")
+	}
+	sm.node.SectorProgress().Enter(sectorID, sctr.Staged)
+
+	// the sector builder keeps its own copy of the piece, nobody retrieves
+	// pledge data
+	if err := removeDAG(ctx, dserv, root.Cid()); err != nil {
+		log.Warningf("failed to remove pledge piece %s: %s", root.Cid(), err)
+	}
+
+	sm.pledgedLk.Lock()
+	defer sm.pledgedLk.Unlock()
+	sm.pledgedSectors[sectorID] = true
+	if err := sm.savePledgedSectors(); err != nil {
+		return 0, err
+	}
+	return sectorID, nil
+}
+
+// PledgedSectors returns the ids of the pledged sectors which are not
+// committed yet.
+func (sm *Miner) PledgedSectors() []uint64 {
+	sm.pledgedLk.Lock()
+	defer sm.pledgedLk.Unlock()
+
+	var ids []uint64
+	for id := range sm.pledgedSectors {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// pledgeToTarget pledges sectors in the background if the power of the miner
+// and its pledged sectors which are not committed yet fall short of the
+// configured target power.
+func (sm *Miner) pledgeToTarget(ctx context.Context) {
+	val, err := sm.porcelainAPI.ConfigGet("mining.pledge")
+	if err != nil {
+		log.Errorf("failed to get pledge config: %s", err)
+		return
+	}
+	cfg, ok := val.(*config.PledgeConfig)
+	if !ok {
+		log.Error("could not retrieve pledge from config")
+		return
+	}
+	if cfg.TargetPower == 0 {
+		return
+	}
+
+	power, err := sm.power(ctx)
+	if err != nil {
+		log.Errorf("failed to get power of miner: %s", err)
+		return
+	}
+
+	sm.pledgedLk.Lock()
+	defer sm.pledgedLk.Unlock()
+	if sm.pledging {
+		return
+	}
+	n := pledgesNeeded(cfg.TargetPower, power, uint64(len(sm.pledgedSectors)), cfg.BatchSize)
+	if n == 0 {
+		return
+	}
+	sm.pledging = true
+
+	go func() {
+		defer func() {
+			sm.pledgedLk.Lock()
+			sm.pledging = false
+			sm.pledgedLk.Unlock()
+		}()
+
+		log.Infof("pledging %d sectors to reach target power %d from %d", n, cfg.TargetPower, power)
+		if _, err := sm.PledgeSectors(context.Background(), n); err != nil {
+			log.Errorf("failed to pledge sectors: %s", err)
+		}
+	}()
+}
+
+// pledgesNeeded returns the number of sectors to pledge for a miner with the
+// given power and pending pledged sectors to get closer to the target power,
+// with at most batch pledged sectors pending at once.
+func pledgesNeeded(target, power, pending, batch uint64) uint64 {
+	if power+pending >= target || pending >= batch {
+		return 0
+	}
+	n := batch - pending
+	if missing := target - power - pending; missing < n {
+		n = missing
+	}
+	return n
+}
+
+// power returns the power of the miner, in sectors.
+func (sm *Miner) power(ctx context.Context) (uint64, error) {
+	rets, _, err := sm.porcelainAPI.MessageQuery(
+		ctx,
+		address.Address{},
+		sm.minerAddr,
+		"getPower",
+	)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to call query method getPower")
+	}
+	return big.NewInt(0).SetBytes(rets[0]).Uint64(), nil
+}
+
+// onPledgedSectorDone forgets a pledged sector once committed, or failed.
+func (sm *Miner) onPledgedSectorDone(sectorID uint64) {
+	sm.pledgedLk.Lock()
+	defer sm.pledgedLk.Unlock()
+	if !sm.pledgedSectors[sectorID] {
+		return
+	}
+	delete(sm.pledgedSectors, sectorID)
+	if err := sm.savePledgedSectors(); err != nil {
+		log.Errorf("failed to save pledged sectors: %s", err)
+	}
+}
+
+func (sm *Miner) loadPledgedSectors() error {
+	sm.pledgedSectors = make(map[uint64]bool)
+
+	key := ds.KeyWithNamespaces([]string{pledgedSectorsDatastorePrefix})
+	result, notFound := sm.dealsDs.Get(key)
+	if notFound == nil {
+		if err := json.Unmarshal(result, &sm.pledgedSectors); err != nil {
+			return errors.Wrap(err, "failed to unmarshal pledged sectors from datastore")
+		}
+	}
+	return nil
+}
+
+// savePledgedSectors persists the pledged sectors. The caller must hold
+// sm.pledgedLk.
+func (sm *Miner) savePledgedSectors() error {
+	data, err := json.Marshal(sm.pledgedSectors)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal pledged sectors")
+	}
+	key := ds.KeyWithNamespaces([]string{pledgedSectorsDatastorePrefix})
+	if err := sm.dealsDs.Put(key, data); err != nil {
+		return errors.Wrap(err, "failed to save pledged sectors")
+	}
+	return nil
+}
+
+// removeDAG removes the DAG with the given root from dserv.
+func removeDAG(ctx context.Context, dserv ipld.DAGService, root cid.Cid) error {
+	var cids []cid.Cid
+	todo := []cid.Cid{root}
+	for len(todo) > 0 {
+		c := todo[len(todo)-1]
+		todo = todo[:len(todo)-1]
+
+		nd, err := dserv.Get(ctx, c)
+		if err != nil {
+			return err
+		}
+		cids = append(cids, c)
+		for _, l := range nd.Links() {
+			todo = append(todo, l.Cid)
+		}
+	}
+	return dserv.RemoveMany(ctx, cids)
+}
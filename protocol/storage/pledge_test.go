@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPledgesNeeded(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name                          string
+		target, power, pending, batch uint64
+		want                          uint64
+	}{
+		{"target reached", 10, 10, 0, 4, 0},
+		{"target reached with pending sectors", 10, 8, 2, 4, 0},
+		{"a batch", 10, 0, 0, 4, 4},
+		{"the rest of a batch", 10, 0, 1, 4, 3},
+		{"no more than a batch pending", 10, 0, 4, 4, 0},
+		{"no more than missing", 10, 8, 0, 4, 2},
+		{"no more than missing with pending sectors", 10, 7, 2, 4, 1},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.want, pledgesNeeded(c.target, c.power, c.pending, c.batch))
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"fmt"
+	"math/big"
+
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/config"
+)
+
+// checkDealPolicy returns an error telling why the miner rejects the proposal,
+// if the configured mining.dealPolicy does not accept it, or its piece does
+// not fit into a sector.
+func (sm *Miner) checkDealPolicy(p *DealProposal) error {
+	val, err := sm.porcelainAPI.ConfigGet("mining.dealPolicy")
+	if err != nil {
+		return errors.Wrap(err, "failed to get deal policy")
+	}
+	policy, ok := val.(*config.DealPolicyConfig)
+	if !ok {
+		return errors.New("could not retrieve dealPolicy from config")
+	}
+
+	var sectorSize uint64
+	if sm.node.SectorBuilder() != nil {
+		sectorSize, err = sm.node.SectorBuilder().GetMaxUserBytesPerStagedSector()
+		if err != nil {
+			return errors.Wrap(err, "failed to get sector size")
+		}
+	}
+
+	return evaluateDealPolicy(policy, p, sectorSize)
+}
+
+// evaluateDealPolicy returns an error if the policy does not accept the
+// proposal, or the piece of the proposal is larger than sectorSize bytes. A
+// sectorSize of zero is not checked.
+func evaluateDealPolicy(policy *config.DealPolicyConfig, p *DealProposal, sectorSize uint64) error {
+	if p.Size == nil {
+		return errors.New("proposed deal has no size")
+	}
+	size := p.Size.Uint64()
+
+	if isClientIn(p.Payment.Payer, policy.BlockedClients) {
+		return fmt.Errorf("miner does not make deals with client %s", p.Payment.Payer)
+	}
+	if len(policy.AllowedClients) > 0 && !isClientIn(p.Payment.Payer, policy.AllowedClients) {
+		return fmt.Errorf("miner does not make deals with client %s", p.Payment.Payer)
+	}
+
+	if p.Duration < policy.MinDuration {
+		return fmt.Errorf("deal duration of %d blocks is below the minimum of %d", p.Duration, policy.MinDuration)
+	}
+	if policy.MaxDuration > 0 && p.Duration > policy.MaxDuration {
+		return fmt.Errorf("deal duration of %d blocks is above the maximum of %d", p.Duration, policy.MaxDuration)
+	}
+
+	if size < policy.MinPieceSize {
+		return fmt.Errorf("piece size of %d bytes is below the minimum of %d", size, policy.MinPieceSize)
+	}
+	if policy.MaxPieceSize > 0 && size > policy.MaxPieceSize {
+		return fmt.Errorf("piece size of %d bytes is above the maximum of %d", size, policy.MaxPieceSize)
+	}
+	if sectorSize > 0 && size > sectorSize {
+		return fmt.Errorf("piece size of %d bytes does not fit into a sector of %d bytes", size, sectorSize)
+	}
+
+	if policy.MinPrice != nil && !policy.MinPrice.IsZero() {
+		units := big.NewInt(0).Mul(big.NewInt(0).SetUint64(size), big.NewInt(0).SetUint64(p.Duration))
+		minTotal := policy.MinPrice.MulBigInt(units)
+		if p.TotalPrice == nil || p.TotalPrice.LessThan(minTotal) {
+			return fmt.Errorf("proposed price is below the minimum of %s per byte per block", policy.MinPrice)
+		}
+	}
+
+	return nil
+}
+
+func isClientIn(client address.Address, clients []address.Address) bool {
+	for _, c := range clients {
+		if c == client {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/config"
+	"github.com/filecoin-project/go-filecoin/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluateDealPolicy(t *testing.T) {
+	t.Parallel()
+
+	addrGetter := address.NewForTestGetter()
+	client, other := addrGetter(), addrGetter()
+
+	proposal := func() *DealProposal {
+		return &DealProposal{
+			Size:       types.NewBytesAmount(1000),
+			Duration:   100,
+			TotalPrice: types.NewAttoFILFromFIL(100000),
+			Payment:    PaymentInfo{Payer: client},
+		}
+	}
+	policy := func() *config.DealPolicyConfig {
+		return &config.DealPolicyConfig{MinPrice: types.NewZeroAttoFIL()}
+	}
+
+	t.Run("accepts everything by default", func(t *testing.T) {
+		assert.NoError(t, evaluateDealPolicy(policy(), proposal(), 0))
+	})
+
+	t.Run("checks the price", func(t *testing.T) {
+		assert := assert.New(t)
+		pol := policy()
+
+		pol.MinPrice = types.NewAttoFILFromFIL(1)
+		assert.NoError(evaluateDealPolicy(pol, proposal(), 0))
+
+		pol.MinPrice = types.NewAttoFILFromFIL(2)
+		err := evaluateDealPolicy(pol, proposal(), 0)
+		assert.Contains(err.Error(), "price is below the minimum")
+	})
+
+	t.Run("checks the duration", func(t *testing.T) {
+		assert := assert.New(t)
+		pol := policy()
+
+		pol.MinDuration = 101
+		assert.Contains(evaluateDealPolicy(pol, proposal(), 0).Error(), "below the minimum")
+
+		pol.MinDuration, pol.MaxDuration = 0, 99
+		assert.Contains(evaluateDealPolicy(pol, proposal(), 0).Error(), "above the maximum")
+
+		pol.MinDuration, pol.MaxDuration = 100, 100
+		assert.NoError(evaluateDealPolicy(pol, proposal(), 0))
+	})
+
+	t.Run("checks the piece size", func(t *testing.T) {
+		assert := assert.New(t)
+		pol := policy()
+
+		pol.MinPieceSize = 1001
+		assert.Contains(evaluateDealPolicy(pol, proposal(), 0).Error(), "below the minimum")
+
+		pol.MinPieceSize, pol.MaxPieceSize = 0, 999
+		assert.Contains(evaluateDealPolicy(pol, proposal(), 0).Error(), "above the maximum")
+
+		pol.MaxPieceSize = 0
+		assert.Contains(evaluateDealPolicy(pol, proposal(), 999).Error(), "does not fit into a sector")
+		assert.NoError(evaluateDealPolicy(pol, proposal(), 1000))
+	})
+
+	t.Run("checks the client", func(t *testing.T) {
+		assert := assert.New(t)
+		pol := policy()
+
+		pol.BlockedClients = []address.Address{client}
+		assert.Error(evaluateDealPolicy(pol, proposal(), 0))
+
+		pol.BlockedClients = []address.Address{other}
+		assert.NoError(evaluateDealPolicy(pol, proposal(), 0))
+
+		pol.AllowedClients = []address.Address{other}
+		assert.Error(evaluateDealPolicy(pol, proposal(), 0))
+
+		pol.AllowedClients = []address.Address{other, client}
+		assert.NoError(evaluateDealPolicy(pol, proposal(), 0))
+	})
+}
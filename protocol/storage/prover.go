@@ -0,0 +1,366 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"sync"
+	"time"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+
+	"github.com/filecoin-project/go-filecoin/actor/builtin/miner"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/config"
+	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/plumbing/sctr"
+	"github.com/filecoin-project/go-filecoin/proofs"
+	"github.com/filecoin-project/go-filecoin/proofs/sectorbuilder"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// submitPoStTimeout bounds sending a submitPoSt message and waiting for it to
+// be mined.
+const submitPoStTimeout = 10 * time.Minute
+
+// maxProverAlerts is the number of most recent alerts the prover keeps.
+const maxProverAlerts = 20
+
+// PoStState is the state of the PoSt of a proving period.
+type PoStState string
+
+const (
+	// PoStWaiting means no PoSt is due yet.
+	PoStWaiting = PoStState("waiting")
+	// PoStGenerating means the PoSt is being generated.
+	PoStGenerating = PoStState("generating")
+	// PoStSubmitting means the PoSt was generated and its submitPoSt message
+	// is waiting to be mined.
+	PoStSubmitting = PoStState("submitting")
+	// PoStSubmitted means the submitPoSt message was mined.
+	PoStSubmitted = PoStState("submitted")
+	// PoStFailed means all attempts to generate or submit the PoSt failed.
+	PoStFailed = PoStState("failed")
+)
+
+// ProverAlert reports a proving period at risk of being missed, or missed.
+type ProverAlert struct {
+	Height  *types.BlockHeight `json:"height"`
+	Message string             `json:"message"`
+}
+
+// ProverStatus reports the PoSt of the current proving period of the miner.
+type ProverStatus struct {
+	ProvingPeriodStart *types.BlockHeight `json:"provingPeriodStart"`
+	ProvingPeriodEnd   *types.BlockHeight `json:"provingPeriodEnd"`
+	State              PoStState          `json:"state"`
+	// Attempts is the number of failed attempts to generate and submit the
+	// PoSt, and LastError the error of the last one.
+	Attempts  int    `json:"attempts"`
+	LastError string `json:"lastError,omitempty"`
+	// Faults lists the sectors the last generated PoSt found faulty.
+	Faults []uint64 `json:"faults,omitempty"`
+	// Message is the submitPoSt message of the PoSt, once sent.
+	Message *cid.Cid `json:"message,omitempty"`
+	// Alerts lists the most recent alerts, oldest first.
+	Alerts []ProverAlert `json:"alerts"`
+}
+
+// proverPorcelain is the subset of the porcelain API the prover needs.
+type proverPorcelain interface {
+	ConfigGet(dottedPath string) (interface{}, error)
+	MessageQuery(ctx context.Context, optFrom, to address.Address, method string, params ...interface{}) ([][]byte, *exec.FunctionSignature, error)
+	MessageSend(ctx context.Context, from, to address.Address, value *types.AttoFIL, gasPrice types.AttoFIL, gasLimit types.GasUnits, method string, params ...interface{}) (cid.Cid, error)
+	MessageWait(ctx context.Context, msgCid cid.Cid, cb func(*types.Block, *types.SignedMessage, *types.MessageReceipt) error) error
+}
+
+// Prover generates and submits the proofs of spacetime of a miner. It follows
+// the proving periods of the miner actor with each new head, proves the
+// sectors committed on chain once a period starts, and retries with backoff
+// until the period ends. Periods at risk of being missed raise alerts, which
+// are logged and kept in the status of the prover.
+type Prover struct {
+	minerAddr address.Address
+	ownerAddr address.Address
+	api       proverPorcelain
+
+	height      func() (*types.BlockHeight, error)
+	commitments func(ctx context.Context) (map[uint64]types.Commitments, error)
+	generate    func(commRs []proofs.CommR, seed proofs.PoStChallengeSeed) (proofs.PoStProof, []uint64, error)
+	sleep       func(time.Duration)
+	// sectors records the proven and faulty sectors.
+	sectors *sctr.Tracker
+
+	lk     sync.Mutex
+	status ProverStatus
+	// atRiskAlerted and missedAlerted are set once the current proving period
+	// raised the alert, not to raise it again with every block.
+	atRiskAlerted bool
+	missedAlerted bool
+}
+
+// NewProver returns a prover of the miner, sending its PoSts from the owner
+// address.
+func NewProver(minerAddr, ownerAddr address.Address, api proverPorcelain, nd node, commitments func(ctx context.Context) (map[uint64]types.Commitments, error)) *Prover {
+	return &Prover{
+		minerAddr:   minerAddr,
+		ownerAddr:   ownerAddr,
+		api:         api,
+		height:      nd.BlockHeight,
+		commitments: commitments,
+		generate: func(commRs []proofs.CommR, seed proofs.PoStChallengeSeed) (proofs.PoStProof, []uint64, error) {
+			res, err := nd.SectorBuilder().GeneratePoST(sectorbuilder.GeneratePoSTRequest{
+				CommRs:        commRs,
+				ChallengeSeed: seed,
+			})
+			if err != nil {
+				return proofs.PoStProof{}, nil, err
+			}
+			return res.Proof, res.Faults, nil
+		},
+		sleep:   time.Sleep,
+		sectors: nd.SectorProgress(),
+		status:  ProverStatus{State: PoStWaiting},
+	}
+}
+
+// Status returns the state of the PoSt of the current proving period.
+func (p *Prover) Status() ProverStatus {
+	p.lk.Lock()
+	defer p.lk.Unlock()
+
+	status := p.status
+	status.Alerts = append([]ProverAlert{}, p.status.Alerts...)
+	return status
+}
+
+// OnNewHead starts proving the sectors of the miner if a proving period
+// started at height h, and raises an alert if its PoSt is not on chain close
+// to the end of the period.
+func (p *Prover) OnNewHead(ctx context.Context, h *types.BlockHeight) {
+	commitments, err := p.commitments(ctx)
+	if err != nil {
+		log.Errorf("failed to get sector commitments: %s", err)
+		return
+	}
+	if len(commitments) == 0 {
+		// no sector sealed, nothing to prove
+		return
+	}
+
+	start, err := p.provingPeriodStart(ctx)
+	if err != nil {
+		log.Errorf("failed to get provingPeriodStart: %s", err)
+		return
+	}
+	end := start.Add(miner.ProvingPeriodBlocks)
+
+	cfg, err := p.config()
+	if err != nil {
+		log.Errorf("failed to get PoSt config: %s", err)
+		return
+	}
+
+	p.lk.Lock()
+	defer p.lk.Unlock()
+
+	if p.status.ProvingPeriodStart == nil || !p.status.ProvingPeriodStart.Equal(start) {
+		// the actor moves to the next proving period when a PoSt is mined
+		p.status = ProverStatus{
+			ProvingPeriodStart: start,
+			ProvingPeriodEnd:   end,
+			State:              PoStWaiting,
+			Alerts:             p.status.Alerts,
+		}
+		p.atRiskAlerted = false
+		p.missedAlerted = false
+	}
+
+	if h.LessThan(start) {
+		return
+	}
+	if h.GreaterEqual(end) {
+		if !p.missedAlerted {
+			p.missedAlerted = true
+			p.alert(h, "missed the proving period from %s to %s, no PoSt was mined in time", start, end)
+		}
+		return
+	}
+
+	if p.status.State == PoStWaiting {
+		p.status.State = PoStGenerating
+		sectorIDs := make([]uint64, 0, len(commitments))
+		commRs := make([]proofs.CommR, 0, len(commitments))
+		for id, c := range commitments {
+			sectorIDs = append(sectorIDs, id)
+			commRs = append(commRs, c.CommR)
+		}
+		go p.prove(start, end, sectorIDs, commRs, cfg)
+	}
+
+	if p.status.State != PoStSubmitted && !p.atRiskAlerted && end.Sub(h).LessEqual(types.NewBlockHeight(cfg.AtRiskBlocks)) {
+		p.atRiskAlerted = true
+		p.alert(h, "PoSt of the proving period ending at %s is not on chain %s blocks before its end, it is %s", end, end.Sub(h), p.status.State)
+	}
+}
+
+// prove generates and submits the PoSt of the proving period from start to
+// end, retrying on failure.
+func (p *Prover) prove(start, end *types.BlockHeight, sectorIDs []uint64, commRs []proofs.CommR, cfg *config.PoStConfig) {
+	backoff, err := time.ParseDuration(cfg.RetryBackoff)
+	if err != nil {
+		log.Errorf("invalid PoSt retry backoff %s, retrying without backoff: %s", cfg.RetryBackoff, err)
+		backoff = 0
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := p.proveOnce(start, end, sectorIDs, commRs, cfg)
+		if err == nil {
+			log.Debug("submitted PoSt")
+			return
+		}
+
+		tooLate := err == errProvingPeriodOver
+		retry := !tooLate && attempt < cfg.Retries
+		p.update(start, func(s *ProverStatus) {
+			s.Attempts++
+			s.LastError = err.Error()
+			if !retry {
+				s.State = PoStFailed
+			}
+		})
+		if !retry {
+			h, _ := p.height()
+			p.lk.Lock()
+			p.alert(h, "failed to submit PoSt of the proving period ending at %s after %d attempts: %s", end, attempt+1, err)
+			p.lk.Unlock()
+			return
+		}
+
+		log.Warningf("failed to submit PoSt, retrying in %s: %s", backoff, err)
+		p.sleep(backoff)
+		backoff *= 2
+	}
+}
+
+var errProvingPeriodOver = errors.New("proving period is over")
+
+func (p *Prover) proveOnce(start, end *types.BlockHeight, sectorIDs []uint64, commRs []proofs.CommR, cfg *config.PoStConfig) error {
+	p.update(start, func(s *ProverStatus) {
+		s.State = PoStGenerating
+	})
+
+	// TODO: real seed generation
+	seed := proofs.PoStChallengeSeed{}
+	if _, err := rand.Read(seed[:]); err != nil {
+		return errors.Wrap(err, "failed to generate challenge seed")
+	}
+
+	proof, faults, err := p.generate(commRs, seed)
+	if err != nil {
+		return errors.Wrap(err, "failed to generate PoSt")
+	}
+	if len(faults) != 0 {
+		log.Warningf("some faults when generating PoSt: %v", faults)
+		// TODO: proper fault handling
+	}
+
+	height, err := p.height()
+	if err != nil {
+		return errors.Wrap(err, "failed to get block height")
+	}
+	if height.GreaterEqual(end) {
+		return errProvingPeriodOver
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), submitPoStTimeout)
+	defer cancel()
+
+	msgCid, err := p.api.MessageSend(ctx, p.ownerAddr, p.minerAddr, types.ZeroAttoFIL, *cfg.GasPrice, types.NewGasUnits(cfg.GasLimit), "submitPoSt", proof[:])
+	if err != nil {
+		return errors.Wrap(err, "failed to send submitPoSt message")
+	}
+	p.update(start, func(s *ProverStatus) {
+		s.State = PoStSubmitting
+		s.Faults = faults
+		s.Message = &msgCid
+	})
+
+	var receipt *types.MessageReceipt
+	err = p.api.MessageWait(ctx, msgCid, func(_ *types.Block, _ *types.SignedMessage, r *types.MessageReceipt) error {
+		receipt = r
+		return nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to wait for submitPoSt message %s", msgCid)
+	}
+	if receipt != nil && receipt.ExitCode != 0 {
+		return fmt.Errorf("submitPoSt message %s failed with exit code %d", msgCid, receipt.ExitCode)
+	}
+
+	// the PoSt proves the sectors, but for the ones it found faulty
+	faulty := make(map[uint64]bool, len(faults))
+	for _, id := range faults {
+		faulty[id] = true
+	}
+	for _, id := range sectorIDs {
+		if faulty[id] {
+			p.sectors.Enter(id, sctr.Faulted)
+		} else {
+			p.sectors.Enter(id, sctr.Proving)
+		}
+	}
+
+	p.update(start, func(s *ProverStatus) {
+		s.State = PoStSubmitted
+	})
+	return nil
+}
+
+// update changes the status if it still is the status of the proving period
+// starting at start.
+func (p *Prover) update(start *types.BlockHeight, f func(*ProverStatus)) {
+	p.lk.Lock()
+	defer p.lk.Unlock()
+	if p.status.ProvingPeriodStart != nil && p.status.ProvingPeriodStart.Equal(start) {
+		f(&p.status)
+	}
+}
+
+// alert logs the alert and keeps it in the status. The caller holds lk.
+func (p *Prover) alert(h *types.BlockHeight, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	log.Errorf("PoSt alert: %s", msg)
+
+	p.status.Alerts = append(p.status.Alerts, ProverAlert{Height: h, Message: msg})
+	if len(p.status.Alerts) > maxProverAlerts {
+		p.status.Alerts = p.status.Alerts[len(p.status.Alerts)-maxProverAlerts:]
+	}
+}
+
+func (p *Prover) provingPeriodStart(ctx context.Context) (*types.BlockHeight, error) {
+	res, _, err := p.api.MessageQuery(
+		ctx,
+		address.Address{},
+		p.minerAddr,
+		"getProvingPeriodStart",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return types.NewBlockHeightFromBytes(res[0]), nil
+}
+
+func (p *Prover) config() (*config.PoStConfig, error) {
+	val, err := p.api.ConfigGet("mining.post")
+	if err != nil {
+		return nil, err
+	}
+	cfg, ok := val.(*config.PoStConfig)
+	if !ok {
+		return nil, errors.New("could not retrieve post from config")
+	}
+	return cfg, nil
+}
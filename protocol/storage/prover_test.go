@@ -0,0 +1,218 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+
+	"github.com/filecoin-project/go-filecoin/actor/builtin/miner"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/plumbing/cfg"
+	"github.com/filecoin-project/go-filecoin/plumbing/sctr"
+	"github.com/filecoin-project/go-filecoin/proofs"
+	"github.com/filecoin-project/go-filecoin/repo"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type proverTestPorcelain struct {
+	config      *cfg.Config
+	periodStart *types.BlockHeight
+	exitCode    uint8
+
+	lk   sync.Mutex
+	sent []string
+}
+
+func (ptp *proverTestPorcelain) ConfigGet(dottedPath string) (interface{}, error) {
+	return ptp.config.Get(dottedPath)
+}
+
+func (ptp *proverTestPorcelain) MessageQuery(ctx context.Context, optFrom, to address.Address, method string, params ...interface{}) ([][]byte, *exec.FunctionSignature, error) {
+	return [][]byte{ptp.periodStart.Bytes()}, nil, nil
+}
+
+func (ptp *proverTestPorcelain) MessageSend(ctx context.Context, from, to address.Address, value *types.AttoFIL, gasPrice types.AttoFIL, gasLimit types.GasUnits, method string, params ...interface{}) (cid.Cid, error) {
+	ptp.lk.Lock()
+	defer ptp.lk.Unlock()
+	ptp.sent = append(ptp.sent, method)
+	return types.SomeCid(), nil
+}
+
+func (ptp *proverTestPorcelain) MessageWait(ctx context.Context, msgCid cid.Cid, cb func(*types.Block, *types.SignedMessage, *types.MessageReceipt) error) error {
+	return cb(nil, nil, &types.MessageReceipt{ExitCode: ptp.exitCode})
+}
+
+func (ptp *proverTestPorcelain) sentMessages() []string {
+	ptp.lk.Lock()
+	defer ptp.lk.Unlock()
+	return append([]string{}, ptp.sent...)
+}
+
+// newTestProver returns a prover of a miner with a sector committed, whose
+// current proving period starts at block 100, and a function failing the
+// generation of the next n PoSts.
+func newTestProver(height *types.BlockHeight) (*Prover, *proverTestPorcelain, func(n int), *[]time.Duration) {
+	api := &proverTestPorcelain{
+		config:      cfg.NewConfig(repo.NewInMemoryRepo()),
+		periodStart: types.NewBlockHeight(100),
+	}
+
+	var lk sync.Mutex
+	failures := 0
+	var slept []time.Duration
+
+	addrGetter := address.NewForTestGetter()
+	p := &Prover{
+		minerAddr: addrGetter(),
+		ownerAddr: addrGetter(),
+		api:       api,
+		height:    func() (*types.BlockHeight, error) { return height, nil },
+		commitments: func(ctx context.Context) (map[uint64]types.Commitments, error) {
+			return map[uint64]types.Commitments{1: {}}, nil
+		},
+		generate: func(commRs []proofs.CommR, seed proofs.PoStChallengeSeed) (proofs.PoStProof, []uint64, error) {
+			lk.Lock()
+			defer lk.Unlock()
+			if failures > 0 {
+				failures--
+				return proofs.PoStProof{}, nil, errors.New("out of memory")
+			}
+			return proofs.PoStProof{}, nil, nil
+		},
+		sleep: func(d time.Duration) {
+			lk.Lock()
+			defer lk.Unlock()
+			slept = append(slept, d)
+		},
+		sectors: sctr.NewTracker(),
+		status:  ProverStatus{State: PoStWaiting},
+	}
+	fail := func(n int) {
+		lk.Lock()
+		defer lk.Unlock()
+		failures = n
+	}
+	return p, api, fail, &slept
+}
+
+func waitForPoSt(p *Prover, state PoStState) bool {
+	for i := 0; i < 100; i++ {
+		if p.Status().State == state {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func TestProverSubmitsPoSt(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+	assert := assert.New(t)
+
+	p, api, _, _ := newTestProver(types.NewBlockHeight(101))
+
+	p.OnNewHead(context.Background(), types.NewBlockHeight(99))
+	assert.Equal(PoStWaiting, p.Status().State)
+
+	p.OnNewHead(context.Background(), types.NewBlockHeight(101))
+	require.True(waitForPoSt(p, PoStSubmitted))
+	p.OnNewHead(context.Background(), types.NewBlockHeight(102))
+
+	status := p.Status()
+	assert.Equal(types.NewBlockHeight(100), status.ProvingPeriodStart)
+	assert.Equal(types.NewBlockHeight(100).Add(miner.ProvingPeriodBlocks), status.ProvingPeriodEnd)
+	assert.Equal(0, status.Attempts)
+	assert.Equal([]string{"submitPoSt"}, api.sentMessages())
+	assert.Empty(status.Alerts)
+
+	progress, err := p.sectors.Progress(1)
+	require.NoError(err)
+	assert.Equal(sctr.Proving, progress.Stage)
+}
+
+func TestProverRetries(t *testing.T) {
+	t.Parallel()
+
+	t.Run("with backoff until the PoSt is submitted", func(t *testing.T) {
+		p, api, fail, slept := newTestProver(types.NewBlockHeight(101))
+		fail(2)
+
+		p.OnNewHead(context.Background(), types.NewBlockHeight(101))
+		require.True(t, waitForPoSt(p, PoStSubmitted))
+
+		status := p.Status()
+		assert.Equal(t, 2, status.Attempts)
+		assert.Contains(t, status.LastError, "out of memory")
+		assert.Equal(t, []time.Duration{30 * time.Second, time.Minute}, *slept)
+		assert.Equal(t, []string{"submitPoSt"}, api.sentMessages())
+	})
+
+	t.Run("and alerts when giving up", func(t *testing.T) {
+		p, api, fail, _ := newTestProver(types.NewBlockHeight(101))
+		fail(10)
+
+		p.OnNewHead(context.Background(), types.NewBlockHeight(101))
+		require.True(t, waitForPoSt(p, PoStFailed))
+
+		status := p.Status()
+		assert.Equal(t, 6, status.Attempts)
+		assert.Empty(t, api.sentMessages())
+		require.Len(t, status.Alerts, 1)
+		assert.Contains(t, status.Alerts[0].Message, "after 6 attempts")
+	})
+
+	t.Run("when the message fails", func(t *testing.T) {
+		p, api, _, _ := newTestProver(types.NewBlockHeight(101))
+		api.exitCode = 1
+		require.NoError(t, api.config.Set("mining.post.retries", "1"))
+
+		p.OnNewHead(context.Background(), types.NewBlockHeight(101))
+		require.True(t, waitForPoSt(p, PoStFailed))
+		assert.Equal(t, []string{"submitPoSt", "submitPoSt"}, api.sentMessages())
+		assert.Contains(t, p.Status().LastError, "exit code 1")
+	})
+}
+
+func TestProverAlerts(t *testing.T) {
+	t.Parallel()
+
+	end := types.NewBlockHeight(100).Add(miner.ProvingPeriodBlocks)
+
+	t.Run("once when the PoSt is at risk", func(t *testing.T) {
+		atRisk := end.Sub(types.NewBlockHeight(10))
+		p, _, fail, _ := newTestProver(atRisk)
+		fail(10)
+
+		p.OnNewHead(context.Background(), atRisk)
+		p.OnNewHead(context.Background(), atRisk.Add(types.NewBlockHeight(1)))
+
+		atRiskAlerts := 0
+		for _, a := range p.Status().Alerts {
+			if strings.Contains(a.Message, "is not on chain 10 blocks before its end") {
+				atRiskAlerts++
+			}
+		}
+		assert.Equal(t, 1, atRiskAlerts)
+	})
+
+	t.Run("once when the proving period is missed", func(t *testing.T) {
+		p, api, _, _ := newTestProver(end)
+
+		p.OnNewHead(context.Background(), end)
+		p.OnNewHead(context.Background(), end.Add(types.NewBlockHeight(1)))
+
+		alerts := p.Status().Alerts
+		require.Len(t, alerts, 1)
+		assert.Contains(t, alerts[0].Message, "missed the proving period")
+		assert.Empty(t, api.sentMessages())
+	})
+}
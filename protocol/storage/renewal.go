@@ -0,0 +1,182 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+
+	"github.com/filecoin-project/go-filecoin/actor/builtin/miner"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// SetDealRenewal opts the deal with the given proposal cid in to, or out of,
+// automatic renewal. Renewals cost at most maxPrice per byte per block, or
+// the configured client.maxRenewalPrice if maxPrice is nil.
+func (smc *Client) SetDealRenewal(proposalCid cid.Cid, renew bool, maxPrice *types.AttoFIL) error {
+	smc.dealsLk.Lock()
+	defer smc.dealsLk.Unlock()
+	deal, ok := smc.deals[proposalCid]
+	if !ok {
+		return fmt.Errorf("no such proposal by cid: %s", proposalCid)
+	}
+	if deal.RenewedBy != nil {
+		return fmt.Errorf("deal was renewed already by %s", deal.RenewedBy)
+	}
+
+	deal.Renew = renew
+	deal.MaxRenewalPrice = maxPrice
+	return smc.saveDeal(proposalCid)
+}
+
+// renewExpiringDeals proposes the renewal of the deals that are to be renewed
+// and expire within the configured renewal window of the given height.
+func (smc *Client) renewExpiringDeals(height uint64) {
+	window, err := smc.api.ConfigGet("client.renewalWindow")
+	if err != nil {
+		log.Errorf("failed to get renewal window: %s", err)
+		return
+	}
+	windowBlocks, ok := window.(uint64)
+	if !ok {
+		log.Error("could not retrieve renewalWindow from config")
+		return
+	}
+	// deals expiring before this height are renewed
+	renewBefore := types.NewBlockHeight(height + windowBlocks)
+
+	for _, c := range smc.dealsToRenew(renewBefore) {
+		go smc.renewDeal(c)
+	}
+}
+
+// dealsToRenew returns the deals to be renewed that expire before h, and
+// marks them as being renewed.
+func (smc *Client) dealsToRenew(h *types.BlockHeight) []cid.Cid {
+	smc.dealsLk.Lock()
+	defer smc.dealsLk.Unlock()
+
+	var toRenew []cid.Cid
+	for c, deal := range smc.deals {
+		if !deal.Renew || deal.RenewedBy != nil || smc.renewing[c] || deal.Expiry == nil {
+			continue
+		}
+		switch deal.Response.State {
+		case Rejected, Failed, Unknown:
+			continue
+		}
+		if deal.Expiry.LessThan(h) {
+			smc.renewing[c] = true
+			toRenew = append(toRenew, c)
+		}
+	}
+	return toRenew
+}
+
+// renewDeal proposes a deal storing the data of the deal with the given
+// proposal cid with the same miner for the same duration. The renewal is
+// retried on the next head if it fails.
+func (smc *Client) renewDeal(c cid.Cid) {
+	defer func() {
+		smc.dealsLk.Lock()
+		delete(smc.renewing, c)
+		smc.dealsLk.Unlock()
+	}()
+
+	ctx := context.Background()
+
+	smc.dealsLk.Lock()
+	deal := *smc.deals[c]
+	smc.dealsLk.Unlock()
+
+	maxPrice, err := smc.renewalPriceCeiling(&deal)
+	if err != nil {
+		log.Errorf("could not renew deal %s: %s", c, err)
+		return
+	}
+
+	ask, err := smc.renewalAsk(ctx, &deal, maxPrice)
+	if err != nil {
+		log.Errorf("could not renew deal %s: %s", c, err)
+		return
+	}
+
+	resp, err := smc.ProposeDeal(ctx, deal.Miner, deal.Proposal.PieceRef, ask.ID.Uint64(), deal.Proposal.Duration, true)
+	if err != nil {
+		log.Errorf("could not renew deal %s: %s", c, err)
+		return
+	}
+
+	smc.dealsLk.Lock()
+	defer smc.dealsLk.Unlock()
+	smc.deals[c].RenewedBy = &resp.ProposalCid
+	// the renewal is renewed in turn
+	renewal := smc.deals[resp.ProposalCid]
+	renewal.Renew = true
+	renewal.MaxRenewalPrice = deal.MaxRenewalPrice
+	if err := smc.saveDeal(resp.ProposalCid); err != nil {
+		log.Errorf("could not save renewal %s: %s", resp.ProposalCid, err)
+	}
+	if err := smc.saveDeal(c); err != nil {
+		log.Errorf("could not save renewed deal %s: %s", c, err)
+		return
+	}
+	log.Infof("renewed deal %s by %s", c, resp.ProposalCid)
+}
+
+// renewalPriceCeiling returns the highest price per byte per block the client
+// pays for renewing the deal.
+func (smc *Client) renewalPriceCeiling(deal *clientDeal) (*types.AttoFIL, error) {
+	if deal.MaxRenewalPrice != nil {
+		return deal.MaxRenewalPrice, nil
+	}
+
+	configured, err := smc.api.ConfigGet("client.maxRenewalPrice")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get max renewal price")
+	}
+	maxPrice, ok := configured.(*types.AttoFIL)
+	if !ok {
+		return nil, errors.New("could not retrieve maxRenewalPrice from config")
+	}
+	if !maxPrice.IsZero() {
+		return maxPrice, nil
+	}
+
+	if deal.AskPrice == nil {
+		return nil, errors.New("no price ceiling for renewal")
+	}
+	return deal.AskPrice, nil
+}
+
+// renewalAsk returns the cheapest ask of the miner of the deal costing at most
+// maxPrice that covers the piece of the deal.
+func (smc *Client) renewalAsk(ctx context.Context, deal *clientDeal, maxPrice *types.AttoFIL) (*miner.Ask, error) {
+	pid, err := smc.api.MinerGetPeerID(ctx, deal.Miner)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := smc.QueryAsks(ctx, pid)
+	if err != nil {
+		return nil, err
+	}
+
+	var cheapest *miner.Ask
+	for _, ask := range resp.Asks {
+		if ask.Price.GreaterThan(maxPrice) {
+			continue
+		}
+		if checkPieceSize(*ask, deal.Proposal.Size.Uint64()) != nil {
+			continue
+		}
+		if cheapest == nil || ask.Price.LessThan(cheapest.Price) {
+			cheapest = ask
+		}
+	}
+	if cheapest == nil {
+		return nil, fmt.Errorf("miner %s has no ask at a price of at most %s", deal.Miner, maxPrice)
+	}
+	return cheapest, nil
+}
@@ -0,0 +1,275 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	cbor "gx/ipfs/QmRoARq3nkUb13HSKZGepCZSWe5GrVPwx7xURJGZ7KWv9V/go-ipld-cbor"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	"gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
+	"gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore/query"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+const replicationDatastorePrefix = "replications"
+
+func init() {
+	cbor.RegisterCborType(replicationSet{})
+}
+
+// replicationSet is data the client keeps stored by several miners. The
+// client replaces the replicas whose deals fail, until the set expires.
+type replicationSet struct {
+	Data     cid.Cid
+	Replicas int
+	// Expiry is the block height the deals of the replicas end at.
+	Expiry *types.BlockHeight
+	// MaxPrice is the highest price per byte per block the client pays for a
+	// replica, the price of the ask of the first replica.
+	MaxPrice *types.AttoFIL
+	// Deals are the proposal cids of the deals made for the replicas,
+	// including the failed ones.
+	Deals []cid.Cid
+}
+
+// ReplicaStatus is the state of a deal storing a replica.
+type ReplicaStatus struct {
+	ProposalCid cid.Cid         `json:"proposalCid"`
+	Miner       address.Address `json:"miner"`
+	State       DealState       `json:"state"`
+}
+
+// ReplicationStatus describes the health of data replicated to several
+// miners.
+type ReplicationStatus struct {
+	Data cid.Cid `json:"data"`
+	// Replicas is the number of replicas the client keeps.
+	Replicas int `json:"replicas"`
+	// Healthy is the number of replicas whose deals have not failed.
+	Healthy int                `json:"healthy"`
+	Expiry  *types.BlockHeight `json:"expiry"`
+	Deals   []ReplicaStatus    `json:"deals"`
+}
+
+// ProposeReplicatedDeal stores data with replicas miners. The deal with the
+// given miner and ask is the first replica, the others are proposed to the
+// miners selected by SelectMiners. The client replaces the replicas
+// whose deals fail for as long as the deals last. It returns the responses to
+// the proposals that were accepted.
+func (smc *Client) ProposeReplicatedDeal(ctx context.Context, miner address.Address, data cid.Cid, askID uint64, duration uint64, replicas int, allowDuplicates bool) ([]*DealResponse, error) {
+	if replicas < 1 {
+		return nil, errors.New("at least one replica is required")
+	}
+
+	smc.dealsLk.Lock()
+	_, ok := smc.replications[data]
+	smc.dealsLk.Unlock()
+	if ok {
+		return nil, fmt.Errorf("data %s is replicated already", data)
+	}
+
+	resp, err := smc.ProposeDeal(ctx, miner, data, askID, duration, allowDuplicates)
+	if err != nil {
+		return nil, err
+	}
+
+	smc.dealsLk.Lock()
+	first := smc.deals[resp.ProposalCid]
+	set := &replicationSet{
+		Data:     data,
+		Replicas: replicas,
+		Expiry:   first.Expiry,
+		MaxPrice: first.AskPrice,
+		Deals:    []cid.Cid{resp.ProposalCid},
+	}
+	smc.replications[data] = set
+	smc.repairing[data] = true
+	err = smc.saveReplicationSet(data)
+	smc.dealsLk.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	responses, err := smc.replicate(ctx, data)
+	return append([]*DealResponse{resp}, responses...), err
+}
+
+// Replication returns the status of the replicas of data.
+func (smc *Client) Replication(data cid.Cid) (*ReplicationStatus, error) {
+	smc.dealsLk.Lock()
+	defer smc.dealsLk.Unlock()
+
+	set, ok := smc.replications[data]
+	if !ok {
+		return nil, fmt.Errorf("data %s is not replicated", data)
+	}
+
+	status := &ReplicationStatus{
+		Data:     data,
+		Replicas: set.Replicas,
+		Expiry:   set.Expiry,
+	}
+	for _, c := range set.Deals {
+		deal := smc.deals[c]
+		status.Deals = append(status.Deals, ReplicaStatus{
+			ProposalCid: c,
+			Miner:       deal.Miner,
+			State:       deal.Response.State,
+		})
+		if isLiveReplica(deal) {
+			status.Healthy++
+		}
+	}
+	return status, nil
+}
+
+// isLiveReplica returns true if the deal stores, or is about to store, a
+// replica.
+func isLiveReplica(deal *clientDeal) bool {
+	switch deal.Response.State {
+	case Rejected, Failed, Unknown:
+		return false
+	}
+	return true
+}
+
+// replicate proposes deals for the missing replicas of data, as long as
+// there are candidate miners. It returns the responses to the proposals that
+// were accepted, and an error if replicas are still missing. The caller must
+// have marked the set as being repaired.
+func (smc *Client) replicate(ctx context.Context, data cid.Cid) ([]*DealResponse, error) {
+	defer func() {
+		smc.dealsLk.Lock()
+		delete(smc.repairing, data)
+		smc.dealsLk.Unlock()
+	}()
+
+	chainHeight, err := smc.api.ChainBlockHeight(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	smc.dealsLk.Lock()
+	set := smc.replications[data]
+	exclude := map[address.Address]bool{}
+	missing := set.Replicas
+	for _, c := range set.Deals {
+		deal := smc.deals[c]
+		// miners whose replica failed aren't asked again
+		exclude[deal.Miner] = true
+		if isLiveReplica(deal) {
+			missing--
+		}
+	}
+	expiry, maxPrice := set.Expiry, set.MaxPrice
+	smc.dealsLk.Unlock()
+
+	if missing <= 0 {
+		return nil, nil
+	}
+	if !chainHeight.LessThan(expiry) {
+		return nil, nil
+	}
+	// replacement replicas end with the others
+	duration := expiry.Sub(chainHeight).AsBigInt().Uint64()
+
+	choices, err := smc.SelectMiners(ctx, data, maxPrice, exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	var responses []*DealResponse
+	for _, choice := range choices {
+		if missing == 0 {
+			break
+		}
+		resp, err := smc.ProposeDeal(ctx, choice.Miner, data, choice.Ask.ID.Uint64(), duration, false)
+		if err != nil {
+			log.Warningf("miner %s did not take replica of %s: %s", choice.Miner, data, err)
+			continue
+		}
+		responses = append(responses, resp)
+		missing--
+
+		smc.dealsLk.Lock()
+		set.Deals = append(set.Deals, resp.ProposalCid)
+		err = smc.saveReplicationSet(data)
+		smc.dealsLk.Unlock()
+		if err != nil {
+			return responses, err
+		}
+	}
+
+	if missing > 0 {
+		return responses, fmt.Errorf("could not find miners for %d of %d replicas", missing, set.Replicas)
+	}
+	return responses, nil
+}
+
+// repairReplications replaces the failed replicas of the replication sets
+// that have not expired at height h.
+func (smc *Client) repairReplications(h *types.BlockHeight) {
+	smc.dealsLk.Lock()
+	var toRepair []cid.Cid
+	for data, set := range smc.replications {
+		if smc.repairing[data] || !h.LessThan(set.Expiry) {
+			continue
+		}
+		live := 0
+		for _, c := range set.Deals {
+			if isLiveReplica(smc.deals[c]) {
+				live++
+			}
+		}
+		if live < set.Replicas {
+			smc.repairing[data] = true
+			toRepair = append(toRepair, data)
+		}
+	}
+	smc.dealsLk.Unlock()
+
+	for _, data := range toRepair {
+		go func(data cid.Cid) {
+			if _, err := smc.replicate(context.Background(), data); err != nil {
+				log.Errorf("could not repair replicas of %s: %s", data, err)
+			}
+		}(data)
+	}
+}
+
+func (smc *Client) loadReplications() error {
+	res, err := smc.dealsDs.Query(query.Query{
+		Prefix: "/" + replicationDatastorePrefix,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to query replications from datastore")
+	}
+	defer res.Close() // nolint: errcheck
+
+	for entry := range res.Next() {
+		var set replicationSet
+		if err := cbor.DecodeInto(entry.Value, &set); err != nil {
+			return errors.Wrap(err, "failed to unmarshal replication from datastore")
+		}
+		smc.replications[set.Data] = &set
+	}
+	return nil
+}
+
+// saveReplicationSet persists the replication set of data. smc.dealsLk must
+// be held.
+func (smc *Client) saveReplicationSet(data cid.Cid) error {
+	datum, err := cbor.DumpObject(smc.replications[data])
+	if err != nil {
+		return errors.Wrap(err, "could not marshal replication")
+	}
+
+	key := datastore.KeyWithNamespaces([]string{replicationDatastorePrefix, data.String()})
+	if err := smc.dealsDs.Put(key, datum); err != nil {
+		return errors.Wrap(err, "could not save replication to disk")
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"gx/ipfs/QmY5Grm8pJdiSSVsYxx4uNRgweY72EmYwuSDbRnbFok3iY/go-libp2p-peer"
+
+	"github.com/filecoin-project/go-filecoin/actor/builtin/miner"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/repo"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/util/convert"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientReplicatesDeals(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	addrGetter := address.NewForTestGetter()
+	first, cheap, pricier, expensive, late := addrGetter(), addrGetter(), addrGetter(), addrGetter(), addrGetter()
+
+	// the miners operated by the connected peers, with the price of their ask
+	prices := map[peer.ID]uint64{"cheap": 20, "pricier": 30, "expensive": 50}
+	miners := map[peer.ID]address.Address{"cheap": cheap, "pricier": pricier, "expensive": expensive, "late": late}
+
+	var proposedTo []address.Address
+	testAPI := newTestClientAPI()
+	testNode := newTestClientNode(func(request interface{}) (interface{}, error) {
+		p, ok := request.(*DealProposal)
+		if !ok {
+			return nil, fmt.Errorf("unexpected request %T", request)
+		}
+		proposedTo = append(proposedTo, p.MinerAddress)
+		pcid, err := convert.ToCid(p)
+		require.NoError(err)
+		sig, err := p.Terms().Sign(testAPI.signer, testAPI.target)
+		require.NoError(err)
+		return &DealResponse{State: Accepted, ProposalCid: pcid, Signature: sig}, nil
+	})
+	testNode.peers = []peer.ID{"expensive", "pricier", "cheap"}
+	testNode.peerResponder = func(pid peer.ID, request interface{}) (interface{}, error) {
+		return &AskResponse{Miner: miners[pid], Asks: []*miner.Ask{
+			{ID: big.NewInt(1), Price: types.NewAttoFILFromFIL(prices[pid]), Expiry: types.NewBlockHeight(20000)},
+		}}, nil
+	}
+
+	client, err := NewClient(testNode, testAPI, repo.NewInMemoryRepo().DealsDs)
+	require.NoError(err)
+
+	ctx := context.Background()
+	data := types.SomeCid()
+
+	// the first replica is at a price of 32, miners asking more are skipped
+	responses, err := client.ProposeReplicatedDeal(ctx, first, data, 0, 10000, 3, false)
+	require.NoError(err)
+	assert.Equal(3, len(responses))
+	assert.Equal([]address.Address{first, cheap, pricier}, proposedTo)
+
+	status, err := client.Replication(data)
+	require.NoError(err)
+	assert.Equal(3, status.Replicas)
+	assert.Equal(3, status.Healthy)
+	assert.Equal(types.NewBlockHeight(10773), status.Expiry)
+
+	_, err = client.ProposeReplicatedDeal(ctx, first, data, 0, 10000, 3, false)
+	assert.Contains(err.Error(), "replicated already")
+
+	t.Run("replaces failed replicas", func(t *testing.T) {
+		client.deals[responses[1].ProposalCid].Response.State = Failed
+
+		status, err := client.Replication(data)
+		require.NoError(err)
+		assert.Equal(2, status.Healthy)
+
+		// no miner but the expensive one is left
+		client.repairing[data] = true
+		_, err = client.replicate(ctx, data)
+		assert.Contains(err.Error(), "could not find miners for 1 of 3 replicas")
+
+		prices["late"] = 25
+		testNode.peers = append(testNode.peers, "late")
+		testAPI.blockHeight = types.NewBlockHeight(1773)
+		client.repairing[data] = true
+		replaced, err := client.replicate(ctx, data)
+		require.NoError(err)
+		require.Equal(1, len(replaced))
+		assert.Equal(late, proposedTo[len(proposedTo)-1])
+		// the replacement ends with the other replicas
+		assert.Equal(types.NewBlockHeight(10773), client.deals[replaced[0].ProposalCid].Expiry)
+
+		status, err = client.Replication(data)
+		require.NoError(err)
+		assert.Equal(3, status.Healthy)
+		assert.Equal(4, len(status.Deals))
+		assert.False(client.repairing[data])
+	})
+
+	t.Run("prefers miners that did not fail the client", func(t *testing.T) {
+		client.reputation.update(cheap, func(r *MinerReputation) {
+			r.Failed++
+			r.Faults++
+		})
+
+		choices, err := client.SelectMiners(ctx, data, types.NewAttoFILFromFIL(40), nil)
+		require.NoError(err)
+		var order []address.Address
+		for _, c := range choices {
+			order = append(order, c.Miner)
+		}
+		// cheap is the cheapest, but lost its replica
+		assert.Equal([]address.Address{late, pricier, cheap}, order)
+	})
+}
@@ -0,0 +1,326 @@
+package storage
+
+import (
+	"context"
+	"math/big"
+	"sort"
+	"sync"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	cbor "gx/ipfs/QmRoARq3nkUb13HSKZGepCZSWe5GrVPwx7xURJGZ7KWv9V/go-ipld-cbor"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	"gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
+	"gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore/query"
+
+	"github.com/filecoin-project/go-filecoin/actor/builtin/miner"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/repo"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+const reputationDatastorePrefix = "reputation"
+
+func init() {
+	cbor.RegisterCborType(MinerReputation{})
+}
+
+// MinerReputation is what the client observed of the deals it made with a
+// miner.
+type MinerReputation struct {
+	Miner address.Address `json:"miner"`
+
+	// Proposals is the number of proposals the client sent the miner, of
+	// which the miner accepted Accepted and rejected Rejected. The others
+	// did not get a response.
+	Proposals uint64 `json:"proposals"`
+	Accepted  uint64 `json:"accepted"`
+	Rejected  uint64 `json:"rejected"`
+
+	// Proven is the number of deals the miner sealed and started proving,
+	// Failed the number of deals that failed.
+	Proven uint64 `json:"proven"`
+	Failed uint64 `json:"failed"`
+
+	// Faults is the number of failed proofs of spacetime of the miner
+	// observed on chain.
+	Faults uint64 `json:"faults"`
+
+	// TransferredBytes is the size of the data transferred to the miner in
+	// TransferSeconds seconds.
+	TransferredBytes uint64 `json:"transferredBytes"`
+	TransferSeconds  uint64 `json:"transferSeconds"`
+}
+
+// AcceptanceRate returns the fraction of proposals the miner accepted.
+func (r *MinerReputation) AcceptanceRate() float64 {
+	if r.Proposals == 0 {
+		return 0
+	}
+	return float64(r.Accepted) / float64(r.Proposals)
+}
+
+// TransferSpeed returns the average speed of the transfers to the miner in
+// bytes per second, 0 if unknown.
+func (r *MinerReputation) TransferSpeed() uint64 {
+	if r.TransferSeconds == 0 {
+		return 0
+	}
+	return r.TransferredBytes / r.TransferSeconds
+}
+
+// Score rates the miner between 0 and 1, from the chance it accepts a
+// proposal and the chance it keeps proving the data of an accepted deal.
+// Both are smoothed so that miners the client knows nothing of score 0.25,
+// and a single outcome doesn't make or break a miner.
+func (r *MinerReputation) Score() float64 {
+	acceptance := float64(r.Accepted+1) / float64(r.Proposals+2)
+	reliability := float64(r.Proven+1) / float64(r.Proven+r.Failed+r.Faults+2)
+	return acceptance * reliability
+}
+
+// reputationStore keeps the reputations of the miners the client made deals
+// with.
+type reputationStore struct {
+	lk     sync.Mutex
+	ds     repo.Datastore
+	miners map[address.Address]*MinerReputation
+}
+
+func newReputationStore(ds repo.Datastore) (*reputationStore, error) {
+	rs := &reputationStore{
+		ds:     ds,
+		miners: make(map[address.Address]*MinerReputation),
+	}
+
+	res, err := ds.Query(query.Query{Prefix: "/" + reputationDatastorePrefix})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query reputations from datastore")
+	}
+	defer res.Close() // nolint: errcheck
+	for entry := range res.Next() {
+		var r MinerReputation
+		if err := cbor.DecodeInto(entry.Value, &r); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal reputation from datastore")
+		}
+		rs.miners[r.Miner] = &r
+	}
+	return rs, nil
+}
+
+// get returns a copy of the reputation of miner m.
+func (rs *reputationStore) get(m address.Address) MinerReputation {
+	rs.lk.Lock()
+	defer rs.lk.Unlock()
+	if r, ok := rs.miners[m]; ok {
+		return *r
+	}
+	return MinerReputation{Miner: m}
+}
+
+// list returns copies of all reputations.
+func (rs *reputationStore) list() []MinerReputation {
+	rs.lk.Lock()
+	defer rs.lk.Unlock()
+	var out []MinerReputation
+	for _, r := range rs.miners {
+		out = append(out, *r)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Miner.String() < out[j].Miner.String()
+	})
+	return out
+}
+
+// update applies f to the reputation of miner m and persists it. Failures
+// to persist are logged, reputations are best effort.
+func (rs *reputationStore) update(m address.Address, f func(*MinerReputation)) {
+	rs.lk.Lock()
+	defer rs.lk.Unlock()
+
+	r, ok := rs.miners[m]
+	if !ok {
+		r = &MinerReputation{Miner: m}
+		rs.miners[m] = r
+	}
+	f(r)
+
+	datum, err := cbor.DumpObject(r)
+	if err != nil {
+		log.Errorf("could not marshal reputation of miner %s: %s", m, err)
+		return
+	}
+	key := datastore.KeyWithNamespaces([]string{reputationDatastorePrefix, m.String()})
+	if err := rs.ds.Put(key, datum); err != nil {
+		log.Errorf("could not save reputation of miner %s: %s", m, err)
+	}
+}
+
+// recordTransition updates the reputation of the miner of the deal when the
+// deal moves from state prev to its current state. smc.dealsLk must be held.
+func (smc *Client) recordTransition(deal *clientDeal, prev DealState) {
+	next := deal.Response.State
+	smc.reputation.update(deal.Miner, func(r *MinerReputation) {
+		switch next {
+		case Proving:
+			r.Proven++
+		case Failed:
+			r.Failed++
+		}
+
+		// transfers are measured from the first response reporting them
+		// to the first one reporting they are over
+		if prev != Transferring || next == Failed {
+			return
+		}
+		var start int64
+		for _, t := range deal.History {
+			if t.State == Transferring {
+				start = t.Time
+				break
+			}
+		}
+		end := deal.History[len(deal.History)-1].Time
+		if start > 0 && end > start {
+			r.TransferredBytes += deal.Proposal.Size.Uint64()
+			r.TransferSeconds += uint64(end - start)
+		}
+	})
+}
+
+// recordFaults counts the failed proofs of spacetime in ts against the
+// reputation of their miners. The receipts come from the plumbing, which
+// recomputes them for tipsets with several blocks.
+func (smc *Client) recordFaults(ts types.TipSet) {
+	receipts, err := smc.api.MessageReceipts(context.Background(), ts)
+	if err != nil {
+		log.Errorf("failed to get receipts of tipset %s: %s", ts.String(), err)
+		return
+	}
+
+	seen := make(map[cid.Cid]struct{})
+	for _, blk := range ts.ToSlice() {
+		for _, msg := range blk.Messages {
+			if msg.Method != "submitPoSt" {
+				continue
+			}
+			c, err := msg.Cid()
+			if err != nil {
+				log.Errorf("failed to compute message cid: %s", err)
+				continue
+			}
+			if _, ok := seen[c]; ok {
+				continue
+			}
+			seen[c] = struct{}{}
+
+			rcpt := receipts[c]
+			if rcpt == nil || rcpt.ExitCode == 0 {
+				continue
+			}
+			smc.reputation.update(msg.To, func(r *MinerReputation) {
+				r.Faults++
+			})
+		}
+	}
+}
+
+// MinerReputations returns the reputations of the miners the client made
+// deals with.
+func (smc *Client) MinerReputations() []MinerReputation {
+	return smc.reputation.list()
+}
+
+// MinerChoice is a miner the client may propose a deal to, with the best ask
+// of the miner for the deal.
+type MinerChoice struct {
+	Miner      address.Address `json:"miner"`
+	Ask        *miner.Ask      `json:"ask"`
+	Reputation MinerReputation `json:"reputation"`
+	Score      float64         `json:"score"`
+}
+
+// SelectMiners returns the miners operated by the connected peers that have
+// an ask for storing data, at a price of at most maxPrice if it is not nil,
+// best first. Miners are ranked by the price of their cheapest ask divided by
+// their reputation score, and by their transfer speed when that ties. Miners
+// in exclude are skipped.
+func (smc *Client) SelectMiners(ctx context.Context, data cid.Cid, maxPrice *types.AttoFIL, exclude map[address.Address]bool) ([]MinerChoice, error) {
+	size, err := smc.node.GetFileSize(ctx, data)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to determine the size of the data")
+	}
+
+	height, err := smc.api.ChainBlockHeight(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var choices []MinerChoice
+	for _, pid := range smc.node.Peers() {
+		qctx, cancel := context.WithTimeout(ctx, smc.node.GetBlockTime())
+		resp, err := smc.QueryAsks(qctx, pid)
+		cancel()
+		if err != nil {
+			log.Debugf("could not query asks of peer %s: %s", pid, err)
+			continue
+		}
+		if exclude[resp.Miner] {
+			continue
+		}
+
+		var cheapest *miner.Ask
+		for _, ask := range resp.Asks {
+			if maxPrice != nil && ask.Price.GreaterThan(maxPrice) {
+				continue
+			}
+			if ask.Expiry.LessThan(height) || checkPieceSize(*ask, size) != nil {
+				continue
+			}
+			if cheapest == nil || ask.Price.LessThan(cheapest.Price) {
+				cheapest = ask
+			}
+		}
+		if cheapest == nil {
+			continue
+		}
+
+		reputation := smc.reputation.get(resp.Miner)
+		choices = append(choices, MinerChoice{
+			Miner:      resp.Miner,
+			Ask:        cheapest,
+			Reputation: reputation,
+			Score:      reputation.Score(),
+		})
+	}
+
+	sort.SliceStable(choices, func(i, j int) bool {
+		a, b := choices[i], choices[j]
+		// a.price / a.score < b.price / b.score, in millionths of score
+		aCost := a.Ask.Price.MulBigInt(big.NewInt(int64(b.Score * 1e6)))
+		bCost := b.Ask.Price.MulBigInt(big.NewInt(int64(a.Score * 1e6)))
+		if !aCost.Equal(bCost) {
+			return aCost.LessThan(bCost)
+		}
+		return a.Reputation.TransferSpeed() > b.Reputation.TransferSpeed()
+	})
+	return choices, nil
+}
+
+// ProposeDealToBestMiner proposes a deal storing data to the miners chosen by
+// SelectMiners in turn, until one accepts it.
+func (smc *Client) ProposeDealToBestMiner(ctx context.Context, data cid.Cid, duration uint64, allowDuplicates bool) (*DealResponse, error) {
+	choices, err := smc.SelectMiners(ctx, data, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, choice := range choices {
+		resp, err := smc.ProposeDeal(ctx, choice.Miner, data, choice.Ask.ID.Uint64(), duration, allowDuplicates)
+		if err != nil {
+			log.Warningf("miner %s did not take deal for %s: %s", choice.Miner, data, err)
+			continue
+		}
+		return resp, nil
+	}
+	return nil, errors.New("no miner accepted the deal")
+}
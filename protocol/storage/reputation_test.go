@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/repo"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/util/convert"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMinerReputationScore(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	unknown := MinerReputation{}
+	assert.Equal(0.25, unknown.Score())
+	assert.Equal(float64(0), unknown.AcceptanceRate())
+	assert.Equal(uint64(0), unknown.TransferSpeed())
+
+	good := MinerReputation{Proposals: 10, Accepted: 10, Proven: 10, TransferredBytes: 1000, TransferSeconds: 10}
+	bad := MinerReputation{Proposals: 10, Accepted: 10, Proven: 10, Faults: 5}
+	assert.True(good.Score() > bad.Score())
+	assert.True(bad.Score() > unknown.Score())
+	assert.Equal(float64(1), good.AcceptanceRate())
+	assert.Equal(uint64(100), good.TransferSpeed())
+}
+
+func TestClientRecordsMinerReputation(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	testAPI := newTestClientAPI()
+	state := Accepted
+	testNode := newTestClientNode(func(request interface{}) (interface{}, error) {
+		p := request.(*DealProposal)
+		pcid, err := convert.ToCid(p)
+		require.NoError(err)
+		sig, err := p.Terms().Sign(testAPI.signer, testAPI.target)
+		require.NoError(err)
+		return &DealResponse{State: state, ProposalCid: pcid, Signature: sig}, nil
+	})
+
+	ds := repo.NewInMemoryRepo().DealsDs
+	client, err := NewClient(testNode, testAPI, ds)
+	require.NoError(err)
+
+	ctx := context.Background()
+	minerAddr := address.NewForTestGetter()()
+	resp, err := client.ProposeDeal(ctx, minerAddr, types.SomeCid(), 0, 10000, false)
+	require.NoError(err)
+	state = Rejected
+	_, err = client.ProposeDeal(ctx, minerAddr, types.SomeCid(), 0, 10000, true)
+	require.Error(err)
+
+	t.Run("records proposal outcomes", func(t *testing.T) {
+		r := client.reputation.get(minerAddr)
+		assert.Equal(uint64(2), r.Proposals)
+		assert.Equal(uint64(1), r.Accepted)
+		assert.Equal(uint64(1), r.Rejected)
+	})
+
+	t.Run("measures transfers", func(t *testing.T) {
+		deal := client.deals[resp.ProposalCid]
+		deal.History = append(deal.History,
+			DealTransition{State: Transferring, Time: 100},
+			DealTransition{State: Proving, Time: 110},
+		)
+		deal.Response.State = Proving
+		client.recordTransition(deal, Transferring)
+
+		r := client.reputation.get(minerAddr)
+		assert.Equal(uint64(1), r.Proven)
+		assert.Equal(deal.Proposal.Size.Uint64(), r.TransferredBytes)
+		assert.Equal(uint64(10), r.TransferSeconds)
+	})
+
+	t.Run("counts failed proofs on chain", func(t *testing.T) {
+		msg := types.NewMessage(address.NewForTestGetter()(), minerAddr, 0, nil, "submitPoSt", nil)
+		blk := &types.Block{
+			Messages:        []*types.SignedMessage{{MeteredMessage: types.MeteredMessage{Message: *msg}}},
+			MessageReceipts: []*types.MessageReceipt{{ExitCode: 1}},
+		}
+		client.recordFaults(types.RequireNewTipSet(require, blk))
+		assert.Equal(uint64(1), client.reputation.get(minerAddr).Faults)
+	})
+
+	t.Run("counts failed proofs in tipsets with several blocks", func(t *testing.T) {
+		var blks []*types.Block
+		for i := 0; i < 2; i++ {
+			msg := types.NewMessage(address.NewForTestGetter()(), minerAddr, uint64(i), nil, "submitPoSt", nil)
+			blks = append(blks, &types.Block{
+				Nonce:           types.Uint64(i),
+				Messages:        []*types.SignedMessage{{MeteredMessage: types.MeteredMessage{Message: *msg}}},
+				MessageReceipts: []*types.MessageReceipt{{ExitCode: uint8(i)}},
+			})
+		}
+		client.recordFaults(types.RequireNewTipSet(require, blks...))
+		assert.Equal(uint64(2), client.reputation.get(minerAddr).Faults)
+	})
+
+	t.Run("survives restarts", func(t *testing.T) {
+		client, err := NewClient(testNode, testAPI, ds)
+		require.NoError(err)
+		reputations := client.MinerReputations()
+		require.Equal(1, len(reputations))
+		assert.Equal(uint64(2), reputations[0].Proposals)
+		assert.Equal(uint64(2), reputations[0].Faults)
+	})
+}
@@ -0,0 +1,371 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	ds "gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
+
+	"github.com/filecoin-project/go-filecoin/config"
+	"github.com/filecoin-project/go-filecoin/proofs/sectorbuilder"
+)
+
+const scrubDatastorePrefix = "scrub"
+
+// maxScrubAlerts is the number of most recent alerts scrubbing keeps.
+const maxScrubAlerts = 20
+
+// ScrubRange is a range of a sealed sector file.
+type ScrubRange struct {
+	File   string `json:"file"`
+	Offset int64  `json:"offset"`
+	Length int64  `json:"length"`
+	// Message tells why the range was found corrupt.
+	Message string `json:"message,omitempty"`
+}
+
+// ScrubResult is the outcome of a scrub of the sealed sectors of the miner.
+type ScrubResult struct {
+	Started time.Time `json:"started"`
+	// Ranges is the number of ranges of sealed sector files read, and
+	// Baselined the number of them read for the first time, whose digests
+	// later scrubs check them against.
+	Ranges    int `json:"ranges"`
+	Baselined int `json:"baselined"`
+	// CorruptRanges lists the ranges which changed since they were first
+	// read, or failed to read.
+	CorruptRanges []ScrubRange `json:"corruptRanges,omitempty"`
+	// Sector is the check of the sector verified against its commitments on
+	// chain, nil if the miner committed none.
+	Sector *SectorCheck `json:"sector,omitempty"`
+}
+
+// ScrubAlert is an alert raised by a scrub.
+type ScrubAlert struct {
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+}
+
+// ScrubStatus reports the scrubs of the sealed sectors of the miner.
+type ScrubStatus struct {
+	Running bool `json:"running"`
+	// Runs is the number of scrubs done, and the other counters add up the
+	// outcomes of all of them.
+	Runs           uint64 `json:"runs"`
+	RangesChecked  uint64 `json:"rangesChecked"`
+	CorruptRanges  uint64 `json:"corruptRanges"`
+	SectorsChecked uint64 `json:"sectorsChecked"`
+	CorruptSectors uint64 `json:"corruptSectors"`
+	// Last is the outcome of the last scrub, nil before the first.
+	Last *ScrubResult `json:"last,omitempty"`
+	// Alerts lists the most recent alerts, oldest first.
+	Alerts []ScrubAlert `json:"alerts"`
+}
+
+// scrubState is the state of scrubbing persisted across restarts.
+type scrubState struct {
+	Status ScrubStatus
+	// Files holds the digests of the ranges of the sealed sector files read
+	// so far, by file name.
+	Files map[string]*scrubFile
+}
+
+// scrubFile holds the sha256 digests of the ranges of a sealed sector file
+// read so far, by offset, when the file had Size bytes.
+type scrubFile struct {
+	Size    int64
+	Digests map[int64]string
+}
+
+// Scrub reads random ranges of the sealed sector files of the miner, checking
+// them against the digests recorded when each range was first read, and
+// verifies a random sector against its commitments on chain by unsealing its
+// pieces. Corrupt ranges and sectors raise alerts, and corrupt sectors are
+// flagged like CheckSectors does, before they make the miner fail its PoSts.
+func (sm *Miner) Scrub(ctx context.Context) (*ScrubResult, error) {
+	cfg, err := sm.scrubConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	sm.scrubLk.Lock()
+	if sm.scrubbing {
+		sm.scrubLk.Unlock()
+		return nil, errors.New("a scrub is already running")
+	}
+	sm.scrubbing = true
+	sm.scrubLk.Unlock()
+
+	return sm.runScrub(ctx, cfg)
+}
+
+// ScrubStatus returns the status of the scrubs of the miner.
+func (sm *Miner) ScrubStatus() ScrubStatus {
+	sm.scrubLk.Lock()
+	defer sm.scrubLk.Unlock()
+
+	status := sm.scrub.Status
+	status.Running = sm.scrubbing
+	status.Alerts = append([]ScrubAlert{}, sm.scrub.Status.Alerts...)
+	return status
+}
+
+// scrubIfDue starts a scrub in the background once the configured interval
+// passed since the last one.
+func (sm *Miner) scrubIfDue(ctx context.Context) {
+	cfg, err := sm.scrubConfig()
+	if err != nil {
+		log.Errorf("failed to get scrub config: %s", err)
+		return
+	}
+	interval, err := time.ParseDuration(cfg.Interval)
+	if err != nil || interval <= 0 {
+		return
+	}
+
+	sm.scrubLk.Lock()
+	last := sm.scrub.Status.Last
+	if sm.scrubbing || (last != nil && time.Since(last.Started) < interval) {
+		sm.scrubLk.Unlock()
+		return
+	}
+	sm.scrubbing = true
+	sm.scrubLk.Unlock()
+
+	go func() {
+		if _, err := sm.runScrub(ctx, cfg); err != nil {
+			log.Errorf("failed to scrub sealed sectors: %s", err)
+		}
+	}()
+}
+
+// runScrub scrubs the sealed sectors. The caller sets sm.scrubbing, which
+// runScrub clears when done.
+func (sm *Miner) runScrub(ctx context.Context, cfg *config.ScrubConfig) (*ScrubResult, error) {
+	defer func() {
+		sm.scrubLk.Lock()
+		sm.scrubbing = false
+		sm.scrubLk.Unlock()
+	}()
+
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	result := &ScrubResult{Started: time.Now()}
+
+	// only runScrub changes the digests, and it doesn't run concurrently
+	if dir := sm.node.SectorDir(sectorbuilder.SealedPath); dir != "" && cfg.Samples > 0 && cfg.RangeBytes > 0 {
+		var err error
+		result.Ranges, result.Baselined, result.CorruptRanges, err = sampleRanges(dir, sm.scrub.Files, cfg.Samples, int64(cfg.RangeBytes), rnd)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	check, err := sm.scrubSector(ctx, rnd)
+	if err != nil {
+		return nil, err
+	}
+	result.Sector = check
+
+	sm.scrubLk.Lock()
+	defer sm.scrubLk.Unlock()
+
+	status := &sm.scrub.Status
+	status.Runs++
+	status.RangesChecked += uint64(result.Ranges)
+	status.CorruptRanges += uint64(len(result.CorruptRanges))
+	for _, r := range result.CorruptRanges {
+		sm.scrubAlert("range %d-%d of sealed sector file %s is corrupt: %s", r.Offset, r.Offset+r.Length, r.File, r.Message)
+	}
+	if check != nil {
+		status.SectorsChecked++
+		if check.Status == SectorMissing || check.Status == SectorCorrupt {
+			status.CorruptSectors++
+			sm.scrubAlert("sector %d is %s: %s", check.SectorID, check.Status, check.Message)
+		}
+	}
+	status.Last = result
+
+	if err := sm.saveScrubState(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// scrubSector checks a random sector the miner committed on chain, flagging
+// it if missing or corrupt. It returns nil if there is no sector to check.
+func (sm *Miner) scrubSector(ctx context.Context, rnd *rand.Rand) (*SectorCheck, error) {
+	sb := sm.node.SectorBuilder()
+	if sb == nil {
+		return nil, nil
+	}
+
+	commitments, err := sm.sectorCommitments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(commitments) == 0 {
+		return nil, nil
+	}
+	var ids []uint64
+	for id := range commitments {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	id := ids[rnd.Intn(len(ids))]
+
+	check := checkSector(id, commitments[id], sm.sectorPieces()[id], sb.ReadPieceFromSealedSector)
+	if err := sm.flagSectors([]SectorCheck{check}); err != nil {
+		return nil, err
+	}
+	return &check, nil
+}
+
+// sampleRanges reads samples random ranges of rangeBytes bytes of the files
+// in dir, comparing their digests with the ones in files. Ranges read for the
+// first time have their digests recorded. The digests of a file whose size
+// changed, e.g. as it was sealed again, are dropped, as are the digests of
+// files no longer in dir. sampleRanges returns the number of ranges read and
+// recorded, and the corrupt ranges.
+func sampleRanges(dir string, files map[string]*scrubFile, samples int, rangeBytes int64, rnd *rand.Rand) (int, int, []ScrubRange, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return 0, 0, nil, errors.Wrapf(err, "failed to list sealed sector files in %s", dir)
+	}
+	var sealed []os.FileInfo
+	present := make(map[string]bool)
+	for _, fi := range infos {
+		if fi.Mode().IsRegular() && fi.Size() > 0 {
+			sealed = append(sealed, fi)
+			present[fi.Name()] = true
+		}
+	}
+	for name := range files {
+		if !present[name] {
+			delete(files, name)
+		}
+	}
+	if len(sealed) == 0 {
+		return 0, 0, nil, nil
+	}
+
+	var ranges, baselined int
+	var corrupt []ScrubRange
+	for i := 0; i < samples; i++ {
+		fi := sealed[rnd.Intn(len(sealed))]
+		f, ok := files[fi.Name()]
+		if !ok || f.Size != fi.Size() {
+			f = &scrubFile{Size: fi.Size(), Digests: make(map[int64]string)}
+			files[fi.Name()] = f
+		}
+
+		n := (fi.Size() + rangeBytes - 1) / rangeBytes
+		r := ScrubRange{File: fi.Name(), Offset: rnd.Int63n(n) * rangeBytes, Length: rangeBytes}
+		if r.Offset+r.Length > fi.Size() {
+			r.Length = fi.Size() - r.Offset
+		}
+		ranges++
+
+		digest, err := digestRange(filepath.Join(dir, fi.Name()), r.Offset, r.Length)
+		if err != nil {
+			r.Message = err.Error()
+			corrupt = append(corrupt, r)
+			continue
+		}
+		expected, ok := f.Digests[r.Offset]
+		if !ok {
+			f.Digests[r.Offset] = digest
+			baselined++
+			continue
+		}
+		if digest != expected {
+			r.Message = "data changed since the range was first read"
+			corrupt = append(corrupt, r)
+		}
+	}
+	return ranges, baselined, corrupt, nil
+}
+
+// digestRange returns the hex sha256 digest of the length bytes at offset of
+// the file at path.
+func digestRange(path string, offset, length int64) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close() // nolint: errcheck
+
+	h := sha256.New()
+	n, err := io.Copy(h, io.NewSectionReader(f, offset, length))
+	if err != nil {
+		return "", err
+	}
+	if n != length {
+		return "", fmt.Errorf("read %d bytes, expected %d", n, length)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (sm *Miner) scrubConfig() (*config.ScrubConfig, error) {
+	val, err := sm.porcelainAPI.ConfigGet("mining.scrub")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get scrub config")
+	}
+	cfg, ok := val.(*config.ScrubConfig)
+	if !ok {
+		return nil, errors.New("could not retrieve scrub from config")
+	}
+	return cfg, nil
+}
+
+// scrubAlert logs the alert and keeps it in the status. The caller holds
+// sm.scrubLk.
+func (sm *Miner) scrubAlert(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	log.Errorf("scrub alert: %s", msg)
+
+	status := &sm.scrub.Status
+	status.Alerts = append(status.Alerts, ScrubAlert{Time: time.Now(), Message: msg})
+	if len(status.Alerts) > maxScrubAlerts {
+		status.Alerts = status.Alerts[len(status.Alerts)-maxScrubAlerts:]
+	}
+}
+
+func (sm *Miner) loadScrubState() error {
+	sm.scrub = scrubState{Files: make(map[string]*scrubFile)}
+
+	key := ds.KeyWithNamespaces([]string{scrubDatastorePrefix})
+	result, notFound := sm.dealsDs.Get(key)
+	if notFound == nil {
+		if err := json.Unmarshal(result, &sm.scrub); err != nil {
+			return errors.Wrap(err, "failed to unmarshal scrub state from datastore")
+		}
+		if sm.scrub.Files == nil {
+			sm.scrub.Files = make(map[string]*scrubFile)
+		}
+	}
+	return nil
+}
+
+// saveScrubState persists the scrub state. The caller must hold sm.scrubLk.
+func (sm *Miner) saveScrubState() error {
+	data, err := json.Marshal(sm.scrub)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal scrub state")
+	}
+	key := ds.KeyWithNamespaces([]string{scrubDatastorePrefix})
+	if err := sm.dealsDs.Put(key, data); err != nil {
+		return errors.Wrap(err, "failed to save scrub state")
+	}
+	return nil
+}
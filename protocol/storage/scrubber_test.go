@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSampleRanges(t *testing.T) {
+	t.Parallel()
+
+	newSealedDir := func(t *testing.T) (string, func()) {
+		dir, err := ioutil.TempDir("", "sealed")
+		require.NoError(t, err)
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "sector"), make([]byte, 10), 0644))
+		return dir, func() { os.RemoveAll(dir) } // nolint: errcheck
+	}
+
+	t.Run("records the digests of ranges read first", func(t *testing.T) {
+		dir, cleanup := newSealedDir(t)
+		defer cleanup()
+
+		files := make(map[string]*scrubFile)
+		ranges, baselined, corrupt, err := sampleRanges(dir, files, 20, 4, rand.New(rand.NewSource(1)))
+		require.NoError(t, err)
+		assert.Equal(t, 20, ranges)
+		assert.Equal(t, 3, baselined)
+		assert.Empty(t, corrupt)
+		assert.Len(t, files["sector"].Digests, 3)
+
+		ranges, baselined, corrupt, err = sampleRanges(dir, files, 20, 4, rand.New(rand.NewSource(2)))
+		require.NoError(t, err)
+		assert.Equal(t, 20, ranges)
+		assert.Equal(t, 0, baselined)
+		assert.Empty(t, corrupt)
+	})
+
+	t.Run("finds changed ranges", func(t *testing.T) {
+		dir, cleanup := newSealedDir(t)
+		defer cleanup()
+
+		files := make(map[string]*scrubFile)
+		_, _, _, err := sampleRanges(dir, files, 20, 4, rand.New(rand.NewSource(1)))
+		require.NoError(t, err)
+
+		data := make([]byte, 10)
+		data[9] = 1
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "sector"), data, 0644))
+
+		_, _, corrupt, err := sampleRanges(dir, files, 20, 4, rand.New(rand.NewSource(1)))
+		require.NoError(t, err)
+		require.NotEmpty(t, corrupt)
+		for _, r := range corrupt {
+			assert.Equal(t, ScrubRange{File: "sector", Offset: 8, Length: 2, Message: "data changed since the range was first read"}, r)
+		}
+	})
+
+	t.Run("drops the digests of resized and removed files", func(t *testing.T) {
+		dir, cleanup := newSealedDir(t)
+		defer cleanup()
+
+		files := map[string]*scrubFile{
+			"removed": {Size: 10, Digests: map[int64]string{0: "digest"}},
+		}
+		_, _, _, err := sampleRanges(dir, files, 20, 4, rand.New(rand.NewSource(1)))
+		require.NoError(t, err)
+		assert.NotContains(t, files, "removed")
+
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "sector"), make([]byte, 12), 0644))
+		_, baselined, corrupt, err := sampleRanges(dir, files, 20, 4, rand.New(rand.NewSource(1)))
+		require.NoError(t, err)
+		assert.Equal(t, 3, baselined)
+		assert.Empty(t, corrupt)
+		assert.Equal(t, int64(12), files["sector"].Size)
+	})
+}
@@ -0,0 +1,414 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+
+	imp "gx/ipfs/QmQXze9tG878pa4Euya4rrDpyTNX3kQe4dhCaBzBozGgpe/go-unixfs/importer"
+	chunk "gx/ipfs/QmR4QQVkBZsZENRjYFVi8dEtPL3daZRNKk24m4r6WKJHNm/go-ipfs-chunker"
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	car "gx/ipfs/QmRa5sdhUGtLptMNYSHFWcU3axEJntpKht3LngrBpuurv1/go-car"
+	cbor "gx/ipfs/QmRoARq3nkUb13HSKZGepCZSWe5GrVPwx7xURJGZ7KWv9V/go-ipld-cbor"
+	"gx/ipfs/QmS2aqUZLJp8kF1ihE5rvDGE5LvmKDPnx32w9Z1BW9xLV5/go-ipfs-blockstore"
+	dag "gx/ipfs/QmTQdH4848iTVCJmKXYyRiK72HufWTLYQQ8iN3JaQ8K1Hq/go-merkledag"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	bserv "gx/ipfs/QmYPZzd9VqmJDwxUnThfeSbV1Y5o53aVPDijTB7j7rS9Ep/go-blockservice"
+	"gx/ipfs/QmYZwey1thDTynSrvd6qQkX24UpTka6TFhQ2v569UpoqxD/go-ipfs-exchange-offline"
+	ipld "gx/ipfs/QmcKKBwfz6FyQdHR2jsXrrF6XeSBXYL86anmWNewpFpoF5/go-ipld-format"
+	ds "gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/plumbing/sctr"
+	"github.com/filecoin-project/go-filecoin/proofs/sectorbuilder"
+	"github.com/filecoin-project/go-filecoin/protocol/sealing"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+const flaggedSectorsDatastorePrefix = "flaggedSectors"
+
+func init() {
+	cbor.RegisterCborType(sectorExport{})
+}
+
+// SectorStatus is the outcome of checking a sealed sector of the miner.
+type SectorStatus string
+
+const (
+	// SectorOK means the pieces of the sector unseal to their data.
+	SectorOK = SectorStatus("ok")
+	// SectorMissing means the miner can not unseal the sector.
+	SectorMissing = SectorStatus("missing")
+	// SectorCorrupt means the sector does not match its commitments on chain,
+	// or its pieces unseal to the wrong data.
+	SectorCorrupt = SectorStatus("corrupt")
+	// SectorRemote means a remote sealing worker holds the sector.
+	SectorRemote = SectorStatus("remote")
+	// SectorUnverified means no deals of the miner are known to be in the
+	// sector, so there is nothing to check it against.
+	SectorUnverified = SectorStatus("unverified")
+)
+
+// SectorCheck is the outcome of checking a sector committed on chain.
+type SectorCheck struct {
+	SectorID uint64       `json:"sectorId"`
+	Status   SectorStatus `json:"status"`
+	Message  string       `json:"message,omitempty"`
+}
+
+// sectorPiece is a piece of a deal sealed in a sector, with the commitments
+// of the sector as the miner sealed it.
+type sectorPiece struct {
+	piece *sectorbuilder.PieceInfo
+	proof *ProofInfo
+}
+
+// sectorExport describes an exported sector. It is the first root of the CAR
+// file written by ExportSector, the pieces of the sector are the others.
+type sectorExport struct {
+	Miner     address.Address
+	SectorID  uint64
+	CommD     []byte
+	CommR     []byte
+	CommRStar []byte
+	Pieces    []*sectorbuilder.PieceInfo
+}
+
+// CheckSectors checks the sectors the miner committed on chain against the
+// data the miner holds, unsealing the pieces of the miner's deals in each
+// sector. Missing and corrupt sectors are flagged for recovery with
+// ImportSector, sectors found ok again lose their flag.
+func (sm *Miner) CheckSectors(ctx context.Context) ([]SectorCheck, error) {
+	sb := sm.node.SectorBuilder()
+	if sb == nil {
+		return nil, errors.New("mining disabled, no sealed sectors")
+	}
+
+	commitments, err := sm.sectorCommitments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pieces := sm.sectorPieces()
+
+	var ids []uint64
+	for id := range commitments {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var checks []SectorCheck
+	for _, id := range ids {
+		checks = append(checks, checkSector(id, commitments[id], pieces[id], sb.ReadPieceFromSealedSector))
+	}
+
+	if err := sm.flagSectors(checks); err != nil {
+		return nil, err
+	}
+	return checks, nil
+}
+
+// checkSector checks the sector with the given commitments on chain, reading
+// its pieces with unseal.
+func checkSector(id uint64, commitments types.Commitments, pieces []sectorPiece, unseal func(cid.Cid) (io.Reader, error)) SectorCheck {
+	check := SectorCheck{SectorID: id}
+	if sealing.IsWorkerSector(id) {
+		check.Status = SectorRemote
+		check.Message = "sealed and held by a remote sealing worker"
+		return check
+	}
+	if len(pieces) == 0 {
+		check.Status = SectorUnverified
+		check.Message = "no deals of the miner are known to be in the sector"
+		return check
+	}
+
+	for _, p := range pieces {
+		if !bytes.Equal(p.proof.CommR, commitments.CommR[:]) || !bytes.Equal(p.proof.CommD, commitments.CommD[:]) {
+			check.Status = SectorCorrupt
+			check.Message = "sealed commitments differ from the ones committed on chain"
+			return check
+		}
+	}
+
+	for _, p := range pieces {
+		r, err := unseal(p.piece.Ref)
+		if err != nil {
+			check.Status = SectorMissing
+			check.Message = fmt.Sprintf("failed to unseal piece %s: %s", p.piece.Ref, err)
+			return check
+		}
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			check.Status = SectorMissing
+			check.Message = fmt.Sprintf("failed to read piece %s: %s", p.piece.Ref, err)
+			return check
+		}
+		nd, err := buildPiece(newPieceDAGService(), data)
+		if err != nil || !nd.Cid().Equals(p.piece.Ref) {
+			check.Status = SectorCorrupt
+			check.Message = fmt.Sprintf("piece %s unseals to the wrong data", p.piece.Ref)
+			return check
+		}
+	}
+
+	check.Status = SectorOK
+	return check
+}
+
+// sectorPieces returns the pieces of the miner's sealed deals, keyed by the id
+// of the sector they are sealed in.
+func (sm *Miner) sectorPieces() map[uint64][]sectorPiece {
+	sm.dealsLk.Lock()
+	defer sm.dealsLk.Unlock()
+
+	pieces := make(map[uint64][]sectorPiece)
+	seen := make(map[cid.Cid]bool)
+	for _, d := range sm.deals {
+		proof := d.Response.ProofInfo
+		if proof == nil || seen[d.Proposal.PieceRef] {
+			continue
+		}
+		seen[d.Proposal.PieceRef] = true
+		pieces[proof.SectorID] = append(pieces[proof.SectorID], sectorPiece{
+			piece: &sectorbuilder.PieceInfo{Ref: d.Proposal.PieceRef, Size: d.Proposal.Size.Uint64()},
+			proof: proof,
+		})
+	}
+	return pieces
+}
+
+// ExportSector writes the sector with the given id to w as a CAR file, with
+// the unsealed data of the pieces of the miner's deals in it. ImportSector
+// imports it on another node of the miner.
+func (sm *Miner) ExportSector(ctx context.Context, sectorID uint64, w io.Writer) error {
+	sb := sm.node.SectorBuilder()
+	if sb == nil {
+		return errors.New("mining disabled, no sealed sectors")
+	}
+	if sealing.IsWorkerSector(sectorID) {
+		return fmt.Errorf("sector %d is held by a remote sealing worker", sectorID)
+	}
+
+	commitments, err := sm.sectorCommitments(ctx)
+	if err != nil {
+		return err
+	}
+	c, ok := commitments[sectorID]
+	if !ok {
+		return fmt.Errorf("sector %d is not committed on chain", sectorID)
+	}
+	pieces := sm.sectorPieces()[sectorID]
+	if len(pieces) == 0 {
+		return fmt.Errorf("no deals of the miner are known to be in sector %d", sectorID)
+	}
+
+	dserv := newPieceDAGService()
+	export := &sectorExport{
+		Miner:     sm.minerAddr,
+		SectorID:  sectorID,
+		CommD:     c.CommD[:],
+		CommR:     c.CommR[:],
+		CommRStar: c.CommRStar[:],
+	}
+	var roots []cid.Cid
+	for _, p := range pieces {
+		r, err := sb.ReadPieceFromSealedSector(p.piece.Ref)
+		if err != nil {
+			return errors.Wrapf(err, "failed to unseal piece %s", p.piece.Ref)
+		}
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read piece %s", p.piece.Ref)
+		}
+		nd, err := buildPiece(dserv, data)
+		if err != nil {
+			return err
+		}
+		if !nd.Cid().Equals(p.piece.Ref) {
+			return fmt.Errorf("piece %s of sector %d unseals to the wrong data", p.piece.Ref, sectorID)
+		}
+		export.Pieces = append(export.Pieces, p.piece)
+		roots = append(roots, nd.Cid())
+	}
+
+	nd, err := cbor.WrapObject(export, types.DefaultHashFunction, -1)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode sector metadata")
+	}
+	if err := dserv.Add(ctx, nd); err != nil {
+		return err
+	}
+
+	return car.WriteCar(ctx, dserv, append([]cid.Cid{nd.Cid()}, roots...), w)
+}
+
+// ImportSector imports a sector of the miner from the CAR file read from r,
+// written by ExportSector, e.g. to recover a sector flagged by CheckSectors
+// from a copy. The sector must match its commitments on chain. Sealed
+// replicas are bound to the sector builder that sealed them, so the pieces of
+// the sector are sealed again into a new sector, committed like any other.
+// ImportSector returns the id of the imported sector.
+func (sm *Miner) ImportSector(ctx context.Context, r io.Reader) (uint64, error) {
+	sb := sm.node.SectorBuilder()
+	if sb == nil {
+		return 0, errors.New("mining disabled, can not import sectors")
+	}
+
+	bs := sm.node.BlockService().Blockstore()
+	header, err := car.LoadCar(bs, r)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to load car file")
+	}
+	if len(header.Roots) == 0 {
+		return 0, errors.New("car file holds no sector")
+	}
+	blk, err := bs.Get(header.Roots[0])
+	if err != nil {
+		return 0, errors.Wrap(err, "car file holds no sector metadata")
+	}
+	var export sectorExport
+	if err := cbor.DecodeInto(blk.RawData(), &export); err != nil {
+		return 0, errors.Wrap(err, "car file holds no sector metadata")
+	}
+
+	if export.Miner != sm.minerAddr {
+		return 0, fmt.Errorf("sector was sealed for miner %s, not %s", export.Miner, sm.minerAddr)
+	}
+	commitments, err := sm.sectorCommitments(ctx)
+	if err != nil {
+		return 0, err
+	}
+	c, ok := commitments[export.SectorID]
+	if !ok {
+		return 0, fmt.Errorf("sector %d is not committed on chain", export.SectorID)
+	}
+	if !bytes.Equal(export.CommR, c.CommR[:]) || !bytes.Equal(export.CommD, c.CommD[:]) || !bytes.Equal(export.CommRStar, c.CommRStar[:]) {
+		return 0, fmt.Errorf("sector %d does not match its commitments on chain", export.SectorID)
+	}
+
+	dserv := dag.NewDAGService(sm.node.BlockService())
+	for _, p := range export.Pieces {
+		size, err := getFileSize(ctx, p.Ref, dserv)
+		if err != nil {
+			return 0, errors.Wrapf(err, "car file lacks piece %s", p.Ref)
+		}
+		if size != p.Size {
+			return 0, fmt.Errorf("piece %s has %d bytes, expected %d", p.Ref, size, p.Size)
+		}
+	}
+
+	for _, p := range export.Pieces {
+		desc := fmt.Sprintf("add piece %s of imported sector %d", p.Ref, export.SectorID)
+		err := sm.node.SealingScheduler().Do(ctx, sectorbuilder.AddPieceStage, desc, func() error {
+			sectorID, err := sb.AddPiece(ctx, p)
+			if err == nil {
+				sm.node.SectorProgress().Enter(sectorID, sctr.Staged)
+			}
+			return err
+		})
+		if err != nil {
+			return 0, errors.Wrapf(err, "failed to add piece %s", p.Ref)
+		}
+	}
+	desc := fmt.Sprintf("seal imported sector %d", export.SectorID)
+	err = sm.node.SealingScheduler().Do(ctx, sectorbuilder.SealStage, desc, func() error {
+		sm.node.SectorProgress().EnterAll(sctr.Staged, sctr.Sealing)
+		return sb.SealAllStagedSectors(ctx)
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to seal imported pieces")
+	}
+
+	if err := sm.unflagSector(export.SectorID); err != nil {
+		return 0, err
+	}
+	return export.SectorID, nil
+}
+
+// FlaggedSectors returns the sectors CheckSectors found missing or corrupt,
+// which have not been recovered since.
+func (sm *Miner) FlaggedSectors() []SectorCheck {
+	sm.flaggedLk.Lock()
+	defer sm.flaggedLk.Unlock()
+
+	var checks []SectorCheck
+	for _, c := range sm.flaggedSectors {
+		checks = append(checks, c)
+	}
+	sort.Slice(checks, func(i, j int) bool { return checks[i].SectorID < checks[j].SectorID })
+	return checks
+}
+
+// flagSectors flags the missing and corrupt sectors among checks, and unflags
+// the ones found ok.
+func (sm *Miner) flagSectors(checks []SectorCheck) error {
+	sm.flaggedLk.Lock()
+	defer sm.flaggedLk.Unlock()
+
+	for _, c := range checks {
+		switch c.Status {
+		case SectorMissing, SectorCorrupt:
+			sm.flaggedSectors[c.SectorID] = c
+			sm.node.SectorProgress().Enter(c.SectorID, sctr.Faulted)
+		case SectorOK:
+			delete(sm.flaggedSectors, c.SectorID)
+		}
+	}
+	return sm.saveFlaggedSectors()
+}
+
+func (sm *Miner) unflagSector(sectorID uint64) error {
+	sm.flaggedLk.Lock()
+	defer sm.flaggedLk.Unlock()
+
+	delete(sm.flaggedSectors, sectorID)
+	return sm.saveFlaggedSectors()
+}
+
+func (sm *Miner) loadFlaggedSectors() error {
+	sm.flaggedSectors = make(map[uint64]SectorCheck)
+
+	key := ds.KeyWithNamespaces([]string{flaggedSectorsDatastorePrefix})
+	result, notFound := sm.dealsDs.Get(key)
+	if notFound == nil {
+		if err := json.Unmarshal(result, &sm.flaggedSectors); err != nil {
+			return errors.Wrap(err, "failed to unmarshal flagged sectors from datastore")
+		}
+	}
+	return nil
+}
+
+// saveFlaggedSectors persists the flagged sectors. The caller must hold
+// sm.flaggedLk.
+func (sm *Miner) saveFlaggedSectors() error {
+	data, err := json.Marshal(sm.flaggedSectors)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal flagged sectors")
+	}
+	key := ds.KeyWithNamespaces([]string{flaggedSectorsDatastorePrefix})
+	if err := sm.dealsDs.Put(key, data); err != nil {
+		return errors.Wrap(err, "failed to save flagged sectors")
+	}
+	return nil
+}
+
+// newPieceDAGService returns an in-memory DAG service to rebuild pieces from
+// their unsealed data in.
+func newPieceDAGService() ipld.DAGService {
+	bs := blockstore.NewBlockstore(ds.NewMapDatastore())
+	return dag.NewDAGService(bserv.New(bs, offline.Exchange(bs)))
+}
+
+// buildPiece adds the DAG of the piece with the given data to dserv, chunked
+// like imported files, returning its root.
+func buildPiece(dserv ipld.DAGService, data []byte) (ipld.Node, error) {
+	nd, err := imp.BuildDagFromReader(dserv, chunk.DefaultSplitter(bytes.NewReader(data)))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to rebuild piece")
+	}
+	return nd, nil
+}
@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+
+	"github.com/filecoin-project/go-filecoin/proofs/sectorbuilder"
+	"github.com/filecoin-project/go-filecoin/protocol/sealing"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckSector(t *testing.T) {
+	t.Parallel()
+
+	data := []byte("the data of a piece")
+	nd, err := buildPiece(newPieceDAGService(), data)
+	require.NoError(t, err)
+
+	var commitments types.Commitments
+	commitments.CommD[0] = 1
+	commitments.CommR[0] = 2
+	pieces := []sectorPiece{{
+		piece: &sectorbuilder.PieceInfo{Ref: nd.Cid(), Size: uint64(len(data))},
+		proof: &ProofInfo{SectorID: 7, CommD: commitments.CommD[:], CommR: commitments.CommR[:]},
+	}}
+
+	unsealTo := func(data []byte) func(cid.Cid) (io.Reader, error) {
+		return func(cid.Cid) (io.Reader, error) {
+			return bytes.NewReader(data), nil
+		}
+	}
+
+	t.Run("ok if the pieces unseal to their data", func(t *testing.T) {
+		check := checkSector(7, commitments, pieces, unsealTo(data))
+		assert.Equal(t, SectorCheck{SectorID: 7, Status: SectorOK}, check)
+	})
+
+	t.Run("missing if the pieces can not be unsealed", func(t *testing.T) {
+		check := checkSector(7, commitments, pieces, func(cid.Cid) (io.Reader, error) {
+			return nil, errors.New("no sealed sector")
+		})
+		assert.Equal(t, SectorMissing, check.Status)
+		assert.Contains(t, check.Message, "no sealed sector")
+	})
+
+	t.Run("corrupt if a piece unseals to other data", func(t *testing.T) {
+		check := checkSector(7, commitments, pieces, unsealTo([]byte("other data")))
+		assert.Equal(t, SectorCorrupt, check.Status)
+	})
+
+	t.Run("corrupt if the commitments differ from the chain", func(t *testing.T) {
+		other := commitments
+		other.CommR[0] = 3
+		check := checkSector(7, other, pieces, unsealTo(data))
+		assert.Equal(t, SectorCorrupt, check.Status)
+	})
+
+	t.Run("unverified without known pieces", func(t *testing.T) {
+		check := checkSector(8, commitments, nil, unsealTo(data))
+		assert.Equal(t, SectorUnverified, check.Status)
+	})
+
+	t.Run("remote if sealed by a worker", func(t *testing.T) {
+		check := checkSector(sealing.SectorIDBase("worker")+1, commitments, nil, unsealTo(data))
+		assert.Equal(t, SectorRemote, check.Status)
+	})
+}
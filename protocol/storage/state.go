@@ -1,10 +1,22 @@
 package storage
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	cbor "gx/ipfs/QmRoARq3nkUb13HSKZGepCZSWe5GrVPwx7xURJGZ7KWv9V/go-ipld-cbor"
+)
+
+func init() {
+	cbor.RegisterCborType(DealTransition{})
+}
 
 // DealState signifies the state of a deal
 type DealState int
 
+// The values of deal states are persisted and sent over the wire, new states
+// are only ever appended.
 const (
 	// Unknown signifies an unknown negotiation
 	Unknown = DealState(iota)
@@ -12,24 +24,39 @@ const (
 	// Rejected means the deal was rejected for some reason
 	Rejected
 
-	// Accepted means the deal was accepted but hasnt yet started
+	// Accepted means the deal was accepted and is being published, but the
+	// data hasn't been transferred yet
 	Accepted
 
-	// Started means the deal has started and the transfer is in progress
-	Started
+	// Transferring means the deal is published and the transfer of its data
+	// is in progress
+	Transferring
 
 	// Failed means the deal has failed for some reason
 	Failed
 
-	// Posted means the deal has been posted to the blockchain
-	Posted
+	// Proving means the data of the deal is in a sector committed to the
+	// blockchain, whose storage the miner proves until the deal ends
+	Proving
 
-	// Complete means the deal is complete
-	// TODO: distinguish this from 'Posted'
+	// Complete means the deal ended
 	Complete
 
-	// Staged means that the data in the deal has been staged into a sector
-	Staged
+	// Sealing means that the data in the deal has been staged into a sector
+	// being sealed
+	Sealing
+
+	// Proposed means the client sent the proposal and awaits the miner's
+	// response
+	Proposed
+
+	// WaitingForData means the deal is published and the miner waits for its
+	// data to be imported, as the client delivers it out of band
+	WaitingForData
+
+	// Packing means the data of the deal waits for other pieces to be packed
+	// with into a sector before the sector is sealed
+	Packing
 )
 
 func (s DealState) String() string {
@@ -40,17 +67,109 @@ func (s DealState) String() string {
 		return "rejected"
 	case Accepted:
 		return "accepted"
-	case Started:
-		return "started"
+	case Transferring:
+		return "transferring"
 	case Failed:
 		return "failed"
-	case Posted:
-		return "posted"
+	case Proving:
+		return "proving"
 	case Complete:
 		return "complete"
-	case Staged:
-		return "staged"
+	case Sealing:
+		return "sealing"
+	case Proposed:
+		return "proposed"
+	case WaitingForData:
+		return "waiting for data"
+	case Packing:
+		return "packing"
 	default:
 		return fmt.Sprintf("<unrecognized %d>", s)
 	}
 }
+
+// dealTransitions lists the states a deal moves to from each state. A deal
+// has no state until the client proposes it, or the miner responds.
+var dealTransitions = map[DealState][]DealState{
+	Unknown:        {Proposed, Accepted, Rejected},
+	Proposed:       {Accepted, Rejected, Failed},
+	Accepted:       {Transferring, WaitingForData, Failed},
+	Transferring:   {Packing, Sealing, Failed},
+	WaitingForData: {Packing, Sealing, Failed},
+	Packing:        {Sealing, Failed},
+	Sealing:        {Proving, Failed},
+	Proving:        {Complete, Failed},
+}
+
+// IsFinal returns true if a deal in state s never changes state again.
+func (s DealState) IsFinal() bool {
+	return len(dealTransitions[s]) == 0
+}
+
+// CanMoveTo returns true if a deal in state s may later be in state next.
+// Deals may move through several transitions at once, e.g. the client only
+// observes some of the states the miner moves a deal through.
+func (s DealState) CanMoveTo(next DealState) bool {
+	seen := map[DealState]bool{}
+	todo := []DealState{s}
+	for len(todo) > 0 {
+		cur := todo[0]
+		todo = todo[1:]
+		for _, to := range dealTransitions[cur] {
+			if to == next {
+				return true
+			}
+			if !seen[to] {
+				seen[to] = true
+				todo = append(todo, to)
+			}
+		}
+	}
+	return false
+}
+
+// DealTransition records a deal moving to a state.
+type DealTransition struct {
+	State DealState
+	// Message tells why the deal moved to the state, e.g. why it failed.
+	Message string
+	// Time is the unix time of the transition, in seconds.
+	Time int64
+}
+
+// DealRecord is what a node knows about a deal it is party to.
+type DealRecord struct {
+	ProposalCid cid.Cid
+	// Role is the part the node plays in the deal, client or miner.
+	Role     string
+	Proposal *DealProposal
+	Response *DealResponse
+	// History lists the states the deal went through, the last one is the
+	// current state.
+	History []DealTransition
+}
+
+// The roles of a node in a deal.
+const (
+	ClientRole = "client"
+	MinerRole  = "miner"
+)
+
+// moveDeal moves the deal whose response is resp from the state prev to the
+// state of resp, recording the transition in history. It fails if deals in
+// state prev can't move to that state, and leaves resp in state prev.
+func moveDeal(prev DealState, resp *DealResponse, history []DealTransition) ([]DealTransition, error) {
+	if resp.State == prev {
+		return history, nil
+	}
+	if !prev.CanMoveTo(resp.State) {
+		next := resp.State
+		resp.State = prev
+		return history, fmt.Errorf("deal can't move from state %s to %s", prev, next)
+	}
+	return append(history, DealTransition{
+		State:   resp.State,
+		Message: resp.Message,
+		Time:    time.Now().Unix(),
+	}), nil
+}
@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDealStateTransitions(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	assert.True(Proposed.CanMoveTo(Accepted))
+	assert.True(Accepted.CanMoveTo(Transferring))
+	assert.True(Sealing.CanMoveTo(Proving))
+	assert.True(Proving.CanMoveTo(Complete))
+	assert.True(Accepted.CanMoveTo(WaitingForData))
+	assert.True(WaitingForData.CanMoveTo(Sealing))
+	assert.False(Transferring.CanMoveTo(WaitingForData))
+	assert.True(Transferring.CanMoveTo(Packing))
+	assert.True(Packing.CanMoveTo(Sealing))
+	assert.False(Packing.CanMoveTo(Transferring))
+
+	// the client only observes some of the states
+	assert.True(Proposed.CanMoveTo(Proving))
+	assert.True(Accepted.CanMoveTo(Failed))
+
+	assert.False(Proving.CanMoveTo(Accepted))
+	assert.False(Accepted.CanMoveTo(Proposed))
+	assert.False(Complete.CanMoveTo(Failed))
+	assert.False(Rejected.CanMoveTo(Accepted))
+
+	for _, s := range []DealState{Rejected, Failed, Complete} {
+		assert.True(s.IsFinal(), s.String())
+	}
+	for _, s := range []DealState{Proposed, Accepted, Transferring, WaitingForData, Packing, Sealing, Proving} {
+		assert.False(s.IsFinal(), s.String())
+	}
+}
+
+func TestMoveDeal(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	resp := &DealResponse{State: Sealing}
+	history, err := moveDeal(Transferring, resp, []DealTransition{{State: Transferring}})
+	assert.NoError(err)
+	assert.Len(history, 2)
+	assert.Equal(Sealing, history[1].State)
+
+	resp = &DealResponse{State: Accepted}
+	history, err = moveDeal(Proving, resp, history)
+	assert.Error(err)
+	assert.Len(history, 2)
+	assert.Equal(Proving, resp.State)
+}
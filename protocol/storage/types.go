@@ -5,7 +5,9 @@ import (
 	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
 	cbor "gx/ipfs/QmRoARq3nkUb13HSKZGepCZSWe5GrVPwx7xURJGZ7KWv9V/go-ipld-cbor"
 
+	"github.com/filecoin-project/go-filecoin/actor/builtin/storagemarket"
 	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/protocol/datatransfer"
 	"github.com/filecoin-project/go-filecoin/types"
 )
 
@@ -61,7 +63,33 @@ type DealProposal struct {
 	// miner using on-chain information.
 	Payment PaymentInfo
 
-	// Signature types.Signature
+	// Signature is the signature of the payer over the terms of the deal,
+	// see Terms.
+	Signature types.Signature
+
+	// CommP is the piece commitment of the data of offline deals, whose data
+	// the client delivers out of band and the miner imports. The miner checks
+	// the imported data against it.
+	CommP []byte
+}
+
+// IsOffline returns true if the data of the deal is delivered out of band,
+// rather than transferred over the network.
+func (p *DealProposal) IsOffline() bool {
+	return len(p.CommP) > 0
+}
+
+// Terms returns the terms of the deal the client and the miner sign, and
+// publish to the storage market.
+func (p *DealProposal) Terms() *storagemarket.StorageDealProposal {
+	return &storagemarket.StorageDealProposal{
+		PieceRef:   p.PieceRef,
+		Size:       p.Size,
+		TotalPrice: p.TotalPrice,
+		Duration:   p.Duration,
+		Client:     p.Payment.Payer,
+		Miner:      p.MinerAddress,
+	}
 }
 
 // DealResponse is the information sent over the wire, when a miner responds to a client.
@@ -79,8 +107,21 @@ type DealResponse struct {
 	// the miner has sealed the data into a sector.
 	ProofInfo *ProofInfo
 
-	// Signature is a signature from the miner over the response
+	// Signature is the counter-signature of the miner's owner over the terms
+	// of the accepted proposal.
 	Signature types.Signature
+
+	// PublishMessage is the cid of the message publishing the accepted deal
+	// to the storage market.
+	PublishMessage *cid.Cid
+
+	// DealID is the id of the deal in the storage market, set once the
+	// deal is published.
+	DealID uint64
+
+	// Transfer is the progress of the transfer of the data to the miner,
+	// set while the data is being transferred.
+	Transfer *datatransfer.Status
 }
 
 // ProofInfo contains the details about a seal proof, that the client needs to know to verify that his deal was posted on chain.
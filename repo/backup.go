@@ -0,0 +1,346 @@
+package repo
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	crypto "gx/ipfs/QmNiJiXwWE3kRhZrC5ej3kSjWHm337pYfhjLGSCDNKJP2s/go-libp2p-crypto"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	"gx/ipfs/QmdcULN1WCzgoQmcCaUAmEhwcxHYsDrbZ2LvRJKCL8dMrK/go-homedir"
+	"gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
+	"gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore/query"
+
+	"github.com/filecoin-project/go-filecoin/config"
+)
+
+// A backup is a gzipped tar archive holding, in order, the version and the
+// config of the repo, its swarm key if any, the keys of its keystore and the
+// entries of its datastores.
+const (
+	backupVersionEntry    = "version"
+	backupConfigEntry     = "config.json"
+	backupSwarmKeyEntry   = "swarm.key"
+	backupKeystorePrefix  = "keystore/"
+	backupDatastorePrefix = "datastores/"
+)
+
+type namedDatastore struct {
+	name string
+	ds   Datastore
+}
+
+// backupDatastores returns the datastores of r in the order they are backed
+// up. The blocks one comes last, so that blocks read as they are iterated
+// include those of the chain head the backup holds.
+func backupDatastores(r Repo) []namedDatastore {
+	return []namedDatastore{
+		{"wallet", r.WalletDatastore()},
+		{"deals", r.DealsDatastore()},
+		{"pieces", r.PiecesDatastore()},
+		{"chain", r.ChainDatastore()},
+		{"blocks", r.Datastore()},
+	}
+}
+
+// writeHolder is implemented by the repos whose datastore writes can be held
+// off, see FSRepo.holdWrites.
+type writeHolder interface {
+	holdWrites() (release func())
+}
+
+// backupSnapshot is the content of a backup, read at a single point in time.
+type backupSnapshot struct {
+	version    uint
+	config     []byte
+	swarmKey   []byte
+	keys       []backupKey
+	datastores []backupResults
+}
+
+type backupKey struct {
+	name string
+	data []byte
+}
+
+type backupResults struct {
+	name    string
+	results query.Results
+}
+
+// Backup writes a backup of r to w, which Restore turns back into a repo.
+// The repo may be in use meanwhile: the writes to its datastores are held off
+// while the backup is read, and each datastore is then read from a snapshot
+// taken at that time. The blocks of a flatfs blocks datastore, which has no
+// snapshots, are read as they are iterated.
+func Backup(r Repo, w io.Writer) error {
+	snap, err := takeBackupSnapshot(r)
+	if err != nil {
+		return err
+	}
+	defer snap.close()
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	if err := writeBackupEntry(tw, backupVersionEntry, []byte(strconv.Itoa(int(snap.version)))); err != nil {
+		return err
+	}
+	if err := writeBackupEntry(tw, backupConfigEntry, snap.config); err != nil {
+		return err
+	}
+	if snap.swarmKey != nil {
+		if err := writeBackupEntry(tw, backupSwarmKeyEntry, snap.swarmKey); err != nil {
+			return err
+		}
+	}
+	for _, key := range snap.keys {
+		if err := writeBackupEntry(tw, backupKeystorePrefix+key.name, key.data); err != nil {
+			return err
+		}
+	}
+	for _, ds := range snap.datastores {
+		if err := backupDatastore(ds, tw); err != nil {
+			return errors.Wrapf(err, "failed to back the %s datastore up", ds.name)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// BackupToFile writes a backup of r to the file at p. The backup is written
+// to a temporary file first, which replaces p once the backup is complete.
+func BackupToFile(r Repo, p string) (err error) {
+	f, err := ioutil.TempFile(filepath.Dir(p), "."+filepath.Base(p)+".tmp")
+	if err != nil {
+		return errors.Wrap(err, "failed to create backup file")
+	}
+	defer func() {
+		if err != nil {
+			f.Close()           // nolint: errcheck
+			os.Remove(f.Name()) // nolint: errcheck
+		}
+	}()
+
+	if err := Backup(r, f); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return errors.Wrap(err, "failed to write backup file")
+	}
+	if err := f.Close(); err != nil {
+		return errors.Wrap(err, "failed to write backup file")
+	}
+	return errors.Wrap(os.Rename(f.Name(), p), "failed to write backup file")
+}
+
+// takeBackupSnapshot reads everything but the datastore entries, and queries
+// the datastores, with the writes of r held off.
+func takeBackupSnapshot(r Repo) (*backupSnapshot, error) {
+	if h, ok := r.(writeHolder); ok {
+		release := h.holdWrites()
+		defer release()
+	}
+
+	snap := &backupSnapshot{version: r.Version()}
+
+	cfg, err := json.MarshalIndent(r.Config(), "", "\t")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode config")
+	}
+	snap.config = cfg
+
+	if snap.swarmKey, err = r.SwarmKey(); err != nil {
+		return nil, err
+	}
+
+	if snap.keys, err = backupKeystore(r); err != nil {
+		return nil, errors.Wrap(err, "failed to back the keystore up")
+	}
+
+	for _, nds := range backupDatastores(r) {
+		results, err := nds.ds.Query(query.Query{})
+		if err != nil {
+			snap.close()
+			return nil, errors.Wrapf(err, "failed to back the %s datastore up", nds.name)
+		}
+		snap.datastores = append(snap.datastores, backupResults{name: nds.name, results: results})
+	}
+	return snap, nil
+}
+
+func (snap *backupSnapshot) close() {
+	for _, ds := range snap.datastores {
+		ds.results.Close() // nolint: errcheck
+	}
+}
+
+func backupKeystore(r Repo) ([]backupKey, error) {
+	names, err := r.Keystore().List()
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []backupKey
+	for _, name := range names {
+		key, err := r.Keystore().Get(name)
+		if err != nil {
+			return nil, err
+		}
+		data, err := crypto.MarshalPrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, backupKey{name: name, data: data})
+	}
+	return keys, nil
+}
+
+func backupDatastore(ds backupResults, tw *tar.Writer) error {
+	for res := range ds.results.Next() {
+		if res.Error != nil {
+			return res.Error
+		}
+		if err := writeBackupEntry(tw, backupDatastorePrefix+ds.name+res.Key, res.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeBackupEntry(tw *tar.Writer, name string, data []byte) error {
+	if err := tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0600, Size: int64(len(data))}); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// Restore creates a repo at the given path from the backup read from rd.
+// The repo must not exist yet, and is removed again if the restoration
+// fails.
+func Restore(rd io.Reader, p string) error {
+	expath, err := homedir.Expand(p)
+	if err != nil {
+		return err
+	}
+
+	_, statErr := os.Stat(expath)
+	existed := statErr == nil
+
+	if err := restore(rd, expath); err != nil {
+		if existed {
+			return errors.Wrapf(err, "failed to restore the repo, %s has to be emptied before trying again", p)
+		}
+		os.RemoveAll(expath) // nolint: errcheck
+		return err
+	}
+	return nil
+}
+
+func restore(rd io.Reader, p string) error {
+	gz, err := gzip.NewReader(rd)
+	if err != nil {
+		return errors.Wrap(err, "failed to read backup")
+	}
+	tr := tar.NewReader(gz)
+
+	version, err := readBackupEntry(tr, backupVersionEntry)
+	if err != nil {
+		return err
+	}
+	if string(version) != strconv.Itoa(int(Version)) {
+		return fmt.Errorf("the backup is of repo version %s, but version %d is required", version, Version)
+	}
+
+	rawConfig, err := readBackupEntry(tr, backupConfigEntry)
+	if err != nil {
+		return err
+	}
+	cfg := config.NewDefaultConfig()
+	if err := json.Unmarshal(rawConfig, &cfg); err != nil {
+		return errors.Wrap(err, "failed to decode config")
+	}
+
+	if err := InitFSRepo(p, cfg); err != nil {
+		return err
+	}
+	r, err := OpenFSRepo(p)
+	if err != nil {
+		return err
+	}
+
+	if err := restoreEntries(r, tr); err != nil {
+		r.Close() // nolint: errcheck
+		return err
+	}
+	return r.Close()
+}
+
+func restoreEntries(r *FSRepo, tr *tar.Reader) error {
+	datastores := make(map[string]Datastore)
+	for _, nds := range backupDatastores(r) {
+		datastores[nds.name] = nds.ds
+	}
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to read backup")
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read backup entry %s", hdr.Name)
+		}
+
+		switch {
+		case hdr.Name == backupSwarmKeyEntry:
+			if err := r.SetSwarmKey(data); err != nil {
+				return err
+			}
+		case strings.HasPrefix(hdr.Name, backupKeystorePrefix):
+			key, err := crypto.UnmarshalPrivateKey(data)
+			if err != nil {
+				return errors.Wrapf(err, "failed to decode key %s", hdr.Name)
+			}
+			if err := r.Keystore().Put(strings.TrimPrefix(hdr.Name, backupKeystorePrefix), key); err != nil {
+				return err
+			}
+		case strings.HasPrefix(hdr.Name, backupDatastorePrefix):
+			name := strings.TrimPrefix(hdr.Name, backupDatastorePrefix)
+			i := strings.Index(name, "/")
+			if i < 0 || datastores[name[:i]] == nil {
+				return fmt.Errorf("unknown backup entry %s", hdr.Name)
+			}
+			if err := datastores[name[:i]].Put(datastore.NewKey(name[i:]), data); err != nil {
+				return errors.Wrapf(err, "failed to restore %s", hdr.Name)
+			}
+		default:
+			return fmt.Errorf("unknown backup entry %s", hdr.Name)
+		}
+	}
+}
+
+func readBackupEntry(tr *tar.Reader, name string) ([]byte, error) {
+	hdr, err := tr.Next()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read backup")
+	}
+	if hdr.Name != name {
+		return nil, fmt.Errorf("expected backup entry %s, got %s", name, hdr.Name)
+	}
+	return ioutil.ReadAll(tr)
+}
@@ -0,0 +1,220 @@
+package repo
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	crypto "gx/ipfs/QmNiJiXwWE3kRhZrC5ej3kSjWHm337pYfhjLGSCDNKJP2s/go-libp2p-crypto"
+	"gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/config"
+)
+
+func TestBackupRestore(t *testing.T) {
+	t.Parallel()
+
+	t.Run("restores a repo equal to the one backed up", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		src := NewInMemoryRepo()
+		defer src.CleanupSectorDirs()
+		src.C.Bootstrap.Period = "5m"
+		src.SK = []byte("swarm key")
+		key, _, err := crypto.GenerateKeyPair(crypto.RSA, 1024)
+		require.NoError(err)
+		require.NoError(src.Keystore().Put("self", key))
+		require.NoError(src.Datastore().Put(datastore.NewKey("/blocks/a"), []byte("block")))
+		require.NoError(src.ChainDatastore().Put(datastore.NewKey("/chain/heaviestTipSet"), []byte("head")))
+		require.NoError(src.WalletDatastore().Put(datastore.NewKey("/t1abc"), []byte("wallet")))
+		require.NoError(src.DealsDatastore().Put(datastore.NewKey("/client/deal"), []byte("deal")))
+		require.NoError(src.PiecesDatastore().Put(datastore.NewKey("/blocks/b"), []byte("piece")))
+
+		var buf bytes.Buffer
+		require.NoError(Backup(src, &buf))
+
+		dir, err := ioutil.TempDir("", "")
+		require.NoError(err)
+		defer os.RemoveAll(dir)
+		p := filepath.Join(dir, "repo")
+		require.NoError(Restore(&buf, p))
+
+		r, err := OpenFSRepo(p)
+		require.NoError(err)
+		defer r.Close() // nolint: errcheck
+
+		assert.Equal("5m", r.Config().Bootstrap.Period)
+		swarmKey, err := r.SwarmKey()
+		require.NoError(err)
+		assert.Equal([]byte("swarm key"), swarmKey)
+
+		restoredKey, err := r.Keystore().Get("self")
+		require.NoError(err)
+		assert.True(key.Equals(restoredKey))
+
+		for _, entry := range []struct {
+			ds    Datastore
+			key   string
+			value string
+		}{
+			{r.Datastore(), "/blocks/a", "block"},
+			{r.ChainDatastore(), "/chain/heaviestTipSet", "head"},
+			{r.WalletDatastore(), "/t1abc", "wallet"},
+			{r.DealsDatastore(), "/client/deal", "deal"},
+			{r.PiecesDatastore(), "/blocks/b", "piece"},
+		} {
+			value, err := entry.ds.Get(datastore.NewKey(entry.key))
+			require.NoError(err)
+			assert.Equal(entry.value, string(value))
+		}
+	})
+
+	t.Run("refuses to overwrite a repo", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		src := NewInMemoryRepo()
+		defer src.CleanupSectorDirs()
+		var buf bytes.Buffer
+		require.NoError(Backup(src, &buf))
+
+		dir, err := ioutil.TempDir("", "")
+		require.NoError(err)
+		defer os.RemoveAll(dir)
+		require.NoError(InitFSRepo(dir, src.Config()))
+
+		err = Restore(&buf, dir)
+		assert.Contains(err.Error(), "repo already initialized")
+		assert.True(fileExists(filepath.Join(dir, configFilename)))
+	})
+
+	t.Run("removes the repo when restoring fails", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		dir, err := ioutil.TempDir("", "")
+		require.NoError(err)
+		defer os.RemoveAll(dir)
+		p := filepath.Join(dir, "repo")
+
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gz)
+		require.NoError(writeBackupEntry(tw, backupVersionEntry, []byte(strconv.Itoa(int(Version)))))
+		require.NoError(writeBackupEntry(tw, backupConfigEntry, []byte("{}")))
+		require.NoError(writeBackupEntry(tw, "bogus", []byte("bogus")))
+		require.NoError(tw.Close())
+		require.NoError(gz.Close())
+
+		err = Restore(&buf, p)
+		assert.EqualError(err, "unknown backup entry bogus")
+		_, err = os.Stat(p)
+		assert.True(os.IsNotExist(err))
+	})
+
+	t.Run("reads the datastores from a snapshot", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		dir, err := ioutil.TempDir("", "")
+		require.NoError(err)
+		defer os.RemoveAll(dir)
+		require.NoError(InitFSRepo(dir, config.NewDefaultConfig()))
+		r, err := OpenFSRepo(dir)
+		require.NoError(err)
+		defer r.Close() // nolint: errcheck
+
+		require.NoError(r.DealsDatastore().Put(datastore.NewKey("/client/before"), []byte("deal")))
+		snap, err := takeBackupSnapshot(r)
+		require.NoError(err)
+		defer snap.close()
+		require.NoError(r.DealsDatastore().Put(datastore.NewKey("/client/after"), []byte("deal")))
+
+		for _, ds := range snap.datastores {
+			if ds.name != "deals" {
+				continue
+			}
+			entries, err := ds.results.Rest()
+			require.NoError(err)
+			require.Len(entries, 1)
+			assert.Equal("/client/before", entries[0].Key)
+		}
+	})
+}
+
+func TestBackupToFile(t *testing.T) {
+	t.Parallel()
+
+	t.Run("replaces the file once the backup is complete", func(t *testing.T) {
+		require := require.New(t)
+
+		src := NewInMemoryRepo()
+		defer src.CleanupSectorDirs()
+		require.NoError(src.DealsDatastore().Put(datastore.NewKey("/client/deal"), []byte("deal")))
+
+		dir, err := ioutil.TempDir("", "")
+		require.NoError(err)
+		defer os.RemoveAll(dir)
+		p := filepath.Join(dir, "backup")
+		require.NoError(ioutil.WriteFile(p, []byte("older backup"), 0600))
+
+		require.NoError(BackupToFile(src, p))
+		assertDirHolds(t, dir, "backup")
+
+		f, err := os.Open(p)
+		require.NoError(err)
+		defer f.Close() // nolint: errcheck
+		restored := filepath.Join(dir, "repo")
+		require.NoError(Restore(f, restored))
+	})
+
+	t.Run("leaves the file as it was when the backup fails", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		src := &swarmKeyFailingRepo{MemRepo: NewInMemoryRepo()}
+		defer src.CleanupSectorDirs()
+
+		dir, err := ioutil.TempDir("", "")
+		require.NoError(err)
+		defer os.RemoveAll(dir)
+		p := filepath.Join(dir, "backup")
+		require.NoError(ioutil.WriteFile(p, []byte("older backup"), 0600))
+
+		assert.Error(BackupToFile(src, p))
+		assertDirHolds(t, dir, "backup")
+		data, err := ioutil.ReadFile(p)
+		require.NoError(err)
+		assert.Equal("older backup", string(data))
+	})
+}
+
+type swarmKeyFailingRepo struct {
+	*MemRepo
+}
+
+func (r *swarmKeyFailingRepo) SwarmKey() ([]byte, error) {
+	return nil, errors.New("failed to read swarm key")
+}
+
+// assertDirHolds asserts that dir holds the files with the given names and
+// no others.
+func assertDirHolds(t *testing.T, dir string, names ...string) {
+	infos, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	var found []string
+	for _, info := range infos {
+		found = append(found, info.Name())
+	}
+	assert.Equal(t, names, found)
+}
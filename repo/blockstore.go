@@ -0,0 +1,293 @@
+package repo
+
+import (
+	"context"
+	"sync"
+
+	cid "gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	bstore "gx/ipfs/QmS2aqUZLJp8kF1ihE5rvDGE5LvmKDPnx32w9Z1BW9xLV5/go-ipfs-blockstore"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	blocks "gx/ipfs/QmWoXtvgC8inqFkAATB7cp2Dax7XBi9VDvSg9RCCZufmRk/go-block-format"
+)
+
+// NewBlockstore returns the blockstore of the node of r. The chain, state
+// and message blocks, all dag-cbor, are kept in the datastore of r, and any
+// other block, e.g. the unixfs dags of the pieces of storage deals, in its
+// pieces datastore.
+func NewBlockstore(r Repo) *SplitBlockstore {
+	bs := &SplitBlockstore{
+		chain:  bstore.NewBlockstore(r.Datastore()),
+		pieces: bstore.NewBlockstore(r.PiecesDatastore()),
+	}
+	bs.gcCond = sync.NewCond(&bs.gcLk)
+	return bs
+}
+
+// SplitBlockstore routes the blocks to the chain or pieces blockstore by
+// their codec. Blocks of the pieces stored before the split were kept with
+// the chain, and are still found there.
+type SplitBlockstore struct {
+	chain  bstore.Blockstore
+	pieces bstore.Blockstore
+
+	// put holds the blocks put since BeginGC, which Sweep keeps. It is nil
+	// unless garbage is being collected.
+	put *cid.Set
+	// collecting is set from BeginGC to EndGC, and holds counts the holds,
+	// see HoldGC.
+	collecting bool
+	holds      int
+	gcLk       sync.Mutex
+	gcCond     *sync.Cond
+}
+
+var _ bstore.Blockstore = (*SplitBlockstore)(nil)
+
+// GCResult is the outcome of a garbage collection of the blockstore.
+type GCResult struct {
+	DryRun bool `json:"dryRun"`
+	// Live is the number of live blocks kept.
+	Live int `json:"live"`
+	// Blocks and Size are the number and total size in bytes of the blocks
+	// deleted, or which would be deleted if DryRun is set.
+	Blocks uint64 `json:"blocks"`
+	Size   uint64 `json:"size"`
+}
+
+// ErrGCRunning is returned by BeginGC if garbage is already being collected.
+var ErrGCRunning = errors.New("garbage collection already running")
+
+// BeginGC starts a garbage collection of the blockstore, once the holds in
+// progress are released, see HoldGC. Until EndGC, the blocks put are recorded
+// and kept by Sweep, so that blocks stored while the live ones are being
+// marked aren't deleted.
+func (bs *SplitBlockstore) BeginGC() error {
+	bs.gcLk.Lock()
+	defer bs.gcLk.Unlock()
+
+	if bs.collecting {
+		return ErrGCRunning
+	}
+	bs.collecting = true
+	for bs.holds > 0 {
+		bs.gcCond.Wait()
+	}
+	bs.put = cid.NewSet()
+	return nil
+}
+
+// EndGC ends the garbage collection started by BeginGC.
+func (bs *SplitBlockstore) EndGC() {
+	bs.gcLk.Lock()
+	defer bs.gcLk.Unlock()
+
+	bs.put = nil
+	bs.collecting = false
+	bs.gcCond.Broadcast()
+}
+
+// HoldGC keeps garbage from being collected until release is called. It is
+// held by whoever puts blocks which no live root links to yet, from before
+// putting them until they are linked, e.g. until the chain head is set to the
+// blocks synced, or the data imported is pinned. Otherwise a collection
+// started in between would delete them, since Sweep only keeps the blocks put
+// since BeginGC.
+//
+// HoldGC waits for the collection in progress, if any. A collection about to
+// begin waits for the holds instead, so that holds can be nested.
+func (bs *SplitBlockstore) HoldGC() (release func()) {
+	bs.gcLk.Lock()
+	defer bs.gcLk.Unlock()
+
+	for bs.put != nil {
+		bs.gcCond.Wait()
+	}
+	bs.holds++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			bs.gcLk.Lock()
+			defer bs.gcLk.Unlock()
+
+			bs.holds--
+			bs.gcCond.Broadcast()
+		})
+	}
+}
+
+// Sweep deletes the blocks which are neither live nor put since BeginGC, and
+// returns their number and total size. If dryRun is set, the blocks are only
+// counted.
+func (bs *SplitBlockstore) Sweep(ctx context.Context, live *cid.Set, dryRun bool) (uint64, uint64, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	keys, err := bs.AllKeysChan(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var count, size uint64
+	for c := range keys {
+		if live.Has(c) {
+			continue
+		}
+		n, err := bs.sweepBlock(c, dryRun)
+		if err != nil {
+			return count, size, err
+		}
+		if n >= 0 {
+			count++
+			size += uint64(n)
+		}
+	}
+	return count, size, ctx.Err()
+}
+
+// sweepBlock deletes the block c unless it was put since BeginGC, and returns
+// its size, or -1 if it is kept. The check and the deletion happen under
+// gcLk, which Put takes to record a block before storing it.
+func (bs *SplitBlockstore) sweepBlock(c cid.Cid, dryRun bool) (int, error) {
+	bs.gcLk.Lock()
+	defer bs.gcLk.Unlock()
+
+	if bs.put != nil && bs.put.Has(c) {
+		return -1, nil
+	}
+	n, err := bs.GetSize(c)
+	if err == bstore.ErrNotFound {
+		return -1, nil
+	}
+	if err != nil || dryRun {
+		return n, err
+	}
+	if err := bs.DeleteBlock(c); err != nil && err != bstore.ErrNotFound {
+		return -1, err
+	}
+	return n, nil
+}
+
+// recordPut records the blocks as put if garbage is being collected.
+func (bs *SplitBlockstore) recordPut(blks ...blocks.Block) {
+	bs.gcLk.Lock()
+	defer bs.gcLk.Unlock()
+
+	if bs.put == nil {
+		return
+	}
+	for _, blk := range blks {
+		bs.put.Add(blk.Cid())
+	}
+}
+
+func isChainBlock(c cid.Cid) bool {
+	return c.Type() == cid.DagCBOR
+}
+
+func (bs *SplitBlockstore) DeleteBlock(c cid.Cid) error {
+	if isChainBlock(c) {
+		return bs.chain.DeleteBlock(c)
+	}
+	err := bs.pieces.DeleteBlock(c)
+	if err == bstore.ErrNotFound {
+		return bs.chain.DeleteBlock(c)
+	}
+	return err
+}
+
+func (bs *SplitBlockstore) Has(c cid.Cid) (bool, error) {
+	if isChainBlock(c) {
+		return bs.chain.Has(c)
+	}
+	has, err := bs.pieces.Has(c)
+	if err != nil || has {
+		return has, err
+	}
+	return bs.chain.Has(c)
+}
+
+func (bs *SplitBlockstore) Get(c cid.Cid) (blocks.Block, error) {
+	if isChainBlock(c) {
+		return bs.chain.Get(c)
+	}
+	blk, err := bs.pieces.Get(c)
+	if err == bstore.ErrNotFound {
+		return bs.chain.Get(c)
+	}
+	return blk, err
+}
+
+func (bs *SplitBlockstore) GetSize(c cid.Cid) (int, error) {
+	if isChainBlock(c) {
+		return bs.chain.GetSize(c)
+	}
+	size, err := bs.pieces.GetSize(c)
+	if err == bstore.ErrNotFound {
+		return bs.chain.GetSize(c)
+	}
+	return size, err
+}
+
+func (bs *SplitBlockstore) Put(blk blocks.Block) error {
+	bs.recordPut(blk)
+	if isChainBlock(blk.Cid()) {
+		return bs.chain.Put(blk)
+	}
+	return bs.pieces.Put(blk)
+}
+
+func (bs *SplitBlockstore) PutMany(blks []blocks.Block) error {
+	bs.recordPut(blks...)
+	var chainBlks, piecesBlks []blocks.Block
+	for _, blk := range blks {
+		if isChainBlock(blk.Cid()) {
+			chainBlks = append(chainBlks, blk)
+		} else {
+			piecesBlks = append(piecesBlks, blk)
+		}
+	}
+
+	if len(chainBlks) > 0 {
+		if err := bs.chain.PutMany(chainBlks); err != nil {
+			return err
+		}
+	}
+	if len(piecesBlks) > 0 {
+		return bs.pieces.PutMany(piecesBlks)
+	}
+	return nil
+}
+
+// AllKeysChan returns the keys of the chain blockstore, then of the pieces
+// one.
+func (bs *SplitBlockstore) AllKeysChan(ctx context.Context) (<-chan cid.Cid, error) {
+	chainKeys, err := bs.chain.AllKeysChan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	piecesKeys, err := bs.pieces.AllKeysChan(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan cid.Cid)
+	go func() {
+		defer close(out)
+		for _, keys := range []<-chan cid.Cid{chainKeys, piecesKeys} {
+			for c := range keys {
+				select {
+				case out <- c:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (bs *SplitBlockstore) HashOnRead(enabled bool) {
+	bs.chain.HashOnRead(enabled)
+	bs.pieces.HashOnRead(enabled)
+}
@@ -0,0 +1,252 @@
+package repo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cid "gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	bstore "gx/ipfs/QmS2aqUZLJp8kF1ihE5rvDGE5LvmKDPnx32w9Z1BW9xLV5/go-ipfs-blockstore"
+	blocks "gx/ipfs/QmWoXtvgC8inqFkAATB7cp2Dax7XBi9VDvSg9RCCZufmRk/go-block-format"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func chainBlock(t *testing.T, data string) blocks.Block {
+	c, err := cid.V1Builder{Codec: cid.DagCBOR, MhType: types.DefaultHashFunction}.Sum([]byte(data))
+	require.NoError(t, err)
+	blk, err := blocks.NewBlockWithCid([]byte(data), c)
+	require.NoError(t, err)
+	return blk
+}
+
+func TestSplitBlockstore(t *testing.T) {
+	t.Parallel()
+
+	t.Run("routes the blocks by codec", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		r := NewInMemoryRepo()
+		defer r.CleanupSectorDirs()
+		bs := NewBlockstore(r)
+
+		chainBlk := chainBlock(t, "header")
+		pieceBlk := blocks.NewBlock([]byte("piece"))
+		require.NoError(bs.PutMany([]blocks.Block{chainBlk, pieceBlk}))
+
+		chain := bstore.NewBlockstore(r.Datastore())
+		pieces := bstore.NewBlockstore(r.PiecesDatastore())
+		for _, check := range []struct {
+			bs  bstore.Blockstore
+			blk blocks.Block
+			has bool
+		}{
+			{chain, chainBlk, true},
+			{chain, pieceBlk, false},
+			{pieces, chainBlk, false},
+			{pieces, pieceBlk, true},
+		} {
+			has, err := check.bs.Has(check.blk.Cid())
+			require.NoError(err)
+			assert.Equal(check.has, has)
+		}
+
+		for _, blk := range []blocks.Block{chainBlk, pieceBlk} {
+			got, err := bs.Get(blk.Cid())
+			require.NoError(err)
+			assert.Equal(blk.RawData(), got.RawData())
+		}
+
+		keys, err := bs.AllKeysChan(context.Background())
+		require.NoError(err)
+		var all []cid.Cid
+		for c := range keys {
+			all = append(all, c)
+		}
+		assert.ElementsMatch([]cid.Cid{chainBlk.Cid(), pieceBlk.Cid()}, all)
+	})
+
+	t.Run("finds the pieces stored before the split", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		r := NewInMemoryRepo()
+		defer r.CleanupSectorDirs()
+		pieceBlk := blocks.NewBlock([]byte("piece"))
+		require.NoError(bstore.NewBlockstore(r.Datastore()).Put(pieceBlk))
+
+		bs := NewBlockstore(r)
+		has, err := bs.Has(pieceBlk.Cid())
+		require.NoError(err)
+		assert.True(has)
+		got, err := bs.Get(pieceBlk.Cid())
+		require.NoError(err)
+		assert.Equal(pieceBlk.RawData(), got.RawData())
+		size, err := bs.GetSize(pieceBlk.Cid())
+		require.NoError(err)
+		assert.Equal(len(pieceBlk.RawData()), size)
+
+		require.NoError(bs.DeleteBlock(pieceBlk.Cid()))
+		_, err = bs.Get(pieceBlk.Cid())
+		assert.Equal(bstore.ErrNotFound, err)
+	})
+}
+
+func TestSplitBlockstoreSweep(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	setup := func(t *testing.T) (*SplitBlockstore, blocks.Block, blocks.Block, blocks.Block) {
+		bs := NewBlockstore(NewInMemoryRepo())
+		liveBlk := chainBlock(t, "live")
+		deadChainBlk := chainBlock(t, "dead")
+		deadPieceBlk := blocks.NewBlock([]byte("dead piece"))
+		require.NoError(t, bs.PutMany([]blocks.Block{liveBlk, deadChainBlk, deadPieceBlk}))
+		return bs, liveBlk, deadChainBlk, deadPieceBlk
+	}
+	hasAll := func(t *testing.T, bs bstore.Blockstore, blks ...blocks.Block) bool {
+		for _, blk := range blks {
+			has, err := bs.Has(blk.Cid())
+			require.NoError(t, err)
+			if !has {
+				return false
+			}
+		}
+		return true
+	}
+
+	t.Run("deletes the blocks which aren't live", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		bs, liveBlk, deadChainBlk, deadPieceBlk := setup(t)
+		require.NoError(bs.BeginGC())
+		defer bs.EndGC()
+
+		live := cid.NewSet()
+		live.Add(liveBlk.Cid())
+		count, size, err := bs.Sweep(ctx, live, false)
+		require.NoError(err)
+		assert.Equal(uint64(2), count)
+		assert.Equal(uint64(len(deadChainBlk.RawData())+len(deadPieceBlk.RawData())), size)
+
+		assert.True(hasAll(t, bs, liveBlk))
+		assert.False(hasAll(t, bs, deadChainBlk))
+		assert.False(hasAll(t, bs, deadPieceBlk))
+	})
+
+	t.Run("dry run only counts the blocks", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		bs, liveBlk, deadChainBlk, deadPieceBlk := setup(t)
+		require.NoError(bs.BeginGC())
+		defer bs.EndGC()
+
+		live := cid.NewSet()
+		live.Add(liveBlk.Cid())
+		count, _, err := bs.Sweep(ctx, live, true)
+		require.NoError(err)
+		assert.Equal(uint64(2), count)
+		assert.True(hasAll(t, bs, liveBlk, deadChainBlk, deadPieceBlk))
+	})
+
+	t.Run("keeps the blocks put during the collection", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		bs, liveBlk, deadChainBlk, deadPieceBlk := setup(t)
+		require.NoError(bs.BeginGC())
+		defer bs.EndGC()
+
+		// putting a block again marks it as used, even if it is stored already
+		require.NoError(bs.Put(deadPieceBlk))
+		newBlk := chainBlock(t, "new")
+		require.NoError(bs.Put(newBlk))
+
+		count, _, err := bs.Sweep(ctx, cid.NewSet(), false)
+		require.NoError(err)
+		assert.Equal(uint64(2), count)
+		assert.True(hasAll(t, bs, deadPieceBlk, newBlk))
+		assert.False(hasAll(t, bs, liveBlk))
+		assert.False(hasAll(t, bs, deadChainBlk))
+	})
+
+	t.Run("waits for the holds before marking", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		bs, _, _, _ := setup(t)
+		release := bs.HoldGC()
+		// the block is put before the collection, but only linked after
+		newBlk := chainBlock(t, "new")
+		require.NoError(bs.Put(newBlk))
+
+		began := make(chan error)
+		go func() {
+			began <- bs.BeginGC()
+		}()
+		select {
+		case <-began:
+			t.Fatal("collection began while held")
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		live := cid.NewSet()
+		live.Add(newBlk.Cid())
+		release()
+		require.NoError(<-began)
+		defer bs.EndGC()
+
+		_, _, err := bs.Sweep(ctx, live, false)
+		require.NoError(err)
+		assert.True(hasAll(t, bs, newBlk))
+	})
+
+	t.Run("holds wait for the collection", func(t *testing.T) {
+		bs, _, _, _ := setup(t)
+		require.NoError(t, bs.BeginGC())
+
+		held := make(chan func())
+		go func() {
+			held <- bs.HoldGC()
+		}()
+		select {
+		case <-held:
+			t.Fatal("held while collecting")
+		case <-time.After(100 * time.Millisecond):
+		}
+		bs.EndGC()
+		release := <-held
+		release()
+	})
+
+	t.Run("holds nest while a collection waits", func(t *testing.T) {
+		bs, _, _, _ := setup(t)
+		release := bs.HoldGC()
+
+		began := make(chan error)
+		go func() {
+			began <- bs.BeginGC()
+		}()
+		time.Sleep(50 * time.Millisecond)
+
+		nested := bs.HoldGC()
+		nested()
+		release()
+		require.NoError(t, <-began)
+		bs.EndGC()
+	})
+
+	t.Run("one collection at a time", func(t *testing.T) {
+		bs, _, _, _ := setup(t)
+		require.NoError(t, bs.BeginGC())
+		assert.Equal(t, ErrGCRunning, bs.BeginGC())
+		bs.EndGC()
+		assert.NoError(t, bs.BeginGC())
+	})
+}
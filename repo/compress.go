@@ -0,0 +1,138 @@
+package repo
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"io/ioutil"
+
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	"gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
+	"gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore/query"
+)
+
+// The values of a compressed datastore start with a byte telling how the
+// rest of them is encoded. Values which deflate doesn't shrink are stored
+// as they are.
+const (
+	rawValue      = byte(0)
+	deflatedValue = byte(1)
+)
+
+// compressedDatastore is a datastore compressing its values with deflate.
+// A datastore must be compressed from its creation on, the values written
+// to it before would not be readable.
+type compressedDatastore struct {
+	Datastore
+}
+
+func (ds *compressedDatastore) Put(key datastore.Key, value []byte) error {
+	encoded, err := compressValue(value)
+	if err != nil {
+		return err
+	}
+	return ds.Datastore.Put(key, encoded)
+}
+
+func (ds *compressedDatastore) Get(key datastore.Key) ([]byte, error) {
+	encoded, err := ds.Datastore.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	return decompressValue(encoded)
+}
+
+// Query decompresses the values of the results. Filters and orders are
+// applied to the decompressed values, by the datastore if they only look at
+// keys.
+func (ds *compressedDatastore) Query(q query.Query) (query.Results, error) {
+	naive := len(q.Filters) > 0 || len(q.Orders) > 0
+	childQuery := q
+	if naive {
+		childQuery = query.Query{Prefix: q.Prefix, KeysOnly: q.KeysOnly}
+	}
+
+	res, err := ds.Datastore.Query(childQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan query.Result)
+	go func() {
+		defer close(out)
+		for r := range res.Next() {
+			if r.Error == nil && !childQuery.KeysOnly {
+				r.Value, r.Error = decompressValue(r.Value)
+			}
+			select {
+			case out <- r:
+			case <-res.Process().Closing():
+				return
+			}
+		}
+	}()
+	decompressed := query.DerivedResults(res, out)
+	if naive {
+		return query.NaiveQueryApply(q, decompressed), nil
+	}
+	return decompressed, nil
+}
+
+func (ds *compressedDatastore) Batch() (datastore.Batch, error) {
+	b, err := ds.Datastore.Batch()
+	if err != nil {
+		return nil, err
+	}
+	return &compressedBatch{Batch: b}, nil
+}
+
+// compressedBatch is a batch of a compressed datastore.
+type compressedBatch struct {
+	datastore.Batch
+}
+
+func (b *compressedBatch) Put(key datastore.Key, value []byte) error {
+	encoded, err := compressValue(value)
+	if err != nil {
+		return err
+	}
+	return b.Batch.Put(key, encoded)
+}
+
+func compressValue(value []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte(deflatedValue)
+	w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to compress value")
+	}
+	if _, err := w.Write(value); err != nil {
+		return nil, errors.Wrap(err, "failed to compress value")
+	}
+	if err := w.Close(); err != nil {
+		return nil, errors.Wrap(err, "failed to compress value")
+	}
+
+	if buf.Len() > len(value) {
+		return append([]byte{rawValue}, value...), nil
+	}
+	return buf.Bytes(), nil
+}
+
+func decompressValue(encoded []byte) ([]byte, error) {
+	if len(encoded) == 0 {
+		return nil, errors.New("compressed value is empty")
+	}
+	switch encoded[0] {
+	case rawValue:
+		return encoded[1:], nil
+	case deflatedValue:
+		value, err := ioutil.ReadAll(flate.NewReader(bytes.NewReader(encoded[1:])))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to decompress value")
+		}
+		return value, nil
+	default:
+		return nil, fmt.Errorf("unknown value encoding %d, the datastore may not be compressed", encoded[0])
+	}
+}
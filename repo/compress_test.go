@@ -0,0 +1,102 @@
+package repo
+
+import (
+	"bytes"
+	"testing"
+
+	"gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
+	"gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore/query"
+	dss "gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore/sync"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressedDatastore(t *testing.T) {
+	t.Parallel()
+
+	compressible := bytes.Repeat([]byte("filecoin"), 100)
+
+	t.Run("stores values compressed", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		child := dss.MutexWrap(datastore.NewMapDatastore())
+		ds := &compressedDatastore{Datastore: child}
+
+		require.NoError(ds.Put(datastore.NewKey("big"), compressible))
+		require.NoError(ds.Put(datastore.NewKey("small"), []byte("x")))
+		require.NoError(ds.Put(datastore.NewKey("empty"), []byte{}))
+
+		stored, err := child.Get(datastore.NewKey("big"))
+		require.NoError(err)
+		assert.True(len(stored) < len(compressible))
+		// values deflate doesn't shrink are stored as they are
+		stored, err = child.Get(datastore.NewKey("small"))
+		require.NoError(err)
+		assert.Equal([]byte{rawValue, 'x'}, stored)
+
+		val, err := ds.Get(datastore.NewKey("big"))
+		require.NoError(err)
+		assert.Equal(compressible, val)
+		val, err = ds.Get(datastore.NewKey("small"))
+		require.NoError(err)
+		assert.Equal([]byte("x"), val)
+		val, err = ds.Get(datastore.NewKey("empty"))
+		require.NoError(err)
+		assert.Len(val, 0)
+	})
+
+	t.Run("batches are compressed", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		ds := &compressedDatastore{Datastore: dss.MutexWrap(datastore.NewMapDatastore())}
+		batch, err := ds.Batch()
+		require.NoError(err)
+		require.NoError(batch.Put(datastore.NewKey("big"), compressible))
+		require.NoError(batch.Commit())
+
+		val, err := ds.Get(datastore.NewKey("big"))
+		require.NoError(err)
+		assert.Equal(compressible, val)
+	})
+
+	t.Run("queries return the values decompressed", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		ds := &compressedDatastore{Datastore: dss.MutexWrap(datastore.NewMapDatastore())}
+		require.NoError(ds.Put(datastore.NewKey("/a/1"), compressible))
+		require.NoError(ds.Put(datastore.NewKey("/a/2"), []byte("two")))
+		require.NoError(ds.Put(datastore.NewKey("/b/1"), []byte("other")))
+
+		res, err := ds.Query(query.Query{
+			Prefix:  "/a",
+			Filters: []query.Filter{query.FilterValueCompare{Op: query.Equal, Value: []byte("two")}},
+		})
+		require.NoError(err)
+		entries, err := res.Rest()
+		require.NoError(err)
+		require.Len(entries, 1)
+		assert.Equal("/a/2", entries[0].Key)
+		assert.Equal([]byte("two"), entries[0].Value)
+
+		res, err = ds.Query(query.Query{Prefix: "/a", Orders: []query.Order{query.OrderByKeyDescending{}}})
+		require.NoError(err)
+		entries, err = res.Rest()
+		require.NoError(err)
+		require.Len(entries, 2)
+		assert.Equal([]byte("two"), entries[0].Value)
+		assert.Equal(compressible, entries[1].Value)
+	})
+
+	t.Run("uncompressed values fail", func(t *testing.T) {
+		child := dss.MutexWrap(datastore.NewMapDatastore())
+		require.NoError(t, child.Put(datastore.NewKey("plain"), []byte("\x07plain")))
+
+		ds := &compressedDatastore{Datastore: child}
+		_, err := ds.Get(datastore.NewKey("plain"))
+		assert.Error(t, err)
+	})
+}
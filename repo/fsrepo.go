@@ -15,10 +15,15 @@ import (
 	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
 	badgerds "gx/ipfs/QmVoK2ivqzp5ZgWiEdBNFbKH7nzf9C4wPYr8cH7CGPMHtC/go-ds-badger"
 	keystore "gx/ipfs/QmZxaF6uz9VWbuQ5Jk43stXksbnX8x5veYS73eFD4hKqtD/go-ipfs-keystore"
+	flatfs "gx/ipfs/QmaHSUAhuf9WB3G7SxGyVqhSn7dr8RpbCzD3YoJQVN5Xg8/go-ds-flatfs"
 	lockfile "gx/ipfs/QmcWjZkQxyPMkgZRpda4hqWwaD6E1yqCvcxZfxbt98CEAK/go-fs-lock"
+	levelds "gx/ipfs/QmccqjKZUTqp4ikWNyAbjBuP5HEdqSqRuAr9mcEhYab54a/go-ds-leveldb"
 	logging "gx/ipfs/QmcuXC5cxs79ro2cUuHs4HQ2bkDLJUYokwL8aivcX6HW3C/go-log"
 	"gx/ipfs/QmdcULN1WCzgoQmcCaUAmEhwcxHYsDrbZ2LvRJKCL8dMrK/go-homedir"
+	"gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
+	"gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore/mount"
 
+	"github.com/filecoin-project/go-filecoin/auth"
 	"github.com/filecoin-project/go-filecoin/config"
 )
 
@@ -29,11 +34,23 @@ const (
 	tempConfigFilename     = ".config.json.temp"
 	lockFile               = "repo.lock"
 	versionFilename        = "version"
+	networkFilename        = "network"
 	walletDatastorePrefix  = "wallet"
 	chainDatastorePrefix   = "chain"
 	dealsDatastorePrefix   = "deals"
+	piecesDatastorePrefix  = "pieces"
+	blocksDatastorePrefix  = "blocks"
 	snapshotStorePrefix    = "snapshots"
 	snapshotFilenamePrefix = "snapshot"
+	// SwarmKeyFile is the filename containing the key of the private
+	// network of the node, if any.
+	SwarmKeyFile = "swarm.key"
+	// APITokenFile is the filename containing an admin token of the api,
+	// which the commands run locally authenticate with.
+	APITokenFile = "token"
+	// APISecretFile is the filename containing the secret api tokens are
+	// signed with.
+	APISecretFile = "api.secret"
 )
 
 // NoRepoError is returned when trying to open a repo where one does not exist
@@ -51,6 +68,7 @@ func (err NoRepoError) Error() string {
 type FSRepo struct {
 	path    string
 	version uint
+	network string
 
 	// lk protects the config file
 	lk       sync.RWMutex
@@ -60,6 +78,18 @@ type FSRepo struct {
 	walletDs Datastore
 	chainDs  Datastore
 	dealsDs  Datastore
+	piecesDs Datastore
+	// collectors are the badger datastores by the interval their value
+	// logs are garbage collected at, until stopGC is closed.
+	collectors map[string][]garbageCollector
+	stopGC     chan struct{}
+	gcLoops    sync.WaitGroup
+	// guard makes closing the repo wait for the operations in flight on
+	// its datastores.
+	guard *closeGuard
+
+	// secretLk protects the api secret file
+	secretLk sync.Mutex
 
 	// lockfile is the file system lock to prevent others from opening the same repo.
 	lockfile io.Closer
@@ -83,7 +113,7 @@ func OpenFSRepo(p string) (*FSRepo, error) {
 		return nil, &NoRepoError{p}
 	}
 
-	r := &FSRepo{path: expath}
+	r := &FSRepo{path: expath, guard: &closeGuard{}}
 
 	r.lockfile, err = lockfile.Lock(r.path, lockFile)
 	if err != nil {
@@ -98,12 +128,26 @@ func OpenFSRepo(p string) (*FSRepo, error) {
 	return r, nil
 }
 
+// CheckConfig checks the config file of the repo at the given path strictly,
+// see config.CheckFile.
+func CheckConfig(p string) error {
+	expath, err := homedir.Expand(p)
+	if err != nil {
+		return err
+	}
+	return config.CheckFile(filepath.Join(expath, configFilename))
+}
+
 func (r *FSRepo) loadFromDisk() error {
 	localVersion, err := r.loadVersion()
 	if err != nil {
 		return errors.Wrap(err, "failed to load version")
 	}
 
+	if localVersion < Version {
+		return fmt.Errorf("repo version %d is older than %d.\nplease run: 'go-filecoin repo migrate [--repodir=%s]'", localVersion, Version, r.path)
+	}
+
 	if localVersion != Version {
 		return fmt.Errorf("invalid repo version, got %d expected %d", localVersion, Version)
 	}
@@ -114,6 +158,10 @@ func (r *FSRepo) loadFromDisk() error {
 		return errors.Wrap(err, "failed to load config file")
 	}
 
+	if err := r.loadNetwork(); err != nil {
+		return errors.Wrap(err, "failed to load network file")
+	}
+
 	if err := r.openDatastore(); err != nil {
 		return errors.Wrap(err, "failed to open datastore")
 	}
@@ -133,6 +181,14 @@ func (r *FSRepo) loadFromDisk() error {
 	if err := r.openDealsDatastore(); err != nil {
 		return errors.Wrap(err, "failed to open deals datastore")
 	}
+
+	if err := r.openPiecesDatastore(); err != nil {
+		return errors.Wrap(err, "failed to open pieces datastore")
+	}
+
+	if err := r.startGC(); err != nil {
+		return errors.Wrap(err, "failed to start datastore garbage collection")
+	}
 	return nil
 }
 
@@ -164,6 +220,10 @@ func InitFSRepo(p string, cfg *config.Config) error {
 		return errors.Wrap(err, "initializing config file failed")
 	}
 
+	if err := initNetwork(expath, cfg.Network.Name); err != nil {
+		return errors.Wrap(err, "initializing network file failed")
+	}
+
 	return nil
 }
 
@@ -227,18 +287,40 @@ func (r *FSRepo) DealsDatastore() Datastore {
 	return r.dealsDs
 }
 
+// PiecesDatastore returns the datastore of the pieces of storage deals.
+func (r *FSRepo) PiecesDatastore() Datastore {
+	return r.piecesDs
+}
+
 // Version returns the version of the repo
 func (r *FSRepo) Version() uint {
 	return r.version
 }
 
+// Network returns the name of the network the repo was initialized for.
+func (r *FSRepo) Network() string {
+	return r.network
+}
+
 // Keystore returns the keystore
 func (r *FSRepo) Keystore() keystore.Keystore {
 	return r.keystore
 }
 
+// holdWrites waits for the datastore writes in flight and holds the later
+// ones off until release is called.
+func (r *FSRepo) holdWrites() (release func()) {
+	return r.guard.holdWrites()
+}
+
 // Close closes the repo.
 func (r *FSRepo) Close() error {
+	if r.stopGC != nil {
+		close(r.stopGC)
+		r.gcLoops.Wait()
+	}
+	r.guard.close()
+
 	if err := r.ds.Close(); err != nil {
 		return errors.Wrap(err, "failed to close datastore")
 	}
@@ -255,6 +337,10 @@ func (r *FSRepo) Close() error {
 		return errors.Wrap(err, "failed to close miner deals datastore")
 	}
 
+	if err := r.piecesDs.Close(); err != nil {
+		return errors.Wrap(err, "failed to close pieces datastore")
+	}
+
 	if err := r.removeAPIFile(); err != nil {
 		return errors.Wrap(err, "error removing API file")
 	}
@@ -289,20 +375,33 @@ func isInitialized(p string) (bool, error) {
 }
 
 func (r *FSRepo) loadConfig() error {
-	configFile := filepath.Join(r.path, configFilename)
-
-	cfg, err := config.ReadFile(configFile)
+	cfg, err := r.ReadConfig()
 	if err != nil {
-		return errors.Wrapf(err, "failed to read config file at %q", configFile)
+		return err
 	}
 
 	r.cfg = cfg
 	return nil
 }
 
+// ReadConfig reads the config file of the repo.
+func (r *FSRepo) ReadConfig() (*config.Config, error) {
+	configFile := filepath.Join(r.path, configFilename)
+
+	cfg, err := config.ReadFile(configFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read config file at %q", configFile)
+	}
+	return cfg, nil
+}
+
 func (r *FSRepo) loadVersion() (uint, error) {
+	return readVersion(r.path)
+}
+
+func readVersion(p string) (uint, error) {
 	// TODO: limited file reading, to avoid attack vector
-	file, err := ioutil.ReadFile(filepath.Join(r.path, versionFilename))
+	file, err := ioutil.ReadFile(filepath.Join(p, versionFilename))
 	if err != nil {
 		return 0, err
 	}
@@ -315,18 +414,44 @@ func (r *FSRepo) loadVersion() (uint, error) {
 	return uint(version), nil
 }
 
+// loadNetwork reads the network file. Repos initialized before it was
+// written are of the network named by their config.
+func (r *FSRepo) loadNetwork() error {
+	file, err := ioutil.ReadFile(filepath.Join(r.path, networkFilename))
+	if os.IsNotExist(err) {
+		r.network = r.cfg.Network.Name
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	r.network = strings.TrimSpace(string(file))
+	return nil
+}
+
 func (r *FSRepo) openDatastore() error {
-	switch r.cfg.Datastore.Type {
-	case "badgerds":
-		ds, err := badgerds.NewDatastore(filepath.Join(r.path, r.cfg.Datastore.Path), nil)
+	ds, err := r.openKeyValueDatastore(filepath.Join(r.path, r.cfg.Datastore.Path), r.gcInterval())
+	if err != nil {
+		return err
+	}
+
+	switch r.cfg.Datastore.BlocksType {
+	case "":
+	case "flatfs":
+		blocks, err := openFlatfs(filepath.Join(r.path, blocksDatastorePrefix))
 		if err != nil {
 			return err
 		}
-		r.ds = ds
+		ds = mount.New([]mount.Mount{
+			{Prefix: datastore.NewKey(blocksDatastorePrefix), Datastore: blocks},
+			{Prefix: datastore.NewKey("/"), Datastore: ds},
+		})
 	default:
-		return fmt.Errorf("unknown datastore type in config: %s", r.cfg.Datastore.Type)
+		return fmt.Errorf("unknown blocks datastore type in config: %s", r.cfg.Datastore.BlocksType)
 	}
 
+	r.ds = r.wrap(ds)
+
 	return nil
 }
 
@@ -344,43 +469,204 @@ func (r *FSRepo) openKeystore() error {
 }
 
 func (r *FSRepo) openChainDatastore() error {
-	ds, err := badgerds.NewDatastore(filepath.Join(r.path, chainDatastorePrefix), nil)
+	ds, err := r.openKeyValueDatastore(filepath.Join(r.path, chainDatastorePrefix), r.gcInterval())
 	if err != nil {
 		return err
 	}
 
-	r.chainDs = ds
+	r.chainDs = r.wrap(ds)
 
 	return nil
 }
 
 func (r *FSRepo) openWalletDatastore() error {
 	// TODO: read wallet datastore info from config, use that to open it up
-	ds, err := badgerds.NewDatastore(filepath.Join(r.path, walletDatastorePrefix), nil)
+	ds, err := r.openKeyValueDatastore(filepath.Join(r.path, walletDatastorePrefix), r.gcInterval())
 	if err != nil {
 		return err
 	}
 
-	r.walletDs = ds
+	r.walletDs = r.wrap(ds)
 
 	return nil
 }
 
 func (r *FSRepo) openDealsDatastore() error {
-	ds, err := badgerds.NewDatastore(filepath.Join(r.path, dealsDatastorePrefix), nil)
+	ds, err := r.openKeyValueDatastore(filepath.Join(r.path, dealsDatastorePrefix), r.gcInterval())
 	if err != nil {
 		return err
 	}
 
-	r.dealsDs = ds
+	r.dealsDs = r.wrap(ds)
+
+	return nil
+}
+
+func (r *FSRepo) openPiecesDatastore() error {
+	p := piecesDatastorePrefix
+	gcInterval := r.gcInterval()
+	if cfg := r.cfg.Datastore.Pieces; cfg != nil {
+		if cfg.Path != "" {
+			p = cfg.Path
+		}
+		if cfg.GCInterval != "" {
+			gcInterval = cfg.GCInterval
+		}
+	}
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(r.path, p)
+	}
+
+	// the pieces datastore only holds blocks
+	var ds Datastore
+	switch r.cfg.Datastore.BlocksType {
+	case "":
+		var err error
+		ds, err = r.openKeyValueDatastore(p, gcInterval)
+		if err != nil {
+			return err
+		}
+	case "flatfs":
+		blocks, err := openFlatfs(p)
+		if err != nil {
+			return err
+		}
+		ds = mount.New([]mount.Mount{
+			{Prefix: datastore.NewKey(blocksDatastorePrefix), Datastore: blocks},
+		})
+	default:
+		return fmt.Errorf("unknown blocks datastore type in config: %s", r.cfg.Datastore.BlocksType)
+	}
+
+	r.piecesDs = r.wrap(ds)
+
+	return nil
+}
+
+// garbageCollector is a datastore reclaiming the space of the values deleted
+// or overwritten on demand, like badger.
+type garbageCollector interface {
+	CollectGarbage() error
+}
+
+// gcInterval returns the interval the badger datastores are garbage
+// collected at by default.
+func (r *FSRepo) gcInterval() string {
+	if r.cfg.Datastore.Badger == nil {
+		return ""
+	}
+	return r.cfg.Datastore.Badger.GCInterval
+}
+
+// openKeyValueDatastore opens the datastore of the type of the config at p.
+func (r *FSRepo) openKeyValueDatastore(p string, gcInterval string) (Datastore, error) {
+	switch r.cfg.Datastore.Type {
+	case "badgerds":
+		return r.openBadger(p, gcInterval)
+	case "leveldb":
+		return levelds.NewDatastore(p, nil)
+	default:
+		return nil, fmt.Errorf("unknown datastore type in config: %s", r.cfg.Datastore.Type)
+	}
+}
+
+// openFlatfs opens the flatfs datastore at p, which stores each value in a
+// file of its own and only accepts the keys of blocks.
+func openFlatfs(p string) (Datastore, error) {
+	return flatfs.CreateOrOpen(p, flatfs.NextToLast(2), true)
+}
 
+// openBadger opens the badger datastore at p with the options of the
+// config, to be garbage collected every gcInterval.
+func (r *FSRepo) openBadger(p string, gcInterval string) (Datastore, error) {
+	opts := badgerds.DefaultOptions
+	if cfg := r.cfg.Datastore.Badger; cfg != nil {
+		opts.ValueLogFileSize = cfg.ValueLogFileSize
+		opts.SyncWrites = cfg.SyncWrites
+	}
+
+	ds, err := badgerds.NewDatastore(p, &opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.collectors == nil {
+		r.collectors = make(map[string][]garbageCollector)
+	}
+	r.collectors[gcInterval] = append(r.collectors[gcInterval], ds)
+	return ds, nil
+}
+
+// startGC garbage collects the badger datastores at their interval until
+// the repo is closed.
+func (r *FSRepo) startGC() error {
+	intervals := make(map[time.Duration][]garbageCollector)
+	for s, collectors := range r.collectors {
+		if s == "" {
+			continue
+		}
+		interval, err := time.ParseDuration(s)
+		if err != nil {
+			return errors.Wrapf(err, "invalid gc interval %s", s)
+		}
+		if interval > 0 {
+			intervals[interval] = append(intervals[interval], collectors...)
+		}
+	}
+
+	r.stopGC = make(chan struct{})
+	for interval, collectors := range intervals {
+		r.gcLoops.Add(1)
+		go r.gcLoop(interval, collectors)
+	}
 	return nil
 }
 
+func (r *FSRepo) gcLoop(interval time.Duration, collectors []garbageCollector) {
+	defer r.gcLoops.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.collectGarbage(collectors)
+		case <-r.stopGC:
+			return
+		}
+	}
+}
+
+func (r *FSRepo) collectGarbage(collectors []garbageCollector) {
+	if err := r.guard.enter(); err != nil {
+		return
+	}
+	defer r.guard.leave()
+
+	for _, c := range collectors {
+		if err := c.CollectGarbage(); err != nil {
+			log.Warningf("failed to garbage collect datastore: %s", err)
+		}
+	}
+}
+
+// wrap returns ds, its values compressed if the config says so, and its
+// operations tracked so that closing the repo waits for them.
+func (r *FSRepo) wrap(ds Datastore) Datastore {
+	if r.cfg.Datastore.Compress {
+		ds = &compressedDatastore{Datastore: ds}
+	}
+	return &guardedDatastore{Datastore: ds, guard: r.guard}
+}
+
 func initVersion(p string, version uint) error {
 	return ioutil.WriteFile(filepath.Join(p, versionFilename), []byte(strconv.Itoa(int(version))), 0644)
 }
 
+func initNetwork(p string, network string) error {
+	return ioutil.WriteFile(filepath.Join(p, networkFilename), []byte(network), 0644)
+}
+
 func initConfig(p string, cfg *config.Config) error {
 	configFile := filepath.Join(p, configFilename)
 	if fileExists(configFile) {
@@ -480,3 +766,66 @@ func APIAddrFromFile(apiFilePath string) (string, error) {
 func (r *FSRepo) APIAddr() (string, error) {
 	return APIAddrFromFile(filepath.Join(filepath.Clean(r.path), APIFile))
 }
+
+// SwarmKey reads the swarm key file of the repo, returning nil if there is
+// none.
+func (r *FSRepo) SwarmKey() ([]byte, error) {
+	data, err := ioutil.ReadFile(filepath.Join(r.path, SwarmKeyFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read swarm key file")
+	}
+	return data, nil
+}
+
+// SetSwarmKey writes the swarm key file of the repo, making the node part
+// of the private network of the key.
+func (r *FSRepo) SetSwarmKey(data []byte) error {
+	return errors.Wrap(ioutil.WriteFile(filepath.Join(r.path, SwarmKeyFile), data, 0600), "failed to write swarm key file")
+}
+
+// APISecret returns the secret api tokens are signed with, generating it the
+// first time.
+func (r *FSRepo) APISecret() ([]byte, error) {
+	r.secretLk.Lock()
+	defer r.secretLk.Unlock()
+
+	path := filepath.Join(r.path, APISecretFile)
+	secret, err := ioutil.ReadFile(path)
+	if err == nil {
+		return secret, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, errors.Wrap(err, "failed to read api secret file")
+	}
+
+	secret, err = auth.NewSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(path, secret, 0600); err != nil {
+		return nil, errors.Wrap(err, "failed to write api secret file")
+	}
+	return secret, nil
+}
+
+// SetAPIToken writes the token file, so that commands run locally can
+// authenticate to the api.
+func (r *FSRepo) SetAPIToken(token string) error {
+	return errors.Wrap(ioutil.WriteFile(filepath.Join(r.path, APITokenFile), []byte(token), 0600), "failed to write api token file")
+}
+
+// APITokenFromFile reads the token from the token file at the given path,
+// returning an empty token if there is none.
+func APITokenFromFile(tokenFilePath string) (string, error) {
+	contents, err := ioutil.ReadFile(tokenFilePath)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read api token file")
+	}
+	return strings.TrimSpace(string(contents)), nil
+}
@@ -1,22 +1,28 @@
 package repo
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	ds "gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
+	"gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore/query"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/filecoin-project/go-filecoin/auth"
 	"github.com/filecoin-project/go-filecoin/config"
 )
 
 const (
 	expectContent = `{
+	"role": "miner",
 	"api": {
 		"address": "/ip4/127.0.0.1/tcp/3453",
 		"accessControlAllowOrigin": [
@@ -30,24 +36,99 @@ const (
 			"GET",
 			"POST",
 			"PUT"
-		]
+		],
+		"listenAddresses": [],
+		"readyMinPeers": 1,
+		"readyMaxSyncLag": 5
 	},
 	"bootstrap": {
 		"addresses": [],
 		"minPeerThreshold": 0,
 		"period": "1m"
 	},
+	"peering": {
+		"peers": []
+	},
 	"datastore": {
 		"type": "badgerds",
-		"path": "badger"
+		"path": "badger",
+		"blocksType": "",
+		"compress": false,
+		"badger": {
+			"valueLogFileSize": 1073741823,
+			"syncWrites": true,
+			"gcInterval": "15m"
+		},
+		"pieces": {
+			"path": "pieces"
+		}
 	},
 	"swarm": {
-		"address": "/ip4/0.0.0.0/tcp/6000"
+		"address": "/ip4/0.0.0.0/tcp/6000",
+		"listenAddresses": [],
+		"announceAddresses": [],
+		"natPortMap": true,
+		"autoNATService": false,
+		"holePunching": true,
+		"connMgr": {
+			"lowWater": 100,
+			"highWater": 300,
+			"gracePeriod": "20s"
+		},
+		"bandwidthLimits": {}
 	},
 	"mining": {
 		"minerAddress": "",
 		"autoSealIntervalSeconds": 120,
-		"storagePrice": "0"
+		"storagePrice": "0",
+		"retrievalPrice": "0",
+		"unsealPrice": "0",
+		"unsealCacheSize": 1073741824,
+		"dealPolicy": {
+			"minPrice": "0",
+			"minDuration": 0,
+			"maxDuration": 0,
+			"minPieceSize": 0,
+			"maxPieceSize": 0,
+			"allowedClients": [],
+			"blockedClients": []
+		},
+		"sealing": {
+			"addPieceConcurrency": 2,
+			"sealConcurrency": 1,
+			"commitConcurrency": 1,
+			"commitBatchSize": 1,
+			"commitBatchWait": "1m",
+			"workers": []
+		},
+		"storagePaths": [],
+		"post": {
+			"gasPrice": "0",
+			"gasLimit": 300,
+			"retries": 5,
+			"retryBackoff": "30s",
+			"atRiskBlocks": 2000
+		},
+		"pledge": {
+			"targetPower": 0,
+			"batchSize": 1
+		},
+		"scrub": {
+			"interval": "6h",
+			"samples": 16,
+			"rangeBytes": 1048576
+		},
+		"packing": {
+			"enabled": false,
+			"maxWait": "1h"
+		}
+	},
+	"client": {
+		"renewalWindow": 1000,
+		"maxRenewalPrice": "0"
+	},
+	"dataTransfer": {
+		"maxBandwidth": 0
 	},
 	"wallet": {
 		"defaultAddress": ""
@@ -57,6 +138,26 @@ const (
 		"beatPeriod": "3s",
 		"reconnectPeriod": "10s",
 		"nickname": ""
+	},
+	"proofs": {
+		"parameterCache": "",
+		"parameterManifest": "",
+		"parameterGateway": "https://ipfs.io/ipfs/",
+		"insecure": false,
+		"useGpu": true,
+		"maxCpus": 0,
+		"memoryPerProof": 0
+	},
+	"network": {
+		"name": "local",
+		"blockTime": "30s"
+	},
+	"pubsub": {
+		"seenMessagesTTL": "2m",
+		"seenCacheSize": 100000
+	},
+	"log": {
+		"levels": {}
 	}
 }`
 )
@@ -88,6 +189,10 @@ func TestFSRepoInit(t *testing.T) {
 	version, err := ioutil.ReadFile(filepath.Join(dir, versionFilename))
 	assert.NoError(err)
 	assert.Equal("1", string(version))
+
+	network, err := ioutil.ReadFile(filepath.Join(dir, networkFilename))
+	assert.NoError(err)
+	assert.Equal("local", string(network))
 }
 
 func getSnapshotFilenames(t *testing.T, dir string) []string {
@@ -122,6 +227,61 @@ func TestFSRepoOpen(t *testing.T) {
 		_, err = OpenFSRepo(dir)
 		assert.EqualError(err, "invalid repo version, got 2 expected 1")
 	})
+
+	t.Run("[fail] older version asks for a migration", func(t *testing.T) {
+		assert := assert.New(t)
+
+		dir, err := ioutil.TempDir("", "")
+		assert.NoError(err)
+		defer os.RemoveAll(dir)
+
+		assert.NoError(InitFSRepo(dir, config.NewDefaultConfig()))
+		assert.NoError(ioutil.WriteFile(filepath.Join(dir, versionFilename), []byte("0"), 0644))
+
+		_, err = OpenFSRepo(dir)
+		assert.EqualError(err, fmt.Sprintf("repo version 0 is older than 1.\nplease run: 'go-filecoin repo migrate [--repodir=%s]'", dir))
+	})
+
+	t.Run("network is the one the repo was initialized for", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		dir, err := ioutil.TempDir("", "")
+		require.NoError(err)
+		defer os.RemoveAll(dir)
+
+		cfg := config.NewDefaultConfig()
+		cfg.Network.Name = "nightly"
+		require.NoError(InitFSRepo(dir, cfg))
+
+		// editing the config doesn't change the network of the repo
+		cfg.Network.Name = "devnet"
+		require.NoError(cfg.WriteFile(filepath.Join(dir, configFilename)))
+
+		r, err := OpenFSRepo(dir)
+		require.NoError(err)
+		assert.Equal("nightly", r.Network())
+		assert.NoError(r.Close())
+	})
+
+	t.Run("repos without a network file are of the network of their config", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		dir, err := ioutil.TempDir("", "")
+		require.NoError(err)
+		defer os.RemoveAll(dir)
+
+		cfg := config.NewDefaultConfig()
+		cfg.Network.Name = "staging"
+		require.NoError(InitFSRepo(dir, cfg))
+		require.NoError(os.Remove(filepath.Join(dir, networkFilename)))
+
+		r, err := OpenFSRepo(dir)
+		require.NoError(err)
+		assert.Equal("staging", r.Network())
+		assert.NoError(r.Close())
+	})
 }
 
 func TestFSRepoRoundtrip(t *testing.T) {
@@ -153,6 +313,250 @@ func TestFSRepoRoundtrip(t *testing.T) {
 	assert.NoError(r2.Close())
 }
 
+func TestFSRepoDatastoreBackends(t *testing.T) {
+	t.Parallel()
+
+	blockKey := ds.NewKey("/blocks/CIQBED3K6YA5I3QQWLJOCHWXDRK5EXZQILBCKAPEDUJENZ5B5HJ5R3A")
+
+	roundtrip := func(t *testing.T, configure func(*config.Config)) string {
+		require := require.New(t)
+		assert := assert.New(t)
+
+		dir, err := ioutil.TempDir("", "")
+		require.NoError(err)
+
+		cfg := config.NewDefaultConfig()
+		configure(cfg)
+		require.NoError(InitFSRepo(dir, cfg))
+
+		r, err := OpenFSRepo(dir)
+		require.NoError(err)
+		require.NoError(r.Datastore().Put(ds.NewKey("beep"), []byte("boop")))
+		require.NoError(r.Datastore().Put(blockKey, []byte("block")))
+		require.NoError(r.PiecesDatastore().Put(blockKey, []byte("piece")))
+		require.NoError(r.Close())
+
+		r, err = OpenFSRepo(dir)
+		require.NoError(err)
+		defer r.Close() // nolint: errcheck
+
+		val, err := r.Datastore().Get(ds.NewKey("beep"))
+		assert.NoError(err)
+		assert.Equal([]byte("boop"), val)
+		val, err = r.Datastore().Get(blockKey)
+		assert.NoError(err)
+		assert.Equal([]byte("block"), val)
+		val, err = r.PiecesDatastore().Get(blockKey)
+		assert.NoError(err)
+		assert.Equal([]byte("piece"), val)
+
+		res, err := r.Datastore().Query(query.Query{Prefix: "/blocks"})
+		require.NoError(err)
+		entries, err := res.Rest()
+		require.NoError(err)
+		require.Len(entries, 1)
+		assert.Equal([]byte("block"), entries[0].Value)
+
+		return dir
+	}
+
+	t.Run("leveldb", func(t *testing.T) {
+		dir := roundtrip(t, func(cfg *config.Config) {
+			cfg.Datastore.Type = "leveldb"
+		})
+		defer os.RemoveAll(dir)
+	})
+
+	t.Run("flatfs blocks", func(t *testing.T) {
+		dir := roundtrip(t, func(cfg *config.Config) {
+			cfg.Datastore.BlocksType = "flatfs"
+		})
+		defer os.RemoveAll(dir)
+
+		// the block is a file of the flatfs directory
+		matches, err := filepath.Glob(filepath.Join(dir, blocksDatastorePrefix, "*", "*.data"))
+		require.NoError(t, err)
+		assert.Len(t, matches, 1)
+	})
+
+	t.Run("compressed", func(t *testing.T) {
+		dir := roundtrip(t, func(cfg *config.Config) {
+			cfg.Datastore.Compress = true
+		})
+		defer os.RemoveAll(dir)
+	})
+
+	t.Run("unknown type fails", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		cfg := config.NewDefaultConfig()
+		cfg.Datastore.Type = "boltdb"
+		require.NoError(t, InitFSRepo(dir, cfg))
+		_, err = OpenFSRepo(dir)
+		assert.Error(t, err)
+	})
+}
+
+type countingCollector struct {
+	count int32
+}
+
+func (c *countingCollector) CollectGarbage() error {
+	atomic.AddInt32(&c.count, 1)
+	return nil
+}
+
+func TestFSRepoGarbageCollection(t *testing.T) {
+	t.Parallel()
+
+	t.Run("collects each datastore at its interval until closed", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		frequent, rare := &countingCollector{}, &countingCollector{}
+		r := &FSRepo{guard: &closeGuard{}, collectors: map[string][]garbageCollector{
+			"10ms": {frequent},
+			"1h":   {rare},
+		}}
+		require.NoError(r.startGC())
+
+		deadline := time.Now().Add(5 * time.Second)
+		for atomic.LoadInt32(&frequent.count) < 2 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		assert.True(atomic.LoadInt32(&frequent.count) >= 2)
+		assert.Equal(int32(0), atomic.LoadInt32(&rare.count))
+
+		close(r.stopGC)
+		r.gcLoops.Wait()
+		r.guard.close()
+
+		count := atomic.LoadInt32(&frequent.count)
+		r.collectGarbage([]garbageCollector{frequent})
+		assert.Equal(count, atomic.LoadInt32(&frequent.count))
+	})
+
+	t.Run("zero interval disables collection", func(t *testing.T) {
+		assert := assert.New(t)
+
+		collector := &countingCollector{}
+		r := &FSRepo{guard: &closeGuard{}, collectors: map[string][]garbageCollector{
+			"0s": {collector},
+			"":   {collector},
+		}}
+		assert.NoError(r.startGC())
+		time.Sleep(50 * time.Millisecond)
+		close(r.stopGC)
+		r.gcLoops.Wait()
+		assert.Equal(int32(0), atomic.LoadInt32(&collector.count))
+	})
+
+	t.Run("invalid interval fails", func(t *testing.T) {
+		r := &FSRepo{guard: &closeGuard{}, collectors: map[string][]garbageCollector{
+			"often": {&countingCollector{}},
+		}}
+		assert.Error(t, r.startGC())
+	})
+}
+
+func TestFSRepoClosedDatastores(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(InitFSRepo(dir, config.NewDefaultConfig()))
+	r, err := OpenFSRepo(dir)
+	require.NoError(err)
+
+	deals := r.DealsDatastore()
+	require.NoError(deals.Put(ds.NewKey("deal"), []byte("state")))
+	require.NoError(r.Close())
+
+	// the datastores fail rather than write to badger once it is closed
+	assert.Equal(ErrClosed, deals.Put(ds.NewKey("deal"), []byte("later")))
+	_, err = r.Datastore().Get(ds.NewKey("deal"))
+	assert.Equal(ErrClosed, err)
+}
+
+func TestFSRepoCloseWaitsForQueries(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(InitFSRepo(dir, config.NewDefaultConfig()))
+	r, err := OpenFSRepo(dir)
+	require.NoError(err)
+
+	deals := r.DealsDatastore()
+	require.NoError(deals.Put(ds.NewKey("deal"), []byte("state")))
+
+	batch, err := deals.Batch()
+	require.NoError(err)
+	require.NoError(batch.Put(ds.NewKey("deal"), []byte("batched")))
+
+	res, err := deals.Query(query.Query{})
+	require.NoError(err)
+
+	closed := make(chan error)
+	go func() {
+		closed <- r.Close()
+	}()
+
+	// the repo stays open as long as the query results are
+	select {
+	case <-closed:
+		t.Fatal("repo closed while a query was open")
+	case <-time.After(100 * time.Millisecond):
+	}
+	require.NoError(res.Close())
+	require.NoError(<-closed)
+
+	assert.Equal(ErrClosed, batch.Commit())
+}
+
+func TestFSRepoHoldWrites(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(InitFSRepo(dir, config.NewDefaultConfig()))
+	r, err := OpenFSRepo(dir)
+	require.NoError(err)
+	defer r.Close() // nolint: errcheck
+
+	deals := r.DealsDatastore()
+	release := r.holdWrites()
+
+	// reads go on while the writes are held off
+	_, err = deals.Has(ds.NewKey("deal"))
+	require.NoError(err)
+
+	written := make(chan error)
+	go func() {
+		written <- deals.Put(ds.NewKey("deal"), []byte("state"))
+	}()
+	select {
+	case <-written:
+		t.Fatal("datastore written while the writes were held off")
+	case <-time.After(100 * time.Millisecond):
+	}
+	release()
+	require.NoError(<-written)
+}
+
 func TestFSRepoReplaceAndSnapshotConfig(t *testing.T) {
 	t.Parallel()
 	assert := assert.New(t)
@@ -341,6 +745,45 @@ func TestRepoAPIFile(t *testing.T) {
 	})
 }
 
+func TestRepoAPIAuth(t *testing.T) {
+	t.Parallel()
+
+	t.Run("APISecret is generated once", func(t *testing.T) {
+		t.Parallel()
+		assert := assert.New(t)
+		require := require.New(t)
+
+		withFSRepo(t, func(r *FSRepo) {
+			secret, err := r.APISecret()
+			require.NoError(err)
+			assert.Len(secret, auth.SecretSize)
+
+			again, err := r.APISecret()
+			require.NoError(err)
+			assert.Equal(secret, again)
+		})
+	})
+
+	t.Run("APITokenFromFile returns the token set", func(t *testing.T) {
+		t.Parallel()
+		assert := assert.New(t)
+		require := require.New(t)
+
+		withFSRepo(t, func(r *FSRepo) {
+			path := filepath.Join(r.path, APITokenFile)
+
+			token, err := APITokenFromFile(path)
+			require.NoError(err)
+			assert.Equal("", token)
+
+			require.NoError(r.SetAPIToken("a.b.c"))
+			token, err = APITokenFromFile(path)
+			require.NoError(err)
+			assert.Equal("a.b.c", token)
+		})
+	})
+}
+
 func withFSRepo(t *testing.T, f func(*FSRepo)) {
 	require := require.New(t)
 
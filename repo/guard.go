@@ -0,0 +1,214 @@
+package repo
+
+import (
+	"errors"
+	"sync"
+
+	"gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
+	"gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore/query"
+)
+
+// ErrClosed is returned by the datastores of a repo once it is closed.
+var ErrClosed = errors.New("repo is closed")
+
+// closeGuard tracks the operations on the datastores of a repo, so that
+// closing the repo waits for the ones in flight and fails the later ones,
+// instead of badger being written to while it closes. Operations entered
+// while the repo closes fail rather than wait, so an operation started by a
+// goroutine which is already in one, e.g. a Get while iterating a query,
+// can't deadlock the close.
+//
+// The writes can also be held off for a while, see holdWrites.
+type closeGuard struct {
+	lk       sync.Mutex
+	closed   bool
+	inFlight sync.WaitGroup
+	writes   sync.RWMutex
+}
+
+func (g *closeGuard) enter() error {
+	g.lk.Lock()
+	defer g.lk.Unlock()
+	if g.closed {
+		return ErrClosed
+	}
+	g.inFlight.Add(1)
+	return nil
+}
+
+func (g *closeGuard) leave() {
+	g.inFlight.Done()
+}
+
+// enterWrite enters a write, waiting while the writes are held off.
+func (g *closeGuard) enterWrite() error {
+	g.writes.RLock()
+	if err := g.enter(); err != nil {
+		g.writes.RUnlock()
+		return err
+	}
+	return nil
+}
+
+func (g *closeGuard) leaveWrite() {
+	g.leave()
+	g.writes.RUnlock()
+}
+
+// holdWrites waits for the writes in flight and holds the later ones off
+// until release is called.
+func (g *closeGuard) holdWrites() (release func()) {
+	g.writes.Lock()
+	return g.writes.Unlock
+}
+
+// close waits for the operations in flight and fails the later ones.
+func (g *closeGuard) close() {
+	g.lk.Lock()
+	g.closed = true
+	g.lk.Unlock()
+	g.inFlight.Wait()
+}
+
+// guardedDatastore is a datastore whose operations are tracked by guard.
+type guardedDatastore struct {
+	Datastore
+	guard *closeGuard
+}
+
+func (ds *guardedDatastore) Put(key datastore.Key, value []byte) error {
+	if err := ds.guard.enterWrite(); err != nil {
+		return err
+	}
+	defer ds.guard.leaveWrite()
+	return ds.Datastore.Put(key, value)
+}
+
+func (ds *guardedDatastore) Get(key datastore.Key) ([]byte, error) {
+	if err := ds.guard.enter(); err != nil {
+		return nil, err
+	}
+	defer ds.guard.leave()
+	return ds.Datastore.Get(key)
+}
+
+func (ds *guardedDatastore) Has(key datastore.Key) (bool, error) {
+	if err := ds.guard.enter(); err != nil {
+		return false, err
+	}
+	defer ds.guard.leave()
+	return ds.Datastore.Has(key)
+}
+
+func (ds *guardedDatastore) Delete(key datastore.Key) error {
+	if err := ds.guard.enterWrite(); err != nil {
+		return err
+	}
+	defer ds.guard.leaveWrite()
+	return ds.Datastore.Delete(key)
+}
+
+// Query keeps the operation in flight until the results are closed or
+// exhausted, since they are read from the datastore as they are iterated.
+func (ds *guardedDatastore) Query(q query.Query) (query.Results, error) {
+	if err := ds.guard.enter(); err != nil {
+		return nil, err
+	}
+	res, err := ds.Datastore.Query(q)
+	if err != nil {
+		ds.guard.leave()
+		return nil, err
+	}
+	return &guardedResults{Results: res, guard: ds.guard, closing: make(chan struct{})}, nil
+}
+
+func (ds *guardedDatastore) Batch() (datastore.Batch, error) {
+	if err := ds.guard.enter(); err != nil {
+		return nil, err
+	}
+	defer ds.guard.leave()
+	b, err := ds.Datastore.Batch()
+	if err != nil {
+		return nil, err
+	}
+	return &guardedBatch{Batch: b, guard: ds.guard}, nil
+}
+
+// guardedBatch is a batch whose operations are tracked by guard.
+type guardedBatch struct {
+	datastore.Batch
+	guard *closeGuard
+}
+
+func (b *guardedBatch) Put(key datastore.Key, value []byte) error {
+	if err := b.guard.enter(); err != nil {
+		return err
+	}
+	defer b.guard.leave()
+	return b.Batch.Put(key, value)
+}
+
+func (b *guardedBatch) Delete(key datastore.Key) error {
+	if err := b.guard.enter(); err != nil {
+		return err
+	}
+	defer b.guard.leave()
+	return b.Batch.Delete(key)
+}
+
+func (b *guardedBatch) Commit() error {
+	if err := b.guard.enterWrite(); err != nil {
+		return err
+	}
+	defer b.guard.leaveWrite()
+	return b.Batch.Commit()
+}
+
+// guardedResults are query results which leave guard once they are closed
+// or exhausted. Most callers range over Next without closing the results.
+type guardedResults struct {
+	query.Results
+	guard   *closeGuard
+	left    sync.Once
+	closing chan struct{}
+	closed  sync.Once
+}
+
+func (r *guardedResults) leave() {
+	r.left.Do(r.guard.leave)
+}
+
+func (r *guardedResults) Next() <-chan query.Result {
+	out := make(chan query.Result)
+	go func() {
+		defer close(out)
+		defer r.leave()
+		for res := range r.Results.Next() {
+			select {
+			case out <- res:
+			case <-r.closing:
+				return
+			}
+		}
+	}()
+	return out
+}
+
+func (r *guardedResults) NextSync() (query.Result, bool) {
+	res, ok := r.Results.NextSync()
+	if !ok {
+		r.leave()
+	}
+	return res, ok
+}
+
+func (r *guardedResults) Rest() ([]query.Entry, error) {
+	defer r.leave()
+	return r.Results.Rest()
+}
+
+func (r *guardedResults) Close() error {
+	r.closed.Do(func() { close(r.closing) })
+	defer r.leave()
+	return r.Results.Close()
+}
@@ -1,6 +1,7 @@
 package repo
 
 import (
+	"encoding/json"
 	"io/ioutil"
 	"os"
 	"sync"
@@ -9,6 +10,7 @@ import (
 	"gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
 	dss "gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore/sync"
 
+	"github.com/filecoin-project/go-filecoin/auth"
 	"github.com/filecoin-project/go-filecoin/config"
 )
 
@@ -23,8 +25,12 @@ type MemRepo struct {
 	W          Datastore
 	Chain      Datastore
 	DealsDs    Datastore
+	PiecesDs   Datastore
+	SK         []byte
 	version    uint
 	apiAddress string
+	apiToken   string
+	apiSecret  []byte
 	stagingDir string
 	sealedDir  string
 }
@@ -57,6 +63,7 @@ func NewInMemoryRepoWithSectorDirectories(staging, sealedDir string) *MemRepo {
 		W:          dss.MutexWrap(datastore.NewMapDatastore()),
 		Chain:      dss.MutexWrap(datastore.NewMapDatastore()),
 		DealsDs:    dss.MutexWrap(datastore.NewMapDatastore()),
+		PiecesDs:   dss.MutexWrap(datastore.NewMapDatastore()),
 		version:    Version,
 		stagingDir: staging,
 		sealedDir:  sealedDir,
@@ -81,6 +88,23 @@ func (mr *MemRepo) ReplaceConfig(cfg *config.Config) error {
 	return nil
 }
 
+// ReadConfig returns a copy of the current config, as the config of a
+// MemRepo isn't stored anywhere else.
+func (mr *MemRepo) ReadConfig() (*config.Config, error) {
+	mr.lk.RLock()
+	defer mr.lk.RUnlock()
+
+	raw, err := json.Marshal(mr.C)
+	if err != nil {
+		return nil, err
+	}
+	cfg := config.NewDefaultConfig()
+	if err := json.Unmarshal(raw, cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
 // Datastore returns the datastore.
 func (mr *MemRepo) Datastore() Datastore {
 	return mr.D
@@ -106,11 +130,21 @@ func (mr *MemRepo) DealsDatastore() Datastore {
 	return mr.DealsDs
 }
 
+// PiecesDatastore returns the datastore of the pieces of storage deals.
+func (mr *MemRepo) PiecesDatastore() Datastore {
+	return mr.PiecesDs
+}
+
 // Version returns the version of the repo.
 func (mr *MemRepo) Version() uint {
 	return mr.version
 }
 
+// Network returns the network named by the config.
+func (mr *MemRepo) Network() string {
+	return mr.C.Network.Name
+}
+
 // Close deletes the temporary directories which hold staged piece data and
 // sealed sectors.
 func (mr *MemRepo) Close() error {
@@ -144,3 +178,30 @@ func (mr *MemRepo) SetAPIAddr(addr string) error {
 func (mr *MemRepo) APIAddr() (string, error) {
 	return mr.apiAddress, nil
 }
+
+// SwarmKey returns the swarm key of the repo, nil for the public network.
+func (mr *MemRepo) SwarmKey() ([]byte, error) {
+	return mr.SK, nil
+}
+
+// SetAPIToken writes the token of the running API to memory.
+func (mr *MemRepo) SetAPIToken(token string) error {
+	mr.apiToken = token
+	return nil
+}
+
+// APISecret returns the secret API tokens are signed with, generating it
+// the first time.
+func (mr *MemRepo) APISecret() ([]byte, error) {
+	mr.lk.Lock()
+	defer mr.lk.Unlock()
+
+	if mr.apiSecret == nil {
+		secret, err := auth.NewSecret()
+		if err != nil {
+			return nil, err
+		}
+		mr.apiSecret = secret
+	}
+	return mr.apiSecret, nil
+}
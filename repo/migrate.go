@@ -0,0 +1,221 @@
+package repo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	lockfile "gx/ipfs/QmcWjZkQxyPMkgZRpda4hqWwaD6E1yqCvcxZfxbt98CEAK/go-fs-lock"
+	"gx/ipfs/QmdcULN1WCzgoQmcCaUAmEhwcxHYsDrbZ2LvRJKCL8dMrK/go-homedir"
+)
+
+// Migration upgrades a repo from one version to the next.
+type Migration struct {
+	// From is the version of the repos the migration applies to, which it
+	// leaves at version From+1.
+	From uint
+	// Description tells the user what the migration changes.
+	Description string
+	// Run migrates the closed repo at the given path in place: its
+	// datastores, config file, keystore or anything else in it.
+	Run func(repoPath string) error
+}
+
+// migrations brings the repos of older versions up to Version, one version
+// at a time. Bumping Version requires adding the migration from the previous
+// one here.
+var migrations []Migration
+
+// MigrateResult describes the migration of a repo.
+type MigrateResult struct {
+	From uint
+	To   uint
+	// Applied holds the description of each migration run, in order.
+	Applied []string
+	// Backup is the path of the copy of the repo taken before migrating it,
+	// empty if the repo was up to date.
+	Backup string
+}
+
+// Migrate upgrades the repo at the given path to the version this binary
+// requires. The repo is copied next to it first, and restored from the copy
+// if any of the migrations fails; the copy is kept otherwise, to be removed
+// by the user once they are happy with the migrated repo.
+func Migrate(p string) (*MigrateResult, error) {
+	return migrate(p, Version, migrations)
+}
+
+func migrate(p string, target uint, migrations []Migration) (*MigrateResult, error) {
+	expath, err := homedir.Expand(p)
+	if err != nil {
+		return nil, err
+	}
+
+	isInit, err := isInitialized(expath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to check if repo was initialized")
+	}
+	if !isInit {
+		return nil, &NoRepoError{p}
+	}
+
+	lock, err := lockfile.Lock(expath, lockFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to take repo lock")
+	}
+	defer lock.Close() // nolint: errcheck
+
+	from, err := readVersion(expath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load version")
+	}
+	if from > target {
+		return nil, fmt.Errorf("repo version %d is newer than %d, the latest version supported", from, target)
+	}
+
+	res := &MigrateResult{From: from, To: target}
+	if from == target {
+		return res, nil
+	}
+
+	pending, err := migrationPath(migrations, from, target)
+	if err != nil {
+		return nil, err
+	}
+
+	res.Backup = fmt.Sprintf("%s-v%d-backup-%d", expath, from, time.Now().UTC().Unix())
+	if err := copyRepo(expath, res.Backup); err != nil {
+		os.RemoveAll(res.Backup) // nolint: errcheck
+		return nil, errors.Wrap(err, "failed to back the repo up")
+	}
+
+	for _, m := range pending {
+		if err := runMigration(expath, m); err != nil {
+			if rerr := restoreRepo(expath, res.Backup); rerr != nil {
+				return nil, errors.Wrapf(rerr, "failed to restore the repo from %s after the migration from version %d failed with %s", res.Backup, m.From, err)
+			}
+			return nil, errors.Wrapf(err, "migration from version %d failed, the repo was restored", m.From)
+		}
+		res.Applied = append(res.Applied, m.Description)
+	}
+
+	return res, nil
+}
+
+// migrationPath returns the migrations taking a repo from version from to
+// version to.
+func migrationPath(migrations []Migration, from, to uint) ([]Migration, error) {
+	var path []Migration
+	for v := from; v < to; v++ {
+		found := false
+		for _, m := range migrations {
+			if m.From == v {
+				path = append(path, m)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no migration from repo version %d", v)
+		}
+	}
+	return path, nil
+}
+
+func runMigration(p string, m Migration) error {
+	if err := m.Run(p); err != nil {
+		return err
+	}
+	return initVersion(p, m.From+1)
+}
+
+// ConfigMigration returns the Run of a migration changing the schema of the
+// config file, which up is given decoded as generic JSON to edit in place.
+func ConfigMigration(up func(cfg map[string]interface{}) error) func(string) error {
+	return func(p string) error {
+		configFile := filepath.Join(p, configFilename)
+		raw, err := ioutil.ReadFile(configFile)
+		if err != nil {
+			return err
+		}
+
+		var cfg map[string]interface{}
+		if err := json.Unmarshal(raw, &cfg); err != nil {
+			return errors.Wrap(err, "failed to decode config file")
+		}
+		if err := up(cfg); err != nil {
+			return err
+		}
+
+		out, err := json.MarshalIndent(cfg, "", "\t")
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(configFile, out, 0644)
+	}
+}
+
+// copyRepo copies the repo at src to dst, leaving out the files only
+// meaningful while a daemon runs it.
+func copyRepo(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if rel == lockFile || rel == APIFile {
+			return nil
+		}
+
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, info.Mode())
+		}
+		return copyFile(path, target, info.Mode())
+	})
+}
+
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close() // nolint: errcheck
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close() // nolint: errcheck
+		return err
+	}
+	return out.Close()
+}
+
+// restoreRepo replaces the content of the repo at p, but for its lock, with
+// the backup at backup.
+func restoreRepo(p, backup string) error {
+	entries, err := ioutil.ReadDir(p)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.Name() == lockFile {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(p, e.Name())); err != nil {
+			return err
+		}
+	}
+	return copyRepo(backup, p)
+}
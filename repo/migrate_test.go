@@ -0,0 +1,159 @@
+package repo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/config"
+)
+
+func initMigrateRepo(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	require.NoError(t, InitFSRepo(dir, config.NewDefaultConfig()))
+	return dir
+}
+
+func setAPIAddress(addr string) func(string) error {
+	return ConfigMigration(func(cfg map[string]interface{}) error {
+		cfg["api"].(map[string]interface{})["address"] = addr
+		return nil
+	})
+}
+
+func TestMigrate(t *testing.T) {
+	t.Parallel()
+
+	t.Run("up to date repo is left alone", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		dir := initMigrateRepo(t)
+		defer os.RemoveAll(dir)
+
+		res, err := migrate(dir, Version, nil)
+		require.NoError(err)
+		assert.Equal(&MigrateResult{From: Version, To: Version}, res)
+	})
+
+	t.Run("migrations are applied in order", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		dir := initMigrateRepo(t)
+		defer os.RemoveAll(dir)
+
+		migrations := []Migration{
+			{From: 2, Description: "add a file", Run: func(p string) error {
+				return ioutil.WriteFile(filepath.Join(p, "added"), []byte("hello"), 0644)
+			}},
+			{From: 1, Description: "move the api", Run: setAPIAddress("/ip4/127.0.0.1/tcp/4321")},
+		}
+		res, err := migrate(dir, 3, migrations)
+		require.NoError(err)
+		defer os.RemoveAll(res.Backup)
+		assert.Equal(uint(1), res.From)
+		assert.Equal(uint(3), res.To)
+		assert.Equal([]string{"move the api", "add a file"}, res.Applied)
+
+		version, err := readVersion(dir)
+		require.NoError(err)
+		assert.Equal(uint(3), version)
+		cfg, err := config.ReadFile(filepath.Join(dir, configFilename))
+		require.NoError(err)
+		assert.Equal("/ip4/127.0.0.1/tcp/4321", cfg.API.Address)
+		assert.True(fileExists(filepath.Join(dir, "added")))
+
+		// the backup holds the repo as it was
+		version, err = readVersion(res.Backup)
+		require.NoError(err)
+		assert.Equal(uint(1), version)
+		cfg, err = config.ReadFile(filepath.Join(res.Backup, configFilename))
+		require.NoError(err)
+		assert.Equal(config.NewDefaultConfig().API.Address, cfg.API.Address)
+		assert.False(fileExists(filepath.Join(res.Backup, "added")))
+	})
+
+	t.Run("failed migration restores the repo", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		dir := initMigrateRepo(t)
+		defer os.RemoveAll(dir)
+		defer func() {
+			backups, _ := filepath.Glob(dir + "-v1-backup-*")
+			for _, b := range backups {
+				os.RemoveAll(b) // nolint: errcheck
+			}
+		}()
+
+		migrations := []Migration{
+			{From: 1, Description: "move the api", Run: setAPIAddress("/ip4/127.0.0.1/tcp/4321")},
+			{From: 2, Description: "fail", Run: func(p string) error {
+				if err := ioutil.WriteFile(filepath.Join(p, "added"), []byte("hello"), 0644); err != nil {
+					return err
+				}
+				return fmt.Errorf("boom")
+			}},
+		}
+		_, err := migrate(dir, 3, migrations)
+		assert.EqualError(err, "migration from version 2 failed, the repo was restored: boom")
+
+		version, err := readVersion(dir)
+		require.NoError(err)
+		assert.Equal(uint(1), version)
+		cfg, err := config.ReadFile(filepath.Join(dir, configFilename))
+		require.NoError(err)
+		assert.Equal(config.NewDefaultConfig().API.Address, cfg.API.Address)
+		assert.False(fileExists(filepath.Join(dir, "added")))
+
+		r, err := OpenFSRepo(dir)
+		require.NoError(err)
+		assert.NoError(r.Close())
+	})
+
+	t.Run("missing migration fails before touching the repo", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		dir := initMigrateRepo(t)
+		defer os.RemoveAll(dir)
+
+		migrations := []Migration{
+			{From: 1, Description: "move the api", Run: setAPIAddress("/ip4/127.0.0.1/tcp/4321")},
+		}
+		_, err := migrate(dir, 3, migrations)
+		assert.EqualError(err, "no migration from repo version 2")
+
+		version, err := readVersion(dir)
+		require.NoError(err)
+		assert.Equal(uint(1), version)
+	})
+
+	t.Run("newer repo is rejected", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		dir := initMigrateRepo(t)
+		defer os.RemoveAll(dir)
+		require.NoError(initVersion(dir, Version+1))
+
+		_, err := Migrate(dir)
+		assert.EqualError(err, fmt.Sprintf("repo version %d is newer than %d, the latest version supported", Version+1, Version))
+	})
+
+	t.Run("missing repo", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		_, err = Migrate(filepath.Join(dir, "nope"))
+		assert.IsType(t, &NoRepoError{}, err)
+	})
+}
@@ -0,0 +1,65 @@
+package repo
+
+import (
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	"gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
+	"gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore/query"
+)
+
+const pinsDatastorePrefix = "pins"
+
+// Pins records the roots of the dags pinned in the blockstore of a repo,
+// which garbage collection keeps, with all the blocks they link to. The pins
+// are kept in the datastore of the repo.
+type Pins struct {
+	ds Datastore
+}
+
+// NewPins returns the pins of r.
+func NewPins(r Repo) *Pins {
+	return &Pins{ds: r.Datastore()}
+}
+
+func pinKey(c cid.Cid) datastore.Key {
+	return datastore.KeyWithNamespaces([]string{pinsDatastorePrefix, c.String()})
+}
+
+// Add pins the dag with root c.
+func (p *Pins) Add(c cid.Cid) error {
+	return errors.Wrapf(p.ds.Put(pinKey(c), []byte{}), "failed to pin %s", c)
+}
+
+// Remove unpins the dag with root c.
+func (p *Pins) Remove(c cid.Cid) error {
+	has, err := p.ds.Has(pinKey(c))
+	if err != nil {
+		return err
+	}
+	if !has {
+		return errors.Errorf("%s is not pinned", c)
+	}
+	return errors.Wrapf(p.ds.Delete(pinKey(c)), "failed to unpin %s", c)
+}
+
+// List returns the roots of the pinned dags.
+func (p *Pins) List() ([]cid.Cid, error) {
+	res, err := p.ds.Query(query.Query{Prefix: "/" + pinsDatastorePrefix, KeysOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close() // nolint: errcheck
+
+	var pins []cid.Cid
+	for entry := range res.Next() {
+		if entry.Error != nil {
+			return nil, entry.Error
+		}
+		c, err := cid.Decode(datastore.NewKey(entry.Key).BaseNamespace())
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid pin %s", entry.Key)
+		}
+		pins = append(pins, c)
+	}
+	return pins, nil
+}
@@ -0,0 +1,40 @@
+package repo
+
+import (
+	"testing"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func TestPins(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	pins := NewPins(NewInMemoryRepo())
+	list, err := pins.List()
+	require.NoError(err)
+	assert.Empty(list)
+
+	cidGetter := types.NewCidForTestGetter()
+	c1, c2 := cidGetter(), cidGetter()
+	require.NoError(pins.Add(c1))
+	require.NoError(pins.Add(c2))
+	require.NoError(pins.Add(c1))
+
+	list, err = pins.List()
+	require.NoError(err)
+	assert.ElementsMatch([]cid.Cid{c1, c2}, list)
+
+	require.NoError(pins.Remove(c1))
+	list, err = pins.List()
+	require.NoError(err)
+	assert.Equal([]cid.Cid{c2}, list)
+
+	assert.EqualError(pins.Remove(c1), c1.String()+" is not pinned")
+}
@@ -24,6 +24,9 @@ type Repo interface {
 	Config() *config.Config
 	// ReplaceConfig replaces the current config, with the newly passed in one.
 	ReplaceConfig(cfg *config.Config) error
+	// ReadConfig reads the config stored in the repo, e.g. after it was
+	// edited by hand, without replacing the current config.
+	ReadConfig() (*config.Config, error)
 
 	// Datastore is a general storage solution for things like blocks.
 	Datastore() Datastore
@@ -38,14 +41,34 @@ type Repo interface {
 	// DealsDatastore holds deals data.
 	DealsDatastore() Datastore
 
+	// PiecesDatastore holds the data of the pieces of storage deals, apart
+	// from the chain and state blocks.
+	PiecesDatastore() Datastore
+
 	// SetAPIAddr sets the address of the running API.
 	SetAPIAddr(string) error
 
 	// APIAddr returns the address of the running API.
 	APIAddr() (string, error)
 
+	// SetAPIToken saves a token of the running API for the commands run
+	// locally to authenticate with.
+	SetAPIToken(string) error
+
+	// APISecret returns the secret API tokens are signed with, generating
+	// it the first time.
+	APISecret() ([]byte, error)
+
+	// SwarmKey returns the encoded pre-shared key of the private network the
+	// node is part of, nil if it is part of the public network.
+	SwarmKey() ([]byte, error)
+
 	Version() uint
 
+	// Network returns the name of the network the repo was initialized
+	// for, which its config must keep naming.
+	Network() string
+
 	// StagingDir is used to store staged sectors.
 	StagingDir() string
 
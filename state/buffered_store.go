@@ -0,0 +1,71 @@
+package state
+
+import (
+	"context"
+	"sync"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	"gx/ipfs/QmRXf2uUSdGSunRJsM9wXSUNVwLUGCY3So5fAs7h2CBJVf/go-hamt-ipld"
+	blocks "gx/ipfs/QmWoXtvgC8inqFkAATB7cp2Dax7XBi9VDvSg9RCCZufmRk/go-block-format"
+)
+
+// blockService is the block service of a hamt.CborIpldStore.
+type blockService interface {
+	GetBlock(ctx context.Context, c cid.Cid) (blocks.Block, error)
+	AddBlock(b blocks.Block) error
+}
+
+// BufferedBlocks buffers the blocks added to a store in memory until they
+// are flushed to the store's underlying block service.
+type BufferedBlocks struct {
+	blocks blockService
+
+	lk  sync.RWMutex
+	buf map[cid.Cid]blocks.Block
+}
+
+// NewBufferedCborStore returns a store reading through cst whose writes are
+// buffered by the returned BufferedBlocks. State trees loaded from the store
+// are only written to cst when the BufferedBlocks are flushed.
+func NewBufferedCborStore(cst *hamt.CborIpldStore) (*hamt.CborIpldStore, *BufferedBlocks) {
+	bb := &BufferedBlocks{
+		blocks: cst.Blocks,
+		buf:    make(map[cid.Cid]blocks.Block),
+	}
+	return &hamt.CborIpldStore{Blocks: bb, Atlas: cst.Atlas}, bb
+}
+
+// GetBlock returns the block with the given cid.
+func (bb *BufferedBlocks) GetBlock(ctx context.Context, c cid.Cid) (blocks.Block, error) {
+	bb.lk.RLock()
+	blk, ok := bb.buf[c]
+	bb.lk.RUnlock()
+	if ok {
+		return blk, nil
+	}
+	return bb.blocks.GetBlock(ctx, c)
+}
+
+// AddBlock buffers b.
+func (bb *BufferedBlocks) AddBlock(b blocks.Block) error {
+	bb.lk.Lock()
+	defer bb.lk.Unlock()
+
+	bb.buf[b.Cid()] = b
+	return nil
+}
+
+// Flush adds the buffered blocks to the underlying block service and empties
+// the buffer.
+func (bb *BufferedBlocks) Flush() error {
+	bb.lk.Lock()
+	defer bb.lk.Unlock()
+
+	for c, b := range bb.buf {
+		if err := bb.blocks.AddBlock(b); err != nil {
+			return err
+		}
+		delete(bb.buf, c)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package state
+
+import (
+	"context"
+	"testing"
+
+	"gx/ipfs/QmRXf2uUSdGSunRJsM9wXSUNVwLUGCY3So5fAs7h2CBJVf/go-hamt-ipld"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func TestBufferedCborStore(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+
+	cst := hamt.NewCborStore()
+	buffered, bb := NewBufferedCborStore(cst)
+
+	tree := NewEmptyStateTree(buffered)
+	addr := address.NewForTestGetter()()
+	require.NoError(tree.SetActor(ctx, addr, actor.NewActor(types.AccountActorCodeCid, types.NewAttoFILFromFIL(1))))
+	root, err := tree.Flush(ctx)
+	require.NoError(err)
+
+	// the tree is only in the buffer
+	_, err = LoadStateTree(ctx, cst, root, nil)
+	assert.Error(err)
+	_, err = LoadStateTree(ctx, buffered, root, nil)
+	assert.NoError(err)
+
+	require.NoError(bb.Flush())
+	loaded, err := LoadStateTree(ctx, cst, root, nil)
+	require.NoError(err)
+	assert.Equal(types.NewAttoFILFromFIL(1), MustGetActor(loaded, addr).Balance)
+}
@@ -0,0 +1,109 @@
+package state
+
+import (
+	"context"
+	"sort"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	"gx/ipfs/QmRXf2uUSdGSunRJsM9wXSUNVwLUGCY3So5fAs7h2CBJVf/go-hamt-ipld"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/address"
+)
+
+// Kinds of ActorDiff.
+const (
+	// ActorCreated marks an actor that is only in the second state.
+	ActorCreated = "created"
+	// ActorDeleted marks an actor that is only in the first state.
+	ActorDeleted = "deleted"
+	// ActorModified marks an actor that is in both states but differs.
+	ActorModified = "modified"
+)
+
+// ActorDiff is the difference of an actor between two states. Before is nil
+// for created actors and After is nil for deleted ones.
+type ActorDiff struct {
+	Address address.Address `json:"address"`
+	Kind    string          `json:"kind"`
+	Before  *actor.Actor    `json:"before,omitempty"`
+	After   *actor.Actor    `json:"after,omitempty"`
+}
+
+// StateDiff returns the actors that differ between the state trees with
+// roots rootA and rootB, ordered by address.
+func StateDiff(ctx context.Context, store *hamt.CborIpldStore, rootA, rootB cid.Cid) ([]*ActorDiff, error) {
+	if rootA.Equals(rootB) {
+		return nil, nil
+	}
+
+	a, err := LoadStateTree(ctx, store, rootA, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load state %s", rootA)
+	}
+	b, err := LoadStateTree(ctx, store, rootB, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load state %s", rootB)
+	}
+	return Diff(ctx, a, b)
+}
+
+// Diff returns the actors that differ between the state trees a and b,
+// ordered by address.
+//
+// TODO: this walks both trees entirely, it could skip the subtrees the two
+// hamts share.
+func Diff(ctx context.Context, a, b Tree) ([]*ActorDiff, error) {
+	before := map[address.Address]*actor.Actor{}
+	err := a.ForEachActor(ctx, func(addr address.Address, act *actor.Actor) error {
+		before[addr] = act
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to walk first state")
+	}
+
+	var diffs []*ActorDiff
+	err = b.ForEachActor(ctx, func(addr address.Address, act *actor.Actor) error {
+		old, ok := before[addr]
+		if !ok {
+			diffs = append(diffs, &ActorDiff{Address: addr, Kind: ActorCreated, After: act})
+			return nil
+		}
+		delete(before, addr)
+
+		same, err := sameActor(old, act)
+		if err != nil {
+			return err
+		}
+		if !same {
+			diffs = append(diffs, &ActorDiff{Address: addr, Kind: ActorModified, Before: old, After: act})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to walk second state")
+	}
+
+	for addr, act := range before {
+		diffs = append(diffs, &ActorDiff{Address: addr, Kind: ActorDeleted, Before: act})
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Address.String() < diffs[j].Address.String()
+	})
+	return diffs, nil
+}
+
+func sameActor(a, b *actor.Actor) (bool, error) {
+	ca, err := a.Cid()
+	if err != nil {
+		return false, err
+	}
+	cb, err := b.Cid()
+	if err != nil {
+		return false, err
+	}
+	return ca.Equals(cb), nil
+}
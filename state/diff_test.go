@@ -0,0 +1,90 @@
+package state
+
+import (
+	"context"
+	"testing"
+
+	"gx/ipfs/QmRXf2uUSdGSunRJsM9wXSUNVwLUGCY3So5fAs7h2CBJVf/go-hamt-ipld"
+
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateDiff(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+	cst := hamt.NewCborStore()
+
+	addrGetter := address.NewForTestGetter()
+	unchanged, modified, deleted, created := addrGetter(), addrGetter(), addrGetter(), addrGetter()
+
+	treeA := NewEmptyStateTree(cst)
+	require.NoError(treeA.SetActor(ctx, unchanged, actor.NewActor(types.AccountActorCodeCid, types.NewAttoFILFromFIL(1))))
+	require.NoError(treeA.SetActor(ctx, modified, actor.NewActor(types.AccountActorCodeCid, types.NewAttoFILFromFIL(2))))
+	require.NoError(treeA.SetActor(ctx, deleted, actor.NewActor(types.AccountActorCodeCid, types.NewAttoFILFromFIL(3))))
+	rootA, err := treeA.Flush(ctx)
+	require.NoError(err)
+
+	modifiedAfter := actor.NewActor(types.AccountActorCodeCid, types.NewAttoFILFromFIL(5))
+	modifiedAfter.IncNonce()
+	treeB := NewEmptyStateTree(cst)
+	require.NoError(treeB.SetActor(ctx, unchanged, actor.NewActor(types.AccountActorCodeCid, types.NewAttoFILFromFIL(1))))
+	require.NoError(treeB.SetActor(ctx, modified, modifiedAfter))
+	require.NoError(treeB.SetActor(ctx, created, actor.NewActor(types.AccountActorCodeCid, types.NewAttoFILFromFIL(4))))
+	rootB, err := treeB.Flush(ctx)
+	require.NoError(err)
+
+	t.Run("reports created, deleted and modified actors", func(t *testing.T) {
+		diffs, err := StateDiff(ctx, cst, rootA, rootB)
+		require.NoError(err)
+		require.Len(diffs, 3)
+
+		byAddr := map[address.Address]*ActorDiff{}
+		for _, d := range diffs {
+			byAddr[d.Address] = d
+		}
+
+		assert.Equal(ActorModified, byAddr[modified].Kind)
+		assert.Equal(types.NewAttoFILFromFIL(2), byAddr[modified].Before.Balance)
+		assert.Equal(types.NewAttoFILFromFIL(5), byAddr[modified].After.Balance)
+		assert.Equal(types.Uint64(1), byAddr[modified].After.Nonce)
+
+		assert.Equal(ActorDeleted, byAddr[deleted].Kind)
+		assert.Equal(types.NewAttoFILFromFIL(3), byAddr[deleted].Before.Balance)
+		assert.Nil(byAddr[deleted].After)
+
+		assert.Equal(ActorCreated, byAddr[created].Kind)
+		assert.Nil(byAddr[created].Before)
+		assert.Equal(types.NewAttoFILFromFIL(4), byAddr[created].After.Balance)
+
+		for i := 1; i < len(diffs); i++ {
+			assert.True(diffs[i-1].Address.String() < diffs[i].Address.String())
+		}
+	})
+
+	t.Run("the reverse diff swaps creations and deletions", func(t *testing.T) {
+		diffs, err := StateDiff(ctx, cst, rootB, rootA)
+		require.NoError(err)
+		require.Len(diffs, 3)
+		for _, d := range diffs {
+			switch d.Address {
+			case deleted:
+				assert.Equal(ActorCreated, d.Kind)
+			case created:
+				assert.Equal(ActorDeleted, d.Kind)
+			default:
+				assert.Equal(ActorModified, d.Kind)
+			}
+		}
+	})
+
+	t.Run("identical states have no diff", func(t *testing.T) {
+		diffs, err := StateDiff(ctx, cst, rootA, rootA)
+		require.NoError(err)
+		assert.Empty(diffs)
+	})
+}
@@ -123,7 +123,7 @@ type TestBlockRewarder struct{}
 var _ consensus.BlockRewarder = (*TestBlockRewarder)(nil)
 
 // BlockReward is a noop
-func (tbr *TestBlockRewarder) BlockReward(ctx context.Context, st state.Tree, minerAddr address.Address) error {
+func (tbr *TestBlockRewarder) BlockReward(ctx context.Context, st state.Tree, vms vm.StorageMap, minerAddr address.Address, bh *types.BlockHeight) error {
 	// do nothing to keep state root the same
 	return nil
 }
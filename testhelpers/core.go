@@ -13,7 +13,9 @@ import (
 	"github.com/filecoin-project/go-filecoin/actor"
 	"github.com/filecoin-project/go-filecoin/actor/builtin"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/account"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/cron"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/miner"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/reward"
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/filnet"
 	"github.com/filecoin-project/go-filecoin/state"
@@ -65,6 +67,31 @@ func RequireNewMinerActor(require *require.Assertions, vms vm.StorageMap, addr a
 	return act
 }
 
+// RequireNewRewardActor creates a new reward actor with the given balance
+// that has paid out mined in block rewards, and requires that its steps
+// succeed.
+func RequireNewRewardActor(require *require.Assertions, vms vm.StorageMap, balance, mined *types.AttoFIL) *actor.Actor {
+	act := actor.NewActor(types.RewardActorCodeCid, balance)
+	storage := vms.NewStorage(address.RewardAddress, act)
+	id, err := storage.Put(&reward.State{TotalMined: mined})
+	require.NoError(err)
+	require.NoError(storage.Commit(id, cid.Undef))
+	require.NoError(storage.Flush())
+	return act
+}
+
+// RequireNewCronActor creates a new cron actor with the given callbacks
+// registered, and requires that its steps succeed.
+func RequireNewCronActor(require *require.Assertions, vms vm.StorageMap, callbacks ...cron.Callback) *actor.Actor {
+	act := cron.NewActor()
+	storage := vms.NewStorage(address.CronAddress, act)
+	id, err := storage.Put(&cron.State{Callbacks: callbacks})
+	require.NoError(err)
+	require.NoError(storage.Commit(id, cid.Undef))
+	require.NoError(storage.Flush())
+	return act
+}
+
 // RequireNewFakeActor instantiates and returns a new fake actor and requires
 // that its steps succeed.
 func RequireNewFakeActor(require *require.Assertions, vms vm.StorageMap, addr address.Address, codeCid cid.Cid) *actor.Actor {
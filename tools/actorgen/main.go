@@ -0,0 +1,357 @@
+// actorgen generates the dispatch code of an actor from its exports table.
+//
+// The generated code decodes the params of a message, calls the actor method
+// with typed arguments and encodes its return values, replacing the reflection
+// done by actor.MakeTypedExport. A method whose Go signature doesn't match its
+// exported signature makes the generated code fail to compile.
+//
+// It is meant to be run by go generate from the package of the actor:
+//
+//	//go:generate go run ../../../tools/actorgen/main.go -type Actor -exports minerExports
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+const (
+	actorImport  = "github.com/filecoin-project/go-filecoin/actor"
+	addressPkg   = "github.com/filecoin-project/go-filecoin/address"
+	typesPkg     = "github.com/filecoin-project/go-filecoin/types"
+	bigPkg       = "math/big"
+	peerPkg      = "gx/ipfs/QmY5Grm8pJdiSSVsYxx4uNRgweY72EmYwuSDbRnbFok3iY/go-libp2p-peer"
+	abiImport    = "github.com/filecoin-project/go-filecoin/abi"
+	execImport   = "github.com/filecoin-project/go-filecoin/exec"
+	errorsImport = "github.com/filecoin-project/go-filecoin/vm/errors"
+)
+
+// goType is the go type of a value of an abi.Type, and the package the type
+// needs imported.
+type goType struct {
+	expr string
+	pkg  string
+}
+
+// goTypes maps the names of the abi types to their go types. It must match
+// the typeTable of package abi.
+var goTypes = map[string]goType{
+	"Address":        {"address.Address", addressPkg},
+	"AttoFIL":        {"*types.AttoFIL", typesPkg},
+	"BytesAmount":    {"*types.BytesAmount", typesPkg},
+	"ChannelID":      {"*types.ChannelID", typesPkg},
+	"BlockHeight":    {"*types.BlockHeight", typesPkg},
+	"Integer":        {"*big.Int", bigPkg},
+	"Bytes":          {"[]byte", ""},
+	"String":         {"string", ""},
+	"UintArray":      {"[]uint64", ""},
+	"PeerID":         {"peer.ID", peerPkg},
+	"SectorID":       {"uint64", ""},
+	"CommitmentsMap": {"map[string]types.Commitments", typesPkg},
+	"Addresses":      {"[]address.Address", addressPkg},
+}
+
+type method struct {
+	Name   string
+	GoName string
+	Params []string
+	Return int
+}
+
+func (m method) Args() string {
+	args := []string{"ctx"}
+	for i, p := range m.Params {
+		args = append(args, fmt.Sprintf("params[%d].Val.(%s)", i, p))
+	}
+	return strings.Join(args, ", ")
+}
+
+func (m method) Results() string {
+	var rets []string
+	for i := 0; i < m.Return; i++ {
+		rets = append(rets, fmt.Sprintf("r%d", i))
+	}
+	return strings.Join(rets, ", ")
+}
+
+func (m method) ResultsPrefix() string {
+	if m.Return == 0 {
+		return ""
+	}
+	return m.Results() + ", "
+}
+
+var tmpl = template.Must(template.New("dispatch").Parse(`// Code generated by actorgen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- range $i, $group := .Imports}}
+{{- if $i}}
+{{end}}
+{{- range $group}}
+	"{{.}}"
+{{- end}}
+{{- end}}
+)
+
+var _ exec.Dispatcher = (*{{.Type}})(nil)
+
+// Method returns the exported method with the given name, bound to a.
+func (a *{{.Type}}) Method(name string) (exec.ExportedFunc, bool) {
+	switch name {
+{{- range .Methods}}
+	case "{{.Name}}":
+		return a.dispatch{{.GoName}}, true
+{{- end}}
+	default:
+		return nil, false
+	}
+}
+{{range .Methods}}
+func (a *{{$.Type}}) dispatch{{.GoName}}(ctx exec.VMContext) ([]byte, uint8, error) {
+	params, err := abi.DecodeValues(ctx.Message().Params, {{$.Exports}}["{{.Name}}"].Params)
+	if err != nil {
+		return nil, errors.ErrInvalidParams, errors.CodedRevertErrorWrap(errors.ErrInvalidParams, err, "invalid params")
+	}
+
+	{{.ResultsPrefix}}code, err := a.{{.GoName}}({{.Args}})
+	if err != nil {
+		return nil, code, {{$.ActorQualifier}}CheckExportError(a, "{{.Name}}", params, err)
+	}
+
+	ret, err := abi.ToEncodedValues({{.Results}})
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "failed to marshal output value")
+	}
+	return ret, code, nil
+}
+{{end}}`))
+
+func main() {
+	typeName := flag.String("type", "Actor", "name of the actor type")
+	exportsName := flag.String("exports", "", "name of the variable holding the exports of the actor")
+	out := flag.String("out", "dispatch_gen.go", "file to write the generated code to")
+	flag.Parse()
+
+	if *exportsName == "" {
+		fail("-exports is required")
+	}
+
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, ".", func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go") && fi.Name() != *out
+	}, 0)
+	if err != nil {
+		fail(err.Error())
+	}
+	if len(pkgs) != 1 {
+		fail("expected a single package in the current directory")
+	}
+
+	var pkg *ast.Package
+	for _, p := range pkgs {
+		pkg = p
+	}
+
+	exports := findExports(pkg, *exportsName)
+	if exports == nil {
+		fail(fmt.Sprintf("no exports variable %s", *exportsName))
+	}
+	decls := findMethods(pkg, *typeName)
+
+	imports := map[string]bool{abiImport: true, execImport: true, errorsImport: true}
+	actorQualifier := "actor."
+	if pkg.Name == "actor" {
+		actorQualifier = ""
+	} else {
+		imports[actorImport] = true
+	}
+
+	var methods []method
+	for _, elt := range exports.Elts {
+		kv := elt.(*ast.KeyValueExpr)
+		name, err := strconv.Unquote(kv.Key.(*ast.BasicLit).Value)
+		if err != nil {
+			fail(err.Error())
+		}
+		m := method{Name: name, GoName: strings.Title(name)}
+
+		params, ret := signature(kv.Value)
+		for _, p := range params {
+			gt, ok := goTypes[p]
+			if !ok {
+				fail(fmt.Sprintf("%s: unknown abi type %s", name, p))
+			}
+			if gt.pkg != "" {
+				imports[gt.pkg] = true
+			}
+			m.Params = append(m.Params, gt.expr)
+		}
+		m.Return = len(ret)
+
+		decl, ok := decls[m.GoName]
+		if !ok {
+			fail(fmt.Sprintf("%s: %s has no method %s", name, *typeName, m.GoName))
+		}
+		if n := countFields(decl.Type.Params); n != len(m.Params)+1 {
+			fail(fmt.Sprintf("%s: %s takes %d params, exports declare %d and the context", name, m.GoName, n, len(m.Params)))
+		}
+		if n := countFields(decl.Type.Results); n != m.Return+2 {
+			fail(fmt.Sprintf("%s: %s returns %d values, exports declare %d and the exit code and error", name, m.GoName, n, m.Return))
+		}
+
+		methods = append(methods, m)
+	}
+	sort.Slice(methods, func(i, j int) bool { return methods[i].Name < methods[j].Name })
+
+	var std, gx, other []string
+	for imp := range imports {
+		switch {
+		case strings.HasPrefix(imp, "gx/"):
+			gx = append(gx, imp)
+		case !strings.Contains(imp, "."):
+			std = append(std, imp)
+		default:
+			other = append(other, imp)
+		}
+	}
+	// Group the imports the way the repo does: standard library, gx and then
+	// the rest.
+	var groups [][]string
+	for _, group := range [][]string{std, gx, other} {
+		if len(group) > 0 {
+			sort.Strings(group)
+			groups = append(groups, group)
+		}
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, map[string]interface{}{
+		"Package":        pkg.Name,
+		"Type":           *typeName,
+		"Exports":        *exportsName,
+		"ActorQualifier": actorQualifier,
+		"Imports":        groups,
+		"Methods":        methods,
+	})
+	if err != nil {
+		fail(err.Error())
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		fail(fmt.Sprintf("generated invalid code: %s", err))
+	}
+	if err := ioutil.WriteFile(*out, src, 0644); err != nil {
+		fail(err.Error())
+	}
+}
+
+// findExports returns the composite literal the exports variable is
+// initialized with.
+func findExports(pkg *ast.Package, name string) *ast.CompositeLit {
+	for _, f := range pkg.Files {
+		for _, decl := range f.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.VAR {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				vs := spec.(*ast.ValueSpec)
+				for i, n := range vs.Names {
+					if n.Name == name && i < len(vs.Values) {
+						if cl, ok := vs.Values[i].(*ast.CompositeLit); ok {
+							return cl
+						}
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// findMethods returns the methods of the pointer type typeName by name.
+func findMethods(pkg *ast.Package, typeName string) map[string]*ast.FuncDecl {
+	methods := map[string]*ast.FuncDecl{}
+	for _, f := range pkg.Files {
+		for _, decl := range f.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if !ok || fd.Recv == nil || len(fd.Recv.List) != 1 {
+				continue
+			}
+			star, ok := fd.Recv.List[0].Type.(*ast.StarExpr)
+			if !ok {
+				continue
+			}
+			if id, ok := star.X.(*ast.Ident); ok && id.Name == typeName {
+				methods[fd.Name.Name] = fd
+			}
+		}
+	}
+	return methods
+}
+
+// signature returns the names of the abi types of the params and return
+// values of an &exec.FunctionSignature{...} literal.
+func signature(expr ast.Expr) (params, ret []string) {
+	if u, ok := expr.(*ast.UnaryExpr); ok {
+		expr = u.X
+	}
+	cl, ok := expr.(*ast.CompositeLit)
+	if !ok {
+		fail("exports must be function signature literals")
+	}
+	for _, elt := range cl.Elts {
+		kv := elt.(*ast.KeyValueExpr)
+		var names []string
+		if list, ok := kv.Value.(*ast.CompositeLit); ok {
+			for _, t := range list.Elts {
+				sel, ok := t.(*ast.SelectorExpr)
+				if !ok {
+					fail("abi types must be referred to as abi.<Type>")
+				}
+				names = append(names, sel.Sel.Name)
+			}
+		}
+		switch kv.Key.(*ast.Ident).Name {
+		case "Params":
+			params = names
+		case "Return":
+			ret = names
+		}
+	}
+	return params, ret
+}
+
+func countFields(fl *ast.FieldList) int {
+	if fl == nil {
+		return 0
+	}
+	n := 0
+	for _, f := range fl.List {
+		if len(f.Names) == 0 {
+			n++
+		} else {
+			n += len(f.Names)
+		}
+	}
+	return n
+}
+
+func fail(msg string) {
+	fmt.Fprintln(os.Stderr, "actorgen:", msg)
+	os.Exit(1)
+}
@@ -44,7 +44,7 @@ func (f *Filecoin) WalletImport(ctx context.Context, file files.File) ([]address
 
 // WalletExport run the wallet export command against the filecoin process.
 func (f *Filecoin) WalletExport(ctx context.Context, addrs []address.Address) ([]*types.KeyInfo, error) {
-	// the command returns an KeyInfoListResult
+	// the command returns a WalletExportResult
 	var klr commands.WalletExportResult
 	// we expect to interact with an array of KeyInfo(s)
 	var out []*types.KeyInfo
@@ -57,6 +57,13 @@ func (f *Filecoin) WalletExport(ctx context.Context, addrs []address.Address) ([
 		return nil, err
 	}
 
-	// transform the KeyInfoListResult to an array of KeyInfo(s)
-	return append(out, klr.KeyInfo...), nil
+	// transform the key files to an array of KeyInfo(s)
+	for _, kf := range klr.Keys {
+		ki, err := kf.KeyInfo()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, ki)
+	}
+	return out, nil
 }
@@ -31,18 +31,23 @@ func POAutoSealIntervalSeconds(seconds int) ProcessInitOption {
 	}
 }
 
-// PODevnetTest provides the `--devnet-test` option to process at init
-func PODevnetTest() ProcessInitOption {
+// PONetwork provides the `--network=<name>` option to process at init
+func PONetwork(name string) ProcessInitOption {
 	return func() []string {
-		return []string{"--devnet-test"}
+		return []string{"--network", name}
 	}
 }
 
-// PODevnetNightly provides the `--devnet-nightly` option to process at init
+// PODevnetTest provides the `--network=staging` option to process at init,
+// which the deprecated `--devnet-test` option is an alias of
+func PODevnetTest() ProcessInitOption {
+	return PONetwork("staging")
+}
+
+// PODevnetNightly provides the `--network=nightly` option to process at init,
+// which the deprecated `--devnet-nightly` option is an alias of
 func PODevnetNightly() ProcessInitOption {
-	return func() []string {
-		return []string{"--devnet-nightly"}
-	}
+	return PONetwork("nightly")
 }
 
 // ProcessDaemonOption are options passed to process when starting.
@@ -135,7 +135,7 @@ func TestRetrieval(t *testing.T) {
 	require.NoError(err)
 
 	// Wait for the deal to be posted
-	err = series.WaitForDealState(ctx, client, deal, storage.Posted)
+	err = series.WaitForDealState(ctx, client, deal, storage.Proving)
 	require.NoError(err)
 
 	// Retrieve the stored piece of data
@@ -0,0 +1,74 @@
+package captcha
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultVerifyURL is the verification endpoint of reCAPTCHA. hCaptcha
+// implements the same protocol at https://hcaptcha.com/siteverify.
+const DefaultVerifyURL = "https://www.google.com/recaptcha/api/siteverify"
+
+// ResponseField is the form field the captcha widget submits its response in.
+const ResponseField = "g-recaptcha-response"
+
+// Verifier checks the responses to captchas with the verification endpoint
+// of the captcha service.
+type Verifier struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+// NewVerifier returns a verifier using the endpoint at verifyURL and the
+// secret key of the site.
+func NewVerifier(verifyURL, secret string, client *http.Client) *Verifier {
+	return &Verifier{url: verifyURL, secret: secret, client: client}
+}
+
+type verifyResult struct {
+	Success    bool     `json:"success"`
+	ErrorCodes []string `json:"error-codes"`
+}
+
+// Verify returns an error unless response, submitted by the client at
+// remoteIP, solves a captcha.
+func (v *Verifier) Verify(ctx context.Context, response, remoteIP string) error {
+	if response == "" {
+		return fmt.Errorf("missing captcha response")
+	}
+
+	form := url.Values{}
+	form.Set("secret", v.secret)
+	form.Set("response", response)
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+	req, err := http.NewRequest(http.MethodPost, v.url, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := v.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to verify captcha: %s", err)
+	}
+	defer resp.Body.Close() // nolint: errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to verify captcha: %s", resp.Status)
+	}
+	var res verifyResult
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return fmt.Errorf("failed to decode captcha verification: %s", err)
+	}
+	if !res.Success {
+		return fmt.Errorf("captcha verification failed: %s", strings.Join(res.ErrorCodes, ", "))
+	}
+	return nil
+}
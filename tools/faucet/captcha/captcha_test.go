@@ -0,0 +1,61 @@
+package captcha
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("secret") != "secret" {
+			w.Write([]byte(`{"success": false, "error-codes": ["invalid-input-secret"]}`)) // nolint: errcheck
+			return
+		}
+		if r.FormValue("response") != "solved" || r.FormValue("remoteip") != "1.2.3.4" {
+			w.Write([]byte(`{"success": false, "error-codes": ["invalid-input-response"]}`)) // nolint: errcheck
+			return
+		}
+		w.Write([]byte(`{"success": true}`)) // nolint: errcheck
+	}))
+	defer server.Close()
+
+	t.Run("Accepts a solved captcha", func(t *testing.T) {
+		assert := assert.New(t)
+
+		v := NewVerifier(server.URL, "secret", server.Client())
+		assert.NoError(v.Verify(ctx, "solved", "1.2.3.4"))
+	})
+
+	t.Run("Rejects an unsolved captcha", func(t *testing.T) {
+		assert := assert.New(t)
+
+		v := NewVerifier(server.URL, "secret", server.Client())
+		err := v.Verify(ctx, "wrong", "1.2.3.4")
+		assert.EqualError(err, "captcha verification failed: invalid-input-response")
+	})
+
+	t.Run("Rejects a missing response", func(t *testing.T) {
+		assert := assert.New(t)
+
+		v := NewVerifier(server.URL, "secret", server.Client())
+		assert.EqualError(v.Verify(ctx, "", "1.2.3.4"), "missing captcha response")
+	})
+
+	t.Run("Fails when the endpoint does", func(t *testing.T) {
+		assert := assert.New(t)
+
+		failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+		}))
+		defer failing.Close()
+
+		v := NewVerifier(failing.URL, "secret", failing.Client())
+		assert.Error(v.Verify(ctx, "solved", "1.2.3.4"))
+	})
+}
@@ -1,18 +1,29 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"html/template"
 	"io/ioutil"
+	"math/big"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"strings"
 	"time"
 
 	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
 	logging "gx/ipfs/QmcuXC5cxs79ro2cUuHs4HQ2bkDLJUYokwL8aivcX6HW3C/go-log"
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
 	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/tools/faucet/captcha"
 	"github.com/filecoin-project/go-filecoin/tools/faucet/limiter"
+	"github.com/filecoin-project/go-filecoin/types"
 )
 
 var log = logging.Logger("faucet")
@@ -23,6 +34,9 @@ var limiterCleanTick = time.Minute * 15
 // Default timeout between wallet fund requests
 var defaultLimiterExpiry = time.Hour * 1
 
+// Default timeout between fund requests from the same ip
+var defaultIPLimiterExpiry = time.Minute * 10
+
 func init() {
 	// Info level
 	logging.SetAllLoggers(4)
@@ -35,11 +49,46 @@ func (mt *timeImpl) Until(t time.Time) time.Duration {
 	return time.Until(t)
 }
 
+// faucet sends funds from the wallet of a filecoin node to the addresses
+// requesting them.
+type faucet struct {
+	// api is the api address of the filecoin node, wallet the address funds
+	// are sent from and token the api token of the node.
+	api    string
+	wallet string
+	token  string
+
+	// value is the FIL sent to each requester, unless target is set, in
+	// which case the balance of the requester is topped up to target FIL.
+	value  uint64
+	target uint64
+
+	expiry      time.Duration
+	ipExpiry    time.Duration
+	addrLimiter *limiter.Limiter
+	ipLimiter   *limiter.Limiter
+	// trustProxy is whether the ip of the requester is taken from the
+	// X-Forwarded-For header, when the faucet runs behind a proxy.
+	trustProxy bool
+
+	// verifier checks the captchas of requests, when siteKey is set.
+	verifier *captcha.Verifier
+	siteKey  string
+}
+
 func main() {
 	filapi := flag.String("fil-api", "localhost:3453", "set the api address of the filecoin node to use")
 	filwal := flag.String("fil-wallet", "", "(required) set the wallet address for the controlled filecoin node to send funds from")
 	expiry := flag.Duration("limiter-expiry", defaultLimiterExpiry, "minimum time duration between faucet request to the same wallet addr")
-	faucetval := flag.Int64("faucet-val", 500, "set the amount of fil to pay to each requester")
+	ipExpiry := flag.Duration("ip-limiter-expiry", defaultIPLimiterExpiry, "minimum time duration between faucet requests from the same ip, 0 to disable")
+	trustProxy := flag.Bool("trust-proxy", false, "take the ip of requesters from the X-Forwarded-For header set by a proxy in front of the faucet")
+	faucetval := flag.Uint64("faucet-val", 500, "set the amount of fil to pay to each requester")
+	faucetTarget := flag.Uint64("faucet-target", 0, "top up the balance of requesters to this amount of fil instead of paying faucet-val, 0 to disable")
+	filtoken := flag.String("fil-token", os.Getenv("FIL_API_TOKEN"), "set the api token, granting the sign permission, of the filecoin node to use")
+	captchaSiteKey := flag.String("captcha-site-key", "", "set the site key of the captcha to solve to request funds, captchas are disabled if empty")
+	captchaSecret := flag.String("captcha-secret", os.Getenv("FAUCET_CAPTCHA_SECRET"), "set the secret key verifying the captchas")
+	captchaURL := flag.String("captcha-verify-url", captcha.DefaultVerifyURL, "set the verification endpoint of the captcha service")
+	listen := flag.String("listen", ":9797", "set the address to serve the faucet on, metrics are served at /metrics")
 	flag.Parse()
 
 	if *filwal == "" {
@@ -47,87 +96,226 @@ func main() {
 		flag.Usage()
 		return
 	}
+	if *captchaSiteKey != "" && *captchaSecret == "" {
+		fmt.Println("ERROR: must provide the captcha secret with the captcha site key")
+		flag.Usage()
+		return
+	}
 
-	addrLimiter := limiter.NewLimiter(&timeImpl{})
+	f := &faucet{
+		api:         *filapi,
+		wallet:      *filwal,
+		token:       *filtoken,
+		value:       *faucetval,
+		target:      *faucetTarget,
+		expiry:      *expiry,
+		ipExpiry:    *ipExpiry,
+		addrLimiter: limiter.NewLimiter(&timeImpl{}),
+		ipLimiter:   limiter.NewLimiter(&timeImpl{}),
+		trustProxy:  *trustProxy,
+		siteKey:     *captchaSiteKey,
+	}
+	if f.siteKey != "" {
+		f.verifier = captcha.NewVerifier(*captchaURL, *captchaSecret, &http.Client{Timeout: 10 * time.Second})
+	}
 
-	// Clean the limiter every limiterCleanTick
+	// Clean the limiters every limiterCleanTick
 	go func() {
 		c := time.Tick(limiterCleanTick)
 		for range c {
-			addrLimiter.Clean()
+			f.addrLimiter.Clean()
+			f.ipLimiter.Clean()
 		}
 	}()
 
-	http.HandleFunc("/", displayForm)
-	http.HandleFunc("/tap", func(w http.ResponseWriter, r *http.Request) {
-		target := r.FormValue("target")
-		if target == "" {
-			http.Error(w, "must specify a target address to send FIL to", 400)
-			return
-		}
-		log.Infof("Request to send funds to: %s", target)
+	http.HandleFunc("/", f.displayForm)
+	http.HandleFunc("/tap", f.tap)
+	http.Handle("/metrics", promhttp.Handler())
 
-		addr, err := address.NewFromString(target)
-		if err != nil {
-			log.Errorf("failed to parse target address: %s %s", target, err)
-			http.Error(w, fmt.Sprintf("Failed to parse target address %s %s", target, err.Error()), 400)
-			return
-		}
+	panic(http.ListenAndServe(*listen, nil))
+}
 
-		if readyIn, ok := addrLimiter.Ready(target); !ok {
-			log.Errorf("limit hit for target address %s", target)
-			w.Header().Add("Retry-After", fmt.Sprintf("%d", int64(readyIn/time.Second)))
-			http.Error(w, fmt.Sprintf("Too Many Requests, please wait %s", readyIn), http.StatusTooManyRequests)
-			return
-		}
+func (f *faucet) tap(w http.ResponseWriter, r *http.Request) {
+	target := r.FormValue("target")
+	if target == "" {
+		requestsTotal.WithLabelValues(resultInvalid).Inc()
+		http.Error(w, "must specify a target address to send FIL to", 400)
+		return
+	}
+	log.Infof("Request to send funds to: %s", target)
 
-		reqStr := fmt.Sprintf("http://%s/api/message/send?arg=%s&value=%d&from=%s&price=0&limit=0", *filapi, addr.String(), *faucetval, *filwal)
-		log.Infof("Request URL: %s", reqStr)
+	addr, err := address.NewFromString(target)
+	if err != nil {
+		log.Errorf("failed to parse target address: %s %s", target, err)
+		requestsTotal.WithLabelValues(resultInvalid).Inc()
+		http.Error(w, fmt.Sprintf("Failed to parse target address %s %s", target, err.Error()), 400)
+		return
+	}
+	// the canonical form, so that encodings of the same address share a limit
+	target = addr.String()
 
-		resp, err := http.Post(reqStr, "application/json", nil)
-		if err != nil {
-			log.Errorf("failed to Post request. Status: %s Error: %s", resp.Status, err)
-			http.Error(w, err.Error(), 500)
+	ip := f.clientIP(r)
+	if readyIn, ok := f.ready(target, ip); !ok {
+		log.Errorf("limit hit for target address %s from %s", target, ip)
+		requestsTotal.WithLabelValues(resultRateLimited).Inc()
+		w.Header().Add("Retry-After", fmt.Sprintf("%d", int64(readyIn/time.Second)))
+		http.Error(w, fmt.Sprintf("Too Many Requests, please wait %s", readyIn), http.StatusTooManyRequests)
+		return
+	}
+
+	if f.verifier != nil {
+		if err := f.verifier.Verify(r.Context(), r.FormValue(captcha.ResponseField), ip); err != nil {
+			log.Errorf("captcha rejected for target address %s from %s: %s", target, ip, err)
+			requestsTotal.WithLabelValues(resultCaptchaFailed).Inc()
+			http.Error(w, "Captcha verification failed", http.StatusForbidden)
 			return
 		}
+	}
 
-		out, err := ioutil.ReadAll(resp.Body)
+	value := f.value
+	if f.target > 0 {
+		balance, err := f.balance(r.Context(), addr)
 		if err != nil {
-			log.Errorf("failed to read response body: %s", err)
-			http.Error(w, "failed to read response", 500)
+			log.Errorf("failed to get balance of %s: %s", target, err)
+			requestsTotal.WithLabelValues(resultError).Inc()
+			http.Error(w, "failed to get balance", 500)
 			return
 		}
-		if resp.StatusCode != 200 {
-			log.Errorf("status: %s body: %s", resp.Status, string(out))
-			http.Error(w, "failed to send funds", 500)
+		value = topUpValue(balance, f.target)
+		if value == 0 {
+			requestsTotal.WithLabelValues(resultFunded).Inc()
+			fmt.Fprintf(w, "Balance already at least %d FIL, nothing sent\n", f.target) // nolint: errcheck
 			return
 		}
+	}
 
-		msgResp := struct{ Cid cid.Cid }{}
+	msgcid, err := f.send(r.Context(), addr, value)
+	if err != nil {
+		log.Errorf("failed to send funds to %s: %s", target, err)
+		requestsTotal.WithLabelValues(resultError).Inc()
+		http.Error(w, "failed to send funds", 500)
+		return
+	}
 
-		// result should be a message cid
-		if err := json.Unmarshal(out, &msgResp); err != nil {
-			log.Errorf("json unmarshal from response failed: %s", err)
-			log.Errorf("response data was: %s", out)
-			http.Error(w, "faucet unmarshal failed", 500)
-			return
+	f.addrLimiter.Add(target, time.Now().Add(f.expiry))
+	if f.ipExpiry > 0 {
+		f.ipLimiter.Add(ip, time.Now().Add(f.ipExpiry))
+	}
+	requestsTotal.WithLabelValues(resultSent).Inc()
+	sentFILTotal.Add(float64(value))
+
+	log.Infof("Request successful. Message CID: %s", msgcid.String())
+	w.Header().Add("Message-Cid", msgcid.String())
+	w.WriteHeader(200)
+	fmt.Fprint(w, "Success! Message CID: ") // nolint: errcheck
+	fmt.Fprintln(w, msgcid.String())        // nolint: errcheck
+}
+
+// ready checks both the limit of the target address and the one of the ip
+// of the requester, and returns the longest time remaining.
+func (f *faucet) ready(target, ip string) (time.Duration, bool) {
+	addrIn, addrOk := f.addrLimiter.Ready(target)
+	ipIn, ipOk := f.ipLimiter.Ready(ip)
+	if ipIn > addrIn {
+		return ipIn, false
+	}
+	return addrIn, addrOk && ipOk
+}
+
+// clientIP returns the ip of the requester of r.
+func (f *faucet) clientIP(r *http.Request) string {
+	if f.trustProxy {
+		// the proxy appends the ip it received the request from
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			hops := strings.Split(fwd, ",")
+			return strings.TrimSpace(hops[len(hops)-1])
 		}
-		msgcid := msgResp.Cid
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// topUpValue returns the whole FIL to send for balance to reach target FIL,
+// or 0 when it already does.
+func topUpValue(balance *types.AttoFIL, target uint64) uint64 {
+	if balance.GreaterEqual(types.NewAttoFILFromFIL(target)) {
+		return 0
+	}
+	// the balance is below target, so its whole FIL fit a uint64
+	whole, ok := new(big.Int).SetString(strings.SplitN(balance.String(), ".", 2)[0], 10)
+	if !ok {
+		return target
+	}
+	return target - whole.Uint64()
+}
+
+// balance returns the balance of addr.
+func (f *faucet) balance(ctx context.Context, addr address.Address) (*types.AttoFIL, error) {
+	var balance types.AttoFIL
+	if err := f.call(ctx, "wallet/balance", url.Values{"arg": {addr.String()}}, &balance); err != nil {
+		return nil, err
+	}
+	return &balance, nil
+}
 
-		addrLimiter.Add(target, time.Now().Add(*expiry))
+// send sends value FIL to addr and returns the cid of the message.
+func (f *faucet) send(ctx context.Context, addr address.Address, value uint64) (cid.Cid, error) {
+	params := url.Values{
+		"arg":   {addr.String()},
+		"value": {fmt.Sprintf("%d", value)},
+		"from":  {f.wallet},
+		"price": {"0"},
+		"limit": {"0"},
+	}
+	msgResp := struct{ Cid cid.Cid }{}
+	if err := f.call(ctx, "message/send", params, &msgResp); err != nil {
+		return cid.Cid{}, err
+	}
+	return msgResp.Cid, nil
+}
 
-		log.Info("Request successful. Message CID: %s", msgcid.String())
-		w.Header().Add("Message-Cid", msgcid.String())
-		w.WriteHeader(200)
-		fmt.Fprint(w, "Success! Message CID: ") // nolint: errcheck
-		fmt.Fprintln(w, msgcid.String())        // nolint: errcheck
-	})
+// call runs the command at path of the api of the node and decodes its JSON
+// result into out.
+func (f *faucet) call(ctx context.Context, path string, params url.Values, out interface{}) error {
+	reqStr := fmt.Sprintf("http://%s/api/%s?%s", f.api, path, params.Encode())
+	log.Infof("Request URL: %s", reqStr)
 
-	panic(http.ListenAndServe(":9797", nil))
+	req, err := http.NewRequest(http.MethodPost, reqStr, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if f.token != "" {
+		req.Header.Set("Authorization", "Bearer "+f.token)
+	}
+
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %s", err)
+	}
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("status: %s body: %s", resp.Status, string(body))
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("json unmarshal of response %s failed: %s", body, err)
+	}
+	return nil
 }
 
-const form = `
+var form = template.Must(template.New("form").Parse(`
 <html>
+	<head>
+		{{if .SiteKey}}<script src="https://www.google.com/recaptcha/api.js" async defer></script>{{end}}
+	</head>
 	<body>
 		<h1> What is your wallet address </h1>
 		<p> You can find this by running: </p>
@@ -135,12 +323,13 @@ const form = `
 		<p> Address: </p>
 		<form action="/tap" method="post">
 			<input type="text" name="target" size="30" />
+			{{if .SiteKey}}<div class="g-recaptcha" data-sitekey="{{.SiteKey}}"></div>{{end}}
 			<input type="submit" value="Submit" size="30" />
 		</form>
 	</body>
 </html>
-`
+`))
 
-func displayForm(w http.ResponseWriter, r *http.Request) {
-	fmt.Fprint(w, form) // nolint: errcheck
+func (f *faucet) displayForm(w http.ResponseWriter, r *http.Request) {
+	form.Execute(w, struct{ SiteKey string }{f.siteKey}) // nolint: errcheck
 }
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/filecoin-project/go-filecoin/tools/faucet/limiter"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func TestTopUpValue(t *testing.T) {
+	assert := assert.New(t)
+
+	fil := func(s string) *types.AttoFIL {
+		v, ok := types.NewAttoFILFromFILString(s)
+		assert.True(ok)
+		return v
+	}
+
+	assert.Equal(uint64(500), topUpValue(fil("0"), 500))
+	assert.Equal(uint64(400), topUpValue(fil("100"), 500))
+	// fractions of FIL round the value up
+	assert.Equal(uint64(401), topUpValue(fil("99.5"), 500))
+	assert.Equal(uint64(0), topUpValue(fil("500"), 500))
+	assert.Equal(uint64(0), topUpValue(fil("1000.25"), 500))
+}
+
+func TestReady(t *testing.T) {
+	newFaucet := func() *faucet {
+		return &faucet{
+			addrLimiter: limiter.NewLimiter(&timeImpl{}),
+			ipLimiter:   limiter.NewLimiter(&timeImpl{}),
+		}
+	}
+
+	t.Run("Limits the address", func(t *testing.T) {
+		assert := assert.New(t)
+
+		f := newFaucet()
+		f.addrLimiter.Add("addr", time.Now().Add(time.Hour))
+		_, ok := f.ready("addr", "1.2.3.4")
+		assert.False(ok)
+		_, ok = f.ready("other", "1.2.3.4")
+		assert.True(ok)
+	})
+
+	t.Run("Limits the ip", func(t *testing.T) {
+		assert := assert.New(t)
+
+		f := newFaucet()
+		f.addrLimiter.Add("addr", time.Now().Add(time.Minute))
+		f.ipLimiter.Add("1.2.3.4", time.Now().Add(time.Hour))
+		readyIn, ok := f.ready("other", "1.2.3.4")
+		assert.False(ok)
+		assert.True(readyIn > time.Minute)
+
+		_, ok = f.ready("other", "5.6.7.8")
+		assert.True(ok)
+	})
+}
+
+func TestClientIP(t *testing.T) {
+	assert := assert.New(t)
+
+	r := httptest.NewRequest("POST", "/tap", nil)
+	r.RemoteAddr = "10.0.0.1:4321"
+	r.Header.Set("X-Forwarded-For", "6.6.6.6, 1.2.3.4")
+
+	assert.Equal("10.0.0.1", (&faucet{}).clientIP(r))
+	assert.Equal("1.2.3.4", (&faucet{trustProxy: true}).clientIP(r))
+}
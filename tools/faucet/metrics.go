@@ -0,0 +1,31 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// The results of requests to the faucet, labelling requestsTotal.
+const (
+	resultSent          = "sent"
+	resultFunded        = "funded"
+	resultInvalid       = "invalid"
+	resultRateLimited   = "rate_limited"
+	resultCaptchaFailed = "captcha_failed"
+	resultError         = "error"
+)
+
+var requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "faucet",
+	Name:      "requests_total",
+	Help:      "Number of requests for funds, by result.",
+}, []string{"result"})
+
+var sentFILTotal = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: "faucet",
+	Name:      "sent_fil_total",
+	Help:      "FIL sent by the faucet.",
+})
+
+func init() {
+	prometheus.MustRegister(requestsTotal, sentFILTotal)
+}
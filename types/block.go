@@ -54,6 +54,11 @@ type Block struct {
 	// Proof is a proof of spacetime generated using the hash of the previous ticket as
 	// a challenge
 	Proof proofs.PoStProof `json:"proof"`
+
+	// BLSAggregate is the aggregate of the signatures of the BLS signed
+	// messages of the block, in message order. It is empty when there are
+	// none.
+	BLSAggregate Signature `json:"blsAggregate,omitempty" refmt:",omitempty"`
 }
 
 // Cid returns the content id of this block.
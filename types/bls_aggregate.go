@@ -0,0 +1,115 @@
+package types
+
+import (
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	wutil "github.com/filecoin-project/go-filecoin/wallet/util"
+)
+
+// IsBLSSigned returns true if the message carries a BLS signature.
+func (smsg *SignedMessage) IsBLSSigned() bool {
+	return wutil.IsBLSSignature(smsg.Signature)
+}
+
+// AggregateBLSSignatures aggregates the signatures of BLS signed messages
+// into a single signature, allowing block producers to include one signature
+// for all of them. It returns the aggregate and the signers' public keys in
+// message order, which are needed to verify it.
+func AggregateBLSSignatures(smsgs []*SignedMessage) (Signature, [][]byte, error) {
+	sigs := make([][]byte, len(smsgs))
+	pubKeys := make([][]byte, len(smsgs))
+	for i, smsg := range smsgs {
+		if !smsg.IsBLSSigned() {
+			return nil, nil, errors.Errorf("message %d is not BLS signed", i)
+		}
+		sigs[i] = smsg.Signature
+		pubKeys[i] = wutil.BLSSignaturePublicKey(smsg.Signature)
+	}
+
+	agg, err := wutil.BLSAggregate(sigs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return agg, pubKeys, nil
+}
+
+// VerifyBLSAggregate verifies that agg is the aggregate of signatures of
+// msgs by their senders, where pubKeys holds the senders' public keys in
+// message order.
+func VerifyBLSAggregate(agg Signature, msgs []*MeteredMessage, pubKeys [][]byte) (bool, error) {
+	if len(msgs) != len(pubKeys) {
+		return false, errors.New("number of messages and public keys differ")
+	}
+
+	data := make([][]byte, len(msgs))
+	for i, msg := range msgs {
+		if address.NewMainnet(address.Hash(pubKeys[i])) != msg.From {
+			return false, nil
+		}
+
+		bmsg, err := msg.Marshal()
+		if err != nil {
+			return false, err
+		}
+		data[i] = bmsg
+	}
+
+	return wutil.BLSVerifyAggregate(agg, data, pubKeys), nil
+}
+
+// AggregateBLSSignatures sets the BLS aggregate of the block to the aggregate
+// of the signatures of its BLS signed messages.
+func (b *Block) AggregateBLSSignatures() error {
+	smsgs := blsSignedMessages(b.Messages)
+	if len(smsgs) == 0 {
+		b.BLSAggregate = nil
+		return nil
+	}
+
+	agg, _, err := AggregateBLSSignatures(smsgs)
+	if err != nil {
+		return err
+	}
+	b.BLSAggregate = agg
+	return nil
+}
+
+// VerifyBLSAggregate returns an error unless the BLS aggregate of the block
+// is the aggregate of the signatures of its BLS signed messages by their
+// senders.
+func (b *Block) VerifyBLSAggregate() error {
+	smsgs := blsSignedMessages(b.Messages)
+	if len(smsgs) == 0 {
+		if len(b.BLSAggregate) != 0 {
+			return errors.New("block has a BLS aggregate but no BLS signed messages")
+		}
+		return nil
+	}
+
+	msgs := make([]*MeteredMessage, len(smsgs))
+	pubKeys := make([][]byte, len(smsgs))
+	for i, smsg := range smsgs {
+		msgs[i] = &smsg.MeteredMessage
+		pubKeys[i] = wutil.BLSSignaturePublicKey(smsg.Signature)
+	}
+
+	valid, err := VerifyBLSAggregate(b.BLSAggregate, msgs, pubKeys)
+	if err != nil {
+		return errors.Wrap(err, "failed to verify BLS aggregate")
+	}
+	if !valid {
+		return errors.New("invalid BLS aggregate")
+	}
+	return nil
+}
+
+func blsSignedMessages(smsgs []*SignedMessage) []*SignedMessage {
+	var out []*SignedMessage
+	for _, smsg := range smsgs {
+		if smsg.IsBLSSigned() {
+			out = append(out, smsg)
+		}
+	}
+	return out
+}
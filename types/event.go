@@ -0,0 +1,26 @@
+package types
+
+import (
+	cbor "gx/ipfs/QmRoARq3nkUb13HSKZGepCZSWe5GrVPwx7xURJGZ7KWv9V/go-ipld-cbor"
+
+	"github.com/filecoin-project/go-filecoin/address"
+)
+
+func init() {
+	cbor.RegisterCborType(Event{})
+}
+
+// Event is a record an actor emits while executing a message, so that what
+// actors do can be observed without polling their state. The events of a
+// message are kept in its receipt.
+type Event struct {
+	// Actor is the address of the actor that emitted the event.
+	Actor address.Address `json:"actor"`
+	// Method is the method the actor was executing.
+	Method string `json:"method"`
+	// Type names the event. Each actor documents the types it emits and
+	// the values they carry.
+	Type string `json:"type"`
+	// Data holds the abi encoded values of the event.
+	Data Bytes `json:"data,omitempty"`
+}
@@ -11,6 +11,8 @@ import (
 const (
 	// SECP256K1 is a curve used to compute private keys
 	SECP256K1 = "secp256k1"
+	// BLS is the BLS12-381 curve used for aggregatable signatures
+	BLS = "bls"
 )
 
 // MustGenerateKeyInfo generates a slice of KeyInfo size `n` with seed `seed`
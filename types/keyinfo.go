@@ -10,6 +10,7 @@ import (
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/crypto"
 	cu "github.com/filecoin-project/go-filecoin/crypto/util"
+	wutil "github.com/filecoin-project/go-filecoin/wallet/util"
 )
 
 func init() {
@@ -72,8 +73,13 @@ func (ki *KeyInfo) Address() (address.Address, error) {
 	return address.NewMainnet(addrHash), nil
 }
 
-// PublicKey returns the public key part as uncompressed bytes.
+// PublicKey returns the public key part as uncompressed bytes, or for BLS
+// keys as compressed bytes.
 func (ki *KeyInfo) PublicKey() ([]byte, error) {
+	if ki.Curve == BLS {
+		return wutil.BLSPublicKey(ki.Key())
+	}
+
 	prv, err := crypto.BytesToECDSA(ki.Key())
 	if err != nil {
 		return nil, err
@@ -18,6 +18,15 @@ type MessageReceipt struct {
 	// programmatically readable detail about errors).
 	Return []Bytes `json:"return"`
 
+	// RevertReason tells why a message failed. It is set by the actor that
+	// reverted and is meant to be read by people, programs should use
+	// ExitCode.
+	RevertReason Bytes `json:"revertReason,omitempty" refmt:",omitempty"`
+
 	// GasAttoFIL Charge is the actual amount of FIL transferred from the sender to the miner for processing the message
 	GasAttoFIL *AttoFIL `json:"gasAttoFIL"`
+
+	// Events are emitted by the actors that processed the message, in order.
+	// Events of calls that failed are not kept.
+	Events []*Event `json:"events,omitempty" refmt:",omitempty"`
 }
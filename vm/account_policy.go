@@ -0,0 +1,79 @@
+package vm
+
+import (
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/account"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/vm/errors"
+)
+
+// AccountCreationPolicy decides what the VM does with an address of a given
+// protocol that has no actor yet.
+type AccountCreationPolicy interface {
+	// NewActor returns the actor to install at addr when a message is sent
+	// to it. The actor holds any value transferred until code is installed.
+	NewActor(addr address.Address) (*actor.Actor, error)
+	// UpgradeSender is called when the actor at addr, which has no code,
+	// sends a message. It may install code in act so it can send.
+	UpgradeSender(addr address.Address, act *actor.Actor) error
+}
+
+// AccountCreationPolicies maps address protocols to their account creation
+// policy. Messages to and from addresses of a protocol without a policy are
+// reverted, so supporting a new protocol only takes adding its policy here.
+var AccountCreationPolicies = map[address.Protocol]AccountCreationPolicy{
+	address.ProtocolHash:      &hashAccountPolicy{},
+	address.ProtocolDelegated: &delegatedAccountPolicy{},
+}
+
+// NewActorForAddress returns the actor to install at addr, which has no
+// actor, when a message is sent to it.
+func NewActorForAddress(addr address.Address) (*actor.Actor, error) {
+	policy, ok := AccountCreationPolicies[addr.Protocol()]
+	if !ok {
+		return nil, errors.Errors[errors.ErrUnknownAddressProtocol]
+	}
+	return policy.NewActor(addr)
+}
+
+// UpgradeEmptySender upgrades the actor without code at addr when it sends a
+// message.
+func UpgradeEmptySender(addr address.Address, act *actor.Actor) error {
+	policy, ok := AccountCreationPolicies[addr.Protocol()]
+	if !ok {
+		return errors.Errors[errors.ErrUnknownAddressProtocol]
+	}
+	return policy.UpgradeSender(addr, act)
+}
+
+// hashAccountPolicy is the policy of public key hash addresses.
+type hashAccountPolicy struct{}
+
+// NewActor returns an empty actor. Addresses are deterministic so sending a
+// message to a non-existent address must not install an actor, else actors
+// could be installed ahead of address activation.
+func (p *hashAccountPolicy) NewActor(addr address.Address) (*actor.Actor, error) {
+	return &actor.Actor{}, nil
+}
+
+// UpgradeSender turns the actor into an account actor: only the holder of
+// the key can have signed its message.
+func (p *hashAccountPolicy) UpgradeSender(addr address.Address, act *actor.Actor) error {
+	return account.UpgradeActor(act)
+}
+
+// delegatedAccountPolicy is the policy of addresses managed by a namespace
+// actor.
+type delegatedAccountPolicy struct{}
+
+// NewActor returns an empty actor collecting value for the delegated address.
+func (p *delegatedAccountPolicy) NewActor(addr address.Address) (*actor.Actor, error) {
+	return &actor.Actor{}, nil
+}
+
+// UpgradeSender leaves the actor empty. A delegated address has no key of its
+// own, so its messages are rejected as coming from a non-account actor until
+// its namespace actor installs code at it.
+func (p *delegatedAccountPolicy) UpgradeSender(addr address.Address, act *actor.Actor) error {
+	return nil
+}
@@ -0,0 +1,55 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/vm/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountCreationPolicies(t *testing.T) {
+	t.Parallel()
+
+	hashAddr := address.NewForTestGetter()()
+	delegatedAddr := address.NewDelegated(address.Testnet, address.TestAddress, []byte("sub"))
+	unknownAddr := address.NewWithProtocol(address.Testnet, 1, address.Hash([]byte("unknown")))
+
+	t.Run("hash addresses become accounts when they send", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		act, err := NewActorForAddress(hashAddr)
+		require.NoError(err)
+		assert.False(act.Code.Defined())
+
+		require.NoError(UpgradeEmptySender(hashAddr, act))
+		assert.Equal(types.AccountActorCodeCid, act.Code)
+	})
+
+	t.Run("delegated addresses stay empty when they send", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		act, err := NewActorForAddress(delegatedAddr)
+		require.NoError(err)
+
+		require.NoError(UpgradeEmptySender(delegatedAddr, act))
+		assert.False(act.Code.Defined())
+	})
+
+	t.Run("addresses of unknown protocols are reverted", func(t *testing.T) {
+		assert := assert.New(t)
+
+		_, err := NewActorForAddress(unknownAddr)
+		assert.True(errors.ShouldRevert(err))
+		assert.Equal(uint8(errors.ErrUnknownAddressProtocol), errors.CodeError(err))
+
+		err = UpgradeEmptySender(unknownAddr, &actor.Actor{})
+		assert.Equal(uint8(errors.ErrUnknownAddressProtocol), errors.CodeError(err))
+	})
+}
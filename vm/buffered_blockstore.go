@@ -0,0 +1,118 @@
+package vm
+
+import (
+	"sync"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	"gx/ipfs/QmS2aqUZLJp8kF1ihE5rvDGE5LvmKDPnx32w9Z1BW9xLV5/go-ipfs-blockstore"
+	blocks "gx/ipfs/QmWoXtvgC8inqFkAATB7cp2Dax7XBi9VDvSg9RCCZufmRk/go-block-format"
+)
+
+// BufferedBlockstore buffers the blocks put in it in memory until they are
+// flushed to the underlying blockstore. Reads see buffered blocks first.
+//
+// Running a state transition over a BufferedBlockstore keeps the state
+// objects it writes out of the underlying blockstore unless it is committed,
+// e.g. when a tipset turns out invalid.
+type BufferedBlockstore struct {
+	blockstore.Blockstore
+
+	lk  sync.RWMutex
+	buf map[cid.Cid]blocks.Block
+}
+
+var _ blockstore.Blockstore = (*BufferedBlockstore)(nil)
+
+// NewBufferedBlockstore returns a BufferedBlockstore over bs.
+func NewBufferedBlockstore(bs blockstore.Blockstore) *BufferedBlockstore {
+	return &BufferedBlockstore{
+		Blockstore: bs,
+		buf:        make(map[cid.Cid]blocks.Block),
+	}
+}
+
+// Get returns the block with the given cid.
+func (b *BufferedBlockstore) Get(c cid.Cid) (blocks.Block, error) {
+	b.lk.RLock()
+	blk, ok := b.buf[c]
+	b.lk.RUnlock()
+	if ok {
+		return blk, nil
+	}
+	return b.Blockstore.Get(c)
+}
+
+// Has returns true if the block with the given cid is buffered or in the
+// underlying blockstore.
+func (b *BufferedBlockstore) Has(c cid.Cid) (bool, error) {
+	b.lk.RLock()
+	_, ok := b.buf[c]
+	b.lk.RUnlock()
+	if ok {
+		return true, nil
+	}
+	return b.Blockstore.Has(c)
+}
+
+// GetSize returns the size of the block with the given cid.
+func (b *BufferedBlockstore) GetSize(c cid.Cid) (int, error) {
+	b.lk.RLock()
+	blk, ok := b.buf[c]
+	b.lk.RUnlock()
+	if ok {
+		return len(blk.RawData()), nil
+	}
+	return b.Blockstore.GetSize(c)
+}
+
+// Put buffers blk.
+func (b *BufferedBlockstore) Put(blk blocks.Block) error {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	b.buf[blk.Cid()] = blk
+	return nil
+}
+
+// PutMany buffers blks.
+func (b *BufferedBlockstore) PutMany(blks []blocks.Block) error {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	for _, blk := range blks {
+		b.buf[blk.Cid()] = blk
+	}
+	return nil
+}
+
+// DeleteBlock removes the block with the given cid from the buffer and the
+// underlying blockstore.
+func (b *BufferedBlockstore) DeleteBlock(c cid.Cid) error {
+	b.lk.Lock()
+	_, ok := b.buf[c]
+	delete(b.buf, c)
+	b.lk.Unlock()
+
+	err := b.Blockstore.DeleteBlock(c)
+	if ok && err == blockstore.ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+// Flush writes the buffered blocks to the underlying blockstore and empties
+// the buffer.
+func (b *BufferedBlockstore) Flush() error {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	blks := make([]blocks.Block, 0, len(b.buf))
+	for _, blk := range b.buf {
+		blks = append(blks, blk)
+	}
+	if err := b.Blockstore.PutMany(blks); err != nil {
+		return err
+	}
+	b.buf = make(map[cid.Cid]blocks.Block)
+	return nil
+}
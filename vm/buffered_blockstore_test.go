@@ -0,0 +1,45 @@
+package vm
+
+import (
+	"testing"
+
+	"gx/ipfs/QmS2aqUZLJp8kF1ihE5rvDGE5LvmKDPnx32w9Z1BW9xLV5/go-ipfs-blockstore"
+	blocks "gx/ipfs/QmWoXtvgC8inqFkAATB7cp2Dax7XBi9VDvSg9RCCZufmRk/go-block-format"
+	"gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBufferedBlockstore(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	bs := blockstore.NewBlockstore(datastore.NewMapDatastore())
+	buf := NewBufferedBlockstore(bs)
+
+	blk := blocks.NewBlock([]byte("some state"))
+	require.NoError(buf.Put(blk))
+
+	// buffered blocks are visible through the buffer only
+	got, err := buf.Get(blk.Cid())
+	require.NoError(err)
+	assert.Equal(blk.RawData(), got.RawData())
+	has, err := buf.Has(blk.Cid())
+	require.NoError(err)
+	assert.True(has)
+	size, err := buf.GetSize(blk.Cid())
+	require.NoError(err)
+	assert.Equal(len(blk.RawData()), size)
+	has, err = bs.Has(blk.Cid())
+	require.NoError(err)
+	assert.False(has)
+
+	require.NoError(buf.Flush())
+	has, err = bs.Has(blk.Cid())
+	require.NoError(err)
+	assert.True(has)
+	size, err = buf.GetSize(blk.Cid())
+	require.NoError(err)
+	assert.Equal(len(blk.RawData()), size)
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/binary"
+	"fmt"
 
 	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
 
@@ -28,6 +29,9 @@ type Context struct {
 	blockHeight *types.BlockHeight
 	ancestors   []types.TipSet
 	lookBack    int
+	trace       *Trace
+	events      *EventLog
+	prices      *PriceList
 
 	deps *deps // Inject external dependencies so we can unit test robustly.
 }
@@ -45,6 +49,10 @@ type NewContextParams struct {
 	BlockHeight *types.BlockHeight
 	Ancestors   []types.TipSet
 	LookBack    int
+	// Trace, when set, records the execution of the message.
+	Trace *Trace
+	// Events, when set, collects the events emitted by the actors.
+	Events *EventLog
 }
 
 // NewVMContext returns an initialized context.
@@ -59,6 +67,9 @@ func NewVMContext(params NewContextParams) *Context {
 		blockHeight: params.BlockHeight,
 		ancestors:   params.Ancestors,
 		lookBack:    params.LookBack,
+		trace:       params.Trace,
+		events:      params.Events,
+		prices:      Prices.PricesAt(params.BlockHeight),
 		deps:        makeDeps(params.State),
 	}
 }
@@ -66,8 +77,12 @@ func NewVMContext(params NewContextParams) *Context {
 var _ exec.VMContext = (*Context)(nil)
 
 // Storage returns an implementation of the storage module for this context.
+// Reads and writes are charged for.
 func (ctx *Context) Storage() exec.Storage {
-	return ctx.storageMap.NewStorage(ctx.message.To, ctx.to)
+	return &meteredStorage{
+		Storage: ctx.storageMap.NewStorage(ctx.message.To, ctx.to),
+		ctx:     ctx,
+	}
 }
 
 // Message retrieves the message associated with this context.
@@ -95,9 +110,39 @@ func (ctx *Context) ReadStorage() ([]byte, error) {
 
 // Charge attempts to add the given cost to the accrued gas cost of this transaction
 func (ctx *Context) Charge(cost types.GasUnits) error {
+	if ctx.trace != nil {
+		ctx.trace.GasCharges = append(ctx.trace.GasCharges, cost)
+	}
 	return ctx.gasTracker.Charge(cost)
 }
 
+// chargeOperation charges the cost of an operation the VM performs on behalf
+// of an actor. Free operations are not charged, so they don't show in traces.
+func (ctx *Context) chargeOperation(cost types.GasUnits) error {
+	if cost == 0 {
+		return nil
+	}
+	return ctx.Charge(cost)
+}
+
+// ChargeSignatureVerification charges for verifying the signature of the
+// message. It is called once for each signed message before sending it.
+func (ctx *Context) ChargeSignatureVerification() error {
+	if err := ctx.chargeOperation(ctx.prices.SignatureVerification); err != nil {
+		return errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+	return nil
+}
+
+// ChargeSealVerification charges for verifying the seal proofs of the given
+// number of sectors.
+func (ctx *Context) ChargeSealVerification(sectors int) error {
+	if err := ctx.chargeOperation(ctx.prices.OnSealVerification(sectors)); err != nil {
+		return errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+	return nil
+}
+
 // GasUnits retrieves the gas cost so far
 func (ctx *Context) GasUnits() types.GasUnits {
 	return ctx.gasTracker.gasConsumedByMessage
@@ -120,6 +165,46 @@ func (ctx *Context) WriteStorage(memory interface{}) error {
 	return nil
 }
 
+// EmitEvent records an event of the given type carrying values in the receipt
+// of the message. It is charged for by the encoded size of the values.
+func (ctx *Context) EmitEvent(eventType string, values ...interface{}) error {
+	data, err := abi.ToEncodedValues(values...)
+	if err != nil {
+		return errors.FaultErrorWrap(err, "failed to encode event values")
+	}
+
+	if err := ctx.chargeOperation(ctx.prices.OnEmitEvent(len(data))); err != nil {
+		return errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	ctx.events.emit(&types.Event{
+		Actor:  ctx.message.To,
+		Method: ctx.message.Method,
+		Type:   eventType,
+		Data:   data,
+	})
+	return nil
+}
+
+// abort is the value Context.Abort panics with. send recovers it into a
+// revert error.
+type abort struct {
+	code uint8
+	msg  string
+}
+
+// Abort stops the execution of the actor method, reverting the message with
+// the given exit code and message. Codes up to errors.ReservedErrors are set
+// by the VM only, an actor aborting with one reverts with ErrGeneric instead
+// so that its failure is never mistaken for a VM one.
+func (ctx *Context) Abort(code uint8, msg string) {
+	if code <= errors.ReservedErrors {
+		msg = fmt.Sprintf("%s (aborted with reserved exit code %d)", msg, code)
+		code = errors.ErrGeneric
+	}
+	panic(abort{code: code, msg: msg})
+}
+
 // BlockHeight returns the block height of the block currently being processed
 func (ctx *Context) BlockHeight() *types.BlockHeight {
 	return ctx.blockHeight
@@ -156,9 +241,11 @@ func (ctx *Context) Send(to address.Address, method string, value *types.AttoFIL
 	}
 
 	toActor, err := deps.GetOrCreateActor(context.TODO(), msg.To, func() (*actor.Actor, error) {
-		return &actor.Actor{}, nil
+		return NewActorForAddress(msg.To)
 	})
-	if err != nil {
+	if errors.ShouldRevert(err) {
+		return nil, errors.CodeError(err), err
+	} else if err != nil {
 		return nil, 1, errors.FaultErrorWrapf(err, "failed to get or create To actor %s", msg.To)
 	}
 	// TODO(fritz) de-dup some of the logic between here and core.Send
@@ -171,11 +258,19 @@ func (ctx *Context) Send(to address.Address, method string, value *types.AttoFIL
 		GasTracker:  ctx.gasTracker,
 		BlockHeight: ctx.blockHeight,
 		Ancestors:   ctx.ancestors,
+		Events:      ctx.events,
+	}
+	if ctx.trace != nil {
+		innerParams.Trace = &Trace{}
+		ctx.trace.Subcalls = append(ctx.trace.Subcalls, innerParams.Trace)
 	}
 	innerCtx := NewVMContext(innerParams)
 
+	// The events of a failed call are dropped along with its state changes.
+	mark := ctx.events.mark()
 	out, ret, err := deps.Send(context.Background(), innerCtx)
 	if err != nil {
+		ctx.events.revertTo(mark)
 		return nil, ret, err
 	}
 
@@ -239,6 +334,10 @@ func (ctx *Context) CreateNewActor(addr address.Address, code cid.Cid, initializ
 		return err
 	}
 
+	if ctx.trace != nil {
+		ctx.trace.recordChange(addr, StateChangeCreated, "", code.String())
+	}
+
 	return nil
 }
 
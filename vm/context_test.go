@@ -373,3 +373,93 @@ func TestVMContextRand(t *testing.T) {
 		assert.Equal([]byte(strconv.Itoa(0)), r)
 	})
 }
+
+func TestVMContextEmitEvent(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	newAddress := address.NewForTestGetter()
+	tree := state.NewCachedStateTree(&state.MockStateTree{})
+
+	msg := types.NewMessage(newAddress(), newAddress(), 0, nil, "foo", nil)
+	events := &EventLog{}
+	vmCtxParams := NewContextParams{
+		From:        actor.NewActor(cid.Undef, types.NewAttoFILFromFIL(100)),
+		To:          actor.NewActor(cid.Undef, types.NewAttoFILFromFIL(50)),
+		Message:     msg,
+		State:       tree,
+		StorageMap:  NewStorageMap(blockstore.NewBlockstore(datastore.NewMapDatastore())),
+		GasTracker:  NewGasTracker(),
+		BlockHeight: types.NewBlockHeight(0),
+		Events:      events,
+	}
+
+	sendEmitting := func(fail bool) func(context.Context, *Context) ([][]byte, uint8, error) {
+		return func(_ context.Context, vmCtx *Context) ([][]byte, uint8, error) {
+			require.NoError(vmCtx.EmitEvent("inner"))
+			if fail {
+				return nil, 1, errors.NewRevertError("failed")
+			}
+			return nil, 0, nil
+		}
+	}
+	deps := &deps{
+		EncodeValues: func(_ []*abi.Value) ([]byte, error) { return nil, nil },
+		GetOrCreateActor: func(_ context.Context, _ address.Address, f func() (*actor.Actor, error)) (*actor.Actor, error) {
+			return f()
+		},
+		ToValues: func(_ []interface{}) ([]*abi.Value, error) { return nil, nil },
+	}
+
+	vmCtx := NewVMContext(vmCtxParams)
+	vmCtx.deps = deps
+
+	require.NoError(vmCtx.EmitEvent("outer", uint64(7)))
+
+	deps.Send = sendEmitting(true)
+	_, _, err := vmCtx.Send(newAddress(), "bar", nil, nil)
+	require.Error(err)
+
+	deps.Send = sendEmitting(false)
+	_, _, err = vmCtx.Send(newAddress(), "bar", nil, nil)
+	require.NoError(err)
+
+	require.Len(events.Events(), 2)
+	outer := events.Events()[0]
+	assert.Equal(msg.To, outer.Actor)
+	assert.Equal("foo", outer.Method)
+	assert.Equal("outer", outer.Type)
+	values, err := abi.DecodeValues(outer.Data, []abi.Type{abi.SectorID})
+	require.NoError(err)
+	assert.Equal(uint64(7), values[0].Val)
+
+	inner := events.Events()[1]
+	assert.Equal("inner", inner.Type)
+	assert.Equal("bar", inner.Method)
+
+	// Without a log, events are dropped.
+	vmCtxParams.Events = nil
+	assert.NoError(NewVMContext(vmCtxParams).EmitEvent("dropped"))
+	assert.Len(events.Events(), 2)
+}
+
+func TestVMContextAbort(t *testing.T) {
+	assert := assert.New(t)
+
+	aborted := func(code uint8) (a abort) {
+		defer func() {
+			a = recover().(abort)
+		}()
+		(&Context{}).Abort(code, "aborted")
+		return
+	}
+
+	assert.Equal(abort{code: 43, msg: "aborted"}, aborted(43))
+
+	// reserved codes can't pass for VM failures
+	for _, code := range []uint8{0, errors.ErrInsufficientGas, errors.ErrMissingExport, errors.ReservedErrors} {
+		a := aborted(code)
+		assert.Equal(uint8(errors.ErrGeneric), a.code)
+		assert.Contains(a.msg, "reserved exit code")
+	}
+}
@@ -55,15 +55,29 @@ func NewCodedRevertErrorf(code uint8, format string, args ...interface{}) error
 	return NewCodedRevertError(code, fmt.Sprintf(format, args...))
 }
 
-// RevertErrorWrap wraps a given error in a RevertError.
+// RevertErrorWrap wraps a given error in a RevertError. It keeps the code of
+// err if err is a revert error.
 func RevertErrorWrap(err error, msg string) error {
-	return &RevertError{err: err, msg: msg}
+	return &RevertError{err: err, msg: msg, code: wrappedCode(err)}
 }
 
 // RevertErrorWrapf wraps a given error in a RevertError and adds a message
-// using Sprintf formatting.
+// using Sprintf formatting. It keeps the code of err if err is a revert error.
 func RevertErrorWrapf(err error, format string, args ...interface{}) error { // nolint: deadcode
-	return &RevertError{err: err, msg: fmt.Sprintf(format, args...), code: 1}
+	return &RevertError{err: err, msg: fmt.Sprintf(format, args...), code: wrappedCode(err)}
+}
+
+// CodedRevertErrorWrap wraps a given error in a RevertError with the given
+// code.
+func CodedRevertErrorWrap(code uint8, err error, msg string) error {
+	return &RevertError{err: err, msg: msg, code: code}
+}
+
+func wrappedCode(err error) uint8 {
+	if ShouldRevert(err) {
+		return CodeError(err)
+	}
+	return ErrGeneric
 }
 
 // Code returns the error code for this error
@@ -83,12 +97,28 @@ func ShouldRevert(err error) bool {
 	return ok && re.ShouldRevert()
 }
 
-// CodeError returns the RevertError's error code if it is a revert error, or 1 otherwise.
+// CodeError returns the RevertError's error code if it is a revert error, or
+// ErrGeneric otherwise. It looks at the root Cause().
 func CodeError(err error) uint8 {
-	if ShouldRevert(err) {
-		return err.(*RevertError).Code()
+	if re, ok := errors.Cause(err).(*RevertError); ok && re.code != 0 {
+		return re.code
+	}
+	return ErrGeneric
+}
+
+// RevertReason returns the reason of a revert error: the message of the
+// outermost RevertError, excluding the errors it wraps. Unlike Error(), it
+// doesn't depend on errors from outside the VM and so is the same on every
+// node, which lets it be recorded in receipts. It returns nil if err is not
+// a revert error.
+func RevertReason(err error) []byte {
+	if !ShouldRevert(err) {
+		return nil
+	}
+	if re, ok := errors.Cause(err).(*RevertError); ok && re.msg != "" {
+		return []byte(re.msg)
 	}
-	return 1
+	return nil
 }
 
 // FaultError is an error wrapper that signifies a system fault (corrupted
@@ -49,6 +49,66 @@ func TestRevertError(t *testing.T) {
 	assert.Equal(re, errors.Cause(wrapped2))
 }
 
+func TestRevertErrorCodes(t *testing.T) {
+	assert := assert.New(t)
+
+	coded := NewCodedRevertError(40, "coded")
+	assert.Equal(uint8(40), CodeError(coded))
+	assert.Equal(uint8(40), CodeError(errors.Wrap(coded, "wrapped")))
+	assert.Equal(uint8(40), CodeError(RevertErrorWrap(coded, "reverted")))
+	assert.Equal(uint8(40), CodeError(RevertErrorWrapf(coded, "%s", "reverted")))
+
+	assert.Equal(uint8(ErrGeneric), CodeError(NewRevertError("boom")))
+	assert.Equal(uint8(ErrGeneric), CodeError(RevertErrorWrap(errors.New("source"), "msg")))
+	assert.Equal(uint8(ErrGeneric), CodeError(errors.New("not a revert error")))
+	assert.Equal(uint8(ErrInvalidParams), CodeError(CodedRevertErrorWrap(ErrInvalidParams, errors.New("source"), "msg")))
+}
+
+func TestRevertReason(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(RevertReason(nil))
+	assert.Nil(RevertReason(errors.New("boom")))
+	assert.Nil(RevertReason(NewFaultError("boom")))
+
+	// The wrapped error is not part of the reason, only the revert message.
+	re := RevertErrorWrap(errors.New("source"), "msg")
+	assert.Equal([]byte("msg"), RevertReason(re))
+	assert.Equal([]byte("msg"), RevertReason(errors.Wrap(re, "wrapped")))
+}
+
+func TestVMExitCodesAreStable(t *testing.T) {
+	assert := assert.New(t)
+
+	// Exit codes are recorded in receipts, changing one forks the chain.
+	assert.Equal(1, ErrGeneric)
+	assert.Equal(3, ErrCannotTransferNegativeValue)
+	assert.Equal(4, ErrInsufficientBalance)
+	assert.Equal(5, ErrMissingExport)
+	assert.Equal(6, ErrNoActorCode)
+	assert.Equal(7, ErrInsufficientGas)
+	assert.Equal(8, ErrDecode)
+	assert.Equal(9, ErrDanglingPointer)
+	assert.Equal(10, ErrStaleHead)
+	assert.Equal(11, ErrInvalidParams)
+	assert.Equal(12, ErrInvalidReturn)
+	assert.Equal(13, ErrSenderNotFound)
+	assert.Equal(14, ErrNonceTooLow)
+	assert.Equal(15, ErrNonceTooHigh)
+	assert.Equal(16, ErrNonAccountSender)
+	assert.Equal(17, ErrInsufficientFundsForGas)
+	assert.Equal(18, ErrInvalidSignature)
+	assert.Equal(19, ErrGasAboveBlockLimit)
+	assert.Equal(20, ErrGasTooHighForCurrentBlock)
+	assert.Equal(21, ErrSelfSend)
+	assert.Equal(22, ErrUnknownAddressProtocol)
+
+	for code, err := range Errors {
+		assert.True(code <= ReservedErrors)
+		assert.Equal(code, CodeError(err))
+	}
+}
+
 func TestApplyErrorPermanent(t *testing.T) {
 	t.Run("random errors dont satisfy", func(t *testing.T) {
 		assert := assert.New(t)
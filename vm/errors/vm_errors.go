@@ -3,8 +3,12 @@ package errors
 // ReservedErrors is the highest error code that may not be used by actors
 const ReservedErrors = 32
 
+// Exit codes up to ReservedErrors are set by the VM and are the same for
+// every actor. They are recorded in receipts so their values must never
+// change; new codes are only ever appended.
 const (
-	_ = iota
+	// ErrGeneric is the exit code of a failure that has no more specific code.
+	ErrGeneric = iota + 1
 	_
 	// ErrCannotTransferNegativeValue is the error code for attempting to transfer a negative number
 	ErrCannotTransferNegativeValue
@@ -14,16 +18,68 @@ const (
 	ErrMissingExport
 	// ErrNoActorCode indicates the recipient's code could not be loaded.
 	ErrNoActorCode
+	// ErrInsufficientGas indicates the message ran out of gas.
+	ErrInsufficientGas
+	// ErrDecode indicates that a chunk an actor tried to write could not be decoded.
+	ErrDecode
+	// ErrDanglingPointer indicates that an actor attempted to commit a pointer to a non-existent chunk.
+	ErrDanglingPointer
+	// ErrStaleHead indicates that an actor attempted to commit over a stale chunk.
+	ErrStaleHead
+	// ErrInvalidParams indicates the params of a message don't match the method's signature.
+	ErrInvalidParams
+	// ErrInvalidReturn indicates a method returned values that could not be decoded.
+	ErrInvalidReturn
+	// ErrSenderNotFound indicates the sender of a message has no actor.
+	ErrSenderNotFound
+	// ErrNonceTooLow indicates the nonce of a message was already used.
+	ErrNonceTooLow
+	// ErrNonceTooHigh indicates the nonce of a message is ahead of the sender's.
+	ErrNonceTooHigh
+	// ErrNonAccountSender indicates a message was sent by an actor that is not an account.
+	ErrNonAccountSender
+	// ErrInsufficientFundsForGas indicates the sender can't pay for the value and gas of a message.
+	ErrInsufficientFundsForGas
+	// ErrInvalidSignature indicates the signature of a message is invalid.
+	ErrInvalidSignature
+	// ErrGasAboveBlockLimit indicates the gas limit of a message is above the block gas limit.
+	ErrGasAboveBlockLimit
+	// ErrGasTooHighForCurrentBlock indicates the gas limit of a message exceeds the gas left in the block.
+	ErrGasTooHighForCurrentBlock
+	// ErrSelfSend indicates a message sent by an actor to itself.
+	ErrSelfSend
+	// ErrUnknownAddressProtocol indicates a message sent to or from an address of a protocol the VM has no account creation policy for.
+	ErrUnknownAddressProtocol
+	// ErrActorPanic indicates the actor executing a message panicked, rather than aborting or returning an error.
+	ErrActorPanic
 )
 
 // Errors is a map from exit codes to errors.
 // Most errors should live in the actors that throw them. However some
 // errors will be pervasive so we define them centrally here.
 var Errors = map[uint8]error{
+	ErrGeneric:                     NewCodedRevertError(ErrGeneric, "message execution failed"),
 	ErrCannotTransferNegativeValue: NewCodedRevertError(ErrCannotTransferNegativeValue, "cannot transfer negative values"),
 	ErrInsufficientBalance:         NewCodedRevertError(ErrInsufficientBalance, "not enough balance"),
-	ErrMissingExport:               NewCodedRevertError(ErrInsufficientBalance, "actor does not export method"),
+	ErrMissingExport:               NewCodedRevertError(ErrMissingExport, "actor does not export method"),
 	ErrNoActorCode:                 NewCodedRevertError(ErrNoActorCode, "actor code not found"),
+	ErrInsufficientGas:             NewCodedRevertError(ErrInsufficientGas, "gas cost exceeds gas limit"),
+	ErrDecode:                      NewCodedRevertError(ErrDecode, "State could not be decoded"),
+	ErrDanglingPointer:             NewCodedRevertError(ErrDanglingPointer, "State contains pointer to non-existent chunk"),
+	ErrStaleHead:                   NewCodedRevertError(ErrStaleHead, "Expected head is stale"),
+	ErrInvalidParams:               NewCodedRevertError(ErrInvalidParams, "invalid params"),
+	ErrInvalidReturn:               NewCodedRevertError(ErrInvalidReturn, "method return doesn't decode as array"),
+	ErrSenderNotFound:              NewCodedRevertError(ErrSenderNotFound, "from (sender) account not found"),
+	ErrNonceTooLow:                 NewCodedRevertError(ErrNonceTooLow, "nonce too low"),
+	ErrNonceTooHigh:                NewCodedRevertError(ErrNonceTooHigh, "nonce too high"),
+	ErrNonAccountSender:            NewCodedRevertError(ErrNonAccountSender, "message from non-account actor"),
+	ErrInsufficientFundsForGas:     NewCodedRevertError(ErrInsufficientFundsForGas, "balance insufficient to cover transfer+gas"),
+	ErrInvalidSignature:            NewCodedRevertError(ErrInvalidSignature, "invalid signature by sender over message data"),
+	ErrGasAboveBlockLimit:          NewCodedRevertError(ErrGasAboveBlockLimit, "message gas limit above block gas limit"),
+	ErrGasTooHighForCurrentBlock:   NewCodedRevertError(ErrGasTooHighForCurrentBlock, "message gas limit too high for current block"),
+	ErrSelfSend:                    NewCodedRevertError(ErrSelfSend, "cannot send to self"),
+	ErrUnknownAddressProtocol:      NewCodedRevertError(ErrUnknownAddressProtocol, "no account creation policy for address protocol"),
+	ErrActorPanic:                  NewCodedRevertError(ErrActorPanic, "actor panicked"),
 }
 
 // VMExitCodeToError tries to locate an error in either the VM errors or the provide error map
@@ -0,0 +1,42 @@
+package vm
+
+import (
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// EventLog collects the events emitted while executing a message, including
+// those emitted by the calls it makes. A nil EventLog drops the events.
+type EventLog struct {
+	events []*types.Event
+}
+
+// Events returns the events in the order they were emitted.
+func (l *EventLog) Events() []*types.Event {
+	if l == nil {
+		return nil
+	}
+	return l.events
+}
+
+func (l *EventLog) emit(e *types.Event) {
+	if l == nil {
+		return
+	}
+	l.events = append(l.events, e)
+}
+
+// mark returns the position to revert to to drop the events emitted after
+// this call.
+func (l *EventLog) mark() int {
+	if l == nil {
+		return 0
+	}
+	return len(l.events)
+}
+
+func (l *EventLog) revertTo(mark int) {
+	if l == nil {
+		return
+	}
+	l.events = l.events[:mark]
+}
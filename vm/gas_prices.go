@@ -0,0 +1,177 @@
+package vm
+
+import (
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// PriceList is the gas cost of each operation the VM charges for. All costs
+// are integral gas units and are combined with integer arithmetic only, so
+// the gas used by a message is the same on every platform.
+type PriceList struct {
+	// SendBase is charged for every send, including the sends actors make.
+	SendBase types.GasUnits
+	// SendTransferFunds is charged in addition to SendBase for sends that
+	// transfer a non zero value.
+	SendTransferFunds types.GasUnits
+	// SendPerParamByte is charged per byte of the encoded params of a send.
+	SendPerParamByte types.GasUnits
+
+	// SignatureVerification is charged once for each signed message applied.
+	SignatureVerification types.GasUnits
+
+	// SealVerification is charged for each sector whose seal proof an actor
+	// verifies, so that committing sectors in a batch costs the same per
+	// sector as committing them one at a time.
+	SealVerification types.GasUnits
+
+	// StorageGetBase and StorageGetPerByte are charged for each chunk an
+	// actor reads from its storage.
+	StorageGetBase    types.GasUnits
+	StorageGetPerByte types.GasUnits
+	// StoragePutBase and StoragePutPerByte are charged for each chunk an
+	// actor writes to its storage.
+	StoragePutBase    types.GasUnits
+	StoragePutPerByte types.GasUnits
+
+	// EventBase and EventPerByte are charged for each event an actor emits.
+	EventBase    types.GasUnits
+	EventPerByte types.GasUnits
+
+	// ActorMethods is the cost of invoking the methods of an actor, by actor
+	// code. Methods of actors whose code is missing are not charged for,
+	// they are expected to charge for themselves.
+	ActorMethods map[cid.Cid]MethodPrices
+}
+
+// MethodPrices is the cost of invoking the methods of an actor.
+type MethodPrices struct {
+	// Default is the cost of a method missing from Methods.
+	Default types.GasUnits
+	Methods map[string]types.GasUnits
+}
+
+// OnSend returns the gas to charge for sending msg, excluding the cost of
+// the method it invokes.
+func (pl *PriceList) OnSend(msg *types.Message) types.GasUnits {
+	cost := pl.SendBase + pl.SendPerParamByte*types.GasUnits(len(msg.Params))
+	if msg.Value != nil && !msg.Value.IsZero() {
+		cost += pl.SendTransferFunds
+	}
+	return cost
+}
+
+// OnMethodInvocation returns the gas to charge for invoking method on an
+// actor with the given code.
+func (pl *PriceList) OnMethodInvocation(code cid.Cid, method string) types.GasUnits {
+	prices, ok := pl.ActorMethods[code]
+	if !ok {
+		return 0
+	}
+	if cost, ok := prices.Methods[method]; ok {
+		return cost
+	}
+	return prices.Default
+}
+
+// OnSealVerification returns the gas to charge for verifying the seal proofs
+// of the given number of sectors.
+func (pl *PriceList) OnSealVerification(sectors int) types.GasUnits {
+	return pl.SealVerification * types.GasUnits(sectors)
+}
+
+// OnStorageGet returns the gas to charge for reading a chunk of size bytes.
+func (pl *PriceList) OnStorageGet(size int) types.GasUnits {
+	return pl.StorageGetBase + pl.StorageGetPerByte*types.GasUnits(size)
+}
+
+// OnStoragePut returns the gas to charge for writing a chunk of size bytes.
+func (pl *PriceList) OnStoragePut(size int) types.GasUnits {
+	return pl.StoragePutBase + pl.StoragePutPerByte*types.GasUnits(size)
+}
+
+// OnEmitEvent returns the gas to charge for emitting an event whose values
+// encode to size bytes.
+func (pl *PriceList) OnEmitEvent(size int) types.GasUnits {
+	return pl.EventBase + pl.EventPerByte*types.GasUnits(size)
+}
+
+// PriceSchedule lists the price lists of the protocol ordered by the block
+// height from which they apply. The first entry must apply from genesis.
+type PriceSchedule []struct {
+	Height *types.BlockHeight
+	Prices *PriceList
+}
+
+// PricesAt returns the price list that applies at block height bh. A nil bh
+// gets the genesis prices.
+func (ps PriceSchedule) PricesAt(bh *types.BlockHeight) *PriceList {
+	prices := ps[0].Prices
+	if bh == nil {
+		return prices
+	}
+	for _, entry := range ps[1:] {
+		if bh.LessThan(entry.Height) {
+			break
+		}
+		prices = entry.Prices
+	}
+	return prices
+}
+
+// builtinMethodPrices prices the methods of every builtin actor the same.
+func builtinMethodPrices(prices MethodPrices) map[cid.Cid]MethodPrices {
+	return map[cid.Cid]MethodPrices{
+		types.StorageMarketActorCodeCid:  prices,
+		types.PaymentBrokerActorCodeCid:  prices,
+		types.MinerActorCodeCid:          prices,
+		types.BootstrapMinerActorCodeCid: prices,
+		types.MultisigActorCodeCid:       prices,
+		types.RewardActorCodeCid:         prices,
+		types.CronActorCodeCid:           prices,
+	}
+}
+
+// GenesisPrices is the price list in effect from genesis. It only charges a
+// flat fee for invoking the methods of the builtin actors.
+var GenesisPrices = &PriceList{
+	ActorMethods: builtinMethodPrices(MethodPrices{Default: 100}),
+}
+
+// OperationPrices is the price list that also charges for the operations a
+// message performs: sends, signature verification, storage access and events.
+var OperationPrices = &PriceList{
+	SendBase:              10,
+	SendTransferFunds:     10,
+	SendPerParamByte:      1,
+	SignatureVerification: 20,
+	SealVerification:      900,
+	StorageGetBase:        5,
+	StorageGetPerByte:     1,
+	StoragePutBase:        10,
+	StoragePutPerByte:     2,
+	EventBase:             10,
+	EventPerByte:          1,
+	ActorMethods: builtinMethodPrices(MethodPrices{
+		Default: 100,
+		Methods: map[string]types.GasUnits{
+			// commitSector and commitSectors pay the default on top of
+			// SealVerification for each sector they commit.
+			"submitPoSt": 1000,
+			// These verify a voucher signature.
+			"redeem": 120,
+			"close":  120,
+		},
+	}),
+}
+
+// OperationPricesHeight is the height of the protocol upgrade from which
+// OperationPrices apply.
+var OperationPricesHeight = types.NewBlockHeight(100000)
+
+// Prices is the price schedule of the protocol.
+var Prices = PriceSchedule{
+	{Height: types.NewBlockHeight(0), Prices: GenesisPrices},
+	{Height: OperationPricesHeight, Prices: OperationPrices},
+}
@@ -0,0 +1,224 @@
+package vm
+
+import (
+	"context"
+	"testing"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	"gx/ipfs/QmRXf2uUSdGSunRJsM9wXSUNVwLUGCY3So5fAs7h2CBJVf/go-hamt-ipld"
+	cbor "gx/ipfs/QmRoARq3nkUb13HSKZGepCZSWe5GrVPwx7xURJGZ7KWv9V/go-ipld-cbor"
+	"gx/ipfs/QmS2aqUZLJp8kF1ihE5rvDGE5LvmKDPnx32w9Z1BW9xLV5/go-ipfs-blockstore"
+	"gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/account"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/state"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/vm/errors"
+)
+
+func TestPriceScheduleAt(t *testing.T) {
+	assert := assert.New(t)
+
+	first, second, third := &PriceList{SendBase: 1}, &PriceList{SendBase: 2}, &PriceList{SendBase: 3}
+	schedule := PriceSchedule{
+		{Height: types.NewBlockHeight(0), Prices: first},
+		{Height: types.NewBlockHeight(10), Prices: second},
+		{Height: types.NewBlockHeight(20), Prices: third},
+	}
+
+	assert.Equal(first, schedule.PricesAt(nil))
+	assert.Equal(first, schedule.PricesAt(types.NewBlockHeight(0)))
+	assert.Equal(first, schedule.PricesAt(types.NewBlockHeight(9)))
+	assert.Equal(second, schedule.PricesAt(types.NewBlockHeight(10)))
+	assert.Equal(second, schedule.PricesAt(types.NewBlockHeight(19)))
+	assert.Equal(third, schedule.PricesAt(types.NewBlockHeight(20)))
+	assert.Equal(third, schedule.PricesAt(types.NewBlockHeight(1000000)))
+
+	assert.Equal(GenesisPrices, Prices.PricesAt(types.NewBlockHeight(0)))
+	assert.Equal(OperationPrices, Prices.PricesAt(OperationPricesHeight))
+}
+
+func TestPriceListCosts(t *testing.T) {
+	// The expected costs are spelled out so that a change to the price lists,
+	// which would fork the chain, has to be made here too.
+	addrGetter := address.NewForTestGetter()
+
+	t.Run("genesis prices only charge for builtin methods", func(t *testing.T) {
+		assert := assert.New(t)
+
+		msg := types.NewMessage(addrGetter(), addrGetter(), 0, types.NewAttoFILFromFIL(1), "addAsk", []byte{1, 2, 3})
+		assert.Equal(types.NewGasUnits(0), GenesisPrices.OnSend(msg))
+		assert.Equal(types.NewGasUnits(0), GenesisPrices.OnStorageGet(100))
+		assert.Equal(types.NewGasUnits(0), GenesisPrices.OnStoragePut(100))
+		assert.Equal(types.NewGasUnits(0), GenesisPrices.SignatureVerification)
+		assert.Equal(types.NewGasUnits(0), GenesisPrices.OnEmitEvent(100))
+		assert.Equal(types.NewGasUnits(0), GenesisPrices.OnSealVerification(3))
+
+		assert.Equal(types.NewGasUnits(100), GenesisPrices.OnMethodInvocation(types.MinerActorCodeCid, "addAsk"))
+		assert.Equal(types.NewGasUnits(100), GenesisPrices.OnMethodInvocation(types.MinerActorCodeCid, "submitPoSt"))
+		assert.Equal(types.NewGasUnits(100), GenesisPrices.OnMethodInvocation(types.PaymentBrokerActorCodeCid, "redeem"))
+		assert.Equal(types.NewGasUnits(0), GenesisPrices.OnMethodInvocation(types.AccountActorCodeCid, "foo"))
+		assert.Equal(types.NewGasUnits(0), GenesisPrices.OnMethodInvocation(types.SomeCid(), "foo"))
+	})
+
+	t.Run("operation prices", func(t *testing.T) {
+		assert := assert.New(t)
+
+		withValue := types.NewMessage(addrGetter(), addrGetter(), 0, types.NewAttoFILFromFIL(1), "", []byte{1, 2, 3})
+		assert.Equal(types.NewGasUnits(23), OperationPrices.OnSend(withValue))
+		zeroValue := types.NewMessage(addrGetter(), addrGetter(), 0, types.ZeroAttoFIL, "", nil)
+		assert.Equal(types.NewGasUnits(10), OperationPrices.OnSend(zeroValue))
+
+		assert.Equal(types.NewGasUnits(105), OperationPrices.OnStorageGet(100))
+		assert.Equal(types.NewGasUnits(210), OperationPrices.OnStoragePut(100))
+		assert.Equal(types.NewGasUnits(20), OperationPrices.SignatureVerification)
+		assert.Equal(types.NewGasUnits(110), OperationPrices.OnEmitEvent(100))
+
+		assert.Equal(types.NewGasUnits(100), OperationPrices.OnMethodInvocation(types.MinerActorCodeCid, "addAsk"))
+		assert.Equal(types.NewGasUnits(1000), OperationPrices.OnMethodInvocation(types.BootstrapMinerActorCodeCid, "submitPoSt"))
+		assert.Equal(types.NewGasUnits(120), OperationPrices.OnMethodInvocation(types.PaymentBrokerActorCodeCid, "redeem"))
+		assert.Equal(types.NewGasUnits(0), OperationPrices.OnMethodInvocation(types.SomeCid(), "foo"))
+	})
+
+	t.Run("committing sectors is charged per sector", func(t *testing.T) {
+		assert := assert.New(t)
+
+		commitSector := OperationPrices.OnMethodInvocation(types.MinerActorCodeCid, "commitSector") + OperationPrices.OnSealVerification(1)
+		assert.Equal(types.NewGasUnits(1000), commitSector)
+
+		for _, sectors := range []int{1, 2, 10} {
+			commitSectors := OperationPrices.OnMethodInvocation(types.MinerActorCodeCid, "commitSectors") + OperationPrices.OnSealVerification(sectors)
+			assert.Equal(types.NewGasUnits(100+900*uint64(sectors)), commitSectors)
+		}
+	})
+}
+
+func TestVMChargesForOperations(t *testing.T) {
+	addrGetter := address.NewForTestGetter()
+	ctx := context.Background()
+
+	newContext := func(t *testing.T, bh *types.BlockHeight, gasLimit uint64, msg *types.Message) *Context {
+		st := state.NewEmptyStateTree(hamt.NewCborStore())
+		cstate := state.NewCachedStateTree(st)
+
+		to, err := account.NewActor(types.NewAttoFILFromFIL(0))
+		require.NoError(t, err)
+		require.NoError(t, st.SetActor(ctx, msg.To, to))
+		to, err = cstate.GetActor(ctx, msg.To)
+		require.NoError(t, err)
+
+		gasTracker := NewGasTracker()
+		gasTracker.MsgGasLimit = types.NewGasUnits(gasLimit)
+
+		return NewVMContext(NewContextParams{
+			From:        actor.NewActor(cid.Undef, types.NewAttoFILFromFIL(100)),
+			To:          to,
+			Message:     msg,
+			State:       cstate,
+			StorageMap:  NewStorageMap(blockstore.NewBlockstore(datastore.NewMapDatastore())),
+			GasTracker:  gasTracker,
+			BlockHeight: bh,
+		})
+	}
+
+	t.Run("storage is free at genesis prices", func(t *testing.T) {
+		assert := assert.New(t)
+
+		msg := types.NewMessage(addrGetter(), addrGetter(), 0, nil, "", nil)
+		vmCtx := newContext(t, types.NewBlockHeight(0), 0, msg)
+
+		node, err := cbor.WrapObject([]byte("hello"), types.DefaultHashFunction, -1)
+		require.NoError(t, err)
+		assert.NoError(vmCtx.WriteStorage(node.RawData()))
+		_, err = vmCtx.ReadStorage()
+		assert.NoError(err)
+		assert.Equal(types.NewGasUnits(0), vmCtx.GasUnits())
+	})
+
+	t.Run("storage reads and writes are charged by size", func(t *testing.T) {
+		assert := assert.New(t)
+
+		msg := types.NewMessage(addrGetter(), addrGetter(), 0, nil, "", nil)
+		vmCtx := newContext(t, OperationPricesHeight, 1000, msg)
+
+		node, err := cbor.WrapObject([]byte("hello"), types.DefaultHashFunction, -1)
+		require.NoError(t, err)
+		require.Equal(t, 6, len(node.RawData()))
+
+		assert.NoError(vmCtx.WriteStorage(node.RawData()))
+		assert.Equal(types.NewGasUnits(10+2*6), vmCtx.GasUnits())
+
+		_, err = vmCtx.ReadStorage()
+		assert.NoError(err)
+		assert.Equal(types.NewGasUnits(10+2*6+5+6), vmCtx.GasUnits())
+	})
+
+	t.Run("storage writes fail when out of gas", func(t *testing.T) {
+		assert := assert.New(t)
+
+		msg := types.NewMessage(addrGetter(), addrGetter(), 0, nil, "", nil)
+		vmCtx := newContext(t, OperationPricesHeight, 20, msg)
+
+		node, err := cbor.WrapObject([]byte("hello"), types.DefaultHashFunction, -1)
+		require.NoError(t, err)
+
+		err = vmCtx.WriteStorage(node.RawData())
+		assert.Error(err)
+		assert.True(errors.ShouldRevert(err))
+	})
+
+	t.Run("sends are charged for their value and params", func(t *testing.T) {
+		assert := assert.New(t)
+
+		msg := types.NewMessage(addrGetter(), addrGetter(), 0, types.NewAttoFILFromFIL(1), "", []byte{1, 2})
+		vmCtx := newContext(t, OperationPricesHeight, 1000, msg)
+
+		_, code, err := Send(ctx, vmCtx)
+		assert.NoError(err)
+		assert.Equal(uint8(0), code)
+		assert.Equal(types.NewGasUnits(10+10+2), vmCtx.GasUnits())
+	})
+
+	t.Run("sends fail when out of gas", func(t *testing.T) {
+		assert := assert.New(t)
+
+		msg := types.NewMessage(addrGetter(), addrGetter(), 0, types.NewAttoFILFromFIL(1), "", nil)
+		vmCtx := newContext(t, OperationPricesHeight, 5, msg)
+
+		_, _, err := Send(ctx, vmCtx)
+		assert.Error(err)
+		assert.True(errors.ShouldRevert(err))
+	})
+
+	t.Run("signature verification is charged", func(t *testing.T) {
+		assert := assert.New(t)
+
+		msg := types.NewMessage(addrGetter(), addrGetter(), 0, nil, "", nil)
+		vmCtx := newContext(t, OperationPricesHeight, 1000, msg)
+		assert.NoError(vmCtx.ChargeSignatureVerification())
+		assert.Equal(types.NewGasUnits(20), vmCtx.GasUnits())
+
+		vmCtx = newContext(t, types.NewBlockHeight(0), 0, msg)
+		assert.NoError(vmCtx.ChargeSignatureVerification())
+		assert.Equal(types.NewGasUnits(0), vmCtx.GasUnits())
+	})
+
+	t.Run("seal verification is charged per sector", func(t *testing.T) {
+		assert := assert.New(t)
+
+		msg := types.NewMessage(addrGetter(), addrGetter(), 0, nil, "", nil)
+		vmCtx := newContext(t, OperationPricesHeight, 10000, msg)
+		assert.NoError(vmCtx.ChargeSealVerification(3))
+		assert.Equal(types.NewGasUnits(2700), vmCtx.GasUnits())
+
+		vmCtx = newContext(t, OperationPricesHeight, 1000, msg)
+		err := vmCtx.ChargeSealVerification(2)
+		assert.Error(err)
+		assert.True(errors.ShouldRevert(err))
+	})
+}
@@ -32,7 +32,7 @@ func (gasTracker *GasTracker) Charge(cost types.GasUnits) error {
 	if gasTracker.gasConsumedByMessage+cost > gasTracker.MsgGasLimit {
 		gasTracker.gasConsumedByMessage = gasTracker.MsgGasLimit
 		gasTracker.gasConsumedByBlock += gasTracker.MsgGasLimit
-		return errors.NewRevertError("gas cost exceeds gas limit")
+		return errors.Errors[errors.ErrInsufficientGas]
 	}
 
 	gasTracker.gasConsumedByMessage += cost
@@ -40,6 +40,12 @@ func (gasTracker *GasTracker) Charge(cost types.GasUnits) error {
 	return nil
 }
 
+// Merge adds the gas consumed for the block by other, e.g. while executing a
+// message apart from the block, to the gas consumed for the block.
+func (gasTracker *GasTracker) Merge(other *GasTracker) {
+	gasTracker.gasConsumedByBlock += other.gasConsumedByBlock
+}
+
 // GasAboveBlockLimit will return true if the MsgGasLimit of the current message is greater than the block gas limit.
 func (gasTracker *GasTracker) GasAboveBlockLimit() bool {
 	return gasTracker.MsgGasLimit > types.BlockGasLimit
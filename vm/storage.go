@@ -37,6 +37,7 @@ type storageMap struct {
 type StorageMap interface {
 	NewStorage(addr address.Address, actor *actor.Actor) Storage
 	Flush() error
+	Fork() StorageMap
 }
 
 var _ StorageMap = &storageMap{}
@@ -72,6 +73,12 @@ func (s *storageMap) NewStorage(addr address.Address, actor *actor.Actor) Storag
 	return storage
 }
 
+// Fork returns an empty StorageMap staging chunks apart from s over the same
+// blockstore. Chunks staged in s are only visible to it once flushed.
+func (s *storageMap) Fork() StorageMap {
+	return NewStorageMap(s.blockstore)
+}
+
 // Flush saves all valid staged changes to the datastore
 func (s *storageMap) Flush() error {
 	for _, storage := range s.storageMap {
@@ -243,3 +250,39 @@ func (s Storage) liveDescendantIds(id cid.Cid) (*cid.Set, error) {
 
 	return ids, nil
 }
+
+// meteredStorage charges the gas of a context for the chunks read from and
+// written to a storage.
+type meteredStorage struct {
+	exec.Storage
+	ctx *Context
+}
+
+// Put adds a node to temporary storage, charging for its size.
+func (ms *meteredStorage) Put(v interface{}) (cid.Cid, error) {
+	c, err := ms.Storage.Put(v)
+	if err != nil {
+		return cid.Undef, err
+	}
+	// The chunk was just staged, reading it back to learn its size is cheap.
+	chunk, err := ms.Storage.Get(c)
+	if err != nil {
+		return cid.Undef, err
+	}
+	if err := ms.ctx.chargeOperation(ms.ctx.prices.OnStoragePut(len(chunk))); err != nil {
+		return cid.Undef, vmerrors.RevertErrorWrap(err, "Insufficient gas")
+	}
+	return c, nil
+}
+
+// Get retrieves a chunk, charging for its size.
+func (ms *meteredStorage) Get(c cid.Cid) ([]byte, error) {
+	chunk, err := ms.Storage.Get(c)
+	if err != nil {
+		return chunk, err
+	}
+	if err := ms.ctx.chargeOperation(ms.ctx.prices.OnStorageGet(len(chunk))); err != nil {
+		return nil, vmerrors.RevertErrorWrap(err, "Insufficient gas")
+	}
+	return chunk, nil
+}
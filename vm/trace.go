@@ -0,0 +1,109 @@
+package vm
+
+import (
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// State change kinds recorded in a Trace.
+const (
+	// StateChangeBalance marks a change of an actor's balance.
+	StateChangeBalance = "balance"
+	// StateChangeHead marks a change of the head of an actor's storage.
+	StateChangeHead = "head"
+	// StateChangeCreated marks the creation of an actor, New is its code.
+	StateChangeCreated = "created"
+)
+
+// StateChange is a change made to an actor while executing a message.
+type StateChange struct {
+	Actor address.Address `json:"actor"`
+	Kind  string          `json:"kind"`
+	Old   string          `json:"old,omitempty"`
+	New   string          `json:"new"`
+}
+
+// Trace records the execution of a message by the VM, including the messages
+// the called actor sends in turn. It is filled in by Send when set in the
+// NewContextParams. The balance and head changes of a call are the net
+// changes to its sender and receiver, including those made by its subcalls.
+// Changes recorded by a call that fails are rolled back by the caller, they
+// are kept in the trace for debugging.
+type Trace struct {
+	From   address.Address `json:"from"`
+	To     address.Address `json:"to"`
+	Method string          `json:"method,omitempty"`
+	Value  *types.AttoFIL  `json:"value,omitempty"`
+	Params types.Bytes     `json:"params,omitempty"`
+
+	// GasCharges lists the gas charged by the called method, in order.
+	GasCharges []types.GasUnits `json:"gasCharges,omitempty"`
+	// GasUsed is the gas used by the call, including the calls it made.
+	GasUsed types.GasUnits `json:"gasUsed"`
+
+	StateChanges []*StateChange `json:"stateChanges,omitempty"`
+	Subcalls     []*Trace       `json:"subcalls,omitempty"`
+
+	ExitCode uint8         `json:"exitCode"`
+	Error    string        `json:"error,omitempty"`
+	Return   []types.Bytes `json:"return,omitempty"`
+}
+
+// traceSend runs send, recording its execution in vmCtx.trace.
+func traceSend(vmCtx *Context, send func() ([][]byte, uint8, error)) ([][]byte, uint8, error) {
+	trace := vmCtx.trace
+	msg := vmCtx.message
+	trace.From = msg.From
+	trace.To = msg.To
+	trace.Method = msg.Method
+	trace.Value = msg.Value
+	trace.Params = msg.Params
+
+	fromBalance := balanceOf(vmCtx.from)
+	toBalance := balanceOf(vmCtx.to)
+	toHead := vmCtx.to.Head
+	gasBefore := vmCtx.gasTracker.gasConsumedByMessage
+
+	ret, code, err := send()
+
+	trace.recordChange(msg.From, StateChangeBalance, fromBalance, balanceOf(vmCtx.from))
+	trace.recordChange(msg.To, StateChangeBalance, toBalance, balanceOf(vmCtx.to))
+	if !toHead.Equals(vmCtx.to.Head) {
+		var old string
+		if toHead.Defined() {
+			old = toHead.String()
+		}
+		trace.StateChanges = append(trace.StateChanges, &StateChange{
+			Actor: msg.To,
+			Kind:  StateChangeHead,
+			Old:   old,
+			New:   vmCtx.to.Head.String(),
+		})
+	}
+
+	trace.GasUsed = vmCtx.gasTracker.gasConsumedByMessage - gasBefore
+	trace.ExitCode = code
+	if err != nil {
+		trace.Error = err.Error()
+	}
+	for _, r := range ret {
+		trace.Return = append(trace.Return, r)
+	}
+
+	return ret, code, err
+}
+
+func (t *Trace) recordChange(addr address.Address, kind string, oldVal, newVal string) {
+	if oldVal == newVal {
+		return
+	}
+	t.StateChanges = append(t.StateChanges, &StateChange{Actor: addr, Kind: kind, Old: oldVal, New: newVal})
+}
+
+func balanceOf(act *actor.Actor) string {
+	if act == nil || act.Balance == nil {
+		return types.ZeroAttoFIL.String()
+	}
+	return act.Balance.String()
+}
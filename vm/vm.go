@@ -5,13 +5,19 @@ package vm
 
 import (
 	"context"
+	"runtime/debug"
+
 	cbor "gx/ipfs/QmRoARq3nkUb13HSKZGepCZSWe5GrVPwx7xURJGZ7KWv9V/go-ipld-cbor"
+	logging "gx/ipfs/QmcuXC5cxs79ro2cUuHs4HQ2bkDLJUYokwL8aivcX6HW3C/go-log"
 
 	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/exec"
 	"github.com/filecoin-project/go-filecoin/types"
 	"github.com/filecoin-project/go-filecoin/vm/errors"
 )
 
+var log = logging.Logger("vm")
+
 // Send executes a message pass inside the VM. If error is set it
 // will always satisfy either ShouldRevert() or IsFault().
 func Send(ctx context.Context, vmCtx *Context) ([][]byte, uint8, error) {
@@ -19,6 +25,11 @@ func Send(ctx context.Context, vmCtx *Context) ([][]byte, uint8, error) {
 		transfer: Transfer,
 	}
 
+	if vmCtx.trace != nil {
+		return traceSend(vmCtx, func() ([][]byte, uint8, error) {
+			return send(ctx, deps, vmCtx)
+		})
+	}
 	return send(ctx, deps, vmCtx)
 }
 
@@ -28,6 +39,10 @@ type sendDeps struct {
 
 // send executes a message pass inside the VM. It exists alongside Send so that we can inject its dependencies during test.
 func send(ctx context.Context, deps sendDeps, vmCtx *Context) ([][]byte, uint8, error) {
+	if err := vmCtx.chargeOperation(vmCtx.prices.OnSend(vmCtx.message)); err != nil {
+		return nil, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
 	if vmCtx.message.Value != nil {
 		if err := deps.transfer(vmCtx.from, vmCtx.to, vmCtx.message.Value); err != nil {
 			if errors.ShouldRevert(err) {
@@ -49,21 +64,46 @@ func send(ctx context.Context, deps sendDeps, vmCtx *Context) ([][]byte, uint8,
 	}
 
 	if !toExecutable.Exports().Has(vmCtx.message.Method) {
-		return nil, 1, errors.Errors[errors.ErrMissingExport]
+		return nil, errors.ErrMissingExport, errors.Errors[errors.ErrMissingExport]
+	}
+
+	if err := vmCtx.chargeOperation(vmCtx.prices.OnMethodInvocation(vmCtx.to.Code, vmCtx.message.Method)); err != nil {
+		return nil, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
 	}
 
-	r, code, err := actor.MakeTypedExport(toExecutable, vmCtx.message.Method)(vmCtx)
+	r, code, err := invoke(toExecutable, vmCtx)
 	if r != nil {
 		var rv [][]byte
 		err = cbor.DecodeInto(r, &rv)
 		if err != nil {
-			return nil, 1, errors.NewRevertErrorf("method return doesn't decode as array: %s", err)
+			return nil, errors.ErrInvalidReturn, errors.CodedRevertErrorWrap(errors.ErrInvalidReturn, err, "method return doesn't decode as array")
 		}
 		return rv, code, err
 	}
 	return nil, code, err
 }
 
+// invoke calls the method of the message on the actor. An actor aborting
+// reverts the message with the code it aborted with. Any other panic is a bug
+// in the actor: it is logged and reverts the message with ErrActorPanic, so
+// that every node records the same receipt instead of crashing.
+func invoke(toExecutable exec.ExecutableActor, vmCtx *Context) (r []byte, code uint8, err error) {
+	defer func() {
+		p := recover()
+		if p == nil {
+			return
+		}
+		if a, ok := p.(abort); ok {
+			r, code, err = nil, a.code, errors.NewCodedRevertError(a.code, a.msg)
+			return
+		}
+		log.Errorf("actor %s panicked executing method %s: %v\n%s", vmCtx.message.To, vmCtx.message.Method, p, debug.Stack())
+		r, code, err = nil, errors.ErrActorPanic, errors.Errors[errors.ErrActorPanic]
+	}()
+
+	return actor.Dispatch(toExecutable, vmCtx.message.Method)(vmCtx)
+}
+
 // Transfer transfers the given value between two actors.
 func Transfer(fromActor, toActor *actor.Actor, value *types.AttoFIL) error {
 	if value.IsNegative() {
@@ -110,7 +110,7 @@ func TestSendErrorHandling(t *testing.T) {
 		assert.True(errors.ShouldRevert(sendErr))
 	})
 
-	t.Run("returns exit code 1 and a revert error if code doesn't export a matching method", func(t *testing.T) {
+	t.Run("returns right exit code and a revert error if code doesn't export a matching method", func(t *testing.T) {
 		assert := assert.New(t)
 
 		msg := newMsg()
@@ -138,7 +138,7 @@ func TestSendErrorHandling(t *testing.T) {
 		_, code, sendErr := send(context.Background(), deps, vmCtx)
 
 		assert.Error(sendErr)
-		assert.Equal(1, int(code))
+		assert.Equal(errors.ErrMissingExport, int(code))
 		assert.True(errors.ShouldRevert(sendErr))
 	})
 }
@@ -0,0 +1,163 @@
+package wallet
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	ds "gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
+	dsq "gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore/query"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/repo"
+)
+
+// addressBookPrefix is the datastore key prefix under which labels are stored.
+const addressBookPrefix = "/addressbook/"
+
+// ErrUnknownLabel is returned when resolving a name that is not in the
+// address book.
+var ErrUnknownLabel = errors.New("unknown address label")
+
+// AddressBook maps human readable names to addresses, so that users can
+// refer to addresses by name. Labels are persisted in the repo's datastore.
+type AddressBook struct {
+	lk sync.Mutex
+	ds repo.Datastore
+}
+
+// NewAddressBook returns an address book storing its labels in ds.
+func NewAddressBook(ds repo.Datastore) *AddressBook {
+	return &AddressBook{ds: ds}
+}
+
+// Label gives addr the given name. A name refers to a single address, so
+// relabelling a name to a different address fails unless force is set.
+func (ab *AddressBook) Label(addr address.Address, name string, force bool) error {
+	if err := validateLabel(name); err != nil {
+		return err
+	}
+
+	ab.lk.Lock()
+	defer ab.lk.Unlock()
+
+	key := labelKey(name)
+	if !force {
+		existing, err := ab.ds.Get(key)
+		switch err {
+		case nil:
+			if string(existing) != addr.String() {
+				return errors.Errorf("label %q already refers to %s", name, string(existing))
+			}
+		case ds.ErrNotFound:
+		default:
+			return errors.Wrap(err, "failed to read address book")
+		}
+	}
+
+	return errors.Wrap(ab.ds.Put(key, []byte(addr.String())), "failed to write address book")
+}
+
+// Unlabel removes name from the address book.
+func (ab *AddressBook) Unlabel(name string) error {
+	ab.lk.Lock()
+	defer ab.lk.Unlock()
+
+	key := labelKey(name)
+	has, err := ab.ds.Has(key)
+	if err != nil {
+		return errors.Wrap(err, "failed to read address book")
+	}
+	if !has {
+		return ErrUnknownLabel
+	}
+
+	return errors.Wrap(ab.ds.Delete(key), "failed to write address book")
+}
+
+// Lookup returns the address labelled with name.
+func (ab *AddressBook) Lookup(name string) (address.Address, error) {
+	ab.lk.Lock()
+	defer ab.lk.Unlock()
+
+	raw, err := ab.ds.Get(labelKey(name))
+	if err == ds.ErrNotFound {
+		return address.Address{}, ErrUnknownLabel
+	}
+	if err != nil {
+		return address.Address{}, errors.Wrap(err, "failed to read address book")
+	}
+
+	return address.NewFromString(string(raw))
+}
+
+// Resolve returns the address s refers to: s itself if it is an address,
+// otherwise the address labelled with s.
+func (ab *AddressBook) Resolve(s string) (address.Address, error) {
+	if addr, err := address.NewFromString(s); err == nil {
+		return addr, nil
+	}
+
+	addr, err := ab.Lookup(s)
+	if err != nil {
+		return address.Address{}, errors.Wrapf(err, "%q is neither an address nor a known label", s)
+	}
+	return addr, nil
+}
+
+// AddressLabel is an entry of the address book.
+type AddressLabel struct {
+	Name    string
+	Address address.Address
+}
+
+// Labels lists the entries of the address book, sorted by name.
+func (ab *AddressBook) Labels() ([]AddressLabel, error) {
+	ab.lk.Lock()
+	defer ab.lk.Unlock()
+
+	res, err := ab.ds.Query(dsq.Query{Prefix: addressBookPrefix})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query address book")
+	}
+
+	entries, err := res.Rest()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read address book")
+	}
+
+	out := make([]AddressLabel, 0, len(entries))
+	for _, e := range entries {
+		addr, err := address.NewFromString(string(e.Value))
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid address stored for label %s", e.Key)
+		}
+		out = append(out, AddressLabel{
+			Name:    strings.TrimPrefix(e.Key, addressBookPrefix),
+			Address: addr,
+		})
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+// validateLabel checks that name can be stored and can't be mistaken for an
+// address.
+func validateLabel(name string) error {
+	if name == "" {
+		return errors.New("label must not be empty")
+	}
+	if strings.ContainsAny(name, "/ \t\n") {
+		return errors.Errorf("label %q must not contain slashes or whitespace", name)
+	}
+	if _, err := address.NewFromString(name); err == nil {
+		return errors.Errorf("label %q must not be an address", name)
+	}
+	return nil
+}
+
+func labelKey(name string) ds.Key {
+	return ds.NewKey(addressBookPrefix + name)
+}
@@ -0,0 +1,83 @@
+package wallet
+
+import (
+	"testing"
+
+	"gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/address"
+)
+
+func TestAddressBook(t *testing.T) {
+	t.Parallel()
+
+	addrGetter := address.NewForTestGetter()
+	alice, bob := addrGetter(), addrGetter()
+
+	t.Run("resolves labels and addresses", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		ab := NewAddressBook(datastore.NewMapDatastore())
+		require.NoError(ab.Label(alice, "alice", false))
+
+		addr, err := ab.Resolve("alice")
+		require.NoError(err)
+		assert.Equal(alice, addr)
+
+		addr, err = ab.Resolve(bob.String())
+		require.NoError(err)
+		assert.Equal(bob, addr)
+
+		_, err = ab.Resolve("bob")
+		assert.Error(err)
+	})
+
+	t.Run("refuses to silently relabel", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		ab := NewAddressBook(datastore.NewMapDatastore())
+		require.NoError(ab.Label(alice, "friend", false))
+		require.NoError(ab.Label(alice, "friend", false))
+		assert.Error(ab.Label(bob, "friend", false))
+
+		require.NoError(ab.Label(bob, "friend", true))
+		addr, err := ab.Lookup("friend")
+		require.NoError(err)
+		assert.Equal(bob, addr)
+	})
+
+	t.Run("rejects invalid labels", func(t *testing.T) {
+		assert := assert.New(t)
+
+		ab := NewAddressBook(datastore.NewMapDatastore())
+		assert.Error(ab.Label(alice, "", false))
+		assert.Error(ab.Label(alice, "a/b", false))
+		assert.Error(ab.Label(alice, "my friend", false))
+		assert.Error(ab.Label(alice, bob.String(), false))
+	})
+
+	t.Run("lists and removes labels", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		ds := datastore.NewMapDatastore()
+		ab := NewAddressBook(ds)
+		require.NoError(ab.Label(bob, "bob", false))
+		require.NoError(ab.Label(alice, "alice", false))
+
+		// labels are persisted in the datastore
+		labels, err := NewAddressBook(ds).Labels()
+		require.NoError(err)
+		assert.Equal([]AddressLabel{{Name: "alice", Address: alice}, {Name: "bob", Address: bob}}, labels)
+
+		require.NoError(ab.Unlabel("alice"))
+		assert.Equal(ErrUnknownLabel, ab.Unlabel("alice"))
+		_, err = ab.Lookup("alice")
+		assert.Equal(ErrUnknownLabel, err)
+	})
+}
@@ -21,6 +21,8 @@ import (
 const (
 	// SECP256K1 is a curve used to computer private keys
 	SECP256K1 = "secp256k1"
+	// BLS is the BLS12-381 curve, whose signatures can be aggregated
+	BLS = "bls"
 )
 
 // DSBackendType is the reflect type of the DSBackend.
@@ -35,6 +37,9 @@ type DSBackend struct {
 
 	// TODO: proper cache
 	cache map[address.Address]struct{}
+
+	// hdLk serializes derivation of HD addresses, see dsbackend_hd.go.
+	hdLk sync.Mutex
 }
 
 var _ Backend = (*DSBackend)(nil)
@@ -55,6 +60,9 @@ func NewDSBackend(ds repo.Datastore) (*DSBackend, error) {
 
 	cache := make(map[address.Address]struct{})
 	for _, el := range list {
+		if strings.HasPrefix(el.Key, hdKeyPrefix) {
+			continue
+		}
 		parsedAddr, err := address.NewFromString(strings.Trim(el.Key, "/"))
 		if err != nil {
 			return nil, errors.Wrapf(err, "trying to restore invalid address: %s", el.Key)
@@ -95,18 +103,36 @@ func (backend *DSBackend) HasAddress(addr address.Address) bool {
 	return ok
 }
 
-// NewAddress creates a new address and stores it.
+// NewAddress creates a new secp256k1 address and stores it.
 // Safe for concurrent access.
 func (backend *DSBackend) NewAddress() (address.Address, error) {
-	prv, err := crypto.GenerateKey()
-	if err != nil {
-		return address.Address{}, err
-	}
+	return backend.NewAddressOfType(SECP256K1)
+}
+
+// NewAddressOfType creates a new address backed by a key of the given type,
+// SECP256K1 or BLS, and stores it.
+// Safe for concurrent access.
+func (backend *DSBackend) NewAddressOfType(keyType string) (address.Address, error) {
+	var ki *types.KeyInfo
+	switch keyType {
+	case SECP256K1:
+		prv, err := crypto.GenerateKey()
+		if err != nil {
+			return address.Address{}, err
+		}
 
-	// TODO: maybe the above call should just return a keyinfo?
-	ki := &types.KeyInfo{
-		PrivateKey: crypto.ECDSAToBytes(prv),
-		Curve:      SECP256K1,
+		// TODO: maybe the above call should just return a keyinfo?
+		ki = &types.KeyInfo{
+			PrivateKey: crypto.ECDSAToBytes(prv),
+			Curve:      SECP256K1,
+		}
+	case BLS:
+		ki = &types.KeyInfo{
+			PrivateKey: wutil.NewBLSPrivateKey(),
+			Curve:      BLS,
+		}
+	default:
+		return address.Address{}, fmt.Errorf("unknown key type %q", keyType)
 	}
 
 	if err := backend.putKeyInfo(ki); err != nil {
@@ -145,6 +171,10 @@ func (backend *DSBackend) SignBytes(data []byte, addr address.Address) (types.Si
 		return nil, err
 	}
 
+	if ki.Type() == BLS {
+		return wutil.BLSSign(ki.Key(), data)
+	}
+
 	privateKey, _, err := keysFromInfo(ki)
 	if err != nil {
 		return nil, err
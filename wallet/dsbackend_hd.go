@@ -0,0 +1,158 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	ds "gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/wallet/hd"
+)
+
+// hdKeyPrefix namespaces the HD wallet state in the backend's datastore so
+// it is not mistaken for a stored address.
+const hdKeyPrefix = "/hd/"
+
+var (
+	hdSeedKey      = ds.NewKey(hdKeyPrefix + "seed")
+	hdNextIndexKey = ds.NewKey(hdKeyPrefix + "next")
+)
+
+// HasHDSeed returns true if the backend has an HD seed to derive addresses
+// from.
+func (backend *DSBackend) HasHDSeed() (bool, error) {
+	return backend.ds.Has(hdSeedKey)
+}
+
+// NewHDAddress derives the next address from the backend's HD seed along the
+// BIP44 path m/44'/461'/0'/0/i and stores it. If the backend has no seed yet
+// a new one is generated and its mnemonic is returned alongside the address;
+// otherwise the returned mnemonic is empty. The mnemonic is not stored, so it
+// is the caller's only chance to record it.
+// Safe for concurrent access.
+func (backend *DSBackend) NewHDAddress() (address.Address, string, error) {
+	backend.hdLk.Lock()
+	defer backend.hdLk.Unlock()
+
+	var mnemonic string
+	seed, err := backend.ds.Get(hdSeedKey)
+	if err == ds.ErrNotFound {
+		mnemonic, err = hd.NewMnemonic()
+		if err != nil {
+			return address.Address{}, "", err
+		}
+		seed, err = hd.SeedFromMnemonic(mnemonic, "")
+		if err != nil {
+			return address.Address{}, "", err
+		}
+		if err := backend.ds.Put(hdSeedKey, seed); err != nil {
+			return address.Address{}, "", errors.Wrap(err, "failed to store hd seed")
+		}
+	} else if err != nil {
+		return address.Address{}, "", errors.Wrap(err, "failed to read hd seed")
+	}
+
+	next, err := backend.nextHDIndex()
+	if err != nil {
+		return address.Address{}, "", err
+	}
+
+	addrs, err := backend.deriveHDAddresses(seed, next, 1)
+	if err != nil {
+		return address.Address{}, "", err
+	}
+	return addrs[0], mnemonic, nil
+}
+
+// RecoverHD restores the backend's HD seed from mnemonic and derives and
+// stores the first n addresses. It fails if the backend already holds a
+// different seed.
+// Safe for concurrent access.
+func (backend *DSBackend) RecoverHD(mnemonic string, n uint32) ([]address.Address, error) {
+	seed, err := hd.SeedFromMnemonic(mnemonic, "")
+	if err != nil {
+		return nil, err
+	}
+
+	backend.hdLk.Lock()
+	defer backend.hdLk.Unlock()
+
+	existing, err := backend.ds.Get(hdSeedKey)
+	switch {
+	case err == ds.ErrNotFound:
+		if err := backend.ds.Put(hdSeedKey, seed); err != nil {
+			return nil, errors.Wrap(err, "failed to store hd seed")
+		}
+	case err != nil:
+		return nil, errors.Wrap(err, "failed to read hd seed")
+	case !bytes.Equal(existing, seed):
+		return nil, errors.New("wallet already has a different hd seed")
+	}
+
+	return backend.deriveHDAddresses(seed, 0, n)
+}
+
+// deriveHDAddresses derives and stores n addresses starting at index start,
+// advancing the stored next index past them. Callers must hold hdLk.
+func (backend *DSBackend) deriveHDAddresses(seed []byte, start, n uint32) ([]address.Address, error) {
+	master, err := hd.NewMasterKey(seed)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []address.Address
+	for i := start; i < start+n; i++ {
+		path, err := hd.ParsePath(hd.AccountPath(i))
+		if err != nil {
+			return nil, err
+		}
+		key, err := master.Derive(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to derive key %d", i)
+		}
+
+		ki := &types.KeyInfo{
+			PrivateKey: key.Key,
+			Curve:      SECP256K1,
+		}
+		if err := backend.putKeyInfo(ki); err != nil {
+			return nil, err
+		}
+		addr, err := ki.Address()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, addr)
+	}
+
+	next, err := backend.nextHDIndex()
+	if err != nil {
+		return nil, err
+	}
+	if next < start+n {
+		if err := backend.putNextHDIndex(start + n); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func (backend *DSBackend) nextHDIndex() (uint32, error) {
+	b, err := backend.ds.Get(hdNextIndexKey)
+	if err == ds.ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to read next hd index")
+	}
+	return binary.BigEndian.Uint32(b), nil
+}
+
+func (backend *DSBackend) putNextHDIndex(i uint32) error {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], i)
+	return errors.Wrap(backend.ds.Put(hdNextIndexKey, b[:]), "failed to store next hd index")
+}
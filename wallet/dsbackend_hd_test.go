@@ -0,0 +1,89 @@
+package wallet
+
+import (
+	"testing"
+
+	"gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDSBackendHD(t *testing.T) {
+	t.Run("derives addresses from a generated seed", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		ds := datastore.NewMapDatastore()
+		defer ds.Close()
+
+		fs, err := NewDSBackend(ds)
+		require.NoError(err)
+
+		has, err := fs.HasHDSeed()
+		require.NoError(err)
+		assert.False(has)
+
+		addr1, mnemonic, err := fs.NewHDAddress()
+		require.NoError(err)
+		assert.NotEmpty(mnemonic)
+
+		addr2, again, err := fs.NewHDAddress()
+		require.NoError(err)
+		assert.Empty(again)
+		assert.NotEqual(addr1, addr2)
+
+		t.Log("hd state is not mistaken for addresses when reloading")
+		fs2, err := NewDSBackend(ds)
+		require.NoError(err)
+		assert.Len(fs2.Addresses(), 2)
+		assert.True(fs2.HasAddress(addr1))
+		assert.True(fs2.HasAddress(addr2))
+
+		t.Log("recovering from the mnemonic reproduces the same addresses")
+		other := datastore.NewMapDatastore()
+		defer other.Close()
+		fs3, err := NewDSBackend(other)
+		require.NoError(err)
+
+		recovered, err := fs3.RecoverHD(mnemonic, 2)
+		require.NoError(err)
+		assert.Equal(addr1, recovered[0])
+		assert.Equal(addr2, recovered[1])
+
+		addr3, _, err := fs3.NewHDAddress()
+		require.NoError(err)
+		assert.NotContains(recovered, addr3)
+	})
+
+	t.Run("refuses to replace an existing seed", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		ds := datastore.NewMapDatastore()
+		defer ds.Close()
+
+		fs, err := NewDSBackend(ds)
+		require.NoError(err)
+
+		_, _, err = fs.NewHDAddress()
+		require.NoError(err)
+
+		_, err = fs.RecoverHD("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about", 1)
+		assert.Error(err)
+	})
+
+	t.Run("rejects invalid mnemonics", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		ds := datastore.NewMapDatastore()
+		defer ds.Close()
+
+		fs, err := NewDSBackend(ds)
+		require.NoError(err)
+
+		_, err = fs.RecoverHD("not a mnemonic", 1)
+		assert.Error(err)
+	})
+}
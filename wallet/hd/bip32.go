@@ -0,0 +1,133 @@
+package hd
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+
+	"github.com/filecoin-project/go-filecoin/crypto"
+	cu "github.com/filecoin-project/go-filecoin/crypto/util"
+)
+
+// HardenedOffset is added to a child index to request hardened derivation.
+const HardenedOffset uint32 = 0x80000000
+
+// FilecoinCoinType is the SLIP-44 coin type registered for Filecoin.
+const FilecoinCoinType = 461
+
+// masterKeySalt is the HMAC key BIP32 uses to derive the master key from a
+// seed.
+var masterKeySalt = []byte("Bitcoin seed")
+
+// ExtendedKey is a BIP32 extended private key.
+type ExtendedKey struct {
+	// Key is the 32 byte secp256k1 private key.
+	Key []byte
+	// ChainCode is the 32 byte chain code used to derive children.
+	ChainCode []byte
+}
+
+// NewMasterKey derives the BIP32 master key from seed.
+func NewMasterKey(seed []byte) (*ExtendedKey, error) {
+	if len(seed) < 16 || len(seed) > 64 {
+		return nil, errors.Errorf("invalid seed length %d", len(seed))
+	}
+
+	mac := hmac.New(sha512.New, masterKeySalt)
+	mac.Write(seed) // nolint: errcheck
+	sum := mac.Sum(nil)
+
+	k := new(big.Int).SetBytes(sum[:32])
+	if k.Sign() == 0 || k.Cmp(crypto.S256().Params().N) >= 0 {
+		return nil, errors.New("seed produced an invalid master key")
+	}
+
+	return &ExtendedKey{Key: sum[:32], ChainCode: sum[32:]}, nil
+}
+
+// Child derives the child key at index i. Indexes at or above HardenedOffset
+// request hardened derivation.
+func (k *ExtendedKey) Child(i uint32) (*ExtendedKey, error) {
+	var data []byte
+	if i >= HardenedOffset {
+		data = append([]byte{0}, k.Key...)
+	} else {
+		prv, err := crypto.BytesToECDSA(k.Key)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid parent key")
+		}
+		data = cu.SerializeCompressed(&prv.PublicKey)
+	}
+	var idx [4]byte
+	binary.BigEndian.PutUint32(idx[:], i)
+	data = append(data, idx[:]...)
+
+	mac := hmac.New(sha512.New, k.ChainCode)
+	mac.Write(data) // nolint: errcheck
+	sum := mac.Sum(nil)
+
+	n := crypto.S256().Params().N
+	il := new(big.Int).SetBytes(sum[:32])
+	if il.Cmp(n) >= 0 {
+		return nil, errors.Errorf("invalid child key at index %d", i)
+	}
+	child := il.Add(il, new(big.Int).SetBytes(k.Key))
+	child.Mod(child, n)
+	if child.Sign() == 0 {
+		return nil, errors.Errorf("invalid child key at index %d", i)
+	}
+
+	return &ExtendedKey{Key: cu.PaddedBigBytes(child, 32), ChainCode: sum[32:]}, nil
+}
+
+// Derive walks path from k, returning the key at its end.
+func (k *ExtendedKey) Derive(path []uint32) (*ExtendedKey, error) {
+	key := k
+	for _, i := range path {
+		var err error
+		key, err = key.Child(i)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// ParsePath parses a derivation path such as "m/44'/461'/0'/0/0". Hardened
+// indexes are marked with a trailing ' or h.
+func ParsePath(path string) ([]uint32, error) {
+	parts := strings.Split(path, "/")
+	if parts[0] != "m" {
+		return nil, errors.Errorf("derivation path %q must start with m", path)
+	}
+
+	var out []uint32
+	for _, p := range parts[1:] {
+		hardened := strings.HasSuffix(p, "'") || strings.HasSuffix(p, "h")
+		if hardened {
+			p = p[:len(p)-1]
+		}
+		i, err := strconv.ParseUint(p, 10, 31)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid derivation path %q", path)
+		}
+		idx := uint32(i)
+		if hardened {
+			idx += HardenedOffset
+		}
+		out = append(out, idx)
+	}
+	return out, nil
+}
+
+// AccountPath returns the BIP44 derivation path of the i-th Filecoin address
+// of the first account, m/44'/461'/0'/0/i.
+func AccountPath(i uint32) string {
+	return fmt.Sprintf("m/44'/%d'/0'/0/%d", FilecoinCoinType, i)
+}
@@ -0,0 +1,48 @@
+package hd
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeriveVector(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// BIP32 test vector 1.
+	seed, err := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	require.NoError(err)
+
+	master, err := NewMasterKey(seed)
+	require.NoError(err)
+	assert.Equal("e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35", hex.EncodeToString(master.Key))
+	assert.Equal("873dff81c02f525623fd1fe5167eac3a55a049de3d314bb42ee227ffed37d508", hex.EncodeToString(master.ChainCode))
+
+	path, err := ParsePath("m/0'/1")
+	require.NoError(err)
+	assert.Equal([]uint32{HardenedOffset, 1}, path)
+
+	key, err := master.Derive(path)
+	require.NoError(err)
+	assert.Equal("3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368", hex.EncodeToString(key.Key))
+	assert.Equal("2a7857631386ba23dacac34180dd1983734e444fdbf774041578e9b6adb37c19", hex.EncodeToString(key.ChainCode))
+}
+
+func TestParsePath(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	path, err := ParsePath(AccountPath(7))
+	require.NoError(err)
+	assert.Equal([]uint32{44 + HardenedOffset, FilecoinCoinType + HardenedOffset, HardenedOffset, 0, 7}, path)
+
+	_, err = ParsePath("44'/0")
+	assert.Error(err)
+	_, err = ParsePath("m/x")
+	assert.Error(err)
+	_, err = ParsePath("m/2147483648")
+	assert.Error(err)
+}
@@ -0,0 +1,138 @@
+package hd
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"math/big"
+	"strings"
+
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+)
+
+// DefaultEntropyBits is the amount of entropy used for newly generated
+// mnemonics, which results in 24 words.
+const DefaultEntropyBits = 256
+
+// seedIterations is the number of PBKDF2 rounds BIP39 prescribes when
+// turning a mnemonic into a seed.
+const seedIterations = 2048
+
+var wordIndex = func() map[string]int {
+	idx := make(map[string]int, len(englishWords))
+	for i, w := range englishWords {
+		idx[w] = i
+	}
+	return idx
+}()
+
+// NewMnemonic generates a fresh BIP39 mnemonic with DefaultEntropyBits of
+// entropy.
+func NewMnemonic() (string, error) {
+	entropy := make([]byte, DefaultEntropyBits/8)
+	if _, err := rand.Read(entropy); err != nil {
+		return "", errors.Wrap(err, "failed to read entropy")
+	}
+	return MnemonicFromEntropy(entropy)
+}
+
+// MnemonicFromEntropy encodes entropy as a BIP39 mnemonic. The entropy must be
+// between 128 and 256 bits long and a multiple of 32 bits.
+func MnemonicFromEntropy(entropy []byte) (string, error) {
+	entBits := len(entropy) * 8
+	if entBits < 128 || entBits > 256 || entBits%32 != 0 {
+		return "", errors.Errorf("invalid entropy length %d bits", entBits)
+	}
+	csBits := entBits / 32
+
+	sum := sha256.Sum256(entropy)
+	bits := new(big.Int).SetBytes(entropy)
+	bits.Lsh(bits, uint(csBits))
+	bits.Or(bits, big.NewInt(int64(sum[0]>>uint(8-csBits))))
+
+	nWords := (entBits + csBits) / 11
+	words := make([]string, nWords)
+	mask := big.NewInt(2047)
+	for i := nWords - 1; i >= 0; i-- {
+		words[i] = englishWords[new(big.Int).And(bits, mask).Int64()]
+		bits.Rsh(bits, 11)
+	}
+	return strings.Join(words, " "), nil
+}
+
+// EntropyFromMnemonic decodes a BIP39 mnemonic back into its entropy,
+// verifying its checksum.
+func EntropyFromMnemonic(mnemonic string) ([]byte, error) {
+	words := strings.Fields(mnemonic)
+	if len(words)%3 != 0 || len(words) < 12 || len(words) > 24 {
+		return nil, errors.Errorf("invalid mnemonic length %d", len(words))
+	}
+
+	bits := new(big.Int)
+	for _, w := range words {
+		i, ok := wordIndex[w]
+		if !ok {
+			return nil, errors.Errorf("invalid mnemonic word %q", w)
+		}
+		bits.Lsh(bits, 11)
+		bits.Or(bits, big.NewInt(int64(i)))
+	}
+
+	csBits := len(words) * 11 / 33
+	entBits := csBits * 32
+	checksum := new(big.Int).And(bits, big.NewInt(int64(1<<uint(csBits)-1)))
+	bits.Rsh(bits, uint(csBits))
+
+	entropy := make([]byte, entBits/8)
+	raw := bits.Bytes()
+	copy(entropy[len(entropy)-len(raw):], raw)
+
+	sum := sha256.Sum256(entropy)
+	if checksum.Int64() != int64(sum[0]>>uint(8-csBits)) {
+		return nil, errors.New("invalid mnemonic checksum")
+	}
+	return entropy, nil
+}
+
+// ValidateMnemonic returns an error if mnemonic is not a well formed BIP39
+// mnemonic.
+func ValidateMnemonic(mnemonic string) error {
+	_, err := EntropyFromMnemonic(mnemonic)
+	return err
+}
+
+// SeedFromMnemonic validates mnemonic and derives the 64 byte BIP39 seed
+// from it, salted with the optional passphrase.
+func SeedFromMnemonic(mnemonic, passphrase string) ([]byte, error) {
+	if err := ValidateMnemonic(mnemonic); err != nil {
+		return nil, err
+	}
+	normalized := strings.Join(strings.Fields(mnemonic), " ")
+	return pbkdf2SHA512([]byte(normalized), []byte("mnemonic"+passphrase), seedIterations), nil
+}
+
+// pbkdf2SHA512 implements PBKDF2 (RFC 2898) with HMAC-SHA512, producing a
+// single 64 byte block, which is all BIP39 needs.
+func pbkdf2SHA512(password, salt []byte, iterations int) []byte {
+	prf := hmac.New(sha512.New, password)
+
+	var ctr [4]byte
+	binary.BigEndian.PutUint32(ctr[:], 1)
+	prf.Write(salt)   // nolint: errcheck
+	prf.Write(ctr[:]) // nolint: errcheck
+	u := prf.Sum(nil)
+
+	out := make([]byte, len(u))
+	copy(out, u)
+	for i := 1; i < iterations; i++ {
+		prf.Reset()
+		prf.Write(u) // nolint: errcheck
+		u = prf.Sum(u[:0])
+		for j := range out {
+			out[j] ^= u[j]
+		}
+	}
+	return out
+}
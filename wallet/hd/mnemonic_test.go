@@ -0,0 +1,61 @@
+package hd
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMnemonicVectors(t *testing.T) {
+	vectors := []struct {
+		entropy  string
+		mnemonic string
+	}{
+		{"00000000000000000000000000000000", "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"},
+		{"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f", "legal winner thank year wave sausage worth useful legal winner thank yellow"},
+		{"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo vote"},
+	}
+
+	for _, v := range vectors {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		entropy, err := hex.DecodeString(v.entropy)
+		require.NoError(err)
+
+		mnemonic, err := MnemonicFromEntropy(entropy)
+		require.NoError(err)
+		assert.Equal(v.mnemonic, mnemonic)
+
+		decoded, err := EntropyFromMnemonic(mnemonic)
+		require.NoError(err)
+		assert.Equal(entropy, decoded)
+	}
+}
+
+func TestSeedFromMnemonic(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	mnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	seed, err := SeedFromMnemonic(mnemonic, "TREZOR")
+	require.NoError(err)
+	assert.Equal("c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04", hex.EncodeToString(seed))
+}
+
+func TestValidateMnemonic(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	mnemonic, err := NewMnemonic()
+	require.NoError(err)
+	assert.Len(strings.Fields(mnemonic), 24)
+	assert.NoError(ValidateMnemonic(mnemonic))
+
+	assert.Error(ValidateMnemonic("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon"))
+	assert.Error(ValidateMnemonic("abandon abandon abandon"))
+	assert.Error(ValidateMnemonic("notaword abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"))
+}
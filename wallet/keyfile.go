@@ -0,0 +1,126 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io"
+
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// KeyFileVersion is the version of the key interchange format written by
+// this version of go-filecoin.
+const KeyFileVersion = 1
+
+// KeyFile is the interchange format in which keys are exported from and
+// imported into the wallet. It is stable across go-filecoin versions; any
+// incompatible change bumps KeyFileVersion. Encoded as JSON it looks like:
+//
+//	{
+//	  "version": 1,
+//	  "type": "secp256k1" or "bls",
+//	  "privateKey": "<hex encoded private key>",
+//	  "address": "<address derived from the key>"
+//	}
+//
+// The address is redundant and only checked on import, guarding against
+// corrupted files and making key files easy to tell apart.
+type KeyFile struct {
+	Version    int    `json:"version"`
+	Type       string `json:"type"`
+	PrivateKey string `json:"privateKey"`
+	Address    string `json:"address"`
+}
+
+// NewKeyFile returns the KeyFile for ki.
+func NewKeyFile(ki *types.KeyInfo) (*KeyFile, error) {
+	addr, err := ki.Address()
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeyFile{
+		Version:    KeyFileVersion,
+		Type:       ki.Type(),
+		PrivateKey: hex.EncodeToString(ki.Key()),
+		Address:    addr.String(),
+	}, nil
+}
+
+// KeyInfo validates the key file and returns the key it holds.
+func (kf *KeyFile) KeyInfo() (*types.KeyInfo, error) {
+	if kf.Version != KeyFileVersion {
+		return nil, errors.Errorf("unsupported key file version %d", kf.Version)
+	}
+	if kf.Type != SECP256K1 && kf.Type != BLS {
+		return nil, errors.Errorf("unsupported key type %q", kf.Type)
+	}
+
+	prv, err := hex.DecodeString(kf.PrivateKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid private key encoding")
+	}
+	ki := &types.KeyInfo{
+		PrivateKey: prv,
+		Curve:      kf.Type,
+	}
+
+	addr, err := ki.Address()
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid private key")
+	}
+	if addr.String() != kf.Address {
+		return nil, errors.Errorf("key file is for address %s but its key belongs to %s", kf.Address, addr)
+	}
+	return ki, nil
+}
+
+// DecodeKeyFiles reads a stream of JSON encoded key files from r and returns
+// the keys they hold. For backwards compatibility it also accepts the bare
+// types.KeyInfo JSON written by earlier versions, which carries no version.
+func DecodeKeyFiles(r io.Reader) ([]*types.KeyInfo, error) {
+	var out []*types.KeyInfo
+
+	dec := json.NewDecoder(r)
+	for {
+		var raw json.RawMessage
+		err := dec.Decode(&raw)
+		if err == io.EOF {
+			return out, nil
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to decode key file")
+		}
+
+		ki, err := decodeKeyFile(raw)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, ki)
+	}
+}
+
+func decodeKeyFile(raw json.RawMessage) (*types.KeyInfo, error) {
+	var probe struct {
+		Version *int `json:"version"`
+	}
+	if err := json.Unmarshal(raw, &probe); err != nil {
+		return nil, errors.Wrap(err, "failed to decode key file")
+	}
+
+	if probe.Version == nil {
+		var ki types.KeyInfo
+		if err := json.Unmarshal(raw, &ki); err != nil {
+			return nil, errors.Wrap(err, "failed to decode legacy key info")
+		}
+		return &ki, nil
+	}
+
+	var kf KeyFile
+	if err := json.Unmarshal(raw, &kf); err != nil {
+		return nil, errors.Wrap(err, "failed to decode key file")
+	}
+	return kf.KeyInfo()
+}
@@ -0,0 +1,90 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/crypto"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func requireNewKeyInfo(require *require.Assertions) *types.KeyInfo {
+	prv, err := crypto.GenerateKey()
+	require.NoError(err)
+	return &types.KeyInfo{
+		PrivateKey: crypto.ECDSAToBytes(prv),
+		Curve:      SECP256K1,
+	}
+}
+
+func TestKeyFileRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ki1 := requireNewKeyInfo(require)
+	ki2 := requireNewKeyInfo(require)
+
+	var buf bytes.Buffer
+	for _, ki := range []*types.KeyInfo{ki1, ki2} {
+		kf, err := NewKeyFile(ki)
+		require.NoError(err)
+		assert.Equal(KeyFileVersion, kf.Version)
+		require.NoError(json.NewEncoder(&buf).Encode(kf))
+	}
+
+	kis, err := DecodeKeyFiles(&buf)
+	require.NoError(err)
+	require.Len(kis, 2)
+	assert.True(ki1.Equals(kis[0]))
+	assert.True(ki2.Equals(kis[1]))
+}
+
+func TestDecodeKeyFiles(t *testing.T) {
+	t.Run("accepts legacy key infos", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		ki := requireNewKeyInfo(require)
+		raw, err := json.Marshal(ki)
+		require.NoError(err)
+
+		kis, err := DecodeKeyFiles(bytes.NewReader(raw))
+		require.NoError(err)
+		require.Len(kis, 1)
+		assert.True(ki.Equals(kis[0]))
+	})
+
+	t.Run("rejects mismatched addresses", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		kf, err := NewKeyFile(requireNewKeyInfo(require))
+		require.NoError(err)
+		other, err := NewKeyFile(requireNewKeyInfo(require))
+		require.NoError(err)
+		kf.Address = other.Address
+
+		raw, err := json.Marshal(kf)
+		require.NoError(err)
+		_, err = DecodeKeyFiles(bytes.NewReader(raw))
+		assert.Error(err)
+	})
+
+	t.Run("rejects unknown versions", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		kf, err := NewKeyFile(requireNewKeyInfo(require))
+		require.NoError(err)
+		kf.Version = KeyFileVersion + 1
+
+		raw, err := json.Marshal(kf)
+		require.NoError(err)
+		_, err = DecodeKeyFiles(bytes.NewReader(raw))
+		assert.Error(err)
+	})
+}
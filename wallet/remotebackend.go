@@ -0,0 +1,325 @@
+package wallet
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	logging "gx/ipfs/QmcuXC5cxs79ro2cUuHs4HQ2bkDLJUYokwL8aivcX6HW3C/go-log"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/types"
+	wutil "github.com/filecoin-project/go-filecoin/wallet/util"
+)
+
+var log = logging.Logger("wallet")
+
+// RemoteBackendType is the reflect type of the RemoteBackend.
+var RemoteBackendType = reflect.TypeOf(&RemoteBackend{})
+
+// ErrKeyNotExportable is returned when asking a backend for key material it
+// does not hold, such as a remote signer.
+var ErrKeyNotExportable = errors.New("private key cannot be exported from this backend")
+
+const (
+	// DefaultRemoteSignerRetries is the number of times a failed request to
+	// a remote signer is retried.
+	DefaultRemoteSignerRetries = 3
+	// DefaultRemoteSignerBackoff is the delay before the first retry of a
+	// failed request to a remote signer. It doubles with each retry.
+	DefaultRemoteSignerBackoff = 500 * time.Millisecond
+	// DefaultRemoteSignerTimeout is how long a request to a remote signer
+	// takes at most.
+	DefaultRemoteSignerTimeout = 10 * time.Second
+)
+
+// RemoteBackend is a wallet backend that holds no keys itself but forwards
+// signing requests to a remote signing service, for instance one backed by
+// an HSM. The service is expected to expose:
+//
+//	GET  /health     200 if the service is able to sign
+//	GET  /addresses  {"addresses": ["<address>", ...]}
+//	POST /sign       {"address": "<address>", "data": "<base64>"} -> {"signature": "<base64>"}
+//
+// Requests that fail with a network error or a 5xx status are retried with
+// exponential backoff.
+type RemoteBackend struct {
+	url     string
+	client  *http.Client
+	retries int
+	backoff time.Duration
+
+	lk      sync.RWMutex
+	addrs   map[address.Address]struct{}
+	healthy bool
+}
+
+var _ Backend = (*RemoteBackend)(nil)
+
+// RemoteBackendOption configures a RemoteBackend.
+type RemoteBackendOption func(*RemoteBackend)
+
+// WithHTTPClient sets the http client used to talk to the remote signer,
+// e.g. one created by NewMutualTLSClient.
+func WithHTTPClient(client *http.Client) RemoteBackendOption {
+	return func(rb *RemoteBackend) {
+		rb.client = client
+	}
+}
+
+// WithRetries sets how often and after what initial delay failed requests
+// are retried.
+func WithRetries(retries int, backoff time.Duration) RemoteBackendOption {
+	return func(rb *RemoteBackend) {
+		rb.retries = retries
+		rb.backoff = backoff
+	}
+}
+
+// NewRemoteBackend returns a backend that signs using the service at url.
+// It checks the service's health and loads its addresses right away, but a
+// failure to do so is only logged: the backend keeps reporting no addresses
+// until a later health check succeeds.
+func NewRemoteBackend(url string, opts ...RemoteBackendOption) *RemoteBackend {
+	rb := &RemoteBackend{
+		url:     strings.TrimRight(url, "/"),
+		client:  &http.Client{Timeout: DefaultRemoteSignerTimeout},
+		retries: DefaultRemoteSignerRetries,
+		backoff: DefaultRemoteSignerBackoff,
+		addrs:   make(map[address.Address]struct{}),
+	}
+	for _, o := range opts {
+		o(rb)
+	}
+
+	if err := rb.CheckHealth(context.Background()); err != nil {
+		log.Warningf("remote signer at %s is unavailable: %s", rb.url, err)
+	}
+	return rb
+}
+
+// NewMutualTLSClient returns an http client that authenticates itself with
+// the given client certificate and only trusts servers signed by the CA in
+// caFile.
+func NewMutualTLSClient(certFile, keyFile, caFile string, timeout time.Duration) (*http.Client, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load client certificate")
+	}
+
+	caPEM, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read CA certificate")
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("no certificates found in %s", caFile)
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				Certificates: []tls.Certificate{cert},
+				RootCAs:      pool,
+			},
+		},
+	}, nil
+}
+
+// Addresses returns the addresses the remote signer reported at the last
+// successful health check.
+func (rb *RemoteBackend) Addresses() []address.Address {
+	rb.lk.RLock()
+	defer rb.lk.RUnlock()
+
+	var cpy []address.Address
+	for addr := range rb.addrs {
+		cpy = append(cpy, addr)
+	}
+	return cpy
+}
+
+// HasAddress checks if the remote signer holds the key for addr.
+// Safe for concurrent access.
+func (rb *RemoteBackend) HasAddress(addr address.Address) bool {
+	rb.lk.RLock()
+	defer rb.lk.RUnlock()
+
+	_, ok := rb.addrs[addr]
+	return ok
+}
+
+// Healthy returns the outcome of the last health check.
+func (rb *RemoteBackend) Healthy() bool {
+	rb.lk.RLock()
+	defer rb.lk.RUnlock()
+
+	return rb.healthy
+}
+
+type remoteAddressesResponse struct {
+	Addresses []string `json:"addresses"`
+}
+
+// CheckHealth asks the remote signer whether it is healthy and refreshes the
+// list of addresses it holds keys for.
+func (rb *RemoteBackend) CheckHealth(ctx context.Context) (err error) {
+	defer func() {
+		rb.lk.Lock()
+		rb.healthy = err == nil
+		rb.lk.Unlock()
+	}()
+
+	if err := rb.do(ctx, http.MethodGet, "/health", nil, nil); err != nil {
+		return errors.Wrap(err, "health check failed")
+	}
+
+	var res remoteAddressesResponse
+	if err := rb.do(ctx, http.MethodGet, "/addresses", nil, &res); err != nil {
+		return errors.Wrap(err, "failed to list addresses")
+	}
+
+	addrs := make(map[address.Address]struct{})
+	for _, s := range res.Addresses {
+		addr, err := address.NewFromString(s)
+		if err != nil {
+			return errors.Wrapf(err, "remote signer returned invalid address %s", s)
+		}
+		addrs[addr] = struct{}{}
+	}
+
+	rb.lk.Lock()
+	rb.addrs = addrs
+	rb.lk.Unlock()
+	return nil
+}
+
+// RunHealthChecks calls CheckHealth every period until ctx is done.
+func (rb *RemoteBackend) RunHealthChecks(ctx context.Context, period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			wasHealthy := rb.Healthy()
+			err := rb.CheckHealth(ctx)
+			if err != nil && wasHealthy {
+				log.Warningf("remote signer at %s became unavailable: %s", rb.url, err)
+			} else if err == nil && !wasHealthy {
+				log.Infof("remote signer at %s is available again", rb.url)
+			}
+		}
+	}
+}
+
+type remoteSignRequest struct {
+	Address string `json:"address"`
+	Data    []byte `json:"data"`
+}
+
+type remoteSignResponse struct {
+	Signature types.Signature `json:"signature"`
+}
+
+// SignBytes asks the remote signer to sign data with the key for addr. The
+// signers of the node take no context, so the request is bounded by the
+// timeout of the http client and the retries rather than cancelled.
+func (rb *RemoteBackend) SignBytes(data []byte, addr address.Address) (types.Signature, error) {
+	if !rb.HasAddress(addr) {
+		return nil, errors.New("backend does not contain address")
+	}
+
+	var res remoteSignResponse
+	if err := rb.do(context.Background(), http.MethodPost, "/sign", &remoteSignRequest{addr.String(), data}, &res); err != nil {
+		return nil, errors.Wrapf(err, "remote signer failed to sign with %s", addr)
+	}
+	return res.Signature, nil
+}
+
+// Verify cryptographically verifies that 'sig' is the signed hash of 'data' with
+// the public key `pk`.
+func (rb *RemoteBackend) Verify(data []byte, pk []byte, sig types.Signature) (bool, error) {
+	return wutil.Verify(pk, data, sig)
+}
+
+// GetKeyInfo always fails, the keys never leave the remote signer.
+func (rb *RemoteBackend) GetKeyInfo(addr address.Address) (*types.KeyInfo, error) {
+	return nil, ErrKeyNotExportable
+}
+
+// do sends a request to the remote signer, retrying transient failures, and
+// decodes the JSON response into out if it is not nil.
+func (rb *RemoteBackend) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		body, err = json.Marshal(in)
+		if err != nil {
+			return err
+		}
+	}
+
+	backoff := rb.backoff
+	var err error
+	for attempt := 0; ; attempt++ {
+		var retry bool
+		retry, err = rb.doOnce(ctx, method, path, body, out)
+		if err == nil || !retry || attempt >= rb.retries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// doOnce sends a single request, reporting whether a failure is worth
+// retrying.
+func (rb *RemoteBackend) doOnce(ctx context.Context, method, path string, body []byte, out interface{}) (bool, error) {
+	req, err := http.NewRequest(method, rb.url+path, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req = req.WithContext(ctx)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := rb.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		err := fmt.Errorf("remote signer responded %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+		return resp.StatusCode >= 500, err
+	}
+
+	if out == nil {
+		return false, nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return false, errors.Wrap(err, "failed to decode remote signer response")
+	}
+	return false, nil
+}
@@ -0,0 +1,162 @@
+package wallet
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/address"
+)
+
+// fakeSigner is a minimal remote signing service that signs with the keys
+// of a local DSBackend.
+type fakeSigner struct {
+	backend *DSBackend
+	// failures is the number of sign requests to fail before succeeding.
+	failures int32
+	healthy  int32
+}
+
+func (fs *fakeSigner) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/health":
+		if atomic.LoadInt32(&fs.healthy) == 0 {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+		}
+	case "/addresses":
+		var res remoteAddressesResponse
+		for _, a := range fs.backend.Addresses() {
+			res.Addresses = append(res.Addresses, a.String())
+		}
+		json.NewEncoder(w).Encode(res) // nolint: errcheck
+	case "/sign":
+		if atomic.AddInt32(&fs.failures, -1) >= 0 {
+			http.Error(w, "try again", http.StatusInternalServerError)
+			return
+		}
+		var req remoteSignRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		addr, err := address.NewFromString(req.Address)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		sig, err := fs.backend.SignBytes(req.Data, addr)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(remoteSignResponse{sig}) // nolint: errcheck
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newFakeSigner(t *testing.T) (*fakeSigner, address.Address) {
+	backend, err := NewDSBackend(datastore.NewMapDatastore())
+	require.NoError(t, err)
+	addr, err := backend.NewAddress()
+	require.NoError(t, err)
+	return &fakeSigner{backend: backend, healthy: 1}, addr
+}
+
+func TestRemoteBackend(t *testing.T) {
+	t.Run("signs with remote keys", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		signer, addr := newFakeSigner(t)
+		srv := httptest.NewServer(signer)
+		defer srv.Close()
+
+		rb := NewRemoteBackend(srv.URL, WithRetries(2, time.Millisecond))
+		assert.True(rb.Healthy())
+		assert.True(rb.HasAddress(addr))
+
+		data := []byte("data to sign")
+		sig, err := rb.SignBytes(data, addr)
+		require.NoError(err)
+
+		ki, err := signer.backend.GetKeyInfo(addr)
+		require.NoError(err)
+		pk, err := ki.PublicKey()
+		require.NoError(err)
+		valid, err := rb.Verify(data, pk, sig)
+		require.NoError(err)
+		assert.True(valid)
+
+		_, err = rb.GetKeyInfo(addr)
+		assert.Equal(ErrKeyNotExportable, err)
+	})
+
+	t.Run("retries transient failures", func(t *testing.T) {
+		assert := assert.New(t)
+
+		signer, addr := newFakeSigner(t)
+		srv := httptest.NewServer(signer)
+		defer srv.Close()
+
+		signer.failures = 2
+		rb := NewRemoteBackend(srv.URL, WithRetries(2, time.Millisecond))
+		_, err := rb.SignBytes([]byte("data"), addr)
+		assert.NoError(err)
+
+		signer.failures = 3
+		_, err = rb.SignBytes([]byte("data"), addr)
+		assert.Error(err)
+	})
+
+	t.Run("times out stuck signers", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		signer, addr := newFakeSigner(t)
+		stuck := make(chan struct{})
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/sign" {
+				<-stuck
+			}
+			signer.ServeHTTP(w, r)
+		}))
+		defer srv.Close()
+		defer close(stuck)
+
+		rb := NewRemoteBackend(srv.URL, WithRetries(0, time.Millisecond))
+		assert.Equal(DefaultRemoteSignerTimeout, rb.client.Timeout)
+		require.True(rb.HasAddress(addr))
+
+		rb.client = &http.Client{Timeout: 10 * time.Millisecond}
+		_, err := rb.SignBytes([]byte("data"), addr)
+		assert.Error(err)
+	})
+
+	t.Run("tracks health", func(t *testing.T) {
+		assert := assert.New(t)
+
+		signer, addr := newFakeSigner(t)
+		signer.healthy = 0
+		srv := httptest.NewServer(signer)
+		defer srv.Close()
+
+		rb := NewRemoteBackend(srv.URL, WithRetries(0, time.Millisecond))
+		assert.False(rb.Healthy())
+		assert.False(rb.HasAddress(addr))
+
+		atomic.StoreInt32(&signer.healthy, 1)
+		assert.NoError(rb.CheckHealth(context.Background()))
+		assert.True(rb.Healthy())
+		assert.True(rb.HasAddress(addr))
+	})
+}
@@ -15,6 +15,7 @@ import (
 
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/types"
+	wutil "github.com/filecoin-project/go-filecoin/wallet/util"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -174,3 +175,169 @@ func TestSignedMessageCorrupted(t *testing.T) {
 	smsg.Message.Nonce = types.Uint64(uint64(42))
 	assert.False(smsg.VerifySignature())
 }
+
+/* Test BLS signatures */
+
+func requireBLSSignerAddr(require *require.Assertions) (*DSBackend, address.Address) {
+	fs, err := NewDSBackend(datastore.NewMapDatastore())
+	require.NoError(err)
+
+	addr, err := fs.NewAddressOfType(BLS)
+	require.NoError(err)
+	return fs, addr
+}
+
+func TestBLSSignature(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	fs, addr := requireBLSSignerAddr(require)
+
+	data := []byte("THESE BYTES WILL BE SIGNED")
+	sig, err := fs.SignBytes(data, addr)
+	require.NoError(err)
+	assert.Len(sig, wutil.BLSSignatureBytes)
+	assert.True(types.IsValidSignature(data, addr, sig))
+
+	ki, err := fs.GetKeyInfo(addr)
+	require.NoError(err)
+	pk, err := ki.PublicKey()
+	require.NoError(err)
+	valid, err := fs.Verify(data, pk, sig)
+	require.NoError(err)
+	assert.True(valid)
+
+	assert.False(types.IsValidSignature([]byte("THESE BYTEZ WILL BE SIGNED"), addr, sig))
+
+	otherAddr, err := fs.NewAddressOfType(BLS)
+	require.NoError(err)
+	assert.False(types.IsValidSignature(data, otherAddr, sig))
+
+	sig[0] = sig[0] ^ 0xFF
+	assert.False(types.IsValidSignature(data, addr, sig))
+}
+
+func TestBLSSignedMessage(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	fs, addr := requireBLSSignerAddr(require)
+
+	msg := types.NewMessage(addr, addr, 1, nil, "", nil)
+	smsg, err := types.NewSignedMessage(*msg, fs, types.NewGasPrice(0), types.NewGasUnits(0))
+	require.NoError(err)
+
+	assert.True(smsg.IsBLSSigned())
+	assert.True(smsg.VerifySignature())
+
+	recovered, err := smsg.RecoverAddress(&types.MockRecoverer{})
+	require.NoError(err)
+	assert.Equal(addr, recovered)
+
+	smsg.Message.Nonce = types.Uint64(uint64(42))
+	assert.False(smsg.VerifySignature())
+}
+
+func TestBLSAggregate(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	fs, err := NewDSBackend(datastore.NewMapDatastore())
+	require.NoError(err)
+
+	var smsgs []*types.SignedMessage
+	var msgs []*types.MeteredMessage
+	for i := 0; i < 3; i++ {
+		addr, err := fs.NewAddressOfType(BLS)
+		require.NoError(err)
+
+		msg := types.NewMessage(addr, address.TestAddress, uint64(i), nil, "", nil)
+		smsg, err := types.NewSignedMessage(*msg, fs, types.NewGasPrice(0), types.NewGasUnits(0))
+		require.NoError(err)
+		smsgs = append(smsgs, smsg)
+		msgs = append(msgs, &smsg.MeteredMessage)
+	}
+
+	agg, pubKeys, err := types.AggregateBLSSignatures(smsgs)
+	require.NoError(err)
+
+	valid, err := types.VerifyBLSAggregate(agg, msgs, pubKeys)
+	require.NoError(err)
+	assert.True(valid)
+
+	t.Run("fails when a message changed", func(t *testing.T) {
+		changed := *msgs[1]
+		changed.Nonce = types.Uint64(uint64(42))
+		valid, err := types.VerifyBLSAggregate(agg, []*types.MeteredMessage{msgs[0], &changed, msgs[2]}, pubKeys)
+		require.NoError(err)
+		assert.False(valid)
+	})
+
+	t.Run("fails when keys do not match the senders", func(t *testing.T) {
+		swapped := [][]byte{pubKeys[1], pubKeys[0], pubKeys[2]}
+		valid, err := types.VerifyBLSAggregate(agg, msgs, swapped)
+		require.NoError(err)
+		assert.False(valid)
+	})
+
+	t.Run("rejects secp256k1 signed messages", func(t *testing.T) {
+		secpFS, secpAddr := requireSignerAddr(require)
+		msg := types.NewMessage(secpAddr, address.TestAddress, 0, nil, "", nil)
+		smsg, err := types.NewSignedMessage(*msg, secpFS, types.NewGasPrice(0), types.NewGasUnits(0))
+		require.NoError(err)
+
+		_, _, err = types.AggregateBLSSignatures(append(smsgs, smsg))
+		assert.Error(err)
+	})
+}
+
+func TestBlockBLSAggregate(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	fs, err := NewDSBackend(datastore.NewMapDatastore())
+	require.NoError(err)
+	secpFS, secpAddr := requireSignerAddr(require)
+
+	var smsgs []*types.SignedMessage
+	for i := 0; i < 2; i++ {
+		addr, err := fs.NewAddressOfType(BLS)
+		require.NoError(err)
+
+		msg := types.NewMessage(addr, address.TestAddress, uint64(i), nil, "", nil)
+		smsg, err := types.NewSignedMessage(*msg, fs, types.NewGasPrice(0), types.NewGasUnits(0))
+		require.NoError(err)
+		smsgs = append(smsgs, smsg)
+	}
+	msg := types.NewMessage(secpAddr, address.TestAddress, 0, nil, "", nil)
+	secpMsg, err := types.NewSignedMessage(*msg, secpFS, types.NewGasPrice(0), types.NewGasUnits(0))
+	require.NoError(err)
+
+	// secp256k1 signed messages are left out of the aggregate
+	blk := &types.Block{Messages: []*types.SignedMessage{smsgs[0], secpMsg, smsgs[1]}}
+	require.NoError(blk.AggregateBLSSignatures())
+	assert.NotEmpty(blk.BLSAggregate)
+	assert.NoError(blk.VerifyBLSAggregate())
+
+	t.Run("fails without the aggregate", func(t *testing.T) {
+		noAgg := *blk
+		noAgg.BLSAggregate = nil
+		assert.Error(noAgg.VerifyBLSAggregate())
+	})
+
+	t.Run("fails when a message is left out", func(t *testing.T) {
+		missing := *blk
+		missing.Messages = []*types.SignedMessage{smsgs[0], secpMsg}
+		assert.Error(missing.VerifyBLSAggregate())
+	})
+
+	t.Run("has no aggregate without BLS signed messages", func(t *testing.T) {
+		secpOnly := &types.Block{Messages: []*types.SignedMessage{secpMsg}}
+		require.NoError(secpOnly.AggregateBLSSignatures())
+		assert.Empty(secpOnly.BLSAggregate)
+		assert.NoError(secpOnly.VerifyBLSAggregate())
+
+		secpOnly.BLSAggregate = blk.BLSAggregate
+		assert.Error(secpOnly.VerifyBLSAggregate())
+	})
+}
@@ -0,0 +1,145 @@
+package walletutil
+
+import (
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+
+	bls "github.com/filecoin-project/go-filecoin/bls-signatures"
+)
+
+// BLSSignatureBytes is the length of the signatures produced by BLSSign.
+//
+// Filecoin addresses are hashes of public keys and, unlike secp256k1, a BLS
+// public key cannot be recovered from a signature. A BLS signature in a
+// message is therefore sent as the 96 byte signature followed by the 48 byte
+// public key of the signer, which lets verifiers check the key against the
+// sender's address.
+const BLSSignatureBytes = bls.SignatureBytes + bls.PublicKeyBytes
+
+// ErrInvalidBLSSignature is returned when a BLS signature does not verify.
+var ErrInvalidBLSSignature = errors.New("invalid BLS signature")
+
+// NewBLSPrivateKey generates a new BLS12-381 private key.
+func NewBLSPrivateKey() []byte {
+	prv := bls.PrivateKeyGenerate()
+	return prv[:]
+}
+
+// BLSPublicKey returns the compressed public key of a BLS private key.
+func BLSPublicKey(priv []byte) ([]byte, error) {
+	if len(priv) != bls.PrivateKeyBytes {
+		return nil, errors.Errorf("invalid BLS private key length %d", len(priv))
+	}
+
+	var prv bls.PrivateKey
+	copy(prv[:], priv)
+	pub := bls.PrivateKeyPublicKey(prv)
+	return pub[:], nil
+}
+
+// IsBLSSignature returns true if sig has the length of a signature produced
+// by BLSSign.
+func IsBLSSignature(sig []byte) bool {
+	return len(sig) == BLSSignatureBytes
+}
+
+// BLSSign signs data with a BLS private key and returns the signature
+// followed by the signer's public key, see BLSSignatureBytes.
+func BLSSign(priv []byte, data []byte) ([]byte, error) {
+	pub, err := BLSPublicKey(priv)
+	if err != nil {
+		return nil, err
+	}
+
+	var prv bls.PrivateKey
+	copy(prv[:], priv)
+	sig := bls.PrivateKeySign(prv, data)
+
+	return append(sig[:], pub...), nil
+}
+
+// BLSVerify verifies that sig, as returned by BLSSign, is a signature of
+// data by the public key pk.
+func BLSVerify(pk, data, sig []byte) bool {
+	if !IsBLSSignature(sig) || len(pk) != bls.PublicKeyBytes {
+		return false
+	}
+
+	var pub bls.PublicKey
+	copy(pub[:], pk)
+	if pub != blsPublicKeyOf(sig) {
+		return false
+	}
+
+	return bls.Verify(blsSignatureOf(sig), []bls.Digest{bls.Hash(data)}, []bls.PublicKey{pub})
+}
+
+// BLSRecover verifies sig, as returned by BLSSign, against data and returns
+// the public key it carries. It is the BLS counterpart of Ecrecover.
+func BLSRecover(data, sig []byte) ([]byte, error) {
+	if !IsBLSSignature(sig) {
+		return nil, errors.Errorf("invalid BLS signature length %d", len(sig))
+	}
+
+	pub := blsPublicKeyOf(sig)
+	if !BLSVerify(pub[:], data, sig) {
+		return nil, ErrInvalidBLSSignature
+	}
+	return pub[:], nil
+}
+
+// BLSSignaturePublicKey returns the public key carried by a signature
+// returned by BLSSign. It does not verify the signature.
+func BLSSignaturePublicKey(sig []byte) []byte {
+	pub := blsPublicKeyOf(sig)
+	return pub[:]
+}
+
+// BLSAggregate aggregates signatures returned by BLSSign into a single
+// signature. The result does not carry public keys, they have to be provided
+// separately to BLSVerifyAggregate.
+func BLSAggregate(sigs [][]byte) ([]byte, error) {
+	raw := make([]bls.Signature, len(sigs))
+	for i, sig := range sigs {
+		if !IsBLSSignature(sig) {
+			return nil, errors.Errorf("signature %d is not a BLS signature", i)
+		}
+		raw[i] = blsSignatureOf(sig)
+	}
+
+	agg := bls.Aggregate(raw)
+	return agg[:], nil
+}
+
+// BLSVerifyAggregate verifies that agg is the aggregate of signatures of each
+// of data by the public key at the same index of pubKeys.
+func BLSVerifyAggregate(agg []byte, data [][]byte, pubKeys [][]byte) bool {
+	if len(agg) != bls.SignatureBytes || len(data) != len(pubKeys) {
+		return false
+	}
+
+	digests := make([]bls.Digest, len(data))
+	pubs := make([]bls.PublicKey, len(pubKeys))
+	for i := range data {
+		if len(pubKeys[i]) != bls.PublicKeyBytes {
+			return false
+		}
+		digests[i] = bls.Hash(data[i])
+		copy(pubs[i][:], pubKeys[i])
+	}
+
+	var sig bls.Signature
+	copy(sig[:], agg)
+	return bls.Verify(sig, digests, pubs)
+}
+
+func blsSignatureOf(sig []byte) bls.Signature {
+	var out bls.Signature
+	copy(out[:], sig[:bls.SignatureBytes])
+	return out
+}
+
+func blsPublicKeyOf(sig []byte) bls.PublicKey {
+	var out bls.PublicKey
+	copy(out[:], sig[bls.SignatureBytes:])
+	return out
+}
@@ -24,6 +24,10 @@ func Sign(priv *ecdsa.PrivateKey, data []byte) ([]byte, error) {
 // Verify cryptographically verifies that 'sig' is the signed hash of 'data' with
 // the public key `pk`.
 func Verify(pk, data, signature []byte) (bool, error) {
+	if IsBLSSignature(signature) {
+		return BLSVerify(pk, data, signature), nil
+	}
+
 	hash := blake2b.Sum256(data)
 	// remove recovery id
 	sig := signature[:len(signature)-1]
@@ -33,8 +37,14 @@ func Verify(pk, data, signature []byte) (bool, error) {
 // Ecrecover returns an uncompressed public key that could produce the given
 // signature from data.
 // Note: The returned public key should not be used to verify `data` is valid
-// since a public key may have N private key pairs
+// since a public key may have N private key pairs.
+// BLS signatures are handed to BLSRecover, which verifies them and returns
+// the public key they carry.
 func Ecrecover(data, signature []byte) ([]byte, error) {
+	if IsBLSSignature(signature) {
+		return BLSRecover(data, signature)
+	}
+
 	hash := blake2b.Sum256(data)
 	return crypto.Ecrecover(hash[:], signature)
 }
@@ -122,11 +122,48 @@ func (w *Wallet) Ecrecover(data []byte, sig types.Signature) ([]byte, error) {
 
 // NewAddress creates a new account address on the default wallet backend.
 func NewAddress(w *Wallet) (address.Address, error) {
+	backend, err := defaultDSBackend(w)
+	if err != nil {
+		return address.Address{}, err
+	}
+	return backend.NewAddress()
+}
+
+// NewAddressOfType creates a new account address backed by a key of the
+// given type on the default wallet backend.
+func NewAddressOfType(w *Wallet, keyType string) (address.Address, error) {
+	backend, err := defaultDSBackend(w)
+	if err != nil {
+		return address.Address{}, err
+	}
+	return backend.NewAddressOfType(keyType)
+}
+
+// NewHDAddress derives a new account address from the HD seed of the default
+// wallet backend, generating the seed if necessary. See
+// DSBackend.NewHDAddress.
+func NewHDAddress(w *Wallet) (address.Address, string, error) {
+	backend, err := defaultDSBackend(w)
+	if err != nil {
+		return address.Address{}, "", err
+	}
+	return backend.NewHDAddress()
+}
+
+// RecoverHD restores the HD seed of the default wallet backend from mnemonic
+// and derives its first n addresses.
+func RecoverHD(w *Wallet, mnemonic string, n uint32) ([]address.Address, error) {
+	backend, err := defaultDSBackend(w)
+	if err != nil {
+		return nil, err
+	}
+	return backend.RecoverHD(mnemonic, n)
+}
+
+func defaultDSBackend(w *Wallet) (*DSBackend, error) {
 	backends := w.Backends(DSBackendType)
 	if len(backends) == 0 {
-		return address.Address{}, fmt.Errorf("missing default ds backend")
+		return nil, fmt.Errorf("missing default ds backend")
 	}
-
-	backend := (backends[0]).(*DSBackend)
-	return backend.NewAddress()
+	return (backends[0]).(*DSBackend), nil
 }